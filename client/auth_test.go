@@ -0,0 +1,31 @@
+package client_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/client"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWithBearerToken(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v1/zones", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`[{"name":"SE3","timezone":"Europe/Stockholm"}]`))
+	}))
+	defer server.Close()
+
+	c, err := client.NewClientWithResponses(server.URL+"/api/v1", client.WithBearerToken("secret"))
+	require.NoError(t, err)
+
+	resp, err := c.GetZonesWithResponse(context.Background(), nil)
+	require.NoError(t, err)
+	require.NotNil(t, resp.JSON200)
+	require.Len(t, *resp.JSON200, 1)
+	assert.Equal(t, "SE3", (*resp.JSON200)[0].Name)
+}
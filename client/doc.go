@@ -0,0 +1,17 @@
+// Package client is a typed Go client for the WattWatch API, generated from its OpenAPI 3
+// document. Scripts that ingest spot prices or read them back can import it instead of
+// building the HTTP requests by hand:
+//
+//	c, err := client.NewClientWithResponses("https://wattwatch.example.com/api/v1",
+//		client.WithBearerToken(token))
+//	if err != nil {
+//		return err
+//	}
+//	resp, err := c.PostSpotPricesWithResponse(ctx, client.ModelsCreateSpotPricesRequest{...})
+//
+// The server URL includes the /api/v1 base path. Run go generate ./client after changing
+// the swag annotations and regenerating the docs package.
+package client
+
+//go:generate go run ../cmd/openapi -o openapi.json
+//go:generate go run github.com/oapi-codegen/oapi-codegen/v2/cmd/oapi-codegen@v2.4.1 -config oapi-codegen.yaml openapi.json
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"wattwatch/internal/admin"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/config"
+	"wattwatch/internal/database"
+	"wattwatch/internal/repository/postgres"
+
+	"github.com/joho/godotenv"
+)
+
+// runAdmin runs "wattwatch admin <command>" against the database and returns the exit code
+func runAdmin(args []string) int {
+	fs := flag.NewFlagSet("admin", flag.ContinueOnError)
+	envFile := fs.String("env", ".env", "Path to env file")
+	configFile := fs.String("config", "", "Path to a YAML or TOML config file, environment variables override its values")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), admin.Usage)
+		fmt.Fprintln(fs.Output(), "\nGlobal flags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	cfg, db, err := openDatabase(*envFile, *configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	refreshTokenRepo := postgres.NewRefreshTokenRepository(db)
+	cli := admin.NewCLI(
+		postgres.NewUserRepository(db),
+		postgres.NewRoleRepository(db),
+		postgres.NewPasswordHistoryRepository(db),
+		postgres.NewLoginAttemptRepository(db),
+		refreshTokenRepo,
+		postgres.NewAuditLogRepository(db),
+		auth.NewService(cfg, refreshTokenRepo),
+		os.Stdout,
+	)
+
+	if err := cli.Run(context.Background(), fs.Args()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if errors.Is(err, admin.ErrUsage) {
+			return 2
+		}
+		return 1
+	}
+	return 0
+}
+
+// openDatabase loads the configuration the server would use and connects to its database,
+// for commands that work on the data without starting the server
+func openDatabase(envFile, configFile string) (*config.Config, *sql.DB, error) {
+	if err := godotenv.Load(envFile); err != nil && envFile != ".env" {
+		return nil, nil, fmt.Errorf("failed to load env file: %w", err)
+	}
+
+	cfg := &config.Config{}
+	if err := cfg.LoadFromFile(configFile); err != nil {
+		return nil, nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	db, err := database.Connect(cfg.Database)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return cfg, db, nil
+}
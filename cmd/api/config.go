@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"wattwatch/internal/config"
+
+	"github.com/joho/godotenv"
+)
+
+// configUsage describes the config subcommands
+const configUsage = `Usage: wattwatch config [flags] <command>
+
+Commands:
+  validate               Load the configuration, report invalid settings and settings that don't work together
+  print [--redacted]     Print the effective configuration as YAML, secrets are masked unless --redacted=false
+`
+
+// runConfig runs "wattwatch config <command>" and returns the exit code
+func runConfig(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("config", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	envFile := fs.String("env", ".env", "Path to env file")
+	configFile := fs.String("config", "", "Path to a YAML or TOML config file, environment variables override its values")
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), configUsage)
+		fmt.Fprintln(fs.Output(), "\nGlobal flags:")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	if err := godotenv.Load(*envFile); err != nil && *envFile != ".env" {
+		fmt.Fprintf(stderr, "Failed to load env file: %v\n", err)
+		return 1
+	}
+
+	cfg := &config.Config{}
+	loadErr := cfg.LoadFromFile(*configFile)
+
+	switch command, cmdArgs := fs.Arg(0), fs.Args()[1:]; command {
+	case "validate":
+		if loadErr != nil {
+			fmt.Fprintln(stderr, loadErr)
+			return 1
+		}
+		for _, warning := range cfg.Warnings() {
+			fmt.Fprintf(stdout, "warning: %s\n", warning)
+		}
+		fmt.Fprintln(stdout, "Configuration is valid")
+		return 0
+
+	case "print":
+		printFlags := flag.NewFlagSet("config print", flag.ContinueOnError)
+		printFlags.SetOutput(stderr)
+		redact := printFlags.Bool("redacted", true, "Mask secrets such as passwords and keys")
+		if err := printFlags.Parse(cmdArgs); err != nil {
+			return 2
+		}
+		// Printing an invalid configuration helps finding what is wrong with it, so
+		// only loading errors that left it unparsed stop here
+		if loadErr != nil && !errors.Is(loadErr, config.ErrInvalid) {
+			fmt.Fprintln(stderr, loadErr)
+			return 1
+		}
+		if err := cfg.WriteYAML(stdout, *redact); err != nil {
+			fmt.Fprintf(stderr, "Failed to print configuration: %v\n", err)
+			return 1
+		}
+		if loadErr != nil {
+			fmt.Fprintln(stderr, loadErr)
+			return 1
+		}
+		return 0
+
+	default:
+		fmt.Fprintf(stderr, "Unknown command %q\n\n", command)
+		fs.Usage()
+		return 2
+	}
+}
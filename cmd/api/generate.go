@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/database"
+	"wattwatch/internal/generate"
+	"wattwatch/internal/repository/postgres"
+)
+
+// runGenerate runs "wattwatch generate", filling the database with synthetic data for load
+// testing, and returns the exit code
+func runGenerate(args []string) int {
+	fs := flag.NewFlagSet("generate", flag.ContinueOnError)
+	envFile := fs.String("env", ".env", "Path to env file")
+	configFile := fs.String("config", "", "Path to a YAML or TOML config file, environment variables override its values")
+	zones := fs.Int("zones", 4, fmt.Sprintf("number of zones with prices, at most %d", generate.MaxZones))
+	years := fs.Int("years", 2, "years of hourly prices, ending today")
+	users := fs.Int("users", 1000, "number of generated users")
+	currency := fs.String("currency", "EUR", "currency of the generated prices")
+	password := fs.String("password", "loadtest-password", "password of every generated user")
+	seed := fs.Uint64("seed", 1, "seed for the price series, the same seed produces the same prices")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: wattwatch generate [flags]")
+		fmt.Fprintf(fs.Output(), "\nFills the database with synthetic zones, hourly spot prices and users named %s00001 and up.\nDon't run it against a production database.\n\nFlags:\n", generate.UsernamePrefix)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return 0
+		}
+		return 2
+	}
+
+	cfg, db, err := openDatabase(*envFile, *configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer db.Close()
+
+	// Generated data goes into the current schema
+	if err := database.RunMigrations(cfg.Database); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to run migrations: %v\n", err)
+		return 1
+	}
+
+	refreshTokenRepo := postgres.NewRefreshTokenRepository(db)
+	generator := generate.NewGenerator(
+		postgres.NewZoneRepository(db),
+		postgres.NewCurrencyRepository(db),
+		postgres.NewSpotPriceRepository(db),
+		postgres.NewUserRepository(db),
+		postgres.NewRoleRepository(db),
+		auth.NewService(cfg, refreshTokenRepo),
+		os.Stdout,
+	)
+
+	started := time.Now()
+	err = generator.Run(context.Background(), generate.Options{
+		Zones:    *zones,
+		Years:    *years,
+		Users:    *users,
+		Currency: *currency,
+		Password: *password,
+		Seed:     *seed,
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	fmt.Printf("Done in %s\n", time.Since(started).Round(time.Second))
+	return 0
+}
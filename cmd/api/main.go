@@ -21,19 +21,45 @@ import (
 	"os/signal"
 	"strconv"
 	"syscall"
-	"time"
 	"wattwatch/internal/api/routes"
+	"wattwatch/internal/api/server"
+	"wattwatch/internal/cache"
 	"wattwatch/internal/config"
 	"wattwatch/internal/database"
+	"wattwatch/internal/leader"
 	"wattwatch/internal/provider"
+	"wattwatch/internal/provider/entsoe"
+	"wattwatch/internal/provider/forecast"
+	"wattwatch/internal/provider/nordpool"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/scheduler"
+	"wattwatch/internal/selfcheck"
 	"wattwatch/internal/validation"
+	"wattwatch/internal/worker"
 
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	// Break-glass admin commands run against the database without starting the server
+	if len(os.Args) > 1 && os.Args[1] == "admin" {
+		os.Exit(runAdmin(os.Args[2:]))
+	}
+	// Synthetic data for load testing
+	if len(os.Args) > 1 && os.Args[1] == "generate" {
+		os.Exit(runGenerate(os.Args[2:]))
+	}
+	// Configuration checks load the settings the server would use and exit
+	if len(os.Args) > 1 && os.Args[1] == "config" {
+		os.Exit(runConfig(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	// Parse command line flags
 	envFile := flag.String("env", ".env", "Path to env file")
+	configFile := flag.String("config", "", "Path to a YAML or TOML config file, environment variables override its values")
+	migrateOnly := flag.Bool("migrate-only", false, "Apply pending database migrations and exit without serving")
 	flag.Parse()
 
 	// Load environment file
@@ -43,9 +69,25 @@ func main() {
 
 	// Load configuration
 	cfg := &config.Config{}
-	if err := cfg.LoadFromEnv(); err != nil {
+	if err := cfg.LoadFromFile(*configFile); err != nil {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
+	for _, warning := range cfg.Warnings() {
+		log.Printf("Configuration warning: %s", warning)
+	}
+
+	// Orchestrated deployments apply migrations in a job of their own before the servers start
+	if *migrateOnly {
+		if err := database.RunMigrations(cfg.Database); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+		state, err := database.MigrationStatus(cfg.Database)
+		if err != nil {
+			log.Fatalf("Failed to get migration status: %v", err)
+		}
+		log.Printf("Database schema at version %d", state.Current)
+		return
+	}
 
 	// Initialize database
 	db, err := database.Connect(cfg.Database)
@@ -54,19 +96,114 @@ func main() {
 	}
 	defer db.Close()
 
-	// Run migrations
-	if err := database.RunMigrations(cfg.Database); err != nil {
-		log.Fatalf("Failed to run migrations: %v", err)
+	// Listings are read from the replicas when configured, keeping dashboard traffic off the primary
+	replicaDBs, err := database.ConnectReplicas(cfg.Database)
+	if err != nil {
+		log.Fatalf("Failed to connect to replicas: %v", err)
+	}
+	var replicas *repository.ReplicaSet
+	if len(replicaDBs) > 0 {
+		replicas = repository.NewReplicaSet(db, replicaDBs...)
+		defer replicas.Close()
+	}
+
+	// Run migrations, unless they are applied separately
+	if cfg.Database.AutoMigrate {
+		if err := database.RunMigrations(cfg.Database); err != nil {
+			log.Fatalf("Failed to run migrations: %v", err)
+		}
+	} else {
+		log.Printf("Automatic migrations disabled, apply pending ones with --migrate-only or POST /api/v1/admin/migrations")
 	}
 
 	// Initialize validators
 	validation.Initialize()
 
-	// Initialize provider manager
+	// Spot prices stored by the providers and the API are pushed to streaming clients
+	hub := pubsub.NewHub(cfg.API.StreamMaxClients)
+
+	// Zones, currencies and recent spot prices are cached when enabled. Spot prices stored
+	// by the providers drop the cached ones, like those stored through the API.
+	var lookupCache *cache.Cache
+	if cfg.Cache.Enabled {
+		var store cache.Store = cache.NewMemoryStore(cfg.Cache.Size)
+		if cfg.Cache.Backend == config.CacheBackendRedis {
+			// Instances sharing Redis share the entries, lookups go to the database while it is unreachable
+			if redisStore, err := cache.NewRedisStore(cfg.Cache.RedisURL); err != nil {
+				log.Printf("Caching in memory: %v", err)
+			} else {
+				store = redisStore
+			}
+		}
+		lookupCache = cache.New(store)
+	}
+	var spotPriceSources repository.SpotPriceSourceRepository = hub.Sources(postgres.NewSpotPriceSourceRepository(db))
+	if lookupCache != nil {
+		spotPriceSources = lookupCache.Sources(spotPriceSources)
+	}
+
+	// Initialize provider manager with the providers of the registry, each configured by
+	// its providers.<name> block
+	providers := provider.NewRegistry()
+	providers.Register(nordpool.ProviderName, nordpool.Factory)
+	providers.Register(entsoe.ProviderName, entsoe.Factory)
+	providers.Register(forecast.ProviderName, forecast.Factory)
 	providerManager := provider.NewManager(db)
+	if err := providerManager.Load(providers, provider.Dependencies{
+		SpotPrices:  spotPriceSources,
+		Forecasts:   postgres.NewPriceForecastRepository(db),
+		Zones:       postgres.NewZoneRepository(db),
+		Currencies:  postgres.NewCurrencyRepository(db),
+		EntsoeAreas: postgres.NewEntsoeAreaRepository(db),
+	}, cfg.ProviderSettings); err != nil {
+		log.Fatalf("Failed to load providers: %v", err)
+	}
+	if p, ok := providerManager.GetProvider(entsoe.ProviderName); ok {
+		p.(*entsoe.Provider).SetTokenSource(cfg.EntsoeToken)
+	}
+
+	// Check dependencies and report what works before accepting requests
+	report := selfcheck.Run(context.Background(), selfcheck.Default(cfg, db, replicas, providerManager.GetProviders()), cfg.Startup.CheckTimeout)
+	report.Log()
+	if failed := report.Failed(); len(failed) > 0 {
+		if cfg.Startup.Strict {
+			log.Fatalf("Refusing to start, %d critical startup checks failed", len(failed))
+		}
+		log.Printf("Starting despite %d failed critical startup checks, set STARTUP_STRICT=true to refuse", len(failed))
+	}
 
 	// Setup routes
-	router := routes.SetupRoutes(cfg, db, providerManager)
+	reloader := config.NewReloader(cfg, *configFile)
+	workers := worker.NewGroup()
+	if replicas != nil {
+		if err := workers.Go("replica checks", func(ctx context.Context) {
+			replicas.Run(ctx, cfg.Database.ReplicaCheckInterval)
+		}); err != nil {
+			log.Fatalf("Failed to start replica checks: %v", err)
+		}
+	}
+
+	// With several replicas only the elected leader runs scheduled jobs
+	if cfg.Leader.Election {
+		elector := leader.NewElector(db, int64(cfg.Leader.LockID))
+		providerManager.SetLeader(elector)
+		if err := workers.Go("leader election", elector.Run); err != nil {
+			log.Fatalf("Failed to start leader election: %v", err)
+		}
+	}
+
+	// Recurring jobs, such as fetching prices on the providers' schedules, run on the leader
+	jobScheduler := scheduler.New(postgres.NewJobRepository(db))
+	jobScheduler.SetLeader(providerManager)
+	if err := providerManager.Schedule(jobScheduler); err != nil {
+		log.Printf("Provider scheduler disabled: %v", err)
+	}
+
+	router := routes.SetupRoutes(cfg, db, replicas, providerManager, jobScheduler, hub, lookupCache, reloader, workers)
+
+	if err := workers.Go("job scheduler", jobScheduler.Run); err != nil {
+		log.Fatalf("Failed to start job scheduler: %v", err)
+	}
 
 	// Convert port string to int
 	port, err := strconv.Atoi(cfg.API.Port)
@@ -80,13 +217,41 @@ func main() {
 		Handler: router,
 	}
 
+	// Serve HTTPS directly when TLS is configured, on the TCP port, a Unix socket or a systemd socket
+	listener := server.NewListener(srv, cfg.API, cfg.TLS)
+
 	// Start server in goroutine
 	go func() {
-		log.Printf("Starting server on port %d", port)
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		log.Printf("Starting %s server on %s", listener.Scheme(), listener.Address())
+		if err := listener.Serve(); err != nil && err != http.ErrServerClosed {
 			log.Fatalf("Failed to start server: %v", err)
 		}
 	}()
+	if listener.HTTP != nil {
+		go func() {
+			log.Printf("Redirecting HTTP on %s to HTTPS", listener.HTTP.Addr)
+			if err := listener.HTTP.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Fatalf("Failed to start HTTP redirect server: %v", err)
+			}
+		}()
+	}
+
+	// Reload the runtime-safe subset of the configuration on SIGHUP
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			result, err := reloader.Reload()
+			if err != nil {
+				log.Printf("Configuration reload failed, keeping current settings: %v", err)
+				continue
+			}
+			log.Printf("Configuration reloaded, applied: %v", result.Applied)
+			if len(result.RestartRequired) > 0 {
+				log.Printf("Restart required for changed settings: %v", result.RestartRequired)
+			}
+		}
+	}()
 
 	// Wait for interrupt signal
 	quit := make(chan os.Signal, 1)
@@ -94,9 +259,25 @@ func main() {
 	<-quit
 	log.Println("Shutting down server...")
 
-	// Give outstanding requests 5 seconds to complete
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	// Stop scheduling jobs and let running jobs and ingestions finish first, then
+	// stop background workers, and only then close the HTTP server. All steps share
+	// the configured timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.API.ShutdownTimeout)
 	defer cancel()
+	if err := jobScheduler.Shutdown(ctx); err != nil {
+		log.Printf("Scheduled jobs interrupted: %v", err)
+	}
+	if err := providerManager.Shutdown(ctx); err != nil {
+		log.Printf("Provider jobs interrupted: %v", err)
+	}
+	if err := workers.Stop(ctx); err != nil {
+		log.Printf("Background workers interrupted: %v", err)
+	}
+	if listener.HTTP != nil {
+		if err := listener.HTTP.Shutdown(ctx); err != nil {
+			log.Printf("HTTP redirect server forced to shutdown: %v", err)
+		}
+	}
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Fatal("Server forced to shutdown:", err)
 	}
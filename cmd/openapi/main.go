@@ -0,0 +1,31 @@
+// Package main writes the OpenAPI 3 document of the API, which the client package is
+// generated from
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+	"wattwatch/internal/openapi"
+)
+
+func main() {
+	output := flag.String("o", "", "File to write the document to, standard output when empty")
+	flag.Parse()
+
+	spec, err := openapi.Spec()
+	if err != nil {
+		log.Fatal(err)
+	}
+	spec = append(spec, '\n')
+
+	if *output == "" {
+		if _, err := os.Stdout.Write(spec); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if err := os.WriteFile(*output, spec, 0o644); err != nil {
+		log.Fatalf("Failed to write %s: %v", *output, err)
+	}
+}
@@ -0,0 +1,372 @@
+// Package admin implements break-glass administration commands that work directly on the
+// repositories, for use when the API is unreachable or no administrator can log in
+package admin
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+// minPasswordLength matches the default minimum of the API password policy, break-glass
+// passwords are not held to the configured policy
+const minPasswordLength = 8
+
+// ErrUsage is returned when a command is called with missing or invalid arguments
+var ErrUsage = errors.New("invalid usage")
+
+// CLI runs admin subcommands
+type CLI struct {
+	userRepo         repository.UserRepository
+	roleRepo         repository.RoleRepository
+	passwordHistory  repository.PasswordHistoryRepository
+	loginAttemptRepo repository.LoginAttemptRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	auditRepo        repository.AuditLogRepository
+	authService      *auth.Service
+	out              io.Writer
+}
+
+// NewCLI creates a new CLI writing its output to out
+func NewCLI(
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	passwordHistory repository.PasswordHistoryRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	auditRepo repository.AuditLogRepository,
+	authService *auth.Service,
+	out io.Writer,
+) *CLI {
+	return &CLI{
+		userRepo:         userRepo,
+		roleRepo:         roleRepo,
+		passwordHistory:  passwordHistory,
+		loginAttemptRepo: loginAttemptRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		auditRepo:        auditRepo,
+		authService:      authService,
+		out:              out,
+	}
+}
+
+// Usage describes the available commands
+const Usage = `Usage: wattwatch admin <command> [flags]
+
+Commands:
+  create-user     Create a user, --admin gives it the admin role
+  reset-password  Set a new password, unlock the account and revoke its sessions
+  promote         Move a user to another role, admin by default
+  list-users      List all users
+
+Run "wattwatch admin <command> -h" for the flags of a command.
+`
+
+// Run executes the command named by the first argument
+func (c *CLI) Run(ctx context.Context, args []string) error {
+	if len(args) == 0 {
+		fmt.Fprint(c.out, Usage)
+		return ErrUsage
+	}
+
+	switch args[0] {
+	case "create-user":
+		return c.createUser(ctx, args[1:])
+	case "reset-password":
+		return c.resetPassword(ctx, args[1:])
+	case "promote":
+		return c.promote(ctx, args[1:])
+	case "list-users":
+		return c.listUsers(ctx, args[1:])
+	case "help", "-h", "--help":
+		fmt.Fprint(c.out, Usage)
+		return nil
+	default:
+		fmt.Fprint(c.out, Usage)
+		return fmt.Errorf("%w: unknown command %q", ErrUsage, args[0])
+	}
+}
+
+func (c *CLI) flagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(c.out)
+	return fs
+}
+
+// parse parses command flags, reporting -h as success
+func parse(fs *flag.FlagSet, args []string) (bool, error) {
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return false, nil
+		}
+		return false, fmt.Errorf("%w: %v", ErrUsage, err)
+	}
+	return true, nil
+}
+
+func (c *CLI) createUser(ctx context.Context, args []string) error {
+	fs := c.flagSet("create-user")
+	username := fs.String("username", "", "username (required)")
+	email := fs.String("email", "", "email address, marked as verified")
+	password := fs.String("password", "", "password, a random one is generated and printed when empty")
+	isAdmin := fs.Bool("admin", false, "give the user the admin role")
+	if ok, err := parse(fs, args); !ok {
+		return err
+	}
+
+	if len(*username) < 3 || len(*username) > 50 || strings.ContainsAny(*username, " \t") {
+		return fmt.Errorf("%w: --username must be 3 to 50 characters without spaces", ErrUsage)
+	}
+
+	existing, err := c.userRepo.GetByUsername(ctx, *username)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		return fmt.Errorf("failed to check username: %w", err)
+	}
+	if existing != nil {
+		return fmt.Errorf("username %q already exists", *username)
+	}
+
+	var emailPtr *string
+	if *email != "" {
+		existing, err = c.userRepo.GetByEmail(ctx, *email)
+		if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+			return fmt.Errorf("failed to check email: %w", err)
+		}
+		if existing != nil {
+			return fmt.Errorf("email %q already exists", *email)
+		}
+		emailPtr = email
+	}
+
+	plain, generated, err := passwordOrRandom(*password)
+	if err != nil {
+		return err
+	}
+	hashed, err := c.authService.HashPassword(plain)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	roleName := "user"
+	if *isAdmin {
+		roleName = "admin"
+	}
+	role, err := c.roleRepo.GetByName(ctx, roleName)
+	if err != nil {
+		return fmt.Errorf("failed to get role %s: %w", roleName, err)
+	}
+
+	user := &models.User{
+		Username: *username,
+		Password: hashed,
+		Email:    emailPtr,
+		RoleID:   role.ID,
+		Role:     role,
+		// Accounts created by an operator are trusted, so skip email verification
+		EmailVerified: emailPtr != nil,
+	}
+	if err := c.userRepo.Create(ctx, user); err != nil {
+		return fmt.Errorf("failed to create user: %w", err)
+	}
+	if err := c.passwordHistory.Add(ctx, user.ID, hashed); err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+
+	c.audit(ctx, models.AuditActionCreate, user, "User created via admin CLI", map[string]interface{}{
+		"username": user.Username,
+		"role":     role.Name,
+	})
+
+	fmt.Fprintf(c.out, "Created user %s (%s) with role %s\n", user.Username, user.ID, role.Name)
+	if generated {
+		fmt.Fprintf(c.out, "Password: %s\n", plain)
+	}
+	return nil
+}
+
+func (c *CLI) resetPassword(ctx context.Context, args []string) error {
+	fs := c.flagSet("reset-password")
+	username := fs.String("username", "", "username (required)")
+	password := fs.String("password", "", "new password, a random one is generated and printed when empty")
+	if ok, err := parse(fs, args); !ok {
+		return err
+	}
+
+	user, err := c.lookup(ctx, *username)
+	if err != nil {
+		return err
+	}
+
+	plain, generated, err := passwordOrRandom(*password)
+	if err != nil {
+		return err
+	}
+	hashed, err := c.authService.HashPassword(plain)
+	if err != nil {
+		return fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	if err := c.userRepo.UpdatePassword(ctx, user.ID, hashed); err != nil {
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	if err := c.passwordHistory.Add(ctx, user.ID, hashed); err != nil {
+		return fmt.Errorf("failed to record password history: %w", err)
+	}
+
+	// Unlock the account and sign out everywhere
+	if err := c.userRepo.ResetFailedAttempts(ctx, user.Username); err != nil {
+		return fmt.Errorf("failed to reset failed login attempts: %w", err)
+	}
+	if err := c.loginAttemptRepo.ClearAttempts(ctx, user.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+	if err := c.refreshTokenRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		return fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	c.audit(ctx, models.AuditActionUpdate, user, "Password reset via admin CLI", map[string]interface{}{
+		"username": user.Username,
+	})
+
+	fmt.Fprintf(c.out, "Reset password for %s, the account is unlocked and existing sessions were revoked\n", user.Username)
+	if generated {
+		fmt.Fprintf(c.out, "Password: %s\n", plain)
+	}
+	return nil
+}
+
+func (c *CLI) promote(ctx context.Context, args []string) error {
+	fs := c.flagSet("promote")
+	username := fs.String("username", "", "username (required)")
+	roleName := fs.String("role", "admin", "role to assign")
+	if ok, err := parse(fs, args); !ok {
+		return err
+	}
+
+	user, err := c.lookup(ctx, *username)
+	if err != nil {
+		return err
+	}
+
+	role, err := c.roleRepo.GetByName(ctx, *roleName)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("role %q not found", *roleName)
+		}
+		return fmt.Errorf("failed to get role: %w", err)
+	}
+
+	if user.RoleID == role.ID {
+		fmt.Fprintf(c.out, "%s already has role %s\n", user.Username, role.Name)
+		return nil
+	}
+
+	previous := ""
+	if user.Role != nil {
+		previous = user.Role.Name
+	}
+	user.RoleID = role.ID
+	user.Role = role
+	if err := c.userRepo.Update(ctx, user); err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	c.audit(ctx, models.AuditActionUpdate, user, "Role changed via admin CLI", map[string]interface{}{
+		"username":      user.Username,
+		"previous_role": previous,
+		"role":          role.Name,
+	})
+
+	fmt.Fprintf(c.out, "%s now has role %s\n", user.Username, role.Name)
+	return nil
+}
+
+func (c *CLI) listUsers(ctx context.Context, args []string) error {
+	fs := c.flagSet("list-users")
+	search := fs.String("search", "", "only show users whose username or email contains this text")
+	if ok, err := parse(fs, args); !ok {
+		return err
+	}
+
+	filter := repository.UserFilter{}
+	if *search != "" {
+		filter.Search = search
+	}
+	users, err := c.userRepo.List(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("failed to list users: %w", err)
+	}
+
+	w := tabwriter.NewWriter(c.out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tUSERNAME\tEMAIL\tROLE\tVERIFIED\tLAST LOGIN")
+	for _, u := range users {
+		email, role, lastLogin := "-", "-", "never"
+		if u.Email != nil {
+			email = *u.Email
+		}
+		if u.Role != nil {
+			role = u.Role.Name
+		}
+		if u.LastLoginAt != nil {
+			lastLogin = u.LastLoginAt.Format("2006-01-02 15:04")
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%t\t%s\n", u.ID, u.Username, email, role, u.EmailVerified, lastLogin)
+	}
+	return w.Flush()
+}
+
+// lookup finds a user by username, the error explains what went wrong
+func (c *CLI) lookup(ctx context.Context, username string) (*models.User, error) {
+	if username == "" {
+		return nil, fmt.Errorf("%w: --username is required", ErrUsage)
+	}
+	user, err := c.userRepo.GetByUsername(ctx, username)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			return nil, fmt.Errorf("user %q not found", username)
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+// audit records a CLI action. There is no acting user, so the entry has no user ID.
+func (c *CLI) audit(ctx context.Context, action models.AuditAction, user *models.User, description string, details map[string]interface{}) {
+	metadata, _ := json.Marshal(details)
+	if err := c.auditRepo.Create(ctx, &models.CreateAuditLogRequest{
+		Action:      action,
+		EntityType:  "user",
+		EntityID:    user.ID.String(),
+		Description: description,
+		Metadata:    string(metadata),
+		UserAgent:   "wattwatch-admin-cli",
+	}); err != nil {
+		fmt.Fprintf(c.out, "Warning: failed to write audit log: %v\n", err)
+	}
+}
+
+// passwordOrRandom validates the given password or generates one when it is empty
+func passwordOrRandom(password string) (string, bool, error) {
+	if password != "" {
+		if len(password) < minPasswordLength || len(password) > 72 {
+			return "", false, fmt.Errorf("%w: password must be %d to 72 characters", ErrUsage, minPasswordLength)
+		}
+		return password, false, nil
+	}
+
+	b := make([]byte, 18)
+	if _, err := rand.Read(b); err != nil {
+		return "", false, fmt.Errorf("failed to generate password: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), true, nil
+}
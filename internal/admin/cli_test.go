@@ -0,0 +1,100 @@
+package admin_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/admin"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newCLI(tc *testutil.TestContext) (*admin.CLI, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	return admin.NewCLI(
+		tc.UserRepo,
+		tc.RoleRepo,
+		tc.PasswordHistoryRepo,
+		tc.LoginAttemptRepo,
+		tc.RefreshTokenRepo,
+		tc.AuditRepo,
+		tc.AuthService,
+		out,
+	), out
+}
+
+func TestCLI_CreateUser(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	cli, out := newCLI(tc)
+	ctx := context.Background()
+
+	err := cli.Run(ctx, []string{"create-user", "--username", "rescue", "--email", "rescue@test.com", "--admin"})
+	require.NoError(t, err)
+	require.Contains(t, out.String(), "Password: ")
+
+	user, err := tc.UserRepo.GetByUsername(ctx, "rescue")
+	require.NoError(t, err)
+	require.True(t, user.IsAdmin())
+	require.True(t, user.EmailVerified)
+
+	err = cli.Run(ctx, []string{"create-user", "--username", "rescue", "--password", "password123"})
+	require.Error(t, err)
+
+	err = cli.Run(ctx, []string{"create-user", "--username", "short-pass", "--password", "short"})
+	require.ErrorIs(t, err, admin.ErrUsage)
+}
+
+func TestCLI_ResetPassword(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	user := tc.CreateTestUser("locked", "locked@test.com", "password123", true)
+	cli, _ := newCLI(tc)
+	ctx := context.Background()
+
+	for i := 0; i < 5; i++ {
+		require.NoError(t, tc.LoginAttemptRepo.Create(ctx, user.ID, false, "127.0.0.1", time.Now()))
+	}
+	_, err := tc.AuthService.GenerateRefreshToken(ctx, user.ID, models.SessionClient{})
+	require.NoError(t, err)
+
+	err = cli.Run(ctx, []string{"reset-password", "--username", "locked", "--password", "new-password123"})
+	require.NoError(t, err)
+
+	updated, err := tc.UserRepo.GetByUsername(ctx, "locked")
+	require.NoError(t, err)
+	require.NoError(t, tc.AuthService.ComparePasswords(updated.Password, "new-password123"))
+
+	attempts, err := tc.LoginAttemptRepo.GetRecentAttempts(ctx, user.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	require.Zero(t, attempts)
+
+	var sessions int
+	require.NoError(t, tc.DB.QueryRow("SELECT COUNT(*) FROM refresh_tokens WHERE user_id = $1", user.ID).Scan(&sessions))
+	require.Zero(t, sessions)
+
+	err = cli.Run(ctx, []string{"reset-password", "--username", "missing"})
+	require.Error(t, err)
+}
+
+func TestCLI_PromoteAndList(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	tc.CreateTestUser("regular", "regular@test.com", "password123", false)
+	cli, out := newCLI(tc)
+	ctx := context.Background()
+
+	require.NoError(t, cli.Run(ctx, []string{"promote", "--username", "regular"}))
+	user, err := tc.UserRepo.GetByUsername(ctx, "regular")
+	require.NoError(t, err)
+	require.True(t, user.IsAdmin())
+
+	require.Error(t, cli.Run(ctx, []string{"promote", "--username", "regular", "--role", "missing"}))
+
+	out.Reset()
+	require.NoError(t, cli.Run(ctx, []string{"list-users"}))
+	require.Contains(t, out.String(), "USERNAME")
+	require.Contains(t, out.String(), "regular@test.com")
+
+	require.ErrorIs(t, cli.Run(ctx, []string{"unknown"}), admin.ErrUsage)
+}
@@ -0,0 +1,266 @@
+// Package alert checks users' price alerts against spot prices as they are stored and
+// notifies the users whose thresholds are crossed
+package alert
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/notification"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// MaxPriceAge is how far before the current time a spot price may start and still trigger
+// alerts, so backfilled history doesn't notify anyone
+const MaxPriceAge = time.Hour
+
+// WindowLayout is the format of the times of day limiting an alert
+const WindowLayout = "15:04"
+
+// Notifier delivers a notification to all of a user's channels
+type Notifier interface {
+	Notify(ctx context.Context, userID uuid.UUID, msg *notification.Message) error
+}
+
+// EventPublisher passes triggered alerts on to the webhooks subscribed to them
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.WebhookEvent, data interface{}) error
+}
+
+// Evaluator matches new spot prices against the enabled price alerts of their zone and
+// currency. Repeated alerts are throttled by the notifier, keyed by alert ID.
+type Evaluator struct {
+	alerts     repository.PriceAlertRepository
+	zones      repository.ZoneRepository
+	currencies repository.CurrencyRepository
+	notifier   Notifier
+	webhooks   EventPublisher
+	now        func() time.Time
+}
+
+// NewEvaluator creates an evaluator sending the triggered alerts to notifier
+func NewEvaluator(
+	alerts repository.PriceAlertRepository,
+	zones repository.ZoneRepository,
+	currencies repository.CurrencyRepository,
+	notifier Notifier,
+) *Evaluator {
+	return &Evaluator{
+		alerts:     alerts,
+		zones:      zones,
+		currencies: currencies,
+		notifier:   notifier,
+		now:        time.Now,
+	}
+}
+
+// SetWebhooks publishes an alert.triggered event to publisher for every triggered alert
+func (e *Evaluator) SetWebhooks(publisher EventPublisher) {
+	e.webhooks = publisher
+}
+
+// Run evaluates the spot prices published to hub until ctx is cancelled. The evaluator
+// takes one of the hub's subscriber slots.
+func (e *Evaluator) Run(ctx context.Context, hub *pubsub.Hub) {
+	hub.Follow(ctx, "Price alerts", pubsub.Filter{}, func(ctx context.Context, spotPrices []models.SpotPrice) {
+		if err := e.Evaluate(ctx, spotPrices); err != nil {
+			log.Printf("Failed to evaluate price alerts: %v", err)
+		}
+	})
+}
+
+// seriesKey identifies the spot prices of one zone and currency
+type seriesKey struct {
+	zoneID     uuid.UUID
+	currencyID uuid.UUID
+}
+
+// Evaluate notifies the owners of the alerts the spot prices trigger. Each alert sends at
+// most one notification per call, covering all of its matching prices. Prices that started
+// more than MaxPriceAge ago are ignored.
+func (e *Evaluator) Evaluate(ctx context.Context, spotPrices []models.SpotPrice) error {
+	cutoff := e.now().Add(-MaxPriceAge)
+	series := make(map[seriesKey][]models.SpotPrice)
+	var keys []seriesKey
+	for _, sp := range spotPrices {
+		if sp.Timestamp.Before(cutoff) {
+			continue
+		}
+		key := seriesKey{sp.ZoneID, sp.CurrencyID}
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], sp)
+	}
+
+	var errs []error
+	for _, key := range keys {
+		if err := e.evaluateSeries(ctx, key, series[key]); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (e *Evaluator) evaluateSeries(ctx context.Context, key seriesKey, spotPrices []models.SpotPrice) error {
+	alerts, err := e.alerts.ListEnabled(ctx, key.zoneID, key.currencyID)
+	if err != nil {
+		return fmt.Errorf("failed to list price alerts: %w", err)
+	}
+	if len(alerts) == 0 {
+		return nil
+	}
+
+	zone, err := e.zones.GetByID(ctx, key.zoneID)
+	if err != nil {
+		return fmt.Errorf("failed to get zone %s: %w", key.zoneID, err)
+	}
+	currency, err := e.currencies.GetByID(ctx, key.currencyID)
+	if err != nil {
+		return fmt.Errorf("failed to get currency %s: %w", key.currencyID, err)
+	}
+	loc, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q of zone %s: %w", zone.Timezone, zone.Name, err)
+	}
+
+	slices.SortFunc(spotPrices, func(a, b models.SpotPrice) int { return a.Timestamp.Compare(b.Timestamp) })
+
+	var errs []error
+	for i := range alerts {
+		alert := &alerts[i]
+		matching, err := match(alert, spotPrices, loc)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("alert %s: %w", alert.ID, err))
+			continue
+		}
+		if len(matching) == 0 {
+			continue
+		}
+
+		msg := message(alert, matching, zone, currency, loc)
+		if err := e.notifier.Notify(ctx, alert.UserID, msg); err != nil {
+			errs = append(errs, fmt.Errorf("alert %s: %w", alert.ID, err))
+		}
+		if e.webhooks != nil {
+			if err := e.webhooks.Publish(ctx, models.WebhookEventAlertTriggered, event(alert, matching, zone, currency)); err != nil {
+				errs = append(errs, fmt.Errorf("failed to publish alert %s to webhooks: %w", alert.ID, err))
+			}
+		}
+		if err := e.alerts.MarkTriggered(ctx, alert.ID, e.now()); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("failed to mark alert %s as triggered: %w", alert.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// event is the webhook data of a triggered alert, with the prices that triggered it
+func event(alert *models.PriceAlert, matching []models.SpotPrice, zone *models.Zone, currency *models.Currency) map[string]interface{} {
+	prices := make([]map[string]interface{}, len(matching))
+	for i, sp := range matching {
+		prices[i] = map[string]interface{}{"timestamp": sp.Timestamp, "price": sp.Price}
+	}
+	return map[string]interface{}{
+		"alert_id":  alert.ID,
+		"user_id":   alert.UserID,
+		"zone":      zone.Name,
+		"currency":  currency.Name,
+		"direction": alert.Direction,
+		"threshold": alert.Threshold,
+		"prices":    prices,
+	}
+}
+
+// match returns the spot prices on the alert's side of its threshold that start within its window
+func match(alert *models.PriceAlert, spotPrices []models.SpotPrice, loc *time.Location) ([]models.SpotPrice, error) {
+	start, end, err := window(alert)
+	if err != nil {
+		return nil, err
+	}
+
+	var matching []models.SpotPrice
+	for _, sp := range spotPrices {
+		if !alert.Direction.Matches(sp.Price, alert.Threshold) {
+			continue
+		}
+		if start >= 0 && !inWindow(sp.Timestamp.In(loc), start, end) {
+			continue
+		}
+		matching = append(matching, sp)
+	}
+	return matching, nil
+}
+
+// window returns the alert's window in minutes after midnight, or -1 when it has none
+func window(alert *models.PriceAlert) (int, int, error) {
+	if alert.WindowStart == nil || alert.WindowEnd == nil {
+		return -1, -1, nil
+	}
+	start, err := time.Parse(WindowLayout, *alert.WindowStart)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window start %q", *alert.WindowStart)
+	}
+	end, err := time.Parse(WindowLayout, *alert.WindowEnd)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid window end %q", *alert.WindowEnd)
+	}
+	return start.Hour()*60 + start.Minute(), end.Hour()*60 + end.Minute(), nil
+}
+
+// inWindow reports whether t's time of day is in [start, end), wrapping past midnight when
+// end is before start
+func inWindow(t time.Time, start, end int) bool {
+	minute := t.Hour()*60 + t.Minute()
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+// message describes the matching prices, which are sorted by time, and the most extreme of them
+func message(alert *models.PriceAlert, matching []models.SpotPrice, zone *models.Zone, currency *models.Currency, loc *time.Location) *notification.Message {
+	extreme := matching[0]
+	for _, sp := range matching[1:] {
+		if alert.Direction.Matches(sp.Price, extreme.Price) {
+			extreme = sp
+		}
+	}
+	superlative := "highest"
+	if alert.Direction == models.PriceAlertBelow {
+		superlative = "lowest"
+	}
+
+	const layout = "2006-01-02 15:04"
+	body := fmt.Sprintf("The spot price in %s is %s %.2f %s", zone.Name, alert.Direction, alert.Threshold, currency.Name)
+	if len(matching) == 1 {
+		body += fmt.Sprintf(" at %s: %.2f %s.", extreme.Timestamp.In(loc).Format(layout), extreme.Price, currency.Name)
+	} else {
+		body += fmt.Sprintf(" for %d prices from %s to %s, the %s is %.2f %s at %s.",
+			len(matching),
+			matching[0].Timestamp.In(loc).Format(layout),
+			matching[len(matching)-1].Timestamp.In(loc).Format(layout),
+			superlative, extreme.Price, currency.Name,
+			extreme.Timestamp.In(loc).Format(layout))
+	}
+
+	return &notification.Message{
+		AlertType:   models.NotificationAlertPrice,
+		ThrottleKey: alert.ID.String(),
+		Title:       fmt.Sprintf("%s spot price %s %.2f %s", zone.Name, alert.Direction, alert.Threshold, currency.Name),
+		Body:        body,
+		Data: map[string]string{
+			"alert_id":  alert.ID.String(),
+			"zone":      zone.Name,
+			"currency":  currency.Name,
+			"price":     fmt.Sprintf("%.2f", extreme.Price),
+			"timestamp": extreme.Timestamp.UTC().Format(time.RFC3339),
+		},
+	}
+}
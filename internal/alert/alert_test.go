@@ -0,0 +1,124 @@
+package alert
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/notification"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type notifyRecorder struct {
+	sent map[uuid.UUID][]*notification.Message
+}
+
+func (r *notifyRecorder) Notify(ctx context.Context, userID uuid.UUID, msg *notification.Message) error {
+	r.sent[userID] = append(r.sent[userID], msg)
+	return nil
+}
+
+type publishRecorder struct {
+	events []map[string]interface{}
+}
+
+func (r *publishRecorder) Publish(ctx context.Context, event models.WebhookEvent, data interface{}) error {
+	r.events = append(r.events, data.(map[string]interface{}))
+	return nil
+}
+
+func TestEvaluator_Evaluate(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	roles := memory.NewRoleRepository(store)
+	zones := memory.NewZoneRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+	alerts := memory.NewPriceAlertRepository(store)
+
+	role, err := roles.GetByName(ctx, "user")
+	require.NoError(t, err)
+	alice := &models.User{Username: "alice", Password: "hash", RoleID: role.ID}
+	require.NoError(t, users.Create(ctx, alice))
+	bob := &models.User{Username: "bob", Password: "hash", RoleID: role.ID}
+	require.NoError(t, users.Create(ctx, bob))
+	se3, err := zones.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	se4, err := zones.GetByName(ctx, "SE4")
+	require.NoError(t, err)
+	eur, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	evening, late := "17:00", "20:00"
+	expensive := &models.PriceAlert{UserID: alice.ID, ZoneID: se3.ID, CurrencyID: eur.ID, Direction: models.PriceAlertAbove,
+		Threshold: 100, WindowStart: &evening, WindowEnd: &late, Enabled: true}
+	negative := &models.PriceAlert{UserID: bob.ID, ZoneID: se3.ID, CurrencyID: eur.ID, Direction: models.PriceAlertBelow,
+		Threshold: 0, Enabled: true}
+	disabled := &models.PriceAlert{UserID: bob.ID, ZoneID: se3.ID, CurrencyID: eur.ID, Direction: models.PriceAlertAbove,
+		Threshold: 100, Enabled: false}
+	otherZone := &models.PriceAlert{UserID: bob.ID, ZoneID: se4.ID, CurrencyID: eur.ID, Direction: models.PriceAlertAbove,
+		Threshold: 100, Enabled: true}
+	for _, a := range []*models.PriceAlert{expensive, negative, disabled, otherZone} {
+		require.NoError(t, alerts.Create(ctx, a))
+	}
+
+	notifier := &notifyRecorder{sent: make(map[uuid.UUID][]*notification.Message)}
+	evaluator := NewEvaluator(alerts, zones, currencies, notifier)
+	publisher := &publishRecorder{}
+	evaluator.SetWebhooks(publisher)
+	now := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	evaluator.now = func() time.Time { return now }
+
+	// Stockholm is an hour ahead of UTC in January
+	price := func(hour int, value float64) models.SpotPrice {
+		return models.SpotPrice{Timestamp: time.Date(2025, 1, 15, hour, 0, 0, 0, time.UTC), ZoneID: se3.ID, CurrencyID: eur.ID, Price: value}
+	}
+	require.NoError(t, evaluator.Evaluate(ctx, []models.SpotPrice{
+		price(17, 210),
+		price(16, 150),
+		price(20, 300), // 21:00, after the window
+		price(26, -5),  // 03:00 the next day
+		{Timestamp: now.AddDate(0, 0, -1), ZoneID: se3.ID, CurrencyID: eur.ID, Price: -50}, // backfilled
+	}))
+
+	require.Len(t, notifier.sent[alice.ID], 1)
+	msg := notifier.sent[alice.ID][0]
+	assert.Equal(t, models.NotificationAlertPrice, msg.AlertType)
+	assert.Equal(t, expensive.ID.String(), msg.ThrottleKey)
+	assert.Equal(t, "SE3 spot price above 100.00 EUR", msg.Title)
+	assert.Equal(t, "The spot price in SE3 is above 100.00 EUR for 2 prices from 2025-01-15 17:00 to 2025-01-15 18:00, the highest is 210.00 EUR at 2025-01-15 18:00.", msg.Body)
+
+	require.Len(t, notifier.sent[bob.ID], 1)
+	assert.Equal(t, negative.ID.String(), notifier.sent[bob.ID][0].ThrottleKey)
+	assert.Equal(t, "-5.00", notifier.sent[bob.ID][0].Data["price"])
+
+	require.Len(t, publisher.events, 2)
+	assert.Equal(t, expensive.ID, publisher.events[0]["alert_id"])
+	assert.Equal(t, "SE3", publisher.events[0]["zone"])
+	assert.Len(t, publisher.events[0]["prices"], 2)
+
+	got, err := alerts.GetByID(ctx, expensive.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastTriggeredAt)
+	assert.True(t, got.LastTriggeredAt.Equal(now))
+	got, err = alerts.GetByID(ctx, disabled.ID)
+	require.NoError(t, err)
+	assert.Nil(t, got.LastTriggeredAt)
+}
+
+func TestInWindow(t *testing.T) {
+	at := func(hour, minute int) time.Time { return time.Date(2025, 1, 1, hour, minute, 0, 0, time.UTC) }
+
+	assert.True(t, inWindow(at(6, 0), 6*60, 22*60))
+	assert.False(t, inWindow(at(22, 0), 6*60, 22*60))
+	assert.False(t, inWindow(at(5, 59), 6*60, 22*60))
+
+	// Windows ending before they start wrap past midnight
+	assert.True(t, inWindow(at(23, 0), 22*60, 6*60))
+	assert.True(t, inWindow(at(2, 0), 22*60, 6*60))
+	assert.False(t, inWindow(at(12, 0), 22*60, 6*60))
+}
@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// AuditLogHandler lets administrators search the audit log
+type AuditLogHandler struct {
+	auditRepo repository.AuditLogRepository
+	limits    ListLimits
+}
+
+// NewAuditLogHandler creates a new AuditLogHandler
+func NewAuditLogHandler(auditRepo repository.AuditLogRepository) *AuditLogHandler {
+	return &AuditLogHandler{
+		auditRepo: auditRepo,
+		limits:    DefaultListLimits,
+	}
+}
+
+// SetListLimits sets the default and maximum number of audit log entries listed
+func (h *AuditLogHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// ListAuditLogs godoc
+// @Summary List audit log entries
+// @Description Searches the audit log, newest first unless another order is requested. Parameters taking several values accept them comma separated or repeated. (admin only)
+// @Tags audit-logs
+// @Produce json
+// @Security BearerAuth
+// @Param user_id query string false "Filter by the user who performed the action"
+// @Param impersonator_id query string false "Filter by the admin who performed the action while impersonating the user"
+// @Param action query string false "Filter by actions, e.g. create,delete"
+// @Param entity_type query string false "Filter by entity types, e.g. user,zone"
+// @Param entity_id query string false "Filter by entity IDs"
+// @Param ip_address query string false "Filter by IP address"
+// @Param created_after query string false "Only entries created after this time (RFC3339)"
+// @Param created_before query string false "Only entries created before this time (RFC3339)"
+// @Param search query string false "Search in description and metadata"
+// @Param order_by query string false "Field to order by (created_at, action, entity_type)"
+// @Param order_desc query bool false "Order descending (default true when ordering by created_at)"
+// @Param limit query integer false "Limit results (default 50, maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the entries in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.AuditLog]
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /audit-logs [get]
+func (h *AuditLogHandler) ListAuditLogs(c *gin.Context) {
+	filter, err := h.parseFilter(c)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	logs, err := h.auditRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error listing audit logs: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list audit logs")
+		return
+	}
+	if logs == nil {
+		logs = []models.AuditLog{}
+	}
+
+	respondPage(c, logs, filter.Limit, filter.Offset, func() (int, error) {
+		return h.auditRepo.Total(c.Request.Context(), filter)
+	}, "failed to list audit logs")
+}
+
+// parseFilter reads the audit log filter from the query parameters
+func (h *AuditLogHandler) parseFilter(c *gin.Context) (repository.AuditLogFilter, error) {
+	var filter repository.AuditLogFilter
+
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			return filter, errors.New("invalid user_id")
+		}
+		filter.UserID = &id
+	}
+	if impersonatorID := c.Query("impersonator_id"); impersonatorID != "" {
+		id, err := uuid.Parse(impersonatorID)
+		if err != nil {
+			return filter, errors.New("invalid impersonator_id")
+		}
+		filter.ImpersonatorID = &id
+	}
+
+	for _, action := range queryList(c, "action") {
+		filter.Actions = append(filter.Actions, models.AuditAction(action))
+	}
+	filter.EntityTypes = queryList(c, "entity_type")
+	filter.EntityIDs = queryList(c, "entity_id")
+
+	if ip := c.Query("ip_address"); ip != "" {
+		filter.IPAddress = &ip
+	}
+
+	for _, param := range []struct {
+		name   string
+		target **time.Time
+	}{
+		{"created_after", &filter.CreatedAfter},
+		{"created_before", &filter.CreatedBefore},
+	} {
+		if value := c.Query(param.name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, errors.New("invalid " + param.name + ", expected RFC3339")
+			}
+			*param.target = &t
+		}
+	}
+
+	if search := c.Query("search"); search != "" {
+		filter.SearchTerm = &search
+	}
+
+	filter.OrderBy = c.DefaultQuery("order_by", "created_at")
+	if !slices.Contains(repository.AuditLogOrderFields, filter.OrderBy) {
+		return filter, errors.New("invalid order_by, must be one of " + strings.Join(repository.AuditLogOrderFields, ", "))
+	}
+	filter.OrderDesc = filter.OrderBy == "created_at"
+	if orderDesc := c.Query("order_desc"); orderDesc != "" {
+		desc, err := strconv.ParseBool(orderDesc)
+		if err != nil {
+			return filter, errors.New("invalid order_desc")
+		}
+		filter.OrderDesc = desc
+	}
+
+	limit, err := h.limits.limit(c, h.limits.Default)
+	if err != nil {
+		return filter, err
+	}
+	filter.Limit = &limit
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			return filter, errors.New("invalid offset")
+		}
+		filter.Offset = &offset
+	}
+
+	return filter, nil
+}
+
+// queryList returns the values of a query parameter given repeatedly, comma separated or both
+func queryList(c *gin.Context, name string) []string {
+	var values []string
+	for _, param := range c.QueryArray(name) {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
+}
+
+// GetAuditLog godoc
+// @Summary Get an audit log entry
+// @Description Returns a single audit log entry (admin only)
+// @Tags audit-logs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Audit log entry ID (UUID)"
+// @Success 200 {object} models.AuditLog
+// @Failure 400 {object} apierror.Problem "Invalid ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Entry not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /audit-logs/{id} [get]
+func (h *AuditLogHandler) GetAuditLog(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid audit log ID")
+		return
+	}
+
+	entry, err := h.auditRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.AuditLogNotFound, "audit log entry not found")
+			return
+		}
+		log.Printf("Error getting audit log %s: %v", id, err)
+		apierror.Write(c, apierror.Internal, "failed to get audit log entry")
+		return
+	}
+
+	c.JSON(http.StatusOK, entry)
+}
@@ -0,0 +1,117 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLogHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+	ctx := context.Background()
+
+	for _, entry := range []models.CreateAuditLogRequest{
+		{UserID: &admin.ID, Action: models.AuditActionCreate, EntityType: "zone", EntityID: "SE3", Description: "Zone created", IPAddress: "10.0.0.1"},
+		{UserID: &admin.ID, Action: models.AuditActionDelete, EntityType: "zone", EntityID: "SE4", Description: "Zone deleted", IPAddress: "10.0.0.1"},
+		{UserID: &user.ID, Action: models.AuditActionUpdate, EntityType: "user", EntityID: user.ID.String(), Description: "Password changed", Metadata: `{"reason":"expired"}`, IPAddress: "10.0.0.2"},
+	} {
+		require.NoError(t, tc.AuditRepo.Create(ctx, &entry))
+	}
+
+	handler := handlers.NewAuditLogHandler(tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	router.GET("/audit-logs", handler.ListAuditLogs)
+	router.GET("/audit-logs/:id", handler.GetAuditLog)
+
+	get := func(userID uuid.UUID, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	list := func(query string) models.Page[models.AuditLog] {
+		w := get(admin.ID, "/audit-logs"+query)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page models.Page[models.AuditLog]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+	descriptions := func(page models.Page[models.AuditLog]) []string {
+		var result []string
+		for _, entry := range page.Items {
+			result = append(result, entry.Description)
+		}
+		return result
+	}
+
+	t.Run("Filters", func(t *testing.T) {
+		assert.Equal(t, 3, list("").Total)
+		assert.ElementsMatch(t, []string{"Zone created", "Zone deleted"}, descriptions(list("?entity_type=zone")))
+		assert.ElementsMatch(t, []string{"Zone created", "Password changed"}, descriptions(list("?action=create,update")))
+		assert.ElementsMatch(t, []string{"Zone created", "Password changed"}, descriptions(list("?action=create&action=update")))
+		assert.Equal(t, []string{"Zone deleted"}, descriptions(list("?entity_id=SE4")))
+		assert.Equal(t, []string{"Password changed"}, descriptions(list("?user_id="+user.ID.String())))
+		assert.Equal(t, []string{"Password changed"}, descriptions(list("?ip_address=10.0.0.2")))
+		assert.Equal(t, []string{"Password changed"}, descriptions(list("?search=EXPIRED")))
+		assert.Empty(t, list("?created_after=2999-01-01T00:00:00Z").Items)
+		assert.Equal(t, 3, list("?created_before=2999-01-01T00:00:00Z").Total)
+	})
+
+	t.Run("Order And Pagination", func(t *testing.T) {
+		assert.Equal(t, []string{"Zone created", "Zone deleted", "Password changed"}, descriptions(list("?order_by=action")))
+		assert.Equal(t, []string{"Password changed", "Zone deleted", "Zone created"}, descriptions(list("?order_by=action&order_desc=true")))
+
+		page := list("?order_by=action&limit=1&offset=1")
+		assert.Equal(t, []string{"Zone deleted"}, descriptions(page))
+		assert.Equal(t, 3, page.Total)
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		for _, query := range []string{
+			"?user_id=nope",
+			"?created_after=yesterday",
+			"?order_by=description",
+			"?order_desc=maybe",
+			"?limit=0",
+			"?offset=-1",
+		} {
+			assert.Equal(t, http.StatusBadRequest, get(admin.ID, "/audit-logs"+query).Code, query)
+		}
+	})
+
+	t.Run("Get Entry", func(t *testing.T) {
+		logs, err := tc.AuditRepo.List(ctx, repository.AuditLogFilter{EntityIDs: []string{"SE3"}})
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+
+		w := get(admin.ID, "/audit-logs/"+logs[0].ID.String())
+		require.Equal(t, http.StatusOK, w.Code)
+		var entry models.AuditLog
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &entry))
+		assert.Equal(t, "Zone created", entry.Description)
+
+		assert.Equal(t, http.StatusNotFound, get(admin.ID, "/audit-logs/"+uuid.New().String()).Code)
+		assert.Equal(t, http.StatusBadRequest, get(admin.ID, "/audit-logs/nope").Code)
+	})
+
+	t.Run("Non Admin", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, get(user.ID, "/audit-logs").Code)
+	})
+}
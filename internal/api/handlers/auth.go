@@ -1,18 +1,25 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"time"
+	"wattwatch/internal/apierror"
 	"wattwatch/internal/auth"
+	"wattwatch/internal/captcha"
 	"wattwatch/internal/config"
 	"wattwatch/internal/email"
+	"wattwatch/internal/metrics"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
+	"wattwatch/internal/settings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 // AuthHandler handles HTTP requests for authentication and user management
@@ -26,6 +33,17 @@ type AuthHandler struct {
 	loginAttemptRepo  repository.LoginAttemptRepository
 	emailVerifyRepo   repository.EmailVerificationRepository
 	passwordResetRepo repository.PasswordResetRepository
+	settings          *settings.Store
+	webhooks          EventPublisher
+	twoFactorRepo     repository.TwoFactorRepository
+	txManager         repository.TxManager
+	securityEvents    repository.SecurityEventRepository
+	captcha           captcha.Verifier
+}
+
+// EventPublisher passes events on to the webhooks subscribed to them
+type EventPublisher interface {
+	Publish(ctx context.Context, event models.WebhookEvent, data interface{}) error
 }
 
 // NewAuthHandler creates a new authentication handler with the given dependencies
@@ -39,6 +57,7 @@ func NewAuthHandler(
 	loginAttemptRepo repository.LoginAttemptRepository,
 	emailVerifyRepo repository.EmailVerificationRepository,
 	passwordResetRepo repository.PasswordResetRepository,
+	settings *settings.Store,
 ) *AuthHandler {
 	return &AuthHandler{
 		userRepo:          userRepo,
@@ -50,9 +69,33 @@ func NewAuthHandler(
 		loginAttemptRepo:  loginAttemptRepo,
 		emailVerifyRepo:   emailVerifyRepo,
 		passwordResetRepo: passwordResetRepo,
+		settings:          settings,
 	}
 }
 
+// SetWebhooks publishes a user.registered event to publisher for every registration
+func (h *AuthHandler) SetWebhooks(publisher EventPublisher) {
+	h.webhooks = publisher
+}
+
+// SetSecurityEvents records logins from new devices and IP addresses in repo, and emails
+// users about logins from new devices
+func (h *AuthHandler) SetSecurityEvents(repo repository.SecurityEventRepository) {
+	h.securityEvents = repo
+}
+
+// SetCaptcha requires CAPTCHAs verified by verifier to register, request a password reset
+// and log in after the failed logins configured in captcha.login_failures
+func (h *AuthHandler) SetCaptcha(verifier captcha.Verifier) {
+	h.captcha = verifier
+}
+
+// SetTxManager makes registrations atomic, so a user isn't created without its email
+// verification and audit log
+func (h *AuthHandler) SetTxManager(txManager repository.TxManager) {
+	h.txManager = txManager
+}
+
 // LoginRequest represents the login credentials
 type LoginRequest struct {
 	Username string `json:"username" binding:"required,max=50" example:"johndoe"`
@@ -67,98 +110,163 @@ type LoginResponse struct {
 
 // Login godoc
 // @Summary User login
-// @Description Authenticate user and return access and refresh tokens
+// @Description Authenticate user and return access and refresh tokens. Users with two-factor authentication get a models.TwoFactorChallenge instead, whose token is exchanged with a code at /auth/login/2fa.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body models.LoginRequest true "Login credentials"
-// @Success 200 {object} models.LoginResponse "Login successful"
-// @Failure 400 {object} models.ErrorResponse "Invalid request format"
-// @Failure 401 {object} models.ErrorResponse "Invalid credentials"
-// @Failure 403 {object} models.ErrorResponse "Account locked or email not verified"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Success 200 {object} models.LoginResponse "Login successful, or models.TwoFactorChallenge when a code is required"
+// @Failure 400 {object} apierror.Problem "Invalid request format"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Invalid credentials"
+// @Failure 400 {object} apierror.Problem "CAPTCHA missing or rejected, required after repeated failed logins when enabled"
+// @Failure 403 {object} apierror.Problem "Account locked or email not verified"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Failure 502 {object} apierror.Problem "CAPTCHA provider failed"
 // @Router /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	ipAddress := c.ClientIP()
 
 	var req models.LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	// Validate username length
-	if len(req.Username) > 50 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Key: 'LoginRequest.Username' Error:Field validation for 'Username' failed on the 'max' tag"})
+	// Unknown and inactive users take the same path as a wrong password, comparing a
+	// password as long and counting towards the lockout of the username, so usernames can't
+	// be enumerated
+	user, err := h.userRepo.GetByUsername(c.Request.Context(), req.Username)
+	if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+		apierror.Write(c, apierror.Internal, "failed to process login")
 		return
 	}
+	if user != nil && user.DeletedAt != nil {
+		user = nil
+	}
 
-	// Get user first to check if exists and is active
-	user, err := h.userRepo.GetByUsername(c.Request.Context(), req.Username)
+	// Compare the password before checking for a lockout, so every path compares once
+	var passwordErr error
+	if user != nil {
+		passwordErr = h.authService.ComparePasswords(user.Password, req.Password)
+	} else {
+		_ = h.authService.CompareDummyPassword(req.Password)
+	}
+
+	// Check for too many recent failed attempts
+	threshold := h.settings.LockoutThreshold()
+	cutoff := time.Now().Add(-h.settings.LockoutWindow())
+	recentAttempts, err := h.loginAttemptRepo.GetRecentFailures(c.Request.Context(), req.Username, cutoff)
 	if err != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "invalid credentials"})
+		apierror.Write(c, apierror.Internal, "failed to process login")
 		return
 	}
 
-	// Check if account is active before anything else
-	if user.DeletedAt != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "account is inactive"})
+	if threshold > 0 && recentAttempts >= threshold {
+		metrics.LoginAttempt(metrics.LoginLocked)
+		apierror.Write(c, apierror.TooManyAttempts, "too many failed login attempts")
 		return
 	}
 
-	// Check for too many recent failed attempts
-	cutoff := time.Now().Add(-15 * time.Minute)
-	recentAttempts, err := h.loginAttemptRepo.GetRecentAttempts(c.Request.Context(), user.ID, cutoff)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process login"})
+	// Usernames failing to log in repeatedly need a CAPTCHA, which isn't counted as an attempt
+	if recentAttempts >= h.config.Captcha.LoginFailures && !h.verifyCaptcha(c, req.CaptchaToken) {
 		return
 	}
 
-	if recentAttempts >= repository.MaxLoginAttempts {
-		c.JSON(http.StatusTooManyRequests, models.ErrorResponse{Error: "too many failed login attempts"})
+	if user == nil || passwordErr != nil {
+		// Record failed attempt
+		if err := h.loginAttemptRepo.CreateFailure(c.Request.Context(), req.Username, ipAddress, time.Now()); err != nil {
+			apierror.Write(c, apierror.Internal, "failed to process login")
+			return
+		}
+		if user != nil {
+			if err := h.userRepo.IncrementFailedAttempts(c.Request.Context(), req.Username); err != nil {
+				apierror.Write(c, apierror.Internal, "failed to process login")
+				return
+			}
+		}
+		metrics.LoginAttempt(metrics.LoginFailure)
+		apierror.Write(c, apierror.InvalidCredentials, "invalid credentials")
 		return
 	}
 
-	// Verify password before recording attempt
-	if err := h.authService.ComparePasswords(user.Password, req.Password); err != nil {
-		// Record failed attempt
-		if err := h.loginAttemptRepo.Create(c.Request.Context(), user.ID, false, ipAddress, time.Now()); err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process login"})
+	// Users with two-factor authentication finish logging in with a code at /auth/login/2fa
+	if h.twoFactorRepo != nil {
+		twoFactor, err := h.twoFactorRepo.GetByUserID(c.Request.Context(), user.ID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.Internal, "failed to process login")
 			return
 		}
-		if err := h.userRepo.IncrementFailedAttempts(c.Request.Context(), req.Username); err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process login"})
+		if twoFactor != nil && twoFactor.Enabled() {
+			token, expiresAt, err := h.authService.GeneratePreAuthToken(user)
+			if err != nil {
+				apierror.Write(c, apierror.Internal, "failed to process login")
+				return
+			}
+			c.JSON(http.StatusOK, models.TwoFactorChallenge{TwoFactorRequired: true, Token: token, ExpiresAt: expiresAt})
 			return
 		}
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "invalid credentials"})
+	}
+
+	h.completeLogin(c, user, ipAddress)
+}
+
+// completeLogin records a successful login of the user and responds with new access and
+// refresh tokens
+func (h *AuthHandler) completeLogin(c *gin.Context, user *models.User, ipAddress string) {
+	// Compare the device with the earlier logins before recording this one
+	client := models.SessionClient{UserAgent: c.GetHeader("User-Agent"), IPAddress: ipAddress}
+	fingerprint := models.DeviceFingerprint(client.UserAgent)
+	history, err := h.loginAttemptRepo.LoginHistory(c.Request.Context(), user.ID, fingerprint, ipAddress)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process login")
 		return
 	}
 
 	// Record successful attempt
-	if err := h.loginAttemptRepo.Create(c.Request.Context(), user.ID, true, ipAddress, time.Now()); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process login"})
+	if err := h.loginAttemptRepo.CreateSuccess(c.Request.Context(), user.ID, client, fingerprint, time.Now()); err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process login")
 		return
 	}
 
 	// Reset failed attempts on successful login
-	if err := h.userRepo.ResetFailedAttempts(c.Request.Context(), req.Username); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process login"})
+	if err := h.userRepo.ResetFailedAttempts(c.Request.Context(), user.Username); err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process login")
 		return
 	}
 
-	// Clear login attempts
-	if err := h.loginAttemptRepo.ClearAttempts(c.Request.Context(), user.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process login"})
+	// Clear failed login attempts
+	if err := h.loginAttemptRepo.ClearAttempts(c.Request.Context(), user.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.Internal, "failed to process login")
 		return
 	}
 
 	// Update last login
 	if err := h.userRepo.UpdateLastLogin(c.Request.Context(), user.ID, time.Now()); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to update login time"})
+		apierror.Write(c, apierror.Internal, "failed to update login time")
 		return
 	}
 
+	// Logging in within the grace period keeps the account the user deleted
+	if user.DeletionScheduledAt != nil {
+		if err := h.userRepo.CancelDeletion(c.Request.Context(), user.ID); err != nil {
+			apierror.Write(c, apierror.Internal, "failed to process login")
+			return
+		}
+		if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+			UserID:      &user.ID,
+			Action:      models.AuditActionCancelDeletion,
+			EntityType:  "user",
+			EntityID:    user.ID.String(),
+			Description: fmt.Sprintf("Deletion of user %s cancelled by logging in", user.Username),
+			IPAddress:   ipAddress,
+			UserAgent:   c.GetHeader("User-Agent"),
+		}); err != nil {
+			log.Printf("Failed to create audit log: %v", err)
+		}
+		user.DeletionScheduledAt = nil
+	}
+
 	// Create audit log entry for successful login
 	details, _ := json.Marshal(map[string]interface{}{"username": user.Username})
 	auditLog := &models.CreateAuditLogRequest{
@@ -176,9 +284,11 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		log.Printf("Failed to create audit log: %v", err)
 	}
 
+	h.reportUnusualLogin(c, user, client, history)
+
 	role, err := h.roleRepo.GetByID(c.Request.Context(), user.RoleID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get user role"})
+		apierror.Write(c, apierror.Internal, "failed to get user role")
 		return
 	}
 	user.Role = role
@@ -186,17 +296,18 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	// Generate access token
 	accessToken, err := h.authService.GenerateToken(user, false)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to generate access token"})
+		apierror.Write(c, apierror.Internal, "failed to generate access token")
 		return
 	}
 
 	// Generate refresh token
-	refreshToken, err := h.authService.GenerateRefreshToken(c.Request.Context(), user.ID)
+	refreshToken, err := h.authService.GenerateRefreshToken(c.Request.Context(), user.ID, sessionClient(c))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to generate refresh token"})
+		apierror.Write(c, apierror.Internal, "failed to generate refresh token")
 		return
 	}
 
+	metrics.LoginAttempt(metrics.LoginSuccess)
 	c.JSON(http.StatusOK, LoginResponse{
 		AccessToken:  accessToken,
 		RefreshToken: refreshToken,
@@ -211,26 +322,32 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Produce json
 // @Param request body models.CreateUserRequest true "User registration details"
 // @Success 201 {object} models.User "User created successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid request format, username/email already exists, or validation error"
-// @Failure 403 {object} models.ErrorResponse "Registration is disabled (unless admin or first user)"
-// @Failure 409 {object} models.ErrorResponse "Username or email already exists"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Failed to create user or process request"
+// @Failure 400 {object} apierror.Problem "Invalid request format, username/email already exists, or validation error"
+// @Failure 400 {object} apierror.Problem "Password does not meet the password policy"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 400 {object} apierror.Problem "CAPTCHA missing or rejected, unless admin or CAPTCHAs are disabled"
+// @Failure 403 {object} apierror.Problem "Registration is disabled (unless admin or first user)"
+// @Failure 409 {object} apierror.Problem "Username or email already exists"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Failed to create user or process request"
+// @Failure 502 {object} apierror.Problem "CAPTCHA provider failed"
 // @Router /auth/register [post]
 func (h *AuthHandler) Register(c *gin.Context) {
 	var req models.CreateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// Get user from context if authenticated
 	isAdmin := c.GetBool("is_admin")
+	if !isAdmin && !h.verifyCaptcha(c, req.CaptchaToken) {
+		return
+	}
 
 	// Get existing users count
-	users, err := h.userRepo.List(c.Request.Context(), repository.UserFilter{})
+	userCount, err := h.userRepo.Count(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to check existing users"})
+		apierror.Write(c, apierror.Internal, "failed to check existing users")
 		return
 	}
 
@@ -238,20 +355,20 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	// 1. No users exist (first user)
 	// 2. Registration is open
 	// 3. User is an admin
-	isFirstUser := len(users) == 0
-	if !isFirstUser && !isAdmin && !h.config.Auth.RegistrationOpen {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "registration is disabled"})
+	isFirstUser := userCount == 0
+	if !isFirstUser && !isAdmin && !h.settings.RegistrationOpen() {
+		apierror.Write(c, apierror.RegistrationDisabled, "registration is disabled")
 		return
 	}
 
 	// Check if username exists
 	existingUser, err := h.userRepo.GetByUsername(c.Request.Context(), req.Username)
 	if err != nil && err != repository.ErrUserNotFound {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to check username"})
+		apierror.Write(c, apierror.Internal, "failed to check username")
 		return
 	}
 	if existingUser != nil {
-		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "username already exists"})
+		apierror.Write(c, apierror.UserExists, "username already exists")
 		return
 	}
 
@@ -259,19 +376,23 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	if req.Email != nil {
 		existingUser, err = h.userRepo.GetByEmail(c.Request.Context(), *req.Email)
 		if err != nil && err != repository.ErrUserNotFound {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to check email"})
+			apierror.Write(c, apierror.Internal, "failed to check email")
 			return
 		}
 		if existingUser != nil {
-			c.JSON(http.StatusConflict, models.ErrorResponse{Error: "email already exists"})
+			apierror.Write(c, apierror.UserExists, "email already exists")
 			return
 		}
 	}
 
+	if !checkPassword(c, h.config, req.Password, req.Username, req.Email) {
+		return
+	}
+
 	// Hash password
 	hashedPassword, err := h.authService.HashPassword(req.Password)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process registration"})
+		apierror.Write(c, apierror.Internal, "failed to process registration")
 		return
 	}
 
@@ -280,10 +401,10 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	if isFirstUser {
 		role, err = h.roleRepo.GetByName(c.Request.Context(), "admin")
 	} else {
-		role, err = h.roleRepo.GetByName(c.Request.Context(), "user")
+		role, err = h.roleRepo.GetByName(c.Request.Context(), h.settings.DefaultRole())
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get role"})
+		apierror.Write(c, apierror.Internal, "failed to get role")
 		return
 	}
 
@@ -296,44 +417,75 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		Role:          role,
 		EmailVerified: false,
 	}
+	if req.Language != nil {
+		user.Language = *req.Language
+	}
+
+	// The user, its email verification and the audit log are created together
+	var verification *repository.EmailVerification
+	err = withinTx(c.Request.Context(), h.txManager, func(ctx context.Context) error {
+		if err := h.userRepo.Create(ctx, user); err != nil {
+			return fmt.Errorf("failed to create user: %w", err)
+		}
 
-	if err := h.userRepo.Create(c.Request.Context(), user); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to create user"})
+		if req.Email != nil {
+			var err error
+			verification, err = h.emailVerifyRepo.Create(ctx, user.ID, h.config.EmailSettings().VerificationTTL)
+			if err != nil {
+				return fmt.Errorf("failed to create email verification: %w", err)
+			}
+		}
+
+		details, _ := json.Marshal(map[string]interface{}{
+			"username": user.Username,
+			"role":     role.Name,
+		})
+		auditLog := &models.CreateAuditLogRequest{
+			UserID:      &user.ID,
+			Action:      "user_registered",
+			EntityType:  "user",
+			EntityID:    user.ID.String(),
+			Description: fmt.Sprintf("User %s registered successfully", user.Username),
+			Metadata:    string(details),
+			IPAddress:   c.ClientIP(),
+			UserAgent:   c.GetHeader("User-Agent"),
+		}
+		if err := h.auditRepo.Create(ctx, auditLog); err != nil {
+			return fmt.Errorf("failed to create audit log: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		log.Printf("Failed to register user %s: %v", user.Username, err)
+		apierror.Write(c, apierror.Internal, "failed to create user")
 		return
 	}
 
 	// Send verification email if email provided
 	if req.Email != nil {
-		verification, err := h.emailVerifyRepo.Create(c.Request.Context(), user.ID)
-		if err != nil {
-			// Don't fail registration if email verification fails
-			log.Printf("Failed to create email verification: %v", err)
-		} else {
-			if err := h.emailService.SendVerificationEmail(*req.Email, req.Username, verification.Token); err != nil {
-				// Don't fail registration if sending email fails
-				log.Printf("Failed to send verification email: %v", err)
+		if err := h.emailService.SendVerificationEmail(*req.Email, req.Username, user.Language, verification.Token, verification.ExpiresAt); err != nil {
+			// Don't fail registration if sending email fails
+			log.Printf("Failed to send verification email: %v", err)
+		}
+
+		if h.config.EmailSettings().WelcomeEmail {
+			if err := h.emailService.SendWelcomeEmail(*req.Email, req.Username, user.Language); err != nil {
+				log.Printf("Failed to send welcome email: %v", err)
 			}
 		}
 	}
 
-	// Create audit log
-	details, _ := json.Marshal(map[string]interface{}{
-		"username": user.Username,
-		"role":     role.Name,
-	})
-	auditLog := &models.CreateAuditLogRequest{
-		UserID:      &user.ID,
-		Action:      "user_registered",
-		EntityType:  "user",
-		EntityID:    user.ID.String(),
-		Description: fmt.Sprintf("User %s registered successfully", user.Username),
-		Metadata:    string(details),
-		IPAddress:   c.ClientIP(),
-		UserAgent:   c.GetHeader("User-Agent"),
-	}
-	if err := h.auditRepo.Create(c.Request.Context(), auditLog); err != nil {
-		// Don't fail registration if audit log fails
-		log.Printf("Failed to create audit log: %v", err)
+	if h.webhooks != nil {
+		event := map[string]interface{}{
+			"id":         user.ID,
+			"username":   user.Username,
+			"role":       role.Name,
+			"created_at": user.CreatedAt,
+		}
+		if err := h.webhooks.Publish(c.Request.Context(), models.WebhookEventUserRegistered, event); err != nil {
+			// Don't fail registration if webhooks can't be queued
+			log.Printf("Failed to publish registration to webhooks: %v", err)
+		}
 	}
 
 	c.JSON(http.StatusCreated, user)
@@ -347,14 +499,14 @@ func (h *AuthHandler) Register(c *gin.Context) {
 // @Produce json
 // @Param token query string true "Email verification token"
 // @Success 200 {object} models.SuccessResponse "Email verified successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid, expired, or missing token"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid, expired, or missing token"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Router /auth/verify-email [get]
 func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	token := c.Query("token")
 	if token == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "verification token is required"})
+		apierror.Write(c, apierror.InvalidRequest, "verification token is required")
 		return
 	}
 
@@ -362,11 +514,11 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 	if err := h.emailVerifyRepo.Verify(c.Request.Context(), token); err != nil {
 		switch err {
 		case repository.ErrTokenExpired:
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "verification token has expired"})
+			apierror.Write(c, apierror.InvalidRequest, "verification token has expired")
 		case repository.ErrTokenInvalid:
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid verification token"})
+			apierror.Write(c, apierror.InvalidRequest, "invalid verification token")
 		default:
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to verify email"})
+			apierror.Write(c, apierror.Internal, "failed to verify email")
 		}
 		return
 	}
@@ -382,52 +534,62 @@ func (h *AuthHandler) VerifyEmail(c *gin.Context) {
 // @Produce json
 // @Param request body models.ResendVerificationRequest true "Resend verification request"
 // @Success 200 {object} models.SuccessResponse
-// @Failure 400 {object} models.ErrorResponse "Email already verified or missing"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Email already verified or missing"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded or too many verification emails requested"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /auth/resend-verification [post]
 func (h *AuthHandler) ResendVerification(c *gin.Context) {
 	var req models.ResendVerificationRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// Get user by email
 	user, err := h.userRepo.GetByEmail(c.Request.Context(), req.Email)
 	if err == repository.ErrUserNotFound {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "no user found with this email address"})
+		apierror.Write(c, apierror.InvalidRequest, "no user found with this email address")
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get user"})
+		apierror.Write(c, apierror.Internal, "failed to get user")
 		return
 	}
 
 	// Check if email exists
 	if user.Email == nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "no email address associated with account"})
+		apierror.Write(c, apierror.InvalidRequest, "no email address associated with account")
 		return
 	}
 
 	// Check if already verified
 	if user.EmailVerified {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "email already verified"})
+		apierror.Write(c, apierror.InvalidRequest, "email already verified")
+		return
+	}
+
+	limited, err := h.resendLimitReached(c.Request.Context(), user.ID, h.emailVerifyRepo.CountSince)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to check resend limit")
+		return
+	}
+	if limited {
+		apierror.Write(c, apierror.RateLimited, "too many verification emails requested, try again later")
 		return
 	}
 
 	// Create verification token
-	verification, err := h.emailVerifyRepo.Create(c.Request.Context(), user.ID)
+	verification, err := h.emailVerifyRepo.Create(c.Request.Context(), user.ID, h.config.EmailSettings().VerificationTTL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to create verification token"})
+		apierror.Write(c, apierror.Internal, "failed to create verification token")
 		return
 	}
 
 	// Send verification email
-	err = h.emailService.SendVerificationEmail(req.Email, user.Username, verification.Token)
+	err = h.emailService.SendVerificationEmail(req.Email, user.Username, user.Language, verification.Token, verification.ExpiresAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to send verification email"})
+		apierror.Write(c, apierror.Internal, "failed to send verification email")
 		return
 	}
 
@@ -436,20 +598,25 @@ func (h *AuthHandler) ResendVerification(c *gin.Context) {
 
 // RequestPasswordReset godoc
 // @Summary Request password reset
-// @Description Request a password reset email. For security, always returns success even if email doesn't exist.
+// @Description Request a password reset email. For security, always returns success even if email doesn't exist or too many reset emails were requested.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body models.PasswordResetRequest true "User's email"
 // @Success 200 {object} models.SuccessResponse "Reset link will be sent if email exists"
-// @Failure 400 {object} models.ErrorResponse "Invalid email format or user has no email"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Failed to process request, create token, or send email"
+// @Failure 400 {object} apierror.Problem "Invalid email format or user has no email"
+// @Failure 400 {object} apierror.Problem "CAPTCHA missing or rejected when enabled"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Failed to process request, create token, or send email"
+// @Failure 502 {object} apierror.Problem "CAPTCHA provider failed"
 // @Router /auth/reset-password [post]
 func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
 	var req models.PasswordResetRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
+		return
+	}
+	if !h.verifyCaptcha(c, req.CaptchaToken) {
 		return
 	}
 
@@ -461,33 +628,45 @@ func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process request"})
+		apierror.Write(c, apierror.Internal, "failed to process request")
 		return
 	}
 
 	// Check if user has an email
 	if user.Email == nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "user has no email address"})
+		apierror.Write(c, apierror.InvalidRequest, "user has no email address")
 		return
 	}
 
 	// Check if email is verified
 	if !user.EmailVerified {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "email address must be verified before requesting a password reset"})
+		apierror.Write(c, apierror.InvalidRequest, "email address must be verified before requesting a password reset")
+		return
+	}
+
+	// Silently drop requests over the limit so the response doesn't reveal the address exists
+	limited, err := h.resendLimitReached(c.Request.Context(), user.ID, h.passwordResetRepo.CountSince)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process request")
+		return
+	}
+	if limited {
+		log.Printf("Password reset email limit reached for user %s", user.ID)
+		c.JSON(http.StatusOK, models.SuccessResponse{Message: "if the email exists, a reset link will be sent"})
 		return
 	}
 
 	// Create password reset token
-	reset, err := h.passwordResetRepo.Create(c.Request.Context(), user.ID)
+	reset, err := h.passwordResetRepo.Create(c.Request.Context(), user.ID, h.config.EmailSettings().PasswordResetTTL)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to create reset token"})
+		apierror.Write(c, apierror.Internal, "failed to create reset token")
 		return
 	}
 
 	// Send password reset email
-	err = h.emailService.SendPasswordResetEmail(*user.Email, user.Username, reset.Token)
+	err = h.emailService.SendPasswordResetEmail(*user.Email, user.Username, user.Language, reset.Token, reset.ExpiresAt)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to send password reset email"})
+		apierror.Write(c, apierror.Internal, "failed to send password reset email")
 		return
 	}
 
@@ -502,14 +681,15 @@ func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
 // @Produce json
 // @Param request body models.CompleteResetRequest true "Reset completion details"
 // @Success 200 {object} models.SuccessResponse "Password reset successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid request, expired/invalid/used token, or password reuse"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Failed to verify token, process password, or update user"
+// @Failure 400 {object} apierror.Problem "Invalid request, expired/invalid/used token, or password reuse"
+// @Failure 400 {object} apierror.Problem "Password does not meet the password policy"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Failed to verify token, process password, or update user"
 // @Router /auth/reset-password/complete [post]
 func (h *AuthHandler) CompletePasswordReset(c *gin.Context) {
 	var req models.CompleteResetRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -519,39 +699,48 @@ func (h *AuthHandler) CompletePasswordReset(c *gin.Context) {
 	case nil:
 		// Token is valid
 	case repository.ErrResetTokenExpired:
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "reset token has expired"})
+		apierror.Write(c, apierror.InvalidRequest, "reset token has expired")
 		return
 	case repository.ErrResetTokenInvalid:
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid reset token"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid reset token")
 		return
 	case repository.ErrResetTokenUsed:
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "reset token has already been used"})
+		apierror.Write(c, apierror.InvalidRequest, "reset token has already been used")
 		return
 	default:
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to verify token"})
+		apierror.Write(c, apierror.Internal, "failed to verify token")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), reset.UserID)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return
+	}
+	if !checkPassword(c, h.config, req.NewPassword, user.Username, user.Email) {
 		return
 	}
 
 	// Hash new password
 	hashedPassword, err := h.authService.HashPassword(req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to process password"})
+		apierror.Write(c, apierror.Internal, "failed to process password")
 		return
 	}
 
 	// Update password
 	if err := h.userRepo.UpdatePassword(c.Request.Context(), reset.UserID, hashedPassword); err != nil {
 		if err == repository.ErrPasswordReuse {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "cannot reuse recent passwords"})
+			apierror.Write(c, apierror.InvalidRequest, "cannot reuse recent passwords")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to update password"})
+		apierror.Write(c, apierror.Internal, "failed to update password")
 		return
 	}
 
 	// Mark reset token as used
 	if err := h.passwordResetRepo.MarkAsUsed(c.Request.Context(), reset.ID); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to complete reset"})
+		apierror.Write(c, apierror.Internal, "failed to complete reset")
 		return
 	}
 
@@ -566,47 +755,52 @@ type RefreshRequest struct {
 // RefreshResponse represents the response after refreshing an access token
 type RefreshResponse struct {
 	AccessToken string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIs..."`
+	// RefreshToken replaces the refresh token of the request, which can't be used again
+	RefreshToken string `json:"refresh_token" example:"dG9rZW4uLi4="`
 }
 
 // Refresh godoc
 // @Summary Refresh access token
-// @Description Get a new access token using a refresh token
+// @Description Get a new access token using a refresh token. The refresh token is rotated: the response holds a new one and the one sent can't be used again. Sending a refresh token that was already used revokes every token rotated from the same login, as it may have been stolen.
 // @Tags auth
 // @Accept json
 // @Produce json
 // @Param request body RefreshRequest true "Refresh token"
 // @Success 200 {object} RefreshResponse
-// @Failure 400 {object} models.ErrorResponse "Invalid request"
-// @Failure 401 {object} models.ErrorResponse "Invalid or expired refresh token"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Invalid, expired or reused refresh token"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Router /auth/refresh [post]
 func (h *AuthHandler) Refresh(c *gin.Context) {
 	var req RefreshRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
-	// Validate refresh token
-	userID, err := h.authService.ValidateRefreshToken(req.RefreshToken)
+	// Rotate refresh token
+	userID, refreshToken, err := h.authService.RotateRefreshToken(c.Request.Context(), req.RefreshToken, sessionClient(c))
+	if errors.Is(err, auth.ErrTokenReused) {
+		h.auditRefreshTokenReuse(c, userID)
+	}
 	if err != nil {
 		// Any error validating the token should result in 401
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "invalid or expired refresh token"})
+		apierror.Write(c, apierror.InvalidToken, "invalid or expired refresh token")
 		return
 	}
 
 	// Get user
 	user, err := h.userRepo.GetByID(c.Request.Context(), userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get user"})
+		apierror.Write(c, apierror.Internal, "failed to get user")
 		return
 	}
 
 	// Load user's role
 	role, err := h.roleRepo.GetByID(c.Request.Context(), user.RoleID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get user role"})
+		apierror.Write(c, apierror.Internal, "failed to get user role")
 		return
 	}
 	user.Role = role
@@ -614,11 +808,184 @@ func (h *AuthHandler) Refresh(c *gin.Context) {
 	// Generate new access token
 	accessToken, err := h.authService.GenerateToken(user, false)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to generate access token"})
+		apierror.Write(c, apierror.Internal, "failed to generate access token")
 		return
 	}
 
 	c.JSON(http.StatusOK, RefreshResponse{
-		AccessToken: accessToken,
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
 	})
 }
+
+// reportUnusualLogin records a login from a device or an IP address the user hadn't logged
+// in from before, and emails the user about new devices. The first login of a user has
+// nothing to compare with and isn't reported. Failures are logged, the login goes on.
+func (h *AuthHandler) reportUnusualLogin(c *gin.Context, user *models.User, client models.SessionClient, history models.LoginHistory) {
+	if h.securityEvents == nil || history.FirstLogin || (history.KnownDevice && history.KnownIP) {
+		return
+	}
+
+	event := &models.SecurityEvent{
+		UserID:    user.ID,
+		Type:      models.SecurityEventNewIP,
+		IPAddress: client.IPAddress,
+		UserAgent: client.UserAgent,
+		CreatedAt: time.Now(),
+	}
+	if !history.KnownDevice {
+		event.Type = models.SecurityEventNewDevice
+	}
+	if err := h.securityEvents.Create(c.Request.Context(), event); err != nil {
+		log.Printf("Failed to record security event: %v", err)
+	}
+
+	if event.Type == models.SecurityEventNewDevice && user.Email != nil {
+		if err := h.emailService.SendNewDeviceLoginEmail(*user.Email, user.Username, user.Language, client.IPAddress, client.UserAgent, event.CreatedAt); err != nil {
+			log.Printf("Failed to send new device login email: %v", err)
+		}
+	}
+}
+
+// verifyCaptcha checks the CAPTCHA token sent with the request and responds with an error
+// when it doesn't pass. Every request passes while no verifier is set.
+func (h *AuthHandler) verifyCaptcha(c *gin.Context, token string) bool {
+	if h.captcha == nil {
+		return true
+	}
+	err := h.captcha.Verify(c.Request.Context(), token, c.ClientIP())
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, captcha.ErrMissingToken):
+		apierror.Write(c, apierror.CaptchaFailed, "captcha required")
+	case errors.Is(err, captcha.ErrInvalidToken):
+		apierror.Write(c, apierror.CaptchaFailed, "captcha verification failed")
+	default:
+		log.Printf("Failed to verify captcha with %s: %v", h.captcha.Name(), err)
+		apierror.Write(c, apierror.UpstreamFailed, "failed to verify captcha")
+	}
+	return false
+}
+
+// sessionClient describes the client of the request for its session
+func sessionClient(c *gin.Context) models.SessionClient {
+	return models.SessionClient{UserAgent: c.GetHeader("User-Agent"), IPAddress: c.ClientIP()}
+}
+
+// auditRefreshTokenReuse records that a rotated refresh token of the user was sent again,
+// which revoked the user's session
+func (h *AuthHandler) auditRefreshTokenReuse(c *gin.Context, userID uuid.UUID) {
+	log.Printf("Refresh token of user %s reused, revoked its session", userID)
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &userID,
+		Action:      "refresh_token_reused",
+		EntityType:  "user",
+		EntityID:    userID.String(),
+		Description: "A refresh token was used after it was rotated, the session was revoked",
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+}
+
+// LogoutRequest represents the request to end a session
+type LogoutRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required" example:"dG9rZW4uLi4="`
+}
+
+// Logout godoc
+// @Summary Log out
+// @Description Ends the session of a refresh token, revoking it and every token rotated from the same login. Access tokens already issued stay valid until they expire.
+// @Tags auth
+// @Accept json
+// @Param request body LogoutRequest true "Refresh token of the session"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Invalid refresh token"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	var req LogoutRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if err := h.authService.RevokeRefreshToken(c.Request.Context(), req.RefreshToken); err != nil {
+		if errors.Is(err, auth.ErrInvalidToken) {
+			apierror.Write(c, apierror.InvalidToken, "invalid refresh token")
+			return
+		}
+		log.Printf("Error revoking refresh token: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to log out")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll godoc
+// @Summary Log out everywhere
+// @Description Ends all sessions of the authenticated user by revoking all of their refresh tokens. Access tokens already issued stay valid until they expire.
+// @Tags auth
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/logout-all [post]
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	if err := h.authService.RevokeAllRefreshTokens(c.Request.Context(), authUser.ID); err != nil {
+		log.Printf("Error revoking refresh tokens: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to log out")
+		return
+	}
+
+	h.auditUser(c, authUser, "logout_all", fmt.Sprintf("User %s logged out of all sessions", authUser.Username))
+	c.Status(http.StatusNoContent)
+}
+
+// auditUser records an action of the user on their own account
+func (h *AuthHandler) auditUser(c *gin.Context, user *models.User, action models.AuditAction, description string) {
+	details, _ := json.Marshal(map[string]interface{}{"username": user.Username})
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &user.ID,
+		Action:      action,
+		EntityType:  "user",
+		EntityID:    user.ID.String(),
+		Description: description,
+		Metadata:    string(details),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+}
+
+// resendLimitReached reports whether the user already received the configured number of
+// verification or reset emails within the resend window
+func (h *AuthHandler) resendLimitReached(
+	ctx context.Context,
+	userID uuid.UUID,
+	countSince func(context.Context, uuid.UUID, time.Time) (int, error),
+) (bool, error) {
+	settings := h.config.EmailSettings()
+	if settings.ResendLimit <= 0 {
+		return false, nil
+	}
+
+	count, err := countSince(ctx, userID, time.Now().Add(-settings.ResendWindow))
+	if err != nil {
+		return false, err
+	}
+	return count >= settings.ResendLimit, nil
+}
@@ -4,16 +4,23 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/captcha"
 	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
 	"wattwatch/internal/testutil"
+	"wattwatch/internal/validation"
 
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -128,7 +135,7 @@ func TestAuthHandler_Login(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'LoginRequest.Username' Error:Field validation for 'Username' failed on the 'required' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name:      "Missing Password",
@@ -140,7 +147,7 @@ func TestAuthHandler_Login(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'LoginRequest.Password' Error:Field validation for 'Password' failed on the 'required' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name:     "Email Not Verified",
@@ -207,7 +214,7 @@ func TestAuthHandler_Login(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'LoginRequest.Username' Error:Field validation for 'Username' failed on the 'max' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name:      "SQL Injection Attempt",
@@ -452,6 +459,110 @@ func TestAuthHandler_Login(t *testing.T) {
 	}
 }
 
+// TestAuthHandler_LoginInMemory runs the login flow against the in-memory repositories, so
+// it also runs without a database
+func TestAuthHandler_LoginInMemory(t *testing.T) {
+	tests := []struct {
+		name       string
+		setupFunc  func(*testutil.TestContext)
+		input      models.LoginRequest
+		wantStatus int
+	}{
+		{
+			name: "Success",
+			setupFunc: func(tc *testutil.TestContext) {
+				tc.CreateTestUser("test_user", "test@example.com", "test_password", false)
+			},
+			input:      models.LoginRequest{Username: "test_user", Password: "test_password"},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Deleted User",
+			setupFunc: func(tc *testutil.TestContext) {
+				user := tc.CreateTestUser("test_user", "test@example.com", "test_password", false)
+				require.NoError(t, tc.UserRepo.Delete(context.Background(), user.ID))
+			},
+			input:      models.LoginRequest{Username: "test_user", Password: "test_password"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name: "Invalid Credentials",
+			setupFunc: func(tc *testutil.TestContext) {
+				tc.CreateTestUser("test_user", "test@example.com", "test_password", false)
+			},
+			input:      models.LoginRequest{Username: "test_user", Password: "wrong_password"},
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Unknown User",
+			setupFunc:  func(tc *testutil.TestContext) {},
+			input:      models.LoginRequest{Username: "unknown_user", Password: "test_password"},
+			wantStatus: http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewMemoryTestContext(t)
+			tt.setupFunc(tc)
+
+			router := gin.New()
+			router.POST("/login", tc.AuthHandler.Login)
+
+			body, err := json.Marshal(tt.input)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			if tt.wantStatus != http.StatusOK {
+				// Failures can't tell unknown users from wrong passwords
+				require.JSONEq(t, `{"type":"/api/v1/errors/AUTH001","title":"Invalid credentials","status":401,
+					"detail":"invalid credentials","instance":"/login","code":"AUTH001","error":"invalid credentials"}`, w.Body.String())
+				return
+			}
+
+			var resp handlers.LoginResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			_, err = tc.AuthService.ValidateToken(resp.AccessToken)
+			require.NoError(t, err)
+
+			user, err := tc.UserRepo.GetByUsername(context.Background(), "test_user")
+			require.NoError(t, err)
+			require.NotNil(t, user.LastLoginAt)
+		})
+	}
+}
+
+func TestAuthHandler_LoginValidation(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	router := gin.New()
+	router.POST("/login", tc.AuthHandler.Login)
+
+	// Usernames are limited in characters, not bytes, like the other requests validate them
+	for username, want := range map[string]int{
+		strings.Repeat("ä", 50): http.StatusUnauthorized,
+		strings.Repeat("a", 51): http.StatusBadRequest,
+	} {
+		body, err := json.Marshal(models.LoginRequest{Username: username, Password: "test_password"})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, want, w.Code, w.Body.String())
+		if want == http.StatusBadRequest {
+			var resp apierror.Problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+			assert.Equal(t, apierror.ValidationFailed, resp.Code)
+			assert.Equal(t, []validation.FieldError{{Field: "username", Rule: "max", Message: "must have at most 50 characters"}}, resp.Errors)
+		}
+	}
+}
+
 func TestAuthHandler_Register(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -560,13 +671,166 @@ type refreshTest struct {
 	errMsg     string
 }
 
+func TestAuthHandler_RegisterPasswordPolicy(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	tc.Config.Password.RequireDigit = true
+	tc.Config.Password.RequireSymbol = true
+
+	router := gin.New()
+	router.POST("/register", tc.AuthHandler.Register)
+	register := func(password string) *httptest.ResponseRecorder {
+		body, err := json.Marshal(models.CreateUserRequest{Username: "test_user", Password: password})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/register", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := register("Test_User")
+	require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	var resp apierror.Problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, apierror.PasswordPolicy, resp.Code)
+	assert.Equal(t, "password does not meet the password policy", resp.Detail)
+	var rules []validation.PasswordRule
+	for _, f := range resp.Errors {
+		assert.Equal(t, "password", f.Field)
+		rules = append(rules, validation.PasswordRule(f.Rule))
+	}
+	assert.Equal(t, []validation.PasswordRule{validation.PasswordRuleDigit, validation.PasswordRuleUserInfo}, rules)
+
+	w = register("correct-horse-9")
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+}
+
+// stubCaptcha passes the token "passed" and fails "unavailable" like an unreachable provider
+type stubCaptcha struct{}
+
+func (stubCaptcha) Name() string { return "stub" }
+
+func (stubCaptcha) Verify(ctx context.Context, token, remoteIP string) error {
+	switch token {
+	case "":
+		return captcha.ErrMissingToken
+	case "passed":
+		return nil
+	case "unavailable":
+		return errors.New("provider unavailable")
+	default:
+		return captcha.ErrInvalidToken
+	}
+}
+
+func TestAuthHandler_Captcha(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	tc.Config.Captcha.LoginFailures = 2
+	tc.AuthHandler.SetCaptcha(stubCaptcha{})
+
+	router := gin.New()
+	router.POST("/register", tc.AuthHandler.Register)
+	router.POST("/login", tc.AuthHandler.Login)
+	router.POST("/reset-password", tc.AuthHandler.RequestPasswordReset)
+	post := func(path string, payload interface{}) *httptest.ResponseRecorder {
+		t.Helper()
+		body, err := json.Marshal(payload)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	requireCaptchaFailed := func(w *httptest.ResponseRecorder, detail string) {
+		t.Helper()
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		var resp apierror.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, apierror.CaptchaFailed, resp.Code)
+		assert.Equal(t, detail, resp.Detail)
+	}
+
+	register := models.CreateUserRequest{Username: "test_user", Password: "correct-horse-9"}
+	requireCaptchaFailed(post("/register", register), "captcha required")
+	register.CaptchaToken = "unavailable"
+	assert.Equal(t, http.StatusBadGateway, post("/register", register).Code)
+	register.CaptchaToken = "passed"
+	require.Equal(t, http.StatusCreated, post("/register", register).Code)
+
+	// Logins need a CAPTCHA once the account failed to log in twice
+	login := models.LoginRequest{Username: "test_user", Password: "correct-horse-9"}
+	require.Equal(t, http.StatusOK, post("/login", login).Code)
+	wrong := models.LoginRequest{Username: "test_user", Password: "wrong-password"}
+	require.Equal(t, http.StatusUnauthorized, post("/login", wrong).Code)
+	require.Equal(t, http.StatusUnauthorized, post("/login", wrong).Code)
+	requireCaptchaFailed(post("/login", login), "captcha required")
+	login.CaptchaToken = "rejected"
+	requireCaptchaFailed(post("/login", login), "captcha verification failed")
+
+	// Failed CAPTCHAs don't count towards a lockout
+	user, err := tc.UserRepo.GetByUsername(context.Background(), "test_user")
+	require.NoError(t, err)
+	attempts, err := tc.LoginAttemptRepo.GetRecentAttempts(context.Background(), user.ID, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts)
+
+	login.CaptchaToken = "passed"
+	require.Equal(t, http.StatusOK, post("/login", login).Code)
+
+	reset := models.PasswordResetRequest{Email: "nobody@example.com"}
+	requireCaptchaFailed(post("/reset-password", reset), "captcha required")
+	reset.CaptchaToken = "passed"
+	require.Equal(t, http.StatusOK, post("/reset-password", reset).Code)
+}
+
+func TestAuthHandler_LoginUnknownUsername(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	tc.Config.Captcha.LoginFailures = 2
+	tc.AuthHandler.SetCaptcha(stubCaptcha{})
+	tc.CreateTestUser("test_user", "test@example.com", "test_password", false)
+
+	router := gin.New()
+	router.POST("/login", tc.AuthHandler.Login)
+	type response struct {
+		status int
+		body   string
+	}
+	hammer := func(username string) []response {
+		t.Helper()
+		var responses []response
+		for i := 0; i < 7; i++ {
+			login := models.LoginRequest{Username: username, Password: "wrong_password"}
+			if i >= 3 {
+				login.CaptchaToken = "passed"
+			}
+			body, err := json.Marshal(login)
+			require.NoError(t, err)
+			req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			responses = append(responses, response{w.Code, w.Body.String()})
+		}
+		return responses
+	}
+
+	// Unknown usernames need a CAPTCHA and get locked out like existing ones
+	known := hammer("test_user")
+	unknown := hammer("unknown_user")
+	require.Equal(t, known, unknown)
+	for i, want := range []int{401, 401, 400, 401, 401, 401, 429} {
+		assert.Equal(t, want, unknown[i].status, unknown[i].body)
+	}
+}
+
 func TestAuthHandler_Refresh(t *testing.T) {
 	tests := []refreshTest{
 		{
 			name: "Success",
 			setupFunc: func(tc *testutil.TestContext) (string, error) {
 				user := tc.CreateTestUser("test_user", "test@example.com", "test_password", false)
-				return tc.AuthService.GenerateRefreshToken(context.Background(), user.ID)
+				return tc.AuthService.GenerateRefreshToken(context.Background(), user.ID, models.SessionClient{})
 			},
 			wantStatus: http.StatusOK,
 			wantErr:    false,
@@ -584,7 +848,7 @@ func TestAuthHandler_Refresh(t *testing.T) {
 			name: "Expired Token",
 			setupFunc: func(tc *testutil.TestContext) (string, error) {
 				user := tc.CreateTestUser("test_user_expired", "expired@example.com", "test_password", false)
-				token, err := tc.AuthService.GenerateRefreshToken(context.Background(), user.ID)
+				token, err := tc.AuthService.GenerateRefreshToken(context.Background(), user.ID, models.SessionClient{})
 				if err != nil {
 					return "", err
 				}
@@ -600,7 +864,7 @@ func TestAuthHandler_Refresh(t *testing.T) {
 			name: "Deleted User",
 			setupFunc: func(tc *testutil.TestContext) (string, error) {
 				user := tc.CreateTestUser("test_user_deleted", "deleted@example.com", "test_password", false)
-				token, err := tc.AuthService.GenerateRefreshToken(context.Background(), user.ID)
+				token, err := tc.AuthService.GenerateRefreshToken(context.Background(), user.ID, models.SessionClient{})
 				if err != nil {
 					return "", err
 				}
@@ -619,7 +883,7 @@ func TestAuthHandler_Refresh(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'RefreshRequest.RefreshToken' Error:Field validation for 'RefreshToken' failed on the 'required' tag",
+			errMsg:     "request validation failed",
 		},
 	}
 
@@ -663,6 +927,8 @@ func TestAuthHandler_Refresh(t *testing.T) {
 				err = json.NewDecoder(w.Body).Decode(&resp)
 				require.NoError(t, err)
 				require.NotEmpty(t, resp.AccessToken)
+				require.NotEmpty(t, resp.RefreshToken)
+				require.NotEqual(t, refreshToken, resp.RefreshToken)
 
 				// Verify the new access token is valid
 				claims, err := tc.AuthService.ValidateToken(resp.AccessToken)
@@ -676,6 +942,95 @@ func TestAuthHandler_Refresh(t *testing.T) {
 	}
 }
 
+func TestAuthHandler_RefreshRotation(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.POST("/auth/refresh", tc.AuthHandler.Refresh)
+	router.POST("/auth/logout", tc.AuthHandler.Logout)
+	router.POST("/auth/logout-all", authMiddleware.AuthRequired(), tc.AuthHandler.LogoutAll)
+
+	send := func(path, token string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, path, &buf)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	refresh := func(token string) (handlers.RefreshResponse, int) {
+		w := send("/auth/refresh", "", handlers.RefreshRequest{RefreshToken: token})
+		var resp handlers.RefreshResponse
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		}
+		return resp, w.Code
+	}
+	login := func() string {
+		token, err := tc.AuthService.GenerateRefreshToken(context.Background(), user.ID, models.SessionClient{})
+		require.NoError(t, err)
+		return token
+	}
+
+	t.Run("Rotation", func(t *testing.T) {
+		first := login()
+		second, code := refresh(first)
+		require.Equal(t, http.StatusOK, code)
+		require.NotEqual(t, first, second.RefreshToken)
+		third, code := refresh(second.RefreshToken)
+		require.Equal(t, http.StatusOK, code)
+
+		// Replaying a rotated token revokes the whole chain
+		other := login()
+		_, code = refresh(first)
+		assert.Equal(t, http.StatusUnauthorized, code)
+		_, code = refresh(third.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, code)
+		_, code = refresh(other)
+		assert.Equal(t, http.StatusOK, code, "other sessions stay")
+
+		logs, err := tc.AuditRepo.List(context.Background(), repository.AuditLogFilter{
+			Actions: []models.AuditAction{"refresh_token_reused"},
+		})
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, user.ID, *logs[0].UserID)
+	})
+
+	t.Run("Logout", func(t *testing.T) {
+		first := login()
+		second, code := refresh(first)
+		require.Equal(t, http.StatusOK, code)
+
+		w := send("/auth/logout", "", handlers.LogoutRequest{RefreshToken: "unknown"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = send("/auth/logout", "", handlers.LogoutRequest{RefreshToken: first})
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+		_, code = refresh(second.RefreshToken)
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+
+	t.Run("Logout All", func(t *testing.T) {
+		sessions := []string{login(), login()}
+		w := send("/auth/logout-all", "", nil)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = send("/auth/logout-all", tc.GetTestJWT(user.ID), nil)
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+		for _, token := range sessions {
+			_, code := refresh(token)
+			assert.Equal(t, http.StatusUnauthorized, code)
+		}
+	})
+}
+
 func TestAuthHandler_RequestPasswordReset(t *testing.T) {
 	tests := []struct {
 		name       string
@@ -731,7 +1086,7 @@ func TestAuthHandler_RequestPasswordReset(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'PasswordResetRequest.Email' Error:Field validation for 'Email' failed on the 'email' tag",
+			errMsg:     "request validation failed",
 		},
 	}
 
@@ -781,3 +1136,24 @@ func TestAuthHandler_RequestPasswordReset(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthHandler_ResendVerificationLimit(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	tc.Config.Email.ResendLimit = 2
+	user := tc.CreateTestUser("unverified_user", "unverified@example.com", "password123", false)
+
+	router := gin.New()
+	router.POST("/auth/resend-verification", tc.AuthHandler.ResendVerification)
+
+	body, err := json.Marshal(models.ResendVerificationRequest{Email: "unverified@example.com"})
+	require.NoError(t, err)
+
+	for i, wantStatus := range []int{http.StatusOK, http.StatusOK, http.StatusTooManyRequests} {
+		req := httptest.NewRequest(http.MethodPost, "/auth/resend-verification", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, wantStatus, w.Code, "request %d", i+1)
+	}
+}
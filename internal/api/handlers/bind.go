@@ -0,0 +1,25 @@
+package handlers
+
+import (
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// bindJSON binds the JSON request body to req, and responds with the fields that failed
+// validation when it doesn't bind
+func bindJSON(c *gin.Context, req interface{}) bool {
+	err := c.ShouldBindJSON(req)
+	if err == nil {
+		return true
+	}
+
+	fields := validation.FieldErrors(err)
+	if fields == nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid request body")
+		return false
+	}
+	apierror.Respond(c, &apierror.Error{Code: apierror.ValidationFailed, Detail: "request validation failed", Errors: fields})
+	return false
+}
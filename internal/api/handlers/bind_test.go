@@ -0,0 +1,88 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/testutil"
+	"wattwatch/internal/validation"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBindJSON(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	handler := handlers.NewRoleHandler(tc.RoleRepo, tc.UserRepo, tc.AuditRepo)
+	router := gin.New()
+	router.POST("/roles", func(c *gin.Context) {
+		c.Set("user", admin)
+		c.Set("is_admin", true)
+		handler.CreateRole(c)
+	})
+
+	send := func(t *testing.T, body string) apierror.Problem {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/roles", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+
+		var resp apierror.Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+
+	tests := []struct {
+		name     string
+		body     string
+		wantCode apierror.Code
+		want     []validation.FieldError
+	}{
+		{
+			name:     "missing field",
+			body:     `{}`,
+			wantCode: apierror.ValidationFailed,
+			want:     []validation.FieldError{{Field: "name", Rule: "required", Message: "is required"}},
+		},
+		{
+			name:     "too short",
+			body:     `{"name":"ab"}`,
+			wantCode: apierror.ValidationFailed,
+			want:     []validation.FieldError{{Field: "name", Rule: "min", Message: "must have at least 3 characters"}},
+		},
+		{
+			name:     "blank",
+			body:     `{"name":"     "}`,
+			wantCode: apierror.ValidationFailed,
+			want:     []validation.FieldError{{Field: "name", Rule: "nospaces", Message: "must not be blank"}},
+		},
+		{
+			name:     "wrong type",
+			body:     `{"name":42}`,
+			wantCode: apierror.ValidationFailed,
+			want:     []validation.FieldError{{Field: "name", Rule: validation.RuleType, Message: "must be a string"}},
+		},
+		{
+			name:     "malformed",
+			body:     `{"name":`,
+			wantCode: apierror.InvalidRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := send(t, tt.body)
+			assert.Equal(t, tt.wantCode, resp.Code)
+			assert.Equal(t, tt.want, resp.Errors)
+		})
+	}
+}
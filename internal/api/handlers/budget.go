@@ -0,0 +1,409 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/budget"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// BudgetHandler handles users' monthly budgets and the projected cost of the month
+type BudgetHandler struct {
+	budgetRepo   repository.BudgetRepository
+	zoneRepo     repository.ZoneRepository
+	currencyRepo repository.CurrencyRepository
+	projector    *budget.Projector
+	// preferences holds the zone and currency projected when they are omitted, there are
+	// none when it is nil
+	preferences repository.UserPreferenceRepository
+	// tariffs holds the tariffs added to the spot prices, there are none when it is nil
+	tariffs repository.TariffRepository
+}
+
+// NewBudgetHandler creates a new BudgetHandler
+func NewBudgetHandler(
+	budgetRepo repository.BudgetRepository,
+	zoneRepo repository.ZoneRepository,
+	currencyRepo repository.CurrencyRepository,
+	projector *budget.Projector,
+) *BudgetHandler {
+	return &BudgetHandler{
+		budgetRepo:   budgetRepo,
+		zoneRepo:     zoneRepo,
+		currencyRepo: currencyRepo,
+		projector:    projector,
+	}
+}
+
+// SetPreferences makes projections without a zone or currency use the defaults of the user
+func (h *BudgetHandler) SetPreferences(repo repository.UserPreferenceRepository) {
+	h.preferences = repo
+}
+
+// SetTariffs makes projections add the fees and taxes of the user's tariff
+func (h *BudgetHandler) SetTariffs(repo repository.TariffRepository) {
+	h.tariffs = repo
+}
+
+// ListBudgets godoc
+// @Summary List budgets
+// @Description Lists the authenticated user's monthly budgets
+// @Tags budgets
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Budget
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /budgets [get]
+func (h *BudgetHandler) ListBudgets(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	budgets, err := h.budgetRepo.ListByUserID(c.Request.Context(), authUser.ID)
+	if err != nil {
+		log.Printf("Error listing budgets: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list budgets")
+		return
+	}
+
+	c.JSON(http.StatusOK, budgets)
+}
+
+// CreateBudget godoc
+// @Summary Create a budget
+// @Description Sets the amount the authenticated user plans to spend on electricity in the zone and currency each month, at most one budget per zone and currency. Unless notify is false, the user is notified once a month when the projected cost of the month exceeds the amount.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateBudgetRequest true "Budget"
+// @Success 201 {object} models.Budget
+// @Failure 400 {object} apierror.Problem "Invalid request or unknown zone or currency"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 409 {object} apierror.Problem "A budget for the zone and currency exists"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /budgets [post]
+func (h *BudgetHandler) CreateBudget(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreateBudgetRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	b := &models.Budget{
+		UserID:     authUser.ID,
+		ZoneID:     req.ZoneID,
+		CurrencyID: req.CurrencyID,
+		Amount:     *req.Amount,
+		Notify:     req.Notify == nil || *req.Notify,
+	}
+	if err := h.budgetRepo.Create(c.Request.Context(), b); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			apierror.Write(c, apierror.InvalidRequest, "unknown zone or currency")
+		case errors.Is(err, repository.ErrDuplicateEntry):
+			apierror.Write(c, apierror.BudgetExists, "a budget for the zone and currency already exists")
+		default:
+			log.Printf("Error creating budget: %v", err)
+			apierror.Write(c, apierror.Internal, "failed to create budget")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, b)
+}
+
+// GetBudget godoc
+// @Summary Get a budget
+// @Description Returns one of the authenticated user's budgets
+// @Tags budgets
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Budget ID (UUID)"
+// @Success 200 {object} models.Budget
+// @Failure 400 {object} apierror.Problem "Invalid budget ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Budget not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /budgets/{id} [get]
+func (h *BudgetHandler) GetBudget(c *gin.Context) {
+	b, ok := h.getOwnedBudget(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, b)
+}
+
+// UpdateBudget godoc
+// @Summary Update a budget
+// @Description Updates the amount of one of the authenticated user's budgets, or whether they are notified about it
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Budget ID (UUID)"
+// @Param request body models.UpdateBudgetRequest true "Budget changes"
+// @Success 200 {object} models.Budget
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Budget not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /budgets/{id} [put]
+func (h *BudgetHandler) UpdateBudget(c *gin.Context) {
+	b, ok := h.getOwnedBudget(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateBudgetRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Amount != nil {
+		b.Amount = *req.Amount
+	}
+	if req.Notify != nil {
+		b.Notify = *req.Notify
+	}
+
+	if err := h.budgetRepo.Update(c.Request.Context(), b); err != nil {
+		log.Printf("Error updating budget: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to update budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, b)
+}
+
+// DeleteBudget godoc
+// @Summary Delete a budget
+// @Description Removes one of the authenticated user's budgets
+// @Tags budgets
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Budget ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid budget ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Budget not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /budgets/{id} [delete]
+func (h *BudgetHandler) DeleteBudget(c *gin.Context) {
+	b, ok := h.getOwnedBudget(c)
+	if !ok {
+		return
+	}
+
+	if err := h.budgetRepo.Delete(c.Request.Context(), b.ID); err != nil {
+		log.Printf("Error deleting budget: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to delete budget")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// ProjectCost godoc
+// @Summary Project the cost of the month
+// @Description Returns the cost of the authenticated user's consumption this month at the spot prices of the zone, in the zone's timezone, and projects it to the end of the month. Hours without readings are projected at the user's usual consumption for the hour of the day over the last 28 days, priced at the published spot prices or the month's average beyond them. The transfer fee, energy tax, monthly fee and VAT of the user's tariff for the zone and currency are added, or of the tariff picked with tariff_id; the user's own tariff comes before those of their organizations. Unless meter_id is set, the energy the user exported to the grid is valued at the spot price less the tariff's export fee, projected like consumption, and subtracted from the net costs. When the user has a budget for the zone and currency it is included, and over_budget tells whether the projected net cost exceeds it.
+// @Tags costs
+// @Produce json
+// @Security BearerAuth
+// @Param zone query string false "Zone name (e.g., 'SE3'), required unless the user picked a default"
+// @Param currency query string false "Currency name (e.g., 'SEK'), required unless the user picked a default"
+// @Param meter_id query string false "Meter to project, all of the user's meters by default"
+// @Param tariff_id query string false "Tariff of the user or their organizations to add, the user's tariff for the zone and currency by default"
+// @Success 200 {object} models.CostProjection
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone, currency or tariff not found, or no spot prices are stored for the month"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /costs/projection [get]
+func (h *BudgetHandler) ProjectCost(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	defaults := userDefaults(c, h.preferences)
+	zoneName := c.DefaultQuery("zone", defaults.zone)
+	if zoneName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "zone is required")
+		return
+	}
+	currencyName := c.DefaultQuery("currency", defaults.currency)
+	if currencyName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "currency is required")
+		return
+	}
+	var meterID *string
+	if meter := c.Query("meter_id"); meter != "" {
+		meterID = &meter
+	}
+
+	ctx := c.Request.Context()
+	zone, err := h.zoneRepo.GetByName(ctx, zoneName)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch zone")
+		return
+	}
+	currency, err := h.currencyRepo.GetByName(ctx, currencyName)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch currency")
+		return
+	}
+
+	tariff, ok := h.projectedTariff(c, authUser.ID, zone, currency)
+	if !ok {
+		return
+	}
+
+	projection, err := h.projector.Project(ctx, authUser.ID, meterID, zone, currency, tariff, time.Now())
+	if errors.Is(err, budget.ErrNoPrices) {
+		apierror.Write(c, apierror.SpotPriceNotFound, "no spot prices are stored for the month")
+		return
+	}
+	if err != nil {
+		log.Printf("Error projecting cost: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to project cost")
+		return
+	}
+
+	budgets, err := h.budgetRepo.ListByUserID(ctx, authUser.ID)
+	if err != nil {
+		log.Printf("Error listing budgets: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list budgets")
+		return
+	}
+	for i := range budgets {
+		if budgets[i].ZoneID == zone.ID && budgets[i].CurrencyID == currency.ID {
+			projection.Budget = &budgets[i]
+			projection.OverBudget = projection.ProjectedNetCost > budgets[i].Amount
+			break
+		}
+	}
+
+	c.JSON(http.StatusOK, projection)
+}
+
+// projectedTariff returns the tariff picked with the tariff_id query parameter, which must
+// be the user's or one of their organizations' for the zone and currency, or else the
+// user's tariff for the zone and currency. It writes an error response when the picked
+// tariff can't be used.
+func (h *BudgetHandler) projectedTariff(c *gin.Context, userID uuid.UUID, zone *models.Zone, currency *models.Currency) (*models.Tariff, bool) {
+	ctx := c.Request.Context()
+	param := c.Query("tariff_id")
+	if h.tariffs == nil {
+		if param != "" {
+			apierror.Write(c, apierror.TariffNotFound, "tariff not found")
+			return nil, false
+		}
+		return nil, true
+	}
+
+	if param == "" {
+		tariff, err := h.tariffs.FindForUser(ctx, userID, zone.ID, currency.ID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, true
+		}
+		if err != nil {
+			log.Printf("Error finding tariff: %v", err)
+			apierror.Write(c, apierror.Internal, "failed to find tariff")
+			return nil, false
+		}
+		return tariff, true
+	}
+
+	id, err := uuid.Parse(param)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid tariff_id")
+		return nil, false
+	}
+	tariffs, err := h.tariffs.ListForUser(ctx, userID)
+	if err != nil {
+		log.Printf("Error listing tariffs: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list tariffs")
+		return nil, false
+	}
+	for i := range tariffs {
+		if tariffs[i].ID != id {
+			continue
+		}
+		if tariffs[i].ZoneID != zone.ID || tariffs[i].CurrencyID != currency.ID {
+			apierror.Write(c, apierror.InvalidRequest, "the tariff is for another zone or currency")
+			return nil, false
+		}
+		return &tariffs[i], true
+	}
+	apierror.Write(c, apierror.TariffNotFound, "tariff not found")
+	return nil, false
+}
+
+// getOwnedBudget loads the budget from the id path parameter and writes an error
+// response if it does not exist or belongs to another user
+func (h *BudgetHandler) getOwnedBudget(c *gin.Context) (*models.Budget, bool) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid budget ID")
+		return nil, false
+	}
+
+	b, err := h.budgetRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.BudgetNotFound, "budget not found")
+			return nil, false
+		}
+		log.Printf("Error getting budget: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get budget")
+		return nil, false
+	}
+
+	if b.UserID != authUser.ID {
+		apierror.Write(c, apierror.BudgetNotFound, "budget not found")
+		return nil, false
+	}
+
+	return b, true
+}
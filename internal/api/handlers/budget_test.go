@@ -0,0 +1,180 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/budget"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	ctx := context.Background()
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@test.com", "password123", false)
+	zone, err := tc.ZoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := tc.CurrencyRepo.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+
+	projector := budget.NewProjector(tc.ConsumptionRepo, tc.SpotPriceRepo)
+	handler := handlers.NewBudgetHandler(tc.BudgetRepo, tc.ZoneRepo, tc.CurrencyRepo, projector)
+	handler.SetPreferences(tc.UserPreferenceRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/budgets", handler.ListBudgets)
+	router.POST("/budgets", handler.CreateBudget)
+	router.GET("/budgets/:id", handler.GetBudget)
+	router.PUT("/budgets/:id", handler.UpdateBudget)
+	router.DELETE("/budgets/:id", handler.DeleteBudget)
+	router.GET("/costs/projection", handler.ProjectCost)
+
+	send := func(method, path string, userID uuid.UUID, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	floatPtr := func(f float64) *float64 { return &f }
+
+	t.Run("Create Validation", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			input      models.CreateBudgetRequest
+			wantStatus int
+		}{
+			{"Missing Amount", models.CreateBudgetRequest{ZoneID: zone.ID, CurrencyID: currency.ID}, http.StatusBadRequest},
+			{"Zero Amount", models.CreateBudgetRequest{ZoneID: zone.ID, CurrencyID: currency.ID, Amount: floatPtr(0)}, http.StatusBadRequest},
+			{"Unknown Currency", models.CreateBudgetRequest{ZoneID: zone.ID, CurrencyID: uuid.New(), Amount: floatPtr(100)}, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := send("POST", "/budgets", alice.ID, tt.input)
+				assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			})
+		}
+	})
+
+	w := send("POST", "/budgets", alice.ID, models.CreateBudgetRequest{ZoneID: zone.ID, CurrencyID: currency.ID, Amount: floatPtr(10)})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.Budget
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, created.Notify)
+	assert.Equal(t, 10.0, created.Amount)
+	budgetPath := "/budgets/" + created.ID.String()
+
+	t.Run("Duplicate", func(t *testing.T) {
+		w := send("POST", "/budgets", alice.ID, models.CreateBudgetRequest{ZoneID: zone.ID, CurrencyID: currency.ID, Amount: floatPtr(20)})
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "BUDGET409")
+	})
+
+	t.Run("Ownership", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("GET", budgetPath, alice.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", budgetPath, bob.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("PUT", budgetPath, bob.ID, models.UpdateBudgetRequest{Amount: floatPtr(1)}).Code)
+		assert.Equal(t, http.StatusNotFound, send("DELETE", budgetPath, bob.ID, nil).Code)
+
+		var budgets []models.Budget
+		require.NoError(t, json.Unmarshal(send("GET", "/budgets", bob.ID, nil).Body.Bytes(), &budgets))
+		assert.Empty(t, budgets)
+		require.NoError(t, json.Unmarshal(send("GET", "/budgets", alice.ID, nil).Body.Bytes(), &budgets))
+		assert.Len(t, budgets, 1)
+	})
+
+	t.Run("Projection", func(t *testing.T) {
+		loc, err := time.LoadLocation(zone.Timezone)
+		require.NoError(t, err)
+		now := time.Now().In(loc)
+		start := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, loc)
+		end := start.AddDate(0, 1, 0)
+		hours := end.Sub(start).Hours()
+
+		// 1 kWh every hour until now, at 1 SEK all month
+		var records []models.ConsumptionRecord
+		consumed := 0.0
+		for at := now.Truncate(time.Hour).AddDate(0, 0, -budget.ProfileDays); at.Before(now.Truncate(time.Hour)); at = at.Add(time.Hour) {
+			records = append(records, models.ConsumptionRecord{UserID: alice.ID, MeterID: "main", Timestamp: at, KWh: 1})
+			if !at.Before(start) {
+				consumed++
+			}
+		}
+		require.NoError(t, tc.ConsumptionRepo.CreateBatch(ctx, records))
+		var prices []models.SpotPrice
+		for at := start; at.Before(end); at = at.Add(time.Hour) {
+			prices = append(prices, models.SpotPrice{Timestamp: at, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 100})
+		}
+		require.NoError(t, tc.SpotPriceRepo.CreateBatch(ctx, prices))
+
+		w := send("GET", "/costs/projection?zone=SE3&currency=SEK", alice.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var projection models.CostProjection
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projection))
+		assert.True(t, start.Equal(projection.Start))
+		assert.True(t, end.Equal(projection.End))
+		assert.Equal(t, consumed, projection.ConsumedKWh)
+		assert.Equal(t, consumed, projection.Cost)
+		assert.Equal(t, hours, projection.ProjectedKWh)
+		assert.Equal(t, hours, projection.ProjectedCost)
+		require.NotNil(t, projection.Budget)
+		assert.Equal(t, created.ID, projection.Budget.ID)
+		assert.True(t, projection.OverBudget)
+
+		// The zone and currency picked in the preferences are used when left out
+		require.NoError(t, tc.UserPreferenceRepo.Upsert(ctx, &models.UserPreferences{UserID: alice.ID, ZoneID: &zone.ID, CurrencyID: &currency.ID}))
+		w = send("GET", "/costs/projection", alice.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		// Bob has no readings nor a budget
+		w = send("GET", "/costs/projection?zone=SE3&currency=SEK", bob.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		projection = models.CostProjection{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projection))
+		assert.Equal(t, 0.0, projection.ProjectedCost)
+		assert.Nil(t, projection.Budget)
+		assert.False(t, projection.OverBudget)
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/costs/projection?zone=SE3", bob.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/costs/projection?zone=XX&currency=SEK", bob.ID, nil).Code)
+		w = send("GET", "/costs/projection?zone=SE3&currency=EUR", bob.ID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "no spot prices")
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		notify := false
+		w := send("PUT", budgetPath, alice.ID, models.UpdateBudgetRequest{Amount: floatPtr(2500), Notify: &notify})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.Budget
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, 2500.0, updated.Amount)
+		assert.False(t, updated.Notify)
+
+		assert.Equal(t, http.StatusBadRequest, send("PUT", budgetPath, alice.ID, models.UpdateBudgetRequest{Amount: floatPtr(-1)}).Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("DELETE", budgetPath, alice.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", budgetPath, alice.ID, nil).Code)
+	})
+}
@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// reassignTarget parses the optional reassign_to parameter of a forced deletion of id
+func reassignTarget(c *gin.Context, id uuid.UUID) (*uuid.UUID, string) {
+	value := c.Query("reassign_to")
+	if value == "" {
+		return nil, ""
+	}
+	target, err := uuid.Parse(value)
+	if err != nil {
+		return nil, "Invalid reassign_to ID"
+	}
+	if target == id {
+		return nil, "reassign_to must differ from the deleted ID"
+	}
+	return &target, ""
+}
+
+// logForcedDeletion records a deletion that removed or moved spot prices in the audit log
+func logForcedDeletion(c *gin.Context, auditRepo repository.AuditLogRepository, user *models.User, entityType string, id uuid.UUID, description string, summary *models.DeletionSummary) {
+	metadata, _ := json.Marshal(summary)
+	if err := auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &user.ID,
+		Action:      models.AuditActionDelete,
+		EntityType:  entityType,
+		EntityID:    id.String(),
+		Description: description,
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging forced deletion of %s %s: %v", entityType, id, err)
+	}
+}
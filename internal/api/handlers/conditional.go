@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etagOf returns an entity tag of the parts. Tags are weak, as compression changes the
+// bytes sent for the same content.
+func etagOf(parts ...interface{}) string {
+	hash := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(hash, "%v\x00", part)
+	}
+	return `W/"` + hex.EncodeToString(hash.Sum(nil)[:16]) + `"`
+}
+
+// notModified sets the ETag and Last-Modified of the response and reports whether the
+// client's copy is current, answering 304 Not Modified when it is. If-None-Match takes
+// precedence over If-Modified-Since as it also notices removed items. A zero
+// lastModified is left out.
+func notModified(c *gin.Context, etag string, lastModified time.Time) bool {
+	c.Header("ETag", etag)
+	if !lastModified.IsZero() {
+		c.Header("Last-Modified", lastModified.UTC().Format(http.TimeFormat))
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return false
+	}
+
+	current := false
+	if match := c.GetHeader("If-None-Match"); match != "" {
+		current = etagMatches(match, etag)
+	} else if since := c.GetHeader("If-Modified-Since"); since != "" && !lastModified.IsZero() {
+		t, err := http.ParseTime(since)
+		current = err == nil && !lastModified.Truncate(time.Second).After(t)
+	}
+	if current {
+		c.Status(http.StatusNotModified)
+	}
+	return current
+}
+
+// etagMatches reports whether an If-None-Match header lists etag, comparing weakly
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// respondConditional responds with value as JSON, tagged with an ETag of its encoding and
+// lastModified, or with 304 Not Modified when the client has it already
+func respondConditional(c *gin.Context, value interface{}, lastModified time.Time) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		c.JSON(http.StatusOK, value)
+		return
+	}
+	if notModified(c, etagOf(string(body)), lastModified) {
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", body)
+}
+
+// latest returns the latest of the times of items, zero when there are none
+func latest[T any](items []T, at func(T) time.Time) time.Time {
+	var t time.Time
+	for _, item := range items {
+		if at(item).After(t) {
+			t = at(item)
+		}
+	}
+	return t
+}
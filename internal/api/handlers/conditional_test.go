@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConditionalRequests(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewStore()
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	auditRepo := memory.NewAuditLogRepository(store)
+
+	zone, err := zoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	hour := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, spotPriceRepo.Create(ctx, &models.SpotPrice{Timestamp: hour, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 10}))
+
+	router := gin.New()
+	router.GET("/zones", handlers.NewZoneHandler(zoneRepo, auditRepo).ListZones)
+	router.GET("/currencies/:id", handlers.NewCurrencyHandler(currencyRepo, auditRepo).GetCurrency)
+	router.GET("/spot-prices", handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo).ListSpotPrices)
+
+	get := func(path string, headers ...string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		for i := 0; i < len(headers); i += 2 {
+			req.Header.Set(headers[i], headers[i+1])
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Zones", func(t *testing.T) {
+		w := get("/zones")
+		require.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+		lastModified := w.Header().Get("Last-Modified")
+		require.NotEmpty(t, lastModified)
+
+		w = get("/zones", "If-None-Match", etag)
+		assert.Equal(t, http.StatusNotModified, w.Code)
+		assert.Empty(t, w.Body.String())
+		assert.Equal(t, http.StatusNotModified, get("/zones", "If-Modified-Since", lastModified).Code)
+		assert.Equal(t, http.StatusOK, get("/zones", "If-None-Match", `W/"other"`).Code)
+		assert.Equal(t, http.StatusOK, get("/zones?limit=2", "If-None-Match", etag).Code, "another page has another tag")
+
+		zone.DisplayName = nil
+		require.NoError(t, zoneRepo.Update(ctx, zone))
+		assert.Equal(t, http.StatusOK, get("/zones", "If-None-Match", etag).Code)
+	})
+
+	t.Run("Currency", func(t *testing.T) {
+		w := get("/currencies/" + currency.ID.String())
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, http.StatusNotModified, get("/currencies/"+currency.ID.String(), "If-None-Match", w.Header().Get("ETag")).Code)
+	})
+
+	t.Run("Spot Prices", func(t *testing.T) {
+		path := "/spot-prices?zone=SE3&currency=EUR&start_time=2024-03-20T00:00:00Z&end_time=2024-03-21T00:00:00Z"
+		w := get(path)
+		require.Equal(t, http.StatusOK, w.Code)
+		etag := w.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+		assert.Equal(t, http.StatusNotModified, get(path, "If-None-Match", etag).Code)
+		assert.Equal(t, http.StatusOK, get(path+"&format=columnar", "If-None-Match", etag).Code)
+
+		require.NoError(t, spotPriceRepo.Create(ctx, &models.SpotPrice{Timestamp: hour, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 12}))
+		w = get(path, "If-None-Match", etag)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"price":12`)
+	})
+}
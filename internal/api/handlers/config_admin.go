@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ConfigAdminHandler handles runtime configuration management for administrators
+type ConfigAdminHandler struct {
+	reloader  *config.Reloader
+	auditRepo repository.AuditLogRepository
+}
+
+// NewConfigAdminHandler creates a new ConfigAdminHandler
+func NewConfigAdminHandler(reloader *config.Reloader, auditRepo repository.AuditLogRepository) *ConfigAdminHandler {
+	return &ConfigAdminHandler{
+		reloader:  reloader,
+		auditRepo: auditRepo,
+	}
+}
+
+// ReloadConfig godoc
+// @Summary Reload configuration
+// @Description Re-reads the config file and environment and applies email, rate limit, notification throttling and provider schedule settings without a restart. Other changed settings are listed as requiring a restart. Sessions are not affected. (admin only)
+// @Tags config
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.ConfigReloadResponse
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 422 {object} apierror.Problem "New configuration is invalid, nothing was changed"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /admin/config/reload [post]
+func (h *ConfigAdminHandler) ReloadConfig(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	result, err := h.reloader.Reload()
+	if err != nil {
+		apierror.Write(c, apierror.Unprocessable, err.Error())
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"applied":          result.Applied,
+		"restart_required": result.RestartRequired,
+	})
+	if auditErr := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "user",
+		EntityID:    authUser.ID.String(),
+		Description: "Configuration reloaded",
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); auditErr != nil {
+		log.Printf("Error logging configuration reload: %v", auditErr)
+	}
+
+	c.JSON(http.StatusOK, models.ConfigReloadResponse{
+		Applied:         result.Applied,
+		RestartRequired: result.RestartRequired,
+	})
+}
@@ -0,0 +1,80 @@
+package handlers_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigAdminHandler_ReloadConfig(t *testing.T) {
+	tests := []struct {
+		name        string
+		isAdmin     bool
+		file        string
+		wantStatus  int
+		wantApplied []string
+	}{
+		{
+			name:        "Applies Rate Limit",
+			isAdmin:     true,
+			file:        "rate_limit:\n  requests: 5\n",
+			wantStatus:  http.StatusOK,
+			wantApplied: []string{"rate_limit.requests"},
+		},
+		{
+			name:       "Invalid Config",
+			isAdmin:    true,
+			file:       "rate_limit:\n  requests: -1\n",
+			wantStatus: http.StatusUnprocessableEntity,
+		},
+		{
+			name:       "Non Admin",
+			isAdmin:    false,
+			file:       "rate_limit:\n  requests: 5\n",
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewTestContext(t)
+			user := tc.CreateTestUser("user", "user@test.com", "password123", tt.isAdmin)
+
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			require.NoError(t, os.WriteFile(path, []byte(tt.file), 0o600))
+			t.Setenv("RATE_LIMIT_REQUESTS", "")
+
+			handler := handlers.NewConfigAdminHandler(config.NewReloader(tc.Config, path), tc.AuditRepo)
+			router := gin.New()
+			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+			router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+			router.POST("/admin/config/reload", handler.ReloadConfig)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/admin/config/reload", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var resp models.ConfigReloadResponse
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				for _, key := range tt.wantApplied {
+					assert.Contains(t, resp.Applied, key)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,362 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/importer"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxConsumptionRange is the longest time range consumption can be listed for, a month
+// of readings to calculate a bill from
+const maxConsumptionRange = 31 * 24 * time.Hour
+
+// consumptionImportBatchSize is the number of imported readings stored per statement, well
+// below the number of parameters a PostgreSQL statement can take
+const consumptionImportBatchSize = 5000
+
+// consumptionImportPreviewRows is the number of rows an import preview shows
+const consumptionImportPreviewRows = 20
+
+// ConsumptionHandler handles the energy consumption users report for their meters
+type ConsumptionHandler struct {
+	repo    repository.ConsumptionRepository
+	orgRepo repository.OrganizationRepository
+	limits  ListLimits
+}
+
+// NewConsumptionHandler creates a new ConsumptionHandler
+func NewConsumptionHandler(repo repository.ConsumptionRepository) *ConsumptionHandler {
+	return &ConsumptionHandler{repo: repo, limits: DefaultListLimits}
+}
+
+// SetListLimits sets the maximum number of records listed, which is also the default
+func (h *ConsumptionHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// SetOrganizationRepository lets owners and admins of organizations list the consumption
+// of their members
+func (h *ConsumptionHandler) SetOrganizationRepository(orgRepo repository.OrganizationRepository) {
+	h.orgRepo = orgRepo
+}
+
+// CreateConsumption godoc
+// @Summary Ingest consumption records
+// @Description Stores meter readings of the authenticated user in a single batch. A record for a meter and timestamp that already exists has its kWh replaced.
+// @Tags consumption
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param records body models.CreateConsumptionRequest true "Records to create or update"
+// @Success 201 {array} models.ConsumptionRecord
+// @Failure 400 {object} apierror.Problem "Invalid request body or duplicate records"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /consumption [post]
+func (h *ConsumptionHandler) CreateConsumption(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreateConsumptionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// A batch can't set a record twice, the database would reject the upsert
+	seen := make(map[string]bool, len(req.Records))
+	records := make([]models.ConsumptionRecord, len(req.Records))
+	for i, r := range req.Records {
+		key := r.MeterID + "@" + strconv.FormatInt(r.Timestamp.UnixNano(), 10)
+		if seen[key] {
+			apierror.Write(c, apierror.InvalidRequest,
+				fmt.Sprintf("duplicate record for meter %s at %s", r.MeterID, r.Timestamp.Format(time.RFC3339)))
+			return
+		}
+		seen[key] = true
+
+		records[i] = models.ConsumptionRecord{
+			UserID:    authUser.ID,
+			MeterID:   r.MeterID,
+			Timestamp: r.Timestamp,
+			KWh:       *r.KWh,
+		}
+	}
+
+	if err := h.repo.CreateBatch(c.Request.Context(), records); err != nil {
+		log.Printf("Error storing consumption records: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to store consumption")
+		return
+	}
+
+	c.JSON(http.StatusCreated, records)
+}
+
+// ListConsumption godoc
+// @Summary List consumption records
+// @Description Returns the authenticated user's meter readings within a time range (max 31 days), oldest first. Owners and admins of an organization may list the readings of its members with user_id.
+// @Tags consumption
+// @Produce json
+// @Security BearerAuth
+// @Param start_time query string true "Start time (RFC3339)"
+// @Param end_time query string true "End time (RFC3339)"
+// @Param meter_id query string false "Filter by meter"
+// @Param user_id query string false "List the readings of a member of an organization the authenticated user manages"
+// @Param limit query integer false "Limit results (default and maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the records in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.ConsumptionRecord]
+// @Failure 400 {object} apierror.Problem "Invalid parameters or time range exceeds 31 days"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - user is not a member of an organization the caller manages"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /consumption [get]
+func (h *ConsumptionHandler) ListConsumption(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	filter := repository.ConsumptionFilter{UserID: authUser.ID}
+
+	if userID := c.Query("user_id"); userID != "" {
+		id, err := uuid.Parse(userID)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid user_id")
+			return
+		}
+		if id != authUser.ID {
+			manages := false
+			if h.orgRepo != nil {
+				manages, err = h.orgRepo.SharesOrganization(c.Request.Context(), authUser.ID, id,
+					models.OrganizationRoleOwner, models.OrganizationRoleAdmin)
+				if err != nil {
+					log.Printf("Error checking organizations of user %s: %v", authUser.ID, err)
+					apierror.Write(c, apierror.Internal, "failed to list consumption")
+					return
+				}
+			}
+			if !manages {
+				apierror.Write(c, apierror.Forbidden, "permission denied")
+				return
+			}
+		}
+		filter.UserID = id
+	}
+
+	startTime, err := time.Parse(time.RFC3339, c.Query("start_time"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "start_time is required in RFC3339 format")
+		return
+	}
+	endTime, err := time.Parse(time.RFC3339, c.Query("end_time"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "end_time is required in RFC3339 format")
+		return
+	}
+	if endTime.Before(startTime) {
+		apierror.Write(c, apierror.InvalidRequest, "end_time must be after start_time")
+		return
+	}
+	if endTime.Sub(startTime) > maxConsumptionRange {
+		apierror.Write(c, apierror.InvalidRequest, "time range cannot exceed 31 days")
+		return
+	}
+	filter.StartTime, filter.EndTime = &startTime, &endTime
+
+	if meterID := c.Query("meter_id"); meterID != "" {
+		filter.MeterID = &meterID
+	}
+
+	limit, err := h.limits.limit(c, h.limits.Max)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	filter.Limit = &limit
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	records, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error listing consumption records: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list consumption")
+		return
+	}
+
+	respondPage(c, records, filter.Limit, filter.Offset, func() (int, error) {
+		return h.repo.Total(c.Request.Context(), filter)
+	}, "failed to list consumption")
+}
+
+// ImportConsumption godoc
+// @Summary Import consumption
+// @Description Stores the meter readings of the authenticated user from an export file. Formats are csv, a CSV file with a header row mapped to readings with the column parameters, se_grid, the semicolon separated exports of Swedish grid operators with decimal commas and times in Europe/Stockholm, and tibber, the consumption Tibber's API returns (a whole response, a home's consumption object or its nodes). Columns left out of the mapping are detected from the header, preview the import to check them. Every row is read on its own: rows that can't be imported are reported with their line and the others are stored, replacing the kWh of readings that exist. At most 35136 readings per import.
+// @Tags consumption
+// @Accept text/csv
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param file body string true "Export file"
+// @Param format query string false "Format of the file, tibber for JSON content and csv otherwise by default" Enums(csv, se_grid, tibber)
+// @Param dry_run query bool false "Read the file without storing any reading"
+// @Param delimiter query string false "CSV field delimiter, detected from the header by default"
+// @Param timestamp_column query string false "Column with the start of each reading, or its date when time_column is set"
+// @Param time_column query string false "Column with the time of day of each reading"
+// @Param timestamp_layout query string false "Go time layout of the timestamps, common layouts are tried by default"
+// @Param timezone query string false "IANA timezone of timestamps without an offset, UTC for csv and Europe/Stockholm for se_grid by default"
+// @Param kwh_column query string false "Column with the consumption of each reading"
+// @Param unit query string false "Unit of the consumption column" Enums(kWh, Wh)
+// @Param meter_column query string false "Column with the meter of each reading"
+// @Param meter_id query string false "Meter of readings without a meter column or Tibber home ID"
+// @Success 200 {object} models.ConsumptionImportResponse
+// @Failure 400 {object} apierror.Problem "Invalid format, mapping or file, or too many readings"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /consumption/import [post]
+func (h *ConsumptionHandler) ImportConsumption(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	format, mapping, ok := consumptionImportParams(c)
+	if !ok {
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid dry_run parameter")
+		return
+	}
+
+	rows, err := importer.Read(c.Request.Body, format, mapping)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		apierror.Write(c, apierror.InvalidRequest, "no readings to import")
+		return
+	}
+
+	response := models.ConsumptionImportResponse{Format: format, DryRun: dryRun, Errors: []models.ConsumptionImportRowError{}}
+	records := make([]models.ConsumptionRecord, 0, len(rows))
+	for _, row := range rows {
+		if row.Err != nil {
+			response.Errors = append(response.Errors, models.ConsumptionImportRowError{Row: row.Line, Error: row.Err.Error()})
+			continue
+		}
+		records = append(records, models.ConsumptionRecord{
+			UserID:    authUser.ID,
+			MeterID:   row.MeterID,
+			Timestamp: row.Timestamp,
+			KWh:       row.KWh,
+		})
+	}
+	response.Imported, response.Failed = len(records), len(response.Errors)
+
+	// Readings are upserted, so an import cut short by an error can be sent again
+	if !dryRun {
+		for start := 0; start < len(records); start += consumptionImportBatchSize {
+			batch := records[start:min(start+consumptionImportBatchSize, len(records))]
+			if err := h.repo.CreateBatch(c.Request.Context(), batch); err != nil {
+				log.Printf("Error storing imported consumption records: %v", err)
+				apierror.Write(c, apierror.Internal, "failed to store consumption")
+				return
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// PreviewConsumptionImport godoc
+// @Summary Preview a consumption import
+// @Description Reads the first 20 rows of an export file the way an import would, without storing them. For CSV files it returns the columns of the header and the mapping with the detected columns filled in, or why the file can't be imported with the mapping. Takes the parameters of the import.
+// @Tags consumption
+// @Accept text/csv
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param file body string true "Export file"
+// @Param format query string false "Format of the file, tibber for JSON content and csv otherwise by default" Enums(csv, se_grid, tibber)
+// @Param delimiter query string false "CSV field delimiter, detected from the header by default"
+// @Param timestamp_column query string false "Column with the start of each reading, or its date when time_column is set"
+// @Param time_column query string false "Column with the time of day of each reading"
+// @Param timestamp_layout query string false "Go time layout of the timestamps, common layouts are tried by default"
+// @Param timezone query string false "IANA timezone of timestamps without an offset"
+// @Param kwh_column query string false "Column with the consumption of each reading"
+// @Param unit query string false "Unit of the consumption column" Enums(kWh, Wh)
+// @Param meter_column query string false "Column with the meter of each reading"
+// @Param meter_id query string false "Meter of readings without a meter column or Tibber home ID"
+// @Success 200 {object} models.ConsumptionImportPreview
+// @Failure 400 {object} apierror.Problem "Invalid format or unreadable file"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /consumption/import/preview [post]
+func (h *ConsumptionHandler) PreviewConsumptionImport(c *gin.Context) {
+	if auth.GetUserFromContext(c) == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	format, mapping, ok := consumptionImportParams(c)
+	if !ok {
+		return
+	}
+	preview, err := importer.Preview(c.Request.Body, format, mapping, consumptionImportPreviewRows)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, preview)
+}
+
+// consumptionImportParams reads the format and column mapping of an import, responding
+// with an error when they are invalid
+func consumptionImportParams(c *gin.Context) (string, models.ConsumptionImportMapping, bool) {
+	var mapping models.ConsumptionImportMapping
+	if err := c.ShouldBindQuery(&mapping); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid mapping parameters")
+		return "", mapping, false
+	}
+
+	format := c.Query("format")
+	if format == "" {
+		format = models.ConsumptionImportCSV
+		if c.ContentType() == "application/json" {
+			format = models.ConsumptionImportTibber
+		}
+	}
+	if !importer.Valid(format) {
+		apierror.Write(c, apierror.InvalidRequest, "invalid format, use csv, se_grid or tibber")
+		return "", mapping, false
+	}
+	return format, mapping, true
+}
@@ -0,0 +1,220 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumptionHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@test.com", "password123", false)
+
+	handler := handlers.NewConsumptionHandler(memory.NewConsumptionRepository(memory.NewStore()))
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	consumption := router.Group("/consumption", authMiddleware.AuthRequired())
+	consumption.GET("", handler.ListConsumption)
+	consumption.POST("", handler.CreateConsumption)
+
+	send := func(method, path string, userID uuid.UUID, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	kwh := func(v float64) *float64 { return &v }
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	list := func(userID uuid.UUID, params ...string) *httptest.ResponseRecorder {
+		query := url.Values{
+			"start_time": {start.Format(time.RFC3339)},
+			"end_time":   {start.Add(24 * time.Hour).Format(time.RFC3339)},
+		}
+		for i := 0; i+1 < len(params); i += 2 {
+			query.Set(params[i], params[i+1])
+		}
+		return send("GET", "/consumption?"+query.Encode(), userID, nil)
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		records := make([]models.CreateConsumptionRecordRequest, 0)
+		for i := 0; i < 3; i++ {
+			records = append(records, models.CreateConsumptionRecordRequest{MeterID: "main", Timestamp: start.Add(time.Duration(i) * time.Hour), KWh: kwh(float64(i))})
+		}
+		w := send("POST", "/consumption", alice.ID, models.CreateConsumptionRequest{Records: records})
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var created []models.ConsumptionRecord
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		require.Len(t, created, 3)
+		assert.Equal(t, alice.ID, created[0].UserID)
+		assert.False(t, created[0].CreatedAt.IsZero())
+	})
+
+	t.Run("Invalid Records", func(t *testing.T) {
+		for name, record := range map[string]models.CreateConsumptionRecordRequest{
+			"Negative":   {MeterID: "main", Timestamp: start, KWh: kwh(-1)},
+			"No Meter":   {Timestamp: start, KWh: kwh(1)},
+			"No Reading": {MeterID: "main", Timestamp: start},
+		} {
+			w := send("POST", "/consumption", alice.ID, models.CreateConsumptionRequest{Records: []models.CreateConsumptionRecordRequest{record}})
+			assert.Equal(t, http.StatusBadRequest, w.Code, name)
+		}
+
+		w := send("POST", "/consumption", alice.ID, models.CreateConsumptionRequest{})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		duplicate := models.CreateConsumptionRecordRequest{MeterID: "main", Timestamp: start, KWh: kwh(1)}
+		w = send("POST", "/consumption", alice.ID, models.CreateConsumptionRequest{Records: []models.CreateConsumptionRecordRequest{duplicate, duplicate}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		w := list(alice.ID, "limit", "2")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var page models.Page[models.ConsumptionRecord]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Items, 2)
+		assert.Equal(t, 3, page.Total)
+		require.NotNil(t, page.NextOffset)
+		assert.Equal(t, 2, *page.NextOffset)
+		assert.Equal(t, 0.0, page.Items[0].KWh)
+
+		// Users only see their own records
+		w = list(bob.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Empty(t, page.Items)
+		assert.Equal(t, 0, page.Total)
+	})
+
+	t.Run("Invalid Range", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, list(alice.ID, "start_time", "yesterday").Code)
+		assert.Equal(t, http.StatusBadRequest, list(alice.ID, "end_time", start.Add(-time.Hour).Format(time.RFC3339)).Code)
+		assert.Equal(t, http.StatusBadRequest, list(alice.ID, "end_time", start.AddDate(0, 2, 0).Format(time.RFC3339)).Code)
+		assert.Equal(t, http.StatusBadRequest, list(alice.ID, "offset", "-1").Code)
+	})
+}
+
+func TestConsumptionHandler_Import(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+
+	repo := memory.NewConsumptionRepository(memory.NewStore())
+	handler := handlers.NewConsumptionHandler(repo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	consumption := router.Group("/consumption", authMiddleware.AuthRequired())
+	consumption.POST("/import", handler.ImportConsumption)
+	consumption.POST("/import/preview", handler.PreviewConsumptionImport)
+
+	send := func(path, contentType, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(alice.ID))
+		req.Header.Set("Content-Type", contentType)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	stored := func(meterID string) []models.ConsumptionRecord {
+		start, end := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+		records, err := repo.List(context.Background(), repository.ConsumptionFilter{UserID: alice.ID, MeterID: &meterID, StartTime: &start, EndTime: &end})
+		require.NoError(t, err)
+		return records
+	}
+	gridExport := "Anläggnings-ID;Datum;Tid;Förbrukning (kWh)\n" +
+		"se-1;2024-01-15;00:00;1,25\n" +
+		"se-1;2024-01-15;01:00;fel\n" +
+		"se-1;2024-01-15;02:00;0,75\n"
+
+	t.Run("Dry Run", func(t *testing.T) {
+		w := send("/consumption/import?format=se_grid&dry_run=true", "text/csv", gridExport)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp models.ConsumptionImportResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.True(t, resp.DryRun)
+		assert.Equal(t, 2, resp.Imported)
+		assert.Empty(t, stored("se-1"))
+	})
+
+	t.Run("Swedish Grid Export", func(t *testing.T) {
+		w := send("/consumption/import?format=se_grid", "text/csv", gridExport)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var resp models.ConsumptionImportResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, "se_grid", resp.Format)
+		assert.Equal(t, 2, resp.Imported)
+		assert.Equal(t, 1, resp.Failed)
+		assert.Equal(t, []models.ConsumptionImportRowError{{Row: 3, Error: `invalid kWh "fel"`}}, resp.Errors)
+
+		records := stored("se-1")
+		require.Len(t, records, 2)
+		assert.True(t, time.Date(2024, 1, 14, 23, 0, 0, 0, time.UTC).Equal(records[0].Timestamp))
+		assert.Equal(t, 1.25, records[0].KWh)
+	})
+
+	t.Run("Tibber", func(t *testing.T) {
+		body := `[{"from":"2024-01-15T00:00:00.000+01:00","consumption":0.5,"consumptionUnit":"kWh"}]`
+		w := send("/consumption/import?meter_id=tibber-home", "application/json", body)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		records := stored("tibber-home")
+		require.Len(t, records, 1)
+		assert.Equal(t, 0.5, records[0].KWh)
+	})
+
+	t.Run("Preview", func(t *testing.T) {
+		w := send("/consumption/import/preview?meter_id=main", "text/csv", "time,usage\n2024-01-15T00:00:00Z,2\n")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var preview models.ConsumptionImportPreview
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+		assert.Equal(t, []string{"time", "usage"}, preview.Columns)
+		assert.Contains(t, preview.Error, "no kWh column found")
+
+		w = send("/consumption/import/preview?meter_id=main&kwh_column=usage", "text/csv", "time,usage\n2024-01-15T00:00:00Z,2\n")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		preview = models.ConsumptionImportPreview{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &preview))
+		assert.Empty(t, preview.Error)
+		assert.Equal(t, "time", preview.Mapping.TimestampColumn)
+		require.Len(t, preview.Rows, 1)
+		assert.Equal(t, 2.0, *preview.Rows[0].KWh)
+		assert.Empty(t, stored("main"))
+	})
+
+	t.Run("Invalid Imports", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("/consumption/import?format=xlsx", "text/csv", "a,b\n").Code)
+		assert.Equal(t, http.StatusBadRequest, send("/consumption/import?dry_run=maybe", "text/csv", gridExport).Code)
+		assert.Equal(t, http.StatusBadRequest, send("/consumption/import?meter_id=main", "text/csv", "").Code)
+		assert.Equal(t, http.StatusBadRequest, send("/consumption/import?meter_id=main", "text/csv", "time,kwh\n").Code)
+		assert.Equal(t, http.StatusBadRequest, send("/consumption/import", "text/csv", "time,kwh\n2024-01-15T00:00:00Z,1\n").Code)
+	})
+}
@@ -1,7 +1,12 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
+	"strings"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
 
@@ -11,12 +16,13 @@ import (
 
 // CurrencyHandler handles currency-related requests
 type CurrencyHandler struct {
-	repo repository.CurrencyRepository
+	repo      repository.CurrencyRepository
+	auditRepo repository.AuditLogRepository
 }
 
 // NewCurrencyHandler creates a new CurrencyHandler
-func NewCurrencyHandler(repo repository.CurrencyRepository) *CurrencyHandler {
-	return &CurrencyHandler{repo: repo}
+func NewCurrencyHandler(repo repository.CurrencyRepository, auditRepo repository.AuditLogRepository) *CurrencyHandler {
+	return &CurrencyHandler{repo: repo, auditRepo: auditRepo}
 }
 
 // ListCurrencies godoc
@@ -26,19 +32,22 @@ func NewCurrencyHandler(repo repository.CurrencyRepository) *CurrencyHandler {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
+// @Param If-None-Match header string false "ETag of the currencies the client has"
+// @Param If-Modified-Since header string false "When the client got the currencies it has"
 // @Success 200 {array} models.Currency
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Success 304 "Not Modified"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /currencies [get]
 func (h *CurrencyHandler) ListCurrencies(c *gin.Context) {
 	currencies, err := h.repo.List(c.Request.Context())
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch currencies"})
+		apierror.Write(c, apierror.Internal, "Failed to fetch currencies")
 		return
 	}
 
-	c.JSON(http.StatusOK, currencies)
+	respondConditional(c, currencies, latest(currencies, func(currency models.Currency) time.Time { return currency.UpdatedAt }))
 }
 
 // GetCurrency godoc
@@ -49,56 +58,91 @@ func (h *CurrencyHandler) ListCurrencies(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Currency ID"
+// @Param If-None-Match header string false "ETag of the currency the client has"
+// @Param If-Modified-Since header string false "When the client got the currency it has"
 // @Success 200 {object} models.Currency
-// @Failure 400 {object} models.ErrorResponse "Invalid currency ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 404 {object} models.ErrorResponse "Currency not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} apierror.Problem "Invalid currency ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /currencies/{id} [get]
 func (h *CurrencyHandler) GetCurrency(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid currency ID"})
+		apierror.Write(c, apierror.InvalidRequest, "Invalid currency ID")
 		return
 	}
 
 	currency, err := h.repo.GetByID(c.Request.Context(), id)
 	if err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Currency not found"})
+		apierror.Write(c, apierror.CurrencyNotFound, "Currency not found")
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch currency"})
+		apierror.Write(c, apierror.Internal, "Failed to fetch currency")
 		return
 	}
 
-	c.JSON(http.StatusOK, currency)
+	respondConditional(c, currency, currency.UpdatedAt)
+}
+
+// newISO4217Currency returns the ISO 4217 currency with the code, with the formatting
+// hints that are set replacing the standard ones. It reports false for unknown codes.
+func newISO4217Currency(code string, symbol *string, symbolFirst *bool, minorUnits *int) (models.Currency, bool) {
+	iso, ok := models.LookupISO4217(strings.ToUpper(code))
+	if !ok {
+		return models.Currency{}, false
+	}
+	currency := models.Currency{
+		Name:        iso.Code,
+		NumericCode: iso.NumericCode,
+		Symbol:      iso.Symbol,
+		SymbolFirst: iso.SymbolFirst,
+		MinorUnits:  iso.MinorUnits,
+	}
+	if symbol != nil {
+		currency.Symbol = *symbol
+	}
+	if symbolFirst != nil {
+		currency.SymbolFirst = *symbolFirst
+	}
+	if minorUnits != nil {
+		currency.MinorUnits = *minorUnits
+	}
+	return currency, true
 }
 
 // CreateCurrency godoc
 // @Summary Create a new currency
-// @Description Creates a new currency
+// @Description Creates a new currency. The name must be an ISO 4217 code, which sets the numeric code and the default formatting hints.
 // @Tags currencies
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param currency body models.Currency true "Currency to create"
+// @Param currency body models.CreateCurrencyRequest true "Currency to create"
 // @Success 201 {object} models.Currency
-// @Failure 400 {object} models.ErrorResponse "Invalid request body"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid request body"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /currencies [post]
 func (h *CurrencyHandler) CreateCurrency(c *gin.Context) {
-	var currency models.Currency
-	if err := c.ShouldBindJSON(&currency); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body"})
+	var req models.CreateCurrencyRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	currency, ok := newISO4217Currency(req.Name, req.Symbol, req.SymbolFirst, req.MinorUnits)
+	if !ok {
+		apierror.Write(c, apierror.InvalidRequest, "name must be an ISO 4217 currency code")
 		return
 	}
 
 	if err := h.repo.Create(c.Request.Context(), &currency); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create currency"})
+		apierror.Write(c, apierror.Internal, "Failed to create currency")
 		return
 	}
 
@@ -107,39 +151,44 @@ func (h *CurrencyHandler) CreateCurrency(c *gin.Context) {
 
 // UpdateCurrency godoc
 // @Summary Update a currency
-// @Description Updates an existing currency
+// @Description Updates an existing currency. The name must be an ISO 4217 code, which sets the numeric code and the default formatting hints.
 // @Tags currencies
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Currency ID"
-// @Param currency body models.Currency true "Updated currency"
+// @Param currency body models.UpdateCurrencyRequest true "Updated currency"
 // @Success 200 {object} models.Currency
-// @Failure 400 {object} models.ErrorResponse "Invalid request body or currency ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 404 {object} models.ErrorResponse "Currency not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid request body or currency ID"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /currencies/{id} [put]
 func (h *CurrencyHandler) UpdateCurrency(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid currency ID"})
+		apierror.Write(c, apierror.InvalidRequest, "Invalid currency ID")
 		return
 	}
 
-	var currency models.Currency
-	if err := c.ShouldBindJSON(&currency); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body"})
+	var req models.UpdateCurrencyRequest
+	if !bindJSON(c, &req) {
 		return
 	}
 
+	currency, ok := newISO4217Currency(req.Name, req.Symbol, req.SymbolFirst, req.MinorUnits)
+	if !ok {
+		apierror.Write(c, apierror.InvalidRequest, "name must be an ISO 4217 currency code")
+		return
+	}
 	currency.ID = id
 	if err := h.repo.Update(c.Request.Context(), &currency); err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Currency not found"})
+		apierror.Write(c, apierror.CurrencyNotFound, "Currency not found")
 		return
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update currency"})
+		apierror.Write(c, apierror.Internal, "Failed to update currency")
 		return
 	}
 
@@ -148,36 +197,94 @@ func (h *CurrencyHandler) UpdateCurrency(c *gin.Context) {
 
 // DeleteCurrency godoc
 // @Summary Delete a currency
-// @Description Deletes an existing currency
+// @Description Deletes an existing currency. A currency with spot prices is only deleted with force, which deletes its spot prices, or moves them to the currency reassign_to in one transaction. Spot prices reassign_to already has are deleted. Forced deletions are audited.
 // @Tags currencies
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Currency ID"
+// @Param force query boolean false "Also delete or move the currency's spot prices"
+// @Param reassign_to query string false "Currency ID to move the spot prices to when forced"
+// @Success 200 {object} models.DeletionSummary "Forced deletion, rows affected"
 // @Success 204 "No Content"
-// @Failure 400 {object} models.ErrorResponse "Invalid currency ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 404 {object} models.ErrorResponse "Currency not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid currency ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Currency not found"
+// @Failure 409 {object} apierror.Problem "Currency has spot prices and force is not set"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /currencies/{id} [delete]
 func (h *CurrencyHandler) DeleteCurrency(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid currency ID"})
+		apierror.Write(c, apierror.InvalidRequest, "Invalid currency ID")
+		return
+	}
+
+	if c.Query("force") == "true" {
+		h.forceDelete(c, id)
 		return
 	}
 
 	if err := h.repo.Delete(c.Request.Context(), id); err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Currency not found"})
+		apierror.Write(c, apierror.CurrencyNotFound, "Currency not found")
 		return
 	} else if err == repository.ErrHasAssociatedRecords {
-		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "cannot delete currency that has associated spot prices"})
+		apierror.Write(c, apierror.CurrencyInUse, "cannot delete currency that has associated spot prices")
 		return
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete currency"})
+		apierror.Write(c, apierror.Internal, "Failed to delete currency")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// forceDelete deletes the currency along with its spot prices, or moves them to reassign_to
+func (h *CurrencyHandler) forceDelete(c *gin.Context, id uuid.UUID) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	reassignTo, invalid := reassignTarget(c, id)
+	if invalid != "" {
+		apierror.Write(c, apierror.InvalidRequest, invalid)
+		return
+	}
+
+	currency, err := h.repo.GetByID(c.Request.Context(), id)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.CurrencyNotFound, "Currency not found")
+		return
+	} else if err != nil {
+		apierror.Write(c, apierror.Internal, "Failed to delete currency")
+		return
+	}
+
+	description := fmt.Sprintf("Deleted currency %s and its spot prices", currency.Name)
+	if reassignTo != nil {
+		target, err := h.repo.GetByID(c.Request.Context(), *reassignTo)
+		if err == repository.ErrNotFound {
+			apierror.Write(c, apierror.CurrencyNotFound, "reassign_to currency not found")
+			return
+		} else if err != nil {
+			apierror.Write(c, apierror.Internal, "Failed to delete currency")
+			return
+		}
+		description = fmt.Sprintf("Deleted currency %s and moved its spot prices to %s", currency.Name, target.Name)
+	}
+
+	summary, err := h.repo.DeleteCascade(c.Request.Context(), id, reassignTo)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.CurrencyNotFound, "Currency not found")
+		return
+	} else if err != nil {
+		apierror.Write(c, apierror.Internal, "Failed to delete currency")
+		return
+	}
+
+	logForcedDeletion(c, h.auditRepo, authUser, "currency", id, description, summary)
+	c.JSON(http.StatusOK, summary)
+}
@@ -2,13 +2,17 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"wattwatch/internal/api/handlers"
 	"wattwatch/internal/api/middleware"
 	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
 	"wattwatch/internal/repository/postgres"
 	"wattwatch/internal/testutil"
 
@@ -30,7 +34,7 @@ type currencyTest struct {
 func TestCurrencyHandler_ListCurrencies(t *testing.T) {
 	tc := testutil.NewTestContext(t)
 
-	handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB))
+	handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB), tc.AuditRepo)
 	router := gin.New()
 	router.GET("/currencies", handler.ListCurrencies)
 	router.GET("/currencies/:id", handler.GetCurrency)
@@ -81,7 +85,7 @@ func TestCurrencyHandler_ListCurrencies(t *testing.T) {
 func TestCurrencyHandler_GetCurrency(t *testing.T) {
 	tc := testutil.NewTestContext(t)
 
-	handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB))
+	handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB), tc.AuditRepo)
 	router := gin.New()
 	router.GET("/currencies/:id", handler.GetCurrency)
 
@@ -196,7 +200,7 @@ func TestCurrencyHandler_CreateCurrency(t *testing.T) {
 				token = tt.setupFunc(tc)
 			}
 
-			handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB))
+			handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB), tc.AuditRepo)
 			router := gin.New()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router.Use(authMiddleware.AuthRequired())
@@ -306,7 +310,7 @@ func TestCurrencyHandler_UpdateCurrency(t *testing.T) {
 				token = tt.setupFunc(tc)
 			}
 
-			handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB))
+			handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB), tc.AuditRepo)
 			router := gin.New()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router.Use(authMiddleware.AuthRequired())
@@ -426,7 +430,7 @@ func TestCurrencyHandler_DeleteCurrency(t *testing.T) {
 				}
 			}
 
-			handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB))
+			handler := handlers.NewCurrencyHandler(postgres.NewCurrencyRepository(tc.DB), tc.AuditRepo)
 			router := gin.New()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router.Use(authMiddleware.AuthRequired())
@@ -458,3 +462,96 @@ func TestCurrencyHandler_DeleteCurrency(t *testing.T) {
 		})
 	}
 }
+
+func TestCurrencyHandler_ForceDeleteCurrencyInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewStore()
+	currencyRepo := memory.NewCurrencyRepository(store)
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+
+	zone, err := memory.NewZoneRepository(store).GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	eur, err := currencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	sek, err := currencyRepo.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+	require.NoError(t, spotPriceRepo.Create(ctx, &models.SpotPrice{
+		Timestamp:  time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		ZoneID:     zone.ID,
+		CurrencyID: sek.ID,
+		Price:      10,
+	}))
+
+	handler := handlers.NewCurrencyHandler(currencyRepo, memory.NewAuditLogRepository(store))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &models.User{ID: uuid.New(), Username: "admin"})
+		c.Next()
+	})
+	router.DELETE("/currencies/:id", handler.DeleteCurrency)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("DELETE", "/currencies/"+sek.ID.String()+"?force=true&reassign_to="+eur.ID.String(), nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var summary models.DeletionSummary
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+	assert.Equal(t, int64(1), summary.SpotPricesMoved)
+
+	prices, err := spotPriceRepo.List(ctx, repository.SpotPriceFilter{CurrencyID: &eur.ID})
+	require.NoError(t, err)
+	assert.Len(t, prices, 1)
+}
+
+func TestCurrencyHandler_ISO4217InMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	handler := handlers.NewCurrencyHandler(memory.NewCurrencyRepository(store), memory.NewAuditLogRepository(store))
+	router := gin.New()
+	router.POST("/currencies", handler.CreateCurrency)
+	router.PUT("/currencies/:id", handler.UpdateCurrency)
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The code is matched ignoring case and sets the standard's numeric code and hints
+	w := send("POST", "/currencies", `{"name":"jpy"}`)
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var jpy models.Currency
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &jpy))
+	assert.Equal(t, "JPY", jpy.Name)
+	assert.Equal(t, "392", jpy.NumericCode)
+	assert.Equal(t, "¥", jpy.Symbol)
+	assert.True(t, jpy.SymbolFirst)
+	assert.Equal(t, 0, jpy.MinorUnits)
+
+	// Codes outside the standard are rejected
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/currencies", `{"name":"XYZ"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/currencies/"+jpy.ID.String(), `{"name":"XYZ"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/currencies", `{"name":"NOK","minor_units":9}`).Code)
+
+	// Hints that are set replace the standard ones
+	w = send("PUT", "/currencies/"+jpy.ID.String(), `{"name":"NOK","symbol":"NOK","symbol_first":true}`)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var nok models.Currency
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &nok))
+	assert.Equal(t, jpy.ID, nok.ID)
+	assert.Equal(t, "578", nok.NumericCode)
+	assert.Equal(t, "NOK", nok.Symbol)
+	assert.True(t, nok.SymbolFirst)
+	assert.Equal(t, 2, nok.MinorUnits)
+
+	// The seeded currencies carry the standard's metadata too
+	sek, err := memory.NewCurrencyRepository(store).GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+	assert.Equal(t, "752", sek.NumericCode)
+	assert.Equal(t, "kr", sek.Symbol)
+	assert.False(t, sek.SymbolFirst)
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/quality"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxDataQualityDays limits how many days a single request checks
+const maxDataQualityDays = 31
+
+// DataQualityHandler reports missing and duplicate spot prices
+type DataQualityHandler struct {
+	checker   *quality.Checker
+	auditRepo repository.AuditLogRepository
+}
+
+// NewDataQualityHandler creates a new DataQualityHandler
+func NewDataQualityHandler(checker *quality.Checker, auditRepo repository.AuditLogRepository) *DataQualityHandler {
+	return &DataQualityHandler{
+		checker:   checker,
+		auditRepo: auditRepo,
+	}
+}
+
+// GetDataQuality godoc
+// @Summary Check spot prices for gaps and duplicates
+// @Description Lists the days on which a zone has fewer or more spot prices than the day's length in the zone's timezone calls for, so days clocks change on expect 23 or 25 hourly prices (admin only)
+// @Tags data-quality
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string true "First day checked (YYYY-MM-DD)"
+// @Param end_date query string false "Last day checked (YYYY-MM-DD), defaults to start_date, at most 31 days after it"
+// @Param zone query string false "Zone name, all zones when empty"
+// @Param currency query string false "Currency name, all currencies with prices in the range when empty"
+// @Success 200 {object} models.DataQualityReport
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/data-quality [get]
+func (h *DataQualityHandler) GetDataQuality(c *gin.Context) {
+	opts, ok := h.options(c)
+	if !ok {
+		return
+	}
+	if report, ok := h.check(c, opts); ok {
+		c.JSON(http.StatusOK, report)
+	}
+}
+
+// RefetchDataQuality godoc
+// @Summary Refetch days with missing spot prices
+// @Description Checks spot prices like GET /admin/data-quality and requests the days with missing prices from the first enabled provider supporting the zone and currency. Fetches run in the background, refetched is set on the days requested. (admin only)
+// @Tags data-quality
+// @Produce json
+// @Security BearerAuth
+// @Param start_date query string true "First day checked (YYYY-MM-DD)"
+// @Param end_date query string false "Last day checked (YYYY-MM-DD), defaults to start_date, at most 31 days after it"
+// @Param zone query string false "Zone name, all zones when empty"
+// @Param currency query string false "Currency name, all currencies with prices in the range when empty"
+// @Success 202 {object} models.DataQualityReport
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/data-quality/refetch [post]
+func (h *DataQualityHandler) RefetchDataQuality(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	opts, ok := h.options(c)
+	if !ok {
+		return
+	}
+	opts.Refetch = true
+	report, ok := h.check(c, opts)
+	if !ok {
+		return
+	}
+
+	var refetched []models.DataQualityIssue
+	for _, issue := range report.Issues {
+		if issue.Refetched {
+			refetched = append(refetched, issue)
+		}
+	}
+	if len(refetched) > 0 {
+		metadata, _ := json.Marshal(refetched)
+		if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+			UserID:      &authUser.ID,
+			Action:      models.AuditActionUpdate,
+			EntityType:  "spot_price",
+			EntityID:    report.StartDate,
+			Description: "Refetched spot prices with gaps",
+			Metadata:    string(metadata),
+			IPAddress:   c.ClientIP(),
+			UserAgent:   c.GetHeader("User-Agent"),
+		}); err != nil {
+			log.Printf("Error logging spot price refetch: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusAccepted, report)
+}
+
+// options parses the query parameters, writing an error response when they are invalid
+func (h *DataQualityHandler) options(c *gin.Context) (quality.Options, bool) {
+	startDate := c.Query("start_date")
+	if startDate == "" {
+		apierror.Write(c, apierror.InvalidRequest, "start_date is required")
+		return quality.Options{}, false
+	}
+	from, err := time.Parse("2006-01-02", startDate)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid start_date format, use YYYY-MM-DD")
+		return quality.Options{}, false
+	}
+
+	to, err := time.Parse("2006-01-02", c.DefaultQuery("end_date", startDate))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid end_date format, use YYYY-MM-DD")
+		return quality.Options{}, false
+	}
+	if to.Before(from) {
+		apierror.Write(c, apierror.InvalidRequest, "end_date must not be before start_date")
+		return quality.Options{}, false
+	}
+	if to.After(from.AddDate(0, 0, maxDataQualityDays)) {
+		apierror.Write(c, apierror.InvalidRequest, "date range cannot exceed 31 days")
+		return quality.Options{}, false
+	}
+
+	return quality.Options{
+		From:     from,
+		To:       to,
+		Zone:     c.Query("zone"),
+		Currency: c.Query("currency"),
+	}, true
+}
+
+// check runs the check, writing an error response when it fails
+func (h *DataQualityHandler) check(c *gin.Context, opts quality.Options) (*models.DataQualityReport, bool) {
+	issues, err := h.checker.Check(c.Request.Context(), opts)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.NotFound, "zone or currency not found")
+		return nil, false
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to check spot prices")
+		return nil, false
+	}
+
+	return &models.DataQualityReport{
+		StartDate: opts.From.Format("2006-01-02"),
+		EndDate:   opts.To.Format("2006-01-02"),
+		Issues:    issues,
+	}, true
+}
@@ -0,0 +1,130 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/models"
+	"wattwatch/internal/quality"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type stubRefetcher struct {
+	calls int
+}
+
+func (r *stubRefetcher) Refetch(zone, currency string, date time.Time) error {
+	r.calls++
+	return nil
+}
+
+func TestDataQualityHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+	auditRepo := memory.NewAuditLogRepository(store)
+
+	zone, err := zoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	// October 26, 2025 has 25 hours in Stockholm, store all but the last one
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	require.NoError(t, err)
+	start := time.Date(2025, 10, 26, 0, 0, 0, 0, loc)
+	var prices []models.SpotPrice
+	for i := 0; i < 24; i++ {
+		prices = append(prices, models.SpotPrice{
+			Timestamp:  start.Add(time.Duration(i) * time.Hour),
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      float64(i),
+		})
+	}
+	require.NoError(t, spotPriceRepo.CreateBatch(ctx, prices))
+
+	refetcher := &stubRefetcher{}
+	checker := quality.NewChecker(spotPriceRepo, zoneRepo, currencyRepo, time.Hour)
+	checker.SetRefetcher(refetcher)
+	handler := handlers.NewDataQualityHandler(checker, auditRepo)
+
+	admin := &models.User{ID: uuid.New(), Username: "admin"}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", admin)
+		c.Next()
+	})
+	router.GET("/admin/data-quality", handler.GetDataQuality)
+	router.POST("/admin/data-quality/refetch", handler.RefetchDataQuality)
+
+	send := func(method, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/admin/data-quality"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Report", func(t *testing.T) {
+		w := send("GET", "?start_date=2025-10-25&end_date=2025-10-26&zone=SE3&currency=EUR")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var report models.DataQualityReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		assert.Equal(t, "2025-10-25", report.StartDate)
+		assert.Equal(t, "2025-10-26", report.EndDate)
+		require.Len(t, report.Issues, 2)
+		assert.Equal(t, 24, report.Issues[0].Expected)
+		assert.Zero(t, report.Issues[0].Count)
+		assert.Equal(t, 25, report.Issues[1].Expected)
+		assert.Equal(t, 24, report.Issues[1].Count)
+		assert.Equal(t, []time.Time{time.Date(2025, 10, 26, 22, 0, 0, 0, time.UTC)}, report.Issues[1].Missing)
+		assert.False(t, report.Issues[1].Refetched)
+		assert.Zero(t, refetcher.calls)
+	})
+
+	t.Run("Refetch", func(t *testing.T) {
+		w := send("POST", "/refetch?start_date=2025-10-26&zone=SE3")
+		require.Equal(t, http.StatusAccepted, w.Code)
+
+		var report models.DataQualityReport
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+		require.Len(t, report.Issues, 1)
+		assert.True(t, report.Issues[0].Refetched)
+		assert.Equal(t, 1, refetcher.calls)
+
+		logs, err := auditRepo.List(ctx, repository.AuditLogFilter{UserID: &admin.ID})
+		require.NoError(t, err)
+		assert.Len(t, logs, 1)
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		for _, query := range []string{
+			"",
+			"?start_date=26-10-2025",
+			"?start_date=2025-10-26&end_date=2025-10-25",
+			"?start_date=2025-01-01&end_date=2025-03-01",
+		} {
+			w := send("GET", query)
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		}
+	})
+
+	t.Run("Unknown Zone", func(t *testing.T) {
+		w := send("GET", "?start_date=2025-10-26&zone=XX")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/email"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EmailAdminHandler handles email diagnostics and undelivered email for administrators
+type EmailAdminHandler struct {
+	emailService *email.Service
+	deadLetters  repository.EmailDeadLetterRepository
+	auditRepo    repository.AuditLogRepository
+}
+
+// NewEmailAdminHandler creates a new EmailAdminHandler
+func NewEmailAdminHandler(emailService *email.Service, deadLetters repository.EmailDeadLetterRepository, auditRepo repository.AuditLogRepository) *EmailAdminHandler {
+	return &EmailAdminHandler{
+		emailService: emailService,
+		deadLetters:  deadLetters,
+		auditRepo:    auditRepo,
+	}
+}
+
+// SendTestEmail godoc
+// @Summary Send a test email
+// @Description Sends a test message with the current email configuration so operators can diagnose email setup. With SMTP the SMTP dialogue is returned, credentials and the message body are omitted from the transcript. (admin only)
+// @Tags email
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.SendTestEmailRequest true "Recipient"
+// @Success 200 {object} models.EmailTestResult
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 409 {object} apierror.Problem "Recipient is suppressed"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 502 {object} models.EmailTestResult "Delivery failed"
+// @Failure 503 {object} apierror.Problem "Email not configured"
+// @Router /admin/email/test [post]
+func (h *EmailAdminHandler) SendTestEmail(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.SendTestEmailRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	result, err := h.emailService.SendTestEmail(req.To)
+
+	metadata, _ := json.Marshal(map[string]interface{}{"to": req.To, "success": err == nil})
+	if auditErr := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionCreate,
+		EntityType:  "user",
+		EntityID:    authUser.ID.String(),
+		Description: "Test email sent",
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); auditErr != nil {
+		log.Printf("Error logging test email: %v", auditErr)
+	}
+
+	switch {
+	case err == nil:
+		c.JSON(http.StatusOK, result)
+	case errors.Is(err, email.ErrRecipientSuppressed):
+		apierror.Write(c, apierror.EmailConflict, err.Error())
+	case errors.Is(err, email.ErrNotConfigured):
+		apierror.Write(c, apierror.Unavailable, result.Error)
+	default:
+		c.JSON(http.StatusBadGateway, result)
+	}
+}
+
+// ListDeadLetters godoc
+// @Summary List undelivered emails
+// @Description Lists emails that failed every delivery attempt, newest first. Message bodies are not included. (admin only)
+// @Tags email
+// @Produce json
+// @Security BearerAuth
+// @Param recipient query string false "Search by recipient"
+// @Param kind query string false "Filter by kind (verification, password_reset, email_changed, welcome, weekly_report, alert)"
+// @Param pending query boolean false "Only emails that weren't resent"
+// @Param limit query integer false "Limit results"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the emails in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.EmailDeadLetter]
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/email/dead-letters [get]
+func (h *EmailAdminHandler) ListDeadLetters(c *gin.Context) {
+	filter := repository.EmailDeadLetterFilter{
+		Pending: c.Query("pending") == "true",
+	}
+
+	if recipient := c.Query("recipient"); recipient != "" {
+		filter.Recipient = &recipient
+	}
+
+	if kind := c.Query("kind"); kind != "" {
+		filter.Kind = &kind
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid limit")
+			return
+		}
+		filter.Limit = &limit
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	letters, err := h.deadLetters.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error listing email dead letters: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list dead letters")
+		return
+	}
+
+	respondPage(c, letters, filter.Limit, filter.Offset, func() (int, error) {
+		return h.deadLetters.Total(c.Request.Context(), filter)
+	}, "failed to list dead letters")
+}
+
+// ResendDeadLetter godoc
+// @Summary Resend an undelivered email
+// @Description Sends an email that failed every delivery attempt again with the current email configuration. Links in the email stop working once their token expires. (admin only)
+// @Tags email
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Dead letter ID (UUID)"
+// @Success 200 {object} models.EmailDeadLetter
+// @Failure 400 {object} apierror.Problem "Invalid ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Dead letter not found"
+// @Failure 409 {object} apierror.Problem "Already resent or recipient is suppressed"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Failure 502 {object} apierror.Problem "Delivery failed"
+// @Failure 503 {object} apierror.Problem "Email not configured"
+// @Router /admin/email/dead-letters/{id}/resend [post]
+func (h *EmailAdminHandler) ResendDeadLetter(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid dead letter id")
+		return
+	}
+
+	letter, err := h.deadLetters.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.EmailNotFound, "dead letter not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to get dead letter")
+		return
+	}
+	if letter.ResentAt != nil {
+		apierror.Write(c, apierror.EmailConflict, "dead letter was already resent")
+		return
+	}
+
+	attempts, err := h.emailService.Resend(c.Request.Context(), letter)
+
+	metadata, _ := json.Marshal(map[string]interface{}{"recipient": letter.Recipient, "kind": letter.Kind, "success": err == nil})
+	if auditErr := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "email_dead_letter",
+		EntityID:    letter.ID.String(),
+		Description: "Undelivered email resent",
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); auditErr != nil {
+		log.Printf("Error logging dead letter resend: %v", auditErr)
+	}
+
+	switch {
+	case errors.Is(err, email.ErrRecipientSuppressed):
+		apierror.Write(c, apierror.EmailConflict, err.Error())
+		return
+	case errors.Is(err, email.ErrNotConfigured):
+		apierror.Write(c, apierror.Unavailable, err.Error())
+		return
+	case err != nil:
+		if recordErr := h.deadLetters.RecordFailure(c.Request.Context(), letter.ID, attempts, err.Error()); recordErr != nil {
+			log.Printf("Error recording failed resend of dead letter %s: %v", letter.ID, recordErr)
+		}
+		apierror.Write(c, apierror.UpstreamFailed, err.Error())
+		return
+	}
+
+	if err := h.deadLetters.MarkResent(c.Request.Context(), letter.ID); err != nil {
+		apierror.Write(c, apierror.Internal, "failed to update dead letter")
+		return
+	}
+	letter, err = h.deadLetters.GetByID(c.Request.Context(), letter.ID)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to get dead letter")
+		return
+	}
+	c.JSON(http.StatusOK, letter)
+}
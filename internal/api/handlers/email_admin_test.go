@@ -0,0 +1,153 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/config"
+	"wattwatch/internal/email"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailAdminHandler_SendTestEmail(t *testing.T) {
+	tests := []struct {
+		name       string
+		isAdmin    bool
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "Email Not Configured",
+			isAdmin:    true,
+			body:       `{"to":"admin@test.com"}`,
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "Invalid Address",
+			isAdmin:    true,
+			body:       `{"to":"not-an-address"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Non Admin",
+			isAdmin:    false,
+			body:       `{"to":"admin@test.com"}`,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewTestContext(t)
+			user := tc.CreateTestUser("user", "user@test.com", "password123", tt.isAdmin)
+
+			handler := handlers.NewEmailAdminHandler(email.NewService(config.EmailConfig{}), postgres.NewEmailDeadLetterRepository(tc.DB), tc.AuditRepo)
+			router := gin.New()
+			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+			router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+			router.POST("/admin/email/test", handler.SendTestEmail)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/admin/email/test", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+		})
+	}
+}
+
+func TestEmailAdminHandler_DeadLetters(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	// Mailgun pointed at a server that fails until it is told to accept messages
+	accept := false
+	mailgun := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !accept {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer mailgun.Close()
+
+	deadLetters := memory.NewEmailDeadLetterRepository(memory.NewStore())
+	service := email.NewService(config.EmailConfig{
+		Provider:      config.EmailProviderMailgun,
+		MailgunDomain: "mg.example.com",
+		MailgunAPIKey: "key",
+		MailgunAPIURL: mailgun.URL,
+		MaxAttempts:   2,
+		FromAddress:   "noreply@example.com",
+		AppURL:        "http://localhost:8080",
+	})
+	service.SetDeadLetters(deadLetters)
+	require.Error(t, service.SendWelcomeEmail("jane@example.com", "jane", models.LanguageEnglish))
+
+	handler := handlers.NewEmailAdminHandler(service, deadLetters, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	router.GET("/admin/email/dead-letters", handler.ListDeadLetters)
+	router.POST("/admin/email/dead-letters/:id/resend", handler.ResendDeadLetter)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(admin.ID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	list := func(query string) models.Page[models.EmailDeadLetter] {
+		w := send("GET", "/admin/email/dead-letters"+query)
+		require.Equal(t, http.StatusOK, w.Code)
+		var page models.Page[models.EmailDeadLetter]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+
+	page := list("?pending=true")
+	require.Len(t, page.Items, 1)
+	letter := page.Items[0]
+	assert.Equal(t, email.KindWelcome, letter.Kind)
+	assert.Equal(t, "jane@example.com", letter.Recipient)
+	assert.Equal(t, 2, letter.Attempts)
+	assert.NotContains(t, send("GET", "/admin/email/dead-letters").Body.String(), "Welcome to WattWatch, jane!")
+	assert.Empty(t, list("?kind=password_reset").Items)
+
+	// A failed resend adds its attempts to the dead letter
+	path := "/admin/email/dead-letters/" + letter.ID.String() + "/resend"
+	assert.Equal(t, http.StatusBadGateway, send("POST", path).Code)
+	got, err := deadLetters.GetByID(context.Background(), letter.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 4, got.Attempts)
+
+	accept = true
+	w := send("POST", path)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &letter))
+	assert.NotNil(t, letter.ResentAt)
+	assert.Empty(t, list("?pending=true").Items)
+
+	assert.Equal(t, http.StatusConflict, send("POST", path).Code)
+	assert.Equal(t, http.StatusNotFound, send("POST", "/admin/email/dead-letters/"+uuid.New().String()+"/resend").Code)
+	assert.Equal(t, http.StatusBadRequest, send("POST", "/admin/email/dead-letters/invalid/resend").Code)
+
+	logs, err := tc.AuditRepo.List(context.Background(), repository.AuditLogFilter{EntityTypes: []string{"email_dead_letter"}})
+	require.NoError(t, err)
+	assert.Len(t, logs, 2)
+}
@@ -0,0 +1,331 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/email"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxWebhookBodySize bounds the size of provider callbacks
+const maxWebhookBodySize = 1 << 20
+
+// EmailWebhookHandler receives bounce and complaint callbacks from email providers
+// and manages the resulting suppression list
+type EmailWebhookHandler struct {
+	suppressionRepo repository.EmailSuppressionRepository
+	userRepo        repository.UserRepository
+	auditRepo       repository.AuditLogRepository
+	secret          auth.RotatingSecret
+	client          *http.Client
+}
+
+// NewEmailWebhookHandler creates a new EmailWebhookHandler. Callbacks are rejected when secret is empty.
+func NewEmailWebhookHandler(
+	suppressionRepo repository.EmailSuppressionRepository,
+	userRepo repository.UserRepository,
+	auditRepo repository.AuditLogRepository,
+	secret string,
+) *EmailWebhookHandler {
+	return &EmailWebhookHandler{
+		suppressionRepo: suppressionRepo,
+		userRepo:        userRepo,
+		auditRepo:       auditRepo,
+		secret:          auth.RotatingSecret{Current: secret},
+		client:          &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AcceptPreviousSecret keeps accepting callbacks that send previous after the secret was
+// replaced, until the time until returns, while the provider configuration is updated
+func (h *EmailWebhookHandler) AcceptPreviousSecret(previous string, until func() time.Time) {
+	h.secret.Previous = previous
+	h.secret.Until = until
+}
+
+// HandleSES godoc
+// @Summary Receive SES bounce and complaint notifications
+// @Description Amazon SNS endpoint for SES notifications. Permanent bounces and complaints suppress the address. Subscription confirmations are confirmed automatically.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param token query string true "Webhook secret"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} apierror.Problem "Invalid payload"
+// @Failure 401 {object} apierror.Problem "Invalid token"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Failure 503 {object} apierror.Problem "Webhooks not configured"
+// @Router /webhooks/email/ses [post]
+func (h *EmailWebhookHandler) HandleSES(c *gin.Context) {
+	body, ok := h.readAuthorizedBody(c)
+	if !ok {
+		return
+	}
+
+	events, subscribeURL, err := email.ParseSESNotification(body)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	if subscribeURL != "" {
+		if err := h.confirmSubscription(c, subscribeURL); err != nil {
+			log.Printf("Error confirming SNS subscription: %v", err)
+			apierror.Write(c, apierror.Internal, "failed to confirm subscription")
+			return
+		}
+		c.JSON(http.StatusOK, models.SuccessResponse{Message: "Subscription confirmed"})
+		return
+	}
+
+	h.applyEvents(c, events)
+}
+
+// HandleSendGrid godoc
+// @Summary Receive SendGrid bounce and spam report events
+// @Description SendGrid Event Webhook endpoint. Hard bounces and spam reports suppress the address.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param token query string true "Webhook secret"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} apierror.Problem "Invalid payload"
+// @Failure 401 {object} apierror.Problem "Invalid token"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Failure 503 {object} apierror.Problem "Webhooks not configured"
+// @Router /webhooks/email/sendgrid [post]
+func (h *EmailWebhookHandler) HandleSendGrid(c *gin.Context) {
+	body, ok := h.readAuthorizedBody(c)
+	if !ok {
+		return
+	}
+
+	events, err := email.ParseSendGridEvents(body)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	h.applyEvents(c, events)
+}
+
+// ListSuppressions godoc
+// @Summary List suppressed email addresses
+// @Description Lists addresses that no longer receive email because they bounced or complained (admin only)
+// @Tags email
+// @Produce json
+// @Security BearerAuth
+// @Param search query string false "Search by address"
+// @Param reason query string false "Filter by reason (bounced, complained)"
+// @Param limit query integer false "Limit results"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the suppressions in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.EmailSuppression]
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/email/suppressions [get]
+func (h *EmailWebhookHandler) ListSuppressions(c *gin.Context) {
+	filter := repository.EmailSuppressionFilter{}
+
+	if search := c.Query("search"); search != "" {
+		filter.Search = &search
+	}
+
+	if reason := c.Query("reason"); reason != "" {
+		r := models.EmailSuppressionReason(reason)
+		if r != models.EmailSuppressionBounced && r != models.EmailSuppressionComplained {
+			apierror.Write(c, apierror.InvalidRequest, "invalid reason")
+			return
+		}
+		filter.Reason = &r
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil || limit < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid limit")
+			return
+		}
+		filter.Limit = &limit
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	suppressions, err := h.suppressionRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error listing email suppressions: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list suppressions")
+		return
+	}
+
+	respondPage(c, suppressions, filter.Limit, filter.Offset, func() (int, error) {
+		return h.suppressionRepo.Total(c.Request.Context(), filter)
+	}, "failed to list suppressions")
+}
+
+// DeleteSuppression godoc
+// @Summary Remove an address from the suppression list
+// @Description Allows email to be sent to the address again, e.g. after the user fixed their mailbox (admin only)
+// @Tags email
+// @Produce json
+// @Security BearerAuth
+// @Param email path string true "Email address"
+// @Success 204 "No Content"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Address not suppressed"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/email/suppressions/{email} [delete]
+func (h *EmailWebhookHandler) DeleteSuppression(c *gin.Context) {
+	address := c.Param("email")
+
+	if err := h.suppressionRepo.Delete(c.Request.Context(), address); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.EmailNotFound, "address not suppressed")
+			return
+		}
+		log.Printf("Error deleting email suppression: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to delete suppression")
+		return
+	}
+
+	var actorID *uuid.UUID
+	if authUser := auth.GetUserFromContext(c); authUser != nil {
+		actorID = &authUser.ID
+	}
+	h.auditSuppression(c, actorID, address, models.AuditActionDelete, "Email suppression removed", nil)
+
+	c.Status(http.StatusNoContent)
+}
+
+// readAuthorizedBody checks the shared secret and reads the request body, writing an
+// error response and returning false if either fails
+func (h *EmailWebhookHandler) readAuthorizedBody(c *gin.Context) ([]byte, bool) {
+	if h.secret.Current == "" {
+		apierror.Write(c, apierror.Unavailable, "email webhooks not configured")
+		return nil, false
+	}
+
+	token := c.Query("token")
+	if token == "" {
+		token = c.GetHeader("X-Webhook-Token")
+	}
+	if !h.secret.Matches(token, time.Now()) {
+		apierror.Write(c, apierror.InvalidToken, "invalid webhook token")
+		return nil, false
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBodySize))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "failed to read request body")
+		return nil, false
+	}
+
+	return body, true
+}
+
+// applyEvents suppresses every address in events and responds with the number processed
+func (h *EmailWebhookHandler) applyEvents(c *gin.Context, events []email.SuppressionEvent) {
+	for _, event := range events {
+		if event.Email == "" {
+			continue
+		}
+
+		suppression := &models.EmailSuppression{
+			Email:    event.Email,
+			Reason:   event.Reason,
+			Provider: event.Provider,
+		}
+		if event.Detail != "" {
+			suppression.Detail = &event.Detail
+		}
+
+		if err := h.suppressionRepo.Upsert(c.Request.Context(), suppression); err != nil {
+			log.Printf("Error suppressing %s: %v", event.Email, err)
+			// A non-2xx response makes the provider retry the batch later
+			apierror.Write(c, apierror.Internal, "failed to store suppression")
+			return
+		}
+
+		h.auditSuppression(c, nil, suppression.Email, models.AuditActionCreate,
+			fmt.Sprintf("Email address %s reported by %s", suppression.Reason, suppression.Provider),
+			map[string]string{"reason": string(suppression.Reason), "provider": suppression.Provider})
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: fmt.Sprintf("Processed %d events", len(events))})
+}
+
+// auditSuppression records a suppression change against the user owning the address.
+// Addresses that don't belong to a user are not audited since audit entries reference an entity ID.
+func (h *EmailWebhookHandler) auditSuppression(c *gin.Context, actorID *uuid.UUID, address string, action models.AuditAction, description string, extra map[string]string) {
+	user, err := h.userRepo.GetByEmail(c.Request.Context(), address)
+	if err != nil {
+		if !errors.Is(err, repository.ErrUserNotFound) {
+			log.Printf("Error looking up user for suppressed address: %v", err)
+		}
+		return
+	}
+
+	metadata := map[string]string{"email": address}
+	for k, v := range extra {
+		metadata[k] = v
+	}
+	details, _ := json.Marshal(metadata)
+
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      actorID,
+		Action:      action,
+		EntityType:  "user",
+		EntityID:    user.ID.String(),
+		Description: description,
+		Metadata:    string(details),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging email suppression change: %v", err)
+	}
+}
+
+// confirmSubscription visits the SNS subscribe URL to activate the subscription
+func (h *EmailWebhookHandler) confirmSubscription(c *gin.Context, subscribeURL string) error {
+	req, err := http.NewRequestWithContext(c.Request.Context(), http.MethodGet, subscribeURL, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := h.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("SNS returned status %d", resp.StatusCode)
+	}
+	return nil
+}
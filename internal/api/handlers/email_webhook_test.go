@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailWebhookHandler_HandleSendGrid(t *testing.T) {
+	body := `[{"email":"User@Test.com","event":"bounce","type":"bounce","reason":"550 user unknown"}]`
+
+	tests := []struct {
+		name           string
+		secret         string
+		token          string
+		wantStatus     int
+		wantSuppressed bool
+	}{
+		{
+			name:           "Valid Token",
+			secret:         "s3cret",
+			token:          "s3cret",
+			wantStatus:     http.StatusOK,
+			wantSuppressed: true,
+		},
+		{
+			name:       "Invalid Token",
+			secret:     "s3cret",
+			token:      "wrong",
+			wantStatus: http.StatusUnauthorized,
+		},
+		{
+			name:       "Webhooks Not Configured",
+			wantStatus: http.StatusServiceUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewTestContext(t)
+			user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+			suppressionRepo := postgres.NewEmailSuppressionRepository(tc.DB)
+			handler := handlers.NewEmailWebhookHandler(suppressionRepo, tc.UserRepo, tc.AuditRepo, tt.secret)
+			router := gin.New()
+			router.POST("/webhooks/email/sendgrid", handler.HandleSendGrid)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/webhooks/email/sendgrid?token="+tt.token, bytes.NewBufferString(body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			suppressed, err := suppressionRepo.IsSuppressed(context.Background(), "user@test.com")
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantSuppressed, suppressed)
+
+			got, err := tc.UserRepo.GetByID(context.Background(), user.ID)
+			require.NoError(t, err)
+			if tt.wantSuppressed {
+				assert.Equal(t, "bounced", got.EmailStatus)
+			} else {
+				assert.Equal(t, "deliverable", got.EmailStatus)
+			}
+		})
+	}
+}
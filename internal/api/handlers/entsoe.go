@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"regexp"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// eicCode matches Energy Identification Codes, the area codes ENTSO-E uses
+var eicCode = regexp.MustCompile(`^[0-9A-Z-]{16}$`)
+
+// EntsoeHandler lets administrators map zones to ENTSO-E bidding zones
+type EntsoeHandler struct {
+	areas     repository.EntsoeAreaRepository
+	zones     repository.ZoneRepository
+	auditRepo repository.AuditLogRepository
+}
+
+// NewEntsoeHandler creates a new EntsoeHandler
+func NewEntsoeHandler(areas repository.EntsoeAreaRepository, zones repository.ZoneRepository, auditRepo repository.AuditLogRepository) *EntsoeHandler {
+	return &EntsoeHandler{
+		areas:     areas,
+		zones:     zones,
+		auditRepo: auditRepo,
+	}
+}
+
+// ListAreas godoc
+// @Summary List ENTSO-E area mappings
+// @Description Returns the zones the ENTSO-E provider fetches prices for, with the EIC code of their bidding zone (admin only)
+// @Tags providers
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.EntsoeArea
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/providers/entsoe/areas [get]
+func (h *EntsoeHandler) ListAreas(c *gin.Context) {
+	areas, err := h.areas.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing ENTSO-E areas: %v", err)
+		apierror.Write(c, apierror.Internal, "Failed to list areas")
+		return
+	}
+	c.JSON(http.StatusOK, areas)
+}
+
+// SetArea godoc
+// @Summary Map a zone to an ENTSO-E area
+// @Description Sets the EIC code of the bidding zone the ENTSO-E provider fetches the zone's prices for, replacing any previous mapping. Without area_code the zone's own EIC code is used. (admin only)
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Zone ID"
+// @Param request body models.SetEntsoeAreaRequest true "EIC code of the bidding zone"
+// @Success 200 {object} models.EntsoeArea
+// @Failure 400 {object} apierror.Problem "Invalid zone ID or area code, or no area code for a zone without an EIC code"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Zone not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/providers/entsoe/areas/{id} [put]
+func (h *EntsoeHandler) SetArea(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "Invalid zone ID")
+		return
+	}
+
+	var req models.SetEntsoeAreaRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.AreaCode == "" {
+		zone, err := h.zones.GetByID(c.Request.Context(), id)
+		if err == repository.ErrNotFound {
+			apierror.Write(c, apierror.ZoneNotFound, "Zone not found")
+			return
+		} else if err != nil {
+			log.Printf("Error fetching zone %s: %v", id, err)
+			apierror.Write(c, apierror.Internal, "Failed to set area")
+			return
+		}
+		if zone.EICCode == nil {
+			apierror.Write(c, apierror.InvalidRequest, "area_code is required, the zone has no EIC code")
+			return
+		}
+		req.AreaCode = *zone.EICCode
+	}
+	if !eicCode.MatchString(req.AreaCode) {
+		apierror.Write(c, apierror.InvalidRequest, "area_code must be an EIC code of 16 upper case letters, digits or dashes")
+		return
+	}
+
+	area := models.EntsoeArea{ZoneID: id, AreaCode: req.AreaCode}
+	if err := h.areas.Upsert(c.Request.Context(), &area); err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ZoneNotFound, "Zone not found")
+		return
+	} else if err != nil {
+		log.Printf("Error setting ENTSO-E area of zone %s: %v", id, err)
+		apierror.Write(c, apierror.Internal, "Failed to set area")
+		return
+	}
+
+	h.audit(c, authUser, models.AuditActionUpdate, id, "ENTSO-E area of zone "+area.ZoneName+" set to "+area.AreaCode, map[string]string{
+		"zone":      area.ZoneName,
+		"area_code": area.AreaCode,
+	})
+	c.JSON(http.StatusOK, area)
+}
+
+// DeleteArea godoc
+// @Summary Remove an ENTSO-E area mapping
+// @Description Stops the ENTSO-E provider from fetching prices for the zone (admin only)
+// @Tags providers
+// @Security BearerAuth
+// @Param id path string true "Zone ID"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid zone ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Zone has no area mapping"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/providers/entsoe/areas/{id} [delete]
+func (h *EntsoeHandler) DeleteArea(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "Invalid zone ID")
+		return
+	}
+
+	if err := h.areas.Delete(c.Request.Context(), id); err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ProviderNotFound, "Zone has no area mapping")
+		return
+	} else if err != nil {
+		log.Printf("Error removing ENTSO-E area of zone %s: %v", id, err)
+		apierror.Write(c, apierror.Internal, "Failed to remove area")
+		return
+	}
+
+	h.audit(c, authUser, models.AuditActionDelete, id, "ENTSO-E area mapping removed", nil)
+	c.Status(http.StatusNoContent)
+}
+
+func (h *EntsoeHandler) audit(c *gin.Context, authUser *models.User, action models.AuditAction, zoneID uuid.UUID, description string, metadata map[string]string) {
+	data, _ := json.Marshal(metadata)
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      action,
+		EntityType:  "zone",
+		EntityID:    zoneID.String(),
+		Description: description,
+		Metadata:    string(data),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging ENTSO-E area change: %v", err)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntsoeHandler(t *testing.T) {
+	ctx := context.Background()
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	zone, err := tc.ZoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+
+	handler := handlers.NewEntsoeHandler(tc.EntsoeAreaRepo, tc.ZoneRepo, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	admins := router.Group("/admin", authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	admins.GET("/providers/entsoe/areas", handler.ListAreas)
+	admins.PUT("/providers/entsoe/areas/:id", handler.SetArea)
+	admins.DELETE("/providers/entsoe/areas/:id", handler.DeleteArea)
+
+	send := func(method, path, body string, userID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	path := "/admin/providers/entsoe/areas/" + zone.ID.String()
+
+	t.Run("Set Area", func(t *testing.T) {
+		w := send("PUT", path, `{"area_code":"10Y1001A1001A46L"}`, admin.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var area models.EntsoeArea
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &area))
+		assert.Equal(t, "SE3", area.ZoneName)
+		assert.Equal(t, "10Y1001A1001A46L", area.AreaCode)
+
+		w = send("GET", "/admin/providers/entsoe/areas", "", admin.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		var areas []models.EntsoeArea
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &areas))
+		require.Len(t, areas, 1)
+		assert.Equal(t, zone.ID, areas[0].ZoneID)
+
+		logs, err := tc.AuditRepo.List(ctx, repository.AuditLogFilter{UserID: &admin.ID})
+		require.NoError(t, err)
+		require.NotEmpty(t, logs)
+		assert.Equal(t, zone.ID.String(), logs[0].EntityID)
+	})
+
+	t.Run("Rejects Invalid Requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("PUT", path, `{"area_code":"10y1001a1001a46l"}`, admin.ID).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", path, `{"area_code":"SE3"}`, admin.ID).Code)
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/admin/providers/entsoe/areas/SE3", `{"area_code":"10Y1001A1001A46L"}`, admin.ID).Code)
+		assert.Equal(t, http.StatusNotFound, send("PUT", "/admin/providers/entsoe/areas/"+uuid.New().String(), `{"area_code":"10Y1001A1001A46L"}`, admin.ID).Code)
+		assert.Equal(t, http.StatusForbidden, send("PUT", path, `{"area_code":"10Y1001A1001A46L"}`, user.ID).Code)
+	})
+
+	t.Run("Defaults To Zone EIC Code", func(t *testing.T) {
+		se4, err := tc.ZoneRepo.GetByName(ctx, "SE4")
+		require.NoError(t, err)
+		se4Path := "/admin/providers/entsoe/areas/" + se4.ID.String()
+
+		w := send("PUT", se4Path, `{}`, admin.ID)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var area models.EntsoeArea
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &area))
+		assert.Equal(t, "10Y1001A1001A47J", area.AreaCode)
+		assert.Equal(t, http.StatusNoContent, send("DELETE", se4Path, "", admin.ID).Code)
+
+		zone := &models.Zone{Name: "NO1", Timezone: "Europe/Oslo"}
+		require.NoError(t, tc.ZoneRepo.Create(ctx, zone))
+		assert.Equal(t, http.StatusBadRequest, send("PUT", "/admin/providers/entsoe/areas/"+zone.ID.String(), `{}`, admin.ID).Code)
+		assert.Equal(t, http.StatusNotFound, send("PUT", "/admin/providers/entsoe/areas/"+uuid.New().String(), `{}`, admin.ID).Code)
+	})
+
+	t.Run("Delete Area", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("DELETE", path, "", admin.ID).Code)
+		assert.Equal(t, http.StatusNotFound, send("DELETE", path, "", admin.ID).Code)
+
+		areas, err := tc.EntsoeAreaRepo.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, areas)
+	})
+}
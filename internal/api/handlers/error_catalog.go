@@ -0,0 +1,37 @@
+package handlers
+
+import (
+	"net/http"
+	"wattwatch/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListErrorCodes godoc
+// @Summary List error codes
+// @Description Returns the catalog of codes error responses carry, with their status and title
+// @Tags docs
+// @Produce json
+// @Success 200 {array} apierror.Entry
+// @Router /errors [get]
+func ListErrorCodes(c *gin.Context) {
+	c.JSON(http.StatusOK, apierror.Catalog())
+}
+
+// GetErrorCode godoc
+// @Summary Get error code
+// @Description Returns the catalog entry of an error code, which is what the type of a problem response refers to
+// @Tags docs
+// @Produce json
+// @Param code path string true "Error code" example(ZONE404)
+// @Success 200 {object} apierror.Entry
+// @Failure 404 {object} apierror.Problem "Unknown error code"
+// @Router /errors/{code} [get]
+func GetErrorCode(c *gin.Context) {
+	entry, ok := apierror.Lookup(apierror.Code(c.Param("code")))
+	if !ok {
+		apierror.Write(c, apierror.NotFound, "unknown error code")
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
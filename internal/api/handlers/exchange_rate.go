@@ -0,0 +1,222 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ExchangeRateHandler lets administrators manage the exchange rates spot prices are
+// converted with
+type ExchangeRateHandler struct {
+	repo         repository.ExchangeRateRepository
+	currencyRepo repository.CurrencyRepository
+	auditRepo    repository.AuditLogRepository
+	limits       ListLimits
+}
+
+// NewExchangeRateHandler creates a new ExchangeRateHandler
+func NewExchangeRateHandler(
+	repo repository.ExchangeRateRepository,
+	currencyRepo repository.CurrencyRepository,
+	auditRepo repository.AuditLogRepository,
+) *ExchangeRateHandler {
+	return &ExchangeRateHandler{
+		repo:         repo,
+		currencyRepo: currencyRepo,
+		auditRepo:    auditRepo,
+		limits:       DefaultListLimits,
+	}
+}
+
+// SetListLimits sets the default and maximum number of exchange rates listed
+func (h *ExchangeRateHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// ListExchangeRates godoc
+// @Summary List exchange rates
+// @Description Returns the stored exchange rates, most recent first (admin only)
+// @Tags exchange-rates
+// @Produce json
+// @Security BearerAuth
+// @Param base query string false "Base currency name (e.g., 'EUR')"
+// @Param quote query string false "Quote currency name (e.g., 'SEK')"
+// @Param start_time query string false "Start time (RFC3339)"
+// @Param end_time query string false "End time (RFC3339)"
+// @Param limit query integer false "Limit results (default 50, maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the exchange rates in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.ExchangeRate]
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/exchange-rates [get]
+func (h *ExchangeRateHandler) ListExchangeRates(c *gin.Context) {
+	filter := repository.ExchangeRateFilter{}
+
+	if base := c.Query("base"); base != "" {
+		currency, err := h.currencyRepo.GetByName(c.Request.Context(), base)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.CurrencyNotFound, "base currency not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch currency")
+			return
+		}
+		filter.BaseCurrencyID = &currency.ID
+	}
+
+	if quote := c.Query("quote"); quote != "" {
+		currency, err := h.currencyRepo.GetByName(c.Request.Context(), quote)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.CurrencyNotFound, "quote currency not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch currency")
+			return
+		}
+		filter.QuoteCurrencyID = &currency.ID
+	}
+
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid start time format, use RFC3339")
+			return
+		}
+		filter.StartTime = &startTime
+	}
+
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid end time format, use RFC3339")
+			return
+		}
+		filter.EndTime = &endTime
+	}
+
+	limit, err := h.limits.limit(c, h.limits.Default)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	filter.Limit = &limit
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	rates, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch exchange rates")
+		return
+	}
+
+	respondPage(c, rates, filter.Limit, filter.Offset, func() (int, error) {
+		return h.repo.Total(c.Request.Context(), filter)
+	}, "failed to fetch exchange rates")
+}
+
+// CreateExchangeRates godoc
+// @Summary Store exchange rates
+// @Description Stores exchange rates, each taking effect at its timestamp until the next rate of the pair. Rates the pair already has at a timestamp are replaced. (admin only)
+// @Tags exchange-rates
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateExchangeRatesRequest true "Exchange rates"
+// @Success 201 {array} models.ExchangeRate
+// @Failure 400 {object} apierror.Problem "Invalid request format or duplicate rates in the batch"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/exchange-rates [post]
+func (h *ExchangeRateHandler) CreateExchangeRates(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreateExchangeRatesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// One statement can't update a row twice, so a batch holds each rate once
+	type rateKey struct {
+		base, quote string
+		timestamp   int64
+	}
+	seen := make(map[rateKey]bool, len(req.Rates))
+	rates := make([]models.ExchangeRate, len(req.Rates))
+	for i, r := range req.Rates {
+		if r.BaseCurrencyID == r.QuoteCurrencyID {
+			apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("rate at index %d converts a currency to itself", i))
+			return
+		}
+		key := rateKey{r.BaseCurrencyID.String(), r.QuoteCurrencyID.String(), r.Timestamp.UnixNano()}
+		if seen[key] {
+			apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("duplicate rate at index %d", i))
+			return
+		}
+		seen[key] = true
+		rates[i] = models.ExchangeRate{
+			BaseCurrencyID:  r.BaseCurrencyID,
+			QuoteCurrencyID: r.QuoteCurrencyID,
+			Timestamp:       r.Timestamp,
+			Rate:            r.Rate,
+			Source:          models.ExchangeRateSourceManual,
+		}
+	}
+
+	if err := h.repo.CreateBatch(c.Request.Context(), rates); errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+		return
+	} else if err != nil {
+		log.Printf("Error storing exchange rates: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to store exchange rates")
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]any{"count": len(rates)})
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionCreate,
+		EntityType:  "exchange_rate",
+		EntityID:    "batch",
+		Description: fmt.Sprintf("Stored %d exchange rates", len(rates)),
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging exchange rate creation: %v", err)
+	}
+
+	c.JSON(http.StatusCreated, rates)
+}
@@ -0,0 +1,110 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeRateHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	store := memory.NewStore()
+	currencyRepo := memory.NewCurrencyRepository(store)
+	eur, err := currencyRepo.GetByName(context.Background(), "EUR")
+	require.NoError(t, err)
+	sek, err := currencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+
+	handler := handlers.NewExchangeRateHandler(memory.NewExchangeRateRepository(store), currencyRepo, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	rates := router.Group("/admin/exchange-rates", authMiddleware.AuthRequired())
+	rates.GET("", handler.ListExchangeRates)
+	rates.POST("", handler.CreateExchangeRates)
+
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(admin.ID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	day := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Create", func(t *testing.T) {
+		w := send("POST", "/admin/exchange-rates", models.CreateExchangeRatesRequest{Rates: []models.CreateExchangeRateRequest{
+			{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day, Rate: 11},
+			{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day.AddDate(0, 0, 1), Rate: 11.5},
+		}})
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var created []models.ExchangeRate
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		require.Len(t, created, 2)
+		assert.Equal(t, models.ExchangeRateSourceManual, created[0].Source)
+		assert.False(t, created[0].CreatedAt.IsZero())
+
+		logs, err := tc.AuditRepo.List(context.Background(), repository.AuditLogFilter{EntityTypes: []string{"exchange_rate"}})
+		require.NoError(t, err)
+		assert.Len(t, logs, 1)
+	})
+
+	t.Run("List", func(t *testing.T) {
+		w := send("GET", "/admin/exchange-rates?base=EUR&limit=1", nil)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var page models.Page[models.ExchangeRate]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, 2, page.Total)
+		assert.Equal(t, 11.5, page.Items[0].Rate)
+	})
+
+	t.Run("Invalid Requests", func(t *testing.T) {
+		for name, rate := range map[string]models.CreateExchangeRateRequest{
+			"Zero Rate":      {BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day},
+			"Negative Rate":  {BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day, Rate: -1},
+			"Same Currency":  {BaseCurrencyID: eur.ID, QuoteCurrencyID: eur.ID, Timestamp: day, Rate: 1},
+			"Missing Quote":  {BaseCurrencyID: eur.ID, Timestamp: day, Rate: 1},
+			"Missing Moment": {BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Rate: 1},
+		} {
+			t.Run(name, func(t *testing.T) {
+				w := send("POST", "/admin/exchange-rates", models.CreateExchangeRatesRequest{Rates: []models.CreateExchangeRateRequest{rate}})
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			})
+		}
+
+		rate := models.CreateExchangeRateRequest{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day, Rate: 1}
+		w := send("POST", "/admin/exchange-rates", models.CreateExchangeRatesRequest{Rates: []models.CreateExchangeRateRequest{rate, rate}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unknown Currency", func(t *testing.T) {
+		w := send("POST", "/admin/exchange-rates", models.CreateExchangeRatesRequest{Rates: []models.CreateExchangeRateRequest{
+			{BaseCurrencyID: eur.ID, QuoteCurrencyID: uuid.New(), Timestamp: day, Rate: 1},
+		}})
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
@@ -1,22 +1,47 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
+	"sync"
 	"time"
+	"wattwatch/internal/apierror"
 	"wattwatch/internal/models"
+	"wattwatch/internal/selfcheck"
 
 	"github.com/gin-gonic/gin"
 )
 
+// readinessCacheTTL is how long a readiness report is reused, so frequent probes from
+// several sources don't hit the database and the providers on every request
+const readinessCacheTTL = 5 * time.Second
+
 type HealthHandler struct {
 	db *sql.DB
+
+	readiness    func() []selfcheck.Check
+	checkTimeout time.Duration
+
+	mu          sync.Mutex
+	lastReport  *models.ReadinessResponse
+	lastChecked time.Time
 }
 
 func NewHealthHandler(db *sql.DB) *HealthHandler {
 	return &HealthHandler{db: db}
 }
 
+// SetReadinessChecks sets the checks run by the readiness probe, each bounded by timeout.
+// The checks are built on every run so they can follow configuration changes.
+func (h *HealthHandler) SetReadinessChecks(checks func() []selfcheck.Check, timeout time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.readiness = checks
+	h.checkTimeout = timeout
+	h.lastReport = nil
+}
+
 // Health godoc
 // @Summary Health check
 // @Description Returns the health status of the API and its dependencies
@@ -24,12 +49,12 @@ func NewHealthHandler(db *sql.DB) *HealthHandler {
 // @Accept json
 // @Produce json
 // @Success 200 {object} models.HealthResponse
-// @Failure 503 {object} models.ErrorResponse "Service unavailable"
+// @Failure 503 {object} apierror.Problem "Service unavailable"
 // @Router /health [get]
 func (h *HealthHandler) Health(c *gin.Context) {
 	// Check database connection
 	if err := h.db.Ping(); err != nil {
-		c.JSON(http.StatusServiceUnavailable, models.ErrorResponse{Error: "database connection failed"})
+		apierror.Write(c, apierror.Unavailable, "database connection failed")
 		return
 	}
 
@@ -38,3 +63,84 @@ func (h *HealthHandler) Health(c *gin.Context) {
 		Time:   time.Now().UTC(),
 	})
 }
+
+// Ping godoc
+// @Summary Liveness probe
+// @Description Responds without checking any dependencies, for container healthchecks and load balancer probes
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string "pong"
+// @Router /ping [get]
+func (h *HealthHandler) Ping(c *gin.Context) {
+	c.String(http.StatusOK, "pong")
+}
+
+// Live godoc
+// @Summary Liveness probe
+// @Description Reports that the process is running without checking any dependencies, for Kubernetes liveness probes
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.HealthResponse
+// @Router /healthz [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{
+		Status: "ok",
+		Time:   time.Now().UTC(),
+	})
+}
+
+// Ready godoc
+// @Summary Readiness probe
+// @Description Checks the database, pending migrations, email backend and price providers, for Kubernetes readiness probes. Only failing critical dependencies make the server not ready, the others are reported with a warning. Results are reused for a few seconds.
+// @Tags health
+// @Produce json
+// @Success 200 {object} models.ReadinessResponse
+// @Failure 503 {object} models.ReadinessResponse "A critical dependency failed"
+// @Router /readyz [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.readinessReport(c.Request.Context())
+	status := http.StatusOK
+	if report.Status != "ready" {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// readinessReport runs the readiness checks, or returns the last report while it is recent
+func (h *HealthHandler) readinessReport(ctx context.Context) *models.ReadinessResponse {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	now := time.Now().UTC()
+	if h.lastReport != nil && now.Sub(h.lastChecked) < readinessCacheTTL {
+		return h.lastReport
+	}
+
+	var checks []selfcheck.Check
+	if h.readiness != nil {
+		checks = h.readiness()
+	}
+	// The report is shared with other probes, so it must not fail because this client went away
+	report := selfcheck.Run(context.WithoutCancel(ctx), checks, h.checkTimeout)
+
+	response := &models.ReadinessResponse{
+		Status: "ready",
+		Time:   now,
+		Checks: make([]models.DependencyStatus, len(report.Results)),
+	}
+	for i, result := range report.Results {
+		response.Checks[i] = models.DependencyStatus{
+			Name:     result.Name,
+			Status:   string(result.Status),
+			Critical: checks[i].Critical,
+			Detail:   result.Detail,
+		}
+	}
+	if len(report.Failed()) > 0 {
+		response.Status = "not_ready"
+	}
+
+	h.lastReport = response
+	h.lastChecked = now
+	return response
+}
@@ -1,18 +1,23 @@
 package handlers_test
 
 import (
+	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"wattwatch/internal/api/handlers"
 	"wattwatch/internal/models"
+	"wattwatch/internal/selfcheck"
 	"wattwatch/internal/testutil"
 
 	"github.com/gin-gonic/gin"
-	_ "github.com/lib/pq"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -42,7 +47,7 @@ func TestHealthHandler_Health(t *testing.T) {
 					tc.Config.Database.DBName,
 					tc.Config.Database.SSLMode,
 				)
-				db, err := sql.Open("postgres", connStr)
+				db, err := sql.Open("pgx", connStr)
 				require.NoError(t, err)
 				return db
 			},
@@ -92,3 +97,101 @@ func TestHealthHandler_Health(t *testing.T) {
 		})
 	}
 }
+
+func TestHealthHandler_Ping(t *testing.T) {
+	// Ping must not touch the database, so it works without one
+	handler := handlers.NewHealthHandler(nil)
+	router := gin.New()
+	router.GET("/ping", handler.Ping)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/ping", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Equal(t, "pong", w.Body.String())
+}
+
+func TestHealthHandler_Live(t *testing.T) {
+	handler := handlers.NewHealthHandler(nil)
+	router := gin.New()
+	router.GET("/healthz", handler.Live)
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/healthz", nil)
+	router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusOK, w.Code)
+	var resp models.HealthResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(t, "ok", resp.Status)
+}
+
+func TestHealthHandler_Ready(t *testing.T) {
+	check := func(name string, critical bool, err error) selfcheck.Check {
+		return selfcheck.Check{Name: name, Critical: critical, Run: func(ctx context.Context) (string, error) {
+			return "checked", err
+		}}
+	}
+	ready := func(checks ...selfcheck.Check) (int, models.ReadinessResponse) {
+		handler := handlers.NewHealthHandler(nil)
+		handler.SetReadinessChecks(func() []selfcheck.Check { return checks }, time.Second)
+		router := gin.New()
+		router.GET("/readyz", handler.Ready)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/readyz", nil)
+		router.ServeHTTP(w, req)
+
+		var resp models.ReadinessResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return w.Code, resp
+	}
+
+	t.Run("Ready With Warnings", func(t *testing.T) {
+		code, resp := ready(
+			check("database", true, nil),
+			check("email", false, selfcheck.ErrSkipped),
+			check("providers", false, errors.New("nordpool: timeout")),
+		)
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "ready", resp.Status)
+		assert.Equal(t, []models.DependencyStatus{
+			{Name: "database", Status: "ok", Critical: true, Detail: "checked"},
+			{Name: "email", Status: "skip", Detail: "checked"},
+			{Name: "providers", Status: "warn", Detail: "nordpool: timeout"},
+		}, resp.Checks)
+	})
+
+	t.Run("Critical Failure", func(t *testing.T) {
+		code, resp := ready(
+			check("database", true, nil),
+			check("migrations", true, errors.New("at version 18, 19 is available")),
+		)
+		assert.Equal(t, http.StatusServiceUnavailable, code)
+		assert.Equal(t, "not_ready", resp.Status)
+		assert.Equal(t, "fail", resp.Checks[1].Status)
+		assert.Equal(t, "at version 18, 19 is available", resp.Checks[1].Detail)
+	})
+
+	t.Run("Reuses Recent Report", func(t *testing.T) {
+		runs := 0
+		handler := handlers.NewHealthHandler(nil)
+		handler.SetReadinessChecks(func() []selfcheck.Check {
+			return []selfcheck.Check{{Name: "database", Critical: true, Run: func(ctx context.Context) (string, error) {
+				runs++
+				return "", nil
+			}}}
+		}, time.Second)
+		router := gin.New()
+		router.GET("/readyz", handler.Ready)
+
+		for range 3 {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("GET", "/readyz", nil)
+			router.ServeHTTP(w, req)
+			require.Equal(t, http.StatusOK, w.Code)
+		}
+		assert.Equal(t, 1, runs)
+	})
+}
@@ -0,0 +1,436 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// defaultSensorHours is how many hours of prices a Home Assistant sensor lists by default
+	defaultSensorHours = 24
+	// maxSensorHours is the most hours of prices a Home Assistant sensor lists
+	maxSensorHours = 48
+)
+
+// HomeAssistantHandler serves Home Assistant's REST sensor, authenticated with the
+// integration tokens users issue through it
+type HomeAssistantHandler struct {
+	tokenRepo     repository.IntegrationTokenRepository
+	spotPriceRepo repository.SpotPriceRepository
+	zoneRepo      repository.ZoneRepository
+	currencyRepo  repository.CurrencyRepository
+	auditRepo     repository.AuditLogRepository
+}
+
+// NewHomeAssistantHandler creates a new HomeAssistantHandler
+func NewHomeAssistantHandler(tokenRepo repository.IntegrationTokenRepository, spotPriceRepo repository.SpotPriceRepository, zoneRepo repository.ZoneRepository, currencyRepo repository.CurrencyRepository, auditRepo repository.AuditLogRepository) *HomeAssistantHandler {
+	return &HomeAssistantHandler{
+		tokenRepo:     tokenRepo,
+		spotPriceRepo: spotPriceRepo,
+		zoneRepo:      zoneRepo,
+		currencyRepo:  currencyRepo,
+		auditRepo:     auditRepo,
+	}
+}
+
+// Discover godoc
+// @Summary Describe the Home Assistant integration
+// @Description Returns what's needed to add a REST sensor to Home Assistant: the sensor URL and its parameters, where to issue a token, the zones and currencies available and an example for configuration.yaml. The example uses the zone and currency given, or the first ones available.
+// @Tags integrations
+// @Produce json
+// @Param zone query string false "Zone name used in the example (e.g., 'SE3')"
+// @Param currency query string false "Currency name used in the example (e.g., 'SEK')"
+// @Success 200 {object} models.HomeAssistantDiscovery
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /integrations/homeassistant [get]
+func (h *HomeAssistantHandler) Discover(c *gin.Context) {
+	zones, err := h.zoneRepo.List(c.Request.Context(), repository.ZoneFilter{OrderBy: "name"})
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch zones")
+		return
+	}
+	currencies, err := h.currencyRepo.List(c.Request.Context())
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch currencies")
+		return
+	}
+
+	base := requestBaseURL(c) + "/api/v1/integrations/homeassistant"
+	discovery := models.HomeAssistantDiscovery{
+		SensorURL:      base + "/sensor",
+		TokenURL:       base + "/tokens",
+		Authentication: "Authorization: Bearer <integration token>",
+		Parameters: map[string]string{
+			"zone":     "Zone name, required",
+			"currency": "Currency name, required",
+			"hours":    fmt.Sprintf("Hours of upcoming prices in next_hours, 1 to %d (default %d)", maxSensorHours, defaultSensorHours),
+		},
+		Zones:      make([]string, 0, len(zones)),
+		Currencies: make([]string, 0, len(currencies)),
+	}
+	for _, zone := range zones {
+		discovery.Zones = append(discovery.Zones, zone.Name)
+	}
+	for _, currency := range currencies {
+		discovery.Currencies = append(discovery.Currencies, currency.Name)
+	}
+
+	zone, currency := c.Query("zone"), c.Query("currency")
+	if zone == "" && len(discovery.Zones) > 0 {
+		zone = discovery.Zones[0]
+	}
+	if currency == "" && len(discovery.Currencies) > 0 {
+		currency = discovery.Currencies[0]
+	}
+	discovery.Configuration = homeAssistantConfiguration(discovery.SensorURL, zone, currency)
+
+	c.JSON(http.StatusOK, discovery)
+}
+
+// homeAssistantConfiguration returns an example REST sensor for configuration.yaml
+func homeAssistantConfiguration(sensorURL, zone, currency string) string {
+	query := url.Values{"zone": {zone}, "currency": {currency}}
+	id := strings.ToLower("wattwatch_" + zone + "_" + currency)
+	return fmt.Sprintf(`rest:
+  - resource: %s?%s
+    headers:
+      Authorization: Bearer <integration token>
+    scan_interval: 300
+    sensor:
+      - name: "WattWatch %s price"
+        unique_id: %s_price
+        value_template: "{{ value_json.price }}"
+        unit_of_measurement: "%s/kWh"
+        device_class: monetary
+        json_attributes:
+          - start
+          - end
+          - today_min
+          - today_max
+          - today_avg
+          - next_hours
+`, sensorURL, query.Encode(), zone, id, currency)
+}
+
+// requestBaseURL returns the scheme and host the request was made to, honoring a proxy's
+// X-Forwarded-Proto
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// GetSensor godoc
+// @Summary Get the Home Assistant sensor state
+// @Description Returns the current price with today's minimum, maximum and average and the upcoming prices as flat JSON for Home Assistant's REST sensor. Prices are in the currency per kWh. Authenticate with an integration token, or an access token.
+// @Tags integrations
+// @Produce json
+// @Security BearerAuth
+// @Param zone query string true "Zone name (e.g., 'SE3')"
+// @Param currency query string true "Currency name (e.g., 'SEK')"
+// @Param hours query integer false "Hours of upcoming prices, 1 to 48 (default 24)"
+// @Success 200 {object} models.HomeAssistantSensor
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /integrations/homeassistant/sensor [get]
+func (h *HomeAssistantHandler) GetSensor(c *gin.Context) {
+	zoneName := c.Query("zone")
+	if zoneName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "zone is required")
+		return
+	}
+	currencyName := c.Query("currency")
+	if currencyName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "currency is required")
+		return
+	}
+	hours := defaultSensorHours
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		var err error
+		if hours, err = strconv.Atoi(hoursStr); err != nil || hours < 1 || hours > maxSensorHours {
+			apierror.Write(c, apierror.InvalidRequest, "invalid hours, use 1 to 48")
+			return
+		}
+	}
+
+	zone, err := h.zoneRepo.GetByName(c.Request.Context(), zoneName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch zone")
+		return
+	}
+	loc, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "invalid zone timezone")
+		return
+	}
+
+	currency, err := h.currencyRepo.GetByName(c.Request.Context(), currencyName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch currency")
+		return
+	}
+
+	// Today's prices are needed for its statistics, the hours after now for the next prices
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	end := sensorEnd(now, hours)
+	prices, err := h.spotPriceRepo.List(c.Request.Context(), repository.SpotPriceFilter{
+		ZoneID:     &zone.ID,
+		CurrencyID: &currency.ID,
+		StartTime:  &today,
+		EndTime:    &end,
+		OrderBy:    "timestamp",
+	})
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch spot prices")
+		return
+	}
+
+	c.JSON(http.StatusOK, homeAssistantSensor(zone.Name, currency.Name, prices, now, hours))
+}
+
+// sensorEnd returns the end of the prices a sensor at now needs, the end of today or of
+// the next hours, whichever is later
+func sensorEnd(now time.Time, hours int) time.Time {
+	end := now.Add(time.Duration(hours) * time.Hour)
+	if dayEnd := time.Date(now.Year(), now.Month(), now.Day()+1, 0, 0, 0, 0, now.Location()); end.Before(dayEnd) {
+		return dayEnd
+	}
+	return end
+}
+
+// homeAssistantSensor returns the sensor state at now from prices ordered by timestamp,
+// starting today, listing the next hours of prices
+func homeAssistantSensor(zone, currency string, prices []models.SpotPrice, now time.Time, hours int) models.HomeAssistantSensor {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	tomorrow := today.AddDate(0, 0, 1)
+	summary := summarizeSpotPrices(zone, currency, prices, now, tomorrow, sensorEnd(now, hours), 0)
+
+	sensor := models.HomeAssistantSensor{
+		Unit:      currency + "/kWh",
+		Currency:  currency,
+		Zone:      zone,
+		NextHours: []models.HomeAssistantPrice{},
+		UpdatedAt: now,
+	}
+	if summary.Today != nil {
+		sensor.TodayMin = pricePerKWh(summary.Today.Min)
+		sensor.TodayMax = pricePerKWh(summary.Today.Max)
+		sensor.TodayAvg = pricePerKWh(summary.Today.Avg)
+	}
+
+	var points []models.SpotPricePoint
+	if summary.Today != nil {
+		points = append(points, summary.Today.Prices...)
+	}
+	if summary.Tomorrow != nil {
+		points = append(points, summary.Tomorrow.Prices...)
+	}
+	resolution := models.SpotPriceResolution(points)
+
+	// The hours are counted from the start of the current price
+	from := now
+	if summary.Current != nil {
+		from = summary.Current.Timestamp
+	}
+	until := from.Add(time.Duration(hours) * time.Hour)
+	for _, p := range points {
+		if !now.Before(p.Timestamp.Add(resolution)) || !p.Timestamp.Before(until) {
+			continue
+		}
+		sensor.NextHours = append(sensor.NextHours, models.HomeAssistantPrice{
+			Start: p.Timestamp,
+			End:   p.Timestamp.Add(resolution),
+			Price: *pricePerKWh(p.Price),
+		})
+	}
+
+	if summary.Current != nil {
+		start := summary.Current.Timestamp
+		end := start.Add(resolution)
+		sensor.Price = pricePerKWh(summary.Current.Price)
+		sensor.Start = &start
+		sensor.End = &end
+	}
+	return sensor
+}
+
+// pricePerKWh converts a stored price, in hundredths of the currency per kWh, to the
+// currency per kWh
+func pricePerKWh(price float64) *float64 {
+	perKWh := math.Round(price*1e4) / 1e6
+	return &perKWh
+}
+
+// ListTokens godoc
+// @Summary List integration tokens
+// @Description Lists the authenticated user's integration tokens, newest first, without the tokens themselves
+// @Tags integrations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.IntegrationToken
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /integrations/homeassistant/tokens [get]
+func (h *HomeAssistantHandler) ListTokens(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	tokens, err := h.tokenRepo.ListByUserID(c.Request.Context(), authUser.ID)
+	if err != nil {
+		log.Printf("Error listing integration tokens: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list integration tokens")
+		return
+	}
+	c.JSON(http.StatusOK, tokens)
+}
+
+// CreateToken godoc
+// @Summary Issue an integration token
+// @Description Issues a long-lived token for the sensor endpoint, to configure Home Assistant with. The response contains the token, it isn't returned again. It is valid until revoked or the user is deleted.
+// @Tags integrations
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateIntegrationTokenRequest true "Token"
+// @Success 201 {object} models.CreatedIntegrationToken
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /integrations/homeassistant/tokens [post]
+func (h *HomeAssistantHandler) CreateToken(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreateIntegrationTokenRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	token, hash, err := auth.NewIntegrationToken()
+	if err != nil {
+		log.Printf("Error generating integration token: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to create integration token")
+		return
+	}
+	integration := &models.IntegrationToken{
+		UserID:    authUser.ID,
+		Name:      strings.TrimSpace(req.Name),
+		TokenHash: hash,
+	}
+	if err := h.tokenRepo.Create(c.Request.Context(), integration); err != nil {
+		log.Printf("Error creating integration token: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to create integration token")
+		return
+	}
+
+	h.audit(c, authUser, models.AuditActionCreate, integration, "Integration token issued")
+	c.JSON(http.StatusCreated, models.CreatedIntegrationToken{IntegrationToken: *integration, Token: token})
+}
+
+// DeleteToken godoc
+// @Summary Revoke an integration token
+// @Description Revokes one of the authenticated user's integration tokens, the integration using it stops working
+// @Tags integrations
+// @Security BearerAuth
+// @Param id path string true "Token ID"
+// @Success 204 "Token revoked"
+// @Failure 400 {object} apierror.Problem "Invalid token ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Token not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /integrations/homeassistant/tokens/{id} [delete]
+func (h *HomeAssistantHandler) DeleteToken(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid token ID")
+		return
+	}
+
+	integration, err := h.tokenRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.IntegrationNotFound, "integration token not found")
+			return
+		}
+		log.Printf("Error getting integration token: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get integration token")
+		return
+	}
+
+	// Don't reveal tokens belonging to other users
+	if integration.UserID != authUser.ID {
+		apierror.Write(c, apierror.IntegrationNotFound, "integration token not found")
+		return
+	}
+
+	if err := h.tokenRepo.Delete(c.Request.Context(), id); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Error deleting integration token: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to delete integration token")
+		return
+	}
+
+	h.audit(c, authUser, models.AuditActionDelete, integration, "Integration token revoked")
+	c.Status(http.StatusNoContent)
+}
+
+func (h *HomeAssistantHandler) audit(c *gin.Context, authUser *models.User, action models.AuditAction, integration *models.IntegrationToken, description string) {
+	data, _ := json.Marshal(map[string]string{"name": integration.Name})
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      action,
+		EntityType:  "integration_token",
+		EntityID:    integration.ID.String(),
+		Description: description,
+		Metadata:    string(data),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging integration token change: %v", err)
+	}
+}
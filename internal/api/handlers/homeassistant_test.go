@@ -0,0 +1,171 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHomeAssistantHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+	other := tc.CreateTestUser("other", "other@test.com", "password123", false)
+
+	zone, err := tc.ZoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := tc.CurrencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+	loc, err := time.LoadLocation(zone.Timezone)
+	require.NoError(t, err)
+
+	// Hourly prices for today and tomorrow, each hour one öre dearer than the last
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	var prices []models.SpotPrice
+	for i, ts := 0, today; ts.Before(today.AddDate(0, 0, 2)); i, ts = i+1, ts.Add(time.Hour) {
+		prices = append(prices, models.SpotPrice{Timestamp: ts, ZoneID: zone.ID, CurrencyID: currency.ID, Price: float64(10 + i)})
+	}
+	require.NoError(t, tc.SpotPriceRepo.CreateBatch(context.Background(), prices))
+
+	handler := handlers.NewHomeAssistantHandler(tc.IntegrationRepo, tc.SpotPriceRepo, tc.ZoneRepo, tc.CurrencyRepo, tc.AuditRepo)
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	authMiddleware.AcceptIntegrationTokens(tc.IntegrationRepo)
+
+	router := gin.New()
+	router.GET("/integrations/homeassistant", handler.Discover)
+	router.GET("/integrations/homeassistant/sensor", authMiddleware.IntegrationTokenRequired(), handler.GetSensor)
+	router.GET("/integrations/homeassistant/tokens", authMiddleware.AuthRequired(), handler.ListTokens)
+	router.POST("/integrations/homeassistant/tokens", authMiddleware.AuthRequired(), handler.CreateToken)
+	router.DELETE("/integrations/homeassistant/tokens/:id", authMiddleware.AuthRequired(), handler.DeleteToken)
+
+	send := func(method, path, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "http://wattwatch.test"+path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	createToken := func(t *testing.T, u *models.User) models.CreatedIntegrationToken {
+		t.Helper()
+		w := send(http.MethodPost, "/integrations/homeassistant/tokens", `{"name":"Home Assistant"}`, tc.GetTestJWT(u.ID))
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created models.CreatedIntegrationToken
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		return created
+	}
+
+	t.Run("Discover", func(t *testing.T) {
+		w := send(http.MethodGet, "/integrations/homeassistant?zone=SE3&currency=SEK", "", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+		var discovery models.HomeAssistantDiscovery
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &discovery))
+		assert.Equal(t, "http://wattwatch.test/api/v1/integrations/homeassistant/sensor", discovery.SensorURL)
+		assert.Equal(t, []string{"SE1", "SE2", "SE3", "SE4"}, discovery.Zones)
+		assert.Contains(t, discovery.Currencies, "SEK")
+		assert.Contains(t, discovery.Parameters, "hours")
+		assert.Contains(t, discovery.Configuration, "/sensor?currency=SEK&zone=SE3")
+		assert.Contains(t, discovery.Configuration, `unit_of_measurement: "SEK/kWh"`)
+		assert.Contains(t, discovery.Configuration, "unique_id: wattwatch_se3_sek_price")
+	})
+
+	t.Run("Tokens", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/integrations/homeassistant/tokens", `{}`, tc.GetTestJWT(user.ID)).Code)
+
+		created := createToken(t, user)
+		assert.True(t, strings.HasPrefix(created.Token, "wwi_"))
+		assert.Equal(t, "Home Assistant", created.Name)
+
+		w := send(http.MethodGet, "/integrations/homeassistant/tokens", "", tc.GetTestJWT(user.ID))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), created.Token)
+		var tokens []models.IntegrationToken
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &tokens))
+		require.Len(t, tokens, 1)
+		assert.Equal(t, created.ID, tokens[0].ID)
+
+		// Integration tokens only read the sensor
+		assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/integrations/homeassistant/tokens", "", created.Token).Code)
+
+		// Other users can't revoke the token
+		path := "/integrations/homeassistant/tokens/" + created.ID.String()
+		assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, path, "", tc.GetTestJWT(other.ID)).Code)
+		assert.Equal(t, http.StatusNoContent, send(http.MethodDelete, path, "", tc.GetTestJWT(user.ID)).Code)
+		assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, path, "", tc.GetTestJWT(user.ID)).Code)
+		assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/integrations/homeassistant/sensor?zone=SE3&currency=SEK", "", created.Token).Code)
+	})
+
+	t.Run("Sensor", func(t *testing.T) {
+		created := createToken(t, user)
+		sensor := func(t *testing.T, query, token string) models.HomeAssistantSensor {
+			t.Helper()
+			w := send(http.MethodGet, "/integrations/homeassistant/sensor?"+query, "", token)
+			require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+			var s models.HomeAssistantSensor
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &s))
+			return s
+		}
+
+		s := sensor(t, "zone=SE3&currency=SEK", created.Token)
+		hour := now.Truncate(time.Hour)
+		index := int(hour.Sub(today) / time.Hour)
+		require.NotNil(t, s.Price)
+		assert.InDelta(t, float64(10+index)/100, *s.Price, 1e-9)
+		assert.Equal(t, "SEK/kWh", s.Unit)
+		assert.Equal(t, "SEK", s.Currency)
+		assert.Equal(t, "SE3", s.Zone)
+		require.NotNil(t, s.Start)
+		assert.True(t, hour.Equal(*s.Start))
+		assert.True(t, hour.Add(time.Hour).Equal(*s.End))
+		require.NotNil(t, s.TodayMin)
+		assert.InDelta(t, 0.10, *s.TodayMin, 1e-9)
+
+		require.Len(t, s.NextHours, 24, "the current hour and those starting in the next 24 hours")
+		assert.True(t, hour.Equal(s.NextHours[0].Start))
+		assert.Equal(t, *s.Price, s.NextHours[0].Price)
+		assert.True(t, s.NextHours[23].End.Equal(hour.Add(24*time.Hour)))
+
+		assert.Len(t, sensor(t, "zone=SE3&currency=SEK&hours=3", created.Token).NextHours, 3)
+
+		// Access tokens are accepted too
+		assert.Equal(t, s.Price, sensor(t, "zone=SE3&currency=SEK", tc.GetTestJWT(user.ID)).Price)
+
+		stored, err := tc.IntegrationRepo.GetByID(context.Background(), created.ID)
+		require.NoError(t, err)
+		assert.NotNil(t, stored.LastUsedAt)
+	})
+
+	t.Run("Sensor Errors", func(t *testing.T) {
+		created := createToken(t, user)
+		get := func(query, token string) int {
+			return send(http.MethodGet, "/integrations/homeassistant/sensor?"+query, "", token).Code
+		}
+		assert.Equal(t, http.StatusUnauthorized, get("zone=SE3&currency=SEK", ""))
+		assert.Equal(t, http.StatusUnauthorized, get("zone=SE3&currency=SEK", "wwi_unknown"))
+		assert.Equal(t, http.StatusBadRequest, get("currency=SEK", created.Token))
+		assert.Equal(t, http.StatusBadRequest, get("zone=SE3", created.Token))
+		assert.Equal(t, http.StatusBadRequest, get("zone=SE3&currency=SEK&hours=49", created.Token))
+		assert.Equal(t, http.StatusNotFound, get("zone=XX1&currency=SEK", created.Token))
+		assert.Equal(t, http.StatusNotFound, get("zone=SE3&currency=USD", created.Token))
+
+		// Tokens stop working when their user is deleted
+		require.NoError(t, tc.UserRepo.Delete(context.Background(), user.ID))
+		assert.Equal(t, http.StatusUnauthorized, get("zone=SE3&currency=SEK", created.Token))
+	})
+}
@@ -0,0 +1,174 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ImpersonationHandler lets admins act as other users for support
+type ImpersonationHandler struct {
+	impersonationRepo repository.ImpersonationRepository
+	userRepo          repository.UserRepository
+	authService       *auth.Service
+	auditRepo         repository.AuditLogRepository
+}
+
+// NewImpersonationHandler creates a new ImpersonationHandler
+func NewImpersonationHandler(
+	impersonationRepo repository.ImpersonationRepository,
+	userRepo repository.UserRepository,
+	authService *auth.Service,
+	auditRepo repository.AuditLogRepository,
+) *ImpersonationHandler {
+	return &ImpersonationHandler{
+		impersonationRepo: impersonationRepo,
+		userRepo:          userRepo,
+		authService:       authService,
+		auditRepo:         auditRepo,
+	}
+}
+
+// StartImpersonation godoc
+// @Summary Impersonate a user
+// @Description Issues a short-lived access token of the user for an admin to act as them, for support. Requests made with it are audited with both the user and the admin. No refresh token is issued and other admins can't be impersonated. The token stops working when it expires or /auth/impersonation/stop is called. (admin only)
+// @Tags admin
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} models.ImpersonationResponse
+// @Failure 400 {object} apierror.Problem "Invalid user ID or the admin's own ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only, admins can't be impersonated"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/impersonate/{id} [post]
+func (h *ImpersonationHandler) StartImpersonation(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return
+	}
+	if id == authUser.ID {
+		apierror.Write(c, apierror.InvalidRequest, "cannot impersonate yourself")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "user not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return
+	}
+	// Acting as another admin would hide who made admin changes behind a second account
+	if user.IsAdmin() {
+		apierror.Write(c, apierror.Forbidden, "admins cannot be impersonated")
+		return
+	}
+
+	impersonation, err := h.impersonationRepo.Create(c.Request.Context(), authUser.ID, user.ID, time.Now().Add(auth.ImpersonationTTL))
+	if err != nil {
+		log.Printf("Error starting impersonation of user %s: %v", user.ID, err)
+		apierror.Write(c, apierror.Internal, "failed to start impersonation")
+		return
+	}
+	token, err := h.authService.GenerateImpersonationToken(user, impersonation)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to generate token")
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"impersonation_id": impersonation.ID,
+		"expires_at":       impersonation.ExpiresAt,
+	})
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionImpersonate,
+		EntityType:  "user",
+		EntityID:    user.ID.String(),
+		Description: fmt.Sprintf("Impersonation of user %s started", user.Username),
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging impersonation: %v", err)
+	}
+
+	c.JSON(http.StatusOK, models.ImpersonationResponse{
+		AccessToken:     token,
+		ImpersonationID: impersonation.ID,
+		ExpiresAt:       impersonation.ExpiresAt,
+		User:            user,
+	})
+}
+
+// StopImpersonation godoc
+// @Summary Stop impersonating a user
+// @Description Ends the impersonation the token was issued for, the token stops working immediately. It must be called with the impersonation token.
+// @Tags auth
+// @Security BearerAuth
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Token was not issued for an impersonation"
+// @Failure 401 {object} apierror.Problem "Unauthorized or impersonation already ended"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/impersonation/stop [post]
+func (h *ImpersonationHandler) StopImpersonation(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+	impersonation := auth.GetImpersonationFromContext(c)
+	if impersonation == nil {
+		apierror.Write(c, apierror.InvalidRequest, "not impersonating a user")
+		return
+	}
+
+	if err := h.impersonationRepo.End(c.Request.Context(), impersonation.ID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.InvalidToken, "impersonation has ended")
+			return
+		}
+		log.Printf("Error stopping impersonation %s: %v", impersonation.ID, err)
+		apierror.Write(c, apierror.Internal, "failed to stop impersonation")
+		return
+	}
+
+	// The admin is recorded as the impersonator like for any other request made with the token
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionStopImpersonation,
+		EntityType:  "user",
+		EntityID:    authUser.ID.String(),
+		Description: fmt.Sprintf("Impersonation of user %s stopped", authUser.Username),
+		Metadata:    `{"impersonation_id":"` + impersonation.ID.String() + `"}`,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging impersonation stop: %v", err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
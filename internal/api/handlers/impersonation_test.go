@@ -0,0 +1,90 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	otherAdmin := tc.CreateTestUser("other_admin", "other@test.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	auditRepo := middleware.AuditImpersonation(tc.AuditRepo)
+	handler := handlers.NewImpersonationHandler(tc.ImpersonationRepo, tc.UserRepo, tc.AuthService, auditRepo)
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	authMiddleware.CheckImpersonations(tc.ImpersonationRepo)
+
+	router := gin.New()
+	router.POST("/admin/impersonate/:id", authMiddleware.AuthRequired(), authMiddleware.AdminRequired(), handler.StartImpersonation)
+	router.POST("/auth/impersonation/stop", authMiddleware.AuthRequired(), handler.StopImpersonation)
+	router.GET("/me", authMiddleware.ClaimsRequired(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, handlers.GetUserFromContext(c))
+	})
+
+	send := func(method, path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	adminToken := tc.GetTestJWT(admin.ID)
+
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/admin/impersonate/"+admin.ID.String(), tc.GetTestJWT(user.ID)).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/admin/impersonate/"+admin.ID.String(), adminToken).Code)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/admin/impersonate/"+otherAdmin.ID.String(), adminToken).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/auth/impersonation/stop", adminToken).Code)
+
+	w := send(http.MethodPost, "/admin/impersonate/"+user.ID.String(), adminToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp models.ImpersonationResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.NotEmpty(t, resp.AccessToken)
+	assert.Equal(t, user.ID, resp.User.ID)
+
+	// The token authenticates as the user, who can't start impersonations
+	w = send(http.MethodGet, "/me", resp.AccessToken)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var me models.User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &me))
+	assert.Equal(t, user.ID, me.ID)
+	assert.Equal(t, http.StatusForbidden, send(http.MethodPost, "/admin/impersonate/"+otherAdmin.ID.String(), resp.AccessToken).Code)
+
+	w = send(http.MethodPost, "/auth/impersonation/stop", resp.AccessToken)
+	require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodGet, "/me", resp.AccessToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, send(http.MethodPost, "/auth/impersonation/stop", resp.AccessToken).Code)
+
+	// The start is the admin's action, the stop was made as the user on the admin's behalf
+	logs, err := tc.AuditRepo.List(context.Background(), repository.AuditLogFilter{
+		Actions: []models.AuditAction{models.AuditActionImpersonate, models.AuditActionStopImpersonation},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	byAction := map[models.AuditAction]models.AuditLog{logs[0].Action: logs[0], logs[1].Action: logs[1]}
+	start := byAction[models.AuditActionImpersonate]
+	assert.Equal(t, &admin.ID, start.UserID)
+	assert.Nil(t, start.ImpersonatorID)
+	assert.Equal(t, user.ID.String(), start.EntityID)
+	stop := byAction[models.AuditActionStopImpersonation]
+	assert.Equal(t, &user.ID, stop.UserID)
+	assert.Equal(t, &admin.ID, stop.ImpersonatorID)
+
+	impersonated, err := tc.AuditRepo.List(context.Background(), repository.AuditLogFilter{ImpersonatorID: &admin.ID})
+	require.NoError(t, err)
+	assert.Len(t, impersonated, 1)
+}
@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/scheduler"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JobHandler lets administrators see the background jobs and run them on demand
+type JobHandler struct {
+	scheduler *scheduler.Scheduler
+	repo      repository.JobRepository
+	auditRepo repository.AuditLogRepository
+	limits    ListLimits
+}
+
+// NewJobHandler creates a new JobHandler
+func NewJobHandler(s *scheduler.Scheduler, repo repository.JobRepository, auditRepo repository.AuditLogRepository) *JobHandler {
+	return &JobHandler{
+		scheduler: s,
+		repo:      repo,
+		auditRepo: auditRepo,
+		limits:    DefaultListLimits,
+	}
+}
+
+// SetListLimits sets the default and maximum number of job runs listed
+func (h *JobHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// ListJobs godoc
+// @Summary List background jobs
+// @Description Returns the recurring jobs of this instance with their schedule, next run and most recent run on any instance (admin only)
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Job
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/jobs [get]
+func (h *JobHandler) ListJobs(c *gin.Context) {
+	jobs, err := h.scheduler.Jobs(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing jobs: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list jobs")
+		return
+	}
+	c.JSON(http.StatusOK, jobs)
+}
+
+// ListRuns godoc
+// @Summary List runs of a job
+// @Description Returns the run history of a job, most recent first (admin only)
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name, e.g. token-cleanup"
+// @Param limit query integer false "Limit results (default 50, maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the runs in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.JobRun]
+// @Failure 400 {object} apierror.Problem "Invalid limit or offset"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/jobs/{name}/runs [get]
+func (h *JobHandler) ListRuns(c *gin.Context) {
+	filter := repository.JobRunFilter{JobName: c.Param("name")}
+
+	limit, err := h.limits.limit(c, h.limits.Default)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	filter.Limit = &limit
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	runs, err := h.repo.ListRuns(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error listing runs of job %s: %v", filter.JobName, err)
+		apierror.Write(c, apierror.Internal, "failed to list job runs")
+		return
+	}
+	respondPage(c, runs, filter.Limit, filter.Offset, func() (int, error) {
+		return h.repo.TotalRuns(c.Request.Context(), filter)
+	}, "failed to list job runs")
+}
+
+// TriggerJob godoc
+// @Summary Run a job now
+// @Description Starts a run of the job on this instance in the background, regardless of its schedule and of which instance is leader (admin only)
+// @Tags jobs
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Job name, e.g. token-cleanup"
+// @Success 202 {object} models.JobRun
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Job not found"
+// @Failure 409 {object} apierror.Problem "Job is already running"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/jobs/{name}/run [post]
+func (h *JobHandler) TriggerJob(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	name := c.Param("name")
+	run, err := h.scheduler.Trigger(name, &authUser.ID)
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		apierror.Write(c, apierror.JobNotFound, "job not found")
+		return
+	case errors.Is(err, scheduler.ErrJobRunning):
+		apierror.Write(c, apierror.JobRunning, "job is already running")
+		return
+	case err != nil:
+		log.Printf("Error triggering job %s: %v", name, err)
+		apierror.Write(c, apierror.Internal, "failed to start job")
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]string{"job": name, "run_id": run.ID.String()})
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionCreate,
+		EntityType:  "job_run",
+		EntityID:    run.ID.String(),
+		Description: "Job " + name + " run manually",
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging manual run of job %s: %v", name, err)
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
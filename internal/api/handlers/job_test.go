@@ -0,0 +1,101 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+	"wattwatch/internal/scheduler"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobHandler(t *testing.T) {
+	ctx := context.Background()
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	jobRepo := memory.NewJobRepository(memory.NewStore())
+	jobs := scheduler.New(jobRepo)
+	release := make(chan struct{})
+	require.NoError(t, jobs.Add("token-cleanup", "0 * * * *", func(ctx context.Context) error {
+		<-release
+		return nil
+	}))
+
+	handler := handlers.NewJobHandler(jobs, jobRepo, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	admins := router.Group("/admin", authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	admins.GET("/jobs", handler.ListJobs)
+	admins.GET("/jobs/:name/runs", handler.ListRuns)
+	admins.POST("/jobs/:name/run", handler.TriggerJob)
+
+	send := func(method, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Trigger Job", func(t *testing.T) {
+		w := send("POST", "/admin/jobs/token-cleanup/run", admin.ID)
+		require.Equal(t, http.StatusAccepted, w.Code)
+		var run models.JobRun
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &run))
+		assert.Equal(t, models.JobTriggerManual, run.Trigger)
+		assert.Equal(t, &admin.ID, run.TriggeredBy)
+
+		// A second run waits for the first to finish
+		assert.Equal(t, http.StatusConflict, send("POST", "/admin/jobs/token-cleanup/run", admin.ID).Code)
+		close(release)
+
+		logs, err := tc.AuditRepo.List(ctx, repository.AuditLogFilter{UserID: &admin.ID})
+		require.NoError(t, err)
+		require.NotEmpty(t, logs)
+		assert.Equal(t, run.ID.String(), logs[0].EntityID)
+	})
+
+	t.Run("List Runs", func(t *testing.T) {
+		var runs models.Page[models.JobRun]
+		require.Eventually(t, func() bool {
+			w := send("GET", "/admin/jobs/token-cleanup/runs", admin.ID)
+			require.Equal(t, http.StatusOK, w.Code)
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &runs))
+			return runs.Total == 1 && runs.Items[0].Status == models.JobStatusSucceeded
+		}, time.Second, 5*time.Millisecond)
+
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/admin/jobs/token-cleanup/runs?offset=-1", admin.ID).Code)
+	})
+
+	t.Run("List Jobs", func(t *testing.T) {
+		w := send("GET", "/admin/jobs", admin.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		var list []models.Job
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+		require.Len(t, list, 1)
+		assert.Equal(t, "token-cleanup", list[0].Name)
+		assert.Equal(t, "0 * * * *", list[0].Schedule)
+		require.NotNil(t, list[0].LastRun)
+		assert.Equal(t, models.JobStatusSucceeded, list[0].LastRun.Status)
+	})
+
+	t.Run("Unknown Job And Non Admin", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send("POST", "/admin/jobs/unknown/run", admin.ID).Code)
+		assert.Equal(t, http.StatusForbidden, send("POST", "/admin/jobs/token-cleanup/run", user.ID).Code)
+		assert.Equal(t, http.StatusForbidden, send("GET", "/admin/jobs", user.ID).Code)
+	})
+}
@@ -0,0 +1,33 @@
+package handlers
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ListLimits are the number of items list endpoints return when the client doesn't ask
+// for a limit, and the largest limit they accept
+type ListLimits struct {
+	Default int
+	Max     int
+}
+
+// DefaultListLimits are used until a handler is given the configured limits
+var DefaultListLimits = ListLimits{Default: 50, Max: 1000}
+
+var errInvalidLimit = errors.New("invalid limit parameter")
+
+// limit reads the limit query parameter, using fallback when it's missing. Limits above
+// the maximum are lowered to it rather than rejected.
+func (l ListLimits) limit(c *gin.Context, fallback int) (int, error) {
+	limit := fallback
+	if s := c.Query("limit"); s != "" {
+		var err error
+		if limit, err = strconv.Atoi(s); err != nil || limit < 1 {
+			return 0, errInvalidLimit
+		}
+	}
+	return min(limit, l.Max), nil
+}
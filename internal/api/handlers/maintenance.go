@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaintenanceHandler lets administrators toggle maintenance mode
+type MaintenanceHandler struct {
+	maintenance *middleware.MaintenanceMode
+	auditRepo   repository.AuditLogRepository
+}
+
+// NewMaintenanceHandler creates a new MaintenanceHandler
+func NewMaintenanceHandler(maintenance *middleware.MaintenanceMode, auditRepo repository.AuditLogRepository) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		maintenance: maintenance,
+		auditRepo:   auditRepo,
+	}
+}
+
+// GetMaintenance godoc
+// @Summary Get maintenance mode
+// @Description Returns whether maintenance mode is enabled (admin only)
+// @Tags maintenance
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.MaintenanceStatus
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /admin/maintenance [get]
+func (h *MaintenanceHandler) GetMaintenance(c *gin.Context) {
+	c.JSON(http.StatusOK, h.maintenance.Status())
+}
+
+// UpdateMaintenance godoc
+// @Summary Toggle maintenance mode
+// @Description Enables or disables maintenance mode. While enabled, requests from non-admins get 503 with a Retry-After header; health checks, login and admin requests keep working. The state is stored in the database, so it survives restarts and other instances apply it within 30 seconds. (admin only)
+// @Tags maintenance
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdateMaintenanceRequest true "Maintenance settings"
+// @Success 200 {object} models.MaintenanceStatus
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/maintenance [put]
+func (h *MaintenanceHandler) UpdateMaintenance(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.UpdateMaintenanceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	status, err := h.maintenance.Set(c.Request.Context(), *req.Enabled, req.Message, time.Duration(req.RetryAfterSeconds)*time.Second, &authUser.ID)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to update maintenance mode")
+		return
+	}
+
+	description := "Maintenance mode disabled"
+	if status.Enabled {
+		description = "Maintenance mode enabled"
+	}
+	metadata, _ := json.Marshal(status)
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "user",
+		EntityID:    authUser.ID.String(),
+		Description: description,
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging maintenance change: %v", err)
+	}
+
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceHandler_UpdateMaintenance(t *testing.T) {
+	tests := []struct {
+		name        string
+		isAdmin     bool
+		body        string
+		wantStatus  int
+		wantEnabled bool
+	}{
+		{
+			name:        "Enable",
+			isAdmin:     true,
+			body:        `{"enabled":true,"message":"upgrading","retry_after_seconds":60}`,
+			wantStatus:  http.StatusOK,
+			wantEnabled: true,
+		},
+		{
+			name:       "Missing Enabled",
+			isAdmin:    true,
+			body:       `{"message":"upgrading"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Non Admin",
+			isAdmin:    false,
+			body:       `{"enabled":true}`,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewTestContext(t)
+			user := tc.CreateTestUser("user", "user@test.com", "password123", tt.isAdmin)
+
+			maintenance := middleware.NewMaintenanceMode(tc.AuthService, tc.SettingRepo)
+			handler := handlers.NewMaintenanceHandler(maintenance, tc.AuditRepo)
+			router := gin.New()
+			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+			router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+			router.PUT("/admin/maintenance", handler.UpdateMaintenance)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/admin/maintenance", bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var status models.MaintenanceStatus
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+				assert.Equal(t, tt.wantEnabled, status.Enabled)
+				assert.Equal(t, 60, status.RetryAfterSeconds)
+			}
+			assert.Equal(t, tt.wantStatus == http.StatusOK, maintenance.Status().Enabled)
+
+			// The state outlives the instance that set it
+			restarted := middleware.NewMaintenanceMode(tc.AuthService, tc.SettingRepo)
+			require.NoError(t, restarted.Refresh(context.Background()))
+			assert.Equal(t, tt.wantStatus == http.StatusOK, restarted.Status().Enabled)
+		})
+	}
+}
@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/database"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Migrator reports and applies the migrations of the database
+type Migrator interface {
+	Status() (*database.MigrationState, error)
+	Up() error
+}
+
+// MigrationHandler lets administrators check and apply database migrations
+type MigrationHandler struct {
+	migrator  Migrator
+	auditRepo repository.AuditLogRepository
+}
+
+// NewMigrationHandler creates a new MigrationHandler
+func NewMigrationHandler(migrator Migrator, auditRepo repository.AuditLogRepository) *MigrationHandler {
+	return &MigrationHandler{
+		migrator:  migrator,
+		auditRepo: auditRepo,
+	}
+}
+
+// migrationStatus converts the state of the database to its response
+func migrationStatus(state *database.MigrationState) models.MigrationStatus {
+	status := models.MigrationStatus{
+		CurrentVersion: state.Current,
+		LatestVersion:  state.Latest,
+		Dirty:          state.Dirty,
+		Pending:        make([]models.MigrationInfo, 0, len(state.Available)),
+	}
+	for _, m := range state.Available {
+		status.Pending = append(status.Pending, models.MigrationInfo{Version: m.Version, Name: m.Name})
+	}
+	return status
+}
+
+// GetMigrations godoc
+// @Summary Get database migration status
+// @Description Returns the applied schema version, whether a failed migration left it dirty and the migrations waiting to be applied (admin only)
+// @Tags migrations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.MigrationStatus
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/migrations [get]
+func (h *MigrationHandler) GetMigrations(c *gin.Context) {
+	state, err := h.migrator.Status()
+	if err != nil {
+		log.Printf("Error getting migration status: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get migration status")
+		return
+	}
+
+	c.JSON(http.StatusOK, migrationStatus(state))
+}
+
+// ApplyMigrations godoc
+// @Summary Apply pending database migrations
+// @Description Applies the migrations waiting to be applied and returns the resulting status with the migrations applied. Servers started with automatic migrations turned off rely on this or the --migrate-only flag. A dirty schema is left alone, it must be repaired by hand first. (admin only)
+// @Tags migrations
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.MigrationStatus
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 409 {object} apierror.Problem "Schema is dirty"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "A migration failed"
+// @Router /admin/migrations [post]
+func (h *MigrationHandler) ApplyMigrations(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	before, err := h.migrator.Status()
+	if err != nil {
+		log.Printf("Error getting migration status: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get migration status")
+		return
+	}
+	if before.Dirty {
+		apierror.Write(c, apierror.Conflict, fmt.Sprintf("version %d is dirty, a migration failed and must be repaired by hand", before.Current))
+		return
+	}
+	if len(before.Available) == 0 {
+		c.JSON(http.StatusOK, migrationStatus(before))
+		return
+	}
+
+	upErr := h.migrator.Up()
+	after, err := h.migrator.Status()
+	if err != nil {
+		log.Printf("Error getting migration status: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get migration status")
+		return
+	}
+
+	status := migrationStatus(after)
+	for _, m := range before.Available {
+		if m.Version <= after.Current && !(after.Dirty && m.Version == after.Current) {
+			status.Applied = append(status.Applied, models.MigrationInfo{Version: m.Version, Name: m.Name})
+		}
+	}
+
+	metadata, _ := json.Marshal(map[string]interface{}{
+		"from_version": before.Current,
+		"to_version":   after.Current,
+		"applied":      status.Applied,
+		"success":      upErr == nil,
+	})
+	if auditErr := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "database",
+		EntityID:    strconv.FormatUint(uint64(after.Current), 10),
+		Description: "Database migrations applied",
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); auditErr != nil {
+		log.Printf("Error logging database migration: %v", auditErr)
+	}
+
+	if upErr != nil {
+		log.Printf("Error applying migrations: %v", upErr)
+		apierror.Write(c, apierror.Internal, upErr.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
@@ -0,0 +1,127 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/database"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stubMigrator applies migrations up to stop, failing there when fail is set
+type stubMigrator struct {
+	current    uint
+	dirty      bool
+	migrations []database.Migration
+	stop       uint
+	fail       bool
+}
+
+func (m *stubMigrator) Status() (*database.MigrationState, error) {
+	state := &database.MigrationState{Current: m.current, Dirty: m.dirty}
+	for _, migration := range m.migrations {
+		state.Latest = migration.Version
+		if migration.Version > m.current {
+			state.Available = append(state.Available, migration)
+		}
+	}
+	return state, nil
+}
+
+func (m *stubMigrator) Up() error {
+	if m.fail {
+		m.current, m.dirty = m.stop, true
+		return errors.New("migration 38 failed")
+	}
+	m.current = m.migrations[len(m.migrations)-1].Version
+	return nil
+}
+
+func TestMigrationHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	auditRepo := memory.NewAuditLogRepository(store)
+	migrations := []database.Migration{{Version: 36, Name: "spot_price_revisions"}, {Version: 37, Name: "tariffs"}, {Version: 38, Name: "price_forecasts"}}
+
+	admin := &models.User{ID: uuid.New(), Username: "admin"}
+	send := func(migrator *stubMigrator, method string) (*httptest.ResponseRecorder, models.MigrationStatus) {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			c.Set("user", admin)
+			c.Next()
+		})
+		handler := handlers.NewMigrationHandler(migrator, auditRepo)
+		router.GET("/admin/migrations", handler.GetMigrations)
+		router.POST("/admin/migrations", handler.ApplyMigrations)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/admin/migrations", nil)
+		router.ServeHTTP(w, req)
+		var status models.MigrationStatus
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		}
+		return w, status
+	}
+
+	t.Run("Lists Pending", func(t *testing.T) {
+		w, status := send(&stubMigrator{current: 36, migrations: migrations}, http.MethodGet)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, uint(36), status.CurrentVersion)
+		assert.Equal(t, uint(38), status.LatestVersion)
+		assert.False(t, status.Dirty)
+		assert.Equal(t, []models.MigrationInfo{{Version: 37, Name: "tariffs"}, {Version: 38, Name: "price_forecasts"}}, status.Pending)
+		assert.Nil(t, status.Applied)
+	})
+
+	t.Run("Applies Pending", func(t *testing.T) {
+		migrator := &stubMigrator{current: 36, migrations: migrations}
+		w, status := send(migrator, http.MethodPost)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, uint(38), status.CurrentVersion)
+		assert.Empty(t, status.Pending)
+		assert.Equal(t, []models.MigrationInfo{{Version: 37, Name: "tariffs"}, {Version: 38, Name: "price_forecasts"}}, status.Applied)
+
+		logs, err := auditRepo.List(context.Background(), repository.AuditLogFilter{EntityTypes: []string{"database"}})
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, "38", logs[0].EntityID)
+	})
+
+	t.Run("Nothing Pending", func(t *testing.T) {
+		w, status := send(&stubMigrator{current: 38, migrations: migrations}, http.MethodPost)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, status.Pending)
+		assert.Empty(t, status.Applied)
+	})
+
+	t.Run("Refuses Dirty", func(t *testing.T) {
+		migrator := &stubMigrator{current: 37, dirty: true, migrations: migrations}
+		w, _ := send(migrator, http.MethodPost)
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Equal(t, uint(37), migrator.current)
+
+		w, status := send(migrator, http.MethodGet)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, status.Dirty)
+	})
+
+	t.Run("Migration Fails", func(t *testing.T) {
+		migrator := &stubMigrator{current: 36, migrations: migrations, stop: 38, fail: true}
+		w, _ := send(migrator, http.MethodPost)
+		assert.Equal(t, http.StatusInternalServerError, w.Code)
+		assert.Contains(t, w.Body.String(), "migration 38 failed")
+		assert.True(t, migrator.dirty)
+	})
+}
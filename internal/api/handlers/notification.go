@@ -0,0 +1,394 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/notification"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// NotificationHandler handles push notification device, preference and delivery requests
+type NotificationHandler struct {
+	deviceRepo     repository.DeviceTokenRepository
+	preferenceRepo repository.NotificationPreferenceRepository
+	deliveryRepo   repository.NotificationDeliveryRepository
+	service        *notification.Service
+	vapidPublicKey string
+}
+
+// NewNotificationHandler creates a new NotificationHandler. vapidPublicKey may be empty when WebPush is disabled.
+func NewNotificationHandler(
+	deviceRepo repository.DeviceTokenRepository,
+	preferenceRepo repository.NotificationPreferenceRepository,
+	deliveryRepo repository.NotificationDeliveryRepository,
+	service *notification.Service,
+	vapidPublicKey string,
+) *NotificationHandler {
+	return &NotificationHandler{
+		deviceRepo:     deviceRepo,
+		preferenceRepo: preferenceRepo,
+		deliveryRepo:   deliveryRepo,
+		service:        service,
+		vapidPublicKey: vapidPublicKey,
+	}
+}
+
+// GetWebPushKey godoc
+// @Summary Get WebPush public key
+// @Description Returns the VAPID application server key browsers need to create a push subscription
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} map[string]string
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "WebPush not configured"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /notifications/webpush/key [get]
+func (h *NotificationHandler) GetWebPushKey(c *gin.Context) {
+	if h.vapidPublicKey == "" {
+		apierror.Write(c, apierror.NotificationNotFound, "WebPush not configured")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"public_key": h.vapidPublicKey})
+}
+
+// ListDevices godoc
+// @Summary List registered devices
+// @Description Lists the authenticated user's devices registered for push notifications
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.DeviceToken
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/devices [get]
+func (h *NotificationHandler) ListDevices(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	devices, err := h.deviceRepo.ListByUserID(c.Request.Context(), authUser.ID)
+	if err != nil {
+		log.Printf("Error listing devices: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list devices")
+		return
+	}
+
+	c.JSON(http.StatusOK, devices)
+}
+
+// RegisterDevice godoc
+// @Summary Register a device
+// @Description Registers an FCM token or WebPush subscription for the authenticated user. WebPush requires the subscription keys and an endpoint of a known push service.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.RegisterDeviceRequest true "Device registration"
+// @Success 201 {object} models.DeviceToken
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/devices [post]
+func (h *NotificationHandler) RegisterDevice(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.RegisterDeviceRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if !h.service.HasChannel(req.Channel) {
+		apierror.Write(c, apierror.InvalidRequest, "notification channel not configured")
+		return
+	}
+
+	device := &models.DeviceToken{
+		UserID:  authUser.ID,
+		Channel: req.Channel,
+		Token:   req.Token,
+	}
+	if ua := c.Request.UserAgent(); ua != "" {
+		if len(ua) > 255 {
+			ua = ua[:255]
+		}
+		device.UserAgent = &ua
+	}
+
+	if req.Channel == models.NotificationChannelWebPush {
+		if req.Keys == nil {
+			apierror.Write(c, apierror.InvalidRequest, "keys are required for webpush subscriptions")
+			return
+		}
+		if err := notification.ValidateWebPushEndpoint(req.Token); err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "token must be the endpoint of a known push service")
+			return
+		}
+		device.P256dh = &req.Keys.P256dh
+		device.Auth = &req.Keys.Auth
+	}
+
+	if err := h.deviceRepo.Upsert(c.Request.Context(), device); err != nil {
+		log.Printf("Error registering device: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to register device")
+		return
+	}
+
+	c.JSON(http.StatusCreated, device)
+}
+
+// DeleteDevice godoc
+// @Summary Unregister a device
+// @Description Removes one of the authenticated user's registered devices
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Device ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid device ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Device not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/devices/{id} [delete]
+func (h *NotificationHandler) DeleteDevice(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid device ID")
+		return
+	}
+
+	device, err := h.deviceRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.NotificationNotFound, "device not found")
+			return
+		}
+		log.Printf("Error getting device: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get device")
+		return
+	}
+
+	// Don't reveal devices belonging to other users
+	if device.UserID != authUser.ID {
+		apierror.Write(c, apierror.NotificationNotFound, "device not found")
+		return
+	}
+
+	if err := h.deviceRepo.Delete(c.Request.Context(), id); err != nil {
+		log.Printf("Error deleting device: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to delete device")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// GetPreferences godoc
+// @Summary Get notification preferences
+// @Description Returns the authenticated user's stored notification preferences. Channels without a stored preference are enabled.
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.NotificationPreference
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/preferences [get]
+func (h *NotificationHandler) GetPreferences(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	prefs, err := h.preferenceRepo.ListByUserID(c.Request.Context(), authUser.ID)
+	if err != nil {
+		log.Printf("Error listing notification preferences: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences godoc
+// @Summary Update notification preferences
+// @Description Enables or disables alert types per channel for the authenticated user
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdateNotificationPreferencesRequest true "Preferences"
+// @Success 200 {array} models.NotificationPreference
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/preferences [put]
+func (h *NotificationHandler) UpdatePreferences(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.UpdateNotificationPreferencesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	for _, input := range req.Preferences {
+		pref := &models.NotificationPreference{
+			UserID:    authUser.ID,
+			Channel:   input.Channel,
+			AlertType: input.AlertType,
+			Enabled:   *input.Enabled,
+		}
+		if err := h.preferenceRepo.Upsert(ctx, pref); err != nil {
+			log.Printf("Error updating notification preferences: %v", err)
+			apierror.Write(c, apierror.Internal, "failed to update preferences")
+			return
+		}
+	}
+
+	prefs, err := h.preferenceRepo.ListByUserID(ctx, authUser.ID)
+	if err != nil {
+		log.Printf("Error listing notification preferences: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// ListDeliveries godoc
+// @Summary List notification deliveries
+// @Description Lists the delivery status of notifications sent to the authenticated user
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param status query string false "Filter by status (pending, sent, failed)"
+// @Param channel query string false "Filter by channel (fcm, webpush)"
+// @Param limit query integer false "Limit results (default 50)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the deliveries in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.NotificationDelivery]
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/deliveries [get]
+func (h *NotificationHandler) ListDeliveries(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	limit := 50
+	filter := repository.NotificationDeliveryFilter{
+		UserID: &authUser.ID,
+		Limit:  &limit,
+	}
+
+	if status := c.Query("status"); status != "" {
+		s := models.DeliveryStatus(status)
+		if s != models.DeliveryStatusPending && s != models.DeliveryStatusSent && s != models.DeliveryStatusFailed {
+			apierror.Write(c, apierror.InvalidRequest, "invalid status")
+			return
+		}
+		filter.Status = &s
+	}
+
+	if channel := c.Query("channel"); channel != "" {
+		ch := models.NotificationChannel(channel)
+		filter.Channel = &ch
+	}
+
+	if limitStr := c.Query("limit"); limitStr != "" {
+		l, err := strconv.Atoi(limitStr)
+		if err != nil || l < 1 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid limit")
+			return
+		}
+		filter.Limit = &l
+	}
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	deliveries, err := h.deliveryRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error listing deliveries: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list deliveries")
+		return
+	}
+
+	respondPage(c, deliveries, filter.Limit, filter.Offset, func() (int, error) {
+		return h.deliveryRepo.Total(c.Request.Context(), filter)
+	}, "failed to list deliveries")
+}
+
+// SendTestNotification godoc
+// @Summary Send a test notification
+// @Description Sends a test notification to all of the authenticated user's registered devices
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.SuccessResponse
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 502 {object} apierror.Problem "Delivery failed"
+// @Router /notifications/test [post]
+func (h *NotificationHandler) SendTestNotification(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	err := h.service.Notify(c.Request.Context(), authUser.ID, &notification.Message{
+		AlertType: models.NotificationAlertTest,
+		Title:     "WattWatch test notification",
+		Body:      "Push notifications are working.",
+	})
+	if err != nil {
+		log.Printf("Error sending test notification: %v", err)
+		apierror.Write(c, apierror.UpstreamFailed, "failed to deliver test notification")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Test notification sent"})
+}
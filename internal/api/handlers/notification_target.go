@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/notification"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// NotificationTargetHandler handles Slack, Discord and Telegram notification targets
+type NotificationTargetHandler struct {
+	targetRepo repository.NotificationTargetRepository
+	service    *notification.Service
+}
+
+// NewNotificationTargetHandler creates a new NotificationTargetHandler
+func NewNotificationTargetHandler(targetRepo repository.NotificationTargetRepository, service *notification.Service) *NotificationTargetHandler {
+	return &NotificationTargetHandler{
+		targetRepo: targetRepo,
+		service:    service,
+	}
+}
+
+// ListTargets godoc
+// @Summary List notification targets
+// @Description Lists the authenticated user's Slack, Discord and Telegram targets. Credentials are not returned.
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.NotificationTarget
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/targets [get]
+func (h *NotificationTargetHandler) ListTargets(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	targets, err := h.targetRepo.ListByUserID(c.Request.Context(), authUser.ID)
+	if err != nil {
+		log.Printf("Error listing notification targets: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list targets")
+		return
+	}
+
+	c.JSON(http.StatusOK, targets)
+}
+
+// CreateTarget godoc
+// @Summary Create a notification target
+// @Description Adds a chat destination for alerts. Slack and Discord need webhook_url, Telegram needs bot_token and chat_id.
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateNotificationTargetRequest true "Target"
+// @Success 201 {object} models.NotificationTarget
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/targets [post]
+func (h *NotificationTargetHandler) CreateTarget(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreateNotificationTargetRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	target := &models.NotificationTarget{
+		UserID:     authUser.ID,
+		Channel:    req.Channel,
+		Name:       req.Name,
+		WebhookURL: req.WebhookURL,
+		BotToken:   req.BotToken,
+		ChatID:     req.ChatID,
+	}
+	if err := h.service.ValidateTarget(target); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.targetRepo.Create(c.Request.Context(), target); err != nil {
+		log.Printf("Error creating notification target: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to create target")
+		return
+	}
+
+	c.JSON(http.StatusCreated, target)
+}
+
+// UpdateTarget godoc
+// @Summary Update a notification target
+// @Description Updates the name or credentials of one of the authenticated user's targets
+// @Tags notifications
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Target ID (UUID)"
+// @Param request body models.UpdateNotificationTargetRequest true "Target changes"
+// @Success 200 {object} models.NotificationTarget
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Target not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/targets/{id} [put]
+func (h *NotificationTargetHandler) UpdateTarget(c *gin.Context) {
+	target, ok := h.getOwnedTarget(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateNotificationTargetRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Name != nil {
+		target.Name = *req.Name
+	}
+	if req.WebhookURL != nil {
+		target.WebhookURL = req.WebhookURL
+	}
+	if req.BotToken != nil {
+		target.BotToken = req.BotToken
+	}
+	if req.ChatID != nil {
+		target.ChatID = req.ChatID
+	}
+
+	if err := h.service.ValidateTarget(target); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.targetRepo.Update(c.Request.Context(), target); err != nil {
+		log.Printf("Error updating notification target: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to update target")
+		return
+	}
+
+	c.JSON(http.StatusOK, target)
+}
+
+// DeleteTarget godoc
+// @Summary Delete a notification target
+// @Description Removes one of the authenticated user's targets
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Target ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid target ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Target not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /notifications/targets/{id} [delete]
+func (h *NotificationTargetHandler) DeleteTarget(c *gin.Context) {
+	target, ok := h.getOwnedTarget(c)
+	if !ok {
+		return
+	}
+
+	if err := h.targetRepo.Delete(c.Request.Context(), target.ID); err != nil {
+		log.Printf("Error deleting notification target: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to delete target")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// TestTarget godoc
+// @Summary Send a test message to a target
+// @Description Sends a test message so the user can confirm the target is set up correctly
+// @Tags notifications
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Target ID (UUID)"
+// @Success 200 {object} models.SuccessResponse
+// @Failure 400 {object} apierror.Problem "Invalid target ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Target not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 502 {object} apierror.Problem "Delivery failed"
+// @Router /notifications/targets/{id}/test [post]
+func (h *NotificationTargetHandler) TestTarget(c *gin.Context) {
+	target, ok := h.getOwnedTarget(c)
+	if !ok {
+		return
+	}
+
+	err := h.service.NotifyTarget(c.Request.Context(), target, &notification.Message{
+		AlertType: models.NotificationAlertTest,
+		Title:     "WattWatch test message",
+		Body:      "Alerts will be delivered to " + target.Name + ".",
+	})
+	if err != nil {
+		log.Printf("Error sending test message to target %s: %v", target.ID, err)
+		apierror.Write(c, apierror.UpstreamFailed, err.Error())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "Test message sent"})
+}
+
+// getOwnedTarget loads the target from the id path parameter and writes an error
+// response if it does not exist or belongs to another user
+func (h *NotificationTargetHandler) getOwnedTarget(c *gin.Context) (*models.NotificationTarget, bool) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid target ID")
+		return nil, false
+	}
+
+	target, err := h.targetRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.NotificationNotFound, "target not found")
+			return nil, false
+		}
+		log.Printf("Error getting notification target: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get target")
+		return nil, false
+	}
+
+	if target.UserID != authUser.ID {
+		apierror.Write(c, apierror.NotificationNotFound, "target not found")
+		return nil, false
+	}
+
+	return target, true
+}
@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/notification"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationTargetHandler_CreateTarget(t *testing.T) {
+	strPtr := func(s string) *string { return &s }
+
+	tests := []struct {
+		name       string
+		input      interface{}
+		wantStatus int
+	}{
+		{
+			name: "Valid Slack Target",
+			input: models.CreateNotificationTargetRequest{
+				Channel:    models.NotificationChannelSlack,
+				Name:       "Ops",
+				WebhookURL: strPtr("https://hooks.slack.com/services/T/B/X"),
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Valid Telegram Target",
+			input: models.CreateNotificationTargetRequest{
+				Channel:  models.NotificationChannelTelegram,
+				Name:     "Family",
+				BotToken: strPtr("123:abc"),
+				ChatID:   strPtr("-100123"),
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "Slack Webhook On Other Host",
+			input: models.CreateNotificationTargetRequest{
+				Channel:    models.NotificationChannelSlack,
+				Name:       "Ops",
+				WebhookURL: strPtr("https://example.com/hook"),
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Telegram Missing Chat ID",
+			input: models.CreateNotificationTargetRequest{
+				Channel:  models.NotificationChannelTelegram,
+				Name:     "Family",
+				BotToken: strPtr("123:abc"),
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Unsupported Channel",
+			input: map[string]string{
+				"channel": "sms",
+				"name":    "Phone",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewTestContext(t)
+			user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+			targetRepo := postgres.NewNotificationTargetRepository(tc.DB)
+			service := notification.NewService(
+				postgres.NewDeviceTokenRepository(tc.DB),
+				targetRepo,
+				postgres.NewNotificationPreferenceRepository(tc.DB),
+				postgres.NewNotificationDeliveryRepository(tc.DB),
+			)
+			handler := handlers.NewNotificationTargetHandler(targetRepo, service)
+			router := gin.New()
+			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+			router.Use(authMiddleware.AuthRequired())
+			router.POST("/notifications/targets", handler.CreateTarget)
+
+			body, err := json.Marshal(tt.input)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/notifications/targets", bytes.NewBuffer(body))
+			req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusCreated {
+				// Credentials must never be echoed back
+				assert.NotContains(t, w.Body.String(), "webhook_url")
+				assert.NotContains(t, w.Body.String(), "bot_token")
+			}
+		})
+	}
+}
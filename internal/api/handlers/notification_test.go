@@ -0,0 +1,190 @@
+package handlers_test
+
+import (
+	"bytes"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/notification"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupNotificationRouter(t *testing.T, tc *testutil.TestContext) *gin.Engine {
+	t.Helper()
+
+	vapidKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sender, err := notification.NewWebPushSender(base64.RawURLEncoding.EncodeToString(vapidKey.Bytes()), "mailto:admin@example.com", nil)
+	require.NoError(t, err)
+
+	deviceRepo := postgres.NewDeviceTokenRepository(tc.DB)
+	prefRepo := postgres.NewNotificationPreferenceRepository(tc.DB)
+	deliveryRepo := postgres.NewNotificationDeliveryRepository(tc.DB)
+	service := notification.NewService(deviceRepo, postgres.NewNotificationTargetRepository(tc.DB), prefRepo, deliveryRepo)
+	service.RegisterSender(sender)
+
+	handler := handlers.NewNotificationHandler(deviceRepo, prefRepo, deliveryRepo, service, sender.PublicKey())
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/notifications/devices", handler.ListDevices)
+	router.POST("/notifications/devices", handler.RegisterDevice)
+	router.PUT("/notifications/preferences", handler.UpdatePreferences)
+	return router
+}
+
+func TestNotificationHandler_RegisterDevice(t *testing.T) {
+	tests := []struct {
+		name       string
+		input      interface{}
+		wantStatus int
+	}{
+		{
+			name: "Valid WebPush Subscription",
+			input: models.RegisterDeviceRequest{
+				Channel: models.NotificationChannelWebPush,
+				Token:   "https://fcm.googleapis.com/fcm/send/subscription",
+				Keys:    &models.WebPushKeys{P256dh: "p256dh-key", Auth: "auth-secret"},
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "WebPush Unknown Push Service",
+			input: models.RegisterDeviceRequest{
+				Channel: models.NotificationChannelWebPush,
+				Token:   "https://169.254.169.254/latest/meta-data",
+				Keys:    &models.WebPushKeys{P256dh: "p256dh-key", Auth: "auth-secret"},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "WebPush Without Keys",
+			input: models.RegisterDeviceRequest{
+				Channel: models.NotificationChannelWebPush,
+				Token:   "https://fcm.googleapis.com/fcm/send/subscription",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Unconfigured Channel",
+			input: models.RegisterDeviceRequest{
+				Channel: models.NotificationChannelFCM,
+				Token:   "fcm-token",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name: "Invalid Channel",
+			input: map[string]string{
+				"channel": "sms",
+				"token":   "123",
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewTestContext(t)
+			user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+			token := tc.GetTestJWT(user.ID)
+			router := setupNotificationRouter(t, tc)
+
+			body, err := json.Marshal(tt.input)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("POST", "/notifications/devices", bytes.NewBuffer(body))
+			req.Header.Set("Authorization", "Bearer "+token)
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusCreated {
+				w = httptest.NewRecorder()
+				req, _ = http.NewRequest("GET", "/notifications/devices", nil)
+				req.Header.Set("Authorization", "Bearer "+token)
+				router.ServeHTTP(w, req)
+				require.Equal(t, http.StatusOK, w.Code)
+
+				var devices []models.DeviceToken
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &devices))
+				require.Len(t, devices, 1)
+				assert.Equal(t, models.NotificationChannelWebPush, devices[0].Channel)
+			}
+		})
+	}
+}
+
+func TestNotificationHandler_UpdatePreferences(t *testing.T) {
+	enabled := false
+
+	tests := []struct {
+		name       string
+		input      interface{}
+		wantStatus int
+	}{
+		{
+			name: "Disable Price Alerts",
+			input: models.UpdateNotificationPreferencesRequest{
+				Preferences: []models.NotificationPreferenceInput{
+					{Channel: models.NotificationChannelWebPush, AlertType: models.NotificationAlertPrice, Enabled: &enabled},
+				},
+			},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "Missing Enabled",
+			input: models.UpdateNotificationPreferencesRequest{
+				Preferences: []models.NotificationPreferenceInput{
+					{Channel: models.NotificationChannelWebPush, AlertType: models.NotificationAlertPrice},
+				},
+			},
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Empty Preferences",
+			input:      models.UpdateNotificationPreferencesRequest{},
+			wantStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewTestContext(t)
+			user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+			router := setupNotificationRouter(t, tc)
+
+			body, err := json.Marshal(tt.input)
+			require.NoError(t, err)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/notifications/preferences", bytes.NewBuffer(body))
+			req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			if tt.wantStatus == http.StatusOK {
+				var prefs []models.NotificationPreference
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prefs))
+				require.Len(t, prefs, 1)
+				assert.False(t, prefs[0].Enabled)
+			}
+		})
+	}
+}
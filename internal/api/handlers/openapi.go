@@ -0,0 +1,28 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/openapi"
+
+	"github.com/gin-gonic/gin"
+)
+
+// OpenAPI godoc
+// @Summary OpenAPI document
+// @Description Returns the API description as an OpenAPI 3 document
+// @Tags docs
+// @Produce json
+// @Success 200 {object} object
+// @Failure 500 {object} apierror.Problem
+// @Router /openapi.json [get]
+func OpenAPI(c *gin.Context) {
+	spec, err := openapi.Spec()
+	if err != nil {
+		log.Printf("Failed to build OpenAPI document: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to build OpenAPI document")
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", spec)
+}
@@ -0,0 +1,161 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository/memory"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizationHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@test.com", "password123", false)
+	carol := tc.CreateTestUser("carol", "carol@test.com", "password123", false)
+
+	consumptionRepo := memory.NewConsumptionRepository(memory.NewStore())
+	consumptionHandler := handlers.NewConsumptionHandler(consumptionRepo)
+	consumptionHandler.SetOrganizationRepository(tc.OrganizationRepo)
+	handler := handlers.NewOrganizationHandler(tc.OrganizationRepo, tc.UserRepo, tc.AuditRepo)
+	userHandler := tc.NewUserHandler()
+
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/organizations", handler.ListOrganizations)
+	router.POST("/organizations", handler.CreateOrganization)
+	router.GET("/organizations/:id", handler.GetOrganization)
+	router.PUT("/organizations/:id", handler.UpdateOrganization)
+	router.DELETE("/organizations/:id", handler.DeleteOrganization)
+	router.GET("/organizations/:id/members", handler.ListMembers)
+	router.POST("/organizations/:id/members", handler.AddMember)
+	router.PUT("/organizations/:id/members/:user_id", handler.UpdateMember)
+	router.DELETE("/organizations/:id/members/:user_id", handler.RemoveMember)
+	router.GET("/users", userHandler.ListUsers)
+	router.GET("/users/:id", userHandler.GetUser)
+	router.GET("/consumption", consumptionHandler.ListConsumption)
+
+	send := func(method, path string, userID uuid.UUID, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send("POST", "/organizations", alice.ID, models.CreateOrganizationRequest{Name: "Andersson household"})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var org models.Organization
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &org))
+	orgPath := "/organizations/" + org.ID.String()
+
+	t.Run("Members", func(t *testing.T) {
+		w := send("POST", orgPath+"/members", alice.ID, models.AddOrganizationMemberRequest{Username: "bob", Role: models.OrganizationRoleMember})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusConflict, send("POST", orgPath+"/members", alice.ID, models.AddOrganizationMemberRequest{Username: "bob", Role: models.OrganizationRoleAdmin}).Code)
+		assert.Equal(t, http.StatusBadRequest, send("POST", orgPath+"/members", alice.ID, models.AddOrganizationMemberRequest{Username: "nobody", Role: models.OrganizationRoleMember}).Code)
+
+		// Members can't add others, and non-members don't see the organization at all
+		assert.Equal(t, http.StatusForbidden, send("POST", orgPath+"/members", bob.ID, models.AddOrganizationMemberRequest{Username: "carol", Role: models.OrganizationRoleMember}).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", orgPath, carol.ID, nil).Code)
+		assert.Equal(t, http.StatusOK, send("GET", orgPath, admin.ID, nil).Code)
+
+		w = send("GET", orgPath+"/members", bob.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		var members []models.OrganizationMember
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &members))
+		require.Len(t, members, 2)
+		assert.Equal(t, "alice", members[0].Username)
+		assert.Equal(t, models.OrganizationRoleOwner, members[0].Role)
+		assert.Equal(t, models.OrganizationRoleMember, members[1].Role)
+
+		w = send("GET", "/organizations", bob.ID, nil)
+		var page models.Page[models.Organization]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, 1, page.Total)
+		assert.Equal(t, http.StatusForbidden, send("GET", "/organizations?all=true", bob.ID, nil).Code)
+		w = send("GET", "/organizations", carol.ID, nil)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, 0, page.Total)
+	})
+
+	t.Run("Roles", func(t *testing.T) {
+		bobPath := orgPath + "/members/" + bob.ID.String()
+		alicePath := orgPath + "/members/" + alice.ID.String()
+
+		// Admins manage members but not owners
+		require.Equal(t, http.StatusOK, send("PUT", bobPath, alice.ID, models.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleAdmin}).Code)
+		assert.Equal(t, http.StatusOK, send("PUT", orgPath, bob.ID, models.UpdateOrganizationRequest{Name: "The Anderssons"}).Code)
+		assert.Equal(t, http.StatusForbidden, send("PUT", alicePath, bob.ID, models.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleMember}).Code)
+		assert.Equal(t, http.StatusForbidden, send("DELETE", orgPath, bob.ID, nil).Code)
+
+		// The last owner can't step down or leave
+		assert.Equal(t, http.StatusConflict, send("PUT", alicePath, alice.ID, models.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleAdmin}).Code)
+		assert.Equal(t, http.StatusConflict, send("DELETE", alicePath, alice.ID, nil).Code)
+	})
+
+	t.Run("User Visibility", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("GET", "/users/"+alice.ID.String(), bob.ID, nil).Code)
+		assert.Equal(t, http.StatusForbidden, send("GET", "/users/"+alice.ID.String(), carol.ID, nil).Code)
+
+		w := send("GET", "/users?organization_id="+org.ID.String(), bob.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page models.Page[models.User]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, 2, page.Total)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/users?organization_id="+org.ID.String(), carol.ID, nil).Code)
+	})
+
+	t.Run("Consumption", func(t *testing.T) {
+		start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, consumptionRepo.CreateBatch(context.Background(), []models.ConsumptionRecord{
+			{UserID: alice.ID, MeterID: "meter", Timestamp: start, KWh: 1.5},
+		}))
+		list := func(userID uuid.UUID, member uuid.UUID) *httptest.ResponseRecorder {
+			query := url.Values{
+				"start_time": {start.Format(time.RFC3339)},
+				"end_time":   {start.Add(time.Hour).Format(time.RFC3339)},
+				"user_id":    {member.String()},
+			}
+			return send("GET", "/consumption?"+query.Encode(), userID, nil)
+		}
+
+		// Bob is an admin of the organization since the roles test
+		w := list(bob.ID, alice.ID)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page models.Page[models.ConsumptionRecord]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, 1, page.Total)
+
+		require.Equal(t, http.StatusOK, send("PUT", orgPath+"/members/"+bob.ID.String(), alice.ID, models.UpdateOrganizationMemberRequest{Role: models.OrganizationRoleMember}).Code)
+		assert.Equal(t, http.StatusForbidden, list(bob.ID, alice.ID).Code)
+		assert.Equal(t, http.StatusForbidden, list(carol.ID, alice.ID).Code)
+	})
+
+	t.Run("Leave And Delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("DELETE", orgPath+"/members/"+bob.ID.String(), bob.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", orgPath, bob.ID, nil).Code)
+		assert.Equal(t, http.StatusNoContent, send("DELETE", orgPath, alice.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", orgPath, alice.ID, nil).Code)
+	})
+}
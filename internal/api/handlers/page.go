@@ -0,0 +1,51 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// wantsEnvelope reports whether a list response should be wrapped in a models.Page.
+// Clients written for the bare arrays list endpoints used to return pass envelope=false.
+func wantsEnvelope(c *gin.Context) bool {
+	envelope, err := strconv.ParseBool(c.DefaultQuery("envelope", "true"))
+	return err != nil || envelope
+}
+
+// respondPage responds with the items found with limit and offset as a models.Page,
+// counting the items across all pages with total, or as a bare array when the client
+// turned the envelope off. failure is the error message used when counting fails.
+func respondPage[T any](c *gin.Context, items []T, limit, offset *int, total func() (int, error), failure string) {
+	if page, ok := pageOf(c, items, limit, offset, total, failure); ok {
+		c.JSON(http.StatusOK, page)
+	}
+}
+
+// pageOf returns what respondPage responds with. It writes the error response and
+// returns false when counting fails.
+func pageOf[T any](c *gin.Context, items []T, limit, offset *int, total func() (int, error), failure string) (interface{}, bool) {
+	if !wantsEnvelope(c) {
+		return items, true
+	}
+
+	count, err := total()
+	if err != nil {
+		log.Printf("Error counting items for %s: %v", c.Request.URL.Path, err)
+		apierror.Write(c, apierror.Internal, failure)
+		return nil, false
+	}
+	return models.NewPage(items, count, valueOr(limit, 0), valueOr(offset, 0)), true
+}
+
+// valueOr returns the value p points to, or fallback when p is nil
+func valueOr[T any](p *T, fallback T) T {
+	if p == nil {
+		return fallback
+	}
+	return *p
+}
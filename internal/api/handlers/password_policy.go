@@ -0,0 +1,42 @@
+package handlers
+
+import (
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/config"
+	"wattwatch/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// passwordPolicy returns the configured policy new passwords must meet
+func passwordPolicy(cfg *config.Config) validation.PasswordPolicy {
+	settings := cfg.PasswordSettings()
+	return validation.PasswordPolicy{
+		MinLength:        settings.MinLength,
+		RequireUppercase: settings.RequireUppercase,
+		RequireLowercase: settings.RequireLowercase,
+		RequireDigit:     settings.RequireDigit,
+		RequireSymbol:    settings.RequireSymbol,
+		MinStrength:      settings.MinStrength,
+		DisallowUserInfo: settings.DisallowUserInfo,
+	}
+}
+
+// checkPassword reports whether password meets the password policy for the account with
+// username and email, and responds with the rules it breaks as field errors otherwise
+func checkPassword(c *gin.Context, cfg *config.Config, password, username string, email *string) bool {
+	userInfo := []string{username}
+	if email != nil {
+		userInfo = append(userInfo, *email)
+	}
+	violations := passwordPolicy(cfg).Check(password, userInfo...)
+	if len(violations) == 0 {
+		return true
+	}
+	fields := make([]validation.FieldError, len(violations))
+	for i, v := range violations {
+		fields[i] = validation.FieldError{Field: "password", Rule: string(v.Rule), Message: v.Message}
+	}
+	apierror.Respond(c, &apierror.Error{Code: apierror.PasswordPolicy, Detail: "password does not meet the password policy", Errors: fields})
+	return false
+}
@@ -0,0 +1,285 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/alert"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// PriceAlertHandler handles the price alerts users are notified about
+type PriceAlertHandler struct {
+	alertRepo repository.PriceAlertRepository
+}
+
+// NewPriceAlertHandler creates a new PriceAlertHandler
+func NewPriceAlertHandler(alertRepo repository.PriceAlertRepository) *PriceAlertHandler {
+	return &PriceAlertHandler{
+		alertRepo: alertRepo,
+	}
+}
+
+// ListAlerts godoc
+// @Summary List price alerts
+// @Description Lists the authenticated user's price alerts
+// @Tags alerts
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.PriceAlert
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /alerts [get]
+func (h *PriceAlertHandler) ListAlerts(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	alerts, err := h.alertRepo.ListByUserID(c.Request.Context(), authUser.ID)
+	if err != nil {
+		log.Printf("Error listing price alerts: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list alerts")
+		return
+	}
+
+	c.JSON(http.StatusOK, alerts)
+}
+
+// CreateAlert godoc
+// @Summary Create a price alert
+// @Description Notifies the authenticated user when new spot prices of the zone and currency are above or below the threshold. The optional window (HH:MM in the zone's timezone) limits the hours covered and wraps past midnight when it ends before it starts.
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreatePriceAlertRequest true "Alert"
+// @Success 201 {object} models.PriceAlert
+// @Failure 400 {object} apierror.Problem "Invalid request or unknown zone or currency"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /alerts [post]
+func (h *PriceAlertHandler) CreateAlert(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreatePriceAlertRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	priceAlert := &models.PriceAlert{
+		UserID:      authUser.ID,
+		ZoneID:      req.ZoneID,
+		CurrencyID:  req.CurrencyID,
+		Direction:   req.Direction,
+		Threshold:   *req.Threshold,
+		WindowStart: req.WindowStart,
+		WindowEnd:   req.WindowEnd,
+		Enabled:     req.Enabled == nil || *req.Enabled,
+	}
+	if err := validateAlertWindow(priceAlert); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.alertRepo.Create(c.Request.Context(), priceAlert); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.InvalidRequest, "unknown zone or currency")
+			return
+		}
+		log.Printf("Error creating price alert: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to create alert")
+		return
+	}
+
+	c.JSON(http.StatusCreated, priceAlert)
+}
+
+// GetAlert godoc
+// @Summary Get a price alert
+// @Description Returns one of the authenticated user's price alerts
+// @Tags alerts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Alert ID (UUID)"
+// @Success 200 {object} models.PriceAlert
+// @Failure 400 {object} apierror.Problem "Invalid alert ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Alert not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /alerts/{id} [get]
+func (h *PriceAlertHandler) GetAlert(c *gin.Context) {
+	priceAlert, ok := h.getOwnedAlert(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, priceAlert)
+}
+
+// UpdateAlert godoc
+// @Summary Update a price alert
+// @Description Updates the threshold, window or state of one of the authenticated user's price alerts. Empty window_start and window_end remove the window.
+// @Tags alerts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Alert ID (UUID)"
+// @Param request body models.UpdatePriceAlertRequest true "Alert changes"
+// @Success 200 {object} models.PriceAlert
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Alert not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /alerts/{id} [put]
+func (h *PriceAlertHandler) UpdateAlert(c *gin.Context) {
+	priceAlert, ok := h.getOwnedAlert(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdatePriceAlertRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Direction != nil {
+		priceAlert.Direction = *req.Direction
+	}
+	if req.Threshold != nil {
+		priceAlert.Threshold = *req.Threshold
+	}
+	if req.WindowStart != nil {
+		priceAlert.WindowStart = emptyToNil(*req.WindowStart)
+	}
+	if req.WindowEnd != nil {
+		priceAlert.WindowEnd = emptyToNil(*req.WindowEnd)
+	}
+	if req.Enabled != nil {
+		priceAlert.Enabled = *req.Enabled
+	}
+	if err := validateAlertWindow(priceAlert); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	if err := h.alertRepo.Update(c.Request.Context(), priceAlert); err != nil {
+		log.Printf("Error updating price alert: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to update alert")
+		return
+	}
+
+	c.JSON(http.StatusOK, priceAlert)
+}
+
+// DeleteAlert godoc
+// @Summary Delete a price alert
+// @Description Removes one of the authenticated user's price alerts
+// @Tags alerts
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Alert ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid alert ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Alert not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /alerts/{id} [delete]
+func (h *PriceAlertHandler) DeleteAlert(c *gin.Context) {
+	priceAlert, ok := h.getOwnedAlert(c)
+	if !ok {
+		return
+	}
+
+	if err := h.alertRepo.Delete(c.Request.Context(), priceAlert.ID); err != nil {
+		log.Printf("Error deleting price alert: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to delete alert")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getOwnedAlert loads the alert from the id path parameter and writes an error
+// response if it does not exist or belongs to another user
+func (h *PriceAlertHandler) getOwnedAlert(c *gin.Context) (*models.PriceAlert, bool) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid alert ID")
+		return nil, false
+	}
+
+	priceAlert, err := h.alertRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.AlertNotFound, "alert not found")
+			return nil, false
+		}
+		log.Printf("Error getting price alert: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get alert")
+		return nil, false
+	}
+
+	if priceAlert.UserID != authUser.ID {
+		apierror.Write(c, apierror.AlertNotFound, "alert not found")
+		return nil, false
+	}
+
+	return priceAlert, true
+}
+
+// validateAlertWindow checks that the alert's window has both ends or neither, as
+// distinct HH:MM times of day
+func validateAlertWindow(priceAlert *models.PriceAlert) error {
+	if priceAlert.WindowStart == nil && priceAlert.WindowEnd == nil {
+		return nil
+	}
+	if priceAlert.WindowStart == nil || priceAlert.WindowEnd == nil {
+		return errors.New("window_start and window_end must be set together")
+	}
+	start, err := time.Parse(alert.WindowLayout, *priceAlert.WindowStart)
+	if err != nil {
+		return errors.New("window_start must be a time of day as HH:MM")
+	}
+	end, err := time.Parse(alert.WindowLayout, *priceAlert.WindowEnd)
+	if err != nil {
+		return errors.New("window_end must be a time of day as HH:MM")
+	}
+	if start.Equal(end) {
+		return errors.New("window_start and window_end must differ")
+	}
+	return nil
+}
+
+// emptyToNil returns nil for an empty string and a pointer to s otherwise
+func emptyToNil(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
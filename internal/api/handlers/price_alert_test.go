@@ -0,0 +1,137 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceAlertHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@test.com", "password123", false)
+	zone, err := tc.ZoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := tc.CurrencyRepo.GetByName(context.Background(), "EUR")
+	require.NoError(t, err)
+
+	handler := handlers.NewPriceAlertHandler(tc.PriceAlertRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/alerts", handler.ListAlerts)
+	router.POST("/alerts", handler.CreateAlert)
+	router.GET("/alerts/:id", handler.GetAlert)
+	router.PUT("/alerts/:id", handler.UpdateAlert)
+	router.DELETE("/alerts/:id", handler.DeleteAlert)
+
+	send := func(method, path string, userID uuid.UUID, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	strPtr := func(s string) *string { return &s }
+	threshold := 150.0
+
+	t.Run("Create Validation", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			input      models.CreatePriceAlertRequest
+			wantStatus int
+		}{
+			{
+				name:       "Missing Threshold",
+				input:      models.CreatePriceAlertRequest{ZoneID: zone.ID, CurrencyID: currency.ID, Direction: models.PriceAlertAbove},
+				wantStatus: http.StatusBadRequest,
+			},
+			{
+				name:       "Unknown Zone",
+				input:      models.CreatePriceAlertRequest{ZoneID: uuid.New(), CurrencyID: currency.ID, Direction: models.PriceAlertAbove, Threshold: &threshold},
+				wantStatus: http.StatusBadRequest,
+			},
+			{
+				name: "Half A Window",
+				input: models.CreatePriceAlertRequest{ZoneID: zone.ID, CurrencyID: currency.ID, Direction: models.PriceAlertAbove, Threshold: &threshold,
+					WindowStart: strPtr("06:00")},
+				wantStatus: http.StatusBadRequest,
+			},
+			{
+				name: "Invalid Window Time",
+				input: models.CreatePriceAlertRequest{ZoneID: zone.ID, CurrencyID: currency.ID, Direction: models.PriceAlertAbove, Threshold: &threshold,
+					WindowStart: strPtr("6am"), WindowEnd: strPtr("22:00")},
+				wantStatus: http.StatusBadRequest,
+			},
+		}
+
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := send("POST", "/alerts", alice.ID, tt.input)
+				assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			})
+		}
+	})
+
+	w := send("POST", "/alerts", alice.ID, models.CreatePriceAlertRequest{
+		ZoneID: zone.ID, CurrencyID: currency.ID, Direction: models.PriceAlertAbove, Threshold: &threshold,
+		WindowStart: strPtr("22:00"), WindowEnd: strPtr("06:00"),
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var created models.PriceAlert
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+	assert.True(t, created.Enabled)
+	assert.Equal(t, "22:00", *created.WindowStart)
+	alertPath := "/alerts/" + created.ID.String()
+
+	t.Run("Ownership", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, send("GET", alertPath, alice.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", alertPath, bob.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("DELETE", alertPath, bob.ID, nil).Code)
+
+		var alerts []models.PriceAlert
+		require.NoError(t, json.Unmarshal(send("GET", "/alerts", bob.ID, nil).Body.Bytes(), &alerts))
+		assert.Empty(t, alerts)
+		require.NoError(t, json.Unmarshal(send("GET", "/alerts", alice.ID, nil).Body.Bytes(), &alerts))
+		assert.Len(t, alerts, 1)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		below := models.PriceAlertBelow
+		disabled := false
+		w := send("PUT", alertPath, alice.ID, models.UpdatePriceAlertRequest{
+			Direction: &below, WindowStart: strPtr(""), WindowEnd: strPtr(""), Enabled: &disabled,
+		})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.PriceAlert
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, models.PriceAlertBelow, updated.Direction)
+		assert.Equal(t, 150.0, updated.Threshold)
+		assert.Nil(t, updated.WindowStart)
+		assert.False(t, updated.Enabled)
+
+		assert.Equal(t, http.StatusBadRequest, send("PUT", alertPath, alice.ID, models.UpdatePriceAlertRequest{WindowStart: strPtr("08:00")}).Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("DELETE", alertPath, alice.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", alertPath, alice.ID, nil).Code)
+	})
+}
@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxForecastRange is the longest time range forecasts are listed for at once
+const maxForecastRange = 31 * 24 * time.Hour
+
+// PriceForecastHandler handles forecasts of spot prices and merging them with the
+// published prices
+type PriceForecastHandler struct {
+	forecastRepo  repository.PriceForecastRepository
+	spotPriceRepo repository.SpotPriceRepository
+	zoneRepo      repository.ZoneRepository
+	currencyRepo  repository.CurrencyRepository
+	// preferences supplies the zone and currency authenticated users picked, nil when
+	// they must always be given
+	preferences repository.UserPreferenceRepository
+}
+
+// NewPriceForecastHandler creates a new PriceForecastHandler
+func NewPriceForecastHandler(forecastRepo repository.PriceForecastRepository, spotPriceRepo repository.SpotPriceRepository, zoneRepo repository.ZoneRepository, currencyRepo repository.CurrencyRepository) *PriceForecastHandler {
+	return &PriceForecastHandler{
+		forecastRepo:  forecastRepo,
+		spotPriceRepo: spotPriceRepo,
+		zoneRepo:      zoneRepo,
+		currencyRepo:  currencyRepo,
+	}
+}
+
+// SetPreferences makes authenticated requests default to the zone and currency the user
+// picked in repo
+func (h *PriceForecastHandler) SetPreferences(repo repository.UserPreferenceRepository) {
+	h.preferences = repo
+}
+
+// forecastQuery is the zone, currency and range a forecast listing was asked for
+type forecastQuery struct {
+	zone      *models.Zone
+	currency  *models.Currency
+	startTime time.Time
+	endTime   time.Time
+}
+
+// parseForecastQuery reads the zone, currency and range of a listing and writes an error
+// response if they are missing or invalid
+func (h *PriceForecastHandler) parseForecastQuery(c *gin.Context) (*forecastQuery, bool) {
+	defaults := userDefaults(c, h.preferences)
+	zoneName := c.DefaultQuery("zone", defaults.zone)
+	if zoneName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "zone is required")
+		return nil, false
+	}
+	currencyName := c.DefaultQuery("currency", defaults.currency)
+	if currencyName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "currency is required")
+		return nil, false
+	}
+
+	q := &forecastQuery{}
+	var err error
+	if q.startTime, err = time.Parse(time.RFC3339, c.Query("start_time")); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "start_time is required, use RFC3339")
+		return nil, false
+	}
+	if q.endTime, err = time.Parse(time.RFC3339, c.Query("end_time")); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "end_time is required, use RFC3339")
+		return nil, false
+	}
+	if !q.endTime.After(q.startTime) {
+		apierror.Write(c, apierror.InvalidRequest, "end_time must be after start_time")
+		return nil, false
+	}
+	if q.endTime.Sub(q.startTime) > maxForecastRange {
+		apierror.Write(c, apierror.InvalidRequest, "date range cannot exceed 31 days")
+		return nil, false
+	}
+
+	q.zone, err = h.zoneRepo.GetByName(c.Request.Context(), zoneName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+		return nil, false
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch zone")
+		return nil, false
+	}
+	q.currency, err = h.currencyRepo.GetByName(c.Request.Context(), currencyName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+		return nil, false
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch currency")
+		return nil, false
+	}
+	return q, true
+}
+
+// ListForecasts godoc
+// @Summary List price forecasts
+// @Description Returns the forecasts of the spot prices of a zone and currency from start_time until end_time, ordered by time and source. Forecasts are kept apart from the published spot prices, each source keeping its latest forecast of a time.
+// @Tags forecasts
+// @Produce json
+// @Security BearerAuth
+// @Param zone query string false "Zone name (e.g., 'SE3'), required unless the user picked a default"
+// @Param currency query string false "Currency name (e.g., 'SEK'), required unless the user picked a default"
+// @Param start_time query string true "Start of the range (RFC3339)"
+// @Param end_time query string true "End of the range, exclusive (RFC3339), at most 31 days after start_time"
+// @Param source query string false "Only the forecasts of this source"
+// @Success 200 {array} models.PriceForecast
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /forecasts [get]
+func (h *PriceForecastHandler) ListForecasts(c *gin.Context) {
+	q, ok := h.parseForecastQuery(c)
+	if !ok {
+		return
+	}
+
+	forecasts, err := h.forecastRepo.List(c.Request.Context(), repository.PriceForecastFilter{
+		ZoneID:     q.zone.ID,
+		CurrencyID: q.currency.ID,
+		StartTime:  q.startTime,
+		EndTime:    q.endTime,
+		Source:     c.Query("source"),
+	})
+	if err != nil {
+		log.Printf("Error listing price forecasts: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to fetch forecasts")
+		return
+	}
+
+	c.JSON(http.StatusOK, forecasts)
+}
+
+// CreateForecasts godoc
+// @Summary Store price forecasts
+// @Description Stores forecasts of spot prices made by a source, replacing those it made earlier for the same times, zones and currencies. Prices are in the unit spot prices are stored in, hundredths of the currency per kWh.
+// @Tags forecasts
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreatePriceForecastsRequest true "Forecasts"
+// @Success 201 {array} models.PriceForecast
+// @Failure 400 {object} apierror.Problem "Invalid request, duplicate forecast or unknown zone or currency"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /forecasts [post]
+func (h *PriceForecastHandler) CreateForecasts(c *gin.Context) {
+	var req models.CreatePriceForecastsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// A statement can't upsert the same forecast twice
+	type forecastKey struct {
+		timestamp          int64
+		zoneID, currencyID uuid.UUID
+	}
+	seen := make(map[forecastKey]bool, len(req.Forecasts))
+	forecasts := make([]models.PriceForecast, len(req.Forecasts))
+	for i, f := range req.Forecasts {
+		if *f.Price < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "price cannot be negative")
+			return
+		}
+
+		key := forecastKey{f.Timestamp.UnixNano(), f.ZoneID, f.CurrencyID}
+		if seen[key] {
+			apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("duplicate forecast at index %d", i))
+			return
+		}
+		seen[key] = true
+
+		forecasts[i] = models.PriceForecast{
+			Timestamp:    f.Timestamp,
+			ZoneID:       f.ZoneID,
+			CurrencyID:   f.CurrencyID,
+			Source:       req.Source,
+			Price:        *f.Price,
+			HorizonHours: *f.HorizonHours,
+		}
+	}
+
+	if err := h.forecastRepo.Upsert(c.Request.Context(), forecasts); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.InvalidRequest, "unknown zone or currency")
+			return
+		}
+		log.Printf("Error storing price forecasts: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to store forecasts")
+		return
+	}
+
+	c.JSON(http.StatusCreated, forecasts)
+}
+
+// ListForecastedSpotPrices godoc
+// @Summary List spot prices with forecasts
+// @Description Returns the published spot prices of a zone and currency from start_time until end_time, followed by forecasts for the part of the range after the last published price, such as the days after tomorrow. Each price is flagged as a forecast or not. Without a source the most recently stored forecast of each time is used.
+// @Tags spot-prices
+// @Produce json
+// @Security BearerAuth
+// @Param zone query string false "Zone name (e.g., 'SE3'), required unless the user picked a default"
+// @Param currency query string false "Currency name (e.g., 'SEK'), required unless the user picked a default"
+// @Param start_time query string true "Start of the range (RFC3339)"
+// @Param end_time query string true "End of the range, exclusive (RFC3339), at most 31 days after start_time"
+// @Param source query string false "Only use the forecasts of this source"
+// @Success 200 {object} models.ForecastedSpotPrices
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /spot-prices/forecast [get]
+func (h *PriceForecastHandler) ListForecastedSpotPrices(c *gin.Context) {
+	q, ok := h.parseForecastQuery(c)
+	if !ok {
+		return
+	}
+
+	// The spot price filter includes the end, the range here doesn't
+	prices, err := h.spotPriceRepo.List(c.Request.Context(), repository.SpotPriceFilter{
+		ZoneID:     &q.zone.ID,
+		CurrencyID: &q.currency.ID,
+		StartTime:  &q.startTime,
+		EndTime:    &q.endTime,
+		OrderBy:    "timestamp",
+	})
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch spot prices")
+		return
+	}
+
+	result := models.ForecastedSpotPrices{Zone: q.zone.Name, Currency: q.currency.Name, Prices: []models.ForecastedSpotPrice{}}
+	forecastStart := q.startTime
+	for _, sp := range prices {
+		if !sp.Timestamp.Before(q.endTime) {
+			continue
+		}
+		result.Prices = append(result.Prices, models.ForecastedSpotPrice{Timestamp: sp.Timestamp, Price: sp.Price})
+		forecastStart = sp.Timestamp.Add(time.Nanosecond)
+	}
+
+	forecasts, err := h.forecastRepo.List(c.Request.Context(), repository.PriceForecastFilter{
+		ZoneID:     q.zone.ID,
+		CurrencyID: q.currency.ID,
+		StartTime:  forecastStart,
+		EndTime:    q.endTime,
+		Source:     c.Query("source"),
+		Latest:     true,
+	})
+	if err != nil {
+		log.Printf("Error listing price forecasts: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to fetch forecasts")
+		return
+	}
+	for _, f := range forecasts {
+		result.Prices = append(result.Prices, models.ForecastedSpotPrice{
+			Timestamp:    f.Timestamp,
+			Price:        f.Price,
+			Forecast:     true,
+			Source:       f.Source,
+			HorizonHours: &f.HorizonHours,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,138 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceForecastHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+	zone, err := zoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+
+	// Prices are published for the first two hours
+	start := time.Date(2025, 3, 20, 23, 0, 0, 0, time.UTC)
+	require.NoError(t, spotPriceRepo.CreateBatch(ctx, []models.SpotPrice{
+		{Timestamp: start, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 10},
+		{Timestamp: start.Add(time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: 20},
+	}))
+
+	handler := handlers.NewPriceForecastHandler(memory.NewPriceForecastRepository(store), spotPriceRepo, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.GET("/forecasts", handler.ListForecasts)
+	router.POST("/forecasts", handler.CreateForecasts)
+	router.GET("/spot-prices/forecast", handler.ListForecastedSpotPrices)
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	forecast := func(hour int, price float64) models.CreatePriceForecastRequest {
+		horizon := 24 + hour
+		return models.CreatePriceForecastRequest{
+			Timestamp: start.Add(time.Duration(hour) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID,
+			Price: &price, HorizonHours: &horizon,
+		}
+	}
+	rangeQuery := "zone=SE3&currency=SEK&start_time=2025-03-20T23:00:00Z&end_time=2025-03-21T03:00:00Z"
+
+	t.Run("Create Validation", func(t *testing.T) {
+		duplicate := models.CreatePriceForecastsRequest{Source: "model", Forecasts: []models.CreatePriceForecastRequest{forecast(1, 5), forecast(1, 6)}}
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/forecasts", duplicate).Code)
+		negative := models.CreatePriceForecastsRequest{Source: "model", Forecasts: []models.CreatePriceForecastRequest{forecast(1, -5)}}
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/forecasts", negative).Code)
+		unknown := forecast(1, 5)
+		unknown.ZoneID = uuid.New()
+		w := send("POST", "/forecasts", models.CreatePriceForecastsRequest{Source: "model", Forecasts: []models.CreatePriceForecastRequest{unknown}})
+		assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+		assert.Equal(t, http.StatusBadRequest, send("POST", "/forecasts", models.CreatePriceForecastsRequest{Forecasts: []models.CreatePriceForecastRequest{forecast(1, 5)}}).Code)
+	})
+
+	// Two sources forecast the hours, the second one more recently
+	for _, req := range []models.CreatePriceForecastsRequest{
+		{Source: "model", Forecasts: []models.CreatePriceForecastRequest{forecast(0, 11), forecast(1, 21), forecast(2, 31), forecast(3, 41)}},
+		{Source: "other", Forecasts: []models.CreatePriceForecastRequest{forecast(2, 32)}},
+	} {
+		w := send("POST", "/forecasts", req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		time.Sleep(time.Millisecond)
+	}
+
+	t.Run("List", func(t *testing.T) {
+		w := send("GET", "/forecasts?"+rangeQuery, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var forecasts []models.PriceForecast
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &forecasts))
+		assert.Len(t, forecasts, 5)
+
+		w = send("GET", "/forecasts?source=other&"+rangeQuery, nil)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &forecasts))
+		require.Len(t, forecasts, 1)
+		assert.Equal(t, 26, forecasts[0].HorizonHours)
+	})
+
+	t.Run("Merged With Spot Prices", func(t *testing.T) {
+		w := send("GET", "/spot-prices/forecast?"+rangeQuery, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result models.ForecastedSpotPrices
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "SE3", result.Zone)
+		require.Len(t, result.Prices, 4)
+		for i, want := range []struct {
+			price    float64
+			forecast bool
+			source   string
+		}{{10, false, ""}, {20, false, ""}, {32, true, "other"}, {41, true, "model"}} {
+			p := result.Prices[i]
+			assert.True(t, start.Add(time.Duration(i)*time.Hour).Equal(p.Timestamp))
+			assert.Equal(t, want.price, p.Price, i)
+			assert.Equal(t, want.forecast, p.Forecast, i)
+			assert.Equal(t, want.source, p.Source, i)
+			assert.Equal(t, want.forecast, p.HorizonHours != nil, i)
+		}
+		var raw struct {
+			Prices []map[string]any `json:"prices"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &raw))
+		assert.NotContains(t, raw.Prices[0], "horizon_hours", "published prices leave out the forecast fields")
+		assert.Contains(t, raw.Prices[2], "horizon_hours")
+
+		w = send("GET", "/spot-prices/forecast?source=model&"+rangeQuery, nil)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		require.Len(t, result.Prices, 4)
+		assert.Equal(t, 31.0, result.Prices[2].Price)
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/spot-prices/forecast?zone=SE3&currency=SEK", nil).Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/forecasts?currency=SEK&start_time=2025-03-20T23:00:00Z&end_time=2025-03-21T03:00:00Z", nil).Code)
+		assert.Equal(t, http.StatusBadRequest, send("GET", "/forecasts?zone=SE3&currency=SEK&start_time=2025-03-20T23:00:00Z&end_time=2025-05-21T03:00:00Z", nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", "/forecasts?zone=XX&currency=SEK&start_time=2025-03-20T23:00:00Z&end_time=2025-03-21T03:00:00Z", nil).Code)
+	})
+}
@@ -0,0 +1,168 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ProductionHandler handles the energy users' solar panels and other generation produce
+// and export to the grid
+type ProductionHandler struct {
+	repo   repository.ProductionRepository
+	limits ListLimits
+}
+
+// NewProductionHandler creates a new ProductionHandler
+func NewProductionHandler(repo repository.ProductionRepository) *ProductionHandler {
+	return &ProductionHandler{repo: repo, limits: DefaultListLimits}
+}
+
+// SetListLimits sets the maximum number of records listed, which is also the default
+func (h *ProductionHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// CreateProduction godoc
+// @Summary Ingest production records
+// @Description Stores production readings of the authenticated user in a single batch: the energy produced in each interval and the part of it exported to the grid. A record for a meter and timestamp that already exists has its kWh replaced.
+// @Tags production
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param records body models.CreateProductionRequest true "Records to create or update"
+// @Success 201 {array} models.ProductionRecord
+// @Failure 400 {object} apierror.Problem "Invalid request body or duplicate records"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /production [post]
+func (h *ProductionHandler) CreateProduction(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreateProductionRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	// A batch can't set a record twice, the database would reject the upsert
+	seen := make(map[string]bool, len(req.Records))
+	records := make([]models.ProductionRecord, len(req.Records))
+	for i, r := range req.Records {
+		key := r.MeterID + "@" + strconv.FormatInt(r.Timestamp.UnixNano(), 10)
+		if seen[key] {
+			apierror.Write(c, apierror.InvalidRequest,
+				fmt.Sprintf("duplicate record for meter %s at %s", r.MeterID, r.Timestamp.Format(time.RFC3339)))
+			return
+		}
+		seen[key] = true
+
+		records[i] = models.ProductionRecord{
+			UserID:      authUser.ID,
+			MeterID:     r.MeterID,
+			Timestamp:   r.Timestamp,
+			ProducedKWh: *r.ProducedKWh,
+			ExportedKWh: *r.ExportedKWh,
+		}
+	}
+
+	if err := h.repo.CreateBatch(c.Request.Context(), records); err != nil {
+		log.Printf("Error storing production records: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to store production")
+		return
+	}
+
+	c.JSON(http.StatusCreated, records)
+}
+
+// ListProduction godoc
+// @Summary List production records
+// @Description Returns the authenticated user's production readings within a time range (max 31 days), oldest first
+// @Tags production
+// @Produce json
+// @Security BearerAuth
+// @Param start_time query string true "Start time (RFC3339)"
+// @Param end_time query string true "End time (RFC3339)"
+// @Param meter_id query string false "Filter by meter"
+// @Param limit query integer false "Limit results (default and maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the records in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.ProductionRecord]
+// @Failure 400 {object} apierror.Problem "Invalid parameters or time range exceeds 31 days"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /production [get]
+func (h *ProductionHandler) ListProduction(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	filter := repository.ProductionFilter{UserID: authUser.ID}
+
+	startTime, err := time.Parse(time.RFC3339, c.Query("start_time"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "start_time is required in RFC3339 format")
+		return
+	}
+	endTime, err := time.Parse(time.RFC3339, c.Query("end_time"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "end_time is required in RFC3339 format")
+		return
+	}
+	if endTime.Before(startTime) {
+		apierror.Write(c, apierror.InvalidRequest, "end_time must be after start_time")
+		return
+	}
+	if endTime.Sub(startTime) > maxConsumptionRange {
+		apierror.Write(c, apierror.InvalidRequest, "time range cannot exceed 31 days")
+		return
+	}
+	filter.StartTime, filter.EndTime = &startTime, &endTime
+
+	if meterID := c.Query("meter_id"); meterID != "" {
+		filter.MeterID = &meterID
+	}
+
+	limit, err := h.limits.limit(c, h.limits.Max)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	filter.Limit = &limit
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	records, err := h.repo.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error listing production records: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list production")
+		return
+	}
+
+	respondPage(c, records, filter.Limit, filter.Offset, func() (int, error) {
+		return h.repo.Total(c.Request.Context(), filter)
+	}, "failed to list production")
+}
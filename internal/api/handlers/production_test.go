@@ -0,0 +1,104 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductionHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@test.com", "password123", false)
+
+	handler := handlers.NewProductionHandler(tc.ProductionRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	production := router.Group("/production", authMiddleware.AuthRequired())
+	production.GET("", handler.ListProduction)
+	production.POST("", handler.CreateProduction)
+
+	send := func(method, path string, userID uuid.UUID, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	kwh := func(v float64) *float64 { return &v }
+	start := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	list := func(userID uuid.UUID) models.Page[models.ProductionRecord] {
+		query := url.Values{
+			"start_time": {start.Format(time.RFC3339)},
+			"end_time":   {start.Add(24 * time.Hour).Format(time.RFC3339)},
+		}
+		w := send("GET", "/production?"+query.Encode(), userID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page models.Page[models.ProductionRecord]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		records := []models.CreateProductionRecordRequest{
+			{MeterID: "roof", Timestamp: start.Add(12 * time.Hour), ProducedKWh: kwh(3), ExportedKWh: kwh(2)},
+			{MeterID: "roof", Timestamp: start.Add(13 * time.Hour), ProducedKWh: kwh(2.5), ExportedKWh: kwh(0)},
+		}
+		w := send("POST", "/production", alice.ID, models.CreateProductionRequest{Records: records})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+
+		var created []models.ProductionRecord
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		require.Len(t, created, 2)
+		assert.Equal(t, alice.ID, created[0].UserID)
+		assert.Equal(t, 2.0, created[0].ExportedKWh)
+	})
+
+	t.Run("Invalid Records", func(t *testing.T) {
+		for name, record := range map[string]models.CreateProductionRecordRequest{
+			"Negative":        {MeterID: "roof", Timestamp: start, ProducedKWh: kwh(-1), ExportedKWh: kwh(0)},
+			"Missing Export":  {MeterID: "roof", Timestamp: start, ProducedKWh: kwh(1)},
+			"Missing Meter":   {Timestamp: start, ProducedKWh: kwh(1), ExportedKWh: kwh(0)},
+			"Missing Produce": {MeterID: "roof", Timestamp: start, ExportedKWh: kwh(0)},
+		} {
+			t.Run(name, func(t *testing.T) {
+				w := send("POST", "/production", alice.ID, models.CreateProductionRequest{Records: []models.CreateProductionRecordRequest{record}})
+				assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+			})
+		}
+
+		duplicate := models.CreateProductionRecordRequest{MeterID: "roof", Timestamp: start, ProducedKWh: kwh(1), ExportedKWh: kwh(1)}
+		w := send("POST", "/production", alice.ID, models.CreateProductionRequest{Records: []models.CreateProductionRecordRequest{duplicate, duplicate}})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "duplicate record")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		page := list(alice.ID)
+		assert.Equal(t, 2, page.Total)
+		require.Len(t, page.Items, 2)
+		assert.Equal(t, 3.0, page.Items[0].ProducedKWh)
+		assert.Empty(t, list(bob.ID).Items)
+
+		w := send("GET", "/production?start_time="+start.Format(time.RFC3339), alice.ID, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -2,28 +2,392 @@ package handlers
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
 	"wattwatch/internal/models"
 	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/settings"
 
 	"github.com/gin-gonic/gin"
 )
 
 // ProviderHandler handles provider-related requests
 type ProviderHandler struct {
-	manager *provider.Manager
+	manager    *provider.Manager
+	spotPrices repository.SpotPriceRepository
+	settings   *settings.Store
+	auditRepo  repository.AuditLogRepository
 }
 
 // NewProviderHandler creates a new ProviderHandler
-func NewProviderHandler(manager *provider.Manager) *ProviderHandler {
+func NewProviderHandler(manager *provider.Manager, spotPrices repository.SpotPriceRepository) *ProviderHandler {
 	return &ProviderHandler{
-		manager: manager,
+		manager:    manager,
+		spotPrices: spotPrices,
 	}
 }
 
+// SetSettings lets providers be enabled and disabled through the runtime settings, so
+// the change is stored and applies on every instance
+func (h *ProviderHandler) SetSettings(store *settings.Store, auditRepo repository.AuditLogRepository) {
+	h.settings = store
+	h.auditRepo = auditRepo
+}
+
+// UpdateProviderRequest represents the request body for enabling or disabling a provider
+type UpdateProviderRequest struct {
+	Enabled *bool `json:"enabled" binding:"required"`
+}
+
+// ListProviders godoc
+// @Summary List providers (Admin only)
+// @Description Returns every registered provider with its schedule and how its runs went since the instance started: last run, last error and rows ingested
+// @Tags providers
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} provider.Status
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /admin/providers [get]
+func (h *ProviderHandler) ListProviders(c *gin.Context) {
+	c.JSON(http.StatusOK, h.manager.Statuses())
+}
+
+// GetProvider godoc
+// @Summary Get a provider (Admin only)
+// @Description Returns the schedule of a provider and how its runs went since the instance started
+// @Tags providers
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Provider name, e.g. nordpool"
+// @Success 200 {object} provider.Status
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Provider not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /admin/providers/{name} [get]
+func (h *ProviderHandler) GetProvider(c *gin.Context) {
+	status, err := h.manager.Status(c.Param("name"))
+	if err != nil {
+		apierror.Write(c, apierror.ProviderNotFound, "provider not found")
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// UpdateProvider godoc
+// @Summary Enable or disable a provider (Admin only)
+// @Description Stores whether the provider fetches prices on its schedule as a runtime setting, which takes precedence over the configuration. It applies immediately on this instance and within 30 seconds on others.
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Provider name, e.g. nordpool"
+// @Param request body UpdateProviderRequest true "Whether the provider is enabled"
+// @Success 200 {object} provider.Status
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Provider not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/providers/{name} [put]
+func (h *ProviderHandler) UpdateProvider(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req UpdateProviderRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	name := c.Param("name")
+	if _, found := h.manager.GetProvider(name); !found {
+		apierror.Write(c, apierror.ProviderNotFound, "provider not found")
+		return
+	}
+
+	key := settings.ProviderEnabledKey(name)
+	if _, err := h.settings.Set(c.Request.Context(), key, strconv.FormatBool(*req.Enabled), &authUser.ID); err != nil {
+		if errors.Is(err, settings.ErrUnknownSetting) {
+			// Providers without a configuration block can't be switched at runtime
+			apierror.Write(c, apierror.ProviderNotFound, "provider is not configured")
+			return
+		}
+		log.Printf("Error updating provider %s: %v", name, err)
+		apierror.Write(c, apierror.Internal, "failed to update provider")
+		return
+	}
+
+	description := "Provider " + name + " disabled"
+	if *req.Enabled {
+		description = "Provider " + name + " enabled"
+	}
+	metadata, _ := json.Marshal(map[string]string{"key": key, "enabled": strconv.FormatBool(*req.Enabled)})
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "provider",
+		EntityID:    name,
+		Description: description,
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging provider change: %v", err)
+	}
+
+	status, _ := h.manager.Status(name)
+	c.JSON(http.StatusOK, status)
+}
+
+// RunProviderRequest represents the request body for running a provider. Without a zone
+// the provider runs like on schedule, otherwise it fetches the zone from start_date
+// through end_date, tomorrow when they are left out.
+type RunProviderRequest struct {
+	Zone      string     `json:"zone"`
+	StartDate *time.Time `json:"start_date"`
+	EndDate   *time.Time `json:"end_date"`
+}
+
+// RunProviderResponse represents the response for a queued provider run
+type RunProviderResponse struct {
+	Message string `json:"message"`
+}
+
+// Dry run actions
+const (
+	PriceChangeInsert    = "insert"
+	PriceChangeUpdate    = "update"
+	PriceChangeUnchanged = "unchanged"
+)
+
+// ProviderPriceChange is a spot price a dry run would have stored
+type ProviderPriceChange struct {
+	Timestamp time.Time `json:"timestamp"`
+	Zone      string    `json:"zone"`
+	Currency  string    `json:"currency"`
+	Price     float64   `json:"price"`
+	// Existing is the stored price, nil when the price would be inserted
+	Existing *float64 `json:"existing,omitempty"`
+	// Action is insert, update or unchanged
+	Action string `json:"action"`
+}
+
+// ProviderDryRunResponse lists what a provider run would change
+type ProviderDryRunResponse struct {
+	Provider  string `json:"provider"`
+	Inserted  int    `json:"inserted"`
+	Updated   int    `json:"updated"`
+	Unchanged int    `json:"unchanged"`
+	// Forecasts is the number of price forecasts that would be stored
+	Forecasts int `json:"forecasts"`
+	// Changes are the prices that would be inserted or updated, ordered by zone,
+	// currency and time
+	Changes []ProviderPriceChange `json:"changes"`
+	// Error is why the run failed for some of the prices, empty when it succeeded
+	Error string `json:"error,omitempty"`
+}
+
+// RunProvider godoc
+// @Summary Run a provider (Admin only)
+// @Description Runs a provider in the background, like on schedule or for one zone and up to 14 days. With dry_run=true it fetches the prices without storing them and returns how they differ from the stored spot prices, to validate configuration changes safely. Disabled providers can only be dry run.
+// @Tags providers
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param name path string true "Provider name, e.g. nordpool"
+// @Param dry_run query bool false "Report the changes without storing them"
+// @Param request body RunProviderRequest false "Zone and dates to fetch"
+// @Success 200 {object} ProviderDryRunResponse "Dry run"
+// @Success 202 {object} RunProviderResponse
+// @Failure 400 {object} apierror.Problem "Invalid request body or parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Provider not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Failure 503 {object} apierror.Problem "Server is shutting down"
+// @Router /providers/{name}/run [post]
+func (h *ProviderHandler) RunProvider(c *gin.Context) {
+	var req RunProviderRequest
+	if c.Request.ContentLength != 0 && !bindJSON(c, &req) {
+		return
+	}
+
+	dryRun := false
+	if value := c.Query("dry_run"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "dry_run must be true or false")
+			return
+		}
+	}
+
+	name := c.Param("name")
+	p, found := h.manager.GetProvider(name)
+	if !found {
+		apierror.Write(c, apierror.ProviderNotFound, "provider not found")
+		return
+	}
+
+	var dates provider.DateRange
+	if req.Zone != "" {
+		tomorrow := time.Now().UTC().AddDate(0, 0, 1)
+		dates = provider.DateRange{Start: tomorrow, End: tomorrow}
+		if req.StartDate != nil {
+			dates.Start = *req.StartDate
+			dates.End = *req.StartDate
+		}
+		if req.EndDate != nil {
+			dates.End = *req.EndDate
+		}
+		if dates.End.Before(dates.Start) {
+			apierror.Write(c, apierror.InvalidRequest, "end_date must be after start_date")
+			return
+		}
+		if dates.End.Sub(dates.Start) > 14*24*time.Hour {
+			apierror.Write(c, apierror.InvalidRequest, "date range cannot exceed 14 days")
+			return
+		}
+		if !p.SupportsZone(req.Zone) {
+			apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("unsupported zone: %s", req.Zone))
+			return
+		}
+	} else if req.StartDate != nil || req.EndDate != nil {
+		apierror.Write(c, apierror.InvalidRequest, "start_date and end_date require a zone")
+		return
+	}
+
+	if dryRun {
+		h.dryRun(c, name, req.Zone, dates)
+		return
+	}
+
+	if !p.GetConfig().Enabled {
+		apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("provider %s is disabled, only dry runs are possible", name))
+		return
+	}
+	err := h.manager.Go(name+" manual run", func(ctx context.Context) {
+		var err error
+		if req.Zone == "" {
+			err = h.manager.RunProvider(ctx, name, nil)
+		} else {
+			err = h.manager.Fetch(ctx, name, req.Zone, dates)
+		}
+		if err != nil {
+			log.Printf("Error in manual run of provider %s: %v", name, err)
+		}
+	})
+	if err != nil {
+		apierror.Write(c, apierror.Unavailable, "server is shutting down, try again later")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, RunProviderResponse{
+		Message: "Provider run queued successfully",
+	})
+}
+
+// dryRun runs the provider without storing and responds with the differences to the
+// stored spot prices. Prices fetched before the run failed are still reported.
+func (h *ProviderHandler) dryRun(c *gin.Context, name, zone string, dates provider.DateRange) {
+	ctx := c.Request.Context()
+	batches, runErr := h.manager.DryRun(ctx, name, zone, dates)
+	if ctx.Err() != nil {
+		apierror.Write(c, apierror.Timeout, "dry run timed out")
+		return
+	}
+
+	resp := ProviderDryRunResponse{Provider: name, Changes: []ProviderPriceChange{}}
+	if runErr != nil {
+		resp.Error = runErr.Error()
+	}
+	for _, batch := range batches {
+		resp.Forecasts += len(batch.Forecasts)
+		if len(batch.Prices) == 0 {
+			continue
+		}
+		existing, err := h.storedPrices(ctx, batch)
+		if err != nil {
+			log.Printf("Error listing spot prices for dry run of %s: %v", name, err)
+			apierror.Write(c, apierror.Internal, "failed to compare with stored spot prices")
+			return
+		}
+		for _, sp := range batch.Prices {
+			change := ProviderPriceChange{
+				Timestamp: sp.Timestamp,
+				Zone:      batch.Zone,
+				Currency:  batch.Currency,
+				Price:     sp.Price,
+				Action:    PriceChangeInsert,
+			}
+			if price, ok := existing[sp.Timestamp.UTC()]; ok {
+				change.Existing = &price
+				change.Action = PriceChangeUpdate
+				// Prices are stored with four decimals
+				if math.Round(price*1e4) == math.Round(sp.Price*1e4) {
+					change.Action = PriceChangeUnchanged
+				}
+			}
+			switch change.Action {
+			case PriceChangeInsert:
+				resp.Inserted++
+			case PriceChangeUpdate:
+				resp.Updated++
+			default:
+				resp.Unchanged++
+				continue
+			}
+			resp.Changes = append(resp.Changes, change)
+		}
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// storedPrices returns the stored prices by time for the zone, currency and times of batch
+func (h *ProviderHandler) storedPrices(ctx context.Context, batch provider.Batch) (map[time.Time]float64, error) {
+	start, end := batch.Prices[0].Timestamp, batch.Prices[0].Timestamp
+	for _, sp := range batch.Prices {
+		if sp.Timestamp.Before(start) {
+			start = sp.Timestamp
+		}
+		if sp.Timestamp.After(end) {
+			end = sp.Timestamp
+		}
+	}
+
+	zoneID, currencyID := batch.Prices[0].ZoneID, batch.Prices[0].CurrencyID
+	stored, err := h.spotPrices.List(ctx, repository.SpotPriceFilter{
+		ZoneID:     &zoneID,
+		CurrencyID: &currencyID,
+		StartTime:  &start,
+		EndTime:    &end,
+	})
+	if err != nil {
+		return nil, err
+	}
+	prices := make(map[time.Time]float64, len(stored))
+	for _, sp := range stored {
+		prices[sp.Timestamp.UTC()] = sp.Price
+	}
+	return prices, nil
+}
+
 // TriggerNordpoolFetchRequest represents the request body for triggering nordpool fetch
 type TriggerNordpoolFetchRequest struct {
 	StartDate  time.Time `json:"start_date" binding:"required"`
@@ -46,34 +410,35 @@ type TriggerNordpoolFetchResponse struct {
 // @Security BearerAuth
 // @Param request body TriggerNordpoolFetchRequest true "Fetch request parameters"
 // @Success 202 {object} TriggerNordpoolFetchResponse
-// @Failure 400 {object} models.ErrorResponse "Invalid request body or parameters"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - admin only"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid request body or parameters"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Failure 503 {object} apierror.Problem "Server is shutting down"
 // @Router /providers/nordpool/fetch [post]
 func (h *ProviderHandler) TriggerNordpoolFetch(c *gin.Context) {
 	var req TriggerNordpoolFetchRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// Validate date range
 	if req.EndDate.Before(req.StartDate) {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end_date must be after start_date"})
+		apierror.Write(c, apierror.InvalidRequest, "end_date must be after start_date")
 		return
 	}
 
 	if req.EndDate.Sub(req.StartDate) > 14*24*time.Hour {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "date range cannot exceed 14 days"})
+		apierror.Write(c, apierror.InvalidRequest, "date range cannot exceed 14 days")
 		return
 	}
 
 	// Get nordpool provider
 	nordpoolProvider, exists := h.manager.GetProvider("nordpool")
 	if !exists {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "nordpool provider not found"})
+		apierror.Write(c, apierror.Internal, "nordpool provider not found")
 		return
 	}
 
@@ -89,41 +454,57 @@ func (h *ProviderHandler) TriggerNordpoolFetch(c *gin.Context) {
 	// Validate zones and currencies
 	for _, zone := range req.Zones {
 		if !nordpoolProvider.SupportsZone(zone) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("unsupported zone: %s", zone)})
+			apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("unsupported zone: %s", zone))
 			return
 		}
 	}
 	for _, currency := range req.Currencies {
 		if !nordpoolProvider.SupportsCurrency(currency) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: fmt.Sprintf("unsupported currency: %s", currency)})
+			apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("unsupported currency: %s", currency))
 			return
 		}
 	}
 
-	// Start background job
-	go func() {
+	// Start background job, tracked by the manager so shutdown waits for the current fetch
+	err := h.manager.Go("nordpool backfill", func(ctx context.Context) {
 		currentDate := req.StartDate
 		for currentDate.Before(req.EndDate) || currentDate.Equal(req.EndDate) {
 			for _, zone := range req.Zones {
 				for _, currency := range req.Currencies {
+					// Stop between fetches on shutdown and log what is left so it can be re-run
+					select {
+					case <-h.manager.Stopping():
+						log.Printf("Nordpool backfill interrupted by shutdown, re-run from %s to %s to complete it",
+							currentDate.Format("2006-01-02"), req.EndDate.Format("2006-01-02"))
+						return
+					default:
+					}
+
 					opts := provider.RunOptions{
 						Date:     currentDate,
 						Zone:     zone,
 						Currency: currency,
 					}
 
-					if err := h.manager.RunProvider(context.Background(), "nordpool", &opts); err != nil {
+					if err := h.manager.RunProvider(ctx, "nordpool", &opts); err != nil {
 						log.Printf("Error running nordpool provider for date %s, zone %s, currency %s: %v",
 							currentDate.Format("2006-01-02"), zone, currency, err)
 					}
 
 					// Sleep for 5 seconds between requests
-					time.Sleep(5 * time.Second)
+					select {
+					case <-time.After(5 * time.Second):
+					case <-h.manager.Stopping():
+					}
 				}
 			}
 			currentDate = currentDate.Add(24 * time.Hour)
 		}
-	}()
+	})
+	if err != nil {
+		apierror.Write(c, apierror.Unavailable, "server is shutting down, try again later")
+		return
+	}
 
 	c.JSON(http.StatusAccepted, TriggerNordpoolFetchResponse{
 		Message: "Nordpool fetch request queued successfully",
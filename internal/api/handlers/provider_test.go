@@ -0,0 +1,222 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/provider/nordpool"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/settings"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProviderHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	manager := provider.NewManager(nil)
+	manager.RegisterProvider(nordpool.NewProvider(nil, nil, nil, provider.Config{Enabled: true}))
+	tc.Settings.OnChange(func() {
+		require.NoError(t, manager.Reschedule(nordpool.ProviderName, tc.Settings.ProviderEnabled(nordpool.ProviderName), "", nil))
+	})
+
+	handler := handlers.NewProviderHandler(manager, tc.SpotPriceRepo)
+	handler.SetSettings(tc.Settings, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	router.GET("/admin/providers", handler.ListProviders)
+	router.GET("/admin/providers/:name", handler.GetProvider)
+	router.PUT("/admin/providers/:name", handler.UpdateProvider)
+
+	send := func(method, path, body string, as *models.User) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(as.ID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List", func(t *testing.T) {
+		w := send(http.MethodGet, "/admin/providers", "", admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		var statuses []provider.Status
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &statuses))
+		require.Len(t, statuses, 1)
+		assert.Equal(t, nordpool.ProviderName, statuses[0].Name)
+		assert.True(t, statuses[0].Enabled)
+		assert.Nil(t, statuses[0].LastRunAt)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		w := send(http.MethodPut, "/admin/providers/nordpool", `{"enabled":false}`, admin)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var status provider.Status
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		assert.False(t, status.Enabled)
+
+		setting, err := tc.Settings.Get(settings.ProviderEnabledKey(nordpool.ProviderName))
+		require.NoError(t, err)
+		assert.Equal(t, "false", setting.Value)
+		assert.True(t, setting.Overridden)
+
+		w = send(http.MethodGet, "/admin/providers/nordpool", "", admin)
+		require.Equal(t, http.StatusOK, w.Code)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		assert.False(t, status.Enabled)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/admin/providers/unknown", "", admin).Code)
+		assert.Equal(t, http.StatusNotFound, send(http.MethodPut, "/admin/providers/unknown", `{"enabled":true}`, admin).Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPut, "/admin/providers/nordpool", `{}`, admin).Code)
+		assert.Equal(t, http.StatusForbidden, send(http.MethodPut, "/admin/providers/nordpool", `{"enabled":true}`, user).Code)
+	})
+}
+
+// stubProvider reports a fixed set of SE3 prices in EUR for every fetch
+type stubProvider struct {
+	provider.BaseProvider
+	spotPrices repository.SpotPriceSourceRepository
+	prices     []models.SpotPrice
+}
+
+func (p *stubProvider) Name() string { return "stub" }
+func (p *stubProvider) Run(ctx context.Context) error {
+	return p.Fetch(ctx, "SE3", provider.DateRange{})
+}
+func (p *stubProvider) RunWithOptions(ctx context.Context, _ provider.RunOptions) error { return nil }
+func (p *stubProvider) Fetch(ctx context.Context, zone string, _ provider.DateRange) error {
+	return provider.Record(ctx, p.spotPrices, p.Name(), zone, "EUR", p.prices)
+}
+
+// batchRecorder records sources by storing the spot prices
+type batchRecorder struct {
+	repository.SpotPriceSourceRepository
+	spotPrices repository.SpotPriceRepository
+}
+
+func (r batchRecorder) Record(ctx context.Context, _ string, spotPrices []models.SpotPrice) error {
+	return r.spotPrices.CreateBatch(ctx, spotPrices)
+}
+
+func TestProviderHandler_RunProvider(t *testing.T) {
+	ctx := context.Background()
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	zone, err := tc.ZoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := tc.CurrencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	start := time.Date(2025, 3, 20, 23, 0, 0, 0, time.UTC)
+	price := func(hour int, value float64) models.SpotPrice {
+		return models.SpotPrice{
+			ID:         uuid.New(),
+			Timestamp:  start.Add(time.Duration(hour) * time.Hour),
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      value,
+		}
+	}
+	require.NoError(t, tc.SpotPriceRepo.CreateBatch(ctx, []models.SpotPrice{price(0, 10), price(1, 5)}))
+
+	stub := &stubProvider{
+		BaseProvider: provider.NewBaseProvider(nil, provider.Config{Enabled: true, SupportedZones: []string{"SE3"}}),
+		spotPrices:   batchRecorder{spotPrices: tc.SpotPriceRepo},
+		prices:       []models.SpotPrice{price(0, 10), price(1, 6), price(2, 12.5)},
+	}
+	manager := provider.NewManager(nil)
+	manager.RegisterProvider(stub)
+
+	handler := handlers.NewProviderHandler(manager, tc.SpotPriceRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	router.POST("/providers/:name/run", handler.RunProvider)
+
+	run := func(path, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewBufferString(body))
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(admin.ID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	stored := func() int {
+		t.Helper()
+		prices, err := tc.SpotPriceRepo.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID})
+		require.NoError(t, err)
+		return len(prices)
+	}
+
+	t.Run("Dry Run Reports Changes", func(t *testing.T) {
+		w := run("/providers/stub/run?dry_run=true", `{"zone":"SE3","start_date":"2025-03-21T00:00:00Z"}`)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp handlers.ProviderDryRunResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 1, resp.Inserted)
+		assert.Equal(t, 1, resp.Updated)
+		assert.Equal(t, 1, resp.Unchanged)
+		require.Len(t, resp.Changes, 2)
+		assert.Equal(t, handlers.PriceChangeUpdate, resp.Changes[0].Action)
+		require.NotNil(t, resp.Changes[0].Existing)
+		assert.Equal(t, 5.0, *resp.Changes[0].Existing)
+		assert.Equal(t, 6.0, resp.Changes[0].Price)
+		assert.Equal(t, handlers.PriceChangeInsert, resp.Changes[1].Action)
+		assert.Nil(t, resp.Changes[1].Existing)
+		assert.Equal(t, 2, stored(), "dry runs store nothing")
+
+		status, err := manager.Status("stub")
+		require.NoError(t, err)
+		assert.Zero(t, status.Runs)
+	})
+
+	t.Run("Invalid Requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, run("/providers/stub/run?dry_run=maybe", "").Code)
+		assert.Equal(t, http.StatusBadRequest, run("/providers/stub/run", `{"start_date":"2025-03-21T00:00:00Z"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, run("/providers/stub/run", `{"zone":"SE1"}`).Code)
+		assert.Equal(t, http.StatusBadRequest, run("/providers/stub/run", `{"zone":"SE3","start_date":"2025-03-21T00:00:00Z","end_date":"2025-04-21T00:00:00Z"}`).Code)
+		assert.Equal(t, http.StatusNotFound, run("/providers/unknown/run", "").Code)
+	})
+
+	t.Run("Run Stores Prices", func(t *testing.T) {
+		w := run("/providers/stub/run", "")
+		require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+		require.NoError(t, manager.Shutdown(ctx))
+		assert.Equal(t, 3, stored())
+
+		status, err := manager.Status("stub")
+		require.NoError(t, err)
+		assert.Equal(t, int64(1), status.Runs)
+		assert.Equal(t, int64(3), status.RowsIngested)
+	})
+
+	t.Run("Disabled Provider Can Only Be Dry Run", func(t *testing.T) {
+		stub.SetSchedule(false, "", nil)
+		assert.Equal(t, http.StatusBadRequest, run("/providers/stub/run", "").Code)
+		w := run("/providers/stub/run?dry_run=true", "")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp handlers.ProviderDryRunResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		assert.Equal(t, 3, resp.Unchanged)
+		assert.Empty(t, resp.Changes)
+	})
+}
@@ -1,10 +1,12 @@
 package handlers
 
 import (
+	"encoding/json"
 	"errors"
 	"log"
 	"net/http"
 	"strconv"
+	"wattwatch/internal/apierror"
 	"wattwatch/internal/auth"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
@@ -17,6 +19,7 @@ type RoleHandler struct {
 	roleRepo  repository.RoleRepository
 	userRepo  repository.UserRepository
 	auditRepo repository.AuditLogRepository
+	limits    ListLimits
 }
 
 func NewRoleHandler(roleRepo repository.RoleRepository, userRepo repository.UserRepository, auditRepo repository.AuditLogRepository) *RoleHandler {
@@ -24,9 +27,15 @@ func NewRoleHandler(roleRepo repository.RoleRepository, userRepo repository.User
 		roleRepo:  roleRepo,
 		userRepo:  userRepo,
 		auditRepo: auditRepo,
+		limits:    DefaultListLimits,
 	}
 }
 
+// SetListLimits sets the default and maximum number of roles listed
+func (h *RoleHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
 // GetRole godoc
 // @Summary Get role by ID
 // @Description Get a role by its ID (admin only)
@@ -35,24 +44,24 @@ func NewRoleHandler(roleRepo repository.RoleRepository, userRepo repository.User
 // @Produce json
 // @Param id path string true "Role ID (UUID)"
 // @Success 200 {object} models.Role
-// @Failure 400 {object} models.ErrorResponse "Invalid role ID"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - admin only"
-// @Failure 404 {object} models.ErrorResponse "Role not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid role ID"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Role not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /roles/{id} [get]
 func (h *RoleHandler) GetRole(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := auth.GetUserFromContext(c)
 	if authUser == nil || !authUser.IsAdmin() {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied"})
+		apierror.Write(c, apierror.Forbidden, "permission denied")
 		return
 	}
 
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil || id == uuid.Nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid role ID"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid role ID")
 		return
 	}
 
@@ -60,10 +69,10 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 	if err != nil {
 		log.Printf("Error getting role: %v", err)
 		if errors.Is(err, repository.ErrNotFound) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "role not found"})
+			apierror.Write(c, apierror.RoleNotFound, "role not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get role"})
+		apierror.Write(c, apierror.Internal, "failed to get role")
 		return
 	}
 
@@ -81,18 +90,19 @@ func (h *RoleHandler) GetRole(c *gin.Context) {
 // @Param admin_group query bool false "Filter by admin group status"
 // @Param order_by query string false "Field to order by"
 // @Param order_desc query bool false "Order descending"
-// @Param limit query int false "Limit number of results"
+// @Param limit query int false "Limit number of results (default 50, at most 1000 unless configured otherwise)"
 // @Param offset query int false "Offset results"
-// @Success 200 {array} models.Role
-// @Failure 400 {object} models.ErrorResponse "Invalid request"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Param envelope query bool false "Wrap the roles in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.Role]
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /roles [get]
 func (h *RoleHandler) ListRoles(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := auth.GetUserFromContext(c)
 	if authUser == nil {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied"})
+		apierror.Write(c, apierror.Forbidden, "permission denied")
 		return
 	}
 
@@ -100,10 +110,10 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 	if !authUser.IsAdmin() {
 		role, err := h.roleRepo.GetByID(c.Request.Context(), authUser.RoleID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "internal server error"})
+			apierror.Write(c, apierror.Internal, "internal server error")
 			return
 		}
-		c.JSON(http.StatusOK, []models.Role{*role})
+		respondPage(c, []models.Role{*role}, nil, nil, func() (int, error) { return 1, nil }, "failed to list roles")
 		return
 	}
 
@@ -116,7 +126,7 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 	if protected := c.Query("protected"); protected != "" {
 		protectedBool, err := strconv.ParseBool(protected)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid protected parameter"})
+			apierror.Write(c, apierror.InvalidRequest, "invalid protected parameter")
 			return
 		}
 		filter.Protected = &protectedBool
@@ -124,7 +134,7 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 	if adminGroup := c.Query("admin_group"); adminGroup != "" {
 		adminGroupBool, err := strconv.ParseBool(adminGroup)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid admin_group parameter"})
+			apierror.Write(c, apierror.InvalidRequest, "invalid admin_group parameter")
 			return
 		}
 		filter.AdminGroup = &adminGroupBool
@@ -133,23 +143,21 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 	if orderDesc := c.Query("order_desc"); orderDesc != "" {
 		orderDescBool, err := strconv.ParseBool(orderDesc)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid order_desc parameter"})
+			apierror.Write(c, apierror.InvalidRequest, "invalid order_desc parameter")
 			return
 		}
 		filter.OrderDesc = orderDescBool
 	}
-	if limit := c.Query("limit"); limit != "" {
-		limitInt, err := strconv.Atoi(limit)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid limit parameter"})
-			return
-		}
-		filter.Limit = &limitInt
+	limit, err := h.limits.limit(c, h.limits.Default)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
 	}
+	filter.Limit = &limit
 	if offset := c.Query("offset"); offset != "" {
 		offsetInt, err := strconv.Atoi(offset)
 		if err != nil {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid offset parameter"})
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset parameter")
 			return
 		}
 		filter.Offset = &offsetInt
@@ -157,11 +165,13 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 
 	roles, err := h.roleRepo.List(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to list roles"})
+		apierror.Write(c, apierror.Internal, "failed to list roles")
 		return
 	}
 
-	c.JSON(http.StatusOK, roles)
+	respondPage(c, roles, filter.Limit, filter.Offset, func() (int, error) {
+		return h.roleRepo.Total(c.Request.Context(), filter)
+	}, "failed to list roles")
 }
 
 // CreateRole godoc
@@ -172,24 +182,24 @@ func (h *RoleHandler) ListRoles(c *gin.Context) {
 // @Produce json
 // @Param role body models.CreateRoleRequest true "Role details"
 // @Success 201 {object} models.Role
-// @Failure 400 {object} models.ErrorResponse "Invalid request body"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - admin only"
-// @Failure 409 {object} models.ErrorResponse "Role already exists"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid request body"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 409 {object} apierror.Problem "Role already exists"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /roles [post]
 func (h *RoleHandler) CreateRole(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := auth.GetUserFromContext(c)
 	if authUser == nil || !authUser.IsAdmin() {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied"})
+		apierror.Write(c, apierror.Forbidden, "permission denied")
 		return
 	}
 
 	var req models.CreateRoleRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -201,10 +211,10 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 
 	if err := h.roleRepo.Create(c.Request.Context(), role); err != nil {
 		if errors.Is(err, repository.ErrConflict) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "role name already exists"})
+			apierror.Write(c, apierror.InvalidRequest, "role name already exists")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to create role"})
+		apierror.Write(c, apierror.Internal, "failed to create role")
 		return
 	}
 
@@ -234,35 +244,35 @@ func (h *RoleHandler) CreateRole(c *gin.Context) {
 // @Param id path string true "Role ID (UUID)"
 // @Param role body models.UpdateRoleRequest true "Role details"
 // @Success 200 {object} models.Role
-// @Failure 400 {object} models.ErrorResponse "Invalid request body or role ID"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - admin only"
-// @Failure 404 {object} models.ErrorResponse "Role not found"
-// @Failure 409 {object} models.ErrorResponse "Role already exists"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid request body or role ID"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Role not found"
+// @Failure 409 {object} apierror.Problem "Role already exists"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /roles/{id} [put]
 func (h *RoleHandler) UpdateRole(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := auth.GetUserFromContext(c)
 	if authUser == nil || !authUser.IsAdmin() {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied"})
+		apierror.Write(c, apierror.Forbidden, "permission denied")
 		return
 	}
 
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid role ID"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid role ID")
 		return
 	}
 	if id == uuid.Nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid role ID"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid role ID")
 		return
 	}
 
 	var req models.UpdateRoleRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -271,10 +281,10 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 	if err != nil {
 		log.Printf("Error getting role: %v", err)
 		if errors.Is(err, repository.ErrNotFound) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "role not found"})
+			apierror.Write(c, apierror.RoleNotFound, "role not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get role"})
+		apierror.Write(c, apierror.Internal, "failed to get role")
 		return
 	}
 
@@ -285,14 +295,14 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 
 	if err := h.roleRepo.Update(c.Request.Context(), role); err != nil {
 		if errors.Is(err, repository.ErrConflict) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "role name already exists"})
+			apierror.Write(c, apierror.InvalidRequest, "role name already exists")
 			return
 		}
 		if errors.Is(err, repository.ErrProtectedRole) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "cannot modify protected role"})
+			apierror.Write(c, apierror.InvalidRequest, "cannot modify protected role")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to update role"})
+		apierror.Write(c, apierror.Internal, "failed to update role")
 		return
 	}
 
@@ -321,25 +331,25 @@ func (h *RoleHandler) UpdateRole(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Role ID (UUID)"
 // @Success 200 {object} models.SuccessResponse "Role deleted successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid role ID"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - admin only"
-// @Failure 404 {object} models.ErrorResponse "Role not found"
-// @Failure 409 {object} models.ErrorResponse "Role in use by users"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid role ID"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Role not found"
+// @Failure 409 {object} apierror.Problem "Role in use by users"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /roles/{id} [delete]
 func (h *RoleHandler) DeleteRole(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := auth.GetUserFromContext(c)
 	if authUser == nil || !authUser.IsAdmin() {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied"})
+		apierror.Write(c, apierror.Forbidden, "permission denied")
 		return
 	}
 
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil || id == uuid.Nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid role id"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid role id")
 		return
 	}
 
@@ -347,13 +357,13 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 	if err := h.roleRepo.Delete(c.Request.Context(), id); err != nil {
 		switch {
 		case errors.Is(err, repository.ErrNotFound):
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "role not found"})
+			apierror.Write(c, apierror.RoleNotFound, "role not found")
 		case errors.Is(err, repository.ErrRoleInUse), errors.Is(err, repository.ErrHasAssociatedRecords):
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "cannot delete role with assigned users"})
+			apierror.Write(c, apierror.InvalidRequest, "cannot delete role with assigned users")
 		case errors.Is(err, repository.ErrProtectedRole):
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "cannot delete protected role"})
+			apierror.Write(c, apierror.InvalidRequest, "cannot delete protected role")
 		default:
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "internal server error"})
+			apierror.Write(c, apierror.Internal, "internal server error")
 		}
 		return
 	}
@@ -374,3 +384,118 @@ func (h *RoleHandler) DeleteRole(c *gin.Context) {
 
 	c.JSON(http.StatusOK, models.SuccessResponse{Message: "role deleted successfully"})
 }
+
+// ListPermissions godoc
+// @Summary List grantable permissions
+// @Description Lists the permissions that can be granted to roles. Admin groups have all of them. (admin only)
+// @Tags roles
+// @Produce json
+// @Success 200 {array} models.Permission
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Security BearerAuth
+// @Router /admin/permissions [get]
+func (h *RoleHandler) ListPermissions(c *gin.Context) {
+	c.JSON(http.StatusOK, models.GrantablePermissions)
+}
+
+// GrantPermission godoc
+// @Summary Grant a permission to a role
+// @Description Grants the role a permission, such as spot_prices:write. Tokens issued to users of the role have to be refreshed to use it. (admin only)
+// @Tags roles
+// @Produce json
+// @Param id path string true "Role ID (UUID)"
+// @Param permission path string true "Permission name"
+// @Success 200 {object} models.Role
+// @Failure 400 {object} apierror.Problem "Invalid role ID, unknown permission or protected role"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Role not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Security BearerAuth
+// @Router /roles/{id}/permissions/{permission} [put]
+func (h *RoleHandler) GrantPermission(c *gin.Context) {
+	h.changePermission(c, true)
+}
+
+// RevokePermission godoc
+// @Summary Revoke a permission from a role
+// @Description Removes a permission granted to the role. Tokens issued to users of the role have to be refreshed. (admin only)
+// @Tags roles
+// @Produce json
+// @Param id path string true "Role ID (UUID)"
+// @Param permission path string true "Permission name"
+// @Success 200 {object} models.Role
+// @Failure 400 {object} apierror.Problem "Invalid role ID or protected role"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Role not found or permission not granted"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Security BearerAuth
+// @Router /roles/{id}/permissions/{permission} [delete]
+func (h *RoleHandler) RevokePermission(c *gin.Context) {
+	h.changePermission(c, false)
+}
+
+// changePermission grants or revokes the permission in the path and responds with the
+// updated role
+func (h *RoleHandler) changePermission(c *gin.Context, grant bool) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil || !authUser.IsAdmin() {
+		apierror.Write(c, apierror.Forbidden, "permission denied")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid role ID")
+		return
+	}
+	permission := c.Param("permission")
+
+	change, description := h.roleRepo.RevokePermission, "Permission "+permission+" revoked"
+	if grant {
+		if !models.IsGrantablePermission(permission) {
+			apierror.Write(c, apierror.InvalidRequest, "unknown permission")
+			return
+		}
+		change, description = h.roleRepo.GrantPermission, "Permission "+permission+" granted"
+	}
+
+	if err := change(c.Request.Context(), id, permission); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound) && grant:
+			apierror.Write(c, apierror.RoleNotFound, "role not found")
+		case errors.Is(err, repository.ErrNotFound):
+			apierror.Write(c, apierror.RoleNotFound, "role not found or permission not granted")
+		case errors.Is(err, repository.ErrProtectedRole):
+			apierror.Write(c, apierror.InvalidRequest, "cannot modify protected role")
+		default:
+			log.Printf("Error changing permission %s of role %s: %v", permission, id, err)
+			apierror.Write(c, apierror.Internal, "failed to update role")
+		}
+		return
+	}
+
+	metadata, _ := json.Marshal(map[string]string{"role_id": id.String(), "permission": permission})
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "role",
+		EntityID:    id.String(),
+		Description: description,
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging role permission change: %v", err)
+	}
+
+	role, err := h.roleRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to get role")
+		return
+	}
+	c.JSON(http.StatusOK, role)
+}
@@ -62,7 +62,7 @@ func TestRoleHandler_CreateRole(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'CreateRoleRequest.Name' Error:Field validation for 'Name' failed on the 'required' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_NameTooShort",
@@ -75,7 +75,7 @@ func TestRoleHandler_CreateRole(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'CreateRoleRequest.Name' Error:Field validation for 'Name' failed on the 'min' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_NameTooLong",
@@ -88,7 +88,7 @@ func TestRoleHandler_CreateRole(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'CreateRoleRequest.Name' Error:Field validation for 'Name' failed on the 'max' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_SpacesOnly",
@@ -101,7 +101,7 @@ func TestRoleHandler_CreateRole(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'CreateRoleRequest.Name' Error:Field validation for 'Name' failed on the 'nospaces' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_NonAdmin",
@@ -224,7 +224,7 @@ func TestRoleHandler_UpdateRole(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'UpdateRoleRequest.Name' Error:Field validation for 'Name' failed on the 'required' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_NameTooShort",
@@ -238,7 +238,7 @@ func TestRoleHandler_UpdateRole(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'UpdateRoleRequest.Name' Error:Field validation for 'Name' failed on the 'min' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_NameTooLong",
@@ -252,7 +252,7 @@ func TestRoleHandler_UpdateRole(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'UpdateRoleRequest.Name' Error:Field validation for 'Name' failed on the 'max' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_SpacesOnly",
@@ -266,7 +266,7 @@ func TestRoleHandler_UpdateRole(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'UpdateRoleRequest.Name' Error:Field validation for 'Name' failed on the 'nospaces' tag",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_InvalidID",
@@ -571,14 +571,59 @@ func TestRoleHandler_ListRoles(t *testing.T) {
 				return
 			}
 
-			var resp []models.Role
-			err := json.NewDecoder(w.Body).Decode(&resp)
+			var page models.Page[models.Role]
+			err := json.NewDecoder(w.Body).Decode(&page)
 			require.NoError(t, err)
-			require.Len(t, resp, tt.wantCount)
+			require.Len(t, page.Items, tt.wantCount)
 
 			if tt.validate != nil {
-				tt.validate(t, resp)
+				tt.validate(t, page.Items)
+			}
+		})
+	}
+}
+
+func TestRoleHandler_ListRolesLimit(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	for i := 1; i <= 3; i++ {
+		tc.CreateTestRole(fmt.Sprintf("test_role_%d", i), false, false)
+	}
+	token := tc.GetTestJWT(admin.ID)
+
+	handler := handlers.NewRoleHandler(tc.RoleRepo, tc.UserRepo, tc.AuditRepo)
+	handler.SetListLimits(handlers.ListLimits{Default: 2, Max: 4})
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/api/v1/roles", handler.ListRoles)
+
+	tests := []struct {
+		name       string
+		query      string
+		wantStatus int
+		wantCount  int
+	}{
+		{name: "Default", wantStatus: http.StatusOK, wantCount: 2},
+		{name: "Requested", query: "?limit=3", wantStatus: http.StatusOK, wantCount: 3},
+		{name: "Capped", query: "?limit=100", wantStatus: http.StatusOK, wantCount: 4},
+		{name: "Invalid", query: "?limit=0", wantStatus: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/v1/roles"+tt.query, nil)
+			req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus != http.StatusOK {
+				return
 			}
+			var page models.Page[models.Role]
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&page))
+			require.Len(t, page.Items, tt.wantCount)
 		})
 	}
 }
@@ -658,3 +703,111 @@ func TestRoleHandler_GetRole(t *testing.T) {
 		})
 	}
 }
+
+func TestRoleHandler_Permissions(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	user := tc.CreateTestUser("test_user", "test@example.com", "test_password", false)
+	role := tc.CreateTestRole("price-uploader", false, false)
+	adminRole, err := tc.RoleRepo.GetByName(context.Background(), "admin")
+	require.NoError(t, err)
+
+	handler := handlers.NewRoleHandler(tc.RoleRepo, tc.UserRepo, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/api/v1/admin/permissions", handler.ListPermissions)
+	router.PUT("/api/v1/roles/:id/permissions/:permission", handler.GrantPermission)
+	router.DELETE("/api/v1/roles/:id/permissions/:permission", handler.RevokePermission)
+
+	request := func(method, path, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", fmt.Sprintf("Bearer %s", token))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	adminToken := tc.GetTestJWT(admin.ID)
+	permissionPath := func(id uuid.UUID, permission string) string {
+		return fmt.Sprintf("/api/v1/roles/%s/permissions/%s", id, permission)
+	}
+
+	w := request(http.MethodGet, "/api/v1/admin/permissions", adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var permissions []models.Permission
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&permissions))
+	require.Equal(t, models.GrantablePermissions, permissions)
+
+	w = request(http.MethodPut, permissionPath(role.ID, models.PermissionSpotPricesWrite), adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	var got models.Role
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Equal(t, []string{models.PermissionSpotPricesWrite}, got.Permissions)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		token      string
+		wantStatus int
+		errMsg     string
+	}{
+		{
+			name:       "Error_NonAdmin",
+			method:     http.MethodPut,
+			path:       permissionPath(role.ID, models.PermissionZonesManage),
+			token:      tc.GetTestJWT(user.ID),
+			wantStatus: http.StatusForbidden,
+			errMsg:     "permission denied",
+		},
+		{
+			name:       "Error_UnknownPermission",
+			method:     http.MethodPut,
+			path:       permissionPath(role.ID, "everything"),
+			token:      adminToken,
+			wantStatus: http.StatusBadRequest,
+			errMsg:     "unknown permission",
+		},
+		{
+			name:       "Error_ProtectedRole",
+			method:     http.MethodPut,
+			path:       permissionPath(adminRole.ID, models.PermissionZonesManage),
+			token:      adminToken,
+			wantStatus: http.StatusBadRequest,
+			errMsg:     "cannot modify protected role",
+		},
+		{
+			name:       "Error_RoleNotFound",
+			method:     http.MethodPut,
+			path:       permissionPath(uuid.New(), models.PermissionZonesManage),
+			token:      adminToken,
+			wantStatus: http.StatusNotFound,
+			errMsg:     "role not found",
+		},
+		{
+			name:       "Error_NotGranted",
+			method:     http.MethodDelete,
+			path:       permissionPath(role.ID, models.PermissionZonesManage),
+			token:      adminToken,
+			wantStatus: http.StatusNotFound,
+			errMsg:     "role not found or permission not granted",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := request(tt.method, tt.path, tt.token)
+			require.Equal(t, tt.wantStatus, w.Code)
+
+			var resp models.ErrorResponse
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&resp))
+			require.Equal(t, tt.errMsg, resp.Error)
+		})
+	}
+
+	w = request(http.MethodDelete, permissionPath(role.ID, models.PermissionSpotPricesWrite), adminToken)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&got))
+	require.Empty(t, got.Permissions)
+}
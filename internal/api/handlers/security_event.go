@@ -0,0 +1,105 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"strconv"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// SecurityEventHandler handles the unusual activity recorded on accounts, such as logins
+// from new devices
+type SecurityEventHandler struct {
+	userRepo  repository.UserRepository
+	eventRepo repository.SecurityEventRepository
+	limits    ListLimits
+}
+
+// NewSecurityEventHandler creates a new SecurityEventHandler
+func NewSecurityEventHandler(userRepo repository.UserRepository, eventRepo repository.SecurityEventRepository) *SecurityEventHandler {
+	return &SecurityEventHandler{
+		userRepo:  userRepo,
+		eventRepo: eventRepo,
+		limits:    DefaultListLimits,
+	}
+}
+
+// SetListLimits sets the default and maximum number of events listed
+func (h *SecurityEventHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// ListSecurityEvents godoc
+// @Summary List security events
+// @Description Returns the logins of a user from devices or IP addresses they hadn't logged in from before, newest first. Users are emailed about logins from new devices. Users can list their own events, others require the users:manage permission.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Param limit query integer false "Limit results (default 50, maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the events in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.SecurityEvent]
+// @Failure 400 {object} apierror.Problem "Invalid user ID or parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/security-events [get]
+func (h *SecurityEventHandler) ListSecurityEvents(c *gin.Context) {
+	authUser := GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return
+	}
+	if id != authUser.ID && !authUser.Can(models.PermissionUsersManage) {
+		apierror.Write(c, apierror.Forbidden, "permission denied")
+		return
+	}
+
+	limit, err := h.limits.limit(c, h.limits.Default)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	if _, err := h.userRepo.GetByID(ctx, id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "user not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return
+	}
+
+	events, err := h.eventRepo.ListByUserID(ctx, id, limit, offset)
+	if err != nil {
+		log.Printf("Error listing security events of user %s: %v", id, err)
+		apierror.Write(c, apierror.Internal, "failed to list security events")
+		return
+	}
+
+	respondPage(c, events, &limit, &offset, func() (int, error) {
+		return h.eventRepo.CountByUserID(ctx, id)
+	}, "failed to list security events")
+}
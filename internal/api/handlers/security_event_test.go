@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityEventHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+	other := tc.CreateTestUser("other", "other@test.com", "password123", false)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	handler := handlers.NewSecurityEventHandler(tc.UserRepo, tc.SecurityEventRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.POST("/login", tc.AuthHandler.Login)
+	router.GET("/users/:id/security-events", authMiddleware.AuthRequired(), handler.ListSecurityEvents)
+
+	login := func(userAgent, remoteAddr string) {
+		t.Helper()
+		body, err := json.Marshal(models.LoginRequest{Username: "user", Password: "password123"})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", userAgent)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+	list := func(id uuid.UUID, as *models.User) (*httptest.ResponseRecorder, models.Page[models.SecurityEvent]) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/users/"+id.String()+"/security-events", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(as.ID))
+		router.ServeHTTP(w, req)
+		var page models.Page[models.SecurityEvent]
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		}
+		return w, page
+	}
+
+	// The first login has nothing to compare with, and repeating it is nothing new
+	login("Firefox", "192.0.2.1:1234")
+	login("Firefox", "192.0.2.1:1234")
+	_, page := list(user.ID, user)
+	assert.Empty(t, page.Items)
+
+	login("Firefox", "198.51.100.7:1234")
+	login("Safari", "198.51.100.7:1234")
+	w, page := list(user.ID, user)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	require.Len(t, page.Items, 2)
+	assert.Equal(t, 2, page.Total)
+	assert.Equal(t, models.SecurityEventNewDevice, page.Items[0].Type)
+	assert.Equal(t, "Safari", page.Items[0].UserAgent)
+	assert.Equal(t, models.SecurityEventNewIP, page.Items[1].Type)
+	assert.Equal(t, "198.51.100.7", page.Items[1].IPAddress)
+
+	// Others' events take the users:manage permission
+	w, _ = list(user.ID, other)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w, page = list(user.ID, admin)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Len(t, page.Items, 2)
+	w, _ = list(uuid.New(), admin)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
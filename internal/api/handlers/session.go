@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// sessionUser returns the user of the :id parameter whose sessions the authenticated user
+// may see and end, their own unless they may manage users. It responds itself and returns
+// nil otherwise.
+func (h *UserHandler) sessionUser(c *gin.Context) (*models.User, *models.User) {
+	authUser := GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return nil, nil
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return nil, nil
+	}
+	if id != authUser.ID && !authUser.Can(models.PermissionUsersManage) {
+		apierror.Write(c, apierror.Forbidden, "permission denied - can only manage own sessions unless admin")
+		return nil, nil
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "user not found")
+			return nil, nil
+		}
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return nil, nil
+	}
+	return authUser, user
+}
+
+// ListSessions godoc
+// @Summary List user sessions
+// @Description List the devices a user is logged in on, most recently used first. A session starts with a login and lasts while its refresh token is used. Users can only list their own sessions unless they have the users:manage permission.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {array} models.Session
+// @Failure 400 {object} apierror.Problem "Invalid user ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - can only list own sessions unless admin"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/sessions [get]
+func (h *UserHandler) ListSessions(c *gin.Context) {
+	_, user := h.sessionUser(c)
+	if user == nil {
+		return
+	}
+
+	sessions, err := h.refreshTokenRepo.ListSessions(c.Request.Context(), user.ID, time.Now())
+	if err != nil {
+		log.Printf("Error listing sessions of user %s: %v", user.ID, err)
+		apierror.Write(c, apierror.Internal, "failed to list sessions")
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// RevokeSession godoc
+// @Summary Revoke user session
+// @Description Log a user out of one device by revoking the refresh token of the session. Access tokens already issued stay valid until they expire. Users can only revoke their own sessions unless they have the users:manage permission.
+// @Tags users
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Param sessionId path string true "Session ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid user or session ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - can only revoke own sessions unless admin"
+// @Failure 404 {object} apierror.Problem "User or session not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/sessions/{sessionId} [delete]
+func (h *UserHandler) RevokeSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("sessionId"))
+	if err != nil || sessionID == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid session id")
+		return
+	}
+	authUser, user := h.sessionUser(c)
+	if user == nil {
+		return
+	}
+
+	if err := h.refreshTokenRepo.RevokeSession(c.Request.Context(), user.ID, sessionID, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.SessionNotFound, "session not found")
+			return
+		}
+		log.Printf("Error revoking session %s: %v", sessionID, err)
+		apierror.Write(c, apierror.Internal, "failed to revoke session")
+		return
+	}
+
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionLogout,
+		EntityType:  "session",
+		EntityID:    sessionID.String(),
+		Description: fmt.Sprintf("Session of user %s revoked", user.Username),
+		Metadata:    string(`{"user_id":"` + user.ID.String() + `"}`),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging session revocation: %v", err)
+	}
+
+	c.Status(http.StatusNoContent)
+}
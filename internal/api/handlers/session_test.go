@@ -0,0 +1,77 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserHandler_Sessions(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+	other := tc.CreateTestUser("other", "other@test.com", "password123", false)
+
+	handler := tc.NewUserHandler()
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/users/:id/sessions", handler.ListSessions)
+	router.DELETE("/users/:id/sessions/:sessionId", handler.RevokeSession)
+
+	send := func(method, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	sessions := func(userID uuid.UUID) []models.Session {
+		w := send(http.MethodGet, "/users/"+userID.String()+"/sessions", userID)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var sessions []models.Session
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &sessions))
+		return sessions
+	}
+
+	ctx := context.Background()
+	laptop, err := tc.AuthService.GenerateRefreshToken(ctx, user.ID, models.SessionClient{UserAgent: "laptop", IPAddress: "192.0.2.1"})
+	require.NoError(t, err)
+	_, err = tc.AuthService.GenerateRefreshToken(ctx, user.ID, models.SessionClient{UserAgent: "phone", IPAddress: "192.0.2.2"})
+	require.NoError(t, err)
+	require.Len(t, sessions(user.ID), 2)
+
+	// Rotating keeps the session, with the client of the refresh
+	_, _, err = tc.AuthService.RotateRefreshToken(ctx, laptop, models.SessionClient{UserAgent: "laptop", IPAddress: "198.51.100.7"})
+	require.NoError(t, err)
+	list := sessions(user.ID)
+	require.Len(t, list, 2)
+	assert.Equal(t, "laptop", list[0].UserAgent)
+	assert.Equal(t, "198.51.100.7", list[0].IPAddress)
+	assert.True(t, list[0].LastUsedAt.After(list[0].CreatedAt))
+
+	assert.Equal(t, http.StatusForbidden, send(http.MethodGet, "/users/"+user.ID.String()+"/sessions", other.ID).Code)
+	assert.Equal(t, http.StatusOK, send(http.MethodGet, "/users/"+user.ID.String()+"/sessions", admin.ID).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodGet, "/users/"+uuid.New().String()+"/sessions", admin.ID).Code)
+
+	path := "/users/" + user.ID.String() + "/sessions/" + list[0].ID.String()
+	assert.Equal(t, http.StatusForbidden, send(http.MethodDelete, path, other.ID).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, "/users/"+other.ID.String()+"/sessions/"+list[0].ID.String(), other.ID).Code)
+	assert.Equal(t, http.StatusBadRequest, send(http.MethodDelete, "/users/"+user.ID.String()+"/sessions/phone", user.ID).Code)
+	require.Equal(t, http.StatusNoContent, send(http.MethodDelete, path, user.ID).Code)
+	assert.Equal(t, http.StatusNotFound, send(http.MethodDelete, path, user.ID).Code)
+
+	list = sessions(user.ID)
+	require.Len(t, list, 1)
+	assert.Equal(t, "phone", list[0].UserAgent)
+}
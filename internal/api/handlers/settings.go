@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SettingsHandler lets administrators change runtime settings without a redeploy
+type SettingsHandler struct {
+	settings  *settings.Store
+	auditRepo repository.AuditLogRepository
+}
+
+// NewSettingsHandler creates a new SettingsHandler
+func NewSettingsHandler(store *settings.Store, auditRepo repository.AuditLogRepository) *SettingsHandler {
+	return &SettingsHandler{
+		settings:  store,
+		auditRepo: auditRepo,
+	}
+}
+
+// ListSettings godoc
+// @Summary List runtime settings
+// @Description Returns the settings that can be changed at runtime with their effective and configured values (admin only)
+// @Tags settings
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.RuntimeSetting
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /admin/settings [get]
+// @Router /settings [get]
+func (h *SettingsHandler) ListSettings(c *gin.Context) {
+	c.JSON(http.StatusOK, h.settings.List())
+}
+
+// UpdateSetting godoc
+// @Summary Override a runtime setting
+// @Description Stores a value that takes precedence over the environment and config file. It applies immediately on this instance and within 30 seconds on others. (admin only)
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param key path string true "Setting key, e.g. auth.registration_open"
+// @Param request body models.UpdateSettingRequest true "New value"
+// @Success 200 {object} models.RuntimeSetting
+// @Failure 400 {object} apierror.Problem "Invalid value"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Unknown setting"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /admin/settings/{key} [put]
+func (h *SettingsHandler) UpdateSetting(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.UpdateSettingRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	key := c.Param("key")
+	previous, err := h.settings.Get(key)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+	setting, err := h.settings.Set(c.Request.Context(), key, req.Value, &authUser.ID)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	h.audit(c, authUser, "Runtime setting "+key+" changed", map[string]string{
+		"key": key,
+		"old": previous.Value,
+		"new": setting.Value,
+	})
+	c.JSON(http.StatusOK, setting)
+}
+
+// UpdateSettings godoc
+// @Summary Override several runtime settings
+// @Description Stores values for several settings at once. Every value is validated first, so an invalid one changes nothing. They apply immediately on this instance and within 30 seconds on others. (admin only)
+// @Tags settings
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.UpdateSettingsRequest true "New values by setting key"
+// @Success 200 {array} models.RuntimeSetting
+// @Failure 400 {object} apierror.Problem "Unknown setting or invalid value"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /settings [put]
+func (h *SettingsHandler) UpdateSettings(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.UpdateSettingsRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	previous := make(map[string]string, len(req.Settings))
+	for _, setting := range h.settings.List() {
+		previous[setting.Key] = setting.Value
+	}
+	updated, err := h.settings.SetMany(c.Request.Context(), req.Settings, &authUser.ID)
+	if err != nil {
+		if errors.Is(err, settings.ErrUnknownSetting) {
+			apierror.Write(c, apierror.InvalidRequest, err.Error())
+			return
+		}
+		h.respondError(c, err)
+		return
+	}
+
+	for _, setting := range updated {
+		h.audit(c, authUser, "Runtime setting "+setting.Key+" changed", map[string]string{
+			"key": setting.Key,
+			"old": previous[setting.Key],
+			"new": setting.Value,
+		})
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// ResetSetting godoc
+// @Summary Reset a runtime setting
+// @Description Removes the override so the value from the environment or config file applies again (admin only)
+// @Tags settings
+// @Produce json
+// @Security BearerAuth
+// @Param key path string true "Setting key, e.g. auth.registration_open"
+// @Success 200 {object} models.RuntimeSetting
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Unknown setting"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Router /admin/settings/{key} [delete]
+func (h *SettingsHandler) ResetSetting(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	key := c.Param("key")
+	setting, err := h.settings.Reset(c.Request.Context(), key)
+	if err != nil {
+		h.respondError(c, err)
+		return
+	}
+
+	h.audit(c, authUser, "Runtime setting "+key+" reset to the configured value", map[string]string{
+		"key":   key,
+		"value": setting.Value,
+	})
+	c.JSON(http.StatusOK, setting)
+}
+
+func (h *SettingsHandler) respondError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, settings.ErrUnknownSetting):
+		apierror.Write(c, apierror.SettingNotFound, "unknown setting")
+	case errors.Is(err, settings.ErrInvalidValue):
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+	default:
+		log.Printf("Error updating runtime setting: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to update setting")
+	}
+}
+
+func (h *SettingsHandler) audit(c *gin.Context, authUser *models.User, description string, metadata map[string]string) {
+	data, _ := json.Marshal(metadata)
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "user",
+		EntityID:    authUser.ID.String(),
+		Description: description,
+		Metadata:    string(data),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging runtime setting change: %v", err)
+	}
+}
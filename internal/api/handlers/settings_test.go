@@ -0,0 +1,179 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/settings"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingsHandler_UpdateSetting(t *testing.T) {
+	tests := []struct {
+		name       string
+		isAdmin    bool
+		key        string
+		body       string
+		wantStatus int
+	}{
+		{
+			name:       "Close Registration",
+			isAdmin:    true,
+			key:        settings.KeyRegistrationOpen,
+			body:       `{"value":"false"}`,
+			wantStatus: http.StatusOK,
+		},
+		{
+			name:       "Invalid Value",
+			isAdmin:    true,
+			key:        settings.KeyRegistrationOpen,
+			body:       `{"value":"sometimes"}`,
+			wantStatus: http.StatusBadRequest,
+		},
+		{
+			name:       "Unknown Setting",
+			isAdmin:    true,
+			key:        "database.host",
+			body:       `{"value":"elsewhere"}`,
+			wantStatus: http.StatusNotFound,
+		},
+		{
+			name:       "Non Admin",
+			isAdmin:    false,
+			key:        settings.KeyRegistrationOpen,
+			body:       `{"value":"false"}`,
+			wantStatus: http.StatusForbidden,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tc := testutil.NewTestContext(t)
+			user := tc.CreateTestUser("user", "user@test.com", "password123", tt.isAdmin)
+
+			handler := handlers.NewSettingsHandler(tc.Settings, tc.AuditRepo)
+			router := gin.New()
+			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+			router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+			router.PUT("/admin/settings/:key", handler.UpdateSetting)
+
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest("PUT", "/admin/settings/"+tt.key, bytes.NewBufferString(tt.body))
+			req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusOK {
+				var setting models.RuntimeSetting
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &setting))
+				assert.Equal(t, "false", setting.Value)
+				assert.True(t, setting.Overridden)
+			}
+			assert.Equal(t, tt.wantStatus != http.StatusOK, tc.Settings.RegistrationOpen())
+		})
+	}
+}
+
+func TestSettingsHandler_RegistrationOverride(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	handler := handlers.NewSettingsHandler(tc.Settings, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.POST("/auth/register", tc.AuthHandler.Register)
+	adminRoutes := router.Group("/admin", authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	adminRoutes.PUT("/settings/:key", handler.UpdateSetting)
+	adminRoutes.DELETE("/settings/:key", handler.ResetSetting)
+
+	send := func(method, path, body, token string) int {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+	token := tc.GetTestJWT(admin.ID)
+
+	// Closing registration at runtime overrides the configuration
+	require.True(t, tc.Config.Auth.RegistrationOpen)
+	require.Equal(t, http.StatusOK, send("PUT", "/admin/settings/auth.registration_open", `{"value":"false"}`, token))
+	assert.Equal(t, http.StatusForbidden, send("POST", "/auth/register", `{"username":"newuser","email":"new@example.com","password":"test_password"}`, ""))
+
+	// Resetting it restores the configured value
+	require.Equal(t, http.StatusOK, send("DELETE", "/admin/settings/auth.registration_open", "", token))
+	assert.Equal(t, http.StatusCreated, send("POST", "/auth/register", `{"username":"newuser","email":"new@example.com","password":"test_password"}`, ""))
+
+	// The default role must exist
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/admin/settings/auth.default_role", `{"value":""}`, token))
+}
+
+func TestSettingsHandler_UpdateSettings(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	handler := handlers.NewSettingsHandler(tc.Settings, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.POST("/auth/login", tc.AuthHandler.Login)
+	settingsRoutes := router.Group("/settings", authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	settingsRoutes.GET("", handler.ListSettings)
+	settingsRoutes.PUT("", handler.UpdateSettings)
+
+	send := func(method, path, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+	token := tc.GetTestJWT(admin.ID)
+
+	assert.Equal(t, http.StatusForbidden, send("PUT", "/settings", `{"settings":{"auth.lockout_threshold":"1"}}`, tc.GetTestJWT(user.ID)).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/settings", `{"settings":{}}`, token).Code)
+
+	// An invalid or unknown setting changes nothing
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/settings", `{"settings":{"auth.lockout_threshold":"1","password.history_depth":"many"}}`, token).Code)
+	assert.Equal(t, http.StatusBadRequest, send("PUT", "/settings", `{"settings":{"auth.lockout_threshold":"1","database.host":"elsewhere"}}`, token).Code)
+	assert.Equal(t, tc.Config.Auth.LockoutThreshold, tc.Settings.LockoutThreshold())
+
+	w := send("PUT", "/settings", `{"settings":{"auth.lockout_threshold":"1","auth.lockout_window":"1h"}}`, token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var updated []models.RuntimeSetting
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+	require.Len(t, updated, 2)
+	assert.Equal(t, settings.KeyLockoutThreshold, updated[0].Key)
+	assert.Equal(t, "1", updated[0].Value)
+
+	w = send("GET", "/settings", "", token)
+	require.Equal(t, http.StatusOK, w.Code)
+	var list []models.RuntimeSetting
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+	for _, setting := range list {
+		if setting.Key == settings.KeyLockoutWindow {
+			assert.Equal(t, "1h", setting.Value)
+			assert.True(t, setting.Overridden)
+		}
+	}
+
+	// The lowered threshold locks the account after a single failed login
+	assert.Equal(t, http.StatusUnauthorized, send("POST", "/auth/login", `{"username":"user","password":"wrong"}`, "").Code)
+	assert.Equal(t, http.StatusTooManyRequests, send("POST", "/auth/login", `{"username":"user","password":"password123"}`, "").Code)
+}
@@ -1,8 +1,15 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
 	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/exchangerate"
+	"wattwatch/internal/metrics"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
 
@@ -15,6 +22,18 @@ type SpotPriceHandler struct {
 	repo         repository.SpotPriceRepository
 	zoneRepo     repository.ZoneRepository
 	currencyRepo repository.CurrencyRepository
+	limits       ListLimits
+	// maxRange is the longest time range listed at once
+	maxRange time.Duration
+	// exchangeRates converts prices to the currency asked for, conversion is unavailable
+	// when it is nil
+	exchangeRates repository.ExchangeRateRepository
+	// preferences holds the defaults used for omitted parameters, there are none when it
+	// is nil
+	preferences repository.UserPreferenceRepository
+	// revisions holds the earlier prices of spot prices, their history is unavailable when
+	// it is nil
+	revisions repository.SpotPriceRevisionRepository
 }
 
 // NewSpotPriceHandler creates a new SpotPriceHandler
@@ -23,61 +42,160 @@ func NewSpotPriceHandler(repo repository.SpotPriceRepository, zoneRepo repositor
 		repo:         repo,
 		zoneRepo:     zoneRepo,
 		currencyRepo: currencyRepo,
+		limits:       DefaultListLimits,
+		maxRange:     DefaultSpotPriceMaxRange,
 	}
 }
 
+// DefaultSpotPriceMaxRange is the longest time range spot prices are listed for until the
+// handler is given the configured one
+const DefaultSpotPriceMaxRange = 366 * 24 * time.Hour
+
+// SetListLimits sets the number of spot price conflicts and revisions listed
+func (h *SpotPriceHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// SetMaxRange sets the longest time range spot prices are listed for at once
+func (h *SpotPriceHandler) SetMaxRange(maxRange time.Duration) {
+	h.maxRange = maxRange
+}
+
+// formatRange formats d in days when it is a whole number of them
+func formatRange(d time.Duration) string {
+	if d%(24*time.Hour) == 0 {
+		return fmt.Sprintf("%d days", d/(24*time.Hour))
+	}
+	return d.String()
+}
+
+// SetExchangeRates enables converting listed prices to another currency with the rates
+// in repo
+func (h *SpotPriceHandler) SetExchangeRates(repo repository.ExchangeRateRepository) {
+	h.exchangeRates = repo
+}
+
+// SetPreferences makes authenticated requests without a zone or currency use the
+// defaults of the user, and lists timestamps in the user's timezone
+func (h *SpotPriceHandler) SetPreferences(repo repository.UserPreferenceRepository) {
+	h.preferences = repo
+}
+
+// SetRevisions enables listing the earlier prices of spot prices from repo
+func (h *SpotPriceHandler) SetRevisions(repo repository.SpotPriceRevisionRepository) {
+	h.revisions = repo
+}
+
+// spotPriceDefaults are what the authenticated user picked for omitted parameters
+type spotPriceDefaults struct {
+	zone, currency string
+	// location is the timezone of listed timestamps, nil keeps them as stored
+	location *time.Location
+}
+
+// defaults returns the preferences of the authenticated user, none for anonymous requests.
+// The request is served without them when they can't be read.
+func (h *SpotPriceHandler) defaults(c *gin.Context) spotPriceDefaults {
+	return userDefaults(c, h.preferences)
+}
+
+// userDefaults returns the defaults the authenticated user picked in preferences, none
+// when preferences is nil
+func userDefaults(c *gin.Context, preferences repository.UserPreferenceRepository) spotPriceDefaults {
+	var defaults spotPriceDefaults
+	user := GetUserFromContext(c)
+	if user == nil || preferences == nil {
+		return defaults
+	}
+	prefs, err := preferences.Get(c.Request.Context(), user.ID)
+	if err != nil {
+		log.Printf("Error getting preferences of user %s: %v", user.ID, err)
+		return defaults
+	}
+	if prefs.Zone != nil {
+		defaults.zone = *prefs.Zone
+	}
+	if prefs.Currency != nil {
+		defaults.currency = *prefs.Currency
+	}
+	if prefs.Timezone != nil {
+		if loc, err := time.LoadLocation(*prefs.Timezone); err == nil {
+			defaults.location = loc
+		}
+	}
+	return defaults
+}
+
 // ListSpotPrices godoc
 // @Summary List spot prices
-// @Description Returns a list of spot prices for a specific zone and currency within a date range (max 7 days)
+// @Description Returns a list of spot prices for a specific zone and currency within a date range. The whole range is returned unless a limit is given, for ranges of up to 366 days unless configured otherwise, and large responses are streamed as the prices are read. Longer ranges are walked in pages of at most limit prices, ordered by timestamp and zone: each page without an offset gives the cursor of the next in next_cursor. Authenticated users can leave out the zone and currency they picked as defaults, and get timestamps in the timezone they picked.
 // @Tags spot-prices
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param zone query string true "Zone name (e.g., 'SE1')"
-// @Param currency query string true "Currency name (e.g., 'EUR')"
+// @Param zone query string false "Zone name (e.g., 'SE1'), required unless the user picked a default"
+// @Param currency query string false "Currency name (e.g., 'EUR'), required unless the user picked a default"
 // @Param start_time query string true "Start time (RFC3339)"
 // @Param end_time query string true "End time (RFC3339)"
 // @Param order_desc query boolean false "Order descending"
-// @Success 200 {array} models.SpotPrice
-// @Failure 400 {object} models.ErrorResponse "Invalid parameters or date range exceeds 7 days"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 404 {object} models.ErrorResponse "Zone or currency not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Param limit query integer false "Limit results (default the whole range, or the maximum of 1000 unless configured otherwise with a cursor)"
+// @Param offset query integer false "Offset results"
+// @Param cursor query string false "next_cursor of the previous page, continues the listing after it. Not combined with offset."
+// @Param format query string false "Response format, columnar returns a models.SpotPriceSeries" Enums(objects, columnar)
+// @Param envelope query boolean false "Wrap the objects in a page with the total count (default true)"
+// @Param convert_to query string false "Currency name to convert prices to with the exchange rate in effect at each price's timestamp (e.g., 'SEK')"
+// @Param as_of query string false "List the prices as they were at this time, leaving out those stored later (RFC3339)"
+// @Param If-None-Match header string false "ETag of the prices the client has, not used with convert_to"
+// @Param If-Modified-Since header string false "When the client got the prices it has, not used with convert_to"
+// @Success 200 {object} models.Page[models.SpotPrice]
+// @Success 304 "Not Modified"
+// @Failure 400 {object} apierror.Problem "Invalid parameters, date range too long or no exchange rate at start_time"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /spot-prices [get]
 func (h *SpotPriceHandler) ListSpotPrices(c *gin.Context) {
 	filter := repository.SpotPriceFilter{}
 
+	format := c.DefaultQuery("format", "objects")
+	if format != "objects" && format != "columnar" {
+		apierror.Write(c, apierror.InvalidRequest, "invalid format, use objects or columnar")
+		return
+	}
+
+	defaults := h.defaults(c)
+
 	// Parse zone name and get ID
-	zoneName := c.Query("zone")
+	zoneName := c.DefaultQuery("zone", defaults.zone)
 	if zoneName == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "zone is required"})
+		apierror.Write(c, apierror.InvalidRequest, "zone is required")
 		return
 	}
 	zone, err := h.zoneRepo.GetByName(c.Request.Context(), zoneName)
 	if err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "zone not found"})
+		apierror.Write(c, apierror.ZoneNotFound, "zone not found")
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to fetch zone"})
+		apierror.Write(c, apierror.Internal, "failed to fetch zone")
 		return
 	}
 	filter.ZoneID = &zone.ID
 
 	// Parse currency name and get ID
-	currencyName := c.Query("currency")
+	currencyName := c.DefaultQuery("currency", defaults.currency)
 	if currencyName == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "currency is required"})
+		apierror.Write(c, apierror.InvalidRequest, "currency is required")
 		return
 	}
 	currency, err := h.currencyRepo.GetByName(c.Request.Context(), currencyName)
 	if err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "currency not found"})
+		apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to fetch currency"})
+		apierror.Write(c, apierror.Internal, "failed to fetch currency")
 		return
 	}
 	filter.CurrencyID = &currency.ID
@@ -85,12 +203,12 @@ func (h *SpotPriceHandler) ListSpotPrices(c *gin.Context) {
 	// Parse start_time (required)
 	startTimeStr := c.Query("start_time")
 	if startTimeStr == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "start_time is required"})
+		apierror.Write(c, apierror.InvalidRequest, "start_time is required")
 		return
 	}
 	startTime, err := time.Parse(time.RFC3339, startTimeStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid start time format, use RFC3339"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid start time format, use RFC3339")
 		return
 	}
 	filter.StartTime = &startTime
@@ -98,24 +216,18 @@ func (h *SpotPriceHandler) ListSpotPrices(c *gin.Context) {
 	// Parse end_time (required)
 	endTimeStr := c.Query("end_time")
 	if endTimeStr == "" {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end_time is required"})
+		apierror.Write(c, apierror.InvalidRequest, "end_time is required")
 		return
 	}
 	endTime, err := time.Parse(time.RFC3339, endTimeStr)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid end time format, use RFC3339"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid end time format, use RFC3339")
 		return
 	}
 	filter.EndTime = &endTime
 
-	// Validate date range (max 7 days)
-	if endTime.Sub(startTime) > 7*24*time.Hour {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "date range cannot exceed 7 days"})
-		return
-	}
-
 	if endTime.Before(startTime) {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "end_time must be after start_time"})
+		apierror.Write(c, apierror.InvalidRequest, "end_time must be after start_time")
 		return
 	}
 
@@ -125,17 +237,271 @@ func (h *SpotPriceHandler) ListSpotPrices(c *gin.Context) {
 		filter.OrderDesc = true
 	}
 
-	// Set maximum limit
-	maxLimit := 1000 // This allows for up to ~6 prices per hour for 7 days
-	filter.Limit = &maxLimit
+	if cursorStr := c.Query("cursor"); cursorStr != "" {
+		cursor, err := repository.DecodeSpotPriceCursor(cursorStr)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid cursor")
+			return
+		}
+		if c.Query("offset") != "" {
+			apierror.Write(c, apierror.InvalidRequest, "cursor cannot be combined with offset")
+			return
+		}
+		filter.After = &cursor
+	}
 
-	spotPrices, err := h.repo.List(c.Request.Context(), filter)
+	// The whole range is returned by default, its length bounds the number of prices. Pages
+	// are bounded by their limit instead, so they can walk ranges of any length.
+	limit := -1
+	if c.Query("limit") != "" || filter.After != nil {
+		if limit, err = h.limits.limit(c, h.limits.Max); err != nil {
+			apierror.Write(c, apierror.InvalidRequest, err.Error())
+			return
+		}
+		filter.Limit = &limit
+	} else if endTime.Sub(startTime) > h.maxRange {
+		apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("date range cannot exceed %s without a limit or cursor", formatRange(h.maxRange)))
+		return
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	// Pages without an offset end with the cursor of their last price. One price past the
+	// limit is read to tell whether another page follows.
+	paging := filter.Limit != nil && filter.Offset == nil
+	if paging {
+		readLimit := limit + 1
+		filter.Limit = &readLimit
+	}
+
+	if asOfStr := c.Query("as_of"); asOfStr != "" {
+		asOf, err := time.Parse(time.RFC3339, asOfStr)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid as_of format, use RFC3339")
+			return
+		}
+		filter.AsOf = &asOf
+	}
+
+	// Prices are converted as they are read, with the rates in effect over the range
+	convert := func(sp *models.SpotPrice) error { return nil }
+	converting := false
+	if convertTo := c.Query("convert_to"); convertTo != "" && convertTo != currency.Name {
+		converting = true
+		if h.exchangeRates == nil {
+			apierror.Write(c, apierror.InvalidRequest, "currency conversion is not available")
+			return
+		}
+		target, err := h.currencyRepo.GetByName(c.Request.Context(), convertTo)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.CurrencyNotFound, "convert_to currency not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch currency")
+			return
+		}
+		converter, err := exchangerate.NewConverter(c.Request.Context(), h.exchangeRates, currency.ID, target.ID, startTime, endTime)
+		if errors.Is(err, exchangerate.ErrNoRate) {
+			apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("no exchange rate from %s to %s at start_time", currency.Name, target.Name))
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch exchange rates")
+			return
+		}
+		convert = func(sp *models.SpotPrice) error {
+			price, err := converter.Convert(sp.Price, sp.Timestamp)
+			if err != nil {
+				return err
+			}
+			sp.Price, sp.CurrencyID = price, target.ID
+			return nil
+		}
+		currency = target
+	}
+	if loc := defaults.location; loc != nil {
+		convertPrice := convert
+		convert = func(sp *models.SpotPrice) error {
+			if err := convertPrice(sp); err != nil {
+				return err
+			}
+			sp.Timestamp = sp.Timestamp.In(loc)
+			return nil
+		}
+	}
+
+	// Polling clients get 304 Not Modified while the prices they have are current. Converted
+	// prices also change with the exchange rates, so they are always sent.
+	total := -1
+	if !converting {
+		lastModified, count, err := h.repo.LastModified(c.Request.Context(), filter)
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch spot prices")
+			return
+		}
+		location := ""
+		if defaults.location != nil {
+			location = defaults.location.String()
+		}
+		etag := etagOf(c.Request.URL.RawQuery, zone.ID, currency.ID, location, lastModified.UnixNano(), count)
+		if notModified(c, etag, lastModified) {
+			return
+		}
+		total = count
+	}
+
+	// A year of prices is large, so they are written as they are read
+	read, more := 0, false
+	var last repository.SpotPriceCursor
+	each := func(yield func(*models.SpotPrice) error) error {
+		return h.repo.Each(c.Request.Context(), filter, func(sp *models.SpotPrice) error {
+			if paging && read == limit {
+				more = true
+				return nil
+			}
+			read++
+			last = repository.SpotPriceCursor{Timestamp: sp.Timestamp, ZoneID: sp.ZoneID}
+			if err := convert(sp); err != nil {
+				return err
+			}
+			return yield(sp)
+		})
+	}
+	nextCursor := func() string {
+		if !more {
+			return ""
+		}
+		return last.Encode()
+	}
+
+	if format == "columnar" {
+		series := models.SpotPriceSeries{Zone: zone.Name, Currency: currency.Name, Timestamps: []time.Time{}, Prices: []float64{}}
+		err := each(func(sp *models.SpotPrice) error {
+			series.Timestamps = append(series.Timestamps, sp.Timestamp)
+			series.Prices = append(series.Prices, sp.Price)
+			return nil
+		})
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch spot prices")
+			return
+		}
+		series.NextCursor = nextCursor()
+		c.JSON(http.StatusOK, series)
+		return
+	}
+
+	if !wantsEnvelope(c) {
+		err = streamJSONArray(c, each)
+	} else {
+		if total < 0 {
+			total, err = h.repo.Total(c.Request.Context(), filter)
+		}
+		if limit < 0 {
+			limit = total
+		}
+		if err == nil && paging {
+			err = streamJSONCursorPage(c, total, limit, filter.After == nil, each, nextCursor)
+		} else if err == nil {
+			err = streamJSONPage(c, total, limit, offset, each)
+		}
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch spot prices")
+	}
+}
+
+// aggregateRanges are the longest time ranges aggregated, by bucket length
+var aggregateRanges = map[models.AggregatePeriod]time.Duration{
+	models.AggregateHour:  31 * 24 * time.Hour,
+	models.AggregateDay:   366 * 24 * time.Hour,
+	models.AggregateWeek:  2 * 366 * 24 * time.Hour,
+	models.AggregateMonth: 10 * 366 * 24 * time.Hour,
+}
+
+// AggregateSpotPrices godoc
+// @Summary Aggregate spot prices
+// @Description Returns the average, minimum, maximum and standard deviation of spot prices per zone, currency and hour, day, week or month. Days, weeks and months follow the zone's timezone and weeks start on Monday. The range may span at most 31 days by hour, 366 days by day, 2 years by week and 10 years by month.
+// @Tags spot-prices
+// @Produce json
+// @Security BearerAuth
+// @Param group_by query string true "Bucket length" Enums(hour, day, week, month)
+// @Param zone query string false "Zone name (e.g., 'SE1'), all zones when omitted"
+// @Param currency query string false "Currency name (e.g., 'EUR'), all currencies when omitted"
+// @Param start_time query string true "Start time, inclusive (RFC3339)"
+// @Param end_time query string true "End time, exclusive (RFC3339)"
+// @Success 200 {array} models.SpotPriceAggregate
+// @Failure 400 {object} apierror.Problem "Invalid parameters or range too long for group_by"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /spot-prices/aggregate [get]
+func (h *SpotPriceHandler) AggregateSpotPrices(c *gin.Context) {
+	filter := repository.SpotPriceAggregateFilter{GroupBy: models.AggregatePeriod(c.Query("group_by"))}
+	if !filter.GroupBy.Valid() {
+		apierror.Write(c, apierror.InvalidRequest, "invalid group_by, use hour, day, week or month")
+		return
+	}
+
+	if zoneName := c.Query("zone"); zoneName != "" {
+		zone, err := h.zoneRepo.GetByName(c.Request.Context(), zoneName)
+		if err == repository.ErrNotFound {
+			apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch zone")
+			return
+		}
+		filter.ZoneID = &zone.ID
+	}
+
+	if currencyName := c.Query("currency"); currencyName != "" {
+		currency, err := h.currencyRepo.GetByName(c.Request.Context(), currencyName)
+		if err == repository.ErrNotFound {
+			apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch currency")
+			return
+		}
+		filter.CurrencyID = &currency.ID
+	}
+
+	var err error
+	if filter.StartTime, err = time.Parse(time.RFC3339, c.Query("start_time")); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "start_time is required, use RFC3339")
+		return
+	}
+	if filter.EndTime, err = time.Parse(time.RFC3339, c.Query("end_time")); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "end_time is required, use RFC3339")
+		return
+	}
+	if !filter.EndTime.After(filter.StartTime) {
+		apierror.Write(c, apierror.InvalidRequest, "end_time must be after start_time")
+		return
+	}
+	if filter.EndTime.Sub(filter.StartTime) > aggregateRanges[filter.GroupBy] {
+		apierror.Write(c, apierror.InvalidRequest, "date range is too long for group_by "+string(filter.GroupBy))
+		return
+	}
+
+	aggregates, err := h.repo.Aggregate(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to fetch spot prices"})
+		apierror.Write(c, apierror.Internal, "failed to aggregate spot prices")
 		return
 	}
 
-	c.JSON(http.StatusOK, spotPrices)
+	c.JSON(http.StatusOK, aggregates)
 }
 
 // GetSpotPrice godoc
@@ -146,85 +512,179 @@ func (h *SpotPriceHandler) ListSpotPrices(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Spot Price ID"
+// @Param If-None-Match header string false "ETag of the spot price the client has"
+// @Param If-Modified-Since header string false "When the client got the spot price it has"
 // @Success 200 {object} models.SpotPrice
-// @Failure 400 {object} models.ErrorResponse "Invalid spot price ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 404 {object} models.ErrorResponse "Spot price not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} apierror.Problem "Invalid spot price ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Spot price not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /spot-prices/{id} [get]
 func (h *SpotPriceHandler) GetSpotPrice(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid spot price ID"})
+		apierror.Write(c, apierror.InvalidRequest, "Invalid spot price ID")
 		return
 	}
 
 	spotPrice, err := h.repo.GetByID(c.Request.Context(), id)
 	if err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Spot price not found"})
+		apierror.Write(c, apierror.SpotPriceNotFound, "Spot price not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "Failed to fetch spot price")
+		return
+	}
+
+	respondConditional(c, spotPrice, spotPrice.UpdatedAt)
+}
+
+// GetSpotPriceHistory godoc
+// @Summary Get the history of a spot price
+// @Description Returns the earlier prices of a spot price, newest first, with who or which source changed each and when
+// @Tags spot-prices
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Spot Price ID"
+// @Param limit query integer false "Limit results (default 50, maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the revisions in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.SpotPriceRevision]
+// @Failure 400 {object} apierror.Problem "Invalid spot price ID, limit or offset"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Spot price not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Failure 503 {object} apierror.Problem "Spot price history is not available"
+// @Router /spot-prices/{id}/history [get]
+func (h *SpotPriceHandler) GetSpotPriceHistory(c *gin.Context) {
+	if h.revisions == nil {
+		apierror.Write(c, apierror.Unavailable, "spot price history is not available")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "Invalid spot price ID")
 		return
 	}
+
+	limit, err := h.limits.limit(c, h.limits.Default)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch spot price"})
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if offset, err = strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+	}
+
+	if _, err := h.repo.GetByID(c.Request.Context(), id); err == repository.ErrNotFound {
+		apierror.Write(c, apierror.SpotPriceNotFound, "Spot price not found")
+		return
+	} else if err != nil {
+		apierror.Write(c, apierror.Internal, "Failed to fetch spot price")
 		return
 	}
 
-	c.JSON(http.StatusOK, spotPrice)
+	revisions, err := h.revisions.ListBySpotPriceID(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch spot price history")
+		return
+	}
+	respondPage(c, revisions, &limit, &offset, func() (int, error) {
+		return h.revisions.CountBySpotPriceID(c.Request.Context(), id)
+	}, "failed to fetch spot price history")
 }
 
 // CreateSpotPrices godoc
-// @Summary Create or update spot prices (Admin only)
-// @Description Creates or updates one or more spot prices in a single transaction. If a spot price with the same timestamp, zone_id, and currency_id exists, its price will be updated. Requires admin privileges.
+// @Summary Create or update spot prices
+// @Description Creates or updates one or more spot prices in a single transaction. If a spot price with the same timestamp, zone_id, and currency_id exists, its price will be updated. Requires the spot_prices:write permission.
 // @Tags spot-prices
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param spot_prices body models.CreateSpotPricesRequest true "Spot prices to create or update"
 // @Success 201 {array} models.SpotPrice
-// @Failure 400 {object} models.ErrorResponse "Invalid request body, negative price, or invalid zone/currency"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - admin only"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid request body, negative price, invalid zone/currency or duplicate spot prices"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - spot_prices:write required"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /spot-prices [post]
 func (h *SpotPriceHandler) CreateSpotPrices(c *gin.Context) {
 	var req models.CreateSpotPricesRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	if len(req.SpotPrices) == 0 {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "at least one spot price is required"})
+		apierror.Write(c, apierror.InvalidRequest, "at least one spot price is required")
 		return
 	}
 
-	// Convert request to spot prices
+	// Convert request to spot prices, counting them per zone and currency name. Imports
+	// hold few zones and currencies, so each is looked up once.
 	spotPrices := make([]models.SpotPrice, len(req.SpotPrices))
+	ingested := make(map[[2]string]int)
+	zones := make(map[uuid.UUID]*models.Zone)
+	currencies := make(map[uuid.UUID]*models.Currency)
+	type priceKey struct {
+		timestamp          int64
+		zoneID, currencyID uuid.UUID
+	}
+	seen := make(map[priceKey]bool, len(req.SpotPrices))
 	for i, sp := range req.SpotPrices {
 		if sp.Price < 0 {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "price cannot be negative"})
+			apierror.Write(c, apierror.InvalidRequest, "price cannot be negative")
 			return
 		}
 
-		// Validate zone ID exists
-		if _, err := h.zoneRepo.GetByID(c.Request.Context(), sp.ZoneID); err == repository.ErrNotFound {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid zone id"})
-			return
-		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to validate zone"})
+		// One statement can't update a row twice, so a batch holds each spot price once
+		key := priceKey{sp.Timestamp.UnixNano(), sp.ZoneID, sp.CurrencyID}
+		if seen[key] {
+			apierror.Write(c, apierror.InvalidRequest, fmt.Sprintf("duplicate spot price at index %d", i))
 			return
 		}
+		seen[key] = true
+
+		// Validate zone ID exists
+		zone, ok := zones[sp.ZoneID]
+		if !ok {
+			var err error
+			zone, err = h.zoneRepo.GetByID(c.Request.Context(), sp.ZoneID)
+			if err == repository.ErrNotFound {
+				apierror.Write(c, apierror.InvalidRequest, "invalid zone id")
+				return
+			} else if err != nil {
+				apierror.Write(c, apierror.Internal, "failed to validate zone")
+				return
+			}
+			zones[sp.ZoneID] = zone
+		}
 
 		// Validate currency ID exists
-		if _, err := h.currencyRepo.GetByID(c.Request.Context(), sp.CurrencyID); err == repository.ErrNotFound {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid currency id"})
-			return
-		} else if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to validate currency"})
-			return
+		currency, ok := currencies[sp.CurrencyID]
+		if !ok {
+			var err error
+			currency, err = h.currencyRepo.GetByID(c.Request.Context(), sp.CurrencyID)
+			if err == repository.ErrNotFound {
+				apierror.Write(c, apierror.InvalidRequest, "invalid currency id")
+				return
+			} else if err != nil {
+				apierror.Write(c, apierror.Internal, "failed to validate currency")
+				return
+			}
+			currencies[sp.CurrencyID] = currency
 		}
+		ingested[[2]string{zone.Name, currency.Name}]++
 
 		spotPrices[i] = models.SpotPrice{
 			ID:         uuid.New(),
@@ -235,42 +695,50 @@ func (h *SpotPriceHandler) CreateSpotPrices(c *gin.Context) {
 		}
 	}
 
-	if err := h.repo.CreateBatch(c.Request.Context(), spotPrices); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to create spot prices"})
+	// Prices changed by the upload are attributed to the user in their history
+	ctx := c.Request.Context()
+	if user := GetUserFromContext(c); user != nil {
+		ctx = repository.WithChangedBy(ctx, user.ID)
+	}
+	if err := h.repo.CreateBatch(ctx, spotPrices); err != nil {
+		apierror.Write(c, apierror.Internal, "failed to create spot prices")
 		return
 	}
+	for series, count := range ingested {
+		metrics.SpotPricesIngested("manual", series[0], series[1], count)
+	}
 
 	c.JSON(http.StatusCreated, spotPrices)
 }
 
 // DeleteSpotPrice godoc
-// @Summary Delete a spot price (Admin only)
-// @Description Deletes an existing spot price. Requires admin privileges.
+// @Summary Delete a spot price
+// @Description Deletes an existing spot price. Requires the spot_prices:write permission.
 // @Tags spot-prices
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Spot Price ID"
 // @Success 200 {object} models.SuccessResponse "Spot price deleted successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid spot price ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - admin only"
-// @Failure 404 {object} models.ErrorResponse "Spot price not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid spot price ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - spot_prices:write required"
+// @Failure 404 {object} apierror.Problem "Spot price not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /spot-prices/{id} [delete]
 func (h *SpotPriceHandler) DeleteSpotPrice(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid spot price ID"})
+		apierror.Write(c, apierror.InvalidRequest, "Invalid spot price ID")
 		return
 	}
 
 	if err := h.repo.Delete(c.Request.Context(), id); err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Spot price not found"})
+		apierror.Write(c, apierror.SpotPriceNotFound, "Spot price not found")
 		return
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete spot price"})
+		apierror.Write(c, apierror.Internal, "Failed to delete spot price")
 		return
 	}
 
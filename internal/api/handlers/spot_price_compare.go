@@ -0,0 +1,146 @@
+package handlers
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCompareRange is the longest period compared
+const maxCompareRange = 366 * 24 * time.Hour
+
+// compareOffsets shift a period back to the one it is compared against, by name
+var compareOffsets = map[string]func(time.Time) time.Time{
+	"week":  func(t time.Time) time.Time { return t.AddDate(0, 0, -7) },
+	"month": func(t time.Time) time.Time { return t.AddDate(0, -1, 0) },
+	"year":  func(t time.Time) time.Time { return t.AddDate(-1, 0, 0) },
+}
+
+// CompareSpotPrices godoc
+// @Summary Compare spot prices with earlier periods
+// @Description Returns the average, minimum and maximum spot price from start_time until end_time and those of the same period a week, month or year earlier, with how the period's differ from each in the currency and in percent. Earlier periods are shifted in the zone's timezone, so a month starting at midnight is compared with the month before starting at midnight too.
+// @Tags spot-prices
+// @Produce json
+// @Security BearerAuth
+// @Param zone query string false "Zone name (e.g., 'SE3'), required unless the user picked a default"
+// @Param currency query string false "Currency name (e.g., 'SEK'), required unless the user picked a default"
+// @Param start_time query string true "Start of the period (RFC3339)"
+// @Param end_time query string true "End of the period, exclusive (RFC3339), at most 366 days after start_time"
+// @Param against query string false "Comma separated earlier periods to compare with: week, month or year (default all)"
+// @Success 200 {object} models.SpotPriceComparison
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /spot-prices/compare [get]
+func (h *SpotPriceHandler) CompareSpotPrices(c *gin.Context) {
+	defaults := h.defaults(c)
+	zoneName := c.DefaultQuery("zone", defaults.zone)
+	if zoneName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "zone is required")
+		return
+	}
+	currencyName := c.DefaultQuery("currency", defaults.currency)
+	if currencyName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "currency is required")
+		return
+	}
+
+	startTime, err := time.Parse(time.RFC3339, c.Query("start_time"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "start_time is required, use RFC3339")
+		return
+	}
+	endTime, err := time.Parse(time.RFC3339, c.Query("end_time"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "end_time is required, use RFC3339")
+		return
+	}
+	if !endTime.After(startTime) {
+		apierror.Write(c, apierror.InvalidRequest, "end_time must be after start_time")
+		return
+	}
+	if endTime.Sub(startTime) > maxCompareRange {
+		apierror.Write(c, apierror.InvalidRequest, "date range cannot exceed 366 days")
+		return
+	}
+
+	against := []string{"week", "month", "year"}
+	if againstStr := c.Query("against"); againstStr != "" {
+		against = nil
+		for _, name := range strings.Split(againstStr, ",") {
+			name = strings.TrimSpace(name)
+			if compareOffsets[name] == nil {
+				apierror.Write(c, apierror.InvalidRequest, "invalid against, use week, month or year")
+				return
+			}
+			if !slices.Contains(against, name) {
+				against = append(against, name)
+			}
+		}
+	}
+
+	zone, err := h.zoneRepo.GetByName(c.Request.Context(), zoneName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch zone")
+		return
+	}
+	loc, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "invalid zone timezone")
+		return
+	}
+
+	currency, err := h.currencyRepo.GetByName(c.Request.Context(), currencyName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch currency")
+		return
+	}
+
+	startTime, endTime = startTime.In(loc), endTime.In(loc)
+	filter := repository.SpotPriceCompareFilter{
+		ZoneID:     zone.ID,
+		CurrencyID: currency.ID,
+		Periods:    []repository.SpotPriceRange{{StartTime: startTime, EndTime: endTime}},
+	}
+	for _, name := range against {
+		shift := compareOffsets[name]
+		filter.Periods = append(filter.Periods, repository.SpotPriceRange{StartTime: shift(startTime), EndTime: shift(endTime)})
+	}
+
+	comparisons, err := h.repo.Compare(c.Request.Context(), filter)
+	if err != nil || len(comparisons) != len(filter.Periods) {
+		apierror.Write(c, apierror.Internal, "failed to compare spot prices")
+		return
+	}
+
+	result := models.SpotPriceComparison{
+		Zone:     zone.Name,
+		Currency: currency.Name,
+		Period:   comparisons[0].SpotPricePeriodStats,
+		Previous: comparisons[1:],
+	}
+	result.Period.Start, result.Period.End = startTime, endTime
+	for i := range result.Previous {
+		result.Previous[i].Against = against[i]
+		result.Previous[i].Start = filter.Periods[i+1].StartTime
+		result.Previous[i].End = filter.Periods[i+1].EndTime
+	}
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SpotPriceConflictHandler lets administrators review spot prices that providers disagree on
+type SpotPriceConflictHandler struct {
+	repo         repository.SpotPriceSourceRepository
+	zoneRepo     repository.ZoneRepository
+	currencyRepo repository.CurrencyRepository
+	auditRepo    repository.AuditLogRepository
+	limits       ListLimits
+}
+
+// NewSpotPriceConflictHandler creates a new SpotPriceConflictHandler
+func NewSpotPriceConflictHandler(
+	repo repository.SpotPriceSourceRepository,
+	zoneRepo repository.ZoneRepository,
+	currencyRepo repository.CurrencyRepository,
+	auditRepo repository.AuditLogRepository,
+) *SpotPriceConflictHandler {
+	return &SpotPriceConflictHandler{
+		repo:         repo,
+		zoneRepo:     zoneRepo,
+		currencyRepo: currencyRepo,
+		auditRepo:    auditRepo,
+		limits:       DefaultListLimits,
+	}
+}
+
+// SetListLimits sets the default and maximum number of conflicts listed
+func (h *SpotPriceConflictHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// ListConflicts godoc
+// @Summary List spot price conflicts
+// @Description Returns the spot prices that providers reported differing values for, newest first, with the value of every source (admin only)
+// @Tags spot-prices
+// @Produce json
+// @Security BearerAuth
+// @Param zone query string false "Zone name (e.g., 'SE1')"
+// @Param currency query string false "Currency name (e.g., 'EUR')"
+// @Param start_time query string false "Start time (RFC3339)"
+// @Param end_time query string false "End time (RFC3339)"
+// @Param include_resolved query boolean false "Include conflicts a source was already chosen for"
+// @Param limit query integer false "Limit results (default 50, maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the conflicts in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.SpotPriceConflict]
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/spot-prices/conflicts [get]
+func (h *SpotPriceConflictHandler) ListConflicts(c *gin.Context) {
+	filter := repository.SpotPriceConflictFilter{
+		IncludeResolved: c.Query("include_resolved") == "true",
+	}
+
+	if zoneName := c.Query("zone"); zoneName != "" {
+		zone, err := h.zoneRepo.GetByName(c.Request.Context(), zoneName)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch zone")
+			return
+		}
+		filter.ZoneID = &zone.ID
+	}
+
+	if currencyName := c.Query("currency"); currencyName != "" {
+		currency, err := h.currencyRepo.GetByName(c.Request.Context(), currencyName)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch currency")
+			return
+		}
+		filter.CurrencyID = &currency.ID
+	}
+
+	if startTimeStr := c.Query("start_time"); startTimeStr != "" {
+		startTime, err := time.Parse(time.RFC3339, startTimeStr)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid start time format, use RFC3339")
+			return
+		}
+		filter.StartTime = &startTime
+	}
+
+	if endTimeStr := c.Query("end_time"); endTimeStr != "" {
+		endTime, err := time.Parse(time.RFC3339, endTimeStr)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid end time format, use RFC3339")
+			return
+		}
+		filter.EndTime = &endTime
+	}
+
+	limit, err := h.limits.limit(c, h.limits.Default)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	filter.Limit = &limit
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	conflicts, err := h.repo.ListConflicts(c.Request.Context(), filter)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch spot price conflicts")
+		return
+	}
+
+	respondPage(c, conflicts, filter.Limit, filter.Offset, func() (int, error) {
+		return h.repo.TotalConflicts(c.Request.Context(), filter)
+	}, "failed to fetch spot price conflicts")
+}
+
+// ResolveConflict godoc
+// @Summary Resolve a spot price conflict
+// @Description Sets a spot price to the value a source reported. Values providers report later are still recorded but no longer replace it. (admin only)
+// @Tags spot-prices
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.ResolveSpotPriceConflictRequest true "Spot price and the source to keep"
+// @Success 200 {object} models.SpotPrice
+// @Failure 400 {object} apierror.Problem "Invalid request body"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Source reported no value for the spot price"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /admin/spot-prices/conflicts/resolve [post]
+func (h *SpotPriceConflictHandler) ResolveConflict(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.ResolveSpotPriceConflictRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	ctx := repository.WithChangedBy(c.Request.Context(), authUser.ID)
+	spotPrice, err := h.repo.Resolve(ctx, req.Timestamp, req.ZoneID, req.CurrencyID, req.Source)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.SpotPriceNotFound, "source reported no value for this spot price")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to resolve spot price conflict")
+		return
+	}
+
+	metadata, _ := json.Marshal(req)
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "spot_price",
+		EntityID:    spotPrice.ID.String(),
+		Description: "Resolved spot price conflict in favour of " + req.Source,
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging spot price conflict resolution: %v", err)
+	}
+
+	c.JSON(http.StatusOK, spotPrice)
+}
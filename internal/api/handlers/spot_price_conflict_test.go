@@ -0,0 +1,112 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpotPriceConflictHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewStore()
+	sourceRepo := memory.NewSpotPriceSourceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+
+	zone, err := zoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	timestamp := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	for source, price := range map[string]float64{"nordpool": 40, "entsoe": 42} {
+		require.NoError(t, sourceRepo.Record(ctx, source, []models.SpotPrice{
+			{Timestamp: timestamp, ZoneID: zone.ID, CurrencyID: currency.ID, Price: price},
+		}))
+	}
+
+	handler := handlers.NewSpotPriceConflictHandler(sourceRepo, zoneRepo, currencyRepo, memory.NewAuditLogRepository(store))
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", &models.User{ID: uuid.New(), Username: "admin"})
+		c.Next()
+	})
+	router.GET("/admin/spot-prices/conflicts", handler.ListConflicts)
+	router.POST("/admin/spot-prices/conflicts/resolve", handler.ResolveConflict)
+
+	list := func(query string) (int, []models.SpotPriceConflict) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/admin/spot-prices/conflicts"+query, nil)
+		router.ServeHTTP(w, req)
+		var page models.Page[models.SpotPriceConflict]
+		if w.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		}
+		return w.Code, page.Items
+	}
+	resolve := func(source string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(models.ResolveSpotPriceConflictRequest{
+			Timestamp:  timestamp,
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Source:     source,
+		})
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/admin/spot-prices/conflicts/resolve", bytes.NewBuffer(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("List", func(t *testing.T) {
+		code, conflicts := list("?zone=SE3&currency=EUR")
+		require.Equal(t, http.StatusOK, code)
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, []string{"entsoe", "nordpool"}, []string{conflicts[0].Values[0].Source, conflicts[0].Values[1].Source})
+		assert.Empty(t, conflicts[0].ResolvedSource)
+
+		code, conflicts = list(fmt.Sprintf("?start_time=%s", timestamp.Add(time.Hour).Format(time.RFC3339)))
+		require.Equal(t, http.StatusOK, code)
+		assert.Empty(t, conflicts)
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		code, _ := list("?zone=XX")
+		assert.Equal(t, http.StatusNotFound, code)
+		code, _ = list("?start_time=yesterday")
+		assert.Equal(t, http.StatusBadRequest, code)
+		code, _ = list("?offset=-1")
+		assert.Equal(t, http.StatusBadRequest, code)
+	})
+
+	t.Run("Resolve", func(t *testing.T) {
+		w := resolve("unknown")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		w = resolve("nordpool")
+		require.Equal(t, http.StatusOK, w.Code)
+		var spotPrice models.SpotPrice
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spotPrice))
+		assert.Equal(t, 40.0, spotPrice.Price)
+
+		_, conflicts := list("")
+		assert.Empty(t, conflicts)
+		_, conflicts = list("?include_resolved=true")
+		require.Len(t, conflicts, 1)
+		assert.Equal(t, "nordpool", conflicts[0].ResolvedSource)
+	})
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	// streamWriteWait is how long writing a message to a stream client may take
+	streamWriteWait = 10 * time.Second
+	// streamPongWait is how long a stream client may go without answering a ping
+	streamPongWait = 60 * time.Second
+	// streamPingPeriod is how often stream clients are pinged, less than streamPongWait
+	streamPingPeriod = streamPongWait * 9 / 10
+)
+
+// SpotPriceStreamHandler pushes spot prices to WebSocket clients as they are stored
+type SpotPriceStreamHandler struct {
+	hub          *pubsub.Hub
+	zoneRepo     repository.ZoneRepository
+	currencyRepo repository.CurrencyRepository
+	upgrader     websocket.Upgrader
+}
+
+// NewSpotPriceStreamHandler creates a new SpotPriceStreamHandler
+func NewSpotPriceStreamHandler(hub *pubsub.Hub, zoneRepo repository.ZoneRepository, currencyRepo repository.CurrencyRepository) *SpotPriceStreamHandler {
+	return &SpotPriceStreamHandler{
+		hub:          hub,
+		zoneRepo:     zoneRepo,
+		currencyRepo: currencyRepo,
+		upgrader: websocket.Upgrader{
+			// Spot prices are public and the stream doesn't rely on cookies, so pages on
+			// any origin may open it
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+	}
+}
+
+// StreamSpotPrices godoc
+// @Summary Stream spot prices
+// @Description Opens a WebSocket that receives spot prices as they are ingested or updated on this instance. Each message is a JSON array of the spot prices written together, such as those of one provider run. Clients that fall behind are disconnected with close code 1013.
+// @Tags spot-prices
+// @Param zone query string false "Zone name (e.g., 'SE1'), all zones when omitted"
+// @Param currency query string false "Currency name (e.g., 'EUR'), all currencies when omitted"
+// @Success 101 {array} models.SpotPrice "Switching to the WebSocket protocol"
+// @Failure 400 {object} apierror.Problem "Not a WebSocket handshake"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Failure 503 {object} apierror.Problem "Too many clients streaming"
+// @Router /spot-prices/stream [get]
+func (h *SpotPriceStreamHandler) StreamSpotPrices(c *gin.Context) {
+	var filter pubsub.Filter
+
+	if zoneName := c.Query("zone"); zoneName != "" {
+		zone, err := h.zoneRepo.GetByName(c.Request.Context(), zoneName)
+		if err == repository.ErrNotFound {
+			apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch zone")
+			return
+		}
+		filter.ZoneID = &zone.ID
+	}
+
+	if currencyName := c.Query("currency"); currencyName != "" {
+		currency, err := h.currencyRepo.GetByName(c.Request.Context(), currencyName)
+		if err == repository.ErrNotFound {
+			apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch currency")
+			return
+		}
+		filter.CurrencyID = &currency.ID
+	}
+
+	if !websocket.IsWebSocketUpgrade(c.Request) {
+		apierror.Write(c, apierror.InvalidRequest, "expected a WebSocket handshake")
+		return
+	}
+
+	sub, err := h.hub.Subscribe(filter)
+	if errors.Is(err, pubsub.ErrTooManySubscribers) {
+		apierror.Write(c, apierror.Unavailable, "too many clients streaming, try again later")
+		return
+	}
+	defer sub.Close()
+
+	// The upgrader responds to failed handshakes itself
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	// Clients aren't expected to send anything, reading handles pongs and notices when
+	// the client goes away
+	gone := make(chan struct{})
+	go func() {
+		defer close(gone)
+		conn.SetReadLimit(512)
+		conn.SetReadDeadline(time.Now().Add(streamPongWait))
+		conn.SetPongHandler(func(string) error {
+			return conn.SetReadDeadline(time.Now().Add(streamPongWait))
+		})
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamPingPeriod)
+	defer ping.Stop()
+	for {
+		select {
+		case spotPrices, ok := <-sub.C:
+			if !ok {
+				if sub.Dropped() {
+					closeStream(conn, websocket.CloseTryAgainLater, "client fell behind")
+				}
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(streamWriteWait))
+			if err := conn.WriteJSON(spotPrices); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteWait)); err != nil {
+				return
+			}
+		case <-gone:
+			return
+		}
+	}
+}
+
+// closeStream tells the client why the stream ends before the connection is closed
+func closeStream(conn *websocket.Conn, code int, reason string) {
+	message := websocket.FormatCloseMessage(code, reason)
+	if err := conn.WriteControl(websocket.CloseMessage, message, time.Now().Add(streamWriteWait)); err != nil {
+		log.Printf("Error closing spot price stream: %v", err)
+	}
+}
@@ -0,0 +1,82 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/models"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpotPriceStreamHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewStore()
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+	se3, err := zoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	se4, err := zoneRepo.GetByName(ctx, "SE4")
+	require.NoError(t, err)
+	eur, err := currencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	hub := pubsub.NewHub(1)
+	spotPriceRepo := hub.SpotPrices(memory.NewSpotPriceRepository(store))
+	handler := handlers.NewSpotPriceStreamHandler(hub, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.GET("/spot-prices/stream", handler.StreamSpotPrices)
+	server := httptest.NewServer(router)
+	defer server.Close()
+	url := "ws" + strings.TrimPrefix(server.URL, "http") + "/spot-prices/stream"
+
+	t.Run("Streams Matching Spot Prices", func(t *testing.T) {
+		conn, _, err := websocket.DefaultDialer.Dial(url+"?zone=SE3&currency=EUR", nil)
+		require.NoError(t, err)
+		defer conn.Close()
+
+		// The subscription is made before the handshake completes, so nothing is missed
+		timestamp := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, spotPriceRepo.CreateBatch(ctx, []models.SpotPrice{
+			{Timestamp: timestamp, ZoneID: se4.ID, CurrencyID: eur.ID, Price: 30},
+			{Timestamp: timestamp, ZoneID: se3.ID, CurrencyID: eur.ID, Price: 42.5},
+		}))
+
+		var received []models.SpotPrice
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		require.NoError(t, conn.ReadJSON(&received))
+		require.Len(t, received, 1)
+		assert.Equal(t, se3.ID, received[0].ZoneID)
+		assert.Equal(t, 42.5, received[0].Price)
+
+		// The only slot is taken until the client disconnects
+		_, resp, err := websocket.DefaultDialer.Dial(url, nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	})
+
+	t.Run("Invalid Requests", func(t *testing.T) {
+		_, resp, err := websocket.DefaultDialer.Dial(url+"?zone=XX", nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		_, resp, err = websocket.DefaultDialer.Dial(url+"?currency=XXX", nil)
+		require.Error(t, err)
+		assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/spot-prices/stream", nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
@@ -0,0 +1,165 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxSummaryWindow is the longest cheapest window searched for, in hours
+const maxSummaryWindow = 24
+
+// SummarizeSpotPrices godoc
+// @Summary Summarize spot prices
+// @Description Returns the current price, today's prices with their minimum, maximum and average, tomorrow's once published, and the cheapest window of consecutive prices within the next 24 hours. Days follow the zone's timezone.
+// @Tags spot-prices
+// @Produce json
+// @Security BearerAuth
+// @Param zone query string false "Zone name (e.g., 'SE3'), required unless the user picked a default"
+// @Param currency query string false "Currency name (e.g., 'SEK'), required unless the user picked a default"
+// @Param window query integer false "Length of the cheapest window in hours, 1 to 24 (default 3)"
+// @Success 200 {object} models.SpotPriceSummary
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /spot-prices/summary [get]
+func (h *SpotPriceHandler) SummarizeSpotPrices(c *gin.Context) {
+	defaults := h.defaults(c)
+	zoneName := c.DefaultQuery("zone", defaults.zone)
+	if zoneName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "zone is required")
+		return
+	}
+	currencyName := c.DefaultQuery("currency", defaults.currency)
+	if currencyName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "currency is required")
+		return
+	}
+	window := 3
+	if windowStr := c.Query("window"); windowStr != "" {
+		var err error
+		if window, err = strconv.Atoi(windowStr); err != nil || window < 1 || window > maxSummaryWindow {
+			apierror.Write(c, apierror.InvalidRequest, "invalid window, use 1 to 24 hours")
+			return
+		}
+	}
+
+	zone, err := h.zoneRepo.GetByName(c.Request.Context(), zoneName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch zone")
+		return
+	}
+	loc, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "invalid zone timezone")
+		return
+	}
+
+	currency, err := h.currencyRepo.GetByName(c.Request.Context(), currencyName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch currency")
+		return
+	}
+
+	// Today and tomorrow cover the next 24 hours too
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	tomorrow := today.AddDate(0, 0, 1)
+	end := today.AddDate(0, 0, 2)
+	prices, err := h.repo.List(c.Request.Context(), repository.SpotPriceFilter{
+		ZoneID:     &zone.ID,
+		CurrencyID: &currency.ID,
+		StartTime:  &today,
+		EndTime:    &end,
+		OrderBy:    "timestamp",
+	})
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch spot prices")
+		return
+	}
+
+	summary := summarizeSpotPrices(zone.Name, currency.Name, prices, now, tomorrow, end, window)
+
+	// The window may start with the current price
+	windowStart := now
+	if summary.Current != nil {
+		windowStart = summary.Current.Timestamp
+	}
+	cheapest, resolution, err := h.repo.CheapestPrices(c.Request.Context(), repository.SpotPriceWindowFilter{
+		ZoneID:     zone.ID,
+		CurrencyID: currency.ID,
+		StartTime:  windowStart,
+		EndTime:    now.Add(24 * time.Hour),
+		Duration:   time.Duration(window) * time.Hour,
+		Contiguous: true,
+	})
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch spot prices")
+		return
+	}
+	if len(cheapest) > 0 {
+		var sum float64
+		for _, sp := range cheapest {
+			sum += sp.Price
+		}
+		summary.CheapestWindow = &models.SpotPriceWindow{
+			Start: cheapest[0].Timestamp.In(loc),
+			End:   cheapest[len(cheapest)-1].Timestamp.Add(resolution).In(loc),
+			Avg:   sum / float64(len(cheapest)),
+		}
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
+
+// summarizeSpotPrices summarizes prices, ordered by timestamp, at now, leaving the cheapest
+// window out. The day after today starts at tomorrow and ends at end.
+func summarizeSpotPrices(zone, currency string, prices []models.SpotPrice, now, tomorrow, end time.Time, window int) models.SpotPriceSummary {
+	summary := models.SpotPriceSummary{Zone: zone, Currency: currency, WindowHours: window}
+
+	points := make([]models.SpotPricePoint, 0, len(prices))
+	for _, sp := range prices {
+		if sp.Timestamp.Before(end) {
+			points = append(points, models.SpotPricePoint{Timestamp: sp.Timestamp.In(now.Location()), Price: sp.Price})
+		}
+	}
+	split := len(points)
+	for i, p := range points {
+		if !p.Timestamp.Before(tomorrow) {
+			split = i
+			break
+		}
+	}
+	summary.Today = models.NewSpotPriceDay(points[:split])
+	summary.Tomorrow = models.NewSpotPriceDay(points[split:])
+
+	resolution := models.SpotPriceResolution(points)
+	first := len(points)
+	for i, p := range points {
+		if now.Before(p.Timestamp.Add(resolution)) {
+			first = i
+			break
+		}
+	}
+	if first < len(points) && !now.Before(points[first].Timestamp) {
+		current := points[first]
+		summary.Current = &current
+	}
+
+	return summary
+}
@@ -2,15 +2,19 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 	"wattwatch/internal/api/handlers"
 	"wattwatch/internal/api/middleware"
 	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
 	"wattwatch/internal/repository/postgres"
 	"wattwatch/internal/testutil"
 
@@ -142,12 +146,12 @@ func TestSpotPriceHandler_ListSpotPrices(t *testing.T) {
 			wantErr:    true,
 		},
 		{
-			name: "Invalid Date Range (> 7 days)",
+			name: "Invalid Date Range (> 366 days)",
 			query: fmt.Sprintf("zone=%s&currency=%s&start_time=%s&end_time=%s",
 				zoneName,
 				currencyName,
 				now.Format(time.RFC3339),
-				now.Add(8*24*time.Hour).Format(time.RFC3339)),
+				now.Add(367*24*time.Hour).Format(time.RFC3339)),
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
 		},
@@ -183,10 +187,11 @@ func TestSpotPriceHandler_ListSpotPrices(t *testing.T) {
 			assert.Equal(t, tt.wantStatus, w.Code)
 
 			if !tt.wantErr {
-				var spotPrices []models.SpotPrice
-				err = json.Unmarshal(w.Body.Bytes(), &spotPrices)
+				var page models.Page[models.SpotPrice]
+				err = json.Unmarshal(w.Body.Bytes(), &page)
 				require.NoError(t, err)
-				assert.Equal(t, tt.wantCount, len(spotPrices))
+				assert.Equal(t, tt.wantCount, len(page.Items))
+				spotPrices := page.Items
 
 				if tt.name == "Descending Order" {
 					// Verify descending order
@@ -520,3 +525,834 @@ func TestSpotPriceHandler_CreateSpotPrices(t *testing.T) {
 		})
 	}
 }
+
+func TestSpotPriceHandler_ListSpotPricesInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+
+	zone, err := zoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+
+	// More prices than are written between flushes
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	var prices []models.SpotPrice
+	for i := 0; i < 4*24*2; i++ {
+		prices = append(prices, models.SpotPrice{
+			Timestamp:  start.Add(time.Duration(i) * 15 * time.Minute),
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      float64(i),
+		})
+	}
+	require.NoError(t, spotPriceRepo.CreateBatch(context.Background(), prices))
+
+	handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.Use(middleware.Compression(middleware.DefaultCompressionConfig()))
+	router.GET("/spot-prices", handler.ListSpotPrices)
+
+	get := func(startTime time.Time, params ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/spot-prices?zone=SE3&currency=SEK&start_time=%s&end_time=%s%s",
+			startTime.Format(time.RFC3339), startTime.Add(72*time.Hour).Format(time.RFC3339), strings.Join(params, "")), nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Prices", func(t *testing.T) {
+		w := get(start)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, w.Flushed)
+
+		var page models.Page[models.SpotPrice]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Items, len(prices))
+		assert.Equal(t, len(prices), page.Total)
+		assert.Nil(t, page.NextOffset)
+		for i := 1; i < len(page.Items); i++ {
+			assert.True(t, page.Items[i-1].Timestamp.Before(page.Items[i].Timestamp))
+		}
+	})
+
+	t.Run("Page", func(t *testing.T) {
+		w := get(start, "&limit=50", "&offset=50")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var page models.Page[models.SpotPrice]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Items, 50)
+		assert.Equal(t, prices[50].Price, page.Items[0].Price)
+		assert.Equal(t, len(prices), page.Total)
+		assert.Equal(t, 50, page.Limit)
+		assert.Equal(t, 50, page.Offset)
+		require.NotNil(t, page.NextOffset)
+		assert.Equal(t, 100, *page.NextOffset)
+	})
+
+	t.Run("Without Envelope", func(t *testing.T) {
+		w := get(start, "&envelope=false")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var spotPrices []models.SpotPrice
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spotPrices))
+		require.Len(t, spotPrices, len(prices))
+	})
+
+	t.Run("No Prices", func(t *testing.T) {
+		w := get(start.AddDate(1, 0, 0))
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"items":[],"total":0,"limit":0,"offset":0,"next_offset":null}`, w.Body.String())
+
+		w = get(start.AddDate(1, 0, 0), "&envelope=false")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, "[]", w.Body.String())
+	})
+
+	t.Run("Range Too Long", func(t *testing.T) {
+		handler.SetMaxRange(48 * time.Hour)
+		defer handler.SetMaxRange(handlers.DefaultSpotPriceMaxRange)
+
+		w := get(start)
+		require.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "date range cannot exceed 2 days")
+
+		// Pages are bounded by their limit, so longer ranges can be walked
+		w = get(start, "&limit=10")
+		require.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Cursor", func(t *testing.T) {
+		for _, desc := range []bool{false, true} {
+			var walked []models.SpotPrice
+			cursor := ""
+			for pages := 1; ; pages++ {
+				params := []string{"&limit=50", fmt.Sprintf("&order_desc=%t", desc)}
+				if cursor != "" {
+					params = append(params, "&cursor="+cursor)
+				}
+				w := get(start, params...)
+				require.Equal(t, http.StatusOK, w.Code)
+
+				var page models.Page[models.SpotPrice]
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+				assert.Equal(t, len(prices), page.Total)
+				assert.Equal(t, 50, page.Limit)
+				if cursor != "" {
+					assert.Nil(t, page.NextOffset)
+				}
+				walked = append(walked, page.Items...)
+				if page.NextCursor == nil {
+					assert.Equal(t, 4, pages)
+					break
+				}
+				require.Len(t, page.Items, 50)
+				cursor = *page.NextCursor
+			}
+
+			require.Len(t, walked, len(prices))
+			for i := 1; i < len(walked); i++ {
+				assert.Equal(t, desc, walked[i].Timestamp.Before(walked[i-1].Timestamp))
+			}
+		}
+	})
+
+	t.Run("Cursor Columnar", func(t *testing.T) {
+		w := get(start, "&format=columnar", "&limit=150")
+		require.Equal(t, http.StatusOK, w.Code)
+		var series models.SpotPriceSeries
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+		require.Len(t, series.Prices, 150)
+		require.NotEmpty(t, series.NextCursor)
+
+		w = get(start, "&format=columnar", "&limit=150", "&cursor="+series.NextCursor)
+		require.Equal(t, http.StatusOK, w.Code)
+		series = models.SpotPriceSeries{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+		require.Len(t, series.Prices, len(prices)-150)
+		assert.Equal(t, prices[150].Price, series.Prices[0])
+		assert.Empty(t, series.NextCursor)
+	})
+
+	t.Run("Invalid Cursor", func(t *testing.T) {
+		w := get(start, "&cursor=not-a-cursor")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		cursor := repository.SpotPriceCursor{Timestamp: start, ZoneID: zone.ID}.Encode()
+		w = get(start, "&cursor="+cursor, "&offset=10")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Invalid Offset", func(t *testing.T) {
+		w := get(start, "&offset=-1")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Columnar", func(t *testing.T) {
+		w := get(start, "&format=columnar", "&order_desc=true")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var series models.SpotPriceSeries
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+		assert.Equal(t, "SE3", series.Zone)
+		assert.Equal(t, "SEK", series.Currency)
+		require.Len(t, series.Timestamps, len(prices))
+		require.Len(t, series.Prices, len(prices))
+		last := prices[len(prices)-1]
+		assert.True(t, last.Timestamp.Equal(series.Timestamps[0]))
+		assert.Equal(t, last.Price, series.Prices[0])
+	})
+
+	t.Run("Columnar No Prices", func(t *testing.T) {
+		w := get(start.AddDate(1, 0, 0), "&format=columnar")
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.JSONEq(t, `{"zone":"SE3","currency":"SEK","timestamps":[],"prices":[]}`, w.Body.String())
+	})
+
+	t.Run("Invalid Format", func(t *testing.T) {
+		w := get(start, "&format=csv")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestSpotPriceHandler_CreateSpotPricesInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+
+	zone, err := zoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+
+	handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.POST("/spot-prices", handler.CreateSpotPrices)
+
+	post := func(prices []models.CreateSpotPriceRequest) *httptest.ResponseRecorder {
+		body, err := json.Marshal(models.CreateSpotPricesRequest{SpotPrices: prices})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("POST", "/spot-prices", bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	year := make([]models.CreateSpotPriceRequest, 366*24)
+	for i := range year {
+		year[i] = models.CreateSpotPriceRequest{
+			Timestamp:  start.Add(time.Duration(i) * time.Hour),
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      float64(i % 100),
+		}
+	}
+
+	t.Run("Year Of Hourly Prices", func(t *testing.T) {
+		w := post(year)
+		require.Equal(t, http.StatusCreated, w.Code)
+
+		var created []models.SpotPrice
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		require.Len(t, created, len(year))
+
+		end := start.AddDate(1, 0, 0)
+		total, err := spotPriceRepo.Total(context.Background(), repository.SpotPriceFilter{ZoneID: &zone.ID, StartTime: &start, EndTime: &end})
+		require.NoError(t, err)
+		assert.Equal(t, len(year), total)
+	})
+
+	t.Run("Duplicate Spot Prices", func(t *testing.T) {
+		w := post([]models.CreateSpotPriceRequest{year[0], year[1], year[0]})
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "index 2")
+	})
+}
+
+func TestSpotPriceHandler_AggregateSpotPrices(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+
+	zone, err := zoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+
+	// Midnight in Stockholm is 23:00 UTC in winter, two days of hourly prices 0..47
+	start := time.Date(2024, 12, 31, 23, 0, 0, 0, time.UTC)
+	var prices []models.SpotPrice
+	for i := 0; i < 48; i++ {
+		prices = append(prices, models.SpotPrice{
+			Timestamp:  start.Add(time.Duration(i) * time.Hour),
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      float64(i),
+		})
+	}
+	require.NoError(t, spotPriceRepo.CreateBatch(context.Background(), prices))
+
+	handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.GET("/spot-prices/aggregate", handler.AggregateSpotPrices)
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/spot-prices/aggregate?"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	between := func(from, to time.Time) string {
+		return "&start_time=" + from.Format(time.RFC3339) + "&end_time=" + to.Format(time.RFC3339)
+	}
+
+	t.Run("By Day", func(t *testing.T) {
+		w := get("group_by=day&zone=SE3" + between(start, start.Add(48*time.Hour)))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var aggregates []models.SpotPriceAggregate
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &aggregates))
+		require.Len(t, aggregates, 2)
+		assert.True(t, start.Equal(aggregates[0].Start))
+		assert.Equal(t, "SE3", aggregates[0].Zone)
+		assert.Equal(t, "SEK", aggregates[0].Currency)
+		assert.Equal(t, 11.5, aggregates[0].Avg)
+		assert.Equal(t, 0.0, aggregates[0].Min)
+		assert.Equal(t, 23.0, aggregates[0].Max)
+		assert.InDelta(t, 6.922, aggregates[0].StdDev, 0.001)
+		assert.Equal(t, 24, aggregates[0].Count)
+		assert.Equal(t, 35.5, aggregates[1].Avg)
+	})
+
+	t.Run("By Month", func(t *testing.T) {
+		w := get("group_by=month&currency=SEK" + between(start, start.Add(48*time.Hour)))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var aggregates []models.SpotPriceAggregate
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &aggregates))
+		require.Len(t, aggregates, 1)
+		assert.Equal(t, 48, aggregates[0].Count)
+		assert.Equal(t, 23.5, aggregates[0].Avg)
+	})
+
+	t.Run("End Time Is Exclusive", func(t *testing.T) {
+		w := get("group_by=hour&zone=SE3" + between(start, start.Add(2*time.Hour)))
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var aggregates []models.SpotPriceAggregate
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &aggregates))
+		assert.Len(t, aggregates, 2)
+	})
+
+	t.Run("Invalid Requests", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("group_by=year"+between(start, start.Add(time.Hour))).Code)
+		assert.Equal(t, http.StatusBadRequest, get("group_by=day").Code)
+		assert.Equal(t, http.StatusBadRequest, get("group_by=day"+between(start, start)).Code)
+		assert.Equal(t, http.StatusBadRequest, get("group_by=hour"+between(start, start.AddDate(0, 2, 0))).Code)
+		assert.Equal(t, http.StatusNotFound, get("group_by=day&zone=XX"+between(start, start.Add(time.Hour))).Code)
+		assert.Equal(t, http.StatusNotFound, get("group_by=day&currency=XXX"+between(start, start.Add(time.Hour))).Code)
+	})
+}
+
+func TestSpotPriceHandler_ConvertSpotPrices(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+	exchangeRateRepo := memory.NewExchangeRateRepository(store)
+
+	zone, err := zoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	sek, err := currencyRepo.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+	eur, err := currencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, spotPriceRepo.CreateBatch(ctx, []models.SpotPrice{
+		{Timestamp: start, ZoneID: zone.ID, CurrencyID: sek.ID, Price: 100},
+		{Timestamp: start.Add(36 * time.Hour), ZoneID: zone.ID, CurrencyID: sek.ID, Price: 100},
+	}))
+	// Rates are stored from EUR, so converting SEK prices inverts them
+	require.NoError(t, exchangeRateRepo.CreateBatch(ctx, []models.ExchangeRate{
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: start, Rate: 10, Source: models.ExchangeRateSourceManual},
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: start.Add(24 * time.Hour), Rate: 20, Source: models.ExchangeRateSourceManual},
+	}))
+
+	handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+	handler.SetExchangeRates(exchangeRateRepo)
+	router := gin.New()
+	router.GET("/spot-prices", handler.ListSpotPrices)
+
+	get := func(startTime time.Time, params ...string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/spot-prices?zone=SE3&currency=SEK&start_time=%s&end_time=%s%s",
+			startTime.Format(time.RFC3339), startTime.Add(72*time.Hour).Format(time.RFC3339), strings.Join(params, "")), nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Objects", func(t *testing.T) {
+		w := get(start, "&convert_to=EUR")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var page models.Page[models.SpotPrice]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		require.Len(t, page.Items, 2)
+		assert.Equal(t, eur.ID, page.Items[0].CurrencyID)
+		assert.InDelta(t, 10.0, page.Items[0].Price, 1e-9)
+		assert.InDelta(t, 5.0, page.Items[1].Price, 1e-9)
+	})
+
+	t.Run("Columnar", func(t *testing.T) {
+		w := get(start, "&convert_to=EUR", "&format=columnar")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var series models.SpotPriceSeries
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &series))
+		assert.Equal(t, "EUR", series.Currency)
+		require.Len(t, series.Prices, 2)
+		assert.InDelta(t, 5.0, series.Prices[1], 1e-9)
+	})
+
+	t.Run("Same Currency", func(t *testing.T) {
+		w := get(start, "&convert_to=SEK", "&envelope=false")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var spotPrices []models.SpotPrice
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spotPrices))
+		require.Len(t, spotPrices, 2)
+		assert.Equal(t, 100.0, spotPrices[0].Price)
+	})
+
+	t.Run("No Rate At Start", func(t *testing.T) {
+		w := get(start.Add(-time.Hour), "&convert_to=EUR")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unknown Currency", func(t *testing.T) {
+		w := get(start, "&convert_to=USD")
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	t.Run("Conversion Unavailable", func(t *testing.T) {
+		handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+		router := gin.New()
+		router.GET("/spot-prices", handler.ListSpotPrices)
+
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", fmt.Sprintf("/spot-prices?zone=SE3&currency=SEK&start_time=%s&end_time=%s&convert_to=EUR",
+			start.Format(time.RFC3339), start.Add(time.Hour).Format(time.RFC3339)), nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+}
+
+func TestSpotPriceHandler_SummarizeSpotPricesInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+
+	zone, err := zoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+	loc, err := time.LoadLocation(zone.Timezone)
+	require.NoError(t, err)
+
+	// Hourly prices for today and tomorrow, cheap for three hours starting in five hours
+	now := time.Now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	tomorrow := today.AddDate(0, 0, 1)
+	cheapStart := now.Truncate(time.Hour).Add(5 * time.Hour)
+	var prices []models.SpotPrice
+	for ts := today; ts.Before(today.AddDate(0, 0, 2)); ts = ts.Add(time.Hour) {
+		price := 100.0
+		if !ts.Before(cheapStart) && ts.Before(cheapStart.Add(3*time.Hour)) {
+			price = 1
+		}
+		prices = append(prices, models.SpotPrice{Timestamp: ts, ZoneID: zone.ID, CurrencyID: currency.ID, Price: price})
+	}
+
+	handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.GET("/spot-prices/summary", handler.SummarizeSpotPrices)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/spot-prices/summary?"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	summarize := func(t *testing.T, query string) models.SpotPriceSummary {
+		t.Helper()
+		w := get(query)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var summary models.SpotPriceSummary
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+		return summary
+	}
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("currency=SEK").Code)
+		assert.Equal(t, http.StatusBadRequest, get("zone=SE3").Code)
+		assert.Equal(t, http.StatusBadRequest, get("zone=SE3&currency=SEK&window=25").Code)
+		assert.Equal(t, http.StatusNotFound, get("zone=XX&currency=SEK").Code)
+		assert.Equal(t, http.StatusNotFound, get("zone=SE3&currency=XXX").Code)
+	})
+
+	t.Run("No Prices", func(t *testing.T) {
+		summary := summarize(t, "zone=SE3&currency=SEK")
+		assert.Nil(t, summary.Current)
+		assert.Nil(t, summary.Today)
+		assert.Nil(t, summary.Tomorrow)
+		assert.Nil(t, summary.CheapestWindow)
+		assert.Equal(t, 3, summary.WindowHours)
+	})
+
+	t.Run("Today Only", func(t *testing.T) {
+		var todays []models.SpotPrice
+		for _, sp := range prices {
+			if sp.Timestamp.Before(tomorrow) {
+				todays = append(todays, sp)
+			}
+		}
+		require.NoError(t, spotPriceRepo.CreateBatch(context.Background(), todays))
+
+		summary := summarize(t, "zone=SE3&currency=SEK")
+		require.NotNil(t, summary.Today)
+		assert.Equal(t, today.Format(time.DateOnly), summary.Today.Date)
+		assert.Len(t, summary.Today.Prices, len(todays))
+		assert.Equal(t, 100.0, summary.Today.Max)
+		assert.Nil(t, summary.Tomorrow)
+	})
+
+	t.Run("Today And Tomorrow", func(t *testing.T) {
+		require.NoError(t, spotPriceRepo.CreateBatch(context.Background(), prices))
+
+		summary := summarize(t, "zone=SE3&currency=SEK&window=3")
+		require.NotNil(t, summary.Current)
+		assert.Equal(t, 100.0, summary.Current.Price)
+		assert.WithinDuration(t, time.Now().Truncate(time.Hour), summary.Current.Timestamp, time.Hour)
+
+		require.NotNil(t, summary.Tomorrow)
+		assert.Equal(t, tomorrow.Format(time.DateOnly), summary.Tomorrow.Date)
+		assert.Equal(t, 100.0, summary.Tomorrow.Max)
+
+		require.NotNil(t, summary.CheapestWindow)
+		assert.True(t, cheapStart.Equal(summary.CheapestWindow.Start), summary.CheapestWindow.Start)
+		assert.True(t, cheapStart.Add(3*time.Hour).Equal(summary.CheapestWindow.End))
+		assert.Equal(t, 1.0, summary.CheapestWindow.Avg)
+
+		// A longer window takes in the dearer hours around the cheap ones
+		summary = summarize(t, "zone=SE3&currency=SEK&window=4")
+		require.NotNil(t, summary.CheapestWindow)
+		assert.Equal(t, 4, summary.WindowHours)
+		assert.InDelta(t, 25.75, summary.CheapestWindow.Avg, 1e-9)
+	})
+}
+
+func TestSpotPriceHandler_CheapestSpotPricesInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+
+	zone, err := zoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+
+	// Hourly prices on the day daylight saving time starts, cheapest across the change
+	start := time.Date(2025, 3, 29, 23, 0, 0, 0, time.UTC)
+	values := []float64{90, 20, 10, 30, 80, 5, 95, 70}
+	var prices []models.SpotPrice
+	for i, v := range values {
+		prices = append(prices, models.SpotPrice{Timestamp: start.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: v})
+	}
+	require.NoError(t, spotPriceRepo.CreateBatch(context.Background(), prices))
+
+	handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.GET("/spot-prices/cheapest-window", handler.CheapestSpotPrices)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/spot-prices/cheapest-window?zone=SE3&currency=SEK&start=2025-03-30T00:00:00%2B01:00&"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	find := func(t *testing.T, query string) models.CheapestSpotPrices {
+		t.Helper()
+		w := get(query)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result models.CheapestSpotPrices
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		return result
+	}
+
+	t.Run("Contiguous", func(t *testing.T) {
+		result := find(t, "hours=3&within=8h")
+		assert.True(t, result.Contiguous)
+		assert.Equal(t, 3, result.Hours)
+		// 01:00 CET to 05:00 CEST is three hours
+		assert.Equal(t, "2025-03-30T01:00:00+01:00", result.Start.Format(time.RFC3339))
+		assert.Equal(t, "2025-03-30T05:00:00+02:00", result.End.Format(time.RFC3339))
+		assert.Equal(t, 3*time.Hour, result.End.Sub(result.Start))
+		require.Len(t, result.Prices, 3)
+		assert.InDelta(t, 20.0, result.AvgPrice, 1e-9)
+		assert.InDelta(t, 0.2, result.CostPerKWh, 1e-9)
+	})
+
+	t.Run("Not Contiguous", func(t *testing.T) {
+		result := find(t, "hours=3&within=8h&contiguous=false")
+		require.Len(t, result.Prices, 3)
+		assert.Equal(t, []float64{20, 10, 5}, []float64{result.Prices[0].Price, result.Prices[1].Price, result.Prices[2].Price})
+		assert.Equal(t, "2025-03-30T06:00:00+02:00", result.Prices[2].Start.Format(time.RFC3339))
+		assert.Equal(t, "2025-03-30T07:00:00+02:00", result.End.Format(time.RFC3339))
+	})
+
+	t.Run("Within", func(t *testing.T) {
+		result := find(t, "hours=1&within=3h")
+		assert.Equal(t, 10.0, result.Prices[0].Price)
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("hours=0").Code)
+		assert.Equal(t, http.StatusBadRequest, get("hours=5&within=4h").Code)
+		assert.Equal(t, http.StatusBadRequest, get("within=200h").Code)
+		assert.Equal(t, http.StatusBadRequest, get("within=tomorrow").Code)
+		assert.Equal(t, http.StatusBadRequest, get("contiguous=maybe").Code)
+		assert.Equal(t, http.StatusNotFound, get("hours=9&within=9h").Code)
+	})
+}
+
+// TestSpotPriceHandler_CompareSpotPrices compares against the postgres repository, which
+// passes the periods to the database as arrays
+func TestSpotPriceHandler_CompareSpotPrices(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	spotPriceRepo := postgres.NewSpotPriceRepository(tc.DB)
+	zoneRepo := postgres.NewZoneRepository(tc.DB)
+	currencyRepo := postgres.NewCurrencyRepository(tc.DB)
+
+	zone, err := zoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+	loc, err := time.LoadLocation(zone.Timezone)
+	require.NoError(t, err)
+
+	// Two prices on the day compared and the same day a week earlier
+	var prices []models.SpotPrice
+	for day, values := range map[time.Time][]float64{
+		time.Date(2025, 3, 10, 0, 0, 0, 0, loc): {10, 30},
+		time.Date(2025, 3, 3, 0, 0, 0, 0, loc):  {20, 40},
+	} {
+		for i, v := range values {
+			prices = append(prices, models.SpotPrice{Timestamp: day.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: v})
+		}
+	}
+	require.NoError(t, spotPriceRepo.CreateBatch(context.Background(), prices))
+
+	handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.GET("/spot-prices/compare", handler.CompareSpotPrices)
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/spot-prices/compare?zone=SE3&currency=SEK&start_time=2025-03-10T00:00:00%2B01:00&end_time=2025-03-11T00:00:00%2B01:00", nil)
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	var result models.SpotPriceComparison
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Equal(t, 2, result.Period.Count)
+	assert.Equal(t, 20.0, *result.Period.Avg)
+	require.Len(t, result.Previous, 3)
+	assert.Equal(t, "week", result.Previous[0].Against)
+	assert.Equal(t, -10.0, *result.Previous[0].AvgChange)
+	assert.Equal(t, -33.33, *result.Previous[0].AvgChangePercent)
+	assert.Zero(t, result.Previous[2].Count)
+}
+
+func TestSpotPriceHandler_CompareSpotPricesInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := memory.NewStore()
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+
+	zone, err := zoneRepo.GetByName(context.Background(), "SE3")
+	require.NoError(t, err)
+	currency, err := currencyRepo.GetByName(context.Background(), "SEK")
+	require.NoError(t, err)
+	loc, err := time.LoadLocation(zone.Timezone)
+	require.NoError(t, err)
+
+	// Two prices on the day compared, the same day a week and a month earlier, none a year earlier
+	var prices []models.SpotPrice
+	for day, values := range map[time.Time][]float64{
+		time.Date(2025, 3, 10, 0, 0, 0, 0, loc): {10, 30},
+		time.Date(2025, 3, 3, 0, 0, 0, 0, loc):  {20, 20},
+		time.Date(2025, 2, 10, 0, 0, 0, 0, loc): {0, 50},
+		time.Date(2025, 2, 11, 0, 0, 0, 0, loc): {99},
+	} {
+		for i, v := range values {
+			prices = append(prices, models.SpotPrice{Timestamp: day.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: v})
+		}
+	}
+	require.NoError(t, spotPriceRepo.CreateBatch(context.Background(), prices))
+
+	handler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
+	router := gin.New()
+	router.GET("/spot-prices/compare", handler.CompareSpotPrices)
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/spot-prices/compare?zone=SE3&currency=SEK&"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	period := "start_time=2025-03-10T00:00:00%2B01:00&end_time=2025-03-11T00:00:00%2B01:00"
+
+	t.Run("All Periods", func(t *testing.T) {
+		w := get(period)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result models.SpotPriceComparison
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		assert.Equal(t, "SE3", result.Zone)
+		assert.Equal(t, 2, result.Period.Count)
+		assert.Equal(t, 20.0, *result.Period.Avg)
+		require.Len(t, result.Previous, 3)
+
+		week := result.Previous[0]
+		assert.Equal(t, "week", week.Against)
+		assert.Equal(t, "2025-03-03T00:00:00+01:00", week.Start.Format(time.RFC3339))
+		assert.Equal(t, 0.0, *week.AvgChange)
+		assert.Equal(t, -10.0, *week.MinChange)
+		assert.Equal(t, -50.0, *week.MinChangePercent)
+		assert.Equal(t, 50.0, *week.MaxChangePercent)
+
+		month := result.Previous[1]
+		assert.Equal(t, "month", month.Against)
+		assert.Equal(t, "2025-02-11T00:00:00+01:00", month.End.Format(time.RFC3339))
+		assert.Equal(t, 25.0, *month.Avg)
+		assert.Equal(t, -20.0, *month.AvgChangePercent)
+		assert.Equal(t, 10.0, *month.MinChange)
+		assert.Nil(t, month.MinChangePercent, "no percentage of a zero price")
+
+		year := result.Previous[2]
+		assert.Equal(t, "year", year.Against)
+		assert.Zero(t, year.Count)
+		assert.Nil(t, year.Avg)
+		assert.Nil(t, year.AvgChange)
+		assert.Nil(t, year.AvgChangePercent)
+	})
+
+	t.Run("Against", func(t *testing.T) {
+		w := get(period + "&against=year,week")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var result models.SpotPriceComparison
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+		require.Len(t, result.Previous, 2)
+		assert.Equal(t, "year", result.Previous[0].Against)
+		assert.Equal(t, "week", result.Previous[1].Against)
+	})
+
+	t.Run("Invalid Parameters", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("start_time=2025-03-10T00:00:00Z").Code)
+		assert.Equal(t, http.StatusBadRequest, get("start_time=2025-03-10T00:00:00Z&end_time=2025-03-09T00:00:00Z").Code)
+		assert.Equal(t, http.StatusBadRequest, get("start_time=2023-03-10T00:00:00Z&end_time=2025-03-10T00:00:00Z").Code)
+		assert.Equal(t, http.StatusBadRequest, get(period+"&against=decade").Code)
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/spot-prices/compare?zone=XX&currency=SEK&"+period, nil)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
+
+func TestSpotPriceHandler_GetSpotPriceHistory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	zone, err := tc.ZoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := tc.CurrencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	handler := handlers.NewSpotPriceHandler(tc.SpotPriceRepo, tc.ZoneRepo, tc.CurrencyRepo)
+	handler.SetRevisions(tc.SpotPriceRevisions)
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router := gin.New()
+	router.GET("/spot-prices", handler.ListSpotPrices)
+	router.GET("/spot-prices/:id/history", handler.GetSpotPriceHistory)
+	router.POST("/spot-prices", authMiddleware.AuthRequired(), handler.CreateSpotPrices)
+
+	hour := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	post := func(price float64) models.SpotPrice {
+		t.Helper()
+		body, err := json.Marshal(models.CreateSpotPricesRequest{SpotPrices: []models.CreateSpotPriceRequest{
+			{Timestamp: hour, ZoneID: zone.ID, CurrencyID: currency.ID, Price: price},
+		}})
+		require.NoError(t, err)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/spot-prices", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(admin.ID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created []models.SpotPrice
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		return created[0]
+	}
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	spotPrice := post(10)
+	before := time.Now()
+	post(12)
+
+	t.Run("History", func(t *testing.T) {
+		w := get("/spot-prices/" + spotPrice.ID.String() + "/history")
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page models.Page[models.SpotPriceRevision]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, 1, page.Total)
+		require.Len(t, page.Items, 1)
+		assert.Equal(t, 10.0, page.Items[0].Price)
+		assert.Equal(t, 12.0, page.Items[0].NewPrice)
+		require.NotNil(t, page.Items[0].ChangedBy)
+		assert.Equal(t, admin.ID, *page.Items[0].ChangedBy)
+	})
+
+	t.Run("As Of", func(t *testing.T) {
+		w := get(fmt.Sprintf("/spot-prices?zone=SE3&currency=EUR&start_time=2024-03-20T00:00:00Z&end_time=2024-03-21T00:00:00Z&envelope=false&as_of=%s",
+			before.UTC().Format(time.RFC3339Nano)))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var prices []models.SpotPrice
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prices))
+		require.Len(t, prices, 1)
+		assert.Equal(t, 10.0, prices[0].Price)
+
+		assert.Equal(t, http.StatusBadRequest, get("/spot-prices?zone=SE3&currency=EUR&start_time=2024-03-20T00:00:00Z&end_time=2024-03-21T00:00:00Z&as_of=yesterday").Code)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		assert.Equal(t, http.StatusBadRequest, get("/spot-prices/invalid/history").Code)
+		assert.Equal(t, http.StatusNotFound, get("/spot-prices/"+uuid.New().String()+"/history").Code)
+	})
+}
@@ -0,0 +1,149 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxCheapestWithin is the longest range searched for the cheapest prices
+const maxCheapestWithin = 7 * 24 * time.Hour
+
+// CheapestSpotPrices godoc
+// @Summary Find the cheapest hours
+// @Description Returns the spot prices with the lowest average that together last the given hours, starting at or after start and ending within the given duration, for scheduling appliances or charging a car. Contiguous prices follow each other without gaps, otherwise the cheapest prices are chosen wherever they are. Hours are counted in real time, so a window across a daylight saving change lasts as long as any other.
+// @Tags spot-prices
+// @Produce json
+// @Security BearerAuth
+// @Param zone query string false "Zone name (e.g., 'SE3'), required unless the user picked a default"
+// @Param currency query string false "Currency name (e.g., 'SEK'), required unless the user picked a default"
+// @Param hours query integer false "Hours the prices last (default 3)"
+// @Param within query string false "Duration after start the prices must end in, at most 168h (default 24h)"
+// @Param start query string false "Start of the search (RFC3339, default now)"
+// @Param contiguous query boolean false "Require the prices to follow each other (default true)"
+// @Success 200 {object} models.CheapestSpotPrices
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone or currency not found, or not enough prices are stored"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
+// @Router /spot-prices/cheapest-window [get]
+func (h *SpotPriceHandler) CheapestSpotPrices(c *gin.Context) {
+	defaults := h.defaults(c)
+	zoneName := c.DefaultQuery("zone", defaults.zone)
+	if zoneName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "zone is required")
+		return
+	}
+	currencyName := c.DefaultQuery("currency", defaults.currency)
+	if currencyName == "" {
+		apierror.Write(c, apierror.InvalidRequest, "currency is required")
+		return
+	}
+
+	within := 24 * time.Hour
+	if withinStr := c.Query("within"); withinStr != "" {
+		var err error
+		if within, err = time.ParseDuration(withinStr); err != nil || within <= 0 || within > maxCheapestWithin {
+			apierror.Write(c, apierror.InvalidRequest, "invalid within, use a duration up to 168h")
+			return
+		}
+	}
+	hours := 3
+	if hoursStr := c.Query("hours"); hoursStr != "" {
+		var err error
+		if hours, err = strconv.Atoi(hoursStr); err != nil || hours < 1 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid hours")
+			return
+		}
+	}
+	if time.Duration(hours)*time.Hour > within {
+		apierror.Write(c, apierror.InvalidRequest, "hours must fit within")
+		return
+	}
+	start := time.Now()
+	if startStr := c.Query("start"); startStr != "" {
+		var err error
+		if start, err = time.Parse(time.RFC3339, startStr); err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid start format, use RFC3339")
+			return
+		}
+	}
+	contiguous := true
+	if contiguousStr := c.Query("contiguous"); contiguousStr != "" {
+		var err error
+		if contiguous, err = strconv.ParseBool(contiguousStr); err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid contiguous parameter")
+			return
+		}
+	}
+
+	zone, err := h.zoneRepo.GetByName(c.Request.Context(), zoneName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch zone")
+		return
+	}
+	loc, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "invalid zone timezone")
+		return
+	}
+	currency, err := h.currencyRepo.GetByName(c.Request.Context(), currencyName)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch currency")
+		return
+	}
+
+	prices, resolution, err := h.repo.CheapestPrices(c.Request.Context(), repository.SpotPriceWindowFilter{
+		ZoneID:     zone.ID,
+		CurrencyID: currency.ID,
+		StartTime:  start,
+		EndTime:    start.Add(within),
+		Duration:   time.Duration(hours) * time.Hour,
+		Contiguous: contiguous,
+	})
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to fetch spot prices")
+		return
+	}
+	if len(prices) == 0 {
+		apierror.Write(c, apierror.SpotPriceNotFound, "not enough spot prices are stored for the range")
+		return
+	}
+
+	result := models.CheapestSpotPrices{
+		Zone:       zone.Name,
+		Currency:   currency.Name,
+		Hours:      hours,
+		Contiguous: contiguous,
+		Start:      prices[0].Timestamp.In(loc),
+		End:        prices[len(prices)-1].Timestamp.Add(resolution).In(loc),
+		Prices:     make([]models.SpotPricePeriod, 0, len(prices)),
+	}
+	for _, sp := range prices {
+		result.Prices = append(result.Prices, models.SpotPricePeriod{
+			Start: sp.Timestamp.In(loc),
+			End:   sp.Timestamp.Add(resolution).In(loc),
+			Price: sp.Price,
+		})
+		result.AvgPrice += sp.Price
+	}
+	result.AvgPrice /= float64(len(prices))
+	// Prices are stored in hundredths of the currency
+	result.CostPerKWh = result.AvgPrice / 100
+
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamFlushInterval is the number of items written between flushes of a streamed array
+const streamFlushInterval = 100
+
+// streamJSONArray responds with a JSON array of the items each passes to yield, writing
+// them to the client as they come instead of encoding the whole array in memory first.
+// An error before the first item is returned, so the handler can respond with it. Once
+// items were written the status has been sent, so the array is left unterminated.
+func streamJSONArray[T any](c *gin.Context, each func(yield func(T) error) error) error {
+	return streamJSON(c, "", func() (string, error) { return "", nil }, each)
+}
+
+// streamJSONPage responds with a models.Page of the items each passes to yield, streamed
+// like streamJSONArray. The page is full unless it's the last one, so the offset of the
+// next page is known before the items are read.
+func streamJSONPage[T any](c *gin.Context, total, limit, offset int, each func(yield func(T) error) error) error {
+	var next *int
+	if offset+limit < total {
+		n := offset + limit
+		next = &n
+	}
+	return streamJSON(c, `{"items":`, func() (string, error) {
+		return pageSuffix(total, limit, offset, next, nil)
+	}, each)
+}
+
+// streamJSONCursorPage responds with a models.Page of the items each passes to yield,
+// streamed like streamJSONArray. Its next_cursor is the one next returns once the items
+// are written, none when it returns an empty string. The first page of a listing can be
+// followed by offset too, so it also has a next_offset.
+func streamJSONCursorPage[T any](c *gin.Context, total, limit int, first bool, each func(yield func(T) error) error, next func() string) error {
+	var nextOffset *int
+	if first && limit < total {
+		nextOffset = &limit
+	}
+	return streamJSON(c, `{"items":`, func() (string, error) {
+		var cursor *string
+		if s := next(); s != "" {
+			cursor = &s
+		}
+		return pageSuffix(total, limit, 0, nextOffset, cursor)
+	}, each)
+}
+
+// pageSuffix returns the metadata of a page written after its items, which makes
+// {"items":[...],"total":...}
+func pageSuffix(total, limit, offset int, nextOffset *int, nextCursor *string) (string, error) {
+	meta, err := json.Marshal(struct {
+		Total      int     `json:"total"`
+		Limit      int     `json:"limit"`
+		Offset     int     `json:"offset"`
+		NextOffset *int    `json:"next_offset"`
+		NextCursor *string `json:"next_cursor,omitempty"`
+	}{total, limit, offset, nextOffset, nextCursor})
+	if err != nil {
+		return "", err
+	}
+	return "," + string(meta[1:]), nil
+}
+
+// streamJSON streams the array of items each passes to yield, written between prefix and
+// what suffix returns after the items
+func streamJSON[T any](c *gin.Context, prefix string, suffix func() (string, error), each func(yield func(T) error) error) error {
+	w := c.Writer
+	// Each item is encoded straight to the response, which is compressed as it is written
+	enc := json.NewEncoder(w)
+	started, written := false, 0
+	start := func() error {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		started = true
+		_, err := w.WriteString(prefix + "[")
+		return err
+	}
+	yield := func(item T) error {
+		if !started {
+			if err := start(); err != nil {
+				return err
+			}
+		} else if _, err := w.WriteString(","); err != nil {
+			return err
+		}
+		if err := enc.Encode(item); err != nil {
+			return err
+		}
+		written++
+		if written%streamFlushInterval == 0 {
+			w.Flush()
+		}
+		return nil
+	}
+
+	if err := each(yield); err != nil {
+		if !started {
+			return err
+		}
+		log.Printf("Streamed response for %s cut short after %d items: %v", c.Request.URL.Path, written, err)
+		return nil
+	}
+	if !started {
+		if err := start(); err != nil {
+			return err
+		}
+	}
+	end, err := suffix()
+	if err != nil {
+		return err
+	}
+	_, err = w.WriteString("]" + end)
+	return err
+}
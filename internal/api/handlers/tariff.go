@@ -0,0 +1,315 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/budget"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TariffHandler handles the tariffs of users and organizations
+type TariffHandler struct {
+	tariffRepo repository.TariffRepository
+	orgRepo    repository.OrganizationRepository
+}
+
+// NewTariffHandler creates a new TariffHandler
+func NewTariffHandler(tariffRepo repository.TariffRepository, orgRepo repository.OrganizationRepository) *TariffHandler {
+	return &TariffHandler{
+		tariffRepo: tariffRepo,
+		orgRepo:    orgRepo,
+	}
+}
+
+// ListTariffs godoc
+// @Summary List tariffs
+// @Description Lists the authenticated user's tariffs and those of the organizations they are a member of
+// @Tags tariffs
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Tariff
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /tariffs [get]
+func (h *TariffHandler) ListTariffs(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	tariffs, err := h.tariffRepo.ListForUser(c.Request.Context(), authUser.ID)
+	if err != nil {
+		log.Printf("Error listing tariffs: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list tariffs")
+		return
+	}
+
+	c.JSON(http.StatusOK, tariffs)
+}
+
+// CreateTariff godoc
+// @Summary Create a tariff
+// @Description Sets the fees and taxes the authenticated user pays on top of the spot price in the zone and currency, or those of an organization when organization_id is set, which its owners and admins may do. A user or organization has at most one tariff per zone and currency. Fees and energy tax are in the currency, VAT in percent. The export fee is deducted from the spot price of exported energy. Rates replace the transfer fee between two times of day (HH:MM in the zone's timezone, wrapping past midnight when the end is before the start), optionally on weekdays or in some months only; the first matching rate applies.
+// @Tags tariffs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateTariffRequest true "Tariff"
+// @Success 201 {object} models.Tariff
+// @Failure 400 {object} apierror.Problem "Invalid request, invalid rates or unknown zone or currency"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - organization owners and admins only"
+// @Failure 404 {object} apierror.Problem "Organization not found"
+// @Failure 409 {object} apierror.Problem "A tariff for the zone and currency exists"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /tariffs [post]
+func (h *TariffHandler) CreateTariff(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreateTariffRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := budget.ValidateRates(req.Rates); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	tariff := &models.Tariff{
+		Name:        req.Name,
+		ZoneID:      req.ZoneID,
+		CurrencyID:  req.CurrencyID,
+		TransferFee: req.TransferFee,
+		EnergyTax:   req.EnergyTax,
+		MonthlyFee:  req.MonthlyFee,
+		VAT:         req.VAT,
+		ExportFee:   req.ExportFee,
+		Rates:       req.Rates,
+	}
+	if req.OrganizationID != nil {
+		if !h.canManage(c, authUser.ID, *req.OrganizationID) {
+			return
+		}
+		tariff.OrganizationID = req.OrganizationID
+	} else {
+		tariff.UserID = &authUser.ID
+	}
+
+	if err := h.tariffRepo.Create(c.Request.Context(), tariff); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrNotFound):
+			apierror.Write(c, apierror.InvalidRequest, "unknown zone or currency")
+		case errors.Is(err, repository.ErrDuplicateEntry):
+			apierror.Write(c, apierror.TariffExists, "a tariff for the zone and currency already exists")
+		default:
+			log.Printf("Error creating tariff: %v", err)
+			apierror.Write(c, apierror.Internal, "failed to create tariff")
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, tariff)
+}
+
+// GetTariff godoc
+// @Summary Get a tariff
+// @Description Returns one of the authenticated user's tariffs or one of their organizations'
+// @Tags tariffs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Tariff ID (UUID)"
+// @Success 200 {object} models.Tariff
+// @Failure 400 {object} apierror.Problem "Invalid tariff ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Tariff not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /tariffs/{id} [get]
+func (h *TariffHandler) GetTariff(c *gin.Context) {
+	tariff, ok := h.getTariff(c, false)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, tariff)
+}
+
+// UpdateTariff godoc
+// @Summary Update a tariff
+// @Description Updates the name, fees, taxes or rates of one of the authenticated user's tariffs, or of an organization's they are an owner or admin of. Rates replace the existing ones when given.
+// @Tags tariffs
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Tariff ID (UUID)"
+// @Param request body models.UpdateTariffRequest true "Tariff changes"
+// @Success 200 {object} models.Tariff
+// @Failure 400 {object} apierror.Problem "Invalid request or invalid rates"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - organization owners and admins only"
+// @Failure 404 {object} apierror.Problem "Tariff not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /tariffs/{id} [put]
+func (h *TariffHandler) UpdateTariff(c *gin.Context) {
+	tariff, ok := h.getTariff(c, true)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateTariffRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.Name != nil {
+		tariff.Name = *req.Name
+	}
+	if req.TransferFee != nil {
+		tariff.TransferFee = *req.TransferFee
+	}
+	if req.EnergyTax != nil {
+		tariff.EnergyTax = *req.EnergyTax
+	}
+	if req.MonthlyFee != nil {
+		tariff.MonthlyFee = *req.MonthlyFee
+	}
+	if req.VAT != nil {
+		tariff.VAT = *req.VAT
+	}
+	if req.ExportFee != nil {
+		tariff.ExportFee = *req.ExportFee
+	}
+	if req.Rates != nil {
+		if err := budget.ValidateRates(*req.Rates); err != nil {
+			apierror.Write(c, apierror.InvalidRequest, err.Error())
+			return
+		}
+		tariff.Rates = *req.Rates
+	}
+
+	if err := h.tariffRepo.Update(c.Request.Context(), tariff); err != nil {
+		log.Printf("Error updating tariff: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to update tariff")
+		return
+	}
+
+	c.JSON(http.StatusOK, tariff)
+}
+
+// DeleteTariff godoc
+// @Summary Delete a tariff
+// @Description Removes one of the authenticated user's tariffs, or an organization's they are an owner or admin of
+// @Tags tariffs
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Tariff ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid tariff ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - organization owners and admins only"
+// @Failure 404 {object} apierror.Problem "Tariff not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /tariffs/{id} [delete]
+func (h *TariffHandler) DeleteTariff(c *gin.Context) {
+	tariff, ok := h.getTariff(c, true)
+	if !ok {
+		return
+	}
+
+	if err := h.tariffRepo.Delete(c.Request.Context(), tariff.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Error deleting tariff: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to delete tariff")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// getTariff loads the tariff from the id path parameter and writes an error response if
+// it does not exist or the user can't see it, or can't change it when manage is set
+func (h *TariffHandler) getTariff(c *gin.Context, manage bool) (*models.Tariff, bool) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return nil, false
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid tariff ID")
+		return nil, false
+	}
+
+	tariff, err := h.tariffRepo.GetByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.TariffNotFound, "tariff not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error getting tariff %s: %v", id, err)
+		apierror.Write(c, apierror.Internal, "failed to get tariff")
+		return nil, false
+	}
+
+	if tariff.UserID != nil {
+		if *tariff.UserID != authUser.ID {
+			apierror.Write(c, apierror.TariffNotFound, "tariff not found")
+			return nil, false
+		}
+		return tariff, true
+	}
+
+	member, err := h.orgRepo.GetMember(c.Request.Context(), *tariff.OrganizationID, authUser.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.TariffNotFound, "tariff not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error getting membership in organization %s: %v", *tariff.OrganizationID, err)
+		apierror.Write(c, apierror.Internal, "failed to get tariff")
+		return nil, false
+	}
+	if manage && !member.Role.CanManage() {
+		apierror.Write(c, apierror.Forbidden, "only owners and admins can change the organization's tariffs")
+		return nil, false
+	}
+	return tariff, true
+}
+
+// canManage reports whether the user is an owner or admin of the organization, and writes
+// an error response if not
+func (h *TariffHandler) canManage(c *gin.Context, userID, orgID uuid.UUID) bool {
+	member, err := h.orgRepo.GetMember(c.Request.Context(), orgID, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.OrganizationNotFound, "organization not found")
+		return false
+	}
+	if err != nil {
+		log.Printf("Error getting membership in organization %s: %v", orgID, err)
+		apierror.Write(c, apierror.Internal, "failed to get organization")
+		return false
+	}
+	if !member.Role.CanManage() {
+		apierror.Write(c, apierror.Forbidden, "only owners and admins can change the organization's tariffs")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,203 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/budget"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTariffHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	ctx := context.Background()
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@test.com", "password123", false)
+	carol := tc.CreateTestUser("carol", "carol@test.com", "password123", false)
+	zone, err := tc.ZoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := tc.CurrencyRepo.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+
+	// Alice owns the household Bob is a member of
+	household := &models.Organization{Name: "Household"}
+	require.NoError(t, tc.OrganizationRepo.Create(ctx, household, alice.ID))
+	require.NoError(t, tc.OrganizationRepo.AddMember(ctx, &models.OrganizationMember{
+		OrganizationID: household.ID, UserID: bob.ID, Role: models.OrganizationRoleMember,
+	}))
+
+	handler := handlers.NewTariffHandler(tc.TariffRepo, tc.OrganizationRepo)
+	budgetHandler := handlers.NewBudgetHandler(tc.BudgetRepo, tc.ZoneRepo, tc.CurrencyRepo,
+		budget.NewProjector(tc.ConsumptionRepo, tc.SpotPriceRepo))
+	budgetHandler.SetTariffs(tc.TariffRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/tariffs", handler.ListTariffs)
+	router.POST("/tariffs", handler.CreateTariff)
+	router.GET("/tariffs/:id", handler.GetTariff)
+	router.PUT("/tariffs/:id", handler.UpdateTariff)
+	router.DELETE("/tariffs/:id", handler.DeleteTariff)
+	router.GET("/costs/projection", budgetHandler.ProjectCost)
+
+	send := func(method, path string, userID uuid.UUID, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	floatPtr := func(f float64) *float64 { return &f }
+
+	t.Run("Create Validation", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			input      models.CreateTariffRequest
+			wantStatus int
+		}{
+			{"Missing Name", models.CreateTariffRequest{ZoneID: zone.ID, CurrencyID: currency.ID}, http.StatusBadRequest},
+			{"Negative Fee", models.CreateTariffRequest{Name: "grid", ZoneID: zone.ID, CurrencyID: currency.ID, TransferFee: -1}, http.StatusBadRequest},
+			{"VAT Over 100", models.CreateTariffRequest{Name: "grid", ZoneID: zone.ID, CurrencyID: currency.ID, VAT: 101}, http.StatusBadRequest},
+			{"Invalid Rate Time", models.CreateTariffRequest{Name: "grid", ZoneID: zone.ID, CurrencyID: currency.ID,
+				Rates: []models.TariffRate{{Start: "6am", End: "22:00"}}}, http.StatusBadRequest},
+			{"Invalid Rate Month", models.CreateTariffRequest{Name: "grid", ZoneID: zone.ID, CurrencyID: currency.ID,
+				Rates: []models.TariffRate{{Start: "06:00", End: "22:00", Months: []int{13}}}}, http.StatusBadRequest},
+			{"Unknown Zone", models.CreateTariffRequest{Name: "grid", ZoneID: uuid.New(), CurrencyID: currency.ID}, http.StatusBadRequest},
+			{"Not A Member", models.CreateTariffRequest{Name: "grid", ZoneID: zone.ID, CurrencyID: currency.ID, OrganizationID: &household.ID},
+				http.StatusNotFound},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := send("POST", "/tariffs", carol.ID, tt.input)
+				assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			})
+		}
+	})
+
+	// Alice has her own tariff, the household's is shared with Bob
+	w := send("POST", "/tariffs", alice.ID, models.CreateTariffRequest{
+		Name: "mine", ZoneID: zone.ID, CurrencyID: currency.ID, TransferFee: 0.5, VAT: 25,
+		Rates: []models.TariffRate{{Start: "22:00", End: "06:00", TransferFee: 0.1}},
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var own models.Tariff
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &own))
+	assert.Equal(t, alice.ID, *own.UserID)
+	assert.Len(t, own.Rates, 1)
+
+	w = send("POST", "/tariffs", bob.ID, models.CreateTariffRequest{
+		Name: "household", ZoneID: zone.ID, CurrencyID: currency.ID, OrganizationID: &household.ID,
+	})
+	assert.Equal(t, http.StatusForbidden, w.Code, w.Body.String())
+	w = send("POST", "/tariffs", alice.ID, models.CreateTariffRequest{
+		Name: "household", ZoneID: zone.ID, CurrencyID: currency.ID, OrganizationID: &household.ID, EnergyTax: 0.5,
+	})
+	require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+	var shared models.Tariff
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &shared))
+	assert.Nil(t, shared.UserID)
+	assert.Empty(t, shared.Rates)
+	sharedPath := "/tariffs/" + shared.ID.String()
+
+	t.Run("Duplicate", func(t *testing.T) {
+		w := send("POST", "/tariffs", alice.ID, models.CreateTariffRequest{Name: "again", ZoneID: zone.ID, CurrencyID: currency.ID})
+		assert.Equal(t, http.StatusConflict, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "TARIFF409")
+	})
+
+	t.Run("Access", func(t *testing.T) {
+		ownPath := "/tariffs/" + own.ID.String()
+		assert.Equal(t, http.StatusOK, send("GET", ownPath, alice.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", ownPath, bob.ID, nil).Code)
+		assert.Equal(t, http.StatusOK, send("GET", sharedPath, bob.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", sharedPath, carol.ID, nil).Code)
+		assert.Equal(t, http.StatusForbidden, send("PUT", sharedPath, bob.ID, models.UpdateTariffRequest{VAT: floatPtr(0)}).Code)
+		assert.Equal(t, http.StatusForbidden, send("DELETE", sharedPath, bob.ID, nil).Code)
+
+		var tariffs []models.Tariff
+		require.NoError(t, json.Unmarshal(send("GET", "/tariffs", alice.ID, nil).Body.Bytes(), &tariffs))
+		assert.Len(t, tariffs, 2)
+		require.NoError(t, json.Unmarshal(send("GET", "/tariffs", bob.ID, nil).Body.Bytes(), &tariffs))
+		require.Len(t, tariffs, 1)
+		assert.Equal(t, shared.ID, tariffs[0].ID)
+		require.NoError(t, json.Unmarshal(send("GET", "/tariffs", carol.ID, nil).Body.Bytes(), &tariffs))
+		assert.Empty(t, tariffs)
+	})
+
+	t.Run("Update", func(t *testing.T) {
+		rates := []models.TariffRate{{Start: "06:00", End: "22:00", Weekdays: true, TransferFee: 0.8}}
+		w := send("PUT", sharedPath, alice.ID, models.UpdateTariffRequest{MonthlyFee: floatPtr(300), Rates: &rates})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.Tariff
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.Equal(t, 300.0, updated.MonthlyFee)
+		assert.Equal(t, 0.5, updated.EnergyTax)
+		assert.Equal(t, rates, updated.Rates)
+
+		bad := []models.TariffRate{{Start: "06:00", End: "06:00"}}
+		assert.Equal(t, http.StatusBadRequest, send("PUT", sharedPath, alice.ID, models.UpdateTariffRequest{Rates: &bad}).Code)
+	})
+
+	t.Run("Projection", func(t *testing.T) {
+		loc, err := time.LoadLocation(zone.Timezone)
+		require.NoError(t, err)
+		start := time.Date(time.Now().In(loc).Year(), time.Now().In(loc).Month(), 1, 0, 0, 0, 0, loc)
+		var prices []models.SpotPrice
+		for at := start; at.Before(start.AddDate(0, 1, 0)); at = at.Add(time.Hour) {
+			prices = append(prices, models.SpotPrice{Timestamp: at, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 100})
+		}
+		require.NoError(t, tc.SpotPriceRepo.CreateBatch(ctx, prices))
+
+		// Alice's own tariff comes first, Bob pays the household's
+		var projection models.CostProjection
+		w := send("GET", "/costs/projection?zone=SE3&currency=SEK", alice.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projection))
+		require.NotNil(t, projection.Tariff)
+		assert.Equal(t, own.ID, projection.Tariff.ID)
+
+		w = send("GET", "/costs/projection?zone=SE3&currency=SEK", bob.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		projection = models.CostProjection{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projection))
+		require.NotNil(t, projection.Tariff)
+		assert.Equal(t, shared.ID, projection.Tariff.ID)
+		assert.Equal(t, 300.0, projection.Breakdown.MonthlyFee)
+		assert.Equal(t, 300.0, projection.ProjectedCost, "only the monthly fee without readings")
+
+		w = send("GET", "/costs/projection?zone=SE3&currency=SEK&tariff_id="+shared.ID.String(), alice.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projection))
+		assert.Equal(t, shared.ID, projection.Tariff.ID)
+
+		w = send("GET", "/costs/projection?zone=SE3&currency=SEK&tariff_id="+own.ID.String(), bob.ID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code, w.Body.String())
+		w = send("GET", "/costs/projection?zone=SE3&currency=SEK", carol.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		projection = models.CostProjection{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &projection))
+		assert.Nil(t, projection.Tariff)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		assert.Equal(t, http.StatusNoContent, send("DELETE", sharedPath, alice.ID, nil).Code)
+		assert.Equal(t, http.StatusNotFound, send("GET", sharedPath, bob.ID, nil).Code)
+	})
+}
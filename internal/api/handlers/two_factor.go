@@ -0,0 +1,359 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/metrics"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// SetTwoFactor enables two-factor authentication with TOTP secrets and backup codes stored
+// in repo. Without it logins take a password only and the two-factor endpoints must not be
+// routed.
+func (h *AuthHandler) SetTwoFactor(repo repository.TwoFactorRepository) {
+	h.twoFactorRepo = repo
+}
+
+// LoginTwoFactor godoc
+// @Summary Complete a two-factor login
+// @Description Exchanges the token returned by /auth/login for users with two-factor authentication, together with a code from their authenticator app or an unused backup code, for access and refresh tokens. Each token is exchanged once. Wrong codes count as failed login attempts.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.TwoFactorLoginRequest true "Two-factor token and code"
+// @Success 200 {object} models.LoginResponse "Login successful"
+// @Failure 400 {object} apierror.Problem "Invalid request format"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Invalid or expired token, or invalid code"
+// @Failure 429 {object} apierror.Problem "Too many failed login attempts or rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/login/2fa [post]
+func (h *AuthHandler) LoginTwoFactor(c *gin.Context) {
+	ipAddress := c.ClientIP()
+
+	var req models.TwoFactorLoginRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	preAuth, err := h.authService.ValidatePreAuthToken(req.Token)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidTwoFactor, "invalid or expired two-factor token")
+		return
+	}
+	user, err := h.userRepo.GetByID(c.Request.Context(), preAuth.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) || (err == nil && user.DeletedAt != nil) {
+		apierror.Write(c, apierror.InvalidTwoFactor, "invalid or expired two-factor token")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process login")
+		return
+	}
+
+	// Codes are guessed against the same lockout as passwords
+	threshold := h.settings.LockoutThreshold()
+	cutoff := time.Now().Add(-h.settings.LockoutWindow())
+	recentAttempts, err := h.loginAttemptRepo.GetRecentAttempts(c.Request.Context(), user.ID, cutoff)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process login")
+		return
+	}
+	if threshold > 0 && recentAttempts >= threshold {
+		metrics.LoginAttempt(metrics.LoginLocked)
+		apierror.Write(c, apierror.TooManyAttempts, "too many failed login attempts")
+		return
+	}
+
+	// The token stops working when two-factor authentication was turned off meanwhile
+	twoFactor, err := h.twoFactorRepo.GetByUserID(c.Request.Context(), user.ID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !twoFactor.Enabled()) {
+		apierror.Write(c, apierror.InvalidTwoFactor, "invalid or expired two-factor token")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process login")
+		return
+	}
+
+	valid, err := h.checkTwoFactorCode(c.Request.Context(), twoFactor, req.Code)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process login")
+		return
+	}
+	if !valid {
+		if err := h.loginAttemptRepo.Create(c.Request.Context(), user.ID, false, ipAddress, time.Now()); err != nil {
+			apierror.Write(c, apierror.Internal, "failed to process login")
+			return
+		}
+		if err := h.userRepo.IncrementFailedAttempts(c.Request.Context(), user.Username); err != nil {
+			apierror.Write(c, apierror.Internal, "failed to process login")
+			return
+		}
+		metrics.LoginAttempt(metrics.LoginFailure)
+		apierror.Write(c, apierror.InvalidTwoFactor, "invalid code")
+		return
+	}
+
+	// The token is exchanged once, a captured one can't mint more sessions
+	err = h.twoFactorRepo.UsePreAuthToken(c.Request.Context(), user.ID, preAuth.ID, preAuth.ExpiresAt)
+	if errors.Is(err, repository.ErrConflict) {
+		apierror.Write(c, apierror.InvalidTwoFactor, "invalid or expired two-factor token")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to process login")
+		return
+	}
+
+	h.completeLogin(c, user, ipAddress)
+}
+
+// GetTwoFactor godoc
+// @Summary Get two-factor authentication status
+// @Description Reports whether the authenticated user's logins require a code, and how many backup codes are left
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.TwoFactorStatus
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/2fa [get]
+func (h *AuthHandler) GetTwoFactor(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	status := models.TwoFactorStatus{}
+	twoFactor, err := h.twoFactorRepo.GetByUserID(c.Request.Context(), authUser.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Error getting two-factor authentication: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get two-factor authentication")
+		return
+	}
+	if twoFactor != nil && twoFactor.Enabled() {
+		remaining, err := h.twoFactorRepo.CountBackupCodes(c.Request.Context(), authUser.ID)
+		if err != nil {
+			log.Printf("Error counting backup codes: %v", err)
+			apierror.Write(c, apierror.Internal, "failed to get two-factor authentication")
+			return
+		}
+		status = models.TwoFactorStatus{Enabled: true, EnabledAt: twoFactor.EnabledAt, BackupCodesRemaining: remaining}
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// EnrollTwoFactor godoc
+// @Summary Start two-factor authentication enrollment
+// @Description Generates a TOTP secret for the authenticated user, returned as text, an otpauth:// URL and a QR code to add to an authenticator app. Logins don't require codes until the enrollment is confirmed at /auth/2fa/confirm. Enrolling again replaces an unconfirmed secret.
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.TwoFactorEnrollment
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 409 {object} apierror.Problem "Two-factor authentication is already enabled"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/2fa/enroll [post]
+func (h *AuthHandler) EnrollTwoFactor(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	enrollment, err := auth.NewTOTPEnrollment(authUser.Username)
+	if err != nil {
+		log.Printf("Error generating TOTP secret: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to enroll in two-factor authentication")
+		return
+	}
+	if err := h.twoFactorRepo.Enroll(c.Request.Context(), authUser.ID, enrollment.Secret); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			apierror.Write(c, apierror.TwoFactorState, "two-factor authentication is already enabled")
+			return
+		}
+		log.Printf("Error storing TOTP secret: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to enroll in two-factor authentication")
+		return
+	}
+
+	c.JSON(http.StatusOK, enrollment)
+}
+
+// ConfirmTwoFactor godoc
+// @Summary Enable two-factor authentication
+// @Description Confirms the enrollment with a code from the authenticator app, after which logins require a code. The response holds the backup codes, which are only shown once.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.TwoFactorCodeRequest true "Code from the authenticator app"
+// @Success 200 {object} models.TwoFactorBackupCodes
+// @Failure 400 {object} apierror.Problem "Invalid code or no enrollment to confirm"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 409 {object} apierror.Problem "Two-factor authentication is already enabled"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/2fa/confirm [post]
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.TwoFactorCodeRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	twoFactor, err := h.twoFactorRepo.GetByUserID(c.Request.Context(), authUser.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.InvalidRequest, "no two-factor enrollment to confirm")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting two-factor authentication: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to enable two-factor authentication")
+		return
+	}
+	if twoFactor.Enabled() {
+		apierror.Write(c, apierror.TwoFactorState, "two-factor authentication is already enabled")
+		return
+	}
+
+	step, ok := auth.VerifyTOTP(twoFactor.Secret, req.Code, time.Now())
+	if !ok {
+		apierror.Write(c, apierror.InvalidRequest, "invalid code")
+		return
+	}
+
+	codes, hashes, err := auth.GenerateBackupCodes()
+	if err != nil {
+		log.Printf("Error generating backup codes: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to enable two-factor authentication")
+		return
+	}
+	if err := h.twoFactorRepo.Enable(c.Request.Context(), authUser.ID, step, hashes, time.Now()); err != nil {
+		switch {
+		case errors.Is(err, repository.ErrConflict):
+			apierror.Write(c, apierror.TwoFactorState, "two-factor authentication is already enabled")
+		case errors.Is(err, repository.ErrNotFound):
+			apierror.Write(c, apierror.InvalidRequest, "no two-factor enrollment to confirm")
+		default:
+			log.Printf("Error enabling two-factor authentication: %v", err)
+			apierror.Write(c, apierror.Internal, "failed to enable two-factor authentication")
+		}
+		return
+	}
+
+	h.auditUser(c, authUser, "two_factor_enabled", fmt.Sprintf("User %s enabled two-factor authentication", authUser.Username))
+	c.JSON(http.StatusOK, models.TwoFactorBackupCodes{BackupCodes: codes})
+}
+
+// DisableTwoFactor godoc
+// @Summary Disable two-factor authentication
+// @Description Turns off two-factor authentication for the authenticated user and removes the secret and backup codes. Requires the password and a code from the authenticator app or a backup code.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.DisableTwoFactorRequest true "Password and code"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid request or code"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized or invalid password"
+// @Failure 409 {object} apierror.Problem "Two-factor authentication is not enabled"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.DisableTwoFactorRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), authUser.ID)
+	if err != nil {
+		log.Printf("Error getting user: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to disable two-factor authentication")
+		return
+	}
+	if err := h.authService.ComparePasswords(user.Password, req.Password); err != nil {
+		apierror.Write(c, apierror.InvalidCredentials, "invalid password")
+		return
+	}
+
+	twoFactor, err := h.twoFactorRepo.GetByUserID(c.Request.Context(), authUser.ID)
+	if errors.Is(err, repository.ErrNotFound) || (err == nil && !twoFactor.Enabled()) {
+		apierror.Write(c, apierror.TwoFactorState, "two-factor authentication is not enabled")
+		return
+	}
+	if err != nil {
+		log.Printf("Error getting two-factor authentication: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to disable two-factor authentication")
+		return
+	}
+
+	valid, err := h.checkTwoFactorCode(c.Request.Context(), twoFactor, req.Code)
+	if err != nil {
+		log.Printf("Error checking two-factor code: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to disable two-factor authentication")
+		return
+	}
+	if !valid {
+		apierror.Write(c, apierror.InvalidRequest, "invalid code")
+		return
+	}
+
+	if err := h.twoFactorRepo.Delete(c.Request.Context(), authUser.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		log.Printf("Error disabling two-factor authentication: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to disable two-factor authentication")
+		return
+	}
+
+	h.auditUser(c, authUser, "two_factor_disabled", fmt.Sprintf("User %s disabled two-factor authentication", authUser.Username))
+	c.Status(http.StatusNoContent)
+}
+
+// checkTwoFactorCode accepts a TOTP code of the current time that wasn't used before, or an
+// unused backup code, which is used up
+func (h *AuthHandler) checkTwoFactorCode(ctx context.Context, twoFactor *models.TwoFactor, code string) (bool, error) {
+	if auth.IsTOTPCode(code) {
+		step, ok := auth.VerifyTOTP(twoFactor.Secret, code, time.Now())
+		if !ok {
+			return false, nil
+		}
+		err := h.twoFactorRepo.UseStep(ctx, twoFactor.UserID, step)
+		if errors.Is(err, repository.ErrConflict) {
+			return false, nil
+		}
+		return err == nil, err
+	}
+
+	err := h.twoFactorRepo.UseBackupCode(ctx, twoFactor.UserID, auth.HashBackupCode(code), time.Now())
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
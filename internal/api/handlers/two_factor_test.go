@@ -0,0 +1,214 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthHandler_TwoFactor(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.POST("/auth/login", tc.AuthHandler.Login)
+	router.POST("/auth/login/2fa", tc.AuthHandler.LoginTwoFactor)
+	router.GET("/auth/2fa", authMiddleware.AuthRequired(), tc.AuthHandler.GetTwoFactor)
+	router.POST("/auth/2fa/enroll", authMiddleware.AuthRequired(), tc.AuthHandler.EnrollTwoFactor)
+	router.POST("/auth/2fa/confirm", authMiddleware.AuthRequired(), tc.AuthHandler.ConfirmTwoFactor)
+	router.POST("/auth/2fa/disable", authMiddleware.AuthRequired(), tc.AuthHandler.DisableTwoFactor)
+
+	send := func(method, path string, authenticated bool, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		if authenticated {
+			req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+		}
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	status := func() models.TwoFactorStatus {
+		w := send(http.MethodGet, "/auth/2fa", true, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var status models.TwoFactorStatus
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &status))
+		return status
+	}
+	login := func() models.TwoFactorChallenge {
+		w := send(http.MethodPost, "/auth/login", false, models.LoginRequest{Username: "user", Password: "password123"})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var challenge models.TwoFactorChallenge
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+		return challenge
+	}
+
+	assert.False(t, status().Enabled)
+	w := send(http.MethodPost, "/auth/2fa/confirm", true, models.TwoFactorCodeRequest{Code: "123456"})
+	assert.Equal(t, http.StatusBadRequest, w.Code, "nothing to confirm")
+
+	w = send(http.MethodPost, "/auth/2fa/enroll", true, nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var enrollment models.TwoFactorEnrollment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrollment))
+	require.NotEmpty(t, enrollment.Secret)
+	code := func(at time.Time) string {
+		code, err := totp.GenerateCode(enrollment.Secret, at)
+		require.NoError(t, err)
+		return code
+	}
+
+	// Until the enrollment is confirmed logins take a password only
+	assert.False(t, login().TwoFactorRequired)
+
+	w = send(http.MethodPost, "/auth/2fa/confirm", true, models.TwoFactorCodeRequest{Code: code(time.Now().Add(-time.Hour))})
+	assert.Equal(t, http.StatusBadRequest, w.Code, w.Body.String())
+	w = send(http.MethodPost, "/auth/2fa/confirm", true, models.TwoFactorCodeRequest{Code: code(time.Now())})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var backup models.TwoFactorBackupCodes
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &backup))
+	require.Len(t, backup.BackupCodes, 10)
+	assert.Equal(t, 10, status().BackupCodesRemaining)
+
+	w = send(http.MethodPost, "/auth/2fa/enroll", true, nil)
+	assert.Equal(t, http.StatusConflict, w.Code)
+
+	t.Run("Login", func(t *testing.T) {
+		challenge := login()
+		require.True(t, challenge.TwoFactorRequired)
+		require.NotEmpty(t, challenge.Token)
+
+		// The pre-auth token is no access token
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/auth/2fa", nil)
+		req.Header.Set("Authorization", "Bearer "+challenge.Token)
+		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = send(http.MethodPost, "/auth/login/2fa", false, models.TwoFactorLoginRequest{Token: "invalid", Code: code(time.Now())})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = send(http.MethodPost, "/auth/login/2fa", false, models.TwoFactorLoginRequest{Token: challenge.Token, Code: "000000"})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		// The confirmation used the current code, the next period's is still accepted
+		next := code(time.Now().Add(30 * time.Second))
+		w = send(http.MethodPost, "/auth/login/2fa", false, models.TwoFactorLoginRequest{Token: challenge.Token, Code: next})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp handlers.LoginResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		_, err := tc.AuthService.ValidateToken(resp.AccessToken)
+		require.NoError(t, err)
+
+		// Codes can't be replayed
+		w = send(http.MethodPost, "/auth/login/2fa", false, models.TwoFactorLoginRequest{Token: login().Token, Code: next})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Backup Code", func(t *testing.T) {
+		token := login().Token
+		w := send(http.MethodPost, "/auth/login/2fa", false, models.TwoFactorLoginRequest{Token: token, Code: backup.BackupCodes[0]})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, 9, status().BackupCodesRemaining)
+
+		// Tokens are exchanged once, even with another valid code
+		w = send(http.MethodPost, "/auth/login/2fa", false, models.TwoFactorLoginRequest{Token: token, Code: backup.BackupCodes[3]})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+
+		w = send(http.MethodPost, "/auth/login/2fa", false, models.TwoFactorLoginRequest{Token: login().Token, Code: backup.BackupCodes[0]})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		w := send(http.MethodPost, "/auth/2fa/disable", true, models.DisableTwoFactorRequest{Password: "wrong", Code: backup.BackupCodes[1]})
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		w = send(http.MethodPost, "/auth/2fa/disable", true, models.DisableTwoFactorRequest{Password: "password123", Code: backup.BackupCodes[0]})
+		assert.Equal(t, http.StatusBadRequest, w.Code, "used backup code")
+
+		w = send(http.MethodPost, "/auth/2fa/disable", true, models.DisableTwoFactorRequest{Password: "password123", Code: backup.BackupCodes[1]})
+		require.Equal(t, http.StatusNoContent, w.Code, w.Body.String())
+		assert.Equal(t, models.TwoFactorStatus{}, status())
+		assert.False(t, login().TwoFactorRequired)
+
+		w = send(http.MethodPost, "/auth/2fa/disable", true, models.DisableTwoFactorRequest{Password: "password123", Code: backup.BackupCodes[2]})
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+}
+
+func TestAuthHandler_TwoFactorDuringMaintenance(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	maintenance := middleware.NewMaintenanceMode(tc.AuthService, tc.SettingRepo)
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router := gin.New()
+	router.Use(maintenance.Middleware())
+	router.POST("/api/v1/auth/login", tc.AuthHandler.Login)
+	router.POST("/api/v1/auth/login/2fa", tc.AuthHandler.LoginTwoFactor)
+	router.GET("/api/v1/auth/2fa", authMiddleware.AuthRequired(), tc.AuthHandler.GetTwoFactor)
+	router.POST("/api/v1/auth/2fa/enroll", authMiddleware.AuthRequired(), tc.AuthHandler.EnrollTwoFactor)
+	router.POST("/api/v1/auth/2fa/confirm", authMiddleware.AuthRequired(), tc.AuthHandler.ConfirmTwoFactor)
+
+	send := func(method, path, token string, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(http.MethodPost, "/api/v1/auth/2fa/enroll", tc.GetTestJWT(admin.ID), nil)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var enrollment models.TwoFactorEnrollment
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &enrollment))
+	code, err := totp.GenerateCode(enrollment.Secret, time.Now())
+	require.NoError(t, err)
+	w = send(http.MethodPost, "/api/v1/auth/2fa/confirm", tc.GetTestJWT(admin.ID), models.TwoFactorCodeRequest{Code: code})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	// Administrators with two-factor authentication finish logging in during maintenance
+	_, err = maintenance.Set(context.Background(), true, "upgrading", time.Minute, &admin.ID)
+	require.NoError(t, err)
+	w = send(http.MethodPost, "/api/v1/auth/login", "", models.LoginRequest{Username: "admin", Password: "password123"})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var challenge models.TwoFactorChallenge
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &challenge))
+	require.True(t, challenge.TwoFactorRequired)
+
+	// The confirmation used the current code, the next period's is still accepted
+	code, err = totp.GenerateCode(enrollment.Secret, time.Now().Add(30*time.Second))
+	require.NoError(t, err)
+	w = send(http.MethodPost, "/api/v1/auth/login/2fa", "", models.TwoFactorLoginRequest{Token: challenge.Token, Code: code})
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var resp handlers.LoginResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+
+	w = send(http.MethodGet, "/api/v1/auth/2fa", resp.AccessToken, nil)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	w = send(http.MethodGet, "/api/v1/auth/2fa", "", nil)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+}
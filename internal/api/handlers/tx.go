@@ -0,0 +1,14 @@
+package handlers
+
+import (
+	"context"
+	"wattwatch/internal/repository"
+)
+
+// withinTx runs fn in a transaction of txManager, or without one when txManager is nil
+func withinTx(ctx context.Context, txManager repository.TxManager, fn func(ctx context.Context) error) error {
+	if txManager == nil {
+		return fn(ctx)
+	}
+	return txManager.WithinTx(ctx, fn)
+}
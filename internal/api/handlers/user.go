@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"wattwatch/internal/apierror"
 	"wattwatch/internal/auth"
+	"wattwatch/internal/config"
+	"wattwatch/internal/email"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
+	"wattwatch/internal/settings"
 
 	"encoding/json"
 
@@ -28,47 +35,115 @@ func GetUserFromContext(c *gin.Context) *models.User {
 }
 
 type UserHandler struct {
-	userRepo        repository.UserRepository
-	authService     *auth.Service
-	passwordHistory repository.PasswordHistoryRepository
-	auditRepo       repository.AuditLogRepository
+	userRepo         repository.UserRepository
+	authService      *auth.Service
+	passwordHistory  repository.PasswordHistoryRepository
+	auditRepo        repository.AuditLogRepository
+	emailService     email.EmailSender
+	emailVerifyRepo  repository.EmailVerificationRepository
+	emailChangeRepo  repository.EmailChangeRevertRepository
+	refreshTokenRepo repository.RefreshTokenRepository
+	loginAttemptRepo repository.LoginAttemptRepository
+	orgRepo          repository.OrganizationRepository
+	config           *config.Config
+	settings         *settings.Store
+	limits           ListLimits
 }
 
-func NewUserHandler(userRepo repository.UserRepository, authService *auth.Service, passwordHistory repository.PasswordHistoryRepository, auditRepo repository.AuditLogRepository) *UserHandler {
+func NewUserHandler(
+	userRepo repository.UserRepository,
+	authService *auth.Service,
+	passwordHistory repository.PasswordHistoryRepository,
+	auditRepo repository.AuditLogRepository,
+	emailService email.EmailSender,
+	emailVerifyRepo repository.EmailVerificationRepository,
+	emailChangeRepo repository.EmailChangeRevertRepository,
+	refreshTokenRepo repository.RefreshTokenRepository,
+	loginAttemptRepo repository.LoginAttemptRepository,
+	config *config.Config,
+) *UserHandler {
 	return &UserHandler{
-		userRepo:        userRepo,
-		authService:     authService,
-		passwordHistory: passwordHistory,
-		auditRepo:       auditRepo,
+		userRepo:         userRepo,
+		authService:      authService,
+		passwordHistory:  passwordHistory,
+		auditRepo:        auditRepo,
+		emailService:     emailService,
+		emailVerifyRepo:  emailVerifyRepo,
+		emailChangeRepo:  emailChangeRepo,
+		refreshTokenRepo: refreshTokenRepo,
+		loginAttemptRepo: loginAttemptRepo,
+		config:           config,
+		limits:           DefaultListLimits,
 	}
 }
 
+// SetListLimits sets the default and maximum number of users listed
+func (h *UserHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// SetOrganizationRepository lets users see the members of their organizations
+func (h *UserHandler) SetOrganizationRepository(orgRepo repository.OrganizationRepository) {
+	h.orgRepo = orgRepo
+}
+
+// SetSettings makes the lockout and password history policies follow the runtime
+// settings instead of the configuration
+func (h *UserHandler) SetSettings(store *settings.Store) {
+	h.settings = store
+}
+
+// lockoutPolicy returns how many failed logins within which window lock an account
+func (h *UserHandler) lockoutPolicy() (int, time.Duration) {
+	if h.settings == nil {
+		return h.config.Auth.LockoutThreshold, h.config.Auth.LockoutWindow
+	}
+	return h.settings.LockoutThreshold(), h.settings.LockoutWindow()
+}
+
+// passwordHistoryDepth returns how many previous passwords a user can't reuse
+func (h *UserHandler) passwordHistoryDepth() int {
+	if h.settings == nil {
+		return h.config.Password.HistoryDepth
+	}
+	return h.settings.PasswordHistoryDepth()
+}
+
+// sharesOrganization reports whether the authenticated user and the user with id are
+// members of the same organization
+func (h *UserHandler) sharesOrganization(c *gin.Context, authUser *models.User, id uuid.UUID) (bool, error) {
+	if h.orgRepo == nil {
+		return false, nil
+	}
+	return h.orgRepo.SharesOrganization(c.Request.Context(), authUser.ID, id)
+}
+
 // GetUser godoc
 // @Summary Get user by ID
-// @Description Get a user by their ID (requires auth, users can only access their own profile unless admin)
+// @Description Get a user by their ID (requires auth, users can only access their own profile and those of their organizations' members unless admin)
 // @Tags users
 // @Accept json
 // @Produce json
 // @Param id path string true "User ID (UUID)"
 // @Success 200 {object} models.User
-// @Failure 400 {object} models.ErrorResponse "Invalid user ID"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - can only access own profile unless admin"
-// @Failure 404 {object} models.ErrorResponse "User not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid user ID"
+// @Failure 403 {object} apierror.Problem "Permission denied - can only access own profile unless admin"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /users/{id} [get]
 func (h *UserHandler) GetUser(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil || id == uuid.Nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid user id"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
 		return
 	}
 
 	// Get the authenticated user from context
 	authUser := GetUserFromContext(c)
 	if authUser == nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "unauthorized"})
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
 		return
 	}
 
@@ -77,62 +152,100 @@ func (h *UserHandler) GetUser(c *gin.Context) {
 	if err != nil {
 		switch err {
 		case repository.ErrUserNotFound:
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "user not found"})
+			apierror.Write(c, apierror.UserNotFound, "user not found")
 		default:
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "internal server error"})
+			apierror.Write(c, apierror.Internal, "internal server error")
 		}
 		return
 	}
 
-	// Users can only access their own profile unless they're an admin
-	if id != authUser.ID && !authUser.IsAdmin() {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied"})
-		return
+	// Users can only access their own profile and those of their organizations' members
+	// unless they may manage users
+	if id != authUser.ID && !authUser.Can(models.PermissionUsersManage) {
+		shares, err := h.sharesOrganization(c, authUser, id)
+		if err != nil {
+			log.Printf("Error checking organizations of user %s: %v", authUser.ID, err)
+			apierror.Write(c, apierror.Internal, "internal server error")
+			return
+		}
+		if !shares {
+			apierror.Write(c, apierror.Forbidden, "permission denied")
+			return
+		}
 	}
 
 	c.JSON(http.StatusOK, requestedUser)
 }
 
 // List godoc
-// @Summary List users (Admin only)
-// @Description List users with optional filtering. Requires admin privileges.
+// @Summary List users
+// @Description List users with optional filtering. Users without permission to manage users only see themselves, or the members of one of their organizations with organization_id.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param search query string false "Search by username or email"
 // @Param role_id query string false "Filter by role ID"
+// @Param organization_id query string false "Only members of the organization"
+// @Param include_deleted query bool false "Include soft-deleted users"
 // @Param order_by query string false "Field to order by (username, email, created_at)"
 // @Param order_desc query bool false "Order descending"
-// @Param limit query int false "Limit results (default: 50)"
+// @Param limit query int false "Limit results (default: 50, at most 1000 unless configured otherwise)"
 // @Param offset query int false "Offset results (default: 0)"
-// @Success 200 {array} models.User
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - admin only"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Param envelope query boolean false "Wrap the users in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.User]
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Organization not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Router /users [get]
 func (h *UserHandler) ListUsers(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := GetUserFromContext(c)
 	if authUser == nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "unauthorized"})
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
 		return
 	}
 
-	// If not admin, only return their own user
-	if !authUser.IsAdmin() {
+	var filter repository.UserFilter
+
+	if orgID := c.Query("organization_id"); orgID != "" {
+		id, err := uuid.Parse(orgID)
+		if err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid organization_id")
+			return
+		}
+		filter.OrganizationID = &id
+	}
+
+	// Unless they may manage users, only return their own user or the members of one of
+	// their organizations
+	canManage := authUser.Can(models.PermissionUsersManage)
+	if !canManage && filter.OrganizationID != nil {
+		if h.orgRepo == nil {
+			apierror.Write(c, apierror.OrganizationNotFound, "organization not found")
+			return
+		}
+		if _, err := h.orgRepo.GetMember(c.Request.Context(), *filter.OrganizationID, authUser.ID); err != nil {
+			if errors.Is(err, repository.ErrNotFound) {
+				apierror.Write(c, apierror.OrganizationNotFound, "organization not found")
+				return
+			}
+			log.Printf("Error getting membership of user %s: %v", authUser.ID, err)
+			apierror.Write(c, apierror.Internal, "failed to list users")
+			return
+		}
+	} else if !canManage {
 		user, err := h.userRepo.GetByID(c.Request.Context(), authUser.ID)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get user"})
+			apierror.Write(c, apierror.Internal, "failed to get user")
 			return
 		}
-		c.JSON(http.StatusOK, []models.User{*user})
+		respondPage(c, []models.User{*user}, nil, nil, func() (int, error) { return 1, nil }, "failed to list users")
 		return
 	}
 
-	var filter repository.UserFilter
-
 	// Parse query parameters
 	if search := c.Query("search"); search != "" {
 		filter.Search = &search
@@ -144,6 +257,8 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		}
 	}
 
+	filter.IncludeDeleted = canManage && c.Query("include_deleted") == "true"
+
 	if orderBy := c.Query("order_by"); orderBy != "" {
 		filter.OrderBy = orderBy
 	}
@@ -152,11 +267,12 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 		filter.OrderDesc = true
 	}
 
-	if limit := c.Query("limit"); limit != "" {
-		if limitInt, err := strconv.Atoi(limit); err == nil {
-			filter.Limit = &limitInt
-		}
+	limit, err := h.limits.limit(c, h.limits.Default)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
 	}
+	filter.Limit = &limit
 
 	if offset := c.Query("offset"); offset != "" {
 		if offsetInt, err := strconv.Atoi(offset); err == nil {
@@ -166,11 +282,13 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 
 	users, err := h.userRepo.List(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to list users"})
+		apierror.Write(c, apierror.Internal, "failed to list users")
 		return
 	}
 
-	c.JSON(http.StatusOK, users)
+	respondPage(c, users, filter.Limit, filter.Offset, func() (int, error) {
+		return h.userRepo.Total(c.Request.Context(), filter)
+	}, "failed to list users")
 }
 
 // Update godoc
@@ -182,39 +300,30 @@ func (h *UserHandler) ListUsers(c *gin.Context) {
 // @Param id path string true "User ID (UUID)"
 // @Param request body models.UpdateUserRequest true "User details to update"
 // @Success 200 {object} models.SuccessResponse "User updated successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid request"
-// @Failure 404 {object} models.ErrorResponse "User not found"
-// @Failure 409 {object} models.ErrorResponse "Email already exists"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Password does not meet the password policy"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 409 {object} apierror.Problem "Email already exists"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /users/{id} [put]
 func (h *UserHandler) UpdateUser(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := GetUserFromContext(c)
 	if authUser == nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "unauthorized"})
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
 		return
 	}
 
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil || id == uuid.Nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid user id"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
 		return
 	}
 
 	var req models.UpdateUserRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		// Check if it's a JSON parsing error
-		if _, ok := err.(*json.SyntaxError); ok {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request body"})
-			return
-		}
-		// Check for invalid email
-		if req.Email != nil && !auth.IsValidEmail(*req.Email) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid email address"})
-			return
-		}
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid request body"})
+	if !bindJSON(c, &req) {
 		return
 	}
 
@@ -222,43 +331,58 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	user, err := h.userRepo.GetByID(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "user not found"})
+			apierror.Write(c, apierror.UserNotFound, "user not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get user"})
+		apierror.Write(c, apierror.Internal, "failed to get user")
 		return
 	}
 
-	// Check permissions
-	if id != authUser.ID && !authUser.IsAdmin() {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied"})
+	// Check permissions, administrators can only be changed by administrators
+	if id != authUser.ID && !authUser.Can(models.PermissionUsersManage) {
+		apierror.Write(c, apierror.Forbidden, "permission denied")
+		return
+	}
+	if id != authUser.ID && user.IsAdmin() && !authUser.IsAdmin() {
+		apierror.Write(c, apierror.Forbidden, "only admins can change admin users")
 		return
 	}
 
 	// Non-admin users can't update roles or passwords
 	if !authUser.IsAdmin() {
 		if req.RoleID != nil {
-			c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "only admins can change roles"})
+			apierror.Write(c, apierror.Forbidden, "only admins can change roles")
 			return
 		}
 		if req.Password != nil {
-			c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "only admins can change passwords via this endpoint"})
+			apierror.Write(c, apierror.Forbidden, "only admins can change passwords via this endpoint")
 			return
 		}
 	}
 
 	// Update user fields
-	if req.Email != nil {
+	var previousEmail *string
+	emailChanged := req.Email != nil && (user.Email == nil || !strings.EqualFold(*user.Email, *req.Email))
+	if emailChanged {
+		previousEmail = user.Email
 		emailStr := *req.Email
 		user.Email = &emailStr
+		// The new address has to be verified again
+		user.EmailVerified = false
 	}
 	if req.RoleID != nil {
 		user.RoleID = *req.RoleID
 	}
+	if req.Language != nil {
+		user.Language = *req.Language
+	}
 	if req.Password != nil {
+		if !checkPassword(c, h.config, *req.Password, user.Username, user.Email) {
+			return
+		}
 		hashedPassword, err := h.authService.HashPassword(*req.Password)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to hash password"})
+			apierror.Write(c, apierror.Internal, "failed to hash password")
 			return
 		}
 		user.Password = hashedPassword
@@ -267,47 +391,184 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	// Update user
 	if err := h.userRepo.Update(c.Request.Context(), user); err != nil {
 		if errors.Is(err, repository.ErrConflict) {
-			c.JSON(http.StatusConflict, models.ErrorResponse{Error: "email already exists"})
+			apierror.Write(c, apierror.UserExists, "email already exists")
 			return
 		}
 		if errors.Is(err, repository.ErrNotFound) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "user not found"})
+			apierror.Write(c, apierror.UserNotFound, "user not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to update user"})
+		apierror.Write(c, apierror.Internal, "failed to update user")
 		return
 	}
 
+	if emailChanged {
+		h.handleEmailChange(c, authUser, user, previousEmail)
+	}
+
 	c.JSON(http.StatusOK, user)
 }
 
+// handleEmailChange audits an email change, sends a verification link to the new address and
+// a "this wasn't me" link to the previous one. Failures are logged, the change itself stands.
+func (h *UserHandler) handleEmailChange(c *gin.Context, authUser *models.User, user *models.User, previousEmail *string) {
+	ctx := c.Request.Context()
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"changed_by": authUser.ID,
+	})
+	if err := h.auditRepo.Create(ctx, &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      "email_changed",
+		EntityType:  "user",
+		EntityID:    user.ID.String(),
+		Description: fmt.Sprintf("Email address of user %s changed", user.Username),
+		Metadata:    string(details),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+
+	if user.Email != nil {
+		verification, err := h.emailVerifyRepo.Create(ctx, user.ID, h.config.EmailSettings().VerificationTTL)
+		if err != nil {
+			log.Printf("Failed to create email verification: %v", err)
+		} else if err := h.emailService.SendVerificationEmail(*user.Email, user.Username, user.Language, verification.Token, verification.ExpiresAt); err != nil {
+			log.Printf("Failed to send verification email: %v", err)
+		}
+	}
+
+	if previousEmail == nil {
+		return
+	}
+
+	revert, err := h.emailChangeRepo.Create(ctx, user.ID, *previousEmail, user.Email, h.config.EmailSettings().EmailChangeRevertTTL)
+	if err != nil {
+		log.Printf("Failed to create email change revert token: %v", err)
+		return
+	}
+
+	newEmail := ""
+	if user.Email != nil {
+		newEmail = *user.Email
+	}
+	if err := h.emailService.SendEmailChangedNotification(*previousEmail, user.Username, user.Language, newEmail, revert.Token, revert.ExpiresAt); err != nil {
+		log.Printf("Failed to notify previous email address: %v", err)
+	}
+}
+
+// RevertEmailChange godoc
+// @Summary Undo an email change
+// @Description Restores the previous email address using the link sent to it when the address was changed. All sessions of the account are signed out; the user should reset their password afterwards.
+// @Tags auth
+// @Produce json
+// @Param token query string true "Revert token"
+// @Success 200 {object} models.SuccessResponse "Email address restored"
+// @Failure 400 {object} apierror.Problem "Invalid, expired, or missing token"
+// @Failure 409 {object} apierror.Problem "Previous address is now used by another account"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /auth/revert-email-change [get]
+func (h *UserHandler) RevertEmailChange(c *gin.Context) {
+	token := c.Query("token")
+	if token == "" {
+		apierror.Write(c, apierror.InvalidRequest, "revert token is required")
+		return
+	}
+
+	ctx := c.Request.Context()
+	revert, err := h.emailChangeRepo.GetByToken(ctx, token)
+	if err != nil {
+		switch err {
+		case repository.ErrTokenExpired:
+			apierror.Write(c, apierror.InvalidRequest, "revert token has expired")
+		case repository.ErrTokenInvalid:
+			apierror.Write(c, apierror.InvalidRequest, "invalid revert token")
+		default:
+			apierror.Write(c, apierror.Internal, "failed to revert email change")
+		}
+		return
+	}
+
+	user, err := h.userRepo.GetByID(ctx, revert.UserID)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.InvalidRequest, "invalid revert token")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return
+	}
+
+	// The link was delivered to the previous address, so it counts as verified
+	oldEmail := revert.OldEmail
+	user.Email = &oldEmail
+	user.EmailVerified = true
+	if err := h.userRepo.Update(ctx, user); err != nil {
+		if errors.Is(err, repository.ErrConflict) {
+			apierror.Write(c, apierror.UserExists, "email already exists")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to revert email change")
+		return
+	}
+
+	// Consume this and any later revert links so a hijacker can't bounce the address back
+	if err := h.emailChangeRepo.InvalidateForUser(ctx, user.ID); err != nil {
+		log.Printf("Failed to invalidate email change revert tokens: %v", err)
+	}
+
+	if err := h.refreshTokenRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		log.Printf("Failed to revoke sessions after email change revert: %v", err)
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"restored_email": revert.OldEmail,
+	})
+	if err := h.auditRepo.Create(ctx, &models.CreateAuditLogRequest{
+		UserID:      &user.ID,
+		Action:      "email_change_reverted",
+		EntityType:  "user",
+		EntityID:    user.ID.String(),
+		Description: fmt.Sprintf("Email change of user %s reverted from previous address", user.Username),
+		Metadata:    string(details),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "email address restored and all sessions signed out, please reset your password"})
+}
+
 // Delete godoc
 // @Summary Delete user
-// @Description Delete a user. Users can only delete their own account unless they are an admin.
+// @Description Delete a user. Users can only delete their own account unless they are an admin. Users deleting their own account have it deleted once the grace period has passed, and logging in before then cancels the deletion.
 // @Tags users
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "User ID (UUID)"
-// @Success 200 {object} models.SuccessResponse "User deleted successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid user ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - can only delete own account unless admin"
-// @Failure 404 {object} models.ErrorResponse "User not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Success 200 {object} models.SuccessResponse "User deleted, or deletion of own account scheduled"
+// @Failure 400 {object} apierror.Problem "Invalid user ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - can only delete own account unless admin"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Router /users/{id} [delete]
 func (h *UserHandler) DeleteUser(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := GetUserFromContext(c)
 	if authUser == nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "unauthorized"})
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
 		return
 	}
 
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil || id == uuid.Nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid user id"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
 		return
 	}
 
@@ -315,22 +576,28 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	user, err := h.userRepo.GetByID(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "user not found"})
+			apierror.Write(c, apierror.UserNotFound, "user not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get user"})
+		apierror.Write(c, apierror.Internal, "failed to get user")
 		return
 	}
 
 	// Check permissions
-	if id != authUser.ID && !authUser.IsAdmin() {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied - can only delete own account unless admin"})
+	if id != authUser.ID && !authUser.Can(models.PermissionUsersManage) {
+		apierror.Write(c, apierror.Forbidden, "permission denied - can only delete own account unless admin")
 		return
 	}
 
 	// Check if trying to delete an admin user
 	if user.Role != nil && user.Role.IsAdminGroup {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "cannot delete admin user"})
+		apierror.Write(c, apierror.InvalidRequest, "cannot delete admin user")
+		return
+	}
+
+	// Users deleting their own account get a grace period to change their mind
+	if id == authUser.ID && h.config.Auth.DeletionGracePeriod > 0 {
+		h.scheduleDeletion(c, user)
 		return
 	}
 
@@ -351,16 +618,312 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 	// Delete user
 	if err := h.userRepo.Delete(c.Request.Context(), id); err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "user not found"})
+			apierror.Write(c, apierror.UserNotFound, "user not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to delete user"})
+		apierror.Write(c, apierror.Internal, "failed to delete user")
 		return
 	}
 
 	c.JSON(http.StatusOK, models.SuccessResponse{Message: "user deleted successfully"})
 }
 
+// scheduleDeletion schedules the deletion of the user's own account once the grace period
+// has passed, signs out their sessions and tells them by email how to cancel it
+func (h *UserHandler) scheduleDeletion(c *gin.Context, user *models.User) {
+	ctx := c.Request.Context()
+	deleteAt := time.Now().Add(h.config.Auth.DeletionGracePeriod)
+	if err := h.userRepo.ScheduleDeletion(ctx, user.ID, deleteAt); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "user not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to delete user")
+		return
+	}
+
+	if err := h.refreshTokenRepo.DeleteByUserID(ctx, user.ID); err != nil {
+		log.Printf("Failed to revoke sessions of user scheduled for deletion: %v", err)
+	}
+
+	details, _ := json.Marshal(map[string]interface{}{
+		"delete_at": deleteAt.UTC(),
+	})
+	if err := h.auditRepo.Create(ctx, &models.CreateAuditLogRequest{
+		UserID:      &user.ID,
+		Action:      models.AuditActionScheduleDeletion,
+		EntityType:  "user",
+		EntityID:    user.ID.String(),
+		Description: fmt.Sprintf("Deletion of user %s scheduled", user.Username),
+		Metadata:    string(details),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+
+	if user.Email != nil {
+		if err := h.emailService.SendAccountDeletionEmail(*user.Email, user.Username, user.Language, deleteAt); err != nil {
+			log.Printf("Failed to send account deletion email: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{
+		Message: fmt.Sprintf("account will be deleted at %s, log in before then to cancel", deleteAt.UTC().Format(time.RFC3339)),
+	})
+}
+
+// Restore godoc
+// @Summary Restore deleted user
+// @Description Undo the deletion of a user, who can sign in again. Requires the users:manage permission.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} models.User
+// @Failure 400 {object} apierror.Problem "Invalid user ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - users:manage required"
+// @Failure 404 {object} apierror.Problem "Deleted user not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/restore [post]
+func (h *UserHandler) RestoreUser(c *gin.Context) {
+	authUser := GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return
+	}
+
+	if err := h.userRepo.Restore(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "deleted user not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to restore user")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return
+	}
+
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionRestore,
+		EntityType:  "user",
+		EntityID:    id.String(),
+		Description: "User restored",
+		Metadata:    string(`{"user_id":"` + id.String() + `"}`),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging user restore: %v", err)
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// Purge godoc
+// @Summary Purge deleted user
+// @Description Permanently remove a deleted user with its tokens, login history and other data. Audit logs are kept without the user. Requires the users:manage permission.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} models.SuccessResponse "User purged successfully"
+// @Failure 400 {object} apierror.Problem "Invalid user ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - users:manage required"
+// @Failure 404 {object} apierror.Problem "Deleted user not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/purge [delete]
+func (h *UserHandler) PurgeUser(c *gin.Context) {
+	authUser := GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return
+	}
+
+	if err := h.userRepo.HardDelete(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "deleted user not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to purge user")
+		return
+	}
+
+	// Logged after purging, the purge clears the user from earlier audit logs only
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionPurge,
+		EntityType:  "user",
+		EntityID:    id.String(),
+		Description: "User purged",
+		Metadata:    string(`{"user_id":"` + id.String() + `"}`),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging user purge: %v", err)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "user purged successfully"})
+}
+
+// GetLoginAttempts godoc
+// @Summary Get failed login attempts
+// @Description Get the recent failed login attempts of a user, newest first, and whether they lock the account. Requires the users:manage permission.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Param since query string false "Start of the attempts listed (RFC3339), defaults to the lockout window"
+// @Success 200 {object} models.LoginAttemptsResponse
+// @Failure 400 {object} apierror.Problem "Invalid user ID or time"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - users:manage required"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/login-attempts [get]
+func (h *UserHandler) GetLoginAttempts(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return
+	}
+
+	threshold, window := h.lockoutPolicy()
+	now := time.Now()
+	cutoff := now.Add(-window)
+	since := cutoff
+	if value := c.Query("since"); value != "" {
+		if since, err = time.Parse(time.RFC3339, value); err != nil {
+			apierror.Write(c, apierror.InvalidRequest, "invalid since time")
+			return
+		}
+	}
+
+	if _, err := h.userRepo.GetByID(c.Request.Context(), id); err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "user not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return
+	}
+
+	// The lockout is decided by the attempts in the window, even when fewer are listed
+	from := cutoff
+	if since.Before(cutoff) {
+		from = since
+	}
+	attempts, err := h.loginAttemptRepo.ListRecentAttempts(c.Request.Context(), id, from)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to get login attempts")
+		return
+	}
+
+	response := models.LoginAttemptsResponse{Attempts: []models.LoginAttempt{}}
+	recent := 0
+	for _, attempt := range attempts {
+		if !attempt.CreatedAt.Before(cutoff) {
+			recent++
+		}
+		if !attempt.CreatedAt.Before(since) {
+			response.Attempts = append(response.Attempts, attempt)
+		}
+	}
+	if threshold > 0 && recent >= threshold {
+		// The account unlocks once the oldest attempt that still locks it leaves the window
+		lockedUntil := attempts[threshold-1].CreatedAt.Add(window)
+		response.Locked = true
+		response.LockedUntil = &lockedUntil
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// UnlockUser godoc
+// @Summary Unlock user
+// @Description Clear the failed login attempts of a user, lifting a lockout before it expires. Requires the users:manage permission.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} models.SuccessResponse "User unlocked successfully"
+// @Failure 400 {object} apierror.Problem "Invalid user ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - users:manage required"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/unlock [post]
+func (h *UserHandler) UnlockUser(c *gin.Context) {
+	authUser := GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return
+	}
+
+	user, err := h.userRepo.GetByID(c.Request.Context(), id)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "user not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return
+	}
+
+	// Users without recorded attempts have nothing to clear
+	if err := h.loginAttemptRepo.ClearAttempts(c.Request.Context(), id); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.Internal, "failed to clear login attempts")
+		return
+	}
+	if err := h.userRepo.ResetFailedAttempts(c.Request.Context(), user.Username); err != nil {
+		apierror.Write(c, apierror.Internal, "failed to reset failed login attempts")
+		return
+	}
+
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionUnlock,
+		EntityType:  "user",
+		EntityID:    id.String(),
+		Description: fmt.Sprintf("User %s unlocked", user.Username),
+		Metadata:    string(`{"user_id":"` + id.String() + `"}`),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging user unlock: %v", err)
+	}
+
+	c.JSON(http.StatusOK, models.SuccessResponse{Message: "user unlocked successfully"})
+}
+
 // ChangePassword godoc
 // @Summary Change user password
 // @Description Change a user's password (users can only change their own password)
@@ -370,25 +933,27 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 // @Param id path string true "User ID (UUID)"
 // @Param request body models.ChangePasswordRequest true "Password change details"
 // @Success 200 {object} models.SuccessResponse "Password updated successfully"
-// @Failure 400 {object} models.ErrorResponse "Invalid request or password requirements not met"
-// @Failure 403 {object} models.ErrorResponse "Permission denied - can only change own password"
-// @Failure 404 {object} models.ErrorResponse "User not found"
-// @Failure 409 {object} models.ErrorResponse "Password was recently used"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal server error"
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Password does not meet the password policy"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 403 {object} apierror.Problem "Permission denied - can only change own password"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 409 {object} apierror.Problem "Password was recently used"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
 // @Security BearerAuth
 // @Router /users/{id}/password [put]
 func (h *UserHandler) ChangePassword(c *gin.Context) {
 	// Get the authenticated user from context
 	authUser := GetUserFromContext(c)
 	if authUser == nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "unauthorized"})
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
 		return
 	}
 
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil || id == uuid.Nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "invalid user id"})
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
 		return
 	}
 
@@ -396,61 +961,64 @@ func (h *UserHandler) ChangePassword(c *gin.Context) {
 	user, err := h.userRepo.GetByID(c.Request.Context(), id)
 	if err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "user not found"})
+			apierror.Write(c, apierror.UserNotFound, "user not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to get user"})
+		apierror.Write(c, apierror.Internal, "failed to get user")
 		return
 	}
 
 	// Only allow users to change their own password through this endpoint
 	if id != authUser.ID {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "permission denied"})
+		apierror.Write(c, apierror.Forbidden, "permission denied")
 		return
 	}
 
 	// Admin users should use the update endpoint to change passwords
 	if authUser.IsAdmin() {
-		c.JSON(http.StatusForbidden, models.ErrorResponse{Error: "admins must use the user update endpoint to change passwords"})
+		apierror.Write(c, apierror.Forbidden, "admins must use the user update endpoint to change passwords")
 		return
 	}
 
 	var req models.ChangePasswordRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: err.Error()})
+	if !bindJSON(c, &req) {
 		return
 	}
 
 	// Verify current password
 	if err := h.authService.ComparePasswords(user.Password, req.CurrentPassword); err != nil {
-		c.JSON(http.StatusUnauthorized, models.ErrorResponse{Error: "invalid current password"})
+		apierror.Write(c, apierror.InvalidCredentials, "invalid current password")
+		return
+	}
+
+	if !checkPassword(c, h.config, req.NewPassword, user.Username, user.Email) {
 		return
 	}
 
 	// Hash new password
 	hashedPassword, err := h.authService.HashPassword(req.NewPassword)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to hash password"})
+		apierror.Write(c, apierror.Internal, "failed to hash password")
 		return
 	}
 
 	// Check password history
-	if err := h.passwordHistory.CheckReuse(c.Request.Context(), id, req.NewPassword); err != nil {
+	if err := h.passwordHistory.CheckReuse(c.Request.Context(), id, req.NewPassword, h.passwordHistoryDepth()); err != nil {
 		if errors.Is(err, repository.ErrPasswordReuse) {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "password was recently used"})
+			apierror.Write(c, apierror.InvalidRequest, "password was recently used")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to check password history"})
+		apierror.Write(c, apierror.Internal, "failed to check password history")
 		return
 	}
 
 	// Update password
 	if err := h.userRepo.UpdatePassword(c.Request.Context(), id, hashedPassword); err != nil {
 		if errors.Is(err, repository.ErrUserNotFound) {
-			c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "user not found"})
+			apierror.Write(c, apierror.UserNotFound, "user not found")
 			return
 		}
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "failed to update password"})
+		apierror.Write(c, apierror.Internal, "failed to update password")
 		return
 	}
 
@@ -0,0 +1,137 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UserExportHandler handles downloading all the data held about a user
+type UserExportHandler struct {
+	userRepo        repository.UserRepository
+	preferenceRepo  repository.UserPreferenceRepository
+	consumptionRepo repository.ConsumptionRepository
+	auditRepo       repository.AuditLogRepository
+	// productionRepo holds the energy the user produced, there is none when it is nil
+	productionRepo repository.ProductionRepository
+}
+
+// NewUserExportHandler creates a new UserExportHandler
+func NewUserExportHandler(
+	userRepo repository.UserRepository,
+	preferenceRepo repository.UserPreferenceRepository,
+	consumptionRepo repository.ConsumptionRepository,
+	auditRepo repository.AuditLogRepository,
+) *UserExportHandler {
+	return &UserExportHandler{
+		userRepo:        userRepo,
+		preferenceRepo:  preferenceRepo,
+		consumptionRepo: consumptionRepo,
+		auditRepo:       auditRepo,
+	}
+}
+
+// SetProductionRepository includes the user's production readings in exports
+func (h *UserExportHandler) SetProductionRepository(repo repository.ProductionRepository) {
+	h.productionRepo = repo
+}
+
+// ExportUser godoc
+// @Summary Export user data
+// @Description Download a JSON archive of the profile, preferences, consumption and audit trail of a user. Users can export their own data, others require the users:manage permission.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} models.UserExport
+// @Failure 400 {object} apierror.Problem "Invalid user ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/export [get]
+func (h *UserExportHandler) ExportUser(c *gin.Context) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return
+	}
+
+	authUser := GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+	if id != authUser.ID && !authUser.Can(models.PermissionUsersManage) {
+		apierror.Write(c, apierror.Forbidden, "permission denied")
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.userRepo.GetByID(ctx, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrUserNotFound) {
+			apierror.Write(c, apierror.UserNotFound, "user not found")
+			return
+		}
+		apierror.Write(c, apierror.Internal, "failed to get user")
+		return
+	}
+
+	export := models.UserExport{ExportedAt: time.Now().UTC(), User: *user}
+	if export.Preferences, err = h.preferenceRepo.Get(ctx, id); err != nil {
+		log.Printf("Error exporting user preferences: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to export user")
+		return
+	}
+	if export.Consumption, err = h.consumptionRepo.List(ctx, repository.ConsumptionFilter{UserID: id}); err != nil {
+		log.Printf("Error exporting consumption: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to export user")
+		return
+	}
+	if h.productionRepo != nil {
+		if export.Production, err = h.productionRepo.List(ctx, repository.ProductionFilter{UserID: id}); err != nil {
+			log.Printf("Error exporting production: %v", err)
+			apierror.Write(c, apierror.Internal, "failed to export user")
+			return
+		}
+	}
+	if export.AuditLogs, err = h.auditRepo.List(ctx, repository.AuditLogFilter{UserID: &id, OrderBy: "created_at"}); err != nil {
+		log.Printf("Error exporting audit logs: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to export user")
+		return
+	}
+	if export.Consumption == nil {
+		export.Consumption = []models.ConsumptionRecord{}
+	}
+	if export.Production == nil {
+		export.Production = []models.ProductionRecord{}
+	}
+	if export.AuditLogs == nil {
+		export.AuditLogs = []models.AuditLog{}
+	}
+
+	if err := h.auditRepo.Create(ctx, &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionExport,
+		EntityType:  "user",
+		EntityID:    id.String(),
+		Description: fmt.Sprintf("Data of user %s exported", user.Username),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="wattwatch-user-%s.json"`, id))
+	c.JSON(http.StatusOK, export)
+}
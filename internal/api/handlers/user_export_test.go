@@ -0,0 +1,79 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserExportHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	ctx := context.Background()
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+	other := tc.CreateTestUser("other", "other@test.com", "password123", false)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	hour := time.Now().UTC().Truncate(time.Hour)
+	require.NoError(t, tc.ConsumptionRepo.CreateBatch(ctx, []models.ConsumptionRecord{
+		{UserID: user.ID, MeterID: "meter-1", Timestamp: hour.Add(-time.Hour), KWh: 1.5},
+		{UserID: user.ID, MeterID: "meter-1", Timestamp: hour, KWh: 2},
+		{UserID: other.ID, MeterID: "meter-2", Timestamp: hour, KWh: 3},
+	}))
+	require.NoError(t, tc.AuditRepo.Create(ctx, &models.CreateAuditLogRequest{
+		UserID: &user.ID, Action: models.AuditActionLogin, EntityType: "user", EntityID: user.ID.String(), Description: "Logged in",
+	}))
+	zone := "SE3"
+	require.NoError(t, tc.UserPreferenceRepo.Upsert(ctx, &models.UserPreferences{UserID: user.ID, Zone: &zone, ZoneID: zoneID(t, tc, zone)}))
+
+	handler := handlers.NewUserExportHandler(tc.UserRepo, tc.UserPreferenceRepo, tc.ConsumptionRepo, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.GET("/users/:id/export", authMiddleware.AuthRequired(), handler.ExportUser)
+
+	send := func(id uuid.UUID, as *models.User) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/users/"+id.String()+"/export", nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(as.ID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := send(user.ID, user)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Contains(t, w.Header().Get("Content-Disposition"), "attachment")
+
+	var export models.UserExport
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &export))
+	assert.Equal(t, user.ID, export.User.ID)
+	require.NotNil(t, export.Preferences.Zone)
+	assert.Equal(t, "SE3", *export.Preferences.Zone)
+	require.Len(t, export.Consumption, 2)
+	assert.Equal(t, 1.5, export.Consumption[0].KWh)
+	require.NotEmpty(t, export.AuditLogs)
+	assert.Equal(t, models.AuditActionLogin, export.AuditLogs[0].Action)
+
+	// Others' data takes the users:manage permission
+	assert.Equal(t, http.StatusForbidden, send(user.ID, other).Code)
+	assert.Equal(t, http.StatusOK, send(user.ID, admin).Code)
+	assert.Equal(t, http.StatusNotFound, send(uuid.New(), admin).Code)
+}
+
+// zoneID returns the ID of the named zone
+func zoneID(t *testing.T, tc *testutil.TestContext, name string) *uuid.UUID {
+	t.Helper()
+	zone, err := tc.ZoneRepo.GetByName(context.Background(), name)
+	require.NoError(t, err)
+	return &zone.ID
+}
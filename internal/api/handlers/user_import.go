@@ -0,0 +1,511 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/mail"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/config"
+	"wattwatch/internal/email"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// MaxUserImportRows is the number of users a single import may create
+const MaxUserImportRows = 1000
+
+// userExportPageSize is the number of users read from the database at a time while exporting
+const userExportPageSize = 500
+
+// userImportColumns are the CSV columns an import understands, username is required
+var userImportColumns = map[string]bool{"username": true, "email": true, "role": true}
+
+// userExportHeader is the first line of an export
+var userExportHeader = []string{"id", "username", "email", "email_verified", "role", "language", "created_at", "last_login_at"}
+
+// errUserImportInput is wrapped by errors in the uploaded file
+var errUserImportInput = errors.New("invalid import")
+
+// UserImportHandler moves users in and out of WattWatch in bulk, for migrations from other
+// systems
+type UserImportHandler struct {
+	userRepo          repository.UserRepository
+	roleRepo          repository.RoleRepository
+	passwordResetRepo repository.PasswordResetRepository
+	authService       *auth.Service
+	auditRepo         repository.AuditLogRepository
+	emailService      email.EmailSender
+	settings          *settings.Store
+	config            *config.Config
+}
+
+// NewUserImportHandler creates a new UserImportHandler
+func NewUserImportHandler(
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	passwordResetRepo repository.PasswordResetRepository,
+	authService *auth.Service,
+	auditRepo repository.AuditLogRepository,
+	emailService email.EmailSender,
+	settings *settings.Store,
+	config *config.Config,
+) *UserImportHandler {
+	return &UserImportHandler{
+		userRepo:          userRepo,
+		roleRepo:          roleRepo,
+		passwordResetRepo: passwordResetRepo,
+		authService:       authService,
+		auditRepo:         auditRepo,
+		emailService:      emailService,
+		settings:          settings,
+		config:            config,
+	}
+}
+
+// ImportUsers godoc
+// @Summary Import users
+// @Description Creates users from a CSV file with a header row (columns username, email and role) or a JSON array. Every row is validated on its own, valid rows are created even when others fail. Imported users get no usable password and their email addresses are trusted as verified, so they choose a password through the invitation or a password reset. Roles default to the role of new users. At most 1000 users per import. (admin only)
+// @Tags users
+// @Accept json
+// @Accept text/csv
+// @Produce json
+// @Security BearerAuth
+// @Param users body []models.UserImportRow true "Users to create"
+// @Param send_invites query bool false "Email each created user with an email address a link to choose their password"
+// @Param dry_run query bool false "Validate the rows without creating any user"
+// @Success 200 {object} models.UserImportResponse
+// @Failure 400 {object} apierror.Problem "Invalid file or query parameter, or too many rows"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 415 {object} apierror.Problem "Content type is neither CSV nor JSON"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/import [post]
+func (h *UserImportHandler) ImportUsers(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	sendInvites, err := strconv.ParseBool(c.DefaultQuery("send_invites", "false"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid send_invites parameter")
+		return
+	}
+	dryRun, err := strconv.ParseBool(c.DefaultQuery("dry_run", "false"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid dry_run parameter")
+		return
+	}
+
+	var rows []models.UserImportRow
+	var lines []int
+	switch c.ContentType() {
+	case "text/csv":
+		rows, lines, err = readUserImportCSV(c.Request.Body)
+	case "application/json":
+		rows, err = readUserImportJSON(c.Request.Body)
+	default:
+		apierror.Write(c, apierror.UnsupportedMediaType, "content type must be text/csv or application/json")
+		return
+	}
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	if len(rows) == 0 {
+		apierror.Write(c, apierror.InvalidRequest, "no users to import")
+		return
+	}
+
+	// Imported users have no password until they choose one. A single random password is
+	// hashed for the whole import, as bcrypt is too slow to hash one per row, and it is
+	// never revealed.
+	password, err := randomPassword()
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to import users")
+		return
+	}
+	hashedPassword, err := h.authService.HashPassword(password)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to import users")
+		return
+	}
+
+	importer := &userImporter{
+		handler:     h,
+		c:           c,
+		roles:       make(map[string]*models.Role),
+		usernames:   make(map[string]bool),
+		emails:      make(map[string]bool),
+		defaultRole: h.settings.DefaultRole(),
+	}
+	response := models.UserImportResponse{DryRun: dryRun, Results: make([]models.UserImportResult, 0, len(rows))}
+	var created []string
+	for i, row := range rows {
+		line := i + 1
+		if lines != nil {
+			line = lines[i]
+		}
+		result, user, err := importer.importRow(line, row, hashedPassword, dryRun)
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to import users")
+			return
+		}
+		if user != nil {
+			created = append(created, user.Username)
+			if sendInvites && user.Email != nil {
+				if err := h.invite(c, user); err != nil {
+					log.Printf("Failed to invite imported user %s: %v", user.ID, err)
+					result.Errors = append(result.Errors, "failed to send invitation")
+				} else {
+					result.Invited = true
+					response.Invited++
+				}
+			}
+		}
+
+		switch result.Status {
+		case models.UserImportCreated:
+			response.Created++
+		case models.UserImportFailed:
+			response.Failed++
+		}
+		response.Results = append(response.Results, result)
+	}
+
+	if !dryRun {
+		metadata, _ := json.Marshal(map[string]interface{}{
+			"created":   response.Created,
+			"failed":    response.Failed,
+			"invited":   response.Invited,
+			"usernames": created,
+		})
+		if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+			UserID:      &authUser.ID,
+			Action:      models.AuditActionImport,
+			EntityType:  "user",
+			Description: fmt.Sprintf("Imported %d users, %d failed", response.Created, response.Failed),
+			Metadata:    string(metadata),
+			IPAddress:   c.ClientIP(),
+			UserAgent:   c.GetHeader("User-Agent"),
+		}); err != nil {
+			log.Printf("Failed to create audit log: %v", err)
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// invite emails an imported user a link to choose their password
+func (h *UserImportHandler) invite(c *gin.Context, user *models.User) error {
+	reset, err := h.passwordResetRepo.Create(c.Request.Context(), user.ID, h.config.EmailSettings().InviteTTL)
+	if err != nil {
+		return err
+	}
+	return h.emailService.SendInviteEmail(*user.Email, user.Username, user.Language, reset.Token, reset.ExpiresAt)
+}
+
+// userImporter validates and creates the rows of one import, remembering the roles looked
+// up and the usernames and email addresses seen so far
+type userImporter struct {
+	handler     *UserImportHandler
+	c           *gin.Context
+	roles       map[string]*models.Role // nil for names without a role
+	usernames   map[string]bool
+	emails      map[string]bool
+	defaultRole string
+}
+
+// importRow validates row and creates its user unless dryRun is set. It returns the user
+// when one was created. Errors are only returned when the import can't go on, problems
+// with the row are reported in the result.
+func (i *userImporter) importRow(line int, row models.UserImportRow, hashedPassword string, dryRun bool) (models.UserImportResult, *models.User, error) {
+	ctx := i.c.Request.Context()
+	username := strings.TrimSpace(row.Username)
+	result := models.UserImportResult{Row: line, Username: username}
+
+	if n := utf8.RuneCountInString(username); n < 3 || n > 50 {
+		result.Errors = append(result.Errors, "username must be 3 to 50 characters")
+	} else if i.usernames[username] {
+		result.Errors = append(result.Errors, "username appears more than once in the import")
+	} else {
+		i.usernames[username] = true
+		if _, err := i.handler.userRepo.GetByUsername(ctx, username); err == nil {
+			result.Errors = append(result.Errors, "username already exists")
+		} else if !errors.Is(err, repository.ErrUserNotFound) {
+			return result, nil, err
+		}
+	}
+
+	var address *string
+	if row.Email != nil && strings.TrimSpace(*row.Email) != "" {
+		value := strings.TrimSpace(*row.Email)
+		key := strings.ToLower(value)
+		if parsed, err := mail.ParseAddress(value); err != nil || parsed.Address != value {
+			result.Errors = append(result.Errors, "email is not a valid address")
+		} else if i.emails[key] {
+			result.Errors = append(result.Errors, "email appears more than once in the import")
+		} else {
+			i.emails[key] = true
+			address = &value
+			if _, err := i.handler.userRepo.GetByEmail(ctx, value); err == nil {
+				result.Errors = append(result.Errors, "email already exists")
+			} else if !errors.Is(err, repository.ErrUserNotFound) {
+				return result, nil, err
+			}
+		}
+	}
+
+	roleName := strings.TrimSpace(row.Role)
+	if roleName == "" {
+		roleName = i.defaultRole
+	}
+	role, err := i.role(roleName)
+	if err != nil {
+		return result, nil, err
+	}
+	if role == nil {
+		result.Errors = append(result.Errors, fmt.Sprintf("role %q does not exist", roleName))
+	}
+
+	if len(result.Errors) > 0 {
+		result.Status = models.UserImportFailed
+		return result, nil, nil
+	}
+	if dryRun {
+		result.Status = models.UserImportValid
+		return result, nil, nil
+	}
+
+	// The addresses come from the system being migrated from, where they were in use
+	user := &models.User{
+		Username:      username,
+		Password:      hashedPassword,
+		Email:         address,
+		EmailVerified: address != nil,
+		RoleID:        role.ID,
+		Role:          role,
+	}
+	if err := i.handler.userRepo.Create(ctx, user); err != nil {
+		log.Printf("Failed to create imported user %s: %v", username, err)
+		result.Status = models.UserImportFailed
+		result.Errors = append(result.Errors, "failed to create user")
+		return result, nil, nil
+	}
+	result.Status = models.UserImportCreated
+	result.UserID = &user.ID
+	return result, user, nil
+}
+
+// role returns the role with name, nil when there is none
+func (i *userImporter) role(name string) (*models.Role, error) {
+	if role, ok := i.roles[name]; ok {
+		return role, nil
+	}
+	role, err := i.handler.roleRepo.GetByName(i.c.Request.Context(), name)
+	if errors.Is(err, repository.ErrNotFound) {
+		role, err = nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	i.roles[name] = role
+	return role, nil
+}
+
+// ExportUsers godoc
+// @Summary Export users
+// @Description Streams the users that aren't deleted as CSV, ordered by username, with the columns id, username, email, email_verified, role, language, created_at and last_login_at. Times are RFC 3339 in UTC. (admin only)
+// @Tags users
+// @Produce text/csv
+// @Security BearerAuth
+// @Success 200 {string} string "CSV file"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/export [get]
+func (h *UserImportHandler) ExportUsers(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	ctx := c.Request.Context()
+	limit := userExportPageSize
+	offset := 0
+	filter := repository.UserFilter{Limit: &limit, Offset: &offset}
+
+	// The first page is read before responding, so a failing database still gets a 500
+	users, err := h.userRepo.List(ctx, filter)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to list users")
+		return
+	}
+
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", `attachment; filename="users.csv"`)
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	_ = w.Write(userExportHeader)
+	exported := 0
+	for {
+		for _, user := range users {
+			_ = w.Write(userExportRecord(user))
+		}
+		exported += len(users)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			log.Printf("Failed to write user export: %v", err)
+			return
+		}
+		c.Writer.Flush()
+
+		if len(users) < limit {
+			break
+		}
+		offset += limit
+		if users, err = h.userRepo.List(ctx, filter); err != nil {
+			// The status was sent, the client sees a truncated file
+			log.Printf("Failed to list users for export: %v", err)
+			return
+		}
+	}
+
+	if err := h.auditRepo.Create(ctx, &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      models.AuditActionExport,
+		EntityType:  "user",
+		Description: fmt.Sprintf("Exported %d users", exported),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Failed to create audit log: %v", err)
+	}
+}
+
+// userExportRecord returns the CSV fields of user, in the order of userExportHeader
+func userExportRecord(user models.User) []string {
+	var address, role, lastLogin string
+	if user.Email != nil {
+		address = *user.Email
+	}
+	if user.Role != nil {
+		role = user.Role.Name
+	}
+	if user.LastLoginAt != nil {
+		lastLogin = user.LastLoginAt.UTC().Format(time.RFC3339)
+	}
+	return []string{
+		user.ID.String(),
+		user.Username,
+		address,
+		strconv.FormatBool(user.EmailVerified),
+		role,
+		user.Language,
+		user.CreatedAt.UTC().Format(time.RFC3339),
+		lastLogin,
+	}
+}
+
+// readUserImportCSV reads the rows of a CSV import along with the line each starts on
+func readUserImportCSV(r io.Reader) ([]models.UserImportRow, []int, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, nil, nil
+	}
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", errUserImportInput, err)
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		// Spreadsheets often start UTF-8 files with a byte order mark
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !userImportColumns[name] {
+			return nil, nil, fmt.Errorf("%w: unknown column %q, expected username, email and role", errUserImportInput, name)
+		}
+		if _, ok := columns[name]; ok {
+			return nil, nil, fmt.Errorf("%w: column %q appears more than once", errUserImportInput, name)
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["username"]; !ok {
+		return nil, nil, fmt.Errorf("%w: missing username column", errUserImportInput)
+	}
+
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok {
+			return record[i]
+		}
+		return ""
+	}
+
+	var rows []models.UserImportRow
+	var lines []int
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", errUserImportInput, err)
+		}
+		if len(rows) == MaxUserImportRows {
+			return nil, nil, fmt.Errorf("%w: at most %d users can be imported at once", errUserImportInput, MaxUserImportRows)
+		}
+		line, _ := reader.FieldPos(0)
+		row := models.UserImportRow{Username: field(record, "username"), Role: field(record, "role")}
+		if address := field(record, "email"); address != "" {
+			row.Email = &address
+		}
+		rows = append(rows, row)
+		lines = append(lines, line)
+	}
+	return rows, lines, nil
+}
+
+// readUserImportJSON reads the rows of a JSON import
+func readUserImportJSON(r io.Reader) ([]models.UserImportRow, error) {
+	var rows []models.UserImportRow
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("%w: %v", errUserImportInput, err)
+	}
+	if len(rows) > MaxUserImportRows {
+		return nil, fmt.Errorf("%w: at most %d users can be imported at once", errUserImportInput, MaxUserImportRows)
+	}
+	return rows, nil
+}
+
+// randomPassword returns a password nobody knows
+func randomPassword() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
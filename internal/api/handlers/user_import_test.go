@@ -0,0 +1,159 @@
+package handlers_test
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserImportHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	handler := handlers.NewUserImportHandler(tc.UserRepo, tc.RoleRepo, tc.PasswordResetRepo, tc.AuthService, tc.AuditRepo, tc.EmailService, tc.Settings, tc.Config)
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+
+	router := gin.New()
+	router.POST("/users/import", authMiddleware.AuthRequired(), authMiddleware.AdminRequired(), handler.ImportUsers)
+	router.GET("/users/export", authMiddleware.AuthRequired(), authMiddleware.AdminRequired(), handler.ExportUsers)
+
+	send := func(method, path, contentType, body, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", contentType)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	importUsers := func(t *testing.T, query, contentType, body string) models.UserImportResponse {
+		t.Helper()
+		w := send(http.MethodPost, "/users/import"+query, contentType, body, tc.GetTestJWT(admin.ID))
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var resp models.UserImportResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp
+	}
+	adminToken := tc.GetTestJWT(admin.ID)
+
+	t.Run("Requires Admin", func(t *testing.T) {
+		w := send(http.MethodPost, "/users/import", "text/csv", "username\nalice\n", tc.GetTestJWT(user.ID))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		w = send(http.MethodGet, "/users/export", "", "", tc.GetTestJWT(user.ID))
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Invalid Files", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnsupportedMediaType, send(http.MethodPost, "/users/import", "text/plain", "alice", adminToken).Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/users/import", "text/csv", "email\nalice@test.com\n", adminToken).Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/users/import", "text/csv", "username,password\nalice,secret\n", adminToken).Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/users/import", "text/csv", "username\n", adminToken).Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/users/import", "application/json", `{"username":"alice"}`, adminToken).Code)
+		assert.Equal(t, http.StatusBadRequest, send(http.MethodPost, "/users/import?dry_run=maybe", "application/json", `[]`, adminToken).Code)
+	})
+
+	t.Run("Dry Run", func(t *testing.T) {
+		resp := importUsers(t, "?dry_run=true", "text/csv", "username,email\nalice,alice@test.com\n")
+		assert.True(t, resp.DryRun)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, models.UserImportValid, resp.Results[0].Status)
+		assert.Equal(t, 0, resp.Created)
+
+		_, err := tc.UserRepo.GetByUsername(context.Background(), "alice")
+		assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	})
+
+	t.Run("CSV", func(t *testing.T) {
+		csvFile := "\ufeffUsername, Email, Role\n" +
+			"alice,alice@test.com,\n" +
+			"bob,,admin\n" +
+			"carol,not-an-email,user\n" +
+			"dave,dave@test.com,nobody\n" +
+			"alice,alice2@test.com,user\n" +
+			"user,new@test.com,user\n" +
+			"erin,user@test.com,user\n" +
+			"x,,user\n"
+		resp := importUsers(t, "?send_invites=true", "text/csv", csvFile)
+		assert.False(t, resp.DryRun)
+		assert.Equal(t, 2, resp.Created)
+		assert.Equal(t, 6, resp.Failed)
+		assert.Equal(t, 1, resp.Invited, "bob has no email address")
+		require.Len(t, resp.Results, 8)
+
+		alice := resp.Results[0]
+		assert.Equal(t, 2, alice.Row)
+		assert.Equal(t, models.UserImportCreated, alice.Status)
+		assert.True(t, alice.Invited)
+		require.NotNil(t, alice.UserID)
+		created, err := tc.UserRepo.GetByID(context.Background(), *alice.UserID)
+		require.NoError(t, err)
+		assert.True(t, created.EmailVerified)
+		assert.Equal(t, tc.Settings.DefaultRole(), created.Role.Name)
+		assert.Error(t, tc.AuthService.ComparePasswords(created.Password, ""))
+
+		bob := resp.Results[1]
+		assert.Equal(t, models.UserImportCreated, bob.Status)
+		assert.False(t, bob.Invited)
+
+		wantErrors := map[string]string{
+			"carol": "email is not a valid address",
+			"dave":  `role "nobody" does not exist`,
+			"alice": "username appears more than once in the import",
+			"user":  "username already exists",
+			"erin":  "email already exists",
+			"x":     "username must be 3 to 50 characters",
+		}
+		for i, result := range resp.Results[2:] {
+			assert.Equal(t, i+4, result.Row)
+			assert.Equal(t, models.UserImportFailed, result.Status, result.Username)
+			assert.Equal(t, []string{wantErrors[result.Username]}, result.Errors, result.Username)
+		}
+
+		logs, err := tc.AuditRepo.List(context.Background(), repository.AuditLogFilter{
+			Actions: []models.AuditAction{models.AuditActionImport},
+		})
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, &admin.ID, logs[0].UserID)
+	})
+
+	t.Run("JSON", func(t *testing.T) {
+		resp := importUsers(t, "", "application/json", `[{"username":"frank","email":"frank@test.com","role":"user"}]`)
+		require.Len(t, resp.Results, 1)
+		assert.Equal(t, 1, resp.Results[0].Row)
+		assert.Equal(t, models.UserImportCreated, resp.Results[0].Status)
+		assert.False(t, resp.Results[0].Invited)
+	})
+
+	t.Run("Export", func(t *testing.T) {
+		w := send(http.MethodGet, "/users/export", "", "", adminToken)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+
+		records, err := csv.NewReader(w.Body).ReadAll()
+		require.NoError(t, err)
+		require.NotEmpty(t, records)
+		assert.Equal(t, []string{"id", "username", "email", "email_verified", "role", "language", "created_at", "last_login_at"}, records[0])
+
+		var usernames []string
+		for _, record := range records[1:] {
+			usernames = append(usernames, record[1])
+		}
+		assert.Equal(t, []string{"admin", "alice", "bob", "frank", "user"}, usernames)
+		assert.Equal(t, "alice@test.com", records[2][2])
+		assert.Equal(t, "true", records[2][3])
+	})
+}
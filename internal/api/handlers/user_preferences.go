@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UserPreferenceHandler handles the defaults users pick for zones, currencies, timezones
+// and locales
+type UserPreferenceHandler struct {
+	preferenceRepo repository.UserPreferenceRepository
+	zoneRepo       repository.ZoneRepository
+	currencyRepo   repository.CurrencyRepository
+}
+
+// NewUserPreferenceHandler creates a new UserPreferenceHandler
+func NewUserPreferenceHandler(preferenceRepo repository.UserPreferenceRepository, zoneRepo repository.ZoneRepository, currencyRepo repository.CurrencyRepository) *UserPreferenceHandler {
+	return &UserPreferenceHandler{
+		preferenceRepo: preferenceRepo,
+		zoneRepo:       zoneRepo,
+		currencyRepo:   currencyRepo,
+	}
+}
+
+// GetPreferences godoc
+// @Summary Get user preferences
+// @Description Returns the default zone, currency and timezone of a user and the locale of their emails. Users can get their own preferences, others require the users:manage permission.
+// @Tags users
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Success 200 {object} models.UserPreferences
+// @Failure 400 {object} apierror.Problem "Invalid user ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied"
+// @Failure 404 {object} apierror.Problem "User not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/preferences [get]
+func (h *UserPreferenceHandler) GetPreferences(c *gin.Context) {
+	id, ok := h.userID(c)
+	if !ok {
+		return
+	}
+
+	prefs, err := h.preferenceRepo.Get(c.Request.Context(), id)
+	if err != nil {
+		h.respondError(c, err, "failed to get preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// UpdatePreferences godoc
+// @Summary Update user preferences
+// @Description Replaces the default zone, currency and timezone of a user and whether they get the weekly report, those left out are cleared. The locale is kept when left out. The weekly report emails the consumption and cost of each meter in the default zone and currency, so it needs both. Spot price endpoints called without a zone or currency use the defaults, and listed spot prices have their timestamps in the timezone. Users can update their own preferences, others require the users:manage permission.
+// @Tags users
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "User ID (UUID)"
+// @Param request body models.UpdateUserPreferencesRequest true "Preferences"
+// @Success 200 {object} models.UserPreferences
+// @Failure 400 {object} apierror.Problem "Invalid user ID or timezone, or weekly report without a default zone and currency"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied"
+// @Failure 404 {object} apierror.Problem "User, zone or currency not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /users/{id}/preferences [put]
+func (h *UserPreferenceHandler) UpdatePreferences(c *gin.Context) {
+	id, ok := h.userID(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserPreferencesRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	prefs := &models.UserPreferences{UserID: id}
+	if req.Zone != nil {
+		zone, err := h.zoneRepo.GetByName(c.Request.Context(), *req.Zone)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.ZoneNotFound, "zone not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch zone")
+			return
+		}
+		prefs.ZoneID, prefs.Zone = &zone.ID, &zone.Name
+	}
+	if req.Currency != nil {
+		currency, err := h.currencyRepo.GetByName(c.Request.Context(), *req.Currency)
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.CurrencyNotFound, "currency not found")
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to fetch currency")
+			return
+		}
+		prefs.CurrencyID, prefs.Currency = &currency.ID, &currency.Name
+	}
+	if req.Timezone != nil {
+		// LoadLocation takes "" and "Local" for UTC and the server's timezone
+		if _, err := time.LoadLocation(*req.Timezone); err != nil || *req.Timezone == "" || *req.Timezone == "Local" {
+			apierror.Write(c, apierror.InvalidRequest, "invalid timezone, use an IANA name such as Europe/Stockholm")
+			return
+		}
+		prefs.Timezone = req.Timezone
+	}
+	if req.Locale != nil {
+		prefs.Locale = *req.Locale
+	}
+	if req.WeeklyReport && (prefs.ZoneID == nil || prefs.CurrencyID == nil) {
+		apierror.Write(c, apierror.InvalidRequest, "the weekly report needs a default zone and currency")
+		return
+	}
+	prefs.WeeklyReport = req.WeeklyReport
+
+	if err := h.preferenceRepo.Upsert(c.Request.Context(), prefs); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			// The zone or currency was deleted meanwhile
+			apierror.Write(c, apierror.InvalidRequest, "zone or currency no longer exists")
+			return
+		}
+		h.respondError(c, err, "failed to update preferences")
+		return
+	}
+
+	c.JSON(http.StatusOK, prefs)
+}
+
+// userID parses the user ID of the request and checks that the authenticated user may
+// manage that user's preferences, writing the error response if not
+func (h *UserPreferenceHandler) userID(c *gin.Context) (uuid.UUID, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil || id == uuid.Nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid user id")
+		return uuid.Nil, false
+	}
+
+	authUser := GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return uuid.Nil, false
+	}
+	if id != authUser.ID && !authUser.Can(models.PermissionUsersManage) {
+		apierror.Write(c, apierror.Forbidden, "permission denied")
+		return uuid.Nil, false
+	}
+	return id, true
+}
+
+func (h *UserPreferenceHandler) respondError(c *gin.Context, err error, message string) {
+	if errors.Is(err, repository.ErrUserNotFound) {
+		apierror.Write(c, apierror.UserNotFound, "user not found")
+		return
+	}
+	log.Printf("Error handling user preferences: %v", err)
+	apierror.Write(c, apierror.Internal, message)
+}
@@ -0,0 +1,156 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserPreferenceHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+	other := tc.CreateTestUser("other", "other@test.com", "password123", false)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+
+	handler := handlers.NewUserPreferenceHandler(tc.UserPreferenceRepo, tc.ZoneRepo, tc.CurrencyRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired())
+	router.GET("/users/:id/preferences", handler.GetPreferences)
+	router.PUT("/users/:id/preferences", handler.UpdatePreferences)
+
+	send := func(method string, id uuid.UUID, body string, as *models.User) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, "/users/"+id.String()+"/preferences", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(as.ID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	decode := func(w *httptest.ResponseRecorder) models.UserPreferences {
+		var prefs models.UserPreferences
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prefs))
+		return prefs
+	}
+
+	w := send("GET", user.ID, "", user)
+	require.Equal(t, http.StatusOK, w.Code)
+	prefs := decode(w)
+	assert.Nil(t, prefs.Zone)
+	assert.Equal(t, models.DefaultLanguage, prefs.Locale)
+
+	w = send("PUT", user.ID, `{"zone":"SE3","currency":"SEK","timezone":"Europe/Stockholm","locale":"sv"}`, user)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	prefs = decode(w)
+	assert.Equal(t, "SE3", *prefs.Zone)
+	assert.Equal(t, "SEK", *prefs.Currency)
+	assert.Equal(t, "Europe/Stockholm", *prefs.Timezone)
+	assert.Equal(t, models.LanguageSwedish, prefs.Locale)
+
+	// The locale is the language emails are sent in
+	stored, err := tc.UserRepo.GetByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.LanguageSwedish, stored.Language)
+
+	tests := []struct {
+		name       string
+		id         uuid.UUID
+		body       string
+		as         *models.User
+		wantStatus int
+	}{
+		{"Unknown Zone", user.ID, `{"zone":"XX9"}`, user, http.StatusNotFound},
+		{"Unknown Currency", user.ID, `{"currency":"XXX"}`, user, http.StatusNotFound},
+		{"Invalid Timezone", user.ID, `{"timezone":"Mars/Olympus"}`, user, http.StatusBadRequest},
+		{"Local Timezone", user.ID, `{"timezone":"Local"}`, user, http.StatusBadRequest},
+		{"Invalid Locale", user.ID, `{"locale":"xx"}`, user, http.StatusBadRequest},
+		{"Weekly Report Without Currency", user.ID, `{"zone":"SE3","weekly_report":true}`, user, http.StatusBadRequest},
+		{"Other User", other.ID, `{"zone":"SE1"}`, user, http.StatusForbidden},
+		{"Unknown User", uuid.New(), `{}`, admin, http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.wantStatus, send("PUT", tt.id, tt.body, tt.as).Code)
+		})
+	}
+
+	// Admins manage the preferences of others, defaults left out are cleared
+	w = send("PUT", user.ID, `{"currency":"EUR"}`, admin)
+	require.Equal(t, http.StatusOK, w.Code)
+	prefs = decode(send("GET", user.ID, "", user))
+	assert.Nil(t, prefs.Zone)
+	assert.Nil(t, prefs.Timezone)
+	assert.Equal(t, "EUR", *prefs.Currency)
+	assert.Equal(t, models.LanguageSwedish, prefs.Locale)
+
+	w = send("PUT", user.ID, `{"zone":"SE3","currency":"SEK","weekly_report":true}`, user)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.True(t, decode(send("GET", user.ID, "", user)).WeeklyReport)
+	recipients, err := tc.UserPreferenceRepo.ListWeeklyReports(context.Background())
+	require.NoError(t, err)
+	require.Len(t, recipients, 1)
+	assert.Equal(t, user.ID, recipients[0].UserID)
+	assert.Equal(t, "SE3", *recipients[0].Zone)
+}
+
+func TestSpotPriceHandler_PreferredDefaults(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	ctx := context.Background()
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	zone, err := tc.ZoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := tc.CurrencyRepo.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+	timestamp := time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC)
+	require.NoError(t, tc.SpotPriceRepo.Create(ctx, &models.SpotPrice{Timestamp: timestamp, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 42}))
+	timezone := "Europe/Stockholm"
+	require.NoError(t, tc.UserPreferenceRepo.Upsert(ctx, &models.UserPreferences{UserID: user.ID, ZoneID: &zone.ID, CurrencyID: &currency.ID, Timezone: &timezone}))
+
+	handler := handlers.NewSpotPriceHandler(tc.SpotPriceRepo, tc.ZoneRepo, tc.CurrencyRepo)
+	handler.SetPreferences(tc.UserPreferenceRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.GET("/spot-prices", authMiddleware.ClaimsOptional(), handler.ListSpotPrices)
+
+	list := func(query, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/spot-prices?start_time=2025-01-15T00:00:00Z&end_time=2025-01-16T00:00:00Z&envelope=false"+query, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// Anonymous requests have no defaults
+	assert.Equal(t, http.StatusBadRequest, list("", "").Code)
+	assert.Equal(t, http.StatusUnauthorized, list("", "invalid").Code)
+
+	w := list("", tc.GetTestJWT(user.ID))
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	var prices []models.SpotPrice
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prices))
+	require.Len(t, prices, 1)
+	assert.Equal(t, 42.0, prices[0].Price)
+	assert.Contains(t, w.Body.String(), `"timestamp":"2025-01-15T13:00:00+01:00"`)
+
+	// Parameters take precedence over the defaults
+	w = list("&currency=EUR", tc.GetTestJWT(user.ID))
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &prices))
+	assert.Empty(t, prices)
+}
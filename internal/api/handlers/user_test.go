@@ -8,7 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
-	"wattwatch/internal/api/handlers"
+	"time"
 	"wattwatch/internal/api/middleware"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
@@ -69,7 +69,7 @@ func TestUserHandler_UpdateUser(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "invalid email address",
+			errMsg:     "request validation failed",
 		},
 		{
 			name: "Error_NonAdminUpdatingOtherUser",
@@ -212,7 +212,7 @@ func TestUserHandler_UpdateUser(t *testing.T) {
 			}
 
 			// Create handler and router
-			handler := handlers.NewUserHandler(tc.UserRepo, tc.AuthService, tc.PasswordHistoryRepo, tc.AuditRepo)
+			handler := tc.NewUserHandler()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router := gin.New()
 			router.Use(authMiddleware.AuthRequired())
@@ -330,7 +330,7 @@ func TestUserHandler_GetUser(t *testing.T) {
 			userID, token := tt.setupFunc(tc)
 
 			// Create handler and router
-			handler := handlers.NewUserHandler(tc.UserRepo, tc.AuthService, tc.PasswordHistoryRepo, tc.AuditRepo)
+			handler := tc.NewUserHandler()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router := gin.New()
 			router.Use(authMiddleware.AuthRequired())
@@ -448,7 +448,7 @@ func TestUserHandler_ChangePassword(t *testing.T) {
 			},
 			wantStatus: http.StatusBadRequest,
 			wantErr:    true,
-			errMsg:     "Key: 'ChangePasswordRequest.NewPassword' Error:Field validation for 'NewPassword' failed on the 'min' tag",
+			errMsg:     "password does not meet the password policy",
 		},
 		{
 			name: "Error_NonAdminChangingOtherUserPassword",
@@ -495,7 +495,7 @@ func TestUserHandler_ChangePassword(t *testing.T) {
 			userID, token := tt.setupFunc(tc)
 
 			// Create handler
-			handler := handlers.NewUserHandler(tc.UserRepo, tc.AuthService, tc.PasswordHistoryRepo, tc.AuditRepo)
+			handler := tc.NewUserHandler()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 
 			// Create request
@@ -586,7 +586,7 @@ func TestUserHandler_ListUsers(t *testing.T) {
 			token := tt.setupFunc(tc)
 
 			// Create handler and router
-			handler := handlers.NewUserHandler(tc.UserRepo, tc.AuthService, tc.PasswordHistoryRepo, tc.AuditRepo)
+			handler := tc.NewUserHandler()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router := gin.New()
 			router.Use(authMiddleware.AuthRequired())
@@ -600,9 +600,10 @@ func TestUserHandler_ListUsers(t *testing.T) {
 
 			require.Equal(t, tt.wantStatus, w.Code)
 
-			var users []models.User
-			err := json.NewDecoder(w.Body).Decode(&users)
+			var page models.Page[models.User]
+			err := json.NewDecoder(w.Body).Decode(&page)
 			require.NoError(t, err)
+			users := page.Items
 			require.Len(t, users, tt.wantCount)
 
 			if tt.validateUser != nil && len(users) > 0 {
@@ -741,6 +742,8 @@ type deleteUserTest struct {
 	wantStatus int
 	wantErr    bool
 	errMsg     string
+	// scheduled is set when the deletion is scheduled instead of done right away
+	scheduled bool
 }
 
 func TestUserHandler_DeleteUser(t *testing.T) {
@@ -753,6 +756,7 @@ func TestUserHandler_DeleteUser(t *testing.T) {
 				return user.ID, token
 			},
 			wantStatus: http.StatusOK,
+			scheduled:  true,
 		},
 		{
 			name: "Success_AdminDeletingOtherUser",
@@ -837,7 +841,7 @@ func TestUserHandler_DeleteUser(t *testing.T) {
 			userID, token := tt.setupFunc(tc)
 
 			// Create handler and router
-			handler := handlers.NewUserHandler(tc.UserRepo, tc.AuthService, tc.PasswordHistoryRepo, tc.AuditRepo)
+			handler := tc.NewUserHandler()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router := gin.New()
 			router.Use(authMiddleware.AuthRequired())
@@ -862,6 +866,14 @@ func TestUserHandler_DeleteUser(t *testing.T) {
 			var resp models.SuccessResponse
 			err := json.NewDecoder(w.Body).Decode(&resp)
 			require.NoError(t, err)
+
+			if tt.scheduled {
+				require.Contains(t, resp.Message, "log in before then to cancel")
+				user, err := tc.UserRepo.GetByID(context.Background(), userID)
+				require.NoError(t, err)
+				require.NotNil(t, user.DeletionScheduledAt)
+				return
+			}
 			require.Equal(t, "user deleted successfully", resp.Message)
 
 			// Verify user was actually deleted
@@ -870,3 +882,247 @@ func TestUserHandler_DeleteUser(t *testing.T) {
 		})
 	}
 }
+
+func TestUserHandler_RevertEmailChange(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	user := tc.CreateTestUser("test_user", "old@example.com", "password123", false)
+	tc.MarkEmailVerified(user.ID)
+
+	handler := tc.NewUserHandler()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router := gin.New()
+	router.PUT("/api/v1/users/:id", authMiddleware.AuthRequired(), handler.UpdateUser)
+	router.GET("/api/v1/auth/revert-email-change", handler.RevertEmailChange)
+
+	// Change the address, which must reset verification and issue a revert link
+	body, err := json.Marshal(models.UpdateUserRequest{Email: testutil.String("new@example.com")})
+	require.NoError(t, err)
+	req := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/api/v1/users/%s", user.ID), bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var updated models.User
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&updated))
+	require.False(t, updated.EmailVerified)
+
+	var token string
+	err = tc.DB.QueryRowContext(context.Background(),
+		"SELECT token FROM email_change_reverts WHERE user_id = $1 AND old_email = $2",
+		user.ID, "old@example.com").Scan(&token)
+	require.NoError(t, err)
+
+	revert := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/auth/revert-email-change?token="+token, nil))
+		return w
+	}
+
+	w = revert(token)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	restored, err := tc.UserRepo.GetByID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "old@example.com", *restored.Email)
+	require.True(t, restored.EmailVerified)
+
+	// Links are single use
+	w = revert(token)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+
+	w = revert("")
+	require.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestUserHandler_RestoreAndPurge(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	regular := tc.CreateTestUser("regular", "regular@test.com", "password123", false)
+	deleted := tc.CreateTestUser("deleted", "deleted@test.com", "password123", false)
+
+	handler := tc.NewUserHandler()
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	users := router.Group("/users", authMiddleware.AuthRequired())
+	users.GET("", handler.ListUsers)
+	users.DELETE("/:id", handler.DeleteUser)
+	adminUsers := users.Group("", authMiddleware.RequirePermission(models.PermissionUsersManage))
+	adminUsers.POST("/:id/restore", handler.RestoreUser)
+	adminUsers.DELETE("/:id/purge", handler.PurgeUser)
+
+	send := func(method, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	listed := func(query string) int {
+		w := send("GET", "/users?"+query, admin.ID)
+		require.Equal(t, http.StatusOK, w.Code)
+		var page models.Page[models.User]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		return page.Total
+	}
+
+	require.Equal(t, http.StatusOK, send("DELETE", "/users/"+deleted.ID.String(), admin.ID).Code)
+	require.Equal(t, 2, listed(""))
+	require.Equal(t, 3, listed("include_deleted=true"))
+
+	// Only managers restore and purge, and only deleted users
+	require.Equal(t, http.StatusForbidden, send("POST", "/users/"+deleted.ID.String()+"/restore", regular.ID).Code)
+	require.Equal(t, http.StatusForbidden, send("DELETE", "/users/"+deleted.ID.String()+"/purge", regular.ID).Code)
+	require.Equal(t, http.StatusNotFound, send("POST", "/users/"+regular.ID.String()+"/restore", admin.ID).Code)
+	require.Equal(t, http.StatusNotFound, send("DELETE", "/users/"+regular.ID.String()+"/purge", admin.ID).Code)
+	require.Equal(t, http.StatusBadRequest, send("POST", "/users/invalid/restore", admin.ID).Code)
+
+	w := send("POST", "/users/"+deleted.ID.String()+"/restore", admin.ID)
+	require.Equal(t, http.StatusOK, w.Code)
+	var restored models.User
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &restored))
+	require.Equal(t, deleted.ID, restored.ID)
+	require.Nil(t, restored.DeletedAt)
+	require.Equal(t, 3, listed(""))
+
+	require.Equal(t, http.StatusOK, send("DELETE", "/users/"+deleted.ID.String(), admin.ID).Code)
+	require.Equal(t, http.StatusOK, send("DELETE", "/users/"+deleted.ID.String()+"/purge", admin.ID).Code)
+	require.Equal(t, 2, listed("include_deleted=true"))
+	_, err := tc.UserRepo.GetByID(context.Background(), deleted.ID)
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	for _, action := range []models.AuditAction{models.AuditActionRestore, models.AuditActionPurge} {
+		logs, err := tc.AuditRepo.List(context.Background(), repository.AuditLogFilter{Actions: []models.AuditAction{action}})
+		require.NoError(t, err, action)
+		require.Len(t, logs, 1, action)
+		require.Equal(t, deleted.ID.String(), logs[0].EntityID, action)
+	}
+}
+
+func TestUserHandler_LoginAttemptsAndUnlock(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	locked := tc.CreateTestUser("locked", "locked@test.com", "password123", false)
+
+	handler := tc.NewUserHandler()
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	adminUsers := router.Group("/users", authMiddleware.AuthRequired(), authMiddleware.RequirePermission(models.PermissionUsersManage))
+	adminUsers.GET("/:id/login-attempts", handler.GetLoginAttempts)
+	adminUsers.POST("/:id/unlock", handler.UnlockUser)
+
+	send := func(method, path string, userID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		router.ServeHTTP(w, req)
+		return w
+	}
+	attempts := func(query string) models.LoginAttemptsResponse {
+		w := send("GET", "/users/"+locked.ID.String()+"/login-attempts"+query, admin.ID)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var response models.LoginAttemptsResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response
+	}
+
+	// An attempt outside the lockout window is listed on request but doesn't lock the account
+	threshold, window := tc.Settings.LockoutThreshold(), tc.Settings.LockoutWindow()
+	now := time.Now()
+	require.NoError(t, tc.LoginAttemptRepo.Create(context.Background(), locked.ID, false, "10.0.0.1", now.Add(-time.Hour)))
+	for i := threshold; i > 0; i-- {
+		require.NoError(t, tc.LoginAttemptRepo.Create(context.Background(), locked.ID, false, "10.0.0.2", now.Add(-time.Duration(i)*time.Minute)))
+	}
+
+	response := attempts("")
+	require.Len(t, response.Attempts, threshold)
+	require.True(t, response.Locked)
+	require.NotNil(t, response.LockedUntil)
+	require.WithinDuration(t, now.Add(window-time.Duration(threshold)*time.Minute), *response.LockedUntil, time.Second)
+	require.Len(t, attempts("?since="+now.Add(-2*time.Hour).Format(time.RFC3339)).Attempts, threshold+1)
+
+	require.Equal(t, http.StatusBadRequest, send("GET", "/users/"+locked.ID.String()+"/login-attempts?since=yesterday", admin.ID).Code)
+	require.Equal(t, http.StatusNotFound, send("GET", "/users/"+uuid.New().String()+"/login-attempts", admin.ID).Code)
+	require.Equal(t, http.StatusForbidden, send("POST", "/users/"+locked.ID.String()+"/unlock", locked.ID).Code)
+
+	require.Equal(t, http.StatusOK, send("POST", "/users/"+locked.ID.String()+"/unlock", admin.ID).Code)
+	response = attempts("")
+	require.Empty(t, response.Attempts)
+	require.False(t, response.Locked)
+	require.Nil(t, response.LockedUntil)
+
+	// Unlocking an account that isn't locked succeeds as well
+	require.Equal(t, http.StatusOK, send("POST", "/users/"+locked.ID.String()+"/unlock", admin.ID).Code)
+	require.Equal(t, http.StatusNotFound, send("POST", "/users/"+uuid.New().String()+"/unlock", admin.ID).Code)
+
+	logs, err := tc.AuditRepo.List(context.Background(), repository.AuditLogFilter{Actions: []models.AuditAction{models.AuditActionUnlock}})
+	require.NoError(t, err)
+	require.Len(t, logs, 2)
+	require.Equal(t, locked.ID.String(), logs[0].EntityID)
+}
+
+func TestUserHandler_ScheduledDeletion(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	ctx := context.Background()
+	user := tc.CreateTestUser("leaving", "leaving@test.com", "password123", false)
+
+	handler := tc.NewUserHandler()
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.DELETE("/users/:id", authMiddleware.AuthRequired(), handler.DeleteUser)
+	router.POST("/login", tc.AuthHandler.Login)
+
+	deleteSelf := func() {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodDelete, "/users/"+user.ID.String(), nil)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(user.ID))
+		router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// Deleting the own account keeps it for the grace period and signs out its sessions
+	_, err := tc.AuthService.GenerateRefreshToken(ctx, user.ID, models.SessionClient{})
+	require.NoError(t, err)
+	before := time.Now()
+	deleteSelf()
+
+	scheduled, err := tc.UserRepo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, scheduled.DeletionScheduledAt)
+	require.WithinDuration(t, before.Add(tc.Config.Auth.DeletionGracePeriod), *scheduled.DeletionScheduledAt, time.Minute)
+	sessions, err := tc.RefreshTokenRepo.GetByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+
+	// Logging in cancels the deletion
+	body, err := json.Marshal(models.LoginRequest{Username: "leaving", Password: "password123"})
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+	kept, err := tc.UserRepo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Nil(t, kept.DeletionScheduledAt)
+
+	// Once the grace period has passed the account is deleted
+	deleteSelf()
+	ids, err := tc.UserRepo.DeleteScheduled(ctx, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, ids)
+	ids, err = tc.UserRepo.DeleteScheduled(ctx, time.Now().Add(tc.Config.Auth.DeletionGracePeriod+time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{user.ID}, ids)
+	_, err = tc.UserRepo.GetByID(ctx, user.ID)
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+
+	for _, action := range []models.AuditAction{models.AuditActionScheduleDeletion, models.AuditActionCancelDeletion} {
+		logs, err := tc.AuditRepo.List(ctx, repository.AuditLogFilter{Actions: []models.AuditAction{action}})
+		require.NoError(t, err, action)
+		require.NotEmpty(t, logs, action)
+		require.Equal(t, user.ID.String(), logs[0].EntityID, action)
+	}
+}
@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/webhook"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// WebhookHandler handles the webhooks administrators register to be called on events, and
+// the log of their deliveries
+type WebhookHandler struct {
+	webhookRepo  repository.WebhookRepository
+	deliveryRepo repository.WebhookDeliveryRepository
+	auditRepo    repository.AuditLogRepository
+	limits       ListLimits
+}
+
+// NewWebhookHandler creates a new WebhookHandler
+func NewWebhookHandler(webhookRepo repository.WebhookRepository, deliveryRepo repository.WebhookDeliveryRepository, auditRepo repository.AuditLogRepository) *WebhookHandler {
+	return &WebhookHandler{
+		webhookRepo:  webhookRepo,
+		deliveryRepo: deliveryRepo,
+		auditRepo:    auditRepo,
+		limits:       DefaultListLimits,
+	}
+}
+
+// SetListLimits sets the default and maximum number of deliveries listed
+func (h *WebhookHandler) SetListLimits(limits ListLimits) {
+	h.limits = limits
+}
+
+// ListWebhooks godoc
+// @Summary List webhooks
+// @Description Lists the registered webhooks, oldest first (admin only)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {array} models.Webhook
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/webhooks [get]
+func (h *WebhookHandler) ListWebhooks(c *gin.Context) {
+	webhooks, err := h.webhookRepo.List(c.Request.Context())
+	if err != nil {
+		log.Printf("Error listing webhooks: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list webhooks")
+		return
+	}
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// CreateWebhook godoc
+// @Summary Register a webhook
+// @Description Registers a URL called with a POST request when one of its events occurs (admin only). The response contains the secret the requests are signed with, it isn't returned again. The X-WattWatch-Signature header holds "t=<unix time>,v1=<signature>", where the signature is the hex encoded HMAC-SHA256 of "<unix time>.<body>" keyed with the secret. Failed deliveries are retried with exponential backoff.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateWebhookRequest true "Webhook"
+// @Success 201 {object} models.CreatedWebhook
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/webhooks [post]
+func (h *WebhookHandler) CreateWebhook(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	var req models.CreateWebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if err := validateWebhookURL(req.URL); err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+
+	secret, err := webhook.NewSecret()
+	if err != nil {
+		log.Printf("Error generating webhook secret: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to create webhook")
+		return
+	}
+
+	hook := &models.Webhook{
+		URL:       req.URL,
+		Secret:    secret,
+		Events:    req.Events,
+		Enabled:   true,
+		CreatedBy: &authUser.ID,
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+	if err := h.webhookRepo.Create(c.Request.Context(), hook); err != nil {
+		log.Printf("Error creating webhook: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to create webhook")
+		return
+	}
+
+	h.audit(c, authUser, models.AuditActionCreate, hook.ID, "Webhook registered", map[string]string{
+		"url":    hook.URL,
+		"events": joinEvents(hook.Events),
+	})
+	c.JSON(http.StatusCreated, models.CreatedWebhook{Webhook: *hook, Secret: secret})
+}
+
+// GetWebhook godoc
+// @Summary Get a webhook
+// @Description Returns a registered webhook, without its secret (admin only)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID (UUID)"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} apierror.Problem "Invalid webhook ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Webhook not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/webhooks/{id} [get]
+func (h *WebhookHandler) GetWebhook(c *gin.Context) {
+	hook, ok := h.getWebhook(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, hook)
+}
+
+// UpdateWebhook godoc
+// @Summary Update a webhook
+// @Description Changes the URL, events or enabled state of a webhook (admin only). Pending deliveries of a disabled webhook fail when they are next attempted.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID (UUID)"
+// @Param request body models.UpdateWebhookRequest true "Webhook changes"
+// @Success 200 {object} models.Webhook
+// @Failure 400 {object} apierror.Problem "Invalid request"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Webhook not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/webhooks/{id} [put]
+func (h *WebhookHandler) UpdateWebhook(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+	hook, ok := h.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateWebhookRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+
+	if req.URL != nil {
+		if err := validateWebhookURL(*req.URL); err != nil {
+			apierror.Write(c, apierror.InvalidRequest, err.Error())
+			return
+		}
+		hook.URL = *req.URL
+	}
+	if req.Events != nil {
+		hook.Events = req.Events
+	}
+	if req.Enabled != nil {
+		hook.Enabled = *req.Enabled
+	}
+
+	if err := h.webhookRepo.Update(c.Request.Context(), hook); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.WebhookNotFound, "webhook not found")
+			return
+		}
+		log.Printf("Error updating webhook: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to update webhook")
+		return
+	}
+
+	h.audit(c, authUser, models.AuditActionUpdate, hook.ID, "Webhook updated", map[string]string{
+		"url":     hook.URL,
+		"events":  joinEvents(hook.Events),
+		"enabled": strconv.FormatBool(hook.Enabled),
+	})
+	c.JSON(http.StatusOK, hook)
+}
+
+// DeleteWebhook godoc
+// @Summary Delete a webhook
+// @Description Removes a webhook along with its deliveries (admin only)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID (UUID)"
+// @Success 204 "No Content"
+// @Failure 400 {object} apierror.Problem "Invalid webhook ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Webhook not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/webhooks/{id} [delete]
+func (h *WebhookHandler) DeleteWebhook(c *gin.Context) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+	hook, ok := h.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	if err := h.webhookRepo.Delete(c.Request.Context(), hook.ID); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.WebhookNotFound, "webhook not found")
+			return
+		}
+		log.Printf("Error deleting webhook: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to delete webhook")
+		return
+	}
+
+	h.audit(c, authUser, models.AuditActionDelete, hook.ID, "Webhook deleted", map[string]string{"url": hook.URL})
+	c.Status(http.StatusNoContent)
+}
+
+// ListDeliveries godoc
+// @Summary List a webhook's deliveries
+// @Description Lists the deliveries of a webhook, newest first, with the outcome of their last attempt (admin only)
+// @Tags webhooks
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Webhook ID (UUID)"
+// @Param status query string false "Filter by status (pending, sent, failed)"
+// @Param event query string false "Filter by event"
+// @Param limit query integer false "Limit results (default 50, maximum 1000 unless configured otherwise)"
+// @Param offset query integer false "Offset results"
+// @Param envelope query boolean false "Wrap the deliveries in a page with the total count (default true)"
+// @Success 200 {object} models.Page[models.WebhookDelivery]
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 403 {object} apierror.Problem "Permission denied - admin only"
+// @Failure 404 {object} apierror.Problem "Webhook not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal server error"
+// @Router /admin/webhooks/{id}/deliveries [get]
+func (h *WebhookHandler) ListDeliveries(c *gin.Context) {
+	hook, ok := h.getWebhook(c)
+	if !ok {
+		return
+	}
+
+	filter := repository.WebhookDeliveryFilter{WebhookID: &hook.ID}
+	if status := c.Query("status"); status != "" {
+		deliveryStatus := models.DeliveryStatus(status)
+		switch deliveryStatus {
+		case models.DeliveryStatusPending, models.DeliveryStatusSent, models.DeliveryStatusFailed:
+		default:
+			apierror.Write(c, apierror.InvalidRequest, "invalid status")
+			return
+		}
+		filter.Status = &deliveryStatus
+	}
+	if event := c.Query("event"); event != "" {
+		webhookEvent := models.WebhookEvent(event)
+		filter.Event = &webhookEvent
+	}
+
+	limit, err := h.limits.limit(c, h.limits.Default)
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, err.Error())
+		return
+	}
+	filter.Limit = &limit
+
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		offset, err := strconv.Atoi(offsetStr)
+		if err != nil || offset < 0 {
+			apierror.Write(c, apierror.InvalidRequest, "invalid offset")
+			return
+		}
+		filter.Offset = &offset
+	}
+
+	deliveries, err := h.deliveryRepo.List(c.Request.Context(), filter)
+	if err != nil {
+		log.Printf("Error listing webhook deliveries: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to list deliveries")
+		return
+	}
+
+	respondPage(c, deliveries, filter.Limit, filter.Offset, func() (int, error) {
+		return h.deliveryRepo.Total(c.Request.Context(), filter)
+	}, "failed to list deliveries")
+}
+
+// getWebhook loads the webhook named by the id parameter, responding with an error when it
+// can't
+func (h *WebhookHandler) getWebhook(c *gin.Context) (*models.Webhook, bool) {
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		apierror.Write(c, apierror.InvalidRequest, "invalid webhook ID")
+		return nil, false
+	}
+
+	hook, err := h.webhookRepo.GetByID(c.Request.Context(), id)
+	if errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.WebhookNotFound, "webhook not found")
+		return nil, false
+	}
+	if err != nil {
+		log.Printf("Error getting webhook: %v", err)
+		apierror.Write(c, apierror.Internal, "failed to get webhook")
+		return nil, false
+	}
+	return hook, true
+}
+
+// validateWebhookURL only accepts http and https URLs, the url binding allows any scheme
+func validateWebhookURL(url string) error {
+	if !strings.HasPrefix(url, "https://") && !strings.HasPrefix(url, "http://") {
+		return errors.New("webhook URL must use http or https")
+	}
+	return nil
+}
+
+// joinEvents lists events for an audit log
+func joinEvents(events []models.WebhookEvent) string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	return strings.Join(names, ",")
+}
+
+func (h *WebhookHandler) audit(c *gin.Context, authUser *models.User, action models.AuditAction, webhookID uuid.UUID, description string, metadata map[string]string) {
+	data, _ := json.Marshal(metadata)
+	if err := h.auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		UserID:      &authUser.ID,
+		Action:      action,
+		EntityType:  "webhook",
+		EntityID:    webhookID.String(),
+		Description: description,
+		Metadata:    string(data),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging webhook change: %v", err)
+	}
+}
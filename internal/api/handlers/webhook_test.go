@@ -0,0 +1,117 @@
+package handlers_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookHandler(t *testing.T) {
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@test.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@test.com", "password123", false)
+
+	handler := handlers.NewWebhookHandler(tc.WebhookRepo, tc.WebhookDeliveryRepo, tc.AuditRepo)
+	router := gin.New()
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router.Use(authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+	router.GET("/webhooks", handler.ListWebhooks)
+	router.POST("/webhooks", handler.CreateWebhook)
+	router.GET("/webhooks/:id", handler.GetWebhook)
+	router.PUT("/webhooks/:id", handler.UpdateWebhook)
+	router.DELETE("/webhooks/:id", handler.DeleteWebhook)
+	router.GET("/webhooks/:id/deliveries", handler.ListDeliveries)
+
+	send := func(method, path string, userID uuid.UUID, body any) *httptest.ResponseRecorder {
+		var buf bytes.Buffer
+		if body != nil {
+			require.NoError(t, json.NewEncoder(&buf).Encode(body))
+		}
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, &buf)
+		req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(userID))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Create Validation", func(t *testing.T) {
+		tests := []struct {
+			name       string
+			input      map[string]any
+			wantStatus int
+		}{
+			{"Missing Events", map[string]any{"url": "https://example.com/hook"}, http.StatusBadRequest},
+			{"Unknown Event", map[string]any{"url": "https://example.com/hook", "events": []string{"user.deleted"}}, http.StatusBadRequest},
+			{"Invalid Scheme", map[string]any{"url": "ftp://example.com/hook", "events": []string{"user.registered"}}, http.StatusBadRequest},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				w := send(http.MethodPost, "/webhooks", admin.ID, tt.input)
+				assert.Equal(t, tt.wantStatus, w.Code, w.Body.String())
+			})
+		}
+	})
+
+	t.Run("Admin Only", func(t *testing.T) {
+		w := send(http.MethodGet, "/webhooks", user.ID, nil)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Lifecycle", func(t *testing.T) {
+		w := send(http.MethodPost, "/webhooks", admin.ID, models.CreateWebhookRequest{
+			URL:    "https://example.com/hook",
+			Events: []models.WebhookEvent{models.WebhookEventUserRegistered},
+		})
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var created models.CreatedWebhook
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &created))
+		assert.Len(t, created.Secret, 64)
+		assert.True(t, created.Enabled)
+		path := "/webhooks/" + created.ID.String()
+
+		// The secret is only returned on creation
+		w = send(http.MethodGet, path, admin.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code)
+		assert.NotContains(t, w.Body.String(), created.Secret)
+
+		disabled := false
+		w = send(http.MethodPut, path, admin.ID, models.UpdateWebhookRequest{Enabled: &disabled})
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var updated models.Webhook
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &updated))
+		assert.False(t, updated.Enabled)
+		assert.Equal(t, []models.WebhookEvent{models.WebhookEventUserRegistered}, updated.Events)
+
+		require.NoError(t, tc.WebhookDeliveryRepo.Create(context.Background(), &models.WebhookDelivery{
+			WebhookID: created.ID, Event: models.WebhookEventUserRegistered, Payload: []byte(`{}`),
+		}))
+		w = send(http.MethodGet, path+"/deliveries?status=pending", admin.ID, nil)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var page models.Page[models.WebhookDelivery]
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Equal(t, 1, page.Total)
+		w = send(http.MethodGet, path+"/deliveries?status=sent", admin.ID, nil)
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+		assert.Zero(t, page.Total)
+		w = send(http.MethodGet, path+"/deliveries?status=unknown", admin.ID, nil)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		w = send(http.MethodDelete, path, admin.ID, nil)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		w = send(http.MethodGet, path, admin.ID, nil)
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+}
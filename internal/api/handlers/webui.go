@@ -0,0 +1,85 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io/fs"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// webUIFile is an embedded file kept in memory with its ETag
+type webUIFile struct {
+	data []byte
+	etag string
+}
+
+// WebUIHandler serves the embedded dashboard. Paths without a file fall back to index.html
+// so the dashboard can route them in the browser.
+type WebUIHandler struct {
+	files map[string]webUIFile
+}
+
+// NewWebUIHandler loads every file of the dashboard build into memory
+func NewWebUIHandler(files fs.FS) (*WebUIHandler, error) {
+	h := &WebUIHandler{files: make(map[string]webUIFile)}
+	err := fs.WalkDir(files, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		data, err := fs.ReadFile(files, name)
+		if err != nil {
+			return err
+		}
+		sum := sha256.Sum256(data)
+		h.files[name] = webUIFile{data: data, etag: `"` + hex.EncodeToString(sum[:8]) + `"`}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// Serve answers GET and HEAD requests that matched no API route. API and Swagger paths
+// are left alone so they keep their 404 responses.
+func (h *WebUIHandler) Serve(c *gin.Context) {
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead {
+		return
+	}
+	urlPath := c.Request.URL.Path
+	if strings.HasPrefix(urlPath, "/api/") || strings.HasPrefix(urlPath, "/swagger/") {
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(urlPath), "/")
+	file, ok := h.files[name]
+	if !ok {
+		// Missing assets are real 404s, anything else is a dashboard route
+		if path.Ext(name) != "" {
+			return
+		}
+		name = "index.html"
+		if file, ok = h.files[name]; !ok {
+			return
+		}
+	}
+
+	switch {
+	case name == "index.html":
+		// Always revalidate so a new release is picked up right away
+		c.Header("Cache-Control", "no-cache")
+	case strings.HasPrefix(name, "assets/"):
+		// Asset names change with their content
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	default:
+		c.Header("Cache-Control", "public, max-age=3600")
+	}
+	c.Header("ETag", file.etag)
+	http.ServeContent(c.Writer, c.Request, name, time.Time{}, bytes.NewReader(file.data))
+}
@@ -0,0 +1,89 @@
+package handlers_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/web"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebUIHandler_Serve(t *testing.T) {
+	files := fstest.MapFS{
+		"index.html":         {Data: []byte("<html>dashboard</html>")},
+		"favicon.ico":        {Data: []byte("icon")},
+		"assets/app-1a2b.js": {Data: []byte("console.log('hi')")},
+	}
+	handler, err := handlers.NewWebUIHandler(files)
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/api/v1/health", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.NoRoute(handler.Serve)
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+		wantCache  string
+		wantType   string
+	}{
+		{name: "Index", method: "GET", path: "/", wantStatus: http.StatusOK, wantBody: "<html>dashboard</html>", wantCache: "no-cache", wantType: "text/html; charset=utf-8"},
+		{name: "SPA Route", method: "GET", path: "/zones/SE3", wantStatus: http.StatusOK, wantBody: "<html>dashboard</html>", wantCache: "no-cache"},
+		{name: "Hashed Asset", method: "GET", path: "/assets/app-1a2b.js", wantStatus: http.StatusOK, wantBody: "console.log('hi')", wantCache: "public, max-age=31536000, immutable", wantType: "text/javascript; charset=utf-8"},
+		{name: "Other File", method: "GET", path: "/favicon.ico", wantStatus: http.StatusOK, wantCache: "public, max-age=3600"},
+		{name: "Missing Asset", method: "GET", path: "/assets/app-old.js", wantStatus: http.StatusNotFound},
+		{name: "Unknown API Route", method: "GET", path: "/api/v1/unknown", wantStatus: http.StatusNotFound},
+		{name: "Post", method: "POST", path: "/zones", wantStatus: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(tt.method, tt.path, nil)
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.Equal(t, tt.wantBody, w.Body.String())
+			}
+			if tt.wantCache != "" {
+				assert.Equal(t, tt.wantCache, w.Header().Get("Cache-Control"))
+			}
+			if tt.wantType != "" {
+				assert.Equal(t, tt.wantType, w.Header().Get("Content-Type"))
+			}
+		})
+	}
+
+	// Unchanged files are revalidated without sending them again
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest("GET", "/", nil)
+	req.Header.Set("If-None-Match", etag)
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+}
+
+func TestWebUIHandler_EmbeddedBuild(t *testing.T) {
+	handler, err := handlers.NewWebUIHandler(web.Files())
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.NoRoute(handler.Serve)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "<title>WattWatch</title>")
+}
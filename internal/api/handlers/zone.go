@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
 
@@ -12,12 +17,13 @@ import (
 
 // ZoneHandler handles zone-related requests
 type ZoneHandler struct {
-	repo repository.ZoneRepository
+	repo      repository.ZoneRepository
+	auditRepo repository.AuditLogRepository
 }
 
 // NewZoneHandler creates a new ZoneHandler
-func NewZoneHandler(repo repository.ZoneRepository) *ZoneHandler {
-	return &ZoneHandler{repo: repo}
+func NewZoneHandler(repo repository.ZoneRepository, auditRepo repository.AuditLogRepository) *ZoneHandler {
+	return &ZoneHandler{repo: repo, auditRepo: auditRepo}
 }
 
 // ListZones godoc
@@ -28,15 +34,20 @@ func NewZoneHandler(repo repository.ZoneRepository) *ZoneHandler {
 // @Produce json
 // @Security BearerAuth
 // @Param search query string false "Search zones by name"
+// @Param country query string false "Filter by ISO 3166-1 alpha-2 country code, e.g. SE"
 // @Param order_by query string false "Order by field (name, timezone)"
 // @Param order_desc query boolean false "Order descending"
 // @Param limit query integer false "Limit results"
 // @Param offset query integer false "Offset results"
-// @Success 200 {array} models.Zone
-// @Failure 400 {object} models.ErrorResponse "Invalid parameters"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Param envelope query boolean false "Wrap the zones in a page with the total count (default true)"
+// @Param If-None-Match header string false "ETag of the zones the client has"
+// @Param If-Modified-Since header string false "When the client got the zones it has"
+// @Success 200 {object} models.Page[models.Zone]
+// @Success 304 "Not Modified"
+// @Failure 400 {object} apierror.Problem "Invalid parameters"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /zones [get]
 func (h *ZoneHandler) ListZones(c *gin.Context) {
 	filter := repository.ZoneFilter{}
@@ -46,6 +57,16 @@ func (h *ZoneHandler) ListZones(c *gin.Context) {
 		filter.Search = &search
 	}
 
+	// Parse country
+	if country := c.Query("country"); country != "" {
+		country = strings.ToUpper(country)
+		if !isCountryCode(country) {
+			apierror.Write(c, apierror.InvalidRequest, "Invalid country")
+			return
+		}
+		filter.CountryCode = &country
+	}
+
 	// Parse ordering
 	if orderBy := c.Query("order_by"); orderBy != "" {
 		filter.OrderBy = orderBy
@@ -58,7 +79,7 @@ func (h *ZoneHandler) ListZones(c *gin.Context) {
 	if limitStr := c.Query("limit"); limitStr != "" {
 		limit, err := strconv.Atoi(limitStr)
 		if err != nil || limit < 0 {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid limit"})
+			apierror.Write(c, apierror.InvalidRequest, "Invalid limit")
 			return
 		}
 		filter.Limit = &limit
@@ -67,7 +88,7 @@ func (h *ZoneHandler) ListZones(c *gin.Context) {
 	if offsetStr := c.Query("offset"); offsetStr != "" {
 		offset, err := strconv.Atoi(offsetStr)
 		if err != nil || offset < 0 {
-			c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid offset"})
+			apierror.Write(c, apierror.InvalidRequest, "Invalid offset")
 			return
 		}
 		filter.Offset = &offset
@@ -75,11 +96,21 @@ func (h *ZoneHandler) ListZones(c *gin.Context) {
 
 	zones, err := h.repo.List(c.Request.Context(), filter)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch zones"})
+		apierror.Write(c, apierror.Internal, "Failed to fetch zones")
 		return
 	}
 
-	c.JSON(http.StatusOK, zones)
+	page, ok := pageOf(c, zones, filter.Limit, filter.Offset, func() (int, error) {
+		return h.repo.Total(c.Request.Context(), filter)
+	}, "Failed to fetch zones")
+	if ok {
+		respondConditional(c, page, latest(zones, func(zone models.Zone) time.Time { return zone.UpdatedAt }))
+	}
+}
+
+// isCountryCode reports whether s has the form of an ISO 3166-1 alpha-2 code
+func isCountryCode(s string) bool {
+	return len(s) == 2 && s[0] >= 'A' && s[0] <= 'Z' && s[1] >= 'A' && s[1] <= 'Z'
 }
 
 // GetZone godoc
@@ -90,31 +121,34 @@ func (h *ZoneHandler) ListZones(c *gin.Context) {
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Zone ID"
+// @Param If-None-Match header string false "ETag of the zone the client has"
+// @Param If-Modified-Since header string false "When the client got the zone it has"
 // @Success 200 {object} models.Zone
-// @Failure 400 {object} models.ErrorResponse "Invalid zone ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 404 {object} models.ErrorResponse "Zone not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Success 304 "Not Modified"
+// @Failure 400 {object} apierror.Problem "Invalid zone ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /zones/{id} [get]
 func (h *ZoneHandler) GetZone(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid zone ID"})
+		apierror.Write(c, apierror.InvalidRequest, "Invalid zone ID")
 		return
 	}
 
 	zone, err := h.repo.GetByID(c.Request.Context(), id)
 	if err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Zone not found"})
+		apierror.Write(c, apierror.ZoneNotFound, "Zone not found")
 		return
 	}
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to fetch zone"})
+		apierror.Write(c, apierror.Internal, "Failed to fetch zone")
 		return
 	}
 
-	c.JSON(http.StatusOK, zone)
+	respondConditional(c, zone, zone.UpdatedAt)
 }
 
 // CreateZone godoc
@@ -124,22 +158,35 @@ func (h *ZoneHandler) GetZone(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Security BearerAuth
-// @Param zone body models.Zone true "Zone to create"
+// @Param zone body models.CreateZoneRequest true "Zone to create"
 // @Success 201 {object} models.Zone
-// @Failure 400 {object} models.ErrorResponse "Invalid request body"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid request body"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /zones [post]
 func (h *ZoneHandler) CreateZone(c *gin.Context) {
-	var zone models.Zone
-	if err := c.ShouldBindJSON(&zone); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body"})
+	var req models.CreateZoneRequest
+	if !bindJSON(c, &req) {
 		return
 	}
+	if req.EICCode != nil && !eicCode.MatchString(*req.EICCode) {
+		apierror.Write(c, apierror.InvalidRequest, "eic_code must be an EIC code of 16 upper case letters, digits or dashes")
+		return
+	}
+
+	zone := models.Zone{
+		Name:         req.Name,
+		Timezone:     req.Timezone,
+		CountryCode:  req.CountryCode,
+		EICCode:      req.EICCode,
+		GridOperator: req.GridOperator,
+		DisplayName:  req.DisplayName,
+	}
 
 	if err := h.repo.Create(c.Request.Context(), &zone); err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to create zone"})
+		apierror.Write(c, apierror.Internal, "Failed to create zone")
 		return
 	}
 
@@ -148,39 +195,51 @@ func (h *ZoneHandler) CreateZone(c *gin.Context) {
 
 // UpdateZone godoc
 // @Summary Update a zone
-// @Description Updates an existing zone
+// @Description Updates an existing zone. Metadata left out of the request is cleared.
 // @Tags zones
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Zone ID"
-// @Param zone body models.Zone true "Updated zone"
+// @Param zone body models.UpdateZoneRequest true "Updated zone"
 // @Success 200 {object} models.Zone
-// @Failure 400 {object} models.ErrorResponse "Invalid request body or zone ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 404 {object} models.ErrorResponse "Zone not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid request body or zone ID"
+// @Failure 400 {object} apierror.Problem "Request body failed validation"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone not found"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /zones/{id} [put]
 func (h *ZoneHandler) UpdateZone(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid zone ID"})
+		apierror.Write(c, apierror.InvalidRequest, "Invalid zone ID")
 		return
 	}
 
-	var zone models.Zone
-	if err := c.ShouldBindJSON(&zone); err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid request body"})
+	var req models.UpdateZoneRequest
+	if !bindJSON(c, &req) {
+		return
+	}
+	if req.EICCode != nil && !eicCode.MatchString(*req.EICCode) {
+		apierror.Write(c, apierror.InvalidRequest, "eic_code must be an EIC code of 16 upper case letters, digits or dashes")
 		return
 	}
 
-	zone.ID = id
+	zone := models.Zone{
+		ID:           id,
+		Name:         req.Name,
+		Timezone:     req.Timezone,
+		CountryCode:  req.CountryCode,
+		EICCode:      req.EICCode,
+		GridOperator: req.GridOperator,
+		DisplayName:  req.DisplayName,
+	}
 	if err := h.repo.Update(c.Request.Context(), &zone); err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Zone not found"})
+		apierror.Write(c, apierror.ZoneNotFound, "Zone not found")
 		return
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to update zone"})
+		apierror.Write(c, apierror.Internal, "Failed to update zone")
 		return
 	}
 
@@ -189,36 +248,94 @@ func (h *ZoneHandler) UpdateZone(c *gin.Context) {
 
 // DeleteZone godoc
 // @Summary Delete a zone
-// @Description Deletes an existing zone
+// @Description Deletes an existing zone. A zone with spot prices is only deleted with force, which deletes its spot prices, or moves them to the zone reassign_to in one transaction. Spot prices reassign_to already has are deleted. Forced deletions are audited.
 // @Tags zones
 // @Accept json
 // @Produce json
 // @Security BearerAuth
 // @Param id path string true "Zone ID"
+// @Param force query boolean false "Also delete or move the zone's spot prices"
+// @Param reassign_to query string false "Zone ID to move the spot prices to when forced"
+// @Success 200 {object} models.DeletionSummary "Forced deletion, rows affected"
 // @Success 204 "No Content"
-// @Failure 400 {object} models.ErrorResponse "Invalid zone ID"
-// @Failure 401 {object} models.ErrorResponse "Unauthorized"
-// @Failure 404 {object} models.ErrorResponse "Zone not found"
-// @Failure 429 {object} models.ErrorResponse "Rate limit exceeded"
-// @Failure 500 {object} models.ErrorResponse "Internal Server Error"
+// @Failure 400 {object} apierror.Problem "Invalid zone ID"
+// @Failure 401 {object} apierror.Problem "Unauthorized"
+// @Failure 404 {object} apierror.Problem "Zone not found"
+// @Failure 409 {object} apierror.Problem "Zone has spot prices and force is not set"
+// @Failure 429 {object} apierror.Problem "Rate limit exceeded"
+// @Failure 500 {object} apierror.Problem "Internal Server Error"
 // @Router /zones/{id} [delete]
 func (h *ZoneHandler) DeleteZone(c *gin.Context) {
 	id, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, models.ErrorResponse{Error: "Invalid zone ID"})
+		apierror.Write(c, apierror.InvalidRequest, "Invalid zone ID")
+		return
+	}
+
+	if c.Query("force") == "true" {
+		h.forceDelete(c, id)
 		return
 	}
 
 	if err := h.repo.Delete(c.Request.Context(), id); err == repository.ErrNotFound {
-		c.JSON(http.StatusNotFound, models.ErrorResponse{Error: "Zone not found"})
+		apierror.Write(c, apierror.ZoneNotFound, "Zone not found")
 		return
 	} else if err == repository.ErrHasAssociatedRecords {
-		c.JSON(http.StatusConflict, models.ErrorResponse{Error: "cannot delete zone that has associated spot prices"})
+		apierror.Write(c, apierror.ZoneInUse, "cannot delete zone that has associated spot prices")
 		return
 	} else if err != nil {
-		c.JSON(http.StatusInternalServerError, models.ErrorResponse{Error: "Failed to delete zone"})
+		apierror.Write(c, apierror.Internal, "Failed to delete zone")
 		return
 	}
 
 	c.Status(http.StatusNoContent)
 }
+
+// forceDelete deletes the zone along with its spot prices, or moves them to reassign_to
+func (h *ZoneHandler) forceDelete(c *gin.Context, id uuid.UUID) {
+	authUser := auth.GetUserFromContext(c)
+	if authUser == nil {
+		apierror.Write(c, apierror.Unauthorized, "unauthorized")
+		return
+	}
+
+	reassignTo, invalid := reassignTarget(c, id)
+	if invalid != "" {
+		apierror.Write(c, apierror.InvalidRequest, invalid)
+		return
+	}
+
+	zone, err := h.repo.GetByID(c.Request.Context(), id)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ZoneNotFound, "Zone not found")
+		return
+	} else if err != nil {
+		apierror.Write(c, apierror.Internal, "Failed to delete zone")
+		return
+	}
+
+	description := fmt.Sprintf("Deleted zone %s and its spot prices", zone.Name)
+	if reassignTo != nil {
+		target, err := h.repo.GetByID(c.Request.Context(), *reassignTo)
+		if err == repository.ErrNotFound {
+			apierror.Write(c, apierror.ZoneNotFound, "reassign_to zone not found")
+			return
+		} else if err != nil {
+			apierror.Write(c, apierror.Internal, "Failed to delete zone")
+			return
+		}
+		description = fmt.Sprintf("Deleted zone %s and moved its spot prices to %s", zone.Name, target.Name)
+	}
+
+	summary, err := h.repo.DeleteCascade(c.Request.Context(), id, reassignTo)
+	if err == repository.ErrNotFound {
+		apierror.Write(c, apierror.ZoneNotFound, "Zone not found")
+		return
+	} else if err != nil {
+		apierror.Write(c, apierror.Internal, "Failed to delete zone")
+		return
+	}
+
+	logForcedDeletion(c, h.auditRepo, authUser, "zone", id, description, summary)
+	c.JSON(http.StatusOK, summary)
+}
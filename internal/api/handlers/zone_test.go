@@ -2,13 +2,17 @@ package handlers_test
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 	"wattwatch/internal/api/handlers"
 	"wattwatch/internal/api/middleware"
 	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
 	"wattwatch/internal/repository/postgres"
 	"wattwatch/internal/testutil"
 
@@ -30,7 +34,7 @@ type zoneTest struct {
 func TestZoneHandler_ListZones(t *testing.T) {
 	tc := testutil.NewTestContext(t)
 
-	handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB))
+	handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB), tc.AuditRepo)
 	router := gin.New()
 	router.GET("/zones", handler.ListZones)
 
@@ -51,10 +55,12 @@ func TestZoneHandler_ListZones(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, w.Code)
 
-	var zones []models.Zone
-	err = json.Unmarshal(w.Body.Bytes(), &zones)
+	var page models.Page[models.Zone]
+	err = json.Unmarshal(w.Body.Bytes(), &page)
 	require.NoError(t, err)
+	zones := page.Items
 	assert.Len(t, zones, 6) // 4 defaults + 2 inserted
+	assert.Equal(t, 6, page.Total)
 	// Check they're ordered alphabetically
 	assert.Equal(t, "SE1", zones[0].Name)
 	assert.Equal(t, "SE2", zones[1].Name)
@@ -67,7 +73,7 @@ func TestZoneHandler_ListZones(t *testing.T) {
 func TestZoneHandler_GetZone(t *testing.T) {
 	tc := testutil.NewTestContext(t)
 
-	handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB))
+	handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB), tc.AuditRepo)
 	router := gin.New()
 	router.GET("/zones/:id", handler.GetZone)
 
@@ -187,7 +193,7 @@ func TestZoneHandler_CreateZone(t *testing.T) {
 				token = tt.setupFunc(tc)
 			}
 
-			handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB))
+			handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB), tc.AuditRepo)
 			router := gin.New()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router.Use(authMiddleware.AuthRequired())
@@ -316,7 +322,7 @@ func TestZoneHandler_UpdateZone(t *testing.T) {
 				token = tt.setupFunc(tc)
 			}
 
-			handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB))
+			handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB), tc.AuditRepo)
 			router := gin.New()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router.Use(authMiddleware.AuthRequired())
@@ -437,7 +443,7 @@ func TestZoneHandler_DeleteZone(t *testing.T) {
 				}
 			}
 
-			handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB))
+			handler := handlers.NewZoneHandler(postgres.NewZoneRepository(tc.DB), tc.AuditRepo)
 			router := gin.New()
 			authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
 			router.Use(authMiddleware.AuthRequired())
@@ -469,3 +475,95 @@ func TestZoneHandler_DeleteZone(t *testing.T) {
 		})
 	}
 }
+
+func TestZoneHandler_ForceDeleteZoneInMemory(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	store := memory.NewStore()
+	zoneRepo := memory.NewZoneRepository(store)
+	currencyRepo := memory.NewCurrencyRepository(store)
+	spotPriceRepo := memory.NewSpotPriceRepository(store)
+	sourceRepo := memory.NewSpotPriceSourceRepository(store)
+	auditRepo := memory.NewAuditLogRepository(store)
+
+	admin := &models.User{ID: uuid.New(), Username: "admin"}
+	handler := handlers.NewZoneHandler(zoneRepo, auditRepo)
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user", admin)
+		c.Next()
+	})
+	router.DELETE("/zones/:id", handler.DeleteZone)
+
+	currency, err := currencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	zone := func(name string) *models.Zone {
+		z, err := zoneRepo.GetByName(ctx, name)
+		require.NoError(t, err)
+		return z
+	}
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	addPrices := func(zone *models.Zone, hours int) {
+		var prices []models.SpotPrice
+		for i := 0; i < hours; i++ {
+			prices = append(prices, models.SpotPrice{Timestamp: start.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: 10})
+		}
+		require.NoError(t, sourceRepo.Record(ctx, "nordpool", prices))
+	}
+	remove := func(id uuid.UUID, query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("DELETE", "/zones/"+id.String()+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	count := func(zone *models.Zone) int {
+		prices, err := spotPriceRepo.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID})
+		require.NoError(t, err)
+		return len(prices)
+	}
+
+	t.Run("Without Force", func(t *testing.T) {
+		se1 := zone("SE1")
+		addPrices(se1, 2)
+		w := remove(se1.ID, "")
+		assert.Equal(t, http.StatusConflict, w.Code)
+	})
+
+	t.Run("Delete Spot Prices", func(t *testing.T) {
+		w := remove(zone("SE1").ID, "?force=true")
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var summary models.DeletionSummary
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+		assert.Equal(t, models.DeletionSummary{SpotPricesDeleted: 2, SourceValuesDeleted: 2}, summary)
+		_, err := zoneRepo.GetByName(ctx, "SE1")
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+
+	t.Run("Reassign Spot Prices", func(t *testing.T) {
+		se2, se3 := zone("SE2"), zone("SE3")
+		addPrices(se2, 3)
+		// SE3 has the first hour already, SE2's price for it is dropped
+		addPrices(se3, 1)
+
+		w := remove(se2.ID, "?force=true&reassign_to="+se3.ID.String())
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var summary models.DeletionSummary
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &summary))
+		assert.Equal(t, models.DeletionSummary{SpotPricesDeleted: 1, SpotPricesMoved: 2, SourceValuesDeleted: 1, SourceValuesMoved: 2}, summary)
+		assert.Equal(t, 3, count(se3))
+
+		logs, err := auditRepo.List(ctx, repository.AuditLogFilter{UserID: &admin.ID})
+		require.NoError(t, err)
+		assert.Len(t, logs, 2)
+	})
+
+	t.Run("Invalid Target", func(t *testing.T) {
+		se4 := zone("SE4")
+		assert.Equal(t, http.StatusBadRequest, remove(se4.ID, "?force=true&reassign_to=nope").Code)
+		assert.Equal(t, http.StatusBadRequest, remove(se4.ID, "?force=true&reassign_to="+se4.ID.String()).Code)
+		assert.Equal(t, http.StatusNotFound, remove(se4.ID, "?force=true&reassign_to="+uuid.New().String()).Code)
+		assert.Equal(t, http.StatusNotFound, remove(uuid.New(), "?force=true").Code)
+	})
+}
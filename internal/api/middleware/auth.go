@@ -1,19 +1,24 @@
 package middleware
 
 import (
-	"net/http"
 	"strings"
+	"wattwatch/internal/apierror"
 	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
 
 	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
 )
 
 type AuthMiddleware struct {
-	authService *auth.Service
-	userRepo    repository.UserRepository
-	roleRepo    repository.RoleRepository
+	authService    *auth.Service
+	userRepo       repository.UserRepository
+	roleRepo       repository.RoleRepository
+	cache          *UserCache
+	impersonations repository.ImpersonationRepository
+	integrations   repository.IntegrationTokenRepository
 }
 
 func NewAuthMiddleware(authService *auth.Service, userRepo repository.UserRepository, roleRepo repository.RoleRepository) *AuthMiddleware {
@@ -24,65 +29,172 @@ func NewAuthMiddleware(authService *auth.Service, userRepo repository.UserReposi
 	}
 }
 
+// CacheUsers makes AuthRequired look users up in cache before querying the repositories.
+// The repositories given to handlers that change users or roles should be wrapped by
+// cache.Users and cache.Roles so changes apply immediately.
+func (m *AuthMiddleware) CacheUsers(cache *UserCache) {
+	m.cache = cache
+}
+
+// AuthRequired authenticates the request, checking that the user still exists and that
+// the role claims of the token are still current
 func (m *AuthMiddleware) AuthRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "no authorization header"})
+		claims, userID, ok := m.parseToken(c)
+		if !ok || !m.checkImpersonation(c, claims, userID) {
 			c.Abort()
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid authorization header"})
+		user, ok := m.loadUser(c, userID)
+		if !ok {
 			c.Abort()
 			return
 		}
 
-		claims, err := m.authService.ValidateToken(parts[1])
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+		// Tokens issued before the user's role changed can't be used until refreshed
+		if rc, ok := auth.ParseRoleClaims(claims); ok && !rc.Current(user) {
+			apierror.Write(c, apierror.InvalidToken, "token is outdated, please log in again")
 			c.Abort()
 			return
 		}
 
-		// Get user ID from claims
-		userIDStr, ok := (*claims)["user_id"].(string)
-		if !ok {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid token claims"})
+		// Store full user object in context
+		c.Set("user", user)
+		c.Set("is_admin", user.Role.IsAdminGroup)
+
+		c.Next()
+	}
+}
+
+// ClaimsRequired authenticates the request from the token alone, trusting its role claims
+// for as long as the token is valid. It's meant for read-only endpoints, the user in the
+// context only has the ID, username and role set. Tokens without role claims are checked
+// like in AuthRequired, impersonation tokens are checked against their impersonation.
+func (m *AuthMiddleware) ClaimsRequired() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, userID, ok := m.parseToken(c)
+		if !ok || !m.checkImpersonation(c, claims, userID) {
 			c.Abort()
 			return
 		}
 
-		userID, err := uuid.Parse(userIDStr)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid user id in token"})
+		var user *models.User
+		if rc, ok := auth.ParseRoleClaims(claims); ok {
+			username, _ := claims["username"].(string)
+			user = &models.User{
+				ID:       userID,
+				Username: username,
+				RoleID:   rc.RoleID,
+				Role: &models.Role{
+					ID:           rc.RoleID,
+					Name:         rc.Role,
+					IsAdminGroup: rc.Has(models.PermissionAdmin),
+					Permissions:  rc.Permissions,
+					TokenVersion: rc.Version,
+				},
+			}
+		} else if user, ok = m.loadUser(c, userID); !ok {
 			c.Abort()
 			return
 		}
 
-		// Get full user object from database
-		user, err := m.userRepo.GetByID(c.Request.Context(), userID)
-		if err != nil {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "user not found"})
-			c.Abort()
+		c.Set("user", user)
+		c.Set("is_admin", user.Role.IsAdminGroup)
+
+		c.Next()
+	}
+}
+
+// ClaimsOptional authenticates the request like ClaimsRequired when it has an
+// Authorization header and lets it through anonymously otherwise, for public endpoints that
+// tailor their response to the user
+func (m *AuthMiddleware) ClaimsOptional() gin.HandlerFunc {
+	claimsRequired := m.ClaimsRequired()
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			c.Next()
 			return
 		}
+		claimsRequired(c)
+	}
+}
 
-		// Get user's role
-		role, err := m.roleRepo.GetByID(c.Request.Context(), user.RoleID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to get user role"})
-			c.Abort()
-			return
+// parseToken validates the bearer token of the request and returns its claims and user
+// ID, writing the error response if it can't
+func (m *AuthMiddleware) parseToken(c *gin.Context) (jwt.MapClaims, uuid.UUID, bool) {
+	authHeader := c.GetHeader("Authorization")
+	if authHeader == "" {
+		apierror.Write(c, apierror.Unauthorized, "no authorization header")
+		return nil, uuid.Nil, false
+	}
+
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		apierror.Write(c, apierror.Unauthorized, "invalid authorization header")
+		return nil, uuid.Nil, false
+	}
+
+	claims, err := m.authService.ValidateToken(parts[1])
+	if err != nil {
+		apierror.Write(c, apierror.InvalidToken, err.Error())
+		return nil, uuid.Nil, false
+	}
+
+	// Get user ID from claims
+	userIDStr, ok := (*claims)["user_id"].(string)
+	if !ok {
+		apierror.Write(c, apierror.Unauthorized, "invalid token claims")
+		return nil, uuid.Nil, false
+	}
+
+	userID, err := uuid.Parse(userIDStr)
+	if err != nil {
+		apierror.Write(c, apierror.Unauthorized, "invalid user id in token")
+		return nil, uuid.Nil, false
+	}
+	return *claims, userID, true
+}
+
+// loadUser returns the user with its role, writing the error response if it can't
+func (m *AuthMiddleware) loadUser(c *gin.Context, userID uuid.UUID) (*models.User, bool) {
+	if m.cache != nil {
+		if user, ok := m.cache.Get(userID); ok {
+			return user, true
 		}
-		user.Role = role
+	}
 
-		// Store full user object in context
-		c.Set("user", user)
-		c.Set("is_admin", user.Role.IsAdminGroup)
+	// Get full user object from database
+	user, err := m.userRepo.GetByID(c.Request.Context(), userID)
+	if err != nil {
+		apierror.Write(c, apierror.Unauthorized, "user not found")
+		return nil, false
+	}
+
+	// Get user's role
+	role, err := m.roleRepo.GetByID(c.Request.Context(), user.RoleID)
+	if err != nil {
+		apierror.Write(c, apierror.Internal, "failed to get user role")
+		return nil, false
+	}
+	user.Role = role
+
+	if m.cache != nil {
+		m.cache.Set(user)
+	}
+	return user, true
+}
 
+// RequirePermission lets the request through when the user's role grants the permission,
+// admin groups have every permission. It runs after AuthRequired or ClaimsRequired.
+func (m *AuthMiddleware) RequirePermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		if user == nil || !user.Can(permission) {
+			apierror.Write(c, apierror.Forbidden, "permission "+permission+" required")
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -91,7 +203,7 @@ func (m *AuthMiddleware) AdminRequired() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		isAdmin, exists := c.Get("is_admin")
 		if !exists || !isAdmin.(bool) {
-			c.JSON(http.StatusForbidden, gin.H{"error": "admin access required"})
+			apierror.Write(c, apierror.Forbidden, "admin access required")
 			c.Abort()
 			return
 		}
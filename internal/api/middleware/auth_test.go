@@ -8,10 +8,12 @@ import (
 	"testing"
 	"time"
 	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/models"
 	"wattwatch/internal/testutil"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 )
 
@@ -175,3 +177,105 @@ func TestAuthMiddleware_AdminRequired(t *testing.T) {
 		})
 	}
 }
+
+func TestAuthMiddleware_ClaimsRequired(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tc := testutil.NewMemoryTestContext(t)
+	admin := tc.CreateTestUser("admin", "admin@example.com", "password123", true)
+	user := tc.CreateTestUser("testuser", "test@example.com", "password123", false)
+
+	counting := &countingUserRepository{UserRepository: tc.UserRepo}
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, counting, tc.RoleRepo)
+	router := gin.New()
+	router.GET("/test", authMiddleware.ClaimsRequired(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"is_admin": c.GetBool("is_admin")})
+	})
+	get := func(token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("From Claims", func(t *testing.T) {
+		w := get(tc.GetTestJWT(admin.ID))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"is_admin":true}`, w.Body.String())
+
+		w = get(tc.GetTestJWT(user.ID))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `{"is_admin":false}`, w.Body.String())
+		require.Equal(t, 0, counting.lookups)
+	})
+
+	t.Run("Without Role Claims", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+			"user_id":  user.ID,
+			"username": user.Username,
+			"is_admin": false,
+			"exp":      time.Now().Add(time.Minute).Unix(),
+		})
+		tokenString, err := token.SignedString([]byte(tc.Config.JWTSecret))
+		require.NoError(t, err)
+
+		require.Equal(t, http.StatusOK, get(tokenString).Code)
+		require.Equal(t, 1, counting.lookups)
+	})
+
+	t.Run("Missing Token", func(t *testing.T) {
+		require.Equal(t, http.StatusUnauthorized, get("").Code)
+	})
+}
+
+func TestAuthMiddleware_RequirePermission(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+
+	role := tc.CreateTestRole("price-uploader", false, false)
+	require.NoError(t, tc.RoleRepo.GrantPermission(ctx, role.ID, models.PermissionSpotPricesWrite))
+
+	uploader := tc.CreateTestUser("uploader", "uploader@example.com", "password123", false)
+	uploader.RoleID = role.ID
+	require.NoError(t, tc.UserRepo.Update(ctx, uploader))
+	user := tc.CreateTestUser("regularuser", "user@example.com", "password123", false)
+	admin := tc.CreateTestUser("adminuser", "admin@example.com", "password123", true)
+
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, tc.UserRepo, tc.RoleRepo)
+	router := gin.New()
+	router.GET("/test",
+		authMiddleware.AuthRequired(),
+		authMiddleware.RequirePermission(models.PermissionSpotPricesWrite),
+		func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		},
+	)
+
+	tests := []struct {
+		name       string
+		userID     uuid.UUID
+		wantStatus int
+	}{
+		{name: "Granted", userID: uploader.ID, wantStatus: http.StatusOK},
+		{name: "Admin Has Every Permission", userID: admin.ID, wantStatus: http.StatusOK},
+		{name: "Not Granted", userID: user.ID, wantStatus: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/test", nil)
+			req.Header.Set("Authorization", "Bearer "+tc.GetTestJWT(tt.userID))
+			router.ServeHTTP(w, req)
+
+			require.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantStatus == http.StatusForbidden {
+				var resp gin.H
+				require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+				require.Equal(t, "permission spot_prices:write required", resp["error"])
+			}
+		})
+	}
+}
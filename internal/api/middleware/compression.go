@@ -7,8 +7,10 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"strconv"
 	"strings"
 
+	"github.com/andybalholm/brotli"
 	"github.com/gin-gonic/gin"
 )
 
@@ -27,16 +29,69 @@ type CompressionConfig struct {
 	MinLength int
 	// Gzip compression level (1-9, higher = better compression but slower)
 	Level int
+	// Brotli compression level (0-11, higher = better compression but slower)
+	BrotliLevel int
 }
 
 // DefaultCompressionConfig returns the default compression configuration
 func DefaultCompressionConfig() CompressionConfig {
 	return CompressionConfig{
-		MinLength: 1024, // 1KB
-		Level:     gzip.DefaultCompression,
+		MinLength:   1024, // 1KB
+		Level:       gzip.DefaultCompression,
+		BrotliLevel: 4,
 	}
 }
 
+// encoder compresses a response body
+type encoder interface {
+	io.WriteCloser
+	Flush() error
+}
+
+// newEncoder returns a function creating the encoder of the content coding, nil for
+// codings that aren't supported
+func (cfg CompressionConfig) newEncoder(coding string) func(w io.Writer) (encoder, error) {
+	switch coding {
+	case "br":
+		return func(w io.Writer) (encoder, error) { return brotli.NewWriterLevel(w, cfg.BrotliLevel), nil }
+	case "gzip":
+		return func(w io.Writer) (encoder, error) { return gzip.NewWriterLevel(w, cfg.Level) }
+	}
+	return nil
+}
+
+// supportedCodings are the content codings responses are compressed with, preferred first
+var supportedCodings = []string{"br", "gzip"}
+
+// negotiateCoding returns the supported content coding the Accept-Encoding header gives
+// the highest quality, preferring brotli on ties, and an empty string when it accepts none
+func negotiateCoding(acceptEncoding string) string {
+	qualities := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		qualities[strings.ToLower(strings.TrimSpace(coding))] = q
+	}
+
+	best, bestQ := "", 0.0
+	for _, coding := range supportedCodings {
+		q, ok := qualities[coding]
+		if !ok {
+			q, ok = qualities["*"]
+		}
+		if ok && q > bestQ {
+			best, bestQ = coding, q
+		}
+	}
+	return best
+}
+
 // shouldCompress checks if the response should be compressed based on content type
 func shouldCompress(contentType string) bool {
 	// Skip compression for excluded content types
@@ -48,7 +103,10 @@ func shouldCompress(contentType string) bool {
 	return true
 }
 
-// Compression returns a middleware that compresses HTTP responses using gzip compression
+// Compression returns a middleware that compresses HTTP responses with brotli or gzip,
+// whichever the client prefers. Responses are buffered until they are complete or the
+// handler flushes, so small responses are sent as they are and streamed ones are
+// compressed as they are written.
 func Compression(cfg CompressionConfig) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Check if request is compressed
@@ -68,20 +126,23 @@ func Compression(cfg CompressionConfig) gin.HandlerFunc {
 			c.Request.Body = io.NopCloser(bytes.NewReader(body))
 		}
 
-		// Check if client accepts gzip for response
-		if !strings.Contains(c.Request.Header.Get("Accept-Encoding"), "gzip") {
+		// Check which coding the client accepts for the response. WebSocket handshakes are
+		// left alone as the handler takes over the connection.
+		coding := negotiateCoding(c.Request.Header.Get("Accept-Encoding"))
+		if coding == "" || c.Request.Header.Get("Upgrade") != "" {
 			c.Next()
 			return
 		}
 
-		// Replace writer with gzip writer
-		gzipWriter := &gzipResponseWriter{
+		// Replace writer with compressing writer
+		compressWriter := &compressResponseWriter{
 			ResponseWriter: c.Writer,
 			minLength:      cfg.MinLength,
-			level:          cfg.Level,
+			coding:         coding,
+			newEncoder:     cfg.newEncoder(coding),
 			contentBuf:     new(bytes.Buffer),
 		}
-		c.Writer = gzipWriter
+		c.Writer = compressWriter
 
 		// Add Vary header to prevent caching issues
 		c.Header("Vary", "Accept-Encoding")
@@ -89,81 +150,124 @@ func Compression(cfg CompressionConfig) gin.HandlerFunc {
 		c.Next()
 
 		// Ensure everything is written
-		gzipWriter.finishWriting()
+		compressWriter.finishWriting()
 	}
 }
 
-type gzipResponseWriter struct {
+type compressResponseWriter struct {
 	gin.ResponseWriter
-	writer     *gzip.Writer
+	writer     encoder
 	minLength  int
-	level      int
+	coding     string
+	newEncoder func(w io.Writer) (encoder, error)
 	contentBuf *bytes.Buffer
+	// streaming is set once the handler flushes, after which writes aren't buffered
+	streaming bool
 }
 
-func (g *gzipResponseWriter) Write(data []byte) (int, error) {
+func (g *compressResponseWriter) Write(data []byte) (int, error) {
+	if g.streaming {
+		if g.writer != nil {
+			return g.writer.Write(data)
+		}
+		return g.ResponseWriter.Write(data)
+	}
 	// Write to buffer first
 	return g.contentBuf.Write(data)
 }
 
-func (g *gzipResponseWriter) finishWriting() error {
+// startStreaming writes what was buffered and stops buffering. The length of a streamed
+// response isn't known up front, so it is compressed regardless of the minimum length.
+func (g *compressResponseWriter) startStreaming() error {
+	g.streaming = true
+	content := g.contentBuf.Bytes()
+	g.contentBuf = nil
+
+	if !shouldCompress(g.Header().Get("Content-Type")) {
+		_, err := g.ResponseWriter.Write(content)
+		return err
+	}
+	enc, err := g.newEncoder(g.ResponseWriter)
+	if err != nil {
+		return err
+	}
+	g.Header().Set("Content-Encoding", g.coding)
+	g.Header().Del("Content-Length")
+	g.writer = enc
+	_, err = enc.Write(content)
+	return err
+}
+
+func (g *compressResponseWriter) finishWriting() error {
+	if g.streaming {
+		if g.writer != nil {
+			return g.writer.Close()
+		}
+		return nil
+	}
+
 	contentType := g.Header().Get("Content-Type")
 	content := g.contentBuf.Bytes()
-	shouldGzip := shouldCompress(contentType) && len(content) >= g.minLength
+	shouldEncode := shouldCompress(contentType) && len(content) >= g.minLength
 
-	if shouldGzip {
-		gz, err := gzip.NewWriterLevel(g.ResponseWriter, g.level)
+	if shouldEncode {
+		enc, err := g.newEncoder(g.ResponseWriter)
 		if err != nil {
 			return err
 		}
-		g.Header().Set("Content-Encoding", "gzip")
+		g.Header().Set("Content-Encoding", g.coding)
 		g.Header().Del("Content-Length")
 
-		_, err = gz.Write(content)
+		_, err = enc.Write(content)
 		if err != nil {
-			gz.Close()
+			enc.Close()
 			return err
 		}
 
-		return gz.Close()
+		return enc.Close()
 	}
 
 	_, err := g.ResponseWriter.Write(content)
 	return err
 }
 
-func (g *gzipResponseWriter) WriteString(s string) (int, error) {
+func (g *compressResponseWriter) WriteString(s string) (int, error) {
 	return g.Write([]byte(s))
 }
 
 // Implement other required interfaces
-func (g *gzipResponseWriter) CloseNotify() <-chan bool {
+func (g *compressResponseWriter) CloseNotify() <-chan bool {
 	return g.ResponseWriter.CloseNotify()
 }
 
-func (g *gzipResponseWriter) Flush() {
+func (g *compressResponseWriter) Flush() {
+	if !g.streaming {
+		if err := g.startStreaming(); err != nil {
+			return
+		}
+	}
 	if g.writer != nil {
 		g.writer.Flush()
 	}
 	g.ResponseWriter.Flush()
 }
 
-func (g *gzipResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+func (g *compressResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return g.ResponseWriter.Hijack()
 }
 
-func (g *gzipResponseWriter) Size() int {
+func (g *compressResponseWriter) Size() int {
 	return g.ResponseWriter.Size()
 }
 
-func (g *gzipResponseWriter) Written() bool {
+func (g *compressResponseWriter) Written() bool {
 	return g.ResponseWriter.Written()
 }
 
-func (g *gzipResponseWriter) WriteHeaderNow() {
+func (g *compressResponseWriter) WriteHeaderNow() {
 	g.ResponseWriter.WriteHeaderNow()
 }
 
-func (g *gzipResponseWriter) Status() int {
+func (g *compressResponseWriter) Status() int {
 	return g.ResponseWriter.Status()
 }
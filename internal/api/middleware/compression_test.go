@@ -9,6 +9,7 @@ import (
 	"strings"
 	"testing"
 
+	"github.com/andybalholm/brotli"
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
 )
@@ -173,3 +174,103 @@ func TestInvalidCompressedRequest(t *testing.T) {
 	// Check response
 	assert.Equal(t, http.StatusBadRequest, w.Code)
 }
+
+func TestCompressionStreaming(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Compression(DefaultCompressionConfig()))
+	r.GET("/test", func(c *gin.Context) {
+		c.Header("Content-Type", "application/json")
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("[1")
+		c.Writer.Flush()
+		c.Writer.WriteString(",2]")
+	})
+
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest("GET", "/test", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	r.ServeHTTP(w, req)
+
+	// Flushed responses are compressed even when they are smaller than the minimum length
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.True(t, w.Flushed)
+	reader, err := gzip.NewReader(bytes.NewReader(w.Body.Bytes()))
+	assert.NoError(t, err)
+	defer reader.Close()
+	decompressed, err := io.ReadAll(reader)
+	assert.NoError(t, err)
+	assert.Equal(t, "[1,2]", string(decompressed))
+}
+
+func TestNegotiateCoding(t *testing.T) {
+	tests := []struct {
+		acceptEncoding string
+		expected       string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, deflate, br", "br"},
+		{"gzip;q=1.0, br;q=0.5", "gzip"},
+		{"br;q=0, gzip", "gzip"},
+		{"br;q=0, gzip;q=0", ""},
+		{"*", "br"},
+		{"*;q=0.1, gzip;q=0.5", "gzip"},
+		{"identity", ""},
+		{"BR", "br"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.acceptEncoding, func(t *testing.T) {
+			assert.Equal(t, tt.expected, negotiateCoding(tt.acceptEncoding))
+		})
+	}
+}
+
+func TestBrotliCompression(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(Compression(DefaultCompressionConfig()))
+	data := strings.Repeat("a", 2048)
+	r.GET("/test", func(c *gin.Context) {
+		c.Header("Content-Type", "application/json")
+		c.String(http.StatusOK, data)
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "application/json")
+		c.Status(http.StatusOK)
+		c.Writer.WriteString("[1")
+		c.Writer.Flush()
+		c.Writer.WriteString(",2]")
+	})
+
+	t.Run("Buffered", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/test", nil)
+		req.Header.Set("Accept-Encoding", "gzip, br")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+		assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+		decompressed, err := io.ReadAll(brotli.NewReader(bytes.NewReader(w.Body.Bytes())))
+		assert.NoError(t, err)
+		assert.Equal(t, data, string(decompressed))
+	})
+
+	t.Run("Streaming", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/stream", nil)
+		req.Header.Set("Accept-Encoding", "br")
+		r.ServeHTTP(w, req)
+
+		assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+		assert.True(t, w.Flushed)
+		decompressed, err := io.ReadAll(brotli.NewReader(bytes.NewReader(w.Body.Bytes())))
+		assert.NoError(t, err)
+		assert.Equal(t, "[1,2]", string(decompressed))
+	})
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"wattwatch/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// corsExposedHeaders are the response headers scripts on other origins can read
+var corsExposedHeaders = []string{
+	"Content-Disposition",
+	"ETag",
+	"Retry-After",
+	"X-RateLimit-Limit",
+	"X-RateLimit-Remaining",
+	"X-RateLimit-Reset",
+}
+
+// CORS returns a middleware answering preflight requests and adding CORS headers to the
+// responses of requests from the allowed origins. Requests from other origins are served
+// without the headers, so browsers don't let scripts read the responses.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	origins := cfg.Origins()
+	anyOrigin := slices.Contains(origins, "*")
+	methods := strings.Join(cfg.Methods(), ", ")
+	headers := strings.Join(cfg.Headers(), ", ")
+	exposed := strings.Join(corsExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		// Caches must keep the responses of different origins apart
+		c.Writer.Header().Add("Vary", "Origin")
+
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+		allowed := anyOrigin || slices.ContainsFunc(origins, func(o string) bool {
+			return strings.EqualFold(strings.TrimSuffix(o, "/"), origin)
+		})
+		if !allowed {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if anyOrigin && !cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Header("Access-Control-Allow-Methods", methods)
+			if headers != "" {
+				c.Header("Access-Control-Allow-Headers", headers)
+			}
+			c.Header("Access-Control-Max-Age", maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Header("Access-Control-Expose-Headers", exposed)
+		c.Next()
+	}
+}
@@ -0,0 +1,76 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(cfg config.CORSConfig) *gin.Engine {
+		router := gin.New()
+		router.Use(CORS(cfg))
+		router.GET("/zones", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return router
+	}
+	request := func(router *gin.Engine, method, origin string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(method, "/zones", nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+		}
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	cfg := config.CORSConfig{
+		AllowedOrigins:   "https://dashboard.example.com",
+		AllowedMethods:   "GET,POST",
+		AllowedHeaders:   "Authorization,Content-Type",
+		AllowCredentials: true,
+		MaxAge:           10 * time.Minute,
+	}
+	router := newRouter(cfg)
+
+	w := request(router, http.MethodOptions, "https://dashboard.example.com")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "https://dashboard.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization, Content-Type", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+
+	w = request(router, http.MethodGet, "https://dashboard.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "https://dashboard.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Contains(t, w.Header().Get("Access-Control-Expose-Headers"), "Retry-After")
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	// Other origins get no CORS headers, so browsers block the response
+	w = request(router, http.MethodOptions, "https://evil.example.com")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	w = request(router, http.MethodGet, "https://evil.example.com")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Same-origin and non-browser requests pass untouched
+	w = request(router, http.MethodGet, "")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Vary"))
+
+	// Any origin is allowed with "*", which browsers only accept without credentials
+	router = newRouter(config.CORSConfig{AllowedOrigins: "*", AllowedMethods: "GET"})
+	w = request(router, http.MethodGet, "https://other.example.com")
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+}
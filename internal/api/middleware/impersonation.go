@@ -0,0 +1,69 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// CheckImpersonations lets AuthRequired and ClaimsRequired accept the tokens issued for
+// impersonations stored in repo while they are active. Without it such tokens are refused.
+func (m *AuthMiddleware) CheckImpersonations(repo repository.ImpersonationRepository) {
+	m.impersonations = repo
+}
+
+// checkImpersonation lets tokens issued for an active impersonation through, recording
+// the impersonation in the gin context and the admin in the request context. Other
+// tokens pass unchanged. It writes the error response if the token can't be used.
+func (m *AuthMiddleware) checkImpersonation(c *gin.Context, claims jwt.MapClaims, userID uuid.UUID) bool {
+	ic, ok, err := auth.ParseImpersonationClaims(claims)
+	if !ok {
+		return true
+	}
+	if err != nil || m.impersonations == nil {
+		apierror.Write(c, apierror.Unauthorized, "invalid token claims")
+		return false
+	}
+
+	impersonation, err := m.impersonations.GetByID(c.Request.Context(), ic.ID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		apierror.Write(c, apierror.Internal, "failed to check impersonation")
+		return false
+	}
+	if err != nil || impersonation.UserID != userID || impersonation.AdminID != ic.ImpersonatorID ||
+		!impersonation.Active(time.Now()) {
+		apierror.Write(c, apierror.InvalidToken, "impersonation has ended")
+		return false
+	}
+
+	c.Set("impersonation", impersonation)
+	c.Request = c.Request.WithContext(auth.WithImpersonator(c.Request.Context(), impersonation.AdminID))
+	return true
+}
+
+// AuditImpersonation wraps repo so that the entries created while handling a request made
+// with an impersonation token record the impersonating admin
+func AuditImpersonation(repo repository.AuditLogRepository) repository.AuditLogRepository {
+	return &impersonationAuditLogRepository{AuditLogRepository: repo}
+}
+
+type impersonationAuditLogRepository struct {
+	repository.AuditLogRepository
+}
+
+func (r *impersonationAuditLogRepository) Create(ctx context.Context, log *models.CreateAuditLogRequest) error {
+	if adminID, ok := auth.ImpersonatorFromContext(ctx); ok && log.ImpersonatorID == nil {
+		entry := *log
+		entry.ImpersonatorID = &adminID
+		log = &entry
+	}
+	return r.AuditLogRepository.Create(ctx, log)
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+	"strings"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AcceptIntegrationTokens lets IntegrationTokenRequired authenticate the integration tokens
+// stored in repo. Without it only access tokens are accepted.
+func (m *AuthMiddleware) AcceptIntegrationTokens(repo repository.IntegrationTokenRepository) {
+	m.integrations = repo
+}
+
+// IntegrationTokenRequired authenticates the request with an integration token, which
+// integrations such as Home Assistant keep instead of logging in. Access tokens are
+// accepted too, checked like in ClaimsRequired.
+func (m *AuthMiddleware) IntegrationTokenRequired() gin.HandlerFunc {
+	claimsRequired := m.ClaimsRequired()
+	return func(c *gin.Context) {
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		if !ok || !auth.IsIntegrationToken(token) {
+			claimsRequired(c)
+			return
+		}
+		if m.integrations == nil {
+			apierror.Write(c, apierror.InvalidToken, "integration tokens are not accepted")
+			c.Abort()
+			return
+		}
+
+		integration, err := m.integrations.GetByHash(c.Request.Context(), auth.HashIntegrationToken(token))
+		if errors.Is(err, repository.ErrNotFound) {
+			apierror.Write(c, apierror.InvalidToken, "invalid integration token")
+			c.Abort()
+			return
+		}
+		if err != nil {
+			apierror.Write(c, apierror.Internal, "failed to check integration token")
+			c.Abort()
+			return
+		}
+
+		// Tokens of deleted users stop working along with their logins
+		user, ok := m.loadUser(c, integration.UserID)
+		if !ok {
+			c.Abort()
+			return
+		}
+		if err := m.integrations.MarkUsed(c.Request.Context(), integration.ID, time.Now()); err != nil {
+			log.Printf("Failed to record use of integration token %s: %v", integration.ID, err)
+		}
+
+		c.Set("user", user)
+		c.Set("is_admin", user.Role.IsAdminGroup)
+
+		c.Next()
+	}
+}
@@ -0,0 +1,124 @@
+package middleware
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/netip"
+	"sync"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+)
+
+// deniedRecordInterval is how often a denied request is recorded for each client and filter
+const deniedRecordInterval = time.Minute
+
+// IPFilter refuses requests from client IPs outside its allow list or inside its deny list
+type IPFilter struct {
+	// name identifies the filter in the audit log, e.g. "global" or "admin"
+	name   string
+	ranges config.IPRanges
+
+	mu        sync.Mutex
+	auditRepo repository.AuditLogRepository
+	recorded  map[string]time.Time
+}
+
+// NewIPFilter creates a filter checking clients against ranges. Deny takes precedence over
+// allow, and an empty allow list allows every client not denied.
+func NewIPFilter(name string, ranges config.IPRanges) *IPFilter {
+	return &IPFilter{
+		name:     name,
+		ranges:   ranges,
+		recorded: make(map[string]time.Time),
+	}
+}
+
+// RecordDenials writes an audit log entry when a client is refused, at most once a minute
+// for each client
+func (f *IPFilter) RecordDenials(auditRepo repository.AuditLogRepository) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.auditRepo = auditRepo
+}
+
+// Allowed reports whether the client at ip may pass. Clients without a valid IP, such as
+// those connecting over a Unix socket without a trusted proxy, only pass an empty allow list.
+func (f *IPFilter) Allowed(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return len(f.ranges.Allow) == 0
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range f.ranges.Deny {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+	if len(f.ranges.Allow) == 0 {
+		return true
+	}
+	for _, prefix := range f.ranges.Allow {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Middleware returns a Gin middleware function refusing clients the filter doesn't allow.
+// Requests pass untouched when the filter has no ranges.
+func (f *IPFilter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if f.ranges.Empty() || f.Allowed(c.ClientIP()) {
+			c.Next()
+			return
+		}
+		f.recordDenial(c)
+		apierror.Abort(c, apierror.AddressDenied, "client address not allowed")
+	}
+}
+
+// recordDenial writes the audit log entry for a refused client unless one was written for
+// it during the last interval
+func (f *IPFilter) recordDenial(c *gin.Context) {
+	ip := c.ClientIP()
+	now := time.Now()
+
+	f.mu.Lock()
+	auditRepo := f.auditRepo
+	if auditRepo == nil || now.Sub(f.recorded[ip]) < deniedRecordInterval {
+		f.mu.Unlock()
+		return
+	}
+	f.recorded[ip] = now
+	for k, t := range f.recorded {
+		if now.Sub(t) > deniedRecordInterval {
+			delete(f.recorded, k)
+		}
+	}
+	f.mu.Unlock()
+
+	metadata, _ := json.Marshal(map[string]any{
+		"filter": f.name,
+		"method": c.Request.Method,
+		"path":   c.Request.URL.Path,
+	})
+	if err := auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		Action:      models.AuditActionAddressDenied,
+		EntityType:  "ip_filter",
+		EntityID:    f.name,
+		Description: fmt.Sprintf("Request from %s refused by the %s IP filter", ip, f.name),
+		Metadata:    string(metadata),
+		IPAddress:   ip,
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging denied request: %v", err)
+	}
+}
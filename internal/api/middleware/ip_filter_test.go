@@ -0,0 +1,90 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPFilter_Allowed(t *testing.T) {
+	ranges := func(allow, deny string) config.IPRanges {
+		a, err := config.ParseCIDRs(allow)
+		require.NoError(t, err)
+		d, err := config.ParseCIDRs(deny)
+		require.NoError(t, err)
+		return config.IPRanges{Allow: a, Deny: d}
+	}
+
+	tests := []struct {
+		name    string
+		ranges  config.IPRanges
+		ip      string
+		allowed bool
+	}{
+		{name: "No ranges", ranges: ranges("", ""), ip: "203.0.113.1", allowed: true},
+		{name: "Allowed range", ranges: ranges("10.0.0.0/8", ""), ip: "10.1.2.3", allowed: true},
+		{name: "Outside allowed range", ranges: ranges("10.0.0.0/8", ""), ip: "203.0.113.1", allowed: false},
+		{name: "Single address", ranges: ranges("192.0.2.1", ""), ip: "192.0.2.1", allowed: true},
+		{name: "IPv4-mapped IPv6", ranges: ranges("10.0.0.0/8", ""), ip: "::ffff:10.1.2.3", allowed: true},
+		{name: "IPv6 range", ranges: ranges("2001:db8::/32", ""), ip: "2001:db8::1", allowed: true},
+		{name: "Denied range", ranges: ranges("", "203.0.113.0/24"), ip: "203.0.113.1", allowed: false},
+		{name: "Deny takes precedence", ranges: ranges("10.0.0.0/8", "10.0.0.0/16"), ip: "10.0.1.1", allowed: false},
+		{name: "No address with allow list", ranges: ranges("10.0.0.0/8", ""), ip: "", allowed: false},
+		{name: "No address with deny list", ranges: ranges("", "10.0.0.0/8"), ip: "", allowed: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, NewIPFilter("test", tt.ranges).Allowed(tt.ip))
+		})
+	}
+}
+
+func TestIPFilter_Middleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	allow, err := config.ParseCIDRs("10.0.0.0/8")
+	require.NoError(t, err)
+	auditRepo := memory.NewAuditLogRepository(memory.NewStore())
+	filter := NewIPFilter("admin", config.IPRanges{Allow: allow})
+	filter.RecordDenials(auditRepo)
+
+	router := gin.New()
+	require.NoError(t, router.SetTrustedProxies([]string{"192.0.2.1"}))
+	router.GET("/admin", filter.Middleware(), func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(remoteAddr, forwardedFor string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	assert.Equal(t, http.StatusOK, request("10.0.0.1:1234", ""))
+	assert.Equal(t, http.StatusForbidden, request("203.0.113.1:1234", ""))
+	// The client IP is read from the headers of trusted proxies only
+	assert.Equal(t, http.StatusOK, request("192.0.2.1:1234", "10.0.0.1"))
+	assert.Equal(t, http.StatusForbidden, request("203.0.113.1:1234", "10.0.0.1"))
+
+	// Denials are recorded once a minute for each client
+	logs, err := auditRepo.List(context.Background(), repository.AuditLogFilter{
+		Actions: []models.AuditAction{models.AuditActionAddressDenied},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, "203.0.113.1", logs[0].IPAddress)
+	assert.Equal(t, "admin", logs[0].EntityID)
+}
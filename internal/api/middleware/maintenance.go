@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DefaultMaintenanceRetryAfter is the Retry-After sent when none was given
+const DefaultMaintenanceRetryAfter = 5 * time.Minute
+
+// MaintenanceSettingKey is the key of the settings row holding the maintenance state as JSON
+const MaintenanceSettingKey = "maintenance"
+
+// maintenanceExemptPaths stay reachable during maintenance so monitoring keeps working
+// and administrators can still log in, including with two-factor authentication
+var maintenanceExemptPaths = []string{
+	"/api/v1/health",
+	"/api/v1/auth/login",
+	"/api/v1/auth/login/2fa",
+	"/api/v1/auth/refresh",
+	"/api/v1/openapi.json",
+	"/swagger/",
+}
+
+// MaintenanceMode rejects requests from non-admin clients with 503 while enabled.
+// The state is stored in the settings table, so it survives restarts and every instance
+// applies it once it refreshes.
+type MaintenanceMode struct {
+	authService *auth.Service
+	settings    repository.SettingRepository
+	// setMu serializes changes, so the stored and the current status agree, without
+	// blocking requests reading the status through mu while the change is stored
+	setMu  sync.Mutex
+	mu     sync.RWMutex
+	status models.MaintenanceStatus
+}
+
+// NewMaintenanceMode creates a disabled maintenance mode storing its state in settings, nil
+// keeps the state in memory. Call Refresh to load the stored state.
+func NewMaintenanceMode(authService *auth.Service, settings repository.SettingRepository) *MaintenanceMode {
+	return &MaintenanceMode{
+		authService: authService,
+		settings:    settings,
+		status: models.MaintenanceStatus{
+			RetryAfterSeconds: int(DefaultMaintenanceRetryAfter.Seconds()),
+		},
+	}
+}
+
+// Status returns the current maintenance state
+func (m *MaintenanceMode) Status() models.MaintenanceStatus {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.status
+}
+
+// Set turns maintenance mode on or off, stores the new state and returns it
+func (m *MaintenanceMode) Set(ctx context.Context, enabled bool, message string, retryAfter time.Duration, updatedBy *uuid.UUID) (models.MaintenanceStatus, error) {
+	m.setMu.Lock()
+	defer m.setMu.Unlock()
+	current := m.Status()
+
+	if retryAfter <= 0 {
+		retryAfter = DefaultMaintenanceRetryAfter
+	}
+
+	status := models.MaintenanceStatus{
+		Enabled:           enabled,
+		RetryAfterSeconds: int(retryAfter.Seconds()),
+	}
+	if enabled {
+		status.Message = message
+		// Keep the original start time when only the message changes
+		if current.Enabled && current.Since != nil {
+			status.Since = current.Since
+		} else {
+			now := time.Now()
+			status.Since = &now
+		}
+	}
+
+	if m.settings != nil {
+		value, err := json.Marshal(status)
+		if err != nil {
+			return current, err
+		}
+		setting := &models.Setting{Key: MaintenanceSettingKey, Value: string(value), UpdatedBy: updatedBy}
+		if err := m.settings.Upsert(ctx, setting); err != nil {
+			return current, fmt.Errorf("failed to store maintenance mode: %w", err)
+		}
+	}
+
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+	return status, nil
+}
+
+// Refresh loads the state stored by any instance
+func (m *MaintenanceMode) Refresh(ctx context.Context) error {
+	if m.settings == nil {
+		return nil
+	}
+	list, err := m.settings.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load maintenance mode: %w", err)
+	}
+
+	status := models.MaintenanceStatus{RetryAfterSeconds: int(DefaultMaintenanceRetryAfter.Seconds())}
+	for _, setting := range list {
+		if setting.Key != MaintenanceSettingKey {
+			continue
+		}
+		if err := json.Unmarshal([]byte(setting.Value), &status); err != nil {
+			return fmt.Errorf("invalid maintenance mode %q: %w", setting.Value, err)
+		}
+	}
+
+	m.mu.Lock()
+	m.status = status
+	m.mu.Unlock()
+	return nil
+}
+
+// Run refreshes the state every interval until ctx is cancelled
+func (m *MaintenanceMode) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := m.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to refresh maintenance mode: %v", err)
+			}
+		}
+	}
+}
+
+// Middleware returns a Gin middleware function that enforces maintenance mode
+func (m *MaintenanceMode) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		status := m.Status()
+		if !status.Enabled || isMaintenanceExempt(c.Request.URL.Path) || m.isAdminRequest(c) {
+			c.Next()
+			return
+		}
+
+		message := status.Message
+		if message == "" {
+			message = "service is under maintenance"
+		}
+		c.Header("Retry-After", strconv.Itoa(status.RetryAfterSeconds))
+		apierror.Write(c, apierror.Maintenance, message)
+		c.Abort()
+	}
+}
+
+// isAdminRequest checks the bearer token's admin claim. Admin routes still
+// verify the role against the database.
+func (m *MaintenanceMode) isAdminRequest(c *gin.Context) bool {
+	token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+	if !ok {
+		return false
+	}
+	claims, err := m.authService.ValidateToken(token)
+	if err != nil {
+		return false
+	}
+	isAdmin, _ := (*claims)["is_admin"].(bool)
+	return isAdmin
+}
+
+func isMaintenanceExempt(path string) bool {
+	for _, exempt := range maintenanceExemptPaths {
+		if path == exempt || (strings.HasSuffix(exempt, "/") && strings.HasPrefix(path, exempt)) {
+			return true
+		}
+	}
+	return false
+}
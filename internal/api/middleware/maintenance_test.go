@@ -0,0 +1,169 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMaintenanceMode(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	authService := auth.NewService(&config.Config{JWTSecret: "test-secret"}, nil)
+	token := func(isAdmin bool) string {
+		user := &models.User{ID: uuid.New(), Username: "user", Role: &models.Role{IsAdminGroup: isAdmin}}
+		token, err := authService.GenerateToken(user, false)
+		require.NoError(t, err)
+		return token
+	}
+
+	maintenance := NewMaintenanceMode(authService, nil)
+	router := gin.New()
+	router.Use(maintenance.Middleware())
+	for _, path := range []string{"/api/v1/zones", "/api/v1/health", "/api/v1/auth/login", "/api/v1/auth/login/2fa"} {
+		router.Any(path, func(c *gin.Context) { c.Status(http.StatusOK) })
+	}
+
+	tests := []struct {
+		name           string
+		enabled        bool
+		method         string
+		path           string
+		token          string
+		wantStatus     int
+		wantRetryAfter string
+	}{
+		{name: "Disabled", path: "/api/v1/zones", wantStatus: http.StatusOK},
+		{name: "Anonymous", enabled: true, path: "/api/v1/zones", wantStatus: http.StatusServiceUnavailable, wantRetryAfter: "120"},
+		{name: "Non Admin", enabled: true, path: "/api/v1/zones", token: token(false), wantStatus: http.StatusServiceUnavailable, wantRetryAfter: "120"},
+		{name: "Invalid Token", enabled: true, path: "/api/v1/zones", token: "invalid", wantStatus: http.StatusServiceUnavailable, wantRetryAfter: "120"},
+		{name: "Admin", enabled: true, path: "/api/v1/zones", token: token(true), wantStatus: http.StatusOK},
+		{name: "Health", enabled: true, path: "/api/v1/health", wantStatus: http.StatusOK},
+		{name: "Login", enabled: true, method: "POST", path: "/api/v1/auth/login", wantStatus: http.StatusOK},
+		{name: "Two Factor Login", enabled: true, method: "POST", path: "/api/v1/auth/login/2fa", wantStatus: http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := maintenance.Set(context.Background(), tt.enabled, "upgrading", 2*time.Minute, nil)
+			require.NoError(t, err)
+
+			method := tt.method
+			if method == "" {
+				method = "GET"
+			}
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(method, tt.path, nil)
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			assert.Equal(t, tt.wantRetryAfter, w.Header().Get("Retry-After"))
+			if tt.wantStatus == http.StatusServiceUnavailable {
+				assert.Contains(t, w.Body.String(), "upgrading")
+			}
+		})
+	}
+}
+
+func TestMaintenanceMode_Set(t *testing.T) {
+	ctx := context.Background()
+	maintenance := NewMaintenanceMode(nil, nil)
+	require.False(t, maintenance.Status().Enabled)
+
+	status, err := maintenance.Set(ctx, true, "first", 0, nil)
+	require.NoError(t, err)
+	require.True(t, status.Enabled)
+	require.Equal(t, int(DefaultMaintenanceRetryAfter.Seconds()), status.RetryAfterSeconds)
+	require.NotNil(t, status.Since)
+	since := *status.Since
+
+	// Updating the message keeps the start time
+	status, err = maintenance.Set(ctx, true, "second", time.Minute, nil)
+	require.NoError(t, err)
+	require.Equal(t, since, *status.Since)
+	require.Equal(t, "second", status.Message)
+	require.Equal(t, 60, status.RetryAfterSeconds)
+
+	status, err = maintenance.Set(ctx, false, "ignored", 0, nil)
+	require.NoError(t, err)
+	require.False(t, status.Enabled)
+	require.Empty(t, status.Message)
+	require.Nil(t, status.Since)
+}
+
+func TestMaintenanceMode_Refresh(t *testing.T) {
+	ctx := context.Background()
+	settings := memory.NewSettingRepository(memory.NewStore())
+	first := NewMaintenanceMode(nil, settings)
+	second := NewMaintenanceMode(nil, settings)
+
+	// Instances apply the state another one set once they refresh
+	status, err := first.Set(ctx, true, "upgrading", time.Minute, nil)
+	require.NoError(t, err)
+	require.False(t, second.Status().Enabled)
+	require.NoError(t, second.Refresh(ctx))
+	assert.True(t, second.Status().Enabled)
+	assert.Equal(t, "upgrading", second.Status().Message)
+	assert.Equal(t, 60, second.Status().RetryAfterSeconds)
+	assert.True(t, status.Since.Equal(*second.Status().Since))
+
+	// Restarted instances start with the stored state
+	restarted := NewMaintenanceMode(nil, settings)
+	require.NoError(t, restarted.Refresh(ctx))
+	assert.True(t, restarted.Status().Enabled)
+
+	_, err = second.Set(ctx, false, "", 0, nil)
+	require.NoError(t, err)
+	require.NoError(t, first.Refresh(ctx))
+	assert.False(t, first.Status().Enabled)
+}
+
+// blockingSettings holds upserts until release is closed
+type blockingSettings struct {
+	repository.SettingRepository
+	upserting chan struct{}
+	release   chan struct{}
+}
+
+func (s *blockingSettings) Upsert(ctx context.Context, setting *models.Setting) error {
+	close(s.upserting)
+	<-s.release
+	return s.SettingRepository.Upsert(ctx, setting)
+}
+
+func TestMaintenanceMode_SetDoesNotBlockStatus(t *testing.T) {
+	settings := &blockingSettings{
+		SettingRepository: memory.NewSettingRepository(memory.NewStore()),
+		upserting:         make(chan struct{}),
+		release:           make(chan struct{}),
+	}
+	maintenance := NewMaintenanceMode(nil, settings)
+
+	done := make(chan error)
+	go func() {
+		_, err := maintenance.Set(context.Background(), true, "upgrading", 0, nil)
+		done <- err
+	}()
+	<-settings.upserting
+
+	// Requests read the previous state while the new one is stored
+	assert.False(t, maintenance.Status().Enabled)
+	close(settings.release)
+	require.NoError(t, <-done)
+	assert.True(t, maintenance.Status().Enabled)
+}
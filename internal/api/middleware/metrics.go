@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"crypto/subtle"
+	"strings"
+	"time"
+	"wattwatch/internal/apierror"
+	"wattwatch/internal/metrics"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Metrics records the count and duration of every request by route. Requests that match
+// no route share the "other" route so unknown paths can't create new series.
+func Metrics() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "other"
+		}
+		metrics.ObserveRequest(c.Request.Method, route, c.Writer.Status(), time.Since(start))
+	}
+}
+
+// MetricsHandler serves the Prometheus metrics. When token is set, scrapers must send it
+// as a bearer token.
+func MetricsHandler(token string) gin.HandlerFunc {
+	handler := metrics.Handler()
+	return func(c *gin.Context) {
+		if token != "" {
+			given, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+			if !ok || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+				apierror.Abort(c, apierror.InvalidToken, "invalid metrics token")
+				return
+			}
+		}
+		handler.ServeHTTP(c.Writer, c.Request)
+	}
+}
@@ -0,0 +1,52 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetrics(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.GET("/metrics", MetricsHandler("secret"))
+	r.Use(Metrics())
+	r.GET("/zones/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"id": c.Param("id")})
+	})
+
+	send := func(path, token string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	send("/zones/1", "")
+	send("/zones/2", "")
+	send("/unknown/path", "")
+
+	t.Run("Requires Token", func(t *testing.T) {
+		assert.Equal(t, http.StatusUnauthorized, send("/metrics", "").Code)
+		assert.Equal(t, http.StatusUnauthorized, send("/metrics", "wrong").Code)
+	})
+
+	t.Run("Records Route Patterns", func(t *testing.T) {
+		w := send("/metrics", "secret")
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		body := w.Body.String()
+		assert.Contains(t, body, `wattwatch_http_requests_total{method="GET",route="/zones/:id",status="200"} 2`)
+		assert.Contains(t, body, `wattwatch_http_requests_total{method="GET",route="other",status="404"} 1`)
+		assert.NotContains(t, body, `route="/metrics"`)
+		assert.False(t, strings.Contains(body, "/zones/1"))
+	})
+}
@@ -1,99 +1,122 @@
 package middleware
 
 import (
+	"context"
+	"encoding/json"
 	"fmt"
-	"net/http"
+	"log"
 	"sync"
 	"time"
+	"wattwatch/internal/apierror"
 	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
 
 	"github.com/gin-gonic/gin"
 	"golang.org/x/time/rate"
 )
 
+// Rate limit policies. The default policy applies to every request, route groups can add
+// a stricter one on top of it.
+const (
+	RateLimitDefault = "default"
+	RateLimitAuth    = "auth"
+)
+
+// RateLimitPolicy allows a client Requests requests per Window, refilled evenly over the window
+type RateLimitPolicy struct {
+	Requests int
+	Window   time.Duration
+}
+
+// RateLimitStore keeps the token buckets of rate limited clients
+type RateLimitStore interface {
+	// Take removes a token from the bucket of key, which refills at the rate of policy. It
+	// returns the tokens left and, when the bucket was empty, how long until the next one.
+	Take(ctx context.Context, key string, policy RateLimitPolicy) (remaining int, retryAfter time.Duration, err error)
+}
+
 // RateLimiter implements rate limiting using token bucket algorithm
 type RateLimiter struct {
-	limiters map[string]*rate.Limiter
+	store    RateLimitStore
 	mu       sync.RWMutex
-	rate     rate.Limit
-	burst    int
-	cleanup  time.Duration
-	window   int // Store window size for header calculations
-	requests int // Store total requests for header calculations
+	policies map[string]RateLimitPolicy
+	// auditRepo records violations, recorded holds when a client was last recorded for a
+	// policy so a client hammering the API doesn't flood the audit log
+	auditRepo repository.AuditLogRepository
+	recorded  map[string]time.Time
 }
 
-// NewRateLimiter creates a new rate limiter middleware
+// NewRateLimiter creates a new rate limiter middleware with buckets kept in memory
 func NewRateLimiter(cfg *config.Config) *RateLimiter {
-	// Calculate rate as requests per second
-	ratePerSecond := rate.Every(time.Duration(cfg.RateLimit.Window) * time.Second / time.Duration(cfg.RateLimit.Requests))
-
 	limiter := &RateLimiter{
-		limiters: make(map[string]*rate.Limiter),
-		rate:     rate.Limit(ratePerSecond),
-		burst:    cfg.RateLimit.Requests, // Use total requests as burst
-		cleanup:  time.Hour,
-		window:   cfg.RateLimit.Window,
-		requests: cfg.RateLimit.Requests,
+		store:    newMemoryRateLimitStore(time.Hour),
+		policies: make(map[string]RateLimitPolicy),
+		recorded: make(map[string]time.Time),
+	}
+	limiter.SetLimits(cfg.RateLimit.Requests, cfg.RateLimit.Window)
+	if cfg.RateLimit.AuthRequests > 0 {
+		limiter.SetPolicy(RateLimitAuth, cfg.RateLimit.AuthRequests, cfg.RateLimit.AuthWindow)
 	}
-
-	// Start cleanup routine
-	go limiter.cleanupRoutine()
-
 	return limiter
 }
 
-// getLimiter returns a rate limiter for the given key
-func (rl *RateLimiter) getLimiter(key string) *rate.Limiter {
+// UseStore replaces the store of the token buckets, such as with a Redis store shared
+// by all instances
+func (rl *RateLimiter) UseStore(store RateLimitStore) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.store = store
+}
+
+// Run drops the buckets kept in memory every hour until ctx is cancelled. It returns at once
+// when the buckets are kept in another store.
+func (rl *RateLimiter) Run(ctx context.Context) {
 	rl.mu.RLock()
-	limiter, exists := rl.limiters[key]
+	store, ok := rl.store.(*memoryRateLimitStore)
 	rl.mu.RUnlock()
-
-	if exists {
-		return limiter
+	if ok {
+		store.run(ctx)
 	}
+}
 
+// RecordViolations writes an audit log entry when a client exceeds a policy, at most
+// once per window for each client and policy
+func (rl *RateLimiter) RecordViolations(auditRepo repository.AuditLogRepository) {
 	rl.mu.Lock()
 	defer rl.mu.Unlock()
+	rl.auditRepo = auditRepo
+}
 
-	// Double check after acquiring write lock
-	limiter, exists = rl.limiters[key]
-	if exists {
-		return limiter
-	}
-
-	// Create new limiter with full capacity
-	limiter = rate.NewLimiter(rl.rate, rl.burst)
-
-	// Reserve initial tokens to allow immediate requests
-	now := time.Now()
-	r := limiter.ReserveN(now, 0) // Reserve 0 tokens to get rate info
-	if r.OK() {
-		delay := r.Delay()
-		if delay > 0 {
-			limiter.AllowN(now.Add(-delay), 1) // Pre-fill one token if there would be a delay
-		}
-	}
-
-	rl.limiters[key] = limiter
-	return limiter
+// SetLimits changes the number of requests allowed per window in seconds by the default
+// policy. The new limits apply immediately since buckets are kept per limit.
+func (rl *RateLimiter) SetLimits(requests, window int) {
+	rl.SetPolicy(RateLimitDefault, requests, window)
 }
 
-// cleanupRoutine periodically removes old limiters
-func (rl *RateLimiter) cleanupRoutine() {
-	ticker := time.NewTicker(rl.cleanup)
-	defer ticker.Stop()
+// SetPolicy adds or changes the named policy, allowing requests per window in seconds
+func (rl *RateLimiter) SetPolicy(name string, requests, window int) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	rl.policies[name] = RateLimitPolicy{Requests: requests, Window: time.Duration(window) * time.Second}
+}
 
-	for range ticker.C {
-		rl.mu.Lock()
-		// In a production environment, you might want to track last access time
-		// and only remove limiters that haven't been used for a while
-		rl.limiters = make(map[string]*rate.Limiter)
-		rl.mu.Unlock()
-	}
+// policy returns the named policy and the store to apply it with
+func (rl *RateLimiter) policy(name string) (RateLimitPolicy, RateLimitStore, bool) {
+	rl.mu.RLock()
+	defer rl.mu.RUnlock()
+	policy, ok := rl.policies[name]
+	return policy, rl.store, ok
 }
 
-// Middleware returns a Gin middleware function that implements rate limiting
+// Middleware returns a Gin middleware function that applies the default policy
 func (rl *RateLimiter) Middleware() gin.HandlerFunc {
+	return rl.Policy(RateLimitDefault)
+}
+
+// Policy returns a Gin middleware function that applies the named policy. Requests pass
+// when the policy isn't configured.
+func (rl *RateLimiter) Policy(name string) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Skip rate limiting for Swagger documentation
 		if c.Request.URL.Path == "/swagger/index.html" ||
@@ -103,51 +126,163 @@ func (rl *RateLimiter) Middleware() gin.HandlerFunc {
 			return
 		}
 
-		key := c.ClientIP()
-		limiter := rl.getLimiter(key)
+		policy, store, ok := rl.policy(name)
+		if !ok || policy.Requests <= 0 || policy.Window <= 0 {
+			c.Next()
+			return
+		}
 
-		// Try to reserve a token
+		// Buckets are kept per limit, so changed limits start with fresh buckets
+		key := fmt.Sprintf("%s:%d:%d:%s", name, policy.Requests, int(policy.Window.Seconds()), c.ClientIP())
 		now := time.Now()
-		r := limiter.ReserveN(now, 1)
-		if !r.OK() {
-			c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", rl.requests))
-			c.Header("X-RateLimit-Remaining", "0")
-			c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", now.Add(time.Duration(rl.window)*time.Second).Unix()))
-			c.Header("Retry-After", fmt.Sprintf("%d", rl.window))
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       "rate limit exceeded",
-				"retry_after": fmt.Sprintf("%ds", rl.window),
-			})
-			c.Abort()
+		remaining, retryAfter, err := store.Take(c.Request.Context(), key, policy)
+		if err != nil {
+			// Let requests through rather than failing them when the store is unavailable
+			log.Printf("Rate limiting with policy %s failed: %v", name, err)
+			c.Next()
 			return
 		}
 
-		// Calculate delay
-		delay := r.Delay()
-		if delay > 0 {
-			c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", rl.requests))
+		c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", policy.Requests))
+		if retryAfter > 0 {
+			// Round up so clients retrying after the given seconds find a token
+			seconds := int((retryAfter + time.Second - 1) / time.Second)
 			c.Header("X-RateLimit-Remaining", "0")
-			c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", now.Add(delay).Unix()))
-			c.Header("Retry-After", fmt.Sprintf("%d", int(delay.Seconds())))
-			c.JSON(http.StatusTooManyRequests, gin.H{
-				"error":       "rate limit exceeded",
-				"retry_after": fmt.Sprintf("%ds", int(delay.Seconds())),
-			})
-			c.Abort()
+			c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", now.Add(retryAfter).Unix()))
+			c.Header("Retry-After", fmt.Sprintf("%d", seconds))
+			rl.recordViolation(c, name, policy)
+			apierror.Abort(c, apierror.RateLimited, fmt.Sprintf("rate limit exceeded, retry after %ds", seconds))
 			return
 		}
 
-		// Calculate remaining tokens
-		tokens := int(limiter.Tokens())
-		if tokens > rl.requests {
-			tokens = rl.requests
+		c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", min(remaining, policy.Requests)))
+		c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", now.Add(policy.Window).Unix()))
+
+		c.Next()
+	}
+}
+
+// recordViolation writes the audit log entry for a client exceeding the policy unless one
+// was written for it during the last window
+func (rl *RateLimiter) recordViolation(c *gin.Context, name string, policy RateLimitPolicy) {
+	key := name + ":" + c.ClientIP()
+	now := time.Now()
+
+	rl.mu.Lock()
+	auditRepo := rl.auditRepo
+	if auditRepo == nil || now.Sub(rl.recorded[key]) < policy.Window {
+		rl.mu.Unlock()
+		return
+	}
+	rl.recorded[key] = now
+	// Forget clients after an hour, at worst a violation is recorded twice in a long window
+	for k, t := range rl.recorded {
+		if now.Sub(t) > time.Hour {
+			delete(rl.recorded, k)
 		}
+	}
+	rl.mu.Unlock()
+
+	metadata, _ := json.Marshal(map[string]any{
+		"policy":   name,
+		"requests": policy.Requests,
+		"window":   int(policy.Window.Seconds()),
+		"method":   c.Request.Method,
+		"path":     c.Request.URL.Path,
+	})
+	if err := auditRepo.Create(c.Request.Context(), &models.CreateAuditLogRequest{
+		Action:      models.AuditActionRateLimited,
+		EntityType:  "rate_limit",
+		EntityID:    name,
+		Description: fmt.Sprintf("Rate limit %s exceeded", name),
+		Metadata:    string(metadata),
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.GetHeader("User-Agent"),
+	}); err != nil {
+		log.Printf("Error logging rate limit violation: %v", err)
+	}
+}
 
-		// Add rate limit headers
-		c.Header("X-RateLimit-Limit", fmt.Sprintf("%d", rl.requests))
-		c.Header("X-RateLimit-Remaining", fmt.Sprintf("%d", tokens))
-		c.Header("X-RateLimit-Reset", fmt.Sprintf("%d", now.Add(time.Duration(rl.window)*time.Second).Unix()))
+// memoryRateLimitStore keeps token buckets in memory, limiting clients per process
+type memoryRateLimitStore struct {
+	limiters map[string]*rate.Limiter
+	mu       sync.RWMutex
+	cleanup  time.Duration
+}
 
-		c.Next()
+// newMemoryRateLimitStore creates a store that drops its buckets every cleanup interval
+// while run is running
+func newMemoryRateLimitStore(cleanup time.Duration) *memoryRateLimitStore {
+	return &memoryRateLimitStore{
+		limiters: make(map[string]*rate.Limiter),
+		cleanup:  cleanup,
+	}
+}
+
+// Take implements RateLimitStore
+func (s *memoryRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (int, time.Duration, error) {
+	limiter := s.getLimiter(key, policy)
+
+	now := time.Now()
+	r := limiter.ReserveN(now, 1)
+	if !r.OK() {
+		return 0, policy.Window, nil
 	}
+	if delay := r.DelayFrom(now); delay > 0 {
+		// Give the token back, rejected requests don't count against the client
+		r.CancelAt(now)
+		return 0, delay, nil
+	}
+	return int(limiter.TokensAt(now)), 0, nil
+}
+
+// getLimiter returns a rate limiter for the given key, starting with a full bucket
+func (s *memoryRateLimitStore) getLimiter(key string, policy RateLimitPolicy) *rate.Limiter {
+	s.mu.RLock()
+	limiter, exists := s.limiters[key]
+	s.mu.RUnlock()
+
+	if exists {
+		return limiter
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Double check after acquiring write lock
+	limiter, exists = s.limiters[key]
+	if exists {
+		return limiter
+	}
+
+	// Use total requests as burst
+	limiter = rate.NewLimiter(rate.Every(policy.Window/time.Duration(policy.Requests)), policy.Requests)
+	s.limiters[key] = limiter
+	return limiter
+}
+
+// run periodically removes old limiters until ctx is cancelled
+func (s *memoryRateLimitStore) run(ctx context.Context) {
+	ticker := time.NewTicker(s.cleanup)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.mu.Lock()
+			// In a production environment, you might want to track last access time
+			// and only remove limiters that haven't been used for a while
+			s.limiters = make(map[string]*rate.Limiter)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// size returns the number of limiters kept
+func (s *memoryRateLimitStore) size() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.limiters)
 }
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeTokenScript refills the bucket in KEYS[1] for the time passed since it was last used
+// and takes a token when there is one. ARGV holds the refill rate in tokens per
+// millisecond, the bucket size, the current time in milliseconds and the expiry of idle
+// buckets in milliseconds. It returns 1 when a token was taken and the tokens left.
+var takeTokenScript = redis.NewScript(`
+local rate = tonumber(ARGV[1])
+local burst = tonumber(ARGV[2])
+local now = tonumber(ARGV[3])
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1]) or burst
+local ts = tonumber(state[2]) or now
+tokens = math.min(burst, tokens + math.max(0, now - ts) * rate)
+local taken = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	taken = 1
+end
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now)
+redis.call("PEXPIRE", KEYS[1], ARGV[4])
+return {taken, tostring(tokens)}
+`)
+
+// RedisRateLimitStore keeps token buckets in Redis so every instance shares them
+type RedisRateLimitStore struct {
+	client *redis.Client
+	prefix string
+}
+
+// NewRedisRateLimitStore connects to the Redis server at url, such as redis://localhost:6379/0
+func NewRedisRateLimitStore(url string) (*RedisRateLimitStore, error) {
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis URL: %w", err)
+	}
+	return &RedisRateLimitStore{client: redis.NewClient(opts), prefix: "wattwatch:ratelimit:"}, nil
+}
+
+// Ping checks that the Redis server can be reached
+func (s *RedisRateLimitStore) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close closes the connections to Redis
+func (s *RedisRateLimitStore) Close() error {
+	return s.client.Close()
+}
+
+// Take implements RateLimitStore
+func (s *RedisRateLimitStore) Take(ctx context.Context, key string, policy RateLimitPolicy) (int, time.Duration, error) {
+	perMilli := float64(policy.Requests) / float64(policy.Window.Milliseconds())
+	result, err := takeTokenScript.Run(ctx, s.client, []string{s.prefix + key},
+		strconv.FormatFloat(perMilli, 'g', -1, 64),
+		policy.Requests,
+		time.Now().UnixMilli(),
+		policy.Window.Milliseconds(),
+	).Slice()
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(result) != 2 {
+		return 0, 0, fmt.Errorf("unexpected rate limit script result %v", result)
+	}
+
+	taken, _ := result[0].(int64)
+	tokensValue, _ := result[1].(string)
+	tokens, err := strconv.ParseFloat(tokensValue, 64)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid token count %q: %w", tokensValue, err)
+	}
+	if taken == 1 {
+		return int(tokens), 0, nil
+	}
+	// Time until the bucket holds a whole token again
+	wait := math.Max(1, math.Ceil((1-tokens)/perMilli))
+	return 0, time.Duration(wait) * time.Millisecond, nil
+}
@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
 
 	"github.com/gin-gonic/gin"
 	"github.com/stretchr/testify/assert"
@@ -15,13 +19,6 @@ func TestRateLimiter(t *testing.T) {
 	// Set Gin to test mode
 	gin.SetMode(gin.TestMode)
 
-	// Define RateLimit struct type to match config
-	type RateLimit struct {
-		Requests int `envconfig:"RATE_LIMIT_REQUESTS" default:"1000"`
-		Window   int `envconfig:"RATE_LIMIT_WINDOW" default:"60"`
-		Burst    int `envconfig:"RATE_LIMIT_BURST" default:"50"`
-	}
-
 	tests := []struct {
 		name          string
 		config        config.Config
@@ -34,7 +31,7 @@ func TestRateLimiter(t *testing.T) {
 		{
 			name: "Normal usage - under limit",
 			config: config.Config{
-				RateLimit: RateLimit{
+				RateLimit: config.RateLimitConfig{
 					Requests: 10,
 					Window:   1,
 					Burst:    10,
@@ -49,7 +46,7 @@ func TestRateLimiter(t *testing.T) {
 		{
 			name: "At rate limit",
 			config: config.Config{
-				RateLimit: RateLimit{
+				RateLimit: config.RateLimitConfig{
 					Requests: 2,
 					Window:   1,
 					Burst:    2,
@@ -64,7 +61,7 @@ func TestRateLimiter(t *testing.T) {
 		{
 			name: "Exceeds rate limit",
 			config: config.Config{
-				RateLimit: RateLimit{
+				RateLimit: config.RateLimitConfig{
 					Requests: 2,
 					Window:   1,
 					Burst:    2,
@@ -79,7 +76,7 @@ func TestRateLimiter(t *testing.T) {
 		{
 			name: "Different IPs - separate limits",
 			config: config.Config{
-				RateLimit: RateLimit{
+				RateLimit: config.RateLimitConfig{
 					Requests: 1,
 					Window:   1,
 					Burst:    1,
@@ -152,37 +149,103 @@ func TestRateLimiter(t *testing.T) {
 }
 
 func TestRateLimiterCleanup(t *testing.T) {
-	type RateLimit struct {
-		Requests int `envconfig:"RATE_LIMIT_REQUESTS" default:"1000"`
-		Window   int `envconfig:"RATE_LIMIT_WINDOW" default:"60"`
-		Burst    int `envconfig:"RATE_LIMIT_BURST" default:"50"`
-	}
-
-	cfg := &config.Config{
-		RateLimit: RateLimit{
-			Requests: 10,
-			Window:   1,
-			Burst:    10,
-		},
-	}
-
-	limiter := NewRateLimiter(cfg)
-
-	// Override cleanup duration for testing
-	limiter.cleanup = 100 * time.Millisecond
+	// Use a short cleanup interval for testing
+	store := newMemoryRateLimitStore(100 * time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go store.run(ctx)
+	policy := RateLimitPolicy{Requests: 10, Window: time.Second}
 
 	// Create some test limiters
 	ips := []string{"192.168.1.1", "192.168.1.2", "192.168.1.3"}
 	for _, ip := range ips {
-		limiter.getLimiter(ip)
+		_, _, err := store.Take(context.Background(), ip, policy)
+		assert.NoError(t, err)
 	}
 
 	// Verify limiters were created
-	assert.Equal(t, len(ips), len(limiter.limiters), "Expected limiters to be created")
+	assert.Equal(t, len(ips), store.size(), "Expected limiters to be created")
 
 	// Wait for cleanup
 	time.Sleep(150 * time.Millisecond)
 
 	// Verify cleanup occurred
-	assert.Equal(t, 0, len(limiter.limiters), "Expected limiters to be cleaned up")
+	assert.Equal(t, 0, store.size(), "Expected limiters to be cleaned up")
+}
+
+func TestRateLimiterSetLimits(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RateLimit.Requests = 1
+	cfg.RateLimit.Window = 60
+
+	limiter := NewRateLimiter(cfg)
+	router := gin.New()
+	router.Use(limiter.Middleware())
+	router.GET("/", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest("GET", "/", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	assert.Equal(t, http.StatusOK, request().Code)
+	assert.Equal(t, http.StatusTooManyRequests, request().Code)
+
+	limiter.SetLimits(10, 60)
+
+	w := request()
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "10", w.Header().Get("X-RateLimit-Limit"))
+}
+
+func TestRateLimiterPolicy(t *testing.T) {
+	cfg := &config.Config{}
+	cfg.RateLimit.Requests = 100
+	cfg.RateLimit.Window = 60
+	cfg.RateLimit.AuthRequests = 2
+	cfg.RateLimit.AuthWindow = 60
+
+	auditRepo := memory.NewAuditLogRepository(memory.NewStore())
+	limiter := NewRateLimiter(cfg)
+	limiter.RecordViolations(auditRepo)
+
+	router := gin.New()
+	router.Use(limiter.Middleware())
+	router.POST("/auth/login", limiter.Policy(RateLimitAuth), func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/zones", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(method, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The auth policy is stricter than the default one
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/auth/login").Code)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/auth/login").Code)
+	w := request(http.MethodPost, "/auth/login")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Equal(t, "2", w.Header().Get("X-RateLimit-Limit"))
+	assert.Equal(t, "30", w.Header().Get("Retry-After"))
+
+	w = request(http.MethodGet, "/zones")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "100", w.Header().Get("X-RateLimit-Limit"))
+
+	// Violations are recorded once per window
+	assert.Equal(t, http.StatusTooManyRequests, request(http.MethodPost, "/auth/login").Code)
+	logs, err := auditRepo.List(context.Background(), repository.AuditLogFilter{
+		Actions: []models.AuditAction{models.AuditActionRateLimited},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, logs, 1) {
+		assert.Equal(t, RateLimitAuth, logs[0].EntityID)
+	}
+
+	// Disabling the policy lets requests through
+	limiter.SetPolicy(RateLimitAuth, 0, 60)
+	assert.Equal(t, http.StatusOK, request(http.MethodPost, "/auth/login").Code)
 }
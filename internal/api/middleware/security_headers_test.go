@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSecurityHeaders(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	router := gin.New()
+	router.Use(SecurityHeaders(config.SecurityHeadersConfig{Enabled: true, HSTSMaxAge: time.Hour}))
+	router.GET("/api/v1/zones", func(c *gin.Context) { c.Status(http.StatusOK) })
+	router.GET("/swagger/*any", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	request := func(path string, https bool) http.Header {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if https {
+			req.Header.Set("X-Forwarded-Proto", "https")
+		}
+		router.ServeHTTP(w, req)
+		return w.Header()
+	}
+
+	h := request("/api/v1/zones", false)
+	assert.Equal(t, "nosniff", h.Get("X-Content-Type-Options"))
+	assert.Equal(t, "DENY", h.Get("X-Frame-Options"))
+	assert.Equal(t, "no-referrer", h.Get("Referrer-Policy"))
+	assert.Equal(t, defaultCSP, h.Get("Content-Security-Policy"))
+	assert.Empty(t, h.Get("Strict-Transport-Security"))
+
+	// HSTS is only sent over HTTPS, browsers ignore it otherwise
+	h = request("/api/v1/zones", true)
+	assert.Equal(t, "max-age=3600", h.Get("Strict-Transport-Security"))
+
+	// The Swagger UI needs its inline scripts and styles
+	h = request("/swagger/index.html", false)
+	assert.Equal(t, swaggerCSP, h.Get("Content-Security-Policy"))
+}
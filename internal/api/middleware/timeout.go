@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+	"wattwatch/internal/apierror"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestTimeout gives requests a deadline. The request context is cancelled when it
+// passes, which cancels the repository queries the handler is waiting on.
+type RequestTimeout struct {
+	timeout     time.Duration
+	longTimeout time.Duration
+	long        map[string]bool
+	exempt      map[string]bool
+}
+
+// NewRequestTimeout creates a middleware giving requests timeout to finish, and routes
+// registered with Long longTimeout. A zero duration leaves requests without a deadline.
+func NewRequestTimeout(timeout, longTimeout time.Duration) *RequestTimeout {
+	return &RequestTimeout{
+		timeout:     timeout,
+		longTimeout: longTimeout,
+		long:        make(map[string]bool),
+		exempt:      make(map[string]bool),
+	}
+}
+
+// Long makes requests to the route, given as registered, use the long timeout. It is
+// meant for imports, exports and other requests expected to take a while.
+func (t *RequestTimeout) Long(method, path string) {
+	t.long[method+" "+path] = true
+}
+
+// Exempt leaves requests to the route without a deadline, for streams clients keep open
+func (t *RequestTimeout) Exempt(method, path string) {
+	t.exempt[method+" "+path] = true
+}
+
+// Middleware returns the gin middleware. A handler that fails because the deadline
+// passed gets its error response replaced with 504 Gateway Timeout.
+func (t *RequestTimeout) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		route := c.Request.Method + " " + c.FullPath()
+		timeout := t.timeout
+		if t.long[route] {
+			timeout = t.longTimeout
+		}
+		if timeout <= 0 || t.exempt[route] {
+			c.Next()
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+
+		writer := &timeoutWriter{ResponseWriter: c.Writer, ctx: ctx}
+		c.Writer = writer
+		c.Next()
+		c.Writer = writer.ResponseWriter
+
+		if writer.timedOut || (!c.Writer.Written() && errors.Is(ctx.Err(), context.DeadlineExceeded)) {
+			apierror.Abort(c, apierror.Timeout, "request timed out")
+		}
+	}
+}
+
+// timeoutWriter drops the error response of a handler whose deadline passed, so the
+// middleware can respond with a timeout instead
+type timeoutWriter struct {
+	gin.ResponseWriter
+	ctx      context.Context
+	timedOut bool
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	if code >= http.StatusInternalServerError && !w.Written() && errors.Is(w.ctx.Err(), context.DeadlineExceeded) {
+		w.timedOut = true
+		return
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	if !w.timedOut {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *timeoutWriter) Write(data []byte) (int, error) {
+	if w.timedOut {
+		return len(data), nil
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	if w.timedOut {
+		return len(s), nil
+	}
+	return w.ResponseWriter.WriteString(s)
+}
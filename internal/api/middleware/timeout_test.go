@@ -0,0 +1,89 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	timeout := NewRequestTimeout(20*time.Millisecond, time.Hour)
+	timeout.Long(http.MethodPost, "/import")
+	timeout.Exempt(http.MethodGet, "/stream")
+
+	r := gin.New()
+	r.Use(timeout.Middleware())
+	r.GET("/fast", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"ok": true})
+	})
+	r.GET("/stuck", func(c *gin.Context) {
+		// Like a query cancelled by the request context
+		<-c.Request.Context().Done()
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch"})
+	})
+	r.GET("/silent", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+	})
+	r.POST("/import", func(c *gin.Context) {
+		deadline, ok := c.Request.Context().Deadline()
+		assert.True(t, ok)
+		assert.Greater(t, time.Until(deadline), time.Minute)
+		c.Status(http.StatusNoContent)
+	})
+	r.GET("/stream", func(c *gin.Context) {
+		_, ok := c.Request.Context().Deadline()
+		assert.False(t, ok)
+		c.Status(http.StatusNoContent)
+	})
+
+	tests := []struct {
+		name       string
+		method     string
+		path       string
+		wantStatus int
+		wantBody   string
+	}{
+		{name: "Fast", method: http.MethodGet, path: "/fast", wantStatus: http.StatusOK, wantBody: `{"ok":true}`},
+		{name: "Error After Deadline", method: http.MethodGet, path: "/stuck", wantStatus: http.StatusGatewayTimeout,
+			wantBody: `{"type":"/api/v1/errors/REQ504","title":"Request timed out","status":504,"detail":"request timed out","instance":"/stuck","code":"REQ504","error":"request timed out"}`},
+		{name: "No Response", method: http.MethodGet, path: "/silent", wantStatus: http.StatusGatewayTimeout,
+			wantBody: `{"type":"/api/v1/errors/REQ504","title":"Request timed out","status":504,"detail":"request timed out","instance":"/silent","code":"REQ504","error":"request timed out"}`},
+		{name: "Long Route", method: http.MethodPost, path: "/import", wantStatus: http.StatusNoContent},
+		{name: "Exempt Route", method: http.MethodGet, path: "/stream", wantStatus: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, nil)
+			r.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+			if tt.wantBody != "" {
+				assert.JSONEq(t, tt.wantBody, w.Body.String())
+			}
+		})
+	}
+}
+
+func TestRequestTimeoutDisabled(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	r := gin.New()
+	r.Use(NewRequestTimeout(0, 0).Middleware())
+	r.GET("/test", func(c *gin.Context) {
+		_, ok := c.Request.Context().Deadline()
+		assert.False(t, ok)
+		c.Status(http.StatusOK)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/test", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+}
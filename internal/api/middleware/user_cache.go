@@ -0,0 +1,204 @@
+package middleware
+
+import (
+	"context"
+	"sync"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// UserCache keeps authenticated users with their role for a short time, so AuthRequired
+// doesn't query the users and roles tables on every request. Entries are dropped when
+// the user or role is changed through the repositories returned by Users and Roles.
+// Changes made elsewhere, such as by another instance, apply once the entry expires.
+type UserCache struct {
+	ttl     time.Duration
+	now     func() time.Time
+	mu      sync.Mutex
+	entries map[uuid.UUID]cachedUser
+}
+
+type cachedUser struct {
+	user    models.User
+	role    models.Role
+	expires time.Time
+}
+
+// NewUserCache creates a cache keeping users for ttl
+func NewUserCache(ttl time.Duration) *UserCache {
+	return &UserCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[uuid.UUID]cachedUser),
+	}
+}
+
+// Get returns a copy of the cached user with its role
+func (c *UserCache) Get(id uuid.UUID) (*models.User, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+	if !c.now().Before(entry.expires) {
+		delete(c.entries, id)
+		return nil, false
+	}
+	user, role := entry.user, entry.role
+	user.Role = &role
+	return &user, true
+}
+
+// Set caches the user, which must have its role set
+func (c *UserCache) Set(user *models.User) {
+	if user.Role == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry := cachedUser{user: *user, role: *user.Role, expires: c.now().Add(c.ttl)}
+	entry.user.Role = nil
+	c.entries[user.ID] = entry
+}
+
+// Invalidate drops the user
+func (c *UserCache) Invalidate(id uuid.UUID) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, id)
+}
+
+// invalidateWhere drops the users matching
+func (c *UserCache) invalidateWhere(match func(entry *cachedUser) bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for id, entry := range c.entries {
+		if match(&entry) {
+			delete(c.entries, id)
+		}
+	}
+}
+
+// Users wraps repo so that changing a user drops it from the cache
+func (c *UserCache) Users(repo repository.UserRepository) repository.UserRepository {
+	return &invalidatingUserRepository{UserRepository: repo, cache: c}
+}
+
+// Roles wraps repo so that changing a role drops the users holding it from the cache
+func (c *UserCache) Roles(repo repository.RoleRepository) repository.RoleRepository {
+	return &invalidatingRoleRepository{RoleRepository: repo, cache: c}
+}
+
+type invalidatingUserRepository struct {
+	repository.UserRepository
+	cache *UserCache
+}
+
+// byUsername drops the user with the username, for changes that don't know the ID
+func (r *invalidatingUserRepository) byUsername(username string) {
+	r.cache.invalidateWhere(func(entry *cachedUser) bool { return entry.user.Username == username })
+}
+
+func (r *invalidatingUserRepository) Update(ctx context.Context, user *models.User) error {
+	defer r.cache.Invalidate(user.ID)
+	return r.UserRepository.Update(ctx, user)
+}
+
+func (r *invalidatingUserRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.Delete(ctx, id)
+}
+
+func (r *invalidatingUserRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.Restore(ctx, id)
+}
+
+func (r *invalidatingUserRepository) HardDelete(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.HardDelete(ctx, id)
+}
+
+func (r *invalidatingUserRepository) ScheduleDeletion(ctx context.Context, id uuid.UUID, at time.Time) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.ScheduleDeletion(ctx, id, at)
+}
+
+func (r *invalidatingUserRepository) CancelDeletion(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.CancelDeletion(ctx, id)
+}
+
+func (r *invalidatingUserRepository) DeleteScheduled(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	ids, err := r.UserRepository.DeleteScheduled(ctx, now)
+	for _, id := range ids {
+		r.cache.Invalidate(id)
+	}
+	return ids, err
+}
+
+func (r *invalidatingUserRepository) UpdatePassword(ctx context.Context, id uuid.UUID, hashedPassword string) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.UpdatePassword(ctx, id, hashedPassword)
+}
+
+func (r *invalidatingUserRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID, lastLoginAt time.Time) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.UpdateLastLogin(ctx, id, lastLoginAt)
+}
+
+func (r *invalidatingUserRepository) UpdateFailedAttempts(ctx context.Context, id uuid.UUID, attempts int) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.UpdateFailedAttempts(ctx, id, attempts)
+}
+
+func (r *invalidatingUserRepository) VerifyEmail(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Invalidate(id)
+	return r.UserRepository.VerifyEmail(ctx, id)
+}
+
+func (r *invalidatingUserRepository) IncrementFailedAttempts(ctx context.Context, username string) error {
+	defer r.byUsername(username)
+	return r.UserRepository.IncrementFailedAttempts(ctx, username)
+}
+
+func (r *invalidatingUserRepository) ResetFailedAttempts(ctx context.Context, username string) error {
+	defer r.byUsername(username)
+	return r.UserRepository.ResetFailedAttempts(ctx, username)
+}
+
+type invalidatingRoleRepository struct {
+	repository.RoleRepository
+	cache *UserCache
+}
+
+func (r *invalidatingRoleRepository) byRole(id uuid.UUID) {
+	r.cache.invalidateWhere(func(entry *cachedUser) bool { return entry.role.ID == id })
+}
+
+func (r *invalidatingRoleRepository) Update(ctx context.Context, role *models.Role) error {
+	defer r.byRole(role.ID)
+	return r.RoleRepository.Update(ctx, role)
+}
+
+func (r *invalidatingRoleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.byRole(id)
+	return r.RoleRepository.Delete(ctx, id)
+}
+
+func (r *invalidatingRoleRepository) GrantPermission(ctx context.Context, roleID uuid.UUID, permission string) error {
+	defer r.byRole(roleID)
+	return r.RoleRepository.GrantPermission(ctx, roleID, permission)
+}
+
+func (r *invalidatingRoleRepository) RevokePermission(ctx context.Context, roleID uuid.UUID, permission string) error {
+	defer r.byRole(roleID)
+	return r.RoleRepository.RevokePermission(ctx, roleID, permission)
+}
@@ -0,0 +1,120 @@
+package middleware_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/testutil"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+// countingUserRepository counts the users looked up by ID
+type countingUserRepository struct {
+	repository.UserRepository
+	lookups int
+}
+
+func (r *countingUserRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	r.lookups++
+	return r.UserRepository.GetByID(ctx, id)
+}
+
+func TestAuthMiddleware_UserCache(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tc := testutil.NewMemoryTestContext(t)
+	user := tc.CreateTestUser("testuser", "test@example.com", "password123", false)
+	// Protected roles can't be changed, so give the user one that can
+	user.RoleID = tc.CreateTestRole("members", false, false).ID
+	require.NoError(t, tc.UserRepo.Update(context.Background(), user))
+	token := tc.GetTestJWT(user.ID)
+
+	cache := middleware.NewUserCache(time.Minute)
+	counting := &countingUserRepository{UserRepository: tc.UserRepo}
+	userRepo := cache.Users(counting)
+	roleRepo := cache.Roles(tc.RoleRepo)
+
+	authMiddleware := middleware.NewAuthMiddleware(tc.AuthService, userRepo, roleRepo)
+	authMiddleware.CacheUsers(cache)
+	router := gin.New()
+	router.GET("/test", authMiddleware.AuthRequired(), func(c *gin.Context) {
+		user := auth.GetUserFromContext(c)
+		c.JSON(http.StatusOK, gin.H{"username": user.Username, "role": user.Role.Name})
+	})
+	get := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/test", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Cached", func(t *testing.T) {
+		require.Equal(t, http.StatusOK, get().Code)
+		require.Equal(t, http.StatusOK, get().Code)
+		require.Equal(t, 1, counting.lookups)
+	})
+
+	t.Run("User Updated", func(t *testing.T) {
+		updated, err := tc.UserRepo.GetByID(context.Background(), user.ID)
+		require.NoError(t, err)
+		updated.Username = "renamed"
+		require.NoError(t, userRepo.Update(context.Background(), updated))
+
+		w := get()
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"username":"renamed"`)
+		require.Equal(t, 2, counting.lookups)
+	})
+
+	t.Run("Role Updated", func(t *testing.T) {
+		role, err := tc.RoleRepo.GetByID(context.Background(), user.RoleID)
+		require.NoError(t, err)
+		role.Name = "staff"
+		require.NoError(t, roleRepo.Update(context.Background(), role))
+
+		// The token still carries the previous role version
+		w := get()
+		require.Equal(t, http.StatusUnauthorized, w.Code)
+		require.Equal(t, 3, counting.lookups)
+
+		token = tc.GetTestJWT(user.ID)
+		w = get()
+		require.Equal(t, http.StatusOK, w.Code)
+		require.Contains(t, w.Body.String(), `"role":"staff"`)
+		require.Equal(t, 3, counting.lookups)
+	})
+
+	t.Run("User Deleted", func(t *testing.T) {
+		require.NoError(t, userRepo.Delete(context.Background(), user.ID))
+		require.Equal(t, http.StatusUnauthorized, get().Code)
+	})
+}
+
+func TestUserCache_Expiry(t *testing.T) {
+	cache := middleware.NewUserCache(10 * time.Millisecond)
+	user := &models.User{ID: uuid.New(), Username: "user", Role: &models.Role{ID: uuid.New(), Name: "user"}}
+	cache.Set(user)
+
+	cached, ok := cache.Get(user.ID)
+	require.True(t, ok)
+	require.Equal(t, "user", cached.Username)
+
+	// Changing the returned user must not change the cached one
+	cached.Role.IsAdminGroup = true
+	cached, ok = cache.Get(user.ID)
+	require.True(t, ok)
+	require.False(t, cached.Role.IsAdminGroup)
+
+	time.Sleep(20 * time.Millisecond)
+	_, ok = cache.Get(user.ID)
+	require.False(t, ok)
+}
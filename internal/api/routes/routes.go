@@ -2,37 +2,137 @@
 package routes
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
 	_ "wattwatch/docs" // Import swagger docs
+	"wattwatch/internal/alert"
 	"wattwatch/internal/api/handlers"
 	"wattwatch/internal/api/middleware"
 	"wattwatch/internal/auth"
+	"wattwatch/internal/budget"
+	"wattwatch/internal/cache"
+	"wattwatch/internal/captcha"
+	"wattwatch/internal/cleanup"
 	"wattwatch/internal/config"
+	"wattwatch/internal/database"
 	"wattwatch/internal/email"
+	"wattwatch/internal/exchangerate"
+	"wattwatch/internal/models"
+	"wattwatch/internal/mqtt"
+	"wattwatch/internal/notification"
 	"wattwatch/internal/provider"
+	"wattwatch/internal/provider/entsoe"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/quality"
+	"wattwatch/internal/repository"
 	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/scheduler"
+	"wattwatch/internal/selfcheck"
+	"wattwatch/internal/settings"
+	"wattwatch/internal/webhook"
+	"wattwatch/internal/worker"
+	"wattwatch/web"
 
 	"github.com/gin-gonic/gin"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
 )
 
-// SetupRoutes configures all API routes and their handlers
-func SetupRoutes(cfg *config.Config, db *sql.DB, providerManager *provider.Manager) *gin.Engine {
-	// Create router
+// SetupRoutes configures all API routes and their handlers. Background loops are
+// started on workers so the caller can stop them on shutdown. Spot prices written
+// through the API are published to hub, which the stream endpoint serves. Zones,
+// currencies and recent spot prices are read through lookupCache unless it is nil, and
+// listings from replicas unless it is nil.
+func SetupRoutes(cfg *config.Config, db *sql.DB, replicas *repository.ReplicaSet, providerManager *provider.Manager, jobScheduler *scheduler.Scheduler, hub *pubsub.Hub, lookupCache *cache.Cache, reloader *config.Reloader, workers *worker.Group) *gin.Engine {
+	// Create router, reading client IPs from the headers set by trusted proxies only
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.IPAccess.Proxies()); err != nil {
+		log.Printf("Trusting no proxies: %v", err)
+	}
+	r.RemoteIPHeaders = cfg.IPAccess.Headers()
+
+	// Initialize health handler for basic routes
+	healthHandler := handlers.NewHealthHandler(db)
+	healthHandler.SetReadinessChecks(func() []selfcheck.Check {
+		return selfcheck.Readiness(cfg, db, replicas, providerManager.GetProviders())
+	}, cfg.Startup.CheckTimeout)
+
+	// Probes, registered before any middleware so they are never throttled
+	r.GET("/ping", healthHandler.Ping)
+	r.GET("/healthz", healthHandler.Live)
+	r.GET("/readyz", healthHandler.Ready)
+
+	// Refuse clients outside the allowed IP ranges, admin routes can be restricted further
+	ipFilter := middleware.NewIPFilter("global", cfg.IPAccess.Global())
+	adminIPFilter := middleware.NewIPFilter("admin", cfg.IPAccess.Admin())
+	r.Use(ipFilter.Middleware())
+
+	// Security headers go on every response, cross-origin preflights are answered before
+	// they reach the rate limits
+	if cfg.SecurityHeaders.Enabled {
+		r.Use(middleware.SecurityHeaders(cfg.SecurityHeaders))
+	}
+	if cfg.CORS.Enabled() {
+		r.Use(middleware.CORS(cfg.CORS))
+	}
+
+	// Scrapes are registered before the instrumentation so they don't count themselves
+	if cfg.Metrics.Enabled {
+		r.GET("/metrics", middleware.MetricsHandler(cfg.Metrics.Token))
+	}
+	r.Use(middleware.Metrics())
 
 	// Apply compression middleware globally
 	r.Use(middleware.Compression(middleware.DefaultCompressionConfig()))
 
-	// Initialize health handler for basic routes
-	healthHandler := handlers.NewHealthHandler(db)
+	// Routes without rate limiting, Swagger UI shows the OpenAPI 3 document and the error
+	// catalog describes the codes of problem responses
+	r.GET("/api/v1/openapi.json", handlers.OpenAPI)
+	r.GET("/api/v1/errors", handlers.ListErrorCodes)
+	r.GET("/api/v1/errors/:code", handlers.GetErrorCode)
+	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler, ginSwagger.URL("/api/v1/openapi.json")))
 
-	// Routes without rate limiting
-	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
+	// Serve the embedded dashboard for paths that aren't API routes
+	if cfg.Web.Enabled {
+		webUIHandler, err := handlers.NewWebUIHandler(web.Files())
+		if err != nil {
+			log.Printf("Web UI disabled: %v", err)
+		} else {
+			r.NoRoute(webUIHandler.Serve)
+		}
+	}
 
 	// Apply rate limiting to all other routes
-	r.Use(middleware.NewRateLimiter(cfg).Middleware())
+	rateLimiter := middleware.NewRateLimiter(cfg)
+	if cfg.RateLimit.Backend == config.RateLimitBackendRedis {
+		// Instances sharing Redis share the limits, requests pass while it is unreachable
+		if store, err := middleware.NewRedisRateLimitStore(cfg.RateLimit.RedisURL); err != nil {
+			log.Printf("Rate limiting in memory: %v", err)
+		} else {
+			rateLimiter.UseStore(store)
+		}
+	}
+	if err := workers.Go("rate limit cleanup", rateLimiter.Run); err != nil {
+		log.Printf("Rate limit cleanup disabled: %v", err)
+	}
+	r.Use(rateLimiter.Middleware())
+
+	// Cancel requests that take too long, along with the queries they are waiting on
+	requestTimeout := middleware.NewRequestTimeout(cfg.API.RequestTimeout, cfg.API.LongRequestTimeout)
+	requestTimeout.Long(http.MethodGet, "/api/v1/spot-prices")
+	requestTimeout.Long(http.MethodPost, "/api/v1/spot-prices")
+	requestTimeout.Long(http.MethodPost, "/api/v1/providers/nordpool/fetch")
+	requestTimeout.Long(http.MethodPost, "/api/v1/providers/:name/run")
+	requestTimeout.Long(http.MethodPost, "/api/v1/consumption/import")
+	requestTimeout.Long(http.MethodPost, "/api/v1/users/import")
+	requestTimeout.Long(http.MethodGet, "/api/v1/users/export")
+	requestTimeout.Exempt(http.MethodGet, "/api/v1/spot-prices/stream")
+	r.Use(requestTimeout.Middleware())
 
 	// Add provider manager to context
 	r.Use(func(c *gin.Context) {
@@ -44,21 +144,242 @@ func SetupRoutes(cfg *config.Config, db *sql.DB, providerManager *provider.Manag
 	passwordHistory := postgres.NewPasswordHistoryRepository(db)
 	userRepo := postgres.NewUserRepository(db)
 	roleRepo := postgres.NewRoleRepository(db)
-	auditRepo := postgres.NewAuditLogRepository(db)
+	var userCache *middleware.UserCache
+	if cfg.Auth.UserCacheTTL > 0 {
+		// Changes made through the handlers drop the cached users they affect
+		userCache = middleware.NewUserCache(cfg.Auth.UserCacheTTL)
+		userRepo = userCache.Users(userRepo)
+		roleRepo = userCache.Roles(roleRepo)
+	}
+	// Entries written during impersonated requests record the admin
+	auditRepo := middleware.AuditImpersonation(postgres.NewAuditLogRepository(db))
+	rateLimiter.RecordViolations(auditRepo)
+	ipFilter.RecordDenials(auditRepo)
+	adminIPFilter.RecordDenials(auditRepo)
 	refreshTokenRepo := postgres.NewRefreshTokenRepository(db)
-	currencyRepo := postgres.NewCurrencyRepository(db)
-	zoneRepo := postgres.NewZoneRepository(db)
-	spotPriceRepo := postgres.NewSpotPriceRepository(db)
+	currencyRepo := postgres.NewCurrencyRepositoryWithReplicas(db, replicas)
+	zoneRepo := postgres.NewZoneRepositoryWithReplicas(db, replicas)
+	spotPriceRepo := hub.SpotPrices(postgres.NewSpotPriceRepositoryWithReplicas(db, replicas))
+	spotPriceSourceRepo := hub.Sources(postgres.NewSpotPriceSourceRepository(db))
+	if lookupCache != nil {
+		// Writes through these repositories drop the cached entries they affect
+		zoneRepo = lookupCache.Zones(zoneRepo, cfg.Cache.TTL)
+		currencyRepo = lookupCache.Currencies(currencyRepo, cfg.Cache.TTL)
+		spotPriceRepo = lookupCache.SpotPrices(spotPriceRepo, cfg.Cache.SpotPriceTTL)
+		spotPriceSourceRepo = lookupCache.Sources(spotPriceSourceRepo)
+	}
+	consumptionRepo := postgres.NewConsumptionRepositoryWithReplicas(db, replicas)
+	productionRepo := postgres.NewProductionRepositoryWithReplicas(db, replicas)
+	exchangeRateRepo := postgres.NewExchangeRateRepositoryWithReplicas(db, replicas)
 	loginAttemptRepo := postgres.NewLoginAttemptRepository(db)
 	emailVerifyRepo := postgres.NewEmailVerificationRepository(db)
 	passwordResetRepo := postgres.NewPasswordResetRepository(db)
+	emailChangeRepo := postgres.NewEmailChangeRevertRepository(db)
+	deviceTokenRepo := postgres.NewDeviceTokenRepository(db)
+	notificationTargetRepo := postgres.NewNotificationTargetRepository(db)
+	notificationPrefRepo := postgres.NewNotificationPreferenceRepository(db)
+	userPreferenceRepo := postgres.NewUserPreferenceRepository(db)
+	securityEventRepo := postgres.NewSecurityEventRepository(db)
+	notificationDeliveryRepo := postgres.NewNotificationDeliveryRepository(db)
+	emailSuppressionRepo := postgres.NewEmailSuppressionRepository(db)
+	settingRepo := postgres.NewSettingRepository(db)
+	entsoeAreaRepo := postgres.NewEntsoeAreaRepository(db)
+	jobRepo := postgres.NewJobRepository(db)
+	organizationRepo := postgres.NewOrganizationRepository(db)
+	priceAlertRepo := postgres.NewPriceAlertRepository(db)
+	budgetRepo := postgres.NewBudgetRepository(db)
+	tariffRepo := postgres.NewTariffRepository(db)
+	webhookRepo := postgres.NewWebhookRepository(db)
+	webhookDeliveryRepo := postgres.NewWebhookDeliveryRepository(db)
+	twoFactorRepo := postgres.NewTwoFactorRepository(db)
+	impersonationRepo := postgres.NewImpersonationRepository(db)
+	integrationTokenRepo := postgres.NewIntegrationTokenRepository(db)
 
 	// Initialize services
 	authService := auth.NewService(cfg, refreshTokenRepo)
 	emailService := email.NewService(cfg.Email)
+	emailService.SetSuppressionChecker(emailSuppressionRepo)
+	emailDeadLetterRepo := postgres.NewEmailDeadLetterRepository(db)
+	emailService.SetDeadLetters(emailDeadLetterRepo)
+	notificationService, vapidPublicKey := setupNotifications(cfg.Push, workers, deviceTokenRepo, notificationTargetRepo, notificationPrefRepo, notificationDeliveryRepo)
+	notificationService.SetEmail(emailService, userRepo)
+
+	// Runtime settings stored in the database take precedence over the configuration
+	runtimeSettings := settings.NewStore(settingRepo, cfg)
+	runtimeSettings.Validator(settings.KeyDefaultRole, func(ctx context.Context, value string) error {
+		if _, err := roleRepo.GetByName(ctx, value); err != nil {
+			return fmt.Errorf("role %q does not exist", value)
+		}
+		return nil
+	})
+	applyThrottleInterval := func() {
+		notificationService.SetThrottleInterval(models.NotificationAlertPrice, runtimeSettings.ThrottleInterval())
+		notificationService.SetThrottleInterval(models.NotificationAlertConsumption, runtimeSettings.ThrottleInterval())
+	}
+	runtimeSettings.OnChange(applyThrottleInterval)
+	// Providers follow their schedules from the configuration, and can be enabled or disabled
+	// through the runtime settings
+	applyProviderSchedules := func() {
+		for _, p := range providerManager.GetProviders() {
+			settings, ok := cfg.ProviderSettings(p.Name())
+			if !ok {
+				continue
+			}
+			if err := providerManager.Reschedule(p.Name(), runtimeSettings.ProviderEnabled(p.Name()), settings.Schedule, settings.ZoneSchedules); err != nil {
+				log.Printf("Failed to reschedule provider %s: %v", p.Name(), err)
+			}
+		}
+	}
+	runtimeSettings.OnChange(applyProviderSchedules)
+	// A token stored through the settings API replaces the configured one without a restart
+	if p, ok := providerManager.GetProvider(entsoe.ProviderName); ok {
+		if entsoeProvider, ok := p.(*entsoe.Provider); ok {
+			entsoeProvider.SetTokenSource(runtimeSettings.EntsoeToken)
+		}
+	}
+	// Secrets being rotated out stay valid until the end set in the runtime settings
+	authService.AcceptPreviousSecret(cfg.Auth.JWTPreviousSecret, runtimeSettings.JWTPreviousSecretUntil)
+	if err := runtimeSettings.Refresh(context.Background()); err != nil {
+		log.Printf("Using configured values only: %v", err)
+	}
+	if err := workers.Go("runtime settings refresh", func(ctx context.Context) {
+		runtimeSettings.Run(ctx, settings.DefaultRefreshInterval)
+	}); err != nil {
+		log.Printf("Runtime settings refresh disabled: %v", err)
+	}
+
+	// Look for missing and duplicate prices, days with gaps can be fetched again
+	qualityChecker := quality.NewChecker(spotPriceRepo, zoneRepo, currencyRepo, cfg.Quality.Resolution)
+	qualityChecker.SetRefetcher(providerManager)
+	qualityChecker.SetLeader(providerManager)
+	if cfg.Quality.Interval > 0 {
+		if err := workers.Go("data quality check", func(ctx context.Context) {
+			qualityChecker.Run(ctx, cfg.Quality.Interval, cfg.Quality.Days, cfg.Quality.Refetch)
+		}); err != nil {
+			log.Printf("Data quality check disabled: %v", err)
+		}
+	}
+
+	// Webhook deliveries are queued in the database and sent by whichever instance claims
+	// them first, so every instance runs the sender
+	webhookDispatcher := webhook.NewDispatcher(webhookRepo, webhookDeliveryRepo, nil)
+	if err := workers.Go("webhook deliveries", func(ctx context.Context) {
+		webhookDispatcher.Run(ctx, webhook.DefaultSendInterval)
+	}); err != nil {
+		log.Printf("Webhook deliveries disabled: %v", err)
+	}
+	if err := workers.Go("spot price webhooks", func(ctx context.Context) {
+		webhookDispatcher.FollowSpotPrices(ctx, hub)
+	}); err != nil {
+		log.Printf("Spot price webhooks disabled: %v", err)
+	}
+
+	// Price alerts are checked as new spot prices are stored
+	alertEvaluator := alert.NewEvaluator(priceAlertRepo, zoneRepo, currencyRepo, notificationService)
+	alertEvaluator.SetWebhooks(webhookDispatcher)
+	if err := workers.Go("price alerts", func(ctx context.Context) {
+		alertEvaluator.Run(ctx, hub)
+	}); err != nil {
+		log.Printf("Price alerts disabled: %v", err)
+	}
+
+	// Spot prices are published to the MQTT broker as retained messages for devices that
+	// subscribe instead of polling
+	if cfg.MQTT.Enabled() {
+		if client, err := mqtt.NewClient(cfg.MQTT); err != nil {
+			log.Printf("MQTT publishing disabled: %v", err)
+		} else {
+			mqttPublisher := mqtt.NewPublisher(client, cfg.MQTT, spotPriceRepo, zoneRepo, currencyRepo)
+			if err := workers.Go("mqtt publisher", func(ctx context.Context) {
+				mqttPublisher.Run(ctx, hub)
+			}); err != nil {
+				log.Printf("MQTT publishing disabled: %v", err)
+			}
+		}
+	}
+
+	// Budgets are checked against the projected cost of the month once the day-ahead prices
+	// are in, on the leader only so owners are notified once
+	costProjector := budget.NewProjector(consumptionRepo, spotPriceRepo)
+	costProjector.SetProduction(productionRepo)
+	if cfg.Budgets.Schedule != "" {
+		budgetChecker := budget.NewChecker(budgetRepo, zoneRepo, currencyRepo, costProjector, notificationService)
+		budgetChecker.SetTariffs(tariffRepo)
+		if err := jobScheduler.Add("budget-check", cfg.Budgets.Schedule, budgetChecker.Run); err != nil {
+			log.Printf("Budget notifications disabled: %v", err)
+		}
+	}
+
+	// Users who opted in are emailed the consumption and cost of the past week, on the
+	// leader only so each report is sent once
+	if cfg.Reports.WeeklySchedule != "" {
+		reporter := budget.NewReporter(userPreferenceRepo, userRepo, zoneRepo, currencyRepo, consumptionRepo, spotPriceRepo, emailService)
+		reporter.SetTariffs(tariffRepo)
+		if err := jobScheduler.Add("weekly-report", cfg.Reports.WeeklySchedule, reporter.Run); err != nil {
+			log.Printf("Weekly reports disabled: %v", err)
+		}
+	}
+
+	// Expired tokens, old audit logs, old login attempts and accounts past their deletion
+	// grace period are removed by scheduled jobs, which run on the leader only since every
+	// instance would find the same rows
+	tokenCleaner := cleanup.NewCleaner(cfg.Cleanup.Grace)
+	tokenCleaner.Add(cleanup.KindRefreshToken, refreshTokenRepo)
+	tokenCleaner.Add(cleanup.KindPasswordReset, passwordResetRepo)
+	tokenCleaner.Add(cleanup.KindEmailVerification, emailVerifyRepo)
+	tokenCleaner.Add(cleanup.KindEmailChangeRevert, emailChangeRepo)
+	if cfg.Cleanup.Schedule != "" {
+		if err := jobScheduler.Add("token-cleanup", cfg.Cleanup.Schedule, tokenCleaner.Run); err != nil {
+			log.Printf("Expired token cleanup disabled: %v", err)
+		}
+		accounts := cleanup.NewAccounts(userRepo, auditRepo)
+		if err := jobScheduler.Add("account-deletion", cfg.Cleanup.Schedule, accounts.Run); err != nil {
+			log.Printf("Scheduled account deletion disabled: %v", err)
+		}
+	}
+	retention := cleanup.NewRetention(auditRepo)
+	retention.Add(cleanup.KindAuditLog, cfg.Retention.AuditLogs, auditRepo)
+	retention.Add(cleanup.KindLoginAttempt, cfg.Retention.LoginAttempts, loginAttemptRepo)
+	if !retention.Empty() {
+		if err := jobScheduler.Add("retention", cfg.Retention.Schedule, retention.Run); err != nil {
+			log.Printf("Retention disabled: %v", err)
+		}
+	}
+	if cfg.ExchangeRates.ECBSchedule != "" {
+		ecbFetcher := exchangerate.NewECBFetcher(exchangeRateRepo, currencyRepo)
+		if err := jobScheduler.Add("ecb-exchange-rates", cfg.ExchangeRates.ECBSchedule, ecbFetcher.Run); err != nil {
+			log.Printf("ECB exchange rate fetching disabled: %v", err)
+		}
+	}
+
+	// Apply reloaded settings to the services that cache them
+	reloader.OnReload(func(cfg *config.Config) {
+		emailService.Reconfigure(cfg.EmailSettings())
+		rateLimiter.SetLimits(cfg.RateLimitSettings())
+		authRequests, authWindow := cfg.AuthRateLimitSettings()
+		rateLimiter.SetPolicy(middleware.RateLimitAuth, authRequests, authWindow)
+		applyThrottleInterval()
+		applyProviderSchedules()
+	})
 
 	// Initialize middleware
 	authMiddleware := middleware.NewAuthMiddleware(authService, userRepo, roleRepo)
+	if userCache != nil {
+		authMiddleware.CacheUsers(userCache)
+	}
+	authMiddleware.CheckImpersonations(impersonationRepo)
+	authMiddleware.AcceptIntegrationTokens(integrationTokenRepo)
+	// Maintenance mode is shared through the settings table, so every instance applies it
+	maintenanceMode := middleware.NewMaintenanceMode(authService, settingRepo)
+	if err := maintenanceMode.Refresh(context.Background()); err != nil {
+		log.Printf("Starting without maintenance mode: %v", err)
+	}
+	if err := workers.Go("maintenance mode refresh", func(ctx context.Context) {
+		maintenanceMode.Run(ctx, settings.DefaultRefreshInterval)
+	}); err != nil {
+		log.Printf("Maintenance mode refresh disabled: %v", err)
+	}
+	r.Use(maintenanceMode.Middleware())
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(
@@ -71,13 +392,93 @@ func SetupRoutes(cfg *config.Config, db *sql.DB, providerManager *provider.Manag
 		loginAttemptRepo,
 		emailVerifyRepo,
 		passwordResetRepo,
+		runtimeSettings,
+	)
+	authHandler.SetWebhooks(webhookDispatcher)
+	authHandler.SetTwoFactor(twoFactorRepo)
+	authHandler.SetTxManager(repository.NewTxManager(db))
+	authHandler.SetSecurityEvents(securityEventRepo)
+	if cfg.Captcha.Enabled() {
+		authHandler.SetCaptcha(captcha.New(cfg.Captcha))
+	}
+	userHandler := handlers.NewUserHandler(
+		userRepo,
+		authService,
+		passwordHistory,
+		auditRepo,
+		emailService,
+		emailVerifyRepo,
+		emailChangeRepo,
+		refreshTokenRepo,
+		loginAttemptRepo,
+		cfg,
 	)
-	userHandler := handlers.NewUserHandler(userRepo, authService, passwordHistory, auditRepo)
 	roleHandler := handlers.NewRoleHandler(roleRepo, userRepo, auditRepo)
-	currencyHandler := handlers.NewCurrencyHandler(currencyRepo)
-	zoneHandler := handlers.NewZoneHandler(zoneRepo)
+	currencyHandler := handlers.NewCurrencyHandler(currencyRepo, auditRepo)
+	zoneHandler := handlers.NewZoneHandler(zoneRepo, auditRepo)
 	spotPriceHandler := handlers.NewSpotPriceHandler(spotPriceRepo, zoneRepo, currencyRepo)
-	providerHandler := handlers.NewProviderHandler(providerManager)
+	listLimits := handlers.ListLimits{Default: cfg.API.ListDefaultLimit, Max: cfg.API.ListMaxLimit}
+	userImportHandler := handlers.NewUserImportHandler(userRepo, roleRepo, passwordResetRepo, authService, auditRepo, emailService, runtimeSettings, cfg)
+	userHandler.SetListLimits(listLimits)
+	userHandler.SetOrganizationRepository(organizationRepo)
+	userHandler.SetSettings(runtimeSettings)
+	roleHandler.SetListLimits(listLimits)
+	spotPriceHandler.SetListLimits(listLimits)
+	spotPriceHandler.SetMaxRange(cfg.API.SpotPriceMaxRange)
+	spotPriceHandler.SetExchangeRates(exchangeRateRepo)
+	spotPriceHandler.SetPreferences(userPreferenceRepo)
+	spotPriceHandler.SetRevisions(postgres.NewSpotPriceRevisionRepository(db))
+	userPreferenceHandler := handlers.NewUserPreferenceHandler(userPreferenceRepo, zoneRepo, currencyRepo)
+	userExportHandler := handlers.NewUserExportHandler(userRepo, userPreferenceRepo, consumptionRepo, auditRepo)
+	userExportHandler.SetProductionRepository(productionRepo)
+	securityEventHandler := handlers.NewSecurityEventHandler(userRepo, securityEventRepo)
+	securityEventHandler.SetListLimits(listLimits)
+	spotPriceStreamHandler := handlers.NewSpotPriceStreamHandler(hub, zoneRepo, currencyRepo)
+	spotPriceConflictHandler := handlers.NewSpotPriceConflictHandler(spotPriceSourceRepo, zoneRepo, currencyRepo, auditRepo)
+	spotPriceConflictHandler.SetListLimits(listLimits)
+	consumptionHandler := handlers.NewConsumptionHandler(consumptionRepo)
+	consumptionHandler.SetListLimits(listLimits)
+	consumptionHandler.SetOrganizationRepository(organizationRepo)
+	productionHandler := handlers.NewProductionHandler(productionRepo)
+	productionHandler.SetListLimits(listLimits)
+	priceForecastHandler := handlers.NewPriceForecastHandler(postgres.NewPriceForecastRepositoryWithReplicas(db, replicas), spotPriceRepo, zoneRepo, currencyRepo)
+	priceForecastHandler.SetPreferences(userPreferenceRepo)
+	organizationHandler := handlers.NewOrganizationHandler(organizationRepo, userRepo, auditRepo)
+	organizationHandler.SetListLimits(listLimits)
+	exchangeRateHandler := handlers.NewExchangeRateHandler(exchangeRateRepo, currencyRepo, auditRepo)
+	exchangeRateHandler.SetListLimits(listLimits)
+	providerHandler := handlers.NewProviderHandler(providerManager, spotPriceRepo)
+	providerHandler.SetSettings(runtimeSettings, auditRepo)
+	entsoeHandler := handlers.NewEntsoeHandler(entsoeAreaRepo, zoneRepo, auditRepo)
+	jobHandler := handlers.NewJobHandler(jobScheduler, jobRepo, auditRepo)
+	jobHandler.SetListLimits(listLimits)
+	auditLogHandler := handlers.NewAuditLogHandler(auditRepo)
+	auditLogHandler.SetListLimits(listLimits)
+	notificationHandler := handlers.NewNotificationHandler(
+		deviceTokenRepo,
+		notificationPrefRepo,
+		notificationDeliveryRepo,
+		notificationService,
+		vapidPublicKey,
+	)
+	notificationTargetHandler := handlers.NewNotificationTargetHandler(notificationTargetRepo, notificationService)
+	priceAlertHandler := handlers.NewPriceAlertHandler(priceAlertRepo)
+	budgetHandler := handlers.NewBudgetHandler(budgetRepo, zoneRepo, currencyRepo, costProjector)
+	budgetHandler.SetPreferences(userPreferenceRepo)
+	budgetHandler.SetTariffs(tariffRepo)
+	tariffHandler := handlers.NewTariffHandler(tariffRepo, organizationRepo)
+	homeAssistantHandler := handlers.NewHomeAssistantHandler(integrationTokenRepo, spotPriceRepo, zoneRepo, currencyRepo, auditRepo)
+	webhookHandler := handlers.NewWebhookHandler(webhookRepo, webhookDeliveryRepo, auditRepo)
+	webhookHandler.SetListLimits(listLimits)
+	emailAdminHandler := handlers.NewEmailAdminHandler(emailService, emailDeadLetterRepo, auditRepo)
+	configAdminHandler := handlers.NewConfigAdminHandler(reloader, auditRepo)
+	impersonationHandler := handlers.NewImpersonationHandler(impersonationRepo, userRepo, authService, auditRepo)
+	maintenanceHandler := handlers.NewMaintenanceHandler(maintenanceMode, auditRepo)
+	migrationHandler := handlers.NewMigrationHandler(database.NewMigrator(cfg.Database), auditRepo)
+	settingsHandler := handlers.NewSettingsHandler(runtimeSettings, auditRepo)
+	dataQualityHandler := handlers.NewDataQualityHandler(qualityChecker, auditRepo)
+	emailWebhookHandler := handlers.NewEmailWebhookHandler(emailSuppressionRepo, userRepo, auditRepo, cfg.Email.WebhookSecret)
+	emailWebhookHandler.AcceptPreviousSecret(cfg.Email.WebhookPreviousSecret, runtimeSettings.WebhookPreviousSecretUntil)
 
 	// API v1 routes
 	v1 := r.Group("/api/v1")
@@ -87,14 +488,24 @@ func SetupRoutes(cfg *config.Config, db *sql.DB, providerManager *provider.Manag
 
 		// Auth routes
 		auth := v1.Group("/auth")
+		auth.Use(rateLimiter.Policy(middleware.RateLimitAuth))
 		{
 			auth.POST("/login", authHandler.Login)
+			auth.POST("/login/2fa", authHandler.LoginTwoFactor)
 			auth.POST("/register", authHandler.Register)
 			auth.GET("/verify-email", authHandler.VerifyEmail)
 			auth.POST("/resend-verification", authMiddleware.AuthRequired(), authHandler.ResendVerification)
 			auth.POST("/reset-password", authHandler.RequestPasswordReset)
 			auth.POST("/reset-password/complete", authHandler.CompletePasswordReset)
+			auth.GET("/revert-email-change", userHandler.RevertEmailChange)
 			auth.POST("/refresh", authHandler.Refresh)
+			auth.POST("/logout", authHandler.Logout)
+			auth.POST("/logout-all", authMiddleware.AuthRequired(), authHandler.LogoutAll)
+			auth.GET("/2fa", authMiddleware.AuthRequired(), authHandler.GetTwoFactor)
+			auth.POST("/2fa/enroll", authMiddleware.AuthRequired(), authHandler.EnrollTwoFactor)
+			auth.POST("/2fa/confirm", authMiddleware.AuthRequired(), authHandler.ConfirmTwoFactor)
+			auth.POST("/2fa/disable", authMiddleware.AuthRequired(), authHandler.DisableTwoFactor)
+			auth.POST("/impersonation/stop", authMiddleware.AuthRequired(), impersonationHandler.StopImpersonation)
 		}
 
 		// User routes (requires authentication)
@@ -106,6 +517,23 @@ func SetupRoutes(cfg *config.Config, db *sql.DB, providerManager *provider.Manag
 			users.PUT("/:id", userHandler.UpdateUser)
 			users.PUT("/:id/password", userHandler.ChangePassword)
 			users.DELETE("/:id", userHandler.DeleteUser)
+			users.GET("/:id/sessions", userHandler.ListSessions)
+			users.DELETE("/:id/sessions/:sessionId", userHandler.RevokeSession)
+			users.GET("/:id/preferences", userPreferenceHandler.GetPreferences)
+			users.PUT("/:id/preferences", userPreferenceHandler.UpdatePreferences)
+			users.GET("/:id/export", userExportHandler.ExportUser)
+			users.GET("/:id/security-events", securityEventHandler.ListSecurityEvents)
+
+			adminUsers := users.Group("")
+			adminUsers.Use(authMiddleware.RequirePermission(models.PermissionUsersManage))
+			{
+				adminUsers.POST("/import", userImportHandler.ImportUsers)
+				adminUsers.GET("/export", userImportHandler.ExportUsers)
+				adminUsers.POST("/:id/restore", userHandler.RestoreUser)
+				adminUsers.DELETE("/:id/purge", userHandler.PurgeUser)
+				adminUsers.GET("/:id/login-attempts", userHandler.GetLoginAttempts)
+				adminUsers.POST("/:id/unlock", userHandler.UnlockUser)
+			}
 		}
 
 		// Role routes (requires authentication)
@@ -117,19 +545,20 @@ func SetupRoutes(cfg *config.Config, db *sql.DB, providerManager *provider.Manag
 			roles.POST("", roleHandler.CreateRole)
 			roles.PUT("/:id", roleHandler.UpdateRole)
 			roles.DELETE("/:id", roleHandler.DeleteRole)
+			roles.PUT("/:id/permissions/:permission", roleHandler.GrantPermission)
+			roles.DELETE("/:id/permissions/:permission", roleHandler.RevokePermission)
 		}
 
 		// Currency routes
 		currencies := v1.Group("/currencies")
 		{
-			// Public routes (require authentication)
-			currencies.Use(authMiddleware.AuthRequired())
-			currencies.GET("", currencyHandler.ListCurrencies)
-			currencies.GET("/:id", currencyHandler.GetCurrency)
+			// Public routes (authorized by the token claims alone)
+			currencies.GET("", authMiddleware.ClaimsRequired(), currencyHandler.ListCurrencies)
+			currencies.GET("/:id", authMiddleware.ClaimsRequired(), currencyHandler.GetCurrency)
 
-			// Admin-only routes
+			// Routes for roles granted currencies:manage
 			adminCurrencies := currencies.Group("")
-			adminCurrencies.Use(authMiddleware.AdminRequired())
+			adminCurrencies.Use(authMiddleware.AuthRequired(), authMiddleware.RequirePermission(models.PermissionCurrenciesManage))
 			{
 				adminCurrencies.POST("", currencyHandler.CreateCurrency)
 				adminCurrencies.PUT("/:id", currencyHandler.UpdateCurrency)
@@ -140,14 +569,13 @@ func SetupRoutes(cfg *config.Config, db *sql.DB, providerManager *provider.Manag
 		// Zone routes
 		zones := v1.Group("/zones")
 		{
-			// Public routes (require authentication)
-			zones.Use(authMiddleware.AuthRequired())
-			zones.GET("", zoneHandler.ListZones)
-			zones.GET("/:id", zoneHandler.GetZone)
+			// Public routes (authorized by the token claims alone)
+			zones.GET("", authMiddleware.ClaimsRequired(), zoneHandler.ListZones)
+			zones.GET("/:id", authMiddleware.ClaimsRequired(), zoneHandler.GetZone)
 
-			// Admin-only routes
+			// Routes for roles granted zones:manage
 			adminZones := zones.Group("")
-			adminZones.Use(authMiddleware.AdminRequired())
+			adminZones.Use(authMiddleware.AuthRequired(), authMiddleware.RequirePermission(models.PermissionZonesManage))
 			{
 				adminZones.POST("", zoneHandler.CreateZone)
 				adminZones.PUT("/:id", zoneHandler.UpdateZone)
@@ -158,19 +586,242 @@ func SetupRoutes(cfg *config.Config, db *sql.DB, providerManager *provider.Manag
 		// Spot price routes
 		spotPrices := v1.Group("/spot-prices")
 		{
-			spotPrices.GET("", spotPriceHandler.ListSpotPrices)
+			// Authenticated requests can leave out the zone and currency the user picked
+			spotPrices.GET("", authMiddleware.ClaimsOptional(), spotPriceHandler.ListSpotPrices)
+			spotPrices.GET("/aggregate", spotPriceHandler.AggregateSpotPrices)
+			spotPrices.GET("/summary", authMiddleware.ClaimsOptional(), spotPriceHandler.SummarizeSpotPrices)
+			spotPrices.GET("/cheapest-window", authMiddleware.ClaimsOptional(), spotPriceHandler.CheapestSpotPrices)
+			spotPrices.GET("/compare", authMiddleware.ClaimsOptional(), spotPriceHandler.CompareSpotPrices)
+			spotPrices.GET("/forecast", authMiddleware.ClaimsOptional(), priceForecastHandler.ListForecastedSpotPrices)
+			spotPrices.GET("/stream", spotPriceStreamHandler.StreamSpotPrices)
 			spotPrices.GET("/:id", spotPriceHandler.GetSpotPrice)
-			spotPrices.POST("", authMiddleware.AdminRequired(), spotPriceHandler.CreateSpotPrices)
-			spotPrices.DELETE("/:id", authMiddleware.AdminRequired(), spotPriceHandler.DeleteSpotPrice)
+			spotPrices.GET("/:id/history", spotPriceHandler.GetSpotPriceHistory)
+			spotPrices.POST("", authMiddleware.AuthRequired(), authMiddleware.RequirePermission(models.PermissionSpotPricesWrite), spotPriceHandler.CreateSpotPrices)
+			spotPrices.DELETE("/:id", authMiddleware.AuthRequired(), authMiddleware.RequirePermission(models.PermissionSpotPricesWrite), spotPriceHandler.DeleteSpotPrice)
+		}
+
+		// Price forecast routes, kept apart from the published spot prices
+		forecasts := v1.Group("/forecasts")
+		{
+			forecasts.GET("", authMiddleware.ClaimsOptional(), priceForecastHandler.ListForecasts)
+			forecasts.POST("", authMiddleware.AuthRequired(), authMiddleware.RequirePermission(models.PermissionSpotPricesWrite), priceForecastHandler.CreateForecasts)
+		}
+
+		// Consumption routes (requires authentication)
+		consumption := v1.Group("/consumption")
+		consumption.Use(authMiddleware.AuthRequired())
+		{
+			consumption.GET("", consumptionHandler.ListConsumption)
+			consumption.POST("", consumptionHandler.CreateConsumption)
+			consumption.POST("/import", consumptionHandler.ImportConsumption)
+			consumption.POST("/import/preview", consumptionHandler.PreviewConsumptionImport)
+		}
+
+		// Production routes (requires authentication)
+		production := v1.Group("/production")
+		production.Use(authMiddleware.AuthRequired())
+		{
+			production.GET("", productionHandler.ListProduction)
+			production.POST("", productionHandler.CreateProduction)
+		}
+
+		// Organization routes (requires authentication, membership is checked by the handler)
+		organizations := v1.Group("/organizations")
+		organizations.Use(authMiddleware.AuthRequired())
+		{
+			organizations.GET("", organizationHandler.ListOrganizations)
+			organizations.POST("", organizationHandler.CreateOrganization)
+			organizations.GET("/:id", organizationHandler.GetOrganization)
+			organizations.PUT("/:id", organizationHandler.UpdateOrganization)
+			organizations.DELETE("/:id", organizationHandler.DeleteOrganization)
+			organizations.GET("/:id/members", organizationHandler.ListMembers)
+			organizations.POST("/:id/members", organizationHandler.AddMember)
+			organizations.PUT("/:id/members/:user_id", organizationHandler.UpdateMember)
+			organizations.DELETE("/:id/members/:user_id", organizationHandler.RemoveMember)
+		}
+
+		// Notification routes (requires authentication)
+		notifications := v1.Group("/notifications")
+		notifications.Use(authMiddleware.AuthRequired())
+		{
+			notifications.GET("/webpush/key", notificationHandler.GetWebPushKey)
+			notifications.GET("/devices", notificationHandler.ListDevices)
+			notifications.POST("/devices", notificationHandler.RegisterDevice)
+			notifications.DELETE("/devices/:id", notificationHandler.DeleteDevice)
+			notifications.GET("/preferences", notificationHandler.GetPreferences)
+			notifications.PUT("/preferences", notificationHandler.UpdatePreferences)
+			notifications.GET("/deliveries", notificationHandler.ListDeliveries)
+			notifications.POST("/test", notificationHandler.SendTestNotification)
+			notifications.GET("/targets", notificationTargetHandler.ListTargets)
+			notifications.POST("/targets", notificationTargetHandler.CreateTarget)
+			notifications.PUT("/targets/:id", notificationTargetHandler.UpdateTarget)
+			notifications.DELETE("/targets/:id", notificationTargetHandler.DeleteTarget)
+			notifications.POST("/targets/:id/test", notificationTargetHandler.TestTarget)
+		}
+
+		// Price alert routes (requires authentication)
+		alerts := v1.Group("/alerts")
+		alerts.Use(authMiddleware.AuthRequired())
+		{
+			alerts.GET("", priceAlertHandler.ListAlerts)
+			alerts.POST("", priceAlertHandler.CreateAlert)
+			alerts.GET("/:id", priceAlertHandler.GetAlert)
+			alerts.PUT("/:id", priceAlertHandler.UpdateAlert)
+			alerts.DELETE("/:id", priceAlertHandler.DeleteAlert)
+		}
+
+		// Budget, tariff and cost routes (requires authentication)
+		budgets := v1.Group("/budgets")
+		budgets.Use(authMiddleware.AuthRequired())
+		{
+			budgets.GET("", budgetHandler.ListBudgets)
+			budgets.POST("", budgetHandler.CreateBudget)
+			budgets.GET("/:id", budgetHandler.GetBudget)
+			budgets.PUT("/:id", budgetHandler.UpdateBudget)
+			budgets.DELETE("/:id", budgetHandler.DeleteBudget)
+		}
+		tariffs := v1.Group("/tariffs")
+		tariffs.Use(authMiddleware.AuthRequired())
+		{
+			tariffs.GET("", tariffHandler.ListTariffs)
+			tariffs.POST("", tariffHandler.CreateTariff)
+			tariffs.GET("/:id", tariffHandler.GetTariff)
+			tariffs.PUT("/:id", tariffHandler.UpdateTariff)
+			tariffs.DELETE("/:id", tariffHandler.DeleteTariff)
+		}
+		costs := v1.Group("/costs")
+		costs.Use(authMiddleware.AuthRequired())
+		{
+			costs.GET("/projection", budgetHandler.ProjectCost)
+		}
+
+		// Integration routes, the sensor is read with long-lived integration tokens
+		homeAssistant := v1.Group("/integrations/homeassistant")
+		{
+			homeAssistant.GET("", homeAssistantHandler.Discover)
+			homeAssistant.GET("/sensor", authMiddleware.IntegrationTokenRequired(), homeAssistantHandler.GetSensor)
+			homeAssistant.GET("/tokens", authMiddleware.AuthRequired(), homeAssistantHandler.ListTokens)
+			homeAssistant.POST("/tokens", authMiddleware.AuthRequired(), homeAssistantHandler.CreateToken)
+			homeAssistant.DELETE("/tokens/:id", authMiddleware.AuthRequired(), homeAssistantHandler.DeleteToken)
+		}
+
+		// Email provider callbacks (authenticated with the webhook secret)
+		webhooks := v1.Group("/webhooks")
+		{
+			webhooks.POST("/email/ses", emailWebhookHandler.HandleSES)
+			webhooks.POST("/email/sendgrid", emailWebhookHandler.HandleSendGrid)
+		}
+
+		// Audit log routes (admin only)
+		auditLogs := v1.Group("/audit-logs")
+		auditLogs.Use(adminIPFilter.Middleware(), authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+		{
+			auditLogs.GET("", auditLogHandler.ListAuditLogs)
+			auditLogs.GET("/:id", auditLogHandler.GetAuditLog)
+		}
+
+		// Runtime settings routes (admin only)
+		settingsRoutes := v1.Group("/settings")
+		settingsRoutes.Use(adminIPFilter.Middleware(), authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+		{
+			settingsRoutes.GET("", settingsHandler.ListSettings)
+			settingsRoutes.PUT("", settingsHandler.UpdateSettings)
+		}
+
+		// Admin routes
+		admin := v1.Group("/admin")
+		admin.Use(adminIPFilter.Middleware(), authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
+		{
+			admin.GET("/permissions", roleHandler.ListPermissions)
+			admin.POST("/email/test", emailAdminHandler.SendTestEmail)
+			admin.GET("/email/dead-letters", emailAdminHandler.ListDeadLetters)
+			admin.POST("/email/dead-letters/:id/resend", emailAdminHandler.ResendDeadLetter)
+			admin.GET("/email/suppressions", emailWebhookHandler.ListSuppressions)
+			admin.DELETE("/email/suppressions/:email", emailWebhookHandler.DeleteSuppression)
+			admin.POST("/config/reload", configAdminHandler.ReloadConfig)
+			admin.POST("/impersonate/:id", impersonationHandler.StartImpersonation)
+			admin.GET("/maintenance", maintenanceHandler.GetMaintenance)
+			admin.PUT("/maintenance", maintenanceHandler.UpdateMaintenance)
+			admin.GET("/migrations", migrationHandler.GetMigrations)
+			admin.POST("/migrations", migrationHandler.ApplyMigrations)
+			admin.GET("/settings", settingsHandler.ListSettings)
+			admin.PUT("/settings/:key", settingsHandler.UpdateSetting)
+			admin.DELETE("/settings/:key", settingsHandler.ResetSetting)
+			admin.GET("/data-quality", dataQualityHandler.GetDataQuality)
+			admin.POST("/data-quality/refetch", dataQualityHandler.RefetchDataQuality)
+			admin.GET("/spot-prices/conflicts", spotPriceConflictHandler.ListConflicts)
+			admin.POST("/spot-prices/conflicts/resolve", spotPriceConflictHandler.ResolveConflict)
+			admin.GET("/exchange-rates", exchangeRateHandler.ListExchangeRates)
+			admin.POST("/exchange-rates", exchangeRateHandler.CreateExchangeRates)
+			admin.GET("/providers", providerHandler.ListProviders)
+			admin.GET("/providers/:name", providerHandler.GetProvider)
+			admin.PUT("/providers/:name", providerHandler.UpdateProvider)
+			admin.GET("/providers/entsoe/areas", entsoeHandler.ListAreas)
+			admin.PUT("/providers/entsoe/areas/:id", entsoeHandler.SetArea)
+			admin.DELETE("/providers/entsoe/areas/:id", entsoeHandler.DeleteArea)
+			admin.GET("/jobs", jobHandler.ListJobs)
+			admin.GET("/jobs/:name/runs", jobHandler.ListRuns)
+			admin.POST("/jobs/:name/run", jobHandler.TriggerJob)
+			admin.GET("/webhooks", webhookHandler.ListWebhooks)
+			admin.POST("/webhooks", webhookHandler.CreateWebhook)
+			admin.GET("/webhooks/:id", webhookHandler.GetWebhook)
+			admin.PUT("/webhooks/:id", webhookHandler.UpdateWebhook)
+			admin.DELETE("/webhooks/:id", webhookHandler.DeleteWebhook)
+			admin.GET("/webhooks/:id/deliveries", webhookHandler.ListDeliveries)
 		}
 
 		// Provider routes
 		providers := v1.Group("/providers")
-		providers.Use(authMiddleware.AdminRequired())
+		providers.Use(adminIPFilter.Middleware(), authMiddleware.AuthRequired(), authMiddleware.AdminRequired())
 		{
 			providers.POST("/nordpool/fetch", providerHandler.TriggerNordpoolFetch)
+			providers.POST("/:name/run", providerHandler.RunProvider)
 		}
 	}
 
 	return r
 }
+
+// setupNotifications creates the notification service and registers a sender for each
+// configured push channel. It returns the VAPID public key when WebPush is enabled.
+func setupNotifications(
+	cfg config.PushConfig,
+	workers *worker.Group,
+	devices repository.DeviceTokenRepository,
+	targets repository.NotificationTargetRepository,
+	preferences repository.NotificationPreferenceRepository,
+	deliveries repository.NotificationDeliveryRepository,
+) (*notification.Service, string) {
+	service := notification.NewService(devices, targets, preferences, deliveries)
+	service.SetThrottleInterval(models.NotificationAlertPrice, cfg.ThrottleInterval)
+	service.SetThrottleInterval(models.NotificationAlertConsumption, cfg.ThrottleInterval)
+	if err := workers.Go("notification digests", func(ctx context.Context) {
+		service.RunDigests(ctx, time.Minute)
+	}); err != nil {
+		log.Printf("Notification digests disabled: %v", err)
+	}
+
+	if cfg.FCMCredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.FCMCredentialsFile)
+		if err != nil {
+			log.Printf("FCM disabled: failed to read credentials: %v", err)
+		} else if sender, err := notification.NewFCMSender(credentials, nil); err != nil {
+			log.Printf("FCM disabled: %v", err)
+		} else {
+			service.RegisterSender(sender)
+		}
+	}
+
+	var vapidPublicKey string
+	if cfg.VAPIDPrivateKey != "" {
+		sender, err := notification.NewWebPushSender(cfg.VAPIDPrivateKey, cfg.VAPIDSubject, nil)
+		if err != nil {
+			log.Printf("WebPush disabled: %v", err)
+		} else {
+			service.RegisterSender(sender)
+			vapidPublicKey = sender.PublicKey()
+		}
+	}
+
+	return service, vapidPublicKey
+}
@@ -33,9 +33,15 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"net/http"
 	"strconv"
 	"wattwatch/internal/api/routes"
 	"wattwatch/internal/config"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/scheduler"
+	"wattwatch/internal/worker"
 )
 
 // Server represents the HTTP server
@@ -55,7 +61,7 @@ func New(cfg *config.Config, db *sql.DB) *Server {
 // Start starts the HTTP server
 func (s *Server) Start() error {
 	// Setup routes using the routes package
-	router := routes.SetupRoutes(s.cfg, s.db)
+	router := routes.SetupRoutes(s.cfg, s.db, nil, provider.NewManager(s.db), scheduler.New(postgres.NewJobRepository(s.db)), pubsub.NewHub(s.cfg.API.StreamMaxClients), nil, config.NewReloader(s.cfg, ""), worker.NewGroup())
 
 	// Convert port string to int
 	port, err := strconv.Atoi(s.cfg.API.Port)
@@ -64,7 +70,18 @@ func (s *Server) Start() error {
 	}
 
 	// Start server
-	addr := fmt.Sprintf(":%d", port)
-	log.Printf("Starting server on %s", addr)
-	return router.Run(addr)
+	srv := &http.Server{
+		Addr:    fmt.Sprintf(":%d", port),
+		Handler: router,
+	}
+	listener := NewListener(srv, s.cfg.API, s.cfg.TLS)
+	if listener.HTTP != nil {
+		go func() {
+			if err := listener.HTTP.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				log.Printf("HTTP redirect server stopped: %v", err)
+			}
+		}()
+	}
+	log.Printf("Starting %s server on %s", listener.Scheme(), listener.Address())
+	return listener.Serve()
 }
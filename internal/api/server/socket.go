@@ -0,0 +1,83 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+	"wattwatch/internal/config"
+)
+
+// listenFDsStart is the first file descriptor passed by systemd socket activation
+const listenFDsStart = 3
+
+// listenerFor returns a function opening the listener selected by api.Listen
+func listenerFor(addr string, api config.APIConfig) func() (net.Listener, error) {
+	if path, ok := api.UnixSocket(); ok {
+		return func() (net.Listener, error) {
+			mode, err := api.FileMode()
+			if err != nil {
+				return nil, err
+			}
+			return listenUnix(path, mode)
+		}
+	}
+	if api.Listen == config.ListenSystemd {
+		return listenSystemd
+	}
+	return func() (net.Listener, error) { return net.Listen("tcp", addr) }
+}
+
+// listenUnix listens on a Unix domain socket at path. A socket left behind by a previous run
+// that didn't shut down cleanly is replaced, one that still accepts connections is not.
+func listenUnix(path string, mode os.FileMode) (net.Listener, error) {
+	if fi, err := os.Lstat(path); err == nil {
+		if fi.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s exists and is not a socket", path)
+		}
+		if conn, err := net.Dial("unix", path); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("socket %s is in use by another process", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, fmt.Errorf("failed to remove stale socket: %w", err)
+		}
+	}
+
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, mode); err != nil {
+		ln.Close()
+		return nil, fmt.Errorf("failed to set socket permissions: %w", err)
+	}
+	return ln, nil
+}
+
+// listenSystemd inherits the socket passed by systemd, see sd_listen_fds(3). The service
+// must be started through a .socket unit declaring exactly one stream socket.
+func listenSystemd() (net.Listener, error) {
+	pid, err := strconv.Atoi(os.Getenv("LISTEN_PID"))
+	if err != nil || pid != os.Getpid() {
+		return nil, errors.New("no socket passed by systemd, start the service through its .socket unit")
+	}
+	fds, err := strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	if err != nil || fds != 1 {
+		return nil, fmt.Errorf("expected one socket from systemd, got LISTEN_FDS=%q", os.Getenv("LISTEN_FDS"))
+	}
+
+	// The variables are meant for this process only, don't leak them to children
+	os.Unsetenv("LISTEN_PID")
+	os.Unsetenv("LISTEN_FDS")
+	os.Unsetenv("LISTEN_FDNAMES")
+
+	f := os.NewFile(listenFDsStart, "systemd-socket")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("failed to use systemd socket: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,104 @@
+package server
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+	"wattwatch/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// socketDir returns a short temporary directory, socket paths are limited to about 100 bytes
+func socketDir(t *testing.T) string {
+	dir, err := os.MkdirTemp("", "ww")
+	require.NoError(t, err)
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return dir
+}
+
+func TestListener_UnixSocket(t *testing.T) {
+	path := filepath.Join(socketDir(t), "api.sock")
+	api := config.APIConfig{Port: "8080", Listen: "unix:" + path, SocketMode: "0600"}
+
+	// A socket left behind by a crashed process is replaced
+	stale, err := net.Listen("unix", path)
+	require.NoError(t, err)
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "pong")
+	})}
+	l := NewListener(srv, api, config.TLSConfig{})
+	assert.Equal(t, "unix socket "+path, l.Address())
+
+	done := make(chan error, 1)
+	go func() { done <- l.Serve() }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	require.Eventually(t, func() bool {
+		resp, err := client.Get("http://unix/ping")
+		if err != nil {
+			return false
+		}
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return string(body) == "pong"
+	}, time.Second, 10*time.Millisecond)
+
+	fi, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	// A socket that still accepts connections is left alone
+	_, err = listenUnix(path, 0o600)
+	assert.ErrorContains(t, err, "in use")
+
+	require.NoError(t, srv.Shutdown(context.Background()))
+	assert.ErrorIs(t, <-done, http.ErrServerClosed)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "the socket is removed on shutdown")
+}
+
+func TestListenUnix_NotASocket(t *testing.T) {
+	path := filepath.Join(socketDir(t), "api.sock")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+
+	_, err := listenUnix(path, 0o660)
+	assert.ErrorContains(t, err, "not a socket")
+}
+
+func TestListenSystemd(t *testing.T) {
+	t.Run("Not Activated", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "")
+		t.Setenv("LISTEN_FDS", "")
+		_, err := listenSystemd()
+		assert.ErrorContains(t, err, "no socket passed by systemd")
+	})
+
+	t.Run("Other Process", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "1")
+		t.Setenv("LISTEN_FDS", "1")
+		_, err := listenSystemd()
+		assert.ErrorContains(t, err, "no socket passed by systemd")
+	})
+
+	t.Run("Several Sockets", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		t.Setenv("LISTEN_FDS", "2")
+		_, err := listenSystemd()
+		assert.ErrorContains(t, err, "expected one socket")
+	})
+}
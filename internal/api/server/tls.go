@@ -0,0 +1,107 @@
+package server
+
+import (
+	"crypto/tls"
+	"net"
+	"net/http"
+	"wattwatch/internal/config"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Listener starts srv according to the listen and TLS settings. HTTP/2 is negotiated
+// automatically when serving HTTPS.
+type Listener struct {
+	srv    *http.Server
+	api    config.APIConfig
+	listen func() (net.Listener, error)
+	serve  func(net.Listener) error
+	// HTTP serves ACME challenges and redirects to HTTPS, nil when not configured
+	HTTP *http.Server
+}
+
+// NewListener prepares srv to serve plain HTTP, HTTPS from certificate files, or HTTPS with
+// certificates obtained from Let's Encrypt for the configured domains. Connections are
+// accepted on the TCP port in srv.Addr, a Unix domain socket or a systemd socket.
+func NewListener(srv *http.Server, api config.APIConfig, cfg config.TLSConfig) *Listener {
+	l := &Listener{srv: srv, api: api, listen: listenerFor(srv.Addr, api)}
+	apiPort := api.Port
+
+	switch {
+	case cfg.AutocertDomains != "":
+		manager := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(cfg.Domains()...),
+			Cache:      autocert.DirCache(cfg.AutocertCacheDir),
+			Email:      cfg.AutocertEmail,
+		}
+		srv.TLSConfig = manager.TLSConfig()
+		srv.TLSConfig.MinVersion = tls.VersionTLS12
+		l.serve = func(ln net.Listener) error { return srv.ServeTLS(ln, "", "") }
+		if cfg.HTTPPort != "" {
+			l.HTTP = &http.Server{
+				Addr:    ":" + cfg.HTTPPort,
+				Handler: manager.HTTPHandler(redirectToHTTPS(apiPort)),
+			}
+		}
+
+	case cfg.CertFile != "":
+		srv.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+		l.serve = func(ln net.Listener) error { return srv.ServeTLS(ln, cfg.CertFile, cfg.KeyFile) }
+		if cfg.HTTPPort != "" {
+			l.HTTP = &http.Server{
+				Addr:    ":" + cfg.HTTPPort,
+				Handler: redirectToHTTPS(apiPort),
+			}
+		}
+
+	default:
+		l.serve = srv.Serve
+	}
+
+	return l
+}
+
+// Scheme returns "https" when TLS is enabled and "http" otherwise
+func (l *Listener) Scheme() string {
+	if l.srv.TLSConfig != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// Address describes where connections are accepted, for logging
+func (l *Listener) Address() string {
+	if path, ok := l.api.UnixSocket(); ok {
+		return "unix socket " + path
+	}
+	if l.api.Listen == config.ListenSystemd {
+		return "systemd socket"
+	}
+	return "port " + l.api.Port
+}
+
+// Serve blocks serving the API, like http.Server.ListenAndServe
+func (l *Listener) Serve() error {
+	ln, err := l.listen()
+	if err != nil {
+		return err
+	}
+	return l.serve(ln)
+}
+
+// redirectToHTTPS sends plain HTTP requests to the same path on the HTTPS port
+func redirectToHTTPS(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		target := "https://" + host + r.URL.RequestURI()
+		http.Redirect(w, r, target, http.StatusMovedPermanently)
+	})
+}
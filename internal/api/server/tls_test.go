@@ -0,0 +1,61 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewListener(t *testing.T) {
+	tests := []struct {
+		name       string
+		cfg        config.TLSConfig
+		wantScheme string
+		wantHTTP   bool
+	}{
+		{name: "Plain HTTP", wantScheme: "http"},
+		{name: "Certificate Files", cfg: config.TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem"}, wantScheme: "https"},
+		{name: "Certificate Files With Redirect", cfg: config.TLSConfig{CertFile: "cert.pem", KeyFile: "key.pem", HTTPPort: "80"}, wantScheme: "https", wantHTTP: true},
+		{name: "Autocert", cfg: config.TLSConfig{AutocertDomains: "example.com", AutocertCacheDir: t.TempDir(), HTTPPort: "80"}, wantScheme: "https", wantHTTP: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := NewListener(&http.Server{Addr: ":8443"}, config.APIConfig{Port: "8443"}, tt.cfg)
+			assert.Equal(t, tt.wantScheme, l.Scheme())
+			assert.Equal(t, tt.wantHTTP, l.HTTP != nil)
+			if tt.wantScheme == "https" {
+				require.NotNil(t, l.srv.TLSConfig)
+				assert.NotZero(t, l.srv.TLSConfig.MinVersion)
+			}
+		})
+	}
+}
+
+func TestRedirectToHTTPS(t *testing.T) {
+	tests := []struct {
+		name      string
+		httpsPort string
+		host      string
+		want      string
+	}{
+		{name: "Default Port", httpsPort: "443", host: "example.com", want: "https://example.com/api/v1/zones?page=2"},
+		{name: "Custom Port", httpsPort: "8443", host: "example.com:8080", want: "https://example.com:8443/api/v1/zones?page=2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest("GET", "/api/v1/zones?page=2", nil)
+			req.Host = tt.host
+			redirectToHTTPS(tt.httpsPort).ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusMovedPermanently, w.Code)
+			assert.Equal(t, tt.want, w.Header().Get("Location"))
+		})
+	}
+}
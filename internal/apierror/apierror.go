@@ -0,0 +1,95 @@
+// Package apierror defines the error codes of the API and writes errors as RFC 7807
+// application/problem+json responses, so clients can branch on stable codes rather than
+// on messages meant for people
+package apierror
+
+import (
+	"errors"
+	"log"
+	"wattwatch/internal/validation"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ContentType is the media type of problem responses
+const ContentType = "application/problem+json"
+
+// TypeBase prefixes the code of an error to form the type URI of its problems, which
+// resolves to the catalog entry of the code
+const TypeBase = "/api/v1/errors/"
+
+// Error is a failure reported to the client, with a code from the catalog and a detail
+// explaining this occurrence
+type Error struct {
+	Code   Code
+	Detail string
+	// Errors lists the fields of the request that failed validation
+	Errors []validation.FieldError
+}
+
+// New returns an error with code and detail
+func New(code Code, detail string) *Error {
+	return &Error{Code: code, Detail: detail}
+}
+
+func (e *Error) Error() string {
+	return string(e.Code) + ": " + e.Detail
+}
+
+// Status returns the HTTP status of the error's code
+func (e *Error) Status() int {
+	return e.Code.Entry().Status
+}
+
+// Problem is an error response as defined by RFC 7807
+type Problem struct {
+	Type     string `json:"type" example:"/api/v1/errors/ZONE404"`
+	Title    string `json:"title" example:"Zone not found"`
+	Status   int    `json:"status" example:"404"`
+	Detail   string `json:"detail" example:"zone not found"`
+	Instance string `json:"instance" example:"/api/v1/zones/SE5"`
+	Code     Code   `json:"code" example:"ZONE404"`
+	// Error repeats Detail for clients written for the former {"error": "..."} responses
+	Error  string                  `json:"error" example:"zone not found"`
+	Errors []validation.FieldError `json:"errors,omitempty"`
+}
+
+// NewProblem returns the problem describing err at the request path instance
+func NewProblem(err *Error, instance string) Problem {
+	entry := err.Code.Entry()
+	return Problem{
+		Type:     TypeBase + string(err.Code),
+		Title:    entry.Title,
+		Status:   entry.Status,
+		Detail:   err.Detail,
+		Instance: instance,
+		Code:     err.Code,
+		Error:    err.Detail,
+		Errors:   err.Errors,
+	}
+}
+
+// Write responds with code and detail as a problem
+func Write(c *gin.Context, code Code, detail string) {
+	Respond(c, New(code, detail))
+}
+
+// Abort responds with code and detail as a problem and stops the remaining handlers
+func Abort(c *gin.Context, code Code, detail string) {
+	Respond(c, New(code, detail))
+	c.Abort()
+}
+
+// Respond responds with err as a problem. Errors other than *Error are logged and
+// reported as Internal without their message.
+func Respond(c *gin.Context, err error) {
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		log.Printf("Error handling %s %s: %v", c.Request.Method, c.Request.URL.Path, err)
+		apiErr = New(Internal, "internal server error")
+	}
+
+	problem := NewProblem(apiErr, c.Request.URL.Path)
+	c.Header("Content-Type", ContentType)
+	c.JSON(problem.Status, problem)
+}
@@ -0,0 +1,72 @@
+package apierror
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRespond(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	respond := func(err error) (*httptest.ResponseRecorder, Problem) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/api/v1/zones/SE5", nil)
+		Respond(c, err)
+
+		var problem Problem
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problem))
+		return w, problem
+	}
+
+	w, problem := respond(New(ZoneNotFound, "zone not found"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ContentType, w.Header().Get("Content-Type"))
+	assert.Equal(t, Problem{
+		Type:     "/api/v1/errors/ZONE404",
+		Title:    "Zone not found",
+		Status:   http.StatusNotFound,
+		Detail:   "zone not found",
+		Instance: "/api/v1/zones/SE5",
+		Code:     ZoneNotFound,
+		Error:    "zone not found",
+	}, problem)
+
+	// Wrapped errors keep their code
+	w, problem = respond(fmt.Errorf("loading zone: %w", New(ZoneNotFound, "zone not found")))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, ZoneNotFound, problem.Code)
+
+	// Other errors don't leak their message
+	w, problem = respond(errors.New("pq: connection refused"))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, Internal, problem.Code)
+	assert.Equal(t, "internal server error", problem.Detail)
+}
+
+func TestCatalog(t *testing.T) {
+	entries := Catalog()
+	require.Len(t, entries, len(catalog))
+	for i, entry := range entries {
+		assert.NotEmpty(t, entry.Title, entry.Code)
+		assert.GreaterOrEqual(t, entry.Status, 400, entry.Code)
+		if i > 0 {
+			assert.Less(t, entries[i-1].Code, entry.Code)
+		}
+	}
+
+	entry, ok := Lookup(UserNotFound)
+	assert.True(t, ok)
+	assert.Equal(t, Entry{Code: "USER404", Status: http.StatusNotFound, Title: "User not found"}, entry)
+	_, ok = Lookup("USER999")
+	assert.False(t, ok)
+	assert.Equal(t, http.StatusInternalServerError, Code("USER999").Entry().Status)
+}
@@ -0,0 +1,166 @@
+package apierror
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Code identifies a kind of error. Codes are stable, clients may branch on them. They
+// start with the area of the API and mostly end with the HTTP status of the error.
+type Code string
+
+// Request errors
+const (
+	InvalidRequest       Code = "REQ400"
+	ValidationFailed     Code = "REQ001"
+	AddressDenied        Code = "REQ403"
+	NotFound             Code = "REQ404"
+	Conflict             Code = "REQ409"
+	UnsupportedMediaType Code = "REQ415"
+	Unprocessable        Code = "REQ422"
+	RateLimited          Code = "REQ429"
+	Timeout              Code = "REQ504"
+)
+
+// Server errors
+const (
+	Internal       Code = "SRV500"
+	Unavailable    Code = "SRV503"
+	Maintenance    Code = "SRV001"
+	UpstreamFailed Code = "SRV502"
+)
+
+// Authentication and authorization errors
+const (
+	Unauthorized         Code = "AUTH401"
+	InvalidCredentials   Code = "AUTH001"
+	InvalidToken         Code = "AUTH002"
+	InvalidTwoFactor     Code = "AUTH003"
+	TooManyAttempts      Code = "AUTH004"
+	RegistrationDisabled Code = "AUTH005"
+	PasswordPolicy       Code = "AUTH006"
+	CaptchaFailed        Code = "AUTH007"
+	Forbidden            Code = "AUTH403"
+	TwoFactorState       Code = "AUTH409"
+)
+
+// Resource errors
+const (
+	UserNotFound         Code = "USER404"
+	UserExists           Code = "USER409"
+	RoleNotFound         Code = "ROLE404"
+	ZoneNotFound         Code = "ZONE404"
+	ZoneInUse            Code = "ZONE409"
+	CurrencyNotFound     Code = "CURRENCY404"
+	CurrencyInUse        Code = "CURRENCY409"
+	SpotPriceNotFound    Code = "PRICE404"
+	OrganizationNotFound Code = "ORG404"
+	OrganizationConflict Code = "ORG409"
+	SessionNotFound      Code = "SESSION404"
+	WebhookNotFound      Code = "WEBHOOK404"
+	AlertNotFound        Code = "ALERT404"
+	BudgetNotFound       Code = "BUDGET404"
+	BudgetExists         Code = "BUDGET409"
+	TariffNotFound       Code = "TARIFF404"
+	TariffExists         Code = "TARIFF409"
+	NotificationNotFound Code = "NOTIFY404"
+	IntegrationNotFound  Code = "INTEGRATION404"
+	JobNotFound          Code = "JOB404"
+	JobRunning           Code = "JOB409"
+	SettingNotFound      Code = "SETTING404"
+	AuditLogNotFound     Code = "AUDIT404"
+	EmailNotFound        Code = "EMAIL404"
+	EmailConflict        Code = "EMAIL409"
+	ProviderNotFound     Code = "PROVIDER404"
+)
+
+// Entry describes a code of the catalog
+type Entry struct {
+	Code   Code   `json:"code" example:"ZONE404"`
+	Status int    `json:"status" example:"404"`
+	Title  string `json:"title" example:"Zone not found"`
+}
+
+var catalog = map[Code]Entry{
+	InvalidRequest:       {Status: http.StatusBadRequest, Title: "Invalid request"},
+	ValidationFailed:     {Status: http.StatusBadRequest, Title: "Request validation failed"},
+	AddressDenied:        {Status: http.StatusForbidden, Title: "Client address not allowed"},
+	NotFound:             {Status: http.StatusNotFound, Title: "Not found"},
+	Conflict:             {Status: http.StatusConflict, Title: "Conflict"},
+	UnsupportedMediaType: {Status: http.StatusUnsupportedMediaType, Title: "Unsupported media type"},
+	Unprocessable:        {Status: http.StatusUnprocessableEntity, Title: "Unprocessable request"},
+	RateLimited:          {Status: http.StatusTooManyRequests, Title: "Too many requests"},
+	Timeout:              {Status: http.StatusGatewayTimeout, Title: "Request timed out"},
+
+	Internal:       {Status: http.StatusInternalServerError, Title: "Internal server error"},
+	Unavailable:    {Status: http.StatusServiceUnavailable, Title: "Service unavailable"},
+	Maintenance:    {Status: http.StatusServiceUnavailable, Title: "Down for maintenance"},
+	UpstreamFailed: {Status: http.StatusBadGateway, Title: "Upstream service failed"},
+
+	Unauthorized:         {Status: http.StatusUnauthorized, Title: "Authentication required"},
+	InvalidCredentials:   {Status: http.StatusUnauthorized, Title: "Invalid credentials"},
+	InvalidToken:         {Status: http.StatusUnauthorized, Title: "Invalid or expired token"},
+	InvalidTwoFactor:     {Status: http.StatusUnauthorized, Title: "Invalid two-factor authentication"},
+	TooManyAttempts:      {Status: http.StatusTooManyRequests, Title: "Too many failed login attempts"},
+	RegistrationDisabled: {Status: http.StatusForbidden, Title: "Registration is disabled"},
+	PasswordPolicy:       {Status: http.StatusBadRequest, Title: "Password does not meet the password policy"},
+	CaptchaFailed:        {Status: http.StatusBadRequest, Title: "CAPTCHA verification failed"},
+	Forbidden:            {Status: http.StatusForbidden, Title: "Permission denied"},
+	TwoFactorState:       {Status: http.StatusConflict, Title: "Two-factor authentication state conflict"},
+
+	UserNotFound:         {Status: http.StatusNotFound, Title: "User not found"},
+	UserExists:           {Status: http.StatusConflict, Title: "User already exists"},
+	RoleNotFound:         {Status: http.StatusNotFound, Title: "Role not found"},
+	ZoneNotFound:         {Status: http.StatusNotFound, Title: "Zone not found"},
+	ZoneInUse:            {Status: http.StatusConflict, Title: "Zone is in use"},
+	CurrencyNotFound:     {Status: http.StatusNotFound, Title: "Currency not found"},
+	CurrencyInUse:        {Status: http.StatusConflict, Title: "Currency is in use"},
+	SpotPriceNotFound:    {Status: http.StatusNotFound, Title: "Spot price not found"},
+	OrganizationNotFound: {Status: http.StatusNotFound, Title: "Organization not found"},
+	OrganizationConflict: {Status: http.StatusConflict, Title: "Organization conflict"},
+	SessionNotFound:      {Status: http.StatusNotFound, Title: "Session not found"},
+	WebhookNotFound:      {Status: http.StatusNotFound, Title: "Webhook not found"},
+	AlertNotFound:        {Status: http.StatusNotFound, Title: "Price alert not found"},
+	BudgetNotFound:       {Status: http.StatusNotFound, Title: "Budget not found"},
+	BudgetExists:         {Status: http.StatusConflict, Title: "Budget already exists"},
+	TariffNotFound:       {Status: http.StatusNotFound, Title: "Tariff not found"},
+	TariffExists:         {Status: http.StatusConflict, Title: "Tariff already exists"},
+	NotificationNotFound: {Status: http.StatusNotFound, Title: "Notification channel not found"},
+	IntegrationNotFound:  {Status: http.StatusNotFound, Title: "Integration token not found"},
+	JobNotFound:          {Status: http.StatusNotFound, Title: "Job not found"},
+	JobRunning:           {Status: http.StatusConflict, Title: "Job is already running"},
+	SettingNotFound:      {Status: http.StatusNotFound, Title: "Setting not found"},
+	AuditLogNotFound:     {Status: http.StatusNotFound, Title: "Audit log entry not found"},
+	EmailNotFound:        {Status: http.StatusNotFound, Title: "Email not found"},
+	EmailConflict:        {Status: http.StatusConflict, Title: "Email conflict"},
+	ProviderNotFound:     {Status: http.StatusNotFound, Title: "Provider or area mapping not found"},
+}
+
+// Entry returns the catalog entry of the code. Codes missing from the catalog are
+// reported as internal server errors.
+func (c Code) Entry() Entry {
+	entry, ok := catalog[c]
+	if !ok {
+		entry = catalog[Internal]
+	}
+	entry.Code = c
+	return entry
+}
+
+// Lookup returns the catalog entry of code, false when the catalog doesn't hold it
+func Lookup(code Code) (Entry, bool) {
+	entry, ok := catalog[code]
+	entry.Code = code
+	return entry, ok
+}
+
+// Catalog returns every entry of the catalog ordered by code
+func Catalog() []Entry {
+	entries := make([]Entry, 0, len(catalog))
+	for code := range catalog {
+		entries = append(entries, code.Entry())
+	}
+	slices.SortFunc(entries, func(a, b Entry) int { return strings.Compare(string(a.Code), string(b.Code)) })
+	return entries
+}
@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"slices"
+	"wattwatch/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// RoleClaims are the role claims of an access token. They let read-only endpoints
+// authorize a request without looking the user up, while endpoints that change data
+// check them against the role stored in the database.
+type RoleClaims struct {
+	RoleID      uuid.UUID
+	Role        string
+	Version     int
+	Permissions []string
+}
+
+// roleClaims returns the claims describing the user's role
+func roleClaims(role *models.Role) jwt.MapClaims {
+	return jwt.MapClaims{
+		"role_id":      role.ID,
+		"role":         role.Name,
+		"role_version": role.TokenVersion,
+		"permissions":  role.Grants(),
+	}
+}
+
+// ParseRoleClaims reads the role claims of a validated token. It returns false for
+// tokens that don't carry them, such as ones issued before they were added.
+func ParseRoleClaims(claims jwt.MapClaims) (RoleClaims, bool) {
+	var rc RoleClaims
+	roleID, _ := claims["role_id"].(string)
+	id, err := uuid.Parse(roleID)
+	if err != nil {
+		return rc, false
+	}
+	rc.RoleID = id
+
+	if rc.Role, _ = claims["role"].(string); rc.Role == "" {
+		return rc, false
+	}
+	// JSON numbers are decoded as float64
+	version, ok := claims["role_version"].(float64)
+	if !ok {
+		return rc, false
+	}
+	rc.Version = int(version)
+
+	permissions, _ := claims["permissions"].([]interface{})
+	for _, p := range permissions {
+		if s, ok := p.(string); ok {
+			rc.Permissions = append(rc.Permissions, s)
+		}
+	}
+	return rc, true
+}
+
+// Has reports whether the claims grant the permission
+func (rc RoleClaims) Has(permission string) bool {
+	return slices.Contains(rc.Permissions, permission)
+}
+
+// Current reports whether the claims still describe the user's role, they don't once
+// the user was given another role or the role was changed after the token was issued
+func (rc RoleClaims) Current(user *models.User) bool {
+	return user.Role != nil && rc.RoleID == user.RoleID && rc.Version == user.Role.TokenVersion
+}
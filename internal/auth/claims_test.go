@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"testing"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRoleClaims(t *testing.T) {
+	service := NewService(&config.Config{JWTSecret: "secret"}, nil)
+	role := &models.Role{ID: uuid.New(), Name: "admin", IsAdminGroup: true, TokenVersion: 3}
+	user := &models.User{ID: uuid.New(), Username: "user", RoleID: role.ID, Role: role}
+
+	token, err := service.GenerateToken(user, false)
+	require.NoError(t, err)
+	claims, err := service.ValidateToken(token)
+	require.NoError(t, err)
+
+	rc, ok := ParseRoleClaims(*claims)
+	require.True(t, ok)
+	require.Equal(t, RoleClaims{
+		RoleID:      role.ID,
+		Role:        "admin",
+		Version:     3,
+		Permissions: []string{models.PermissionRead, models.PermissionAdmin},
+	}, rc)
+	require.True(t, rc.Has(models.PermissionAdmin))
+	require.True(t, rc.Current(user))
+
+	// Changing the role or giving the user another one makes the claims outdated
+	changed := *user
+	changed.Role = &models.Role{ID: role.ID, TokenVersion: 4}
+	require.False(t, rc.Current(&changed))
+	changed.RoleID = uuid.New()
+	changed.Role = &models.Role{ID: changed.RoleID, TokenVersion: 3}
+	require.False(t, rc.Current(&changed))
+
+	// Tokens issued before role claims were added don't have them
+	delete(*claims, "role_version")
+	_, ok = ParseRoleClaims(*claims)
+	require.False(t, ok)
+}
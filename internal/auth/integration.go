@@ -0,0 +1,35 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// IntegrationTokenPrefix starts every integration token, telling them apart from access
+// tokens in the Authorization header
+const IntegrationTokenPrefix = "wwi_"
+
+// NewIntegrationToken returns a random integration token and the hash it is stored by
+func NewIntegrationToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := IntegrationTokenPrefix + hex.EncodeToString(b)
+	return token, HashIntegrationToken(token), nil
+}
+
+// IsIntegrationToken reports whether a bearer token is an integration token rather than
+// an access token
+func IsIntegrationToken(token string) bool {
+	return strings.HasPrefix(token, IntegrationTokenPrefix)
+}
+
+// HashIntegrationToken hashes an integration token for storage and lookup, only the hash
+// is kept so a leaked database doesn't hand out working tokens
+func HashIntegrationToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
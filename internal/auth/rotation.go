@@ -0,0 +1,40 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"time"
+)
+
+// RotatingSecret is a shared secret that may be in the middle of a rotation. New values
+// are signed with Current, and values signed with Previous are still accepted until the
+// rotation ends, so replacing a secret doesn't invalidate what was issued with the old one.
+type RotatingSecret struct {
+	Current  string
+	Previous string
+	// Until returns when Previous stops being accepted. A nil func or the zero time keeps
+	// accepting it for as long as it is configured.
+	Until func() time.Time
+}
+
+// Accepted returns the secrets that are valid at now, the current one first
+func (s RotatingSecret) Accepted(now time.Time) []string {
+	secrets := []string{s.Current}
+	if s.Previous == "" || s.Previous == s.Current {
+		return secrets
+	}
+	if s.Until != nil {
+		if until := s.Until(); !until.IsZero() && !now.Before(until) {
+			return secrets
+		}
+	}
+	return append(secrets, s.Previous)
+}
+
+// Matches reports whether value equals an accepted secret, in constant time
+func (s RotatingSecret) Matches(value string, now time.Time) bool {
+	matched := 0
+	for _, secret := range s.Accepted(now) {
+		matched |= subtle.ConstantTimeCompare([]byte(value), []byte(secret))
+	}
+	return matched == 1
+}
@@ -0,0 +1,89 @@
+package auth
+
+import (
+	"testing"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRotatingSecret(t *testing.T) {
+	now := time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC)
+	at := func(t time.Time) func() time.Time { return func() time.Time { return t } }
+
+	tests := []struct {
+		name   string
+		secret RotatingSecret
+		want   []string
+	}{
+		{
+			name:   "no rotation",
+			secret: RotatingSecret{Current: "new"},
+			want:   []string{"new"},
+		},
+		{
+			name:   "rotation without end",
+			secret: RotatingSecret{Current: "new", Previous: "old"},
+			want:   []string{"new", "old"},
+		},
+		{
+			name:   "rotation ending later",
+			secret: RotatingSecret{Current: "new", Previous: "old", Until: at(now.Add(time.Hour))},
+			want:   []string{"new", "old"},
+		},
+		{
+			name:   "rotation ended",
+			secret: RotatingSecret{Current: "new", Previous: "old", Until: at(now)},
+			want:   []string{"new"},
+		},
+		{
+			name:   "unset end",
+			secret: RotatingSecret{Current: "new", Previous: "old", Until: at(time.Time{})},
+			want:   []string{"new", "old"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, tt.secret.Accepted(now))
+			for _, secret := range tt.want {
+				require.True(t, tt.secret.Matches(secret, now))
+			}
+			require.False(t, tt.secret.Matches("", now))
+			require.False(t, tt.secret.Matches("other", now))
+		})
+	}
+}
+
+func TestServiceSecretRotation(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "user", Role: &models.Role{}}
+
+	old := NewService(&config.Config{JWTSecret: "old-secret"}, nil)
+	token, err := old.GenerateToken(user, false)
+	require.NoError(t, err)
+
+	// After the rotation tokens signed with the previous secret stay valid
+	rotated := NewService(&config.Config{JWTSecret: "new-secret"}, nil)
+	_, err = rotated.ValidateToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	until := time.Now().Add(time.Hour)
+	rotated.AcceptPreviousSecret("old-secret", func() time.Time { return until })
+	claims, err := rotated.ValidateToken(token)
+	require.NoError(t, err)
+	require.Equal(t, user.ID.String(), (*claims)["user_id"])
+
+	// New tokens are signed with the new secret only
+	fresh, err := rotated.GenerateToken(user, false)
+	require.NoError(t, err)
+	_, err = old.ValidateToken(fresh)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	// Once the rotation ends the previous secret is rejected
+	until = time.Now().Add(-time.Second)
+	_, err = rotated.ValidateToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -5,6 +5,7 @@ import (
 	"crypto/rand"
 	"encoding/base64"
 	"errors"
+	"sync"
 	"time"
 	"wattwatch/internal/config"
 	"wattwatch/internal/models"
@@ -21,12 +22,22 @@ var (
 	ErrInvalidToken = errors.New("invalid token")
 	// ErrTokenExpired indicates the token has expired
 	ErrTokenExpired = errors.New("token expired")
+	// ErrTokenReused indicates a refresh token was used after it was rotated, so its family
+	// was revoked
+	ErrTokenReused = errors.New("token reused")
 )
 
+// refreshTokenTTL is how long a refresh token can be used, rotation issues a new one with
+// the full lifetime
+const refreshTokenTTL = time.Hour * 24 * 7
+
 // Service provides authentication functionality
 type Service struct {
 	config           *config.Config
 	refreshTokenRepo repository.RefreshTokenRepository
+	// previousSecret and previousUntil describe a JWT secret rotation in progress
+	previousSecret string
+	previousUntil  func() time.Time
 }
 
 // NewService creates a new authentication service
@@ -37,6 +48,18 @@ func NewService(config *config.Config, refreshTokenRepo repository.RefreshTokenR
 	}
 }
 
+// AcceptPreviousSecret keeps tokens signed with previous valid after the JWT secret was
+// replaced, until the time until returns, so a rotation doesn't log everyone out
+func (s *Service) AcceptPreviousSecret(previous string, until func() time.Time) {
+	s.previousSecret = previous
+	s.previousUntil = until
+}
+
+// jwtSecret returns the JWT secret with the rotation state
+func (s *Service) jwtSecret() RotatingSecret {
+	return RotatingSecret{Current: s.config.JWTSecret, Previous: s.previousSecret, Until: s.previousUntil}
+}
+
 // GenerateToken generates a new JWT token
 func (s *Service) GenerateToken(user *models.User, isRefresh bool) (string, error) {
 	// Set expiration based on token type
@@ -53,57 +76,82 @@ func (s *Service) GenerateToken(user *models.User, isRefresh bool) (string, erro
 		"is_admin": user.Role.IsAdminGroup,
 		"exp":      time.Now().Add(expiration).Unix(),
 	}
+	// Access tokens carry the role, so read-only endpoints don't have to look it up
+	if !isRefresh {
+		for k, v := range roleClaims(user.Role) {
+			claims[k] = v
+		}
+	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(s.config.JWTSecret))
+	return token.SignedString([]byte(s.jwtSecret().Current))
 }
 
-// GenerateRefreshToken generates a new refresh token
-func (s *Service) GenerateRefreshToken(ctx context.Context, userID uuid.UUID) (string, error) {
-	// Generate random bytes for the token
-	b := make([]byte, 32)
-	if _, err := rand.Read(b); err != nil {
+// GenerateRefreshToken generates a new refresh token, starting a session of the user on client
+func (s *Service) GenerateRefreshToken(ctx context.Context, userID uuid.UUID, client models.SessionClient) (string, error) {
+	token, err := newRefreshToken()
+	if err != nil {
 		return "", err
 	}
 
-	// Convert to base64
-	token := base64.URLEncoding.EncodeToString(b)
-
-	// Set expiration to 7 days
-	expiresAt := time.Now().Add(time.Hour * 24 * 7)
-
 	// Store in database
-	if err := s.refreshTokenRepo.Create(ctx, userID, token, expiresAt); err != nil {
+	if err := s.refreshTokenRepo.Create(ctx, userID, token, time.Now().Add(refreshTokenTTL), client); err != nil {
 		return "", err
 	}
 
 	return token, nil
 }
 
-// ValidateRefreshToken validates a refresh token and returns the associated user ID
-func (s *Service) ValidateRefreshToken(token string) (uuid.UUID, error) {
-	refreshToken, err := s.refreshTokenRepo.GetByToken(context.Background(), token)
+// newRefreshToken returns a random refresh token
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.URLEncoding.EncodeToString(b), nil
+}
+
+// RotateRefreshToken exchanges a refresh token for a new one, revoking it. It returns the
+// user the token belongs to and the new token. Using a token again after it was rotated
+// revokes all tokens rotated from the same login and returns ErrTokenReused, with the user.
+// Tokens revoked otherwise give ErrInvalidToken. The session records client as its last user.
+func (s *Service) RotateRefreshToken(ctx context.Context, token string, client models.SessionClient) (uuid.UUID, string, error) {
+	newToken, err := newRefreshToken()
 	if err != nil {
-		if errors.Is(err, repository.ErrTokenNotFound) {
-			return uuid.Nil, ErrInvalidToken
-		}
-		if errors.Is(err, repository.ErrTokenExpired) {
-			return uuid.Nil, ErrTokenExpired
-		}
-		return uuid.Nil, err
+		return uuid.Nil, "", err
 	}
 
-	return refreshToken.UserID, nil
+	now := time.Now()
+	rotated, err := s.refreshTokenRepo.Rotate(ctx, token, newToken, now.Add(refreshTokenTTL), now, client)
+	switch {
+	case errors.Is(err, repository.ErrTokenInvalid):
+		return uuid.Nil, "", ErrInvalidToken
+	case errors.Is(err, repository.ErrTokenExpired):
+		return uuid.Nil, "", ErrTokenExpired
+	case errors.Is(err, repository.ErrTokenReused):
+		return rotated.UserID, "", ErrTokenReused
+	case err != nil:
+		return uuid.Nil, "", err
+	}
+
+	return rotated.UserID, rotated.Token, nil
 }
 
-// DeleteRefreshToken removes a refresh token
-func (s *Service) DeleteRefreshToken(token string) error {
-	return s.refreshTokenRepo.DeleteByToken(context.Background(), token)
+// RevokeRefreshToken logs out the session of a refresh token, revoking it with the tokens
+// rotated from the same login
+func (s *Service) RevokeRefreshToken(ctx context.Context, token string) error {
+	if err := s.refreshTokenRepo.RevokeFamily(ctx, token, time.Now()); err != nil {
+		if errors.Is(err, repository.ErrTokenInvalid) {
+			return ErrInvalidToken
+		}
+		return err
+	}
+	return nil
 }
 
-// DeleteAllRefreshTokens removes all refresh tokens for a user
-func (s *Service) DeleteAllRefreshTokens(userID uuid.UUID) error {
-	return s.refreshTokenRepo.DeleteByUserID(context.Background(), userID)
+// RevokeAllRefreshTokens logs out all sessions of a user
+func (s *Service) RevokeAllRefreshTokens(ctx context.Context, userID uuid.UUID) error {
+	return s.refreshTokenRepo.RevokeByUserID(ctx, userID, time.Now())
 }
 
 // HashPassword hashes a password using bcrypt
@@ -117,13 +165,36 @@ func (s *Service) ComparePasswords(hashedPassword, password string) error {
 	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
 }
 
+// dummyHash is compared against when there is no user to check a password for
+var dummyHash = sync.OnceValue(func() []byte {
+	hash, err := bcrypt.GenerateFromPassword([]byte("wattwatch-dummy-password"), bcrypt.DefaultCost)
+	if err != nil {
+		panic(err)
+	}
+	return hash
+})
+
+// CompareDummyPassword takes as long as ComparePasswords but always fails. Logins for
+// unknown users call it so they can't be told apart from wrong passwords by timing.
+func (s *Service) CompareDummyPassword(password string) error {
+	if err := bcrypt.CompareHashAndPassword(dummyHash(), []byte(password)); err != nil {
+		return err
+	}
+	return bcrypt.ErrMismatchedHashAndPassword
+}
+
 // ValidateToken validates a JWT token and returns the claims
 func (s *Service) ValidateToken(tokenString string) (*jwt.MapClaims, error) {
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
 			return nil, ErrInvalidToken
 		}
-		return []byte(s.config.JWTSecret), nil
+		// Tokens signed with a secret that is being rotated out stay valid until the rotation ends
+		keys := jwt.VerificationKeySet{}
+		for _, secret := range s.jwtSecret().Accepted(time.Now()) {
+			keys.Keys = append(keys.Keys, []byte(secret))
+		}
+		return keys, nil
 	})
 
 	if err != nil {
@@ -0,0 +1,18 @@
+package auth
+
+import (
+	"testing"
+	"wattwatch/internal/config"
+
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestCompareDummyPassword(t *testing.T) {
+	service := NewService(&config.Config{JWTSecret: "secret"}, nil)
+
+	for _, password := range []string{"", "password123", "wattwatch-dummy-password"} {
+		err := service.CompareDummyPassword(password)
+		require.ErrorIs(t, err, bcrypt.ErrMismatchedHashAndPassword, password)
+	}
+}
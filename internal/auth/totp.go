@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"image/png"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+)
+
+const (
+	// TOTPIssuer names the service in authenticator apps
+	TOTPIssuer = "WattWatch"
+	// TOTPPeriod is how long a TOTP code is valid, codes of the periods before and after
+	// the current one are accepted for clock drift
+	TOTPPeriod = 30 * time.Second
+	// BackupCodeCount is the number of backup codes handed out when two-factor
+	// authentication is enabled
+	BackupCodeCount = 10
+	// PreAuthTTL is how long the token of the first login step can be exchanged
+	PreAuthTTL = 5 * time.Minute
+
+	// preAuthPurpose marks pre-auth tokens in their claims
+	preAuthPurpose = "two_factor"
+	// qrCodeSize is the width and height of enrollment QR codes in pixels
+	qrCodeSize = 200
+)
+
+// NewTOTPEnrollment generates a TOTP secret for the account, with the otpauth:// URL and QR
+// code authenticator apps add it from
+func NewTOTPEnrollment(account string) (*models.TwoFactorEnrollment, error) {
+	key, err := totp.Generate(totp.GenerateOpts{Issuer: TOTPIssuer, AccountName: account})
+	if err != nil {
+		return nil, err
+	}
+	img, err := key.Image(qrCodeSize, qrCodeSize)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+
+	return &models.TwoFactorEnrollment{
+		Secret: key.Secret(),
+		URL:    key.URL(),
+		QRCode: "data:image/png;base64," + base64.StdEncoding.EncodeToString(buf.Bytes()),
+	}, nil
+}
+
+// VerifyTOTP checks a code against the secret at now, allowing one period of clock drift
+// either way. It returns the time step the code belongs to, which callers record so the
+// code can't be used twice.
+func VerifyTOTP(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	for _, drift := range []time.Duration{0, -TOTPPeriod, TOTPPeriod} {
+		at := now.Add(drift)
+		expected, err := totp.GenerateCode(secret, at)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return at.Unix() / int64(TOTPPeriod/time.Second), true
+		}
+	}
+	return 0, false
+}
+
+// IsTOTPCode reports whether code looks like a TOTP code rather than a backup code
+func IsTOTPCode(code string) bool {
+	code = strings.TrimSpace(code)
+	if len(code) != 6 {
+		return false
+	}
+	for _, r := range code {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// backupCodeEncoding writes backup codes in lowercase base32, which avoids 0, 1 and 8
+var backupCodeEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").WithPadding(base32.NoPadding)
+
+// GenerateBackupCodes returns BackupCodeCount new backup codes, formatted as two groups of
+// five characters, and their hashes for storage
+func GenerateBackupCodes() ([]string, []string, error) {
+	codes := make([]string, BackupCodeCount)
+	hashes := make([]string, BackupCodeCount)
+	for i := range codes {
+		b := make([]byte, 7)
+		if _, err := rand.Read(b); err != nil {
+			return nil, nil, err
+		}
+		code := backupCodeEncoding.EncodeToString(b)[:10]
+		codes[i] = code[:5] + "-" + code[5:]
+		hashes[i] = HashBackupCode(codes[i])
+	}
+	return codes, hashes, nil
+}
+
+// HashBackupCode hashes a backup code for storage and lookup, ignoring case, spaces and
+// dashes
+func HashBackupCode(code string) string {
+	normalized := strings.Map(func(r rune) rune {
+		if r == '-' || r == ' ' {
+			return -1
+		}
+		return r
+	}, strings.ToLower(code))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// preAuthKey derives the key pre-auth tokens are signed with from a JWT secret, so they are
+// never accepted as access tokens
+func preAuthKey(secret string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("wattwatch two-factor pre-auth"))
+	return mac.Sum(nil)
+}
+
+// PreAuthToken is a valid token of the first login step
+type PreAuthToken struct {
+	// ID identifies the token, which is exchanged for a session once
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	ExpiresAt time.Time
+}
+
+// GeneratePreAuthToken issues the token of the first login step of a user with two-factor
+// authentication, proving the password was correct. It is exchanged once for the access
+// and refresh tokens with a code within PreAuthTTL.
+func (s *Service) GeneratePreAuthToken(user *models.User) (string, time.Time, error) {
+	expiresAt := time.Now().Add(PreAuthTTL)
+	claims := jwt.MapClaims{
+		"jti":     uuid.NewString(),
+		"user_id": user.ID,
+		"purpose": preAuthPurpose,
+		"exp":     expiresAt.Unix(),
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(preAuthKey(s.jwtSecret().Current))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// ValidatePreAuthToken returns the pre-auth token, unless it is invalid or expired
+func (s *Service) ValidatePreAuthToken(tokenString string) (*PreAuthToken, error) {
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, ErrInvalidToken
+		}
+		keys := jwt.VerificationKeySet{}
+		for _, secret := range s.jwtSecret().Accepted(time.Now()) {
+			keys.Keys = append(keys.Keys, preAuthKey(secret))
+		}
+		return keys, nil
+	})
+	if err != nil {
+		if errors.Is(err, jwt.ErrTokenExpired) {
+			return nil, ErrTokenExpired
+		}
+		return nil, ErrInvalidToken
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok || !token.Valid || claims["purpose"] != preAuthPurpose {
+		return nil, ErrInvalidToken
+	}
+	tokenID, ok := claims["jti"].(string)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	userID, ok := claims["user_id"].(string)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil || expiresAt == nil {
+		return nil, ErrInvalidToken
+	}
+
+	preAuth := &PreAuthToken{ExpiresAt: expiresAt.Time}
+	if preAuth.ID, err = uuid.Parse(tokenID); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if preAuth.UserID, err = uuid.Parse(userID); err != nil {
+		return nil, ErrInvalidToken
+	}
+	return preAuth, nil
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/pquerna/otp/totp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyTOTP(t *testing.T) {
+	enrollment, err := NewTOTPEnrollment("user")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(enrollment.URL, "otpauth://totp/WattWatch:user?"))
+	require.True(t, strings.HasPrefix(enrollment.QRCode, "data:image/png;base64,"))
+
+	now := time.Date(2025, 1, 31, 12, 0, 10, 0, time.UTC)
+	step := now.Unix() / 30
+	codeAt := func(at time.Time) string {
+		code, err := totp.GenerateCode(enrollment.Secret, at)
+		require.NoError(t, err)
+		return code
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		wantStep int64
+		wantOK   bool
+	}{
+		{"current", codeAt(now), step, true},
+		{"previous period", codeAt(now.Add(-TOTPPeriod)), step - 1, true},
+		{"next period", codeAt(now.Add(TOTPPeriod)), step + 1, true},
+		{"too old", codeAt(now.Add(-2 * TOTPPeriod)), 0, false},
+		{"too new", codeAt(now.Add(2 * TOTPPeriod)), 0, false},
+		{"not a code", "abcdef", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotStep, ok := VerifyTOTP(enrollment.Secret, tt.code, now)
+			require.Equal(t, tt.wantOK, ok)
+			require.Equal(t, tt.wantStep, gotStep)
+		})
+	}
+}
+
+func TestBackupCodes(t *testing.T) {
+	codes, hashes, err := GenerateBackupCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, BackupCodeCount)
+	require.Len(t, hashes, BackupCodeCount)
+
+	for i, code := range codes {
+		require.Len(t, code, 11)
+		require.False(t, IsTOTPCode(code))
+		require.Equal(t, hashes[i], HashBackupCode(code))
+		// Codes are matched however they are typed
+		require.Equal(t, hashes[i], HashBackupCode(strings.ToUpper(strings.ReplaceAll(code, "-", " "))))
+	}
+	require.True(t, IsTOTPCode("012345"))
+	require.False(t, IsTOTPCode("01234"))
+}
+
+func TestPreAuthToken(t *testing.T) {
+	user := &models.User{ID: uuid.New(), Username: "user", Role: &models.Role{}}
+	service := NewService(&config.Config{JWTSecret: "secret"}, nil)
+
+	token, expiresAt, err := service.GeneratePreAuthToken(user)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(PreAuthTTL), expiresAt, time.Minute)
+
+	preAuth, err := service.ValidatePreAuthToken(token)
+	require.NoError(t, err)
+	require.Equal(t, user.ID, preAuth.UserID)
+	require.Equal(t, expiresAt.Unix(), preAuth.ExpiresAt.Unix())
+
+	// Every token has its own ID, so each is exchanged once
+	second, _, err := service.GeneratePreAuthToken(user)
+	require.NoError(t, err)
+	secondPreAuth, err := service.ValidatePreAuthToken(second)
+	require.NoError(t, err)
+	require.NotEqual(t, preAuth.ID, secondPreAuth.ID)
+
+	// Pre-auth tokens and access tokens can't stand in for each other
+	_, err = service.ValidateToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+	access, err := service.GenerateToken(user, false)
+	require.NoError(t, err)
+	_, err = service.ValidatePreAuthToken(access)
+	require.ErrorIs(t, err, ErrInvalidToken)
+
+	other := NewService(&config.Config{JWTSecret: "other"}, nil)
+	_, err = other.ValidatePreAuthToken(token)
+	require.ErrorIs(t, err, ErrInvalidToken)
+}
@@ -0,0 +1,340 @@
+package budget
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/email"
+	"wattwatch/internal/models"
+	"wattwatch/internal/notification"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type notifyRecorder struct {
+	sent map[uuid.UUID][]*notification.Message
+}
+
+func (r *notifyRecorder) Notify(ctx context.Context, userID uuid.UUID, msg *notification.Message) error {
+	r.sent[userID] = append(r.sent[userID], msg)
+	return nil
+}
+
+// sentReport is a weekly report email
+type sentReport struct {
+	to      string
+	reports []email.WeeklyReport
+}
+
+type reportRecorder struct {
+	sent []sentReport
+}
+
+func (r *reportRecorder) SendWeeklyReport(to, username, language string, reports []email.WeeklyReport) error {
+	r.sent = append(r.sent, sentReport{to: to, reports: reports})
+	return nil
+}
+
+// fixture holds a store with a user who uses 1 kWh every hour and 2 kWh at 18:00
+// Stockholm time until the start of 16 January 2025, and SE3 prices in EUR of 100 for the
+// first 16 days of January except 400 at 18:00 on the 16th
+type fixture struct {
+	store    *memory.Store
+	user     *models.User
+	zone     *models.Zone
+	eur, sek *models.Currency
+	now      time.Time
+}
+
+func newFixture(t *testing.T) *fixture {
+	ctx := context.Background()
+	store := memory.NewStore()
+	roles := memory.NewRoleRepository(store)
+	users := memory.NewUserRepository(store)
+
+	role, err := roles.GetByName(ctx, "user")
+	require.NoError(t, err)
+	user := &models.User{Username: "alice", Password: "hash", RoleID: role.ID}
+	require.NoError(t, users.Create(ctx, user))
+	zone, err := memory.NewZoneRepository(store).GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	eur, err := memory.NewCurrencyRepository(store).GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	sek, err := memory.NewCurrencyRepository(store).GetByName(ctx, "SEK")
+	require.NoError(t, err)
+
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	require.NoError(t, err)
+	now := time.Date(2025, 1, 16, 0, 0, 0, 0, loc)
+
+	var records []models.ConsumptionRecord
+	for at := now.AddDate(0, 0, -ProfileDays); at.Before(now); at = at.Add(time.Hour) {
+		kwh := 1.0
+		if at.In(loc).Hour() == 18 {
+			kwh = 2
+		}
+		// A reading missing in the month is projected like the hours after now
+		if at.Equal(time.Date(2025, 1, 10, 3, 0, 0, 0, loc)) {
+			continue
+		}
+		records = append(records, models.ConsumptionRecord{UserID: user.ID, MeterID: "main", Timestamp: at, KWh: kwh})
+	}
+	require.NoError(t, memory.NewConsumptionRepository(store).CreateBatch(ctx, records))
+
+	var prices []models.SpotPrice
+	for at := time.Date(2025, 1, 1, 0, 0, 0, 0, loc); at.Before(time.Date(2025, 1, 17, 0, 0, 0, 0, loc)); at = at.Add(time.Hour) {
+		price := 100.0
+		if at.Equal(time.Date(2025, 1, 16, 18, 0, 0, 0, loc)) {
+			price = 400
+		}
+		prices = append(prices, models.SpotPrice{Timestamp: at, ZoneID: zone.ID, CurrencyID: eur.ID, Price: price})
+	}
+	require.NoError(t, memory.NewSpotPriceRepository(store).CreateBatch(ctx, prices))
+
+	return &fixture{store: store, user: user, zone: zone, eur: eur, sek: sek, now: now}
+}
+
+func (f *fixture) projector() *Projector {
+	return NewProjector(memory.NewConsumptionRepository(f.store), memory.NewSpotPriceRepository(f.store))
+}
+
+func TestProjector_Project(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	projector := f.projector()
+
+	projection, err := projector.Project(ctx, f.user.ID, nil, f.zone, f.eur, nil, f.now)
+	require.NoError(t, err)
+	assert.Equal(t, "SE3", projection.Zone)
+	assert.Equal(t, "EUR", projection.Currency)
+	assert.Equal(t, "2025-01-01T00:00:00+01:00", projection.Start.Format(time.RFC3339))
+	assert.Equal(t, "2025-02-01T00:00:00+01:00", projection.End.Format(time.RFC3339))
+	require.NotNil(t, projection.PricedUntil)
+	assert.Equal(t, "2025-01-17T00:00:00+01:00", projection.PricedUntil.Format(time.RFC3339))
+
+	// 15 days of 25 kWh at 1 EUR, less the missing reading
+	assert.Equal(t, 374.0, projection.ConsumedKWh)
+	assert.Equal(t, 374.0, projection.Cost)
+	// The missing hour and 16 days of 25 kWh are added. The 16th is priced at its spot
+	// prices, 23 + 2 * 4 EUR, and the rest at the average price of 100.78125.
+	assert.Equal(t, 775.0, projection.ProjectedKWh)
+	assert.Equal(t, 783.93, projection.ProjectedCost)
+	assert.False(t, projection.OverBudget)
+
+	meter := "garage"
+	projection, err = projector.Project(ctx, f.user.ID, &meter, f.zone, f.eur, nil, f.now)
+	require.NoError(t, err)
+	assert.Equal(t, 0.0, projection.ConsumedKWh)
+	assert.Equal(t, 0.0, projection.ProjectedCost, "nothing is projected without readings")
+
+	_, err = projector.Project(ctx, f.user.ID, nil, f.zone, f.sek, nil, f.now)
+	assert.ErrorIs(t, err, ErrNoPrices)
+}
+
+func TestProjector_ProjectTariff(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	tariff := &models.Tariff{Name: "grid", TransferFee: 0.5, EnergyTax: 0.25, MonthlyFee: 100, VAT: 25}
+
+	projection, err := f.projector().Project(ctx, f.user.ID, nil, f.zone, f.eur, tariff, f.now)
+	require.NoError(t, err)
+	assert.Same(t, tariff, projection.Tariff)
+	// 374 kWh at 1 EUR and 0.75 EUR of fees and tax, with 25% VAT and no monthly fee
+	assert.Equal(t, 818.13, projection.Cost)
+	assert.Equal(t, models.CostBreakdown{Spot: 783.93, Transfer: 387.5, EnergyTax: 193.75, MonthlyFee: 100, VAT: 366.29},
+		projection.Breakdown)
+	assert.Equal(t, 1831.47, projection.ProjectedCost)
+}
+
+func TestProjector_ProjectExport(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	projector := f.projector()
+	projector.SetProduction(memory.NewProductionRepository(f.store))
+
+	// 3 kWh produced and 2 exported at noon every day, sold at the spot price less 0.1 EUR
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	require.NoError(t, err)
+	var records []models.ProductionRecord
+	for day := f.now.AddDate(0, 0, -ProfileDays); day.Before(f.now); day = day.AddDate(0, 0, 1) {
+		at := time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, loc)
+		records = append(records, models.ProductionRecord{UserID: f.user.ID, MeterID: "solar", Timestamp: at, ProducedKWh: 3, ExportedKWh: 2})
+	}
+	require.NoError(t, memory.NewProductionRepository(f.store).CreateBatch(ctx, records))
+	tariff := &models.Tariff{Name: "grid", ExportFee: 0.1}
+
+	projection, err := projector.Project(ctx, f.user.ID, nil, f.zone, f.eur, tariff, f.now)
+	require.NoError(t, err)
+	require.NotNil(t, projection.Export)
+	// 15 days of 2 kWh at 0.9 EUR
+	assert.Equal(t, 45.0, projection.Export.ProducedKWh)
+	assert.Equal(t, 30.0, projection.Export.ExportedKWh)
+	assert.Equal(t, 27.0, projection.Export.Revenue)
+	assert.Equal(t, 347.0, projection.NetCost)
+	// 16 more days of 2 kWh, nothing is exported in the hours without readings. The 16th is
+	// priced at 0.9 EUR and the rest at the average price less the fee.
+	assert.Equal(t, 62.0, projection.Export.ProjectedExportedKWh)
+	assert.Equal(t, 56.03, projection.Export.ProjectedRevenue)
+	assert.Equal(t, 783.93, projection.ProjectedCost)
+	assert.Equal(t, 727.9, projection.ProjectedNetCost)
+
+	// A single meter's projection leaves out the export
+	meter := "main"
+	projection, err = projector.Project(ctx, f.user.ID, &meter, f.zone, f.eur, tariff, f.now)
+	require.NoError(t, err)
+	assert.Nil(t, projection.Export)
+	assert.Equal(t, projection.ProjectedCost, projection.ProjectedNetCost)
+}
+
+func TestTransferFee(t *testing.T) {
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	require.NoError(t, err)
+	tariff := &models.Tariff{
+		TransferFee: 0.2,
+		Rates: []models.TariffRate{
+			{Start: "06:00", End: "22:00", Weekdays: true, Months: []int{1, 2, 3, 11, 12}, TransferFee: 0.8},
+			{Start: "22:00", End: "06:00", TransferFee: 0.1},
+		},
+	}
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want float64
+	}{
+		{"Winter weekday", time.Date(2025, 1, 15, 6, 0, 0, 0, loc), 0.8},
+		{"Winter weekday evening", time.Date(2025, 1, 15, 21, 59, 0, 0, loc), 0.8},
+		{"Winter weekend", time.Date(2025, 1, 18, 12, 0, 0, 0, loc), 0.2},
+		{"Summer weekday", time.Date(2025, 7, 16, 12, 0, 0, 0, loc), 0.2},
+		{"Night before midnight", time.Date(2025, 1, 15, 22, 0, 0, 0, loc), 0.1},
+		{"Night after midnight", time.Date(2025, 1, 16, 5, 59, 0, 0, loc), 0.1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, TransferFee(tariff, tt.at))
+		})
+	}
+
+	assert.NoError(t, ValidateRates(tariff.Rates))
+	assert.Error(t, ValidateRates([]models.TariffRate{{Start: "6", End: "22:00"}}))
+	assert.Error(t, ValidateRates([]models.TariffRate{{Start: "06:00", End: "24:00"}}))
+	assert.Error(t, ValidateRates([]models.TariffRate{{Start: "06:00", End: "06:00"}}))
+}
+
+func TestChecker_Run(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	budgets := memory.NewBudgetRepository(f.store)
+
+	over := &models.Budget{UserID: f.user.ID, ZoneID: f.zone.ID, CurrencyID: f.eur.ID, Amount: 700, Notify: true}
+	require.NoError(t, budgets.Create(ctx, over))
+	// Without prices there is nothing to project
+	unpriced := &models.Budget{UserID: f.user.ID, ZoneID: f.zone.ID, CurrencyID: f.sek.ID, Amount: 1, Notify: true}
+	require.NoError(t, budgets.Create(ctx, unpriced))
+
+	notifier := &notifyRecorder{sent: make(map[uuid.UUID][]*notification.Message)}
+	checker := NewChecker(budgets, memory.NewZoneRepository(f.store), memory.NewCurrencyRepository(f.store), f.projector(), notifier)
+	now := f.now
+	checker.now = func() time.Time { return now }
+
+	require.NoError(t, checker.Run(ctx))
+	require.Len(t, notifier.sent[f.user.ID], 1)
+	msg := notifier.sent[f.user.ID][0]
+	assert.Equal(t, models.NotificationAlertConsumption, msg.AlertType)
+	assert.Equal(t, over.ID.String(), msg.ThrottleKey)
+	assert.Contains(t, msg.Body, "783.93 EUR")
+	assert.Contains(t, msg.Body, "January 2025")
+	assert.Equal(t, "700.00", msg.Data["budget"])
+	assert.Equal(t, "2025-01", msg.Data["month"])
+
+	got, err := budgets.GetByID(ctx, over.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastNotifiedAt)
+	assert.True(t, got.LastNotifiedAt.Equal(now))
+
+	// Owners are notified once a month
+	now = now.Add(24 * time.Hour)
+	require.NoError(t, checker.Run(ctx))
+	assert.Len(t, notifier.sent[f.user.ID], 1)
+
+	// Raising the budget above the projection or turning notifications off keeps them quiet
+	over.Amount = 800
+	require.NoError(t, budgets.Update(ctx, over))
+	require.NoError(t, budgets.MarkNotified(ctx, over.ID, time.Date(2024, 12, 1, 0, 0, 0, 0, time.UTC)))
+	require.NoError(t, checker.Run(ctx))
+	assert.Len(t, notifier.sent[f.user.ID], 1)
+	over.Amount, over.Notify = 700, false
+	require.NoError(t, budgets.Update(ctx, over))
+	require.NoError(t, checker.Run(ctx))
+	assert.Len(t, notifier.sent[f.user.ID], 1)
+}
+
+func TestReporter_Run(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t)
+	users := memory.NewUserRepository(f.store)
+	preferences := memory.NewUserPreferenceRepository(f.store)
+
+	address := "alice@example.com"
+	f.user.Email, f.user.EmailVerified = &address, true
+	require.NoError(t, users.Update(ctx, f.user))
+	require.NoError(t, preferences.Upsert(ctx, &models.UserPreferences{UserID: f.user.ID, ZoneID: &f.zone.ID, CurrencyID: &f.eur.ID, WeeklyReport: true}))
+	// A second meter only has a reading in the reported week
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	require.NoError(t, err)
+	garage := models.ConsumptionRecord{UserID: f.user.ID, MeterID: "garage", Timestamp: time.Date(2025, 1, 8, 12, 0, 0, 0, loc), KWh: 3}
+	require.NoError(t, memory.NewConsumptionRepository(f.store).CreateBatch(ctx, []models.ConsumptionRecord{garage}))
+
+	// Users without a verified address or prices in their currency get no report
+	unverified := &models.User{Username: "bob", Password: "hash", RoleID: f.user.RoleID}
+	require.NoError(t, users.Create(ctx, unverified))
+	require.NoError(t, preferences.Upsert(ctx, &models.UserPreferences{UserID: unverified.ID, ZoneID: &f.zone.ID, CurrencyID: &f.eur.ID, WeeklyReport: true}))
+	carolAddress := "carol@example.com"
+	unpriced := &models.User{Username: "carol", Password: "hash", RoleID: f.user.RoleID, Email: &carolAddress, EmailVerified: true}
+	require.NoError(t, users.Create(ctx, unpriced))
+	require.NoError(t, preferences.Upsert(ctx, &models.UserPreferences{UserID: unpriced.ID, ZoneID: &f.zone.ID, CurrencyID: &f.sek.ID, WeeklyReport: true}))
+
+	sender := &reportRecorder{}
+	reporter := NewReporter(preferences, users, memory.NewZoneRepository(f.store), memory.NewCurrencyRepository(f.store),
+		memory.NewConsumptionRepository(f.store), memory.NewSpotPriceRepository(f.store), sender)
+	reporter.now = func() time.Time { return f.now }
+
+	require.NoError(t, reporter.Run(ctx))
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, address, sender.sent[0].to)
+	reports := sender.sent[0].reports
+	require.Len(t, reports, 2)
+
+	garageReport := reports[0]
+	assert.Equal(t, "garage", garageReport.HomeName)
+	assert.Equal(t, 3.0, garageReport.ConsumptionKWh)
+	assert.Equal(t, 3.0, garageReport.Cost)
+	assert.Nil(t, garageReport.PreviousConsumptionKWh)
+	assert.Nil(t, garageReport.PreviousCost)
+	assert.Equal(t, "2025-01-08T00:00:00+01:00", garageReport.MostExpensiveDay.Format(time.RFC3339))
+
+	// The week from Monday 6 January at 1 EUR a kWh, less the missing reading on the 10th.
+	// The week before is partly priced at the average price.
+	main := reports[1]
+	assert.Equal(t, "main", main.HomeName)
+	assert.Equal(t, "EUR", main.Currency)
+	assert.Equal(t, "2025-01-06T00:00:00+01:00", main.WeekStart.Format(time.RFC3339))
+	assert.Equal(t, 174.0, main.ConsumptionKWh)
+	assert.Equal(t, 174.0, main.Cost)
+	require.NotNil(t, main.PreviousConsumptionKWh)
+	assert.Equal(t, 175.0, *main.PreviousConsumptionKWh)
+	require.NotNil(t, main.PreviousCost)
+	assert.Equal(t, 175.0, *main.PreviousCost)
+	assert.Equal(t, "2025-01-06T00:00:00+01:00", main.MostExpensiveDay.Format(time.RFC3339))
+	assert.Equal(t, 25.0, main.MostExpensiveDayCost)
+
+	// The tariff adds its fees and taxes
+	tariff := &models.Tariff{Name: "grid", TransferFee: 0.5, EnergyTax: 0.25, VAT: 25}
+	reports, err = reporter.Reports(ctx, f.user.ID, f.zone, f.eur, tariff, f.now)
+	require.NoError(t, err)
+	require.Len(t, reports, 2)
+	assert.Equal(t, 6.56, reports[0].Cost)
+}
@@ -0,0 +1,136 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/notification"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Notifier delivers a notification to all of a user's channels
+type Notifier interface {
+	Notify(ctx context.Context, userID uuid.UUID, msg *notification.Message) error
+}
+
+// Checker projects the cost of the month for the budgets whose owners are notified, and
+// notifies those whose projection exceeds their budget. Each budget notifies at most once
+// a month.
+type Checker struct {
+	budgets    repository.BudgetRepository
+	zones      repository.ZoneRepository
+	currencies repository.CurrencyRepository
+	projector  *Projector
+	notifier   Notifier
+	now        func() time.Time
+	// tariffs holds the tariffs added to the spot prices, there are none when it is nil
+	tariffs repository.TariffRepository
+}
+
+// NewChecker creates a checker sending the notifications to notifier
+func NewChecker(
+	budgets repository.BudgetRepository,
+	zones repository.ZoneRepository,
+	currencies repository.CurrencyRepository,
+	projector *Projector,
+	notifier Notifier,
+) *Checker {
+	return &Checker{
+		budgets:    budgets,
+		zones:      zones,
+		currencies: currencies,
+		projector:  projector,
+		notifier:   notifier,
+		now:        time.Now,
+	}
+}
+
+// SetTariffs makes the projections add the fees and taxes of the owners' tariffs
+func (c *Checker) SetTariffs(repo repository.TariffRepository) {
+	c.tariffs = repo
+}
+
+// Run checks the budgets once, for running as a scheduled job
+func (c *Checker) Run(ctx context.Context) error {
+	budgets, err := c.budgets.ListNotify(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list budgets: %w", err)
+	}
+
+	now := c.now()
+	var errs []error
+	for i := range budgets {
+		if err := c.check(ctx, &budgets[i], now); err != nil {
+			errs = append(errs, fmt.Errorf("budget %s: %w", budgets[i].ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// check notifies the owner of the budget when the projection of the month exceeds it and
+// they haven't been notified this month
+func (c *Checker) check(ctx context.Context, budget *models.Budget, now time.Time) error {
+	zone, err := c.zones.GetByID(ctx, budget.ZoneID)
+	if err != nil {
+		return fmt.Errorf("failed to get zone %s: %w", budget.ZoneID, err)
+	}
+	currency, err := c.currencies.GetByID(ctx, budget.CurrencyID)
+	if err != nil {
+		return fmt.Errorf("failed to get currency %s: %w", budget.CurrencyID, err)
+	}
+
+	var tariff *models.Tariff
+	if c.tariffs != nil {
+		tariff, err = c.tariffs.FindForUser(ctx, budget.UserID, budget.ZoneID, budget.CurrencyID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to find tariff: %w", err)
+		}
+	}
+
+	projection, err := c.projector.Project(ctx, budget.UserID, nil, zone, currency, tariff, now)
+	if errors.Is(err, ErrNoPrices) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if projection.ProjectedNetCost <= budget.Amount {
+		return nil
+	}
+	if budget.LastNotifiedAt != nil && !budget.LastNotifiedAt.Before(projection.Start) {
+		return nil
+	}
+
+	if err := c.notifier.Notify(ctx, budget.UserID, message(budget, projection)); err != nil {
+		return err
+	}
+	if err := c.budgets.MarkNotified(ctx, budget.ID, now); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return fmt.Errorf("failed to mark as notified: %w", err)
+	}
+	return nil
+}
+
+// message tells the owner of the budget how far the projection exceeds it
+func message(budget *models.Budget, projection *models.CostProjection) *notification.Message {
+	month := projection.Start.Format("January 2006")
+	return &notification.Message{
+		AlertType:   models.NotificationAlertConsumption,
+		ThrottleKey: budget.ID.String(),
+		Title:       fmt.Sprintf("%s electricity cost projected over budget", projection.Zone),
+		Body: fmt.Sprintf("Your electricity in %s is projected to cost %.2f %s in %s, over your budget of %.2f %s. So far it has cost %.2f %s.",
+			projection.Zone, projection.ProjectedNetCost, projection.Currency, month,
+			budget.Amount, projection.Currency, projection.NetCost, projection.Currency),
+		Data: map[string]string{
+			"budget_id":      budget.ID.String(),
+			"zone":           projection.Zone,
+			"currency":       projection.Currency,
+			"month":          projection.Start.Format("2006-01"),
+			"budget":         fmt.Sprintf("%.2f", budget.Amount),
+			"projected_cost": fmt.Sprintf("%.2f", projection.ProjectedNetCost),
+		},
+	}
+}
@@ -0,0 +1,364 @@
+// Package budget projects the cost of users' consumption to the end of the month at spot
+// prices and the fees and taxes of their tariffs, notifies the users whose projection exceeds
+// their monthly budget, and emails weekly consumption and cost reports
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ProfileDays is the number of days before now whose readings give the usual consumption
+// of each hour of the day, which the hours of the month without readings are projected at
+const ProfileDays = 28
+
+// ErrNoPrices is returned when no spot prices are stored for the month, so nothing can be
+// priced
+var ErrNoPrices = errors.New("no spot prices are stored for the month")
+
+// Projector projects the cost of a month from the consumption stored so far and the spot
+// prices of the month, including the day-ahead prices published for tomorrow
+type Projector struct {
+	consumption repository.ConsumptionRepository
+	spotPrices  repository.SpotPriceRepository
+	// production holds the energy users export, which is subtracted from the cost, there
+	// is none when it is nil
+	production repository.ProductionRepository
+}
+
+// NewProjector creates a projector reading consumption and spot prices from the repositories
+func NewProjector(consumption repository.ConsumptionRepository, spotPrices repository.SpotPriceRepository) *Projector {
+	return &Projector{
+		consumption: consumption,
+		spotPrices:  spotPrices,
+	}
+}
+
+// SetProduction makes projections subtract the value of the energy users export to the grid
+func (p *Projector) SetProduction(repo repository.ProductionRepository) {
+	p.production = repo
+}
+
+// Project returns the cost of the user's consumption in the calendar month of now, in the
+// zone's timezone, of the meter or of all the user's meters when meterID is nil. Readings
+// are priced at the spot price they fall in. Hours without readings, past or future, are
+// projected at the user's usual consumption for the hour of the day over the last
+// ProfileDays, priced at the hour's spot prices or the month's average when they aren't
+// published yet. The tariff, when not nil, adds its transfer fee, energy tax, monthly fee
+// and VAT to the projected cost; the cost so far leaves out the monthly fee. Unless meterID
+// is set, the energy exported to the grid is valued at the spot price less the tariff's
+// export fee and projected like consumption, and subtracted from the net costs.
+func (p *Projector) Project(ctx context.Context, userID uuid.UUID, meterID *string, zone *models.Zone, currency *models.Currency, tariff *models.Tariff, now time.Time) (*models.CostProjection, error) {
+	loc, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q of zone %s: %w", zone.Timezone, zone.Name, err)
+	}
+	local := now.In(loc)
+	start := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 1, 0)
+
+	prices, err := loadPrices(ctx, p.spotPrices, zone.ID, currency.ID, start, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(prices.prices) == 0 {
+		return nil, ErrNoPrices
+	}
+
+	historyStart := now.AddDate(0, 0, -ProfileDays)
+	if start.Before(historyStart) {
+		historyStart = start
+	}
+	records, err := p.consumption.List(ctx, repository.ConsumptionFilter{
+		UserID:    userID,
+		MeterID:   meterID,
+		StartTime: &historyStart,
+		EndTime:   &end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumption: %w", err)
+	}
+
+	projection := &models.CostProjection{
+		Zone:     zone.Name,
+		Currency: currency.Name,
+		MeterID:  meterID,
+		Start:    start,
+		End:      end,
+		Tariff:   tariff,
+	}
+	pricedUntil := prices.end().In(loc)
+	projection.PricedUntil = &pricedUntil
+
+	// Hours of the month with readings, counted from its start
+	consumed := costs{tariff: tariff}
+	measured := make(map[int]bool)
+	readings := make([]reading, len(records))
+	for i, r := range records {
+		readings[i] = reading{r.Timestamp, r.KWh}
+		if r.Timestamp.Before(start) || !r.Timestamp.Before(end) {
+			continue
+		}
+		projection.ConsumedKWh += r.KWh
+		consumed.add(r.KWh, prices.at(r.Timestamp), r.Timestamp.In(loc))
+		measured[int(r.Timestamp.Sub(start)/time.Hour)] = true
+	}
+
+	usual := profile(readings, now.AddDate(0, 0, -ProfileDays), now, loc, true)
+	projected := consumed
+	projection.ProjectedKWh = projection.ConsumedKWh
+	for hour, t := 0, start; t.Before(end); hour, t = hour+1, t.Add(time.Hour) {
+		if measured[hour] {
+			continue
+		}
+		kwh := usual(t.In(loc).Hour())
+		projection.ProjectedKWh += kwh
+		projected.add(kwh, prices.hour(t), t.In(loc))
+	}
+	if tariff != nil {
+		projected.monthlyFee = tariff.MonthlyFee
+	}
+
+	projection.Cost = roundCost(consumed.total())
+	projection.ProjectedCost = roundCost(projected.total())
+	projection.Breakdown = projected.breakdown()
+	projection.ConsumedKWh = roundKWh(projection.ConsumedKWh)
+	projection.ProjectedKWh = roundKWh(projection.ProjectedKWh)
+
+	projection.NetCost, projection.ProjectedNetCost = projection.Cost, projection.ProjectedCost
+	if p.production != nil && meterID == nil {
+		export, err := p.export(ctx, userID, tariff, prices, start, end, historyStart, now, loc)
+		if err != nil {
+			return nil, err
+		}
+		if export != nil {
+			projection.Export = export
+			projection.NetCost = roundCost(projection.Cost - export.Revenue)
+			projection.ProjectedNetCost = roundCost(projection.ProjectedCost - export.ProjectedRevenue)
+		}
+	}
+	return projection, nil
+}
+
+// export returns the energy the user produced and exported in the month from start until
+// end, and its value, or nil when the user has no production readings since historyStart.
+// Hours without readings are projected at the user's usual export for the hour of the day.
+func (p *Projector) export(ctx context.Context, userID uuid.UUID, tariff *models.Tariff, prices *monthPrices, start, end, historyStart, now time.Time, loc *time.Location) (*models.ExportProjection, error) {
+	records, err := p.production.List(ctx, repository.ProductionFilter{
+		UserID:    userID,
+		StartTime: &historyStart,
+		EndTime:   &end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list production: %w", err)
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	var fee float64
+	if tariff != nil {
+		fee = tariff.ExportFee
+	}
+	export := &models.ExportProjection{}
+	measured := make(map[int]bool)
+	readings := make([]reading, len(records))
+	for i, r := range records {
+		readings[i] = reading{r.Timestamp, r.ExportedKWh}
+		if r.Timestamp.Before(start) || !r.Timestamp.Before(end) {
+			continue
+		}
+		export.ProducedKWh += r.ProducedKWh
+		export.ExportedKWh += r.ExportedKWh
+		export.Revenue += r.ExportedKWh * (prices.at(r.Timestamp)/100 - fee)
+		measured[int(r.Timestamp.Sub(start)/time.Hour)] = true
+	}
+
+	// Hours of the day without readings are those the sun doesn't shine in
+	usual := profile(readings, now.AddDate(0, 0, -ProfileDays), now, loc, false)
+	export.ProjectedExportedKWh, export.ProjectedRevenue = export.ExportedKWh, export.Revenue
+	for hour, t := 0, start; t.Before(end); hour, t = hour+1, t.Add(time.Hour) {
+		if measured[hour] {
+			continue
+		}
+		kwh := usual(t.In(loc).Hour())
+		export.ProjectedExportedKWh += kwh
+		export.ProjectedRevenue += kwh * (prices.hour(t)/100 - fee)
+	}
+
+	export.ProducedKWh = roundKWh(export.ProducedKWh)
+	export.ExportedKWh = roundKWh(export.ExportedKWh)
+	export.ProjectedExportedKWh = roundKWh(export.ProjectedExportedKWh)
+	export.Revenue = roundCost(export.Revenue)
+	export.ProjectedRevenue = roundCost(export.ProjectedRevenue)
+	return export, nil
+}
+
+// loadPrices reads the spot prices of the zone and currency from start until end
+func loadPrices(ctx context.Context, spotPrices repository.SpotPriceRepository, zoneID, currencyID uuid.UUID, start, end time.Time) (*monthPrices, error) {
+	month := &monthPrices{resolution: time.Hour}
+	var sum float64
+	err := spotPrices.Each(ctx, repository.SpotPriceFilter{
+		ZoneID:     &zoneID,
+		CurrencyID: &currencyID,
+		StartTime:  &start,
+		EndTime:    &end,
+		OrderBy:    "timestamp",
+	}, func(sp *models.SpotPrice) error {
+		if !sp.Timestamp.Before(end) {
+			return nil
+		}
+		month.prices = append(month.prices, models.SpotPrice{Timestamp: sp.Timestamp, Price: sp.Price})
+		sum += sp.Price
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list spot prices: %w", err)
+	}
+	if len(month.prices) == 0 {
+		return month, nil
+	}
+
+	month.average = sum / float64(len(month.prices))
+	for i := 1; i < len(month.prices); i++ {
+		gap := month.prices[i].Timestamp.Sub(month.prices[i-1].Timestamp)
+		if i == 1 || gap < month.resolution {
+			month.resolution = gap
+		}
+	}
+	return month, nil
+}
+
+// monthPrices are the spot prices of a month ordered by time. Each applies for resolution,
+// the shortest gap between two of them.
+type monthPrices struct {
+	prices     []models.SpotPrice
+	resolution time.Duration
+	average    float64
+}
+
+// at returns the price applying at t, or the average when none does
+func (m *monthPrices) at(t time.Time) float64 {
+	i := sort.Search(len(m.prices), func(i int) bool { return m.prices[i].Timestamp.After(t) }) - 1
+	if i >= 0 && t.Before(m.prices[i].Timestamp.Add(m.resolution)) {
+		return m.prices[i].Price
+	}
+	return m.average
+}
+
+// hour returns the average of the prices starting in the hour from t, or the price
+// applying at t when none does
+func (m *monthPrices) hour(t time.Time) float64 {
+	i := sort.Search(len(m.prices), func(i int) bool { return !m.prices[i].Timestamp.Before(t) })
+	var sum float64
+	n := 0
+	for ; i < len(m.prices) && m.prices[i].Timestamp.Before(t.Add(time.Hour)); i++ {
+		sum += m.prices[i].Price
+		n++
+	}
+	if n == 0 {
+		return m.at(t)
+	}
+	return sum / float64(n)
+}
+
+// end returns when the last price stops applying
+func (m *monthPrices) end() time.Time {
+	return m.prices[len(m.prices)-1].Timestamp.Add(m.resolution)
+}
+
+// reading is the energy consumed or exported in the interval starting at a time
+type reading struct {
+	at  time.Time
+	kwh float64
+}
+
+// profile returns the average kWh of each hour of the day in loc, over the hours from
+// start until end that have readings. Hours of the day without readings get the average
+// of all hours when fill is set and zero otherwise, and everything is zero without readings.
+func profile(readings []reading, start, end time.Time, loc *time.Location, fill bool) func(hourOfDay int) float64 {
+	hours := make(map[int64]float64)
+	for _, r := range readings {
+		if r.at.Before(start) || !r.at.Before(end) {
+			continue
+		}
+		hours[r.at.Unix()/3600] += r.kwh
+	}
+
+	var sums [24]float64
+	var counts [24]int
+	var total float64
+	for hour, kwh := range hours {
+		h := time.Unix(hour*3600, 0).In(loc).Hour()
+		sums[h] += kwh
+		counts[h]++
+		total += kwh
+	}
+	var average float64
+	if fill && len(hours) > 0 {
+		average = total / float64(len(hours))
+	}
+	return func(h int) float64 {
+		if counts[h] == 0 {
+			return average
+		}
+		return sums[h] / float64(counts[h])
+	}
+}
+
+// costs sums the parts of a cost in the currency, the spot prices and the fees and taxes of
+// the tariff when there is one
+type costs struct {
+	tariff     *models.Tariff
+	spot       float64
+	transfer   float64
+	energyTax  float64
+	monthlyFee float64
+}
+
+// add prices kwh consumed at local time t, at the spot price in hundredths of the currency
+func (c *costs) add(kwh, price float64, t time.Time) {
+	c.spot += kwh * price / 100
+	if c.tariff != nil {
+		c.transfer += kwh * TransferFee(c.tariff, t)
+		c.energyTax += kwh * c.tariff.EnergyTax
+	}
+}
+
+// vat returns the VAT of the tariff on all the parts
+func (c *costs) vat() float64 {
+	if c.tariff == nil {
+		return 0
+	}
+	return (c.spot + c.transfer + c.energyTax + c.monthlyFee) * c.tariff.VAT / 100
+}
+
+func (c *costs) total() float64 {
+	return c.spot + c.transfer + c.energyTax + c.monthlyFee + c.vat()
+}
+
+func (c *costs) breakdown() models.CostBreakdown {
+	return models.CostBreakdown{
+		Spot:       roundCost(c.spot),
+		Transfer:   roundCost(c.transfer),
+		EnergyTax:  roundCost(c.energyTax),
+		MonthlyFee: roundCost(c.monthlyFee),
+		VAT:        roundCost(c.vat()),
+	}
+}
+
+func roundCost(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func roundKWh(v float64) float64 {
+	return math.Round(v*1000) / 1000
+}
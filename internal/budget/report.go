@@ -0,0 +1,256 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"wattwatch/internal/email"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ReportSender emails a user the weekly reports of their meters
+type ReportSender interface {
+	SendWeeklyReport(to, username, language string, reports []email.WeeklyReport) error
+}
+
+// Reporter emails the users who opted in to the weekly report the consumption and cost of
+// each of their meters in the last complete week, Monday to Monday in the timezone of
+// their default zone, compared with the week before. Costs are priced at the spot prices
+// of the default zone and currency.
+type Reporter struct {
+	preferences repository.UserPreferenceRepository
+	users       repository.UserRepository
+	zones       repository.ZoneRepository
+	currencies  repository.CurrencyRepository
+	consumption repository.ConsumptionRepository
+	spotPrices  repository.SpotPriceRepository
+	sender      ReportSender
+	now         func() time.Time
+	// tariffs holds the tariffs added to the spot prices, there are none when it is nil
+	tariffs repository.TariffRepository
+}
+
+// NewReporter creates a reporter emailing the reports through sender
+func NewReporter(
+	preferences repository.UserPreferenceRepository,
+	users repository.UserRepository,
+	zones repository.ZoneRepository,
+	currencies repository.CurrencyRepository,
+	consumption repository.ConsumptionRepository,
+	spotPrices repository.SpotPriceRepository,
+	sender ReportSender,
+) *Reporter {
+	return &Reporter{
+		preferences: preferences,
+		users:       users,
+		zones:       zones,
+		currencies:  currencies,
+		consumption: consumption,
+		spotPrices:  spotPrices,
+		sender:      sender,
+		now:         time.Now,
+	}
+}
+
+// SetTariffs makes the reports add the fees and taxes of the users' tariffs
+func (r *Reporter) SetTariffs(repo repository.TariffRepository) {
+	r.tariffs = repo
+}
+
+// Run emails the reports once, for running as a scheduled job. It stops at the first user
+// when email isn't configured.
+func (r *Reporter) Run(ctx context.Context) error {
+	list, err := r.preferences.ListWeeklyReports(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list weekly report preferences: %w", err)
+	}
+
+	now := r.now()
+	var errs []error
+	for i := range list {
+		err := r.send(ctx, &list[i], now)
+		if errors.Is(err, email.ErrNotConfigured) {
+			return err
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("user %s: %w", list[i].UserID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// send emails the user their reports of the week before now, unless they have no verified
+// email address or nothing to report
+func (r *Reporter) send(ctx context.Context, prefs *models.UserPreferences, now time.Time) error {
+	if prefs.ZoneID == nil || prefs.CurrencyID == nil {
+		return nil
+	}
+	user, err := r.users.GetByID(ctx, prefs.UserID)
+	if errors.Is(err, repository.ErrUserNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.DeletedAt != nil || user.Email == nil || !user.EmailVerified {
+		return nil
+	}
+
+	zone, err := r.zones.GetByID(ctx, *prefs.ZoneID)
+	if err != nil {
+		return fmt.Errorf("failed to get zone %s: %w", *prefs.ZoneID, err)
+	}
+	currency, err := r.currencies.GetByID(ctx, *prefs.CurrencyID)
+	if err != nil {
+		return fmt.Errorf("failed to get currency %s: %w", *prefs.CurrencyID, err)
+	}
+	var tariff *models.Tariff
+	if r.tariffs != nil {
+		tariff, err = r.tariffs.FindForUser(ctx, user.ID, zone.ID, currency.ID)
+		if err != nil && !errors.Is(err, repository.ErrNotFound) {
+			return fmt.Errorf("failed to find tariff: %w", err)
+		}
+	}
+
+	reports, err := r.Reports(ctx, user.ID, zone, currency, tariff, now)
+	if errors.Is(err, ErrNoPrices) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	err = r.sender.SendWeeklyReport(*user.Email, user.Username, user.Language, reports)
+	if errors.Is(err, email.ErrRecipientSuppressed) {
+		return nil
+	}
+	return err
+}
+
+// Reports returns the report of each of the user's meters with readings in the last
+// complete week before now, ordered by meter. The tariff, when not nil, adds its transfer
+// fee, energy tax and VAT to the costs.
+func (r *Reporter) Reports(ctx context.Context, userID uuid.UUID, zone *models.Zone, currency *models.Currency, tariff *models.Tariff, now time.Time) ([]email.WeeklyReport, error) {
+	loc, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone %q of zone %s: %w", zone.Timezone, zone.Name, err)
+	}
+	local := now.In(loc)
+	end := time.Date(local.Year(), local.Month(), local.Day()-(int(local.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	start := end.AddDate(0, 0, -7)
+	previousStart := start.AddDate(0, 0, -7)
+
+	prices, err := loadPrices(ctx, r.spotPrices, zone.ID, currency.ID, previousStart, end)
+	if err != nil {
+		return nil, err
+	}
+	if len(prices.prices) == 0 {
+		return nil, ErrNoPrices
+	}
+
+	records, err := r.consumption.List(ctx, repository.ConsumptionFilter{
+		UserID:    userID,
+		StartTime: &previousStart,
+		EndTime:   &end,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list consumption: %w", err)
+	}
+
+	meters := make(map[string]*meterWeeks)
+	for _, rec := range records {
+		if !rec.Timestamp.Before(end) {
+			continue
+		}
+		m := meters[rec.MeterID]
+		if m == nil {
+			m = &meterWeeks{}
+			meters[rec.MeterID] = m
+		}
+		w := &m.current
+		if rec.Timestamp.Before(start) {
+			w = &m.previous
+		}
+		w.add(rec.KWh, prices.at(rec.Timestamp), rec.Timestamp.In(loc), tariff)
+	}
+
+	ids := make([]string, 0, len(meters))
+	for id, m := range meters {
+		if len(m.current.days) > 0 {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	reports := make([]email.WeeklyReport, 0, len(ids))
+	for _, id := range ids {
+		m := meters[id]
+		report := email.WeeklyReport{
+			HomeName:       id,
+			WeekStart:      start,
+			Currency:       currency.Name,
+			ConsumptionKWh: roundKWh(m.current.kwh),
+			Cost:           roundCost(m.current.cost()),
+		}
+		if len(m.previous.days) > 0 {
+			kwh, cost := roundKWh(m.previous.kwh), roundCost(m.previous.cost())
+			report.PreviousConsumptionKWh = &kwh
+			report.PreviousCost = &cost
+		}
+		day, cost := m.current.mostExpensiveDay()
+		report.MostExpensiveDay, report.MostExpensiveDayCost = day, roundCost(cost)
+		reports = append(reports, report)
+	}
+	return reports, nil
+}
+
+// meterWeeks are the readings of a meter in the reported week and the week before
+type meterWeeks struct {
+	current, previous week
+}
+
+// week sums the consumption of a meter in a week, and its cost by local day
+type week struct {
+	kwh  float64
+	days map[time.Time]*costs
+}
+
+// add prices kwh consumed at local time t
+func (w *week) add(kwh, price float64, t time.Time, tariff *models.Tariff) {
+	if w.days == nil {
+		w.days = make(map[time.Time]*costs)
+	}
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+	c := w.days[day]
+	if c == nil {
+		c = &costs{tariff: tariff}
+		w.days[day] = c
+	}
+	w.kwh += kwh
+	c.add(kwh, price, t)
+}
+
+func (w *week) cost() float64 {
+	var sum float64
+	for _, c := range w.days {
+		sum += c.total()
+	}
+	return sum
+}
+
+// mostExpensiveDay returns the earliest of the days costing the most and its cost
+func (w *week) mostExpensiveDay() (time.Time, float64) {
+	var day time.Time
+	var highest float64
+	for d, c := range w.days {
+		total := c.total()
+		if day.IsZero() || total > highest || (total == highest && d.Before(day)) {
+			day, highest = d, total
+		}
+	}
+	return day, highest
+}
@@ -0,0 +1,59 @@
+package budget
+
+import (
+	"fmt"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+)
+
+// clockLayout is the layout of the times of day rates start and end at
+const clockLayout = "15:04"
+
+// ValidateRates checks that the rates start and end at valid times of day, and that no
+// rate starts when it ends
+func ValidateRates(rates []models.TariffRate) error {
+	for i, rate := range rates {
+		start, err := time.Parse(clockLayout, rate.Start)
+		if err != nil {
+			return fmt.Errorf("rate %d: start %q is not a time of day like 06:00", i+1, rate.Start)
+		}
+		end, err := time.Parse(clockLayout, rate.End)
+		if err != nil {
+			return fmt.Errorf("rate %d: end %q is not a time of day like 22:00", i+1, rate.End)
+		}
+		if start.Equal(end) {
+			return fmt.Errorf("rate %d: starts when it ends", i+1)
+		}
+	}
+	return nil
+}
+
+// TransferFee returns the transfer fee per kWh of the tariff at t, which must be in the
+// zone's timezone: that of the first rate applying at t, or the tariff's own when none
+// does
+func TransferFee(tariff *models.Tariff, t time.Time) float64 {
+	minute := t.Hour()*60 + t.Minute()
+	for _, rate := range tariff.Rates {
+		if rate.Weekdays && (t.Weekday() == time.Saturday || t.Weekday() == time.Sunday) {
+			continue
+		}
+		if len(rate.Months) > 0 && !slices.Contains(rate.Months, int(t.Month())) {
+			continue
+		}
+		start, err := time.Parse(clockLayout, rate.Start)
+		if err != nil {
+			continue
+		}
+		end, err := time.Parse(clockLayout, rate.End)
+		if err != nil {
+			continue
+		}
+		from, until := start.Hour()*60+start.Minute(), end.Hour()*60+end.Minute()
+		if from < until && minute >= from && minute < until ||
+			from > until && (minute >= from || minute < until) {
+			return rate.TransferFee
+		}
+	}
+	return tariff.TransferFee
+}
@@ -0,0 +1,77 @@
+// Package cache keeps the results of frequent reads for a short time, in memory on each
+// instance or in Redis shared by all of them. Repositories wrapped by a Cache drop the
+// entries their writes affect.
+package cache
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"time"
+	"wattwatch/internal/metrics"
+)
+
+// Store keeps encoded values until they expire
+type Store interface {
+	// Get returns the value under key, false when there is none or it has expired
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	// Set keeps value under key for ttl
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// DeletePrefix removes the values under the keys starting with prefix
+	DeletePrefix(ctx context.Context, prefix string) error
+}
+
+// Cache keeps values of several kinds in a store, each under its own namespace. Values are
+// stored as JSON, so a hit returns a copy that callers may change. Failing to reach the
+// store is logged and treated as a miss, the cache never fails a read.
+type Cache struct {
+	store Store
+}
+
+// New creates a cache keeping its values in store
+func New(store Store) *Cache {
+	return &Cache{store: store}
+}
+
+// get decodes the value of key in namespace into dst and reports whether it was found
+func (c *Cache) get(ctx context.Context, namespace, key string, dst interface{}) bool {
+	data, ok, err := c.store.Get(ctx, namespace+":"+key)
+	if err != nil {
+		log.Printf("Failed to read %s from cache: %v", namespace, err)
+	}
+	if ok {
+		if err := json.Unmarshal(data, dst); err != nil {
+			log.Printf("Failed to decode %s from cache: %v", namespace, err)
+			ok = false
+		}
+	}
+	if ok {
+		metrics.CacheHit(namespace)
+	} else {
+		metrics.CacheMiss(namespace)
+	}
+	return ok
+}
+
+// set keeps value under key in namespace for ttl
+func (c *Cache) set(ctx context.Context, namespace, key string, value interface{}, ttl time.Duration) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		log.Printf("Failed to encode %s for cache: %v", namespace, err)
+		return
+	}
+	if err := c.store.Set(ctx, namespace+":"+key, data, ttl); err != nil {
+		log.Printf("Failed to write %s to cache: %v", namespace, err)
+	}
+}
+
+// Invalidate drops every value in the namespaces. It runs even when ctx is cancelled, as
+// the write it follows has already been made.
+func (c *Cache) Invalidate(ctx context.Context, namespaces ...string) {
+	ctx = context.WithoutCancel(ctx)
+	for _, namespace := range namespaces {
+		if err := c.store.DeletePrefix(ctx, namespace+":"); err != nil {
+			log.Printf("Failed to drop %s from cache: %v", namespace, err)
+		}
+	}
+}
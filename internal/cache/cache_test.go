@@ -0,0 +1,175 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore(2)
+	now := time.Now()
+	store.now = func() time.Time { return now }
+
+	require.NoError(t, store.Set(ctx, "a", []byte("1"), time.Minute))
+	require.NoError(t, store.Set(ctx, "b", []byte("2"), time.Minute))
+	// Reading a makes b the least recently used, which c pushes out
+	_, ok, _ := store.Get(ctx, "a")
+	require.True(t, ok)
+	require.NoError(t, store.Set(ctx, "c", []byte("3"), time.Minute))
+	_, ok, _ = store.Get(ctx, "b")
+	assert.False(t, ok)
+	value, ok, _ := store.Get(ctx, "a")
+	require.True(t, ok)
+	assert.Equal(t, []byte("1"), value)
+
+	now = now.Add(time.Minute)
+	_, ok, _ = store.Get(ctx, "a")
+	assert.False(t, ok)
+	assert.Equal(t, 1, store.Len())
+
+	require.NoError(t, store.Set(ctx, "zones:a", []byte("1"), time.Minute))
+	require.NoError(t, store.DeletePrefix(ctx, "zones:"))
+	_, ok, _ = store.Get(ctx, "zones:a")
+	assert.False(t, ok)
+}
+
+// countingZoneRepository counts the zones looked up by name
+type countingZoneRepository struct {
+	repository.ZoneRepository
+	lookups int
+}
+
+func (r *countingZoneRepository) GetByName(ctx context.Context, name string) (*models.Zone, error) {
+	r.lookups++
+	return r.ZoneRepository.GetByName(ctx, name)
+}
+
+func TestCache_Zones(t *testing.T) {
+	ctx := context.Background()
+	counting := &countingZoneRepository{ZoneRepository: memory.NewZoneRepository(memory.NewStore())}
+	zones := New(NewMemoryStore(100)).Zones(counting, time.Minute)
+
+	zone := &models.Zone{Name: "FI", Timezone: "Europe/Helsinki"}
+	require.NoError(t, zones.Create(ctx, zone))
+
+	for i := 0; i < 3; i++ {
+		got, err := zones.GetByName(ctx, "FI")
+		require.NoError(t, err)
+		assert.Equal(t, zone.ID, got.ID)
+	}
+	assert.Equal(t, 1, counting.lookups)
+
+	// Unknown zones aren't cached
+	_, err := zones.GetByName(ctx, "SE9")
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = zones.GetByName(ctx, "SE9")
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	assert.Equal(t, 3, counting.lookups)
+
+	// Changing a zone drops the cached zones
+	zone.Timezone = "Europe/Mariehamn"
+	require.NoError(t, zones.Update(ctx, zone))
+	got, err := zones.GetByName(ctx, "FI")
+	require.NoError(t, err)
+	assert.Equal(t, "Europe/Mariehamn", got.Timezone)
+	assert.Equal(t, 4, counting.lookups)
+}
+
+// countingSpotPriceRepository counts the spot prices listed
+type countingSpotPriceRepository struct {
+	repository.SpotPriceRepository
+	reads int
+}
+
+func (r *countingSpotPriceRepository) Each(ctx context.Context, filter repository.SpotPriceFilter, fn func(*models.SpotPrice) error) error {
+	r.reads++
+	return r.SpotPriceRepository.Each(ctx, filter, fn)
+}
+
+func TestCache_SpotPrices(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	zone := &models.Zone{Name: "FI", Timezone: "Europe/Helsinki"}
+	require.NoError(t, memory.NewZoneRepository(store).Create(ctx, zone))
+	currency := &models.Currency{Name: "NOK"}
+	require.NoError(t, memory.NewCurrencyRepository(store).Create(ctx, currency))
+
+	cache := New(NewMemoryStore(100))
+	counting := &countingSpotPriceRepository{SpotPriceRepository: memory.NewSpotPriceRepository(store)}
+	spotPrices := cache.SpotPrices(counting, time.Minute)
+	sources := cache.Sources(memory.NewSpotPriceSourceRepository(store))
+
+	start := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	batch := make([]models.SpotPrice, 24)
+	for i := range batch {
+		batch[i] = models.SpotPrice{Timestamp: start.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: float64(i)}
+	}
+	require.NoError(t, spotPrices.CreateBatch(ctx, batch))
+
+	end := start.Add(24 * time.Hour)
+	filter := repository.SpotPriceFilter{ZoneID: &zone.ID, CurrencyID: &currency.ID, StartTime: &start, EndTime: &end, OrderBy: "timestamp"}
+	each := func(filter repository.SpotPriceFilter) []float64 {
+		var prices []float64
+		require.NoError(t, spotPrices.Each(ctx, filter, func(sp *models.SpotPrice) error {
+			prices = append(prices, sp.Price)
+			return nil
+		}))
+		return prices
+	}
+
+	// Each and List share the cached spot prices
+	assert.Len(t, each(filter), 24)
+	assert.Len(t, each(filter), 24)
+	listed, err := spotPrices.List(ctx, filter)
+	require.NoError(t, err)
+	assert.Len(t, listed, 24)
+	assert.Equal(t, 1, counting.reads)
+
+	// Recording prices from a provider drops the cached ones
+	require.NoError(t, sources.Record(ctx, "nordpool", []models.SpotPrice{{Timestamp: start, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 99}}))
+	assert.Equal(t, 99.0, each(filter)[0])
+	assert.Equal(t, 2, counting.reads)
+
+	// Ranges longer than the list endpoint allows aren't cached
+	longEnd := start.Add(MaxSpotPriceRange + time.Hour)
+	long := filter
+	long.EndTime = &longEnd
+	each(long)
+	each(long)
+	assert.Equal(t, 4, counting.reads)
+}
+
+func TestCache_StoreFailure(t *testing.T) {
+	ctx := context.Background()
+	zones := New(failingStore{}).Zones(memory.NewZoneRepository(memory.NewStore()), time.Minute)
+
+	zone := &models.Zone{Name: "FI", Timezone: "Europe/Helsinki"}
+	require.NoError(t, zones.Create(ctx, zone))
+	got, err := zones.GetByID(ctx, zone.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "FI", got.Name)
+}
+
+// failingStore can't be reached
+type failingStore struct{}
+
+func (failingStore) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	return nil, false, fmt.Errorf("connection refused")
+}
+
+func (failingStore) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return fmt.Errorf("connection refused")
+}
+
+func (failingStore) DeletePrefix(ctx context.Context, prefix string) error {
+	return fmt.Errorf("connection refused")
+}
@@ -0,0 +1,331 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Namespaces of the cached values, which are also the cache label of the hit and miss metrics
+const (
+	NamespaceZones      = "zones"
+	NamespaceCurrencies = "currencies"
+	NamespaceSpotPrices = "spot_prices"
+)
+
+// MaxSpotPriceRange is the longest range of spot prices cached, the week dashboards and
+// polling clients ask for. Longer ranges, such as year long listings and those read by the
+// data quality check, go to the database.
+const MaxSpotPriceRange = 7 * 24 * time.Hour
+
+// Zones wraps repo so that zones are read from the cache for ttl, and changing a zone drops
+// the cached zones. Deleting a zone with its spot prices also drops the cached spot prices.
+func (c *Cache) Zones(repo repository.ZoneRepository, ttl time.Duration) repository.ZoneRepository {
+	return &cachingZoneRepository{ZoneRepository: repo, cache: c, ttl: ttl}
+}
+
+// Currencies wraps repo so that currencies are read from the cache for ttl, and changing a
+// currency drops the cached currencies. Deleting a currency with its spot prices also drops
+// the cached spot prices.
+func (c *Cache) Currencies(repo repository.CurrencyRepository, ttl time.Duration) repository.CurrencyRepository {
+	return &cachingCurrencyRepository{CurrencyRepository: repo, cache: c, ttl: ttl}
+}
+
+// SpotPrices wraps repo so that the spot prices of one zone and currency over at most
+// MaxSpotPriceRange are read from the cache for ttl, and writing spot prices drops the
+// cached ones
+func (c *Cache) SpotPrices(repo repository.SpotPriceRepository, ttl time.Duration) repository.SpotPriceRepository {
+	return &cachingSpotPriceRepository{SpotPriceRepository: repo, cache: c, ttl: ttl}
+}
+
+// Sources wraps repo so that recording and resolving spot prices drops the cached ones
+func (c *Cache) Sources(repo repository.SpotPriceSourceRepository) repository.SpotPriceSourceRepository {
+	return &invalidatingSourceRepository{SpotPriceSourceRepository: repo, cache: c}
+}
+
+// optional formats a filter field for a key, nil as "-"
+func optional[T any](value *T) string {
+	if value == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%q", fmt.Sprint(*value))
+}
+
+type cachingZoneRepository struct {
+	repository.ZoneRepository
+	cache *Cache
+	ttl   time.Duration
+}
+
+func (r *cachingZoneRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Zone, error) {
+	key := "id:" + id.String()
+	zone := &models.Zone{}
+	if r.cache.get(ctx, NamespaceZones, key, zone) {
+		return zone, nil
+	}
+	zone, err := r.ZoneRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(ctx, NamespaceZones, key, zone, r.ttl)
+	return zone, nil
+}
+
+func (r *cachingZoneRepository) GetByName(ctx context.Context, name string) (*models.Zone, error) {
+	key := fmt.Sprintf("name:%q", name)
+	zone := &models.Zone{}
+	if r.cache.get(ctx, NamespaceZones, key, zone) {
+		return zone, nil
+	}
+	zone, err := r.ZoneRepository.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(ctx, NamespaceZones, key, zone, r.ttl)
+	return zone, nil
+}
+
+// zoneFilterKey identifies the zones matching filter, under prefix
+func zoneFilterKey(prefix string, filter repository.ZoneFilter) string {
+	return fmt.Sprintf("%s:%s:%s:%q:%t:%s:%s", prefix, optional(filter.Search), optional(filter.CountryCode),
+		filter.OrderBy, filter.OrderDesc, optional(filter.Limit), optional(filter.Offset))
+}
+
+func (r *cachingZoneRepository) List(ctx context.Context, filter repository.ZoneFilter) ([]models.Zone, error) {
+	key := zoneFilterKey("list", filter)
+	var zones []models.Zone
+	if r.cache.get(ctx, NamespaceZones, key, &zones) {
+		return zones, nil
+	}
+	zones, err := r.ZoneRepository.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(ctx, NamespaceZones, key, zones, r.ttl)
+	return zones, nil
+}
+
+func (r *cachingZoneRepository) Total(ctx context.Context, filter repository.ZoneFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	key := zoneFilterKey("total", filter)
+	var total int
+	if r.cache.get(ctx, NamespaceZones, key, &total) {
+		return total, nil
+	}
+	total, err := r.ZoneRepository.Total(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	r.cache.set(ctx, NamespaceZones, key, total, r.ttl)
+	return total, nil
+}
+
+func (r *cachingZoneRepository) Create(ctx context.Context, zone *models.Zone) error {
+	defer r.cache.Invalidate(ctx, NamespaceZones)
+	return r.ZoneRepository.Create(ctx, zone)
+}
+
+func (r *cachingZoneRepository) Update(ctx context.Context, zone *models.Zone) error {
+	defer r.cache.Invalidate(ctx, NamespaceZones)
+	return r.ZoneRepository.Update(ctx, zone)
+}
+
+func (r *cachingZoneRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Invalidate(ctx, NamespaceZones)
+	return r.ZoneRepository.Delete(ctx, id)
+}
+
+func (r *cachingZoneRepository) DeleteCascade(ctx context.Context, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error) {
+	defer r.cache.Invalidate(ctx, NamespaceZones, NamespaceSpotPrices)
+	return r.ZoneRepository.DeleteCascade(ctx, id, reassignTo)
+}
+
+type cachingCurrencyRepository struct {
+	repository.CurrencyRepository
+	cache *Cache
+	ttl   time.Duration
+}
+
+func (r *cachingCurrencyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Currency, error) {
+	key := "id:" + id.String()
+	currency := &models.Currency{}
+	if r.cache.get(ctx, NamespaceCurrencies, key, currency) {
+		return currency, nil
+	}
+	currency, err := r.CurrencyRepository.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(ctx, NamespaceCurrencies, key, currency, r.ttl)
+	return currency, nil
+}
+
+func (r *cachingCurrencyRepository) GetByName(ctx context.Context, name string) (*models.Currency, error) {
+	key := fmt.Sprintf("name:%q", name)
+	currency := &models.Currency{}
+	if r.cache.get(ctx, NamespaceCurrencies, key, currency) {
+		return currency, nil
+	}
+	currency, err := r.CurrencyRepository.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(ctx, NamespaceCurrencies, key, currency, r.ttl)
+	return currency, nil
+}
+
+func (r *cachingCurrencyRepository) List(ctx context.Context) ([]models.Currency, error) {
+	var currencies []models.Currency
+	if r.cache.get(ctx, NamespaceCurrencies, "list", &currencies) {
+		return currencies, nil
+	}
+	currencies, err := r.CurrencyRepository.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(ctx, NamespaceCurrencies, "list", currencies, r.ttl)
+	return currencies, nil
+}
+
+func (r *cachingCurrencyRepository) Create(ctx context.Context, currency *models.Currency) error {
+	defer r.cache.Invalidate(ctx, NamespaceCurrencies)
+	return r.CurrencyRepository.Create(ctx, currency)
+}
+
+func (r *cachingCurrencyRepository) Update(ctx context.Context, currency *models.Currency) error {
+	defer r.cache.Invalidate(ctx, NamespaceCurrencies)
+	return r.CurrencyRepository.Update(ctx, currency)
+}
+
+func (r *cachingCurrencyRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Invalidate(ctx, NamespaceCurrencies)
+	return r.CurrencyRepository.Delete(ctx, id)
+}
+
+func (r *cachingCurrencyRepository) DeleteCascade(ctx context.Context, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error) {
+	defer r.cache.Invalidate(ctx, NamespaceCurrencies, NamespaceSpotPrices)
+	return r.CurrencyRepository.DeleteCascade(ctx, id, reassignTo)
+}
+
+type cachingSpotPriceRepository struct {
+	repository.SpotPriceRepository
+	cache *Cache
+	ttl   time.Duration
+}
+
+// spotPriceFilterKey identifies the spot prices matching filter under prefix, false when
+// they aren't cached. Prices as of a time aren't, they are seldom asked for twice.
+func spotPriceFilterKey(prefix string, filter repository.SpotPriceFilter) (string, bool) {
+	if filter.AsOf != nil || filter.ZoneID == nil || filter.CurrencyID == nil || filter.StartTime == nil || filter.EndTime == nil ||
+		filter.EndTime.Sub(*filter.StartTime) > MaxSpotPriceRange {
+		return "", false
+	}
+	after := ""
+	if filter.After != nil {
+		after = filter.After.Encode()
+	}
+	return fmt.Sprintf("%s:%s:%s:%s:%s:%q:%t:%s:%s:%s", prefix, filter.ZoneID, filter.CurrencyID,
+		filter.StartTime.UTC().Format(time.RFC3339Nano), filter.EndTime.UTC().Format(time.RFC3339Nano),
+		filter.OrderBy, filter.OrderDesc, optional(filter.Limit), optional(filter.Offset), after), true
+}
+
+func (r *cachingSpotPriceRepository) List(ctx context.Context, filter repository.SpotPriceFilter) ([]models.SpotPrice, error) {
+	key, ok := spotPriceFilterKey("list", filter)
+	if !ok {
+		return r.SpotPriceRepository.List(ctx, filter)
+	}
+	var spotPrices []models.SpotPrice
+	if r.cache.get(ctx, NamespaceSpotPrices, key, &spotPrices) {
+		return spotPrices, nil
+	}
+	spotPrices, err := r.SpotPriceRepository.List(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+	r.cache.set(ctx, NamespaceSpotPrices, key, spotPrices, r.ttl)
+	return spotPrices, nil
+}
+
+// Each shares its cached spot prices with List. On a miss they are kept as they are read,
+// and only cached when fn accepted all of them.
+func (r *cachingSpotPriceRepository) Each(ctx context.Context, filter repository.SpotPriceFilter, fn func(*models.SpotPrice) error) error {
+	key, ok := spotPriceFilterKey("list", filter)
+	if !ok {
+		return r.SpotPriceRepository.Each(ctx, filter, fn)
+	}
+	var spotPrices []models.SpotPrice
+	if r.cache.get(ctx, NamespaceSpotPrices, key, &spotPrices) {
+		for i := range spotPrices {
+			if err := fn(&spotPrices[i]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	err := r.SpotPriceRepository.Each(ctx, filter, func(sp *models.SpotPrice) error {
+		spotPrices = append(spotPrices, *sp)
+		return fn(sp)
+	})
+	if err != nil {
+		return err
+	}
+	r.cache.set(ctx, NamespaceSpotPrices, key, spotPrices, r.ttl)
+	return nil
+}
+
+func (r *cachingSpotPriceRepository) Total(ctx context.Context, filter repository.SpotPriceFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	key, ok := spotPriceFilterKey("total", filter)
+	if !ok {
+		return r.SpotPriceRepository.Total(ctx, filter)
+	}
+	var total int
+	if r.cache.get(ctx, NamespaceSpotPrices, key, &total) {
+		return total, nil
+	}
+	total, err := r.SpotPriceRepository.Total(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	r.cache.set(ctx, NamespaceSpotPrices, key, total, r.ttl)
+	return total, nil
+}
+
+func (r *cachingSpotPriceRepository) Create(ctx context.Context, spotPrice *models.SpotPrice) error {
+	defer r.cache.Invalidate(ctx, NamespaceSpotPrices)
+	return r.SpotPriceRepository.Create(ctx, spotPrice)
+}
+
+func (r *cachingSpotPriceRepository) CreateBatch(ctx context.Context, spotPrices []models.SpotPrice) error {
+	defer r.cache.Invalidate(ctx, NamespaceSpotPrices)
+	return r.SpotPriceRepository.CreateBatch(ctx, spotPrices)
+}
+
+func (r *cachingSpotPriceRepository) Update(ctx context.Context, spotPrice *models.SpotPrice) error {
+	defer r.cache.Invalidate(ctx, NamespaceSpotPrices)
+	return r.SpotPriceRepository.Update(ctx, spotPrice)
+}
+
+func (r *cachingSpotPriceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	defer r.cache.Invalidate(ctx, NamespaceSpotPrices)
+	return r.SpotPriceRepository.Delete(ctx, id)
+}
+
+type invalidatingSourceRepository struct {
+	repository.SpotPriceSourceRepository
+	cache *Cache
+}
+
+func (r *invalidatingSourceRepository) Record(ctx context.Context, source string, spotPrices []models.SpotPrice) error {
+	defer r.cache.Invalidate(ctx, NamespaceSpotPrices)
+	return r.SpotPriceSourceRepository.Record(ctx, source, spotPrices)
+}
+
+func (r *invalidatingSourceRepository) Resolve(ctx context.Context, timestamp time.Time, zoneID, currencyID uuid.UUID, source string) (*models.SpotPrice, error) {
+	defer r.cache.Invalidate(ctx, NamespaceSpotPrices)
+	return r.SpotPriceSourceRepository.Resolve(ctx, timestamp, zoneID, currencyID, source)
+}
@@ -0,0 +1,108 @@
+// Package captcha verifies the CAPTCHA tokens clients get from solving a challenge, which
+// registrations, password resets and repeated logins can be required to carry
+package captcha
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"wattwatch/internal/config"
+)
+
+var (
+	// ErrMissingToken is returned when a request carries no token to verify
+	ErrMissingToken = errors.New("captcha token missing")
+	// ErrInvalidToken is returned when the provider rejects a token, e.g. because it expired
+	// or was used before
+	ErrInvalidToken = errors.New("captcha token invalid")
+)
+
+// Verifier checks tokens with a CAPTCHA provider
+type Verifier interface {
+	// Name identifies the provider in logs
+	Name() string
+	// Verify checks the token of the client at remoteIP, returning ErrMissingToken or
+	// ErrInvalidToken when it doesn't pass and other errors when the provider failed
+	Verify(ctx context.Context, token, remoteIP string) error
+}
+
+const (
+	hCaptchaVerifyURL  = "https://api.hcaptcha.com/siteverify"
+	turnstileVerifyURL = "https://challenges.cloudflare.com/turnstile/v0/siteverify"
+)
+
+// New creates the verifier of the configured provider, nil when CAPTCHAs are disabled
+func New(cfg config.CaptchaConfig) Verifier {
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch cfg.Provider {
+	case config.CaptchaProviderHCaptcha:
+		return &siteVerifier{name: cfg.Provider, client: client, verifyURL: hCaptchaVerifyURL, secret: cfg.SecretKey}
+	case config.CaptchaProviderTurnstile:
+		return &siteVerifier{name: cfg.Provider, client: client, verifyURL: turnstileVerifyURL, secret: cfg.SecretKey}
+	default:
+		return nil
+	}
+}
+
+// siteVerifier verifies tokens with a siteverify API, which hCaptcha and Turnstile share
+type siteVerifier struct {
+	name      string
+	client    *http.Client
+	verifyURL string
+	secret    string
+}
+
+func (v *siteVerifier) Name() string { return v.name }
+
+func (v *siteVerifier) Verify(ctx context.Context, token, remoteIP string) error {
+	if strings.TrimSpace(token) == "" {
+		return ErrMissingToken
+	}
+
+	form := url.Values{}
+	form.Set("secret", v.secret)
+	form.Set("response", token)
+	if remoteIP != "" {
+		form.Set("remoteip", remoteIP)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, v.verifyURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return fmt.Errorf("%s: %w", v.name, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := v.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s: request failed: %w", v.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("%s: status %d: %s", v.name, resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+
+	var result struct {
+		Success    bool     `json:"success"`
+		ErrorCodes []string `json:"error-codes"`
+	}
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 64*1024)).Decode(&result); err != nil {
+		return fmt.Errorf("%s: failed to decode response: %w", v.name, err)
+	}
+	if !result.Success {
+		// A rejected secret is a configuration problem rather than a bad token
+		for _, code := range result.ErrorCodes {
+			if code == "invalid-input-secret" || code == "missing-input-secret" {
+				return fmt.Errorf("%s: secret key rejected: %s", v.name, code)
+			}
+		}
+		return fmt.Errorf("%w: %s", ErrInvalidToken, strings.Join(result.ErrorCodes, ", "))
+	}
+	return nil
+}
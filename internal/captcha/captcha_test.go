@@ -0,0 +1,60 @@
+package captcha
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	assert.Nil(t, New(config.CaptchaConfig{}))
+
+	verifier := New(config.CaptchaConfig{Provider: config.CaptchaProviderHCaptcha, SecretKey: "secret"})
+	require.NotNil(t, verifier)
+	assert.Equal(t, hCaptchaVerifyURL, verifier.(*siteVerifier).verifyURL)
+
+	verifier = New(config.CaptchaConfig{Provider: config.CaptchaProviderTurnstile, SecretKey: "secret"})
+	require.NotNil(t, verifier)
+	assert.Equal(t, turnstileVerifyURL, verifier.(*siteVerifier).verifyURL)
+}
+
+func TestSiteVerifier(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "application/x-www-form-urlencoded", r.Header.Get("Content-Type"))
+		assert.Equal(t, "192.0.2.1", r.PostForm.Get("remoteip"))
+		switch {
+		case r.PostForm.Get("secret") != "secret":
+			_, _ = w.Write([]byte(`{"success":false,"error-codes":["invalid-input-secret"]}`))
+		case r.PostForm.Get("response") == "passed":
+			_, _ = w.Write([]byte(`{"success":true}`))
+		case r.PostForm.Get("response") == "unavailable":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+			_, _ = w.Write([]byte(`{"success":false,"error-codes":["invalid-input-response"]}`))
+		}
+	}))
+	defer server.Close()
+
+	verifier := &siteVerifier{name: "test", client: server.Client(), verifyURL: server.URL, secret: "secret"}
+	ctx := context.Background()
+
+	require.NoError(t, verifier.Verify(ctx, "passed", "192.0.2.1"))
+	assert.ErrorIs(t, verifier.Verify(ctx, "", "192.0.2.1"), ErrMissingToken)
+	assert.ErrorIs(t, verifier.Verify(ctx, "failed", "192.0.2.1"), ErrInvalidToken)
+
+	// Provider failures aren't blamed on the token
+	err := verifier.Verify(ctx, "unavailable", "192.0.2.1")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken)
+
+	verifier.secret = "wrong"
+	err = verifier.Verify(ctx, "passed", "192.0.2.1")
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken)
+}
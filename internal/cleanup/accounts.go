@@ -0,0 +1,58 @@
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ScheduledDeleter deletes the users whose deletion is scheduled at or before now,
+// returning their IDs. It is implemented by the user repository.
+type ScheduledDeleter interface {
+	DeleteScheduled(ctx context.Context, now time.Time) ([]uuid.UUID, error)
+}
+
+// Accounts deletes the accounts users deleted themselves once their grace period has
+// passed, and records each deletion in the audit log
+type Accounts struct {
+	users ScheduledDeleter
+	audit AuditRecorder
+	now   func() time.Time
+}
+
+// NewAccounts creates a job deleting the accounts scheduled for deletion in users
+func NewAccounts(users ScheduledDeleter, audit AuditRecorder) *Accounts {
+	return &Accounts{
+		users: users,
+		audit: audit,
+		now:   time.Now,
+	}
+}
+
+// Run deletes the accounts due once, for running as a scheduled job
+func (a *Accounts) Run(ctx context.Context) error {
+	ids, err := a.users.DeleteScheduled(ctx, a.now())
+	if err != nil {
+		return fmt.Errorf("failed to delete scheduled accounts: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := a.audit.Create(ctx, &models.CreateAuditLogRequest{
+			Action:      models.AuditActionDelete,
+			EntityType:  "user",
+			EntityID:    id.String(),
+			Description: "User deleted after the deletion grace period",
+			Metadata:    `{"user_id":"` + id.String() + `"}`,
+		}); err != nil {
+			log.Printf("Error logging scheduled user deletion: %v", err)
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("Deleted %d accounts scheduled for deletion", len(ids))
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+package cleanup
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccounts(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	roles := memory.NewRoleRepository(store)
+	auditLogs := memory.NewAuditLogRepository(store)
+
+	role, err := roles.GetByName(ctx, "user")
+	require.NoError(t, err)
+	adminRole, err := roles.GetByName(ctx, "admin")
+	require.NoError(t, err)
+
+	now := time.Now()
+	create := func(username string, role *models.Role, deleteAt *time.Time) *models.User {
+		user := &models.User{Username: username, Password: "hash", RoleID: role.ID}
+		require.NoError(t, users.Create(ctx, user))
+		if deleteAt != nil {
+			require.NoError(t, users.ScheduleDeletion(ctx, user.ID, *deleteAt))
+		}
+		return user
+	}
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	due := create("due", role, &past)
+	pending := create("pending", role, &future)
+	kept := create("kept", role, nil)
+	admin := create("admin", adminRole, &past)
+
+	accounts := NewAccounts(users, auditLogs)
+	accounts.now = func() time.Time { return now }
+	require.NoError(t, accounts.Run(ctx))
+
+	_, err = users.GetByID(ctx, due.ID)
+	assert.ErrorIs(t, err, repository.ErrUserNotFound)
+	for _, user := range []*models.User{pending, kept, admin} {
+		_, err := users.GetByID(ctx, user.ID)
+		assert.NoError(t, err, user.Username)
+	}
+
+	logs, err := auditLogs.List(ctx, repository.AuditLogFilter{EntityIDs: []string{due.ID.String()}})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+	assert.Equal(t, models.AuditActionDelete, logs[0].Action)
+
+	// Deleted accounts aren't deleted again
+	require.NoError(t, accounts.Run(ctx))
+	logs, err = auditLogs.List(ctx, repository.AuditLogFilter{EntityIDs: []string{due.ID.String()}})
+	require.NoError(t, err)
+	assert.Len(t, logs, 1)
+}
@@ -0,0 +1,95 @@
+// Package cleanup removes expired refresh, password reset, email verification and email
+// change revert tokens, and audit logs and login attempts past their retention period,
+// which would otherwise be kept forever. It also deletes the accounts users deleted
+// themselves once their grace period has passed.
+package cleanup
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"wattwatch/internal/metrics"
+)
+
+// Kinds of tokens removed, used as the metrics label and in Result
+const (
+	KindRefreshToken      = "refresh_token"
+	KindPasswordReset     = "password_reset"
+	KindEmailVerification = "email_verification"
+	KindEmailChangeRevert = "email_change_revert"
+)
+
+// Deleter removes the tokens of one kind that expired before the given time. It is
+// implemented by the token repositories.
+type Deleter interface {
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
+
+// Result is the number of tokens or records removed by kind
+type Result map[string]int64
+
+// Cleaner removes expired tokens once they have been expired for a grace period. The
+// grace period keeps recently expired tokens around, so using one still reports it as
+// expired rather than unknown and resend limits still count it.
+type Cleaner struct {
+	deleters map[string]Deleter
+	kinds    []string
+	grace    time.Duration
+	now      func() time.Time
+}
+
+// NewCleaner creates a cleaner keeping expired tokens for grace before removing them
+func NewCleaner(grace time.Duration) *Cleaner {
+	return &Cleaner{
+		deleters: make(map[string]Deleter),
+		grace:    grace,
+		now:      time.Now,
+	}
+}
+
+// Add registers the repository removing tokens of kind. Kinds are cleaned in the order
+// they were added.
+func (c *Cleaner) Add(kind string, d Deleter) {
+	if _, ok := c.deleters[kind]; !ok {
+		c.kinds = append(c.kinds, kind)
+	}
+	c.deleters[kind] = d
+}
+
+// Run cleans once and logs what was removed, for running as a scheduled job
+func (c *Cleaner) Run(ctx context.Context) error {
+	result, err := c.Clean(ctx)
+	for _, kind := range c.kinds {
+		if result[kind] > 0 {
+			log.Printf("Removed %d expired %s tokens", result[kind], kind)
+		}
+	}
+	if err != nil {
+		return fmt.Errorf("failed to clean up expired tokens: %w", err)
+	}
+	return nil
+}
+
+// Clean removes the tokens that expired more than the grace period ago. A failing kind
+// doesn't stop the others from being cleaned, the first error is returned along with
+// what was removed.
+func (c *Cleaner) Clean(ctx context.Context) (Result, error) {
+	before := c.now().Add(-c.grace)
+	result := make(Result, len(c.kinds))
+
+	var firstErr error
+	for _, kind := range c.kinds {
+		deleted, err := c.deleters[kind].DeleteExpired(ctx, before)
+		if err != nil {
+			metrics.TokenCleanupFailed(kind)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", kind, err)
+			}
+			continue
+		}
+		metrics.TokensDeleted(kind, deleted)
+		result[kind] = deleted
+	}
+	return result, firstErr
+}
@@ -0,0 +1,73 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingDeleter struct{}
+
+func (failingDeleter) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return 0, errors.New("connection lost")
+}
+
+func TestCleaner(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	roles := memory.NewRoleRepository(store)
+	refreshTokens := memory.NewRefreshTokenRepository(store)
+	passwordResets := memory.NewPasswordResetRepository(store)
+
+	role, err := roles.GetByName(ctx, "user")
+	require.NoError(t, err)
+	user := &models.User{Username: "alice", Password: "hash", RoleID: role.ID}
+	require.NoError(t, users.Create(ctx, user))
+
+	now := time.Now()
+	require.NoError(t, refreshTokens.Create(ctx, user.ID, "long expired", now.Add(-48*time.Hour), models.SessionClient{}))
+	require.NoError(t, refreshTokens.Create(ctx, user.ID, "recently expired", now.Add(-time.Hour), models.SessionClient{}))
+	require.NoError(t, refreshTokens.Create(ctx, user.ID, "valid", now.Add(time.Hour), models.SessionClient{}))
+	reset, err := passwordResets.Create(ctx, user.ID, time.Hour)
+	require.NoError(t, err)
+
+	cleaner := NewCleaner(24 * time.Hour)
+	cleaner.Add(KindRefreshToken, refreshTokens)
+	cleaner.Add(KindPasswordReset, passwordResets)
+
+	t.Run("Keeps Tokens Within Grace", func(t *testing.T) {
+		result, err := cleaner.Clean(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Result{KindRefreshToken: 1, KindPasswordReset: 0}, result)
+
+		tokens, err := refreshTokens.GetByUserID(ctx, user.ID)
+		require.NoError(t, err)
+		assert.Len(t, tokens, 2)
+	})
+
+	t.Run("Removes Tokens After Grace", func(t *testing.T) {
+		cleaner.now = func() time.Time { return now.Add(26 * time.Hour) }
+		result, err := cleaner.Clean(ctx)
+		require.NoError(t, err)
+		assert.Equal(t, Result{KindRefreshToken: 2, KindPasswordReset: 1}, result)
+
+		_, err = passwordResets.GetByToken(ctx, reset.Token)
+		assert.Error(t, err)
+	})
+
+	t.Run("Failure Doesn't Stop Other Kinds", func(t *testing.T) {
+		require.NoError(t, refreshTokens.Create(ctx, user.ID, "expired again", now.Add(-time.Hour), models.SessionClient{}))
+		cleaner.Add(KindEmailVerification, failingDeleter{})
+
+		result, err := cleaner.Clean(ctx)
+		require.ErrorContains(t, err, KindEmailVerification)
+		assert.Equal(t, int64(1), result[KindRefreshToken])
+	})
+}
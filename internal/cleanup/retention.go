@@ -0,0 +1,120 @@
+package cleanup
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+	"wattwatch/internal/metrics"
+	"wattwatch/internal/models"
+)
+
+// Kinds of records removed by Retention
+const (
+	KindAuditLog     = "audit_log"
+	KindLoginAttempt = "login_attempt"
+)
+
+// Purger removes the records of one kind created before the given time. It is implemented
+// by the audit log and login attempt repositories.
+type Purger interface {
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
+}
+
+// AuditRecorder records what a retention run removed
+type AuditRecorder interface {
+	Create(ctx context.Context, log *models.CreateAuditLogRequest) error
+}
+
+// policy keeps the records of a kind for a period
+type policy struct {
+	kind   string
+	keep   time.Duration
+	purger Purger
+}
+
+// Retention removes records once they are older than the period kept for their kind, and
+// records what it removed in the audit log
+type Retention struct {
+	policies []policy
+	audit    AuditRecorder
+	now      func() time.Time
+}
+
+// NewRetention creates a retention job recording its removals with audit
+func NewRetention(audit AuditRecorder) *Retention {
+	return &Retention{
+		audit: audit,
+		now:   time.Now,
+	}
+}
+
+// Add keeps the records of kind for keep before purger removes them. A zero period keeps
+// them forever, the kind is then left out. Kinds are purged in the order they were added.
+func (r *Retention) Add(kind string, keep time.Duration, purger Purger) {
+	if keep <= 0 {
+		return
+	}
+	r.policies = append(r.policies, policy{kind: kind, keep: keep, purger: purger})
+}
+
+// Empty reports whether no kind of record is removed
+func (r *Retention) Empty() bool {
+	return len(r.policies) == 0
+}
+
+// Run purges once, logs what was removed and records it in the audit log, for running as
+// a scheduled job
+func (r *Retention) Run(ctx context.Context) error {
+	result, err := r.Purge(ctx)
+
+	var removed []string
+	for _, p := range r.policies {
+		if result[p.kind] > 0 {
+			log.Printf("Removed %d %s records older than %s", result[p.kind], p.kind, p.keep)
+			removed = append(removed, fmt.Sprintf("%d %s", result[p.kind], p.kind))
+		}
+	}
+	if len(removed) > 0 {
+		metadata, _ := json.Marshal(result)
+		if auditErr := r.audit.Create(ctx, &models.CreateAuditLogRequest{
+			Action:      models.AuditActionDelete,
+			EntityType:  "retention",
+			EntityID:    "retention",
+			Description: "Removed old records: " + strings.Join(removed, ", "),
+			Metadata:    string(metadata),
+		}); auditErr != nil {
+			log.Printf("Error logging retention run: %v", auditErr)
+		}
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to remove old records: %w", err)
+	}
+	return nil
+}
+
+// Purge removes the records older than the period kept for their kind. A failing kind
+// doesn't stop the others from being purged, the first error is returned along with what
+// was removed.
+func (r *Retention) Purge(ctx context.Context) (Result, error) {
+	now := r.now()
+	result := make(Result, len(r.policies))
+
+	var firstErr error
+	for _, p := range r.policies {
+		deleted, err := p.purger.DeleteOlderThan(ctx, now.Add(-p.keep))
+		if err != nil {
+			metrics.RetentionFailed(p.kind)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", p.kind, err)
+			}
+			continue
+		}
+		metrics.RecordsPurged(p.kind, deleted)
+		result[p.kind] = deleted
+	}
+	return result, firstErr
+}
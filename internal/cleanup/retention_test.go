@@ -0,0 +1,83 @@
+package cleanup
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type failingPurger struct{}
+
+func (failingPurger) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	return 0, errors.New("connection lost")
+}
+
+func TestRetention(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	roles := memory.NewRoleRepository(store)
+	auditLogs := memory.NewAuditLogRepository(store)
+	loginAttempts := memory.NewLoginAttemptRepository(store)
+
+	role, err := roles.GetByName(ctx, "user")
+	require.NoError(t, err)
+	user := &models.User{Username: "alice", Password: "hash", RoleID: role.ID}
+	require.NoError(t, users.Create(ctx, user))
+
+	now := time.Now()
+	require.NoError(t, loginAttempts.Create(ctx, user.ID, false, "10.0.0.1", now.Add(-40*24*time.Hour)))
+	require.NoError(t, loginAttempts.Create(ctx, user.ID, false, "10.0.0.1", now.Add(-31*24*time.Hour)))
+	require.NoError(t, loginAttempts.Create(ctx, user.ID, false, "10.0.0.1", now.Add(-time.Minute)))
+	require.NoError(t, auditLogs.Create(ctx, &models.CreateAuditLogRequest{
+		Action: models.AuditActionLogin, EntityType: "user", EntityID: user.ID.String(), Description: "Logged in",
+	}))
+
+	t.Run("Zero Keeps Forever", func(t *testing.T) {
+		retention := NewRetention(auditLogs)
+		retention.Add(KindAuditLog, 0, auditLogs)
+		assert.True(t, retention.Empty())
+	})
+
+	t.Run("Removes Old Records And Records Them", func(t *testing.T) {
+		retention := NewRetention(auditLogs)
+		retention.Add(KindAuditLog, 90*24*time.Hour, auditLogs)
+		retention.Add(KindLoginAttempt, 30*24*time.Hour, loginAttempts)
+		require.NoError(t, retention.Run(ctx))
+
+		attempts, err := loginAttempts.ListRecentAttempts(ctx, user.ID, now.Add(-365*24*time.Hour))
+		require.NoError(t, err)
+		assert.Len(t, attempts, 1)
+
+		logs, err := auditLogs.List(ctx, repository.AuditLogFilter{EntityTypes: []string{"retention"}})
+		require.NoError(t, err)
+		require.Len(t, logs, 1)
+		assert.Equal(t, models.AuditActionDelete, logs[0].Action)
+		assert.Equal(t, "Removed old records: 2 login_attempt", logs[0].Description)
+		assert.JSONEq(t, `{"audit_log":0,"login_attempt":2}`, logs[0].Metadata)
+
+		// Nothing left to remove isn't recorded
+		require.NoError(t, retention.Run(ctx))
+		logs, err = auditLogs.List(ctx, repository.AuditLogFilter{EntityTypes: []string{"retention"}})
+		require.NoError(t, err)
+		assert.Len(t, logs, 1)
+	})
+
+	t.Run("Failure Doesn't Stop Other Kinds", func(t *testing.T) {
+		retention := NewRetention(auditLogs)
+		retention.now = func() time.Time { return now.Add(24 * time.Hour) }
+		retention.Add(KindLoginAttempt, time.Hour, failingPurger{})
+		retention.Add(KindAuditLog, time.Hour, auditLogs)
+
+		result, err := retention.Purge(ctx)
+		require.ErrorContains(t, err, KindLoginAttempt)
+		assert.Equal(t, int64(2), result[KindAuditLog])
+	})
+}
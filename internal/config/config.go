@@ -3,13 +3,20 @@ package config
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
+	"strings"
 	"time"
 	"wattwatch/internal/provider"
 
-	_ "github.com/lib/pq"
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/robfig/cron/v3"
 )
 
 // Config represents the application configuration
@@ -18,26 +25,100 @@ type Config struct {
 	API APIConfig
 	// Auth contains authentication configuration
 	Auth AuthConfig
+	// Password contains the policy new passwords must meet
+	Password PasswordConfig
+	// Captcha contains settings for requiring CAPTCHAs on registration, password resets
+	// and repeated logins
+	Captcha CaptchaConfig
 	// Database contains database configuration
 	Database DatabaseConfig
 	// Email contains email service configuration
 	Email EmailConfig
+	// Push contains push notification configuration
+	Push PushConfig
+	// Leader contains leader election configuration
+	Leader LeaderConfig
+	// Startup contains startup self-check configuration
+	Startup StartupConfig
+	// Quality contains settings for the spot price data quality check
+	Quality QualityConfig
+	// Cleanup contains settings for removing expired tokens
+	Cleanup CleanupConfig
+	// Retention contains settings for removing old audit logs and login attempts
+	Retention RetentionConfig
+	// ExchangeRates contains settings for fetching exchange rates
+	ExchangeRates ExchangeRatesConfig
+	// Budgets contains settings for the job checking users' budgets
+	Budgets BudgetsConfig
+	// Reports contains settings for the job emailing weekly reports
+	Reports ReportsConfig
+	// Web contains settings for the embedded dashboard
+	Web WebConfig
+	// Metrics contains settings for the Prometheus endpoint
+	Metrics MetricsConfig
+	// Cache contains settings for caching zones, currencies and recent spot prices
+	Cache CacheConfig
+	// Entsoe contains settings for the ENTSO-E Transparency Platform provider
+	Entsoe EntsoeConfig
+	// MQTT contains settings for publishing spot prices to an MQTT broker
+	MQTT MQTTConfig
+	// TLS contains HTTPS configuration
+	TLS TLSConfig
+	// CORS contains settings for cross-origin requests from browser dashboards
+	CORS CORSConfig
+	// SecurityHeaders contains settings for the security headers sent with every response
+	SecurityHeaders SecurityHeadersConfig
+	// IPAccess contains the client IP ranges allowed to use the API and the proxies trusted
+	// to report client IPs
+	IPAccess IPAccessConfig
 	// JWT settings
 	JWTSecret            string        `envconfig:"JWT_SECRET" required:"true"`
 	AccessTokenDuration  time.Duration `envconfig:"ACCESS_TOKEN_DURATION" default:"15m"`
 	RefreshTokenDuration time.Duration `envconfig:"REFRESH_TOKEN_DURATION" default:"168h"` // 7 days
 
 	// Rate Limiting Configuration
-	RateLimit struct {
-		Requests int `envconfig:"RATE_LIMIT_REQUESTS" default:"1000"` // Number of requests allowed per window
-		Window   int `envconfig:"RATE_LIMIT_WINDOW" default:"60"`     // Time window in seconds
-		Burst    int `envconfig:"RATE_LIMIT_BURST" default:"50"`      // Maximum burst size
-	}
+	RateLimit RateLimitConfig
 
 	DB       *sql.DB                    `json:"-"` // Connection pool, not serialized
 	Provider map[string]provider.Config `json:"providers"`
 }
 
+// RateLimitConfig contains the rate limits applied per client IP
+type RateLimitConfig struct {
+	Requests int `envconfig:"RATE_LIMIT_REQUESTS" default:"1000"` // Number of requests allowed per window
+	Window   int `envconfig:"RATE_LIMIT_WINDOW" default:"60"`     // Time window in seconds
+	Burst    int `envconfig:"RATE_LIMIT_BURST" default:"50"`      // Maximum burst size
+	// AuthRequests and AuthWindow limit the auth endpoints on top of the limit above,
+	// AuthRequests 0 disables the stricter limit
+	AuthRequests int
+	AuthWindow   int
+	// Backend keeps the request counts in "memory", per instance, or in "redis", shared
+	// by every instance using RedisURL
+	Backend  string
+	RedisURL string
+}
+
+// Rate limit backends
+const (
+	RateLimitBackendMemory = "memory"
+	RateLimitBackendRedis  = "redis"
+)
+
+// Email providers
+const (
+	EmailProviderSMTP     = "smtp"
+	EmailProviderSendGrid = "sendgrid"
+	EmailProviderMailgun  = "mailgun"
+	// EmailProviderConsole logs messages instead of sending them, for development
+	EmailProviderConsole = "console"
+)
+
+// CAPTCHA providers
+const (
+	CaptchaProviderHCaptcha  = "hcaptcha"
+	CaptchaProviderTurnstile = "turnstile"
+)
+
 // DatabaseConfig contains database connection settings
 type DatabaseConfig struct {
 	// Host is the database server hostname
@@ -54,26 +135,315 @@ type DatabaseConfig struct {
 	SSLMode string
 	// MigrationsPath is the path to database migrations
 	MigrationsPath string
+	// AutoMigrate applies pending migrations when the server starts. Deployments running
+	// them separately, with --migrate-only or the admin API, turn it off.
+	AutoMigrate bool
+	// MaxConns is the number of connections the pool opens at most
+	MaxConns int
+	// MinConns is the number of connections the pool keeps open when idle
+	MinConns int
+	// MaxConnLifetime is how long a connection is used before it is replaced
+	MaxConnLifetime time.Duration
+	// MaxConnIdleTime is how long an idle connection is kept open
+	MaxConnIdleTime time.Duration
+	// HealthCheckPeriod is how often idle connections are checked and closed when broken,
+	// too old or idle too long
+	HealthCheckPeriod time.Duration
+	// ReplicaHosts is a comma separated list of read-only replicas, as host or host:port,
+	// that listings are read from. They share the credentials, name and pool settings of
+	// the primary and use its port when none is given.
+	ReplicaHosts string
+	// ReplicaCheckInterval is how often replicas are checked, reads go to the primary
+	// while none passes
+	ReplicaCheckInterval time.Duration
+}
+
+// Replicas returns the connection settings of each replica
+func (c DatabaseConfig) Replicas() ([]DatabaseConfig, error) {
+	var replicas []DatabaseConfig
+	for _, host := range splitList(c.ReplicaHosts) {
+		replica := c
+		replica.Host, replica.ReplicaHosts = host, ""
+		if h, port, err := net.SplitHostPort(host); err == nil {
+			replica.Host = h
+			if replica.Port, err = strconv.Atoi(port); err != nil || replica.Port < 1 || replica.Port > 65535 {
+				return nil, fmt.Errorf("invalid port in replica %q", host)
+			}
+		}
+		if replica.Host == "" {
+			return nil, fmt.Errorf("invalid replica %q", host)
+		}
+		replicas = append(replicas, replica)
+	}
+	return replicas, nil
 }
 
 // APIConfig contains API server settings
 type APIConfig struct {
 	// Port is the server port to listen on
 	Port string
+	// ShutdownTimeout is how long shutdown waits for provider jobs, background workers
+	// and open requests before stopping them
+	ShutdownTimeout time.Duration
+	// Listen selects where connections are accepted: empty for the TCP port, "unix:<path>"
+	// for a Unix domain socket or "systemd" for a socket passed by systemd socket activation
+	Listen string
+	// SocketMode is the octal file mode of the Unix domain socket
+	SocketMode string
+	// ListDefaultLimit is the number of items list endpoints return when no limit is given
+	ListDefaultLimit int
+	// ListMaxLimit caps the limit clients can ask list endpoints for
+	ListMaxLimit int
+	// SpotPriceMaxRange is the longest time range spot prices are listed for at once, longer
+	// ranges are walked page by page
+	SpotPriceMaxRange time.Duration
+	// RequestTimeout is how long a request may take before it is cancelled, 0 for no limit
+	RequestTimeout time.Duration
+	// LongRequestTimeout replaces RequestTimeout for imports and other slow requests
+	LongRequestTimeout time.Duration
+	// StreamMaxClients caps the clients streaming spot prices over WebSocket at once. The
+	// price alert evaluator and the spot price webhooks take one of the slots each.
+	StreamMaxClients int
+}
+
+// ListenSystemd is the Listen value that inherits a socket from systemd
+const ListenSystemd = "systemd"
+
+// UnixSocket returns the socket path when listening on a Unix domain socket
+func (c APIConfig) UnixSocket() (string, bool) {
+	return strings.CutPrefix(c.Listen, "unix:")
+}
+
+// FileMode returns SocketMode parsed as an octal file mode
+func (c APIConfig) FileMode() (os.FileMode, error) {
+	mode, err := strconv.ParseUint(c.SocketMode, 8, 32)
+	if err != nil || mode > 0o777 {
+		return 0, fmt.Errorf("invalid octal file mode %q", c.SocketMode)
+	}
+	return os.FileMode(mode), nil
+}
+
+// LeaderConfig contains settings for electing the instance that runs scheduled jobs
+type LeaderConfig struct {
+	// Election enables leader election, required when more than one instance shares the database
+	Election bool
+	// LockID is the Postgres advisory lock key, instances competing for leadership must share it.
+	// Zero uses the built-in key.
+	LockID int
+}
+
+// StartupConfig contains settings for the dependency checks run when the server starts
+type StartupConfig struct {
+	// Strict refuses to start when a critical check fails instead of only logging it
+	Strict bool
+	// CheckTimeout bounds each check
+	CheckTimeout time.Duration
+}
+
+// QualityConfig contains settings for the job that looks for missing and duplicate spot prices
+type QualityConfig struct {
+	// Interval is how often recent days are checked, zero disables the job
+	Interval time.Duration
+	// Days is the number of days checked, ending with today
+	Days int
+	// Resolution is the time between two spot prices
+	Resolution time.Duration
+	// Refetch requests days with missing prices from a provider again
+	Refetch bool
+}
+
+// CleanupConfig contains settings for the job removing expired tokens
+type CleanupConfig struct {
+	// Schedule is the cron expression of the job, empty disables it
+	Schedule string
+	// Grace is how long tokens are kept after expiring. It should cover the email resend
+	// window, since the resend limit counts tokens that may have expired.
+	Grace time.Duration
+}
+
+// RetentionConfig contains settings for the job removing old audit logs and login attempts
+type RetentionConfig struct {
+	// AuditLogs is how long audit logs are kept, zero keeps them forever
+	AuditLogs time.Duration
+	// LoginAttempts is how long login attempts are kept, zero keeps them forever. Attempts
+	// within the lockout window are needed to lock accounts, so at least an hour is required.
+	LoginAttempts time.Duration
+	// Schedule is the cron expression of the job
+	Schedule string
+}
+
+// Enabled reports whether anything is removed by the retention job
+func (c RetentionConfig) Enabled() bool {
+	return c.AuditLogs > 0 || c.LoginAttempts > 0
 }
 
+// ExchangeRatesConfig contains settings for the job fetching the ECB reference rates
+type ExchangeRatesConfig struct {
+	// ECBSchedule is the cron expression of the job, empty disables it
+	ECBSchedule string
+}
+
+// BudgetsConfig contains settings for the job projecting the cost of the month for users'
+// budgets and notifying those whose projection exceeds it
+type BudgetsConfig struct {
+	// Schedule is the cron expression of the job, empty disables it
+	Schedule string
+}
+
+// ReportsConfig contains settings for the job emailing the users who opted in a report of
+// the consumption and cost of their meters in the past week
+type ReportsConfig struct {
+	// WeeklySchedule is the cron expression of the job, empty disables it
+	WeeklySchedule string
+}
+
+// WebConfig contains settings for the dashboard embedded in the binary
+type WebConfig struct {
+	// Enabled serves the dashboard from the root path
+	Enabled bool
+}
+
+// MetricsConfig contains settings for the Prometheus metrics endpoint
+type MetricsConfig struct {
+	// Enabled serves the metrics at /metrics
+	Enabled bool
+	// Token is the bearer token scrapers must send, empty leaves the endpoint open
+	Token string
+}
+
+// CacheConfig contains settings for caching zone, currency and recent spot price lookups
+type CacheConfig struct {
+	// Enabled caches the lookups, disabled every read goes to the database
+	Enabled bool
+	// Backend keeps entries in "memory", per instance, or in "redis", shared by every
+	// instance using RedisURL. In memory, writes only drop the entries of the instance
+	// making them, the others see the change once their entries expire.
+	Backend  string
+	RedisURL string
+	// Size is the number of entries kept in memory, the least recently used are dropped first
+	Size int
+	// TTL is how long zones and currencies are kept
+	TTL time.Duration
+	// SpotPriceTTL is how long spot prices are kept
+	SpotPriceTTL time.Duration
+}
+
+// Cache backends
+const (
+	CacheBackendMemory = "memory"
+	CacheBackendRedis  = "redis"
+)
+
+// EntsoeConfig contains settings for the ENTSO-E Transparency Platform provider. Its
+// schedule is configured with the other providers.
+type EntsoeConfig struct {
+	// Token is the API security token issued by ENTSO-E
+	Token string
+}
+
+// MQTTConfig contains settings for publishing spot prices to an MQTT broker as retained
+// messages, so devices can read them without polling the API
+type MQTTConfig struct {
+	// Broker is the URL of the broker, e.g. tcp://localhost:1883 or ssl://broker:8883.
+	// Publishing is disabled when empty.
+	Broker string
+	// ClientID identifies the connection to the broker, instances sharing a broker need
+	// different IDs. A random ID is used when empty.
+	ClientID string
+	// Username and Password authenticate with the broker
+	Username string
+	Password string
+	// TopicPrefix starts every topic published to
+	TopicPrefix string
+	// QoS is the quality of service level of published messages, 0, 1 or 2
+	QoS int
+	// TLSCAFile is a PEM file of the certificate authorities trusted for the broker, the
+	// system pool is used when empty
+	TLSCAFile string
+	// TLSCertFile and TLSKeyFile are a PEM client certificate and key for brokers that
+	// require one
+	TLSCertFile string
+	TLSKeyFile  string
+	// TLSInsecureSkipVerify accepts any broker certificate, for testing only
+	TLSInsecureSkipVerify bool
+	// MaxReconnectInterval caps the wait between connection attempts, which doubles after
+	// each failure
+	MaxReconnectInterval time.Duration
+}
+
+// Enabled reports whether spot prices are published to a broker
+func (c MQTTConfig) Enabled() bool {
+	return c.Broker != ""
+}
+
+// mqttSchemes are the broker URL schemes supported by the MQTT client
+var mqttSchemes = []string{"tcp", "mqtt", "ssl", "tls", "mqtts", "ws", "wss"}
+
 // AuthConfig contains authentication settings
 type AuthConfig struct {
 	// JWTSecret is the secret key used to sign JWT tokens
 	JWTSecret string
+	// JWTPreviousSecret is the secret JWTSecret replaced, tokens signed with it stay valid during a rotation
+	JWTPreviousSecret string
 	// JWTExpiration is the JWT token expiration time in hours
 	JWTExpiration int
 	// RegistrationOpen determines if new user registration is allowed
 	RegistrationOpen bool
+	// DefaultRole is the role given to users who register themselves
+	DefaultRole string
+	// UserCacheTTL is how long authenticated users are cached between requests, 0 disables the cache
+	UserCacheTTL time.Duration
+	// LockoutThreshold is the number of failed logins within LockoutWindow that lock an
+	// account, 0 disables lockouts
+	LockoutThreshold int
+	// LockoutWindow is how long failed logins count towards a lockout, and how long it lasts
+	LockoutWindow time.Duration
+	// DeletionGracePeriod is how long users can cancel the deletion of their own account by
+	// logging in, 0 deletes accounts right away
+	DeletionGracePeriod time.Duration
+}
+
+// PasswordConfig contains the policy new passwords must meet
+type PasswordConfig struct {
+	// MinLength is the minimum number of characters
+	MinLength int
+	// RequireUppercase requires an uppercase letter
+	RequireUppercase bool
+	// RequireLowercase requires a lowercase letter
+	RequireLowercase bool
+	// RequireDigit requires a digit
+	RequireDigit bool
+	// RequireSymbol requires a character that is no letter or digit
+	RequireSymbol bool
+	// MinStrength is the minimum zxcvbn score from 0 to 4, 0 disables the check
+	MinStrength int
+	// DisallowUserInfo rejects passwords containing the username or email address
+	DisallowUserInfo bool
+	// HistoryDepth is the number of previous passwords that can't be used again, 0 allows
+	// reusing them
+	HistoryDepth int
+}
+
+// CaptchaConfig contains settings for verifying the CAPTCHAs clients solve
+type CaptchaConfig struct {
+	// Provider verifies the CAPTCHAs: hcaptcha or turnstile, none are required when empty
+	Provider string
+	// SecretKey authenticates with the verification API of the provider
+	SecretKey string
+	// LoginFailures is the number of failed logins within the lockout window after which
+	// logging in to an account requires a CAPTCHA, 0 requires one for every login
+	LoginFailures int
+}
+
+// Enabled reports whether CAPTCHAs are verified
+func (c CaptchaConfig) Enabled() bool {
+	return c.Provider != ""
 }
 
 // EmailConfig contains email service settings
 type EmailConfig struct {
+	// Provider delivers the email: smtp, sendgrid, mailgun or console
+	Provider string
 	// SMTPHost is the SMTP server hostname
 	SMTPHost string
 	// SMTPPort is the SMTP server port
@@ -82,10 +452,222 @@ type EmailConfig struct {
 	SMTPUsername string
 	// SMTPPassword is the SMTP authentication password
 	SMTPPassword string
+	// SendGridAPIKey authenticates with the SendGrid mail send API
+	SendGridAPIKey string
+	// MailgunDomain is the sending domain registered with Mailgun
+	MailgunDomain string
+	// MailgunAPIKey authenticates with the Mailgun messages API
+	MailgunAPIKey string
+	// MailgunAPIURL is the Mailgun API of the account's region
+	MailgunAPIURL string
+	// MaxAttempts is how often a message is tried before it is moved to the dead-letter table
+	MaxAttempts int
+	// RetryBackoff is the wait before the second attempt, doubling for each further one
+	RetryBackoff time.Duration
 	// FromAddress is the email address used as sender
 	FromAddress string
 	// AppURL is the base URL of the application
 	AppURL string
+	// WebhookSecret authenticates bounce and complaint callbacks, webhooks are disabled when empty
+	WebhookSecret string
+	// WebhookPreviousSecret is the secret WebhookSecret replaced, still accepted during a rotation
+	WebhookPreviousSecret string
+	// VerificationTTL is how long email verification links stay valid
+	VerificationTTL time.Duration
+	// PasswordResetTTL is how long password reset links stay valid
+	PasswordResetTTL time.Duration
+	// InviteTTL is how long the links in invitations to imported users stay valid
+	InviteTTL time.Duration
+	// ResendLimit is the number of verification or reset emails an address may receive per ResendWindow
+	ResendLimit int
+	// ResendWindow is the period ResendLimit applies to
+	ResendWindow time.Duration
+	// EmailChangeRevertTTL is how long the previous address can undo an email change
+	EmailChangeRevertTTL time.Duration
+	// WelcomeEmail enables the onboarding email sent after registration
+	WelcomeEmail bool
+	// DocsURL links to user documentation in the welcome email, the link is left out when empty
+	DocsURL string
+	// TemplatesDir holds email templates that replace the built-in ones, laid out like
+	// internal/email/templates, e.g. sv/welcome.html.tmpl. Unused when empty.
+	TemplatesDir string
+}
+
+// PushConfig contains push notification settings
+type PushConfig struct {
+	// FCMCredentialsFile is the path to a Firebase service account key file, FCM is disabled when empty
+	FCMCredentialsFile string
+	// VAPIDPrivateKey is the base64url encoded P-256 key used to sign WebPush requests, WebPush is disabled when empty
+	VAPIDPrivateKey string
+	// VAPIDSubject is the mailto: or https: contact URI sent to push services
+	VAPIDSubject string
+	// ThrottleInterval is how often the same price or consumption alert is sent before
+	// further alerts are batched into a digest, zero disables throttling
+	ThrottleInterval time.Duration
+}
+
+// TLSConfig contains settings for serving HTTPS directly, without a reverse proxy
+type TLSConfig struct {
+	// CertFile is the path to a PEM certificate chain, HTTPS is served when set together with KeyFile
+	CertFile string
+	// KeyFile is the path to the PEM private key for CertFile
+	KeyFile string
+	// AutocertDomains is a comma separated list of domains to get Let's Encrypt certificates for
+	AutocertDomains string
+	// AutocertEmail is the contact address registered with Let's Encrypt
+	AutocertEmail string
+	// AutocertCacheDir stores issued certificates between restarts
+	AutocertCacheDir string
+	// HTTPPort serves HTTPS redirects and ACME HTTP challenges on plain HTTP, disabled when empty
+	HTTPPort string
+}
+
+// Enabled reports whether the API is served over HTTPS
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.AutocertDomains != ""
+}
+
+// Domains returns the autocert domains
+func (c TLSConfig) Domains() []string {
+	return splitList(c.AutocertDomains)
+}
+
+// CORSConfig contains settings for cross-origin requests. Lists are comma separated.
+type CORSConfig struct {
+	// AllowedOrigins lists the origins allowed to call the API from a browser, such as
+	// https://dashboard.example.com, or "*" for any origin. Cross-origin requests are
+	// refused when empty.
+	AllowedOrigins string
+	// AllowedMethods lists the methods cross-origin requests may use
+	AllowedMethods string
+	// AllowedHeaders lists the request headers cross-origin requests may send
+	AllowedHeaders string
+	// AllowCredentials lets browsers send cookies and authorization headers, it can't be
+	// combined with the "*" origin
+	AllowCredentials bool
+	// MaxAge is how long browsers may cache the answer to a preflight request
+	MaxAge time.Duration
+}
+
+// Enabled reports whether cross-origin requests are allowed
+func (c CORSConfig) Enabled() bool {
+	return c.AllowedOrigins != ""
+}
+
+// Origins returns the allowed origins
+func (c CORSConfig) Origins() []string {
+	return splitList(c.AllowedOrigins)
+}
+
+// Methods returns the allowed methods
+func (c CORSConfig) Methods() []string {
+	return splitList(c.AllowedMethods)
+}
+
+// Headers returns the allowed request headers
+func (c CORSConfig) Headers() []string {
+	return splitList(c.AllowedHeaders)
+}
+
+// SecurityHeadersConfig contains settings for the security headers of responses
+type SecurityHeadersConfig struct {
+	// Enabled sends X-Content-Type-Options, X-Frame-Options, Referrer-Policy and a
+	// Content-Security-Policy with every response
+	Enabled bool
+	// HSTSMaxAge is sent in Strict-Transport-Security with responses to HTTPS requests,
+	// 0 leaves the header out
+	HSTSMaxAge time.Duration
+}
+
+// IPAccessConfig contains settings for restricting the API to client IP ranges. Lists are
+// comma separated CIDR ranges and IP addresses, e.g. "10.0.0.0/8, 192.0.2.1".
+type IPAccessConfig struct {
+	// TrustedProxies lists the proxies and load balancers in front of the API, whose
+	// RemoteIPHeaders are trusted to hold the client IP. No proxy is trusted when empty.
+	TrustedProxies string
+	// RemoteIPHeaders is a comma separated list of the headers trusted proxies put the
+	// client IP in, tried in order
+	RemoteIPHeaders string
+	// Allow lists the clients allowed to use the API, all are allowed when empty
+	Allow string
+	// Deny lists the clients denied, even when Allow includes them
+	Deny string
+	// AdminAllow and AdminDeny restrict the admin, settings and audit log routes further
+	AdminAllow string
+	AdminDeny  string
+}
+
+// IPRanges is an allow and a deny list of client IP ranges
+type IPRanges struct {
+	Allow []netip.Prefix
+	Deny  []netip.Prefix
+}
+
+// Empty reports whether the ranges let every client through
+func (r IPRanges) Empty() bool {
+	return len(r.Allow) == 0 && len(r.Deny) == 0
+}
+
+// Global returns the ranges every request is checked against
+func (c IPAccessConfig) Global() IPRanges {
+	return IPRanges{Allow: mustParseCIDRs(c.Allow), Deny: mustParseCIDRs(c.Deny)}
+}
+
+// Admin returns the ranges requests to admin routes are checked against as well
+func (c IPAccessConfig) Admin() IPRanges {
+	return IPRanges{Allow: mustParseCIDRs(c.AdminAllow), Deny: mustParseCIDRs(c.AdminDeny)}
+}
+
+// Proxies returns the trusted proxies
+func (c IPAccessConfig) Proxies() []string {
+	return splitList(c.TrustedProxies)
+}
+
+// Headers returns the headers holding the client IP
+func (c IPAccessConfig) Headers() []string {
+	return splitList(c.RemoteIPHeaders)
+}
+
+// ParseCIDRs parses a comma separated list of CIDR ranges and IP addresses, addresses are
+// taken as ranges holding only them
+func ParseCIDRs(list string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, item := range splitList(list) {
+		if strings.Contains(item, "/") {
+			prefix, err := netip.ParsePrefix(item)
+			if err != nil {
+				return nil, fmt.Errorf("invalid CIDR range %q", item)
+			}
+			prefixes = append(prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(item)
+		if err != nil {
+			return nil, fmt.Errorf("invalid IP address %q", item)
+		}
+		prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+	}
+	return prefixes, nil
+}
+
+// mustParseCIDRs parses a list Validate accepted
+func mustParseCIDRs(list string) []netip.Prefix {
+	prefixes, err := ParseCIDRs(list)
+	if err != nil {
+		panic(err)
+	}
+	return prefixes
+}
+
+// splitList returns the trimmed, non-empty items of a comma separated list
+func splitList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // ProviderConfig represents configuration for a data provider
@@ -118,7 +700,7 @@ func Load(filename string) (*Config, error) {
 	)
 
 	// Open database connection
-	db, err := sql.Open("postgres", connStr)
+	db, err := sql.Open("pgx", connStr)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to database: %w", err)
 	}
@@ -141,76 +723,488 @@ func (c *Config) Close() error {
 	return nil
 }
 
+// Default returns the configuration used when nothing is configured. It has no JWT secret,
+// so it does not pass Validate until one is set.
+func Default() *Config {
+	c := &Config{}
+	c.setDefaults()
+	return c
+}
+
 // LoadFromEnv retrieves configuration from environment variables
 func (c *Config) LoadFromEnv() error {
-	c.API = APIConfig{
-		Port: getEnvOrDefault("API_PORT", "8080"),
-	}
-	c.Database = DatabaseConfig{
-		Host:           getEnvOrDefault("DB_HOST", "localhost"),
-		Port:           getEnvAsInt("DB_PORT", 5432),
-		User:           getEnvOrDefault("DB_USER", "postgres"),
-		Password:       getEnvOrDefault("DB_PASSWORD", "postgres"),
-		DBName:         getEnvOrDefault("DB_NAME", "wattwatch"),
-		SSLMode:        getEnvOrDefault("DB_SSL_MODE", "disable"),
-		MigrationsPath: "migrations",
-	}
-	c.Auth = AuthConfig{
-		JWTSecret:        os.Getenv("JWT_SECRET"),
-		JWTExpiration:    getEnvAsInt("JWT_EXPIRATION_HOURS", 24),
-		RegistrationOpen: getEnvAsBool("REGISTRATION_OPEN", true),
-	}
-	c.Email = EmailConfig{
-		SMTPHost:     os.Getenv("SMTP_HOST"),
-		SMTPPort:     getEnvAsInt("SMTP_PORT", 587),
-		SMTPUsername: os.Getenv("SMTP_USERNAME"),
-		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
-		FromAddress:  os.Getenv("SMTP_FROM"),
-		AppURL:       os.Getenv("APP_URL"),
-	}
-
-	// Initialize provider configuration
-	c.Provider = make(map[string]provider.Config)
-	c.Provider["nordpool"] = provider.Config{
-		Enabled: getEnvAsBool("ENABLE_NORDPOOL", false),
-	}
-
-	// Load rate limit configuration
-	c.RateLimit.Requests = getEnvAsInt("RATE_LIMIT_REQUESTS", 1000)
-	c.RateLimit.Window = getEnvAsInt("RATE_LIMIT_WINDOW", 60)
-	c.RateLimit.Burst = getEnvAsInt("RATE_LIMIT_BURST", 50)
-
-	// Validate required fields
-	if c.Auth.JWTSecret == "" {
-		return fmt.Errorf("JWT_SECRET is required")
+	return c.LoadFromFile("")
+}
+
+// ErrInvalid is returned when the configuration was loaded but has invalid settings
+var ErrInvalid = errors.New("invalid configuration")
+
+// LoadFromFile reads configuration from a YAML or TOML file, environment variables
+// override values from the file. An empty path loads from the environment only.
+func (c *Config) LoadFromFile(path string) error {
+	c.setDefaults()
+
+	if path != "" {
+		if err := c.applyFile(path); err != nil {
+			return err
+		}
 	}
 
-	return nil
+	if err := c.applyEnv(); err != nil {
+		return err
+	}
+
+	// The auth service signs tokens with the top-level secret
+	c.JWTSecret = c.Auth.JWTSecret
+
+	return c.Validate()
 }
 
-// getEnvAsInt retrieves an environment variable and converts it to an integer
-func getEnvAsInt(key string, defaultVal int) int {
-	if v := os.Getenv(key); v != "" {
-		if i, err := strconv.Atoi(v); err == nil {
-			return i
+// Validate checks the configuration and reports every invalid setting at once
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(key, env, format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf("%s: %s", settingName(key, env), fmt.Sprintf(format, args...)))
+	}
+
+	if port, err := strconv.Atoi(c.API.Port); err != nil || port < 1 || port > 65535 {
+		invalid("api.port", "API_PORT", "must be a port between 1 and 65535, got %q", c.API.Port)
+	}
+
+	if c.API.ShutdownTimeout <= 0 {
+		invalid("api.shutdown_timeout", "SHUTDOWN_TIMEOUT", "must be positive, got %s", c.API.ShutdownTimeout)
+	}
+	if path, ok := c.API.UnixSocket(); ok {
+		if path == "" {
+			invalid("api.listen", "API_LISTEN", "needs a socket path after unix:")
 		}
+		if _, err := c.API.FileMode(); err != nil {
+			invalid("api.socket_mode", "API_SOCKET_MODE", "must be an octal file mode such as 0660, got %q", c.API.SocketMode)
+		}
+	} else if c.API.Listen != "" && c.API.Listen != ListenSystemd {
+		invalid("api.listen", "API_LISTEN", "must be empty, unix:<path> or %s, got %q", ListenSystemd, c.API.Listen)
+	}
+	if c.API.ListDefaultLimit < 1 {
+		invalid("api.list_default_limit", "LIST_DEFAULT_LIMIT", "must be positive, got %d", c.API.ListDefaultLimit)
+	}
+	if c.API.SpotPriceMaxRange <= 0 {
+		invalid("api.spot_price_max_range", "SPOT_PRICE_MAX_RANGE", "must be positive, got %s", c.API.SpotPriceMaxRange)
+	}
+	if c.API.RequestTimeout < 0 {
+		invalid("api.request_timeout", "REQUEST_TIMEOUT", "must not be negative, got %s", c.API.RequestTimeout)
 	}
-	return defaultVal
+	if c.API.LongRequestTimeout < 0 {
+		invalid("api.long_request_timeout", "LONG_REQUEST_TIMEOUT", "must not be negative, got %s", c.API.LongRequestTimeout)
+	}
+	if c.API.StreamMaxClients <= 0 {
+		invalid("api.stream_max_clients", "STREAM_MAX_CLIENTS", "must be positive, got %d", c.API.StreamMaxClients)
+	}
+	if c.API.ListMaxLimit < c.API.ListDefaultLimit {
+		invalid("api.list_max_limit", "LIST_MAX_LIMIT", "must be at least api.list_default_limit (%d), got %d", c.API.ListDefaultLimit, c.API.ListMaxLimit)
+	}
+
+	if (c.TLS.CertFile == "") != (c.TLS.KeyFile == "") {
+		invalid("tls.cert_file", "TLS_CERT_FILE", "must be set together with tls.key_file (TLS_KEY_FILE)")
+	}
+	if c.TLS.CertFile != "" && c.TLS.AutocertDomains != "" {
+		invalid("tls.autocert_domains", "TLS_AUTOCERT_DOMAINS", "cannot be combined with tls.cert_file, use one or the other")
+	}
+	for _, f := range []struct{ key, env, path string }{
+		{"tls.cert_file", "TLS_CERT_FILE", c.TLS.CertFile},
+		{"tls.key_file", "TLS_KEY_FILE", c.TLS.KeyFile},
+	} {
+		if f.path == "" {
+			continue
+		}
+		if _, err := os.Stat(f.path); err != nil {
+			invalid(f.key, f.env, "cannot read %q: %v", f.path, err)
+		}
+	}
+	if c.Email.TemplatesDir != "" {
+		if info, err := os.Stat(c.Email.TemplatesDir); err != nil {
+			invalid("email.templates_dir", "EMAIL_TEMPLATES_DIR", "cannot read %q: %v", c.Email.TemplatesDir, err)
+		} else if !info.IsDir() {
+			invalid("email.templates_dir", "EMAIL_TEMPLATES_DIR", "%q is not a directory", c.Email.TemplatesDir)
+		}
+	}
+	if c.TLS.AutocertDomains != "" && c.TLS.AutocertCacheDir == "" {
+		invalid("tls.autocert_cache_dir", "TLS_AUTOCERT_CACHE_DIR", "is required when autocert is enabled")
+	}
+	if c.TLS.HTTPPort != "" {
+		if port, err := strconv.Atoi(c.TLS.HTTPPort); err != nil || port < 1 || port > 65535 {
+			invalid("tls.http_port", "TLS_HTTP_PORT", "must be a port between 1 and 65535, got %q", c.TLS.HTTPPort)
+		} else if !c.TLS.Enabled() {
+			invalid("tls.http_port", "TLS_HTTP_PORT", "requires tls.cert_file or tls.autocert_domains")
+		} else if c.TLS.HTTPPort == c.API.Port {
+			invalid("tls.http_port", "TLS_HTTP_PORT", "must differ from api.port")
+		}
+	}
+
+	for _, origin := range c.CORS.Origins() {
+		if origin == "*" {
+			if c.CORS.AllowCredentials {
+				invalid("cors.allow_credentials", "CORS_ALLOW_CREDENTIALS", "cannot be combined with the \"*\" origin, list the origins instead")
+			}
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") || u.RawQuery != "" {
+			invalid("cors.allowed_origins", "CORS_ALLOWED_ORIGINS", "must be \"*\" or origins such as https://dashboard.example.com, got %q", origin)
+		}
+	}
+	if c.CORS.Enabled() && len(c.CORS.Methods()) == 0 {
+		invalid("cors.allowed_methods", "CORS_ALLOWED_METHODS", "is required when origins are allowed")
+	}
+	if c.CORS.MaxAge < 0 {
+		invalid("cors.max_age", "CORS_MAX_AGE", "must not be negative, got %s", c.CORS.MaxAge)
+	}
+	if c.SecurityHeaders.HSTSMaxAge < 0 {
+		invalid("security_headers.hsts_max_age", "HSTS_MAX_AGE", "must not be negative, got %s", c.SecurityHeaders.HSTSMaxAge)
+	}
+
+	for _, list := range []struct{ key, env, value string }{
+		{"ip_access.trusted_proxies", "TRUSTED_PROXIES", c.IPAccess.TrustedProxies},
+		{"ip_access.allow", "IP_ALLOW", c.IPAccess.Allow},
+		{"ip_access.deny", "IP_DENY", c.IPAccess.Deny},
+		{"ip_access.admin_allow", "ADMIN_IP_ALLOW", c.IPAccess.AdminAllow},
+		{"ip_access.admin_deny", "ADMIN_IP_DENY", c.IPAccess.AdminDeny},
+	} {
+		if _, err := ParseCIDRs(list.value); err != nil {
+			invalid(list.key, list.env, "must be a comma separated list of CIDR ranges and IP addresses: %v", err)
+		}
+	}
+	if c.IPAccess.TrustedProxies != "" && len(c.IPAccess.Headers()) == 0 {
+		invalid("ip_access.remote_ip_headers", "REMOTE_IP_HEADERS", "is required when proxies are trusted")
+	}
+
+	if c.Startup.CheckTimeout <= 0 {
+		invalid("startup.check_timeout", "STARTUP_CHECK_TIMEOUT", "must be positive, got %s", c.Startup.CheckTimeout)
+	}
+
+	if c.Quality.Interval < 0 {
+		invalid("quality.interval", "QUALITY_CHECK_INTERVAL", "must not be negative, got %s", c.Quality.Interval)
+	}
+	if c.Cleanup.Schedule != "" {
+		if _, err := cron.ParseStandard(c.Cleanup.Schedule); err != nil {
+			invalid("cleanup.schedule", "TOKEN_CLEANUP_SCHEDULE", "must be a cron expression: %v", err)
+		}
+	}
+	if c.Cleanup.Grace < 0 {
+		invalid("cleanup.grace", "TOKEN_CLEANUP_GRACE", "must not be negative, got %s", c.Cleanup.Grace)
+	}
+	if c.Retention.AuditLogs < 0 {
+		invalid("retention.audit_logs", "AUDIT_LOG_RETENTION", "must not be negative, got %s", c.Retention.AuditLogs)
+	}
+	if c.Retention.LoginAttempts < 0 || (c.Retention.LoginAttempts > 0 && c.Retention.LoginAttempts < time.Hour) {
+		invalid("retention.login_attempts", "LOGIN_ATTEMPT_RETENTION", "must be 0 or at least 1h, got %s", c.Retention.LoginAttempts)
+	}
+	if _, err := cron.ParseStandard(c.Retention.Schedule); c.Retention.Enabled() && err != nil {
+		invalid("retention.schedule", "RETENTION_SCHEDULE", "must be a cron expression: %v", err)
+	}
+	if c.ExchangeRates.ECBSchedule != "" {
+		if _, err := cron.ParseStandard(c.ExchangeRates.ECBSchedule); err != nil {
+			invalid("exchange_rates.ecb_schedule", "ECB_EXCHANGE_RATE_SCHEDULE", "must be a cron expression: %v", err)
+		}
+	}
+	if c.Budgets.Schedule != "" {
+		if _, err := cron.ParseStandard(c.Budgets.Schedule); err != nil {
+			invalid("budgets.schedule", "BUDGET_CHECK_SCHEDULE", "must be a cron expression: %v", err)
+		}
+	}
+	if c.Reports.WeeklySchedule != "" {
+		if _, err := cron.ParseStandard(c.Reports.WeeklySchedule); err != nil {
+			invalid("reports.weekly_schedule", "WEEKLY_REPORT_SCHEDULE", "must be a cron expression: %v", err)
+		}
+	}
+	if c.Quality.Days < 1 {
+		invalid("quality.days", "QUALITY_CHECK_DAYS", "must be at least 1, got %d", c.Quality.Days)
+	}
+	if c.Quality.Resolution <= 0 || (24*time.Hour)%c.Quality.Resolution != 0 {
+		invalid("quality.resolution", "PRICE_RESOLUTION", "must evenly divide a day, got %s", c.Quality.Resolution)
+	}
+
+	if c.Database.Host == "" {
+		invalid("database.host", "DB_HOST", "is required")
+	}
+	if c.Database.Port < 1 || c.Database.Port > 65535 {
+		invalid("database.port", "DB_PORT", "must be between 1 and 65535, got %d", c.Database.Port)
+	}
+	if c.Database.DBName == "" {
+		invalid("database.name", "DB_NAME", "is required")
+	}
+	switch c.Database.SSLMode {
+	case "disable", "allow", "prefer", "require", "verify-ca", "verify-full":
+	default:
+		invalid("database.ssl_mode", "DB_SSL_MODE",
+			"must be one of disable, allow, prefer, require, verify-ca or verify-full, got %q", c.Database.SSLMode)
+	}
+	if c.Database.MaxConns < 1 {
+		invalid("database.max_conns", "DB_MAX_CONNS", "must be at least 1, got %d", c.Database.MaxConns)
+	}
+	if c.Database.MinConns < 0 || c.Database.MinConns > c.Database.MaxConns {
+		invalid("database.min_conns", "DB_MIN_CONNS", "must be between 0 and database.max_conns (%d), got %d", c.Database.MaxConns, c.Database.MinConns)
+	}
+	if c.Database.MaxConnLifetime <= 0 {
+		invalid("database.max_conn_lifetime", "DB_MAX_CONN_LIFETIME", "must be positive, got %s", c.Database.MaxConnLifetime)
+	}
+	if c.Database.MaxConnIdleTime <= 0 {
+		invalid("database.max_conn_idle_time", "DB_MAX_CONN_IDLE_TIME", "must be positive, got %s", c.Database.MaxConnIdleTime)
+	}
+	if c.Database.HealthCheckPeriod <= 0 {
+		invalid("database.health_check_period", "DB_HEALTH_CHECK_PERIOD", "must be positive, got %s", c.Database.HealthCheckPeriod)
+	}
+	if _, err := c.Database.Replicas(); err != nil {
+		invalid("database.replica_hosts", "DB_REPLICA_HOSTS", "%v", err)
+	}
+	if c.Database.ReplicaCheckInterval <= 0 {
+		invalid("database.replica_check_interval", "DB_REPLICA_CHECK_INTERVAL", "must be positive, got %s", c.Database.ReplicaCheckInterval)
+	}
+
+	if c.Auth.JWTSecret == "" {
+		invalid("auth.jwt_secret", "JWT_SECRET", "is required")
+	}
+	if c.Email.WebhookPreviousSecret != "" && c.Email.WebhookSecret == "" {
+		invalid("email.webhook_previous_secret", "EMAIL_WEBHOOK_PREVIOUS_SECRET", "requires email.webhook_secret")
+	}
+	if c.Auth.DefaultRole == "" {
+		invalid("auth.default_role", "DEFAULT_ROLE", "is required")
+	}
+	if c.Auth.JWTExpiration <= 0 {
+		invalid("auth.jwt_expiration_hours", "JWT_EXPIRATION_HOURS", "must be positive, got %d", c.Auth.JWTExpiration)
+	}
+	if c.Auth.UserCacheTTL < 0 {
+		invalid("auth.user_cache_ttl", "AUTH_USER_CACHE_TTL", "must not be negative, got %s", c.Auth.UserCacheTTL)
+	}
+	if c.Auth.LockoutThreshold < 0 {
+		invalid("auth.lockout_threshold", "LOCKOUT_THRESHOLD", "must not be negative, got %d", c.Auth.LockoutThreshold)
+	}
+	if c.Auth.LockoutWindow <= 0 {
+		invalid("auth.lockout_window", "LOCKOUT_WINDOW", "must be positive, got %s", c.Auth.LockoutWindow)
+	}
+	if c.Auth.DeletionGracePeriod < 0 {
+		invalid("auth.deletion_grace_period", "ACCOUNT_DELETION_GRACE_PERIOD", "must not be negative, got %s", c.Auth.DeletionGracePeriod)
+	}
+	if c.Password.HistoryDepth < 0 {
+		invalid("password.history_depth", "PASSWORD_HISTORY_DEPTH", "must not be negative, got %d", c.Password.HistoryDepth)
+	}
+	if c.Password.MinLength < 1 || c.Password.MinLength > 72 {
+		invalid("password.min_length", "PASSWORD_MIN_LENGTH", "must be between 1 and 72, got %d", c.Password.MinLength)
+	}
+	if c.Password.MinStrength < 0 || c.Password.MinStrength > 4 {
+		invalid("password.min_strength", "PASSWORD_MIN_STRENGTH", "must be between 0 and 4, got %d", c.Password.MinStrength)
+	}
+
+	switch c.Captcha.Provider {
+	case "", CaptchaProviderHCaptcha, CaptchaProviderTurnstile:
+	default:
+		invalid("captcha.provider", "CAPTCHA_PROVIDER", "must be empty, hcaptcha or turnstile, got %q", c.Captcha.Provider)
+	}
+	if c.Captcha.Enabled() && c.Captcha.SecretKey == "" {
+		invalid("captcha.secret_key", "CAPTCHA_SECRET_KEY", "is required with a captcha provider")
+	}
+	if c.Captcha.LoginFailures < 0 {
+		invalid("captcha.login_failures", "CAPTCHA_LOGIN_FAILURES", "must not be negative, got %d", c.Captcha.LoginFailures)
+	}
+
+	switch c.Email.Provider {
+	case EmailProviderSMTP, EmailProviderSendGrid, EmailProviderMailgun, EmailProviderConsole:
+	default:
+		invalid("email.provider", "EMAIL_PROVIDER", "must be smtp, sendgrid, mailgun or console, got %q", c.Email.Provider)
+	}
+	if u, err := url.Parse(c.Email.MailgunAPIURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("email.mailgun_api_url", "MAILGUN_API_URL", "must be an absolute http or https URL, got %q", c.Email.MailgunAPIURL)
+	}
+	if c.Email.MaxAttempts < 1 {
+		invalid("email.max_attempts", "EMAIL_MAX_ATTEMPTS", "must be at least 1, got %d", c.Email.MaxAttempts)
+	}
+	if c.Email.RetryBackoff < 0 {
+		invalid("email.retry_backoff", "EMAIL_RETRY_BACKOFF", "must not be negative, got %s", c.Email.RetryBackoff)
+	}
+	if c.Email.SMTPPort < 1 || c.Email.SMTPPort > 65535 {
+		invalid("email.smtp_port", "SMTP_PORT", "must be between 1 and 65535, got %d", c.Email.SMTPPort)
+	}
+	if c.Email.AppURL != "" {
+		if u, err := url.Parse(c.Email.AppURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("email.app_url", "APP_URL", "must be an absolute http or https URL, got %q", c.Email.AppURL)
+		}
+	}
+	if c.Email.VerificationTTL <= 0 {
+		invalid("email.verification_ttl", "EMAIL_VERIFICATION_TTL", "must be positive, got %s", c.Email.VerificationTTL)
+	}
+	if c.Email.PasswordResetTTL <= 0 {
+		invalid("email.password_reset_ttl", "PASSWORD_RESET_TTL", "must be positive, got %s", c.Email.PasswordResetTTL)
+	}
+	if c.Email.InviteTTL <= 0 {
+		invalid("email.invite_ttl", "EMAIL_INVITE_TTL", "must be positive, got %s", c.Email.InviteTTL)
+	}
+	if c.Email.ResendLimit < 0 {
+		invalid("email.resend_limit", "EMAIL_RESEND_LIMIT", "must not be negative, got %d", c.Email.ResendLimit)
+	}
+	if c.Email.ResendLimit > 0 && c.Email.ResendWindow <= 0 {
+		invalid("email.resend_window", "EMAIL_RESEND_WINDOW", "must be positive when a resend limit is set, got %s", c.Email.ResendWindow)
+	}
+	if c.Email.EmailChangeRevertTTL <= 0 {
+		invalid("email.email_change_revert_ttl", "EMAIL_CHANGE_REVERT_TTL", "must be positive, got %s", c.Email.EmailChangeRevertTTL)
+	}
+
+	if c.Push.ThrottleInterval < 0 {
+		invalid("push.throttle_interval", "NOTIFICATION_THROTTLE_INTERVAL", "must not be negative, got %s", c.Push.ThrottleInterval)
+	}
+
+	if c.RateLimit.Requests <= 0 {
+		invalid("rate_limit.requests", "RATE_LIMIT_REQUESTS", "must be positive, got %d", c.RateLimit.Requests)
+	}
+	if c.RateLimit.Window <= 0 {
+		invalid("rate_limit.window", "RATE_LIMIT_WINDOW", "must be positive, got %d", c.RateLimit.Window)
+	}
+	if c.RateLimit.Burst <= 0 {
+		invalid("rate_limit.burst", "RATE_LIMIT_BURST", "must be positive, got %d", c.RateLimit.Burst)
+	}
+	if c.RateLimit.AuthRequests < 0 {
+		invalid("rate_limit.auth_requests", "RATE_LIMIT_AUTH_REQUESTS", "must not be negative, got %d", c.RateLimit.AuthRequests)
+	}
+	if c.RateLimit.AuthRequests > 0 && c.RateLimit.AuthWindow <= 0 {
+		invalid("rate_limit.auth_window", "RATE_LIMIT_AUTH_WINDOW", "must be positive, got %d", c.RateLimit.AuthWindow)
+	}
+	switch c.RateLimit.Backend {
+	case RateLimitBackendMemory:
+	case RateLimitBackendRedis:
+		if c.RateLimit.RedisURL == "" {
+			invalid("rate_limit.redis_url", "RATE_LIMIT_REDIS_URL", "is required with the redis backend")
+		}
+	default:
+		invalid("rate_limit.backend", "RATE_LIMIT_BACKEND", "must be memory or redis, got %q", c.RateLimit.Backend)
+	}
+
+	if c.Cache.Enabled {
+		switch c.Cache.Backend {
+		case CacheBackendMemory:
+			if c.Cache.Size <= 0 {
+				invalid("cache.size", "CACHE_SIZE", "must be positive, got %d", c.Cache.Size)
+			}
+		case CacheBackendRedis:
+			if c.Cache.RedisURL == "" {
+				invalid("cache.redis_url", "CACHE_REDIS_URL", "is required with the redis backend")
+			}
+		default:
+			invalid("cache.backend", "CACHE_BACKEND", "must be memory or redis, got %q", c.Cache.Backend)
+		}
+		if c.Cache.TTL <= 0 {
+			invalid("cache.ttl", "CACHE_TTL", "must be positive, got %s", c.Cache.TTL)
+		}
+		if c.Cache.SpotPriceTTL <= 0 {
+			invalid("cache.spot_price_ttl", "CACHE_SPOT_PRICE_TTL", "must be positive, got %s", c.Cache.SpotPriceTTL)
+		}
+	}
+
+	if c.MQTT.Enabled() {
+		if u, err := url.Parse(c.MQTT.Broker); err != nil || !slices.Contains(mqttSchemes, u.Scheme) || u.Host == "" {
+			invalid("mqtt.broker", "MQTT_BROKER", "must be a URL such as tcp://localhost:1883 with scheme %s, got %q", strings.Join(mqttSchemes, ", "), c.MQTT.Broker)
+		}
+		if c.MQTT.TopicPrefix == "" || strings.ContainsAny(c.MQTT.TopicPrefix, "+#") {
+			invalid("mqtt.topic_prefix", "MQTT_TOPIC_PREFIX", "must be a topic without wildcards, got %q", c.MQTT.TopicPrefix)
+		}
+		if c.MQTT.QoS < 0 || c.MQTT.QoS > 2 {
+			invalid("mqtt.qos", "MQTT_QOS", "must be 0, 1 or 2, got %d", c.MQTT.QoS)
+		}
+		if (c.MQTT.TLSCertFile == "") != (c.MQTT.TLSKeyFile == "") {
+			invalid("mqtt.tls_cert_file", "MQTT_TLS_CERT_FILE", "must be set together with mqtt.tls_key_file (MQTT_TLS_KEY_FILE)")
+		}
+		for _, f := range []struct{ key, env, path string }{
+			{"mqtt.tls_ca_file", "MQTT_TLS_CA_FILE", c.MQTT.TLSCAFile},
+			{"mqtt.tls_cert_file", "MQTT_TLS_CERT_FILE", c.MQTT.TLSCertFile},
+			{"mqtt.tls_key_file", "MQTT_TLS_KEY_FILE", c.MQTT.TLSKeyFile},
+		} {
+			if f.path == "" {
+				continue
+			}
+			if _, err := os.Stat(f.path); err != nil {
+				invalid(f.key, f.env, "cannot read %q: %v", f.path, err)
+			}
+		}
+		if c.MQTT.MaxReconnectInterval <= 0 {
+			invalid("mqtt.max_reconnect_interval", "MQTT_MAX_RECONNECT_INTERVAL", "must be positive, got %s", c.MQTT.MaxReconnectInterval)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("%w: %w", ErrInvalid, errors.Join(errs...))
+	}
+	return nil
 }
 
-// getEnvAsBool retrieves an environment variable and converts it to a boolean
-func getEnvAsBool(key string, defaultVal bool) bool {
-	if v := os.Getenv(key); v != "" {
-		if b, err := strconv.ParseBool(v); err == nil {
-			return b
+// Warnings reports settings that are valid on their own but don't work together, such as
+// partially configured email. The server still starts, the features involved stay disabled.
+func (c *Config) Warnings() []string {
+	var warnings []string
+	warn := func(key, env, format string, args ...interface{}) {
+		warnings = append(warnings, fmt.Sprintf("%s: %s", settingName(key, env), fmt.Sprintf(format, args...)))
+	}
+
+	// The email service needs all of these and silently sends nothing otherwise
+	type requirement struct{ key, env, value string }
+	required := []requirement{{"email.app_url", "APP_URL", c.Email.AppURL}}
+	if c.Email.Provider != EmailProviderConsole {
+		required = append(required, requirement{"email.from_address", "SMTP_FROM", c.Email.FromAddress})
+	}
+	switch c.Email.Provider {
+	case EmailProviderSMTP:
+		required = append(required,
+			requirement{"email.smtp_host", "SMTP_HOST", c.Email.SMTPHost},
+			requirement{"email.smtp_username", "SMTP_USERNAME", c.Email.SMTPUsername},
+			requirement{"email.smtp_password", "SMTP_PASSWORD", c.Email.SMTPPassword},
+		)
+	case EmailProviderSendGrid:
+		required = append(required, requirement{"email.sendgrid_api_key", "SENDGRID_API_KEY", c.Email.SendGridAPIKey})
+	case EmailProviderMailgun:
+		required = append(required,
+			requirement{"email.mailgun_domain", "MAILGUN_DOMAIN", c.Email.MailgunDomain},
+			requirement{"email.mailgun_api_key", "MAILGUN_API_KEY", c.Email.MailgunAPIKey},
+		)
+	}
+	var configured int
+	for _, s := range required {
+		if s.value != "" {
+			configured++
 		}
 	}
-	return defaultVal
+	if configured > 0 && configured < len(required) {
+		for _, s := range required {
+			if s.value == "" {
+				warn(s.key, s.env, "is required to send email, no emails are sent until it is set")
+			}
+		}
+	}
+	if configured == 0 && c.Email.WebhookSecret != "" {
+		warn("email.webhook_secret", "EMAIL_WEBHOOK_SECRET", "has no effect while email is not configured")
+	}
+
+	if c.Push.VAPIDPrivateKey != "" && c.Push.VAPIDSubject == "" {
+		warn("push.vapid_subject", "VAPID_SUBJECT", "is required for WebPush, which stays disabled")
+	}
+	if c.TLS.AutocertDomains != "" && c.TLS.AutocertEmail == "" {
+		warn("tls.autocert_email", "TLS_AUTOCERT_EMAIL", "is recommended so Let's Encrypt can warn about certificates that fail to renew")
+	}
+	// A previous secret equal to the current one means the rotation was only half done
+	if c.Auth.JWTPreviousSecret != "" && c.Auth.JWTPreviousSecret == c.Auth.JWTSecret {
+		warn("auth.jwt_previous_secret", "JWT_PREVIOUS_SECRET", "is the same as auth.jwt_secret, set auth.jwt_secret to the new secret")
+	}
+	if c.Email.WebhookPreviousSecret != "" && c.Email.WebhookPreviousSecret == c.Email.WebhookSecret {
+		warn("email.webhook_previous_secret", "EMAIL_WEBHOOK_PREVIOUS_SECRET", "is the same as email.webhook_secret, set email.webhook_secret to the new secret")
+	}
+	if !c.MQTT.Enabled() && c.MQTT.Username != "" {
+		warn("mqtt.username", "MQTT_USERNAME", "has no effect while mqtt.broker is not set")
+	}
+	if !c.Leader.Election && c.Leader.LockID != 0 {
+		warn("leader.lock_id", "LEADER_LOCK_ID", "has no effect while leader.election is disabled")
+	}
+
+	return warnings
 }
 
-func getEnvOrDefault(key string, defaultVal string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+// settingName formats a setting for messages, with its environment variable when it has one
+func settingName(key, env string) string {
+	if env == "" {
+		return key
 	}
-	return defaultVal
+	return fmt.Sprintf("%s (%s)", key, env)
 }
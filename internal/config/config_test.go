@@ -1,7 +1,12 @@
 package config
 
 import (
+	"bytes"
+	"os"
+	"path/filepath"
 	"testing"
+	"time"
+	"wattwatch/internal/provider"
 
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/require"
@@ -32,6 +37,7 @@ func TestLoadFromEnv(t *testing.T) {
 
 	// Verify configuration values
 	require.Equal(t, "8080", cfg.API.Port)
+	require.Equal(t, 30*time.Second, cfg.API.ShutdownTimeout)
 	require.Equal(t, "localhost", cfg.Database.Host)
 	require.Equal(t, 5432, cfg.Database.Port)
 	require.Equal(t, "postgres", cfg.Database.User)
@@ -41,4 +47,448 @@ func TestLoadFromEnv(t *testing.T) {
 	require.Equal(t, "test_secret_key", cfg.Auth.JWTSecret)
 	require.Equal(t, 24, cfg.Auth.JWTExpiration)
 	require.True(t, cfg.Auth.RegistrationOpen)
+	require.Equal(t, 24*time.Hour, cfg.Email.VerificationTTL)
+	require.Equal(t, time.Hour, cfg.Email.PasswordResetTTL)
+	require.Equal(t, 3, cfg.Email.ResendLimit)
+	require.Equal(t, time.Hour, cfg.Email.ResendWindow)
+	require.Equal(t, 7*24*time.Hour, cfg.Email.EmailChangeRevertTTL)
+	require.Equal(t, 6*time.Hour, cfg.Push.ThrottleInterval)
+	require.True(t, cfg.Email.WelcomeEmail)
+}
+
+// clearSettingsEnv blanks every settings variable so values loaded from .env.test don't override the file
+func clearSettingsEnv(t *testing.T) {
+	t.Helper()
+	for _, s := range settings {
+		if s.env != "" {
+			t.Setenv(s.env, "")
+		}
+	}
+}
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+// TestLoadFromFile tests loading YAML and TOML files with environment overrides
+func TestLoadFromFile(t *testing.T) {
+	clearSettingsEnv(t)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+	}{
+		{
+			name: "yaml",
+			file: "config.yaml",
+			content: `
+api:
+  port: "9090"
+database:
+  host: db.internal
+  port: 6543
+auth:
+  jwt_secret: file-secret
+email:
+  verification_ttl: 48h
+providers:
+  nordpool:
+    enabled: true
+    zones: [SE3, SE4]
+  awattar:
+    enabled: true
+    schedule: "0 14 * * *"
+    zones: [DE]
+    options:
+      region: de
+`,
+		},
+		{
+			name: "toml",
+			file: "config.toml",
+			content: `
+[api]
+port = "9090"
+
+[database]
+host = "db.internal"
+port = 6543
+
+[auth]
+jwt_secret = "file-secret"
+
+[email]
+verification_ttl = "48h"
+
+[providers.nordpool]
+enabled = true
+zones = ["SE3", "SE4"]
+
+[providers.awattar]
+enabled = true
+schedule = "0 14 * * *"
+zones = ["DE"]
+options = { region = "de" }
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := writeConfigFile(t, tt.file, tt.content)
+
+			cfg := &Config{}
+			require.NoError(t, cfg.LoadFromFile(path))
+			require.Equal(t, "9090", cfg.API.Port)
+			require.Equal(t, "db.internal", cfg.Database.Host)
+			require.Equal(t, 6543, cfg.Database.Port)
+			require.Equal(t, "file-secret", cfg.Auth.JWTSecret)
+			require.Equal(t, "file-secret", cfg.JWTSecret)
+			require.Equal(t, 48*time.Hour, cfg.Email.VerificationTTL)
+			require.True(t, cfg.Provider["nordpool"].Enabled)
+			require.Equal(t, []string{"SE3", "SE4"}, cfg.Provider["nordpool"].SupportedZones)
+			// Providers without settings of their own are configured by their block
+			require.Equal(t, provider.Config{
+				Enabled:        true,
+				Schedule:       "0 14 * * *",
+				SupportedZones: []string{"DE"},
+				Options:        map[string]string{"region": "de"},
+			}, cfg.Provider["awattar"])
+			// Unset values keep their defaults
+			require.Equal(t, "disable", cfg.Database.SSLMode)
+			require.Equal(t, time.Hour, cfg.Email.PasswordResetTTL)
+
+			t.Setenv("DB_HOST", "db.env")
+			t.Setenv("JWT_SECRET", "env-secret")
+			require.NoError(t, cfg.LoadFromFile(path))
+			require.Equal(t, "db.env", cfg.Database.Host)
+			require.Equal(t, "env-secret", cfg.Auth.JWTSecret)
+			require.Equal(t, 6543, cfg.Database.Port)
+		})
+	}
+}
+
+// TestLoadFromFileErrors tests that invalid files and values are reported clearly
+func TestLoadFromFileErrors(t *testing.T) {
+	clearSettingsEnv(t)
+
+	tests := []struct {
+		name    string
+		file    string
+		content string
+		env     map[string]string
+		wantErr []string
+	}{
+		{
+			name:    "unsupported extension",
+			file:    "config.json",
+			content: `{}`,
+			wantErr: []string{"unsupported config file type"},
+		},
+		{
+			name:    "unknown key",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\n  jwt_secert: y\n",
+			wantErr: []string{`unknown setting "auth.jwt_secert"`},
+		},
+		{
+			name:    "unknown provider setting",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\nproviders:\n  awattar:\n    token: y\n",
+			wantErr: []string{`unknown setting "providers.awattar.token"`},
+		},
+		{
+			name:    "list of tables",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\nproviders:\n  nordpool:\n    zones:\n      - name: SE3\n",
+			wantErr: []string{"providers.nordpool.zones: lists may only hold plain values"},
+		},
+		{
+			name:    "invalid duration",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\nemail:\n  verification_ttl: tomorrow\n",
+			wantErr: []string{"email.verification_ttl", `"tomorrow"`},
+		},
+		{
+			name:    "invalid env value",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\n",
+			env:     map[string]string{"DB_PORT": "postgres"},
+			wantErr: []string{"DB_PORT", "expected a whole number"},
+		},
+		{
+			name:    "invalid replica port",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\ndatabase:\n  replica_hosts: replica-1, replica-2:primary\n",
+			wantErr: []string{"database.replica_hosts (DB_REPLICA_HOSTS)", `invalid port in replica "replica-2:primary"`},
+		},
+		{
+			name:    "certificate without key",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\ntls:\n  cert_file: /nonexistent/cert.pem\n  http_port: \"80\"\n",
+			wantErr: []string{
+				"tls.cert_file (TLS_CERT_FILE): must be set together with tls.key_file",
+				"cannot read \"/nonexistent/cert.pem\"",
+			},
+		},
+		{
+			name:    "redirect port without tls",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\ntls:\n  http_port: \"80\"\n",
+			wantErr: []string{"tls.http_port (TLS_HTTP_PORT): requires tls.cert_file or tls.autocert_domains"},
+		},
+		{
+			name:    "unknown listen mode",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\napi:\n  listen: tcp:8080\n",
+			wantErr: []string{"api.listen (API_LISTEN): must be empty, unix:<path> or systemd"},
+		},
+		{
+			name:    "invalid socket mode",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\napi:\n  listen: unix:/run/wattwatch.sock\n  socket_mode: rw-rw----\n",
+			wantErr: []string{"api.socket_mode (API_SOCKET_MODE): must be an octal file mode"},
+		},
+		{
+			name:    "previous webhook secret without a current one",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\nemail:\n  webhook_previous_secret: old\n",
+			wantErr: []string{"email.webhook_previous_secret (EMAIL_WEBHOOK_PREVIOUS_SECRET): requires email.webhook_secret"},
+		},
+		{
+			name:    "invalid mqtt settings",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\nmqtt:\n  broker: localhost:1883\n  topic_prefix: prices/#\n  qos: 3\n",
+			wantErr: []string{
+				"mqtt.broker (MQTT_BROKER): must be a URL such as tcp://localhost:1883",
+				"mqtt.topic_prefix (MQTT_TOPIC_PREFIX): must be a topic without wildcards",
+				"mqtt.qos (MQTT_QOS): must be 0, 1 or 2, got 3",
+			},
+		},
+		{
+			name:    "invalid database pool settings",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\ndatabase:\n  max_conns: 4\n  min_conns: 5\n  health_check_period: 0s\n",
+			wantErr: []string{
+				"database.min_conns (DB_MIN_CONNS): must be between 0 and database.max_conns (4), got 5",
+				"database.health_check_period (DB_HEALTH_CHECK_PERIOD): must be positive, got 0s",
+			},
+		},
+		{
+			name:    "redis rate limiting without a url",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\nrate_limit:\n  backend: redis\n",
+			wantErr: []string{"rate_limit.redis_url (RATE_LIMIT_REDIS_URL): is required with the redis backend"},
+		},
+		{
+			name:    "redis cache without a url",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\ncache:\n  backend: redis\n  spot_price_ttl: 0s\n",
+			wantErr: []string{
+				"cache.redis_url (CACHE_REDIS_URL): is required with the redis backend",
+				"cache.spot_price_ttl (CACHE_SPOT_PRICE_TTL): must be positive, got 0s",
+			},
+		},
+		{
+			name:    "login attempts kept shorter than the lockout window",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\nretention:\n  login_attempts: 10m\n",
+			wantErr: []string{"retention.login_attempts (LOGIN_ATTEMPT_RETENTION): must be 0 or at least 1h"},
+		},
+		{
+			name:    "invalid lockout and deletion settings",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\n  lockout_threshold: -1\n  deletion_grace_period: -1h\n",
+			wantErr: []string{
+				"auth.lockout_threshold (LOCKOUT_THRESHOLD): must not be negative, got -1",
+				"auth.deletion_grace_period (ACCOUNT_DELETION_GRACE_PERIOD): must not be negative, got -1h0m0s",
+			},
+		},
+		{
+			name:    "invalid cors settings",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\ncors:\n  allowed_origins: \"*, dashboard.example.com, https://example.com/app\"\n  allowed_methods: \"\"\n  allow_credentials: true\n  max_age: -1s\n",
+			wantErr: []string{
+				"cors.allow_credentials (CORS_ALLOW_CREDENTIALS): cannot be combined with the \"*\" origin, list the origins instead",
+				"cors.allowed_origins (CORS_ALLOWED_ORIGINS): must be \"*\" or origins such as https://dashboard.example.com, got \"dashboard.example.com\"",
+				"cors.allowed_origins (CORS_ALLOWED_ORIGINS): must be \"*\" or origins such as https://dashboard.example.com, got \"https://example.com/app\"",
+				"cors.allowed_methods (CORS_ALLOWED_METHODS): is required when origins are allowed",
+				"cors.max_age (CORS_MAX_AGE): must not be negative, got -1s",
+			},
+		},
+		{
+			name:    "invalid ip access lists",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\nip_access:\n  trusted_proxies: 10.0.0.1\n  remote_ip_headers: \" \"\n  admin_allow: 10.0.0.0/33\n  deny: localhost\n",
+			wantErr: []string{
+				"ip_access.deny (IP_DENY): must be a comma separated list of CIDR ranges and IP addresses: invalid IP address \"localhost\"",
+				"ip_access.admin_allow (ADMIN_IP_ALLOW): must be a comma separated list of CIDR ranges and IP addresses: invalid CIDR range \"10.0.0.0/33\"",
+				"ip_access.remote_ip_headers (REMOTE_IP_HEADERS): is required when proxies are trusted",
+			},
+		},
+		{
+			name:    "invalid captcha settings",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\ncaptcha:\n  provider: recaptcha\n  login_failures: -1\n",
+			wantErr: []string{
+				"captcha.provider (CAPTCHA_PROVIDER): must be empty, hcaptcha or turnstile, got \"recaptcha\"",
+				"captcha.secret_key (CAPTCHA_SECRET_KEY): is required with a captcha provider",
+				"captcha.login_failures (CAPTCHA_LOGIN_FAILURES): must not be negative, got -1",
+			},
+		},
+		{
+			name:    "password policy out of range",
+			file:    "config.yaml",
+			content: "auth:\n  jwt_secret: x\npassword:\n  min_length: 0\n  min_strength: 5\n",
+			wantErr: []string{
+				"password.min_length (PASSWORD_MIN_LENGTH): must be between 1 and 72, got 0",
+				"password.min_strength (PASSWORD_MIN_STRENGTH): must be between 0 and 4, got 5",
+			},
+		},
+		{
+			name:    "validation collects every error",
+			file:    "config.toml",
+			content: "[database]\nssl_mode = \"sometimes\"\n\n[email]\napp_url = \"localhost\"\n",
+			wantErr: []string{
+				"auth.jwt_secret (JWT_SECRET): is required",
+				"database.ssl_mode (DB_SSL_MODE)",
+				"email.app_url (APP_URL)",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			path := writeConfigFile(t, tt.file, tt.content)
+
+			cfg := &Config{}
+			err := cfg.LoadFromFile(path)
+			require.Error(t, err)
+			for _, want := range tt.wantErr {
+				require.Contains(t, err.Error(), want)
+			}
+		})
+	}
+}
+
+// TestReloader tests that only reloadable settings are applied on reload
+func TestReloader(t *testing.T) {
+	clearSettingsEnv(t)
+	path := writeConfigFile(t, "config.yaml", "auth:\n  jwt_secret: secret\n")
+
+	cfg := &Config{}
+	require.NoError(t, cfg.LoadFromFile(path))
+
+	reloader := NewReloader(cfg, path)
+	var hookCalls int
+	reloader.OnReload(func(cfg *Config) { hookCalls++ })
+
+	require.NoError(t, os.WriteFile(path, []byte(`
+auth:
+  jwt_secret: rotated
+database:
+  host: db.internal
+rate_limit:
+  requests: 5
+email:
+  smtp_host: smtp.internal
+providers:
+  nordpool:
+    schedule: "0 13 * * *"
+`), 0o600))
+
+	result, err := reloader.Reload()
+	require.NoError(t, err)
+	require.ElementsMatch(t, []string{"rate_limit.requests", "email.smtp_host", "providers.nordpool.schedule"}, result.Applied)
+	require.ElementsMatch(t, []string{"auth.jwt_secret", "database.host"}, result.RestartRequired)
+	require.Equal(t, 1, hookCalls)
+
+	requests, _ := cfg.RateLimitSettings()
+	require.Equal(t, 5, requests)
+	require.Equal(t, "smtp.internal", cfg.EmailSettings().SMTPHost)
+	nordpool, ok := cfg.ProviderSettings("nordpool")
+	require.True(t, ok)
+	require.Equal(t, "0 13 * * *", nordpool.Schedule)
+	// Settings that need a restart keep their running values so sessions stay valid
+	require.Equal(t, "secret", cfg.Auth.JWTSecret)
+	require.Equal(t, "localhost", cfg.Database.Host)
+
+	// An invalid file leaves the running configuration untouched
+	require.NoError(t, os.WriteFile(path, []byte("rate_limit:\n  requests: 0\n"), 0o600))
+	_, err = reloader.Reload()
+	require.Error(t, err)
+	requests, _ = cfg.RateLimitSettings()
+	require.Equal(t, 5, requests)
+	require.Equal(t, 1, hookCalls)
+}
+
+// TestWriteYAML tests that the printed configuration masks secrets and loads back unchanged
+func TestWriteYAML(t *testing.T) {
+	clearSettingsEnv(t)
+	path := writeConfigFile(t, "config.yaml", `
+api:
+  listen: unix:/run/wattwatch/api.sock
+  socket_mode: "0600"
+auth:
+  jwt_secret: "123456"
+database:
+  password: hunter2
+`)
+	cfg := &Config{}
+	require.NoError(t, cfg.LoadFromFile(path))
+
+	var redactedOut bytes.Buffer
+	require.NoError(t, cfg.WriteYAML(&redactedOut, true))
+	require.NotContains(t, redactedOut.String(), "hunter2")
+	require.NotContains(t, redactedOut.String(), "123456")
+	require.Contains(t, redactedOut.String(), `password: "********" # DB_PASSWORD`)
+
+	// The full output loads back into the same configuration
+	var out bytes.Buffer
+	require.NoError(t, cfg.WriteYAML(&out, false))
+	reloaded := &Config{}
+	require.NoError(t, reloaded.LoadFromFile(writeConfigFile(t, "printed.yaml", out.String())))
+	require.Equal(t, cfg, reloaded)
+}
+
+// TestWarnings tests that settings that don't work together are reported
+func TestWarnings(t *testing.T) {
+	cfg := &Config{}
+	cfg.setDefaults()
+	require.Empty(t, cfg.Warnings())
+
+	cfg.Email.SMTPHost = "smtp.example.com"
+	cfg.Email.SMTPUsername = "wattwatch"
+	cfg.Email.SMTPPassword = "secret"
+	cfg.Email.FromAddress = "noreply@example.com"
+	cfg.Push.VAPIDPrivateKey = "key"
+	cfg.Leader.LockID = 42
+	cfg.Auth.JWTSecret = "secret"
+	cfg.Auth.JWTPreviousSecret = "secret"
+	require.Equal(t, []string{
+		"email.app_url (APP_URL): is required to send email, no emails are sent until it is set",
+		"push.vapid_subject (VAPID_SUBJECT): is required for WebPush, which stays disabled",
+		"auth.jwt_previous_secret (JWT_PREVIOUS_SECRET): is the same as auth.jwt_secret, set auth.jwt_secret to the new secret",
+		"leader.lock_id (LEADER_LOCK_ID): has no effect while leader.election is disabled",
+	}, cfg.Warnings())
+
+	// Only the settings of the selected email provider are required
+	cfg = &Config{}
+	cfg.setDefaults()
+	cfg.Email.Provider = EmailProviderSendGrid
+	cfg.Email.SMTPHost = "smtp.example.com"
+	cfg.Email.FromAddress = "noreply@example.com"
+	cfg.Email.AppURL = "https://wattwatch.example.com"
+	require.Equal(t, []string{
+		"email.sendgrid_api_key (SENDGRID_API_KEY): is required to send email, no emails are sent until it is set",
+	}, cfg.Warnings())
+	cfg.Email.Provider = EmailProviderConsole
+	require.Empty(t, cfg.Warnings())
 }
@@ -0,0 +1,69 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// redacted replaces secret values when printing the configuration
+const redacted = "********"
+
+// WriteYAML writes the effective configuration as a config file, in the same layout
+// LoadFromFile reads. Secrets are masked when redact is set.
+func (c *Config) WriteYAML(w io.Writer, redact bool) error {
+	root := &yaml.Node{Kind: yaml.MappingNode}
+	for _, s := range settings {
+		value := s.get(c)
+		if redact && s.secret && value != "" {
+			value = redacted
+		}
+
+		parts := strings.Split(s.key, ".")
+		parent := root
+		for _, name := range parts[:len(parts)-1] {
+			parent = childMapping(parent, name)
+		}
+
+		node := &yaml.Node{Kind: yaml.ScalarNode, Value: value}
+		if !roundTrips(value) {
+			node.Style = yaml.DoubleQuotedStyle
+		}
+		if s.env != "" {
+			node.LineComment = s.env
+		}
+		parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: parts[len(parts)-1]}, node)
+	}
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(root); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// roundTrips reports whether value reads back unchanged when written unquoted. Empty
+// values and ones like "0660", which YAML reads as an octal number, need quotes.
+func roundTrips(value string) bool {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil || v == nil {
+		return false
+	}
+	_, isMap := v.(map[string]interface{})
+	return !isMap && fmt.Sprint(v) == value
+}
+
+// childMapping returns the mapping stored under name in parent, adding it when missing
+func childMapping(parent *yaml.Node, name string) *yaml.Node {
+	for i := 0; i+1 < len(parent.Content); i += 2 {
+		if parent.Content[i].Value == name {
+			return parent.Content[i+1]
+		}
+	}
+	child := &yaml.Node{Kind: yaml.MappingNode}
+	parent.Content = append(parent.Content, &yaml.Node{Kind: yaml.ScalarNode, Value: name}, child)
+	return child
+}
@@ -0,0 +1,175 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"wattwatch/internal/provider"
+)
+
+// reloadablePrefixes lists the settings that can change while the server is running.
+// Everything else, such as database and listener settings or the JWT secret, is only
+// read at startup so that a reload never drops connections or invalidates sessions.
+var reloadablePrefixes = []string{
+	"email.",
+	"password.",
+	"rate_limit.",
+	"providers.",
+	"push.throttle_interval",
+}
+
+// restartOnly lists settings under a reloadable prefix that are still only read at startup
+var restartOnly = map[string]bool{
+	"email.webhook_secret":          true,
+	"email.webhook_previous_secret": true,
+	// Zones are only read when the provider is created
+	"providers.nordpool.zones": true,
+	// The store is created at startup
+	"rate_limit.backend":   true,
+	"rate_limit.redis_url": true,
+}
+
+// liveMu guards the reloadable settings of a Config while a reload is applied
+var liveMu sync.RWMutex
+
+func isReloadable(key string) bool {
+	if restartOnly[key] {
+		return false
+	}
+	for _, prefix := range reloadablePrefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// EmailSettings returns a copy of the email configuration that is safe to use during a reload
+func (c *Config) EmailSettings() EmailConfig {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return c.Email
+}
+
+// PasswordSettings returns a copy of the password policy that is safe to use during a reload
+func (c *Config) PasswordSettings() PasswordConfig {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return c.Password
+}
+
+// RateLimitSettings returns the rate limit requests and window in seconds
+func (c *Config) RateLimitSettings() (requests, window int) {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return c.RateLimit.Requests, c.RateLimit.Window
+}
+
+// AuthRateLimitSettings returns the requests and window in seconds of the auth endpoints
+func (c *Config) AuthRateLimitSettings() (requests, window int) {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return c.RateLimit.AuthRequests, c.RateLimit.AuthWindow
+}
+
+// ProviderSettings returns the configuration of the named provider
+func (c *Config) ProviderSettings(name string) (provider.Config, bool) {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	p, ok := c.Provider[name]
+	return p, ok
+}
+
+// ProviderNames returns the names of the configured providers in alphabetical order
+func (c *Config) ProviderNames() []string {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return slices.Sorted(maps.Keys(c.Provider))
+}
+
+// EntsoeToken returns the ENTSO-E API token
+func (c *Config) EntsoeToken() string {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return c.Entsoe.Token
+}
+
+// ThrottleInterval returns how often the same notification alert may be sent
+func (c *Config) ThrottleInterval() time.Duration {
+	liveMu.RLock()
+	defer liveMu.RUnlock()
+	return c.Push.ThrottleInterval
+}
+
+// ReloadResult lists the settings that changed during a reload
+type ReloadResult struct {
+	// Applied are the settings that took effect
+	Applied []string
+	// RestartRequired are changed settings that only take effect after a restart
+	RestartRequired []string
+}
+
+// Reloader re-reads the configuration and applies the settings that are safe to change at runtime
+type Reloader struct {
+	cfg   *Config
+	path  string
+	mu    sync.Mutex
+	hooks []func(cfg *Config)
+}
+
+// NewReloader creates a reloader for cfg, which was loaded from the config file at path
+// (empty when only the environment is used)
+func NewReloader(cfg *Config, path string) *Reloader {
+	return &Reloader{cfg: cfg, path: path}
+}
+
+// OnReload registers a function that is called with the updated configuration after a
+// reload changed at least one setting
+func (r *Reloader) OnReload(fn func(cfg *Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hooks = append(r.hooks, fn)
+}
+
+// Reload loads the configuration again and applies the reloadable settings that changed.
+// The running configuration is left untouched when the new one is invalid.
+func (r *Reloader) Reload() (*ReloadResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	next := &Config{}
+	if err := next.LoadFromFile(r.path); err != nil {
+		return nil, err
+	}
+
+	result := &ReloadResult{Applied: []string{}, RestartRequired: []string{}}
+
+	liveMu.Lock()
+	for _, s := range settings {
+		value := s.get(next)
+		if s.get(r.cfg) == value {
+			continue
+		}
+		if !isReloadable(s.key) {
+			result.RestartRequired = append(result.RestartRequired, s.key)
+			continue
+		}
+		if err := s.set(r.cfg, value); err != nil {
+			liveMu.Unlock()
+			return nil, fmt.Errorf("%s: %w", s.key, err)
+		}
+		result.Applied = append(result.Applied, s.key)
+	}
+	liveMu.Unlock()
+
+	if len(result.Applied) > 0 {
+		for _, fn := range r.hooks {
+			fn(r.cfg)
+		}
+	}
+
+	return result, nil
+}
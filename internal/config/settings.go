@@ -0,0 +1,531 @@
+package config
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"wattwatch/internal/provider"
+)
+
+// setting maps a configuration field to its key in config files and its environment variable
+type setting struct {
+	// key is the dotted path of the setting in a config file, e.g. "email.smtp_host"
+	key string
+	// env is the environment variable overriding the setting, empty if it can only be set in a file
+	env string
+	set func(c *Config, value string) error
+	get func(c *Config) string
+	// secret values are masked when the configuration is printed
+	secret bool
+}
+
+// settings lists every configurable field. Values from files and the environment are
+// parsed the same way so both produce the same errors.
+var settings = []setting{
+	stringSetting("api.port", "API_PORT", func(c *Config) *string { return &c.API.Port }),
+	durationSetting("api.shutdown_timeout", "SHUTDOWN_TIMEOUT", func(c *Config) *time.Duration { return &c.API.ShutdownTimeout }),
+	stringSetting("api.listen", "API_LISTEN", func(c *Config) *string { return &c.API.Listen }),
+	stringSetting("api.socket_mode", "API_SOCKET_MODE", func(c *Config) *string { return &c.API.SocketMode }),
+	intSetting("api.list_default_limit", "LIST_DEFAULT_LIMIT", func(c *Config) *int { return &c.API.ListDefaultLimit }),
+	intSetting("api.list_max_limit", "LIST_MAX_LIMIT", func(c *Config) *int { return &c.API.ListMaxLimit }),
+	durationSetting("api.spot_price_max_range", "SPOT_PRICE_MAX_RANGE", func(c *Config) *time.Duration { return &c.API.SpotPriceMaxRange }),
+	durationSetting("api.request_timeout", "REQUEST_TIMEOUT", func(c *Config) *time.Duration { return &c.API.RequestTimeout }),
+	durationSetting("api.long_request_timeout", "LONG_REQUEST_TIMEOUT", func(c *Config) *time.Duration { return &c.API.LongRequestTimeout }),
+	intSetting("api.stream_max_clients", "STREAM_MAX_CLIENTS", func(c *Config) *int { return &c.API.StreamMaxClients }),
+
+	stringSetting("database.host", "DB_HOST", func(c *Config) *string { return &c.Database.Host }),
+	intSetting("database.port", "DB_PORT", func(c *Config) *int { return &c.Database.Port }),
+	stringSetting("database.user", "DB_USER", func(c *Config) *string { return &c.Database.User }),
+	secretSetting(stringSetting("database.password", "DB_PASSWORD", func(c *Config) *string { return &c.Database.Password })),
+	stringSetting("database.name", "DB_NAME", func(c *Config) *string { return &c.Database.DBName }),
+	stringSetting("database.ssl_mode", "DB_SSL_MODE", func(c *Config) *string { return &c.Database.SSLMode }),
+	stringSetting("database.migrations_path", "", func(c *Config) *string { return &c.Database.MigrationsPath }),
+	boolSetting("database.auto_migrate", "DB_AUTO_MIGRATE", func(c *Config) *bool { return &c.Database.AutoMigrate }),
+	intSetting("database.max_conns", "DB_MAX_CONNS", func(c *Config) *int { return &c.Database.MaxConns }),
+	intSetting("database.min_conns", "DB_MIN_CONNS", func(c *Config) *int { return &c.Database.MinConns }),
+	durationSetting("database.max_conn_lifetime", "DB_MAX_CONN_LIFETIME", func(c *Config) *time.Duration { return &c.Database.MaxConnLifetime }),
+	durationSetting("database.max_conn_idle_time", "DB_MAX_CONN_IDLE_TIME", func(c *Config) *time.Duration { return &c.Database.MaxConnIdleTime }),
+	durationSetting("database.health_check_period", "DB_HEALTH_CHECK_PERIOD", func(c *Config) *time.Duration { return &c.Database.HealthCheckPeriod }),
+	stringSetting("database.replica_hosts", "DB_REPLICA_HOSTS", func(c *Config) *string { return &c.Database.ReplicaHosts }),
+	durationSetting("database.replica_check_interval", "DB_REPLICA_CHECK_INTERVAL", func(c *Config) *time.Duration { return &c.Database.ReplicaCheckInterval }),
+
+	secretSetting(stringSetting("auth.jwt_secret", "JWT_SECRET", func(c *Config) *string { return &c.Auth.JWTSecret })),
+	secretSetting(stringSetting("auth.jwt_previous_secret", "JWT_PREVIOUS_SECRET", func(c *Config) *string { return &c.Auth.JWTPreviousSecret })),
+	intSetting("auth.jwt_expiration_hours", "JWT_EXPIRATION_HOURS", func(c *Config) *int { return &c.Auth.JWTExpiration }),
+	boolSetting("auth.registration_open", "REGISTRATION_OPEN", func(c *Config) *bool { return &c.Auth.RegistrationOpen }),
+	stringSetting("auth.default_role", "DEFAULT_ROLE", func(c *Config) *string { return &c.Auth.DefaultRole }),
+	durationSetting("auth.user_cache_ttl", "AUTH_USER_CACHE_TTL", func(c *Config) *time.Duration { return &c.Auth.UserCacheTTL }),
+	intSetting("auth.lockout_threshold", "LOCKOUT_THRESHOLD", func(c *Config) *int { return &c.Auth.LockoutThreshold }),
+	durationSetting("auth.lockout_window", "LOCKOUT_WINDOW", func(c *Config) *time.Duration { return &c.Auth.LockoutWindow }),
+	durationSetting("auth.deletion_grace_period", "ACCOUNT_DELETION_GRACE_PERIOD", func(c *Config) *time.Duration { return &c.Auth.DeletionGracePeriod }),
+
+	intSetting("password.min_length", "PASSWORD_MIN_LENGTH", func(c *Config) *int { return &c.Password.MinLength }),
+	boolSetting("password.require_uppercase", "PASSWORD_REQUIRE_UPPERCASE", func(c *Config) *bool { return &c.Password.RequireUppercase }),
+	boolSetting("password.require_lowercase", "PASSWORD_REQUIRE_LOWERCASE", func(c *Config) *bool { return &c.Password.RequireLowercase }),
+	boolSetting("password.require_digit", "PASSWORD_REQUIRE_DIGIT", func(c *Config) *bool { return &c.Password.RequireDigit }),
+	boolSetting("password.require_symbol", "PASSWORD_REQUIRE_SYMBOL", func(c *Config) *bool { return &c.Password.RequireSymbol }),
+	intSetting("password.min_strength", "PASSWORD_MIN_STRENGTH", func(c *Config) *int { return &c.Password.MinStrength }),
+	boolSetting("password.disallow_user_info", "PASSWORD_DISALLOW_USER_INFO", func(c *Config) *bool { return &c.Password.DisallowUserInfo }),
+	intSetting("password.history_depth", "PASSWORD_HISTORY_DEPTH", func(c *Config) *int { return &c.Password.HistoryDepth }),
+
+	stringSetting("captcha.provider", "CAPTCHA_PROVIDER", func(c *Config) *string { return &c.Captcha.Provider }),
+	secretSetting(stringSetting("captcha.secret_key", "CAPTCHA_SECRET_KEY", func(c *Config) *string { return &c.Captcha.SecretKey })),
+	intSetting("captcha.login_failures", "CAPTCHA_LOGIN_FAILURES", func(c *Config) *int { return &c.Captcha.LoginFailures }),
+
+	stringSetting("email.provider", "EMAIL_PROVIDER", func(c *Config) *string { return &c.Email.Provider }),
+	stringSetting("email.smtp_host", "SMTP_HOST", func(c *Config) *string { return &c.Email.SMTPHost }),
+	intSetting("email.smtp_port", "SMTP_PORT", func(c *Config) *int { return &c.Email.SMTPPort }),
+	stringSetting("email.smtp_username", "SMTP_USERNAME", func(c *Config) *string { return &c.Email.SMTPUsername }),
+	secretSetting(stringSetting("email.smtp_password", "SMTP_PASSWORD", func(c *Config) *string { return &c.Email.SMTPPassword })),
+	secretSetting(stringSetting("email.sendgrid_api_key", "SENDGRID_API_KEY", func(c *Config) *string { return &c.Email.SendGridAPIKey })),
+	stringSetting("email.mailgun_domain", "MAILGUN_DOMAIN", func(c *Config) *string { return &c.Email.MailgunDomain }),
+	secretSetting(stringSetting("email.mailgun_api_key", "MAILGUN_API_KEY", func(c *Config) *string { return &c.Email.MailgunAPIKey })),
+	stringSetting("email.mailgun_api_url", "MAILGUN_API_URL", func(c *Config) *string { return &c.Email.MailgunAPIURL }),
+	intSetting("email.max_attempts", "EMAIL_MAX_ATTEMPTS", func(c *Config) *int { return &c.Email.MaxAttempts }),
+	durationSetting("email.retry_backoff", "EMAIL_RETRY_BACKOFF", func(c *Config) *time.Duration { return &c.Email.RetryBackoff }),
+	stringSetting("email.from_address", "SMTP_FROM", func(c *Config) *string { return &c.Email.FromAddress }),
+	stringSetting("email.app_url", "APP_URL", func(c *Config) *string { return &c.Email.AppURL }),
+	secretSetting(stringSetting("email.webhook_secret", "EMAIL_WEBHOOK_SECRET", func(c *Config) *string { return &c.Email.WebhookSecret })),
+	secretSetting(stringSetting("email.webhook_previous_secret", "EMAIL_WEBHOOK_PREVIOUS_SECRET", func(c *Config) *string { return &c.Email.WebhookPreviousSecret })),
+	durationSetting("email.verification_ttl", "EMAIL_VERIFICATION_TTL", func(c *Config) *time.Duration { return &c.Email.VerificationTTL }),
+	durationSetting("email.password_reset_ttl", "PASSWORD_RESET_TTL", func(c *Config) *time.Duration { return &c.Email.PasswordResetTTL }),
+	durationSetting("email.invite_ttl", "EMAIL_INVITE_TTL", func(c *Config) *time.Duration { return &c.Email.InviteTTL }),
+	intSetting("email.resend_limit", "EMAIL_RESEND_LIMIT", func(c *Config) *int { return &c.Email.ResendLimit }),
+	durationSetting("email.resend_window", "EMAIL_RESEND_WINDOW", func(c *Config) *time.Duration { return &c.Email.ResendWindow }),
+	durationSetting("email.email_change_revert_ttl", "EMAIL_CHANGE_REVERT_TTL", func(c *Config) *time.Duration { return &c.Email.EmailChangeRevertTTL }),
+	boolSetting("email.welcome_email", "WELCOME_EMAIL_ENABLED", func(c *Config) *bool { return &c.Email.WelcomeEmail }),
+	stringSetting("email.docs_url", "DOCS_URL", func(c *Config) *string { return &c.Email.DocsURL }),
+	stringSetting("email.templates_dir", "EMAIL_TEMPLATES_DIR", func(c *Config) *string { return &c.Email.TemplatesDir }),
+
+	stringSetting("push.fcm_credentials_file", "FCM_CREDENTIALS_FILE", func(c *Config) *string { return &c.Push.FCMCredentialsFile }),
+	secretSetting(stringSetting("push.vapid_private_key", "VAPID_PRIVATE_KEY", func(c *Config) *string { return &c.Push.VAPIDPrivateKey })),
+	stringSetting("push.vapid_subject", "VAPID_SUBJECT", func(c *Config) *string { return &c.Push.VAPIDSubject }),
+	durationSetting("push.throttle_interval", "NOTIFICATION_THROTTLE_INTERVAL", func(c *Config) *time.Duration { return &c.Push.ThrottleInterval }),
+
+	boolSetting("leader.election", "LEADER_ELECTION", func(c *Config) *bool { return &c.Leader.Election }),
+	intSetting("leader.lock_id", "LEADER_LOCK_ID", func(c *Config) *int { return &c.Leader.LockID }),
+	boolSetting("startup.strict", "STARTUP_STRICT", func(c *Config) *bool { return &c.Startup.Strict }),
+	durationSetting("startup.check_timeout", "STARTUP_CHECK_TIMEOUT", func(c *Config) *time.Duration { return &c.Startup.CheckTimeout }),
+	durationSetting("quality.interval", "QUALITY_CHECK_INTERVAL", func(c *Config) *time.Duration { return &c.Quality.Interval }),
+	intSetting("quality.days", "QUALITY_CHECK_DAYS", func(c *Config) *int { return &c.Quality.Days }),
+	durationSetting("quality.resolution", "PRICE_RESOLUTION", func(c *Config) *time.Duration { return &c.Quality.Resolution }),
+	boolSetting("quality.refetch", "QUALITY_REFETCH", func(c *Config) *bool { return &c.Quality.Refetch }),
+	stringSetting("cleanup.schedule", "TOKEN_CLEANUP_SCHEDULE", func(c *Config) *string { return &c.Cleanup.Schedule }),
+	durationSetting("cleanup.grace", "TOKEN_CLEANUP_GRACE", func(c *Config) *time.Duration { return &c.Cleanup.Grace }),
+	durationSetting("retention.audit_logs", "AUDIT_LOG_RETENTION", func(c *Config) *time.Duration { return &c.Retention.AuditLogs }),
+	durationSetting("retention.login_attempts", "LOGIN_ATTEMPT_RETENTION", func(c *Config) *time.Duration { return &c.Retention.LoginAttempts }),
+	stringSetting("retention.schedule", "RETENTION_SCHEDULE", func(c *Config) *string { return &c.Retention.Schedule }),
+	stringSetting("exchange_rates.ecb_schedule", "ECB_EXCHANGE_RATE_SCHEDULE", func(c *Config) *string { return &c.ExchangeRates.ECBSchedule }),
+	stringSetting("budgets.schedule", "BUDGET_CHECK_SCHEDULE", func(c *Config) *string { return &c.Budgets.Schedule }),
+	stringSetting("reports.weekly_schedule", "WEEKLY_REPORT_SCHEDULE", func(c *Config) *string { return &c.Reports.WeeklySchedule }),
+	boolSetting("web.enabled", "WEB_UI_ENABLED", func(c *Config) *bool { return &c.Web.Enabled }),
+	boolSetting("metrics.enabled", "METRICS_ENABLED", func(c *Config) *bool { return &c.Metrics.Enabled }),
+	secretSetting(stringSetting("metrics.token", "METRICS_TOKEN", func(c *Config) *string { return &c.Metrics.Token })),
+	stringSetting("tls.cert_file", "TLS_CERT_FILE", func(c *Config) *string { return &c.TLS.CertFile }),
+	stringSetting("tls.key_file", "TLS_KEY_FILE", func(c *Config) *string { return &c.TLS.KeyFile }),
+	stringSetting("tls.autocert_domains", "TLS_AUTOCERT_DOMAINS", func(c *Config) *string { return &c.TLS.AutocertDomains }),
+	stringSetting("tls.autocert_email", "TLS_AUTOCERT_EMAIL", func(c *Config) *string { return &c.TLS.AutocertEmail }),
+	stringSetting("tls.autocert_cache_dir", "TLS_AUTOCERT_CACHE_DIR", func(c *Config) *string { return &c.TLS.AutocertCacheDir }),
+	stringSetting("tls.http_port", "TLS_HTTP_PORT", func(c *Config) *string { return &c.TLS.HTTPPort }),
+	stringSetting("cors.allowed_origins", "CORS_ALLOWED_ORIGINS", func(c *Config) *string { return &c.CORS.AllowedOrigins }),
+	stringSetting("cors.allowed_methods", "CORS_ALLOWED_METHODS", func(c *Config) *string { return &c.CORS.AllowedMethods }),
+	stringSetting("cors.allowed_headers", "CORS_ALLOWED_HEADERS", func(c *Config) *string { return &c.CORS.AllowedHeaders }),
+	boolSetting("cors.allow_credentials", "CORS_ALLOW_CREDENTIALS", func(c *Config) *bool { return &c.CORS.AllowCredentials }),
+	durationSetting("cors.max_age", "CORS_MAX_AGE", func(c *Config) *time.Duration { return &c.CORS.MaxAge }),
+	boolSetting("security_headers.enabled", "SECURITY_HEADERS_ENABLED", func(c *Config) *bool { return &c.SecurityHeaders.Enabled }),
+	durationSetting("security_headers.hsts_max_age", "HSTS_MAX_AGE", func(c *Config) *time.Duration { return &c.SecurityHeaders.HSTSMaxAge }),
+	stringSetting("ip_access.trusted_proxies", "TRUSTED_PROXIES", func(c *Config) *string { return &c.IPAccess.TrustedProxies }),
+	stringSetting("ip_access.remote_ip_headers", "REMOTE_IP_HEADERS", func(c *Config) *string { return &c.IPAccess.RemoteIPHeaders }),
+	stringSetting("ip_access.allow", "IP_ALLOW", func(c *Config) *string { return &c.IPAccess.Allow }),
+	stringSetting("ip_access.deny", "IP_DENY", func(c *Config) *string { return &c.IPAccess.Deny }),
+	stringSetting("ip_access.admin_allow", "ADMIN_IP_ALLOW", func(c *Config) *string { return &c.IPAccess.AdminAllow }),
+	stringSetting("ip_access.admin_deny", "ADMIN_IP_DENY", func(c *Config) *string { return &c.IPAccess.AdminDeny }),
+
+	providerEnabledSetting("nordpool", "ENABLE_NORDPOOL"),
+	providerScheduleSetting("nordpool", "NORDPOOL_SCHEDULE"),
+	providerZonesSetting("nordpool", "NORDPOOL_ZONES"),
+	providerZoneScheduleSetting("nordpool", "SE1", "NORDPOOL_SE1_SCHEDULE"),
+	providerZoneScheduleSetting("nordpool", "SE2", "NORDPOOL_SE2_SCHEDULE"),
+	providerZoneScheduleSetting("nordpool", "SE3", "NORDPOOL_SE3_SCHEDULE"),
+	providerZoneScheduleSetting("nordpool", "SE4", "NORDPOOL_SE4_SCHEDULE"),
+	providerEnabledSetting("entsoe", "ENABLE_ENTSOE"),
+	providerScheduleSetting("entsoe", "ENTSOE_SCHEDULE"),
+	secretSetting(stringSetting("providers.entsoe.token", "ENTSOE_TOKEN", func(c *Config) *string { return &c.Entsoe.Token })),
+	providerEnabledSetting("forecast", "ENABLE_FORECAST"),
+	providerScheduleSetting("forecast", "FORECAST_SCHEDULE"),
+	providerZonesSetting("forecast", "FORECAST_ZONES"),
+	providerOptionSetting("forecast", "url", "FORECAST_URL"),
+
+	stringSetting("mqtt.broker", "MQTT_BROKER", func(c *Config) *string { return &c.MQTT.Broker }),
+	stringSetting("mqtt.client_id", "MQTT_CLIENT_ID", func(c *Config) *string { return &c.MQTT.ClientID }),
+	stringSetting("mqtt.username", "MQTT_USERNAME", func(c *Config) *string { return &c.MQTT.Username }),
+	secretSetting(stringSetting("mqtt.password", "MQTT_PASSWORD", func(c *Config) *string { return &c.MQTT.Password })),
+	stringSetting("mqtt.topic_prefix", "MQTT_TOPIC_PREFIX", func(c *Config) *string { return &c.MQTT.TopicPrefix }),
+	intSetting("mqtt.qos", "MQTT_QOS", func(c *Config) *int { return &c.MQTT.QoS }),
+	stringSetting("mqtt.tls_ca_file", "MQTT_TLS_CA_FILE", func(c *Config) *string { return &c.MQTT.TLSCAFile }),
+	stringSetting("mqtt.tls_cert_file", "MQTT_TLS_CERT_FILE", func(c *Config) *string { return &c.MQTT.TLSCertFile }),
+	stringSetting("mqtt.tls_key_file", "MQTT_TLS_KEY_FILE", func(c *Config) *string { return &c.MQTT.TLSKeyFile }),
+	boolSetting("mqtt.tls_insecure_skip_verify", "MQTT_TLS_INSECURE_SKIP_VERIFY", func(c *Config) *bool { return &c.MQTT.TLSInsecureSkipVerify }),
+	durationSetting("mqtt.max_reconnect_interval", "MQTT_MAX_RECONNECT_INTERVAL", func(c *Config) *time.Duration { return &c.MQTT.MaxReconnectInterval }),
+
+	intSetting("rate_limit.requests", "RATE_LIMIT_REQUESTS", func(c *Config) *int { return &c.RateLimit.Requests }),
+	intSetting("rate_limit.window", "RATE_LIMIT_WINDOW", func(c *Config) *int { return &c.RateLimit.Window }),
+	intSetting("rate_limit.burst", "RATE_LIMIT_BURST", func(c *Config) *int { return &c.RateLimit.Burst }),
+	intSetting("rate_limit.auth_requests", "RATE_LIMIT_AUTH_REQUESTS", func(c *Config) *int { return &c.RateLimit.AuthRequests }),
+	intSetting("rate_limit.auth_window", "RATE_LIMIT_AUTH_WINDOW", func(c *Config) *int { return &c.RateLimit.AuthWindow }),
+	stringSetting("rate_limit.backend", "RATE_LIMIT_BACKEND", func(c *Config) *string { return &c.RateLimit.Backend }),
+	secretSetting(stringSetting("rate_limit.redis_url", "RATE_LIMIT_REDIS_URL", func(c *Config) *string { return &c.RateLimit.RedisURL })),
+
+	boolSetting("cache.enabled", "CACHE_ENABLED", func(c *Config) *bool { return &c.Cache.Enabled }),
+	stringSetting("cache.backend", "CACHE_BACKEND", func(c *Config) *string { return &c.Cache.Backend }),
+	secretSetting(stringSetting("cache.redis_url", "CACHE_REDIS_URL", func(c *Config) *string { return &c.Cache.RedisURL })),
+	intSetting("cache.size", "CACHE_SIZE", func(c *Config) *int { return &c.Cache.Size }),
+	durationSetting("cache.ttl", "CACHE_TTL", func(c *Config) *time.Duration { return &c.Cache.TTL }),
+	durationSetting("cache.spot_price_ttl", "CACHE_SPOT_PRICE_TTL", func(c *Config) *time.Duration { return &c.Cache.SpotPriceTTL }),
+}
+
+// setDefaults resets the configuration to the values used when nothing is configured
+func (c *Config) setDefaults() {
+	c.API = APIConfig{
+		Port:               "8080",
+		ShutdownTimeout:    30 * time.Second,
+		SocketMode:         "0660",
+		ListDefaultLimit:   50,
+		ListMaxLimit:       1000,
+		SpotPriceMaxRange:  366 * 24 * time.Hour,
+		RequestTimeout:     30 * time.Second,
+		LongRequestTimeout: 5 * time.Minute,
+		StreamMaxClients:   1000,
+	}
+	c.Database = DatabaseConfig{
+		Host:                 "localhost",
+		Port:                 5432,
+		User:                 "postgres",
+		Password:             "postgres",
+		DBName:               "wattwatch",
+		SSLMode:              "disable",
+		MigrationsPath:       "migrations",
+		AutoMigrate:          true,
+		MaxConns:             10,
+		MaxConnLifetime:      time.Hour,
+		MaxConnIdleTime:      30 * time.Minute,
+		HealthCheckPeriod:    time.Minute,
+		ReplicaCheckInterval: 10 * time.Second,
+	}
+	c.Auth = AuthConfig{
+		JWTExpiration:       24,
+		RegistrationOpen:    true,
+		DefaultRole:         "user",
+		UserCacheTTL:        30 * time.Second,
+		LockoutThreshold:    5,
+		LockoutWindow:       15 * time.Minute,
+		DeletionGracePeriod: 14 * 24 * time.Hour,
+	}
+	c.Password = PasswordConfig{
+		MinLength:        8,
+		DisallowUserInfo: true,
+		HistoryDepth:     5,
+	}
+	c.Captcha = CaptchaConfig{
+		LoginFailures: 3,
+	}
+	c.Email = EmailConfig{
+		Provider:             EmailProviderSMTP,
+		SMTPPort:             587,
+		MailgunAPIURL:        "https://api.mailgun.net",
+		MaxAttempts:          3,
+		RetryBackoff:         2 * time.Second,
+		VerificationTTL:      24 * time.Hour,
+		PasswordResetTTL:     time.Hour,
+		InviteTTL:            72 * time.Hour,
+		ResendLimit:          3,
+		ResendWindow:         time.Hour,
+		EmailChangeRevertTTL: 7 * 24 * time.Hour,
+		WelcomeEmail:         true,
+	}
+	c.Push = PushConfig{
+		ThrottleInterval: 6 * time.Hour,
+	}
+	c.Leader = LeaderConfig{}
+	c.Web = WebConfig{
+		Enabled: true,
+	}
+	c.Metrics = MetricsConfig{}
+	c.Startup = StartupConfig{
+		CheckTimeout: 5 * time.Second,
+	}
+	c.Quality = QualityConfig{
+		Interval:   time.Hour,
+		Days:       2,
+		Resolution: time.Hour,
+	}
+	c.Cleanup = CleanupConfig{
+		Schedule: "0 * * * *",
+		Grace:    24 * time.Hour,
+	}
+	c.Retention = RetentionConfig{
+		LoginAttempts: 30 * 24 * time.Hour,
+		Schedule:      "30 3 * * *",
+	}
+	c.Budgets = BudgetsConfig{
+		Schedule: "0 14 * * *",
+	}
+	c.Reports = ReportsConfig{
+		WeeklySchedule: "0 7 * * 1",
+	}
+	c.CORS = CORSConfig{
+		AllowedMethods: "GET,POST,PUT,PATCH,DELETE",
+		AllowedHeaders: "Authorization,Content-Type,If-None-Match,If-Modified-Since",
+		MaxAge:         10 * time.Minute,
+	}
+	c.SecurityHeaders = SecurityHeadersConfig{
+		Enabled:    true,
+		HSTSMaxAge: 365 * 24 * time.Hour,
+	}
+	c.IPAccess = IPAccessConfig{
+		RemoteIPHeaders: "X-Forwarded-For,X-Real-IP",
+	}
+	c.TLS = TLSConfig{
+		AutocertCacheDir: "autocert-cache",
+	}
+	c.MQTT = MQTTConfig{
+		TopicPrefix:          "wattwatch",
+		QoS:                  1,
+		MaxReconnectInterval: 2 * time.Minute,
+	}
+	c.Provider = map[string]provider.Config{
+		"nordpool": {Enabled: false},
+		"entsoe":   {Enabled: false},
+		"forecast": {Enabled: false},
+	}
+	c.RateLimit = RateLimitConfig{
+		Requests:     1000,
+		Window:       60,
+		Burst:        50,
+		AuthRequests: 20,
+		AuthWindow:   60,
+		Backend:      RateLimitBackendMemory,
+	}
+	c.Cache = CacheConfig{
+		Enabled:      true,
+		Backend:      CacheBackendMemory,
+		Size:         10000,
+		TTL:          5 * time.Minute,
+		SpotPriceTTL: time.Minute,
+	}
+}
+
+// applyEnv overrides settings with the environment variables that are set
+func (c *Config) applyEnv() error {
+	for _, s := range settings {
+		if s.env == "" {
+			continue
+		}
+		value, ok := os.LookupEnv(s.env)
+		if !ok || value == "" {
+			continue
+		}
+		if err := s.set(c, value); err != nil {
+			return fmt.Errorf("%s: %w", s.env, err)
+		}
+	}
+	return nil
+}
+
+// secretSetting marks s as holding a credential
+func secretSetting(s setting) setting {
+	s.secret = true
+	return s
+}
+
+func stringSetting(key, env string, field func(*Config) *string) setting {
+	return setting{key: key, env: env,
+		set: func(c *Config, value string) error {
+			*field(c) = value
+			return nil
+		},
+		get: func(c *Config) string { return *field(c) },
+	}
+}
+
+func intSetting(key, env string, field func(*Config) *int) setting {
+	return setting{key: key, env: env,
+		set: func(c *Config, value string) error {
+			i, err := strconv.Atoi(value)
+			if err != nil {
+				return fmt.Errorf("expected a whole number, got %q", value)
+			}
+			*field(c) = i
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(*field(c)) },
+	}
+}
+
+func boolSetting(key, env string, field func(*Config) *bool) setting {
+	return setting{key: key, env: env,
+		set: func(c *Config, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("expected true or false, got %q", value)
+			}
+			*field(c) = b
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatBool(*field(c)) },
+	}
+}
+
+func durationSetting(key, env string, field func(*Config) *time.Duration) setting {
+	return setting{key: key, env: env,
+		set: func(c *Config, value string) error {
+			d, err := time.ParseDuration(value)
+			if err != nil {
+				return fmt.Errorf("expected a duration such as \"30m\" or \"24h\", got %q", value)
+			}
+			*field(c) = d
+			return nil
+		},
+		get: func(c *Config) string { return field(c).String() },
+	}
+}
+
+func providerEnabledSetting(name, env string) setting {
+	return setting{key: "providers." + name + ".enabled", env: env,
+		set: func(c *Config, value string) error {
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("expected true or false, got %q", value)
+			}
+			p := c.providerConfig(name)
+			p.Enabled = b
+			c.Provider[name] = p
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatBool(c.Provider[name].Enabled) },
+	}
+}
+
+func providerScheduleSetting(name, env string) setting {
+	return setting{key: "providers." + name + ".schedule", env: env,
+		set: func(c *Config, value string) error {
+			p := c.providerConfig(name)
+			p.Schedule = value
+			c.Provider[name] = p
+			return nil
+		},
+		get: func(c *Config) string { return c.Provider[name].Schedule },
+	}
+}
+
+// providerZonesSetting sets the zones a provider fetches, given as a comma separated list.
+// Empty leaves the provider's default zones.
+func providerZonesSetting(name, env string) setting {
+	return setting{key: "providers." + name + ".zones", env: env,
+		set: func(c *Config, value string) error {
+			p := c.providerConfig(name)
+			p.SupportedZones = nil
+			for _, zone := range strings.Split(value, ",") {
+				if zone = strings.TrimSpace(zone); zone != "" {
+					p.SupportedZones = append(p.SupportedZones, zone)
+				}
+			}
+			c.Provider[name] = p
+			return nil
+		},
+		get: func(c *Config) string { return strings.Join(c.Provider[name].SupportedZones, ",") },
+	}
+}
+
+// providerZoneScheduleSetting overrides a provider's schedule for one zone. The map is
+// copied on write since configurations returned by ProviderSettings share it.
+func providerZoneScheduleSetting(name, zone, env string) setting {
+	return setting{key: "providers." + name + ".zone_schedules." + zone, env: env,
+		set: func(c *Config, value string) error {
+			p := c.providerConfig(name)
+			schedules := maps.Clone(p.ZoneSchedules)
+			if schedules == nil {
+				schedules = make(map[string]string)
+			}
+			if value == "" {
+				delete(schedules, zone)
+			} else {
+				schedules[zone] = value
+			}
+			if len(schedules) == 0 {
+				schedules = nil
+			}
+			p.ZoneSchedules = schedules
+			c.Provider[name] = p
+			return nil
+		},
+		get: func(c *Config) string { return c.Provider[name].ZoneSchedules[zone] },
+	}
+}
+
+// providerOptionSetting sets a provider specific option. The map is copied on write like
+// zone schedules, an empty value removes the option.
+func providerOptionSetting(name, option, env string) setting {
+	return setting{key: "providers." + name + ".options." + option, env: env,
+		set: func(c *Config, value string) error {
+			p := c.providerConfig(name)
+			options := maps.Clone(p.Options)
+			if options == nil {
+				options = make(map[string]string)
+			}
+			if value == "" {
+				delete(options, option)
+			} else {
+				options[option] = value
+			}
+			if len(options) == 0 {
+				options = nil
+			}
+			p.Options = options
+			c.Provider[name] = p
+			return nil
+		},
+		get: func(c *Config) string { return c.Provider[name].Options[option] },
+	}
+}
+
+// providerBlockSetting returns the setting for a key of a providers.<name> block that has
+// no setting of its own, such as the blocks of providers added to the registry or their
+// options. These are read from the config file only.
+func providerBlockSetting(key string) (setting, bool) {
+	rest, ok := strings.CutPrefix(key, "providers.")
+	if !ok {
+		return setting{}, false
+	}
+	name, field, ok := strings.Cut(rest, ".")
+	if !ok || name == "" {
+		return setting{}, false
+	}
+
+	switch field {
+	case "enabled":
+		return providerEnabledSetting(name, ""), true
+	case "schedule":
+		return providerScheduleSetting(name, ""), true
+	case "zones":
+		return providerZonesSetting(name, ""), true
+	}
+	if zone, ok := strings.CutPrefix(field, "zone_schedules."); ok && zone != "" {
+		return providerZoneScheduleSetting(name, zone, ""), true
+	}
+	if option, ok := strings.CutPrefix(field, "options."); ok && option != "" {
+		return providerOptionSetting(name, option, ""), true
+	}
+	return setting{}, false
+}
+
+// providerConfig returns the named provider's configuration, creating the map if needed
+func (c *Config) providerConfig(name string) provider.Config {
+	if c.Provider == nil {
+		c.Provider = make(map[string]provider.Config)
+	}
+	return c.Provider[name]
+}
@@ -2,37 +2,226 @@
 package database
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
+	"net"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"wattwatch/internal/config"
+	"wattwatch/internal/metrics"
 
 	"github.com/golang-migrate/migrate/v4"
 	_ "github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source"
 	_ "github.com/golang-migrate/migrate/v4/source/file"
-	_ "github.com/lib/pq"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
 )
 
-// Connect establishes a connection to the database using the provided configuration
+// Connect creates a pool of connections to the database using the provided configuration.
+// Connections are opened as they are needed, and closing the returned database closes
+// the pool.
 func Connect(cfg config.DatabaseConfig) (*sql.DB, error) {
-	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
-		cfg.Host, cfg.Port, cfg.User, cfg.Password, cfg.DBName, cfg.SSLMode)
+	db, pool, err := connect(cfg)
+	if err != nil {
+		return nil, err
+	}
+	metrics.ObservePool(pool)
+	return db, nil
+}
+
+// ConnectReplicas creates a pool of connections to each configured replica, like Connect.
+// Only the pool of the primary is reported in the metrics.
+func ConnectReplicas(cfg config.DatabaseConfig) ([]*sql.DB, error) {
+	replicas, err := cfg.Replicas()
+	if err != nil {
+		return nil, err
+	}
+	var dbs []*sql.DB
+	for _, replica := range replicas {
+		db, _, err := connect(replica)
+		if err != nil {
+			for _, opened := range dbs {
+				opened.Close()
+			}
+			return nil, fmt.Errorf("replica %s: %w", replica.Host, err)
+		}
+		dbs = append(dbs, db)
+	}
+	return dbs, nil
+}
+
+// connect creates a database on a new pool of connections
+func connect(cfg config.DatabaseConfig) (*sql.DB, *pgxpool.Pool, error) {
+	connURL := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(cfg.User, cfg.Password),
+		Host:     net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		Path:     cfg.DBName,
+		RawQuery: url.Values{"sslmode": {cfg.SSLMode}}.Encode(),
+	}
+
+	poolConfig, err := pgxpool.ParseConfig(connURL.String())
+	if err != nil {
+		return nil, nil, err
+	}
+	// Settings left at zero keep the defaults of pgxpool
+	if cfg.MaxConns > 0 {
+		poolConfig.MaxConns = int32(cfg.MaxConns)
+	}
+	poolConfig.MinConns = int32(cfg.MinConns)
+	if cfg.MaxConnLifetime > 0 {
+		poolConfig.MaxConnLifetime = cfg.MaxConnLifetime
+	}
+	if cfg.MaxConnIdleTime > 0 {
+		poolConfig.MaxConnIdleTime = cfg.MaxConnIdleTime
+	}
+	if cfg.HealthCheckPeriod > 0 {
+		poolConfig.HealthCheckPeriod = cfg.HealthCheckPeriod
+	}
 
-	return sql.Open("postgres", connStr)
+	pool, err := pgxpool.NewWithConfig(context.Background(), poolConfig)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	db := sql.OpenDB(&poolConnector{
+		Connector: metrics.InstrumentConnector(stdlib.GetPoolConnector(pool)),
+		pool:      pool,
+	})
+	// The pool keeps the idle connections
+	db.SetMaxIdleConns(0)
+	return db, pool, nil
+}
+
+// poolConnector hands out connections of pool, and closes it when the database is closed
+type poolConnector struct {
+	driver.Connector
+	pool *pgxpool.Pool
+}
+
+func (c *poolConnector) Close() error {
+	c.pool.Close()
+	return nil
 }
 
 // RunMigrations executes all pending database migrations
 func RunMigrations(cfg config.DatabaseConfig) error {
+	m, _, err := newMigrate(cfg)
+	if err != nil {
+		return err
+	}
+	defer m.Close()
+
+	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+
+	return nil
+}
+
+// Migration is a migration in the migrations directory
+type Migration struct {
+	Version uint
+	// Name is the part of the file name after the version, such as price_forecasts
+	Name string
+}
+
+// MigrationState describes the schema version of the database
+type MigrationState struct {
+	// Current is the applied version, zero when no migration has run
+	Current uint
+	// Latest is the highest version in the migrations directory
+	Latest uint
+	// Dirty is set when a migration failed halfway and needs manual repair
+	Dirty bool
+	// Available holds the migrations after Current in the order they are applied
+	Available []Migration
+}
+
+// Pending reports whether migrations remain to be applied
+func (s MigrationState) Pending() bool {
+	return s.Current < s.Latest
+}
+
+// MigrationStatus compares the applied schema version with the migrations on disk
+func MigrationStatus(cfg config.DatabaseConfig) (*MigrationState, error) {
+	m, sourceURL, err := newMigrate(cfg)
+	if err != nil {
+		return nil, err
+	}
+	defer m.Close()
+
+	state := &MigrationState{}
+	state.Current, state.Dirty, err = m.Version()
+	if err != nil && err != migrate.ErrNilVersion {
+		return nil, fmt.Errorf("failed to get migration version: %w", err)
+	}
+
+	src, err := source.Open(sourceURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open migrations: %w", err)
+	}
+	defer src.Close()
+
+	version, err := src.First()
+	for err == nil {
+		state.Latest = version
+		if version > state.Current {
+			r, name, readErr := src.ReadUp(version)
+			if readErr != nil {
+				return nil, fmt.Errorf("failed to read migration %d: %w", version, readErr)
+			}
+			r.Close()
+			state.Available = append(state.Available, Migration{Version: version, Name: name})
+		}
+		version, err = src.Next(version)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	return state, nil
+}
+
+// Migrator reports and applies the migrations of a database
+type Migrator struct {
+	cfg config.DatabaseConfig
+}
+
+// NewMigrator creates a Migrator for the configured database
+func NewMigrator(cfg config.DatabaseConfig) *Migrator {
+	return &Migrator{cfg: cfg}
+}
+
+// Status compares the applied schema version with the migrations on disk
+func (m *Migrator) Status() (*MigrationState, error) {
+	return MigrationStatus(m.cfg)
+}
+
+// Up applies the pending migrations. Instances applying them at once take turns, the
+// later ones finding nothing left to apply.
+func (m *Migrator) Up() error {
+	return RunMigrations(m.cfg)
+}
+
+// newMigrate creates a migration instance for the configured database, along with the
+// URL of the migrations source
+func newMigrate(cfg config.DatabaseConfig) (*migrate.Migrate, string, error) {
 	// Ensure we have an absolute path
 	migrationsPath, err := filepath.Abs(cfg.MigrationsPath)
 	if err != nil {
-		return fmt.Errorf("failed to get absolute migrations path: %w", err)
+		return nil, "", fmt.Errorf("failed to get absolute migrations path: %w", err)
 	}
 
 	// Check if directory exists
 	if _, err := os.Stat(migrationsPath); os.IsNotExist(err) {
-		return fmt.Errorf("migrations directory does not exist: %s", migrationsPath)
+		return nil, "", fmt.Errorf("migrations directory does not exist: %s", migrationsPath)
 	}
 
 	connectionString := fmt.Sprintf(
@@ -40,20 +229,13 @@ func RunMigrations(cfg config.DatabaseConfig) error {
 		cfg.User, cfg.Password, cfg.Host, cfg.Port, cfg.DBName, cfg.SSLMode,
 	)
 
-	m, err := migrate.New(
-		fmt.Sprintf("file://%s", migrationsPath),
-		connectionString,
-	)
+	sourceURL := fmt.Sprintf("file://%s", migrationsPath)
+	m, err := migrate.New(sourceURL, connectionString)
 	if err != nil {
-		return fmt.Errorf("failed to create migration instance: %w", err)
+		return nil, "", fmt.Errorf("failed to create migration instance: %w", err)
 	}
-	defer m.Close()
 
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to run migrations: %w", err)
-	}
-
-	return nil
+	return m, sourceURL, nil
 }
 
 func SetupDatabase(cfg config.DatabaseConfig) (*sql.DB, error) {
@@ -62,9 +244,10 @@ func SetupDatabase(cfg config.DatabaseConfig) (*sql.DB, error) {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
 
-	// Run migrations
-	if err := RunMigrations(cfg); err != nil {
-		return nil, fmt.Errorf("failed to run migrations: %w", err)
+	if cfg.AutoMigrate {
+		if err := RunMigrations(cfg); err != nil {
+			return nil, fmt.Errorf("failed to run migrations: %w", err)
+		}
 	}
 
 	return db, nil
@@ -0,0 +1,144 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wattwatch/internal/models"
+)
+
+// transcript records the SMTP dialogue of a connection. Credentials and the
+// message body are left out.
+type transcript struct {
+	mu     sync.Mutex
+	lines  []string
+	inData bool
+}
+
+func (t *transcript) add(prefix string, data []byte) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case prefix == "S" && strings.HasPrefix(line, "354"):
+			t.inData = true
+		case prefix == "C" && t.inData:
+			if line == "." {
+				t.inData = false
+				t.lines = append(t.lines, "C: <message body omitted>", "C: .")
+			}
+			continue
+		case prefix == "C" && strings.HasPrefix(strings.ToUpper(line), "AUTH "):
+			fields := strings.Fields(line)
+			line = strings.Join(fields[:min(len(fields), 2)], " ") + " <redacted>"
+		}
+		t.lines = append(t.lines, prefix+": "+line)
+	}
+}
+
+func (t *transcript) Lines() []string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]string(nil), t.lines...)
+}
+
+// recordingConn copies everything read from and written to a connection into a transcript
+type recordingConn struct {
+	net.Conn
+	t *transcript
+}
+
+func (c *recordingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	if n > 0 {
+		c.t.add("S", p[:n])
+	}
+	return n, err
+}
+
+func (c *recordingConn) Write(p []byte) (int, error) {
+	c.t.add("C", p)
+	return c.Conn.Write(p)
+}
+
+// SendTestEmail sends a diagnostic message using the current configuration. With SMTP it
+// is sent over a dedicated connection and the SMTP dialogue is returned, other providers
+// report the API they used. The returned error is also set on the result.
+func (s *Service) SendTestEmail(to string) (*models.EmailTestResult, error) {
+	cfg := s.settings()
+	result := &models.EmailTestResult{
+		Server:     s.currentTransport().Name(),
+		Transcript: []string{},
+	}
+	server, isSMTP := s.currentTransport().(*smtpTransport)
+	if isSMTP {
+		result.Server = net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+	}
+
+	start := time.Now()
+	trace := &transcript{}
+	var err error
+	if isSMTP {
+		err = s.sendTestEmail(to, server, trace)
+	} else {
+		err = s.sendTestEmail(to, nil, nil)
+	}
+	result.DurationMS = time.Since(start).Milliseconds()
+	result.Transcript = append(result.Transcript, trace.Lines()...)
+
+	if err != nil {
+		result.Error = err.Error()
+		return result, err
+	}
+	result.Success = true
+	return result, nil
+}
+
+// sendTestEmail sends the diagnostic message once, over a new connection of smtp when it is
+// set and through the current transport otherwise
+func (s *Service) sendTestEmail(to string, smtp *smtpTransport, trace *transcript) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
+	if err := s.checkSuppressed([]string{to}); err != nil {
+		return err
+	}
+
+	msg := &Message{
+		From:        cfg.FromAddress,
+		To:          []string{to},
+		Subject:     "WattWatch test email",
+		ContentType: "text/plain",
+		Body: fmt.Sprintf("This is a test email sent from %s at %s.\r\n"+
+			"If you received it, outgoing email is configured correctly.\r\n",
+			cfg.AppURL, time.Now().UTC().Format(time.RFC1123)),
+	}
+	if smtp == nil {
+		return s.currentTransport().Send(context.Background(), msg)
+	}
+
+	client, err := smtp.connect(trace)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := deliver(client, cfg.SMTPUsername, msg.To, renderMIME(msg)); err != nil {
+		return err
+	}
+
+	if err := client.Quit(); err != nil {
+		return fmt.Errorf("failed to close SMTP session: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package email
+
+import (
+	"bufio"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"wattwatch/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeSMTPServer accepts a single session and answers every command with a canned reply.
+// authReply is returned for AUTH so tests can simulate rejected credentials. Received
+// messages are sent on the returned channel.
+func fakeSMTPServer(t *testing.T, authReply string) (int, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+	messages := make(chan string, 1)
+
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		write := func(s string) { conn.Write([]byte(s + "\r\n")) }
+		write("220 localhost ESMTP test")
+
+		inData := false
+		var data strings.Builder
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if inData {
+				if line == "." {
+					inData = false
+					messages <- data.String()
+					write("250 2.0.0 queued")
+					continue
+				}
+				data.WriteString(line + "\n")
+				continue
+			}
+			switch cmd := strings.ToUpper(strings.Fields(line + " x")[0]); cmd {
+			case "EHLO":
+				write("250-localhost")
+				write("250 AUTH PLAIN")
+			case "AUTH":
+				write(authReply)
+			case "MAIL", "RCPT":
+				write("250 2.1.0 OK")
+			case "DATA":
+				inData = true
+				write("354 go ahead")
+			case "QUIT":
+				write("221 bye")
+				return
+			default:
+				write("502 unrecognized")
+			}
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port, messages
+}
+
+func testEmailConfig(port int) config.EmailConfig {
+	return config.EmailConfig{
+		SMTPHost:     "localhost",
+		SMTPPort:     port,
+		SMTPUsername: "mailer",
+		SMTPPassword: "hunter2",
+		FromAddress:  "noreply@example.com",
+		AppURL:       "http://localhost:8080",
+	}
+}
+
+func TestSendTestEmail(t *testing.T) {
+	port, _ := fakeSMTPServer(t, "235 2.7.0 accepted")
+	service := NewService(testEmailConfig(port))
+
+	result, err := service.SendTestEmail("admin@example.com")
+	require.NoError(t, err)
+	assert.True(t, result.Success)
+	assert.Equal(t, "localhost:"+strconv.Itoa(port), result.Server)
+	assert.Contains(t, result.Transcript, "C: RCPT TO:<admin@example.com>")
+	assert.Contains(t, result.Transcript, "C: <message body omitted>")
+	assert.Contains(t, result.Transcript, "S: 250 2.0.0 queued")
+	assert.Contains(t, result.Transcript, "C: AUTH PLAIN <redacted>")
+	for _, line := range result.Transcript {
+		assert.NotContains(t, line, "test email sent from")
+	}
+}
+
+func TestSendTestEmail_AuthFailure(t *testing.T) {
+	port, _ := fakeSMTPServer(t, "535 5.7.8 authentication failed")
+	service := NewService(testEmailConfig(port))
+
+	result, err := service.SendTestEmail("admin@example.com")
+	require.Error(t, err)
+	assert.False(t, result.Success)
+	assert.Contains(t, result.Error, "failed to authenticate")
+	assert.Contains(t, result.Transcript, "S: 535 5.7.8 authentication failed")
+}
+
+func TestSendTestEmail_IncompleteConfig(t *testing.T) {
+	service := NewService(config.EmailConfig{})
+
+	result, err := service.SendTestEmail("admin@example.com")
+	require.Error(t, err)
+	assert.Equal(t, "incomplete email configuration", result.Error)
+	assert.Empty(t, result.Transcript)
+}
@@ -0,0 +1,100 @@
+package email
+
+import (
+	"fmt"
+	"time"
+)
+
+// WeeklyReport summarizes one home's consumption and cost for a week
+type WeeklyReport struct {
+	HomeName  string
+	WeekStart time.Time
+	Currency  string
+	// ConsumptionKWh and Cost are totals for the week
+	ConsumptionKWh float64
+	Cost           float64
+	// PreviousConsumptionKWh and PreviousCost are totals for the week before, nil when there is no data
+	PreviousConsumptionKWh *float64
+	PreviousCost           *float64
+	// MostExpensiveDay is the day with the highest cost and MostExpensiveDayCost its total,
+	// the line is left out when MostExpensiveDay is zero
+	MostExpensiveDay     time.Time
+	MostExpensiveDayCost float64
+}
+
+// renderWeeklyReport renders a weekly report covering all of a user's homes
+func renderWeeklyReport(templates templateSet, language, username string, reports []WeeklyReport) (*renderedEmail, error) {
+	type home struct {
+		Name                 string
+		Consumption          string
+		ConsumptionChange    string
+		Cost                 string
+		CostChange           string
+		MostExpensiveDay     string
+		MostExpensiveDayCost string
+	}
+
+	data := struct {
+		Username string
+		Week     string
+		Homes    []home
+	}{Username: username}
+
+	loc := localeFor(language)
+	for _, r := range reports {
+		if data.Week == "" {
+			data.Week = loc.formatDate(r.WeekStart)
+		}
+		h := home{
+			Name:              r.HomeName,
+			Consumption:       loc.formatNumber(r.ConsumptionKWh, 1) + " kWh",
+			ConsumptionChange: percentChange(r.ConsumptionKWh, r.PreviousConsumptionKWh),
+			Cost:              loc.formatNumber(r.Cost, 2) + " " + r.Currency,
+			CostChange:        percentChange(r.Cost, r.PreviousCost),
+		}
+		if !r.MostExpensiveDay.IsZero() {
+			h.MostExpensiveDay = loc.formatDay(r.MostExpensiveDay)
+			h.MostExpensiveDayCost = loc.formatNumber(r.MostExpensiveDayCost, 2) + " " + r.Currency
+		}
+		data.Homes = append(data.Homes, h)
+	}
+
+	return templates.render(language, KindWeeklyReport, data)
+}
+
+// percentChange formats the change from previous to current, or "" when there is nothing to compare with
+func percentChange(current float64, previous *float64) string {
+	if previous == nil || *previous == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%+.0f%%", (current-*previous) / *previous * 100)
+}
+
+// SendWeeklyReport emails a user the weekly summary of their homes
+func (s *Service) SendWeeklyReport(to, username, language string, reports []WeeklyReport) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
+	if len(reports) == 0 {
+		return nil
+	}
+
+	content, err := renderWeeklyReport(templateSet{dir: cfg.TemplatesDir}, language, username, reports)
+	if err != nil {
+		return err
+	}
+
+	msg := &Message{
+		From:        cfg.FromAddress,
+		To:          []string{to},
+		Subject:     content.Subject,
+		ContentType: "text/html",
+		Body:        content.HTML,
+		TextBody:    content.Text,
+	}
+	if err := s.send(KindWeeklyReport, msg); err != nil {
+		return fmt.Errorf("failed to send weekly report email: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,55 @@
+package email
+
+import (
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderWeeklyReport(t *testing.T) {
+	prevConsumption, prevCost := 100.0, 40.0
+	reports := []WeeklyReport{
+		{
+			HomeName:               "Cottage",
+			WeekStart:              time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+			Currency:               "SEK",
+			ConsumptionKWh:         120,
+			Cost:                   30,
+			PreviousConsumptionKWh: &prevConsumption,
+			PreviousCost:           &prevCost,
+			MostExpensiveDay:       time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+			MostExpensiveDayCost:   8.5,
+		},
+		{
+			HomeName:       "Flat",
+			WeekStart:      time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC),
+			Currency:       "SEK",
+			ConsumptionKWh: 42.25,
+			Cost:           12,
+		},
+	}
+
+	content, err := renderWeeklyReport(templateSet{}, models.LanguageEnglish, "jane", reports)
+	require.NoError(t, err)
+	body := content.HTML
+
+	assert.Equal(t, "Your weekly energy report", content.Subject)
+	assert.Contains(t, body, "week of 4 Mar 2024")
+	assert.Contains(t, body, "Consumption: 120.0 kWh (&#43;20% vs previous week)")
+	assert.Contains(t, body, "Cost: 30.00 SEK (-25% vs previous week)")
+	assert.Contains(t, body, "Most expensive day: Wednesday 6 Mar (8.50 SEK)")
+	assert.Contains(t, body, "Consumption: 42.2 kWh</li>")
+	assert.NotContains(t, body, "Monday 1 Jan")
+	assert.Contains(t, content.Text, "- Cost: 30.00 SEK (-25% vs previous week)\n- Most expensive day: Wednesday 6 Mar (8.50 SEK)\n")
+	assert.Contains(t, content.Text, "- Consumption: 42.2 kWh\n- Cost: 12.00 SEK\n")
+
+	content, err = renderWeeklyReport(templateSet{}, models.LanguageSwedish, "jane", reports)
+	require.NoError(t, err)
+	assert.Equal(t, "Din veckorapport om energi", content.Subject)
+	assert.Contains(t, content.HTML, "veckan som började 4 mars 2024")
+	assert.Contains(t, content.HTML, "Förbrukning: 120,0 kWh (&#43;20% jämfört med föregående vecka)")
+	assert.Contains(t, content.HTML, "Dyraste dagen: onsdag 6 mars (8,50 SEK)")
+}
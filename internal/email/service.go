@@ -1,197 +1,462 @@
 package email
 
 import (
-	"bytes"
+	"context"
+	"errors"
 	"fmt"
-	"html/template"
+	"io"
 	"log"
-	"net/smtp"
+	"strings"
 	"sync"
+	"time"
 	"wattwatch/internal/config"
+	"wattwatch/internal/models"
 )
 
-// EmailSender defines the interface for sending emails
+// EmailSender defines the interface for sending emails. Emails are written in the given
+// language, one of the models.Language constants.
 type EmailSender interface {
-	SendVerificationEmail(to, username, token string) error
-	SendPasswordResetEmail(to, username, token string) error
+	SendVerificationEmail(to, username, language, token string, expiresAt time.Time) error
+	SendPasswordResetEmail(to, username, language, token string, expiresAt time.Time) error
+	SendEmailChangedNotification(to, username, language, newEmail, revertToken string, expiresAt time.Time) error
+	SendWelcomeEmail(to, username, language string) error
+	SendInviteEmail(to, username, language, token string, expiresAt time.Time) error
+	SendAccountDeletionEmail(to, username, language string, deleteAt time.Time) error
+	SendNewDeviceLoginEmail(to, username, language, ipAddress, userAgent string, at time.Time) error
+}
+
+var (
+	// ErrRecipientSuppressed is returned when a recipient previously bounced or complained
+	ErrRecipientSuppressed = errors.New("recipient address is suppressed")
+	// ErrNotConfigured is returned when settings the email provider needs are missing
+	ErrNotConfigured = errors.New("incomplete email configuration")
+)
+
+// Kinds of email, recorded with dead letters
+const (
+	KindVerification    = "verification"
+	KindPasswordReset   = "password_reset"
+	KindEmailChanged    = "email_changed"
+	KindWelcome         = "welcome"
+	KindInvite          = "invite"
+	KindAccountDeletion = "account_deletion"
+	KindNewDeviceLogin  = "new_device_login"
+	KindWeeklyReport    = "weekly_report"
+	KindAlert           = "alert"
+)
+
+// SuppressionChecker reports whether an address must not receive email
+type SuppressionChecker interface {
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+}
+
+// DeadLetterRecorder keeps messages that failed every delivery attempt
+type DeadLetterRecorder interface {
+	Create(ctx context.Context, letter *models.EmailDeadLetter) error
 }
 
 // Service implements the EmailSender interface
 type Service struct {
-	config config.EmailConfig
-	client *smtp.Client
-	mu     sync.Mutex
+	config       config.EmailConfig
+	transport    Transport
+	configMu     sync.RWMutex
+	suppressions SuppressionChecker
+	deadLetters  DeadLetterRecorder
 }
 
+// NewService creates an email service sending through the configured provider
 func NewService(cfg config.EmailConfig) *Service {
 	return &Service{
-		config: cfg,
-		client: nil,
+		config:    cfg,
+		transport: newTransport(cfg),
 	}
 }
 
-// dialSMTP establishes an SMTP connection
-func (s *Service) dialSMTP() (*smtp.Client, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// settings returns a copy of the current email configuration
+func (s *Service) settings() config.EmailConfig {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
 
-	// Reuse existing connection if it's still alive
-	if s.client != nil {
-		if err := s.client.Noop(); err == nil {
-			return s.client, nil
-		}
-		// Connection is dead, close it
-		s.client.Close()
-		s.client = nil
+// currentTransport returns the transport of the current configuration
+func (s *Service) currentTransport() Transport {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.transport
+}
+
+// Reconfigure replaces the email configuration. The transport is replaced as well, closing
+// a cached SMTP connection so the next message is sent with the new settings.
+func (s *Service) Reconfigure(cfg config.EmailConfig) {
+	s.configMu.Lock()
+	previous := s.transport
+	s.config = cfg
+	s.transport = newTransport(cfg)
+	s.configMu.Unlock()
+
+	if closer, ok := previous.(io.Closer); ok {
+		closer.Close()
 	}
+}
 
-	// Create new connection
-	addr := fmt.Sprintf("%s:%d", s.config.SMTPHost, s.config.SMTPPort)
-	client, err := smtp.Dial(addr)
-	if err != nil {
-		return nil, fmt.Errorf("failed to dial SMTP server: %w", err)
+// validateConfig checks that all settings needed to send email are present
+func (s *Service) validateConfig() error {
+	cfg := s.settings()
+	if cfg.AppURL == "" {
+		return ErrNotConfigured
 	}
 
-	if err := client.Auth(smtp.PlainAuth("", s.config.SMTPUsername, s.config.SMTPPassword, s.config.SMTPHost)); err != nil {
-		client.Close()
-		return nil, fmt.Errorf("failed to authenticate with SMTP server: %w", err)
+	var complete bool
+	switch cfg.Provider {
+	case config.EmailProviderConsole:
+		return nil
+	case config.EmailProviderSendGrid:
+		complete = cfg.SendGridAPIKey != ""
+	case config.EmailProviderMailgun:
+		complete = cfg.MailgunDomain != "" && cfg.MailgunAPIKey != ""
+	default:
+		complete = cfg.SMTPHost != "" && cfg.SMTPPort != 0 && cfg.SMTPUsername != "" && cfg.SMTPPassword != ""
+	}
+	if !complete || cfg.FromAddress == "" {
+		return ErrNotConfigured
 	}
+	return nil
+}
 
-	s.client = client
-	return client, nil
+// SetSuppressionChecker makes the service skip addresses that bounced or complained
+func (s *Service) SetSuppressionChecker(checker SuppressionChecker) {
+	s.suppressions = checker
 }
 
-// sendMail sends an email using a pooled SMTP connection
-func (s *Service) sendMail(to []string, msg []byte) error {
-	client, err := s.dialSMTP()
-	if err != nil {
+// SetDeadLetters makes the service keep messages that failed every attempt so they can be resent
+func (s *Service) SetDeadLetters(recorder DeadLetterRecorder) {
+	s.deadLetters = recorder
+}
+
+// send delivers a message of the given kind, moving it to the dead letters when every
+// attempt fails
+func (s *Service) send(kind string, msg *Message) error {
+	ctx := context.Background()
+	if err := s.checkSuppressed(msg.To); err != nil {
 		return err
 	}
 
-	if err := client.Mail(s.config.SMTPUsername); err != nil {
-		return fmt.Errorf("failed to set sender: %w", err)
+	provider, attempts, err := s.deliverWithRetry(ctx, msg)
+	if err != nil && s.deadLetters != nil {
+		for _, to := range msg.To {
+			letter := &models.EmailDeadLetter{
+				Kind:        kind,
+				Recipient:   to,
+				Subject:     msg.Subject,
+				ContentType: msg.ContentType,
+				Body:        msg.Body,
+				TextBody:    msg.TextBody,
+				Provider:    provider,
+				Error:       err.Error(),
+				Attempts:    attempts,
+			}
+			if dlErr := s.deadLetters.Create(ctx, letter); dlErr != nil {
+				log.Printf("Failed to record undelivered email to %s: %v", to, dlErr)
+			}
+		}
 	}
+	return err
+}
+
+// deliverWithRetry hands a message to the transport, retrying with exponential backoff until
+// it succeeds, fails permanently or runs out of attempts. It returns the provider used and
+// the number of attempts made.
+func (s *Service) deliverWithRetry(ctx context.Context, msg *Message) (string, int, error) {
+	cfg := s.settings()
+	transport := s.currentTransport()
+	backoff := cfg.RetryBackoff
+	for attempt := 1; ; attempt++ {
+		err := transport.Send(ctx, msg)
+		if err == nil || isPermanent(err) || attempt >= max(cfg.MaxAttempts, 1) {
+			return transport.Name(), attempt, err
+		}
 
+		log.Printf("Sending email to %s via %s failed, retrying in %s: %v", strings.Join(msg.To, ", "), transport.Name(), backoff, err)
+		select {
+		case <-ctx.Done():
+			return transport.Name(), attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// Resend delivers a dead letter again with the current configuration and returns the
+// number of attempts made. The links in a message stop working once their token expires.
+func (s *Service) Resend(ctx context.Context, letter *models.EmailDeadLetter) (int, error) {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return 0, err
+	}
+
+	msg := &Message{
+		From:        cfg.FromAddress,
+		To:          []string{letter.Recipient},
+		Subject:     letter.Subject,
+		ContentType: letter.ContentType,
+		Body:        letter.Body,
+		TextBody:    letter.TextBody,
+	}
+	if err := s.checkSuppressed(msg.To); err != nil {
+		return 0, err
+	}
+
+	_, attempts, err := s.deliverWithRetry(ctx, msg)
+	return attempts, err
+}
+
+// checkSuppressed returns ErrRecipientSuppressed if any recipient is on the suppression list
+func (s *Service) checkSuppressed(to []string) error {
+	if s.suppressions == nil {
+		return nil
+	}
 	for _, addr := range to {
-		if err := client.Rcpt(addr); err != nil {
-			return fmt.Errorf("failed to add recipient %s: %w", addr, err)
+		suppressed, err := s.suppressions.IsSuppressed(context.Background(), addr)
+		if err != nil {
+			return fmt.Errorf("failed to check suppression list: %w", err)
+		}
+		if suppressed {
+			log.Printf("Not sending email to suppressed address %s", addr)
+			return ErrRecipientSuppressed
 		}
 	}
+	return nil
+}
 
-	w, err := client.Data()
+// Close closes the connection of the transport, if it keeps one
+func (s *Service) Close() error {
+	if closer, ok := s.currentTransport().(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// compose renders the templates of an email kind in the given language into a message to
+// a single recipient
+func (s *Service) compose(cfg config.EmailConfig, to, language, kind string, data interface{}) (*Message, error) {
+	content, err := templateSet{dir: cfg.TemplatesDir}.render(language, kind, data)
 	if err != nil {
-		return fmt.Errorf("failed to create message writer: %w", err)
+		return nil, err
 	}
+	return &Message{
+		From:        cfg.FromAddress,
+		To:          []string{to},
+		Subject:     content.Subject,
+		ContentType: "text/html",
+		Body:        content.HTML,
+		TextBody:    content.Text,
+	}, nil
+}
 
-	if _, err := w.Write(msg); err != nil {
-		return fmt.Errorf("failed to write message: %w", err)
+func (s *Service) SendVerificationEmail(to, username, language, token string, expiresAt time.Time) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
 	}
 
-	if err := w.Close(); err != nil {
-		return fmt.Errorf("failed to close message writer: %w", err)
+	loc := localeFor(language)
+	msg, err := s.compose(cfg, to, language, KindVerification, map[string]string{
+		"Username":  username,
+		"URL":       fmt.Sprintf("%s/api/v1/auth/verify-email?token=%s", cfg.AppURL, token),
+		"ExpiresIn": loc.formatTTL(time.Until(expiresAt)),
+		"ExpiresAt": loc.formatDateTime(expiresAt.UTC()),
+	})
+	if err != nil {
+		return err
 	}
 
+	log.Printf("Sending verification email to %s via %s", to, s.currentTransport().Name())
+	if err := s.send(KindVerification, msg); err != nil {
+		log.Printf("Email error details: %+v", err)
+		return fmt.Errorf("failed to send verification email: %w", err)
+	}
 	return nil
 }
 
-// Close closes the SMTP connection
-func (s *Service) Close() error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+func (s *Service) SendPasswordResetEmail(to, username, language, token string, expiresAt time.Time) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
+
+	loc := localeFor(language)
+	msg, err := s.compose(cfg, to, language, KindPasswordReset, map[string]string{
+		"Username":  username,
+		"URL":       fmt.Sprintf("%s/api/v1/auth/reset-password?token=%s", cfg.AppURL, token),
+		"ExpiresIn": loc.formatTTL(time.Until(expiresAt)),
+		"ExpiresAt": loc.formatDateTime(expiresAt.UTC()),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.send(KindPasswordReset, msg); err != nil {
+		return fmt.Errorf("failed to send password reset email: %w", err)
+	}
+
+	return nil
+}
 
-	if s.client != nil {
-		err := s.client.Quit()
-		s.client = nil
+// SendEmailChangedNotification tells the previous address that the account email was changed
+// and includes a link to undo the change
+func (s *Service) SendEmailChangedNotification(to, username, language, newEmail, revertToken string, expiresAt time.Time) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
 		return err
 	}
+
+	loc := localeFor(language)
+	msg, err := s.compose(cfg, to, language, KindEmailChanged, map[string]string{
+		"Username":  username,
+		"NewEmail":  maskEmail(newEmail),
+		"URL":       fmt.Sprintf("%s/api/v1/auth/revert-email-change?token=%s", cfg.AppURL, revertToken),
+		"ExpiresIn": loc.formatTTL(time.Until(expiresAt)),
+		"ExpiresAt": loc.formatDateTime(expiresAt.UTC()),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.send(KindEmailChanged, msg); err != nil {
+		return fmt.Errorf("failed to send email changed notification: %w", err)
+	}
 	return nil
 }
 
-func (s *Service) SendVerificationEmail(to, username, token string) error {
-	// Validate configuration
-	if s.config.SMTPHost == "" || s.config.SMTPPort == 0 || s.config.SMTPUsername == "" ||
-		s.config.SMTPPassword == "" || s.config.FromAddress == "" || s.config.AppURL == "" {
-		return fmt.Errorf("incomplete email configuration")
+// SendWelcomeEmail sends the onboarding email with links to the instance and next steps
+func (s *Service) SendWelcomeEmail(to, username, language string) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
+
+	msg, err := s.compose(cfg, to, language, KindWelcome, map[string]string{
+		"Username":   username,
+		"AppURL":     cfg.AppURL,
+		"APIDocsURL": strings.TrimRight(cfg.AppURL, "/") + "/swagger/index.html",
+		"DocsURL":    cfg.DocsURL,
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.send(KindWelcome, msg); err != nil {
+		return fmt.Errorf("failed to send welcome email: %w", err)
 	}
+	return nil
+}
 
-	subject := "Verify Your Email Address"
-	verificationURL := fmt.Sprintf("%s/api/v1/auth/verify-email?token=%s", s.config.AppURL, token)
+// SendInviteEmail invites an imported user to WattWatch with a link to choose their
+// password, token is a password reset token
+func (s *Service) SendInviteEmail(to, username, language, token string, expiresAt time.Time) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
 
-	tmpl, err := template.New("verification").Parse(`
-		<h2>Hello {{.Username}},</h2>
-		<p>Please verify your email address by clicking the link below:</p>
-		<p><a href="{{.URL}}">Verify Email Address</a></p>
-		<p>This link will expire in 24 hours.</p>
-		<p>If you did not create an account, no further action is required.</p>
-	`)
+	loc := localeFor(language)
+	msg, err := s.compose(cfg, to, language, KindInvite, map[string]string{
+		"Username":  username,
+		"AppURL":    cfg.AppURL,
+		"URL":       fmt.Sprintf("%s/api/v1/auth/reset-password?token=%s", cfg.AppURL, token),
+		"ExpiresIn": loc.formatTTL(time.Until(expiresAt)),
+		"ExpiresAt": loc.formatDateTime(expiresAt.UTC()),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to parse email template: %w", err)
+		return err
 	}
 
-	var body bytes.Buffer
-	if err := tmpl.Execute(&body, map[string]string{
-		"Username": username,
-		"URL":      verificationURL,
-	}); err != nil {
-		return fmt.Errorf("failed to execute email template: %w", err)
-	}
-
-	msg := fmt.Sprintf("To: %s\r\n"+
-		"From: %s\r\n"+
-		"Subject: %s\r\n"+
-		"MIME-Version: 1.0\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s", to, s.config.FromAddress, subject, body.String())
-
-	log.Printf("Sending verification email to %s via SMTP server %s:%d", to, s.config.SMTPHost, s.config.SMTPPort)
-	if err := s.sendMail([]string{to}, []byte(msg)); err != nil {
-		log.Printf("SMTP error details: %+v", err)
-		return fmt.Errorf("failed to send verification email: %w", err)
+	if err := s.send(KindInvite, msg); err != nil {
+		return fmt.Errorf("failed to send invite email: %w", err)
 	}
 	return nil
 }
 
-func (s *Service) SendPasswordResetEmail(to, username, token string) error {
-	// Validate configuration
-	if s.config.SMTPHost == "" || s.config.SMTPPort == 0 || s.config.SMTPUsername == "" ||
-		s.config.SMTPPassword == "" || s.config.FromAddress == "" || s.config.AppURL == "" {
-		return fmt.Errorf("incomplete email configuration")
+// SendAccountDeletionEmail tells the user their account is deleted at deleteAt unless they
+// log in before then
+func (s *Service) SendAccountDeletionEmail(to, username, language string, deleteAt time.Time) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
+
+	loc := localeFor(language)
+	msg, err := s.compose(cfg, to, language, KindAccountDeletion, map[string]string{
+		"Username": username,
+		"AppURL":   cfg.AppURL,
+		"DeleteIn": loc.formatTTL(time.Until(deleteAt)),
+		"DeleteAt": loc.formatDateTime(deleteAt.UTC()),
+	})
+	if err != nil {
+		return err
+	}
+
+	if err := s.send(KindAccountDeletion, msg); err != nil {
+		return fmt.Errorf("failed to send account deletion email: %w", err)
 	}
+	return nil
+}
 
-	subject := "Reset Your Password"
-	resetURL := fmt.Sprintf("%s/api/v1/auth/reset-password?token=%s", s.config.AppURL, token)
+// SendNewDeviceLoginEmail tells the user about a login from a device they hadn't logged in
+// from before, in case it wasn't them
+func (s *Service) SendNewDeviceLoginEmail(to, username, language, ipAddress, userAgent string, at time.Time) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
 
-	tmpl, err := template.New("reset").Parse(`
-		<h2>Hello {{.Username}},</h2>
-		<p>You have requested to reset your password. Click the link below to proceed:</p>
-		<p><a href="{{.URL}}">Reset Password</a></p>
-		<p>This link will expire in 1 hour.</p>
-		<p>If you did not request a password reset, please ignore this email.</p>
-	`)
+	loc := localeFor(language)
+	msg, err := s.compose(cfg, to, language, KindNewDeviceLogin, map[string]string{
+		"Username":  username,
+		"AppURL":    cfg.AppURL,
+		"IPAddress": ipAddress,
+		"UserAgent": userAgent,
+		"At":        loc.formatDateTime(at.UTC()),
+	})
 	if err != nil {
-		return fmt.Errorf("failed to parse email template: %w", err)
+		return err
 	}
 
-	var body bytes.Buffer
-	if err := tmpl.Execute(&body, map[string]string{
-		"Username": username,
-		"URL":      resetURL,
-	}); err != nil {
-		return fmt.Errorf("failed to execute email template: %w", err)
+	if err := s.send(KindNewDeviceLogin, msg); err != nil {
+		return fmt.Errorf("failed to send new device login email: %w", err)
 	}
+	return nil
+}
 
-	msg := fmt.Sprintf("To: %s\r\n"+
-		"From: %s\r\n"+
-		"Subject: %s\r\n"+
-		"MIME-Version: 1.0\r\n"+
-		"Content-Type: text/html; charset=UTF-8\r\n"+
-		"\r\n"+
-		"%s", to, s.config.FromAddress, subject, body.String())
+// SendAlertEmail sends a price or consumption alert, the title is used as the subject
+func (s *Service) SendAlertEmail(to, username, language, title, body string) error {
+	cfg := s.settings()
+	if err := s.validateConfig(); err != nil {
+		return err
+	}
 
-	if err := s.sendMail([]string{to}, []byte(msg)); err != nil {
-		return fmt.Errorf("failed to send password reset email: %w", err)
+	msg, err := s.compose(cfg, to, language, KindAlert, map[string]string{
+		"Username": username,
+		"Title":    title,
+		"Body":     body,
+		"AppURL":   cfg.AppURL,
+	})
+	if err != nil {
+		return err
 	}
 
+	if err := s.send(KindAlert, msg); err != nil {
+		return fmt.Errorf("failed to send alert email: %w", err)
+	}
 	return nil
 }
+
+// maskEmail hides most of the local part so a leaked notification doesn't reveal the full address
+func maskEmail(address string) string {
+	at := strings.LastIndex(address, "@")
+	if at < 1 {
+		return address
+	}
+	return address[:1] + "***" + address[at:]
+}
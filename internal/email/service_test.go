@@ -0,0 +1,134 @@
+package email
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFormatTTL(t *testing.T) {
+	en := localeFor(models.LanguageEnglish)
+	assert.Equal(t, "24 hours", en.formatTTL(24*time.Hour))
+	assert.Equal(t, "1 hour", en.formatTTL(time.Hour-10*time.Second))
+	assert.Equal(t, "90 minutes", en.formatTTL(90*time.Minute))
+	assert.Equal(t, "1 minute", en.formatTTL(time.Minute))
+
+	sv := localeFor(models.LanguageSwedish)
+	assert.Equal(t, "24 timmar", sv.formatTTL(24*time.Hour))
+	assert.Equal(t, "1 timme", sv.formatTTL(time.Hour))
+	assert.Equal(t, "90 minuter", sv.formatTTL(90*time.Minute))
+}
+
+func TestMaskEmail(t *testing.T) {
+	assert.Equal(t, "j***@example.com", maskEmail("jane.doe@example.com"))
+	assert.Equal(t, "", maskEmail(""))
+	assert.Equal(t, "@example.com", maskEmail("@example.com"))
+}
+
+func TestSendWelcomeEmail(t *testing.T) {
+	port, messages := fakeSMTPServer(t, "235 2.7.0 accepted")
+	cfg := testEmailConfig(port)
+	cfg.DocsURL = "https://docs.example.com"
+	service := NewService(cfg)
+	defer service.Close()
+
+	require.NoError(t, service.SendWelcomeEmail("jane@example.com", "jane", models.LanguageEnglish))
+
+	subject, text, html := readAlternatives(t, <-messages)
+	assert.Equal(t, "Welcome to WattWatch", subject)
+	for _, body := range []string{text, html} {
+		assert.Contains(t, body, "Welcome to WattWatch, jane!")
+		assert.Contains(t, body, "http://localhost:8080/swagger/index.html")
+		assert.Contains(t, body, "https://docs.example.com")
+	}
+	assert.NotContains(t, text, "<")
+}
+
+func TestSendVerificationEmail_Language(t *testing.T) {
+	port, messages := fakeSMTPServer(t, "235 2.7.0 accepted")
+	service := NewService(testEmailConfig(port))
+	defer service.Close()
+
+	expiresAt := time.Now().Add(24 * time.Hour)
+	require.NoError(t, service.SendVerificationEmail("jane@example.com", "jane", models.LanguageSwedish, "abc123", expiresAt))
+
+	subject, text, html := readAlternatives(t, <-messages)
+	assert.Equal(t, "Bekräfta din e-postadress", subject)
+	for _, body := range []string{text, html} {
+		assert.Contains(t, body, "Hej jane,")
+		assert.Contains(t, body, "http://localhost:8080/api/v1/auth/verify-email?token=abc123")
+		assert.Contains(t, body, "Länken går ut om 24 timmar")
+	}
+}
+
+func TestTemplateSet_Overrides(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(dir, "sv"), 0o755))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "sv", "welcome.txt.tmpl"),
+		[]byte(`{{define "subject"}}Hej och välkommen{{end}}Hej {{.Username}}`), 0o644))
+
+	data := map[string]string{"Username": "jane", "AppURL": "http://localhost:8080"}
+
+	// Files in the directory replace the built-in ones, the others are still used
+	content, err := templateSet{dir: dir}.render(models.LanguageSwedish, KindWelcome, data)
+	require.NoError(t, err)
+	assert.Equal(t, "Hej och välkommen", content.Subject)
+	assert.Equal(t, "Hej jane\n", content.Text)
+	assert.Contains(t, content.HTML, "Välkommen till WattWatch, jane!")
+
+	// Unknown languages fall back to English
+	content, err = templateSet{dir: dir}.render("fi", KindWelcome, data)
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome to WattWatch", content.Subject)
+
+	// Every built-in email has both bodies and a subject in every language
+	for language := range locales {
+		for _, kind := range []string{KindVerification, KindPasswordReset, KindEmailChanged, KindWelcome, KindInvite, KindAccountDeletion, KindNewDeviceLogin, KindWeeklyReport, KindAlert} {
+			content, err := templateSet{}.render(language, kind, nil)
+			require.NoError(t, err, "%s/%s", language, kind)
+			assert.NotEmpty(t, content.Subject, "%s/%s", language, kind)
+			assert.NotEmpty(t, content.HTML, "%s/%s", language, kind)
+		}
+	}
+}
+
+// readAlternatives parses a multipart/alternative message into its subject and decoded
+// plain text and HTML parts
+func readAlternatives(t *testing.T, raw string) (subject, text, html string) {
+	t.Helper()
+	msg, err := mail.ReadMessage(strings.NewReader(raw))
+	require.NoError(t, err)
+	subject, err = new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	require.Equal(t, "multipart/alternative", mediaType)
+
+	parts := multipart.NewReader(msg.Body, params["boundary"])
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		body, err := io.ReadAll(part)
+		require.NoError(t, err)
+		if strings.HasPrefix(part.Header.Get("Content-Type"), "text/html") {
+			html = string(body)
+		} else {
+			text = string(body)
+		}
+	}
+	return subject, text, html
+}
@@ -0,0 +1,176 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+	"wattwatch/internal/models"
+)
+
+// templatesFS holds the built-in templates, one directory per language. Each email has an
+// HTML template, <name>.html.tmpl, and a plain text one, <name>.txt.tmpl, which also
+// defines the "subject" template.
+//
+//go:embed templates
+var templatesFS embed.FS
+
+// renderedEmail is an email rendered in one language
+type renderedEmail struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// templateSet renders emails, preferring templates found in dir over the built-in ones
+type templateSet struct {
+	dir string
+}
+
+// render executes the templates of the named email in the given language, falling back to
+// the default language for languages without templates
+func (t templateSet) render(language, name string, data interface{}) (*renderedEmail, error) {
+	if _, ok := locales[language]; !ok {
+		language = models.DefaultLanguage
+	}
+
+	textSource, err := t.read(language, name+".txt.tmpl")
+	if err != nil {
+		return nil, err
+	}
+	htmlSource, err := t.read(language, name+".html.tmpl")
+	if err != nil {
+		return nil, err
+	}
+
+	text, err := template.New(name).Parse(textSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email template: %w", err)
+	}
+	if text.Lookup("subject") == nil {
+		return nil, fmt.Errorf("email template %s/%s.txt.tmpl does not define a subject", language, name)
+	}
+	html, err := htmltemplate.New(name).Parse(htmlSource)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse email template: %w", err)
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return nil, fmt.Errorf("failed to execute email template: %w", err)
+	}
+	if err := text.Execute(&textBody, data); err != nil {
+		return nil, fmt.Errorf("failed to execute email template: %w", err)
+	}
+	if err := html.Execute(&htmlBody, data); err != nil {
+		return nil, fmt.Errorf("failed to execute email template: %w", err)
+	}
+
+	return &renderedEmail{
+		Subject: strings.TrimSpace(subject.String()),
+		HTML:    htmlBody.String(),
+		Text:    strings.TrimSpace(textBody.String()) + "\n",
+	}, nil
+}
+
+// read returns a template file, from dir when it has one and otherwise the built-in one.
+// Templates missing in a language are taken from the default language.
+func (t templateSet) read(language, file string) (string, error) {
+	for _, lang := range []string{language, models.DefaultLanguage} {
+		if t.dir != "" {
+			content, err := os.ReadFile(filepath.Join(t.dir, lang, file))
+			if err == nil {
+				return string(content), nil
+			}
+			if !errors.Is(err, fs.ErrNotExist) {
+				return "", fmt.Errorf("failed to read email template: %w", err)
+			}
+		}
+
+		content, err := templatesFS.ReadFile(path.Join("templates", lang, file))
+		if err == nil {
+			return string(content), nil
+		}
+	}
+	return "", fmt.Errorf("email template %s not found", file)
+}
+
+// locale holds how values in emails are written in a language
+type locale struct {
+	months   [12]string
+	weekdays [7]string
+	// decimalComma writes numbers with a decimal comma instead of a point
+	decimalComma bool
+	// units are the singular and plural forms of hour and minute
+	hour, hours, minute, minutes string
+}
+
+var locales = map[string]locale{
+	models.LanguageEnglish: {
+		months:   [12]string{"Jan", "Feb", "Mar", "Apr", "May", "Jun", "Jul", "Aug", "Sep", "Oct", "Nov", "Dec"},
+		weekdays: [7]string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"},
+		hour:     "hour", hours: "hours", minute: "minute", minutes: "minutes",
+	},
+	models.LanguageSwedish: {
+		months:       [12]string{"januari", "februari", "mars", "april", "maj", "juni", "juli", "augusti", "september", "oktober", "november", "december"},
+		weekdays:     [7]string{"söndag", "måndag", "tisdag", "onsdag", "torsdag", "fredag", "lördag"},
+		decimalComma: true,
+		hour:         "timme", hours: "timmar", minute: "minut", minutes: "minuter",
+	},
+}
+
+// localeFor returns the locale of a language, the default language's when it is unknown
+func localeFor(language string) locale {
+	if l, ok := locales[language]; ok {
+		return l
+	}
+	return locales[models.DefaultLanguage]
+}
+
+// formatDate renders a date, e.g. "4 Mar 2024"
+func (l locale) formatDate(t time.Time) string {
+	return fmt.Sprintf("%d %s %d", t.Day(), l.months[t.Month()-1], t.Year())
+}
+
+// formatDay renders a day of the week with its date, e.g. "Wednesday 6 Mar"
+func (l locale) formatDay(t time.Time) string {
+	return fmt.Sprintf("%s %d %s", l.weekdays[t.Weekday()], t.Day(), l.months[t.Month()-1])
+}
+
+// formatDateTime renders a point in time, e.g. "4 Mar 2024 15:04 UTC"
+func (l locale) formatDateTime(t time.Time) string {
+	return l.formatDate(t) + t.Format(" 15:04 MST")
+}
+
+// formatNumber renders a number with the given number of decimals
+func (l locale) formatNumber(v float64, decimals int) string {
+	s := strconv.FormatFloat(v, 'f', decimals, 64)
+	if l.decimalComma {
+		s = strings.Replace(s, ".", ",", 1)
+	}
+	return s
+}
+
+// formatTTL renders a token lifetime for humans, e.g. "24 hours" or "30 minutes"
+func (l locale) formatTTL(d time.Duration) string {
+	d = d.Round(time.Minute)
+	plural := func(n int64, one, many string) string {
+		if n == 1 {
+			return "1 " + one
+		}
+		return fmt.Sprintf("%d %s", n, many)
+	}
+	if d >= time.Hour && d%time.Hour == 0 {
+		return plural(int64(d/time.Hour), l.hour, l.hours)
+	}
+	return plural(int64(d/time.Minute), l.minute, l.minutes)
+}
@@ -0,0 +1,346 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"wattwatch/internal/config"
+)
+
+// Message is an email ready to be handed to a transport
+type Message struct {
+	From    string
+	To      []string
+	Subject string
+	// ContentType is the MIME type of the body, text/html or text/plain
+	ContentType string
+	Body        string
+	// TextBody is a plain text alternative to an HTML body, sent along with it when set
+	TextBody string
+}
+
+// Transport hands messages to a mail server or delivery API
+type Transport interface {
+	// Name identifies the provider in logs and dead letters
+	Name() string
+	Send(ctx context.Context, msg *Message) error
+}
+
+// permanentError marks failures that fail the same way when retried, such as a request
+// the provider rejected
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+func (e *permanentError) Unwrap() error { return e.err }
+
+// isPermanent reports whether retrying the failed send is pointless
+func isPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// newTransport creates the transport of the configured provider, SMTP unless another is set
+func newTransport(cfg config.EmailConfig) Transport {
+	client := &http.Client{Timeout: 10 * time.Second}
+	switch cfg.Provider {
+	case config.EmailProviderSendGrid:
+		return &sendGridTransport{client: client, apiURL: sendGridAPIURL, apiKey: cfg.SendGridAPIKey}
+	case config.EmailProviderMailgun:
+		return &mailgunTransport{client: client, apiURL: cfg.MailgunAPIURL, domain: cfg.MailgunDomain, apiKey: cfg.MailgunAPIKey}
+	case config.EmailProviderConsole:
+		return consoleTransport{}
+	default:
+		return &smtpTransport{config: cfg}
+	}
+}
+
+// smtpTransport sends messages over a pooled SMTP connection
+type smtpTransport struct {
+	config config.EmailConfig
+	client *smtp.Client
+	mu     sync.Mutex
+}
+
+func (t *smtpTransport) Name() string { return config.EmailProviderSMTP }
+
+func (t *smtpTransport) Send(ctx context.Context, msg *Message) error {
+	client, err := t.dial()
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err := deliver(client, t.config.SMTPUsername, msg.To, renderMIME(msg)); err != nil {
+		// Start over with a fresh connection after a failed transaction
+		client.Close()
+		t.client = nil
+		return err
+	}
+	return nil
+}
+
+// dial returns the pooled connection, establishing a new one when it is gone
+func (t *smtpTransport) dial() (*smtp.Client, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	// Reuse existing connection if it's still alive
+	if t.client != nil {
+		if err := t.client.Noop(); err == nil {
+			return t.client, nil
+		}
+		// Connection is dead, close it
+		t.client.Close()
+		t.client = nil
+	}
+
+	client, err := t.connect(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	t.client = client
+	return client, nil
+}
+
+// connect opens and authenticates a new SMTP connection, recording the dialogue when trace is set
+func (t *smtpTransport) connect(trace *transcript) (*smtp.Client, error) {
+	cfg := t.config
+	conn, err := net.Dial("tcp", net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial SMTP server: %w", err)
+	}
+	if trace != nil {
+		conn = &recordingConn{Conn: conn, t: trace}
+	}
+
+	client, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to dial SMTP server: %w", err)
+	}
+
+	if err := client.Auth(smtp.PlainAuth("", cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPHost)); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to authenticate with SMTP server: %w", err)
+	}
+
+	return client, nil
+}
+
+// Close closes the SMTP connection
+func (t *smtpTransport) Close() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.client != nil {
+		err := t.client.Quit()
+		t.client = nil
+		return err
+	}
+	return nil
+}
+
+// renderMIME formats a message with the headers SMTP servers expect. A message with a
+// plain text alternative is sent as multipart/alternative, with the preferred HTML part last.
+func renderMIME(msg *Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "To: %s\r\n"+
+		"From: %s\r\n"+
+		"Subject: %s\r\n"+
+		"MIME-Version: 1.0\r\n", strings.Join(msg.To, ", "), msg.From, mime.QEncoding.Encode("utf-8", msg.Subject))
+
+	if msg.TextBody == "" {
+		fmt.Fprintf(&b, "Content-Type: %s; charset=UTF-8\r\n\r\n%s", msg.ContentType, msg.Body)
+		return b.Bytes()
+	}
+
+	var parts bytes.Buffer
+	mw := multipart.NewWriter(&parts)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain", msg.TextBody},
+		{msg.ContentType, msg.Body},
+	} {
+		w, _ := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType + "; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		qp := quotedprintable.NewWriter(w)
+		qp.Write([]byte(part.body))
+		qp.Close()
+	}
+	mw.Close()
+
+	fmt.Fprintf(&b, "Content-Type: multipart/alternative; boundary=%s\r\n\r\n", mw.Boundary())
+	b.Write(parts.Bytes())
+	return b.Bytes()
+}
+
+// deliver runs a single mail transaction on an established connection
+func deliver(client *smtp.Client, from string, to []string, msg []byte) error {
+	if err := client.Mail(from); err != nil {
+		return fmt.Errorf("failed to set sender: %w", err)
+	}
+
+	for _, addr := range to {
+		if err := client.Rcpt(addr); err != nil {
+			return fmt.Errorf("failed to add recipient %s: %w", addr, err)
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to create message writer: %w", err)
+	}
+
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to write message: %w", err)
+	}
+
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to close message writer: %w", err)
+	}
+
+	return nil
+}
+
+const sendGridAPIURL = "https://api.sendgrid.com"
+
+// sendGridTransport sends messages with the SendGrid v3 mail send API
+type sendGridTransport struct {
+	client *http.Client
+	apiURL string
+	apiKey string
+}
+
+func (t *sendGridTransport) Name() string { return config.EmailProviderSendGrid }
+
+func (t *sendGridTransport) Send(ctx context.Context, msg *Message) error {
+	type address struct {
+		Email string `json:"email"`
+	}
+	to := make([]address, len(msg.To))
+	for i, addr := range msg.To {
+		to[i] = address{Email: addr}
+	}
+	// SendGrid requires text/plain to come first
+	var content []map[string]string
+	if msg.TextBody != "" {
+		content = append(content, map[string]string{"type": "text/plain", "value": msg.TextBody})
+	}
+	content = append(content, map[string]string{"type": msg.ContentType, "value": msg.Body})
+	payload, err := json.Marshal(map[string]interface{}{
+		"personalizations": []map[string]interface{}{{"to": to}},
+		"from":             address{Email: msg.From},
+		"subject":          msg.Subject,
+		"content":          content,
+	})
+	if err != nil {
+		return &permanentError{err}
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.apiURL+"/v3/mail/send", bytes.NewReader(payload))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	req.Header.Set("Content-Type", "application/json")
+	if err := doRequest(t.client, req); err != nil {
+		return fmt.Errorf("sendgrid: %w", err)
+	}
+	return nil
+}
+
+// mailgunTransport sends messages with the Mailgun messages API
+type mailgunTransport struct {
+	client *http.Client
+	apiURL string
+	domain string
+	apiKey string
+}
+
+func (t *mailgunTransport) Name() string { return config.EmailProviderMailgun }
+
+func (t *mailgunTransport) Send(ctx context.Context, msg *Message) error {
+	form := url.Values{}
+	form.Set("from", msg.From)
+	for _, addr := range msg.To {
+		form.Add("to", addr)
+	}
+	form.Set("subject", msg.Subject)
+	if msg.ContentType == "text/html" {
+		form.Set("html", msg.Body)
+		if msg.TextBody != "" {
+			form.Set("text", msg.TextBody)
+		}
+	} else {
+		form.Set("text", msg.Body)
+	}
+
+	endpoint := fmt.Sprintf("%s/v3/%s/messages", strings.TrimRight(t.apiURL, "/"), url.PathEscape(t.domain))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return &permanentError{err}
+	}
+	req.SetBasicAuth("api", t.apiKey)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := doRequest(t.client, req); err != nil {
+		return fmt.Errorf("mailgun: %w", err)
+	}
+	return nil
+}
+
+// doRequest sends an API request and returns an error for non-2xx responses. Client errors
+// other than rate limiting are permanent.
+func doRequest(client *http.Client, req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	err = fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return &permanentError{err}
+	}
+	return err
+}
+
+// consoleTransport logs messages instead of sending them, so links can be followed
+// during development without a mail server
+type consoleTransport struct{}
+
+func (consoleTransport) Name() string { return config.EmailProviderConsole }
+
+func (consoleTransport) Send(ctx context.Context, msg *Message) error {
+	body := msg.Body
+	if msg.TextBody != "" {
+		body = msg.TextBody
+	}
+	log.Printf("Email to %s: %s\n%s", strings.Join(msg.To, ", "), msg.Subject, body)
+	return nil
+}
@@ -0,0 +1,212 @@
+package email
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSendGridTransport(t *testing.T) {
+	var got map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/mail/send", r.URL.Path)
+		assert.Equal(t, "Bearer sg-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	transport := &sendGridTransport{client: server.Client(), apiURL: server.URL, apiKey: "sg-key"}
+	require.NoError(t, transport.Send(context.Background(), &Message{
+		From:        "noreply@example.com",
+		To:          []string{"jane@example.com"},
+		Subject:     "Hello",
+		ContentType: "text/html",
+		Body:        "<p>Hi</p>",
+		TextBody:    "Hi",
+	}))
+
+	assert.Equal(t, "Hello", got["subject"])
+	assert.Equal(t, map[string]interface{}{"email": "noreply@example.com"}, got["from"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"type": "text/plain", "value": "Hi"},
+		map[string]interface{}{"type": "text/html", "value": "<p>Hi</p>"},
+	}, got["content"])
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"to": []interface{}{map[string]interface{}{"email": "jane@example.com"}},
+	}}, got["personalizations"])
+}
+
+func TestMailgunTransport(t *testing.T) {
+	var form url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v3/mg.example.com/messages", r.URL.Path)
+		user, key, ok := r.BasicAuth()
+		assert.True(t, ok)
+		assert.Equal(t, "api", user)
+		assert.Equal(t, "mg-key", key)
+		require.NoError(t, r.ParseForm())
+		form = r.PostForm
+	}))
+	defer server.Close()
+
+	transport := &mailgunTransport{client: server.Client(), apiURL: server.URL + "/", domain: "mg.example.com", apiKey: "mg-key"}
+	require.NoError(t, transport.Send(context.Background(), &Message{
+		From:        "noreply@example.com",
+		To:          []string{"jane@example.com"},
+		Subject:     "Hello",
+		ContentType: "text/plain",
+		Body:        "Hi",
+	}))
+
+	assert.Equal(t, "noreply@example.com", form.Get("from"))
+	assert.Equal(t, "jane@example.com", form.Get("to"))
+	assert.Equal(t, "Hi", form.Get("text"))
+	assert.Empty(t, form.Get("html"))
+}
+
+func TestDoRequest_PermanentErrors(t *testing.T) {
+	status := http.StatusBadRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		io.WriteString(w, "rejected")
+	}))
+	defer server.Close()
+
+	for code, permanent := range map[int]bool{
+		http.StatusBadRequest:          true,
+		http.StatusUnauthorized:        true,
+		http.StatusTooManyRequests:     false,
+		http.StatusInternalServerError: false,
+	} {
+		status = code
+		req, _ := http.NewRequest(http.MethodPost, server.URL, nil)
+		err := doRequest(server.Client(), req)
+		require.Error(t, err, code)
+		assert.Contains(t, err.Error(), "rejected", code)
+		assert.Equal(t, permanent, isPermanent(err), code)
+	}
+}
+
+// fakeTransport records the messages it is given, failing the first failures of them with err
+type fakeTransport struct {
+	failures int
+	err      error
+	sent     []*Message
+}
+
+func (t *fakeTransport) Name() string { return "fake" }
+
+func (t *fakeTransport) Send(ctx context.Context, msg *Message) error {
+	if len(t.sent) < t.failures {
+		t.sent = append(t.sent, msg)
+		return t.err
+	}
+	t.sent = append(t.sent, msg)
+	return nil
+}
+
+type deadLetterRecorder []*models.EmailDeadLetter
+
+func (r *deadLetterRecorder) Create(ctx context.Context, letter *models.EmailDeadLetter) error {
+	*r = append(*r, letter)
+	return nil
+}
+
+func TestService_Retry(t *testing.T) {
+	cfg := config.EmailConfig{
+		Provider:    config.EmailProviderConsole,
+		AppURL:      "http://localhost:8080",
+		FromAddress: "noreply@example.com",
+		MaxAttempts: 3,
+	}
+
+	t.Run("Delivered After Transient Failures", func(t *testing.T) {
+		service := NewService(cfg)
+		transport := &fakeTransport{failures: 2, err: errors.New("connection reset")}
+		service.transport = transport
+		var letters deadLetterRecorder
+		service.SetDeadLetters(&letters)
+
+		require.NoError(t, service.SendWelcomeEmail("jane@example.com", "jane", models.LanguageEnglish))
+		assert.Len(t, transport.sent, 3)
+		assert.Empty(t, letters)
+	})
+
+	t.Run("Dead Letter After Last Attempt", func(t *testing.T) {
+		service := NewService(cfg)
+		transport := &fakeTransport{failures: 5, err: errors.New("connection reset")}
+		service.transport = transport
+		var letters deadLetterRecorder
+		service.SetDeadLetters(&letters)
+
+		require.Error(t, service.SendWelcomeEmail("jane@example.com", "jane", models.LanguageEnglish))
+		assert.Len(t, transport.sent, 3)
+		require.Len(t, letters, 1)
+		assert.Equal(t, KindWelcome, letters[0].Kind)
+		assert.Equal(t, "jane@example.com", letters[0].Recipient)
+		assert.Equal(t, "Welcome to WattWatch", letters[0].Subject)
+		assert.Equal(t, "fake", letters[0].Provider)
+		assert.Equal(t, "connection reset", letters[0].Error)
+		assert.Equal(t, 3, letters[0].Attempts)
+		assert.Contains(t, letters[0].Body, "Welcome to WattWatch, jane!")
+		assert.Contains(t, letters[0].TextBody, "Welcome to WattWatch, jane!")
+
+		// Resending uses the stored message and reports the attempts it made
+		transport.failures = 0
+		attempts, err := service.Resend(context.Background(), letters[0])
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempts)
+		assert.Equal(t, letters[0].Body, transport.sent[len(transport.sent)-1].Body)
+		assert.Equal(t, letters[0].TextBody, transport.sent[len(transport.sent)-1].TextBody)
+	})
+
+	t.Run("Permanent Failure Is Not Retried", func(t *testing.T) {
+		service := NewService(cfg)
+		transport := &fakeTransport{failures: 5, err: &permanentError{errors.New("invalid recipient")}}
+		service.transport = transport
+		var letters deadLetterRecorder
+		service.SetDeadLetters(&letters)
+
+		require.Error(t, service.SendWelcomeEmail("jane@example.com", "jane", models.LanguageEnglish))
+		assert.Len(t, transport.sent, 1)
+		require.Len(t, letters, 1)
+		assert.Equal(t, 1, letters[0].Attempts)
+	})
+}
+
+func TestService_ValidateConfig(t *testing.T) {
+	base := config.EmailConfig{AppURL: "http://localhost:8080", FromAddress: "noreply@example.com"}
+
+	console := base
+	console.Provider = config.EmailProviderConsole
+	console.FromAddress = ""
+	assert.NoError(t, NewService(console).validateConfig())
+
+	sendgrid := base
+	sendgrid.Provider = config.EmailProviderSendGrid
+	assert.ErrorIs(t, NewService(sendgrid).validateConfig(), ErrNotConfigured)
+	sendgrid.SendGridAPIKey = "sg-key"
+	assert.NoError(t, NewService(sendgrid).validateConfig())
+
+	mailgun := base
+	mailgun.Provider = config.EmailProviderMailgun
+	mailgun.MailgunAPIKey = "mg-key"
+	assert.ErrorIs(t, NewService(mailgun).validateConfig(), ErrNotConfigured)
+	mailgun.MailgunDomain = "mg.example.com"
+	assert.NoError(t, NewService(mailgun).validateConfig())
+
+	// SMTP is used when no provider is set
+	assert.ErrorIs(t, NewService(base).validateConfig(), ErrNotConfigured)
+	assert.NoError(t, NewService(testEmailConfig(25)).validateConfig())
+}
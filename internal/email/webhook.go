@@ -0,0 +1,143 @@
+package email
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+	"wattwatch/internal/models"
+)
+
+// ErrInvalidWebhookPayload is returned when a bounce or complaint callback cannot be parsed
+var ErrInvalidWebhookPayload = errors.New("invalid webhook payload")
+
+// SuppressionEvent is a bounce or complaint reported by an email provider
+type SuppressionEvent struct {
+	Email    string
+	Reason   models.EmailSuppressionReason
+	Provider string
+	Detail   string
+}
+
+// snsEnvelope is the Amazon SNS message SES notifications are delivered in
+type snsEnvelope struct {
+	Type         string `json:"Type"`
+	Message      string `json:"Message"`
+	SubscribeURL string `json:"SubscribeURL"`
+}
+
+// sesNotification is the subset of an SES bounce or complaint notification we use
+type sesNotification struct {
+	NotificationType string `json:"notificationType"`
+	Bounce           struct {
+		BounceType        string `json:"bounceType"`
+		BounceSubType     string `json:"bounceSubType"`
+		BouncedRecipients []struct {
+			EmailAddress   string `json:"emailAddress"`
+			DiagnosticCode string `json:"diagnosticCode"`
+		} `json:"bouncedRecipients"`
+	} `json:"bounce"`
+	Complaint struct {
+		ComplaintFeedbackType string `json:"complaintFeedbackType"`
+		ComplainedRecipients  []struct {
+			EmailAddress string `json:"emailAddress"`
+		} `json:"complainedRecipients"`
+	} `json:"complaint"`
+}
+
+// ParseSESNotification parses an SNS delivered SES notification. Only permanent bounces and
+// complaints produce events, transient bounces are retried by SES. When the message is a
+// subscription confirmation the URL to confirm it is returned instead.
+func ParseSESNotification(body []byte) ([]SuppressionEvent, string, error) {
+	var envelope snsEnvelope
+	if err := json.Unmarshal(body, &envelope); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+
+	switch envelope.Type {
+	case "SubscriptionConfirmation":
+		u, err := url.Parse(envelope.SubscribeURL)
+		if err != nil || u.Scheme != "https" || !strings.HasSuffix(u.Hostname(), ".amazonaws.com") {
+			return nil, "", fmt.Errorf("%w: unexpected subscribe URL", ErrInvalidWebhookPayload)
+		}
+		return nil, envelope.SubscribeURL, nil
+	case "Notification":
+	default:
+		return nil, "", fmt.Errorf("%w: unsupported SNS message type %q", ErrInvalidWebhookPayload, envelope.Type)
+	}
+
+	var n sesNotification
+	if err := json.Unmarshal([]byte(envelope.Message), &n); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+
+	var events []SuppressionEvent
+	switch n.NotificationType {
+	case "Bounce":
+		if n.Bounce.BounceType != "Permanent" {
+			return nil, "", nil
+		}
+		for _, r := range n.Bounce.BouncedRecipients {
+			detail := r.DiagnosticCode
+			if detail == "" {
+				detail = n.Bounce.BounceSubType
+			}
+			events = append(events, SuppressionEvent{
+				Email:    r.EmailAddress,
+				Reason:   models.EmailSuppressionBounced,
+				Provider: "ses",
+				Detail:   detail,
+			})
+		}
+	case "Complaint":
+		for _, r := range n.Complaint.ComplainedRecipients {
+			events = append(events, SuppressionEvent{
+				Email:    r.EmailAddress,
+				Reason:   models.EmailSuppressionComplained,
+				Provider: "ses",
+				Detail:   n.Complaint.ComplaintFeedbackType,
+			})
+		}
+	}
+
+	return events, "", nil
+}
+
+// sendGridEvent is a single entry of a SendGrid Event Webhook batch
+type sendGridEvent struct {
+	Email  string `json:"email"`
+	Event  string `json:"event"`
+	Type   string `json:"type"`
+	Reason string `json:"reason"`
+}
+
+// ParseSendGridEvents parses a SendGrid Event Webhook batch. Hard bounces and spam reports
+// produce events, blocks are temporary and other event types are ignored.
+func ParseSendGridEvents(body []byte) ([]SuppressionEvent, error) {
+	var batch []sendGridEvent
+	if err := json.Unmarshal(body, &batch); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidWebhookPayload, err)
+	}
+
+	var events []SuppressionEvent
+	for _, e := range batch {
+		switch {
+		case e.Event == "bounce" && e.Type != "blocked":
+			events = append(events, SuppressionEvent{
+				Email:    e.Email,
+				Reason:   models.EmailSuppressionBounced,
+				Provider: "sendgrid",
+				Detail:   e.Reason,
+			})
+		case e.Event == "spamreport":
+			events = append(events, SuppressionEvent{
+				Email:    e.Email,
+				Reason:   models.EmailSuppressionComplained,
+				Provider: "sendgrid",
+			})
+		}
+	}
+
+	return events, nil
+}
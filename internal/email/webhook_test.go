@@ -0,0 +1,125 @@
+package email
+
+import (
+	"encoding/json"
+	"testing"
+	"wattwatch/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func snsMessage(t *testing.T, msgType string, message interface{}) []byte {
+	t.Helper()
+	inner, err := json.Marshal(message)
+	require.NoError(t, err)
+	body, err := json.Marshal(map[string]string{
+		"Type":    msgType,
+		"Message": string(inner),
+	})
+	require.NoError(t, err)
+	return body
+}
+
+func TestParseSESNotification(t *testing.T) {
+	tests := []struct {
+		name          string
+		body          []byte
+		wantEvents    []SuppressionEvent
+		wantSubscribe string
+		wantErr       bool
+	}{
+		{
+			name: "Permanent Bounce",
+			body: snsMessage(t, "Notification", map[string]interface{}{
+				"notificationType": "Bounce",
+				"bounce": map[string]interface{}{
+					"bounceType":    "Permanent",
+					"bounceSubType": "General",
+					"bouncedRecipients": []map[string]string{
+						{"emailAddress": "gone@example.com", "diagnosticCode": "smtp; 550 5.1.1 user unknown"},
+					},
+				},
+			}),
+			wantEvents: []SuppressionEvent{{
+				Email:    "gone@example.com",
+				Reason:   models.EmailSuppressionBounced,
+				Provider: "ses",
+				Detail:   "smtp; 550 5.1.1 user unknown",
+			}},
+		},
+		{
+			name: "Transient Bounce Ignored",
+			body: snsMessage(t, "Notification", map[string]interface{}{
+				"notificationType": "Bounce",
+				"bounce": map[string]interface{}{
+					"bounceType":        "Transient",
+					"bouncedRecipients": []map[string]string{{"emailAddress": "full@example.com"}},
+				},
+			}),
+		},
+		{
+			name: "Complaint",
+			body: snsMessage(t, "Notification", map[string]interface{}{
+				"notificationType": "Complaint",
+				"complaint": map[string]interface{}{
+					"complaintFeedbackType": "abuse",
+					"complainedRecipients":  []map[string]string{{"emailAddress": "angry@example.com"}},
+				},
+			}),
+			wantEvents: []SuppressionEvent{{
+				Email:    "angry@example.com",
+				Reason:   models.EmailSuppressionComplained,
+				Provider: "ses",
+				Detail:   "abuse",
+			}},
+		},
+		{
+			name:          "Subscription Confirmation",
+			body:          []byte(`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://sns.eu-north-1.amazonaws.com/?Action=ConfirmSubscription"}`),
+			wantSubscribe: "https://sns.eu-north-1.amazonaws.com/?Action=ConfirmSubscription",
+		},
+		{
+			name:    "Subscription Confirmation To Foreign Host",
+			body:    []byte(`{"Type":"SubscriptionConfirmation","SubscribeURL":"https://evil.example.com/"}`),
+			wantErr: true,
+		},
+		{
+			name:    "Invalid JSON",
+			body:    []byte(`not json`),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, subscribeURL, err := ParseSESNotification(tt.body)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidWebhookPayload)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantEvents, events)
+			assert.Equal(t, tt.wantSubscribe, subscribeURL)
+		})
+	}
+}
+
+func TestParseSendGridEvents(t *testing.T) {
+	body := []byte(`[
+		{"email":"gone@example.com","event":"bounce","type":"bounce","reason":"550 user unknown"},
+		{"email":"busy@example.com","event":"bounce","type":"blocked","reason":"rate limited"},
+		{"email":"angry@example.com","event":"spamreport"},
+		{"email":"fine@example.com","event":"delivered"}
+	]`)
+
+	events, err := ParseSendGridEvents(body)
+	require.NoError(t, err)
+	assert.Equal(t, []SuppressionEvent{
+		{Email: "gone@example.com", Reason: models.EmailSuppressionBounced, Provider: "sendgrid", Detail: "550 user unknown"},
+		{Email: "angry@example.com", Reason: models.EmailSuppressionComplained, Provider: "sendgrid"},
+	}, events)
+
+	_, err = ParseSendGridEvents([]byte(`{"email":"not-a-batch"}`))
+	require.ErrorIs(t, err, ErrInvalidWebhookPayload)
+}
@@ -0,0 +1,90 @@
+// Package exchangerate converts amounts between currencies with stored exchange rates and
+// fetches the reference rates the European Central Bank publishes
+package exchangerate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// ErrNoRate is returned when no exchange rate is in effect for a conversion
+var ErrNoRate = errors.New("no exchange rate available")
+
+// Converter converts amounts from one currency to another with the rates in effect over a
+// period
+type Converter struct {
+	// timestamps and rates hold the rates in the order they took effect
+	timestamps []time.Time
+	rates      []float64
+}
+
+// NewConverter loads the rates converting from one currency to another between start and
+// end. Rates stored for the opposite direction are inverted when the pair has none. It
+// returns ErrNoRate when no rate is in effect at start.
+func NewConverter(ctx context.Context, repo repository.ExchangeRateRepository, from, to uuid.UUID, start, end time.Time) (*Converter, error) {
+	if from == to {
+		return &Converter{timestamps: []time.Time{start}, rates: []float64{1}}, nil
+	}
+
+	rates, err := repo.Effective(ctx, from, to, start, end)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+	}
+	inverse := false
+	if len(rates) == 0 || rates[0].Timestamp.After(start) {
+		inverted, err := repo.Effective(ctx, to, from, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get exchange rates: %w", err)
+		}
+		if len(inverted) > 0 && !inverted[0].Timestamp.After(start) {
+			rates, inverse = inverted, true
+		}
+	}
+	if len(rates) == 0 || rates[0].Timestamp.After(start) {
+		return nil, ErrNoRate
+	}
+
+	return newConverter(rates, inverse), nil
+}
+
+// newConverter creates a converter from rates ordered by timestamp, inverting them when
+// they convert in the opposite direction
+func newConverter(rates []models.ExchangeRate, inverse bool) *Converter {
+	c := &Converter{
+		timestamps: make([]time.Time, len(rates)),
+		rates:      make([]float64, len(rates)),
+	}
+	for i, rate := range rates {
+		c.timestamps[i] = rate.Timestamp
+		c.rates[i] = rate.Rate
+		if inverse {
+			c.rates[i] = 1 / rate.Rate
+		}
+	}
+	return c
+}
+
+// Rate returns the rate in effect at the given time, the last one set at or before it
+func (c *Converter) Rate(at time.Time) (float64, bool) {
+	i := sort.Search(len(c.timestamps), func(i int) bool { return c.timestamps[i].After(at) })
+	if i == 0 {
+		return 0, false
+	}
+	return c.rates[i-1], true
+}
+
+// Convert converts the amount with the rate in effect at the given time
+func (c *Converter) Convert(amount float64, at time.Time) (float64, error) {
+	rate, ok := c.Rate(at)
+	if !ok {
+		return 0, ErrNoRate
+	}
+	return amount * rate, nil
+}
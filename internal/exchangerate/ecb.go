@@ -0,0 +1,133 @@
+package exchangerate
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+const (
+	// ECBURL is the URL of the euro foreign exchange reference rates the ECB publishes
+	// every working day
+	ECBURL = "https://www.ecb.europa.eu/stats/eurofxref/eurofxref-daily.xml"
+	// ECBBaseCurrency is the currency the ECB reference rates are quoted against
+	ECBBaseCurrency = "EUR"
+)
+
+// ecbEnvelope is the reference rate document, a cube per day holding a cube per currency
+type ecbEnvelope struct {
+	Days []struct {
+		Time  string `xml:"time,attr"`
+		Rates []struct {
+			Currency string `xml:"currency,attr"`
+			Rate     string `xml:"rate,attr"`
+		} `xml:"Cube"`
+	} `xml:"Cube>Cube"`
+}
+
+// ECBFetcher stores the ECB reference rates of the currencies in the currencies table
+type ECBFetcher struct {
+	rates      repository.ExchangeRateRepository
+	currencies repository.CurrencyRepository
+	client     *http.Client
+	url        string
+}
+
+// NewECBFetcher creates a fetcher storing rates in rates
+func NewECBFetcher(rates repository.ExchangeRateRepository, currencies repository.CurrencyRepository) *ECBFetcher {
+	return &ECBFetcher{
+		rates:      rates,
+		currencies: currencies,
+		client:     &http.Client{Timeout: 10 * time.Second},
+		url:        ECBURL,
+	}
+}
+
+// Run fetches the latest reference rates and stores those of known currencies from EUR,
+// taking effect at midnight UTC of the day they were published for
+func (f *ECBFetcher) Run(ctx context.Context) error {
+	currencies, err := f.currencies.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list currencies: %w", err)
+	}
+	var base *models.Currency
+	known := make(map[string]models.Currency, len(currencies))
+	for _, currency := range currencies {
+		if currency.Name == ECBBaseCurrency {
+			base = &currency
+			continue
+		}
+		known[currency.Name] = currency
+	}
+	if base == nil || len(known) == 0 {
+		log.Printf("Skipping ECB exchange rates, no currencies to convert %s to", ECBBaseCurrency)
+		return nil
+	}
+
+	envelope, err := f.fetch(ctx)
+	if err != nil {
+		return err
+	}
+
+	var rates []models.ExchangeRate
+	for _, day := range envelope.Days {
+		date, err := time.Parse(time.DateOnly, day.Time)
+		if err != nil {
+			return fmt.Errorf("invalid date %q: %w", day.Time, err)
+		}
+		for _, entry := range day.Rates {
+			quote, ok := known[entry.Currency]
+			if !ok {
+				continue
+			}
+			rate, err := strconv.ParseFloat(entry.Rate, 64)
+			if err != nil || rate <= 0 {
+				return fmt.Errorf("invalid rate %q for %s", entry.Rate, entry.Currency)
+			}
+			rates = append(rates, models.ExchangeRate{
+				BaseCurrencyID:  base.ID,
+				QuoteCurrencyID: quote.ID,
+				Timestamp:       date,
+				Rate:            rate,
+				Source:          models.ExchangeRateSourceECB,
+			})
+		}
+	}
+
+	if err := f.rates.CreateBatch(ctx, rates); err != nil {
+		return fmt.Errorf("failed to store exchange rates: %w", err)
+	}
+	log.Printf("Stored %d ECB exchange rates", len(rates))
+	return nil
+}
+
+// fetch downloads and decodes the reference rate document
+func (f *ECBFetcher) fetch(ctx context.Context) (*ecbEnvelope, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, f.url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch exchange rates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return nil, fmt.Errorf("unexpected status code %d: %s", resp.StatusCode, body)
+	}
+
+	var envelope ecbEnvelope
+	if err := xml.NewDecoder(resp.Body).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("failed to decode exchange rates: %w", err)
+	}
+	return &envelope, nil
+}
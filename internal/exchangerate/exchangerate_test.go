@@ -0,0 +1,114 @@
+package exchangerate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ecbDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2025-03-20">
+			<Cube currency="USD" rate="1.0827"/>
+			<Cube currency="SEK" rate="11.0115"/>
+		</Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestECBFetcher(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	rates := memory.NewExchangeRateRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(ecbDocument))
+	}))
+	defer server.Close()
+
+	fetcher := NewECBFetcher(rates, currencies)
+	fetcher.url = server.URL
+	require.NoError(t, fetcher.Run(ctx))
+
+	eur, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	sek, err := currencies.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+
+	// USD isn't a known currency, so only SEK is stored
+	stored, err := rates.List(ctx, repository.ExchangeRateFilter{})
+	require.NoError(t, err)
+	require.Len(t, stored, 1)
+	assert.Equal(t, eur.ID, stored[0].BaseCurrencyID)
+	assert.Equal(t, sek.ID, stored[0].QuoteCurrencyID)
+	assert.Equal(t, time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC), stored[0].Timestamp)
+	assert.Equal(t, 11.0115, stored[0].Rate)
+	assert.Equal(t, models.ExchangeRateSourceECB, stored[0].Source)
+
+	t.Run("Server Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		fetcher := NewECBFetcher(rates, currencies)
+		fetcher.url = server.URL
+		assert.Error(t, fetcher.Run(ctx))
+	})
+}
+
+func TestConverter(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	rates := memory.NewExchangeRateRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+
+	eur, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	sek, err := currencies.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+
+	day := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, rates.CreateBatch(ctx, []models.ExchangeRate{
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day.AddDate(0, 0, -1), Rate: 10, Source: models.ExchangeRateSourceManual},
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day, Rate: 11, Source: models.ExchangeRateSourceManual},
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day.AddDate(0, 0, 1), Rate: 12, Source: models.ExchangeRateSourceManual},
+	}))
+
+	t.Run("Rate In Effect", func(t *testing.T) {
+		converter, err := NewConverter(ctx, rates, eur.ID, sek.ID, day.Add(6*time.Hour), day.Add(30*time.Hour))
+		require.NoError(t, err)
+
+		amount, err := converter.Convert(2, day.Add(12*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 22.0, amount)
+
+		amount, err = converter.Convert(2, day.Add(26*time.Hour))
+		require.NoError(t, err)
+		assert.Equal(t, 24.0, amount)
+	})
+
+	t.Run("Inverse", func(t *testing.T) {
+		converter, err := NewConverter(ctx, rates, sek.ID, eur.ID, day, day.Add(time.Hour))
+		require.NoError(t, err)
+
+		amount, err := converter.Convert(22, day)
+		require.NoError(t, err)
+		assert.InDelta(t, 2.0, amount, 1e-9)
+	})
+
+	t.Run("No Rate", func(t *testing.T) {
+		_, err := NewConverter(ctx, rates, eur.ID, sek.ID, day.AddDate(0, 0, -2), day)
+		assert.ErrorIs(t, err, ErrNoRate)
+	})
+}
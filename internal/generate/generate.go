@@ -0,0 +1,238 @@
+// Package generate fills a database with synthetic zones, spot prices and users, so
+// performance work can be tested against production sized datasets
+package generate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+// batchSize is the number of spot prices inserted per statement, well below the limit of
+// 65535 query parameters
+const batchSize = 1000
+
+// UsernamePrefix starts the name of every generated user, so they are easy to find and remove
+const UsernamePrefix = "loadtest-"
+
+// zoneTemplates are real bidding zones, used in order when more zones are needed than exist
+var zoneTemplates = []models.Zone{
+	{Name: "SE1", Timezone: "Europe/Stockholm"},
+	{Name: "SE2", Timezone: "Europe/Stockholm"},
+	{Name: "SE3", Timezone: "Europe/Stockholm"},
+	{Name: "SE4", Timezone: "Europe/Stockholm"},
+	{Name: "FI", Timezone: "Europe/Helsinki"},
+	{Name: "NO1", Timezone: "Europe/Oslo"},
+	{Name: "NO2", Timezone: "Europe/Oslo"},
+	{Name: "NO3", Timezone: "Europe/Oslo"},
+	{Name: "NO4", Timezone: "Europe/Oslo"},
+	{Name: "NO5", Timezone: "Europe/Oslo"},
+	{Name: "DK1", Timezone: "Europe/Copenhagen"},
+	{Name: "DK2", Timezone: "Europe/Copenhagen"},
+	{Name: "EE", Timezone: "Europe/Tallinn"},
+	{Name: "LV", Timezone: "Europe/Riga"},
+	{Name: "LT", Timezone: "Europe/Vilnius"},
+}
+
+// MaxZones is the largest number of zones that can be generated
+var MaxZones = len(zoneTemplates)
+
+// Options controls the size of the generated dataset
+type Options struct {
+	// Zones is the number of zones that get prices, existing zones are reused
+	Zones int
+	// Years of hourly prices are generated up to End
+	Years int
+	// Users is the number of regular users that exist once generation is done
+	Users int
+	// Currency of the generated prices
+	Currency string
+	// Password of every generated user
+	Password string
+	// Seed makes runs with the same options produce the same prices
+	Seed uint64
+	// End is the hour the price series stops before, the next midnight UTC when zero
+	End time.Time
+}
+
+// Generator writes synthetic data through the repositories
+type Generator struct {
+	zoneRepo      repository.ZoneRepository
+	currencyRepo  repository.CurrencyRepository
+	spotPriceRepo repository.SpotPriceRepository
+	userRepo      repository.UserRepository
+	roleRepo      repository.RoleRepository
+	authService   *auth.Service
+	out           io.Writer
+}
+
+// NewGenerator creates a Generator reporting progress to out
+func NewGenerator(
+	zoneRepo repository.ZoneRepository,
+	currencyRepo repository.CurrencyRepository,
+	spotPriceRepo repository.SpotPriceRepository,
+	userRepo repository.UserRepository,
+	roleRepo repository.RoleRepository,
+	authService *auth.Service,
+	out io.Writer,
+) *Generator {
+	return &Generator{
+		zoneRepo:      zoneRepo,
+		currencyRepo:  currencyRepo,
+		spotPriceRepo: spotPriceRepo,
+		userRepo:      userRepo,
+		roleRepo:      roleRepo,
+		authService:   authService,
+		out:           out,
+	}
+}
+
+// Run generates the dataset. Prices are upserted and existing users are kept, so running
+// it again with the same options leaves the database unchanged.
+func (g *Generator) Run(ctx context.Context, opts Options) error {
+	if opts.Zones < 0 || opts.Zones > MaxZones {
+		return fmt.Errorf("zones must be between 0 and %d, got %d", MaxZones, opts.Zones)
+	}
+	if opts.Years < 0 || opts.Users < 0 {
+		return errors.New("years and users must not be negative")
+	}
+	end := opts.End
+	if end.IsZero() {
+		end = time.Now().UTC().Truncate(24 * time.Hour).Add(24 * time.Hour)
+	}
+	start := end.AddDate(-opts.Years, 0, 0)
+
+	if opts.Zones > 0 && opts.Years > 0 {
+		currency, err := g.currency(ctx, opts.Currency)
+		if err != nil {
+			return err
+		}
+		for i, template := range zoneTemplates[:opts.Zones] {
+			zone, err := g.zone(ctx, template)
+			if err != nil {
+				return err
+			}
+			count, err := g.prices(ctx, zone, currency, i, opts.Seed, start, end)
+			if err != nil {
+				return fmt.Errorf("failed to generate prices for zone %s: %w", zone.Name, err)
+			}
+			fmt.Fprintf(g.out, "Zone %s: %d hourly prices from %s to %s\n",
+				zone.Name, count, start.Format(time.DateOnly), end.Format(time.DateOnly))
+		}
+	}
+
+	if opts.Users > 0 {
+		created, err := g.users(ctx, opts.Users, opts.Password)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(g.out, "Users: %d created, %d already existed\n", created, opts.Users-created)
+	}
+	return nil
+}
+
+func (g *Generator) currency(ctx context.Context, name string) (*models.Currency, error) {
+	currency, err := g.currencyRepo.GetByName(ctx, name)
+	if err == nil {
+		return currency, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get currency %s: %w", name, err)
+	}
+	currency = &models.Currency{Name: name}
+	if err := g.currencyRepo.Create(ctx, currency); err != nil {
+		return nil, fmt.Errorf("failed to create currency %s: %w", name, err)
+	}
+	return currency, nil
+}
+
+func (g *Generator) zone(ctx context.Context, template models.Zone) (*models.Zone, error) {
+	zone, err := g.zoneRepo.GetByName(ctx, template.Name)
+	if err == nil {
+		return zone, nil
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to get zone %s: %w", template.Name, err)
+	}
+	zone = &models.Zone{Name: template.Name, Timezone: template.Timezone}
+	if err := g.zoneRepo.Create(ctx, zone); err != nil {
+		return nil, fmt.Errorf("failed to create zone %s: %w", template.Name, err)
+	}
+	return zone, nil
+}
+
+// prices upserts an hourly series for zone between start and end and returns its length
+func (g *Generator) prices(ctx context.Context, zone *models.Zone, currency *models.Currency, index int, seed uint64, start, end time.Time) (int, error) {
+	location, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		location = time.UTC
+	}
+	model := newPriceModel(seed, index, location)
+
+	var count int
+	batch := make([]models.SpotPrice, 0, batchSize)
+	for t := start; t.Before(end); t = t.Add(time.Hour) {
+		batch = append(batch, models.SpotPrice{
+			Timestamp:  t,
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      model.next(t),
+		})
+		if len(batch) == batchSize {
+			if err := g.spotPriceRepo.CreateBatch(ctx, batch); err != nil {
+				return count, err
+			}
+			count += len(batch)
+			batch = batch[:0]
+		}
+	}
+	if err := g.spotPriceRepo.CreateBatch(ctx, batch); err != nil {
+		return count, err
+	}
+	return count + len(batch), nil
+}
+
+// users creates the missing users named loadtest-00001 and up and returns how many it created
+func (g *Generator) users(ctx context.Context, n int, password string) (int, error) {
+	role, err := g.roleRepo.GetByName(ctx, "user")
+	if err != nil {
+		return 0, fmt.Errorf("failed to get role user: %w", err)
+	}
+	// Hashing is slow on purpose, every user shares one hash
+	hashed, err := g.authService.HashPassword(password)
+	if err != nil {
+		return 0, fmt.Errorf("failed to hash password: %w", err)
+	}
+
+	var created int
+	for i := 1; i <= n; i++ {
+		username := fmt.Sprintf("%s%05d", UsernamePrefix, i)
+		existing, err := g.userRepo.GetByUsername(ctx, username)
+		if err != nil && !errors.Is(err, repository.ErrUserNotFound) {
+			return created, fmt.Errorf("failed to check user %s: %w", username, err)
+		}
+		if existing != nil {
+			continue
+		}
+
+		email := username + "@example.com"
+		user := &models.User{
+			Username:      username,
+			Password:      hashed,
+			Email:         &email,
+			EmailVerified: true,
+			RoleID:        role.ID,
+			Role:          role,
+		}
+		if err := g.userRepo.Create(ctx, user); err != nil {
+			return created, fmt.Errorf("failed to create user %s: %w", username, err)
+		}
+		created++
+	}
+	return created, nil
+}
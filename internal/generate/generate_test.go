@@ -0,0 +1,48 @@
+package generate_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/generate"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerator_Run(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	spotPriceRepo := postgres.NewSpotPriceRepository(tc.DB)
+	out := &bytes.Buffer{}
+	generator := generate.NewGenerator(tc.ZoneRepo, tc.CurrencyRepo, spotPriceRepo, tc.UserRepo, tc.RoleRepo, tc.AuthService, out)
+
+	end := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	opts := generate.Options{Zones: 2, Years: 1, Users: 3, Currency: "EUR", Password: "password123", Seed: 7, End: end}
+	require.NoError(t, generator.Run(ctx, opts))
+	require.Contains(t, out.String(), "Users: 3 created")
+
+	zone, err := tc.ZoneRepo.GetByName(ctx, "SE2")
+	require.NoError(t, err)
+	limit := 10000
+	prices, err := spotPriceRepo.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID, Limit: &limit})
+	require.NoError(t, err)
+	require.Len(t, prices, 366*24)
+
+	user, err := tc.UserRepo.GetByUsername(ctx, generate.UsernamePrefix+"00003")
+	require.NoError(t, err)
+	require.NoError(t, tc.AuthService.ComparePasswords(user.Password, "password123"))
+
+	// Running again keeps the existing data
+	out.Reset()
+	require.NoError(t, generator.Run(ctx, opts))
+	require.Contains(t, out.String(), "Users: 0 created, 3 already existed")
+	prices, err = spotPriceRepo.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID, Limit: &limit})
+	require.NoError(t, err)
+	require.Len(t, prices, 366*24)
+
+	require.Error(t, generator.Run(ctx, generate.Options{Zones: generate.MaxZones + 1}))
+}
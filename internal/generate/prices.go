@@ -0,0 +1,62 @@
+package generate
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// hourlyShape is the typical price of each local hour relative to the daily average, with
+// a morning and a larger evening peak and cheap nights
+var hourlyShape = [24]float64{
+	0.78, 0.74, 0.72, 0.71, 0.73, 0.80, 0.95, 1.12, 1.22, 1.15, 1.05, 0.98,
+	0.94, 0.92, 0.94, 1.00, 1.10, 1.25, 1.32, 1.28, 1.16, 1.02, 0.92, 0.84,
+}
+
+// priceModel produces an hourly day-ahead price series in EUR/MWh. Prices follow the
+// season, the hour of day and the weekday, with autocorrelated noise, rare spikes and the
+// occasional negative hour on sunny summer weekends.
+type priceModel struct {
+	rng      *rand.Rand
+	location *time.Location
+	// base is the average price, volatility the size of the hour to hour noise
+	base       float64
+	volatility float64
+	noise      float64
+}
+
+func newPriceModel(seed uint64, zoneIndex int, location *time.Location) *priceModel {
+	rng := rand.New(rand.NewPCG(seed, uint64(zoneIndex)))
+	return &priceModel{
+		rng:      rng,
+		location: location,
+		// Zones differ in level and volatility like northern and southern bidding zones do
+		base:       35 + 12*float64(zoneIndex%5) + rng.Float64()*8,
+		volatility: 0.04 + 0.01*float64(zoneIndex%3),
+	}
+}
+
+// next returns the price for hour t. Calls must be made for consecutive hours as the
+// noise carries over from one hour to the next.
+func (m *priceModel) next(t time.Time) float64 {
+	local := t.In(m.location)
+
+	// Prices peak in mid January and bottom out in mid July
+	season := 1 + 0.35*math.Cos(2*math.Pi*float64(local.YearDay()-15)/365.25)
+	daily := hourlyShape[local.Hour()]
+	weekday := 1.0
+	if local.Weekday() == time.Saturday || local.Weekday() == time.Sunday {
+		weekday = 0.85
+	}
+
+	m.noise = 0.92*m.noise + m.rng.NormFloat64()*m.volatility
+	price := m.base * season * daily * weekday * (1 + m.noise)
+
+	switch {
+	case m.rng.Float64() < 0.002:
+		price *= 3 + m.rng.Float64()*3
+	case season < 0.8 && weekday < 1 && local.Hour() >= 11 && local.Hour() <= 15 && m.rng.Float64() < 0.15:
+		price = -m.rng.Float64() * 10
+	}
+	return math.Round(price*100) / 100
+}
@@ -0,0 +1,55 @@
+package generate
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// series returns a year of hourly prices starting on 1 January 2024
+func series(seed uint64, zoneIndex int) map[time.Time]float64 {
+	location, _ := time.LoadLocation("Europe/Stockholm")
+	model := newPriceModel(seed, zoneIndex, location)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	prices := make(map[time.Time]float64)
+	for t := start; t.Before(start.AddDate(1, 0, 0)); t = t.Add(time.Hour) {
+		prices[t] = model.next(t)
+	}
+	return prices
+}
+
+func mean(prices map[time.Time]float64, include func(time.Time) bool) float64 {
+	var sum float64
+	var n int
+	for t, p := range prices {
+		if include(t) {
+			sum += p
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+func TestPriceModel(t *testing.T) {
+	prices := series(1, 0)
+	require.Len(t, prices, 366*24)
+
+	// The same seed produces the same series, another zone a different one
+	assert.Equal(t, prices, series(1, 0))
+	assert.NotEqual(t, prices, series(1, 1))
+
+	for ts, price := range prices {
+		require.Greater(t, price, -20.0, ts)
+		require.Less(t, price, 1000.0, ts)
+	}
+
+	winter := mean(prices, func(t time.Time) bool { return t.Month() == time.January })
+	summer := mean(prices, func(t time.Time) bool { return t.Month() == time.July })
+	assert.Greater(t, winter, summer*1.5, "winter prices are higher")
+
+	evening := mean(prices, func(t time.Time) bool { return t.UTC().Hour() == 17 })
+	night := mean(prices, func(t time.Time) bool { return t.UTC().Hour() == 2 })
+	assert.Greater(t, evening, night*1.3, "evening prices are higher than night prices")
+}
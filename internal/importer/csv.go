@@ -0,0 +1,280 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+	"wattwatch/internal/models"
+)
+
+// errTooManyRows is returned when a file holds more rows than are read
+var errTooManyRows = fmt.Errorf("%w: at most %d readings can be imported at once", ErrInput, MaxRows)
+
+// Keywords of the header names columns are detected by, in order of preference. A column
+// matches when its lowercased name contains the keyword. The Swedish ones are used by grid
+// operators such as Ellevio, Vattenfall Eldistribution and E.ON.
+var (
+	timestampKeywords = []string{"timestamp", "från", "from", "start", "tidpunkt", "datum", "date", "time", "tid", "period"}
+	kwhKeywords       = []string{"kwh", "förbrukning", "consumption", "energi", "energy", "värde", "value"}
+	meterKeywords     = []string{"anläggnings", "anläggning", "mätpunkt", "meter", "metering point"}
+)
+
+// Names of a column holding the time of day next to one holding the date
+var (
+	dateColumns = []string{"datum", "date"}
+	timeColumns = []string{"tid", "time", "klockslag"}
+)
+
+// timestampLayouts are tried in order when the mapping has no layout
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+	"2006-01-02 15.04",
+	"2006-01-02",
+}
+
+// csvReader reads the rows of a CSV import with a column mapping
+type csvReader struct {
+	reader  *csv.Reader
+	format  string
+	columns []string
+	mapping models.ConsumptionImportMapping
+
+	// Resolved from the mapping, column indexes are -1 when unused
+	location                         *time.Location
+	timestamp, timeOfDay, kwh, meter int
+	scale                            float64
+}
+
+// openCSV reads the header of a CSV import, detecting its delimiter unless the mapping
+// has one
+func openCSV(r io.Reader, format string, mapping models.ConsumptionImportMapping) (*csvReader, error) {
+	buffered := bufio.NewReader(r)
+	if mapping.Delimiter == "" {
+		// Peek returns what it could read along with an error for short files
+		head, _ := buffered.Peek(4096)
+		mapping.Delimiter = detectDelimiter(head)
+	}
+	delimiter, size := utf8.DecodeRuneInString(mapping.Delimiter)
+	if size != len(mapping.Delimiter) || delimiter == '"' || delimiter == '\r' || delimiter == '\n' || delimiter == utf8.RuneError {
+		return nil, fmt.Errorf("%w: delimiter must be a single character", ErrInput)
+	}
+
+	reader := csv.NewReader(buffered)
+	reader.Comma = delimiter
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.TrimLeadingSpace = delimiter != ' ' && delimiter != '\t'
+
+	header, err := reader.Read()
+	if err == io.EOF {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInput)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInput, err)
+	}
+	for i, name := range header {
+		// Spreadsheets often start UTF-8 files with a byte order mark
+		header[i] = strings.TrimSpace(strings.TrimPrefix(name, "\ufeff"))
+	}
+	return &csvReader{reader: reader, format: format, columns: header, mapping: mapping}, nil
+}
+
+// detectDelimiter returns the most common of the usual delimiters on the first line
+func detectDelimiter(head []byte) string {
+	if i := bytes.IndexByte(head, '\n'); i >= 0 {
+		head = head[:i]
+	}
+	best, count := ",", 0
+	for _, delimiter := range []string{",", ";", "\t"} {
+		if n := bytes.Count(head, []byte(delimiter)); n > count {
+			best, count = delimiter, n
+		}
+	}
+	return best
+}
+
+// resolve finds the columns of the mapping, detecting those it leaves out, and checks the
+// rest of the mapping. The detected columns are filled into the mapping.
+func (cr *csvReader) resolve() error {
+	m := &cr.mapping
+	taken := make(map[int]bool)
+	var err error
+	if cr.timestamp, err = cr.column(&m.TimestampColumn, "timestamp", timestampKeywords, taken, true); err != nil {
+		return err
+	}
+	if m.TimeColumn == "" && containsFold(dateColumns, m.TimestampColumn) {
+		for i, name := range cr.columns {
+			if !taken[i] && containsFold(timeColumns, name) {
+				m.TimeColumn = name
+				break
+			}
+		}
+	}
+	if cr.timeOfDay, err = cr.column(&m.TimeColumn, "time", nil, taken, false); err != nil {
+		return err
+	}
+	if cr.kwh, err = cr.column(&m.KWhColumn, "kWh", kwhKeywords, taken, true); err != nil {
+		return err
+	}
+	if m.MeterID != "" && m.MeterColumn == "" {
+		cr.meter = -1
+	} else if cr.meter, err = cr.column(&m.MeterColumn, "meter", meterKeywords, taken, false); err != nil {
+		return err
+	}
+	if cr.meter < 0 && m.MeterID == "" {
+		return fmt.Errorf("%w: meter_id is required when the file has no meter column", ErrInput)
+	}
+
+	if m.Timezone == "" {
+		m.Timezone = "UTC"
+		if cr.format == models.ConsumptionImportSwedishGrid {
+			m.Timezone = "Europe/Stockholm"
+		}
+	}
+	if cr.location, err = time.LoadLocation(m.Timezone); err != nil {
+		return fmt.Errorf("%w: unknown timezone %q", ErrInput, m.Timezone)
+	}
+
+	switch strings.ToLower(m.Unit) {
+	case "", "kwh":
+		m.Unit, cr.scale = "kWh", 1
+	case "wh":
+		m.Unit, cr.scale = "Wh", 0.001
+	default:
+		return fmt.Errorf("%w: unit must be kWh or Wh, got %q", ErrInput, m.Unit)
+	}
+	return nil
+}
+
+// column returns the index of the column named by *name, or the first column not taken
+// matching one of the keywords when the name is empty, which is then set to that column.
+// It returns -1 when there is no such column and it isn't required.
+func (cr *csvReader) column(name *string, what string, keywords []string, taken map[int]bool, required bool) (int, error) {
+	if *name != "" {
+		for i, column := range cr.columns {
+			if strings.EqualFold(column, strings.TrimSpace(*name)) {
+				taken[i] = true
+				*name = column
+				return i, nil
+			}
+		}
+		return -1, fmt.Errorf("%w: no column named %q", ErrInput, *name)
+	}
+	for _, keyword := range keywords {
+		for i, column := range cr.columns {
+			if !taken[i] && strings.Contains(strings.ToLower(column), keyword) {
+				taken[i] = true
+				*name = column
+				return i, nil
+			}
+		}
+	}
+	if required {
+		return -1, fmt.Errorf("%w: no %s column found, name it in the mapping", ErrInput, what)
+	}
+	return -1, nil
+}
+
+// readRows reads up to n rows, returning errTooManyRows when more follow
+func (cr *csvReader) readRows(n int) ([]Row, error) {
+	var rows []Row
+	for {
+		record, err := cr.reader.Read()
+		if err == io.EOF {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInput, err)
+		}
+		if len(rows) == n {
+			return rows, errTooManyRows
+		}
+		line, _ := cr.reader.FieldPos(0)
+		rows = append(rows, cr.row(line, record))
+	}
+}
+
+// row reads the reading in record
+func (cr *csvReader) row(line int, record []string) Row {
+	row := Row{Line: line, MeterID: cr.mapping.MeterID}
+	field := func(i int) string {
+		if i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	value := field(cr.timestamp)
+	if cr.timeOfDay >= 0 {
+		value += " " + field(cr.timeOfDay)
+	}
+	if row.Timestamp, row.Err = parseTimestamp(strings.TrimSpace(value), cr.mapping.TimestampLayout, cr.location); row.Err != nil {
+		return row
+	}
+	kwh, err := parseNumber(field(cr.kwh))
+	if err != nil {
+		row.Err = fmt.Errorf("invalid kWh %q", field(cr.kwh))
+		return row
+	}
+	row.KWh = kwh * cr.scale
+	// Rows without a meter of their own are read for the mapping's
+	if cr.meter >= 0 && field(cr.meter) != "" {
+		row.MeterID = field(cr.meter)
+	}
+	validate(&row)
+	return row
+}
+
+// parseTimestamp parses a timestamp with layout, or the first of timestampLayouts that
+// fits when layout is empty. Times without an offset are in loc.
+func parseTimestamp(value, layout string, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("missing timestamp")
+	}
+	layouts := timestampLayouts
+	if layout != "" {
+		layouts = []string{layout}
+	}
+	for _, layout := range layouts {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", value)
+}
+
+// parseNumber parses a number written with a decimal point or, as in Swedish, a decimal
+// comma, ignoring spaces between groups of digits
+func parseNumber(s string) (float64, error) {
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "").Replace(s)
+	if strings.Contains(s, ",") && !strings.Contains(s, ".") {
+		s = strings.Replace(s, ",", ".", 1)
+	}
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+		return 0, errors.New("invalid number")
+	}
+	return v, nil
+}
+
+// containsFold reports whether names holds name, ignoring case
+func containsFold(names []string, name string) bool {
+	for _, n := range names {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
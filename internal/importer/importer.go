@@ -0,0 +1,149 @@
+// Package importer reads the meter readings of consumption imports: CSV files mapped column
+// by column, the CSV exports of Swedish grid operators and the consumption Tibber's API
+// returns
+package importer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+	"wattwatch/internal/models"
+)
+
+// MaxRows is the number of readings a single import may hold, a year of quarter hours
+const MaxRows = 366 * 24 * 4
+
+// maxMeterIDLength is the longest meter ID stored
+const maxMeterIDLength = 100
+
+// ErrInput is wrapped by errors in the file or its mapping as a whole
+var ErrInput = errors.New("invalid import")
+
+// Row is a meter reading read from an import, or the reason it couldn't be read
+type Row struct {
+	// Line is the line of a CSV file counting the header, or the position of the reading
+	// in the JSON starting from 1
+	Line      int
+	MeterID   string
+	Timestamp time.Time
+	KWh       float64
+	// Err explains why the row can't be imported
+	Err error
+}
+
+// Valid reports whether format is a format Read understands
+func Valid(format string) bool {
+	switch format {
+	case models.ConsumptionImportCSV, models.ConsumptionImportSwedishGrid, models.ConsumptionImportTibber:
+		return true
+	}
+	return false
+}
+
+// Read reads the readings of an import in format. Problems with single rows are reported
+// in their Row, and a reading appearing a second time fails. An error wrapping ErrInput is
+// returned when the file can't be read at all.
+func Read(r io.Reader, format string, mapping models.ConsumptionImportMapping) ([]Row, error) {
+	var rows []Row
+	switch format {
+	case models.ConsumptionImportCSV, models.ConsumptionImportSwedishGrid:
+		reader, err := openCSV(r, format, mapping)
+		if err != nil {
+			return nil, err
+		}
+		if err := reader.resolve(); err != nil {
+			return nil, err
+		}
+		if rows, err = reader.readRows(MaxRows); err != nil {
+			return nil, err
+		}
+	case models.ConsumptionImportTibber:
+		var err error
+		if rows, err = readTibber(r, mapping.MeterID); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInput, format)
+	}
+	markDuplicates(rows)
+	return rows, nil
+}
+
+// Preview reads the first n rows of an import the way Read would, along with the columns
+// of a CSV file and the mapping with the detected columns filled in. A mapping that doesn't
+// fit the file is explained in the preview, so the columns can be picked from it.
+func Preview(r io.Reader, format string, mapping models.ConsumptionImportMapping, n int) (*models.ConsumptionImportPreview, error) {
+	preview := &models.ConsumptionImportPreview{Format: format, Columns: []string{}, Mapping: mapping}
+	var rows []Row
+	switch format {
+	case models.ConsumptionImportCSV, models.ConsumptionImportSwedishGrid:
+		reader, err := openCSV(r, format, mapping)
+		if err != nil {
+			return nil, err
+		}
+		preview.Columns = reader.columns
+		if err := reader.resolve(); err != nil {
+			preview.Error = err.Error()
+			preview.Mapping = reader.mapping
+			preview.Rows = []models.ConsumptionImportPreviewRow{}
+			return preview, nil
+		}
+		preview.Mapping = reader.mapping
+		if rows, err = reader.readRows(n); err != nil && !errors.Is(err, errTooManyRows) {
+			return nil, err
+		}
+	case models.ConsumptionImportTibber:
+		var err error
+		if rows, err = readTibber(r, mapping.MeterID); err != nil {
+			return nil, err
+		}
+		rows = rows[:min(n, len(rows))]
+	default:
+		return nil, fmt.Errorf("%w: unknown format %q", ErrInput, format)
+	}
+	markDuplicates(rows)
+
+	preview.Rows = make([]models.ConsumptionImportPreviewRow, len(rows))
+	for i, row := range rows {
+		preview.Rows[i] = models.ConsumptionImportPreviewRow{Row: row.Line}
+		if row.Err != nil {
+			preview.Rows[i].Error = row.Err.Error()
+			continue
+		}
+		timestamp, kwh := row.Timestamp, row.KWh
+		preview.Rows[i].MeterID, preview.Rows[i].Timestamp, preview.Rows[i].KWh = row.MeterID, &timestamp, &kwh
+	}
+	return preview, nil
+}
+
+// markDuplicates fails the rows repeating the meter and timestamp of an earlier row, the
+// database can't store both in one statement
+func markDuplicates(rows []Row) {
+	seen := make(map[string]bool, len(rows))
+	for i, row := range rows {
+		if row.Err != nil {
+			continue
+		}
+		key := row.MeterID + "@" + strconv.FormatInt(row.Timestamp.UnixNano(), 10)
+		if seen[key] {
+			rows[i].Err = fmt.Errorf("reading for meter %s at %s appears more than once", row.MeterID, row.Timestamp.Format(time.RFC3339))
+			continue
+		}
+		seen[key] = true
+	}
+}
+
+// validate fails row when its values can't be stored
+func validate(row *Row) {
+	switch {
+	case row.Err != nil:
+	case row.MeterID == "":
+		row.Err = errors.New("missing meter ID")
+	case len(row.MeterID) > maxMeterIDLength:
+		row.Err = fmt.Errorf("meter ID is longer than %d characters", maxMeterIDLength)
+	case row.KWh < 0:
+		row.Err = errors.New("kWh cannot be negative")
+	}
+}
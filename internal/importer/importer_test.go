@@ -0,0 +1,166 @@
+package importer
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadCSV(t *testing.T) {
+	t.Run("Mapped Columns", func(t *testing.T) {
+		file := "\ufeffmeter,start,usage\n" +
+			"m1,2024-03-20T13:00:00Z,1.5\n" +
+			"m1,2024-03-20T14:00:00Z,n/a\n" +
+			"m2,2024-03-20 15:00,-1\n" +
+			"m1,2024-03-20T13:00:00Z,2\n" +
+			",2024-03-20T16:00:00Z,2\n"
+		mapping := models.ConsumptionImportMapping{TimestampColumn: "Start", KWhColumn: "usage", MeterColumn: "meter"}
+		rows, err := Read(strings.NewReader(file), models.ConsumptionImportCSV, mapping)
+		require.NoError(t, err)
+		require.Len(t, rows, 5)
+
+		assert.NoError(t, rows[0].Err)
+		assert.Equal(t, 2, rows[0].Line)
+		assert.Equal(t, "m1", rows[0].MeterID)
+		assert.True(t, time.Date(2024, 3, 20, 13, 0, 0, 0, time.UTC).Equal(rows[0].Timestamp))
+		assert.Equal(t, 1.5, rows[0].KWh)
+
+		assert.EqualError(t, rows[1].Err, `invalid kWh "n/a"`)
+		assert.EqualError(t, rows[2].Err, "kWh cannot be negative")
+		assert.ErrorContains(t, rows[3].Err, "appears more than once")
+		assert.EqualError(t, rows[4].Err, "missing meter ID")
+		assert.Equal(t, 6, rows[4].Line)
+	})
+
+	t.Run("Detected Columns", func(t *testing.T) {
+		file := "Timestamp;Consumption (Wh)\n2024-03-20 13:00;1 500\n"
+		mapping := models.ConsumptionImportMapping{MeterID: "main", Unit: "wh"}
+		rows, err := Read(strings.NewReader(file), models.ConsumptionImportCSV, mapping)
+		require.NoError(t, err)
+		require.Len(t, rows, 1)
+		require.NoError(t, rows[0].Err)
+		assert.Equal(t, "main", rows[0].MeterID)
+		assert.True(t, time.Date(2024, 3, 20, 13, 0, 0, 0, time.UTC).Equal(rows[0].Timestamp))
+		assert.Equal(t, 1.5, rows[0].KWh)
+	})
+
+	t.Run("Invalid Mappings", func(t *testing.T) {
+		for name, tt := range map[string]struct {
+			file    string
+			mapping models.ConsumptionImportMapping
+			err     string
+		}{
+			"Empty File":       {"", models.ConsumptionImportMapping{MeterID: "m"}, "the file is empty"},
+			"Unknown Column":   {"time,kwh\n", models.ConsumptionImportMapping{MeterID: "m", KWhColumn: "usage"}, `no column named "usage"`},
+			"No kWh Column":    {"time,price\n", models.ConsumptionImportMapping{MeterID: "m"}, "no kWh column found"},
+			"No Meter":         {"time,kwh\n", models.ConsumptionImportMapping{}, "meter_id is required"},
+			"Unknown Timezone": {"time,kwh\n", models.ConsumptionImportMapping{MeterID: "m", Timezone: "Mars/Olympus"}, "unknown timezone"},
+			"Unknown Unit":     {"time,kwh\n", models.ConsumptionImportMapping{MeterID: "m", Unit: "MJ"}, "unit must be kWh or Wh"},
+			"Long Delimiter":   {"time,kwh\n", models.ConsumptionImportMapping{MeterID: "m", Delimiter: ";;"}, "delimiter must be a single character"},
+		} {
+			t.Run(name, func(t *testing.T) {
+				_, err := Read(strings.NewReader(tt.file), models.ConsumptionImportCSV, tt.mapping)
+				assert.ErrorIs(t, err, ErrInput)
+				assert.ErrorContains(t, err, tt.err)
+			})
+		}
+	})
+
+	t.Run("Too Many Rows", func(t *testing.T) {
+		var file strings.Builder
+		file.WriteString("time,kwh\n")
+		start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i := 0; i <= MaxRows; i++ {
+			file.WriteString(start.Add(time.Duration(i)*15*time.Minute).Format(time.RFC3339) + ",1\n")
+		}
+		_, err := Read(strings.NewReader(file.String()), models.ConsumptionImportCSV, models.ConsumptionImportMapping{MeterID: "m"})
+		assert.ErrorIs(t, err, ErrInput)
+	})
+}
+
+func TestReadSwedishGrid(t *testing.T) {
+	file := "Anläggnings-ID;Datum;Tid;Förbrukning (kWh)\n" +
+		"735999100000000001;2024-01-15;00:00;1,25\n" +
+		"735999100000000001;2024-07-15;00:00;0,5\n" +
+		"Summa;;;1,75\n"
+	rows, err := Read(strings.NewReader(file), models.ConsumptionImportSwedishGrid, models.ConsumptionImportMapping{})
+	require.NoError(t, err)
+	require.Len(t, rows, 3)
+
+	require.NoError(t, rows[0].Err)
+	assert.Equal(t, "735999100000000001", rows[0].MeterID)
+	assert.True(t, time.Date(2024, 1, 14, 23, 0, 0, 0, time.UTC).Equal(rows[0].Timestamp), "winter time is an hour ahead of UTC")
+	assert.Equal(t, 1.25, rows[0].KWh)
+	require.NoError(t, rows[1].Err)
+	assert.True(t, time.Date(2024, 7, 14, 22, 0, 0, 0, time.UTC).Equal(rows[1].Timestamp), "summer time is two hours ahead of UTC")
+
+	// Totals at the end of the export aren't readings
+	assert.EqualError(t, rows[2].Err, "missing timestamp")
+}
+
+func TestReadTibber(t *testing.T) {
+	response := `{"data":{"viewer":{"homes":[{"id":"home-1","consumption":{"nodes":[
+		{"from":"2024-03-20T13:00:00.000+01:00","to":"2024-03-20T14:00:00.000+01:00","consumption":1.25,"consumptionUnit":"kWh"},
+		{"from":"2024-03-20T14:00:00.000+01:00","to":"2024-03-20T15:00:00.000+01:00","consumption":null,"consumptionUnit":"kWh"}
+	]}}]}}}`
+
+	rows, err := Read(strings.NewReader(response), models.ConsumptionImportTibber, models.ConsumptionImportMapping{})
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.NoError(t, rows[0].Err)
+	assert.Equal(t, "home-1", rows[0].MeterID)
+	assert.True(t, time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC).Equal(rows[0].Timestamp))
+	assert.Equal(t, 1.25, rows[0].KWh)
+	assert.Equal(t, 2, rows[1].Line)
+	assert.EqualError(t, rows[1].Err, "no consumption reported")
+
+	rows, err = Read(strings.NewReader(response), models.ConsumptionImportTibber, models.ConsumptionImportMapping{MeterID: "main"})
+	require.NoError(t, err)
+	assert.Equal(t, "main", rows[0].MeterID)
+
+	nodes := `[{"from":"2024-03-20T13:00:00Z","consumption":2}]`
+	_, err = Read(strings.NewReader(nodes), models.ConsumptionImportTibber, models.ConsumptionImportMapping{})
+	assert.ErrorContains(t, err, "meter_id is required")
+	rows, err = Read(strings.NewReader(`{"nodes":`+nodes+`}`), models.ConsumptionImportTibber, models.ConsumptionImportMapping{MeterID: "main"})
+	require.NoError(t, err)
+	require.Len(t, rows, 1)
+	assert.Equal(t, 2.0, rows[0].KWh)
+
+	_, err = Read(strings.NewReader(`{"errors":[{"message":"Unauthorized"}]}`), models.ConsumptionImportTibber, models.ConsumptionImportMapping{})
+	assert.ErrorContains(t, err, "Unauthorized")
+	_, err = Read(strings.NewReader("not json"), models.ConsumptionImportTibber, models.ConsumptionImportMapping{})
+	assert.ErrorIs(t, err, ErrInput)
+}
+
+func TestPreview(t *testing.T) {
+	var file strings.Builder
+	file.WriteString("Från,Till,kWh\n")
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 30; i++ {
+		file.WriteString(start.Add(time.Duration(i)*time.Hour).Format(time.RFC3339) + ",," + "1.5\n")
+	}
+
+	preview, err := Preview(strings.NewReader(file.String()), models.ConsumptionImportCSV, models.ConsumptionImportMapping{MeterID: "main"}, 20)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Från", "Till", "kWh"}, preview.Columns)
+	assert.Equal(t, "Från", preview.Mapping.TimestampColumn)
+	assert.Equal(t, "kWh", preview.Mapping.KWhColumn)
+	assert.Equal(t, ",", preview.Mapping.Delimiter)
+	assert.Equal(t, "UTC", preview.Mapping.Timezone)
+	assert.Empty(t, preview.Error)
+	require.Len(t, preview.Rows, 20)
+	assert.Equal(t, 2, preview.Rows[0].Row)
+	require.NotNil(t, preview.Rows[0].KWh)
+	assert.Equal(t, 1.5, *preview.Rows[0].KWh)
+
+	// The columns are shown when the mapping doesn't fit, so the right ones can be picked
+	preview, err = Preview(strings.NewReader(file.String()), models.ConsumptionImportCSV, models.ConsumptionImportMapping{}, 20)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Från", "Till", "kWh"}, preview.Columns)
+	assert.Contains(t, preview.Error, "meter_id is required")
+	assert.Empty(t, preview.Rows)
+}
@@ -0,0 +1,110 @@
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// tibberNode is an hour or day of consumption in Tibber's API. Consumption is null for
+// intervals the grid operator hasn't reported yet.
+type tibberNode struct {
+	From            time.Time `json:"from"`
+	Consumption     *float64  `json:"consumption"`
+	ConsumptionUnit string    `json:"consumptionUnit"`
+}
+
+// tibberConnection is the consumption of a home, a page of nodes
+type tibberConnection struct {
+	Nodes []tibberNode `json:"nodes"`
+}
+
+// tibberHome is a home of the viewer, its ID identifies the meter
+type tibberHome struct {
+	ID          string           `json:"id"`
+	Consumption tibberConnection `json:"consumption"`
+}
+
+// tibberResponse is the response to a consumption query of Tibber's GraphQL API, such as
+// { viewer { homes { id consumption(resolution: HOURLY, last: 744) { nodes { from consumption consumptionUnit } } } } }
+type tibberResponse struct {
+	Data struct {
+		Viewer struct {
+			Homes []tibberHome `json:"homes"`
+		} `json:"viewer"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+	// Nodes are read from the consumption object of a home saved on its own
+	Nodes []tibberNode `json:"nodes"`
+}
+
+// readTibber reads the consumption Tibber's API returned: a whole response, the
+// consumption object of a home or its array of nodes. Readings are for the home's meter
+// unless meterID is set, which is required when the file has no home ID.
+func readTibber(r io.Reader, meterID string) ([]Row, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInput, err)
+	}
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 {
+		return nil, fmt.Errorf("%w: the file is empty", ErrInput)
+	}
+
+	var homes []tibberHome
+	if data[0] == '[' {
+		var nodes []tibberNode
+		if err := json.Unmarshal(data, &nodes); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInput, err)
+		}
+		homes = []tibberHome{{Consumption: tibberConnection{Nodes: nodes}}}
+	} else {
+		var response tibberResponse
+		if err := json.Unmarshal(data, &response); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInput, err)
+		}
+		homes = response.Data.Viewer.Homes
+		if len(homes) == 0 && len(response.Errors) > 0 {
+			return nil, fmt.Errorf("%w: the file is an error from Tibber: %s", ErrInput, response.Errors[0].Message)
+		}
+		if len(homes) == 0 {
+			homes = []tibberHome{{Consumption: tibberConnection{Nodes: response.Nodes}}}
+		}
+	}
+
+	var rows []Row
+	for _, home := range homes {
+		meter := meterID
+		if meter == "" {
+			meter = home.ID
+		}
+		if meter == "" {
+			return nil, fmt.Errorf("%w: meter_id is required when the file has no home ID", ErrInput)
+		}
+		for _, node := range home.Consumption.Nodes {
+			if len(rows) == MaxRows {
+				return nil, errTooManyRows
+			}
+			row := Row{Line: len(rows) + 1, MeterID: meter, Timestamp: node.From}
+			switch {
+			case node.From.IsZero():
+				row.Err = errors.New("missing timestamp")
+			case node.Consumption == nil:
+				row.Err = errors.New("no consumption reported")
+			case node.ConsumptionUnit != "" && !strings.EqualFold(node.ConsumptionUnit, "kWh"):
+				row.Err = fmt.Errorf("unsupported unit %q", node.ConsumptionUnit)
+			default:
+				row.KWh = *node.Consumption
+			}
+			validate(&row)
+			rows = append(rows, row)
+		}
+	}
+	return rows, nil
+}
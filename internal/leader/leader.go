@@ -0,0 +1,112 @@
+// Package leader elects a single instance to run scheduled jobs when several replicas
+// share a database
+package leader
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultLockID is the advisory lock key contended for when none is configured, "watt" in ASCII
+const DefaultLockID = 0x77617474
+
+// DefaultInterval is how often followers try to take over and the leader checks that it
+// still holds the lock
+const DefaultInterval = 15 * time.Second
+
+// Elector holds a Postgres session level advisory lock on a dedicated connection. Whoever
+// holds the lock is the leader, and the lock is released by Postgres when the session ends,
+// so a crashed leader is replaced within one interval.
+type Elector struct {
+	db       *sql.DB
+	lockID   int64
+	interval time.Duration
+	leader   atomic.Bool
+}
+
+// NewElector creates an elector contending for lockID, or DefaultLockID when it is zero.
+// Instances sharing a database must use the same lock ID to compete with each other.
+func NewElector(db *sql.DB, lockID int64) *Elector {
+	if lockID == 0 {
+		lockID = DefaultLockID
+	}
+	return &Elector{
+		db:       db,
+		lockID:   lockID,
+		interval: DefaultInterval,
+	}
+}
+
+// IsLeader reports whether this instance currently holds the lock
+func (e *Elector) IsLeader() bool {
+	return e.leader.Load()
+}
+
+// Run campaigns for leadership until ctx is cancelled, then gives it up
+func (e *Elector) Run(ctx context.Context) {
+	ticker := time.NewTicker(e.interval)
+	defer ticker.Stop()
+
+	var conn *sql.Conn
+	defer func() {
+		if conn != nil {
+			e.release(conn)
+			log.Println("Released scheduler leadership")
+		}
+	}()
+
+	for {
+		conn = e.step(ctx, conn)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// step checks that conn still holds the lock, or tries to acquire it when conn is nil.
+// It returns the connection holding the lock, nil when this instance is a follower.
+func (e *Elector) step(ctx context.Context, conn *sql.Conn) *sql.Conn {
+	if conn != nil {
+		_, err := conn.ExecContext(ctx, "SELECT 1")
+		if err == nil || ctx.Err() != nil {
+			return conn
+		}
+		log.Printf("Lost scheduler leadership: %v", err)
+		e.release(conn)
+	}
+
+	conn, err := e.db.Conn(ctx)
+	if err != nil {
+		if ctx.Err() == nil {
+			log.Printf("Leader election: failed to get connection: %v", err)
+		}
+		return nil
+	}
+
+	var acquired bool
+	if err := conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1)", e.lockID).Scan(&acquired); err != nil || !acquired {
+		if err != nil && ctx.Err() == nil {
+			log.Printf("Leader election: failed to try lock: %v", err)
+		}
+		conn.Close()
+		return nil
+	}
+
+	e.leader.Store(true)
+	log.Println("Acquired scheduler leadership")
+	return conn
+}
+
+// release steps down and discards the connection instead of returning it to the pool,
+// ending the session and with it the lock
+func (e *Elector) release(conn *sql.Conn) {
+	e.leader.Store(false)
+	_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+	conn.Close()
+}
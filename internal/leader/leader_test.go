@@ -0,0 +1,59 @@
+package leader
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/testutil/db"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestElector(t *testing.T) {
+	cfg := db.LoadTestConfig(t)
+	testDB := db.SetupTestDB(t, &cfg.Database)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	first := NewElector(testDB, DefaultLockID)
+	second := NewElector(testDB, DefaultLockID)
+
+	// Only one instance wins the lock
+	conn := first.step(ctx, nil)
+	require.NotNil(t, conn)
+	assert.True(t, first.IsLeader())
+	assert.Nil(t, second.step(ctx, nil))
+	assert.False(t, second.IsLeader())
+
+	// The leader keeps the lock across checks
+	assert.Same(t, conn, first.step(ctx, conn))
+	assert.True(t, first.IsLeader())
+
+	// Stepping down lets another instance take over
+	first.release(conn)
+	assert.False(t, first.IsLeader())
+	taken := second.step(ctx, nil)
+	require.NotNil(t, taken)
+	assert.True(t, second.IsLeader())
+	second.release(taken)
+}
+
+func TestElector_Run(t *testing.T) {
+	cfg := db.LoadTestConfig(t)
+	testDB := db.SetupTestDB(t, &cfg.Database)
+	defer testDB.Close()
+
+	e := NewElector(testDB, DefaultLockID)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		e.Run(ctx)
+		close(done)
+	}()
+
+	require.Eventually(t, e.IsLeader, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+	assert.False(t, e.IsLeader(), "leadership is given up when Run returns")
+}
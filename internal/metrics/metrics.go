@@ -0,0 +1,227 @@
+// Package metrics collects Prometheus metrics about requests, database queries and the
+// connection pool, logins, spot price ingestion, caches and background jobs
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// MQTT publish results
+const (
+	PublishSuccess = "success"
+	PublishFailure = "failure"
+)
+
+// Login attempt results
+const (
+	LoginSuccess = "success"
+	LoginFailure = "failure"
+	// LoginLocked is a login refused after too many failed attempts
+	LoginLocked = "locked"
+)
+
+// Registry holds the metrics served by Handler. The metrics are always collected, the
+// configuration only decides whether they are exposed.
+var Registry = prometheus.NewRegistry()
+
+var (
+	httpRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_http_requests_total",
+		Help: "HTTP requests handled, by route and status code.",
+	}, []string{"method", "route", "status"})
+
+	httpRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wattwatch_http_request_duration_seconds",
+		Help:    "Time taken to handle HTTP requests, by route.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"method", "route"})
+
+	dbQueryDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wattwatch_db_query_duration_seconds",
+		Help:    "Time taken by database queries, by statement type.",
+		Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5},
+	}, []string{"operation"})
+
+	loginAttempts = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_login_attempts_total",
+		Help: "Login attempts, by result.",
+	}, []string{"result"})
+
+	spotPricesIngested = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_spot_prices_ingested_total",
+		Help: "Spot prices stored, by source, zone and currency.",
+	}, []string{"source", "zone", "currency"})
+
+	tokensDeleted = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_expired_tokens_deleted_total",
+		Help: "Expired tokens removed by the cleanup job, by kind of token.",
+	}, []string{"kind"})
+
+	tokenCleanupFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_token_cleanup_failures_total",
+		Help: "Cleanup runs that failed to remove expired tokens, by kind of token.",
+	}, []string{"kind"})
+
+	recordsPurged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_retention_records_deleted_total",
+		Help: "Records removed by the retention job for being older than their retention period, by kind of record.",
+	}, []string{"kind"})
+
+	retentionFailures = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_retention_failures_total",
+		Help: "Retention runs that failed to remove old records, by kind of record.",
+	}, []string{"kind"})
+
+	jobRunDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "wattwatch_job_run_duration_seconds",
+		Help:    "Time taken by background job runs, by job and status.",
+		Buckets: []float64{.1, .5, 1, 5, 15, 30, 60, 300, 900},
+	}, []string{"job", "status"})
+
+	cacheHits = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_cache_hits_total",
+		Help: "Lookups answered from the cache, by cache.",
+	}, []string{"cache"})
+
+	cacheMisses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_cache_misses_total",
+		Help: "Lookups that weren't cached and went to the database, by cache.",
+	}, []string{"cache"})
+
+	streamSubscribers = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "wattwatch_spot_price_stream_clients",
+		Help: "Clients streaming spot prices over WebSocket.",
+	})
+
+	mqttConnected = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "wattwatch_mqtt_connected",
+		Help: "Whether the MQTT publisher is connected to its broker, 1 when connected.",
+	})
+
+	mqttConnectionLosses = prometheus.NewCounter(prometheus.CounterOpts{
+		Name: "wattwatch_mqtt_connection_losses_total",
+		Help: "Times the MQTT publisher lost its connection to the broker.",
+	})
+
+	mqttMessagesPublished = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "wattwatch_mqtt_messages_published_total",
+		Help: "Messages published to the MQTT broker, by kind of message and result.",
+	}, []string{"kind", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		httpRequests,
+		httpRequestDuration,
+		dbQueryDuration,
+		loginAttempts,
+		spotPricesIngested,
+		tokensDeleted,
+		tokenCleanupFailures,
+		recordsPurged,
+		retentionFailures,
+		jobRunDuration,
+		cacheHits,
+		cacheMisses,
+		streamSubscribers,
+		mqttConnected,
+		mqttConnectionLosses,
+		mqttMessagesPublished,
+		poolCollector{},
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// ObserveRequest records a handled HTTP request. route is the route pattern, such as
+// /api/v1/zones/:id, so requests for different IDs share a series.
+func ObserveRequest(method, route string, status int, duration time.Duration) {
+	httpRequests.WithLabelValues(method, route, strconv.Itoa(status)).Inc()
+	httpRequestDuration.WithLabelValues(method, route).Observe(duration.Seconds())
+}
+
+// ObserveQuery records the duration of a database query of the given statement type
+func ObserveQuery(operation string, duration time.Duration) {
+	dbQueryDuration.WithLabelValues(operation).Observe(duration.Seconds())
+}
+
+// LoginAttempt counts a login attempt with one of the Login results
+func LoginAttempt(result string) {
+	loginAttempts.WithLabelValues(result).Inc()
+}
+
+// SpotPricesIngested counts spot prices stored from source for a zone and currency
+func SpotPricesIngested(source, zone, currency string, count int) {
+	spotPricesIngested.WithLabelValues(source, zone, currency).Add(float64(count))
+}
+
+// TokensDeleted counts expired tokens of a kind, such as refresh_token, that were removed
+func TokensDeleted(kind string, count int64) {
+	tokensDeleted.WithLabelValues(kind).Add(float64(count))
+}
+
+// TokenCleanupFailed counts a failed attempt to remove expired tokens of a kind
+func TokenCleanupFailed(kind string) {
+	tokenCleanupFailures.WithLabelValues(kind).Inc()
+}
+
+// RecordsPurged counts records of a kind, such as audit_log, removed by the retention job
+func RecordsPurged(kind string, count int64) {
+	recordsPurged.WithLabelValues(kind).Add(float64(count))
+}
+
+// RetentionFailed counts a failed attempt to remove old records of a kind
+func RetentionFailed(kind string) {
+	retentionFailures.WithLabelValues(kind).Inc()
+}
+
+// JobRunFinished records a finished run of a background job with its status
+func JobRunFinished(job, status string, duration time.Duration) {
+	jobRunDuration.WithLabelValues(job, status).Observe(duration.Seconds())
+}
+
+// StreamSubscribers records the number of clients streaming spot prices
+func StreamSubscribers(count int) {
+	streamSubscribers.Set(float64(count))
+}
+
+// CacheHit counts a lookup answered from a cache, such as zones
+func CacheHit(cache string) {
+	cacheHits.WithLabelValues(cache).Inc()
+}
+
+// CacheMiss counts a lookup a cache didn't hold, which went to the database
+func CacheMiss(cache string) {
+	cacheMisses.WithLabelValues(cache).Inc()
+}
+
+// MQTTConnected records whether the MQTT publisher is connected to its broker
+func MQTTConnected(connected bool) {
+	if connected {
+		mqttConnected.Set(1)
+	} else {
+		mqttConnected.Set(0)
+	}
+}
+
+// MQTTConnectionLost counts a lost connection to the MQTT broker
+func MQTTConnectionLost() {
+	mqttConnectionLosses.Inc()
+}
+
+// MQTTPublished counts a message of a kind, such as today, published to the MQTT broker
+// with one of the Publish results
+func MQTTPublished(kind, result string) {
+	mqttMessagesPublished.WithLabelValues(kind, result).Inc()
+}
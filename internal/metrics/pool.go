@@ -0,0 +1,85 @@
+package metrics
+
+import (
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// pool is the database connection pool whose statistics are collected
+var pool atomic.Pointer[pgxpool.Pool]
+
+// ObservePool collects the statistics of p, in place of the pool observed before
+func ObservePool(p *pgxpool.Pool) {
+	pool.Store(p)
+}
+
+var (
+	poolConnections = prometheus.NewDesc(
+		"wattwatch_db_pool_connections",
+		"Connections of the database pool, by state.",
+		[]string{"state"}, nil)
+	poolMaxConnections = prometheus.NewDesc(
+		"wattwatch_db_pool_max_connections",
+		"Connections the database pool opens at most.",
+		nil, nil)
+	poolAcquires = prometheus.NewDesc(
+		"wattwatch_db_pool_acquires_total",
+		"Connections acquired from the database pool.",
+		nil, nil)
+	poolEmptyAcquires = prometheus.NewDesc(
+		"wattwatch_db_pool_empty_acquires_total",
+		"Connections acquired from the database pool that had to wait for one.",
+		nil, nil)
+	poolCanceledAcquires = prometheus.NewDesc(
+		"wattwatch_db_pool_canceled_acquires_total",
+		"Acquires from the database pool cancelled before getting a connection.",
+		nil, nil)
+	poolAcquireDuration = prometheus.NewDesc(
+		"wattwatch_db_pool_acquire_duration_seconds_total",
+		"Time spent acquiring connections from the database pool.",
+		nil, nil)
+	poolNewConnections = prometheus.NewDesc(
+		"wattwatch_db_pool_new_connections_total",
+		"Connections opened by the database pool.",
+		nil, nil)
+	poolClosedConnections = prometheus.NewDesc(
+		"wattwatch_db_pool_closed_connections_total",
+		"Connections the database pool closed for being too old or idle too long, by reason.",
+		[]string{"reason"}, nil)
+)
+
+// poolCollector reads the statistics of the observed pool as they are scraped
+type poolCollector struct{}
+
+func (poolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- poolConnections
+	ch <- poolMaxConnections
+	ch <- poolAcquires
+	ch <- poolEmptyAcquires
+	ch <- poolCanceledAcquires
+	ch <- poolAcquireDuration
+	ch <- poolNewConnections
+	ch <- poolClosedConnections
+}
+
+func (poolCollector) Collect(ch chan<- prometheus.Metric) {
+	p := pool.Load()
+	if p == nil {
+		return
+	}
+	stat := p.Stat()
+
+	ch <- prometheus.MustNewConstMetric(poolConnections, prometheus.GaugeValue, float64(stat.AcquiredConns()), "acquired")
+	ch <- prometheus.MustNewConstMetric(poolConnections, prometheus.GaugeValue, float64(stat.IdleConns()), "idle")
+	ch <- prometheus.MustNewConstMetric(poolConnections, prometheus.GaugeValue, float64(stat.ConstructingConns()), "constructing")
+	ch <- prometheus.MustNewConstMetric(poolMaxConnections, prometheus.GaugeValue, float64(stat.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(poolAcquires, prometheus.CounterValue, float64(stat.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolEmptyAcquires, prometheus.CounterValue, float64(stat.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolCanceledAcquires, prometheus.CounterValue, float64(stat.CanceledAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(poolAcquireDuration, prometheus.CounterValue, stat.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(poolNewConnections, prometheus.CounterValue, float64(stat.NewConnsCount()))
+	ch <- prometheus.MustNewConstMetric(poolClosedConnections, prometheus.CounterValue, float64(stat.MaxLifetimeDestroyCount()), "lifetime")
+	ch <- prometheus.MustNewConstMetric(poolClosedConnections, prometheus.CounterValue, float64(stat.MaxIdleDestroyCount()), "idle")
+}
@@ -0,0 +1,68 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOperation(t *testing.T) {
+	tests := map[string]string{
+		"SELECT id FROM zones":                          "select",
+		"\n\t\tINSERT INTO zones (name) VALUES ($1)":    "insert",
+		"-- lock the row\n UPDATE zones SET name = $1":  "update",
+		"WITH tz AS (SELECT 1) DELETE FROM spot_prices": "with",
+		"delete from users":                             "delete",
+		"TRUNCATE spot_prices":                          "other",
+		"":                                              "other",
+	}
+	for query, want := range tests {
+		assert.Equal(t, want, operation(query), query)
+	}
+}
+
+// arrayConn is a driver connection that accepts slices as arguments, like pgx does
+type arrayConn struct {
+	args []driver.NamedValue
+}
+
+func (c *arrayConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *arrayConn) Close() error              { return nil }
+func (c *arrayConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *arrayConn) CheckNamedValue(arg *driver.NamedValue) error {
+	if _, ok := arg.Value.([]string); ok {
+		return nil
+	}
+	return driver.ErrSkip
+}
+
+func (c *arrayConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.args = args
+	return driver.RowsAffected(0), nil
+}
+
+type arrayConnector struct {
+	conn *arrayConn
+}
+
+func (c *arrayConnector) Connect(ctx context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c *arrayConnector) Driver() driver.Driver                            { return nil }
+
+func TestInstrumentConnectorChecksNamedValues(t *testing.T) {
+	conn := &arrayConn{}
+	db := sql.OpenDB(InstrumentConnector(&arrayConnector{conn: conn}))
+	defer db.Close()
+
+	_, err := db.Exec("DELETE FROM zones WHERE name = ANY($1)", []string{"SE3", "SE4"})
+	require.NoError(t, err)
+	require.Len(t, conn.args, 1)
+	assert.Equal(t, []string{"SE3", "SE4"}, conn.args[0].Value)
+}
@@ -16,30 +16,55 @@ const (
 	AuditActionRead   AuditAction = "read"
 	AuditActionLogin  AuditAction = "login"
 	AuditActionLogout AuditAction = "logout"
+	// AuditActionRestore records undoing a soft delete
+	AuditActionRestore AuditAction = "restore"
+	// AuditActionPurge records permanently removing a soft-deleted entity
+	AuditActionPurge AuditAction = "purge"
+	// AuditActionUnlock records clearing the failed login attempts locking an account
+	AuditActionUnlock AuditAction = "unlock"
+	// AuditActionRateLimited records a client exceeding a rate limit
+	AuditActionRateLimited AuditAction = "rate_limited"
+	// AuditActionImpersonate records an admin starting to act as another user
+	AuditActionImpersonate AuditAction = "impersonate"
+	// AuditActionStopImpersonation records an admin ending an impersonation
+	AuditActionStopImpersonation AuditAction = "stop_impersonation"
+	// AuditActionImport records creating entities in bulk from a file
+	AuditActionImport AuditAction = "import"
+	// AuditActionExport records downloading entities in bulk
+	AuditActionExport AuditAction = "export"
+	// AuditActionScheduleDeletion records a user deleting their own account, which is
+	// deleted once the grace period has passed
+	AuditActionScheduleDeletion AuditAction = "schedule_deletion"
+	// AuditActionCancelDeletion records a scheduled deletion cancelled by logging in
+	AuditActionCancelDeletion AuditAction = "cancel_deletion"
+	// AuditActionAddressDenied records a request refused for the client IP address
+	AuditActionAddressDenied AuditAction = "address_denied"
 )
 
 // AuditLog represents a record of system activity
 type AuditLog struct {
-	ID          uuid.UUID   `json:"id" db:"id"`
-	UserID      *uuid.UUID  `json:"user_id" db:"user_id"`         // Optional: action might be system-generated
-	Action      AuditAction `json:"action" db:"action"`           // The type of action performed
-	EntityType  string      `json:"entity_type" db:"entity_type"` // The type of entity affected (e.g., "user", "zone", "spot_price")
-	EntityID    string      `json:"entity_id" db:"entity_id"`     // The ID of the affected entity
-	Description string      `json:"description" db:"description"` // Human-readable description of the action
-	Metadata    string      `json:"metadata" db:"metadata"`       // JSON string containing additional context
-	IPAddress   string      `json:"ip_address" db:"ip_address"`   // IP address of the requester
-	UserAgent   string      `json:"user_agent" db:"user_agent"`   // User agent of the requester
-	CreatedAt   time.Time   `json:"created_at" db:"created_at"`
+	ID             uuid.UUID   `json:"id" db:"id"`
+	UserID         *uuid.UUID  `json:"user_id" db:"user_id"`                           // Optional: action might be system-generated
+	ImpersonatorID *uuid.UUID  `json:"impersonator_id,omitempty" db:"impersonator_id"` // The admin acting as the user while impersonating them
+	Action         AuditAction `json:"action" db:"action"`                             // The type of action performed
+	EntityType     string      `json:"entity_type" db:"entity_type"`                   // The type of entity affected (e.g., "user", "zone", "spot_price")
+	EntityID       string      `json:"entity_id" db:"entity_id"`                       // The ID of the affected entity
+	Description    string      `json:"description" db:"description"`                   // Human-readable description of the action
+	Metadata       string      `json:"metadata" db:"metadata"`                         // JSON string containing additional context
+	IPAddress      string      `json:"ip_address" db:"ip_address"`                     // IP address of the requester
+	UserAgent      string      `json:"user_agent" db:"user_agent"`                     // User agent of the requester
+	CreatedAt      time.Time   `json:"created_at" db:"created_at"`
 }
 
 // CreateAuditLogRequest represents the request to create a new audit log entry
 type CreateAuditLogRequest struct {
-	UserID      *uuid.UUID  `json:"user_id"`
-	Action      AuditAction `json:"action" binding:"required"`
-	EntityType  string      `json:"entity_type" binding:"required"`
-	EntityID    string      `json:"entity_id" binding:"required"`
-	Description string      `json:"description" binding:"required"`
-	Metadata    string      `json:"metadata"`
-	IPAddress   string      `json:"ip_address"`
-	UserAgent   string      `json:"user_agent"`
+	UserID         *uuid.UUID  `json:"user_id"`
+	ImpersonatorID *uuid.UUID  `json:"impersonator_id"`
+	Action         AuditAction `json:"action" binding:"required"`
+	EntityType     string      `json:"entity_type" binding:"required"`
+	EntityID       string      `json:"entity_id" binding:"required"`
+	Description    string      `json:"description" binding:"required"`
+	Metadata       string      `json:"metadata"`
+	IPAddress      string      `json:"ip_address"`
+	UserAgent      string      `json:"user_agent"`
 }
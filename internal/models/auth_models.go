@@ -15,6 +15,14 @@ type LoginAttempt struct {
 	CreatedAt time.Time `json:"created_at"`
 }
 
+// LoginAttemptsResponse holds the recent failed login attempts of a user and whether
+// they lock the account
+type LoginAttemptsResponse struct {
+	Attempts    []LoginAttempt `json:"attempts"`
+	Locked      bool           `json:"locked"`
+	LockedUntil *time.Time     `json:"locked_until,omitempty"`
+}
+
 // EmailVerification represents an email verification token
 type EmailVerification struct {
 	ID        uuid.UUID `json:"id" db:"id"`
@@ -9,11 +9,16 @@ type TokenRefreshRequest struct {
 type LoginRequest struct {
 	Username string `json:"username" binding:"required,max=50"`
 	Password string `json:"password" binding:"required"`
+	// CaptchaToken is the token of a solved CAPTCHA, required after repeated failed logins
+	// when CAPTCHAs are enabled
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
 // PasswordResetRequest represents a password reset request
 type PasswordResetRequest struct {
 	Email string `json:"email" binding:"required,email"`
+	// CaptchaToken is the token of a solved CAPTCHA, required when CAPTCHAs are enabled
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
 // EmailVerificationRequest represents an email verification request
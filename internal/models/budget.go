@@ -0,0 +1,94 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Budget is the amount a user plans to spend on electricity in a zone and currency each
+// calendar month. When Notify is set the user is notified once a month, when the
+// projected cost of the month first exceeds the amount.
+type Budget struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         uuid.UUID  `json:"user_id"`
+	ZoneID         uuid.UUID  `json:"zone_id"`
+	CurrencyID     uuid.UUID  `json:"currency_id"`
+	Amount         float64    `json:"amount" example:"1500"`
+	Notify         bool       `json:"notify"`
+	LastNotifiedAt *time.Time `json:"last_notified_at,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+}
+
+// CreateBudgetRequest represents the request to create a budget. A user has at most one
+// budget per zone and currency.
+type CreateBudgetRequest struct {
+	ZoneID     uuid.UUID `json:"zone_id" binding:"required"`
+	CurrencyID uuid.UUID `json:"currency_id" binding:"required"`
+	Amount     *float64  `json:"amount" binding:"required,gt=0" example:"1500"`
+	Notify     *bool     `json:"notify,omitempty"`
+}
+
+// UpdateBudgetRequest represents the request to update a budget
+type UpdateBudgetRequest struct {
+	Amount *float64 `json:"amount,omitempty" binding:"omitempty,gt=0" example:"1200"`
+	Notify *bool    `json:"notify,omitempty"`
+}
+
+// CostProjection is the cost of a user's consumption in a calendar month, measured so far
+// and projected to the end of the month. Costs are in the currency, not the hundredths
+// spot prices are stored in. They add the fees and taxes of the tariff when there is one,
+// and are spot prices only otherwise.
+type CostProjection struct {
+	Zone     string  `json:"zone" example:"SE3"`
+	Currency string  `json:"currency" example:"SEK"`
+	MeterID  *string `json:"meter_id,omitempty" example:"735999100000000001"`
+	// Start and End are the month in the zone's timezone
+	Start time.Time `json:"start" example:"2024-03-01T00:00:00+01:00"`
+	End   time.Time `json:"end" example:"2024-04-01T00:00:00+02:00"`
+	// ConsumedKWh and Cost are the readings stored for the month, the cost leaves out the
+	// monthly fee
+	ConsumedKWh float64 `json:"consumed_kwh" example:"412.5"`
+	Cost        float64 `json:"cost" example:"498.12"`
+	// ProjectedKWh and ProjectedCost are the whole month, adding the hours without readings
+	// at the user's usual consumption for the hour of the day
+	ProjectedKWh  float64 `json:"projected_kwh" example:"905.3"`
+	ProjectedCost float64 `json:"projected_cost" example:"1093.4"`
+	// Breakdown splits the projected cost into its parts
+	Breakdown CostBreakdown `json:"breakdown"`
+	// Tariff is the tariff the fees and taxes are from, if any
+	Tariff *Tariff `json:"tariff,omitempty"`
+	// PricedUntil is when the last spot price stored for the month ends, later hours are
+	// projected at the average price of the month
+	PricedUntil *time.Time `json:"priced_until,omitempty" example:"2024-03-21T00:00:00+01:00"`
+	// Export is the energy exported to the grid in the month, if the user has production
+	// readings, and NetCost and ProjectedNetCost are the costs less its value
+	Export           *ExportProjection `json:"export,omitempty"`
+	NetCost          float64           `json:"net_cost" example:"312.4"`
+	ProjectedNetCost float64           `json:"projected_net_cost" example:"745.9"`
+	// Budget is the user's budget for the zone and currency, if any, and OverBudget tells
+	// whether the projected net cost exceeds it
+	Budget     *Budget `json:"budget,omitempty"`
+	OverBudget bool    `json:"over_budget"`
+}
+
+// CostBreakdown is what a cost is made of, in the currency
+type CostBreakdown struct {
+	Spot       float64 `json:"spot" example:"612.8"`
+	Transfer   float64 `json:"transfer" example:"226.33"`
+	EnergyTax  float64 `json:"energy_tax" example:"397.43"`
+	MonthlyFee float64 `json:"monthly_fee" example:"365"`
+	VAT        float64 `json:"vat" example:"400.39"`
+}
+
+// ExportProjection is the energy a user produced and exported to the grid in a calendar
+// month, measured so far and projected to the end of the month. Exported energy is valued
+// at the spot price less the export fee of the tariff, in the currency.
+type ExportProjection struct {
+	ProducedKWh          float64 `json:"produced_kwh" example:"310.2"`
+	ExportedKWh          float64 `json:"exported_kwh" example:"204.7"`
+	Revenue              float64 `json:"revenue" example:"185.72"`
+	ProjectedExportedKWh float64 `json:"projected_exported_kwh" example:"412.9"`
+	ProjectedRevenue     float64 `json:"projected_revenue" example:"347.5"`
+}
@@ -0,0 +1,7 @@
+package models
+
+// ConfigReloadResponse lists the settings that changed when the configuration was reloaded
+type ConfigReloadResponse struct {
+	Applied         []string `json:"applied" example:"rate_limit.requests,email.smtp_host"`
+	RestartRequired []string `json:"restart_required" example:"database.host"`
+}
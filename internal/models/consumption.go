@@ -0,0 +1,30 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ConsumptionRecord is the energy a user's meter measured during the interval starting at
+// Timestamp, such as an hour
+type ConsumptionRecord struct {
+	UserID    uuid.UUID `json:"user_id"`
+	MeterID   string    `json:"meter_id" example:"735999100000000001"`
+	Timestamp time.Time `json:"timestamp" example:"2024-03-20T13:00:00Z"`
+	KWh       float64   `json:"kwh" example:"1.25"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CreateConsumptionRecordRequest represents a single meter reading in a batch ingestion request
+type CreateConsumptionRecordRequest struct {
+	MeterID   string    `json:"meter_id" binding:"required,max=100" example:"735999100000000001"`
+	Timestamp time.Time `json:"timestamp" binding:"required" example:"2024-03-20T13:00:00Z"`
+	KWh       *float64  `json:"kwh" binding:"required,min=0" example:"1.25"`
+}
+
+// CreateConsumptionRequest represents a batch ingestion request for consumption records
+type CreateConsumptionRequest struct {
+	Records []CreateConsumptionRecordRequest `json:"records" binding:"required,min=1,max=10000,dive"`
+}
@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Consumption import formats
+const (
+	// ConsumptionImportCSV is a CSV file with a header row, read with a column mapping
+	ConsumptionImportCSV = "csv"
+	// ConsumptionImportSwedishGrid is the CSV export of Swedish grid operators: semicolon
+	// separated, decimal commas and local times in Europe/Stockholm
+	ConsumptionImportSwedishGrid = "se_grid"
+	// ConsumptionImportTibber is the consumption Tibber's API returns as JSON
+	ConsumptionImportTibber = "tibber"
+)
+
+// ConsumptionImportMapping tells how the columns of a CSV import map to readings. Columns
+// are named as in the header, ignoring case. Columns left out are detected from the
+// header, and the rest fall back to the defaults of the format.
+type ConsumptionImportMapping struct {
+	// Delimiter separates the fields, detected from the header when left out
+	Delimiter string `json:"delimiter,omitempty" form:"delimiter" example:";"`
+	// TimestampColumn holds the start of each reading's interval, or its date when
+	// TimeColumn holds the time of day
+	TimestampColumn string `json:"timestamp_column,omitempty" form:"timestamp_column" example:"Från"`
+	TimeColumn      string `json:"time_column,omitempty" form:"time_column" example:"Tid"`
+	// TimestampLayout is a Go time layout, common layouts are tried when left out
+	TimestampLayout string `json:"timestamp_layout,omitempty" form:"timestamp_layout" example:"2006-01-02 15:04"`
+	// Timezone is the IANA timezone of timestamps without an offset
+	Timezone  string `json:"timezone,omitempty" form:"timezone" example:"Europe/Stockholm"`
+	KWhColumn string `json:"kwh_column,omitempty" form:"kwh_column" example:"Förbrukning (kWh)"`
+	// Unit of the kWh column, kWh or Wh
+	Unit        string `json:"unit,omitempty" form:"unit" example:"kWh"`
+	MeterColumn string `json:"meter_column,omitempty" form:"meter_column" example:"Anläggnings-ID"`
+	// MeterID is the meter of every reading, for files without a meter column
+	MeterID string `json:"meter_id,omitempty" form:"meter_id" example:"735999100000000001"`
+}
+
+// ConsumptionImportRowError is why a row of a consumption import wasn't imported
+type ConsumptionImportRowError struct {
+	// Row is the line of the CSV file, counting the header, or the position of the reading
+	// in the JSON starting from 1
+	Row   int    `json:"row" example:"14"`
+	Error string `json:"error" example:"invalid kWh \"n/a\""`
+}
+
+// ConsumptionImportResponse summarizes a consumption import. Rows that failed are listed
+// with the reason, the others were stored.
+type ConsumptionImportResponse struct {
+	Format   string                      `json:"format" example:"se_grid"`
+	DryRun   bool                        `json:"dry_run"`
+	Imported int                         `json:"imported" example:"8784"`
+	Failed   int                         `json:"failed" example:"1"`
+	Errors   []ConsumptionImportRowError `json:"errors"`
+}
+
+// ConsumptionImportPreviewRow is a row of an import as it would be imported
+type ConsumptionImportPreviewRow struct {
+	Row       int        `json:"row" example:"2"`
+	MeterID   string     `json:"meter_id,omitempty" example:"735999100000000001"`
+	Timestamp *time.Time `json:"timestamp,omitempty" example:"2024-03-20T13:00:00Z"`
+	KWh       *float64   `json:"kwh,omitempty" example:"1.25"`
+	Error     string     `json:"error,omitempty"`
+}
+
+// ConsumptionImportPreview shows how the first rows of a file would be imported, to check
+// a column mapping before importing
+type ConsumptionImportPreview struct {
+	Format string `json:"format" example:"csv"`
+	// Columns are the header of a CSV file
+	Columns []string `json:"columns"`
+	// Mapping is the column mapping with detected columns filled in
+	Mapping ConsumptionImportMapping `json:"mapping"`
+	// Error explains why the file can't be imported with the mapping, no rows are shown then
+	Error string                        `json:"error,omitempty"`
+	Rows  []ConsumptionImportPreviewRow `json:"rows"`
+}
@@ -8,18 +8,51 @@ import (
 
 // Currency represents a currency in the system
 type Currency struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name" binding:"required,len=3" example:"USD"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID uuid.UUID `json:"id" db:"id"`
+	// Name is the ISO 4217 alphabetic code of the currency
+	Name string `json:"name" db:"name" binding:"required,len=3" example:"USD"`
+	// NumericCode is the ISO 4217 numeric code of the currency
+	NumericCode string `json:"numeric_code" db:"numeric_code" example:"840"`
+	// Symbol, SymbolFirst and MinorUnits are hints for formatting amounts, e.g. $1.50
+	// or 1,50 kr
+	Symbol      string    `json:"symbol" db:"symbol" example:"$"`
+	SymbolFirst bool      `json:"symbol_first" db:"symbol_first" example:"true"`
+	MinorUnits  int       `json:"minor_units" db:"minor_units" example:"2"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
 }
 
-// CreateCurrencyRequest represents the request to create a new currency
+// FillISO4217 fills in the numeric code, symbol and minor units of a currency without a
+// numeric code from the ISO 4217 standard. A symbol that is already set is kept.
+func (c *Currency) FillISO4217() {
+	if c.NumericCode != "" {
+		return
+	}
+	iso, ok := LookupISO4217(c.Name)
+	if !ok {
+		return
+	}
+	c.NumericCode = iso.NumericCode
+	c.MinorUnits = iso.MinorUnits
+	if c.Symbol == "" {
+		c.Symbol, c.SymbolFirst = iso.Symbol, iso.SymbolFirst
+	}
+}
+
+// CreateCurrencyRequest represents the request to create a new currency. The numeric
+// code comes from ISO 4217, as do the formatting hints that are left out.
 type CreateCurrencyRequest struct {
-	Name string `json:"name" binding:"required,len=3" message:"Currency code must be exactly 3 letters (e.g. USD, EUR)" example:"USD"`
+	Name        string  `json:"name" binding:"required,len=3" message:"Currency code must be exactly 3 letters (e.g. USD, EUR)" example:"USD"`
+	Symbol      *string `json:"symbol" binding:"omitempty,min=1,max=8" example:"$"`
+	SymbolFirst *bool   `json:"symbol_first" example:"true"`
+	MinorUnits  *int    `json:"minor_units" binding:"omitempty,min=0,max=4" example:"2"`
 }
 
-// UpdateCurrencyRequest represents the request to update a currency
+// UpdateCurrencyRequest represents the request to update a currency. The numeric code
+// comes from ISO 4217, as do the formatting hints that are left out.
 type UpdateCurrencyRequest struct {
-	Name string `json:"name" binding:"required,len=3" message:"Currency code must be exactly 3 letters (e.g. USD, EUR)" example:"USD"`
+	Name        string  `json:"name" binding:"required,len=3" message:"Currency code must be exactly 3 letters (e.g. USD, EUR)" example:"USD"`
+	Symbol      *string `json:"symbol" binding:"omitempty,min=1,max=8" example:"$"`
+	SymbolFirst *bool   `json:"symbol_first" example:"true"`
+	MinorUnits  *int    `json:"minor_units" binding:"omitempty,min=0,max=4" example:"2"`
 }
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// DataQualityIssue describes a day on which a zone has more or fewer spot prices in a
+// currency than its length calls for
+type DataQualityIssue struct {
+	Zone     string `json:"zone" example:"SE3"`
+	Currency string `json:"currency" example:"EUR"`
+	// Date is the day in the zone's timezone
+	Date string `json:"date" example:"2025-03-30"`
+	// Expected is 23 or 25 hourly prices on days clocks change
+	Expected int `json:"expected" example:"23"`
+	Count    int `json:"count" example:"22"`
+	// Missing holds the start of every interval without a price
+	Missing []time.Time `json:"missing,omitempty"`
+	// Duplicates holds the start of every interval with more than one price
+	Duplicates []time.Time `json:"duplicates,omitempty"`
+	// Refetched is set when the day was requested from a provider again
+	Refetched bool `json:"refetched"`
+}
+
+// DataQualityReport lists the problems found in the spot prices of a range of days
+type DataQualityReport struct {
+	StartDate string             `json:"start_date" example:"2025-03-29"`
+	EndDate   string             `json:"end_date" example:"2025-03-30"`
+	Issues    []DataQualityIssue `json:"issues"`
+}
@@ -0,0 +1,11 @@
+package models
+
+// DeletionSummary counts the rows a forced deletion of a zone or currency affected
+type DeletionSummary struct {
+	// SpotPricesDeleted includes moved spot prices the target already had a price for
+	SpotPricesDeleted int64 `json:"spot_prices_deleted" example:"24"`
+	SpotPricesMoved   int64 `json:"spot_prices_moved" example:"0"`
+	// SourceValuesDeleted and SourceValuesMoved count the prices kept per provider
+	SourceValuesDeleted int64 `json:"source_values_deleted" example:"24"`
+	SourceValuesMoved   int64 `json:"source_values_moved" example:"0"`
+}
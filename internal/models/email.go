@@ -0,0 +1,15 @@
+package models
+
+// SendTestEmailRequest represents a request to send a diagnostic email
+type SendTestEmailRequest struct {
+	To string `json:"to" binding:"required,email" example:"admin@example.com"`
+}
+
+// EmailTestResult describes the outcome of a diagnostic email, including the SMTP dialogue
+type EmailTestResult struct {
+	Success    bool     `json:"success" example:"true"`
+	Server     string   `json:"server" example:"smtp.example.com:587"`
+	DurationMS int64    `json:"duration_ms" example:"412"`
+	Error      string   `json:"error,omitempty" example:"failed to authenticate with SMTP server: 535 5.7.8 authentication failed"`
+	Transcript []string `json:"transcript"`
+}
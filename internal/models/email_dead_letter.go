@@ -0,0 +1,26 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailDeadLetter is an email that failed every delivery attempt, kept so it can be resent
+type EmailDeadLetter struct {
+	ID          uuid.UUID `json:"id"`
+	Kind        string    `json:"kind" example:"password_reset"`
+	Recipient   string    `json:"recipient" example:"user@example.com"`
+	Subject     string    `json:"subject" example:"Reset Your Password"`
+	ContentType string    `json:"content_type" example:"text/html"`
+	// Body and its plain text alternative TextBody are left out of responses since they
+	// hold links with tokens
+	Body      string     `json:"-"`
+	TextBody  string     `json:"-"`
+	Provider  string     `json:"provider" example:"smtp"`
+	Error     string     `json:"error" example:"failed to dial SMTP server: connection refused"`
+	Attempts  int        `json:"attempts" example:"3"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ResentAt  *time.Time `json:"resent_at,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// EmailSuppressionReason is why an address no longer receives email
+type EmailSuppressionReason string
+
+const (
+	EmailSuppressionBounced    EmailSuppressionReason = "bounced"
+	EmailSuppressionComplained EmailSuppressionReason = "complained"
+)
+
+// EmailStatusDeliverable is the email status of users whose address is not suppressed
+const EmailStatusDeliverable = "deliverable"
+
+// EmailSuppression represents an address that is excluded from outgoing email
+type EmailSuppression struct {
+	Email     string                 `json:"email" example:"user@example.com"`
+	Reason    EmailSuppressionReason `json:"reason" example:"bounced"`
+	Provider  string                 `json:"provider" example:"ses"`
+	Detail    *string                `json:"detail,omitempty"`
+	CreatedAt time.Time              `json:"created_at"`
+	UpdatedAt time.Time              `json:"updated_at"`
+}
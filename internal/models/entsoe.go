@@ -0,0 +1,23 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EntsoeArea maps a zone to the ENTSO-E bidding zone its prices are fetched for
+type EntsoeArea struct {
+	ZoneID   uuid.UUID `json:"zone_id" db:"zone_id"`
+	ZoneName string    `json:"zone_name" db:"zone_name" example:"SE3"`
+	// AreaCode is the EIC code of the bidding zone
+	AreaCode  string    `json:"area_code" db:"area_code" example:"10Y1001A1001A46L"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// SetEntsoeAreaRequest maps a zone to an ENTSO-E bidding zone
+type SetEntsoeAreaRequest struct {
+	// AreaCode is the EIC code of the bidding zone, the zone's own EIC code when left out
+	AreaCode string `json:"area_code" binding:"omitempty,len=16" example:"10Y1001A1001A46L"`
+}
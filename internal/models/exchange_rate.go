@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Sources of exchange rates
+const (
+	ExchangeRateSourceManual = "manual"
+	ExchangeRateSourceECB    = "ecb"
+)
+
+// ExchangeRate is how many units of the quote currency one unit of the base currency buys,
+// from Timestamp until the next rate of the pair
+type ExchangeRate struct {
+	BaseCurrencyID  uuid.UUID `json:"base_currency_id"`
+	QuoteCurrencyID uuid.UUID `json:"quote_currency_id"`
+	Timestamp       time.Time `json:"timestamp" example:"2024-03-20T00:00:00Z"`
+	Rate            float64   `json:"rate" example:"11.2345"`
+	Source          string    `json:"source" example:"ecb"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+// CreateExchangeRateRequest represents a single rate in a batch ingestion request
+type CreateExchangeRateRequest struct {
+	BaseCurrencyID  uuid.UUID `json:"base_currency_id" binding:"required"`
+	QuoteCurrencyID uuid.UUID `json:"quote_currency_id" binding:"required"`
+	Timestamp       time.Time `json:"timestamp" binding:"required" example:"2024-03-20T00:00:00Z"`
+	Rate            float64   `json:"rate" binding:"required,gt=0" example:"11.2345"`
+}
+
+// CreateExchangeRatesRequest represents a batch ingestion request for exchange rates
+type CreateExchangeRatesRequest struct {
+	Rates []CreateExchangeRateRequest `json:"rates" binding:"required,min=1,dive"`
+}
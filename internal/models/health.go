@@ -7,3 +7,19 @@ type HealthResponse struct {
 	Status string    `json:"status" example:"healthy"`
 	Time   time.Time `json:"time" example:"2024-03-20T13:00:00Z"`
 }
+
+// ReadinessResponse reports whether the server can take traffic and the state of each dependency
+type ReadinessResponse struct {
+	Status string             `json:"status" example:"ready"`
+	Time   time.Time          `json:"time" example:"2024-03-20T13:00:00Z"`
+	Checks []DependencyStatus `json:"checks"`
+}
+
+// DependencyStatus is the outcome of checking one dependency. Only critical dependencies
+// that fail make the server not ready.
+type DependencyStatus struct {
+	Name     string `json:"name" example:"database"`
+	Status   string `json:"status" example:"ok"`
+	Critical bool   `json:"critical" example:"true"`
+	Detail   string `json:"detail,omitempty" example:"PostgreSQL 16.2"`
+}
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Impersonation is a session in which an admin acts as another user, for support. Its
+// tokens stop working when it is ended or expires.
+type Impersonation struct {
+	ID        uuid.UUID  `json:"id"`
+	AdminID   uuid.UUID  `json:"admin_id"`
+	UserID    uuid.UUID  `json:"user_id"`
+	StartedAt time.Time  `json:"started_at"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	EndedAt   *time.Time `json:"ended_at,omitempty"`
+}
+
+// Active reports whether the impersonation's tokens can be used at the given time
+func (i *Impersonation) Active(at time.Time) bool {
+	return i.EndedAt == nil && at.Before(i.ExpiresAt)
+}
+
+// ImpersonationResponse is the access token an admin acts as the impersonated user with.
+// No refresh token is issued, a new impersonation has to be started once it expires.
+type ImpersonationResponse struct {
+	AccessToken     string    `json:"access_token" example:"eyJhbGciOiJIUzI1NiIs..."`
+	ImpersonationID uuid.UUID `json:"impersonation_id"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	User            *User     `json:"user"`
+}
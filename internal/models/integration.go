@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IntegrationToken is a long-lived token a user issues to an integration such as Home
+// Assistant. It can only read prices, the token itself is only returned when created.
+type IntegrationToken struct {
+	ID         uuid.UUID  `json:"id"`
+	UserID     uuid.UUID  `json:"user_id"`
+	Name       string     `json:"name" example:"Home Assistant"`
+	TokenHash  string     `json:"-"`
+	LastUsedAt *time.Time `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreatedIntegrationToken is a new integration token with the token to configure the
+// integration with
+type CreatedIntegrationToken struct {
+	IntegrationToken
+	Token string `json:"token" example:"wwi_5f2b6c..."`
+}
+
+// CreateIntegrationTokenRequest represents the request to issue an integration token
+type CreateIntegrationTokenRequest struct {
+	Name string `json:"name" binding:"required,max=100" example:"Home Assistant"`
+}
+
+// HomeAssistantPrice is a price of the next hours in a Home Assistant sensor
+type HomeAssistantPrice struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Price float64   `json:"price" example:"0.4215"`
+}
+
+// HomeAssistantSensor is the state of a Home Assistant REST sensor. It is flat so the
+// sensor reads the price as its value and the other fields as attributes. Prices are in
+// the currency per kWh, like Home Assistant's energy dashboard expects.
+type HomeAssistantSensor struct {
+	// Price is the current price, nil when no price covers the current time
+	Price    *float64 `json:"price" example:"0.4215"`
+	Unit     string   `json:"unit" example:"SEK/kWh"`
+	Currency string   `json:"currency" example:"SEK"`
+	Zone     string   `json:"zone" example:"SE3"`
+	// Start and End bound the current price
+	Start *time.Time `json:"start,omitempty"`
+	End   *time.Time `json:"end,omitempty"`
+	// TodayMin, TodayMax and TodayAvg describe today's prices in the zone's timezone
+	TodayMin *float64 `json:"today_min,omitempty" example:"0.1532"`
+	TodayMax *float64 `json:"today_max,omitempty" example:"1.2047"`
+	TodayAvg *float64 `json:"today_avg,omitempty" example:"0.5120"`
+	// NextHours are the prices from the current one on
+	NextHours []HomeAssistantPrice `json:"next_hours"`
+	UpdatedAt time.Time            `json:"updated_at"`
+}
+
+// HomeAssistantDiscovery describes how to set up Home Assistant's REST sensor with the
+// sensor endpoint, so no documentation is needed
+type HomeAssistantDiscovery struct {
+	// SensorURL is the sensor endpoint, zone and currency are appended as query parameters
+	SensorURL string `json:"sensor_url" example:"https://wattwatch.example.com/api/v1/integrations/homeassistant/sensor"`
+	// TokenURL is where integration tokens are issued with an access token
+	TokenURL string `json:"token_url" example:"https://wattwatch.example.com/api/v1/integrations/homeassistant/tokens"`
+	// Authentication explains how the sensor authenticates
+	Authentication string `json:"authentication" example:"Authorization: Bearer <integration token>"`
+	// Parameters of the sensor endpoint, by name
+	Parameters map[string]string `json:"parameters"`
+	Zones      []string          `json:"zones" example:"SE3"`
+	Currencies []string          `json:"currencies" example:"SEK"`
+	// Configuration is an example for configuration.yaml
+	Configuration string `json:"configuration"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// JobTrigger is what started a job run
+type JobTrigger string
+
+const (
+	JobTriggerSchedule JobTrigger = "schedule"
+	JobTriggerManual   JobTrigger = "manual"
+)
+
+// JobStatus is the state of a job run
+type JobStatus string
+
+const (
+	JobStatusRunning   JobStatus = "running"
+	JobStatusSucceeded JobStatus = "succeeded"
+	JobStatusFailed    JobStatus = "failed"
+)
+
+// Job is a recurring background job
+type Job struct {
+	Name string `json:"name" db:"name" example:"token-cleanup"`
+	// Schedule is a cron expression, empty for jobs that only run when triggered
+	Schedule string `json:"schedule" db:"schedule" example:"0 * * * *"`
+	// NextRun is when the job runs next on this instance, unset without a schedule
+	NextRun *time.Time `json:"next_run,omitempty"`
+	// Running is set while a run of the job is in progress on this instance
+	Running bool `json:"running"`
+	// LastRun is the most recent run on any instance
+	LastRun *JobRun `json:"last_run,omitempty"`
+}
+
+// JobRun is one execution of a job
+type JobRun struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	JobName     string     `json:"job_name" db:"job_name"`
+	Trigger     JobTrigger `json:"trigger" db:"trigger"`
+	TriggeredBy *uuid.UUID `json:"triggered_by,omitempty" db:"triggered_by"`
+	Status      JobStatus  `json:"status" db:"status"`
+	Error       string     `json:"error,omitempty" db:"error"`
+	StartedAt   time.Time  `json:"started_at" db:"started_at"`
+	FinishedAt  *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+}
@@ -0,0 +1,18 @@
+package models
+
+import "time"
+
+// MaintenanceStatus describes whether the API is in maintenance mode
+type MaintenanceStatus struct {
+	Enabled           bool       `json:"enabled" example:"true"`
+	Message           string     `json:"message,omitempty" example:"Database upgrade in progress"`
+	RetryAfterSeconds int        `json:"retry_after_seconds" example:"300"`
+	Since             *time.Time `json:"since,omitempty"`
+}
+
+// UpdateMaintenanceRequest turns maintenance mode on or off
+type UpdateMaintenanceRequest struct {
+	Enabled           *bool  `json:"enabled" binding:"required" example:"true"`
+	Message           string `json:"message" binding:"max=500" example:"Database upgrade in progress"`
+	RetryAfterSeconds int    `json:"retry_after_seconds" binding:"omitempty,min=1,max=86400" example:"300"`
+}
@@ -0,0 +1,21 @@
+package models
+
+// MigrationInfo is a database migration
+type MigrationInfo struct {
+	Version uint   `json:"version" example:"38"`
+	Name    string `json:"name" example:"price_forecasts"`
+}
+
+// MigrationStatus is the schema version of the database and the migrations left to apply
+type MigrationStatus struct {
+	// CurrentVersion is the applied version, 0 before the first migration
+	CurrentVersion uint `json:"current_version" example:"37"`
+	// LatestVersion is the highest version the server ships migrations for
+	LatestVersion uint `json:"latest_version" example:"38"`
+	// Dirty is set when a migration failed halfway, the schema must be repaired by hand
+	// before more are applied
+	Dirty   bool            `json:"dirty" example:"false"`
+	Pending []MigrationInfo `json:"pending"`
+	// Applied lists the migrations applied by the request, left out when only listing
+	Applied []MigrationInfo `json:"applied,omitempty"`
+}
@@ -0,0 +1,134 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// NotificationChannel identifies how a notification is delivered
+type NotificationChannel string
+
+const (
+	NotificationChannelFCM      NotificationChannel = "fcm"
+	NotificationChannelWebPush  NotificationChannel = "webpush"
+	NotificationChannelSlack    NotificationChannel = "slack"
+	NotificationChannelDiscord  NotificationChannel = "discord"
+	NotificationChannelTelegram NotificationChannel = "telegram"
+	NotificationChannelEmail    NotificationChannel = "email"
+)
+
+// NotificationAlertType identifies what triggered a notification
+type NotificationAlertType string
+
+const (
+	NotificationAlertPrice       NotificationAlertType = "price"
+	NotificationAlertConsumption NotificationAlertType = "consumption"
+	NotificationAlertTest        NotificationAlertType = "test"
+)
+
+// DeliveryStatus represents the state of a notification delivery
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending DeliveryStatus = "pending"
+	DeliveryStatusSent    DeliveryStatus = "sent"
+	DeliveryStatusFailed  DeliveryStatus = "failed"
+)
+
+// DeviceToken represents a device registered to receive push notifications
+type DeviceToken struct {
+	ID         uuid.UUID           `json:"id"`
+	UserID     uuid.UUID           `json:"user_id"`
+	Channel    NotificationChannel `json:"channel" example:"webpush"`
+	Token      string              `json:"token"`
+	P256dh     *string             `json:"-"`
+	Auth       *string             `json:"-"`
+	UserAgent  *string             `json:"user_agent,omitempty"`
+	LastUsedAt *time.Time          `json:"last_used_at,omitempty"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// WebPushKeys holds the subscription keys issued by the browser push service
+type WebPushKeys struct {
+	P256dh string `json:"p256dh" binding:"required"`
+	Auth   string `json:"auth" binding:"required"`
+}
+
+// RegisterDeviceRequest represents the request to register a device for push notifications.
+// For FCM the token is the registration token, for WebPush it is the subscription endpoint.
+type RegisterDeviceRequest struct {
+	Channel NotificationChannel `json:"channel" binding:"required,oneof=fcm webpush" example:"webpush"`
+	Token   string              `json:"token" binding:"required"`
+	Keys    *WebPushKeys        `json:"keys,omitempty"`
+}
+
+// NotificationPreference represents whether a user receives an alert type on a channel
+type NotificationPreference struct {
+	UserID    uuid.UUID             `json:"user_id"`
+	Channel   NotificationChannel   `json:"channel" example:"fcm"`
+	AlertType NotificationAlertType `json:"alert_type" example:"price"`
+	Enabled   bool                  `json:"enabled"`
+	UpdatedAt time.Time             `json:"updated_at"`
+}
+
+// NotificationPreferenceInput represents a single preference change
+type NotificationPreferenceInput struct {
+	Channel   NotificationChannel   `json:"channel" binding:"required,oneof=fcm webpush slack discord telegram email" example:"fcm"`
+	AlertType NotificationAlertType `json:"alert_type" binding:"required,oneof=price consumption" example:"price"`
+	Enabled   *bool                 `json:"enabled" binding:"required"`
+}
+
+// UpdateNotificationPreferencesRequest represents the request to update notification preferences
+type UpdateNotificationPreferencesRequest struct {
+	Preferences []NotificationPreferenceInput `json:"preferences" binding:"required,min=1,dive"`
+}
+
+// NotificationDelivery records the outcome of sending a notification to a device or target
+type NotificationDelivery struct {
+	ID            uuid.UUID             `json:"id"`
+	UserID        uuid.UUID             `json:"user_id"`
+	DeviceTokenID *uuid.UUID            `json:"device_token_id,omitempty"`
+	TargetID      *uuid.UUID            `json:"target_id,omitempty"`
+	Channel       NotificationChannel   `json:"channel"`
+	AlertType     NotificationAlertType `json:"alert_type"`
+	Title         string                `json:"title"`
+	Body          string                `json:"body"`
+	Status        DeliveryStatus        `json:"status"`
+	Error         *string               `json:"error,omitempty"`
+	CreatedAt     time.Time             `json:"created_at"`
+	DeliveredAt   *time.Time            `json:"delivered_at,omitempty"`
+}
+
+// NotificationTarget is a user-configured chat destination such as a Slack channel,
+// Discord channel or Telegram group. Credentials are never returned by the API.
+type NotificationTarget struct {
+	ID         uuid.UUID           `json:"id"`
+	UserID     uuid.UUID           `json:"user_id"`
+	Channel    NotificationChannel `json:"channel" example:"telegram"`
+	Name       string              `json:"name" example:"Family group"`
+	WebhookURL *string             `json:"-"`
+	BotToken   *string             `json:"-"`
+	ChatID     *string             `json:"chat_id,omitempty" example:"-1001234567890"`
+	CreatedAt  time.Time           `json:"created_at"`
+	UpdatedAt  time.Time           `json:"updated_at"`
+}
+
+// CreateNotificationTargetRequest represents the request to add a chat destination.
+// Slack and Discord require webhook_url, Telegram requires bot_token and chat_id.
+type CreateNotificationTargetRequest struct {
+	Channel    NotificationChannel `json:"channel" binding:"required,oneof=slack discord telegram" example:"slack"`
+	Name       string              `json:"name" binding:"required,max=100" example:"Ops alerts"`
+	WebhookURL *string             `json:"webhook_url,omitempty" binding:"omitempty,url"`
+	BotToken   *string             `json:"bot_token,omitempty" binding:"omitempty,max=255"`
+	ChatID     *string             `json:"chat_id,omitempty" binding:"omitempty,max=100"`
+}
+
+// UpdateNotificationTargetRequest represents the request to update a chat destination
+type UpdateNotificationTargetRequest struct {
+	Name       *string `json:"name,omitempty" binding:"omitempty,max=100"`
+	WebhookURL *string `json:"webhook_url,omitempty" binding:"omitempty,url"`
+	BotToken   *string `json:"bot_token,omitempty" binding:"omitempty,max=255"`
+	ChatID     *string `json:"chat_id,omitempty" binding:"omitempty,max=100"`
+}
@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// OrganizationRole is a member's role within an organization
+type OrganizationRole string
+
+const (
+	// OrganizationRoleOwner manages the organization and can delete it
+	OrganizationRoleOwner OrganizationRole = "owner"
+	// OrganizationRoleAdmin manages the members and sees their consumption
+	OrganizationRoleAdmin OrganizationRole = "admin"
+	// OrganizationRoleMember sees the other members but only their own consumption
+	OrganizationRoleMember OrganizationRole = "member"
+)
+
+// CanManage reports whether the role may change the organization and its members
+func (r OrganizationRole) CanManage() bool {
+	return r == OrganizationRoleOwner || r == OrganizationRoleAdmin
+}
+
+// Organization groups users, such as a household or a company
+type Organization struct {
+	ID        uuid.UUID `json:"id"`
+	Name      string    `json:"name" example:"Andersson household"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrganizationMember is a user's membership in an organization
+type OrganizationMember struct {
+	OrganizationID uuid.UUID        `json:"organization_id"`
+	UserID         uuid.UUID        `json:"user_id"`
+	Username       string           `json:"username" example:"anna"`
+	Role           OrganizationRole `json:"role" example:"member"`
+	CreatedAt      time.Time        `json:"created_at"`
+}
+
+// CreateOrganizationRequest represents the request to create an organization, the caller
+// becomes its owner
+type CreateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100" example:"Andersson household"`
+}
+
+// UpdateOrganizationRequest represents the request to rename an organization
+type UpdateOrganizationRequest struct {
+	Name string `json:"name" binding:"required,max=100" example:"Andersson household"`
+}
+
+// AddOrganizationMemberRequest represents the request to add a user to an organization
+type AddOrganizationMemberRequest struct {
+	Username string           `json:"username" binding:"required" example:"anna"`
+	Role     OrganizationRole `json:"role" binding:"required,oneof=owner admin member" example:"member"`
+}
+
+// UpdateOrganizationMemberRequest represents the request to change a member's role
+type UpdateOrganizationMemberRequest struct {
+	Role OrganizationRole `json:"role" binding:"required,oneof=owner admin member" example:"admin"`
+}
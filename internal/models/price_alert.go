@@ -0,0 +1,63 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceAlertDirection is the side of the threshold that triggers a price alert
+type PriceAlertDirection string
+
+const (
+	PriceAlertAbove PriceAlertDirection = "above"
+	PriceAlertBelow PriceAlertDirection = "below"
+)
+
+// Matches reports whether price is on the triggering side of threshold
+func (d PriceAlertDirection) Matches(price, threshold float64) bool {
+	if d == PriceAlertBelow {
+		return price < threshold
+	}
+	return price > threshold
+}
+
+// PriceAlert is a user's rule for being notified when a spot price crosses a threshold.
+// WindowStart and WindowEnd are times of day (HH:MM) in the zone's timezone limiting the
+// hours the alert covers, the window wraps past midnight when it ends before it starts.
+type PriceAlert struct {
+	ID              uuid.UUID           `json:"id"`
+	UserID          uuid.UUID           `json:"user_id"`
+	ZoneID          uuid.UUID           `json:"zone_id"`
+	CurrencyID      uuid.UUID           `json:"currency_id"`
+	Direction       PriceAlertDirection `json:"direction" example:"above"`
+	Threshold       float64             `json:"threshold" example:"150"`
+	WindowStart     *string             `json:"window_start,omitempty" example:"06:00"`
+	WindowEnd       *string             `json:"window_end,omitempty" example:"22:00"`
+	Enabled         bool                `json:"enabled"`
+	LastTriggeredAt *time.Time          `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time           `json:"created_at"`
+	UpdatedAt       time.Time           `json:"updated_at"`
+}
+
+// CreatePriceAlertRequest represents the request to create a price alert. The window
+// must have both ends or neither.
+type CreatePriceAlertRequest struct {
+	ZoneID      uuid.UUID           `json:"zone_id" binding:"required"`
+	CurrencyID  uuid.UUID           `json:"currency_id" binding:"required"`
+	Direction   PriceAlertDirection `json:"direction" binding:"required,oneof=above below" example:"above"`
+	Threshold   *float64            `json:"threshold" binding:"required" example:"150"`
+	WindowStart *string             `json:"window_start,omitempty" example:"06:00"`
+	WindowEnd   *string             `json:"window_end,omitempty" example:"22:00"`
+	Enabled     *bool               `json:"enabled,omitempty"`
+}
+
+// UpdatePriceAlertRequest represents the request to update a price alert. An empty
+// window_start and window_end remove the window.
+type UpdatePriceAlertRequest struct {
+	Direction   *PriceAlertDirection `json:"direction,omitempty" binding:"omitempty,oneof=above below" example:"below"`
+	Threshold   *float64             `json:"threshold,omitempty" example:"20"`
+	WindowStart *string              `json:"window_start,omitempty" example:"06:00"`
+	WindowEnd   *string              `json:"window_end,omitempty" example:"22:00"`
+	Enabled     *bool                `json:"enabled,omitempty"`
+}
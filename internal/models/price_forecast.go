@@ -0,0 +1,58 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// PriceForecast is a source's forecast of the spot price of a zone and currency at
+// Timestamp. Forecasts are kept apart from the published spot prices.
+type PriceForecast struct {
+	Timestamp  time.Time `json:"timestamp" example:"2024-03-22T13:00:00Z"`
+	ZoneID     uuid.UUID `json:"zone_id"`
+	CurrencyID uuid.UUID `json:"currency_id"`
+	Source     string    `json:"source" example:"forecast"`
+	// Price is in the unit spot prices are stored in, hundredths of the currency per kWh
+	Price float64 `json:"price" example:"45.20"`
+	// HorizonHours is how many hours before Timestamp the forecast was made
+	HorizonHours int `json:"horizon_hours" example:"36"`
+	// CreatedAt is when the forecast was stored. A newer forecast of the source for the
+	// same time replaces it.
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CreatePriceForecastRequest represents a single forecast in a batch creation request
+type CreatePriceForecastRequest struct {
+	Timestamp    time.Time `json:"timestamp" binding:"required" example:"2024-03-22T13:00:00Z"`
+	ZoneID       uuid.UUID `json:"zone_id" binding:"required"`
+	CurrencyID   uuid.UUID `json:"currency_id" binding:"required"`
+	Price        *float64  `json:"price" binding:"required" example:"45.20"`
+	HorizonHours *int      `json:"horizon_hours" binding:"required,min=0" example:"36"`
+}
+
+// CreatePriceForecastsRequest represents a batch creation request for the forecasts of
+// a source
+type CreatePriceForecastsRequest struct {
+	Source    string                       `json:"source" binding:"required,max=100" example:"forecast"`
+	Forecasts []CreatePriceForecastRequest `json:"forecasts" binding:"required,min=1,max=10000,dive"`
+}
+
+// ForecastedSpotPrice is a published spot price, or a forecast where none is published yet
+type ForecastedSpotPrice struct {
+	Timestamp time.Time `json:"timestamp" example:"2024-03-22T13:00:00Z"`
+	Price     float64   `json:"price" example:"45.20"`
+	// Forecast is set when the price is a forecast rather than a published price
+	Forecast bool `json:"forecast" example:"true"`
+	// Source and HorizonHours describe forecasts and are left out for published prices
+	Source       string `json:"source,omitempty" example:"forecast"`
+	HorizonHours *int   `json:"horizon_hours,omitempty" example:"36"`
+}
+
+// ForecastedSpotPrices are the spot prices of a zone and currency in a range, followed by
+// forecasts for the part of the range after the last published price
+type ForecastedSpotPrices struct {
+	Zone     string                `json:"zone" example:"SE3"`
+	Currency string                `json:"currency" example:"SEK"`
+	Prices   []ForecastedSpotPrice `json:"prices"`
+}
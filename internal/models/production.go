@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ProductionRecord is the energy a user's solar panels or other generation produced during
+// the interval starting at Timestamp, and the part of it exported to the grid
+type ProductionRecord struct {
+	UserID      uuid.UUID `json:"user_id"`
+	MeterID     string    `json:"meter_id" example:"735999100000000002"`
+	Timestamp   time.Time `json:"timestamp" example:"2024-06-20T13:00:00Z"`
+	ProducedKWh float64   `json:"produced_kwh" example:"3.2"`
+	ExportedKWh float64   `json:"exported_kwh" example:"2.1"`
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// CreateProductionRecordRequest represents a single production reading in a batch
+// ingestion request
+type CreateProductionRecordRequest struct {
+	MeterID     string    `json:"meter_id" binding:"required,max=100" example:"735999100000000002"`
+	Timestamp   time.Time `json:"timestamp" binding:"required" example:"2024-06-20T13:00:00Z"`
+	ProducedKWh *float64  `json:"produced_kwh" binding:"required,min=0" example:"3.2"`
+	ExportedKWh *float64  `json:"exported_kwh" binding:"required,min=0" example:"2.1"`
+}
+
+// CreateProductionRequest represents a batch ingestion request for production records
+type CreateProductionRequest struct {
+	Records []CreateProductionRecordRequest `json:"records" binding:"required,min=1,max=10000,dive"`
+}
@@ -8,9 +8,39 @@ import (
 
 // RefreshToken represents a refresh token in the database
 type RefreshToken struct {
-	ID        uuid.UUID `json:"id"`
-	UserID    uuid.UUID `json:"user_id"`
-	Token     string    `json:"token"`
+	ID     uuid.UUID `json:"id"`
+	UserID uuid.UUID `json:"user_id"`
+	Token  string    `json:"token"`
+	// FamilyID is shared by the tokens rotated from the same login
+	FamilyID uuid.UUID `json:"family_id"`
+	// RevokedAt is set once the token was rotated or logged out
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	// ReplacedBy is the token the token was rotated to
+	ReplacedBy *uuid.UUID `json:"replaced_by,omitempty"`
+	// UserAgent and IPAddress describe the client the token was issued to
+	UserAgent string `json:"user_agent"`
+	IPAddress string `json:"ip_address"`
+	// SessionCreatedAt is when the login the token was rotated from happened
+	SessionCreatedAt time.Time `json:"session_created_at"`
+	LastUsedAt       time.Time `json:"last_used_at"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
+
+// SessionClient describes the client a refresh token is issued to
+type SessionClient struct {
+	UserAgent string
+	IPAddress string
+}
+
+// Session is a login of a user on a device, which lasts while its refresh token is rotated
+type Session struct {
+	ID uuid.UUID `json:"id"`
+	// UserAgent and IPAddress are those of the last refresh
+	UserAgent  string    `json:"user_agent" example:"Mozilla/5.0 (X11; Linux x86_64)"`
+	IPAddress  string    `json:"ip_address" example:"192.0.2.1"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	// ExpiresAt is when the session ends unless its refresh token is used
 	ExpiresAt time.Time `json:"expires_at"`
-	CreatedAt time.Time `json:"created_at"`
 }
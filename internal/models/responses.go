@@ -6,7 +6,8 @@ type LoginResponse struct {
 	RefreshToken string `json:"refresh_token"`
 }
 
-// ErrorResponse represents an error response
+// ErrorResponse is the error member of error responses, which are apierror.Problem
+// documents keeping it for clients written before them
 type ErrorResponse struct {
 	Error string `json:"error"`
 }
@@ -15,3 +16,31 @@ type ErrorResponse struct {
 type SuccessResponse struct {
 	Message string `json:"message"`
 }
+
+// Page is one page of a list response. NextOffset is the offset of the next page, or
+// null on the last page. Listings paged with cursors give the cursor of the next page in
+// NextCursor instead, which is left out on the last page.
+type Page[T any] struct {
+	Items      []T     `json:"items"`
+	Total      int     `json:"total" example:"120"`
+	Limit      int     `json:"limit" example:"50"`
+	Offset     int     `json:"offset" example:"0"`
+	NextOffset *int    `json:"next_offset" example:"50"`
+	NextCursor *string `json:"next_cursor,omitempty"`
+}
+
+// NewPage returns the page of items found at offset among total matching items. A limit
+// of zero means the page holds every item from offset on.
+func NewPage[T any](items []T, total, limit, offset int) Page[T] {
+	if items == nil {
+		items = []T{}
+	}
+	if limit <= 0 {
+		limit = max(total-offset, len(items))
+	}
+	page := Page[T]{Items: items, Total: total, Limit: limit, Offset: offset}
+	if next := offset + len(items); len(items) > 0 && next < total {
+		page.NextOffset = &next
+	}
+	return page
+}
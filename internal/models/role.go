@@ -1,6 +1,7 @@
 package models
 
 import (
+	"slices"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,15 +9,67 @@ import (
 
 // Role represents a role in the system
 type Role struct {
-	ID           uuid.UUID  `json:"id" db:"id"`
-	Name         string     `json:"name" db:"name" binding:"required,min=3,max=50,nospaces"`
-	IsProtected  bool       `json:"is_protected" db:"is_protected"`
-	IsAdminGroup bool       `json:"is_admin_group" db:"is_admin_group"`
+	ID           uuid.UUID `json:"id" db:"id"`
+	Name         string    `json:"name" db:"name" binding:"required,min=3,max=50,nospaces"`
+	IsProtected  bool      `json:"is_protected" db:"is_protected"`
+	IsAdminGroup bool      `json:"is_admin_group" db:"is_admin_group"`
+	// Permissions are the permissions granted to the role on top of PermissionRead
+	Permissions []string `json:"permissions"`
+	// TokenVersion changes whenever the role does, invalidating the role claims of issued tokens
+	TokenVersion int        `json:"-" db:"token_version"`
 	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
 	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
 	DeletedAt    *time.Time `json:"deleted_at,omitempty" db:"deleted_at"`
 }
 
+// Permissions granted by roles, embedded in access tokens
+const (
+	// PermissionRead allows reading zones, currencies and prices
+	PermissionRead = "read"
+	// PermissionAdmin allows managing users, roles and reference data
+	PermissionAdmin = "admin"
+)
+
+// Permissions that can be granted to roles individually. Admin groups have all of them.
+const (
+	PermissionSpotPricesWrite  = "spot_prices:write"
+	PermissionZonesManage      = "zones:manage"
+	PermissionCurrenciesManage = "currencies:manage"
+	PermissionUsersManage      = "users:manage"
+)
+
+// Permission describes a permission that can be granted to roles
+type Permission struct {
+	Name        string `json:"name" example:"spot_prices:write"`
+	Description string `json:"description" example:"Upload and delete spot prices"`
+}
+
+// GrantablePermissions are the permissions that can be granted to roles
+var GrantablePermissions = []Permission{
+	{Name: PermissionSpotPricesWrite, Description: "Upload and delete spot prices"},
+	{Name: PermissionZonesManage, Description: "Create, update and delete zones"},
+	{Name: PermissionCurrenciesManage, Description: "Create, update and delete currencies"},
+	{Name: PermissionUsersManage, Description: "View all users, and update and delete those that aren't administrators without changing their role or password"},
+}
+
+// IsGrantablePermission reports whether name is one of GrantablePermissions
+func IsGrantablePermission(name string) bool {
+	return slices.ContainsFunc(GrantablePermissions, func(p Permission) bool { return p.Name == name })
+}
+
+// Grants returns the permissions the role grants, as embedded in access tokens
+func (r *Role) Grants() []string {
+	if r.IsAdminGroup {
+		return []string{PermissionRead, PermissionAdmin}
+	}
+	return append([]string{PermissionRead}, r.Permissions...)
+}
+
+// Has reports whether the role grants the permission
+func (r *Role) Has(permission string) bool {
+	return r.IsAdminGroup || permission == PermissionRead || slices.Contains(r.Permissions, permission)
+}
+
 // CreateRoleRequest represents the request to create a new role
 type CreateRoleRequest struct {
 	Name         string `json:"name" binding:"required,min=3,max=50,nospaces"`
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventType is the kind of unusual activity on an account
+type SecurityEventType string
+
+const (
+	// SecurityEventNewDevice is a login from a device the user hadn't logged in from before
+	SecurityEventNewDevice SecurityEventType = "new_device_login"
+	// SecurityEventNewIP is a login from a known device at an IP address the user hadn't
+	// logged in from before
+	SecurityEventNewIP SecurityEventType = "new_ip_login"
+)
+
+// SecurityEvent records unusual activity on an account, such as a login from a new device
+type SecurityEvent struct {
+	ID        uuid.UUID         `json:"id"`
+	UserID    uuid.UUID         `json:"user_id"`
+	Type      SecurityEventType `json:"type" example:"new_device_login"`
+	IPAddress string            `json:"ip_address" example:"192.0.2.1"`
+	UserAgent string            `json:"user_agent" example:"Mozilla/5.0 (X11; Linux x86_64)"`
+	CreatedAt time.Time         `json:"created_at"`
+}
+
+// LoginHistory tells whether a login comes from where the user logged in before
+type LoginHistory struct {
+	// FirstLogin is set when the user never logged in successfully before
+	FirstLogin bool
+	// KnownDevice and KnownIP are set when an earlier successful login came from the
+	// device or the IP address
+	KnownDevice bool
+	KnownIP     bool
+}
+
+// DeviceFingerprint identifies the device of a login by hashing its user agent
+func DeviceFingerprint(userAgent string) string {
+	sum := sha256.Sum256([]byte(strings.TrimSpace(userAgent)))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,39 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Setting is a runtime setting stored in the database, it overrides the configured value
+type Setting struct {
+	Key       string     `json:"key" db:"key"`
+	Value     string     `json:"value" db:"value"`
+	UpdatedBy *uuid.UUID `json:"updated_by,omitempty" db:"updated_by"`
+	UpdatedAt time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// RuntimeSetting describes a setting that can be changed without a redeploy
+type RuntimeSetting struct {
+	Key         string `json:"key" example:"auth.registration_open"`
+	Type        string `json:"type" example:"bool"`
+	Description string `json:"description" example:"Whether anyone can register an account"`
+	// Value is the effective value, the override when there is one
+	Value string `json:"value" example:"false"`
+	// ConfigValue is the value from the environment or config file
+	ConfigValue string     `json:"config_value" example:"true"`
+	Overridden  bool       `json:"overridden" example:"true"`
+	UpdatedBy   *uuid.UUID `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateSettingRequest overrides the configured value of a runtime setting
+type UpdateSettingRequest struct {
+	Value string `json:"value" binding:"required" example:"false"`
+}
+
+// UpdateSettingsRequest overrides the configured values of several runtime settings at once
+type UpdateSettingsRequest struct {
+	Settings map[string]string `json:"settings" binding:"required,min=1" example:"auth.lockout_threshold:10"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"math"
 	"time"
 
 	"github.com/google/uuid"
@@ -29,3 +30,231 @@ type CreateSpotPriceRequest struct {
 type CreateSpotPricesRequest struct {
 	SpotPrices []CreateSpotPriceRequest `json:"spot_prices" binding:"required,min=1"`
 }
+
+// SpotPriceSeries is the columnar form of a list of spot prices, used by charting clients.
+// Prices[i] is the price at Timestamps[i].
+type SpotPriceSeries struct {
+	Zone       string      `json:"zone" example:"SE3"`
+	Currency   string      `json:"currency" example:"EUR"`
+	Timestamps []time.Time `json:"timestamps"`
+	Prices     []float64   `json:"prices"`
+	// NextCursor continues the listing after these prices, left out on the last page
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// SpotPricePoint is a spot price and the time it starts applying
+type SpotPricePoint struct {
+	Timestamp time.Time `json:"timestamp" example:"2024-03-20T13:00:00Z"`
+	Price     float64   `json:"price" example:"42.50"`
+}
+
+// SpotPriceDay holds the spot prices of a day in the zone's timezone, with their statistics
+type SpotPriceDay struct {
+	Date   string           `json:"date" example:"2024-03-20"`
+	Min    float64          `json:"min" example:"12.10"`
+	Max    float64          `json:"max" example:"98.30"`
+	Avg    float64          `json:"avg" example:"42.50"`
+	Prices []SpotPricePoint `json:"prices"`
+}
+
+// NewSpotPriceDay returns the statistics of the points of a day, ordered by timestamp,
+// or nil without points
+func NewSpotPriceDay(points []SpotPricePoint) *SpotPriceDay {
+	if len(points) == 0 {
+		return nil
+	}
+	day := &SpotPriceDay{
+		Date:   points[0].Timestamp.Format(time.DateOnly),
+		Min:    math.Inf(1),
+		Max:    math.Inf(-1),
+		Prices: points,
+	}
+	for _, p := range points {
+		day.Min = math.Min(day.Min, p.Price)
+		day.Max = math.Max(day.Max, p.Price)
+		day.Avg += p.Price
+	}
+	day.Avg /= float64(len(points))
+	return day
+}
+
+// SpotPriceResolution returns how long the points, ordered by timestamp, apply. Prices
+// apply until the next one, the shortest gap is taken as the resolution, an hour for a
+// single point.
+func SpotPriceResolution(points []SpotPricePoint) time.Duration {
+	resolution := time.Hour
+	for i := 1; i < len(points); i++ {
+		if gap := points[i].Timestamp.Sub(points[i-1].Timestamp); gap > 0 && (i == 1 || gap < resolution) {
+			resolution = gap
+		}
+	}
+	return resolution
+}
+
+// SpotPriceWindow is a period of consecutive spot prices, from Start until End
+type SpotPriceWindow struct {
+	Start time.Time `json:"start" example:"2024-03-21T01:00:00Z"`
+	End   time.Time `json:"end" example:"2024-03-21T04:00:00Z"`
+	Avg   float64   `json:"avg" example:"8.75"`
+}
+
+// SpotPriceSummary is an overview of the spot prices of a zone and currency at a moment
+type SpotPriceSummary struct {
+	Zone     string `json:"zone" example:"SE3"`
+	Currency string `json:"currency" example:"SEK"`
+	// Current is the price in effect, null when it is missing
+	Current *SpotPricePoint `json:"current"`
+	// Today is null when no prices are stored for today
+	Today *SpotPriceDay `json:"today"`
+	// Tomorrow is null until tomorrow's prices are published
+	Tomorrow *SpotPriceDay `json:"tomorrow"`
+	// WindowHours is the length of CheapestWindow
+	WindowHours int `json:"window_hours" example:"3"`
+	// CheapestWindow is the period of consecutive prices with the lowest average that
+	// starts no earlier than the current price and ends within 24 hours, null when not enough prices
+	// are stored. The earliest one wins a tie.
+	CheapestWindow *SpotPriceWindow `json:"cheapest_window"`
+}
+
+// SpotPricePeriod is a spot price and the period it applies in
+type SpotPricePeriod struct {
+	Start time.Time `json:"start" example:"2024-03-21T01:00:00Z"`
+	End   time.Time `json:"end" example:"2024-03-21T02:00:00Z"`
+	Price float64   `json:"price" example:"8.75"`
+}
+
+// CheapestSpotPrices are the spot prices with the lowest average that together last the
+// hours asked for, such as when to run an appliance or charge a car
+type CheapestSpotPrices struct {
+	Zone       string `json:"zone" example:"SE3"`
+	Currency   string `json:"currency" example:"SEK"`
+	Hours      int    `json:"hours" example:"3"`
+	Contiguous bool   `json:"contiguous" example:"true"`
+	// Start is when the first price starts applying and End when the last one stops
+	Start time.Time `json:"start" example:"2024-03-21T01:00:00Z"`
+	End   time.Time `json:"end" example:"2024-03-21T04:00:00Z"`
+	// AvgPrice is the average price in the unit prices are stored in, hundredths of the
+	// currency per kWh
+	AvgPrice float64 `json:"avg_price" example:"8.75"`
+	// CostPerKWh is the expected cost of a kWh used evenly over the prices, in the currency
+	CostPerKWh float64           `json:"cost_per_kwh" example:"0.0875"`
+	Prices     []SpotPricePeriod `json:"prices"`
+}
+
+// AggregatePeriod is the length of the buckets spot prices are aggregated in
+type AggregatePeriod string
+
+const (
+	AggregateHour  AggregatePeriod = "hour"
+	AggregateDay   AggregatePeriod = "day"
+	AggregateWeek  AggregatePeriod = "week"
+	AggregateMonth AggregatePeriod = "month"
+)
+
+// Valid reports whether p is one of the supported periods
+func (p AggregatePeriod) Valid() bool {
+	switch p {
+	case AggregateHour, AggregateDay, AggregateWeek, AggregateMonth:
+		return true
+	}
+	return false
+}
+
+// SpotPriceAggregate holds statistics of the spot prices of a zone and currency in one
+// bucket. Buckets start at midnight, Monday for weeks, in the zone's timezone.
+type SpotPriceAggregate struct {
+	Start      time.Time `json:"start" example:"2024-03-20T00:00:00+01:00"`
+	ZoneID     uuid.UUID `json:"zone_id"`
+	Zone       string    `json:"zone" example:"SE3"`
+	CurrencyID uuid.UUID `json:"currency_id"`
+	Currency   string    `json:"currency" example:"EUR"`
+	Avg        float64   `json:"avg" example:"42.50"`
+	Min        float64   `json:"min" example:"12.10"`
+	Max        float64   `json:"max" example:"98.30"`
+	// StdDev is the population standard deviation of the prices
+	StdDev float64 `json:"stddev" example:"18.20"`
+	// Count is the number of spot prices in the bucket
+	Count int `json:"count" example:"24"`
+}
+
+// SpotPricePeriodStats holds statistics of the spot prices of a zone and currency from
+// Start until End. The statistics are null when no prices are stored in the period.
+type SpotPricePeriodStats struct {
+	Start time.Time `json:"start" example:"2024-03-01T00:00:00+01:00"`
+	End   time.Time `json:"end" example:"2024-04-01T00:00:00+02:00"`
+	Avg   *float64  `json:"avg" example:"42.50"`
+	Min   *float64  `json:"min" example:"12.10"`
+	Max   *float64  `json:"max" example:"98.30"`
+	Count int       `json:"count" example:"744"`
+}
+
+// SpotPricePeriodComparison holds the statistics of an earlier period and how those of the
+// compared period differ from them. Changes are the compared value less the earlier one,
+// percentages are relative to the earlier value and rounded to two decimals. A change is
+// null when either period has no prices, a percentage also when the earlier value is 0.
+type SpotPricePeriodComparison struct {
+	SpotPricePeriodStats
+	// Against names how far the period lies before the compared one: week, month or year
+	Against          string   `json:"against,omitempty" example:"year"`
+	AvgChange        *float64 `json:"avg_change" example:"-8.25"`
+	AvgChangePercent *float64 `json:"avg_change_percent" example:"-16.26"`
+	MinChange        *float64 `json:"min_change" example:"1.40"`
+	MinChangePercent *float64 `json:"min_change_percent" example:"13.08"`
+	MaxChange        *float64 `json:"max_change" example:"-20.70"`
+	MaxChangePercent *float64 `json:"max_change_percent" example:"-17.39"`
+}
+
+// SpotPriceComparison compares the spot prices of a period with those of the same period
+// a week, month or year earlier
+type SpotPriceComparison struct {
+	Zone     string                      `json:"zone" example:"SE3"`
+	Currency string                      `json:"currency" example:"SEK"`
+	Period   SpotPricePeriodStats        `json:"period"`
+	Previous []SpotPricePeriodComparison `json:"previous"`
+}
+
+// SpotPriceSourceValue is the price a source reported for a spot price
+type SpotPriceSourceValue struct {
+	Source    string    `json:"source" example:"nordpool"`
+	Price     float64   `json:"price" example:"42.50"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// SpotPriceConflict is a spot price that sources reported differing values for
+type SpotPriceConflict struct {
+	Timestamp  time.Time `json:"timestamp"`
+	ZoneID     uuid.UUID `json:"zone_id"`
+	CurrencyID uuid.UUID `json:"currency_id"`
+	// Price is the value currently stored
+	Price float64 `json:"price" example:"42.50"`
+	// ResolvedSource is the source an administrator chose, empty while unresolved
+	ResolvedSource string                 `json:"resolved_source,omitempty" example:"nordpool"`
+	Values         []SpotPriceSourceValue `json:"values"`
+}
+
+// ResolveSpotPriceConflictRequest chooses the source whose value a spot price keeps
+type ResolveSpotPriceConflictRequest struct {
+	Timestamp  time.Time `json:"timestamp" binding:"required" example:"2024-03-20T13:00:00Z"`
+	ZoneID     uuid.UUID `json:"zone_id" binding:"required"`
+	CurrencyID uuid.UUID `json:"currency_id" binding:"required"`
+	Source     string    `json:"source" binding:"required" example:"nordpool"`
+}
+
+// SpotPriceRevision is an earlier price of a spot price, recorded when an update changed it
+type SpotPriceRevision struct {
+	ID          uuid.UUID `json:"id"`
+	SpotPriceID uuid.UUID `json:"spot_price_id"`
+	Timestamp   time.Time `json:"timestamp"`
+	ZoneID      uuid.UUID `json:"zone_id"`
+	CurrencyID  uuid.UUID `json:"currency_id"`
+	// Price is the value before the change
+	Price float64 `json:"price" example:"42.50"`
+	// NewPrice is the value the change set
+	NewPrice float64 `json:"new_price" example:"44.10"`
+	// ChangedBy is the user who made the change, nil for changes not made by a user
+	ChangedBy *uuid.UUID `json:"changed_by,omitempty"`
+	// Source is the provider that reported the new price, or the source a conflict was
+	// resolved to. It is empty for prices set through the API.
+	Source    string    `json:"source,omitempty" example:"nordpool"`
+	ChangedAt time.Time `json:"changed_at"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Tariff is what a grid operator and the state add to the spot price of electricity in a
+// zone and currency. It belongs to a user, or to an organization whose members share it.
+// Fees and tax are in the currency, not the hundredths spot prices are stored in.
+type Tariff struct {
+	ID             uuid.UUID  `json:"id"`
+	UserID         *uuid.UUID `json:"user_id,omitempty"`
+	OrganizationID *uuid.UUID `json:"organization_id,omitempty"`
+	Name           string     `json:"name" example:"Ellevio Stockholm"`
+	ZoneID         uuid.UUID  `json:"zone_id"`
+	CurrencyID     uuid.UUID  `json:"currency_id"`
+	// TransferFee is the grid operator's fee per kWh outside the rates
+	TransferFee float64 `json:"transfer_fee" example:"0.25"`
+	// EnergyTax is the tax per kWh
+	EnergyTax float64 `json:"energy_tax" example:"0.439"`
+	// MonthlyFee is the fixed fee of each month
+	MonthlyFee float64 `json:"monthly_fee" example:"365"`
+	// VAT is the percentage of value added tax on the spot price, fees and energy tax
+	VAT float64 `json:"vat" example:"25"`
+	// ExportFee is what the buyer of exported energy deducts from the spot price per kWh
+	ExportFee float64 `json:"export_fee" example:"0.05"`
+	// Rates replace the transfer fee at times of day, the first matching rate applies
+	Rates     []TariffRate `json:"rates"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// TariffRate is the transfer fee during a window of the day (HH:MM in the zone's timezone),
+// which wraps past midnight when it ends before it starts. Weekdays limits it to Monday to
+// Friday and Months to the months listed (1 for January), all months when empty.
+type TariffRate struct {
+	Start       string  `json:"start" binding:"required" example:"06:00"`
+	End         string  `json:"end" binding:"required" example:"22:00"`
+	Weekdays    bool    `json:"weekdays,omitempty"`
+	Months      []int   `json:"months,omitempty" binding:"omitempty,dive,min=1,max=12" example:"1,2,3,11,12"`
+	TransferFee float64 `json:"transfer_fee" binding:"min=0" example:"0.76"`
+}
+
+// CreateTariffRequest represents the request to create a tariff. Tariffs of an
+// organization are created by its owners and admins. A user or organization has at most
+// one tariff per zone and currency.
+type CreateTariffRequest struct {
+	OrganizationID *uuid.UUID   `json:"organization_id,omitempty"`
+	Name           string       `json:"name" binding:"required,max=100" example:"Ellevio Stockholm"`
+	ZoneID         uuid.UUID    `json:"zone_id" binding:"required"`
+	CurrencyID     uuid.UUID    `json:"currency_id" binding:"required"`
+	TransferFee    float64      `json:"transfer_fee" binding:"min=0" example:"0.25"`
+	EnergyTax      float64      `json:"energy_tax" binding:"min=0" example:"0.439"`
+	MonthlyFee     float64      `json:"monthly_fee" binding:"min=0" example:"365"`
+	VAT            float64      `json:"vat" binding:"min=0,max=100" example:"25"`
+	ExportFee      float64      `json:"export_fee" binding:"min=0" example:"0.05"`
+	Rates          []TariffRate `json:"rates,omitempty" binding:"max=24,dive"`
+}
+
+// UpdateTariffRequest represents the request to update a tariff, rates replace the
+// existing ones when given
+type UpdateTariffRequest struct {
+	Name        *string       `json:"name,omitempty" binding:"omitempty,min=1,max=100" example:"Ellevio Stockholm"`
+	TransferFee *float64      `json:"transfer_fee,omitempty" binding:"omitempty,min=0" example:"0.25"`
+	EnergyTax   *float64      `json:"energy_tax,omitempty" binding:"omitempty,min=0" example:"0.439"`
+	MonthlyFee  *float64      `json:"monthly_fee,omitempty" binding:"omitempty,min=0" example:"365"`
+	VAT         *float64      `json:"vat,omitempty" binding:"omitempty,min=0,max=100" example:"25"`
+	ExportFee   *float64      `json:"export_fee,omitempty" binding:"omitempty,min=0" example:"0.05"`
+	Rates       *[]TariffRate `json:"rates,omitempty" binding:"omitempty,max=24,dive"`
+}
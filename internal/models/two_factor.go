@@ -0,0 +1,72 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactor is a user's TOTP secret. It protects logins once EnabledAt is set, before that
+// the enrollment waits for a code to confirm it.
+type TwoFactor struct {
+	UserID       uuid.UUID
+	Secret       string
+	EnabledAt    *time.Time
+	LastUsedStep *int64
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+// Enabled reports whether logins require a code
+func (t *TwoFactor) Enabled() bool {
+	return t.EnabledAt != nil
+}
+
+// TwoFactorStatus describes whether two-factor authentication protects the user's logins
+type TwoFactorStatus struct {
+	Enabled              bool       `json:"enabled"`
+	EnabledAt            *time.Time `json:"enabled_at,omitempty"`
+	BackupCodesRemaining int        `json:"backup_codes_remaining" example:"10"`
+}
+
+// TwoFactorEnrollment is a new TOTP secret to add to an authenticator app, as the secret
+// itself, an otpauth:// URL and a QR code of the URL
+type TwoFactorEnrollment struct {
+	Secret string `json:"secret" example:"JBSWY3DPEHPK3PXP"`
+	URL    string `json:"otpauth_url" example:"otpauth://totp/WattWatch:johndoe?issuer=WattWatch&secret=JBSWY3DPEHPK3PXP"`
+	// QRCode is a PNG image as a data URL
+	QRCode string `json:"qr_code" example:"data:image/png;base64,iVBORw0KGgo..."`
+}
+
+// TwoFactorCodeRequest carries a code from the authenticator app
+type TwoFactorCodeRequest struct {
+	Code string `json:"code" binding:"required,max=32" example:"123456"`
+}
+
+// TwoFactorBackupCodes are the single-use codes that replace a TOTP code when the
+// authenticator is lost. They are only shown once.
+type TwoFactorBackupCodes struct {
+	BackupCodes []string `json:"backup_codes" example:"k3m9q-x7d2p"`
+}
+
+// DisableTwoFactorRequest represents the request to turn off two-factor authentication,
+// which needs the password and a TOTP or backup code
+type DisableTwoFactorRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required,max=32" example:"123456"`
+}
+
+// TwoFactorChallenge is the response to a correct password when the user has two-factor
+// authentication enabled. The token is exchanged with a code for the access and refresh
+// tokens at /auth/login/2fa.
+type TwoFactorChallenge struct {
+	TwoFactorRequired bool      `json:"two_factor_required" example:"true"`
+	Token             string    `json:"two_factor_token" example:"eyJhbGciOiJIUzI1NiIs..."`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+// TwoFactorLoginRequest represents the second login step, with a TOTP or backup code
+type TwoFactorLoginRequest struct {
+	Token string `json:"two_factor_token" binding:"required"`
+	Code  string `json:"code" binding:"required,max=32" example:"123456"`
+}
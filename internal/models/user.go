@@ -6,6 +6,15 @@ import (
 	"github.com/google/uuid"
 )
 
+// Languages emails can be sent in
+const (
+	LanguageEnglish = "en"
+	LanguageSwedish = "sv"
+
+	// DefaultLanguage is used for users who haven't picked a language
+	DefaultLanguage = LanguageEnglish
+)
+
 // User represents a user in the system
 type User struct {
 	ID                  uuid.UUID  `json:"id"`
@@ -13,13 +22,18 @@ type User struct {
 	Password            string     `json:"-"`
 	Email               *string    `json:"email"`
 	EmailVerified       bool       `json:"email_verified"`
+	EmailStatus         string     `json:"email_status" example:"deliverable"`
 	RoleID              uuid.UUID  `json:"role_id"`
 	Role                *Role      `json:"role,omitempty"`
 	LastLoginAt         *time.Time `json:"last_login_at"`
 	LastFailedLogin     *time.Time `json:"last_failed_login,omitempty"`
 	PasswordChangedAt   *time.Time `json:"password_changed_at,omitempty"`
 	FailedLoginAttempts int        `json:"-"`
+	Language            string     `json:"language" example:"en"`
 	DeletedAt           *time.Time `json:"deleted_at,omitempty"`
+	// DeletionScheduledAt is when the account the user deleted is deleted for good,
+	// unless they log in before then
+	DeletionScheduledAt *time.Time `json:"deletion_scheduled_at,omitempty"`
 	CreatedAt           time.Time  `json:"created_at"`
 	UpdatedAt           time.Time  `json:"updated_at"`
 }
@@ -27,21 +41,27 @@ type User struct {
 // CreateUserRequest represents the request to create a new user
 type CreateUserRequest struct {
 	Username string  `json:"username" binding:"required,min=3,max=50" validate:"max=50"`
-	Password string  `json:"password" binding:"required,min=8"`
+	Password string  `json:"password" binding:"required"`
 	Email    *string `json:"email" binding:"omitempty,email"`
+	// Language of the emails the user receives, en when left out
+	Language *string `json:"language,omitempty" binding:"omitempty,oneof=en sv" example:"sv"`
+	// CaptchaToken is the token of a solved CAPTCHA, required to register when CAPTCHAs are
+	// enabled
+	CaptchaToken string `json:"captcha_token,omitempty"`
 }
 
 // UpdateUserRequest represents the request to update a user
 type UpdateUserRequest struct {
 	Email    *string    `json:"email,omitempty" binding:"omitempty,email"`
-	Password *string    `json:"password,omitempty" binding:"omitempty,min=8"`
+	Password *string    `json:"password,omitempty"`
 	RoleID   *uuid.UUID `json:"role_id,omitempty"`
+	Language *string    `json:"language,omitempty" binding:"omitempty,oneof=en sv" example:"sv"`
 }
 
 // ChangePasswordRequest represents the request to change a user's password
 type ChangePasswordRequest struct {
 	CurrentPassword string `json:"current_password" binding:"required"`
-	NewPassword     string `json:"new_password" binding:"required,min=8,max=72"`
+	NewPassword     string `json:"new_password" binding:"required"`
 }
 
 // ResetPasswordRequest represents the request to initiate a password reset
@@ -52,10 +72,15 @@ type ResetPasswordRequest struct {
 // CompleteResetRequest represents the request to complete a password reset
 type CompleteResetRequest struct {
 	Token       string `json:"token" binding:"required"`
-	NewPassword string `json:"new_password" binding:"required,min=8,max=72"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 // IsAdmin returns true if the user has an admin role
 func (u *User) IsAdmin() bool {
 	return u.Role != nil && u.Role.IsAdminGroup
 }
+
+// Can reports whether the user's role grants the permission
+func (u *User) Can(permission string) bool {
+	return u.Role != nil && u.Role.Has(permission)
+}
@@ -0,0 +1,15 @@
+package models
+
+import "time"
+
+// UserExport is the archive of the data held about a user, which they can download before
+// deleting their account
+type UserExport struct {
+	ExportedAt  time.Time           `json:"exported_at"`
+	User        User                `json:"user"`
+	Preferences *UserPreferences    `json:"preferences"`
+	Consumption []ConsumptionRecord `json:"consumption"`
+	Production  []ProductionRecord  `json:"production"`
+	// AuditLogs are the actions the user performed, oldest first
+	AuditLogs []AuditLog `json:"audit_logs"`
+}
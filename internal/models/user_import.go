@@ -0,0 +1,42 @@
+package models
+
+import "github.com/google/uuid"
+
+// Outcomes of a row in a user import
+const (
+	UserImportCreated = "created"
+	UserImportValid   = "valid"
+	UserImportFailed  = "failed"
+)
+
+// UserImportRow is a user to create in a bulk import
+type UserImportRow struct {
+	Username string  `json:"username" example:"jane"`
+	Email    *string `json:"email,omitempty" example:"jane@example.com"`
+	// Name of the role, the default role of new users when left out
+	Role string `json:"role,omitempty" example:"user"`
+}
+
+// UserImportResult is the outcome of one row of a user import
+type UserImportResult struct {
+	// Row is the line of the CSV file, counting the header, or the position in the JSON
+	// array starting from 1
+	Row      int    `json:"row" example:"2"`
+	Username string `json:"username" example:"jane"`
+	// Status is created, valid for rows that passed a dry run, or failed
+	Status string     `json:"status" example:"created"`
+	UserID *uuid.UUID `json:"user_id,omitempty"`
+	// Invited reports whether an invitation was sent to the user's email address
+	Invited bool `json:"invited"`
+	// Errors explain why the row failed, or why no invitation was sent to a created user
+	Errors []string `json:"errors,omitempty"`
+}
+
+// UserImportResponse summarizes a user import, with the outcome of every row
+type UserImportResponse struct {
+	DryRun  bool               `json:"dry_run"`
+	Created int                `json:"created" example:"42"`
+	Failed  int                `json:"failed" example:"1"`
+	Invited int                `json:"invited" example:"40"`
+	Results []UserImportResult `json:"results"`
+}
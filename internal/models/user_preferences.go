@@ -0,0 +1,38 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// UserPreferences holds the defaults a user picked. Spot price endpoints called without a
+// zone or currency use the default ones, and listed spot prices have their timestamps in
+// the timezone. Users opting in to the weekly report are emailed the consumption and cost
+// of each of their meters, priced in the default zone and currency.
+type UserPreferences struct {
+	UserID     uuid.UUID  `json:"user_id"`
+	ZoneID     *uuid.UUID `json:"zone_id,omitempty"`
+	Zone       *string    `json:"zone,omitempty" example:"SE3"`
+	CurrencyID *uuid.UUID `json:"currency_id,omitempty"`
+	Currency   *string    `json:"currency,omitempty" example:"SEK"`
+	Timezone   *string    `json:"timezone,omitempty" example:"Europe/Stockholm"`
+	// Locale is the language of the user's emails, stored as the user's language
+	Locale       string     `json:"locale" example:"sv"`
+	WeeklyReport bool       `json:"weekly_report" example:"true"`
+	UpdatedAt    *time.Time `json:"updated_at,omitempty"`
+}
+
+// UpdateUserPreferencesRequest replaces the preferences of a user, defaults left out are
+// cleared
+type UpdateUserPreferencesRequest struct {
+	Zone     *string `json:"zone,omitempty" example:"SE3"`
+	Currency *string `json:"currency,omitempty" example:"SEK"`
+	// Timezone is an IANA timezone name
+	Timezone *string `json:"timezone,omitempty" example:"Europe/Stockholm"`
+	// Locale is kept as it is when left out
+	Locale *string `json:"locale,omitempty" binding:"omitempty,oneof=en sv" example:"sv"`
+	// WeeklyReport opts in to the weekly consumption and cost report, which needs a default
+	// zone and currency
+	WeeklyReport bool `json:"weekly_report,omitempty" example:"true"`
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"encoding/json"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// WebhookEvent identifies what happened when a webhook is called
+type WebhookEvent string
+
+const (
+	WebhookEventSpotPricesIngested WebhookEvent = "spot_prices.ingested"
+	WebhookEventUserRegistered     WebhookEvent = "user.registered"
+	WebhookEventAlertTriggered     WebhookEvent = "alert.triggered"
+)
+
+// Webhook is a URL called with a signed request when one of its events occurs. The secret
+// is only returned when the webhook is created.
+type Webhook struct {
+	ID        uuid.UUID      `json:"id"`
+	URL       string         `json:"url" example:"https://example.com/hooks/wattwatch"`
+	Secret    string         `json:"-"`
+	Events    []WebhookEvent `json:"events" example:"spot_prices.ingested"`
+	Enabled   bool           `json:"enabled"`
+	CreatedBy *uuid.UUID     `json:"created_by,omitempty"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+}
+
+// Subscribes reports whether the webhook is called for event
+func (w *Webhook) Subscribes(event WebhookEvent) bool {
+	return slices.Contains(w.Events, event)
+}
+
+// CreatedWebhook is a new webhook with the secret its requests are signed with
+type CreatedWebhook struct {
+	Webhook
+	Secret string `json:"secret" example:"5f2b6c..."`
+}
+
+// CreateWebhookRequest represents the request to register a webhook
+type CreateWebhookRequest struct {
+	URL     string         `json:"url" binding:"required,url,max=2048" example:"https://example.com/hooks/wattwatch"`
+	Events  []WebhookEvent `json:"events" binding:"required,min=1,dive,oneof=spot_prices.ingested user.registered alert.triggered" example:"spot_prices.ingested"`
+	Enabled *bool          `json:"enabled,omitempty"`
+}
+
+// UpdateWebhookRequest represents the request to update a webhook
+type UpdateWebhookRequest struct {
+	URL     *string        `json:"url,omitempty" binding:"omitempty,url,max=2048"`
+	Events  []WebhookEvent `json:"events,omitempty" binding:"omitempty,min=1,dive,oneof=spot_prices.ingested user.registered alert.triggered"`
+	Enabled *bool          `json:"enabled,omitempty"`
+}
+
+// WebhookDelivery is an event sent, or still to be sent, to a webhook. Pending deliveries
+// are attempted again at NextAttemptAt.
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id"`
+	Event          WebhookEvent    `json:"event" example:"user.registered"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         DeliveryStatus  `json:"status" example:"sent"`
+	Attempts       int             `json:"attempts"`
+	ResponseStatus *int            `json:"response_status,omitempty" example:"200"`
+	Error          *string         `json:"error,omitempty"`
+	NextAttemptAt  *time.Time      `json:"next_attempt_at,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	DeliveredAt    *time.Time      `json:"delivered_at,omitempty"`
+}
@@ -1,6 +1,7 @@
 package models
 
 import (
+	"encoding/json"
 	"time"
 
 	"github.com/google/uuid"
@@ -8,21 +9,64 @@ import (
 
 // Zone represents a zone in the system
 type Zone struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	Name      string    `json:"name" db:"name" binding:"required" example:"SE1"`
-	Timezone  string    `json:"timezone" db:"timezone" binding:"required" example:"Europe/Stockholm"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+	ID       uuid.UUID `json:"id" db:"id"`
+	Name     string    `json:"name" db:"name" binding:"required" example:"SE1"`
+	Timezone string    `json:"timezone" db:"timezone" binding:"required" example:"Europe/Stockholm"`
+	// CountryCode is the ISO 3166-1 alpha-2 code of the country the zone is in
+	CountryCode *string `json:"country_code" db:"country_code" example:"SE"`
+	// EICCode is the ENTSO-E energy identification code of the bidding zone
+	EICCode *string `json:"eic_code" db:"eic_code" example:"10Y1001A1001A44P"`
+	// GridOperator is the transmission system operator responsible for the zone
+	GridOperator *string `json:"grid_operator" db:"grid_operator" example:"Svenska kraftnät"`
+	// DisplayName is a human friendly name, such as the main city of the zone
+	DisplayName *string `json:"display_name" db:"display_name" example:"Luleå"`
+	// LocalDisplayName is the display name followed by the current time zone
+	// abbreviation of the zone, which follows daylight saving time
+	LocalDisplayName string    `json:"local_display_name" db:"-" example:"Luleå (CEST)"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// LocalName returns the display name of the zone, or its name when it has none,
+// followed by the abbreviation of the zone's time zone at the given time, e.g.
+// "Stockholm (CET)" in winter and "Stockholm (CEST)" in summer.
+func (z Zone) LocalName(at time.Time) string {
+	name := z.Name
+	if z.DisplayName != nil && *z.DisplayName != "" {
+		name = *z.DisplayName
+	}
+	loc, err := time.LoadLocation(z.Timezone)
+	if err != nil {
+		return name
+	}
+	abbreviation, _ := at.In(loc).Zone()
+	return name + " (" + abbreviation + ")"
+}
+
+// MarshalJSON fills in LocalDisplayName for the current time
+func (z Zone) MarshalJSON() ([]byte, error) {
+	type zone Zone
+	z.LocalDisplayName = z.LocalName(time.Now())
+	return json.Marshal(zone(z))
 }
 
 // CreateZoneRequest represents the request to create a new zone
 type CreateZoneRequest struct {
-	Name     string `json:"name" binding:"required" example:"SE5"`
-	Timezone string `json:"timezone" binding:"required" example:"Europe/Stockholm"`
+	Name         string  `json:"name" binding:"required" example:"SE5"`
+	Timezone     string  `json:"timezone" binding:"required" example:"Europe/Stockholm"`
+	CountryCode  *string `json:"country_code" binding:"omitempty,iso3166_1_alpha2" example:"SE"`
+	EICCode      *string `json:"eic_code" binding:"omitempty,len=16" example:"10Y1001A1001A44P"`
+	GridOperator *string `json:"grid_operator" binding:"omitempty,max=100" example:"Svenska kraftnät"`
+	DisplayName  *string `json:"display_name" binding:"omitempty,max=100" example:"Luleå"`
 }
 
-// UpdateZoneRequest represents the request to update a zone
+// UpdateZoneRequest represents the request to update a zone. Metadata that is left out
+// is cleared.
 type UpdateZoneRequest struct {
-	Name     string `json:"name" binding:"required" example:"SE5"`
-	Timezone string `json:"timezone" binding:"required" example:"Europe/Stockholm"`
+	Name         string  `json:"name" binding:"required" example:"SE5"`
+	Timezone     string  `json:"timezone" binding:"required" example:"Europe/Stockholm"`
+	CountryCode  *string `json:"country_code" binding:"omitempty,iso3166_1_alpha2" example:"SE"`
+	EICCode      *string `json:"eic_code" binding:"omitempty,len=16" example:"10Y1001A1001A44P"`
+	GridOperator *string `json:"grid_operator" binding:"omitempty,max=100" example:"Svenska kraftnät"`
+	DisplayName  *string `json:"display_name" binding:"omitempty,max=100" example:"Luleå"`
 }
@@ -0,0 +1,160 @@
+// Package mqtt publishes spot prices to an MQTT broker as retained messages, so devices
+// subscribing to a zone get its latest prices as soon as they connect.
+package mqtt
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sync/atomic"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/metrics"
+
+	paho "github.com/eclipse/paho.mqtt.golang"
+	"github.com/google/uuid"
+)
+
+// publishTimeout bounds the wait for the broker to acknowledge a message
+const publishTimeout = 10 * time.Second
+
+// Client is a connection to an MQTT broker
+type Client interface {
+	// Connect makes one attempt to connect to the broker. Once connected, the client
+	// reconnects by itself when the connection is lost.
+	Connect(ctx context.Context) error
+	// Publish sends a retained message, failing while the client is disconnected
+	Publish(ctx context.Context, topic string, payload []byte) error
+	// Reconnected receives a value when the connection is restored after being lost
+	Reconnected() <-chan struct{}
+	// Disconnect closes the connection
+	Disconnect()
+}
+
+type pahoClient struct {
+	client      paho.Client
+	qos         byte
+	connected   atomic.Bool
+	reconnected chan struct{}
+}
+
+// NewClient creates a client for the broker of cfg using the Eclipse Paho library. It
+// doesn't connect until Connect is called.
+func NewClient(cfg config.MQTTConfig) (Client, error) {
+	c := &pahoClient{
+		qos:         byte(cfg.QoS),
+		reconnected: make(chan struct{}, 1),
+	}
+
+	clientID := cfg.ClientID
+	if clientID == "" {
+		clientID = "wattwatch-" + uuid.NewString()[:8]
+	}
+	opts := paho.NewClientOptions().
+		AddBroker(cfg.Broker).
+		SetClientID(clientID).
+		SetUsername(cfg.Username).
+		SetPassword(cfg.Password).
+		SetAutoReconnect(true).
+		SetMaxReconnectInterval(cfg.MaxReconnectInterval).
+		SetOnConnectHandler(c.onConnect).
+		SetConnectionLostHandler(c.onConnectionLost)
+
+	tlsConfig, err := newTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if tlsConfig != nil {
+		opts.SetTLSConfig(tlsConfig)
+	}
+
+	c.client = paho.NewClient(opts)
+	return c, nil
+}
+
+// newTLSConfig returns the TLS settings of cfg, nil when none are configured so the
+// scheme of the broker URL decides whether TLS is used
+func newTLSConfig(cfg config.MQTTConfig) (*tls.Config, error) {
+	if cfg.TLSCAFile == "" && cfg.TLSCertFile == "" && !cfg.TLSInsecureSkipVerify {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: cfg.TLSInsecureSkipVerify,
+	}
+	if cfg.TLSCAFile != "" {
+		pem, err := os.ReadFile(cfg.TLSCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", cfg.TLSCAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.TLSCertFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+func (c *pahoClient) onConnect(paho.Client) {
+	metrics.MQTTConnected(true)
+	// The first connection is made by Connect, only later ones are reconnections
+	if !c.connected.Swap(true) {
+		return
+	}
+	log.Printf("Reconnected to MQTT broker")
+	select {
+	case c.reconnected <- struct{}{}:
+	default:
+	}
+}
+
+func (c *pahoClient) onConnectionLost(_ paho.Client, err error) {
+	metrics.MQTTConnected(false)
+	metrics.MQTTConnectionLost()
+	log.Printf("Lost connection to MQTT broker, reconnecting: %v", err)
+}
+
+func (c *pahoClient) Connect(ctx context.Context) error {
+	return wait(ctx, c.client.Connect())
+}
+
+func (c *pahoClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	if !c.client.IsConnectionOpen() {
+		return errors.New("not connected to the broker")
+	}
+	ctx, cancel := context.WithTimeout(ctx, publishTimeout)
+	defer cancel()
+	return wait(ctx, c.client.Publish(topic, c.qos, true, payload))
+}
+
+func (c *pahoClient) Reconnected() <-chan struct{} {
+	return c.reconnected
+}
+
+func (c *pahoClient) Disconnect() {
+	c.client.Disconnect(250)
+	metrics.MQTTConnected(false)
+}
+
+// wait returns the result of token once it completes, or the error of ctx if it ends first
+func wait(ctx context.Context, token paho.Token) error {
+	select {
+	case <-token.Done():
+		return token.Error()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,298 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"sync"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/metrics"
+	"wattwatch/internal/models"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Kinds of messages, the last level of their topics
+const (
+	// KindIngested holds the points of the latest write of spot prices
+	KindIngested = "ingested"
+	// KindCurrent holds the point of the price applying now
+	KindCurrent = "current"
+	// KindToday holds the day of prices of today in the zone's timezone
+	KindToday = "today"
+	// KindTomorrow holds the day of prices of tomorrow, cleared until they are known
+	KindTomorrow = "tomorrow"
+)
+
+// RefreshInterval is how often the current price and daily curves are published again, so
+// they move on with the time without new spot prices
+const RefreshInterval = 15 * time.Minute
+
+// initialBackoff is the wait after the first failed attempt to connect, doubled after
+// each failure up to the configured maximum
+const initialBackoff = time.Second
+
+// Publisher publishes the spot prices of each zone and currency as retained messages on
+// topics of the form <prefix>/<zone>/<currency>/<kind>
+type Publisher struct {
+	client     Client
+	prefix     string
+	maxBackoff time.Duration
+	spotPrices repository.SpotPriceRepository
+	zones      repository.ZoneRepository
+	currencies repository.CurrencyRepository
+	now        func() time.Time
+}
+
+// NewPublisher creates a publisher sending messages through client
+func NewPublisher(
+	client Client,
+	cfg config.MQTTConfig,
+	spotPrices repository.SpotPriceRepository,
+	zones repository.ZoneRepository,
+	currencies repository.CurrencyRepository,
+) *Publisher {
+	return &Publisher{
+		client:     client,
+		prefix:     cfg.TopicPrefix,
+		maxBackoff: cfg.MaxReconnectInterval,
+		spotPrices: spotPrices,
+		zones:      zones,
+		currencies: currencies,
+		now:        time.Now,
+	}
+}
+
+// Run connects to the broker and publishes the spot prices written to hub until ctx is
+// cancelled. The curves of every zone and currency are published once connected, again
+// every RefreshInterval and after reconnecting. The publisher takes one of the hub's
+// subscriber slots.
+func (p *Publisher) Run(ctx context.Context, hub *pubsub.Hub) {
+	if !p.connect(ctx) {
+		return
+	}
+	defer p.client.Disconnect()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		hub.Follow(ctx, "MQTT publisher", pubsub.Filter{}, func(ctx context.Context, spotPrices []models.SpotPrice) {
+			if err := p.PublishIngested(ctx, spotPrices); err != nil {
+				log.Printf("Failed to publish spot prices to MQTT: %v", err)
+			}
+		})
+	}()
+	defer wg.Wait()
+
+	ticker := time.NewTicker(RefreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := p.PublishAll(ctx); err != nil {
+			log.Printf("Failed to publish spot prices to MQTT: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-p.client.Reconnected():
+		}
+	}
+}
+
+// connect attempts to connect until it succeeds, waiting longer after each failure. It
+// returns false when ctx is cancelled first.
+func (p *Publisher) connect(ctx context.Context) bool {
+	backoff := initialBackoff
+	for {
+		err := p.client.Connect(ctx)
+		if err == nil {
+			log.Printf("Connected to MQTT broker")
+			return true
+		}
+		if ctx.Err() != nil {
+			return false
+		}
+		log.Printf("Failed to connect to MQTT broker, retrying in %v: %v", backoff, err)
+
+		select {
+		case <-ctx.Done():
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, p.maxBackoff)
+	}
+}
+
+// seriesKey identifies the spot prices of one zone and currency
+type seriesKey struct {
+	zoneID     uuid.UUID
+	currencyID uuid.UUID
+}
+
+// PublishIngested publishes the points of the spot prices per zone and currency, then the
+// current price and daily curves of the zones and currencies they belong to
+func (p *Publisher) PublishIngested(ctx context.Context, spotPrices []models.SpotPrice) error {
+	series := make(map[seriesKey][]models.SpotPrice)
+	var keys []seriesKey
+	for _, sp := range spotPrices {
+		key := seriesKey{sp.ZoneID, sp.CurrencyID}
+		if _, ok := series[key]; !ok {
+			keys = append(keys, key)
+		}
+		series[key] = append(series[key], sp)
+	}
+
+	var errs []error
+	for _, key := range keys {
+		zone, err := p.zones.GetByID(ctx, key.zoneID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get zone %s: %w", key.zoneID, err))
+			continue
+		}
+		currency, err := p.currencies.GetByID(ctx, key.currencyID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to get currency %s: %w", key.currencyID, err))
+			continue
+		}
+		loc, err := time.LoadLocation(zone.Timezone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid timezone of zone %s: %w", zone.Name, err))
+			continue
+		}
+
+		prices := series[key]
+		slices.SortFunc(prices, func(a, b models.SpotPrice) int { return a.Timestamp.Compare(b.Timestamp) })
+		points := make([]models.SpotPricePoint, len(prices))
+		for i, sp := range prices {
+			points[i] = models.SpotPricePoint{Timestamp: sp.Timestamp.In(loc), Price: sp.Price}
+		}
+		if err := p.publish(ctx, zone, currency, KindIngested, points); err != nil {
+			errs = append(errs, err)
+		}
+		if err := p.publishSeries(ctx, zone, currency, loc); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PublishAll publishes the current price and daily curves of every zone and currency
+// with spot prices for today or tomorrow
+func (p *Publisher) PublishAll(ctx context.Context) error {
+	zones, err := p.zones.List(ctx, repository.ZoneFilter{})
+	if err != nil {
+		return fmt.Errorf("failed to list zones: %w", err)
+	}
+	currencies, err := p.currencies.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list currencies: %w", err)
+	}
+
+	var errs []error
+	for i := range zones {
+		loc, err := time.LoadLocation(zones[i].Timezone)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid timezone of zone %s: %w", zones[i].Name, err))
+			continue
+		}
+		for j := range currencies {
+			if err := p.publishSeries(ctx, &zones[i], &currencies[j], loc); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// publishSeries publishes the current price and the curves of today and tomorrow of a
+// zone and currency, nothing when neither day has prices
+func (p *Publisher) publishSeries(ctx context.Context, zone *models.Zone, currency *models.Currency, loc *time.Location) error {
+	now := p.now().In(loc)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, loc)
+	tomorrow := today.AddDate(0, 0, 1)
+	end := today.AddDate(0, 0, 2)
+	prices, err := p.spotPrices.List(ctx, repository.SpotPriceFilter{
+		ZoneID:     &zone.ID,
+		CurrencyID: &currency.ID,
+		StartTime:  &today,
+		EndTime:    &end,
+		OrderBy:    "timestamp",
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list spot prices of %s in %s: %w", zone.Name, currency.Name, err)
+	}
+
+	points := make([]models.SpotPricePoint, 0, len(prices))
+	for _, sp := range prices {
+		if sp.Timestamp.Before(end) {
+			points = append(points, models.SpotPricePoint{Timestamp: sp.Timestamp.In(loc), Price: sp.Price})
+		}
+	}
+	if len(points) == 0 {
+		return nil
+	}
+	split := len(points)
+	for i, pt := range points {
+		if !pt.Timestamp.Before(tomorrow) {
+			split = i
+			break
+		}
+	}
+
+	var current *models.SpotPricePoint
+	resolution := models.SpotPriceResolution(points)
+	for i, pt := range points {
+		if !now.Before(pt.Timestamp) && now.Before(pt.Timestamp.Add(resolution)) {
+			current = &points[i]
+			break
+		}
+	}
+
+	var errs []error
+	for _, msg := range []struct {
+		kind    string
+		payload interface{}
+	}{
+		{KindCurrent, current},
+		{KindToday, models.NewSpotPriceDay(points[:split])},
+		{KindTomorrow, models.NewSpotPriceDay(points[split:])},
+	} {
+		if err := p.publish(ctx, zone, currency, msg.kind, msg.payload); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Topic returns the topic of a kind of message for a zone and currency
+func (p *Publisher) Topic(zone, currency, kind string) string {
+	return p.prefix + "/" + zone + "/" + currency + "/" + kind
+}
+
+// publish sends payload as JSON. A nil payload sends an empty message, which clears the
+// retained message of the topic.
+func (p *Publisher) publish(ctx context.Context, zone *models.Zone, currency *models.Currency, kind string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s message: %w", kind, err)
+	}
+	if string(body) == "null" {
+		body = nil
+	}
+
+	topic := p.Topic(zone.Name, currency.Name, kind)
+	if err := p.client.Publish(ctx, topic, body); err != nil {
+		metrics.MQTTPublished(kind, metrics.PublishFailure)
+		return fmt.Errorf("failed to publish to %s: %w", topic, err)
+	}
+	metrics.MQTTPublished(kind, metrics.PublishSuccess)
+	return nil
+}
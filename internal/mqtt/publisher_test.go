@@ -0,0 +1,197 @@
+package mqtt
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeClient struct {
+	mu          sync.Mutex
+	failures    int
+	connects    int
+	messages    map[string][]byte
+	published   chan string
+	reconnected chan struct{}
+}
+
+func newFakeClient(failures int) *fakeClient {
+	return &fakeClient{
+		failures:    failures,
+		messages:    make(map[string][]byte),
+		published:   make(chan string, 100),
+		reconnected: make(chan struct{}),
+	}
+}
+
+func (c *fakeClient) Connect(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.connects++
+	if c.connects <= c.failures {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func (c *fakeClient) Publish(ctx context.Context, topic string, payload []byte) error {
+	c.mu.Lock()
+	c.messages[topic] = payload
+	c.mu.Unlock()
+	c.published <- topic
+	return nil
+}
+
+func (c *fakeClient) Reconnected() <-chan struct{} { return c.reconnected }
+
+func (c *fakeClient) Disconnect() {}
+
+func (c *fakeClient) message(topic string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	payload, ok := c.messages[topic]
+	return payload, ok
+}
+
+func TestPublisher(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	zones := memory.NewZoneRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+	spotPrices := memory.NewSpotPriceRepository(store)
+
+	se3, err := zones.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	eur, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	// Stockholm is an hour ahead of UTC in January, today starts at 23:00 UTC
+	now := time.Date(2025, 1, 15, 12, 30, 0, 0, time.UTC)
+	var today []models.SpotPrice
+	for i, ts := 0, time.Date(2025, 1, 14, 23, 0, 0, 0, time.UTC); i < 24; i, ts = i+1, ts.Add(time.Hour) {
+		today = append(today, models.SpotPrice{Timestamp: ts, ZoneID: se3.ID, CurrencyID: eur.ID, Price: float64(10 + i)})
+	}
+	require.NoError(t, spotPrices.CreateBatch(ctx, today))
+
+	cfg := config.MQTTConfig{TopicPrefix: "wattwatch", MaxReconnectInterval: time.Minute}
+	client := newFakeClient(0)
+	publisher := NewPublisher(client, cfg, spotPrices, zones, currencies)
+	publisher.now = func() time.Time { return now }
+
+	t.Run("PublishAll", func(t *testing.T) {
+		require.NoError(t, publisher.PublishAll(ctx))
+
+		payload, ok := client.message("wattwatch/SE3/EUR/current")
+		require.True(t, ok)
+		var current models.SpotPricePoint
+		require.NoError(t, json.Unmarshal(payload, &current))
+		assert.True(t, time.Date(2025, 1, 15, 12, 0, 0, 0, time.UTC).Equal(current.Timestamp))
+		assert.Equal(t, float64(23), current.Price)
+
+		payload, ok = client.message("wattwatch/SE3/EUR/today")
+		require.True(t, ok)
+		var day models.SpotPriceDay
+		require.NoError(t, json.Unmarshal(payload, &day))
+		assert.Equal(t, "2025-01-15", day.Date)
+		assert.Len(t, day.Prices, 24)
+		assert.Equal(t, float64(10), day.Min)
+		assert.Equal(t, float64(33), day.Max)
+
+		// Tomorrow is cleared until its prices are known
+		payload, ok = client.message("wattwatch/SE3/EUR/tomorrow")
+		require.True(t, ok)
+		assert.Empty(t, payload)
+
+		// Zones and currencies without prices are left alone
+		_, ok = client.message("wattwatch/SE4/EUR/today")
+		assert.False(t, ok)
+		_, ok = client.message("wattwatch/SE3/SEK/today")
+		assert.False(t, ok)
+	})
+
+	t.Run("PublishIngested", func(t *testing.T) {
+		var tomorrow []models.SpotPrice
+		for i, ts := 0, time.Date(2025, 1, 15, 23, 0, 0, 0, time.UTC); i < 24; i, ts = i+1, ts.Add(time.Hour) {
+			tomorrow = append(tomorrow, models.SpotPrice{Timestamp: ts, ZoneID: se3.ID, CurrencyID: eur.ID, Price: float64(50 - i)})
+		}
+		require.NoError(t, spotPrices.CreateBatch(ctx, tomorrow))
+		require.NoError(t, publisher.PublishIngested(ctx, tomorrow))
+
+		payload, ok := client.message("wattwatch/SE3/EUR/ingested")
+		require.True(t, ok)
+		var points []models.SpotPricePoint
+		require.NoError(t, json.Unmarshal(payload, &points))
+		require.Len(t, points, 24)
+		assert.Equal(t, float64(50), points[0].Price)
+
+		payload, ok = client.message("wattwatch/SE3/EUR/tomorrow")
+		require.True(t, ok)
+		var day models.SpotPriceDay
+		require.NoError(t, json.Unmarshal(payload, &day))
+		assert.Equal(t, "2025-01-16", day.Date)
+		assert.Equal(t, float64(27), day.Min)
+	})
+
+	t.Run("Run", func(t *testing.T) {
+		hub := pubsub.NewHub(10)
+		client := newFakeClient(1)
+		publisher := NewPublisher(client, cfg, spotPrices, zones, currencies)
+		publisher.now = func() time.Time { return now }
+
+		ctx, cancel := context.WithCancel(ctx)
+		done := make(chan struct{})
+		go func() {
+			publisher.Run(ctx, hub)
+			close(done)
+		}()
+		defer func() {
+			cancel()
+			<-done
+		}()
+
+		// The curves are published once connected, after a failed attempt
+		awaitTopic := func(topic string) {
+			t.Helper()
+			timeout := time.After(5 * time.Second)
+			for {
+				select {
+				case published := <-client.published:
+					if published == topic {
+						return
+					}
+				case <-timeout:
+					t.Fatalf("%s was not published", topic)
+				}
+			}
+		}
+		awaitTopic("wattwatch/SE3/EUR/today")
+		client.mu.Lock()
+		assert.Equal(t, 2, client.connects)
+		client.mu.Unlock()
+
+		// Written prices are published as they come, once the publisher follows the hub
+		require.Eventually(t, func() bool {
+			hub.Publish(today[:1])
+			select {
+			case topic := <-client.published:
+				return topic == "wattwatch/SE3/EUR/ingested"
+			case <-time.After(50 * time.Millisecond):
+				return false
+			}
+		}, 5*time.Second, 10*time.Millisecond)
+
+		// Everything is published again after reconnecting
+		client.reconnected <- struct{}{}
+		awaitTopic("wattwatch/SE3/EUR/tomorrow")
+	})
+}
@@ -0,0 +1,87 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+)
+
+// ErrInvalidTarget is returned when a target is missing the settings its channel needs
+var ErrInvalidTarget = errors.New("invalid notification target")
+
+// TargetSender delivers a message to a user-configured chat destination
+type TargetSender interface {
+	Channel() models.NotificationChannel
+	// Validate checks that the target carries the settings the channel needs
+	Validate(target *models.NotificationTarget) error
+	Send(ctx context.Context, target *models.NotificationTarget, msg *Message) error
+}
+
+// DefaultTargetSenders returns senders for every built-in chat channel
+func DefaultTargetSenders(client *http.Client) []TargetSender {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return []TargetSender{
+		&SlackSender{client: client},
+		&DiscordSender{client: client},
+		&TelegramSender{client: client, apiURL: telegramAPIURL},
+	}
+}
+
+// postJSON posts a JSON payload and returns an error for non-2xx responses
+func postJSON(ctx context.Context, client *http.Client, endpoint string, payload interface{}) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		// The URL may embed a secret, so don't leak it through the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			return fmt.Errorf("request failed: %w", urlErr.Err)
+		}
+		return fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return nil
+	}
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+}
+
+// validateWebhookURL checks that a webhook URL uses https and points at one of the allowed hosts
+func validateWebhookURL(target *models.NotificationTarget, hosts ...string) error {
+	if target.WebhookURL == nil || *target.WebhookURL == "" {
+		return fmt.Errorf("%w: webhook_url is required", ErrInvalidTarget)
+	}
+	u, err := url.Parse(*target.WebhookURL)
+	if err != nil || u.Scheme != "https" {
+		return fmt.Errorf("%w: webhook_url must be an https URL", ErrInvalidTarget)
+	}
+	for _, host := range hosts {
+		if strings.EqualFold(u.Hostname(), host) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: webhook_url must point to %s", ErrInvalidTarget, strings.Join(hosts, " or "))
+}
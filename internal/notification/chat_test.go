@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"wattwatch/internal/models"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestTargetSenders_Validate(t *testing.T) {
+	senders := map[models.NotificationChannel]TargetSender{}
+	for _, sender := range DefaultTargetSenders(nil) {
+		senders[sender.Channel()] = sender
+	}
+
+	tests := []struct {
+		name    string
+		target  models.NotificationTarget
+		wantErr bool
+	}{
+		{
+			name:   "Slack Webhook",
+			target: models.NotificationTarget{Channel: models.NotificationChannelSlack, WebhookURL: strPtr("https://hooks.slack.com/services/T/B/X")},
+		},
+		{
+			name:    "Slack Wrong Host",
+			target:  models.NotificationTarget{Channel: models.NotificationChannelSlack, WebhookURL: strPtr("https://example.com/hook")},
+			wantErr: true,
+		},
+		{
+			name:    "Slack Plain HTTP",
+			target:  models.NotificationTarget{Channel: models.NotificationChannelSlack, WebhookURL: strPtr("http://hooks.slack.com/services/T/B/X")},
+			wantErr: true,
+		},
+		{
+			name:   "Discord Webhook",
+			target: models.NotificationTarget{Channel: models.NotificationChannelDiscord, WebhookURL: strPtr("https://discord.com/api/webhooks/1/abc")},
+		},
+		{
+			name:    "Discord Missing URL",
+			target:  models.NotificationTarget{Channel: models.NotificationChannelDiscord},
+			wantErr: true,
+		},
+		{
+			name:   "Telegram Bot",
+			target: models.NotificationTarget{Channel: models.NotificationChannelTelegram, BotToken: strPtr("123:abc"), ChatID: strPtr("-100123")},
+		},
+		{
+			name:    "Telegram Missing Chat",
+			target:  models.NotificationTarget{Channel: models.NotificationChannelTelegram, BotToken: strPtr("123:abc")},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := senders[tt.target.Channel].Validate(&tt.target)
+			if tt.wantErr {
+				require.ErrorIs(t, err, ErrInvalidTarget)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestTargetSenders_Send(t *testing.T) {
+	msg := &Message{
+		AlertType: models.NotificationAlertPrice,
+		Title:     "Price <alert>",
+		Body:      "SE3 is below 0.10 & falling",
+	}
+
+	tests := []struct {
+		name      string
+		channel   models.NotificationChannel
+		status    int
+		wantPath  string
+		checkBody func(t *testing.T, body map[string]interface{})
+		wantErr   bool
+	}{
+		{
+			name:    "Slack",
+			channel: models.NotificationChannelSlack,
+			status:  http.StatusOK,
+			checkBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "*Price &lt;alert&gt;*\nSE3 is below 0.10 &amp; falling", body["text"])
+			},
+		},
+		{
+			name:    "Discord",
+			channel: models.NotificationChannelDiscord,
+			status:  http.StatusNoContent,
+			checkBody: func(t *testing.T, body map[string]interface{}) {
+				embeds := body["embeds"].([]interface{})
+				require.Len(t, embeds, 1)
+				assert.Equal(t, "Price <alert>", embeds[0].(map[string]interface{})["title"])
+			},
+		},
+		{
+			name:     "Telegram",
+			channel:  models.NotificationChannelTelegram,
+			status:   http.StatusOK,
+			wantPath: "/bot123:abc/sendMessage",
+			checkBody: func(t *testing.T, body map[string]interface{}) {
+				assert.Equal(t, "-100123", body["chat_id"])
+				assert.Equal(t, "HTML", body["parse_mode"])
+				assert.Equal(t, "<b>Price &lt;alert&gt;</b>\nSE3 is below 0.10 &amp; falling", body["text"])
+			},
+		},
+		{
+			name:    "Slack Error",
+			channel: models.NotificationChannelSlack,
+			status:  http.StatusNotFound,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if tt.wantPath != "" {
+					assert.Equal(t, tt.wantPath, r.URL.Path)
+				}
+				var body map[string]interface{}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				if tt.checkBody != nil {
+					tt.checkBody(t, body)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			target := &models.NotificationTarget{
+				Channel:    tt.channel,
+				WebhookURL: strPtr(server.URL + "/hook"),
+				BotToken:   strPtr("123:abc"),
+				ChatID:     strPtr("-100123"),
+			}
+
+			var sender TargetSender
+			switch tt.channel {
+			case models.NotificationChannelSlack:
+				sender = &SlackSender{client: server.Client()}
+			case models.NotificationChannelDiscord:
+				sender = &DiscordSender{client: server.Client()}
+			case models.NotificationChannelTelegram:
+				sender = &TelegramSender{client: server.Client(), apiURL: server.URL}
+			}
+
+			err := sender.Send(context.Background(), target, msg)
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"wattwatch/internal/models"
+)
+
+// discordEmbedColor is the accent color used for alert embeds
+const discordEmbedColor = 0xF5A623
+
+// DiscordSender posts messages to Discord channel webhooks
+type DiscordSender struct {
+	client *http.Client
+}
+
+// Channel returns the Discord channel
+func (s *DiscordSender) Channel() models.NotificationChannel {
+	return models.NotificationChannelDiscord
+}
+
+// Validate requires a Discord webhook URL
+func (s *DiscordSender) Validate(target *models.NotificationTarget) error {
+	return validateWebhookURL(target, "discord.com", "discordapp.com")
+}
+
+// Send posts the message as an embed with mentions disabled
+func (s *DiscordSender) Send(ctx context.Context, target *models.NotificationTarget, msg *Message) error {
+	payload := map[string]interface{}{
+		"embeds": []map[string]interface{}{{
+			"title":       msg.Title,
+			"description": msg.Body,
+			"color":       discordEmbedColor,
+		}},
+		"allowed_mentions": map[string]interface{}{"parse": []string{}},
+	}
+	if err := postJSON(ctx, s.client, *target.WebhookURL, payload); err != nil {
+		return fmt.Errorf("discord: %w", err)
+	}
+	return nil
+}
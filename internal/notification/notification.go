@@ -0,0 +1,389 @@
+// Package notification delivers price and consumption alerts to user devices and chat targets
+package notification
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrDeviceGone is returned by a Sender when the push service reports that the
+	// device token is no longer valid and should be removed
+	ErrDeviceGone = errors.New("device token is no longer registered")
+	// ErrChannelNotConfigured is returned when no sender is registered for a channel
+	ErrChannelNotConfigured = errors.New("notification channel not configured")
+)
+
+// Message is the content of a notification
+type Message struct {
+	AlertType models.NotificationAlertType
+	// ThrottleKey separates throttling of alerts of the same type, e.g. the ID of the alert rule
+	ThrottleKey string
+	Title       string
+	Body        string
+	Data        map[string]string
+}
+
+// Sender delivers a message to a single device over one channel
+type Sender interface {
+	Channel() models.NotificationChannel
+	Send(ctx context.Context, device *models.DeviceToken, msg *Message) error
+}
+
+// EmailSender delivers alerts by email
+type EmailSender interface {
+	SendAlertEmail(to, username, language, title, body string) error
+}
+
+// Service dispatches notifications to registered devices and chat targets,
+// honouring preferences and recording the outcome of each delivery
+type Service struct {
+	devices       repository.DeviceTokenRepository
+	targets       repository.NotificationTargetRepository
+	preferences   repository.NotificationPreferenceRepository
+	deliveries    repository.NotificationDeliveryRepository
+	senders       map[models.NotificationChannel]Sender
+	targetSenders map[models.NotificationChannel]TargetSender
+	throttle      *throttler
+	email         EmailSender
+	users         repository.UserRepository
+}
+
+// NewService creates a new notification Service. Push senders must be registered
+// separately since they need credentials, the built-in chat senders are always available.
+// Price and consumption alerts are throttled to DefaultThrottleInterval.
+func NewService(
+	devices repository.DeviceTokenRepository,
+	targets repository.NotificationTargetRepository,
+	preferences repository.NotificationPreferenceRepository,
+	deliveries repository.NotificationDeliveryRepository,
+) *Service {
+	s := &Service{
+		devices:       devices,
+		targets:       targets,
+		preferences:   preferences,
+		deliveries:    deliveries,
+		senders:       make(map[models.NotificationChannel]Sender),
+		targetSenders: make(map[models.NotificationChannel]TargetSender),
+		throttle:      newThrottler(),
+	}
+	for _, sender := range DefaultTargetSenders(nil) {
+		s.RegisterTargetSender(sender)
+	}
+	s.SetThrottleInterval(models.NotificationAlertPrice, DefaultThrottleInterval)
+	s.SetThrottleInterval(models.NotificationAlertConsumption, DefaultThrottleInterval)
+	return s
+}
+
+// SetThrottleInterval sets how often the same alert is sent to a user, zero disables throttling
+func (s *Service) SetThrottleInterval(alertType models.NotificationAlertType, interval time.Duration) {
+	s.throttle.setInterval(alertType, interval)
+}
+
+// digestShutdownTimeout bounds the final flush when RunDigests stops
+const digestShutdownTimeout = 10 * time.Second
+
+// RunDigests sends held back alerts as digests every interval until ctx is cancelled.
+// Alerts still held back at that point are sent right away instead of being dropped.
+func (s *Service) RunDigests(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			flushCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), digestShutdownTimeout)
+			defer cancel()
+			if err := s.sendDigests(flushCtx, s.throttle.pending()); err != nil {
+				log.Printf("Failed to send notification digests on shutdown: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := s.FlushDigests(ctx); err != nil {
+				log.Printf("Failed to send notification digests: %v", err)
+			}
+		}
+	}
+}
+
+// FlushDigests sends a digest for every throttled alert stream whose window has ended
+func (s *Service) FlushDigests(ctx context.Context) error {
+	return s.sendDigests(ctx, s.throttle.due())
+}
+
+func (s *Service) sendDigests(ctx context.Context, digests []digest) error {
+	var errs []error
+	for _, d := range digests {
+		var err error
+		if d.targetIDs != nil {
+			err = s.notifyTargets(ctx, d.userID, d.targetIDs, d.msg)
+		} else {
+			err = s.notify(ctx, d.userID, d.msg)
+		}
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RegisterSender enables push delivery over the sender's channel
+func (s *Service) RegisterSender(sender Sender) {
+	s.senders[sender.Channel()] = sender
+}
+
+// RegisterTargetSender enables chat delivery over the sender's channel, replacing any existing sender
+func (s *Service) RegisterTargetSender(sender TargetSender) {
+	s.targetSenders[sender.Channel()] = sender
+}
+
+// SetEmail enables delivery of alerts by email to users with a verified address
+func (s *Service) SetEmail(sender EmailSender, users repository.UserRepository) {
+	s.email = sender
+	s.users = users
+}
+
+// HasChannel reports whether a sender is registered for the channel
+func (s *Service) HasChannel(channel models.NotificationChannel) bool {
+	if channel == models.NotificationChannelEmail {
+		return s.email != nil
+	}
+	if _, ok := s.senders[channel]; ok {
+		return true
+	}
+	_, ok := s.targetSenders[channel]
+	return ok
+}
+
+// ValidateTarget checks that a target has the settings its channel requires
+func (s *Service) ValidateTarget(target *models.NotificationTarget) error {
+	sender, ok := s.targetSenders[target.Channel]
+	if !ok {
+		return ErrChannelNotConfigured
+	}
+	return sender.Validate(target)
+}
+
+// Notify sends the message to all of the user's devices, and by email when email delivery
+// is enabled. Failed deliveries are
+// recorded and returned as a joined error, they do not stop delivery to other devices.
+// Throttled messages are held back for the next digest.
+func (s *Service) Notify(ctx context.Context, userID uuid.UUID, msg *Message) error {
+	if !s.throttle.allow(userID, nil, msg) {
+		return nil
+	}
+	return s.notify(ctx, userID, msg)
+}
+
+func (s *Service) notify(ctx context.Context, userID uuid.UUID, msg *Message) error {
+	devices, err := s.devices.ListByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	var errs []error
+	for i := range devices {
+		device := &devices[i]
+
+		sender, ok := s.senders[device.Channel]
+		if !ok {
+			continue
+		}
+
+		enabled, err := s.preferences.IsEnabled(ctx, userID, device.Channel, msg.AlertType)
+		if err != nil {
+			return fmt.Errorf("failed to check preferences: %w", err)
+		}
+		if !enabled {
+			continue
+		}
+
+		if err := s.deliver(ctx, sender, device, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if s.email != nil {
+		if err := s.notifyEmail(ctx, userID, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// notifyEmail sends the message to the user's verified email address and records the result
+func (s *Service) notifyEmail(ctx context.Context, userID uuid.UUID, msg *Message) error {
+	enabled, err := s.preferences.IsEnabled(ctx, userID, models.NotificationChannelEmail, msg.AlertType)
+	if err != nil {
+		return fmt.Errorf("failed to check preferences: %w", err)
+	}
+	if !enabled {
+		return nil
+	}
+
+	user, err := s.users.GetByID(ctx, userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Email == nil || !user.EmailVerified {
+		return nil
+	}
+
+	delivery := &models.NotificationDelivery{
+		UserID:    userID,
+		Channel:   models.NotificationChannelEmail,
+		AlertType: msg.AlertType,
+		Title:     msg.Title,
+		Body:      msg.Body,
+	}
+	if err := s.deliveries.Create(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+
+	sendErr := s.email.SendAlertEmail(*user.Email, user.Username, user.Language, msg.Title, msg.Body)
+	status := models.DeliveryStatusSent
+	var errMsg *string
+	if sendErr != nil {
+		status = models.DeliveryStatusFailed
+		e := sendErr.Error()
+		errMsg = &e
+	}
+	if err := s.deliveries.UpdateStatus(ctx, delivery.ID, status, errMsg); err != nil {
+		log.Printf("Failed to update delivery status: %v", err)
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("email delivery to user %s failed: %w", userID, sendErr)
+	}
+	return nil
+}
+
+// deliver sends to a single device and records the result
+func (s *Service) deliver(ctx context.Context, sender Sender, device *models.DeviceToken, msg *Message) error {
+	delivery := &models.NotificationDelivery{
+		UserID:        device.UserID,
+		DeviceTokenID: &device.ID,
+		Channel:       device.Channel,
+		AlertType:     msg.AlertType,
+		Title:         msg.Title,
+		Body:          msg.Body,
+	}
+	if err := s.deliveries.Create(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+
+	sendErr := sender.Send(ctx, device, msg)
+	if sendErr == nil {
+		if err := s.deliveries.UpdateStatus(ctx, delivery.ID, models.DeliveryStatusSent, nil); err != nil {
+			log.Printf("Failed to update delivery status: %v", err)
+		}
+		if err := s.devices.MarkUsed(ctx, device.ID, time.Now()); err != nil {
+			log.Printf("Failed to mark device as used: %v", err)
+		}
+		return nil
+	}
+
+	errMsg := sendErr.Error()
+	if err := s.deliveries.UpdateStatus(ctx, delivery.ID, models.DeliveryStatusFailed, &errMsg); err != nil {
+		log.Printf("Failed to update delivery status: %v", err)
+	}
+
+	if errors.Is(sendErr, ErrDeviceGone) {
+		if err := s.devices.Delete(ctx, device.ID); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			log.Printf("Failed to remove stale device %s: %v", device.ID, err)
+		}
+	}
+
+	return fmt.Errorf("%s delivery to device %s failed: %w", device.Channel, device.ID, sendErr)
+}
+
+// NotifyTargets sends the message to the given chat targets, which must belong to the user.
+// Alert rules use this to route alerts to the destinations they select. Throttled messages
+// are held back for the next digest.
+func (s *Service) NotifyTargets(ctx context.Context, userID uuid.UUID, targetIDs []uuid.UUID, msg *Message) error {
+	if len(targetIDs) == 0 {
+		return nil
+	}
+	if !s.throttle.allow(userID, targetIDs, msg) {
+		return nil
+	}
+	return s.notifyTargets(ctx, userID, targetIDs, msg)
+}
+
+func (s *Service) notifyTargets(ctx context.Context, userID uuid.UUID, targetIDs []uuid.UUID, msg *Message) error {
+	var errs []error
+	for _, id := range targetIDs {
+		target, err := s.targets.GetByID(ctx, id)
+		if errors.Is(err, repository.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get target: %w", err)
+		}
+		if target.UserID != userID {
+			continue
+		}
+
+		enabled, err := s.preferences.IsEnabled(ctx, userID, target.Channel, msg.AlertType)
+		if err != nil {
+			return fmt.Errorf("failed to check preferences: %w", err)
+		}
+		if !enabled {
+			continue
+		}
+
+		if err := s.NotifyTarget(ctx, target, msg); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// NotifyTarget sends the message to a single chat target and records the result
+func (s *Service) NotifyTarget(ctx context.Context, target *models.NotificationTarget, msg *Message) error {
+	sender, ok := s.targetSenders[target.Channel]
+	if !ok {
+		return ErrChannelNotConfigured
+	}
+
+	delivery := &models.NotificationDelivery{
+		UserID:    target.UserID,
+		TargetID:  &target.ID,
+		Channel:   target.Channel,
+		AlertType: msg.AlertType,
+		Title:     msg.Title,
+		Body:      msg.Body,
+	}
+	if err := s.deliveries.Create(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to record delivery: %w", err)
+	}
+
+	sendErr := sender.Send(ctx, target, msg)
+	status := models.DeliveryStatusSent
+	var errMsg *string
+	if sendErr != nil {
+		status = models.DeliveryStatusFailed
+		e := sendErr.Error()
+		errMsg = &e
+	}
+	if err := s.deliveries.UpdateStatus(ctx, delivery.ID, status, errMsg); err != nil {
+		log.Printf("Failed to update delivery status: %v", err)
+	}
+
+	if sendErr != nil {
+		return fmt.Errorf("delivery to target %s failed: %w", target.ID, sendErr)
+	}
+	return nil
+}
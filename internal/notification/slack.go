@@ -0,0 +1,38 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"wattwatch/internal/models"
+)
+
+// SlackSender posts messages to Slack incoming webhooks
+type SlackSender struct {
+	client *http.Client
+}
+
+// Channel returns the Slack channel
+func (s *SlackSender) Channel() models.NotificationChannel {
+	return models.NotificationChannelSlack
+}
+
+// Validate requires a hooks.slack.com webhook URL
+func (s *SlackSender) Validate(target *models.NotificationTarget) error {
+	return validateWebhookURL(target, "hooks.slack.com")
+}
+
+// Send posts the message as mrkdwn text
+func (s *SlackSender) Send(ctx context.Context, target *models.NotificationTarget, msg *Message) error {
+	text := fmt.Sprintf("*%s*\n%s", slackEscape(msg.Title), slackEscape(msg.Body))
+	if err := postJSON(ctx, s.client, *target.WebhookURL, map[string]string{"text": text}); err != nil {
+		return fmt.Errorf("slack: %w", err)
+	}
+	return nil
+}
+
+// slackEscape escapes the control characters Slack uses for links and mentions
+func slackEscape(s string) string {
+	return strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;").Replace(s)
+}
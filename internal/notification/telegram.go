@@ -0,0 +1,47 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"html"
+	"net/http"
+	"wattwatch/internal/models"
+)
+
+const telegramAPIURL = "https://api.telegram.org"
+
+// TelegramSender sends messages to a chat through the Telegram Bot API
+type TelegramSender struct {
+	client *http.Client
+	apiURL string
+}
+
+// Channel returns the Telegram channel
+func (s *TelegramSender) Channel() models.NotificationChannel {
+	return models.NotificationChannelTelegram
+}
+
+// Validate requires a bot token and a chat ID
+func (s *TelegramSender) Validate(target *models.NotificationTarget) error {
+	if target.BotToken == nil || *target.BotToken == "" {
+		return fmt.Errorf("%w: bot_token is required", ErrInvalidTarget)
+	}
+	if target.ChatID == nil || *target.ChatID == "" {
+		return fmt.Errorf("%w: chat_id is required", ErrInvalidTarget)
+	}
+	return nil
+}
+
+// Send posts the message to the target chat using HTML formatting
+func (s *TelegramSender) Send(ctx context.Context, target *models.NotificationTarget, msg *Message) error {
+	endpoint := fmt.Sprintf("%s/bot%s/sendMessage", s.apiURL, *target.BotToken)
+	payload := map[string]interface{}{
+		"chat_id":    *target.ChatID,
+		"text":       fmt.Sprintf("<b>%s</b>\n%s", html.EscapeString(msg.Title), html.EscapeString(msg.Body)),
+		"parse_mode": "HTML",
+	}
+	if err := postJSON(ctx, s.client, endpoint, payload); err != nil {
+		return fmt.Errorf("telegram: %w", err)
+	}
+	return nil
+}
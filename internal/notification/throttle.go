@@ -0,0 +1,168 @@
+package notification
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// DefaultThrottleInterval is how often a user is notified about the same alert
+// before further triggers are collected into a digest
+const DefaultThrottleInterval = 6 * time.Hour
+
+// throttleKey identifies a stream of alerts that is throttled together
+type throttleKey struct {
+	userID    uuid.UUID
+	alertType models.NotificationAlertType
+	rule      string
+	// targets is empty for device notifications and the joined target IDs otherwise
+	targets string
+}
+
+type throttleState struct {
+	lastSent  time.Time
+	pending   []*Message
+	targetIDs []uuid.UUID
+}
+
+// digest is a batch of throttled messages that is ready to be sent
+type digest struct {
+	userID    uuid.UUID
+	targetIDs []uuid.UUID
+	msg       *Message
+}
+
+// throttler limits alerts per user, alert type and rule. The first alert in a window is sent
+// right away, later ones are held back and released as a single digest when the window ends.
+type throttler struct {
+	mu        sync.Mutex
+	intervals map[models.NotificationAlertType]time.Duration
+	states    map[throttleKey]*throttleState
+	now       func() time.Time
+}
+
+func newThrottler() *throttler {
+	return &throttler{
+		intervals: make(map[models.NotificationAlertType]time.Duration),
+		states:    make(map[throttleKey]*throttleState),
+		now:       time.Now,
+	}
+}
+
+func (t *throttler) setInterval(alertType models.NotificationAlertType, interval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.intervals[alertType] = interval
+}
+
+// allow reports whether msg may be sent now. Otherwise it is queued for the next digest.
+func (t *throttler) allow(userID uuid.UUID, targetIDs []uuid.UUID, msg *Message) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	interval := t.intervals[msg.AlertType]
+	if interval <= 0 {
+		return true
+	}
+
+	key := newThrottleKey(userID, targetIDs, msg)
+	state, ok := t.states[key]
+	now := t.now()
+	if !ok || now.Sub(state.lastSent) >= interval {
+		if !ok {
+			state = &throttleState{targetIDs: targetIDs}
+			t.states[key] = state
+		}
+		state.lastSent = now
+		return true
+	}
+
+	state.pending = append(state.pending, msg)
+	return false
+}
+
+// due returns the digests whose window has ended and starts a new window for each of them
+func (t *throttler) due() []digest {
+	return t.collect(false)
+}
+
+// pending returns a digest for every stream with held back alerts, whether or not its
+// window has ended, so nothing is lost when the service stops
+func (t *throttler) pending() []digest {
+	return t.collect(true)
+}
+
+func (t *throttler) collect(all bool) []digest {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	var digests []digest
+	for key, state := range t.states {
+		interval := t.intervals[key.alertType]
+		if !all && now.Sub(state.lastSent) < interval {
+			continue
+		}
+		if len(state.pending) == 0 {
+			// Nothing was held back, forget the stream so the map doesn't grow forever
+			delete(t.states, key)
+			continue
+		}
+
+		digests = append(digests, digest{
+			userID:    key.userID,
+			targetIDs: state.targetIDs,
+			msg:       digestMessage(key.alertType, state.pending),
+		})
+		state.pending = nil
+		state.lastSent = now
+	}
+
+	return digests
+}
+
+func newThrottleKey(userID uuid.UUID, targetIDs []uuid.UUID, msg *Message) throttleKey {
+	ids := make([]string, len(targetIDs))
+	for i, id := range targetIDs {
+		ids[i] = id.String()
+	}
+	sort.Strings(ids)
+
+	return throttleKey{
+		userID:    userID,
+		alertType: msg.AlertType,
+		rule:      msg.ThrottleKey,
+		targets:   strings.Join(ids, ","),
+	}
+}
+
+// digestMessage combines held back messages into one
+func digestMessage(alertType models.NotificationAlertType, msgs []*Message) *Message {
+	if len(msgs) == 1 {
+		return msgs[0]
+	}
+
+	var body strings.Builder
+	for i, msg := range msgs {
+		if i > 0 {
+			body.WriteString("\n")
+		}
+		fmt.Fprintf(&body, "• %s: %s", msg.Title, msg.Body)
+	}
+
+	return &Message{
+		AlertType:   alertType,
+		ThrottleKey: msgs[0].ThrottleKey,
+		Title:       fmt.Sprintf("%d %s alerts", len(msgs), alertType),
+		Body:        body.String(),
+		Data: map[string]string{
+			"digest": "true",
+			"count":  fmt.Sprint(len(msgs)),
+		},
+	}
+}
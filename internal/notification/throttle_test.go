@@ -0,0 +1,88 @@
+package notification
+
+import (
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestThrottler(t *testing.T) {
+	now := time.Date(2024, 3, 4, 12, 0, 0, 0, time.UTC)
+	th := newThrottler()
+	th.now = func() time.Time { return now }
+	th.setInterval(models.NotificationAlertPrice, 6*time.Hour)
+
+	user := uuid.New()
+	price := func(rule, title string) *Message {
+		return &Message{AlertType: models.NotificationAlertPrice, ThrottleKey: rule, Title: title, Body: "Spot price is high"}
+	}
+
+	// The first alert of a rule goes out, repeats within the window are held back
+	assert.True(t, th.allow(user, nil, price("rule-1", "Price above 2 SEK")))
+	assert.False(t, th.allow(user, nil, price("rule-1", "Price above 2 SEK")))
+	assert.False(t, th.allow(user, nil, price("rule-1", "Price above 3 SEK")))
+
+	// Other rules, users, destinations and unthrottled alert types are independent
+	assert.True(t, th.allow(user, nil, price("rule-2", "Price below 0 SEK")))
+	assert.True(t, th.allow(uuid.New(), nil, price("rule-1", "Price above 2 SEK")))
+	assert.True(t, th.allow(user, []uuid.UUID{uuid.New()}, price("rule-1", "Price above 2 SEK")))
+	assert.True(t, th.allow(user, nil, &Message{AlertType: models.NotificationAlertTest}))
+	assert.True(t, th.allow(user, nil, &Message{AlertType: models.NotificationAlertTest}))
+
+	// Nothing is due before the window ends
+	now = now.Add(5 * time.Hour)
+	assert.Empty(t, th.due())
+
+	now = now.Add(time.Hour)
+	digests := th.due()
+	require.Len(t, digests, 1)
+	assert.Equal(t, user, digests[0].userID)
+	assert.Nil(t, digests[0].targetIDs)
+	assert.Equal(t, "2 price alerts", digests[0].msg.Title)
+	assert.Equal(t, "• Price above 2 SEK: Spot price is high\n• Price above 3 SEK: Spot price is high", digests[0].msg.Body)
+	assert.Equal(t, "2", digests[0].msg.Data["count"])
+
+	// Sending the digest starts a new window
+	assert.False(t, th.allow(user, nil, price("rule-1", "Price above 4 SEK")))
+	now = now.Add(6 * time.Hour)
+	digests = th.due()
+	require.Len(t, digests, 1)
+	assert.Equal(t, "Price above 4 SEK", digests[0].msg.Title, "a single held back alert is sent as is")
+
+	// Idle streams are forgotten
+	now = now.Add(6 * time.Hour)
+	assert.Empty(t, th.due())
+	assert.Empty(t, th.states)
+}
+
+func TestThrottler_Disabled(t *testing.T) {
+	th := newThrottler()
+	th.setInterval(models.NotificationAlertPrice, 0)
+
+	user := uuid.New()
+	msg := &Message{AlertType: models.NotificationAlertPrice, Title: "Price alert"}
+	assert.True(t, th.allow(user, nil, msg))
+	assert.True(t, th.allow(user, nil, msg))
+	assert.Empty(t, th.due())
+}
+
+func TestThrottler_Pending(t *testing.T) {
+	th := newThrottler()
+	th.setInterval(models.NotificationAlertPrice, 6*time.Hour)
+
+	user := uuid.New()
+	msg := &Message{AlertType: models.NotificationAlertPrice, ThrottleKey: "rule-1", Title: "Price alert"}
+	assert.True(t, th.allow(user, nil, msg))
+	assert.False(t, th.allow(user, nil, msg))
+
+	// Held back alerts are released before the window ends when the service stops
+	assert.Empty(t, th.due())
+	digests := th.pending()
+	require.Len(t, digests, 1)
+	assert.Equal(t, "Price alert", digests[0].msg.Title)
+	assert.Empty(t, th.pending())
+}
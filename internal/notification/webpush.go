@@ -0,0 +1,286 @@
+package notification
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	webPushTTL        = 24 * time.Hour
+	webPushRecordSize = 4096
+)
+
+// ErrInvalidEndpoint is returned for WebPush subscriptions whose endpoint isn't an https URL
+// of a known push service
+var ErrInvalidEndpoint = errors.New("endpoint is not a known push service")
+
+// webPushHosts are the hosts of the browsers' push services, entries starting with a dot
+// match their subdomains. Subscriptions are only posted to these, so users can't make the
+// server send requests to other hosts.
+var webPushHosts = []string{
+	"fcm.googleapis.com",
+	"updates.push.services.mozilla.com",
+	".push.apple.com",
+	".notify.windows.com",
+}
+
+// WebPushSender delivers notifications to browser push services using VAPID (RFC 8292)
+// and aes128gcm payload encryption (RFC 8291)
+type WebPushSender struct {
+	privateKey *ecdsa.PrivateKey
+	publicKey  string
+	subject    string
+	client     *http.Client
+	// hosts are the push service hosts endpoints may point to
+	hosts []string
+}
+
+// NewWebPushSender creates a WebPush sender from a base64url encoded P-256 private key.
+// The subject must be a mailto: or https: URI push services can use to contact the sender.
+func NewWebPushSender(privateKey, subject string, client *http.Client) (*WebPushSender, error) {
+	raw, err := decodeBase64URL(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode VAPID private key: %w", err)
+	}
+	// Validates the scalar is in range for the curve
+	ecdhKey, err := ecdh.P256().NewPrivateKey(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid VAPID private key: %w", err)
+	}
+	if !strings.HasPrefix(subject, "mailto:") && !strings.HasPrefix(subject, "https:") {
+		return nil, errors.New("VAPID subject must be a mailto: or https: URI")
+	}
+
+	curve := elliptic.P256()
+	key := &ecdsa.PrivateKey{
+		PublicKey: ecdsa.PublicKey{Curve: curve},
+		D:         new(big.Int).SetBytes(raw),
+	}
+	key.PublicKey.X, key.PublicKey.Y = curve.ScalarBaseMult(raw)
+
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+
+	return &WebPushSender{
+		privateKey: key,
+		publicKey:  base64.RawURLEncoding.EncodeToString(ecdhKey.PublicKey().Bytes()),
+		subject:    subject,
+		client:     client,
+		hosts:      webPushHosts,
+	}, nil
+}
+
+// Channel returns the WebPush channel
+func (s *WebPushSender) Channel() models.NotificationChannel {
+	return models.NotificationChannelWebPush
+}
+
+// PublicKey returns the base64url encoded application server key browsers subscribe with
+func (s *WebPushSender) PublicKey() string {
+	return s.publicKey
+}
+
+// Send encrypts the message for the subscription and posts it to the push service endpoint
+func (s *WebPushSender) Send(ctx context.Context, device *models.DeviceToken, msg *Message) error {
+	if device.P256dh == nil || device.Auth == nil {
+		return ErrDeviceGone
+	}
+
+	endpoint, err := parseWebPushEndpoint(device.Token, s.hosts)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrDeviceGone, err)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"title":      msg.Title,
+		"body":       msg.Body,
+		"alert_type": msg.AlertType,
+		"data":       msg.Data,
+	})
+	if err != nil {
+		return err
+	}
+
+	body, err := encryptWebPush(payload, *device.P256dh, *device.Auth)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt payload: %w", err)
+	}
+
+	authorization, err := s.vapidAuthorization(endpoint.Scheme + "://" + endpoint.Host)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, device.Token, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", authorization)
+	req.Header.Set("Content-Encoding", "aes128gcm")
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("TTL", fmt.Sprintf("%d", int(webPushTTL.Seconds())))
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send WebPush request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return nil
+	case resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusGone:
+		return ErrDeviceGone
+	default:
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("push service returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+	}
+}
+
+// ValidateWebPushEndpoint checks that a subscription endpoint is an https URL of a known
+// push service
+func ValidateWebPushEndpoint(endpoint string) error {
+	_, err := parseWebPushEndpoint(endpoint, webPushHosts)
+	return err
+}
+
+// parseWebPushEndpoint parses an endpoint, returning ErrInvalidEndpoint unless it is an https
+// URL of one of the hosts
+func parseWebPushEndpoint(endpoint string, hosts []string) (*url.URL, error) {
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Scheme != "https" {
+		return nil, ErrInvalidEndpoint
+	}
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range hosts {
+		if host == allowed || (strings.HasPrefix(allowed, ".") && strings.HasSuffix(host, allowed)) {
+			return u, nil
+		}
+	}
+	return nil, ErrInvalidEndpoint
+}
+
+// vapidAuthorization builds the VAPID Authorization header for the push service origin
+func (s *WebPushSender) vapidAuthorization(audience string) (string, error) {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"aud": audience,
+		"exp": time.Now().Add(12 * time.Hour).Unix(),
+		"sub": s.subject,
+	}).SignedString(s.privateKey)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign VAPID token: %w", err)
+	}
+	return fmt.Sprintf("vapid t=%s, k=%s", token, s.publicKey), nil
+}
+
+// encryptWebPush encrypts a payload for a subscription as a single aes128gcm record
+func encryptWebPush(plaintext []byte, p256dh, authSecret string) ([]byte, error) {
+	uaPublicBytes, err := decodeBase64URL(p256dh)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+	auth, err := decodeBase64URL(authSecret)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth secret: %w", err)
+	}
+
+	uaPublic, err := ecdh.P256().NewPublicKey(uaPublicBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid p256dh key: %w", err)
+	}
+
+	asPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	asPublicBytes := asPrivate.PublicKey().Bytes()
+
+	sharedSecret, err := asPrivate.ECDH(uaPublic)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+
+	cek, nonce, err := deriveWebPushKeys(sharedSecret, auth, salt, uaPublicBytes, asPublicBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(cek)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	// A single record is terminated by the 0x02 padding delimiter
+	record := append(append([]byte{}, plaintext...), 0x02)
+	if len(record)+gcm.Overhead() > webPushRecordSize {
+		return nil, errors.New("payload too large")
+	}
+
+	header := make([]byte, 0, 21+len(asPublicBytes))
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, webPushRecordSize)
+	header = append(header, byte(len(asPublicBytes)))
+	header = append(header, asPublicBytes...)
+
+	return gcm.Seal(header, nonce, record, nil), nil
+}
+
+// deriveWebPushKeys derives the content encryption key and nonce as described in RFC 8291 section 3.4
+func deriveWebPushKeys(sharedSecret, auth, salt, uaPublic, asPublic []byte) ([]byte, []byte, error) {
+	keyInfo := append([]byte("WebPush: info\x00"), uaPublic...)
+	keyInfo = append(keyInfo, asPublic...)
+
+	ikm := make([]byte, 32)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, sharedSecret, auth, keyInfo), ikm); err != nil {
+		return nil, nil, err
+	}
+
+	cek := make([]byte, 16)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: aes128gcm\x00")), cek); err != nil {
+		return nil, nil, err
+	}
+
+	nonce := make([]byte, 12)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, ikm, salt, []byte("Content-Encoding: nonce\x00")), nonce); err != nil {
+		return nil, nil, err
+	}
+
+	return cek, nonce, nil
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers differ
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
@@ -0,0 +1,241 @@
+package notification
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdh"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"wattwatch/internal/models"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSubscription creates browser-side subscription keys for tests
+func newSubscription(t *testing.T) (*ecdh.PrivateKey, string, []byte, string) {
+	t.Helper()
+	uaPrivate, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	auth := make([]byte, 16)
+	_, err = rand.Read(auth)
+	require.NoError(t, err)
+	return uaPrivate,
+		base64.RawURLEncoding.EncodeToString(uaPrivate.PublicKey().Bytes()),
+		auth,
+		base64.RawURLEncoding.EncodeToString(auth)
+}
+
+// decryptWebPush reverses encryptWebPush using the subscription private key
+func decryptWebPush(t *testing.T, body []byte, uaPrivate *ecdh.PrivateKey, auth []byte) []byte {
+	t.Helper()
+	require.Greater(t, len(body), 21)
+
+	salt := body[:16]
+	assert.Equal(t, uint32(webPushRecordSize), binary.BigEndian.Uint32(body[16:20]))
+	idLen := int(body[20])
+	asPublicBytes := body[21 : 21+idLen]
+	ciphertext := body[21+idLen:]
+
+	asPublic, err := ecdh.P256().NewPublicKey(asPublicBytes)
+	require.NoError(t, err)
+	sharedSecret, err := uaPrivate.ECDH(asPublic)
+	require.NoError(t, err)
+
+	cek, nonce, err := deriveWebPushKeys(sharedSecret, auth, salt, uaPrivate.PublicKey().Bytes(), asPublicBytes)
+	require.NoError(t, err)
+
+	block, err := aes.NewCipher(cek)
+	require.NoError(t, err)
+	gcm, err := cipher.NewGCM(block)
+	require.NoError(t, err)
+
+	record, err := gcm.Open(nil, nonce, ciphertext, nil)
+	require.NoError(t, err)
+	require.Equal(t, byte(0x02), record[len(record)-1])
+	return record[:len(record)-1]
+}
+
+func TestEncryptWebPush(t *testing.T) {
+	uaPrivate, p256dh, auth, authEncoded := newSubscription(t)
+
+	tests := []struct {
+		name    string
+		p256dh  string
+		auth    string
+		payload []byte
+		wantErr bool
+	}{
+		{
+			name:    "Success",
+			p256dh:  p256dh,
+			auth:    authEncoded,
+			payload: []byte(`{"title":"Price alert"}`),
+		},
+		{
+			name:    "Padded Keys",
+			p256dh:  p256dh + "=",
+			auth:    authEncoded + "==",
+			payload: []byte("hello"),
+		},
+		{
+			name:    "Invalid Public Key",
+			p256dh:  base64.RawURLEncoding.EncodeToString([]byte("not a key")),
+			auth:    authEncoded,
+			payload: []byte("hello"),
+			wantErr: true,
+		},
+		{
+			name:    "Payload Too Large",
+			p256dh:  p256dh,
+			auth:    authEncoded,
+			payload: make([]byte, webPushRecordSize),
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body, err := encryptWebPush(tt.payload, tt.p256dh, tt.auth)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.payload, decryptWebPush(t, body, uaPrivate, auth))
+		})
+	}
+}
+
+func TestWebPushSender_Send(t *testing.T) {
+	vapidKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	privateKey := base64.RawURLEncoding.EncodeToString(vapidKey.Bytes())
+
+	sender, err := NewWebPushSender(privateKey, "mailto:admin@example.com", nil)
+	require.NoError(t, err)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(vapidKey.PublicKey().Bytes()), sender.PublicKey())
+
+	tests := []struct {
+		name       string
+		statusCode int
+		wantErr    error
+	}{
+		{name: "Created", statusCode: http.StatusCreated},
+		{name: "Gone", statusCode: http.StatusGone, wantErr: ErrDeviceGone},
+		{name: "Server Error", statusCode: http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uaPrivate, p256dh, auth, authEncoded := newSubscription(t)
+
+			server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, "aes128gcm", r.Header.Get("Content-Encoding"))
+
+				authorization := r.Header.Get("Authorization")
+				require.True(t, strings.HasPrefix(authorization, "vapid t="))
+				parts := strings.SplitN(strings.TrimPrefix(authorization, "vapid t="), ", k=", 2)
+				require.Len(t, parts, 2)
+				assert.Equal(t, sender.PublicKey(), parts[1])
+
+				token, err := jwt.Parse(parts[0], func(token *jwt.Token) (interface{}, error) {
+					return &sender.privateKey.PublicKey, nil
+				}, jwt.WithValidMethods([]string{"ES256"}))
+				require.NoError(t, err)
+				claims := token.Claims.(jwt.MapClaims)
+				assert.Equal(t, "mailto:admin@example.com", claims["sub"])
+
+				body, err := io.ReadAll(r.Body)
+				require.NoError(t, err)
+				assert.Contains(t, string(decryptWebPush(t, body, uaPrivate, auth)), "Cheap power")
+
+				w.WriteHeader(tt.statusCode)
+			}))
+			defer server.Close()
+			sender.client = server.Client()
+			sender.hosts = []string{"127.0.0.1"}
+
+			device := &models.DeviceToken{
+				Channel: models.NotificationChannelWebPush,
+				Token:   server.URL + "/push/abc",
+				P256dh:  &p256dh,
+				Auth:    &authEncoded,
+			}
+			err := sender.Send(context.Background(), device, &Message{
+				AlertType: models.NotificationAlertPrice,
+				Title:     "Cheap power",
+				Body:      "Prices are low right now",
+			})
+
+			switch {
+			case tt.wantErr != nil:
+				require.ErrorIs(t, err, tt.wantErr)
+			case tt.statusCode >= 300:
+				require.Error(t, err)
+			default:
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestWebPushSender_SendUnknownHost(t *testing.T) {
+	vapidKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	sender, err := NewWebPushSender(base64.RawURLEncoding.EncodeToString(vapidKey.Bytes()), "mailto:admin@example.com", nil)
+	require.NoError(t, err)
+
+	requested := false
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requested = true
+	}))
+	defer server.Close()
+	sender.client = server.Client()
+
+	_, p256dh, _, auth := newSubscription(t)
+	device := &models.DeviceToken{Channel: models.NotificationChannelWebPush, Token: server.URL + "/push/abc", P256dh: &p256dh, Auth: &auth}
+	err = sender.Send(context.Background(), device, &Message{Title: "Cheap power"})
+	assert.ErrorIs(t, err, ErrDeviceGone)
+	assert.ErrorIs(t, err, ErrInvalidEndpoint)
+	assert.False(t, requested, "endpoints of unknown hosts are not posted to")
+}
+
+func TestValidateWebPushEndpoint(t *testing.T) {
+	for _, endpoint := range []string{
+		"https://fcm.googleapis.com/fcm/send/abc",
+		"https://updates.push.services.mozilla.com/wpush/v2/abc",
+		"https://web.push.apple.com/abc",
+		"https://wns2-par02p.notify.windows.com/w/?token=abc",
+	} {
+		assert.NoError(t, ValidateWebPushEndpoint(endpoint), endpoint)
+	}
+	for _, endpoint := range []string{
+		"http://fcm.googleapis.com/fcm/send/abc",
+		"https://127.0.0.1/push",
+		"https://169.254.169.254/latest/meta-data",
+		"https://push.apple.com.example.com/abc",
+		"https://evilpush.apple.com/abc",
+		"not a url",
+	} {
+		assert.ErrorIs(t, ValidateWebPushEndpoint(endpoint), ErrInvalidEndpoint, endpoint)
+	}
+}
+
+func TestNewWebPushSender_InvalidConfig(t *testing.T) {
+	_, err := NewWebPushSender("not-base64!", "mailto:admin@example.com", nil)
+	require.Error(t, err)
+
+	vapidKey, err := ecdh.P256().GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, err = NewWebPushSender(base64.RawURLEncoding.EncodeToString(vapidKey.Bytes()), "admin@example.com", nil)
+	require.Error(t, err)
+}
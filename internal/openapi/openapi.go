@@ -0,0 +1,54 @@
+// Package openapi publishes the API documentation as OpenAPI 3. The annotations on the
+// handlers are compiled by swag into a Swagger 2 document, which is converted here so
+// clients and code generators can use the current version of the format.
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"wattwatch/docs"
+
+	"github.com/getkin/kin-openapi/openapi2"
+	"github.com/getkin/kin-openapi/openapi2conv"
+	"github.com/getkin/kin-openapi/openapi3"
+)
+
+var (
+	specOnce sync.Once
+	spec     []byte
+	specErr  error
+)
+
+// Spec returns the OpenAPI 3 document as JSON. It is converted on first use and shared
+// afterwards.
+func Spec() ([]byte, error) {
+	specOnce.Do(func() {
+		spec, specErr = Convert([]byte(docs.SwaggerInfo.ReadDoc()))
+	})
+	return spec, specErr
+}
+
+// Convert turns a Swagger 2 document into OpenAPI 3. The server is the base path alone,
+// so the document works wherever the API is hosted and clients pass their own host.
+func Convert(swagger []byte) ([]byte, error) {
+	var doc2 openapi2.T
+	if err := json.Unmarshal(swagger, &doc2); err != nil {
+		return nil, fmt.Errorf("failed to parse swagger document: %w", err)
+	}
+	basePath := doc2.BasePath
+	doc2.Host = ""
+	doc2.Schemes = nil
+
+	doc3, err := openapi2conv.ToV3(&doc2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert swagger document: %w", err)
+	}
+	doc3.Servers = openapi3.Servers{{URL: basePath}}
+
+	data, err := json.MarshalIndent(doc3, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode openapi document: %w", err)
+	}
+	return data, nil
+}
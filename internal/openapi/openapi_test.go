@@ -0,0 +1,66 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvert(t *testing.T) {
+	swagger := `{
+		"swagger": "2.0",
+		"info": {"title": "WattWatch API", "version": "1.0"},
+		"host": "localhost:8080",
+		"basePath": "/api/v1",
+		"paths": {
+			"/zones/{id}": {
+				"get": {
+					"produces": ["application/json"],
+					"parameters": [{"name": "id", "in": "path", "required": true, "type": "string"}],
+					"responses": {"200": {"description": "OK", "schema": {"$ref": "#/definitions/models.Zone"}}}
+				}
+			}
+		},
+		"definitions": {
+			"models.Zone": {"type": "object", "properties": {"name": {"type": "string"}}}
+		}
+	}`
+
+	data, err := Convert([]byte(swagger))
+	require.NoError(t, err)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Servers []struct {
+			URL string `json:"url"`
+		} `json:"servers"`
+		Paths      map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	require.Len(t, doc.Servers, 1)
+	assert.Equal(t, "/api/v1", doc.Servers[0].URL, "the host is left to clients")
+	assert.Contains(t, doc.Paths, "/zones/{id}")
+	assert.Contains(t, doc.Components.Schemas, "models.Zone")
+	assert.Contains(t, string(doc.Paths["/zones/{id}"]), "#/components/schemas/models.Zone")
+}
+
+func TestConvert_Invalid(t *testing.T) {
+	_, err := Convert([]byte("not json"))
+	assert.Error(t, err)
+}
+
+func TestSpec(t *testing.T) {
+	data, err := Spec()
+	require.NoError(t, err)
+
+	var doc map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &doc))
+	assert.Equal(t, "WattWatch API", doc["info"].(map[string]interface{})["title"])
+	assert.Contains(t, doc["paths"], "/spot-prices")
+}
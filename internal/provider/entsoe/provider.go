@@ -0,0 +1,435 @@
+// Package entsoe fetches day-ahead prices from the ENTSO-E Transparency Platform
+package entsoe
+
+import (
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// ProviderName is the unique identifier for the ENTSO-E provider
+	ProviderName = "entsoe"
+	// BaseURL is the base URL of the ENTSO-E Transparency Platform API
+	BaseURL = "https://web-api.tp.entsoe.eu/api"
+	// Currency is the only currency ENTSO-E publishes day-ahead prices in
+	Currency = "EUR"
+
+	// documentTypePrices requests day-ahead prices
+	documentTypePrices = "A44"
+	// curveTypeFilled marks time series leaving out points equal to the previous one
+	curveTypeFilled = "A03"
+	periodLayout    = "200601021504"
+	intervalLayout  = "2006-01-02T15:04Z"
+)
+
+// ErrNoToken is returned when prices are fetched without an API token configured
+var ErrNoToken = errors.New("ENTSO-E API token is not configured")
+
+// DefaultConfig returns the default configuration for the ENTSO-E provider. Zones are
+// the ones mapped to an area rather than configured here.
+func DefaultConfig() provider.Config {
+	return provider.Config{
+		Schedule:            "30 13 * * *", // Run at 13:30 every day, after the day-ahead auction
+		SupportedCurrencies: []string{Currency},
+	}
+}
+
+// Provider implements the provider.Provider interface for ENTSO-E
+type Provider struct {
+	provider.BaseProvider
+	spotPrices repository.SpotPriceSourceRepository
+	areas      repository.EntsoeAreaRepository
+	zones      repository.ZoneRepository
+	currencies repository.CurrencyRepository
+	token      func() string
+	client     *http.Client
+	baseURL    string
+	// delay is waited before each API call to stay within the API's rate limits
+	delay time.Duration
+}
+
+// NewProvider creates a new ENTSO-E provider fetching prices for the zones mapped in areas.
+// token is called for every request, so changes to it apply without a restart.
+func NewProvider(
+	spotPrices repository.SpotPriceSourceRepository,
+	areas repository.EntsoeAreaRepository,
+	zones repository.ZoneRepository,
+	currencies repository.CurrencyRepository,
+	config provider.Config,
+	token func() string,
+) *Provider {
+	if config.Schedule == "" {
+		config.Schedule = DefaultConfig().Schedule
+	}
+	config.SupportedCurrencies = DefaultConfig().SupportedCurrencies
+	if token == nil {
+		token = func() string { return "" }
+	}
+
+	return &Provider{
+		BaseProvider: provider.NewBaseProvider(nil, config),
+		spotPrices:   spotPrices,
+		areas:        areas,
+		zones:        zones,
+		currencies:   currencies,
+		token:        token,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		baseURL:      BaseURL,
+		delay:        time.Second,
+	}
+}
+
+// Factory creates the ENTSO-E provider for the provider registry. It has no token until
+// one is given with SetTokenSource.
+func Factory(deps provider.Dependencies, config provider.Config) (provider.Provider, error) {
+	return NewProvider(deps.SpotPrices, deps.EntsoeAreas, deps.Zones, deps.Currencies, config, nil), nil
+}
+
+// SetTokenSource replaces the function returning the API token
+func (p *Provider) SetTokenSource(token func() string) {
+	p.token = token
+}
+
+// Name returns the provider's unique identifier
+func (p *Provider) Name() string {
+	return ProviderName
+}
+
+// SupportsZone reports whether the zone is mapped to an area
+func (p *Provider) SupportsZone(zoneName string) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	areas, err := p.areas.List(ctx)
+	if err != nil {
+		return false
+	}
+	for _, area := range areas {
+		if area.ZoneName == zoneName {
+			return true
+		}
+	}
+	return false
+}
+
+// Check verifies that the ENTSO-E API can be reached. Any HTTP response counts, since the
+// API rejects requests without a token.
+func (p *Provider) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.baseURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", p.baseURL, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Run fetches and stores tomorrow's prices for every mapped zone. It keeps going when a
+// zone fails and returns the errors together.
+func (p *Provider) Run(ctx context.Context) error {
+	areas, err := p.areas.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list areas: %w", err)
+	}
+
+	tomorrow := time.Now().AddDate(0, 0, 1)
+	var errs []error
+	for _, area := range areas {
+		if err := p.fetchDay(ctx, area, tomorrow); err != nil {
+			errs = append(errs, fmt.Errorf("zone %s: %w", area.ZoneName, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunWithOptions fetches and stores the prices of one zone for one day
+func (p *Provider) RunWithOptions(ctx context.Context, opts provider.RunOptions) error {
+	if opts.Currency != Currency {
+		return fmt.Errorf("unsupported currency: %s", opts.Currency)
+	}
+
+	areas, err := p.areas.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list areas: %w", err)
+	}
+	for _, area := range areas {
+		if area.ZoneName == opts.Zone {
+			return p.fetchDay(ctx, area, opts.Date)
+		}
+	}
+	return fmt.Errorf("unsupported zone: %s", opts.Zone)
+}
+
+// Fetch fetches and stores the prices of a zone for every day of dates. It keeps going
+// when a day fails and returns the errors together.
+func (p *Provider) Fetch(ctx context.Context, zone string, dates provider.DateRange) error {
+	areas, err := p.areas.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to list areas: %w", err)
+	}
+	for _, area := range areas {
+		if area.ZoneName != zone {
+			continue
+		}
+		var errs []error
+		for _, date := range dates.Days() {
+			if err := p.fetchDay(ctx, area, date); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				errs = append(errs, fmt.Errorf("%s: %w", date.Format("2006-01-02"), err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return fmt.Errorf("unsupported zone: %s", zone)
+}
+
+// fetchDay fetches the prices of an area for the day of date in the zone's timezone, from
+// midnight to midnight, and stores them for the zone
+func (p *Provider) fetchDay(ctx context.Context, area models.EntsoeArea, date time.Time) error {
+	zone, err := p.zones.GetByID(ctx, area.ZoneID)
+	if err != nil {
+		return fmt.Errorf("failed to get zone: %w", err)
+	}
+	loc, err := time.LoadLocation(zone.Timezone)
+	if err != nil {
+		return fmt.Errorf("invalid timezone %q: %w", zone.Timezone, err)
+	}
+	currency, err := p.currencies.GetByName(ctx, Currency)
+	if err != nil {
+		return fmt.Errorf("failed to get currency %s: %w", Currency, err)
+	}
+
+	date = date.In(loc)
+	start := time.Date(date.Year(), date.Month(), date.Day(), 0, 0, 0, 0, loc)
+	end := start.AddDate(0, 0, 1)
+
+	// Add delay before the API call
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(p.delay):
+	}
+
+	points, err := p.fetchPrices(ctx, area.AreaCode, start, end)
+	if err != nil {
+		return err
+	}
+
+	spotPrices := make([]models.SpotPrice, 0, len(points))
+	for _, point := range points {
+		spotPrices = append(spotPrices, models.SpotPrice{
+			ID:         uuid.New(),
+			Timestamp:  point.timestamp,
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      parsePrice(point.price),
+		})
+	}
+	if len(spotPrices) == 0 {
+		return fmt.Errorf("no prices published for %s", start.Format("2006-01-02"))
+	}
+
+	if err := provider.Record(ctx, p.spotPrices, p.Name(), zone.Name, Currency, spotPrices); err != nil {
+		return fmt.Errorf("failed to store prices: %w", err)
+	}
+	return nil
+}
+
+// parsePrice converts a price per MWh to hundredths of the currency per kWh, the unit the
+// other providers store
+func parsePrice(price float64) float64 {
+	return price / 10
+}
+
+// fetchPrices requests the day-ahead prices of an area between start and end
+func (p *Provider) fetchPrices(ctx context.Context, areaCode string, start, end time.Time) ([]pricePoint, error) {
+	token := ""
+	if p.token != nil {
+		token = p.token()
+	}
+	if token == "" {
+		return nil, ErrNoToken
+	}
+
+	params := url.Values{}
+	params.Add("securityToken", token)
+	params.Add("documentType", documentTypePrices)
+	params.Add("in_Domain", areaCode)
+	params.Add("out_Domain", areaCode)
+	params.Add("periodStart", start.UTC().Format(periodLayout))
+	params.Add("periodEnd", end.UTC().Format(periodLayout))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.baseURL+"?"+params.Encode(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		// The URL holds the token, keep it out of the error
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized:
+		return nil, errors.New("ENTSO-E rejected the API token")
+	case isAcknowledgement(body):
+		// Errors, including days without prices, come as acknowledgement documents
+		return nil, fmt.Errorf("ENTSO-E returned an error: %s", acknowledgementReason(body))
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var doc marketDocument
+	if err := xml.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return doc.points(start, end)
+}
+
+// marketDocument is the Publication_MarketDocument holding day-ahead prices
+type marketDocument struct {
+	TimeSeries []struct {
+		Currency  string `xml:"currency_Unit.name"`
+		Unit      string `xml:"price_Measure_Unit.name"`
+		CurveType string `xml:"curveType"`
+		Periods   []struct {
+			Start      string `xml:"timeInterval>start"`
+			End        string `xml:"timeInterval>end"`
+			Resolution string `xml:"resolution"`
+			Points     []struct {
+				Position int     `xml:"position"`
+				Price    float64 `xml:"price.amount"`
+			} `xml:"Point"`
+		} `xml:"Period"`
+	} `xml:"TimeSeries"`
+}
+
+type pricePoint struct {
+	timestamp time.Time
+	price     float64
+}
+
+// points returns the prices from start up to end ordered by time. Series using the A03
+// curve type leave out points with the same price as the previous one, those are filled
+// in. When series overlap the first price for a time is kept.
+func (d *marketDocument) points(start, end time.Time) ([]pricePoint, error) {
+	prices := make(map[time.Time]float64)
+	for _, series := range d.TimeSeries {
+		if series.Currency != "" && series.Currency != Currency {
+			return nil, fmt.Errorf("unexpected currency %s", series.Currency)
+		}
+		if series.Unit != "" && series.Unit != "MWH" {
+			return nil, fmt.Errorf("unexpected price unit %s", series.Unit)
+		}
+
+		for _, period := range series.Periods {
+			periodStart, err := time.Parse(intervalLayout, period.Start)
+			if err != nil {
+				return nil, fmt.Errorf("invalid period start %q: %w", period.Start, err)
+			}
+			periodEnd, err := time.Parse(intervalLayout, period.End)
+			if err != nil {
+				return nil, fmt.Errorf("invalid period end %q: %w", period.End, err)
+			}
+			resolution, err := parseResolution(period.Resolution)
+			if err != nil {
+				return nil, err
+			}
+
+			byPosition := make(map[int]float64, len(period.Points))
+			for _, point := range period.Points {
+				byPosition[point.Position] = point.Price
+			}
+
+			count := int(periodEnd.Sub(periodStart) / resolution)
+			var price float64
+			known := false
+			for position := 1; position <= count; position++ {
+				if p, ok := byPosition[position]; ok {
+					price, known = p, true
+				} else if series.CurveType != curveTypeFilled {
+					continue
+				}
+				if !known {
+					continue
+				}
+				timestamp := periodStart.Add(time.Duration(position-1) * resolution)
+				if timestamp.Before(start) || !timestamp.Before(end) {
+					continue
+				}
+				if _, ok := prices[timestamp]; !ok {
+					prices[timestamp] = price
+				}
+			}
+		}
+	}
+
+	points := make([]pricePoint, 0, len(prices))
+	for timestamp, price := range prices {
+		points = append(points, pricePoint{timestamp: timestamp, price: price})
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].timestamp.Before(points[j].timestamp) })
+	return points, nil
+}
+
+// parseResolution parses the ISO 8601 durations ENTSO-E uses, such as PT15M and PT60M
+func parseResolution(value string) (time.Duration, error) {
+	if rest, ok := strings.CutPrefix(value, "PT"); ok && len(rest) > 1 {
+		n, err := strconv.Atoi(rest[:len(rest)-1])
+		if err == nil && n > 0 {
+			switch rest[len(rest)-1] {
+			case 'M':
+				return time.Duration(n) * time.Minute, nil
+			case 'H':
+				return time.Duration(n) * time.Hour, nil
+			}
+		}
+	}
+	return 0, fmt.Errorf("unsupported resolution %q", value)
+}
+
+func isAcknowledgement(body []byte) bool {
+	var root struct {
+		XMLName xml.Name
+	}
+	return xml.Unmarshal(body, &root) == nil && root.XMLName.Local == "Acknowledgement_MarketDocument"
+}
+
+func acknowledgementReason(body []byte) string {
+	var ack struct {
+		Reasons []string `xml:"Reason>text"`
+	}
+	if err := xml.Unmarshal(body, &ack); err != nil || len(ack.Reasons) == 0 {
+		return "no reason given"
+	}
+	return strings.Join(ack.Reasons, "; ")
+}
@@ -0,0 +1,158 @@
+package entsoe
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	areaSE3 = "10Y1001A1001A46L"
+	areaSE4 = "10Y1001A1001A47J"
+)
+
+// pricesDocument returns a day-ahead price document for the Stockholm day starting at
+// 2025-03-20T23:00Z. Position 2 is left out, as A03 curves do for repeated prices.
+const pricesDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<Publication_MarketDocument xmlns="urn:iec62325.351:tc57wg16:451-3:publicationdocument:7:3">
+	<TimeSeries>
+		<currency_Unit.name>EUR</currency_Unit.name>
+		<price_Measure_Unit.name>MWH</price_Measure_Unit.name>
+		<curveType>A03</curveType>
+		<Period>
+			<timeInterval>
+				<start>2025-03-20T23:00Z</start>
+				<end>2025-03-21T02:00Z</end>
+			</timeInterval>
+			<resolution>PT60M</resolution>
+			<Point><position>1</position><price.amount>100</price.amount></Point>
+			<Point><position>3</position><price.amount>300</price.amount></Point>
+		</Period>
+	</TimeSeries>
+</Publication_MarketDocument>`
+
+const noDataDocument = `<?xml version="1.0" encoding="UTF-8"?>
+<Acknowledgement_MarketDocument xmlns="urn:iec62325.351:tc57wg16:451-1:acknowledgementdocument:7:0">
+	<Reason>
+		<code>999</code>
+		<text>No matching data found</text>
+	</Reason>
+</Acknowledgement_MarketDocument>`
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	spotPrices := memory.NewSpotPriceRepository(store)
+	zones := memory.NewZoneRepository(store)
+	areas := memory.NewEntsoeAreaRepository(store)
+
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if query.Get("securityToken") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		requests = append(requests, fmt.Sprintf("%s %s-%s", query.Get("in_Domain"), query.Get("periodStart"), query.Get("periodEnd")))
+		if query.Get("in_Domain") == areaSE4 {
+			fmt.Fprint(w, noDataDocument)
+			return
+		}
+		fmt.Fprint(w, pricesDocument)
+	}))
+	defer server.Close()
+
+	token := "secret"
+	p := NewProvider(memory.NewSpotPriceSourceRepository(store), areas, zones, memory.NewCurrencyRepository(store),
+		provider.Config{Enabled: true}, func() string { return token })
+	p.baseURL = server.URL
+	p.delay = 0
+
+	se3, err := zones.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	se4, err := zones.GetByName(ctx, "SE4")
+	require.NoError(t, err)
+	require.NoError(t, areas.Upsert(ctx, &models.EntsoeArea{ZoneID: se3.ID, AreaCode: areaSE3}))
+
+	day := time.Date(2025, 3, 21, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2025, 3, 20, 23, 0, 0, 0, time.UTC)
+
+	t.Run("Supports Mapped Zones Only", func(t *testing.T) {
+		assert.True(t, p.SupportsZone("SE3"))
+		assert.False(t, p.SupportsZone("SE1"))
+	})
+
+	t.Run("Stores Prices", func(t *testing.T) {
+		require.NoError(t, p.RunWithOptions(ctx, provider.RunOptions{Date: day, Zone: "SE3", Currency: "EUR"}))
+		assert.Equal(t, []string{areaSE3 + " 202503202300-202503212300"}, requests)
+
+		stored, err := spotPrices.List(ctx, repository.SpotPriceFilter{ZoneID: &se3.ID})
+		require.NoError(t, err)
+		prices := make(map[time.Time]float64)
+		for _, sp := range stored {
+			prices[sp.Timestamp.UTC()] = sp.Price
+		}
+		assert.Equal(t, map[time.Time]float64{
+			start:                    10,
+			start.Add(time.Hour):     10,
+			start.Add(2 * time.Hour): 30,
+		}, prices)
+	})
+
+	t.Run("Rejects Unmapped Zone And Other Currencies", func(t *testing.T) {
+		assert.ErrorContains(t, p.RunWithOptions(ctx, provider.RunOptions{Date: day, Zone: "SE1", Currency: "EUR"}), "unsupported zone")
+		assert.ErrorContains(t, p.RunWithOptions(ctx, provider.RunOptions{Date: day, Zone: "SE3", Currency: "SEK"}), "unsupported currency")
+	})
+
+	t.Run("Reports Acknowledgement Reason", func(t *testing.T) {
+		require.NoError(t, areas.Upsert(ctx, &models.EntsoeArea{ZoneID: se4.ID, AreaCode: areaSE4}))
+		defer areas.Delete(ctx, se4.ID)
+
+		err := p.RunWithOptions(ctx, provider.RunOptions{Date: day, Zone: "SE4", Currency: "EUR"})
+		assert.ErrorContains(t, err, "No matching data found")
+	})
+
+	t.Run("Requires Token", func(t *testing.T) {
+		token = ""
+		defer func() { token = "secret" }()
+
+		err := p.RunWithOptions(ctx, provider.RunOptions{Date: day, Zone: "SE3", Currency: "EUR"})
+		assert.ErrorIs(t, err, ErrNoToken)
+	})
+
+	t.Run("Invalid Token Is Not Leaked", func(t *testing.T) {
+		token = "wrong"
+		defer func() { token = "secret" }()
+
+		err := p.RunWithOptions(ctx, provider.RunOptions{Date: day, Zone: "SE3", Currency: "EUR"})
+		require.ErrorContains(t, err, "rejected the API token")
+		assert.NotContains(t, err.Error(), "wrong")
+	})
+}
+
+func TestParseResolution(t *testing.T) {
+	for value, want := range map[string]time.Duration{
+		"PT15M": 15 * time.Minute,
+		"PT60M": time.Hour,
+		"PT1H":  time.Hour,
+	} {
+		got, err := parseResolution(value)
+		require.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	for _, value := range []string{"", "P1D", "PT0M", "PTM"} {
+		_, err := parseResolution(value)
+		assert.Error(t, err, value)
+	}
+}
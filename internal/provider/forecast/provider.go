@@ -0,0 +1,262 @@
+// Package forecast fetches spot price forecasts from an HTTP feed
+package forecast
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+)
+
+const (
+	// ProviderName is the unique identifier for the forecast provider
+	ProviderName = "forecast"
+
+	// optionURL is the option holding the URL of the feed
+	optionURL = "url"
+	// optionSource is the option naming the source forecasts are stored as, the provider
+	// name when unset
+	optionSource = "source"
+)
+
+// ErrNoURL is returned when forecasts are fetched without a feed URL configured
+var ErrNoURL = errors.New("forecast feed URL is not configured, set providers.forecast.options.url")
+
+// Feed is the response of a forecast feed. It is requested with the zone and currency as
+// query parameters, and for manual runs and backfills the date in YYYY-MM-DD too.
+type Feed struct {
+	// IssuedAt is when the forecast was made, the time of the request when left out
+	IssuedAt time.Time `json:"issued_at"`
+	Prices   []Price   `json:"prices"`
+}
+
+// Price is a forecast price in a feed, in the unit spot prices are stored in: hundredths
+// of the currency per kWh
+type Price struct {
+	Timestamp time.Time `json:"timestamp"`
+	Price     float64   `json:"price"`
+}
+
+// DefaultConfig returns the default configuration for the forecast provider. Zones and
+// the feed URL have to be configured.
+func DefaultConfig() provider.Config {
+	return provider.Config{
+		Schedule:            "0 */6 * * *", // Run every six hours, forecasts change more often than prices
+		SupportedCurrencies: []string{"EUR"},
+	}
+}
+
+// Provider implements the provider.Provider interface for forecast feeds
+type Provider struct {
+	provider.BaseProvider
+	forecasts  repository.PriceForecastRepository
+	zones      repository.ZoneRepository
+	currencies repository.CurrencyRepository
+	client     *http.Client
+	now        func() time.Time
+}
+
+// NewProvider creates a new forecast provider storing forecasts through the repositories
+func NewProvider(
+	forecasts repository.PriceForecastRepository,
+	zones repository.ZoneRepository,
+	currencies repository.CurrencyRepository,
+	config provider.Config,
+) *Provider {
+	if len(config.SupportedCurrencies) == 0 {
+		config.SupportedCurrencies = DefaultConfig().SupportedCurrencies
+	}
+	if config.Schedule == "" {
+		config.Schedule = DefaultConfig().Schedule
+	}
+
+	return &Provider{
+		BaseProvider: provider.NewBaseProvider(nil, config),
+		forecasts:    forecasts,
+		zones:        zones,
+		currencies:   currencies,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		now:          time.Now,
+	}
+}
+
+// Factory creates the forecast provider for the provider registry
+func Factory(deps provider.Dependencies, config provider.Config) (provider.Provider, error) {
+	return NewProvider(deps.Forecasts, deps.Zones, deps.Currencies, config), nil
+}
+
+// Name returns the provider's unique identifier
+func (p *Provider) Name() string {
+	return ProviderName
+}
+
+// source returns the name forecasts are stored as
+func (p *Provider) source() string {
+	if source := p.GetConfig().Options[optionSource]; source != "" {
+		return source
+	}
+	return ProviderName
+}
+
+// Check verifies that the feed can be reached. Any HTTP response counts, since feeds may
+// reject requests without a zone.
+func (p *Provider) Check(ctx context.Context) error {
+	feedURL := p.GetConfig().Options[optionURL]
+	if feedURL == "" {
+		return ErrNoURL
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, feedURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", feedURL, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// fetchFeed fetches the forecast of a zone and currency, for a date unless it is zero
+func (p *Provider) fetchFeed(ctx context.Context, date time.Time, zone, currency string) (*Feed, error) {
+	feedURL := p.GetConfig().Options[optionURL]
+	if feedURL == "" {
+		return nil, ErrNoURL
+	}
+	reqURL, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid feed URL: %w", err)
+	}
+	params := reqURL.Query()
+	params.Set("zone", zone)
+	params.Set("currency", currency)
+	if !date.IsZero() {
+		params.Set("date", date.Format("2006-01-02"))
+	}
+	reqURL.RawQuery = params.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	requested := p.now()
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status code: %d", resp.StatusCode)
+	}
+
+	var feed Feed
+	if err := json.NewDecoder(resp.Body).Decode(&feed); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if feed.IssuedAt.IsZero() {
+		feed.IssuedAt = requested
+	}
+	return &feed, nil
+}
+
+// storeForecasts stores the forecasts of a feed for a zone and currency. The horizon is
+// counted in whole hours from when the forecast was issued.
+func (p *Provider) storeForecasts(ctx context.Context, feed *Feed, zoneName, currencyCode string) error {
+	zone, err := p.zones.GetByName(ctx, zoneName)
+	if err != nil {
+		return fmt.Errorf("failed to get zone %s: %w", zoneName, err)
+	}
+	currency, err := p.currencies.GetByName(ctx, currencyCode)
+	if err != nil {
+		return fmt.Errorf("failed to get currency %s: %w", currencyCode, err)
+	}
+
+	source := p.source()
+	forecasts := make([]models.PriceForecast, 0, len(feed.Prices))
+	for _, price := range feed.Prices {
+		forecasts = append(forecasts, models.PriceForecast{
+			Timestamp:    price.Timestamp.UTC(),
+			ZoneID:       zone.ID,
+			CurrencyID:   currency.ID,
+			Source:       source,
+			Price:        price.Price,
+			HorizonHours: max(int(price.Timestamp.Sub(feed.IssuedAt).Hours()), 0),
+		})
+	}
+
+	if err := provider.RecordForecasts(ctx, p.forecasts, zoneName, currencyCode, forecasts); err != nil {
+		return fmt.Errorf("failed to store forecasts: %w", err)
+	}
+	return nil
+}
+
+// Run fetches and stores the current forecasts for all supported zones and currencies
+func (p *Provider) Run(ctx context.Context) error {
+	return p.RunZones(ctx, p.GetConfig().SupportedZones)
+}
+
+// RunZones fetches and stores the current forecasts of the given zones in all supported
+// currencies. It keeps going when a zone fails and returns the errors together.
+func (p *Provider) RunZones(ctx context.Context, zones []string) error {
+	var errs []error
+	for _, zone := range zones {
+		for _, currency := range p.GetConfig().SupportedCurrencies {
+			if err := p.run(ctx, time.Time{}, zone, currency); err != nil {
+				if ctx.Err() != nil {
+					return ctx.Err()
+				}
+				errs = append(errs, fmt.Errorf("%s/%s: %w", zone, currency, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RunWithOptions fetches and stores the forecast of a day for a zone and currency
+func (p *Provider) RunWithOptions(ctx context.Context, opts provider.RunOptions) error {
+	if !p.SupportsZone(opts.Zone) {
+		return fmt.Errorf("unsupported zone: %s", opts.Zone)
+	}
+	if !p.SupportsCurrency(opts.Currency) {
+		return fmt.Errorf("unsupported currency: %s", opts.Currency)
+	}
+	return p.run(ctx, opts.Date, opts.Zone, opts.Currency)
+}
+
+// Fetch fetches and stores the forecasts of a zone for every day of dates in all
+// supported currencies. It keeps going when a day fails and returns the errors together.
+func (p *Provider) Fetch(ctx context.Context, zone string, dates provider.DateRange) error {
+	if !p.SupportsZone(zone) {
+		return fmt.Errorf("unsupported zone: %s", zone)
+	}
+
+	var errs []error
+	for _, date := range dates.Days() {
+		for _, currency := range p.GetConfig().SupportedCurrencies {
+			err := p.run(ctx, date, zone, currency)
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s/%s on %s: %w", zone, currency, date.Format("2006-01-02"), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// run fetches and stores the forecast of a zone and currency, for a date unless it is zero
+func (p *Provider) run(ctx context.Context, date time.Time, zone, currency string) error {
+	feed, err := p.fetchFeed(ctx, date, zone, currency)
+	if err != nil {
+		return fmt.Errorf("failed to fetch forecasts: %w", err)
+	}
+	return p.storeForecasts(ctx, feed, zone, currency)
+}
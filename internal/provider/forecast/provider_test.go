@@ -0,0 +1,103 @@
+package forecast
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	forecasts := memory.NewPriceForecastRepository(store)
+	zones := memory.NewZoneRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+
+	issued := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	start := time.Date(2025, 3, 21, 23, 0, 0, 0, time.UTC)
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zone := r.URL.Query().Get("zone")
+		requests = append(requests, zone+" "+r.URL.Query().Get("currency")+" "+r.URL.Query().Get("date"))
+		if zone == "SE4" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		feed := Feed{IssuedAt: issued}
+		for i := 0; i < 3; i++ {
+			feed.Prices = append(feed.Prices, Price{Timestamp: start.Add(time.Duration(i) * time.Hour), Price: float64(10 * (i + 1))})
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(feed))
+	}))
+	defer server.Close()
+
+	p := NewProvider(forecasts, zones, currencies, provider.Config{
+		Enabled:        true,
+		SupportedZones: []string{"SE3", "SE4"},
+		Options:        map[string]string{"url": server.URL + "?key=secret", "source": "model"},
+	})
+	se3, err := zones.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	eur, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	list := func() []float64 {
+		stored, err := forecasts.List(ctx, repository.PriceForecastFilter{
+			ZoneID: se3.ID, CurrencyID: eur.ID, StartTime: start, EndTime: start.Add(24 * time.Hour),
+		})
+		require.NoError(t, err)
+		var prices []float64
+		for _, f := range stored {
+			assert.Equal(t, "model", f.Source)
+			prices = append(prices, f.Price)
+		}
+		return prices
+	}
+
+	t.Run("Dry Run Stores Nothing", func(t *testing.T) {
+		m := provider.NewManager(nil)
+		m.RegisterProvider(p)
+		batches, err := m.DryRun(ctx, ProviderName, "SE3", provider.DateRange{Start: start, End: start})
+		require.NoError(t, err)
+		require.Len(t, batches, 1)
+		assert.Len(t, batches[0].Forecasts, 3)
+		assert.Empty(t, list())
+	})
+
+	t.Run("Stores Forecasts Per Zone", func(t *testing.T) {
+		requests = nil
+		err := p.Run(ctx)
+		require.ErrorContains(t, err, "SE4/EUR")
+		assert.Equal(t, []string{"SE3 EUR ", "SE4 EUR "}, requests)
+		assert.Equal(t, []float64{10, 20, 30}, list())
+
+		stored, err := forecasts.List(ctx, repository.PriceForecastFilter{
+			ZoneID: se3.ID, CurrencyID: eur.ID, StartTime: start, EndTime: start.Add(time.Hour),
+		})
+		require.NoError(t, err)
+		require.Len(t, stored, 1)
+		assert.Equal(t, 35, stored[0].HorizonHours)
+	})
+
+	t.Run("Fetches Each Day Of Range", func(t *testing.T) {
+		requests = nil
+		day := time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, p.Fetch(ctx, "SE3", provider.DateRange{Start: day, End: day.AddDate(0, 0, 1)}))
+		assert.Equal(t, []string{"SE3 EUR 2025-03-21", "SE3 EUR 2025-03-22"}, requests)
+		assert.ErrorContains(t, p.Fetch(ctx, "XX", provider.DateRange{Start: day, End: day}), "unsupported zone")
+	})
+
+	t.Run("No URL", func(t *testing.T) {
+		p := NewProvider(forecasts, zones, currencies, provider.Config{Enabled: true, SupportedZones: []string{"SE3"}})
+		assert.ErrorIs(t, p.Run(ctx), ErrNoURL)
+		assert.ErrorIs(t, p.Check(ctx), ErrNoURL)
+	})
+}
@@ -2,13 +2,17 @@ package nordpool
 
 import (
 	"context"
-	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"net/url"
 	"time"
+	"wattwatch/internal/models"
 	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
 )
 
 const (
@@ -54,11 +58,22 @@ func DefaultConfig() provider.Config {
 // Provider implements the provider.Provider interface for Nordpool
 type Provider struct {
 	provider.BaseProvider
-	client *http.Client
+	spotPrices repository.SpotPriceSourceRepository
+	zones      repository.ZoneRepository
+	currencies repository.CurrencyRepository
+	client     *http.Client
+	baseURL    string
+	// delay is waited before each API call to stay within the API's rate limits
+	delay time.Duration
 }
 
-// NewProvider creates a new Nordpool provider
-func NewProvider(db *sql.DB, config provider.Config) *Provider {
+// NewProvider creates a new Nordpool provider storing prices through the repositories
+func NewProvider(
+	spotPrices repository.SpotPriceSourceRepository,
+	zones repository.ZoneRepository,
+	currencies repository.CurrencyRepository,
+	config provider.Config,
+) *Provider {
 	// Merge with default config if needed
 	if len(config.SupportedZones) == 0 {
 		config.SupportedZones = DefaultConfig().SupportedZones
@@ -71,16 +86,41 @@ func NewProvider(db *sql.DB, config provider.Config) *Provider {
 	}
 
 	return &Provider{
-		BaseProvider: provider.NewBaseProvider(db, config),
+		BaseProvider: provider.NewBaseProvider(nil, config),
+		spotPrices:   spotPrices,
+		zones:        zones,
+		currencies:   currencies,
 		client:       &http.Client{Timeout: 10 * time.Second},
+		baseURL:      BaseURL,
+		delay:        time.Second,
 	}
 }
 
+// Factory creates the Nordpool provider for the provider registry
+func Factory(deps provider.Dependencies, config provider.Config) (provider.Provider, error) {
+	return NewProvider(deps.SpotPrices, deps.Zones, deps.Currencies, config), nil
+}
+
 // Name returns the provider's unique identifier
 func (p *Provider) Name() string {
 	return ProviderName
 }
 
+// Check verifies that the Nordpool API can be reached. Any HTTP response counts, since the
+// API rejects requests without query parameters.
+func (p *Provider) Check(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodHead, p.baseURL, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach %s: %w", p.baseURL, err)
+	}
+	resp.Body.Close()
+	return nil
+}
+
 // parsePrice converts a price by dividing by 10
 func (p *Provider) parsePrice(price float64) float64 {
 	return price / 10
@@ -96,7 +136,7 @@ func (p *Provider) fetchPrices(ctx context.Context, date time.Time, zone, curren
 	params.Add("date", date.Format("2006-01-02"))
 
 	// Build request URL
-	reqURL := fmt.Sprintf("%s?%s", BaseURL, params.Encode())
+	reqURL := fmt.Sprintf("%s?%s", p.baseURL, params.Encode())
 
 	// Create request
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, reqURL, nil)
@@ -125,125 +165,95 @@ func (p *Provider) fetchPrices(ctx context.Context, date time.Time, zone, curren
 	return response.MultiAreaEntries, nil
 }
 
-// getZoneID fetches the ID for a given zone name from the database
-func (p *Provider) getZoneID(ctx context.Context, zoneName string) (string, error) {
-	var id string
-	err := p.BaseProvider.GetDB().QueryRowContext(ctx,
-		"SELECT id FROM zones WHERE name = $1",
-		zoneName,
-	).Scan(&id)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch zone ID for %s: %w", zoneName, err)
-	}
-	return id, nil
-}
-
-// getCurrencyID fetches the ID for a given currency code from the database
-func (p *Provider) getCurrencyID(ctx context.Context, currencyCode string) (string, error) {
-	var id string
-	err := p.BaseProvider.GetDB().QueryRowContext(ctx,
-		"SELECT id FROM currencies WHERE name = $1",
-		currencyCode,
-	).Scan(&id)
-	if err != nil {
-		return "", fmt.Errorf("failed to fetch currency ID for %s: %w", currencyCode, err)
+// toRequests converts the prices of a zone to spot price requests. Nord Pool reports
+// prices per MWh, they are stored per kWh in hundredths of the currency.
+func (p *Provider) toRequests(entries []MultiAreaEntry, zoneName string, zoneID, currencyID uuid.UUID) ([]models.CreateSpotPriceRequest, error) {
+	requests := make([]models.CreateSpotPriceRequest, 0, len(entries))
+	for _, entry := range entries {
+		price, ok := entry.EntryPerArea[zoneName]
+		if !ok {
+			return nil, fmt.Errorf("no price found for zone %s", zoneName)
+		}
+		requests = append(requests, models.CreateSpotPriceRequest{
+			Timestamp:  entry.DeliveryStart.UTC(),
+			ZoneID:     zoneID,
+			CurrencyID: currencyID,
+			Price:      p.parsePrice(price),
+		})
 	}
-	return id, nil
+	return requests, nil
 }
 
-// storePrices stores spot prices in the database
+// storePrices stores the spot prices of a zone in one batch. The price is kept per source
+// so conflicts with other providers show up, and resolved spot prices keep the value an
+// administrator chose.
 func (p *Provider) storePrices(ctx context.Context, entries []MultiAreaEntry, zoneName, currencyCode string) error {
-	// Get zone and currency IDs
-	zoneID, err := p.getZoneID(ctx, zoneName)
+	zone, err := p.zones.GetByName(ctx, zoneName)
 	if err != nil {
-		return fmt.Errorf("failed to get zone ID: %w", err)
+		return fmt.Errorf("failed to get zone %s: %w", zoneName, err)
 	}
-
-	currencyID, err := p.getCurrencyID(ctx, currencyCode)
+	currency, err := p.currencies.GetByName(ctx, currencyCode)
 	if err != nil {
-		return fmt.Errorf("failed to get currency ID: %w", err)
+		return fmt.Errorf("failed to get currency %s: %w", currencyCode, err)
 	}
 
-	// Start transaction
-	tx, err := p.BaseProvider.GetDB().BeginTx(ctx, nil)
-	if err != nil {
-		return fmt.Errorf("failed to start transaction: %w", err)
-	}
-	defer tx.Rollback()
-
-	// Prepare insert statement
-	stmt, err := tx.PrepareContext(ctx, `
-		WITH tz AS (
-			SELECT timezone FROM zones WHERE id = $2
-		)
-		INSERT INTO spot_prices (timestamp, zone_id, currency_id, price)
-		VALUES (
-			timezone(
-				(SELECT timezone FROM tz),
-				$1::timestamptz
-			),
-			$2, $3, $4
-		)
-		ON CONFLICT (timestamp, zone_id, currency_id) DO UPDATE
-		SET price = EXCLUDED.price
-		WHERE spot_prices.price != EXCLUDED.price
-	`)
+	requests, err := p.toRequests(entries, zoneName, zone.ID, currency.ID)
 	if err != nil {
-		return fmt.Errorf("failed to prepare statement: %w", err)
+		return err
 	}
-	defer stmt.Close()
 
-	// Insert prices
-	for _, entry := range entries {
-		// Get and parse price for the zone
-		price, ok := entry.EntryPerArea[zoneName]
-		if !ok {
-			return fmt.Errorf("no price found for zone %s", zoneName)
-		}
-
-		// Convert price (divide by 10)
-		price = p.parsePrice(price)
-
-		if _, err := stmt.ExecContext(ctx, entry.DeliveryStart, zoneID, currencyID, price); err != nil {
-			return fmt.Errorf("failed to insert price: %w", err)
+	spotPrices := make([]models.SpotPrice, len(requests))
+	for i, req := range requests {
+		spotPrices[i] = models.SpotPrice{
+			ID:         uuid.New(),
+			Timestamp:  req.Timestamp,
+			ZoneID:     req.ZoneID,
+			CurrencyID: req.CurrencyID,
+			Price:      req.Price,
 		}
 	}
 
-	// Commit transaction
-	if err := tx.Commit(); err != nil {
-		return fmt.Errorf("failed to commit transaction: %w", err)
+	if err := provider.Record(ctx, p.spotPrices, p.Name(), zoneName, currencyCode, spotPrices); err != nil {
+		return fmt.Errorf("failed to store prices: %w", err)
 	}
 
 	return nil
 }
 
-// Run executes the provider's data fetching and storing logic for all supported combinations
+// Run fetches and stores tomorrow's prices for all supported zones and currencies
 func (p *Provider) Run(ctx context.Context) error {
+	return p.RunZones(ctx, p.GetConfig().SupportedZones)
+}
+
+// RunZones fetches and stores tomorrow's prices of the given zones in all supported
+// currencies. It keeps going when a zone fails and returns the errors together.
+func (p *Provider) RunZones(ctx context.Context, zones []string) error {
 	// Use tomorrow's date for scheduled runs
 	tomorrow := time.Now().AddDate(0, 0, 1)
 
-	// Fetch and store prices for all zone/currency combinations
-	for _, zone := range p.GetConfig().SupportedZones {
+	var errs []error
+	for _, zone := range zones {
 		for _, currency := range p.GetConfig().SupportedCurrencies {
 			// Add delay between API calls
 			select {
 			case <-ctx.Done():
 				return ctx.Err()
-			case <-time.After(time.Second):
+			case <-time.After(p.delay):
 			}
 
 			entries, err := p.fetchPrices(ctx, tomorrow, zone, currency)
 			if err != nil {
-				return fmt.Errorf("failed to fetch prices for %s/%s: %w", zone, currency, err)
+				errs = append(errs, fmt.Errorf("failed to fetch prices for %s/%s: %w", zone, currency, err))
+				continue
 			}
 
 			if err := p.storePrices(ctx, entries, zone, currency); err != nil {
-				return fmt.Errorf("failed to store prices for %s/%s: %w", zone, currency, err)
+				errs = append(errs, fmt.Errorf("failed to store prices for %s/%s: %w", zone, currency, err))
 			}
 		}
 	}
 
-	return nil
+	return errors.Join(errs...)
 }
 
 // RunWithOptions executes the provider with specific options (for manual runs)
@@ -260,7 +270,7 @@ func (p *Provider) RunWithOptions(ctx context.Context, opts provider.RunOptions)
 	select {
 	case <-ctx.Done():
 		return ctx.Err()
-	case <-time.After(time.Second):
+	case <-time.After(p.delay):
 	}
 
 	// Fetch prices for the specified combination
@@ -276,3 +286,25 @@ func (p *Provider) RunWithOptions(ctx context.Context, opts provider.RunOptions)
 
 	return nil
 }
+
+// Fetch fetches and stores the prices of a zone for every day of dates in all supported
+// currencies. It keeps going when a day fails and returns the errors together.
+func (p *Provider) Fetch(ctx context.Context, zone string, dates provider.DateRange) error {
+	if !p.SupportsZone(zone) {
+		return fmt.Errorf("unsupported zone: %s", zone)
+	}
+
+	var errs []error
+	for _, date := range dates.Days() {
+		for _, currency := range p.GetConfig().SupportedCurrencies {
+			err := p.RunWithOptions(ctx, provider.RunOptions{Date: date, Zone: zone, Currency: currency})
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s/%s on %s: %w", zone, currency, date.Format("2006-01-02"), err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
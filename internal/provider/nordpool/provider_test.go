@@ -0,0 +1,99 @@
+package nordpool
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProvider(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	spotPrices := memory.NewSpotPriceRepository(store)
+	zones := memory.NewZoneRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+
+	start := time.Date(2025, 3, 20, 23, 0, 0, 0, time.UTC)
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		zone := r.URL.Query().Get("deliveryArea")
+		requests = append(requests, zone+" "+r.URL.Query().Get("currency"))
+		if zone == "SE4" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		response := Response{Currency: r.URL.Query().Get("currency")}
+		for i := 0; i < 3; i++ {
+			response.MultiAreaEntries = append(response.MultiAreaEntries, MultiAreaEntry{
+				DeliveryStart: start.Add(time.Duration(i) * time.Hour),
+				DeliveryEnd:   start.Add(time.Duration(i+1) * time.Hour),
+				EntryPerArea:  map[string]float64{zone: float64(100 * (i + 1))},
+			})
+		}
+		require.NoError(t, json.NewEncoder(w).Encode(response))
+	}))
+	defer server.Close()
+
+	p := NewProvider(memory.NewSpotPriceSourceRepository(store), zones, currencies, provider.Config{
+		Enabled:             true,
+		SupportedCurrencies: []string{"EUR"},
+	})
+	p.baseURL = server.URL
+	p.delay = 0
+
+	t.Run("Stores Prices Per Zone", func(t *testing.T) {
+		require.NoError(t, p.RunZones(ctx, []string{"SE3"}))
+		assert.Equal(t, []string{"SE3 EUR"}, requests)
+
+		zone, err := zones.GetByName(ctx, "SE3")
+		require.NoError(t, err)
+		stored, err := spotPrices.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID})
+		require.NoError(t, err)
+		require.Len(t, stored, 3)
+
+		prices := make(map[time.Time]float64)
+		for _, sp := range stored {
+			prices[sp.Timestamp.UTC()] = sp.Price
+		}
+		assert.Equal(t, map[time.Time]float64{
+			start:                    10,
+			start.Add(time.Hour):     20,
+			start.Add(2 * time.Hour): 30,
+		}, prices)
+	})
+
+	t.Run("Failing Zone Doesn't Stop Others", func(t *testing.T) {
+		requests = nil
+		err := p.RunZones(ctx, []string{"SE4", "SE1"})
+		require.ErrorContains(t, err, "SE4/EUR")
+		assert.Equal(t, []string{"SE4 EUR", "SE1 EUR"}, requests)
+
+		zone, err := zones.GetByName(ctx, "SE1")
+		require.NoError(t, err)
+		stored, err := spotPrices.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID})
+		require.NoError(t, err)
+		assert.Len(t, stored, 3)
+	})
+
+	t.Run("Fetches Each Day Of Range", func(t *testing.T) {
+		requests = nil
+		day := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, p.Fetch(ctx, "SE2", provider.DateRange{Start: day, End: day.AddDate(0, 0, 1)}))
+		assert.Equal(t, []string{"SE2 EUR", "SE2 EUR"}, requests)
+		assert.ErrorContains(t, p.Fetch(ctx, "XX", provider.DateRange{Start: day, End: day}), "unsupported zone")
+	})
+
+	t.Run("Unknown Zone", func(t *testing.T) {
+		err := p.RunZones(ctx, []string{"XX"})
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+}
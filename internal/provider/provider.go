@@ -6,7 +6,10 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
+	"wattwatch/internal/worker"
 
 	"github.com/robfig/cron/v3"
 )
@@ -21,6 +24,12 @@ type Config struct {
 	SupportedZones []string `json:"supported_zones"`
 	// SupportedCurrencies is a list of currency codes that this provider supports
 	SupportedCurrencies []string `json:"supported_currencies"`
+	// ZoneSchedules overrides Schedule for individual zones, for providers implementing
+	// ZoneRunner. Zones without an entry are fetched on Schedule.
+	ZoneSchedules map[string]string `json:"zone_schedules,omitempty"`
+	// Options are provider specific settings, set as providers.<name>.options.<key> in the
+	// config file
+	Options map[string]string `json:"options,omitempty"`
 }
 
 // RunOptions represents the options for a manual provider run
@@ -30,6 +39,21 @@ type RunOptions struct {
 	Currency string
 }
 
+// DateRange is the days from Start through End, both included
+type DateRange struct {
+	Start time.Time
+	End   time.Time
+}
+
+// Days returns the dates of the days in the range, at the time of day of Start
+func (r DateRange) Days() []time.Time {
+	var days []time.Time
+	for day := r.Start; !day.After(r.End); day = day.AddDate(0, 0, 1) {
+		days = append(days, day)
+	}
+	return days
+}
+
 // Provider is the interface that all data providers must implement
 type Provider interface {
 	// Name returns the unique name of the provider
@@ -38,17 +62,35 @@ type Provider interface {
 	Run(ctx context.Context) error
 	// RunWithOptions executes the provider with specific options (for manual runs)
 	RunWithOptions(ctx context.Context, opts RunOptions) error
+	// Fetch fetches and stores the prices of a zone for every day of dates, in all
+	// supported currencies
+	Fetch(ctx context.Context, zone string, dates DateRange) error
 	// GetConfig returns the provider's configuration
 	GetConfig() Config
 	// SupportsZone checks if the provider supports a given zone
 	SupportsZone(zoneName string) bool
 	// SupportsCurrency checks if the provider supports a given currency
 	SupportsCurrency(currencyCode string) bool
+	// SetSchedule changes whether and when the provider runs on schedule
+	SetSchedule(enabled bool, schedule string, zoneSchedules map[string]string)
+}
+
+// ZoneRunner is implemented by providers that can fetch a subset of their zones, so that
+// zones can be scheduled separately
+type ZoneRunner interface {
+	RunZones(ctx context.Context, zones []string) error
+}
+
+// Checker is implemented by providers that fetch from a remote service, to verify at
+// startup that the service can be reached
+type Checker interface {
+	Check(ctx context.Context) error
 }
 
 // BaseProvider contains common functionality for all providers
 type BaseProvider struct {
 	db     *sql.DB
+	mu     *sync.RWMutex
 	config Config
 }
 
@@ -56,18 +98,30 @@ type BaseProvider struct {
 func NewBaseProvider(db *sql.DB, config Config) BaseProvider {
 	return BaseProvider{
 		db:     db,
+		mu:     &sync.RWMutex{},
 		config: config,
 	}
 }
 
 // GetConfig returns the provider's configuration
 func (p *BaseProvider) GetConfig() Config {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
 	return p.config
 }
 
+// SetSchedule changes whether and when the provider runs on schedule
+func (p *BaseProvider) SetSchedule(enabled bool, schedule string, zoneSchedules map[string]string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.config.Enabled = enabled
+	p.config.Schedule = schedule
+	p.config.ZoneSchedules = zoneSchedules
+}
+
 // SupportsZone checks if the provider supports a given zone
 func (p *BaseProvider) SupportsZone(zoneName string) bool {
-	for _, zone := range p.config.SupportedZones {
+	for _, zone := range p.GetConfig().SupportedZones {
 		if zone == zoneName {
 			return true
 		}
@@ -77,7 +131,7 @@ func (p *BaseProvider) SupportsZone(zoneName string) bool {
 
 // SupportsCurrency checks if the provider supports a given currency
 func (p *BaseProvider) SupportsCurrency(currencyCode string) bool {
-	for _, currency := range p.config.SupportedCurrencies {
+	for _, currency := range p.GetConfig().SupportedCurrencies {
 		if currency == currencyCode {
 			return true
 		}
@@ -90,24 +144,42 @@ func (p *BaseProvider) GetDB() *sql.DB {
 	return p.db
 }
 
+// Leader reports whether this instance should run scheduled jobs
+type Leader interface {
+	IsLeader() bool
+}
+
+// Scheduler runs jobs on cron schedules, implemented by scheduler.Scheduler
+type Scheduler interface {
+	Add(name, schedule string, fn func(ctx context.Context) error) error
+	Remove(name string)
+}
+
 // Manager handles the scheduling and execution of providers
 type Manager struct {
 	providers []Provider
 	db        *sql.DB
-	cron      *cron.Cron
+	jobs      *worker.Group
+	// leader is nil when every scheduled run executes, as in single instance deployments
+	leader Leader
+
+	mu sync.Mutex
+	// scheduler is set once Schedule has added the provider jobs
+	scheduler Scheduler
+	entries   map[string][]string
+
+	statusMu sync.Mutex
+	statuses map[string]*runStatus
 }
 
 // NewManager creates a new provider manager
 func NewManager(db *sql.DB) *Manager {
-	// Create a new cron scheduler with seconds disabled
-	c := cron.New(cron.WithParser(cron.NewParser(
-		cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow,
-	)))
-
 	return &Manager{
 		db:        db,
 		providers: make([]Provider, 0),
-		cron:      c,
+		jobs:      worker.NewGroup(),
+		entries:   make(map[string][]string),
+		statuses:  make(map[string]*runStatus),
 	}
 }
 
@@ -116,6 +188,23 @@ func (m *Manager) RegisterProvider(p Provider) {
 	m.providers = append(m.providers, p)
 }
 
+// SetLeader makes scheduled runs execute only while l reports this instance as leader, so
+// replicas sharing a database don't ingest the same prices. Manual runs are not affected.
+func (m *Manager) SetLeader(l Leader) {
+	m.leader = l
+}
+
+// IsLeader reports whether this instance runs scheduled jobs, which is always the case
+// without a leader set
+func (m *Manager) IsLeader() bool {
+	return m.leader == nil || m.leader.IsLeader()
+}
+
+// GetProviders returns all registered providers
+func (m *Manager) GetProviders() []Provider {
+	return m.providers
+}
+
 // GetProvider returns a provider by name
 func (m *Manager) GetProvider(name string) (Provider, bool) {
 	for _, p := range m.providers {
@@ -146,48 +235,202 @@ func (m *Manager) RunProvider(ctx context.Context, name string, opts *RunOptions
 		if !provider.SupportsCurrency(opts.Currency) {
 			return fmt.Errorf("provider %s does not support currency %s", name, opts.Currency)
 		}
-		return provider.RunWithOptions(ctx, *opts)
+		return m.track(ctx, name, func(ctx context.Context) error {
+			return provider.RunWithOptions(ctx, *opts)
+		})
 	}
 
-	return provider.Run(ctx)
+	return m.track(ctx, name, provider.Run)
+}
+
+// Fetch fetches the prices of a zone for a range of days with the named provider
+func (m *Manager) Fetch(ctx context.Context, name, zone string, dates DateRange) error {
+	provider, found := m.GetProvider(name)
+	if !found {
+		return ErrProviderNotFound
+	}
+	if !provider.GetConfig().Enabled {
+		return fmt.Errorf("provider %s is disabled", name)
+	}
+	if dates.End.Before(dates.Start) {
+		return fmt.Errorf("date range ends before it starts")
+	}
+	if !provider.SupportsZone(zone) {
+		return fmt.Errorf("provider %s does not support zone %s", name, zone)
+	}
+	return m.track(ctx, name, func(ctx context.Context) error {
+		return provider.Fetch(ctx, zone, dates)
+	})
 }
 
-// StartScheduler starts all enabled providers on their configured schedules
-func (m *Manager) StartScheduler(ctx context.Context) error {
+// Schedule adds a job to s for every enabled provider, or one per group of zones sharing
+// a schedule. Later calls to Reschedule replace the jobs of the provider.
+func (m *Manager) Schedule(s Scheduler) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.scheduler = s
 	for _, p := range m.providers {
-		config := p.GetConfig()
-		if !config.Enabled {
-			log.Printf("Provider %s is disabled, skipping scheduler", p.Name())
-			continue
+		if err := m.schedule(p); err != nil {
+			return err
 		}
+	}
+	return nil
+}
+
+// Reschedule changes whether and when a provider runs. When the provider jobs are scheduled
+// they are replaced, otherwise the change applies once Schedule is called. An empty
+// schedule keeps the current one, zoneSchedules replaces the current zone overrides.
+func (m *Manager) Reschedule(name string, enabled bool, schedule string, zoneSchedules map[string]string) error {
+	p, found := m.GetProvider(name)
+	if !found {
+		return ErrProviderNotFound
+	}
 
-		if config.Schedule == "" {
-			return fmt.Errorf("provider %s has no schedule configured", p.Name())
+	if schedule == "" {
+		schedule = p.GetConfig().Schedule
+	}
+	if enabled {
+		if _, err := cron.ParseStandard(schedule); err != nil {
+			return fmt.Errorf("invalid schedule for provider %s: %w", name, err)
+		}
+		for zone, zoneSchedule := range zoneSchedules {
+			if _, err := cron.ParseStandard(zoneSchedule); err != nil {
+				return fmt.Errorf("invalid schedule for zone %s of provider %s: %w", zone, name, err)
+			}
 		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
 
-		// Create a closure to capture the provider
+	p.SetSchedule(enabled, schedule, zoneSchedules)
+	if m.scheduler == nil {
+		return nil
+	}
+
+	m.unschedule(name)
+	return m.schedule(p)
+}
+
+// Refetch fetches the prices of a zone and currency for one day again in the background,
+// with the first enabled provider supporting both
+func (m *Manager) Refetch(zone, currency string, date time.Time) error {
+	for _, p := range m.providers {
+		if !p.GetConfig().Enabled || !p.SupportsZone(zone) || !p.SupportsCurrency(currency) {
+			continue
+		}
 		provider := p
-		_, err := m.cron.AddFunc(config.Schedule, func() {
-			log.Printf("Running scheduled execution of provider %s", provider.Name())
-			if err := provider.Run(ctx); err != nil {
-				log.Printf("Error running provider %s: %v", provider.Name(), err)
+		opts := RunOptions{Date: date, Zone: zone, Currency: currency}
+		name := fmt.Sprintf("%s refetch of %s %s %s", provider.Name(), zone, currency, date.Format("2006-01-02"))
+		return m.jobs.Go(name, func(ctx context.Context) {
+			err := m.track(ctx, provider.Name(), func(ctx context.Context) error {
+				return provider.RunWithOptions(ctx, opts)
+			})
+			if err != nil {
+				log.Printf("Error running %s: %v", name, err)
 			}
 		})
-		if err != nil {
-			return fmt.Errorf("failed to schedule provider %s: %w", p.Name(), err)
+	}
+	return ErrProviderNotFound
+}
+
+// Go runs fn as a tracked provider job, such as a backfill, so that Shutdown waits for it
+func (m *Manager) Go(name string, fn func(ctx context.Context)) error {
+	return m.jobs.Go(name, fn)
+}
+
+// Stopping is closed when Shutdown begins. Jobs that span many runs should stop
+// starting new ones once it is closed.
+func (m *Manager) Stopping() <-chan struct{} {
+	return m.jobs.Stopping()
+}
+
+// Shutdown stops scheduling new runs and waits for running jobs to finish. When ctx
+// expires first the jobs are cancelled and an error names the ones still running.
+func (m *Manager) Shutdown(ctx context.Context) error {
+	if err := m.jobs.Drain(ctx); err != nil {
+		return fmt.Errorf("provider jobs: %w", err)
+	}
+	return nil
+}
+
+// scheduleGroup is a set of zones of a provider fetched on the same schedule. A nil zones
+// runs the provider for all of its zones.
+type scheduleGroup struct {
+	schedule string
+	zones    []string
+}
+
+// scheduleGroups splits a provider's zones by schedule. Providers that can't run a subset
+// of their zones, or have no zone overrides, get a single group for all zones.
+func scheduleGroups(p Provider, config Config) []scheduleGroup {
+	if _, ok := p.(ZoneRunner); !ok || len(config.ZoneSchedules) == 0 {
+		return []scheduleGroup{{schedule: config.Schedule}}
+	}
+
+	var groups []scheduleGroup
+	index := make(map[string]int)
+	for _, zone := range config.SupportedZones {
+		schedule, ok := config.ZoneSchedules[zone]
+		if !ok || schedule == "" {
+			schedule = config.Schedule
+		}
+		i, ok := index[schedule]
+		if !ok {
+			i = len(groups)
+			index[schedule] = i
+			groups = append(groups, scheduleGroup{schedule: schedule})
 		}
+		groups[i].zones = append(groups[i].zones, zone)
+	}
+	return groups
+}
 
-		log.Printf("Scheduled provider %s with schedule %s", p.Name(), config.Schedule)
+// schedule adds the jobs of an enabled provider, m.mu must be held. Scheduled runs are
+// named provider:<name>, or provider:<name>:<zones> for a group of zones.
+func (m *Manager) schedule(p Provider) error {
+	config := p.GetConfig()
+	if !config.Enabled {
+		log.Printf("Provider %s is disabled, skipping scheduler", p.Name())
+		return nil
 	}
 
-	// Start the cron scheduler
-	m.cron.Start()
-	log.Println("Provider scheduler started")
+	if config.Schedule == "" {
+		return fmt.Errorf("provider %s has no schedule configured", p.Name())
+	}
 
-	// Wait for context cancellation
-	<-ctx.Done()
-	log.Println("Stopping provider scheduler...")
-	m.cron.Stop()
+	for _, group := range scheduleGroups(p, config) {
+		// Create a closure to capture the provider and its zones
+		provider := p
+		zones := group.zones
+		name := "provider:" + provider.Name()
+		if zones != nil {
+			name += ":" + strings.Join(zones, ",")
+		}
+		err := m.scheduler.Add(name, group.schedule, func(ctx context.Context) error {
+			return m.track(ctx, provider.Name(), func(ctx context.Context) error {
+				if zones != nil {
+					return provider.(ZoneRunner).RunZones(ctx, zones)
+				}
+				return provider.Run(ctx)
+			})
+		})
+		if err != nil {
+			m.unschedule(p.Name())
+			return fmt.Errorf("failed to schedule provider %s: %w", p.Name(), err)
+		}
+		m.entries[p.Name()] = append(m.entries[p.Name()], name)
 
+		log.Printf("Scheduled job %s with schedule %s", name, group.schedule)
+	}
 	return nil
 }
+
+// unschedule removes the jobs of a provider, m.mu must be held
+func (m *Manager) unschedule(name string) {
+	for _, job := range m.entries[name] {
+		m.scheduler.Remove(job)
+	}
+	delete(m.entries, name)
+}
@@ -0,0 +1,192 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type zoneProvider struct {
+	BaseProvider
+	fetch func(ctx context.Context, zone string, dates DateRange) error
+}
+
+func (p *zoneProvider) Name() string                                           { return "test" }
+func (p *zoneProvider) Run(ctx context.Context) error                          { return nil }
+func (p *zoneProvider) RunWithOptions(ctx context.Context, _ RunOptions) error { return nil }
+func (p *zoneProvider) RunZones(ctx context.Context, zones []string) error     { return nil }
+
+func (p *zoneProvider) Fetch(ctx context.Context, zone string, dates DateRange) error {
+	if p.fetch == nil {
+		return nil
+	}
+	return p.fetch(ctx, zone, dates)
+}
+
+// fakeScheduler records the jobs added as name to schedule
+type fakeScheduler map[string]string
+
+func (s fakeScheduler) Add(name, schedule string, fn func(ctx context.Context) error) error {
+	s[name] = schedule
+	return nil
+}
+
+func (s fakeScheduler) Remove(name string) {
+	delete(s, name)
+}
+
+func TestScheduleGroups(t *testing.T) {
+	config := Config{
+		Schedule:       "15 12 * * *",
+		SupportedZones: []string{"SE1", "SE2", "SE3", "SE4"},
+	}
+	p := &zoneProvider{BaseProvider: NewBaseProvider(nil, config)}
+
+	t.Run("Without Overrides", func(t *testing.T) {
+		assert.Equal(t, []scheduleGroup{{schedule: "15 12 * * *"}}, scheduleGroups(p, config))
+	})
+
+	t.Run("With Overrides", func(t *testing.T) {
+		config := config
+		config.ZoneSchedules = map[string]string{"SE2": "0 14 * * *", "SE4": "0 14 * * *", "NO1": "0 9 * * *"}
+		assert.Equal(t, []scheduleGroup{
+			{schedule: "15 12 * * *", zones: []string{"SE1", "SE3"}},
+			{schedule: "0 14 * * *", zones: []string{"SE2", "SE4"}},
+		}, scheduleGroups(p, config))
+	})
+
+	t.Run("Reschedule Validates Zone Schedules", func(t *testing.T) {
+		m := NewManager(nil)
+		m.RegisterProvider(p)
+		err := m.Reschedule("test", true, "", map[string]string{"SE1": "not a schedule"})
+		assert.ErrorContains(t, err, "SE1")
+		assert.NoError(t, m.Reschedule("test", true, "", map[string]string{"SE1": "0 14 * * *"}))
+		assert.Equal(t, "0 14 * * *", p.GetConfig().ZoneSchedules["SE1"])
+	})
+
+	t.Run("Reschedule Replaces Jobs", func(t *testing.T) {
+		p := &zoneProvider{BaseProvider: NewBaseProvider(nil, Config{Enabled: true, Schedule: "15 12 * * *", SupportedZones: []string{"SE1", "SE2"}})}
+		m := NewManager(nil)
+		m.RegisterProvider(p)
+		jobs := fakeScheduler{}
+		require.NoError(t, m.Schedule(jobs))
+		assert.Equal(t, fakeScheduler{"provider:test": "15 12 * * *"}, jobs)
+
+		require.NoError(t, m.Reschedule("test", true, "", map[string]string{"SE2": "0 14 * * *"}))
+		assert.Equal(t, fakeScheduler{
+			"provider:test:SE1": "15 12 * * *",
+			"provider:test:SE2": "0 14 * * *",
+		}, jobs)
+
+		require.NoError(t, m.Reschedule("test", false, "", nil))
+		assert.Empty(t, jobs)
+	})
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry()
+	r.Register("test", func(deps Dependencies, config Config) (Provider, error) {
+		return &zoneProvider{BaseProvider: NewBaseProvider(nil, config)}, nil
+	})
+	r.Register("misnamed", func(deps Dependencies, config Config) (Provider, error) {
+		return &zoneProvider{BaseProvider: NewBaseProvider(nil, config)}, nil
+	})
+	r.Register("broken", func(deps Dependencies, config Config) (Provider, error) {
+		return nil, errors.New("missing option")
+	})
+	assert.Equal(t, []string{"test", "misnamed", "broken"}, r.Names())
+
+	t.Run("Creates With Config", func(t *testing.T) {
+		p, err := r.New("test", Dependencies{}, Config{Enabled: true, Options: map[string]string{"region": "north"}})
+		require.NoError(t, err)
+		assert.True(t, p.GetConfig().Enabled)
+		assert.Equal(t, "north", p.GetConfig().Options["region"])
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, err := r.New("unknown", Dependencies{}, Config{})
+		assert.ErrorIs(t, err, ErrProviderNotFound)
+		_, err = r.New("misnamed", Dependencies{}, Config{})
+		assert.ErrorContains(t, err, "is named test")
+		_, err = r.New("broken", Dependencies{}, Config{})
+		assert.ErrorContains(t, err, "missing option")
+	})
+
+	t.Run("Duplicate Panics", func(t *testing.T) {
+		assert.Panics(t, func() { r.Register("test", nil) })
+	})
+
+	t.Run("Load", func(t *testing.T) {
+		r := NewRegistry()
+		r.Register("test", func(deps Dependencies, config Config) (Provider, error) {
+			return &zoneProvider{BaseProvider: NewBaseProvider(nil, config)}, nil
+		})
+		m := NewManager(nil)
+		require.NoError(t, m.Load(r, Dependencies{}, func(name string) (Config, bool) {
+			return Config{Enabled: true, Schedule: "0 * * * *"}, true
+		}))
+		p, ok := m.GetProvider("test")
+		require.True(t, ok)
+		assert.Equal(t, "0 * * * *", p.GetConfig().Schedule)
+	})
+}
+
+func TestManagerStatus(t *testing.T) {
+	ctx := context.Background()
+	var fetched []time.Time
+	p := &zoneProvider{
+		BaseProvider: NewBaseProvider(nil, Config{Enabled: true, Schedule: "15 12 * * *", SupportedZones: []string{"SE1", "SE2"}}),
+		fetch: func(ctx context.Context, zone string, dates DateRange) error {
+			fetched = append(fetched, dates.Days()...)
+			countIngested(ctx, 24*len(dates.Days()))
+			if zone == "SE2" {
+				return errors.New("upstream unavailable")
+			}
+			return nil
+		},
+	}
+	m := NewManager(nil)
+	m.RegisterProvider(p)
+
+	status, err := m.Status("test")
+	require.NoError(t, err)
+	assert.Equal(t, Status{Name: "test", Enabled: true, Schedule: "15 12 * * *"}, status)
+
+	day := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+	require.NoError(t, m.Fetch(ctx, "test", "SE1", DateRange{Start: day, End: day.AddDate(0, 0, 2)}))
+	assert.Equal(t, []time.Time{day, day.AddDate(0, 0, 1), day.AddDate(0, 0, 2)}, fetched)
+	status, err = m.Status("test")
+	require.NoError(t, err)
+	require.NotNil(t, status.LastRunAt)
+	assert.Empty(t, status.LastError)
+	assert.Nil(t, status.LastErrorAt)
+	assert.Equal(t, int64(72), status.LastRowsIngested)
+
+	assert.ErrorContains(t, m.Fetch(ctx, "test", "SE2", DateRange{Start: day, End: day}), "upstream unavailable")
+	status, err = m.Status("test")
+	require.NoError(t, err)
+	assert.Equal(t, "upstream unavailable", status.LastError)
+	assert.NotNil(t, status.LastErrorAt)
+	assert.Equal(t, int64(24), status.LastRowsIngested)
+	assert.Equal(t, int64(96), status.RowsIngested)
+	assert.Equal(t, int64(2), status.Runs)
+	assert.Equal(t, int64(1), status.Failures)
+
+	t.Run("Rejects Invalid Fetches", func(t *testing.T) {
+		assert.ErrorContains(t, m.Fetch(ctx, "test", "SE3", DateRange{Start: day, End: day}), "does not support zone")
+		assert.ErrorContains(t, m.Fetch(ctx, "test", "SE1", DateRange{Start: day, End: day.AddDate(0, 0, -1)}), "ends before")
+		assert.ErrorIs(t, m.Fetch(ctx, "other", "SE1", DateRange{Start: day, End: day}), ErrProviderNotFound)
+		_, err := m.Status("other")
+		assert.ErrorIs(t, err, ErrProviderNotFound)
+	})
+
+	t.Run("Disabled Provider Is Reported", func(t *testing.T) {
+		require.NoError(t, m.Reschedule("test", false, "", nil))
+		assert.False(t, m.Statuses()[0].Enabled)
+		assert.ErrorContains(t, m.Fetch(ctx, "test", "SE1", DateRange{Start: day, End: day}), "disabled")
+	})
+}
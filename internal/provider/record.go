@@ -0,0 +1,93 @@
+package provider
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"wattwatch/internal/metrics"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+// Batch is the spot prices or forecasts of one zone and currency a provider would have
+// stored
+type Batch struct {
+	Zone      string
+	Currency  string
+	Prices    []models.SpotPrice
+	Forecasts []models.PriceForecast
+}
+
+type dryRunKey struct{}
+
+// collector keeps the batches of a dry run
+type collector struct {
+	mu      sync.Mutex
+	batches []Batch
+}
+
+// Record stores the spot prices of a zone and currency as reported by source, and counts
+// them as ingested by the run ctx belongs to. During a dry run they are kept to be
+// reported instead of stored. Providers store their prices through it.
+func Record(ctx context.Context, repo repository.SpotPriceSourceRepository, source, zone, currency string, spotPrices []models.SpotPrice) error {
+	if c, ok := ctx.Value(dryRunKey{}).(*collector); ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.batches = append(c.batches, Batch{Zone: zone, Currency: currency, Prices: spotPrices})
+		return nil
+	}
+
+	if err := repo.Record(ctx, source, spotPrices); err != nil {
+		return err
+	}
+	metrics.SpotPricesIngested(source, zone, currency, len(spotPrices))
+	countIngested(ctx, len(spotPrices))
+	return nil
+}
+
+// RecordForecasts stores the forecasts of a zone and currency, kept apart from the spot
+// prices, and counts them as ingested by the run ctx belongs to. During a dry run they
+// are kept to be reported instead of stored. Providers of forecasts store them through it.
+func RecordForecasts(ctx context.Context, repo repository.PriceForecastRepository, zone, currency string, forecasts []models.PriceForecast) error {
+	if c, ok := ctx.Value(dryRunKey{}).(*collector); ok {
+		c.mu.Lock()
+		defer c.mu.Unlock()
+		c.batches = append(c.batches, Batch{Zone: zone, Currency: currency, Forecasts: forecasts})
+		return nil
+	}
+
+	if err := repo.Upsert(ctx, forecasts); err != nil {
+		return err
+	}
+	countIngested(ctx, len(forecasts))
+	return nil
+}
+
+// DryRun runs the named provider without storing anything and returns the spot prices and
+// forecasts it would have stored. Without a zone it runs like on schedule, otherwise it
+// fetches the zone for dates. Disabled providers can be dry run too, so their
+// configuration can be checked before they are enabled. Dry runs are left out of the
+// provider status.
+func (m *Manager) DryRun(ctx context.Context, name, zone string, dates DateRange) ([]Batch, error) {
+	p, found := m.GetProvider(name)
+	if !found {
+		return nil, ErrProviderNotFound
+	}
+
+	c := &collector{}
+	ctx = context.WithValue(ctx, dryRunKey{}, c)
+	var err error
+	if zone == "" {
+		err = p.Run(ctx)
+	} else if dates.End.Before(dates.Start) {
+		return nil, fmt.Errorf("date range ends before it starts")
+	} else if !p.SupportsZone(zone) {
+		return nil, fmt.Errorf("provider %s does not support zone %s", name, zone)
+	} else {
+		err = p.Fetch(ctx, zone, dates)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.batches, err
+}
@@ -0,0 +1,86 @@
+package provider
+
+import (
+	"fmt"
+	"sync"
+	"wattwatch/internal/repository"
+)
+
+// Dependencies are the repositories providers store prices through
+type Dependencies struct {
+	SpotPrices  repository.SpotPriceSourceRepository
+	Forecasts   repository.PriceForecastRepository
+	Zones       repository.ZoneRepository
+	Currencies  repository.CurrencyRepository
+	EntsoeAreas repository.EntsoeAreaRepository
+}
+
+// Factory creates a provider from its configuration block, providers.<name> in the
+// config file
+type Factory func(deps Dependencies, config Config) (Provider, error)
+
+// Registry holds the factories of the available providers in the order they were
+// registered, which is the order providers are tried in for refetches
+type Registry struct {
+	mu        sync.RWMutex
+	names     []string
+	factories map[string]Factory
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register makes a provider available under name. Registering the same name twice is
+// a programming error and panics.
+func (r *Registry) Register(name string, factory Factory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.factories[name]; ok {
+		panic(fmt.Sprintf("provider %s registered twice", name))
+	}
+	r.names = append(r.names, name)
+	r.factories[name] = factory
+}
+
+// Names returns the names of the registered providers in registration order
+func (r *Registry) Names() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return append([]string(nil), r.names...)
+}
+
+// New creates the named provider with its configuration
+func (r *Registry) New(name string, deps Dependencies, config Config) (Provider, error) {
+	r.mu.RLock()
+	factory, ok := r.factories[name]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, ErrProviderNotFound
+	}
+
+	p, err := factory(deps, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create provider %s: %w", name, err)
+	}
+	if p.Name() != name {
+		return nil, fmt.Errorf("provider registered as %s is named %s", name, p.Name())
+	}
+	return p, nil
+}
+
+// Load creates every registered provider with the configuration settings returns for it
+// and adds it to m. Providers without configuration get the zero Config, so they are
+// disabled unless their factory enables them.
+func (m *Manager) Load(r *Registry, deps Dependencies, settings func(name string) (Config, bool)) error {
+	for _, name := range r.Names() {
+		config, _ := settings(name)
+		p, err := r.New(name, deps, config)
+		if err != nil {
+			return err
+		}
+		m.RegisterProvider(p)
+	}
+	return nil
+}
@@ -0,0 +1,143 @@
+package provider
+
+import (
+	"context"
+	"sync/atomic"
+	"time"
+)
+
+// Status reports how the runs of a provider went since the process started
+type Status struct {
+	Name     string `json:"name"`
+	Enabled  bool   `json:"enabled"`
+	Schedule string `json:"schedule"`
+	// Running is true while a run of the provider is in progress
+	Running bool `json:"running"`
+	// LastRunAt is when the last finished run started, nil before the first one
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	// LastDuration is how long the last finished run took, in milliseconds
+	LastDuration int64 `json:"last_duration_ms"`
+	// LastError is the error of the last finished run, empty when it succeeded
+	LastError string `json:"last_error,omitempty"`
+	// LastErrorAt is when a run last failed, kept after later runs succeed
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	// LastRowsIngested are the spot prices stored by the last finished run
+	LastRowsIngested int64 `json:"last_rows_ingested"`
+	// RowsIngested are the spot prices stored by all runs
+	RowsIngested int64 `json:"rows_ingested"`
+	Runs         int64 `json:"runs"`
+	Failures     int64 `json:"failures"`
+}
+
+// runStatus is what the manager records about the runs of one provider
+type runStatus struct {
+	running     int
+	lastRunAt   time.Time
+	duration    time.Duration
+	lastErr     error
+	lastErrorAt time.Time
+	lastRows    int64
+	rows        int64
+	runs        int64
+	failures    int64
+}
+
+type ingestKey struct{}
+
+// countIngested counts n spot prices as stored by the run ctx belongs to, so the manager
+// can report the rows each run ingested
+func countIngested(ctx context.Context, n int) {
+	if counter, ok := ctx.Value(ingestKey{}).(*atomic.Int64); ok {
+		counter.Add(int64(n))
+	}
+}
+
+// track runs fn as a run of the named provider and records its outcome
+func (m *Manager) track(ctx context.Context, name string, fn func(ctx context.Context) error) error {
+	counter := &atomic.Int64{}
+	started := time.Now()
+
+	m.statusMu.Lock()
+	m.status(name).running++
+	m.statusMu.Unlock()
+
+	err := fn(context.WithValue(ctx, ingestKey{}, counter))
+
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	s := m.status(name)
+	s.running--
+	s.lastRunAt = started
+	s.duration = time.Since(started)
+	s.lastErr = err
+	s.lastRows = counter.Load()
+	s.rows += s.lastRows
+	s.runs++
+	if err != nil {
+		s.lastErrorAt = time.Now()
+		s.failures++
+	}
+	return err
+}
+
+// status returns the run status of the named provider, m.statusMu must be held
+func (m *Manager) status(name string) *runStatus {
+	s, ok := m.statuses[name]
+	if !ok {
+		s = &runStatus{}
+		m.statuses[name] = s
+	}
+	return s
+}
+
+// Status reports the configuration and runs of the named provider
+func (m *Manager) Status(name string) (Status, error) {
+	p, found := m.GetProvider(name)
+	if !found {
+		return Status{}, ErrProviderNotFound
+	}
+
+	config := p.GetConfig()
+	status := Status{
+		Name:     name,
+		Enabled:  config.Enabled,
+		Schedule: config.Schedule,
+	}
+
+	m.statusMu.Lock()
+	defer m.statusMu.Unlock()
+	s, ok := m.statuses[name]
+	if !ok {
+		return status, nil
+	}
+	status.Running = s.running > 0
+	if !s.lastRunAt.IsZero() {
+		lastRunAt := s.lastRunAt
+		status.LastRunAt = &lastRunAt
+		status.LastDuration = s.duration.Milliseconds()
+	}
+	if s.lastErr != nil {
+		status.LastError = s.lastErr.Error()
+	}
+	if !s.lastErrorAt.IsZero() {
+		lastErrorAt := s.lastErrorAt
+		status.LastErrorAt = &lastErrorAt
+	}
+	status.LastRowsIngested = s.lastRows
+	status.RowsIngested = s.rows
+	status.Runs = s.runs
+	status.Failures = s.failures
+	return status, nil
+}
+
+// Statuses reports every registered provider in registration order
+func (m *Manager) Statuses() []Status {
+	statuses := make([]Status, 0, len(m.providers))
+	for _, p := range m.providers {
+		status, err := m.Status(p.Name())
+		if err == nil {
+			statuses = append(statuses, status)
+		}
+	}
+	return statuses
+}
@@ -0,0 +1,235 @@
+// Package pubsub passes spot prices on to the clients streaming them as they are stored.
+// The hub lives in the API process, so clients only see the spot prices written by the
+// instance they are connected to.
+package pubsub
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+	"wattwatch/internal/metrics"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// followRetryDelay is how long Follow waits before subscribing again after being dropped
+// or turned away
+const followRetryDelay = 5 * time.Second
+
+// subscriptionBuffer is the number of updates a subscriber may fall behind by before it
+// is dropped
+const subscriptionBuffer = 16
+
+// ErrTooManySubscribers is returned by Subscribe when the hub is full
+var ErrTooManySubscribers = errors.New("too many subscribers")
+
+// Filter selects the spot prices a subscriber receives, nil fields match everything
+type Filter struct {
+	ZoneID     *uuid.UUID
+	CurrencyID *uuid.UUID
+}
+
+func (f Filter) matches(sp *models.SpotPrice) bool {
+	return (f.ZoneID == nil || *f.ZoneID == sp.ZoneID) &&
+		(f.CurrencyID == nil || *f.CurrencyID == sp.CurrencyID)
+}
+
+// Hub passes the spot prices published to it on to the matching subscribers. Spot prices
+// written through the repositories returned by SpotPrices and Sources are published.
+type Hub struct {
+	max  int
+	mu   sync.Mutex
+	subs map[*Subscription]struct{}
+}
+
+// NewHub creates a hub accepting at most max subscribers at once
+func NewHub(max int) *Hub {
+	return &Hub{
+		max:  max,
+		subs: make(map[*Subscription]struct{}),
+	}
+}
+
+// Subscription receives the spot prices matching its filter. Updates are never blocked
+// by a slow subscriber, one that falls too far behind is dropped instead.
+type Subscription struct {
+	// C receives the matching spot prices of each write. It is closed when the
+	// subscription is closed or dropped.
+	C       <-chan []models.SpotPrice
+	c       chan []models.SpotPrice
+	hub     *Hub
+	filter  Filter
+	dropped bool
+}
+
+// Subscribe adds a subscriber for the spot prices matching filter. The subscription
+// must be closed once it is no longer read.
+func (h *Hub) Subscribe(filter Filter) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if len(h.subs) >= h.max {
+		return nil, ErrTooManySubscribers
+	}
+	c := make(chan []models.SpotPrice, subscriptionBuffer)
+	s := &Subscription{C: c, c: c, hub: h, filter: filter}
+	h.subs[s] = struct{}{}
+	metrics.StreamSubscribers(len(h.subs))
+	return s, nil
+}
+
+// Close unsubscribes, it may be called more than once
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	s.hub.remove(s)
+}
+
+// Dropped reports whether the subscription was closed for falling behind
+func (s *Subscription) Dropped() bool {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+	return s.dropped
+}
+
+// remove unsubscribes s, the hub must be locked
+func (h *Hub) remove(s *Subscription) {
+	if _, ok := h.subs[s]; !ok {
+		return
+	}
+	delete(h.subs, s)
+	close(s.c)
+	metrics.StreamSubscribers(len(h.subs))
+}
+
+// Publish passes the spot prices on to the subscribers whose filter matches any of them
+func (h *Hub) Publish(spotPrices []models.SpotPrice) {
+	if len(spotPrices) == 0 {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.subs {
+		var matching []models.SpotPrice
+		for i := range spotPrices {
+			if s.filter.matches(&spotPrices[i]) {
+				matching = append(matching, spotPrices[i])
+			}
+		}
+		if len(matching) == 0 {
+			continue
+		}
+		select {
+		case s.c <- matching:
+		default:
+			s.dropped = true
+			h.remove(s)
+		}
+	}
+}
+
+// Follow calls fn with the spot prices matching filter until ctx is cancelled. It subscribes
+// again when dropped for falling behind or turned away by a full hub, spot prices published
+// in between are missed. name identifies the follower in logs.
+func (h *Hub) Follow(ctx context.Context, name string, filter Filter, fn func(ctx context.Context, spotPrices []models.SpotPrice)) {
+	for {
+		sub, err := h.Subscribe(filter)
+		if err != nil {
+			log.Printf("%s can't subscribe to spot prices: %v", name, err)
+		} else {
+			follow(ctx, sub, fn)
+			sub.Close()
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("%s fell behind, spot prices were missed", name)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(followRetryDelay):
+		}
+	}
+}
+
+// follow passes the spot prices of sub to fn until ctx is cancelled or sub is closed
+func follow(ctx context.Context, sub *Subscription, fn func(ctx context.Context, spotPrices []models.SpotPrice)) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case spotPrices, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			fn(ctx, spotPrices)
+		}
+	}
+}
+
+// SpotPrices wraps repo so that created and updated spot prices are published
+func (h *Hub) SpotPrices(repo repository.SpotPriceRepository) repository.SpotPriceRepository {
+	return &publishingSpotPriceRepository{SpotPriceRepository: repo, hub: h}
+}
+
+// Sources wraps repo so that recorded and resolved spot prices are published
+func (h *Hub) Sources(repo repository.SpotPriceSourceRepository) repository.SpotPriceSourceRepository {
+	return &publishingSourceRepository{SpotPriceSourceRepository: repo, hub: h}
+}
+
+type publishingSpotPriceRepository struct {
+	repository.SpotPriceRepository
+	hub *Hub
+}
+
+func (r *publishingSpotPriceRepository) Create(ctx context.Context, spotPrice *models.SpotPrice) error {
+	if err := r.SpotPriceRepository.Create(ctx, spotPrice); err != nil {
+		return err
+	}
+	r.hub.Publish([]models.SpotPrice{*spotPrice})
+	return nil
+}
+
+func (r *publishingSpotPriceRepository) CreateBatch(ctx context.Context, spotPrices []models.SpotPrice) error {
+	if err := r.SpotPriceRepository.CreateBatch(ctx, spotPrices); err != nil {
+		return err
+	}
+	r.hub.Publish(spotPrices)
+	return nil
+}
+
+func (r *publishingSpotPriceRepository) Update(ctx context.Context, spotPrice *models.SpotPrice) error {
+	if err := r.SpotPriceRepository.Update(ctx, spotPrice); err != nil {
+		return err
+	}
+	r.hub.Publish([]models.SpotPrice{*spotPrice})
+	return nil
+}
+
+type publishingSourceRepository struct {
+	repository.SpotPriceSourceRepository
+	hub *Hub
+}
+
+func (r *publishingSourceRepository) Record(ctx context.Context, source string, spotPrices []models.SpotPrice) error {
+	if err := r.SpotPriceSourceRepository.Record(ctx, source, spotPrices); err != nil {
+		return err
+	}
+	r.hub.Publish(spotPrices)
+	return nil
+}
+
+func (r *publishingSourceRepository) Resolve(ctx context.Context, timestamp time.Time, zoneID, currencyID uuid.UUID, source string) (*models.SpotPrice, error) {
+	spotPrice, err := r.SpotPriceSourceRepository.Resolve(ctx, timestamp, zoneID, currencyID, source)
+	if err != nil {
+		return nil, err
+	}
+	r.hub.Publish([]models.SpotPrice{*spotPrice})
+	return spotPrice, nil
+}
@@ -0,0 +1,105 @@
+package pubsub
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub(t *testing.T) {
+	zoneA, zoneB, currency := uuid.New(), uuid.New(), uuid.New()
+	price := func(zoneID uuid.UUID, value float64) models.SpotPrice {
+		return models.SpotPrice{Timestamp: time.Now(), ZoneID: zoneID, CurrencyID: currency, Price: value}
+	}
+
+	t.Run("Filters By Zone", func(t *testing.T) {
+		hub := NewHub(10)
+		all, err := hub.Subscribe(Filter{})
+		require.NoError(t, err)
+		defer all.Close()
+		onlyB, err := hub.Subscribe(Filter{ZoneID: &zoneB})
+		require.NoError(t, err)
+		defer onlyB.Close()
+
+		hub.Publish([]models.SpotPrice{price(zoneA, 1), price(zoneB, 2)})
+		hub.Publish([]models.SpotPrice{price(zoneA, 3)})
+
+		assert.Len(t, <-all.C, 2)
+		assert.Len(t, <-all.C, 1)
+		received := <-onlyB.C
+		require.Len(t, received, 1)
+		assert.Equal(t, 2.0, received[0].Price)
+		assert.Empty(t, onlyB.C)
+	})
+
+	t.Run("Limits Subscribers", func(t *testing.T) {
+		hub := NewHub(1)
+		sub, err := hub.Subscribe(Filter{})
+		require.NoError(t, err)
+		_, err = hub.Subscribe(Filter{})
+		assert.ErrorIs(t, err, ErrTooManySubscribers)
+
+		sub.Close()
+		sub.Close()
+		_, open := <-sub.C
+		assert.False(t, open)
+		assert.False(t, sub.Dropped())
+		_, err = hub.Subscribe(Filter{})
+		assert.NoError(t, err)
+	})
+
+	t.Run("Drops Slow Subscribers", func(t *testing.T) {
+		hub := NewHub(10)
+		sub, err := hub.Subscribe(Filter{})
+		require.NoError(t, err)
+		defer sub.Close()
+
+		for i := 0; i <= subscriptionBuffer; i++ {
+			hub.Publish([]models.SpotPrice{price(zoneA, float64(i))})
+		}
+		assert.True(t, sub.Dropped())
+		for range subscriptionBuffer {
+			<-sub.C
+		}
+		_, open := <-sub.C
+		assert.False(t, open)
+	})
+
+	t.Run("Publishes Stored Spot Prices", func(t *testing.T) {
+		ctx := context.Background()
+		store := memory.NewStore()
+		zone, err := memory.NewZoneRepository(store).GetByName(ctx, "SE3")
+		require.NoError(t, err)
+		eur, err := memory.NewCurrencyRepository(store).GetByName(ctx, "EUR")
+		require.NoError(t, err)
+
+		hub := NewHub(10)
+		sub, err := hub.Subscribe(Filter{})
+		require.NoError(t, err)
+		defer sub.Close()
+		spotPrices := hub.SpotPrices(memory.NewSpotPriceRepository(store))
+		sources := hub.Sources(memory.NewSpotPriceSourceRepository(store))
+
+		timestamp := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+		require.NoError(t, sources.Record(ctx, "test", []models.SpotPrice{{Timestamp: timestamp, ZoneID: zone.ID, CurrencyID: eur.ID, Price: 10}}))
+		recorded := <-sub.C
+		require.Len(t, recorded, 1)
+		assert.NotEqual(t, uuid.Nil, recorded[0].ID)
+
+		recorded[0].Price = 12
+		require.NoError(t, spotPrices.Update(ctx, &recorded[0]))
+		updated := <-sub.C
+		require.Len(t, updated, 1)
+		assert.Equal(t, 12.0, updated[0].Price)
+
+		// Failed writes publish nothing
+		assert.Error(t, spotPrices.Update(ctx, &models.SpotPrice{ID: uuid.New(), ZoneID: zone.ID, CurrencyID: eur.ID}))
+		assert.Empty(t, sub.C)
+	})
+}
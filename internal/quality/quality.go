@@ -0,0 +1,222 @@
+// Package quality looks for missing and duplicate spot prices
+package quality
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+)
+
+// Refetcher requests the prices of a zone and currency for one day from a provider again
+type Refetcher interface {
+	Refetch(zone, currency string, date time.Time) error
+}
+
+// Options selects the spot prices to check
+type Options struct {
+	// From and To are the first and last day checked. Only their dates are used, each zone
+	// is checked from midnight to midnight in its own timezone.
+	From time.Time
+	To   time.Time
+	// Zone and Currency limit the check to one zone or currency when set. Without a
+	// currency only the currencies a zone has prices in during the range are checked.
+	Zone     string
+	Currency string
+	// Refetch requests days with missing prices from a provider again
+	Refetch bool
+}
+
+// Checker compares the number of spot prices stored for each day with the length of the
+// day in the zone's timezone, so the 23 and 25 hour days of DST changes are not reported
+type Checker struct {
+	spotPrices repository.SpotPriceRepository
+	zones      repository.ZoneRepository
+	currencies repository.CurrencyRepository
+	resolution time.Duration
+	refetcher  Refetcher
+	// leader is nil when the scheduled check runs on every instance
+	leader provider.Leader
+}
+
+// NewChecker creates a checker expecting one spot price every resolution
+func NewChecker(
+	spotPrices repository.SpotPriceRepository,
+	zones repository.ZoneRepository,
+	currencies repository.CurrencyRepository,
+	resolution time.Duration,
+) *Checker {
+	return &Checker{
+		spotPrices: spotPrices,
+		zones:      zones,
+		currencies: currencies,
+		resolution: resolution,
+	}
+}
+
+// SetRefetcher sets where days with missing prices are requested again
+func (c *Checker) SetRefetcher(r Refetcher) {
+	c.refetcher = r
+}
+
+// SetLeader makes the scheduled check run only while l reports this instance as leader
+func (c *Checker) SetLeader(l provider.Leader) {
+	c.leader = l
+}
+
+// Run checks the last days every interval until ctx is cancelled and logs what it finds
+func (c *Checker) Run(ctx context.Context, interval time.Duration, days int, refetch bool) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if c.leader != nil && !c.leader.IsLeader() {
+				continue
+			}
+			now := time.Now()
+			issues, err := c.Check(ctx, Options{From: now.AddDate(0, 0, 1-days), To: now, Refetch: refetch})
+			if err != nil {
+				log.Printf("Failed to check spot price quality: %v", err)
+				continue
+			}
+			for _, issue := range issues {
+				log.Printf("Spot prices of %s in %s on %s: %d of %d expected, %d missing, %d duplicated",
+					issue.Zone, issue.Currency, issue.Date, issue.Count, issue.Expected, len(issue.Missing), len(issue.Duplicates))
+			}
+		}
+	}
+}
+
+// Check returns the days in the range whose spot prices don't match the zone's day length.
+// It returns repository.ErrNotFound when the zone or currency in opts doesn't exist.
+func (c *Checker) Check(ctx context.Context, opts Options) ([]models.DataQualityIssue, error) {
+	zones, err := c.selectZones(ctx, opts.Zone)
+	if err != nil {
+		return nil, err
+	}
+	currencies, err := c.selectCurrencies(ctx, opts.Currency)
+	if err != nil {
+		return nil, err
+	}
+
+	issues := make([]models.DataQualityIssue, 0)
+	for _, zone := range zones {
+		loc, err := time.LoadLocation(zone.Timezone)
+		if err != nil {
+			return nil, fmt.Errorf("zone %s: %w", zone.Name, err)
+		}
+		for _, currency := range currencies {
+			found, err := c.checkSeries(ctx, zone, currency, loc, opts)
+			if err != nil {
+				return nil, fmt.Errorf("zone %s in %s: %w", zone.Name, currency.Name, err)
+			}
+			issues = append(issues, found...)
+		}
+	}
+	return issues, nil
+}
+
+func (c *Checker) selectZones(ctx context.Context, name string) ([]models.Zone, error) {
+	if name == "" {
+		return c.zones.List(ctx, repository.ZoneFilter{OrderBy: "name"})
+	}
+	zone, err := c.zones.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return []models.Zone{*zone}, nil
+}
+
+func (c *Checker) selectCurrencies(ctx context.Context, name string) ([]models.Currency, error) {
+	if name == "" {
+		return c.currencies.List(ctx)
+	}
+	currency, err := c.currencies.GetByName(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	return []models.Currency{*currency}, nil
+}
+
+// checkSeries checks the days of one zone and currency
+func (c *Checker) checkSeries(ctx context.Context, zone models.Zone, currency models.Currency, loc *time.Location, opts Options) ([]models.DataQualityIssue, error) {
+	fromYear, fromMonth, fromDay := opts.From.Date()
+	toYear, toMonth, toDay := opts.To.Date()
+	start := time.Date(fromYear, fromMonth, fromDay, 0, 0, 0, 0, loc)
+	end := time.Date(toYear, toMonth, toDay+1, 0, 0, 0, 0, loc)
+
+	// Timestamps are read in ascending order and consumed day by day
+	var timestamps []time.Time
+	filter := repository.SpotPriceFilter{
+		ZoneID:     &zone.ID,
+		CurrencyID: &currency.ID,
+		StartTime:  &start,
+		EndTime:    &end,
+		OrderBy:    "timestamp",
+	}
+	err := c.spotPrices.Each(ctx, filter, func(sp *models.SpotPrice) error {
+		// The end of the range is the first price of the following day
+		if sp.Timestamp.Before(end) {
+			timestamps = append(timestamps, sp.Timestamp)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(timestamps) == 0 && opts.Currency == "" {
+		return nil, nil
+	}
+
+	var issues []models.DataQualityIssue
+	for day := start; day.Before(end); day = time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc) {
+		next := time.Date(day.Year(), day.Month(), day.Day()+1, 0, 0, 0, 0, loc)
+
+		// Prices that don't start an interval count towards the interval they fall in
+		slots := make([]int, int(next.Sub(day)/c.resolution))
+		count := 0
+		for len(timestamps) > 0 && timestamps[0].Before(next) {
+			slots[int(timestamps[0].Sub(day)/c.resolution)]++
+			timestamps = timestamps[1:]
+			count++
+		}
+
+		issue := models.DataQualityIssue{
+			Zone:     zone.Name,
+			Currency: currency.Name,
+			Date:     day.Format("2006-01-02"),
+			Expected: len(slots),
+			Count:    count,
+		}
+		for i, n := range slots {
+			slot := day.Add(time.Duration(i) * c.resolution).UTC()
+			switch {
+			case n == 0:
+				issue.Missing = append(issue.Missing, slot)
+			case n > 1:
+				issue.Duplicates = append(issue.Duplicates, slot)
+			}
+		}
+		if issue.Missing == nil && issue.Duplicates == nil {
+			continue
+		}
+
+		// Fetching again fills in missing prices, duplicates have to be removed by hand
+		if opts.Refetch && c.refetcher != nil && issue.Missing != nil {
+			if err := c.refetcher.Refetch(zone.Name, currency.Name, day); err != nil {
+				log.Printf("Failed to refetch spot prices of %s in %s on %s: %v", zone.Name, currency.Name, issue.Date, err)
+			} else {
+				issue.Refetched = true
+			}
+		}
+		issues = append(issues, issue)
+	}
+	return issues, nil
+}
@@ -0,0 +1,158 @@
+package quality
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type refetchRecorder struct {
+	days []string
+	err  error
+}
+
+func (r *refetchRecorder) Refetch(zone, currency string, date time.Time) error {
+	r.days = append(r.days, zone+" "+currency+" "+date.Format("2006-01-02"))
+	return r.err
+}
+
+// storeDay stores a price every hour of a day in Europe/Stockholm, except at the hours listed in skip
+func storeDay(t *testing.T, prices repository.SpotPriceRepository, zone *models.Zone, currency *models.Currency, date string, skip ...int) {
+	t.Helper()
+	loc, err := time.LoadLocation("Europe/Stockholm")
+	require.NoError(t, err)
+	day, err := time.ParseInLocation("2006-01-02", date, loc)
+	require.NoError(t, err)
+	next := day.AddDate(0, 0, 1)
+
+	var batch []models.SpotPrice
+	for i, ts := 0, day; ts.Before(next); i, ts = i+1, ts.Add(time.Hour) {
+		if contains(skip, i) {
+			continue
+		}
+		batch = append(batch, models.SpotPrice{Timestamp: ts, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 10})
+	}
+	require.NoError(t, prices.CreateBatch(context.Background(), batch))
+}
+
+func contains(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func TestChecker_Check(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	prices := memory.NewSpotPriceRepository(store)
+	zones := memory.NewZoneRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+	checker := NewChecker(prices, zones, currencies, time.Hour)
+
+	zone, err := zones.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	eur, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	day := func(date string) time.Time {
+		d, err := time.Parse("2006-01-02", date)
+		require.NoError(t, err)
+		return d
+	}
+
+	t.Run("DST Changes", func(t *testing.T) {
+		// Clocks go forward on March 30 and back on October 26, 2025
+		storeDay(t, prices, zone, eur, "2025-03-30")
+		storeDay(t, prices, zone, eur, "2025-10-26")
+
+		issues, err := checker.Check(ctx, Options{From: day("2025-03-30"), To: day("2025-03-30"), Zone: "SE3"})
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+
+		issues, err = checker.Check(ctx, Options{From: day("2025-10-26"), To: day("2025-10-26"), Zone: "SE3"})
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+	})
+
+	t.Run("Missing Hours", func(t *testing.T) {
+		storeDay(t, prices, zone, eur, "2025-03-31", 0, 13)
+
+		issues, err := checker.Check(ctx, Options{From: day("2025-03-30"), To: day("2025-03-31"), Zone: "SE3"})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, "2025-03-31", issues[0].Date)
+		assert.Equal(t, 24, issues[0].Expected)
+		assert.Equal(t, 22, issues[0].Count)
+		assert.Equal(t, []time.Time{
+			time.Date(2025, 3, 30, 22, 0, 0, 0, time.UTC),
+			time.Date(2025, 3, 31, 11, 0, 0, 0, time.UTC),
+		}, issues[0].Missing)
+		assert.Empty(t, issues[0].Duplicates)
+	})
+
+	t.Run("Duplicate Hours", func(t *testing.T) {
+		storeDay(t, prices, zone, eur, "2025-04-01")
+		// A stray price half way through an hour that already has one
+		require.NoError(t, prices.Create(ctx, &models.SpotPrice{
+			Timestamp:  time.Date(2025, 4, 1, 10, 30, 0, 0, time.UTC),
+			ZoneID:     zone.ID,
+			CurrencyID: eur.ID,
+			Price:      10,
+		}))
+
+		issues, err := checker.Check(ctx, Options{From: day("2025-04-01"), To: day("2025-04-01"), Zone: "SE3", Currency: "EUR"})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, 25, issues[0].Count)
+		assert.Equal(t, []time.Time{time.Date(2025, 4, 1, 10, 0, 0, 0, time.UTC)}, issues[0].Duplicates)
+		assert.Empty(t, issues[0].Missing)
+	})
+
+	t.Run("Currency Without Prices", func(t *testing.T) {
+		// Only currencies with prices are checked unless one is asked for
+		issues, err := checker.Check(ctx, Options{From: day("2025-03-30"), To: day("2025-03-30"), Zone: "SE3"})
+		require.NoError(t, err)
+		assert.Empty(t, issues)
+
+		issues, err = checker.Check(ctx, Options{From: day("2025-03-30"), To: day("2025-03-30"), Zone: "SE3", Currency: "SEK"})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.Equal(t, 23, issues[0].Expected)
+		assert.Len(t, issues[0].Missing, 23)
+	})
+
+	t.Run("Refetch", func(t *testing.T) {
+		recorder := &refetchRecorder{}
+		checker.SetRefetcher(recorder)
+		defer checker.SetRefetcher(nil)
+
+		issues, err := checker.Check(ctx, Options{From: day("2025-03-31"), To: day("2025-04-01"), Zone: "SE3", Refetch: true})
+		require.NoError(t, err)
+		require.Len(t, issues, 2)
+		assert.True(t, issues[0].Refetched)
+		// Fetching again doesn't remove duplicates
+		assert.False(t, issues[1].Refetched)
+		assert.Equal(t, []string{"SE3 EUR 2025-03-31"}, recorder.days)
+
+		recorder.err = errors.New("no provider")
+		issues, err = checker.Check(ctx, Options{From: day("2025-03-31"), To: day("2025-03-31"), Zone: "SE3", Refetch: true})
+		require.NoError(t, err)
+		require.Len(t, issues, 1)
+		assert.False(t, issues[0].Refetched)
+	})
+
+	t.Run("Unknown Zone", func(t *testing.T) {
+		_, err := checker.Check(ctx, Options{From: day("2025-03-30"), To: day("2025-03-30"), Zone: "XX"})
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+}
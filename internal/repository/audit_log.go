@@ -14,23 +14,32 @@ type AuditLogRepository interface {
 	Create(ctx context.Context, log *models.CreateAuditLogRequest) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.AuditLog, error)
 	List(ctx context.Context, filter AuditLogFilter) ([]models.AuditLog, error)
+	// Total counts the entries matching the filter across all pages
+	Total(ctx context.Context, filter AuditLogFilter) (int, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID, filter AuditLogFilter) ([]models.AuditLog, error)
 	GetByEntityTypeAndID(ctx context.Context, entityType, entityID string, filter AuditLogFilter) ([]models.AuditLog, error)
 	CleanupOld(ctx context.Context, olderThan time.Duration) error
+	// DeleteOlderThan removes the entries created before the given time and returns how
+	// many were removed
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
 // AuditLogFilter defines the filter options for listing audit logs
 type AuditLogFilter struct {
-	UserID        *uuid.UUID           // Filter by user ID
-	Actions       []models.AuditAction // Filter by actions
-	EntityTypes   []string             // Filter by entity types
-	EntityIDs     []string             // Filter by entity IDs
-	IPAddress     *string              // Filter by IP address
-	CreatedBefore *time.Time           // Filter by creation time
-	CreatedAfter  *time.Time           // Filter by creation time
-	SearchTerm    *string              // Search in description and metadata
-	OrderBy       string               // Field to order by
-	OrderDesc     bool                 // Order descending
-	Limit         *int                 // Limit results
-	Offset        *int                 // Offset results
+	UserID         *uuid.UUID           // Filter by user ID
+	ImpersonatorID *uuid.UUID           // Filter by the admin impersonating the user
+	Actions        []models.AuditAction // Filter by actions
+	EntityTypes    []string             // Filter by entity types
+	EntityIDs      []string             // Filter by entity IDs
+	IPAddress      *string              // Filter by IP address
+	CreatedBefore  *time.Time           // Filter by creation time
+	CreatedAfter   *time.Time           // Filter by creation time
+	SearchTerm     *string              // Search in description and metadata
+	OrderBy        string               // Field to order by, one of AuditLogOrderFields
+	OrderDesc      bool                 // Order descending
+	Limit          *int                 // Limit results
+	Offset         *int                 // Offset results
 }
+
+// AuditLogOrderFields are the fields audit logs can be ordered by
+var AuditLogOrderFields = []string{"created_at", "action", "entity_type"}
@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// BudgetRepository defines the interface for budget operations
+type BudgetRepository interface {
+	Repository
+	// Create stores a new budget, returning ErrDuplicateEntry when the user already has one
+	// for the zone and currency
+	Create(ctx context.Context, budget *models.Budget) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Budget, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.Budget, error)
+	// ListNotify returns the budgets whose owners are notified, leaving out deleted users
+	ListNotify(ctx context.Context) ([]models.Budget, error)
+	Update(ctx context.Context, budget *models.Budget) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// MarkNotified records when the budget last sent a notification
+	MarkNotified(ctx context.Context, id uuid.UUID, at time.Time) error
+}
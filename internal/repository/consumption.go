@@ -0,0 +1,31 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ConsumptionRepository stores the energy consumption measured by users' meters
+type ConsumptionRepository interface {
+	Repository
+	// CreateBatch stores the records, replacing the kWh of records the meter already has at
+	// the same timestamp. The records are updated to the values stored.
+	CreateBatch(ctx context.Context, records []models.ConsumptionRecord) error
+	// List returns the records of a user, oldest first
+	List(ctx context.Context, filter ConsumptionFilter) ([]models.ConsumptionRecord, error)
+	// Total counts the records matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter ConsumptionFilter) (int, error)
+}
+
+// ConsumptionFilter defines the filter options for listing consumption records
+type ConsumptionFilter struct {
+	UserID    uuid.UUID
+	MeterID   *string    // Filter by meter
+	StartTime *time.Time // Records at or after
+	EndTime   *time.Time // Records at or before
+	Limit     *int       // Limit results
+	Offset    *int       // Offset results
+}
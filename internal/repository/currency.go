@@ -13,6 +13,10 @@ type CurrencyRepository interface {
 	Create(ctx context.Context, currency *models.Currency) error
 	Update(ctx context.Context, currency *models.Currency) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	// DeleteCascade deletes the currency with its spot prices in one transaction. With reassignTo
+	// set the spot prices are moved to that currency instead, except those it already has a
+	// price for, which are deleted.
+	DeleteCascade(ctx context.Context, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Currency, error)
 	GetByName(ctx context.Context, name string) (*models.Currency, error)
 	List(ctx context.Context) ([]models.Currency, error)
@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// DeviceTokenRepository defines the interface for push notification device operations
+type DeviceTokenRepository interface {
+	Repository
+	// Upsert registers a device, reassigning it to the given user if the token is already known
+	Upsert(ctx context.Context, device *models.DeviceToken) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.DeviceToken, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.DeviceToken, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EmailChangeRevertExpiration is the revert link lifetime used when none is configured
+const EmailChangeRevertExpiration = 7 * 24 * time.Hour
+
+// EmailChangeRevert lets the owner of a previous address undo an email change
+type EmailChangeRevert struct {
+	ID        uuid.UUID  `db:"id"`
+	UserID    uuid.UUID  `db:"user_id"`
+	OldEmail  string     `db:"old_email"`
+	NewEmail  *string    `db:"new_email"`
+	Token     string     `db:"token"`
+	ExpiresAt time.Time  `db:"expires_at"`
+	UsedAt    *time.Time `db:"used_at"`
+	CreatedAt time.Time  `db:"created_at"`
+}
+
+// EmailChangeRevertRepository defines the interface for email change revert tokens
+type EmailChangeRevertRepository interface {
+	Repository
+	// Create issues a revert token valid for ttl, or EmailChangeRevertExpiration if ttl is zero
+	Create(ctx context.Context, userID uuid.UUID, oldEmail string, newEmail *string, ttl time.Duration) (*EmailChangeRevert, error)
+	// GetByToken returns an unused token, ErrTokenInvalid if it doesn't exist or was used
+	// and ErrTokenExpired if it has expired
+	GetByToken(ctx context.Context, token string) (*EmailChangeRevert, error)
+	// MarkAsUsed consumes the token so it cannot be used again
+	MarkAsUsed(ctx context.Context, id uuid.UUID) error
+	// InvalidateForUser consumes all outstanding tokens of the user
+	InvalidateForUser(ctx context.Context, userID uuid.UUID) error
+	// DeleteExpired removes the tokens that expired before the given time and returns how many
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
+}
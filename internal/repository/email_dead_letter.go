@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// EmailDeadLetterRepository defines the interface for emails that failed every delivery attempt
+type EmailDeadLetterRepository interface {
+	Repository
+	Create(ctx context.Context, letter *models.EmailDeadLetter) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.EmailDeadLetter, error)
+	// List returns the dead letters matching the filter, newest first
+	List(ctx context.Context, filter EmailDeadLetterFilter) ([]models.EmailDeadLetter, error)
+	// Total counts the dead letters matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter EmailDeadLetterFilter) (int, error)
+	// MarkResent records that the dead letter was delivered after all
+	MarkResent(ctx context.Context, id uuid.UUID) error
+	// RecordFailure adds the attempts of a failed resend and replaces the error
+	RecordFailure(ctx context.Context, id uuid.UUID, attempts int, lastError string) error
+}
+
+// EmailDeadLetterFilter defines the filter options for listing dead letters
+type EmailDeadLetterFilter struct {
+	Recipient *string // Search by recipient
+	Kind      *string // Filter by kind of email
+	Pending   bool    // Only dead letters that weren't resent
+	Limit     *int    // Limit results
+	Offset    *int    // Offset results
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+)
+
+// EmailSuppressionRepository defines the interface for suppressed email address operations.
+// Addresses are compared case-insensitively.
+type EmailSuppressionRepository interface {
+	Repository
+	// Upsert suppresses an address, replacing the reason if it is already suppressed
+	Upsert(ctx context.Context, suppression *models.EmailSuppression) error
+	Get(ctx context.Context, email string) (*models.EmailSuppression, error)
+	IsSuppressed(ctx context.Context, email string) (bool, error)
+	List(ctx context.Context, filter EmailSuppressionFilter) ([]models.EmailSuppression, error)
+	// Total counts the suppressions matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter EmailSuppressionFilter) (int, error)
+	Delete(ctx context.Context, email string) error
+}
+
+// EmailSuppressionFilter defines the filter options for listing suppressions
+type EmailSuppressionFilter struct {
+	Search *string                        // Search by address
+	Reason *models.EmailSuppressionReason // Filter by reason
+	Limit  *int                           // Limit results
+	Offset *int                           // Offset results
+}
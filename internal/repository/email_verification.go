@@ -11,6 +11,7 @@ import (
 )
 
 const (
+	// TokenExpirationHours is the verification token lifetime used when none is configured
 	TokenExpirationHours    = 24
 	VerificationTokenLength = 32
 )
@@ -25,8 +26,13 @@ type EmailVerification struct {
 }
 
 type EmailVerificationRepository interface {
-	Create(ctx context.Context, userID uuid.UUID) (*EmailVerification, error)
+	// Create issues a verification token valid for ttl, or TokenExpirationHours if ttl is zero
+	Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*EmailVerification, error)
 	Verify(ctx context.Context, token string) error
+	// CountSince returns the number of tokens issued to the user since the given time
+	CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	// DeleteExpired removes the tokens that expired before the given time and returns how many
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
 }
 
 func NewEmailVerificationRepository(db *sql.DB) EmailVerificationRepository {
@@ -45,7 +51,11 @@ func generateToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-func (r *emailVerificationRepositoryImpl) Create(ctx context.Context, userID uuid.UUID) (*EmailVerification, error) {
+func (r *emailVerificationRepositoryImpl) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*EmailVerification, error) {
+	if ttl <= 0 {
+		ttl = TokenExpirationHours * time.Hour
+	}
+
 	token, err := generateToken()
 	if err != nil {
 		return nil, err
@@ -55,7 +65,7 @@ func (r *emailVerificationRepositoryImpl) Create(ctx context.Context, userID uui
 		ID:        uuid.New(),
 		UserID:    userID,
 		Token:     token,
-		ExpiresAt: time.Now().Add(TokenExpirationHours * time.Hour),
+		ExpiresAt: time.Now().Add(ttl),
 	}
 
 	query := `
@@ -137,3 +147,20 @@ func (r *emailVerificationRepositoryImpl) Verify(ctx context.Context, token stri
 
 	return tx.Commit()
 }
+
+func (r *emailVerificationRepositoryImpl) CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM email_verifications WHERE user_id = $1 AND created_at >= $2",
+		userID, since,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *emailVerificationRepositoryImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM email_verifications WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
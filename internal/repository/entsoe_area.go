@@ -0,0 +1,19 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// EntsoeAreaRepository defines the interface for the zones fetched from ENTSO-E
+type EntsoeAreaRepository interface {
+	Repository
+	// List returns the mappings ordered by zone name
+	List(ctx context.Context) ([]models.EntsoeArea, error)
+	// Upsert maps a zone to an area, replacing its current area. It returns ErrNotFound
+	// when the zone doesn't exist.
+	Upsert(ctx context.Context, area *models.EntsoeArea) error
+	Delete(ctx context.Context, zoneID uuid.UUID) error
+}
@@ -32,6 +32,7 @@ var (
 	ErrTokenExpired  = errors.New("token expired")
 	ErrTokenUsed     = errors.New("token already used")
 	ErrTokenNotFound = errors.New("token not found")
+	ErrTokenReused   = errors.New("token reused")
 
 	// Reset token errors
 	ErrResetTokenInvalid = errors.New("invalid reset token")
@@ -41,4 +42,7 @@ var (
 	// Zone errors
 	ErrZoneNotFound = errors.New("zone not found")
 	ErrZoneExists   = errors.New("zone already exists")
+
+	// Organization errors
+	ErrLastOwner = errors.New("organization must keep an owner")
 )
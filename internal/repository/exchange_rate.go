@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ExchangeRateRepository stores the exchange rates used to convert spot prices between currencies
+type ExchangeRateRepository interface {
+	Repository
+	// CreateBatch stores the rates, replacing rates the pair already has at the same
+	// timestamp. The rates are updated to the values stored. It returns ErrNotFound when a
+	// currency doesn't exist.
+	CreateBatch(ctx context.Context, rates []models.ExchangeRate) error
+	// List returns rates, most recent first
+	List(ctx context.Context, filter ExchangeRateFilter) ([]models.ExchangeRate, error)
+	// Total counts the rates matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter ExchangeRateFilter) (int, error)
+	// Effective returns the rates from base to quote in effect between start and end, the
+	// last one set at or before start followed by those set until end, oldest first
+	Effective(ctx context.Context, base, quote uuid.UUID, start, end time.Time) ([]models.ExchangeRate, error)
+}
+
+// ExchangeRateFilter defines the filter options for listing exchange rates
+type ExchangeRateFilter struct {
+	BaseCurrencyID  *uuid.UUID
+	QuoteCurrencyID *uuid.UUID
+	StartTime       *time.Time
+	EndTime         *time.Time
+	Limit           *int // Limit results
+	Offset          *int // Offset results
+}
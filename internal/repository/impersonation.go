@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ImpersonationRepository defines the interface for sessions in which an admin acts as
+// another user
+type ImpersonationRepository interface {
+	Repository
+	// Create starts an impersonation of userID by adminID lasting until expiresAt. It
+	// returns ErrNotFound when either user doesn't exist.
+	Create(ctx context.Context, adminID, userID uuid.UUID, expiresAt time.Time) (*models.Impersonation, error)
+	// GetByID returns the impersonation, ended or not, or ErrNotFound
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Impersonation, error)
+	// End ends the impersonation at the given time. It returns ErrNotFound when there is
+	// no such impersonation that is still active.
+	End(ctx context.Context, id uuid.UUID, at time.Time) error
+}
@@ -0,0 +1,24 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// IntegrationTokenRepository defines the interface for integration token operations.
+// Tokens are stored and looked up by their hash.
+type IntegrationTokenRepository interface {
+	Repository
+	// Create stores a token of an existing user, or returns ErrNotFound
+	Create(ctx context.Context, token *models.IntegrationToken) error
+	// GetByHash returns the token with the hash, or ErrNotFound
+	GetByHash(ctx context.Context, hash string) (*models.IntegrationToken, error)
+	GetByID(ctx context.Context, id uuid.UUID) (*models.IntegrationToken, error)
+	// ListByUserID returns the tokens of the user, newest first
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.IntegrationToken, error)
+	Delete(ctx context.Context, id uuid.UUID) error
+	MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error
+}
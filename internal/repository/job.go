@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+)
+
+// JobRepository stores the background jobs and the history of their runs
+type JobRepository interface {
+	Repository
+	// SaveJob creates the job or updates its schedule
+	SaveJob(ctx context.Context, job *models.Job) error
+	// CreateRun records a run that started, the job must have been saved
+	CreateRun(ctx context.Context, run *models.JobRun) error
+	// FinishRun stores the status, error and finish time of a run
+	FinishRun(ctx context.Context, run *models.JobRun) error
+	// ListRuns returns runs, most recent first
+	ListRuns(ctx context.Context, filter JobRunFilter) ([]models.JobRun, error)
+	// TotalRuns counts the runs matching the filter, ignoring its limit and offset
+	TotalRuns(ctx context.Context, filter JobRunFilter) (int, error)
+}
+
+// JobRunFilter defines the filter options for listing job runs
+type JobRunFilter struct {
+	JobName string // Filter by job, all jobs when empty
+	Limit   *int   // Limit results
+	Offset  *int   // Offset results
+}
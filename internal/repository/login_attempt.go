@@ -9,6 +9,8 @@ import (
 	"github.com/google/uuid"
 )
 
+// Lockout policy of the legacy repository. The API reads the policy from the runtime
+// settings instead.
 const (
 	MaxLoginAttempts = 5
 	LockoutDuration  = 15 * time.Minute
@@ -16,8 +18,29 @@ const (
 
 type LoginAttemptRepository interface {
 	Create(ctx context.Context, userID uuid.UUID, successful bool, ipAddress string, createdAt time.Time) error
+	// CreateFailure records a failed login with username, linked to the user having the
+	// username if there is one
+	CreateFailure(ctx context.Context, username, ipAddress string, createdAt time.Time) error
+	// CreateSuccess records a successful login of the user by client, from the device with
+	// the fingerprint
+	CreateSuccess(ctx context.Context, userID uuid.UUID, client models.SessionClient, fingerprint string, createdAt time.Time) error
+	// LoginHistory compares a login from the device with the fingerprint and the IP address
+	// with the earlier successful logins of the user
+	LoginHistory(ctx context.Context, userID uuid.UUID, fingerprint, ipAddress string) (models.LoginHistory, error)
 	GetRecentAttempts(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	// GetRecentFailures counts the failed logins with username since the given time, whether
+	// or not a user has the username, so lockouts don't reveal which usernames exist
+	GetRecentFailures(ctx context.Context, username string, since time.Time) (int, error)
+	// ListRecentAttempts returns the failed attempts of a user since the given time, newest first
+	ListRecentAttempts(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.LoginAttempt, error)
+	// ClearAttempts removes the failed attempts of a user and the failed logins with their
+	// username, which lock the account. Successful ones are kept as the history new devices
+	// are spotted from. It returns ErrNotFound when the user doesn't exist or has no failed
+	// attempts.
 	ClearAttempts(ctx context.Context, userID uuid.UUID) error
+	// DeleteOlderThan removes the attempts of all users made before the given time and
+	// returns how many were removed
+	DeleteOlderThan(ctx context.Context, before time.Time) (int64, error)
 }
 
 type LoginAttemptRepositoryImpl struct {
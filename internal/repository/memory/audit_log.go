@@ -0,0 +1,149 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type auditLogRepository struct {
+	base
+}
+
+// NewAuditLogRepository creates a new in-memory audit log repository
+func NewAuditLogRepository(store *Store) repository.AuditLogRepository {
+	return &auditLogRepository{base{store}}
+}
+
+func (r *auditLogRepository) Create(ctx context.Context, log *models.CreateAuditLogRequest) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.auditLogs = append(s.auditLogs, models.AuditLog{
+		ID:             uuid.New(),
+		UserID:         clonePtr(log.UserID),
+		ImpersonatorID: clonePtr(log.ImpersonatorID),
+		Action:         log.Action,
+		EntityType:     log.EntityType,
+		EntityID:       log.EntityID,
+		Description:    log.Description,
+		Metadata:       log.Metadata,
+		IPAddress:      log.IPAddress,
+		UserAgent:      log.UserAgent,
+		CreatedAt:      time.Now(),
+	})
+	return nil
+}
+
+func (r *auditLogRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AuditLog, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, log := range s.auditLogs {
+		if log.ID == id {
+			log.UserID = clonePtr(log.UserID)
+			log.ImpersonatorID = clonePtr(log.ImpersonatorID)
+			return &log, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+var auditLogOrder = map[string]func(a, b models.AuditLog) int{
+	"created_at":  func(a, b models.AuditLog) int { return compareTime(a.CreatedAt, b.CreatedAt) },
+	"action":      func(a, b models.AuditLog) int { return compareString(string(a.Action), string(b.Action)) },
+	"entity_type": func(a, b models.AuditLog) int { return compareString(a.EntityType, b.EntityType) },
+}
+
+func (r *auditLogRepository) List(ctx context.Context, filter repository.AuditLogFilter) ([]models.AuditLog, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	logs := make([]models.AuditLog, 0)
+	for _, log := range s.auditLogs {
+		if filter.UserID != nil && (log.UserID == nil || *log.UserID != *filter.UserID) {
+			continue
+		}
+		if filter.ImpersonatorID != nil && (log.ImpersonatorID == nil || *log.ImpersonatorID != *filter.ImpersonatorID) {
+			continue
+		}
+		if len(filter.Actions) > 0 && !slices.Contains(filter.Actions, log.Action) {
+			continue
+		}
+		if len(filter.EntityTypes) > 0 && !slices.Contains(filter.EntityTypes, log.EntityType) {
+			continue
+		}
+		if len(filter.EntityIDs) > 0 && !slices.Contains(filter.EntityIDs, log.EntityID) {
+			continue
+		}
+		if filter.IPAddress != nil && log.IPAddress != *filter.IPAddress {
+			continue
+		}
+		if filter.CreatedBefore != nil && !log.CreatedAt.Before(*filter.CreatedBefore) {
+			continue
+		}
+		if filter.CreatedAfter != nil && !log.CreatedAt.After(*filter.CreatedAfter) {
+			continue
+		}
+		if filter.SearchTerm != nil && !containsFold(log.Description, *filter.SearchTerm) &&
+			!containsFold(log.Metadata, *filter.SearchTerm) {
+			continue
+		}
+		log.UserID = clonePtr(log.UserID)
+		log.ImpersonatorID = clonePtr(log.ImpersonatorID)
+		logs = append(logs, log)
+	}
+
+	orderBy, desc := filter.OrderBy, filter.OrderDesc
+	if orderBy == "" {
+		orderBy, desc = "created_at", true
+	}
+	if err := sortBy(logs, orderBy, desc, auditLogOrder); err != nil {
+		return nil, err
+	}
+	return page(logs, filter.Limit, filter.Offset), nil
+}
+
+func (r *auditLogRepository) Total(ctx context.Context, filter repository.AuditLogFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	logs, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(logs), nil
+}
+
+func (r *auditLogRepository) GetByUserID(ctx context.Context, userID uuid.UUID, filter repository.AuditLogFilter) ([]models.AuditLog, error) {
+	filter.UserID = &userID
+	return r.List(ctx, filter)
+}
+
+func (r *auditLogRepository) GetByEntityTypeAndID(ctx context.Context, entityType, entityID string, filter repository.AuditLogFilter) ([]models.AuditLog, error) {
+	filter.EntityTypes = []string{entityType}
+	filter.EntityIDs = []string{entityID}
+	return r.List(ctx, filter)
+}
+
+func (r *auditLogRepository) CleanupOld(ctx context.Context, olderThan time.Duration) error {
+	_, err := r.DeleteOlderThan(ctx, time.Now().Add(-olderThan))
+	return err
+}
+
+func (r *auditLogRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.auditLogs)
+	s.auditLogs = slices.DeleteFunc(s.auditLogs, func(log models.AuditLog) bool {
+		return log.CreatedAt.Before(before)
+	})
+	return int64(count - len(s.auditLogs)), nil
+}
@@ -0,0 +1,140 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type budgetRepository struct {
+	base
+}
+
+// NewBudgetRepository creates a new in-memory budget repository
+func NewBudgetRepository(store *Store) repository.BudgetRepository {
+	return &budgetRepository{base{store}}
+}
+
+func cloneBudget(budget models.Budget) models.Budget {
+	budget.LastNotifiedAt = clonePtr(budget.LastNotifiedAt)
+	return budget
+}
+
+// findBudget returns the position of the first budget matching, or -1. s.mu must be held.
+func (s *Store) findBudget(match func(b *models.Budget) bool) int {
+	return slices.IndexFunc(s.budgets, func(b models.Budget) bool { return match(&b) })
+}
+
+func (r *budgetRepository) Create(ctx context.Context, budget *models.Budget) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(budget.UserID, false) ||
+		s.findZone(func(z *models.Zone) bool { return z.ID == budget.ZoneID }) < 0 ||
+		s.findCurrency(func(c *models.Currency) bool { return c.ID == budget.CurrencyID }) < 0 {
+		return repository.ErrNotFound
+	}
+	if s.findBudget(func(b *models.Budget) bool {
+		return b.UserID == budget.UserID && b.ZoneID == budget.ZoneID && b.CurrencyID == budget.CurrencyID
+	}) >= 0 {
+		return repository.ErrDuplicateEntry
+	}
+
+	now := time.Now()
+	budget.ID = uuid.New()
+	budget.LastNotifiedAt = nil
+	budget.CreatedAt = now
+	budget.UpdatedAt = now
+	s.budgets = append(s.budgets, cloneBudget(*budget))
+	return nil
+}
+
+func (r *budgetRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Budget, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findBudget(func(b *models.Budget) bool { return b.ID == id })
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	budget := cloneBudget(s.budgets[i])
+	return &budget, nil
+}
+
+func (r *budgetRepository) list(match func(b *models.Budget) bool) []models.Budget {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	budgets := make([]models.Budget, 0)
+	for _, budget := range s.budgets {
+		if match(&budget) {
+			budgets = append(budgets, cloneBudget(budget))
+		}
+	}
+	slices.SortStableFunc(budgets, func(a, b models.Budget) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return compareString(a.ID.String(), b.ID.String())
+	})
+	return budgets
+}
+
+func (r *budgetRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.Budget, error) {
+	return r.list(func(b *models.Budget) bool { return b.UserID == userID }), nil
+}
+
+func (r *budgetRepository) ListNotify(ctx context.Context) ([]models.Budget, error) {
+	s := r.store
+	return r.list(func(b *models.Budget) bool { return b.Notify && s.userExists(b.UserID, false) }), nil
+}
+
+func (r *budgetRepository) Update(ctx context.Context, budget *models.Budget) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findBudget(func(b *models.Budget) bool { return b.ID == budget.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	stored := &s.budgets[i]
+	stored.Amount = budget.Amount
+	stored.Notify = budget.Notify
+	stored.UpdatedAt = time.Now()
+	*budget = cloneBudget(*stored)
+	return nil
+}
+
+func (r *budgetRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findBudget(func(b *models.Budget) bool { return b.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.budgets = slices.Delete(s.budgets, i, i+1)
+	return nil
+}
+
+func (r *budgetRepository) MarkNotified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findBudget(func(b *models.Budget) bool { return b.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.budgets[i].LastNotifiedAt = &at
+	return nil
+}
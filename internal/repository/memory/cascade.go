@@ -0,0 +1,82 @@
+package memory
+
+import (
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// deleteCascadeSpotPrices removes the spot prices and source values whose key field
+// selects as id, or moves them to reassignTo. Those the target already has are removed.
+// The revisions of the spot prices are removed or moved with them. s.mu must be held.
+func (s *Store) deleteCascadeSpotPrices(id uuid.UUID, reassignTo *uuid.UUID, field func(k *spotPriceKey) *uuid.UUID) *models.DeletionSummary {
+	summary := &models.DeletionSummary{}
+	now := time.Now()
+
+	for key, sources := range s.spotPriceSources {
+		if *field(&key) != id {
+			continue
+		}
+		delete(s.spotPriceSources, key)
+		if reassignTo == nil {
+			summary.SourceValuesDeleted += int64(len(sources))
+			continue
+		}
+		target := key
+		*field(&target) = *reassignTo
+		if s.spotPriceSources[target] == nil {
+			s.spotPriceSources[target] = make(map[string]models.SpotPriceSourceValue)
+		}
+		for source, value := range sources {
+			if _, ok := s.spotPriceSources[target][source]; ok {
+				summary.SourceValuesDeleted++
+				continue
+			}
+			value.UpdatedAt = now
+			s.spotPriceSources[target][source] = value
+			summary.SourceValuesMoved++
+		}
+	}
+
+	for key, spotPriceID := range s.spotPriceKeys {
+		if *field(&key) != id {
+			continue
+		}
+		delete(s.spotPriceKeys, key)
+		resolved, isResolved := s.resolvedSources[key]
+		delete(s.resolvedSources, key)
+
+		target := key
+		if reassignTo != nil {
+			*field(&target) = *reassignTo
+		}
+		if _, exists := s.spotPriceKeys[target]; reassignTo == nil || exists {
+			delete(s.spotPrices, spotPriceID)
+			summary.SpotPricesDeleted++
+			continue
+		}
+
+		sp := s.spotPrices[spotPriceID]
+		sp.ZoneID, sp.CurrencyID = target.zoneID, target.currencyID
+		sp.UpdatedAt = now
+		s.spotPrices[spotPriceID] = sp
+		s.spotPriceKeys[target] = spotPriceID
+		if isResolved {
+			s.resolvedSources[target] = resolved
+		}
+		summary.SpotPricesMoved++
+	}
+
+	// Revisions follow their spot prices
+	s.spotPriceRevisions = slices.DeleteFunc(s.spotPriceRevisions, func(revision models.SpotPriceRevision) bool {
+		_, exists := s.spotPrices[revision.SpotPriceID]
+		return !exists
+	})
+	for i, revision := range s.spotPriceRevisions {
+		sp := s.spotPrices[revision.SpotPriceID]
+		s.spotPriceRevisions[i].ZoneID, s.spotPriceRevisions[i].CurrencyID = sp.ZoneID, sp.CurrencyID
+	}
+	return summary
+}
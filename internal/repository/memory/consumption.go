@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// consumptionKey is the unique key of a consumption or production record, the user, meter
+// and interval
+type consumptionKey struct {
+	userID    uuid.UUID
+	meterID   string
+	timestamp int64
+}
+
+type consumptionRepository struct {
+	base
+}
+
+// NewConsumptionRepository creates a new in-memory consumption repository
+func NewConsumptionRepository(store *Store) repository.ConsumptionRepository {
+	return &consumptionRepository{base{store}}
+}
+
+func (r *consumptionRepository) CreateBatch(ctx context.Context, records []models.ConsumptionRecord) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := range records {
+		record := &records[i]
+		key := consumptionKey{record.UserID, record.MeterID, record.Timestamp.UnixNano()}
+		if existing, ok := s.consumption[key]; ok {
+			record.CreatedAt = existing.CreatedAt
+		} else {
+			record.CreatedAt = now
+		}
+		record.UpdatedAt = now
+		s.consumption[key] = *record
+	}
+	return nil
+}
+
+func (r *consumptionRepository) List(ctx context.Context, filter repository.ConsumptionFilter) ([]models.ConsumptionRecord, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]models.ConsumptionRecord, 0)
+	for _, record := range s.consumption {
+		if record.UserID != filter.UserID {
+			continue
+		}
+		if filter.MeterID != nil && record.MeterID != *filter.MeterID {
+			continue
+		}
+		if filter.StartTime != nil && record.Timestamp.Before(*filter.StartTime) {
+			continue
+		}
+		if filter.EndTime != nil && record.Timestamp.After(*filter.EndTime) {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	slices.SortFunc(records, func(a, b models.ConsumptionRecord) int {
+		return cmp.Or(compareTime(a.Timestamp, b.Timestamp), cmp.Compare(a.MeterID, b.MeterID))
+	})
+	return page(records, filter.Limit, filter.Offset), nil
+}
+
+func (r *consumptionRepository) Total(ctx context.Context, filter repository.ConsumptionFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	records, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
@@ -0,0 +1,144 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type currencyRepository struct {
+	base
+}
+
+// NewCurrencyRepository creates a new in-memory currency repository
+func NewCurrencyRepository(store *Store) repository.CurrencyRepository {
+	return &currencyRepository{base{store}}
+}
+
+func (s *Store) findCurrency(match func(c *models.Currency) bool) int {
+	return slices.IndexFunc(s.currencies, func(c models.Currency) bool { return match(&c) })
+}
+
+func (r *currencyRepository) Create(ctx context.Context, currency *models.Currency) error {
+	currency.FillISO4217()
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findCurrency(func(c *models.Currency) bool { return c.Name == currency.Name }) >= 0 {
+		return repository.ErrConflict
+	}
+
+	now := time.Now()
+	currency.ID = uuid.New()
+	currency.CreatedAt = now
+	currency.UpdatedAt = now
+	s.currencies = append(s.currencies, *currency)
+	return nil
+}
+
+func (r *currencyRepository) Update(ctx context.Context, currency *models.Currency) error {
+	currency.FillISO4217()
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findCurrency(func(c *models.Currency) bool { return c.ID == currency.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	if s.findCurrency(func(c *models.Currency) bool { return c.Name == currency.Name && c.ID != currency.ID }) >= 0 {
+		return repository.ErrConflict
+	}
+
+	s.currencies[i].Name = currency.Name
+	s.currencies[i].NumericCode = currency.NumericCode
+	s.currencies[i].Symbol = currency.Symbol
+	s.currencies[i].SymbolFirst = currency.SymbolFirst
+	s.currencies[i].MinorUnits = currency.MinorUnits
+	s.currencies[i].UpdatedAt = time.Now()
+	*currency = s.currencies[i]
+	return nil
+}
+
+func (r *currencyRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sp := range s.spotPrices {
+		if sp.CurrencyID == id {
+			return repository.ErrHasAssociatedRecords
+		}
+	}
+
+	i := s.findCurrency(func(c *models.Currency) bool { return c.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.currencies = slices.Delete(s.currencies, i, i+1)
+	s.priceAlerts = slices.DeleteFunc(s.priceAlerts, func(a models.PriceAlert) bool { return a.CurrencyID == id })
+	s.budgets = slices.DeleteFunc(s.budgets, func(b models.Budget) bool { return b.CurrencyID == id })
+	s.tariffs = slices.DeleteFunc(s.tariffs, func(t models.Tariff) bool { return t.CurrencyID == id })
+	s.deletePriceForecasts(func(key priceForecastKey) bool { return key.currencyID == id })
+	s.clearPreferences(id)
+	s.deleteExchangeRates(id)
+	return nil
+}
+
+func (r *currencyRepository) DeleteCascade(ctx context.Context, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findCurrency(func(c *models.Currency) bool { return c.ID == id })
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	summary := s.deleteCascadeSpotPrices(id, reassignTo, func(k *spotPriceKey) *uuid.UUID { return &k.currencyID })
+	s.currencies = slices.Delete(s.currencies, i, i+1)
+	s.priceAlerts = slices.DeleteFunc(s.priceAlerts, func(a models.PriceAlert) bool { return a.CurrencyID == id })
+	s.budgets = slices.DeleteFunc(s.budgets, func(b models.Budget) bool { return b.CurrencyID == id })
+	s.tariffs = slices.DeleteFunc(s.tariffs, func(t models.Tariff) bool { return t.CurrencyID == id })
+	s.deletePriceForecasts(func(key priceForecastKey) bool { return key.currencyID == id })
+	s.clearPreferences(id)
+	s.deleteExchangeRates(id)
+	return summary, nil
+}
+
+func (r *currencyRepository) get(match func(c *models.Currency) bool) (*models.Currency, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findCurrency(match)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	currency := s.currencies[i]
+	return &currency, nil
+}
+
+func (r *currencyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Currency, error) {
+	return r.get(func(c *models.Currency) bool { return c.ID == id })
+}
+
+func (r *currencyRepository) GetByName(ctx context.Context, name string) (*models.Currency, error) {
+	return r.get(func(c *models.Currency) bool { return c.Name == name })
+}
+
+func (r *currencyRepository) List(ctx context.Context) ([]models.Currency, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	currencies := slices.Clone(s.currencies)
+	slices.SortFunc(currencies, func(a, b models.Currency) int { return compareString(a.Name, b.Name) })
+	return currencies, nil
+}
@@ -0,0 +1,116 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type deviceTokenRepository struct {
+	base
+}
+
+// NewDeviceTokenRepository creates a new in-memory device token repository
+func NewDeviceTokenRepository(store *Store) repository.DeviceTokenRepository {
+	return &deviceTokenRepository{base{store}}
+}
+
+func cloneDevice(device models.DeviceToken) models.DeviceToken {
+	device.P256dh = clonePtr(device.P256dh)
+	device.Auth = clonePtr(device.Auth)
+	device.UserAgent = clonePtr(device.UserAgent)
+	device.LastUsedAt = clonePtr(device.LastUsedAt)
+	return device
+}
+
+func (r *deviceTokenRepository) Upsert(ctx context.Context, device *models.DeviceToken) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(device.UserID, false) {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	i := slices.IndexFunc(s.deviceTokens, func(d models.DeviceToken) bool {
+		return d.Channel == device.Channel && d.Token == device.Token
+	})
+	if i < 0 {
+		device.ID = uuid.New()
+		device.LastUsedAt = nil
+		device.CreatedAt = now
+		device.UpdatedAt = now
+		s.deviceTokens = append(s.deviceTokens, cloneDevice(*device))
+		return nil
+	}
+
+	// A token identifies a physical device, so re-registering moves it to the current user
+	stored := &s.deviceTokens[i]
+	stored.UserID = device.UserID
+	stored.P256dh = clonePtr(device.P256dh)
+	stored.Auth = clonePtr(device.Auth)
+	stored.UserAgent = clonePtr(device.UserAgent)
+	stored.UpdatedAt = now
+	*device = cloneDevice(*stored)
+	return nil
+}
+
+func (r *deviceTokenRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DeviceToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, device := range s.deviceTokens {
+		if device.ID == id {
+			device = cloneDevice(device)
+			return &device, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *deviceTokenRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.DeviceToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	devices := make([]models.DeviceToken, 0)
+	for _, device := range s.deviceTokens {
+		if device.UserID == userID {
+			devices = append(devices, cloneDevice(device))
+		}
+	}
+	slices.SortStableFunc(devices, func(a, b models.DeviceToken) int { return compareTime(b.CreatedAt, a.CreatedAt) })
+	return devices, nil
+}
+
+func (r *deviceTokenRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.deviceTokens, func(d models.DeviceToken) bool { return d.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.deviceTokens = slices.Delete(s.deviceTokens, i, i+1)
+	return nil
+}
+
+func (r *deviceTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.deviceTokens {
+		if s.deviceTokens[i].ID == id {
+			s.deviceTokens[i].LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
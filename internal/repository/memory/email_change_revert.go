@@ -0,0 +1,110 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type emailChangeRevertRepository struct {
+	base
+}
+
+// NewEmailChangeRevertRepository creates a new in-memory email change revert repository
+func NewEmailChangeRevertRepository(store *Store) repository.EmailChangeRevertRepository {
+	return &emailChangeRevertRepository{base{store}}
+}
+
+func (r *emailChangeRevertRepository) Create(ctx context.Context, userID uuid.UUID, oldEmail string, newEmail *string, ttl time.Duration) (*repository.EmailChangeRevert, error) {
+	if ttl <= 0 {
+		ttl = repository.EmailChangeRevertExpiration
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID, true) {
+		return nil, repository.ErrNotFound
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	revert := repository.EmailChangeRevert{
+		ID:        uuid.New(),
+		UserID:    userID,
+		OldEmail:  oldEmail,
+		NewEmail:  clonePtr(newEmail),
+		Token:     token,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	s.emailChangeReverts = append(s.emailChangeReverts, revert)
+	return &revert, nil
+}
+
+func (r *emailChangeRevertRepository) GetByToken(ctx context.Context, token string) (*repository.EmailChangeRevert, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, revert := range s.emailChangeReverts {
+		if revert.Token != token || revert.UsedAt != nil {
+			continue
+		}
+		if time.Now().After(revert.ExpiresAt) {
+			return nil, repository.ErrTokenExpired
+		}
+		revert.NewEmail = clonePtr(revert.NewEmail)
+		return &revert, nil
+	}
+	return nil, repository.ErrTokenInvalid
+}
+
+func (r *emailChangeRevertRepository) MarkAsUsed(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.emailChangeReverts {
+		if s.emailChangeReverts[i].ID == id && s.emailChangeReverts[i].UsedAt == nil {
+			now := time.Now()
+			s.emailChangeReverts[i].UsedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrTokenInvalid
+}
+
+func (r *emailChangeRevertRepository) InvalidateForUser(ctx context.Context, userID uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := range s.emailChangeReverts {
+		if s.emailChangeReverts[i].UserID == userID && s.emailChangeReverts[i].UsedAt == nil {
+			s.emailChangeReverts[i].UsedAt = &now
+		}
+	}
+	return nil
+}
+
+func (r *emailChangeRevertRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.emailChangeReverts)
+	s.emailChangeReverts = slices.DeleteFunc(s.emailChangeReverts, func(t repository.EmailChangeRevert) bool {
+		return t.ExpiresAt.Before(before)
+	})
+	return int64(count - len(s.emailChangeReverts)), nil
+}
@@ -0,0 +1,117 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type emailDeadLetterRepository struct {
+	base
+}
+
+// NewEmailDeadLetterRepository creates a new in-memory email dead letter repository
+func NewEmailDeadLetterRepository(store *Store) repository.EmailDeadLetterRepository {
+	return &emailDeadLetterRepository{base{store}}
+}
+
+func (r *emailDeadLetterRepository) Create(ctx context.Context, letter *models.EmailDeadLetter) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	letter.ID, letter.CreatedAt, letter.UpdatedAt, letter.ResentAt = uuid.New(), now, now, nil
+	s.emailDeadLetters = append(s.emailDeadLetters, *letter)
+	return nil
+}
+
+func (r *emailDeadLetterRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.EmailDeadLetter, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findEmailDeadLetter(id)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	letter := s.emailDeadLetters[i]
+	letter.ResentAt = clonePtr(letter.ResentAt)
+	return &letter, nil
+}
+
+func (r *emailDeadLetterRepository) List(ctx context.Context, filter repository.EmailDeadLetterFilter) ([]models.EmailDeadLetter, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	letters := make([]models.EmailDeadLetter, 0)
+	for _, letter := range s.emailDeadLetters {
+		if filter.Recipient != nil && !containsFold(letter.Recipient, *filter.Recipient) {
+			continue
+		}
+		if filter.Kind != nil && letter.Kind != *filter.Kind {
+			continue
+		}
+		if filter.Pending && letter.ResentAt != nil {
+			continue
+		}
+		letter.ResentAt = clonePtr(letter.ResentAt)
+		letters = append(letters, letter)
+	}
+
+	slices.SortStableFunc(letters, func(a, b models.EmailDeadLetter) int {
+		return compareTime(b.CreatedAt, a.CreatedAt)
+	})
+	return page(letters, filter.Limit, filter.Offset), nil
+}
+
+func (r *emailDeadLetterRepository) Total(ctx context.Context, filter repository.EmailDeadLetterFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	items, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+func (r *emailDeadLetterRepository) MarkResent(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findEmailDeadLetter(id)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	s.emailDeadLetters[i].ResentAt = &now
+	s.emailDeadLetters[i].UpdatedAt = now
+	return nil
+}
+
+func (r *emailDeadLetterRepository) RecordFailure(ctx context.Context, id uuid.UUID, attempts int, lastError string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findEmailDeadLetter(id)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.emailDeadLetters[i].Attempts += attempts
+	s.emailDeadLetters[i].Error = lastError
+	s.emailDeadLetters[i].UpdatedAt = time.Now()
+	return nil
+}
+
+// findEmailDeadLetter returns the index of the dead letter, or -1, the store must be locked
+func (s *Store) findEmailDeadLetter(id uuid.UUID) int {
+	return slices.IndexFunc(s.emailDeadLetters, func(letter models.EmailDeadLetter) bool {
+		return letter.ID == id
+	})
+}
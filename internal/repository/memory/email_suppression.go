@@ -0,0 +1,111 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type emailSuppressionRepository struct {
+	base
+}
+
+// NewEmailSuppressionRepository creates a new in-memory email suppression repository
+func NewEmailSuppressionRepository(store *Store) repository.EmailSuppressionRepository {
+	return &emailSuppressionRepository{base{store}}
+}
+
+func (r *emailSuppressionRepository) Upsert(ctx context.Context, suppression *models.EmailSuppression) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	suppression.Email = normalizeEmail(suppression.Email)
+	now := time.Now()
+	stored, ok := s.emailSuppressions[suppression.Email]
+	if !ok {
+		stored = models.EmailSuppression{Email: suppression.Email, CreatedAt: now}
+	}
+	// A complaint is never downgraded to a bounce
+	if stored.Reason != models.EmailSuppressionComplained {
+		stored.Reason = suppression.Reason
+	}
+	stored.Provider = suppression.Provider
+	stored.Detail = clonePtr(suppression.Detail)
+	stored.UpdatedAt = now
+	s.emailSuppressions[stored.Email] = stored
+
+	suppression.Reason = stored.Reason
+	suppression.CreatedAt = stored.CreatedAt
+	suppression.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (r *emailSuppressionRepository) Get(ctx context.Context, email string) (*models.EmailSuppression, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	suppression, ok := s.emailSuppressions[normalizeEmail(email)]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	suppression.Detail = clonePtr(suppression.Detail)
+	return &suppression, nil
+}
+
+func (r *emailSuppressionRepository) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	_, ok := s.emailSuppressions[normalizeEmail(email)]
+	return ok, nil
+}
+
+func (r *emailSuppressionRepository) List(ctx context.Context, filter repository.EmailSuppressionFilter) ([]models.EmailSuppression, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	suppressions := make([]models.EmailSuppression, 0)
+	for _, suppression := range s.emailSuppressions {
+		if filter.Search != nil && !containsFold(suppression.Email, *filter.Search) {
+			continue
+		}
+		if filter.Reason != nil && suppression.Reason != *filter.Reason {
+			continue
+		}
+		suppression.Detail = clonePtr(suppression.Detail)
+		suppressions = append(suppressions, suppression)
+	}
+
+	slices.SortStableFunc(suppressions, func(a, b models.EmailSuppression) int {
+		return compareTime(b.UpdatedAt, a.UpdatedAt)
+	})
+	return page(suppressions, filter.Limit, filter.Offset), nil
+}
+
+func (r *emailSuppressionRepository) Total(ctx context.Context, filter repository.EmailSuppressionFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	items, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+func (r *emailSuppressionRepository) Delete(ctx context.Context, email string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	email = normalizeEmail(email)
+	if _, ok := s.emailSuppressions[email]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(s.emailSuppressions, email)
+	return nil
+}
@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type emailVerificationRepository struct {
+	store *Store
+}
+
+// NewEmailVerificationRepository creates a new in-memory email verification repository
+func NewEmailVerificationRepository(store *Store) repository.EmailVerificationRepository {
+	return &emailVerificationRepository{store: store}
+}
+
+func (r *emailVerificationRepository) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*repository.EmailVerification, error) {
+	if ttl <= 0 {
+		ttl = repository.TokenExpirationHours * time.Hour
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID, true) {
+		return nil, repository.ErrNotFound
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	verification := repository.EmailVerification{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Token:     token,
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	s.emailVerifications = append(s.emailVerifications, verification)
+	return &verification, nil
+}
+
+func (r *emailVerificationRepository) Verify(ctx context.Context, token string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.emailVerifications {
+		verification := &s.emailVerifications[i]
+		if verification.Token != token || verification.VerifiedAt != nil {
+			continue
+		}
+		// The PostgreSQL repository rolls back on expiry, so the token stays unverified
+		now := time.Now()
+		if now.After(verification.ExpiresAt) {
+			return repository.ErrTokenExpired
+		}
+		verification.VerifiedAt = &now
+		for j := range s.users {
+			if s.users[j].ID == verification.UserID {
+				s.users[j].EmailVerified = true
+			}
+		}
+		return nil
+	}
+	return repository.ErrTokenInvalid
+}
+
+func (r *emailVerificationRepository) CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, verification := range s.emailVerifications {
+		if verification.UserID == userID && !verification.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *emailVerificationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.emailVerifications)
+	s.emailVerifications = slices.DeleteFunc(s.emailVerifications, func(t repository.EmailVerification) bool {
+		return t.ExpiresAt.Before(before)
+	})
+	return int64(count - len(s.emailVerifications)), nil
+}
@@ -0,0 +1,69 @@
+package memory
+
+import (
+	"context"
+	"sort"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type entsoeAreaRepository struct {
+	base
+}
+
+// NewEntsoeAreaRepository creates a new in-memory ENTSO-E area repository
+func NewEntsoeAreaRepository(store *Store) repository.EntsoeAreaRepository {
+	return &entsoeAreaRepository{base{store}}
+}
+
+func (r *entsoeAreaRepository) List(ctx context.Context) ([]models.EntsoeArea, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// Names are looked up on read so renamed zones show their current name
+	areas := make([]models.EntsoeArea, 0, len(s.entsoeAreas))
+	for _, area := range s.entsoeAreas {
+		if i := s.findZone(func(z *models.Zone) bool { return z.ID == area.ZoneID }); i >= 0 {
+			area.ZoneName = s.zones[i].Name
+		}
+		areas = append(areas, area)
+	}
+	sort.Slice(areas, func(i, j int) bool { return areas[i].ZoneName < areas[j].ZoneName })
+	return areas, nil
+}
+
+func (r *entsoeAreaRepository) Upsert(ctx context.Context, area *models.EntsoeArea) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findZone(func(z *models.Zone) bool { return z.ID == area.ZoneID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	area.ZoneName = s.zones[i].Name
+	area.CreatedAt, area.UpdatedAt = now, now
+	if current, ok := s.entsoeAreas[area.ZoneID]; ok {
+		area.CreatedAt = current.CreatedAt
+	}
+	s.entsoeAreas[area.ZoneID] = *area
+	return nil
+}
+
+func (r *entsoeAreaRepository) Delete(ctx context.Context, zoneID uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.entsoeAreas[zoneID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(s.entsoeAreas, zoneID)
+	return nil
+}
@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// exchangeRateKey is the unique key of an exchange rate, the currency pair and timestamp
+type exchangeRateKey struct {
+	base      uuid.UUID
+	quote     uuid.UUID
+	timestamp int64
+}
+
+type exchangeRateRepository struct {
+	base
+}
+
+// NewExchangeRateRepository creates a new in-memory exchange rate repository
+func NewExchangeRateRepository(store *Store) repository.ExchangeRateRepository {
+	return &exchangeRateRepository{base{store}}
+}
+
+// deleteExchangeRates removes the rates to and from the currency like the foreign keys
+// cascade. s.mu must be held.
+func (s *Store) deleteExchangeRates(currencyID uuid.UUID) {
+	for key := range s.exchangeRates {
+		if key.base == currencyID || key.quote == currencyID {
+			delete(s.exchangeRates, key)
+		}
+	}
+}
+
+func (r *exchangeRateRepository) CreateBatch(ctx context.Context, rates []models.ExchangeRate) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, rate := range rates {
+		if s.findCurrency(func(c *models.Currency) bool { return c.ID == rate.BaseCurrencyID }) < 0 ||
+			s.findCurrency(func(c *models.Currency) bool { return c.ID == rate.QuoteCurrencyID }) < 0 {
+			return repository.ErrNotFound
+		}
+	}
+
+	now := time.Now()
+	for i := range rates {
+		rate := &rates[i]
+		key := exchangeRateKey{rate.BaseCurrencyID, rate.QuoteCurrencyID, rate.Timestamp.UnixNano()}
+		if existing, ok := s.exchangeRates[key]; ok {
+			rate.CreatedAt = existing.CreatedAt
+		} else {
+			rate.CreatedAt = now
+		}
+		rate.UpdatedAt = now
+		s.exchangeRates[key] = *rate
+	}
+	return nil
+}
+
+func (r *exchangeRateRepository) List(ctx context.Context, filter repository.ExchangeRateFilter) ([]models.ExchangeRate, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	rates := make([]models.ExchangeRate, 0)
+	for _, rate := range s.exchangeRates {
+		if filter.BaseCurrencyID != nil && rate.BaseCurrencyID != *filter.BaseCurrencyID {
+			continue
+		}
+		if filter.QuoteCurrencyID != nil && rate.QuoteCurrencyID != *filter.QuoteCurrencyID {
+			continue
+		}
+		if filter.StartTime != nil && rate.Timestamp.Before(*filter.StartTime) {
+			continue
+		}
+		if filter.EndTime != nil && rate.Timestamp.After(*filter.EndTime) {
+			continue
+		}
+		rates = append(rates, rate)
+	}
+
+	slices.SortFunc(rates, func(a, b models.ExchangeRate) int {
+		return cmp.Or(
+			compareTime(b.Timestamp, a.Timestamp),
+			cmp.Compare(a.BaseCurrencyID.String(), b.BaseCurrencyID.String()),
+			cmp.Compare(a.QuoteCurrencyID.String(), b.QuoteCurrencyID.String()),
+		)
+	})
+	return page(rates, filter.Limit, filter.Offset), nil
+}
+
+func (r *exchangeRateRepository) Total(ctx context.Context, filter repository.ExchangeRateFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	rates, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(rates), nil
+}
+
+func (r *exchangeRateRepository) Effective(ctx context.Context, base, quote uuid.UUID, start, end time.Time) ([]models.ExchangeRate, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var previous *models.ExchangeRate
+	rates := make([]models.ExchangeRate, 0)
+	for _, rate := range s.exchangeRates {
+		if rate.BaseCurrencyID != base || rate.QuoteCurrencyID != quote || rate.Timestamp.After(end) {
+			continue
+		}
+		if rate.Timestamp.After(start) {
+			rates = append(rates, rate)
+		} else if previous == nil || rate.Timestamp.After(previous.Timestamp) {
+			previous = &rate
+		}
+	}
+	if previous != nil {
+		rates = append(rates, *previous)
+	}
+
+	slices.SortFunc(rates, func(a, b models.ExchangeRate) int {
+		return compareTime(a.Timestamp, b.Timestamp)
+	})
+	return rates, nil
+}
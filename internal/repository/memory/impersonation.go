@@ -0,0 +1,66 @@
+package memory
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type impersonationRepository struct {
+	base
+}
+
+// NewImpersonationRepository creates a new in-memory impersonation repository
+func NewImpersonationRepository(store *Store) repository.ImpersonationRepository {
+	return &impersonationRepository{base{store}}
+}
+
+func (r *impersonationRepository) Create(ctx context.Context, adminID, userID uuid.UUID, expiresAt time.Time) (*models.Impersonation, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(adminID, true) || !s.userExists(userID, true) {
+		return nil, repository.ErrNotFound
+	}
+	impersonation := models.Impersonation{
+		ID:        uuid.New(),
+		AdminID:   adminID,
+		UserID:    userID,
+		StartedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	s.impersonations = append(s.impersonations, impersonation)
+	return &impersonation, nil
+}
+
+func (r *impersonationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Impersonation, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, impersonation := range s.impersonations {
+		if impersonation.ID == id {
+			impersonation.EndedAt = clonePtr(impersonation.EndedAt)
+			return &impersonation, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *impersonationRepository) End(ctx context.Context, id uuid.UUID, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.impersonations {
+		if s.impersonations[i].ID == id && s.impersonations[i].Active(at) {
+			s.impersonations[i].EndedAt = &at
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
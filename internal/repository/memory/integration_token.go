@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type integrationTokenRepository struct {
+	base
+}
+
+// NewIntegrationTokenRepository creates a new in-memory integration token repository
+func NewIntegrationTokenRepository(store *Store) repository.IntegrationTokenRepository {
+	return &integrationTokenRepository{base{store}}
+}
+
+func cloneIntegrationToken(token models.IntegrationToken) *models.IntegrationToken {
+	token.LastUsedAt = clonePtr(token.LastUsedAt)
+	return &token
+}
+
+func (r *integrationTokenRepository) Create(ctx context.Context, token *models.IntegrationToken) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(token.UserID, false) {
+		return repository.ErrNotFound
+	}
+	if slices.ContainsFunc(s.integrationTokens, func(t models.IntegrationToken) bool { return t.TokenHash == token.TokenHash }) {
+		return repository.ErrConflict
+	}
+
+	token.ID = uuid.New()
+	token.LastUsedAt = nil
+	token.CreatedAt = time.Now()
+	s.integrationTokens = append(s.integrationTokens, *cloneIntegrationToken(*token))
+	return nil
+}
+
+func (r *integrationTokenRepository) get(match func(t models.IntegrationToken) bool) (*models.IntegrationToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := slices.IndexFunc(s.integrationTokens, match)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	return cloneIntegrationToken(s.integrationTokens[i]), nil
+}
+
+func (r *integrationTokenRepository) GetByHash(ctx context.Context, hash string) (*models.IntegrationToken, error) {
+	return r.get(func(t models.IntegrationToken) bool { return t.TokenHash == hash })
+}
+
+func (r *integrationTokenRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.IntegrationToken, error) {
+	return r.get(func(t models.IntegrationToken) bool { return t.ID == id })
+}
+
+func (r *integrationTokenRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.IntegrationToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tokens := make([]models.IntegrationToken, 0)
+	for _, token := range s.integrationTokens {
+		if token.UserID == userID {
+			tokens = append(tokens, *cloneIntegrationToken(token))
+		}
+	}
+	slices.SortStableFunc(tokens, func(a, b models.IntegrationToken) int { return compareTime(b.CreatedAt, a.CreatedAt) })
+	return tokens, nil
+}
+
+func (r *integrationTokenRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.integrationTokens, func(t models.IntegrationToken) bool { return t.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.integrationTokens = slices.Delete(s.integrationTokens, i, i+1)
+	return nil
+}
+
+func (r *integrationTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.integrationTokens {
+		if s.integrationTokens[i].ID == id {
+			s.integrationTokens[i].LastUsedAt = &usedAt
+		}
+	}
+	return nil
+}
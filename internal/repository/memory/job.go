@@ -0,0 +1,86 @@
+package memory
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"sort"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type jobRepository struct {
+	base
+}
+
+// NewJobRepository creates a new in-memory job repository
+func NewJobRepository(store *Store) repository.JobRepository {
+	return &jobRepository{base{store}}
+}
+
+func (r *jobRepository) SaveJob(ctx context.Context, job *models.Job) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.jobs[job.Name] = models.Job{Name: job.Name, Schedule: job.Schedule}
+	return nil
+}
+
+func (r *jobRepository) CreateRun(ctx context.Context, run *models.JobRun) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Mirrors the foreign key on job_runs.job_name
+	if _, ok := s.jobs[run.JobName]; !ok {
+		return errors.New("job does not exist")
+	}
+	stored := *run
+	stored.TriggeredBy = clonePtr(run.TriggeredBy)
+	stored.FinishedAt = clonePtr(run.FinishedAt)
+	s.jobRuns = append(s.jobRuns, stored)
+	return nil
+}
+
+func (r *jobRepository) FinishRun(ctx context.Context, run *models.JobRun) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.jobRuns, func(jr models.JobRun) bool { return jr.ID == run.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.jobRuns[i].Status = run.Status
+	s.jobRuns[i].Error = run.Error
+	s.jobRuns[i].FinishedAt = clonePtr(run.FinishedAt)
+	return nil
+}
+
+func (r *jobRepository) ListRuns(ctx context.Context, filter repository.JobRunFilter) ([]models.JobRun, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	runs := make([]models.JobRun, 0)
+	for _, run := range s.jobRuns {
+		if filter.JobName != "" && run.JobName != filter.JobName {
+			continue
+		}
+		run.TriggeredBy = clonePtr(run.TriggeredBy)
+		run.FinishedAt = clonePtr(run.FinishedAt)
+		runs = append(runs, run)
+	}
+	sort.SliceStable(runs, func(i, j int) bool { return runs[i].StartedAt.After(runs[j].StartedAt) })
+	return page(runs, filter.Limit, filter.Offset), nil
+}
+
+func (r *jobRepository) TotalRuns(ctx context.Context, filter repository.JobRunFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	items, err := r.ListRuns(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
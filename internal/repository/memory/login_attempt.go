@@ -0,0 +1,208 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// loginAttempt is a row of the login_attempts table
+type loginAttempt struct {
+	id uuid.UUID
+	// userID is uuid.Nil for failed logins with usernames no user has
+	userID     uuid.UUID
+	username   string
+	successful bool
+	ipAddress  string
+	userAgent  string
+	// fingerprint identifies the device of successful logins
+	fingerprint string
+	createdAt   time.Time
+}
+
+type loginAttemptRepository struct {
+	store *Store
+}
+
+// NewLoginAttemptRepository creates a new in-memory login attempt repository
+func NewLoginAttemptRepository(store *Store) repository.LoginAttemptRepository {
+	return &loginAttemptRepository{store: store}
+}
+
+// username returns the username of the user with id, including deleted users. s.mu must
+// be held.
+func (s *Store) username(id uuid.UUID) string {
+	for _, u := range s.users {
+		if u.ID == id {
+			return u.Username
+		}
+	}
+	return ""
+}
+
+func (r *loginAttemptRepository) Create(ctx context.Context, userID uuid.UUID, successful bool, ipAddress string, createdAt time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID, true) {
+		return repository.ErrNotFound
+	}
+	s.loginAttempts = append(s.loginAttempts, loginAttempt{
+		id:         uuid.New(),
+		userID:     userID,
+		username:   s.username(userID),
+		successful: successful,
+		ipAddress:  ipAddress,
+		createdAt:  createdAt,
+	})
+	return nil
+}
+
+func (r *loginAttemptRepository) CreateFailure(ctx context.Context, username, ipAddress string, createdAt time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	attempt := loginAttempt{
+		id:        uuid.New(),
+		username:  username,
+		ipAddress: ipAddress,
+		createdAt: createdAt,
+	}
+	if i := s.findUser(func(u *models.User) bool { return u.Username == username }); i >= 0 {
+		attempt.userID = s.users[i].ID
+	}
+	s.loginAttempts = append(s.loginAttempts, attempt)
+	return nil
+}
+
+func (r *loginAttemptRepository) CreateSuccess(ctx context.Context, userID uuid.UUID, client models.SessionClient, fingerprint string, createdAt time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID, true) {
+		return repository.ErrNotFound
+	}
+	s.loginAttempts = append(s.loginAttempts, loginAttempt{
+		id:          uuid.New(),
+		userID:      userID,
+		username:    s.username(userID),
+		successful:  true,
+		ipAddress:   client.IPAddress,
+		userAgent:   client.UserAgent,
+		fingerprint: fingerprint,
+		createdAt:   createdAt,
+	})
+	return nil
+}
+
+func (r *loginAttemptRepository) LoginHistory(ctx context.Context, userID uuid.UUID, fingerprint, ipAddress string) (models.LoginHistory, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	history := models.LoginHistory{FirstLogin: true}
+	for _, attempt := range s.loginAttempts {
+		if attempt.userID != userID || !attempt.successful {
+			continue
+		}
+		history.FirstLogin = false
+		history.KnownDevice = history.KnownDevice || attempt.fingerprint == fingerprint
+		history.KnownIP = history.KnownIP || attempt.ipAddress == ipAddress
+	}
+	return history, nil
+}
+
+func (r *loginAttemptRepository) GetRecentAttempts(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.userExists(userID, true) {
+		return 0, repository.ErrNotFound
+	}
+	count := 0
+	for _, attempt := range s.loginAttempts {
+		if attempt.userID == userID && !attempt.successful && !attempt.createdAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *loginAttemptRepository) GetRecentFailures(ctx context.Context, username string, since time.Time) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, attempt := range s.loginAttempts {
+		if attempt.username == username && !attempt.successful && !attempt.createdAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *loginAttemptRepository) ListRecentAttempts(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.LoginAttempt, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.userExists(userID, true) {
+		return nil, repository.ErrNotFound
+	}
+	attempts := []models.LoginAttempt{}
+	for _, attempt := range s.loginAttempts {
+		if attempt.userID == userID && !attempt.successful && !attempt.createdAt.Before(since) {
+			attempts = append(attempts, models.LoginAttempt{
+				ID:        attempt.id,
+				UserID:    attempt.userID,
+				Success:   attempt.successful,
+				IP:        attempt.ipAddress,
+				CreatedAt: attempt.createdAt,
+			})
+		}
+	}
+	slices.SortStableFunc(attempts, func(a, b models.LoginAttempt) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return attempts, nil
+}
+
+func (r *loginAttemptRepository) ClearAttempts(ctx context.Context, userID uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID, true) {
+		return repository.ErrNotFound
+	}
+	username := s.username(userID)
+	before := len(s.loginAttempts)
+	s.loginAttempts = slices.DeleteFunc(s.loginAttempts, func(attempt loginAttempt) bool {
+		return (attempt.userID == userID || attempt.username == username) && !attempt.successful
+	})
+	if len(s.loginAttempts) == before {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *loginAttemptRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.loginAttempts)
+	s.loginAttempts = slices.DeleteFunc(s.loginAttempts, func(attempt loginAttempt) bool {
+		return attempt.createdAt.Before(before)
+	})
+	return int64(count - len(s.loginAttempts)), nil
+}
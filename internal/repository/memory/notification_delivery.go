@@ -0,0 +1,101 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type notificationDeliveryRepository struct {
+	base
+}
+
+// NewNotificationDeliveryRepository creates a new in-memory notification delivery repository
+func NewNotificationDeliveryRepository(store *Store) repository.NotificationDeliveryRepository {
+	return &notificationDeliveryRepository{base{store}}
+}
+
+func cloneDelivery(delivery models.NotificationDelivery) models.NotificationDelivery {
+	delivery.DeviceTokenID = clonePtr(delivery.DeviceTokenID)
+	delivery.TargetID = clonePtr(delivery.TargetID)
+	delivery.Error = clonePtr(delivery.Error)
+	delivery.DeliveredAt = clonePtr(delivery.DeliveredAt)
+	return delivery
+}
+
+func (r *notificationDeliveryRepository) Create(ctx context.Context, delivery *models.NotificationDelivery) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delivery.ID = uuid.New()
+	if delivery.Status == "" {
+		delivery.Status = models.DeliveryStatusPending
+	}
+	delivery.CreatedAt = time.Now()
+	s.notificationDeliveries = append(s.notificationDeliveries, cloneDelivery(*delivery))
+	return nil
+}
+
+func (r *notificationDeliveryRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.DeliveryStatus, errMsg *string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.notificationDeliveries {
+		delivery := &s.notificationDeliveries[i]
+		if delivery.ID != id {
+			continue
+		}
+		delivery.Status = status
+		delivery.Error = clonePtr(errMsg)
+		delivery.DeliveredAt = nil
+		if status == models.DeliveryStatusSent {
+			now := time.Now()
+			delivery.DeliveredAt = &now
+		}
+		return nil
+	}
+	return repository.ErrNotFound
+}
+
+func (r *notificationDeliveryRepository) List(ctx context.Context, filter repository.NotificationDeliveryFilter) ([]models.NotificationDelivery, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := make([]models.NotificationDelivery, 0)
+	for _, delivery := range s.notificationDeliveries {
+		if filter.UserID != nil && delivery.UserID != *filter.UserID {
+			continue
+		}
+		if filter.Channel != nil && delivery.Channel != *filter.Channel {
+			continue
+		}
+		if filter.AlertType != nil && delivery.AlertType != *filter.AlertType {
+			continue
+		}
+		if filter.Status != nil && delivery.Status != *filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, cloneDelivery(delivery))
+	}
+
+	slices.SortStableFunc(deliveries, func(a, b models.NotificationDelivery) int {
+		return compareTime(b.CreatedAt, a.CreatedAt)
+	})
+	return page(deliveries, filter.Limit, filter.Offset), nil
+}
+
+func (r *notificationDeliveryRepository) Total(ctx context.Context, filter repository.NotificationDeliveryFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	items, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
@@ -0,0 +1,70 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type notificationPreferenceRepository struct {
+	base
+}
+
+// NewNotificationPreferenceRepository creates a new in-memory notification preference repository
+func NewNotificationPreferenceRepository(store *Store) repository.NotificationPreferenceRepository {
+	return &notificationPreferenceRepository{base{store}}
+}
+
+func (r *notificationPreferenceRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefs := make([]models.NotificationPreference, 0)
+	for _, pref := range s.notificationPreferences {
+		if pref.UserID == userID {
+			prefs = append(prefs, pref)
+		}
+	}
+	slices.SortFunc(prefs, func(a, b models.NotificationPreference) int {
+		if c := compareString(string(a.Channel), string(b.Channel)); c != 0 {
+			return c
+		}
+		return compareString(string(a.AlertType), string(b.AlertType))
+	})
+	return prefs, nil
+}
+
+func (r *notificationPreferenceRepository) Upsert(ctx context.Context, pref *models.NotificationPreference) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pref.UpdatedAt = time.Now()
+	i := slices.IndexFunc(s.notificationPreferences, func(p models.NotificationPreference) bool {
+		return p.UserID == pref.UserID && p.Channel == pref.Channel && p.AlertType == pref.AlertType
+	})
+	if i < 0 {
+		s.notificationPreferences = append(s.notificationPreferences, *pref)
+	} else {
+		s.notificationPreferences[i] = *pref
+	}
+	return nil
+}
+
+func (r *notificationPreferenceRepository) IsEnabled(ctx context.Context, userID uuid.UUID, channel models.NotificationChannel, alertType models.NotificationAlertType) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, pref := range s.notificationPreferences {
+		if pref.UserID == userID && pref.Channel == channel && pref.AlertType == alertType {
+			return pref.Enabled, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,106 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type notificationTargetRepository struct {
+	base
+}
+
+// NewNotificationTargetRepository creates a new in-memory notification target repository
+func NewNotificationTargetRepository(store *Store) repository.NotificationTargetRepository {
+	return &notificationTargetRepository{base{store}}
+}
+
+func cloneTarget(target models.NotificationTarget) models.NotificationTarget {
+	target.WebhookURL = clonePtr(target.WebhookURL)
+	target.BotToken = clonePtr(target.BotToken)
+	target.ChatID = clonePtr(target.ChatID)
+	return target
+}
+
+func (r *notificationTargetRepository) Create(ctx context.Context, target *models.NotificationTarget) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(target.UserID, false) {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	target.ID = uuid.New()
+	target.CreatedAt = now
+	target.UpdatedAt = now
+	s.notificationTargets = append(s.notificationTargets, cloneTarget(*target))
+	return nil
+}
+
+func (r *notificationTargetRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.NotificationTarget, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, target := range s.notificationTargets {
+		if target.ID == id {
+			target = cloneTarget(target)
+			return &target, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *notificationTargetRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.NotificationTarget, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	targets := make([]models.NotificationTarget, 0)
+	for _, target := range s.notificationTargets {
+		if target.UserID == userID {
+			targets = append(targets, cloneTarget(target))
+		}
+	}
+	slices.SortStableFunc(targets, func(a, b models.NotificationTarget) int { return compareString(a.Name, b.Name) })
+	return targets, nil
+}
+
+func (r *notificationTargetRepository) Update(ctx context.Context, target *models.NotificationTarget) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.notificationTargets, func(t models.NotificationTarget) bool { return t.ID == target.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+
+	stored := &s.notificationTargets[i]
+	stored.Name = target.Name
+	stored.WebhookURL = clonePtr(target.WebhookURL)
+	stored.BotToken = clonePtr(target.BotToken)
+	stored.ChatID = clonePtr(target.ChatID)
+	stored.UpdatedAt = time.Now()
+	*target = cloneTarget(*stored)
+	return nil
+}
+
+func (r *notificationTargetRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.notificationTargets, func(t models.NotificationTarget) bool { return t.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.notificationTargets = slices.Delete(s.notificationTargets, i, i+1)
+	return nil
+}
@@ -0,0 +1,260 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type organizationRepository struct {
+	base
+}
+
+// NewOrganizationRepository creates a new in-memory organization repository
+func NewOrganizationRepository(store *Store) repository.OrganizationRepository {
+	return &organizationRepository{base{store}}
+}
+
+// findOrganization returns the position of the organization with id, or -1. s.mu must be held.
+func (s *Store) findOrganization(id uuid.UUID) int {
+	return slices.IndexFunc(s.organizations, func(o models.Organization) bool { return o.ID == id })
+}
+
+// findMember returns the position of a membership, or -1. s.mu must be held.
+func (s *Store) findMember(orgID, userID uuid.UUID) int {
+	return slices.IndexFunc(s.organizationMembers, func(m models.OrganizationMember) bool {
+		return m.OrganizationID == orgID && m.UserID == userID
+	})
+}
+
+// loadMember returns a membership with the member's username, and false when the member is
+// deleted like the join in PostgreSQL leaves them out. s.mu must be held.
+func (s *Store) loadMember(member models.OrganizationMember) (models.OrganizationMember, bool) {
+	i := s.findUser(func(u *models.User) bool { return u.ID == member.UserID })
+	if i < 0 {
+		return member, false
+	}
+	member.Username = s.users[i].Username
+	return member, true
+}
+
+func (r *organizationRepository) Create(ctx context.Context, org *models.Organization, ownerID uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(ownerID, false) {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	org.ID = uuid.New()
+	org.CreatedAt = now
+	org.UpdatedAt = now
+	s.organizations = append(s.organizations, *org)
+	s.organizationMembers = append(s.organizationMembers, models.OrganizationMember{
+		OrganizationID: org.ID,
+		UserID:         ownerID,
+		Role:           models.OrganizationRoleOwner,
+		CreatedAt:      now,
+	})
+	return nil
+}
+
+func (r *organizationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findOrganization(id)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	org := s.organizations[i]
+	return &org, nil
+}
+
+func (r *organizationRepository) List(ctx context.Context, filter repository.OrganizationFilter) ([]models.Organization, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	orgs := make([]models.Organization, 0)
+	for _, org := range s.organizations {
+		if filter.UserID != nil && s.findMember(org.ID, *filter.UserID) < 0 {
+			continue
+		}
+		if filter.Search != nil && !containsFold(org.Name, *filter.Search) {
+			continue
+		}
+		orgs = append(orgs, org)
+	}
+	slices.SortStableFunc(orgs, func(a, b models.Organization) int {
+		if c := compareString(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return compareString(a.ID.String(), b.ID.String())
+	})
+	return page(orgs, filter.Limit, filter.Offset), nil
+}
+
+func (r *organizationRepository) Total(ctx context.Context, filter repository.OrganizationFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	orgs, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(orgs), nil
+}
+
+func (r *organizationRepository) Update(ctx context.Context, org *models.Organization) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findOrganization(org.ID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.organizations[i].Name = org.Name
+	s.organizations[i].UpdatedAt = time.Now()
+	*org = s.organizations[i]
+	return nil
+}
+
+func (r *organizationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findOrganization(id)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.organizations = slices.Delete(s.organizations, i, i+1)
+	s.organizationMembers = slices.DeleteFunc(s.organizationMembers, func(m models.OrganizationMember) bool { return m.OrganizationID == id })
+	s.tariffs = slices.DeleteFunc(s.tariffs, func(t models.Tariff) bool { return t.OrganizationID != nil && *t.OrganizationID == id })
+	return nil
+}
+
+func (r *organizationRepository) GetMember(ctx context.Context, orgID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findMember(orgID, userID)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	member, ok := s.loadMember(s.organizationMembers[i])
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &member, nil
+}
+
+func (r *organizationRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.OrganizationMember, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	members := make([]models.OrganizationMember, 0)
+	for _, m := range s.organizationMembers {
+		if m.OrganizationID != orgID {
+			continue
+		}
+		if member, ok := s.loadMember(m); ok {
+			members = append(members, member)
+		}
+	}
+	slices.SortStableFunc(members, func(a, b models.OrganizationMember) int { return compareString(a.Username, b.Username) })
+	return members, nil
+}
+
+func (r *organizationRepository) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findOrganization(member.OrganizationID) < 0 || !s.userExists(member.UserID, false) {
+		return repository.ErrNotFound
+	}
+	if s.findMember(member.OrganizationID, member.UserID) >= 0 {
+		return repository.ErrDuplicateEntry
+	}
+
+	member.CreatedAt = time.Now()
+	s.organizationMembers = append(s.organizationMembers, *member)
+	*member, _ = s.loadMember(*member)
+	return nil
+}
+
+// keepsOwner returns ErrLastOwner when the member at i is the organization's only owner and
+// would stop being one. s.mu must be held.
+func (s *Store) keepsOwner(i int, newRole *models.OrganizationRole) error {
+	member := s.organizationMembers[i]
+	if member.Role != models.OrganizationRoleOwner || (newRole != nil && *newRole == models.OrganizationRoleOwner) {
+		return nil
+	}
+	for j, m := range s.organizationMembers {
+		if j != i && m.OrganizationID == member.OrganizationID && m.Role == models.OrganizationRoleOwner {
+			return nil
+		}
+	}
+	return repository.ErrLastOwner
+}
+
+func (r *organizationRepository) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role models.OrganizationRole) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findMember(orgID, userID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	if err := s.keepsOwner(i, &role); err != nil {
+		return err
+	}
+	s.organizationMembers[i].Role = role
+	return nil
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findMember(orgID, userID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	if err := s.keepsOwner(i, nil); err != nil {
+		return err
+	}
+	s.organizationMembers = slices.Delete(s.organizationMembers, i, i+1)
+	return nil
+}
+
+func (r *organizationRepository) SharesOrganization(ctx context.Context, userID, memberID uuid.UUID, roles ...models.OrganizationRole) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.userExists(memberID, false) {
+		return false, nil
+	}
+	for _, mine := range s.organizationMembers {
+		if mine.UserID != userID || (len(roles) > 0 && !slices.Contains(roles, mine.Role)) {
+			continue
+		}
+		if s.findMember(mine.OrganizationID, memberID) >= 0 {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,90 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Passwords are checked against the same window as the PostgreSQL repository
+const reuseWindow = 90 * 24 * time.Hour
+
+type passwordHistoryRepository struct {
+	base
+}
+
+// NewPasswordHistoryRepository creates a new in-memory password history repository
+func NewPasswordHistoryRepository(store *Store) repository.PasswordHistoryRepository {
+	return &passwordHistoryRepository{base{store}}
+}
+
+func (r *passwordHistoryRepository) Add(ctx context.Context, userID uuid.UUID, passwordHash string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.passwordHistory = append(s.passwordHistory, models.PasswordHistory{
+		ID:           uuid.New(),
+		UserID:       userID,
+		PasswordHash: passwordHash,
+		CreatedAt:    time.Now(),
+	})
+	return nil
+}
+
+// history returns the entries of the user, newest first. s.mu must be held.
+func (s *Store) history(userID uuid.UUID) []models.PasswordHistory {
+	history := make([]models.PasswordHistory, 0)
+	for _, entry := range s.passwordHistory {
+		if entry.UserID == userID {
+			history = append(history, entry)
+		}
+	}
+	slices.SortStableFunc(history, func(a, b models.PasswordHistory) int { return compareTime(b.CreatedAt, a.CreatedAt) })
+	return history
+}
+
+func (r *passwordHistoryRepository) CheckReuse(ctx context.Context, userID uuid.UUID, newPasswordHash string, depth int) error {
+	s := r.store
+	s.mu.RLock()
+	history := s.history(userID)
+	s.mu.RUnlock()
+
+	cutoff := time.Now().Add(-reuseWindow)
+	checked := 0
+	for _, entry := range history {
+		if checked == depth || !entry.CreatedAt.After(cutoff) {
+			break
+		}
+		checked++
+		if bcrypt.CompareHashAndPassword([]byte(entry.PasswordHash), []byte(newPasswordHash)) == nil {
+			return repository.ErrPasswordReuse
+		}
+	}
+	return nil
+}
+
+func (r *passwordHistoryRepository) CleanupOld(ctx context.Context, olderThan time.Duration) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cutoff := time.Now().Add(-olderThan)
+	s.passwordHistory = slices.DeleteFunc(s.passwordHistory, func(entry models.PasswordHistory) bool {
+		return entry.CreatedAt.Before(cutoff)
+	})
+	return nil
+}
+
+func (r *passwordHistoryRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.PasswordHistory, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.history(userID), nil
+}
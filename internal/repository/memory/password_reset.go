@@ -0,0 +1,105 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type passwordResetRepository struct {
+	store *Store
+}
+
+// NewPasswordResetRepository creates a new in-memory password reset repository
+func NewPasswordResetRepository(store *Store) repository.PasswordResetRepository {
+	return &passwordResetRepository{store: store}
+}
+
+func (r *passwordResetRepository) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*repository.PasswordReset, error) {
+	if ttl <= 0 {
+		ttl = repository.ResetTokenExpiration
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID, true) {
+		return nil, repository.ErrNotFound
+	}
+
+	now := time.Now()
+	reset := repository.PasswordReset{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Token:     uuid.New().String(),
+		ExpiresAt: now.Add(ttl),
+		CreatedAt: now,
+	}
+	s.passwordResets = append(s.passwordResets, reset)
+	return &reset, nil
+}
+
+func (r *passwordResetRepository) GetByToken(ctx context.Context, token string) (*repository.PasswordReset, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, reset := range s.passwordResets {
+		if reset.Token != token {
+			continue
+		}
+		if reset.UsedAt != nil {
+			return nil, repository.ErrResetTokenUsed
+		}
+		if time.Now().After(reset.ExpiresAt) {
+			return nil, repository.ErrResetTokenExpired
+		}
+		return &reset, nil
+	}
+	return nil, repository.ErrResetTokenInvalid
+}
+
+func (r *passwordResetRepository) MarkAsUsed(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.passwordResets {
+		if s.passwordResets[i].ID == id && s.passwordResets[i].UsedAt == nil {
+			now := time.Now()
+			s.passwordResets[i].UsedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrResetTokenInvalid
+}
+
+func (r *passwordResetRepository) CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, reset := range s.passwordResets {
+		if reset.UserID == userID && !reset.CreatedAt.Before(since) {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	count := len(s.passwordResets)
+	s.passwordResets = slices.DeleteFunc(s.passwordResets, func(t repository.PasswordReset) bool {
+		return t.ExpiresAt.Before(before)
+	})
+	return int64(count - len(s.passwordResets)), nil
+}
@@ -0,0 +1,142 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type priceAlertRepository struct {
+	base
+}
+
+// NewPriceAlertRepository creates a new in-memory price alert repository
+func NewPriceAlertRepository(store *Store) repository.PriceAlertRepository {
+	return &priceAlertRepository{base{store}}
+}
+
+func clonePriceAlert(alert models.PriceAlert) models.PriceAlert {
+	alert.WindowStart = clonePtr(alert.WindowStart)
+	alert.WindowEnd = clonePtr(alert.WindowEnd)
+	alert.LastTriggeredAt = clonePtr(alert.LastTriggeredAt)
+	return alert
+}
+
+// findPriceAlert returns the position of the alert with id, or -1. s.mu must be held.
+func (s *Store) findPriceAlert(id uuid.UUID) int {
+	return slices.IndexFunc(s.priceAlerts, func(a models.PriceAlert) bool { return a.ID == id })
+}
+
+func (r *priceAlertRepository) Create(ctx context.Context, alert *models.PriceAlert) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(alert.UserID, false) ||
+		s.findZone(func(z *models.Zone) bool { return z.ID == alert.ZoneID }) < 0 ||
+		s.findCurrency(func(c *models.Currency) bool { return c.ID == alert.CurrencyID }) < 0 {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	alert.ID = uuid.New()
+	alert.LastTriggeredAt = nil
+	alert.CreatedAt = now
+	alert.UpdatedAt = now
+	s.priceAlerts = append(s.priceAlerts, clonePriceAlert(*alert))
+	return nil
+}
+
+func (r *priceAlertRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.PriceAlert, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findPriceAlert(id)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	alert := clonePriceAlert(s.priceAlerts[i])
+	return &alert, nil
+}
+
+func (r *priceAlertRepository) list(match func(a *models.PriceAlert) bool) []models.PriceAlert {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	alerts := make([]models.PriceAlert, 0)
+	for _, alert := range s.priceAlerts {
+		if match(&alert) {
+			alerts = append(alerts, clonePriceAlert(alert))
+		}
+	}
+	slices.SortStableFunc(alerts, func(a, b models.PriceAlert) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return compareString(a.ID.String(), b.ID.String())
+	})
+	return alerts
+}
+
+func (r *priceAlertRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.PriceAlert, error) {
+	return r.list(func(a *models.PriceAlert) bool { return a.UserID == userID }), nil
+}
+
+func (r *priceAlertRepository) ListEnabled(ctx context.Context, zoneID, currencyID uuid.UUID) ([]models.PriceAlert, error) {
+	s := r.store
+	return r.list(func(a *models.PriceAlert) bool {
+		return a.ZoneID == zoneID && a.CurrencyID == currencyID && a.Enabled && s.userExists(a.UserID, false)
+	}), nil
+}
+
+func (r *priceAlertRepository) Update(ctx context.Context, alert *models.PriceAlert) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findPriceAlert(alert.ID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	stored := &s.priceAlerts[i]
+	stored.Direction = alert.Direction
+	stored.Threshold = alert.Threshold
+	stored.WindowStart = clonePtr(alert.WindowStart)
+	stored.WindowEnd = clonePtr(alert.WindowEnd)
+	stored.Enabled = alert.Enabled
+	stored.UpdatedAt = time.Now()
+	*alert = clonePriceAlert(*stored)
+	return nil
+}
+
+func (r *priceAlertRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findPriceAlert(id)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.priceAlerts = slices.Delete(s.priceAlerts, i, i+1)
+	return nil
+}
+
+func (r *priceAlertRepository) MarkTriggered(ctx context.Context, id uuid.UUID, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findPriceAlert(id)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.priceAlerts[i].LastTriggeredAt = &at
+	return nil
+}
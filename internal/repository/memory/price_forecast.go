@@ -0,0 +1,89 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// priceForecastKey identifies the forecast of a source for a time, zone and currency
+type priceForecastKey struct {
+	timestamp  int64
+	zoneID     uuid.UUID
+	currencyID uuid.UUID
+	source     string
+}
+
+type priceForecastRepository struct {
+	base
+}
+
+// NewPriceForecastRepository creates a new in-memory price forecast repository
+func NewPriceForecastRepository(store *Store) repository.PriceForecastRepository {
+	return &priceForecastRepository{base{store}}
+}
+
+func (r *priceForecastRepository) Upsert(ctx context.Context, forecasts []models.PriceForecast) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Like the foreign keys, an unknown zone or currency stores none of the forecasts
+	for _, f := range forecasts {
+		if s.findZone(func(z *models.Zone) bool { return z.ID == f.ZoneID }) < 0 ||
+			s.findCurrency(func(c *models.Currency) bool { return c.ID == f.CurrencyID }) < 0 {
+			return repository.ErrNotFound
+		}
+	}
+
+	now := time.Now()
+	for i := range forecasts {
+		f := &forecasts[i]
+		f.CreatedAt = now
+		s.priceForecasts[priceForecastKey{f.Timestamp.UnixNano(), f.ZoneID, f.CurrencyID, f.Source}] = *f
+	}
+	return nil
+}
+
+func (r *priceForecastRepository) List(ctx context.Context, filter repository.PriceForecastFilter) ([]models.PriceForecast, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	forecasts := make([]models.PriceForecast, 0)
+	for _, f := range s.priceForecasts {
+		if f.ZoneID != filter.ZoneID || f.CurrencyID != filter.CurrencyID ||
+			f.Timestamp.Before(filter.StartTime) || !f.Timestamp.Before(filter.EndTime) {
+			continue
+		}
+		if filter.Source != "" && f.Source != filter.Source {
+			continue
+		}
+		forecasts = append(forecasts, f)
+	}
+
+	if !filter.Latest {
+		slices.SortFunc(forecasts, func(a, b models.PriceForecast) int {
+			return cmp.Or(compareTime(a.Timestamp, b.Timestamp), cmp.Compare(a.Source, b.Source))
+		})
+		return forecasts, nil
+	}
+	slices.SortFunc(forecasts, func(a, b models.PriceForecast) int {
+		return cmp.Or(compareTime(a.Timestamp, b.Timestamp), compareTime(b.CreatedAt, a.CreatedAt), cmp.Compare(a.Source, b.Source))
+	})
+	return slices.CompactFunc(forecasts, func(a, b models.PriceForecast) bool { return a.Timestamp.Equal(b.Timestamp) }), nil
+}
+
+// deletePriceForecasts removes the forecasts matching, s.mu must be held
+func (s *Store) deletePriceForecasts(match func(key priceForecastKey) bool) {
+	for key := range s.priceForecasts {
+		if match(key) {
+			delete(s.priceForecasts, key)
+		}
+	}
+}
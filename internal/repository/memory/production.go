@@ -0,0 +1,76 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type productionRepository struct {
+	base
+}
+
+// NewProductionRepository creates a new in-memory production repository
+func NewProductionRepository(store *Store) repository.ProductionRepository {
+	return &productionRepository{base{store}}
+}
+
+func (r *productionRepository) CreateBatch(ctx context.Context, records []models.ProductionRecord) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	for i := range records {
+		record := &records[i]
+		key := consumptionKey{record.UserID, record.MeterID, record.Timestamp.UnixNano()}
+		if existing, ok := s.production[key]; ok {
+			record.CreatedAt = existing.CreatedAt
+		} else {
+			record.CreatedAt = now
+		}
+		record.UpdatedAt = now
+		s.production[key] = *record
+	}
+	return nil
+}
+
+func (r *productionRepository) List(ctx context.Context, filter repository.ProductionFilter) ([]models.ProductionRecord, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	records := make([]models.ProductionRecord, 0)
+	for _, record := range s.production {
+		if record.UserID != filter.UserID {
+			continue
+		}
+		if filter.MeterID != nil && record.MeterID != *filter.MeterID {
+			continue
+		}
+		if filter.StartTime != nil && record.Timestamp.Before(*filter.StartTime) {
+			continue
+		}
+		if filter.EndTime != nil && record.Timestamp.After(*filter.EndTime) {
+			continue
+		}
+		records = append(records, record)
+	}
+
+	slices.SortFunc(records, func(a, b models.ProductionRecord) int {
+		return cmp.Or(compareTime(a.Timestamp, b.Timestamp), cmp.Compare(a.MeterID, b.MeterID))
+	})
+	return page(records, filter.Limit, filter.Offset), nil
+}
+
+func (r *productionRepository) Total(ctx context.Context, filter repository.ProductionFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	records, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(records), nil
+}
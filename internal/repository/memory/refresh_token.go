@@ -0,0 +1,252 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type refreshTokenRepository struct {
+	base
+}
+
+// NewRefreshTokenRepository creates a new in-memory refresh token repository
+func NewRefreshTokenRepository(store *Store) repository.RefreshTokenRepository {
+	return &refreshTokenRepository{base{store}}
+}
+
+func (r *refreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, client models.SessionClient) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID, true) {
+		return repository.ErrNotFound
+	}
+	if slices.ContainsFunc(s.refreshTokens, func(t models.RefreshToken) bool { return t.Token == token }) {
+		return repository.ErrConflict
+	}
+
+	id := uuid.New()
+	now := time.Now()
+	s.refreshTokens = append(s.refreshTokens, models.RefreshToken{
+		ID:               id,
+		UserID:           userID,
+		Token:            token,
+		FamilyID:         id,
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		SessionCreatedAt: now,
+		LastUsedAt:       now,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        now,
+	})
+	return nil
+}
+
+func cloneRefreshToken(t models.RefreshToken) *models.RefreshToken {
+	t.RevokedAt = clonePtr(t.RevokedAt)
+	t.ReplacedBy = clonePtr(t.ReplacedBy)
+	return &t
+}
+
+func (r *refreshTokenRepository) GetByToken(ctx context.Context, token string) (*models.RefreshToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.refreshTokens {
+		if t.Token != token || t.RevokedAt != nil {
+			continue
+		}
+		if time.Now().After(t.ExpiresAt) {
+			return nil, repository.ErrTokenExpired
+		}
+		return cloneRefreshToken(t), nil
+	}
+	return nil, repository.ErrTokenInvalid
+}
+
+func (r *refreshTokenRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.RefreshToken, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if !s.userExists(userID, true) {
+		return nil, repository.ErrNotFound
+	}
+
+	tokens := make([]models.RefreshToken, 0)
+	for _, t := range s.refreshTokens {
+		if t.UserID == userID {
+			tokens = append(tokens, *cloneRefreshToken(t))
+		}
+	}
+	slices.SortStableFunc(tokens, func(a, b models.RefreshToken) int { return compareTime(b.CreatedAt, a.CreatedAt) })
+	return tokens, nil
+}
+
+// deleteTokens removes the tokens matching and returns how many there were
+func (r *refreshTokenRepository) deleteTokens(match func(t models.RefreshToken) bool) int {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	before := len(s.refreshTokens)
+	s.refreshTokens = slices.DeleteFunc(s.refreshTokens, match)
+	return before - len(s.refreshTokens)
+}
+
+func (r *refreshTokenRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	if r.deleteTokens(func(t models.RefreshToken) bool { return t.ID == id }) == 0 {
+		return repository.ErrTokenInvalid
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) DeleteByToken(ctx context.Context, token string) error {
+	if r.deleteTokens(func(t models.RefreshToken) bool { return t.Token == token }) == 0 {
+		return repository.ErrTokenInvalid
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
+	r.deleteTokens(func(t models.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	return int64(r.deleteTokens(func(t models.RefreshToken) bool { return t.ExpiresAt.Before(before) })), nil
+}
+
+func (r *refreshTokenRepository) IsValid(ctx context.Context, token string) (bool, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, t := range s.refreshTokens {
+		if t.Token == token {
+			return t.RevokedAt == nil && time.Now().Before(t.ExpiresAt), nil
+		}
+	}
+	return false, repository.ErrTokenInvalid
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, token, newToken string, expiresAt, at time.Time, client models.SessionClient) (*models.RefreshToken, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.refreshTokens, func(t models.RefreshToken) bool { return t.Token == token })
+	if i < 0 {
+		return nil, repository.ErrTokenInvalid
+	}
+	current := s.refreshTokens[i]
+	if current.ReplacedBy != nil {
+		s.revokeTokens(at, func(t models.RefreshToken) bool { return t.FamilyID == current.FamilyID })
+		return cloneRefreshToken(current), repository.ErrTokenReused
+	}
+	if current.RevokedAt != nil {
+		return nil, repository.ErrTokenInvalid
+	}
+	if at.After(current.ExpiresAt) {
+		return nil, repository.ErrTokenExpired
+	}
+
+	rotated := models.RefreshToken{
+		ID:               uuid.New(),
+		UserID:           current.UserID,
+		Token:            newToken,
+		FamilyID:         current.FamilyID,
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		SessionCreatedAt: current.SessionCreatedAt,
+		LastUsedAt:       at,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        at,
+	}
+	s.refreshTokens[i].RevokedAt = &at
+	s.refreshTokens[i].ReplacedBy = &rotated.ID
+	s.refreshTokens = append(s.refreshTokens, rotated)
+	return &rotated, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, token string, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.refreshTokens, func(t models.RefreshToken) bool { return t.Token == token })
+	if i < 0 {
+		return repository.ErrTokenInvalid
+	}
+	familyID := s.refreshTokens[i].FamilyID
+	s.revokeTokens(at, func(t models.RefreshToken) bool { return t.FamilyID == familyID })
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeByUserID(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.revokeTokens(at, func(t models.RefreshToken) bool { return t.UserID == userID })
+	return nil
+}
+
+func (r *refreshTokenRepository) ListSessions(ctx context.Context, userID uuid.UUID, at time.Time) ([]models.Session, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]models.Session, 0)
+	for _, t := range s.refreshTokens {
+		if t.UserID != userID || t.RevokedAt != nil || !t.ExpiresAt.After(at) {
+			continue
+		}
+		sessions = append(sessions, models.Session{
+			ID:         t.FamilyID,
+			UserAgent:  t.UserAgent,
+			IPAddress:  t.IPAddress,
+			CreatedAt:  t.SessionCreatedAt,
+			LastUsedAt: t.LastUsedAt,
+			ExpiresAt:  t.ExpiresAt,
+		})
+	}
+	slices.SortStableFunc(sessions, func(a, b models.Session) int {
+		if c := compareTime(b.LastUsedAt, a.LastUsedAt); c != 0 {
+			return c
+		}
+		return compareString(a.ID.String(), b.ID.String())
+	})
+	return sessions, nil
+}
+
+func (r *refreshTokenRepository) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	live := func(t models.RefreshToken) bool {
+		return t.UserID == userID && t.FamilyID == sessionID && t.RevokedAt == nil && t.ExpiresAt.After(at)
+	}
+	if !slices.ContainsFunc(s.refreshTokens, live) {
+		return repository.ErrNotFound
+	}
+	s.revokeTokens(at, live)
+	return nil
+}
+
+// revokeTokens revokes the tokens matching that aren't revoked yet. s.mu must be held.
+func (s *Store) revokeTokens(at time.Time, match func(t models.RefreshToken) bool) {
+	for i, t := range s.refreshTokens {
+		if t.RevokedAt == nil && match(t) {
+			s.refreshTokens[i].RevokedAt = &at
+		}
+	}
+}
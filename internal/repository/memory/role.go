@@ -0,0 +1,224 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type roleRepository struct {
+	base
+}
+
+// NewRoleRepository creates a new in-memory role repository
+func NewRoleRepository(store *Store) repository.RoleRepository {
+	return &roleRepository{base{store}}
+}
+
+// findRole returns the position of the first non-deleted role matching, or -1. s.mu must
+// be held.
+func (s *Store) findRole(match func(r *models.Role) bool) int {
+	for i := range s.roles {
+		if s.roles[i].DeletedAt == nil && match(&s.roles[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findRole(func(other *models.Role) bool { return other.Name == role.Name }) >= 0 {
+		return repository.ErrConflict
+	}
+
+	now := time.Now()
+	role.ID = uuid.New()
+	role.Permissions = []string{}
+	role.TokenVersion = 1
+	role.CreatedAt = now
+	role.UpdatedAt = now
+	role.DeletedAt = nil
+	s.roles = append(s.roles, *role)
+	return nil
+}
+
+func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findRole(func(other *models.Role) bool { return other.ID == role.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	if s.roles[i].IsProtected {
+		return repository.ErrProtectedRole
+	}
+	if s.findRole(func(other *models.Role) bool { return other.Name == role.Name && other.ID != role.ID }) >= 0 {
+		return repository.ErrConflict
+	}
+
+	stored := &s.roles[i]
+	stored.Name = role.Name
+	stored.IsProtected = role.IsProtected
+	stored.IsAdminGroup = role.IsAdminGroup
+	stored.TokenVersion++
+	stored.UpdatedAt = time.Now()
+
+	role.TokenVersion = stored.TokenVersion
+	role.CreatedAt = stored.CreatedAt
+	role.UpdatedAt = stored.UpdatedAt
+	return nil
+}
+
+func (r *roleRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findRole(func(role *models.Role) bool { return role.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	if s.roles[i].IsProtected {
+		return repository.ErrProtectedRole
+	}
+	if s.findUser(func(u *models.User) bool { return u.RoleID == id }) >= 0 {
+		return repository.ErrHasAssociatedRecords
+	}
+
+	now := time.Now()
+	s.roles[i].DeletedAt = &now
+	s.roles[i].UpdatedAt = now
+	return nil
+}
+
+func (r *roleRepository) get(match func(role *models.Role) bool) (*models.Role, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findRole(match)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	role := s.roles[i]
+	role.Permissions = slices.Clone(role.Permissions)
+	return &role, nil
+}
+
+func (r *roleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
+	return r.get(func(role *models.Role) bool { return role.ID == id })
+}
+
+func (r *roleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
+	return r.get(func(role *models.Role) bool { return role.Name == name })
+}
+
+var roleOrder = map[string]func(a, b models.Role) int{
+	"name":       func(a, b models.Role) int { return compareString(a.Name, b.Name) },
+	"created_at": func(a, b models.Role) int { return compareTime(a.CreatedAt, b.CreatedAt) },
+	"updated_at": func(a, b models.Role) int { return compareTime(a.UpdatedAt, b.UpdatedAt) },
+}
+
+func (r *roleRepository) List(ctx context.Context, filter repository.RoleFilter) ([]models.Role, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	roles := make([]models.Role, 0)
+	for _, role := range s.roles {
+		if role.DeletedAt != nil {
+			continue
+		}
+		if filter.Search != nil && !containsFold(role.Name, *filter.Search) {
+			continue
+		}
+		if filter.Protected != nil && role.IsProtected != *filter.Protected {
+			continue
+		}
+		if filter.AdminGroup != nil && role.IsAdminGroup != *filter.AdminGroup {
+			continue
+		}
+		role.Permissions = slices.Clone(role.Permissions)
+		roles = append(roles, role)
+	}
+
+	orderBy := filter.OrderBy
+	if orderBy == "" {
+		orderBy = "name"
+	}
+	if err := sortBy(roles, orderBy, filter.OrderDesc, roleOrder); err != nil {
+		return nil, err
+	}
+	return page(roles, filter.Limit, filter.Offset), nil
+}
+
+func (r *roleRepository) Total(ctx context.Context, filter repository.RoleFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	items, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+// modifiableRole returns the position of the role, or ErrNotFound when it doesn't exist and
+// ErrProtectedRole when it is protected. s.mu must be held.
+func (s *Store) modifiableRole(id uuid.UUID) (int, error) {
+	i := s.findRole(func(role *models.Role) bool { return role.ID == id })
+	if i < 0 {
+		return -1, repository.ErrNotFound
+	}
+	if s.roles[i].IsProtected {
+		return -1, repository.ErrProtectedRole
+	}
+	return i, nil
+}
+
+func (r *roleRepository) GrantPermission(ctx context.Context, roleID uuid.UUID, permission string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.modifiableRole(roleID)
+	if err != nil {
+		return err
+	}
+	role := &s.roles[i]
+	if !slices.Contains(role.Permissions, permission) {
+		role.Permissions = append(role.Permissions, permission)
+		slices.Sort(role.Permissions)
+	}
+	role.TokenVersion++
+	role.UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *roleRepository) RevokePermission(ctx context.Context, roleID uuid.UUID, permission string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i, err := s.modifiableRole(roleID)
+	if err != nil {
+		return err
+	}
+	role := &s.roles[i]
+	j := slices.Index(role.Permissions, permission)
+	if j < 0 {
+		return repository.ErrNotFound
+	}
+	role.Permissions = slices.Delete(role.Permissions, j, j+1)
+	role.TokenVersion++
+	role.UpdatedAt = time.Now()
+	return nil
+}
@@ -0,0 +1,67 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type securityEventRepository struct {
+	base
+}
+
+// NewSecurityEventRepository creates a new in-memory security event repository
+func NewSecurityEventRepository(store *Store) repository.SecurityEventRepository {
+	return &securityEventRepository{base{store}}
+}
+
+func (r *securityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(event.UserID, true) {
+		return repository.ErrUserNotFound
+	}
+	event.ID = uuid.New()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	s.securityEvents = append(s.securityEvents, *event)
+	return nil
+}
+
+func (r *securityEventRepository) ListByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.SecurityEvent, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	events := []models.SecurityEvent{}
+	for _, event := range s.securityEvents {
+		if event.UserID == userID {
+			events = append(events, event)
+		}
+	}
+	slices.SortStableFunc(events, func(a, b models.SecurityEvent) int {
+		return b.CreatedAt.Compare(a.CreatedAt)
+	})
+	return page(events, &limit, &offset), nil
+}
+
+func (r *securityEventRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, event := range s.securityEvents {
+		if event.UserID == userID {
+			count++
+		}
+	}
+	return count, nil
+}
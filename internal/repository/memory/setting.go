@@ -0,0 +1,56 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type settingRepository struct {
+	base
+}
+
+// NewSettingRepository creates a new in-memory setting repository
+func NewSettingRepository(store *Store) repository.SettingRepository {
+	return &settingRepository{base{store}}
+}
+
+func (r *settingRepository) Upsert(ctx context.Context, setting *models.Setting) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	setting.UpdatedAt = time.Now()
+	stored := *setting
+	stored.UpdatedBy = clonePtr(setting.UpdatedBy)
+	s.settings[setting.Key] = stored
+	return nil
+}
+
+func (r *settingRepository) List(ctx context.Context) ([]models.Setting, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := make([]models.Setting, 0, len(s.settings))
+	for _, setting := range s.settings {
+		setting.UpdatedBy = clonePtr(setting.UpdatedBy)
+		settings = append(settings, setting)
+	}
+	slices.SortFunc(settings, func(a, b models.Setting) int { return compareString(a.Key, b.Key) })
+	return settings, nil
+}
+
+func (r *settingRepository) Delete(ctx context.Context, key string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.settings[key]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(s.settings, key)
+	return nil
+}
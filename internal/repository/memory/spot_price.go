@@ -0,0 +1,458 @@
+package memory
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// spotPriceKey is the unique key of a spot price, the hour, zone and currency
+type spotPriceKey struct {
+	timestamp  int64
+	zoneID     uuid.UUID
+	currencyID uuid.UUID
+}
+
+func keyOf(sp *models.SpotPrice) spotPriceKey {
+	return spotPriceKey{sp.Timestamp.UnixNano(), sp.ZoneID, sp.CurrencyID}
+}
+
+type spotPriceRepository struct {
+	base
+}
+
+// NewSpotPriceRepository creates a new in-memory spot price repository
+func NewSpotPriceRepository(store *Store) repository.SpotPriceRepository {
+	return &spotPriceRepository{base{store}}
+}
+
+// upsert inserts the spot price or updates the price stored for its key, recording a
+// revision when the price changes. s.mu must be held.
+func (s *Store) upsertSpotPrice(sp *models.SpotPrice, now time.Time, changedBy *uuid.UUID, source string) {
+	key := keyOf(sp)
+	if id, ok := s.spotPriceKeys[key]; ok {
+		existing := s.spotPrices[id]
+		s.reviseSpotPrice(existing, sp.Price, now, changedBy, source)
+		existing.Price = sp.Price
+		existing.UpdatedAt = now
+		s.spotPrices[id] = existing
+		*sp = existing
+		return
+	}
+
+	sp.ID = uuid.New()
+	sp.CreatedAt = now
+	sp.UpdatedAt = now
+	s.spotPrices[sp.ID] = *sp
+	s.spotPriceKeys[key] = sp.ID
+}
+
+// reviseSpotPrice records the price of sp as a revision when it changes to price, like the
+// trigger on spot_prices does. s.mu must be held.
+func (s *Store) reviseSpotPrice(sp models.SpotPrice, price float64, now time.Time, changedBy *uuid.UUID, source string) {
+	if sp.Price == price {
+		return
+	}
+	s.spotPriceRevisions = append(s.spotPriceRevisions, models.SpotPriceRevision{
+		ID:          uuid.New(),
+		SpotPriceID: sp.ID,
+		Timestamp:   sp.Timestamp,
+		ZoneID:      sp.ZoneID,
+		CurrencyID:  sp.CurrencyID,
+		Price:       sp.Price,
+		NewPrice:    price,
+		ChangedBy:   changedBy,
+		Source:      source,
+		ChangedAt:   now,
+	})
+}
+
+// priceAsOf returns the price sp had at asOf, the price its first later revision replaced
+// or its current price when there is none. s.mu must be held.
+func (s *Store) priceAsOf(sp models.SpotPrice, asOf time.Time) float64 {
+	for _, revision := range s.spotPriceRevisions {
+		if revision.SpotPriceID == sp.ID && revision.ChangedAt.After(asOf) {
+			return revision.Price
+		}
+	}
+	return sp.Price
+}
+
+func (r *spotPriceRepository) Create(ctx context.Context, spotPrice *models.SpotPrice) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.upsertSpotPrice(spotPrice, time.Now(), repository.ChangedBy(ctx), "")
+	return nil
+}
+
+func (r *spotPriceRepository) CreateBatch(ctx context.Context, spotPrices []models.SpotPrice) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	changedBy := repository.ChangedBy(ctx)
+	for i := range spotPrices {
+		s.upsertSpotPrice(&spotPrices[i], now, changedBy, "")
+	}
+	return nil
+}
+
+func (r *spotPriceRepository) Update(ctx context.Context, spotPrice *models.SpotPrice) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.spotPrices[spotPrice.ID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	key := keyOf(spotPrice)
+	if id, ok := s.spotPriceKeys[key]; ok && id != spotPrice.ID {
+		return repository.ErrConflict
+	}
+
+	now := time.Now()
+	delete(s.spotPriceKeys, keyOf(&existing))
+	existing.Timestamp = spotPrice.Timestamp
+	existing.ZoneID = spotPrice.ZoneID
+	existing.CurrencyID = spotPrice.CurrencyID
+	s.reviseSpotPrice(existing, spotPrice.Price, now, repository.ChangedBy(ctx), "")
+	existing.Price = spotPrice.Price
+	existing.UpdatedAt = now
+	s.spotPrices[existing.ID] = existing
+	s.spotPriceKeys[key] = existing.ID
+
+	spotPrice.UpdatedAt = existing.UpdatedAt
+	return nil
+}
+
+func (r *spotPriceRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	existing, ok := s.spotPrices[id]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	delete(s.spotPriceKeys, keyOf(&existing))
+	delete(s.resolvedSources, keyOf(&existing))
+	delete(s.spotPrices, id)
+	s.spotPriceRevisions = slices.DeleteFunc(s.spotPriceRevisions, func(revision models.SpotPriceRevision) bool {
+		return revision.SpotPriceID == id
+	})
+	return nil
+}
+
+func (r *spotPriceRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.SpotPrice, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sp, ok := s.spotPrices[id]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return &sp, nil
+}
+
+var spotPriceOrder = map[string]func(a, b models.SpotPrice) int{
+	"timestamp":  compareSpotPricePosition,
+	"price":      func(a, b models.SpotPrice) int { return compareFloat(a.Price, b.Price) },
+	"created_at": func(a, b models.SpotPrice) int { return compareTime(a.CreatedAt, b.CreatedAt) },
+	"updated_at": func(a, b models.SpotPrice) int { return compareTime(a.UpdatedAt, b.UpdatedAt) },
+}
+
+// compareSpotPricePosition orders spot prices by timestamp and then zone, like cursors
+func compareSpotPricePosition(a, b models.SpotPrice) int {
+	return cmp.Or(compareTime(a.Timestamp, b.Timestamp), bytes.Compare(a.ZoneID[:], b.ZoneID[:]))
+}
+
+func (r *spotPriceRepository) List(ctx context.Context, filter repository.SpotPriceFilter) ([]models.SpotPrice, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	spotPrices := make([]models.SpotPrice, 0)
+	for _, sp := range s.spotPrices {
+		if filter.ZoneID != nil && sp.ZoneID != *filter.ZoneID {
+			continue
+		}
+		if filter.CurrencyID != nil && sp.CurrencyID != *filter.CurrencyID {
+			continue
+		}
+		if filter.StartTime != nil && sp.Timestamp.Before(*filter.StartTime) {
+			continue
+		}
+		if filter.EndTime != nil && sp.Timestamp.After(*filter.EndTime) {
+			continue
+		}
+		if after := filter.After; after != nil {
+			position := compareSpotPricePosition(sp, models.SpotPrice{Timestamp: after.Timestamp, ZoneID: after.ZoneID})
+			if position == 0 || (position < 0) != filter.OrderDesc {
+				continue
+			}
+		}
+		if filter.AsOf != nil {
+			if sp.CreatedAt.After(*filter.AsOf) {
+				continue
+			}
+			sp.Price = s.priceAsOf(sp, *filter.AsOf)
+		}
+		spotPrices = append(spotPrices, sp)
+	}
+
+	orderBy, desc := filter.OrderBy, filter.OrderDesc
+	if orderBy == "" {
+		orderBy, desc = "timestamp", true
+	}
+	if err := sortBy(spotPrices, orderBy, desc, spotPriceOrder); err != nil {
+		return nil, err
+	}
+	return page(spotPrices, filter.Limit, filter.Offset), nil
+}
+
+func (r *spotPriceRepository) Total(ctx context.Context, filter repository.SpotPriceFilter) (int, error) {
+	filter.Limit, filter.Offset, filter.After = nil, nil, nil
+	items, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+func (r *spotPriceRepository) LastModified(ctx context.Context, filter repository.SpotPriceFilter) (time.Time, int, error) {
+	filter.Limit, filter.Offset, filter.After = nil, nil, nil
+	items, err := r.List(ctx, filter)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	var lastModified time.Time
+	for _, sp := range items {
+		if sp.UpdatedAt.After(lastModified) {
+			lastModified = sp.UpdatedAt
+		}
+	}
+	return lastModified, len(items), nil
+}
+
+func (r *spotPriceRepository) Each(ctx context.Context, filter repository.SpotPriceFilter, fn func(*models.SpotPrice) error) error {
+	spotPrices, err := r.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for i := range spotPrices {
+		if err := fn(&spotPrices[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// aggregateKey identifies a bucket, its start is in UTC as times only compare equal with
+// == in the same location
+type aggregateKey struct {
+	zoneID, currencyID uuid.UUID
+	start              time.Time
+}
+
+func (r *spotPriceRepository) Aggregate(ctx context.Context, filter repository.SpotPriceAggregateFilter) ([]models.SpotPriceAggregate, error) {
+	if !filter.GroupBy.Valid() {
+		return nil, fmt.Errorf("invalid aggregation period %q", filter.GroupBy)
+	}
+
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prices := make(map[aggregateKey][]float64)
+	for _, sp := range s.spotPrices {
+		if filter.ZoneID != nil && sp.ZoneID != *filter.ZoneID {
+			continue
+		}
+		if filter.CurrencyID != nil && sp.CurrencyID != *filter.CurrencyID {
+			continue
+		}
+		if sp.Timestamp.Before(filter.StartTime) || !sp.Timestamp.Before(filter.EndTime) {
+			continue
+		}
+		i := s.findZone(func(z *models.Zone) bool { return z.ID == sp.ZoneID })
+		if i < 0 {
+			continue
+		}
+		loc, err := time.LoadLocation(s.zones[i].Timezone)
+		if err != nil {
+			return nil, err
+		}
+		key := aggregateKey{sp.ZoneID, sp.CurrencyID, bucketStart(sp.Timestamp.In(loc), filter.GroupBy).UTC()}
+		prices[key] = append(prices[key], sp.Price)
+	}
+
+	aggregates := make([]models.SpotPriceAggregate, 0, len(prices))
+	for key, values := range prices {
+		a := models.SpotPriceAggregate{Start: key.start, ZoneID: key.zoneID, CurrencyID: key.currencyID, Count: len(values)}
+		if i := s.findZone(func(z *models.Zone) bool { return z.ID == key.zoneID }); i >= 0 {
+			a.Zone = s.zones[i].Name
+		}
+		if i := s.findCurrency(func(c *models.Currency) bool { return c.ID == key.currencyID }); i >= 0 {
+			a.Currency = s.currencies[i].Name
+		}
+
+		a.Min, a.Max = values[0], values[0]
+		var sum float64
+		for _, v := range values {
+			sum += v
+			a.Min, a.Max = min(a.Min, v), max(a.Max, v)
+		}
+		a.Avg = sum / float64(len(values))
+		var squares float64
+		for _, v := range values {
+			squares += (v - a.Avg) * (v - a.Avg)
+		}
+		a.StdDev = math.Sqrt(squares / float64(len(values)))
+		aggregates = append(aggregates, a)
+	}
+
+	slices.SortFunc(aggregates, func(a, b models.SpotPriceAggregate) int {
+		return cmp.Or(
+			cmp.Compare(a.Zone, b.Zone),
+			cmp.Compare(a.Currency, b.Currency),
+			compareTime(a.Start, b.Start),
+		)
+	})
+	return aggregates, nil
+}
+
+func (r *spotPriceRepository) CheapestPrices(ctx context.Context, filter repository.SpotPriceWindowFilter) ([]models.SpotPrice, time.Duration, error) {
+	s := r.store
+	s.mu.RLock()
+	prices := make([]models.SpotPrice, 0)
+	for _, sp := range s.spotPrices {
+		if sp.ZoneID == filter.ZoneID && sp.CurrencyID == filter.CurrencyID &&
+			!sp.Timestamp.Before(filter.StartTime) && sp.Timestamp.Before(filter.EndTime) {
+			prices = append(prices, sp)
+		}
+	}
+	s.mu.RUnlock()
+	slices.SortFunc(prices, func(a, b models.SpotPrice) int { return compareTime(a.Timestamp, b.Timestamp) })
+
+	resolution := time.Hour
+	for i := 1; i < len(prices); i++ {
+		if gap := prices[i].Timestamp.Sub(prices[i-1].Timestamp); i == 1 || gap < resolution {
+			resolution = gap
+		}
+	}
+	// Prices must stop applying by the end of the range
+	prices = slices.DeleteFunc(prices, func(sp models.SpotPrice) bool {
+		return sp.Timestamp.Add(resolution).After(filter.EndTime)
+	})
+	n := int((filter.Duration + resolution - 1) / resolution)
+	if n < 1 || n > len(prices) {
+		return nil, resolution, nil
+	}
+
+	if !filter.Contiguous {
+		cheapest := slices.Clone(prices)
+		slices.SortStableFunc(cheapest, func(a, b models.SpotPrice) int { return cmp.Compare(a.Price, b.Price) })
+		cheapest = cheapest[:n]
+		slices.SortFunc(cheapest, func(a, b models.SpotPrice) int { return compareTime(a.Timestamp, b.Timestamp) })
+		return cheapest, resolution, nil
+	}
+
+	best, bestSum := -1, 0.0
+	for i := 0; i+n <= len(prices); i++ {
+		// The gaps are at least the resolution, so n prices spanning n-1 of them have none
+		if prices[i+n-1].Timestamp.Sub(prices[i].Timestamp) != time.Duration(n-1)*resolution {
+			continue
+		}
+		var sum float64
+		for _, sp := range prices[i : i+n] {
+			sum += sp.Price
+		}
+		if best < 0 || sum < bestSum {
+			best, bestSum = i, sum
+		}
+	}
+	if best < 0 {
+		return nil, resolution, nil
+	}
+	return prices[best : best+n], resolution, nil
+}
+
+func (r *spotPriceRepository) Compare(ctx context.Context, filter repository.SpotPriceCompareFilter) ([]models.SpotPricePeriodComparison, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	comparisons := make([]models.SpotPricePeriodComparison, len(filter.Periods))
+	for i, p := range filter.Periods {
+		c := &comparisons[i]
+		c.Start, c.End = p.StartTime, p.EndTime
+		var sum float64
+		for _, sp := range s.spotPrices {
+			if sp.ZoneID != filter.ZoneID || sp.CurrencyID != filter.CurrencyID ||
+				sp.Timestamp.Before(p.StartTime) || !sp.Timestamp.Before(p.EndTime) {
+				continue
+			}
+			if c.Count == 0 {
+				c.Min, c.Max = clonePtr(&sp.Price), clonePtr(&sp.Price)
+			}
+			*c.Min, *c.Max = min(*c.Min, sp.Price), max(*c.Max, sp.Price)
+			sum += sp.Price
+			c.Count++
+		}
+		if c.Count > 0 {
+			avg := sum / float64(c.Count)
+			c.Avg = &avg
+		}
+	}
+
+	for i := range comparisons {
+		first, c := &comparisons[0], &comparisons[i]
+		c.AvgChange, c.AvgChangePercent = priceChange(first.Avg, c.Avg)
+		c.MinChange, c.MinChangePercent = priceChange(first.Min, c.Min)
+		c.MaxChange, c.MaxChangePercent = priceChange(first.Max, c.Max)
+	}
+	return comparisons, nil
+}
+
+// priceChange returns how much value differs from earlier, in the currency and in percent
+// of earlier rounded to two decimals, like the database does
+func priceChange(value, earlier *float64) (*float64, *float64) {
+	if value == nil || earlier == nil {
+		return nil, nil
+	}
+	change := *value - *earlier
+	if *earlier == 0 {
+		return &change, nil
+	}
+	percent := math.Round(change/math.Abs(*earlier)*100*100) / 100
+	return &change, &percent
+}
+
+// bucketStart truncates a local time to the start of its bucket, like date_trunc does
+func bucketStart(t time.Time, period models.AggregatePeriod) time.Time {
+	year, month, day := t.Date()
+	switch period {
+	case models.AggregateHour:
+		return time.Date(year, month, day, t.Hour(), 0, 0, 0, t.Location())
+	case models.AggregateWeek:
+		// Weeks start on Monday
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, t.Location())
+	case models.AggregateMonth:
+		return time.Date(year, month, 1, 0, 0, 0, 0, t.Location())
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, t.Location())
+	}
+}
@@ -0,0 +1,49 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type spotPriceRevisionRepository struct {
+	base
+}
+
+// NewSpotPriceRevisionRepository creates a new in-memory spot price revision repository
+func NewSpotPriceRevisionRepository(store *Store) repository.SpotPriceRevisionRepository {
+	return &spotPriceRevisionRepository{base{store}}
+}
+
+func (r *spotPriceRevisionRepository) ListBySpotPriceID(ctx context.Context, spotPriceID uuid.UUID, limit, offset int) ([]models.SpotPriceRevision, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	revisions := []models.SpotPriceRevision{}
+	for _, revision := range s.spotPriceRevisions {
+		if revision.SpotPriceID == spotPriceID {
+			revisions = append(revisions, revision)
+		}
+	}
+	// Revisions are appended as they are recorded, so reversing them puts the newest first
+	slices.Reverse(revisions)
+	return page(revisions, &limit, &offset), nil
+}
+
+func (r *spotPriceRevisionRepository) CountBySpotPriceID(ctx context.Context, spotPriceID uuid.UUID) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, revision := range s.spotPriceRevisions {
+		if revision.SpotPriceID == spotPriceID {
+			count++
+		}
+	}
+	return count, nil
+}
@@ -0,0 +1,77 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpotPriceRevisionRepository(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	prices := memory.NewSpotPriceRepository(store)
+	sources := memory.NewSpotPriceSourceRepository(store)
+	revisions := memory.NewSpotPriceRevisionRepository(store)
+	zones := memory.NewZoneRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+
+	zone, err := zones.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	hour := time.Date(2024, 3, 20, 12, 0, 0, 0, time.UTC)
+	recorded := []models.SpotPrice{{Timestamp: hour, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 10}}
+	require.NoError(t, sources.Record(ctx, "nordpool", recorded))
+	original := recorded[0]
+	created := time.Now()
+
+	// Storing the same price again records nothing
+	same := original
+	require.NoError(t, prices.CreateBatch(ctx, []models.SpotPrice{same}))
+	count, err := revisions.CountBySpotPriceID(ctx, original.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	userID := uuid.New()
+	changed := models.SpotPrice{Timestamp: hour, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 12}
+	require.NoError(t, prices.CreateBatch(repository.WithChangedBy(ctx, userID), []models.SpotPrice{changed}))
+	updated := time.Now()
+	require.NoError(t, sources.Record(ctx, "entsoe", []models.SpotPrice{{Timestamp: hour, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 11}}))
+
+	list, err := revisions.ListBySpotPriceID(ctx, original.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, 12.0, list[0].Price, "newest first")
+	require.Equal(t, 11.0, list[0].NewPrice)
+	require.Equal(t, "entsoe", list[0].Source)
+	require.Nil(t, list[0].ChangedBy)
+	require.Equal(t, 10.0, list[1].Price)
+	require.Equal(t, 12.0, list[1].NewPrice)
+	require.Equal(t, &userID, list[1].ChangedBy)
+	require.Empty(t, list[1].Source)
+
+	// Prices as of a time have the value they had then, and leave out later ones
+	asOf := func(at time.Time) []models.SpotPrice {
+		t.Helper()
+		list, err := prices.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID, AsOf: &at})
+		require.NoError(t, err)
+		return list
+	}
+	require.Empty(t, asOf(hour))
+	require.Equal(t, 10.0, asOf(created)[0].Price)
+	require.Equal(t, 12.0, asOf(updated)[0].Price)
+	require.Equal(t, 11.0, asOf(time.Now())[0].Price)
+
+	// Revisions go with their spot price
+	require.NoError(t, prices.Delete(ctx, original.ID))
+	count, err = revisions.CountBySpotPriceID(ctx, original.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type spotPriceSourceRepository struct {
+	base
+}
+
+// NewSpotPriceSourceRepository creates a new in-memory spot price source repository
+func NewSpotPriceSourceRepository(store *Store) repository.SpotPriceSourceRepository {
+	return &spotPriceSourceRepository{base{store}}
+}
+
+func (r *spotPriceSourceRepository) Record(ctx context.Context, source string, spotPrices []models.SpotPrice) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	changedBy := repository.ChangedBy(ctx)
+	for i := range spotPrices {
+		sp := &spotPrices[i]
+		key := keyOf(sp)
+		if s.spotPriceSources[key] == nil {
+			s.spotPriceSources[key] = make(map[string]models.SpotPriceSourceValue)
+		}
+		s.spotPriceSources[key][source] = models.SpotPriceSourceValue{Source: source, Price: sp.Price, UpdatedAt: now}
+
+		if _, resolved := s.resolvedSources[key]; resolved {
+			*sp = s.spotPrices[s.spotPriceKeys[key]]
+			continue
+		}
+		s.upsertSpotPrice(sp, now, changedBy, source)
+	}
+	return nil
+}
+
+func (r *spotPriceSourceRepository) ListConflicts(ctx context.Context, filter repository.SpotPriceConflictFilter) ([]models.SpotPriceConflict, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	conflicts := make([]models.SpotPriceConflict, 0)
+	for key, sources := range s.spotPriceSources {
+		sp, ok := s.spotPrices[s.spotPriceKeys[key]]
+		if !ok {
+			continue
+		}
+		if filter.ZoneID != nil && sp.ZoneID != *filter.ZoneID {
+			continue
+		}
+		if filter.CurrencyID != nil && sp.CurrencyID != *filter.CurrencyID {
+			continue
+		}
+		if filter.StartTime != nil && sp.Timestamp.Before(*filter.StartTime) {
+			continue
+		}
+		if filter.EndTime != nil && sp.Timestamp.After(*filter.EndTime) {
+			continue
+		}
+		resolved := s.resolvedSources[key]
+		if resolved != "" && !filter.IncludeResolved {
+			continue
+		}
+
+		values := make([]models.SpotPriceSourceValue, 0, len(sources))
+		differ := false
+		for _, value := range sources {
+			if len(values) > 0 && value.Price != values[0].Price {
+				differ = true
+			}
+			values = append(values, value)
+		}
+		if !differ {
+			continue
+		}
+		slices.SortFunc(values, func(a, b models.SpotPriceSourceValue) int { return compareString(a.Source, b.Source) })
+
+		conflicts = append(conflicts, models.SpotPriceConflict{
+			Timestamp:      sp.Timestamp,
+			ZoneID:         sp.ZoneID,
+			CurrencyID:     sp.CurrencyID,
+			Price:          sp.Price,
+			ResolvedSource: resolved,
+			Values:         values,
+		})
+	}
+
+	slices.SortFunc(conflicts, func(a, b models.SpotPriceConflict) int { return compareTime(b.Timestamp, a.Timestamp) })
+	return page(conflicts, filter.Limit, filter.Offset), nil
+}
+
+func (r *spotPriceSourceRepository) TotalConflicts(ctx context.Context, filter repository.SpotPriceConflictFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	items, err := r.ListConflicts(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+func (r *spotPriceSourceRepository) Resolve(ctx context.Context, timestamp time.Time, zoneID, currencyID uuid.UUID, source string) (*models.SpotPrice, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := spotPriceKey{timestamp.UnixNano(), zoneID, currencyID}
+	value, ok := s.spotPriceSources[key][source]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	id, ok := s.spotPriceKeys[key]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+
+	now := time.Now()
+	sp := s.spotPrices[id]
+	s.reviseSpotPrice(sp, value.Price, now, repository.ChangedBy(ctx), source)
+	sp.Price = value.Price
+	sp.UpdatedAt = now
+	s.spotPrices[id] = sp
+	s.resolvedSources[key] = source
+	return &sp, nil
+}
@@ -0,0 +1,204 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpotPriceRepository(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	prices := memory.NewSpotPriceRepository(store)
+	zones := memory.NewZoneRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+
+	zone, err := zones.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	start := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	batch := make([]models.SpotPrice, 24)
+	for i := range batch {
+		batch[i] = models.SpotPrice{
+			Timestamp:  start.Add(time.Duration(i) * time.Hour),
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      float64(i),
+		}
+	}
+	require.NoError(t, prices.CreateBatch(ctx, batch))
+
+	// Inserting an existing hour updates its price and keeps the ID
+	again := models.SpotPrice{Timestamp: start, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 99}
+	require.NoError(t, prices.Create(ctx, &again))
+	require.Equal(t, batch[0].ID, again.ID)
+
+	from, to := start.Add(2*time.Hour), start.Add(5*time.Hour)
+	limit := 2
+	list, err := prices.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID, StartTime: &from, EndTime: &to, Limit: &limit})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, to, list[0].Timestamp, "newest first by default")
+
+	list, err = prices.List(ctx, repository.SpotPriceFilter{OrderBy: "price", OrderDesc: true})
+	require.NoError(t, err)
+	require.Len(t, list, 24)
+	require.Equal(t, 99.0, list[0].Price)
+
+	// Zones and currencies with prices can't be deleted
+	require.ErrorIs(t, zones.Delete(ctx, zone.ID), repository.ErrHasAssociatedRecords)
+	require.ErrorIs(t, currencies.Delete(ctx, currency.ID), repository.ErrHasAssociatedRecords)
+
+	require.NoError(t, prices.Delete(ctx, again.ID))
+	_, err = prices.GetByID(ctx, again.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	require.ErrorIs(t, prices.Update(ctx, &again), repository.ErrNotFound)
+}
+
+func TestSpotPriceRepository_Cursor(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	prices := memory.NewSpotPriceRepository(store)
+	zones := memory.NewZoneRepository(store)
+	currencies := memory.NewCurrencyRepository(store)
+
+	currency, err := currencies.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+
+	// Zones share timestamps, so pages split between them must not skip or repeat prices
+	start := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	var batch []models.SpotPrice
+	for _, name := range []string{"SE3", "SE4"} {
+		zone, err := zones.GetByName(ctx, name)
+		require.NoError(t, err)
+		for i := 0; i < 5; i++ {
+			batch = append(batch, models.SpotPrice{Timestamp: start.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID})
+		}
+	}
+	require.NoError(t, prices.CreateBatch(ctx, batch))
+
+	for _, desc := range []bool{false, true} {
+		all, err := prices.List(ctx, repository.SpotPriceFilter{OrderBy: "timestamp", OrderDesc: desc})
+		require.NoError(t, err)
+		require.Len(t, all, len(batch))
+
+		var walked []models.SpotPrice
+		filter := repository.SpotPriceFilter{OrderBy: "timestamp", OrderDesc: desc, Limit: &[]int{3}[0]}
+		for {
+			page, err := prices.List(ctx, filter)
+			require.NoError(t, err)
+			if len(page) == 0 {
+				break
+			}
+			walked = append(walked, page...)
+			last := page[len(page)-1]
+			cursor, err := repository.DecodeSpotPriceCursor(repository.SpotPriceCursor{Timestamp: last.Timestamp, ZoneID: last.ZoneID}.Encode())
+			require.NoError(t, err)
+			filter.After = &cursor
+		}
+		require.Equal(t, all, walked)
+
+		total, err := prices.Total(ctx, filter)
+		require.NoError(t, err)
+		require.Equal(t, len(batch), total, "the cursor doesn't change the total")
+	}
+
+	_, err = repository.DecodeSpotPriceCursor("not-a-cursor")
+	require.ErrorIs(t, err, repository.ErrInvalidCursor)
+}
+
+func TestSpotPriceRepository_CheapestPrices(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	prices := memory.NewSpotPriceRepository(store)
+	zone, err := memory.NewZoneRepository(store).GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	currency, err := memory.NewCurrencyRepository(store).GetByName(ctx, "SEK")
+	require.NoError(t, err)
+	stockholm, err := time.LoadLocation("Europe/Stockholm")
+	require.NoError(t, err)
+
+	// add stores a price per step from start
+	add := func(start time.Time, step time.Duration, values ...float64) {
+		t.Helper()
+		batch := make([]models.SpotPrice, len(values))
+		for i, v := range values {
+			batch[i] = models.SpotPrice{Timestamp: start.Add(time.Duration(i) * step), ZoneID: zone.ID, CurrencyID: currency.ID, Price: v}
+		}
+		require.NoError(t, prices.CreateBatch(ctx, batch))
+	}
+	cheapest := func(start time.Time, within, duration time.Duration, contiguous bool) ([]models.SpotPrice, time.Duration) {
+		t.Helper()
+		result, resolution, err := prices.CheapestPrices(ctx, repository.SpotPriceWindowFilter{
+			ZoneID: zone.ID, CurrencyID: currency.ID,
+			StartTime: start, EndTime: start.Add(within),
+			Duration: duration, Contiguous: contiguous,
+		})
+		require.NoError(t, err)
+		return result, resolution
+	}
+	timestamps := func(prices []models.SpotPrice) []string {
+		var ts []string
+		for _, sp := range prices {
+			ts = append(ts, sp.Timestamp.In(stockholm).Format("01-02 15:04 MST"))
+		}
+		return ts
+	}
+
+	t.Run("Spring Forward", func(t *testing.T) {
+		// The night of March 30 2025 has no 02:00, the cheap hours are three real hours
+		midnight := time.Date(2025, 3, 30, 0, 0, 0, 0, stockholm)
+		add(midnight, time.Hour, 50, 1, 1, 1, 50, 50, 50, 50)
+
+		result, resolution := cheapest(midnight, 8*time.Hour, 3*time.Hour, true)
+		require.Equal(t, time.Hour, resolution)
+		require.Equal(t, []string{"03-30 01:00 CET", "03-30 03:00 CEST", "03-30 04:00 CEST"}, timestamps(result))
+	})
+
+	t.Run("Fall Back", func(t *testing.T) {
+		// The night of October 26 2025 has two 02:00
+		midnight := time.Date(2025, 10, 26, 0, 0, 0, 0, stockholm)
+		add(midnight, time.Hour, 50, 50, 1, 1, 1, 50, 50)
+
+		result, _ := cheapest(midnight, 7*time.Hour, 3*time.Hour, true)
+		require.Equal(t, []string{"10-26 02:00 CEST", "10-26 02:00 CET", "10-26 03:00 CET"}, timestamps(result))
+
+		// The window must end by the end of the range
+		result, _ = cheapest(midnight, 4*time.Hour, 3*time.Hour, true)
+		require.Equal(t, []string{"10-26 01:00 CEST", "10-26 02:00 CEST", "10-26 02:00 CET"}, timestamps(result))
+	})
+
+	t.Run("Gaps", func(t *testing.T) {
+		// 03:00 is missing, so the cheap hours around it aren't contiguous
+		day := time.Date(2025, 6, 1, 0, 0, 0, 0, stockholm)
+		add(day, time.Hour, 40, 30, 1)
+		add(day.Add(4*time.Hour), time.Hour, 1, 2, 40)
+
+		result, _ := cheapest(day, 7*time.Hour, 2*time.Hour, true)
+		require.Equal(t, []string{"06-01 04:00 CEST", "06-01 05:00 CEST"}, timestamps(result))
+		result, _ = cheapest(day, 7*time.Hour, 2*time.Hour, false)
+		require.Equal(t, []string{"06-01 02:00 CEST", "06-01 04:00 CEST"}, timestamps(result))
+
+		// Not enough prices
+		result, _ = cheapest(day, 7*time.Hour, 7*time.Hour, false)
+		require.Empty(t, result)
+		result, _ = cheapest(day, 7*time.Hour, 4*time.Hour, true)
+		require.Empty(t, result)
+	})
+
+	t.Run("Quarter Hours", func(t *testing.T) {
+		day := time.Date(2025, 7, 1, 0, 0, 0, 0, stockholm)
+		add(day, 15*time.Minute, 9, 9, 2, 1, 1, 2, 9, 9)
+
+		result, resolution := cheapest(day, 2*time.Hour, time.Hour, true)
+		require.Equal(t, 15*time.Minute, resolution)
+		require.Equal(t, []string{"07-01 00:30 CEST", "07-01 00:45 CEST", "07-01 01:00 CEST", "07-01 01:15 CEST"}, timestamps(result))
+	})
+}
@@ -0,0 +1,221 @@
+// Package memory implements the repository interfaces in memory, so handlers and services
+// can run without a PostgreSQL instance, such as in unit tests or a demo. The repositories
+// follow the PostgreSQL implementations, including their soft deletes and errors.
+package memory
+
+import (
+	"cmp"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// Store holds the rows of every repository created from it, so repositories see each
+// other's data like tables in one database do
+type Store struct {
+	mu sync.RWMutex
+	tables
+}
+
+// tables holds the rows of a store
+type tables struct {
+	users                   []models.User
+	roles                   []models.Role
+	currencies              []models.Currency
+	zones                   []models.Zone
+	spotPrices              map[uuid.UUID]models.SpotPrice
+	spotPriceKeys           map[spotPriceKey]uuid.UUID
+	spotPriceSources        map[spotPriceKey]map[string]models.SpotPriceSourceValue
+	resolvedSources         map[spotPriceKey]string
+	spotPriceRevisions      []models.SpotPriceRevision
+	auditLogs               []models.AuditLog
+	budgets                 []models.Budget
+	consumption             map[consumptionKey]models.ConsumptionRecord
+	production              map[consumptionKey]models.ProductionRecord
+	deviceTokens            []models.DeviceToken
+	emailChangeReverts      []repository.EmailChangeRevert
+	emailDeadLetters        []models.EmailDeadLetter
+	emailSuppressions       map[string]models.EmailSuppression
+	emailVerifications      []repository.EmailVerification
+	entsoeAreas             map[uuid.UUID]models.EntsoeArea
+	exchangeRates           map[exchangeRateKey]models.ExchangeRate
+	impersonations          []models.Impersonation
+	integrationTokens       []models.IntegrationToken
+	jobs                    map[string]models.Job
+	jobRuns                 []models.JobRun
+	loginAttempts           []loginAttempt
+	notificationDeliveries  []models.NotificationDelivery
+	notificationPreferences []models.NotificationPreference
+	notificationTargets     []models.NotificationTarget
+	organizations           []models.Organization
+	organizationMembers     []models.OrganizationMember
+	passwordHistory         []models.PasswordHistory
+	priceAlerts             []models.PriceAlert
+	priceForecasts          map[priceForecastKey]models.PriceForecast
+	passwordResets          []repository.PasswordReset
+	refreshTokens           []models.RefreshToken
+	tariffs                 []models.Tariff
+	securityEvents          []models.SecurityEvent
+	settings                map[string]models.Setting
+	twoFactors              map[uuid.UUID]models.TwoFactor
+	userPreferences         map[uuid.UUID]userPreference
+	backupCodes             []backupCode
+	usedPreAuthTokens       []usedPreAuthToken
+	webhooks                []models.Webhook
+	webhookDeliveries       []models.WebhookDelivery
+}
+
+// NewStore creates a store holding the roles, currencies and zones the initial migration
+// inserts
+func NewStore() *Store {
+	s := &Store{tables: tables{
+		spotPrices:        make(map[uuid.UUID]models.SpotPrice),
+		spotPriceKeys:     make(map[spotPriceKey]uuid.UUID),
+		spotPriceSources:  make(map[spotPriceKey]map[string]models.SpotPriceSourceValue),
+		resolvedSources:   make(map[spotPriceKey]string),
+		consumption:       make(map[consumptionKey]models.ConsumptionRecord),
+		production:        make(map[consumptionKey]models.ProductionRecord),
+		priceForecasts:    make(map[priceForecastKey]models.PriceForecast),
+		emailSuppressions: make(map[string]models.EmailSuppression),
+		entsoeAreas:       make(map[uuid.UUID]models.EntsoeArea),
+		exchangeRates:     make(map[exchangeRateKey]models.ExchangeRate),
+		jobs:              make(map[string]models.Job),
+		settings:          make(map[string]models.Setting),
+		twoFactors:        make(map[uuid.UUID]models.TwoFactor),
+		userPreferences:   make(map[uuid.UUID]userPreference),
+	}}
+
+	now := time.Now()
+	for _, role := range []models.Role{
+		{Name: "admin", IsProtected: true, IsAdminGroup: true},
+		{Name: "user", IsProtected: true},
+	} {
+		role.ID, role.TokenVersion, role.CreatedAt, role.UpdatedAt = uuid.New(), 1, now, now
+		role.Permissions = []string{}
+		s.roles = append(s.roles, role)
+	}
+	for _, name := range []string{"EUR", "SEK"} {
+		currency := models.Currency{ID: uuid.New(), Name: name, CreatedAt: now, UpdatedAt: now}
+		currency.FillISO4217()
+		s.currencies = append(s.currencies, currency)
+	}
+	// The default zones, as seeded by the migrations
+	for _, zone := range []struct{ name, eicCode, displayName string }{
+		{"SE1", "10Y1001A1001A44P", "Luleå"},
+		{"SE2", "10Y1001A1001A45N", "Sundsvall"},
+		{"SE3", "10Y1001A1001A46L", "Stockholm"},
+		{"SE4", "10Y1001A1001A47J", "Malmö"},
+	} {
+		country, gridOperator := "SE", "Svenska kraftnät"
+		s.zones = append(s.zones, models.Zone{
+			ID: uuid.New(), Name: zone.name, Timezone: "Europe/Stockholm",
+			CountryCode: &country, EICCode: &zone.eicCode, GridOperator: &gridOperator, DisplayName: &zone.displayName,
+			CreatedAt: now, UpdatedAt: now,
+		})
+	}
+	return s
+}
+
+// base implements repository.Repository. There is no database, Transaction runs fn like
+// the store's TxManager does.
+type base struct {
+	store *Store
+}
+
+// DB returns nil, there is no database behind the store
+func (r *base) DB() *sql.DB {
+	return nil
+}
+
+// Transaction implements the Repository interface
+func (r *base) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return NewTxManager(r.store).WithinTx(ctx, fn)
+}
+
+// userExists reports whether a user with id exists, including deleted users when
+// withDeleted is set. s.mu must be held.
+func (s *Store) userExists(id uuid.UUID, withDeleted bool) bool {
+	for _, u := range s.users {
+		if u.ID == id && (withDeleted || u.DeletedAt == nil) {
+			return true
+		}
+	}
+	return false
+}
+
+// page applies an optional limit and offset like SQL LIMIT and OFFSET
+func page[T any](items []T, limit, offset *int) []T {
+	if offset != nil {
+		if *offset >= len(items) {
+			return items[:0]
+		}
+		if *offset > 0 {
+			items = items[*offset:]
+		}
+	}
+	if limit != nil && *limit >= 0 && *limit < len(items) {
+		items = items[:*limit]
+	}
+	return items
+}
+
+// sortBy orders items by the named field like SQL ORDER BY. Unknown fields are rejected
+// as PostgreSQL rejects unknown columns.
+func sortBy[T any](items []T, field string, desc bool, fields map[string]func(a, b T) int) error {
+	compare, ok := fields[field]
+	if !ok {
+		return fmt.Errorf("cannot order by unknown column %q", field)
+	}
+	slices.SortStableFunc(items, func(a, b T) int {
+		if desc {
+			return compare(b, a)
+		}
+		return compare(a, b)
+	})
+	return nil
+}
+
+// containsFold reports whether value contains search ignoring case, like ILIKE '%search%'
+func containsFold(value, search string) bool {
+	return strings.Contains(strings.ToLower(value), strings.ToLower(search))
+}
+
+func compareTime(a, b time.Time) int {
+	return a.Compare(b)
+}
+
+func compareString(a, b string) int {
+	return cmp.Compare(a, b)
+}
+
+// clonePtr copies the value p points to, so stored rows don't share memory with callers
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
+}
+
+func compareFloat(a, b float64) int {
+	return cmp.Compare(a, b)
+}
+
+// generateToken creates a random hex token like the PostgreSQL repositories issue
+func generateToken() (string, error) {
+	bytes := make([]byte, repository.VerificationTokenLength)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(bytes), nil
+}
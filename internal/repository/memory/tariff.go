@@ -0,0 +1,169 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type tariffRepository struct {
+	base
+}
+
+// NewTariffRepository creates a new in-memory tariff repository
+func NewTariffRepository(store *Store) repository.TariffRepository {
+	return &tariffRepository{base{store}}
+}
+
+func cloneTariff(tariff models.Tariff) models.Tariff {
+	tariff.UserID = clonePtr(tariff.UserID)
+	tariff.OrganizationID = clonePtr(tariff.OrganizationID)
+	rates := make([]models.TariffRate, len(tariff.Rates))
+	for i, rate := range tariff.Rates {
+		rate.Months = slices.Clone(rate.Months)
+		rates[i] = rate
+	}
+	tariff.Rates = rates
+	return tariff
+}
+
+// findTariff returns the position of the first tariff matching, or -1. s.mu must be held.
+func (s *Store) findTariff(match func(t *models.Tariff) bool) int {
+	return slices.IndexFunc(s.tariffs, func(t models.Tariff) bool { return match(&t) })
+}
+
+// sameOwner tells whether two tariffs belong to the same user or organization
+func sameOwner(a, b *models.Tariff) bool {
+	if a.UserID != nil {
+		return b.UserID != nil && *a.UserID == *b.UserID
+	}
+	return b.OrganizationID != nil && *a.OrganizationID == *b.OrganizationID
+}
+
+func (r *tariffRepository) Create(ctx context.Context, tariff *models.Tariff) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if (tariff.UserID == nil) == (tariff.OrganizationID == nil) {
+		return repository.ErrNotFound
+	}
+	if tariff.UserID != nil && !s.userExists(*tariff.UserID, false) ||
+		tariff.OrganizationID != nil && s.findOrganization(*tariff.OrganizationID) < 0 ||
+		s.findZone(func(z *models.Zone) bool { return z.ID == tariff.ZoneID }) < 0 ||
+		s.findCurrency(func(c *models.Currency) bool { return c.ID == tariff.CurrencyID }) < 0 {
+		return repository.ErrNotFound
+	}
+	if s.findTariff(func(t *models.Tariff) bool {
+		return sameOwner(t, tariff) && t.ZoneID == tariff.ZoneID && t.CurrencyID == tariff.CurrencyID
+	}) >= 0 {
+		return repository.ErrDuplicateEntry
+	}
+
+	now := time.Now()
+	tariff.ID = uuid.New()
+	tariff.CreatedAt = now
+	tariff.UpdatedAt = now
+	s.tariffs = append(s.tariffs, cloneTariff(*tariff))
+	*tariff = cloneTariff(*tariff)
+	return nil
+}
+
+func (r *tariffRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Tariff, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findTariff(func(t *models.Tariff) bool { return t.ID == id })
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	tariff := cloneTariff(s.tariffs[i])
+	return &tariff, nil
+}
+
+func (r *tariffRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Tariff, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	tariffs := make([]models.Tariff, 0)
+	for _, tariff := range s.tariffs {
+		if tariff.UserID != nil && *tariff.UserID == userID ||
+			tariff.OrganizationID != nil && s.findMember(*tariff.OrganizationID, userID) >= 0 {
+			tariffs = append(tariffs, cloneTariff(tariff))
+		}
+	}
+	slices.SortStableFunc(tariffs, func(a, b models.Tariff) int {
+		if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+			return c
+		}
+		return compareString(a.ID.String(), b.ID.String())
+	})
+	return tariffs, nil
+}
+
+func (r *tariffRepository) FindForUser(ctx context.Context, userID, zoneID, currencyID uuid.UUID) (*models.Tariff, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	priced := func(t *models.Tariff) bool { return t.ZoneID == zoneID && t.CurrencyID == currencyID }
+	i := s.findTariff(func(t *models.Tariff) bool { return priced(t) && t.UserID != nil && *t.UserID == userID })
+	// Memberships are kept in the order they were made
+	for _, m := range s.organizationMembers {
+		if i >= 0 {
+			break
+		}
+		if m.UserID == userID {
+			i = s.findTariff(func(t *models.Tariff) bool {
+				return priced(t) && t.OrganizationID != nil && *t.OrganizationID == m.OrganizationID
+			})
+		}
+	}
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	tariff := cloneTariff(s.tariffs[i])
+	return &tariff, nil
+}
+
+func (r *tariffRepository) Update(ctx context.Context, tariff *models.Tariff) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findTariff(func(t *models.Tariff) bool { return t.ID == tariff.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	stored := &s.tariffs[i]
+	stored.Name = tariff.Name
+	stored.TransferFee = tariff.TransferFee
+	stored.EnergyTax = tariff.EnergyTax
+	stored.MonthlyFee = tariff.MonthlyFee
+	stored.VAT = tariff.VAT
+	stored.ExportFee = tariff.ExportFee
+	stored.Rates = tariff.Rates
+	stored.UpdatedAt = time.Now()
+	*stored = cloneTariff(*stored)
+	*tariff = cloneTariff(*stored)
+	return nil
+}
+
+func (r *tariffRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findTariff(func(t *models.Tariff) bool { return t.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.tariffs = slices.Delete(s.tariffs, i, i+1)
+	return nil
+}
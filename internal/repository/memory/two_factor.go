@@ -0,0 +1,172 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// backupCode is a row of totp_backup_codes
+type backupCode struct {
+	userID   uuid.UUID
+	codeHash string
+	usedAt   *time.Time
+}
+
+// usedPreAuthToken is a row of used_pre_auth_tokens
+type usedPreAuthToken struct {
+	id        uuid.UUID
+	userID    uuid.UUID
+	expiresAt time.Time
+}
+
+type twoFactorRepository struct {
+	base
+}
+
+// NewTwoFactorRepository creates a new in-memory two-factor repository
+func NewTwoFactorRepository(store *Store) repository.TwoFactorRepository {
+	return &twoFactorRepository{base{store}}
+}
+
+func cloneTwoFactor(twoFactor models.TwoFactor) *models.TwoFactor {
+	twoFactor.EnabledAt = clonePtr(twoFactor.EnabledAt)
+	twoFactor.LastUsedStep = clonePtr(twoFactor.LastUsedStep)
+	return &twoFactor
+}
+
+func (r *twoFactorRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.TwoFactor, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	twoFactor, ok := s.twoFactors[userID]
+	if !ok {
+		return nil, repository.ErrNotFound
+	}
+	return cloneTwoFactor(twoFactor), nil
+}
+
+func (r *twoFactorRepository) Enroll(ctx context.Context, userID uuid.UUID, secret string) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.userExists(userID, true) {
+		return repository.ErrNotFound
+	}
+	now := time.Now()
+	twoFactor, ok := s.twoFactors[userID]
+	if ok && twoFactor.Enabled() {
+		return repository.ErrConflict
+	}
+	if !ok {
+		twoFactor = models.TwoFactor{UserID: userID, CreatedAt: now}
+	}
+	twoFactor.Secret = secret
+	twoFactor.LastUsedStep = nil
+	twoFactor.UpdatedAt = now
+	s.twoFactors[userID] = twoFactor
+	return nil
+}
+
+func (r *twoFactorRepository) Enable(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	twoFactor, ok := s.twoFactors[userID]
+	if !ok {
+		return repository.ErrNotFound
+	}
+	if twoFactor.Enabled() {
+		return repository.ErrConflict
+	}
+	twoFactor.EnabledAt = &at
+	twoFactor.LastUsedStep = &step
+	twoFactor.UpdatedAt = time.Now()
+	s.twoFactors[userID] = twoFactor
+
+	s.backupCodes = slices.DeleteFunc(s.backupCodes, func(c backupCode) bool { return c.userID == userID })
+	for _, hash := range codeHashes {
+		s.backupCodes = append(s.backupCodes, backupCode{userID: userID, codeHash: hash})
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	twoFactor, ok := s.twoFactors[userID]
+	if !ok || (twoFactor.LastUsedStep != nil && *twoFactor.LastUsedStep >= step) {
+		return repository.ErrConflict
+	}
+	twoFactor.LastUsedStep = &step
+	twoFactor.UpdatedAt = time.Now()
+	s.twoFactors[userID] = twoFactor
+	return nil
+}
+
+func (r *twoFactorRepository) UsePreAuthToken(ctx context.Context, userID, tokenID uuid.UUID, expiresAt time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	s.usedPreAuthTokens = slices.DeleteFunc(s.usedPreAuthTokens, func(t usedPreAuthToken) bool {
+		return t.userID == userID && !t.expiresAt.After(now)
+	})
+	if slices.ContainsFunc(s.usedPreAuthTokens, func(t usedPreAuthToken) bool { return t.id == tokenID }) {
+		return repository.ErrConflict
+	}
+	s.usedPreAuthTokens = append(s.usedPreAuthTokens, usedPreAuthToken{id: tokenID, userID: userID, expiresAt: expiresAt})
+	return nil
+}
+
+func (r *twoFactorRepository) UseBackupCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.backupCodes, func(c backupCode) bool {
+		return c.userID == userID && c.codeHash == codeHash && c.usedAt == nil
+	})
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.backupCodes[i].usedAt = &at
+	return nil
+}
+
+func (r *twoFactorRepository) CountBackupCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, c := range s.backupCodes {
+		if c.userID == userID && c.usedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func (r *twoFactorRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.twoFactors[userID]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(s.twoFactors, userID)
+	s.backupCodes = slices.DeleteFunc(s.backupCodes, func(c backupCode) bool { return c.userID == userID })
+	return nil
+}
@@ -0,0 +1,104 @@
+package memory
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type txKey struct{}
+
+type txManager struct {
+	store *Store
+}
+
+// NewTxManager creates a transaction manager for the repositories of store. Transactions
+// aren't isolated: a failed transaction restores the rows the store held when it began,
+// which also undoes changes made meanwhile outside of it.
+func NewTxManager(store *Store) repository.TxManager {
+	return &txManager{store: store}
+}
+
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) (err error) {
+	if ctx.Value(txKey{}) != nil {
+		return fn(ctx)
+	}
+
+	m.store.mu.RLock()
+	snapshot := m.store.tables.clone()
+	m.store.mu.RUnlock()
+
+	committed := false
+	defer func() {
+		if !committed {
+			m.store.mu.Lock()
+			m.store.tables = snapshot
+			m.store.mu.Unlock()
+		}
+	}()
+
+	if err := fn(context.WithValue(ctx, txKey{}, true)); err != nil {
+		return err
+	}
+	committed = true
+	return nil
+}
+
+// clone copies the rows, so changing the copy leaves t as it is. Slices within rows are
+// shared, except for the permissions of roles which are changed in place.
+func (t tables) clone() tables {
+	c := t
+	c.users = slices.Clone(t.users)
+	c.roles = slices.Clone(t.roles)
+	for i := range c.roles {
+		c.roles[i].Permissions = slices.Clone(t.roles[i].Permissions)
+	}
+	c.currencies = slices.Clone(t.currencies)
+	c.zones = slices.Clone(t.zones)
+	c.spotPrices = maps.Clone(t.spotPrices)
+	c.spotPriceKeys = maps.Clone(t.spotPriceKeys)
+	c.spotPriceSources = make(map[spotPriceKey]map[string]models.SpotPriceSourceValue, len(t.spotPriceSources))
+	for key, sources := range t.spotPriceSources {
+		c.spotPriceSources[key] = maps.Clone(sources)
+	}
+	c.resolvedSources = maps.Clone(t.resolvedSources)
+	c.spotPriceRevisions = slices.Clone(t.spotPriceRevisions)
+	c.auditLogs = slices.Clone(t.auditLogs)
+	c.budgets = slices.Clone(t.budgets)
+	c.consumption = maps.Clone(t.consumption)
+	c.production = maps.Clone(t.production)
+	c.priceForecasts = maps.Clone(t.priceForecasts)
+	c.deviceTokens = slices.Clone(t.deviceTokens)
+	c.emailChangeReverts = slices.Clone(t.emailChangeReverts)
+	c.emailDeadLetters = slices.Clone(t.emailDeadLetters)
+	c.emailSuppressions = maps.Clone(t.emailSuppressions)
+	c.emailVerifications = slices.Clone(t.emailVerifications)
+	c.entsoeAreas = maps.Clone(t.entsoeAreas)
+	c.exchangeRates = maps.Clone(t.exchangeRates)
+	c.impersonations = slices.Clone(t.impersonations)
+	c.integrationTokens = slices.Clone(t.integrationTokens)
+	c.jobs = maps.Clone(t.jobs)
+	c.jobRuns = slices.Clone(t.jobRuns)
+	c.loginAttempts = slices.Clone(t.loginAttempts)
+	c.notificationDeliveries = slices.Clone(t.notificationDeliveries)
+	c.notificationPreferences = slices.Clone(t.notificationPreferences)
+	c.notificationTargets = slices.Clone(t.notificationTargets)
+	c.organizations = slices.Clone(t.organizations)
+	c.organizationMembers = slices.Clone(t.organizationMembers)
+	c.passwordHistory = slices.Clone(t.passwordHistory)
+	c.priceAlerts = slices.Clone(t.priceAlerts)
+	c.passwordResets = slices.Clone(t.passwordResets)
+	c.tariffs = slices.Clone(t.tariffs)
+	c.refreshTokens = slices.Clone(t.refreshTokens)
+	c.securityEvents = slices.Clone(t.securityEvents)
+	c.settings = maps.Clone(t.settings)
+	c.twoFactors = maps.Clone(t.twoFactors)
+	c.userPreferences = maps.Clone(t.userPreferences)
+	c.backupCodes = slices.Clone(t.backupCodes)
+	c.usedPreAuthTokens = slices.Clone(t.usedPreAuthTokens)
+	c.webhooks = slices.Clone(t.webhooks)
+	c.webhookDeliveries = slices.Clone(t.webhookDeliveries)
+	return c
+}
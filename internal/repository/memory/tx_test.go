@@ -0,0 +1,67 @@
+package memory_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	txManager := memory.NewTxManager(store)
+	users := memory.NewUserRepository(store)
+	roles := memory.NewRoleRepository(store)
+	verifications := memory.NewEmailVerificationRepository(store)
+
+	userRole, err := roles.GetByName(ctx, "user")
+	require.NoError(t, err)
+	editors := &models.Role{Name: "editors"}
+	require.NoError(t, roles.Create(ctx, editors))
+	require.NoError(t, roles.GrantPermission(ctx, editors.ID, "zones:read"))
+
+	t.Run("Commit", func(t *testing.T) {
+		err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+			user := &models.User{Username: "alice", Password: "hash", RoleID: userRole.ID}
+			if err := users.Create(ctx, user); err != nil {
+				return err
+			}
+			_, err := verifications.Create(ctx, user.ID, 0)
+			return err
+		})
+		require.NoError(t, err)
+
+		_, err = users.GetByUsername(ctx, "alice")
+		require.NoError(t, err)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		failed := errors.New("failed")
+		err := txManager.WithinTx(ctx, func(ctx context.Context) error {
+			user := &models.User{Username: "bob", Password: "hash", RoleID: userRole.ID}
+			if err := users.Create(ctx, user); err != nil {
+				return err
+			}
+			require.NoError(t, roles.GrantPermission(ctx, editors.ID, "zones:write"))
+			// Nested transactions join the outer one
+			return txManager.WithinTx(ctx, func(ctx context.Context) error {
+				if _, err := verifications.Create(ctx, user.ID, 0); err != nil {
+					return err
+				}
+				return failed
+			})
+		})
+		require.ErrorIs(t, err, failed)
+
+		_, err = users.GetByUsername(ctx, "bob")
+		require.ErrorIs(t, err, repository.ErrUserNotFound)
+		role, err := roles.GetByID(ctx, editors.ID)
+		require.NoError(t, err)
+		require.Equal(t, []string{"zones:read"}, role.Permissions)
+	})
+}
@@ -0,0 +1,462 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type userRepository struct {
+	base
+}
+
+// NewUserRepository creates a new in-memory user repository
+func NewUserRepository(store *Store) repository.UserRepository {
+	return &userRepository{base{store}}
+}
+
+// findUser returns the position of the first non-deleted user matching, or -1. s.mu must
+// be held.
+func (s *Store) findUser(match func(u *models.User) bool) int {
+	for i := range s.users {
+		if s.users[i].DeletedAt == nil && match(&s.users[i]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// emailStatus returns the suppression reason of email, or deliverable. s.mu must be held.
+func (s *Store) emailStatus(email *string) string {
+	if email != nil {
+		if suppression, ok := s.emailSuppressions[normalizeEmail(*email)]; ok {
+			return string(suppression.Reason)
+		}
+	}
+	return models.EmailStatusDeliverable
+}
+
+// loadUser copies the stored user with its role and email status. s.mu must be held.
+func (s *Store) loadUser(i int) *models.User {
+	user := s.users[i]
+	user.Email = clonePtr(user.Email)
+	user.LastLoginAt = clonePtr(user.LastLoginAt)
+	user.LastFailedLogin = clonePtr(user.LastFailedLogin)
+	user.PasswordChangedAt = clonePtr(user.PasswordChangedAt)
+	user.DeletedAt = clonePtr(user.DeletedAt)
+	user.DeletionScheduledAt = clonePtr(user.DeletionScheduledAt)
+	user.EmailStatus = s.emailStatus(user.Email)
+	user.Role = nil
+	if j := s.findRole(func(r *models.Role) bool { return r.ID == user.RoleID }); j >= 0 {
+		role := s.roles[j]
+		role.Permissions = slices.Clone(role.Permissions)
+		user.Role = &role
+	}
+	return &user
+}
+
+func (r *userRepository) Create(ctx context.Context, user *models.User) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Usernames and emails are unique across deleted users too, like the table constraints
+	for _, u := range s.users {
+		if u.Username == user.Username || (user.Email != nil && u.Email != nil && *u.Email == *user.Email) {
+			return repository.ErrConflict
+		}
+	}
+
+	now := time.Now()
+	user.ID = uuid.New()
+	user.CreatedAt = now
+	user.UpdatedAt = now
+	user.EmailStatus = s.emailStatus(user.Email)
+	if user.Language == "" {
+		user.Language = models.DefaultLanguage
+	}
+
+	stored := *user
+	stored.Email = clonePtr(user.Email)
+	stored.LastLoginAt = clonePtr(user.LastLoginAt)
+	stored.LastFailedLogin = clonePtr(user.LastFailedLogin)
+	stored.PasswordChangedAt = clonePtr(user.PasswordChangedAt)
+	stored.DeletedAt = clonePtr(user.DeletedAt)
+	stored.DeletionScheduledAt = clonePtr(user.DeletionScheduledAt)
+	stored.Role = nil
+	s.users = append(s.users, stored)
+	return nil
+}
+
+func (r *userRepository) Update(ctx context.Context, user *models.User) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if user.Email != nil {
+		if s.findUser(func(u *models.User) bool {
+			return u.ID != user.ID && u.Email != nil && *u.Email == *user.Email
+		}) >= 0 {
+			return repository.ErrConflict
+		}
+	}
+
+	i := s.findUser(func(u *models.User) bool { return u.ID == user.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+
+	stored := &s.users[i]
+	stored.Username = user.Username
+	stored.Email = clonePtr(user.Email)
+	stored.EmailVerified = user.EmailVerified
+	stored.RoleID = user.RoleID
+	if user.Language != "" {
+		stored.Language = user.Language
+	}
+	stored.UpdatedAt = time.Now()
+
+	user.UpdatedAt = stored.UpdatedAt
+	user.Language = stored.Language
+	user.EmailStatus = s.emailStatus(stored.Email)
+	return nil
+}
+
+func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findUser(func(u *models.User) bool { return u.ID == id })
+	if i < 0 {
+		return repository.ErrUserNotFound
+	}
+	if j := s.findRole(func(r *models.Role) bool { return r.ID == s.users[i].RoleID }); j >= 0 && s.roles[j].IsAdminGroup {
+		return repository.ErrAdminDelete
+	}
+
+	now := time.Now()
+	s.users[i].DeletedAt = &now
+	s.users[i].UpdatedAt = now
+	return nil
+}
+
+// findDeletedUser returns the position of the soft-deleted user with the ID, or -1. s.mu
+// must be held.
+func (s *Store) findDeletedUser(id uuid.UUID) int {
+	return slices.IndexFunc(s.users, func(u models.User) bool { return u.ID == id && u.DeletedAt != nil })
+}
+
+func (r *userRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findDeletedUser(id)
+	if i < 0 {
+		return repository.ErrUserNotFound
+	}
+	s.users[i].DeletedAt = nil
+	s.users[i].UpdatedAt = time.Now()
+	return nil
+}
+
+func (r *userRepository) HardDelete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findDeletedUser(id)
+	if i < 0 {
+		return repository.ErrUserNotFound
+	}
+	s.users = slices.Delete(s.users, i, i+1)
+
+	// Remove the rows referencing the user like the purge and foreign keys do, audit
+	// logs are kept without the user
+	for j := range s.auditLogs {
+		if s.auditLogs[j].UserID != nil && *s.auditLogs[j].UserID == id {
+			s.auditLogs[j].UserID = nil
+		}
+		if s.auditLogs[j].ImpersonatorID != nil && *s.auditLogs[j].ImpersonatorID == id {
+			s.auditLogs[j].ImpersonatorID = nil
+		}
+	}
+	for j := range s.jobRuns {
+		if s.jobRuns[j].TriggeredBy != nil && *s.jobRuns[j].TriggeredBy == id {
+			s.jobRuns[j].TriggeredBy = nil
+		}
+	}
+	for j := range s.spotPriceRevisions {
+		if s.spotPriceRevisions[j].ChangedBy != nil && *s.spotPriceRevisions[j].ChangedBy == id {
+			s.spotPriceRevisions[j].ChangedBy = nil
+		}
+	}
+	for key, setting := range s.settings {
+		if setting.UpdatedBy != nil && *setting.UpdatedBy == id {
+			setting.UpdatedBy = nil
+			s.settings[key] = setting
+		}
+	}
+	for i := range s.webhooks {
+		if s.webhooks[i].CreatedBy != nil && *s.webhooks[i].CreatedBy == id {
+			s.webhooks[i].CreatedBy = nil
+		}
+	}
+	for key := range s.consumption {
+		if key.userID == id {
+			delete(s.consumption, key)
+		}
+	}
+	for key := range s.production {
+		if key.userID == id {
+			delete(s.production, key)
+		}
+	}
+	s.loginAttempts = slices.DeleteFunc(s.loginAttempts, func(a loginAttempt) bool { return a.userID == id })
+	s.passwordHistory = slices.DeleteFunc(s.passwordHistory, func(h models.PasswordHistory) bool { return h.UserID == id })
+	s.emailVerifications = slices.DeleteFunc(s.emailVerifications, func(v repository.EmailVerification) bool { return v.UserID == id })
+	s.passwordResets = slices.DeleteFunc(s.passwordResets, func(r repository.PasswordReset) bool { return r.UserID == id })
+	s.emailChangeReverts = slices.DeleteFunc(s.emailChangeReverts, func(r repository.EmailChangeRevert) bool { return r.UserID == id })
+	s.refreshTokens = slices.DeleteFunc(s.refreshTokens, func(t models.RefreshToken) bool { return t.UserID == id })
+	s.securityEvents = slices.DeleteFunc(s.securityEvents, func(e models.SecurityEvent) bool { return e.UserID == id })
+	s.deviceTokens = slices.DeleteFunc(s.deviceTokens, func(t models.DeviceToken) bool { return t.UserID == id })
+	s.integrationTokens = slices.DeleteFunc(s.integrationTokens, func(t models.IntegrationToken) bool { return t.UserID == id })
+	s.notificationTargets = slices.DeleteFunc(s.notificationTargets, func(t models.NotificationTarget) bool { return t.UserID == id })
+	s.notificationPreferences = slices.DeleteFunc(s.notificationPreferences, func(p models.NotificationPreference) bool { return p.UserID == id })
+	s.notificationDeliveries = slices.DeleteFunc(s.notificationDeliveries, func(d models.NotificationDelivery) bool { return d.UserID == id })
+	s.organizationMembers = slices.DeleteFunc(s.organizationMembers, func(m models.OrganizationMember) bool { return m.UserID == id })
+	s.priceAlerts = slices.DeleteFunc(s.priceAlerts, func(a models.PriceAlert) bool { return a.UserID == id })
+	s.budgets = slices.DeleteFunc(s.budgets, func(b models.Budget) bool { return b.UserID == id })
+	s.tariffs = slices.DeleteFunc(s.tariffs, func(t models.Tariff) bool { return t.UserID != nil && *t.UserID == id })
+	delete(s.twoFactors, id)
+	delete(s.userPreferences, id)
+	s.backupCodes = slices.DeleteFunc(s.backupCodes, func(c backupCode) bool { return c.userID == id })
+	s.usedPreAuthTokens = slices.DeleteFunc(s.usedPreAuthTokens, func(t usedPreAuthToken) bool { return t.userID == id })
+	s.impersonations = slices.DeleteFunc(s.impersonations, func(i models.Impersonation) bool {
+		return i.AdminID == id || i.UserID == id
+	})
+	return nil
+}
+
+func (r *userRepository) ScheduleDeletion(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.setDeletionScheduledAt(id, &at)
+}
+
+func (r *userRepository) CancelDeletion(ctx context.Context, id uuid.UUID) error {
+	return r.setDeletionScheduledAt(id, nil)
+}
+
+func (r *userRepository) setDeletionScheduledAt(id uuid.UUID, at *time.Time) error {
+	err := r.update(byID(id), func(u *models.User) {
+		u.DeletionScheduledAt = at
+	})
+	if err == repository.ErrNotFound {
+		return repository.ErrUserNotFound
+	}
+	return err
+}
+
+func (r *userRepository) DeleteScheduled(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ids []uuid.UUID
+	for i := range s.users {
+		u := &s.users[i]
+		if u.DeletedAt != nil || u.DeletionScheduledAt == nil || u.DeletionScheduledAt.After(now) {
+			continue
+		}
+		if j := s.findRole(func(r *models.Role) bool { return r.ID == u.RoleID }); j >= 0 && s.roles[j].IsAdminGroup {
+			continue
+		}
+		deletedAt := now
+		u.DeletedAt = &deletedAt
+		u.DeletionScheduledAt = nil
+		u.UpdatedAt = now
+		ids = append(ids, u.ID)
+	}
+	return ids, nil
+}
+
+func (r *userRepository) get(match func(u *models.User) bool) (*models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findUser(match)
+	if i < 0 {
+		return nil, repository.ErrUserNotFound
+	}
+	return s.loadUser(i), nil
+}
+
+func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
+	return r.get(func(u *models.User) bool { return u.ID == id })
+}
+
+func (r *userRepository) GetByUsername(ctx context.Context, username string) (*models.User, error) {
+	return r.get(func(u *models.User) bool { return u.Username == username })
+}
+
+func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.User, error) {
+	return r.get(func(u *models.User) bool { return u.Email != nil && *u.Email == email })
+}
+
+var userOrder = map[string]func(a, b models.User) int{
+	"username":   func(a, b models.User) int { return compareString(a.Username, b.Username) },
+	"email":      func(a, b models.User) int { return compareString(deref(a.Email), deref(b.Email)) },
+	"created_at": func(a, b models.User) int { return compareTime(a.CreatedAt, b.CreatedAt) },
+	"updated_at": func(a, b models.User) int { return compareTime(a.UpdatedAt, b.UpdatedAt) },
+	"last_login_at": func(a, b models.User) int {
+		return compareTime(deref(a.LastLoginAt), deref(b.LastLoginAt))
+	},
+}
+
+func (r *userRepository) List(ctx context.Context, filter repository.UserFilter) ([]models.User, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	users := make([]models.User, 0)
+	for i, u := range s.users {
+		if u.DeletedAt != nil && !filter.IncludeDeleted {
+			continue
+		}
+		if filter.Search != nil && !containsFold(u.Username, *filter.Search) &&
+			(u.Email == nil || !containsFold(*u.Email, *filter.Search)) {
+			continue
+		}
+		if filter.RoleID != nil && u.RoleID != *filter.RoleID {
+			continue
+		}
+		if filter.OrganizationID != nil && s.findMember(*filter.OrganizationID, u.ID) < 0 {
+			continue
+		}
+		user := s.loadUser(i)
+		// Passwords are not selected when listing
+		user.Password = ""
+		users = append(users, *user)
+	}
+
+	orderBy := filter.OrderBy
+	if orderBy == "" {
+		orderBy = "username"
+	}
+	if err := sortBy(users, orderBy, filter.OrderDesc, userOrder); err != nil {
+		return nil, err
+	}
+	return page(users, filter.Limit, filter.Offset), nil
+}
+
+func (r *userRepository) Total(ctx context.Context, filter repository.UserFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	items, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
+
+func (r *userRepository) Count(ctx context.Context) (int, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	count := 0
+	for _, u := range s.users {
+		if u.DeletedAt == nil {
+			count++
+		}
+	}
+	return count, nil
+}
+
+// update applies fn to the non-deleted user matching, returning ErrNotFound if there is none
+func (r *userRepository) update(match func(u *models.User) bool, fn func(u *models.User)) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findUser(match)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	fn(&s.users[i])
+	s.users[i].UpdatedAt = time.Now()
+	return nil
+}
+
+func byID(id uuid.UUID) func(u *models.User) bool {
+	return func(u *models.User) bool { return u.ID == id }
+}
+
+func byUsername(username string) func(u *models.User) bool {
+	return func(u *models.User) bool { return u.Username == username }
+}
+
+func (r *userRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID, lastLoginAt time.Time) error {
+	return r.update(byID(id), func(u *models.User) {
+		u.LastLoginAt = &lastLoginAt
+	})
+}
+
+func (r *userRepository) UpdatePassword(ctx context.Context, id uuid.UUID, hashedPassword string) error {
+	return r.update(byID(id), func(u *models.User) {
+		now := time.Now()
+		u.Password = hashedPassword
+		u.PasswordChangedAt = &now
+	})
+}
+
+func (r *userRepository) VerifyEmail(ctx context.Context, id uuid.UUID) error {
+	return r.update(byID(id), func(u *models.User) {
+		u.EmailVerified = true
+	})
+}
+
+func (r *userRepository) UpdateFailedAttempts(ctx context.Context, id uuid.UUID, attempts int) error {
+	return r.update(byID(id), func(u *models.User) {
+		u.FailedLoginAttempts = attempts
+		u.LastFailedLogin = nil
+		if attempts > 0 {
+			now := time.Now()
+			u.LastFailedLogin = &now
+		}
+	})
+}
+
+func (r *userRepository) IncrementFailedAttempts(ctx context.Context, username string) error {
+	return r.update(byUsername(username), func(u *models.User) {
+		now := time.Now()
+		u.FailedLoginAttempts++
+		u.LastFailedLogin = &now
+	})
+}
+
+func (r *userRepository) ResetFailedAttempts(ctx context.Context, username string) error {
+	return r.update(byUsername(username), func(u *models.User) {
+		u.FailedLoginAttempts = 0
+		u.LastFailedLogin = nil
+	})
+}
+
+// normalizeEmail matches how suppressions are keyed
+func normalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// deref returns the value p points to, or the zero value
+func deref[T any](p *T) T {
+	var zero T
+	if p == nil {
+		return zero
+	}
+	return *p
+}
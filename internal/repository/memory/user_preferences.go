@@ -0,0 +1,133 @@
+package memory
+
+import (
+	"bytes"
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// userPreference is a row of the user_preferences table, the locale is the user's language
+type userPreference struct {
+	zoneID       *uuid.UUID
+	currencyID   *uuid.UUID
+	timezone     *string
+	weeklyReport bool
+	updatedAt    time.Time
+}
+
+type userPreferenceRepository struct {
+	base
+}
+
+// NewUserPreferenceRepository creates a new in-memory user preference repository
+func NewUserPreferenceRepository(store *Store) repository.UserPreferenceRepository {
+	return &userPreferenceRepository{base{store}}
+}
+
+func (r *userPreferenceRepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findUser(func(u *models.User) bool { return u.ID == userID && u.DeletedAt == nil })
+	if i < 0 {
+		return nil, repository.ErrUserNotFound
+	}
+
+	return s.loadPreferences(userID, s.users[i].Language), nil
+}
+
+// loadPreferences returns the stored preferences of a user with the language. s.mu must be
+// held.
+func (s *Store) loadPreferences(userID uuid.UUID, language string) *models.UserPreferences {
+	prefs := &models.UserPreferences{UserID: userID, Locale: language}
+	stored, ok := s.userPreferences[userID]
+	if !ok {
+		return prefs
+	}
+	prefs.ZoneID = clonePtr(stored.zoneID)
+	prefs.CurrencyID = clonePtr(stored.currencyID)
+	prefs.Timezone = clonePtr(stored.timezone)
+	prefs.WeeklyReport = stored.weeklyReport
+	prefs.UpdatedAt = clonePtr(&stored.updatedAt)
+	if stored.zoneID != nil {
+		if j := s.findZone(func(z *models.Zone) bool { return z.ID == *stored.zoneID }); j >= 0 {
+			prefs.Zone = clonePtr(&s.zones[j].Name)
+		}
+	}
+	if stored.currencyID != nil {
+		if j := s.findCurrency(func(c *models.Currency) bool { return c.ID == *stored.currencyID }); j >= 0 {
+			prefs.Currency = clonePtr(&s.currencies[j].Name)
+		}
+	}
+	return prefs
+}
+
+func (r *userPreferenceRepository) Upsert(ctx context.Context, prefs *models.UserPreferences) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findUser(func(u *models.User) bool { return u.ID == prefs.UserID && u.DeletedAt == nil })
+	if i < 0 {
+		return repository.ErrUserNotFound
+	}
+	if prefs.ZoneID != nil && s.findZone(func(z *models.Zone) bool { return z.ID == *prefs.ZoneID }) < 0 {
+		return repository.ErrNotFound
+	}
+	if prefs.CurrencyID != nil && s.findCurrency(func(c *models.Currency) bool { return c.ID == *prefs.CurrencyID }) < 0 {
+		return repository.ErrNotFound
+	}
+
+	if prefs.Locale != "" {
+		s.users[i].Language = prefs.Locale
+	}
+	prefs.Locale = s.users[i].Language
+
+	now := time.Now()
+	s.userPreferences[prefs.UserID] = userPreference{
+		zoneID:       clonePtr(prefs.ZoneID),
+		currencyID:   clonePtr(prefs.CurrencyID),
+		timezone:     clonePtr(prefs.Timezone),
+		weeklyReport: prefs.WeeklyReport,
+		updatedAt:    now,
+	}
+	prefs.UpdatedAt = &now
+	return nil
+}
+
+func (r *userPreferenceRepository) ListWeeklyReports(ctx context.Context) ([]models.UserPreferences, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	list := []models.UserPreferences{}
+	for _, u := range s.users {
+		if u.DeletedAt == nil && s.userPreferences[u.ID].weeklyReport {
+			list = append(list, *s.loadPreferences(u.ID, u.Language))
+		}
+	}
+	slices.SortFunc(list, func(a, b models.UserPreferences) int {
+		return bytes.Compare(a.UserID[:], b.UserID[:])
+	})
+	return list, nil
+}
+
+// clearPreferences removes a deleted zone or currency from the preferences, like the
+// foreign keys of the table set to null. s.mu must be held.
+func (s *Store) clearPreferences(id uuid.UUID) {
+	for userID, pref := range s.userPreferences {
+		if pref.zoneID != nil && *pref.zoneID == id {
+			pref.zoneID = nil
+		}
+		if pref.currencyID != nil && *pref.currencyID == id {
+			pref.currencyID = nil
+		}
+		s.userPreferences[userID] = pref
+	}
+}
@@ -0,0 +1,61 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserPreferenceRepository(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	roles := memory.NewRoleRepository(store)
+	zones := memory.NewZoneRepository(store)
+	prefs := memory.NewUserPreferenceRepository(store)
+
+	role, err := roles.GetByName(ctx, "user")
+	require.NoError(t, err)
+	user := &models.User{Username: "alice", Password: "hash", RoleID: role.ID}
+	require.NoError(t, users.Create(ctx, user))
+
+	zone := &models.Zone{Name: "NO1", Timezone: "Europe/Oslo"}
+	require.NoError(t, zones.Create(ctx, zone))
+	require.NoError(t, prefs.Upsert(ctx, &models.UserPreferences{UserID: user.ID, ZoneID: &zone.ID, Locale: models.LanguageSwedish}))
+
+	got, err := prefs.Get(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "NO1", *got.Zone)
+	require.Equal(t, models.LanguageSwedish, got.Locale)
+	stored, err := users.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageSwedish, stored.Language)
+
+	// Deleting the zone clears the default, like the foreign key does
+	require.NoError(t, zones.Delete(ctx, zone.ID))
+	got, err = prefs.Get(ctx, user.ID)
+	require.NoError(t, err)
+	require.Nil(t, got.ZoneID)
+	require.Nil(t, got.Zone)
+
+	missing := uuid.New()
+	require.ErrorIs(t, prefs.Upsert(ctx, &models.UserPreferences{UserID: user.ID, CurrencyID: &missing}), repository.ErrNotFound)
+
+	// Users opting in to the weekly report are listed until they are deleted
+	require.NoError(t, prefs.Upsert(ctx, &models.UserPreferences{UserID: user.ID, WeeklyReport: true}))
+	reports, err := prefs.ListWeeklyReports(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, user.ID, reports[0].UserID)
+	require.NoError(t, users.Delete(ctx, user.ID))
+	reports, err = prefs.ListWeeklyReports(ctx)
+	require.NoError(t, err)
+	require.Empty(t, reports)
+	_, err = prefs.Get(ctx, user.ID)
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+}
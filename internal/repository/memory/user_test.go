@@ -0,0 +1,119 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserRepository(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	users := memory.NewUserRepository(store)
+	roles := memory.NewRoleRepository(store)
+	suppressions := memory.NewEmailSuppressionRepository(store)
+
+	userRole, err := roles.GetByName(ctx, "user")
+	require.NoError(t, err)
+	adminRole, err := roles.GetByName(ctx, "admin")
+	require.NoError(t, err)
+
+	email := "Alice@Example.com"
+	alice := &models.User{Username: "alice", Password: "hash", Email: &email, RoleID: userRole.ID}
+	require.NoError(t, users.Create(ctx, alice))
+	require.NotZero(t, alice.ID)
+	require.Equal(t, models.EmailStatusDeliverable, alice.EmailStatus)
+	require.Equal(t, models.DefaultLanguage, alice.Language)
+
+	// Usernames are unique
+	require.ErrorIs(t, users.Create(ctx, &models.User{Username: "alice", RoleID: userRole.ID}), repository.ErrConflict)
+
+	bob := &models.User{Username: "bob", Password: "hash", RoleID: adminRole.ID}
+	require.NoError(t, users.Create(ctx, bob))
+
+	// Reads join the role and the suppression status, and don't share memory with the store
+	require.NoError(t, suppressions.Upsert(ctx, &models.EmailSuppression{Email: email, Reason: models.EmailSuppressionBounced}))
+	got, err := users.GetByEmail(ctx, email)
+	require.NoError(t, err)
+	require.Equal(t, "user", got.Role.Name)
+	require.Equal(t, string(models.EmailSuppressionBounced), got.EmailStatus)
+	*got.Email = "changed@example.com"
+	got, err = users.GetByID(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Equal(t, email, *got.Email)
+
+	// Updating to another user's email conflicts
+	bob.Email = &email
+	require.ErrorIs(t, users.Update(ctx, bob), repository.ErrConflict)
+	bob.Email = nil
+
+	// The language is kept when an update leaves it empty
+	bob.Language = models.LanguageSwedish
+	require.NoError(t, users.Update(ctx, bob))
+	bob.Language = ""
+	require.NoError(t, users.Update(ctx, bob))
+	got, err = users.GetByID(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Equal(t, models.LanguageSwedish, got.Language)
+
+	require.NoError(t, users.IncrementFailedAttempts(ctx, "alice"))
+	got, err = users.GetByUsername(ctx, "alice")
+	require.NoError(t, err)
+	require.Equal(t, 1, got.FailedLoginAttempts)
+	require.NotNil(t, got.LastFailedLogin)
+	require.ErrorIs(t, users.IncrementFailedAttempts(ctx, "missing"), repository.ErrNotFound)
+
+	search := "ALI"
+	list, err := users.List(ctx, repository.UserFilter{Search: &search})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Empty(t, list[0].Password)
+
+	list, err = users.List(ctx, repository.UserFilter{OrderBy: "username", OrderDesc: true})
+	require.NoError(t, err)
+	require.Equal(t, []string{"bob", "alice"}, []string{list[0].Username, list[1].Username})
+	limit := 1
+	total, err := users.Total(ctx, repository.UserFilter{Limit: &limit})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+	_, err = users.List(ctx, repository.UserFilter{OrderBy: "password; DROP TABLE users"})
+	require.Error(t, err)
+
+	// Admins can't be deleted, roles in use can't either, and deleted users disappear
+	require.ErrorIs(t, users.Delete(ctx, bob.ID), repository.ErrAdminDelete)
+	custom := &models.Role{Name: "custom"}
+	require.NoError(t, roles.Create(ctx, custom))
+	got.RoleID = custom.ID
+	require.NoError(t, users.Update(ctx, got))
+	require.ErrorIs(t, roles.Delete(ctx, custom.ID), repository.ErrHasAssociatedRecords)
+	require.ErrorIs(t, roles.Delete(ctx, userRole.ID), repository.ErrProtectedRole)
+
+	require.NoError(t, users.Delete(ctx, alice.ID))
+	_, err = users.GetByID(ctx, alice.ID)
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+	require.ErrorIs(t, users.Delete(ctx, alice.ID), repository.ErrUserNotFound)
+	require.NoError(t, roles.Delete(ctx, custom.ID))
+	_, err = roles.GetByID(ctx, custom.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	// Deleted users are listed on request and can be restored or purged
+	list, err = users.List(ctx, repository.UserFilter{IncludeDeleted: true})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.ErrorIs(t, users.Restore(ctx, bob.ID), repository.ErrUserNotFound)
+	require.ErrorIs(t, users.HardDelete(ctx, bob.ID), repository.ErrUserNotFound)
+	require.NoError(t, users.Restore(ctx, alice.ID))
+	_, err = users.GetByID(ctx, alice.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, users.Delete(ctx, alice.ID))
+	require.NoError(t, users.HardDelete(ctx, alice.ID))
+	list, err = users.List(ctx, repository.UserFilter{IncludeDeleted: true})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.ErrorIs(t, users.Restore(ctx, alice.ID), repository.ErrUserNotFound)
+}
@@ -0,0 +1,234 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type webhookRepository struct {
+	base
+}
+
+// NewWebhookRepository creates a new in-memory webhook repository
+func NewWebhookRepository(store *Store) repository.WebhookRepository {
+	return &webhookRepository{base{store}}
+}
+
+func cloneWebhook(webhook models.Webhook) models.Webhook {
+	webhook.Events = slices.Clone(webhook.Events)
+	webhook.CreatedBy = clonePtr(webhook.CreatedBy)
+	return webhook
+}
+
+// findWebhook returns the position of the webhook with id, or -1. s.mu must be held.
+func (s *Store) findWebhook(id uuid.UUID) int {
+	return slices.IndexFunc(s.webhooks, func(w models.Webhook) bool { return w.ID == id })
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if webhook.CreatedBy != nil && !s.userExists(*webhook.CreatedBy, true) {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	webhook.ID = uuid.New()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	s.webhooks = append(s.webhooks, cloneWebhook(*webhook))
+	return nil
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findWebhook(id)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	webhook := cloneWebhook(s.webhooks[i])
+	return &webhook, nil
+}
+
+func (r *webhookRepository) list(match func(w *models.Webhook) bool) []models.Webhook {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	webhooks := make([]models.Webhook, 0)
+	for _, webhook := range s.webhooks {
+		if match(&webhook) {
+			webhooks = append(webhooks, cloneWebhook(webhook))
+		}
+	}
+	return webhooks
+}
+
+func (r *webhookRepository) List(ctx context.Context) ([]models.Webhook, error) {
+	return r.list(func(w *models.Webhook) bool { return true }), nil
+}
+
+func (r *webhookRepository) ListByEvent(ctx context.Context, event models.WebhookEvent) ([]models.Webhook, error) {
+	return r.list(func(w *models.Webhook) bool { return w.Enabled && w.Subscribes(event) }), nil
+}
+
+func (r *webhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findWebhook(webhook.ID)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	stored := &s.webhooks[i]
+	stored.URL = webhook.URL
+	stored.Events = slices.Clone(webhook.Events)
+	stored.Enabled = webhook.Enabled
+	stored.UpdatedAt = time.Now()
+	*webhook = cloneWebhook(*stored)
+	return nil
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findWebhook(id)
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.webhooks = slices.Delete(s.webhooks, i, i+1)
+	s.webhookDeliveries = slices.DeleteFunc(s.webhookDeliveries, func(d models.WebhookDelivery) bool { return d.WebhookID == id })
+	return nil
+}
+
+type webhookDeliveryRepository struct {
+	base
+}
+
+// NewWebhookDeliveryRepository creates a new in-memory webhook delivery repository
+func NewWebhookDeliveryRepository(store *Store) repository.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{base{store}}
+}
+
+func cloneWebhookDelivery(delivery models.WebhookDelivery) models.WebhookDelivery {
+	delivery.Payload = slices.Clone(delivery.Payload)
+	delivery.ResponseStatus = clonePtr(delivery.ResponseStatus)
+	delivery.Error = clonePtr(delivery.Error)
+	delivery.NextAttemptAt = clonePtr(delivery.NextAttemptAt)
+	delivery.DeliveredAt = clonePtr(delivery.DeliveredAt)
+	return delivery
+}
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findWebhook(delivery.WebhookID) < 0 {
+		return repository.ErrNotFound
+	}
+
+	now := time.Now()
+	delivery.ID = uuid.New()
+	delivery.CreatedAt = now
+	if delivery.Status == "" {
+		delivery.Status = models.DeliveryStatusPending
+	}
+	if delivery.NextAttemptAt == nil {
+		delivery.NextAttemptAt = &now
+	}
+	s.webhookDeliveries = append(s.webhookDeliveries, cloneWebhookDelivery(*delivery))
+	return nil
+}
+
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []int
+	for i, d := range s.webhookDeliveries {
+		if d.Status == models.DeliveryStatusPending && d.NextAttemptAt != nil && !d.NextAttemptAt.After(now) {
+			due = append(due, i)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b int) int {
+		return s.webhookDeliveries[a].NextAttemptAt.Compare(*s.webhookDeliveries[b].NextAttemptAt)
+	})
+
+	claimed := make([]models.WebhookDelivery, 0)
+	leased := now.Add(lease)
+	for _, i := range due[:min(len(due), limit)] {
+		s.webhookDeliveries[i].NextAttemptAt = &leased
+		claimed = append(claimed, cloneWebhookDelivery(s.webhookDeliveries[i]))
+	}
+	return claimed, nil
+}
+
+func (r *webhookDeliveryRepository) Record(ctx context.Context, delivery *models.WebhookDelivery) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.webhookDeliveries, func(d models.WebhookDelivery) bool { return d.ID == delivery.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	stored := &s.webhookDeliveries[i]
+	stored.Status = delivery.Status
+	stored.Attempts = delivery.Attempts
+	stored.ResponseStatus = clonePtr(delivery.ResponseStatus)
+	stored.Error = clonePtr(delivery.Error)
+	stored.NextAttemptAt = clonePtr(delivery.NextAttemptAt)
+	stored.DeliveredAt = clonePtr(delivery.DeliveredAt)
+	return nil
+}
+
+func (r *webhookDeliveryRepository) List(ctx context.Context, filter repository.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	deliveries := make([]models.WebhookDelivery, 0)
+	for _, d := range s.webhookDeliveries {
+		if filter.WebhookID != nil && d.WebhookID != *filter.WebhookID {
+			continue
+		}
+		if filter.Event != nil && d.Event != *filter.Event {
+			continue
+		}
+		if filter.Status != nil && d.Status != *filter.Status {
+			continue
+		}
+		deliveries = append(deliveries, cloneWebhookDelivery(d))
+	}
+	slices.SortStableFunc(deliveries, func(a, b models.WebhookDelivery) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return compareString(a.ID.String(), b.ID.String())
+	})
+	return page(deliveries, filter.Limit, filter.Offset), nil
+}
+
+func (r *webhookDeliveryRepository) Total(ctx context.Context, filter repository.WebhookDeliveryFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	deliveries, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(deliveries), nil
+}
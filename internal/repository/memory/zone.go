@@ -0,0 +1,186 @@
+package memory
+
+import (
+	"context"
+	"slices"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type zoneRepository struct {
+	base
+}
+
+// NewZoneRepository creates a new in-memory zone repository
+func NewZoneRepository(store *Store) repository.ZoneRepository {
+	return &zoneRepository{base{store}}
+}
+
+func (s *Store) findZone(match func(z *models.Zone) bool) int {
+	return slices.IndexFunc(s.zones, func(z models.Zone) bool { return match(&z) })
+}
+
+// sameEICCode reports whether two zones share an EIC code, zones without one never do
+func sameEICCode(a, b *models.Zone) bool {
+	return a.EICCode != nil && b.EICCode != nil && *a.EICCode == *b.EICCode
+}
+
+func (r *zoneRepository) Create(ctx context.Context, zone *models.Zone) error {
+	if _, err := time.LoadLocation(zone.Timezone); err != nil {
+		return repository.ErrInvalidTimezone
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.findZone(func(z *models.Zone) bool { return z.Name == zone.Name || sameEICCode(z, zone) }) >= 0 {
+		return repository.ErrConflict
+	}
+
+	now := time.Now()
+	zone.ID = uuid.New()
+	zone.CreatedAt = now
+	zone.UpdatedAt = now
+	s.zones = append(s.zones, *zone)
+	return nil
+}
+
+func (r *zoneRepository) Update(ctx context.Context, zone *models.Zone) error {
+	if _, err := time.LoadLocation(zone.Timezone); err != nil {
+		return repository.ErrInvalidTimezone
+	}
+
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findZone(func(z *models.Zone) bool { return z.ID == zone.ID })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	if s.findZone(func(z *models.Zone) bool { return (z.Name == zone.Name || sameEICCode(z, zone)) && z.ID != zone.ID }) >= 0 {
+		return repository.ErrConflict
+	}
+
+	s.zones[i].Name = zone.Name
+	s.zones[i].Timezone = zone.Timezone
+	s.zones[i].CountryCode = zone.CountryCode
+	s.zones[i].EICCode = zone.EICCode
+	s.zones[i].GridOperator = zone.GridOperator
+	s.zones[i].DisplayName = zone.DisplayName
+	s.zones[i].UpdatedAt = time.Now()
+	*zone = s.zones[i]
+	return nil
+}
+
+func (r *zoneRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, sp := range s.spotPrices {
+		if sp.ZoneID == id {
+			return repository.ErrHasAssociatedRecords
+		}
+	}
+
+	i := s.findZone(func(z *models.Zone) bool { return z.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	s.zones = slices.Delete(s.zones, i, i+1)
+	delete(s.entsoeAreas, id)
+	s.priceAlerts = slices.DeleteFunc(s.priceAlerts, func(a models.PriceAlert) bool { return a.ZoneID == id })
+	s.budgets = slices.DeleteFunc(s.budgets, func(b models.Budget) bool { return b.ZoneID == id })
+	s.tariffs = slices.DeleteFunc(s.tariffs, func(t models.Tariff) bool { return t.ZoneID == id })
+	s.deletePriceForecasts(func(key priceForecastKey) bool { return key.zoneID == id })
+	s.clearPreferences(id)
+	return nil
+}
+
+func (r *zoneRepository) DeleteCascade(ctx context.Context, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error) {
+	s := r.store
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.findZone(func(z *models.Zone) bool { return z.ID == id })
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	summary := s.deleteCascadeSpotPrices(id, reassignTo, func(k *spotPriceKey) *uuid.UUID { return &k.zoneID })
+	s.zones = slices.Delete(s.zones, i, i+1)
+	delete(s.entsoeAreas, id)
+	s.priceAlerts = slices.DeleteFunc(s.priceAlerts, func(a models.PriceAlert) bool { return a.ZoneID == id })
+	s.budgets = slices.DeleteFunc(s.budgets, func(b models.Budget) bool { return b.ZoneID == id })
+	s.tariffs = slices.DeleteFunc(s.tariffs, func(t models.Tariff) bool { return t.ZoneID == id })
+	s.deletePriceForecasts(func(key priceForecastKey) bool { return key.zoneID == id })
+	s.clearPreferences(id)
+	return summary, nil
+}
+
+func (r *zoneRepository) get(match func(z *models.Zone) bool) (*models.Zone, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.findZone(match)
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	zone := s.zones[i]
+	return &zone, nil
+}
+
+func (r *zoneRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Zone, error) {
+	return r.get(func(z *models.Zone) bool { return z.ID == id })
+}
+
+func (r *zoneRepository) GetByName(ctx context.Context, name string) (*models.Zone, error) {
+	return r.get(func(z *models.Zone) bool { return z.Name == name })
+}
+
+var zoneOrder = map[string]func(a, b models.Zone) int{
+	"name":       func(a, b models.Zone) int { return compareString(a.Name, b.Name) },
+	"timezone":   func(a, b models.Zone) int { return compareString(a.Timezone, b.Timezone) },
+	"created_at": func(a, b models.Zone) int { return compareTime(a.CreatedAt, b.CreatedAt) },
+	"updated_at": func(a, b models.Zone) int { return compareTime(a.UpdatedAt, b.UpdatedAt) },
+}
+
+func (r *zoneRepository) List(ctx context.Context, filter repository.ZoneFilter) ([]models.Zone, error) {
+	s := r.store
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	zones := make([]models.Zone, 0)
+	for _, zone := range s.zones {
+		if filter.Search != nil && !containsFold(zone.Name, *filter.Search) {
+			continue
+		}
+		if filter.CountryCode != nil && (zone.CountryCode == nil || *zone.CountryCode != *filter.CountryCode) {
+			continue
+		}
+		zones = append(zones, zone)
+	}
+
+	orderBy := filter.OrderBy
+	if orderBy == "" {
+		orderBy = "name"
+	}
+	if err := sortBy(zones, orderBy, filter.OrderDesc, zoneOrder); err != nil {
+		return nil, err
+	}
+	return page(zones, filter.Limit, filter.Offset), nil
+}
+
+func (r *zoneRepository) Total(ctx context.Context, filter repository.ZoneFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	items, err := r.List(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	return len(items), nil
+}
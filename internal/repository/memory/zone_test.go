@@ -0,0 +1,77 @@
+package memory_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneRepository(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	zones := memory.NewZoneRepository(store)
+
+	// The default zones carry the metadata the migrations seed
+	se1, err := zones.GetByName(ctx, "SE1")
+	require.NoError(t, err)
+	require.Equal(t, "SE", *se1.CountryCode)
+	require.Equal(t, "10Y1001A1001A44P", *se1.EICCode)
+	require.Equal(t, "Luleå", *se1.DisplayName)
+
+	country, eicCode, displayName := "NO", "10YNO-1--------2", "Oslo"
+	no1 := &models.Zone{Name: "NO1", Timezone: "Europe/Oslo", CountryCode: &country, EICCode: &eicCode, DisplayName: &displayName}
+	require.NoError(t, zones.Create(ctx, no1))
+
+	// EIC codes are unique, zones without one don't conflict
+	duplicate := &models.Zone{Name: "NO9", Timezone: "Europe/Oslo", EICCode: &eicCode}
+	require.ErrorIs(t, zones.Create(ctx, duplicate), repository.ErrConflict)
+	require.NoError(t, zones.Create(ctx, &models.Zone{Name: "X1", Timezone: "UTC"}))
+	require.NoError(t, zones.Create(ctx, &models.Zone{Name: "X2", Timezone: "UTC"}))
+	se1.Name = "SE1"
+	se1.EICCode = &eicCode
+	require.ErrorIs(t, zones.Update(ctx, se1), repository.ErrConflict)
+
+	// Updates replace the metadata
+	operator := "Statnett"
+	no1.GridOperator = &operator
+	no1.DisplayName = nil
+	require.NoError(t, zones.Update(ctx, no1))
+	got, err := zones.GetByID(ctx, no1.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Statnett", *got.GridOperator)
+	require.Nil(t, got.DisplayName)
+	require.Equal(t, "NO", *got.CountryCode)
+
+	// Listing filters by country
+	se := "SE"
+	list, err := zones.List(ctx, repository.ZoneFilter{CountryCode: &se})
+	require.NoError(t, err)
+	require.Len(t, list, 4)
+	total, err := zones.Total(ctx, repository.ZoneFilter{CountryCode: &country})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+}
+
+func TestZoneLocalName(t *testing.T) {
+	displayName := "Stockholm"
+	zone := models.Zone{Name: "SE3", Timezone: "Europe/Stockholm", DisplayName: &displayName}
+
+	// The abbreviation follows daylight saving time
+	require.Equal(t, "Stockholm (CET)", zone.LocalName(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+	require.Equal(t, "Stockholm (CEST)", zone.LocalName(time.Date(2024, 7, 15, 12, 0, 0, 0, time.UTC)))
+
+	zone.DisplayName = nil
+	require.Equal(t, "SE3 (CET)", zone.LocalName(time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)))
+
+	data, err := json.Marshal(zone)
+	require.NoError(t, err)
+	var decoded map[string]interface{}
+	require.NoError(t, json.Unmarshal(data, &decoded))
+	require.Contains(t, decoded["local_display_name"], "SE3 (CE")
+}
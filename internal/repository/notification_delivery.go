@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// NotificationDeliveryRepository defines the interface for notification delivery tracking
+type NotificationDeliveryRepository interface {
+	Repository
+	Create(ctx context.Context, delivery *models.NotificationDelivery) error
+	UpdateStatus(ctx context.Context, id uuid.UUID, status models.DeliveryStatus, errMsg *string) error
+	List(ctx context.Context, filter NotificationDeliveryFilter) ([]models.NotificationDelivery, error)
+	// Total counts the deliveries matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter NotificationDeliveryFilter) (int, error)
+}
+
+// NotificationDeliveryFilter defines the filter options for listing deliveries
+type NotificationDeliveryFilter struct {
+	UserID    *uuid.UUID                    // Filter by user ID
+	Channel   *models.NotificationChannel   // Filter by channel
+	AlertType *models.NotificationAlertType // Filter by alert type
+	Status    *models.DeliveryStatus        // Filter by status
+	Limit     *int                          // Limit results
+	Offset    *int                          // Offset results
+}
@@ -0,0 +1,17 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// NotificationPreferenceRepository defines the interface for notification preference operations
+type NotificationPreferenceRepository interface {
+	Repository
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error)
+	Upsert(ctx context.Context, pref *models.NotificationPreference) error
+	// IsEnabled reports whether the user receives the alert type on the channel, defaulting to true
+	IsEnabled(ctx context.Context, userID uuid.UUID, channel models.NotificationChannel, alertType models.NotificationAlertType) (bool, error)
+}
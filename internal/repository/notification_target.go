@@ -0,0 +1,18 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// NotificationTargetRepository defines the interface for chat notification target operations
+type NotificationTargetRepository interface {
+	Repository
+	Create(ctx context.Context, target *models.NotificationTarget) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.NotificationTarget, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.NotificationTarget, error)
+	Update(ctx context.Context, target *models.NotificationTarget) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
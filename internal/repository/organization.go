@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// OrganizationRepository defines the interface for organization and membership operations
+type OrganizationRepository interface {
+	Repository
+	// Create stores the organization with ownerID as its owner
+	Create(ctx context.Context, org *models.Organization, ownerID uuid.UUID) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error)
+	List(ctx context.Context, filter OrganizationFilter) ([]models.Organization, error)
+	// Total counts the organizations matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter OrganizationFilter) (int, error)
+	Update(ctx context.Context, org *models.Organization) error
+	Delete(ctx context.Context, id uuid.UUID) error
+
+	// GetMember returns ErrNotFound when the user is not a member of the organization
+	GetMember(ctx context.Context, orgID, userID uuid.UUID) (*models.OrganizationMember, error)
+	// ListMembers returns the members that aren't deleted, ordered by username
+	ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.OrganizationMember, error)
+	// AddMember returns ErrNotFound when the organization or user doesn't exist and
+	// ErrDuplicateEntry when the user already is a member
+	AddMember(ctx context.Context, member *models.OrganizationMember) error
+	// UpdateMemberRole returns ErrLastOwner when the organization would be left without an owner
+	UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role models.OrganizationRole) error
+	// RemoveMember returns ErrLastOwner when the organization would be left without an owner
+	RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error
+	// SharesOrganization reports whether userID and memberID belong to the same organization,
+	// with userID in one of roles, or in any role when none are given
+	SharesOrganization(ctx context.Context, userID, memberID uuid.UUID, roles ...models.OrganizationRole) (bool, error)
+}
+
+// OrganizationFilter defines the filter options for listing organizations
+type OrganizationFilter struct {
+	UserID *uuid.UUID // Only organizations the user is a member of
+	Search *string    // Search by name
+	Limit  *int       // Limit results
+	Offset *int       // Offset results
+}
@@ -16,10 +16,16 @@ var (
 )
 
 // PasswordHistoryRepository defines the interface for password history operations
+// DefaultPasswordHistoryDepth is how many previous passwords the legacy user repository
+// refuses to reuse. The API reads the depth from the runtime settings instead.
+const DefaultPasswordHistoryDepth = 5
+
 type PasswordHistoryRepository interface {
 	Repository
 	Add(ctx context.Context, userID uuid.UUID, passwordHash string) error
-	CheckReuse(ctx context.Context, userID uuid.UUID, newPasswordHash string) error
+	// CheckReuse returns ErrPasswordReuse when the password matches one of the last depth
+	// passwords of the user from the past 90 days. A depth of 0 allows any password.
+	CheckReuse(ctx context.Context, userID uuid.UUID, newPasswordHash string, depth int) error
 	CleanupOld(ctx context.Context, olderThan time.Duration) error
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.PasswordHistory, error)
 }
@@ -52,13 +58,19 @@ func (r *PasswordHistoryRepositoryImpl) Add(ctx context.Context, userID uuid.UUI
 	return err
 }
 
-func (r *PasswordHistoryRepositoryImpl) CheckReuse(ctx context.Context, userID uuid.UUID, newPassword string) error {
+func (r *PasswordHistoryRepositoryImpl) CheckReuse(ctx context.Context, userID uuid.UUID, newPassword string, depth int) error {
+	if depth <= 0 {
+		return nil
+	}
+
 	query := `
 		SELECT password_hash FROM password_history
 		WHERE user_id = $1
-		AND created_at > NOW() - INTERVAL '90 days'`
+		AND created_at > NOW() - INTERVAL '90 days'
+		ORDER BY created_at DESC
+		LIMIT $2`
 
-	rows, err := r.db.QueryContext(ctx, query, userID)
+	rows, err := r.db.QueryContext(ctx, query, userID, depth)
 	if err != nil {
 		return err
 	}
@@ -11,7 +11,8 @@ import (
 )
 
 const (
-	ResetTokenLength     = 32
+	ResetTokenLength = 32
+	// ResetTokenExpiration is the reset token lifetime used when none is configured
 	ResetTokenExpiration = 1 * time.Hour
 )
 
@@ -25,9 +26,14 @@ type PasswordReset struct {
 }
 
 type PasswordResetRepository interface {
-	Create(ctx context.Context, userID uuid.UUID) (*PasswordReset, error)
+	// Create issues a reset token valid for ttl, or ResetTokenExpiration if ttl is zero
+	Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*PasswordReset, error)
 	GetByToken(ctx context.Context, token string) (*PasswordReset, error)
 	MarkAsUsed(ctx context.Context, id uuid.UUID) error
+	// CountSince returns the number of tokens issued to the user since the given time
+	CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	// DeleteExpired removes the tokens that expired before the given time and returns how many
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
 }
 
 type passwordResetRepositoryImpl struct {
@@ -46,7 +52,11 @@ func generateResetToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-func (r *passwordResetRepositoryImpl) Create(ctx context.Context, userID uuid.UUID) (*PasswordReset, error) {
+func (r *passwordResetRepositoryImpl) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*PasswordReset, error) {
+	if ttl <= 0 {
+		ttl = ResetTokenExpiration
+	}
+
 	token, err := generateResetToken()
 	if err != nil {
 		return nil, err
@@ -56,7 +66,7 @@ func (r *passwordResetRepositoryImpl) Create(ctx context.Context, userID uuid.UU
 		ID:        uuid.New(),
 		UserID:    userID,
 		Token:     token,
-		ExpiresAt: time.Now().Add(ResetTokenExpiration),
+		ExpiresAt: time.Now().Add(ttl),
 	}
 
 	query := `
@@ -134,3 +144,20 @@ func (r *passwordResetRepositoryImpl) MarkAsUsed(ctx context.Context, id uuid.UU
 
 	return nil
 }
+
+func (r *passwordResetRepositoryImpl) CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM password_resets WHERE user_id = $1 AND created_at >= $2",
+		userID, since,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *passwordResetRepositoryImpl) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.db.ExecContext(ctx, "DELETE FROM password_resets WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"slices"
 	"strings"
 	"time"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
 
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 )
 
 type auditLogRepository struct {
@@ -27,19 +27,20 @@ func NewAuditLogRepository(db *sql.DB) repository.AuditLogRepository {
 func (r *auditLogRepository) Create(ctx context.Context, log *models.CreateAuditLogRequest) error {
 	query := `
 		INSERT INTO audit_logs (
-			id, user_id, action, entity_type, entity_id,
+			id, user_id, impersonator_id, action, entity_type, entity_id,
 			description, metadata, ip_address, user_agent,
 			created_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11
 		)`
 
 	id := uuid.New()
 	now := time.Now()
 
-	_, err := r.DB().ExecContext(ctx, query,
+	_, err := r.Conn(ctx).ExecContext(ctx, query,
 		id,
 		log.UserID,
+		log.ImpersonatorID,
 		log.Action,
 		log.EntityType,
 		log.EntityID,
@@ -55,16 +56,17 @@ func (r *auditLogRepository) Create(ctx context.Context, log *models.CreateAudit
 
 func (r *auditLogRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.AuditLog, error) {
 	query := `
-		SELECT id, user_id, action, entity_type, entity_id,
+		SELECT id, user_id, impersonator_id, action, entity_type, entity_id,
 			   description, metadata, ip_address, user_agent,
 			   created_at
 		FROM audit_logs
 		WHERE id = $1`
 
 	var log models.AuditLog
-	err := r.DB().QueryRowContext(ctx, query, id).Scan(
+	err := r.Conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&log.ID,
 		&log.UserID,
+		&log.ImpersonatorID,
 		&log.Action,
 		&log.EntityType,
 		&log.EntityID,
@@ -91,7 +93,7 @@ func (r *auditLogRepository) buildListQuery(filter repository.AuditLogFilter) (s
 	paramCount := 1
 
 	query := `
-		SELECT id, user_id, action, entity_type, entity_id,
+		SELECT id, user_id, impersonator_id, action, entity_type, entity_id,
 			   description, metadata, ip_address, user_agent,
 			   created_at
 		FROM audit_logs`
@@ -102,21 +104,27 @@ func (r *auditLogRepository) buildListQuery(filter repository.AuditLogFilter) (s
 		paramCount++
 	}
 
+	if filter.ImpersonatorID != nil {
+		conditions = append(conditions, fmt.Sprintf("impersonator_id = $%d", paramCount))
+		params = append(params, filter.ImpersonatorID)
+		paramCount++
+	}
+
 	if len(filter.Actions) > 0 {
 		conditions = append(conditions, fmt.Sprintf("action = ANY($%d)", paramCount))
-		params = append(params, pq.Array(filter.Actions))
+		params = append(params, filter.Actions)
 		paramCount++
 	}
 
 	if len(filter.EntityTypes) > 0 {
 		conditions = append(conditions, fmt.Sprintf("entity_type = ANY($%d)", paramCount))
-		params = append(params, pq.Array(filter.EntityTypes))
+		params = append(params, filter.EntityTypes)
 		paramCount++
 	}
 
 	if len(filter.EntityIDs) > 0 {
 		conditions = append(conditions, fmt.Sprintf("entity_id = ANY($%d)", paramCount))
-		params = append(params, pq.Array(filter.EntityIDs))
+		params = append(params, filter.EntityIDs)
 		paramCount++
 	}
 
@@ -149,7 +157,7 @@ func (r *auditLogRepository) buildListQuery(filter repository.AuditLogFilter) (s
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	if filter.OrderBy != "" {
+	if filter.OrderBy != "" && slices.Contains(repository.AuditLogOrderFields, filter.OrderBy) {
 		query += fmt.Sprintf(" ORDER BY %s", filter.OrderBy)
 		if filter.OrderDesc {
 			query += " DESC"
@@ -177,6 +185,12 @@ func (r *auditLogRepository) List(ctx context.Context, filter repository.AuditLo
 	return r.queryLogs(ctx, query, params...)
 }
 
+func (r *auditLogRepository) Total(ctx context.Context, filter repository.AuditLogFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, params := r.buildListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, params)
+}
+
 func (r *auditLogRepository) GetByUserID(ctx context.Context, userID uuid.UUID, filter repository.AuditLogFilter) ([]models.AuditLog, error) {
 	filter.UserID = &userID
 	return r.List(ctx, filter)
@@ -189,14 +203,20 @@ func (r *auditLogRepository) GetByEntityTypeAndID(ctx context.Context, entityTyp
 }
 
 func (r *auditLogRepository) CleanupOld(ctx context.Context, olderThan time.Duration) error {
-	query := `DELETE FROM audit_logs WHERE created_at < $1`
-	cutoff := time.Now().Add(-olderThan)
-	_, err := r.DB().ExecContext(ctx, query, cutoff)
+	_, err := r.DeleteOlderThan(ctx, time.Now().Add(-olderThan))
 	return err
 }
 
+func (r *auditLogRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM audit_logs WHERE created_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
+
 func (r *auditLogRepository) queryLogs(ctx context.Context, query string, args ...interface{}) ([]models.AuditLog, error) {
-	rows, err := r.DB().QueryContext(ctx, query, args...)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -208,6 +228,7 @@ func (r *auditLogRepository) queryLogs(ctx context.Context, query string, args .
 		err := rows.Scan(
 			&log.ID,
 			&log.UserID,
+			&log.ImpersonatorID,
 			&log.Action,
 			&log.EntityType,
 			&log.EntityID,
@@ -40,6 +40,19 @@ func TestAuditLogRepository_Create(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "Success - Entity Not Identified By UUID",
+			log: &models.CreateAuditLogRequest{
+				UserID:      &user.ID,
+				Action:      models.AuditActionRateLimited,
+				EntityType:  "rate_limit",
+				EntityID:    "auth",
+				Description: "Rate limit auth exceeded",
+				IPAddress:   "127.0.0.1",
+				UserAgent:   "test-agent",
+			},
+			wantErr: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -267,6 +280,11 @@ func TestAuditLogRepository_List(t *testing.T) {
 			}
 		})
 	}
+
+	// Total counts matching entries across all pages
+	total, err := tc.AuditRepo.Total(context.Background(), repository.AuditLogFilter{Limit: &limit, Offset: &offset})
+	require.NoError(t, err)
+	require.Equal(t, 3, total)
 }
 
 func TestAuditLogRepository_GetByUserID(t *testing.T) {
@@ -0,0 +1,156 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type budgetRepository struct {
+	repository.BaseRepository
+}
+
+// NewBudgetRepository creates a new PostgreSQL budget repository
+func NewBudgetRepository(db *sql.DB) repository.BudgetRepository {
+	return &budgetRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const budgetColumns = `id, user_id, zone_id, currency_id, amount, notify, last_notified_at, created_at, updated_at`
+
+func (r *budgetRepository) Create(ctx context.Context, budget *models.Budget) error {
+	query := `
+		INSERT INTO budgets (id, user_id, zone_id, currency_id, amount, notify)
+		SELECT $1, id, $3, $4, $5, $6 FROM users WHERE id = $2 AND deleted_at IS NULL
+		RETURNING ` + budgetColumns
+
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		uuid.New(),
+		budget.UserID,
+		budget.ZoneID,
+		budget.CurrencyID,
+		budget.Amount,
+		budget.Notify,
+	), budget)
+	switch {
+	case errorCode(err) == foreignKeyViolation, err == sql.ErrNoRows:
+		return repository.ErrNotFound
+	case errorCode(err) == uniqueViolation:
+		return repository.ErrDuplicateEntry
+	}
+	return err
+}
+
+func (r *budgetRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE id = $1`
+
+	budget := &models.Budget{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), budget)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return budget, nil
+}
+
+func (r *budgetRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.Budget, error) {
+	query := `SELECT ` + budgetColumns + ` FROM budgets WHERE user_id = $1 ORDER BY created_at, id`
+	return r.list(ctx, query, userID)
+}
+
+func (r *budgetRepository) ListNotify(ctx context.Context) ([]models.Budget, error) {
+	query := `
+		SELECT ` + budgetColumns + ` FROM budgets
+		WHERE notify AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+		ORDER BY created_at, id`
+	return r.list(ctx, query)
+}
+
+func (r *budgetRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.Budget, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	budgets := []models.Budget{}
+	for rows.Next() {
+		var budget models.Budget
+		if err := r.scan(rows, &budget); err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, budget)
+	}
+	return budgets, rows.Err()
+}
+
+func (r *budgetRepository) Update(ctx context.Context, budget *models.Budget) error {
+	query := `
+		UPDATE budgets
+		SET amount = $2, notify = $3
+		WHERE id = $1
+		RETURNING ` + budgetColumns
+
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		budget.ID,
+		budget.Amount,
+		budget.Notify,
+	), budget)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *budgetRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM budgets WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *budgetRepository) MarkNotified(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `UPDATE budgets SET last_notified_at = $2 WHERE id = $1`, id, at)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *budgetRepository) scan(row interface{ Scan(...interface{}) error }, budget *models.Budget) error {
+	return row.Scan(
+		&budget.ID,
+		&budget.UserID,
+		&budget.ZoneID,
+		&budget.CurrencyID,
+		&budget.Amount,
+		&budget.Notify,
+		&budget.LastNotifiedAt,
+		&budget.CreatedAt,
+		&budget.UpdatedAt,
+	)
+}
@@ -0,0 +1,61 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBudgetRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewBudgetRepository(tc.DB)
+
+	user := tc.CreateTestUser("alice", "alice@example.com", "password123", false)
+	zone := tc.CreateTestZone("test-zone", "Europe/Stockholm")
+	eur := tc.CreateTestCurrency("EUR")
+	sek := tc.CreateTestCurrency("SEK")
+
+	budget := &models.Budget{UserID: user.ID, ZoneID: zone.ID, CurrencyID: eur.ID, Amount: 150.5, Notify: true}
+	require.NoError(t, repo.Create(ctx, budget))
+	require.NotEqual(t, uuid.Nil, budget.ID)
+	require.ErrorIs(t, repo.Create(ctx, &models.Budget{UserID: user.ID, ZoneID: zone.ID, CurrencyID: eur.ID, Amount: 1}),
+		repository.ErrDuplicateEntry)
+	require.ErrorIs(t, repo.Create(ctx, &models.Budget{UserID: user.ID, ZoneID: uuid.New(), CurrencyID: eur.ID, Amount: 1}),
+		repository.ErrNotFound)
+
+	quiet := &models.Budget{UserID: user.ID, ZoneID: zone.ID, CurrencyID: sek.ID, Amount: 1500}
+	require.NoError(t, repo.Create(ctx, quiet))
+
+	budgets, err := repo.ListByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, budgets, 2)
+	budgets, err = repo.ListNotify(ctx)
+	require.NoError(t, err)
+	require.Len(t, budgets, 1)
+	require.Equal(t, budget.ID, budgets[0].ID)
+	require.Equal(t, 150.5, budgets[0].Amount)
+
+	budget.Amount, budget.Notify = 200, false
+	require.NoError(t, repo.Update(ctx, budget))
+	require.False(t, budget.Notify)
+
+	at := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repo.MarkNotified(ctx, budget.ID, at))
+	got, err := repo.GetByID(ctx, budget.ID)
+	require.NoError(t, err)
+	require.Equal(t, 200.0, got.Amount)
+	require.True(t, got.LastNotifiedAt.Equal(at))
+
+	require.NoError(t, repo.Delete(ctx, budget.ID))
+	require.ErrorIs(t, repo.Delete(ctx, budget.ID), repository.ErrNotFound)
+	_, err = repo.GetByID(ctx, budget.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
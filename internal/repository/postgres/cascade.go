@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+// cascadeDependent is a table referencing zones and currencies, with the columns of its
+// unique key
+type cascadeDependent struct {
+	table   string
+	key     []string
+	deleted func(s *models.DeletionSummary) *int64
+	moved   func(s *models.DeletionSummary) *int64
+}
+
+var cascadeDependents = []cascadeDependent{
+	{
+		table:   "spot_price_sources",
+		key:     []string{"timestamp", "zone_id", "currency_id", "source"},
+		deleted: func(s *models.DeletionSummary) *int64 { return &s.SourceValuesDeleted },
+		moved:   func(s *models.DeletionSummary) *int64 { return &s.SourceValuesMoved },
+	},
+	{
+		table:   "spot_prices",
+		key:     []string{"timestamp", "zone_id", "currency_id"},
+		deleted: func(s *models.DeletionSummary) *int64 { return &s.SpotPricesDeleted },
+		moved:   func(s *models.DeletionSummary) *int64 { return &s.SpotPricesMoved },
+	},
+}
+
+// deleteCascade deletes a row of zones or currencies together with the rows referencing it
+// through column, or moves them to reassignTo. Rows the target already has under the same
+// key are deleted, as moving them would break the key.
+func deleteCascade(ctx context.Context, r *repository.BaseRepository, table, column string, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Lock the row so no prices are added for it while they are being removed
+	var locked uuid.UUID
+	err = tx.QueryRowContext(ctx, fmt.Sprintf(`SELECT id FROM %s WHERE id = $1 FOR UPDATE`, table), id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := cascadeRevisions(ctx, tx, column, id, reassignTo); err != nil {
+		return nil, err
+	}
+
+	summary := &models.DeletionSummary{}
+	for _, dependent := range cascadeDependents {
+		if reassignTo == nil {
+			query := fmt.Sprintf(`DELETE FROM %s WHERE %s = $1`, dependent.table, column)
+			if *dependent.deleted(summary), err = execCount(ctx, tx, query, id); err != nil {
+				return nil, fmt.Errorf("failed to delete %s: %w", dependent.table, err)
+			}
+			continue
+		}
+
+		var matches []string
+		for _, key := range dependent.key {
+			if key != column {
+				matches = append(matches, fmt.Sprintf("t.%s = d.%s", key, key))
+			}
+		}
+		collisions := fmt.Sprintf(`
+			DELETE FROM %[1]s d
+			WHERE d.%[2]s = $1 AND EXISTS (
+				SELECT 1 FROM %[1]s t WHERE t.%[2]s = $2 AND %[3]s
+			)`, dependent.table, column, strings.Join(matches, " AND "))
+		if *dependent.deleted(summary), err = execCount(ctx, tx, collisions, id, *reassignTo); err != nil {
+			return nil, fmt.Errorf("failed to delete %s the target has: %w", dependent.table, err)
+		}
+
+		move := fmt.Sprintf(`UPDATE %s SET %s = $2, updated_at = CURRENT_TIMESTAMP WHERE %s = $1`, dependent.table, column, column)
+		if *dependent.moved(summary), err = execCount(ctx, tx, move, id, *reassignTo); err != nil {
+			return nil, fmt.Errorf("failed to move %s: %w", dependent.table, err)
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM %s WHERE id = $1`, table), id); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return summary, nil
+}
+
+// cascadeRevisions deletes the spot price revisions referencing the row through column,
+// or moves them to reassignTo along with their spot prices. Revisions of spot prices the
+// target already has are deleted, as those spot prices are. It must run before the spot
+// prices are moved.
+func cascadeRevisions(ctx context.Context, tx repository.Executor, column string, id uuid.UUID, reassignTo *uuid.UUID) error {
+	if reassignTo == nil {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf(`DELETE FROM spot_price_revisions WHERE %s = $1`, column), id); err != nil {
+			return fmt.Errorf("failed to delete spot price revisions: %w", err)
+		}
+		return nil
+	}
+
+	other := "zone_id"
+	if column == "zone_id" {
+		other = "currency_id"
+	}
+	collisions := fmt.Sprintf(`
+		DELETE FROM spot_price_revisions r
+		WHERE r.%[1]s = $1 AND EXISTS (
+			SELECT 1 FROM spot_prices t
+			WHERE t.%[1]s = $2 AND t.timestamp = r.timestamp AND t.%[2]s = r.%[2]s
+		)`, column, other)
+	if _, err := tx.ExecContext(ctx, collisions, id, *reassignTo); err != nil {
+		return fmt.Errorf("failed to delete spot price revisions the target has: %w", err)
+	}
+	move := fmt.Sprintf(`UPDATE spot_price_revisions SET %s = $2 WHERE %s = $1`, column, column)
+	if _, err := tx.ExecContext(ctx, move, id, *reassignTo); err != nil {
+		return fmt.Errorf("failed to move spot price revisions: %w", err)
+	}
+	return nil
+}
+
+// execCount runs a statement and returns the number of rows it affected
+func execCount(ctx context.Context, tx repository.Executor, query string, args ...interface{}) (int64, error) {
+	result, err := tx.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
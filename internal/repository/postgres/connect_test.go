@@ -0,0 +1,50 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/database"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+// TestConnect_SliceArguments runs queries taking slices on a database opened by
+// database.Connect, the way the server opens it. Its instrumented connections have to
+// let pgx encode the slices as arrays.
+func TestConnect_SliceArguments(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	db, err := database.Connect(tc.Config.Database)
+	require.NoError(t, err)
+	defer db.Close()
+	ctx := context.Background()
+
+	user := tc.CreateTestUser("slice-user", "slice@example.com", "password123", false)
+
+	auditLogs := postgres.NewAuditLogRepository(db)
+	require.NoError(t, auditLogs.Create(ctx, &models.CreateAuditLogRequest{
+		UserID:      &user.ID,
+		Action:      models.AuditActionUpdate,
+		EntityType:  "user",
+		EntityID:    user.ID.String(),
+		Description: "Updated user",
+	}))
+	logs, err := auditLogs.List(ctx, repository.AuditLogFilter{
+		Actions:     []models.AuditAction{models.AuditActionUpdate},
+		EntityTypes: []string{"user"},
+		EntityIDs:   []string{user.ID.String()},
+	})
+	require.NoError(t, err)
+	require.Len(t, logs, 1)
+
+	twoFactor := postgres.NewTwoFactorRepository(db)
+	require.NoError(t, twoFactor.Enroll(ctx, user.ID, "secret"))
+	require.NoError(t, twoFactor.Enable(ctx, user.ID, 1, []string{"hash-1", "hash-2"}, time.Now()))
+	count, err := twoFactor.CountBackupCodes(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+}
@@ -0,0 +1,152 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type consumptionRepository struct {
+	repository.BaseRepository
+}
+
+// NewConsumptionRepository creates a new PostgreSQL consumption repository
+func NewConsumptionRepository(db *sql.DB) repository.ConsumptionRepository {
+	return NewConsumptionRepositoryWithReplicas(db, nil)
+}
+
+// NewConsumptionRepositoryWithReplicas creates a new PostgreSQL consumption repository
+// reading listings from replicas, nil reads them from db
+func NewConsumptionRepositoryWithReplicas(db *sql.DB, replicas *repository.ReplicaSet) repository.ConsumptionRepository {
+	return &consumptionRepository{
+		BaseRepository: repository.NewBaseRepositoryWithReplicas(db, replicas),
+	}
+}
+
+func (r *consumptionRepository) CreateBatch(ctx context.Context, records []models.ConsumptionRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	valueStrings := make([]string, 0, len(records))
+	valueArgs := make([]interface{}, 0, len(records)*6)
+	now := time.Now()
+
+	for i, record := range records {
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d)",
+			i*6+1, i*6+2, i*6+3, i*6+4, i*6+5, i*6+6))
+		valueArgs = append(valueArgs,
+			record.UserID,
+			record.MeterID,
+			record.Timestamp,
+			record.KWh,
+			now,
+			now,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO consumption_records (user_id, meter_id, timestamp, kwh, created_at, updated_at)
+		VALUES %s
+		ON CONFLICT (user_id, meter_id, timestamp) DO UPDATE
+		SET kwh = EXCLUDED.kwh,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at`, strings.Join(valueStrings, ","))
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, valueArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Rows are returned in the order of the values
+	i := 0
+	for rows.Next() {
+		if err := rows.Scan(&records[i].CreatedAt, &records[i].UpdatedAt); err != nil {
+			return err
+		}
+		i++
+	}
+
+	return rows.Err()
+}
+
+func (r *consumptionRepository) List(ctx context.Context, filter repository.ConsumptionFilter) ([]models.ConsumptionRecord, error) {
+	query, args := consumptionListQuery(filter)
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]models.ConsumptionRecord, 0)
+	for rows.Next() {
+		var record models.ConsumptionRecord
+		if err := rows.Scan(
+			&record.UserID,
+			&record.MeterID,
+			&record.Timestamp,
+			&record.KWh,
+			&record.CreatedAt,
+			&record.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (r *consumptionRepository) Total(ctx context.Context, filter repository.ConsumptionFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := consumptionListQuery(filter)
+	return countRows(ctx, r.Reader(ctx), query, args)
+}
+
+// consumptionListQuery builds the query selecting the consumption records matching the filter
+func consumptionListQuery(filter repository.ConsumptionFilter) (string, []interface{}) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{filter.UserID}
+	argCount := 2
+
+	if filter.MeterID != nil {
+		conditions = append(conditions, fmt.Sprintf("meter_id = $%d", argCount))
+		args = append(args, *filter.MeterID)
+		argCount++
+	}
+
+	if filter.StartTime != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", argCount))
+		args = append(args, *filter.StartTime)
+		argCount++
+	}
+
+	if filter.EndTime != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp <= $%d", argCount))
+		args = append(args, *filter.EndTime)
+		argCount++
+	}
+
+	query := `
+		SELECT user_id, meter_id, timestamp, kwh, created_at, updated_at
+		FROM consumption_records
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY timestamp ASC, meter_id ASC`
+
+	if filter.Limit != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, *filter.Limit)
+		argCount++
+	}
+
+	if filter.Offset != nil {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, *filter.Offset)
+	}
+
+	return query, args
+}
@@ -0,0 +1,63 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConsumptionRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewConsumptionRepository(tc.DB)
+
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@test.com", "password123", false)
+
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	records := []models.ConsumptionRecord{
+		{UserID: alice.ID, MeterID: "main", Timestamp: start, KWh: 1.5},
+		{UserID: alice.ID, MeterID: "main", Timestamp: start.Add(time.Hour), KWh: 2},
+		{UserID: alice.ID, MeterID: "garage", Timestamp: start, KWh: 0.25},
+		{UserID: bob.ID, MeterID: "main", Timestamp: start, KWh: 3},
+	}
+	require.NoError(t, repo.CreateBatch(ctx, records))
+	for _, record := range records {
+		assert.False(t, record.CreatedAt.IsZero())
+	}
+
+	// Records of the same meter and time are replaced
+	require.NoError(t, repo.CreateBatch(ctx, []models.ConsumptionRecord{
+		{UserID: alice.ID, MeterID: "main", Timestamp: start, KWh: 1.75},
+	}))
+
+	list, err := repo.List(ctx, repository.ConsumptionFilter{UserID: alice.ID})
+	require.NoError(t, err)
+	require.Len(t, list, 3)
+	assert.Equal(t, "garage", list[0].MeterID)
+	assert.Equal(t, "main", list[1].MeterID)
+	assert.Equal(t, 1.75, list[1].KWh)
+	assert.True(t, start.Add(time.Hour).Equal(list[2].Timestamp))
+
+	meter := "main"
+	end := start
+	list, err = repo.List(ctx, repository.ConsumptionFilter{UserID: alice.ID, MeterID: &meter, StartTime: &start, EndTime: &end})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	assert.Equal(t, 1.75, list[0].KWh)
+
+	limit, offset := 1, 1
+	list, err = repo.List(ctx, repository.ConsumptionFilter{UserID: alice.ID, Limit: &limit, Offset: &offset})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	total, err := repo.Total(ctx, repository.ConsumptionFilter{UserID: alice.ID, Limit: &limit, Offset: &offset})
+	require.NoError(t, err)
+	assert.Equal(t, 3, total)
+}
@@ -8,7 +8,6 @@ import (
 	"wattwatch/internal/repository"
 
 	"github.com/google/uuid"
-	"github.com/lib/pq"
 )
 
 type currencyRepository struct {
@@ -17,28 +16,42 @@ type currencyRepository struct {
 
 // NewCurrencyRepository creates a new PostgreSQL currency repository
 func NewCurrencyRepository(db *sql.DB) repository.CurrencyRepository {
+	return NewCurrencyRepositoryWithReplicas(db, nil)
+}
+
+// NewCurrencyRepositoryWithReplicas creates a new PostgreSQL currency repository
+// reading listings from replicas, nil reads them from db
+func NewCurrencyRepositoryWithReplicas(db *sql.DB, replicas *repository.ReplicaSet) repository.CurrencyRepository {
 	return &currencyRepository{
-		BaseRepository: repository.NewBaseRepository(db),
+		BaseRepository: repository.NewBaseRepositoryWithReplicas(db, replicas),
 	}
 }
 
+const currencyColumns = `id, name, numeric_code, symbol, symbol_first, minor_units, created_at, updated_at`
+
 func (r *currencyRepository) Create(ctx context.Context, currency *models.Currency) error {
+	currency.FillISO4217()
+
 	query := `
-		INSERT INTO currencies (id, name, created_at, updated_at)
-		VALUES ($1, $2, $3, $3)
+		INSERT INTO currencies (id, name, numeric_code, symbol, symbol_first, minor_units, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
 		RETURNING id, created_at, updated_at`
 
 	now := time.Now()
 	currency.ID = uuid.New()
 
-	err := r.DB().QueryRowContext(ctx, query,
+	err := r.Conn(ctx).QueryRowContext(ctx, query,
 		currency.ID,
 		currency.Name,
+		currency.NumericCode,
+		currency.Symbol,
+		currency.SymbolFirst,
+		currency.MinorUnits,
 		now,
 	).Scan(&currency.ID, &currency.CreatedAt, &currency.UpdatedAt)
 
 	if err != nil {
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		if errorCode(err) == uniqueViolation {
 			return repository.ErrConflict
 		}
 		return err
@@ -47,14 +60,20 @@ func (r *currencyRepository) Create(ctx context.Context, currency *models.Curren
 }
 
 func (r *currencyRepository) Update(ctx context.Context, currency *models.Currency) error {
+	currency.FillISO4217()
+
 	query := `
 		UPDATE currencies
-		SET name = $1, updated_at = $2
-		WHERE id = $3
+		SET name = $1, numeric_code = $2, symbol = $3, symbol_first = $4, minor_units = $5, updated_at = $6
+		WHERE id = $7
 		RETURNING updated_at`
 
-	result := r.DB().QueryRowContext(ctx, query,
+	result := r.Conn(ctx).QueryRowContext(ctx, query,
 		currency.Name,
+		currency.NumericCode,
+		currency.Symbol,
+		currency.SymbolFirst,
+		currency.MinorUnits,
 		time.Now(),
 		currency.ID,
 	)
@@ -63,7 +82,7 @@ func (r *currencyRepository) Update(ctx context.Context, currency *models.Curren
 		if err == sql.ErrNoRows {
 			return repository.ErrNotFound
 		}
-		if pqErr, ok := err.(*pq.Error); ok && pqErr.Code.Name() == "unique_violation" {
+		if errorCode(err) == uniqueViolation {
 			return repository.ErrConflict
 		}
 		return err
@@ -72,20 +91,21 @@ func (r *currencyRepository) Update(ctx context.Context, currency *models.Curren
 }
 
 func (r *currencyRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	// First check if there are any spot prices using this currency
-	var count int
-	err := r.DB().QueryRowContext(ctx, `
-		SELECT COUNT(*) FROM spot_prices WHERE currency_id = $1
-	`, id).Scan(&count)
+	// First check if there are any spot prices using this currency. EXISTS stops at the
+	// first one instead of counting the prices of every chunk.
+	var exists bool
+	err := r.Conn(ctx).QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM spot_prices WHERE currency_id = $1)
+	`, id).Scan(&exists)
 	if err != nil {
 		return err
 	}
-	if count > 0 {
+	if exists {
 		return repository.ErrHasAssociatedRecords
 	}
 
 	query := `DELETE FROM currencies WHERE id = $1`
-	result, err := r.DB().ExecContext(ctx, query, id)
+	result, err := r.Conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -101,19 +121,15 @@ func (r *currencyRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (r *currencyRepository) DeleteCascade(ctx context.Context, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error) {
+	return deleteCascade(ctx, &r.BaseRepository, "currencies", "currency_id", id, reassignTo)
+}
+
 func (r *currencyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Currency, error) {
-	query := `
-		SELECT id, name, created_at, updated_at
-		FROM currencies
-		WHERE id = $1`
+	query := `SELECT ` + currencyColumns + ` FROM currencies WHERE id = $1`
 
 	currency := &models.Currency{}
-	err := r.DB().QueryRowContext(ctx, query, id).Scan(
-		&currency.ID,
-		&currency.Name,
-		&currency.CreatedAt,
-		&currency.UpdatedAt,
-	)
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), currency)
 
 	if err == sql.ErrNoRows {
 		return nil, repository.ErrNotFound
@@ -125,18 +141,10 @@ func (r *currencyRepository) GetByID(ctx context.Context, id uuid.UUID) (*models
 }
 
 func (r *currencyRepository) GetByName(ctx context.Context, name string) (*models.Currency, error) {
-	query := `
-		SELECT id, name, created_at, updated_at
-		FROM currencies
-		WHERE name = $1`
+	query := `SELECT ` + currencyColumns + ` FROM currencies WHERE name = $1`
 
 	currency := &models.Currency{}
-	err := r.DB().QueryRowContext(ctx, query, name).Scan(
-		&currency.ID,
-		&currency.Name,
-		&currency.CreatedAt,
-		&currency.UpdatedAt,
-	)
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, name), currency)
 
 	if err == sql.ErrNoRows {
 		return nil, repository.ErrNotFound
@@ -148,12 +156,9 @@ func (r *currencyRepository) GetByName(ctx context.Context, name string) (*model
 }
 
 func (r *currencyRepository) List(ctx context.Context) ([]models.Currency, error) {
-	query := `
-		SELECT id, name, created_at, updated_at
-		FROM currencies
-		ORDER BY name ASC`
+	query := `SELECT ` + currencyColumns + ` FROM currencies ORDER BY name ASC`
 
-	rows, err := r.DB().QueryContext(ctx, query)
+	rows, err := r.Reader(ctx).QueryContext(ctx, query)
 	if err != nil {
 		return nil, err
 	}
@@ -162,12 +167,7 @@ func (r *currencyRepository) List(ctx context.Context) ([]models.Currency, error
 	var currencies []models.Currency
 	for rows.Next() {
 		var currency models.Currency
-		if err := rows.Scan(
-			&currency.ID,
-			&currency.Name,
-			&currency.CreatedAt,
-			&currency.UpdatedAt,
-		); err != nil {
+		if err := r.scan(rows, &currency); err != nil {
 			return nil, err
 		}
 		currencies = append(currencies, currency)
@@ -178,3 +178,16 @@ func (r *currencyRepository) List(ctx context.Context) ([]models.Currency, error
 	}
 	return currencies, nil
 }
+
+func (r *currencyRepository) scan(row interface{ Scan(...interface{}) error }, currency *models.Currency) error {
+	return row.Scan(
+		&currency.ID,
+		&currency.Name,
+		&currency.NumericCode,
+		&currency.Symbol,
+		&currency.SymbolFirst,
+		&currency.MinorUnits,
+		&currency.CreatedAt,
+		&currency.UpdatedAt,
+	)
+}
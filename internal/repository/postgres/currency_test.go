@@ -279,7 +279,7 @@ func TestCurrencyRepository_GetByName(t *testing.T) {
 		},
 		{
 			name:    "Non-existent Name",
-			input:   "NOK",
+			input:   "CAD",
 			wantErr: true,
 			errType: repository.ErrNotFound,
 		},
@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type deviceTokenRepository struct {
+	repository.BaseRepository
+}
+
+// NewDeviceTokenRepository creates a new PostgreSQL device token repository
+func NewDeviceTokenRepository(db *sql.DB) repository.DeviceTokenRepository {
+	return &deviceTokenRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const deviceTokenColumns = `id, user_id, channel, token, p256dh, auth, user_agent, last_used_at, created_at, updated_at`
+
+func (r *deviceTokenRepository) Upsert(ctx context.Context, device *models.DeviceToken) error {
+	// First verify the user exists
+	var exists bool
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", device.UserID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrNotFound
+	}
+
+	// A token identifies a physical device, so re-registering moves it to the current user
+	query := `
+		INSERT INTO device_tokens (id, user_id, channel, token, p256dh, auth, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (channel, token) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			p256dh = EXCLUDED.p256dh,
+			auth = EXCLUDED.auth,
+			user_agent = EXCLUDED.user_agent
+		RETURNING ` + deviceTokenColumns
+
+	return r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		uuid.New(),
+		device.UserID,
+		device.Channel,
+		device.Token,
+		device.P256dh,
+		device.Auth,
+		device.UserAgent,
+	), device)
+}
+
+func (r *deviceTokenRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.DeviceToken, error) {
+	query := `SELECT ` + deviceTokenColumns + ` FROM device_tokens WHERE id = $1`
+
+	device := &models.DeviceToken{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), device)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return device, nil
+}
+
+func (r *deviceTokenRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.DeviceToken, error) {
+	query := `SELECT ` + deviceTokenColumns + ` FROM device_tokens WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	devices := []models.DeviceToken{}
+	for rows.Next() {
+		var device models.DeviceToken
+		if err := r.scan(rows, &device); err != nil {
+			return nil, err
+		}
+		devices = append(devices, device)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return devices, nil
+}
+
+func (r *deviceTokenRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM device_tokens WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *deviceTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := r.Conn(ctx).ExecContext(ctx, `UPDATE device_tokens SET last_used_at = $2 WHERE id = $1`, id, usedAt)
+	return err
+}
+
+func (r *deviceTokenRepository) scan(row interface{ Scan(...interface{}) error }, device *models.DeviceToken) error {
+	return row.Scan(
+		&device.ID,
+		&device.UserID,
+		&device.Channel,
+		&device.Token,
+		&device.P256dh,
+		&device.Auth,
+		&device.UserAgent,
+		&device.LastUsedAt,
+		&device.CreatedAt,
+		&device.UpdatedAt,
+	)
+}
@@ -0,0 +1,111 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeviceTokenRepository_Upsert(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewDeviceTokenRepository(tc.DB)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+	other := tc.CreateTestUser("other-user", "other@example.com", "password123", false)
+
+	tests := []struct {
+		name    string
+		device  *models.DeviceToken
+		wantErr bool
+		errType error
+	}{
+		{
+			name: "Success",
+			device: &models.DeviceToken{
+				UserID:  user.ID,
+				Channel: models.NotificationChannelFCM,
+				Token:   "fcm-token-1",
+			},
+		},
+		{
+			name: "Existing Token Moves To New User",
+			device: &models.DeviceToken{
+				UserID:  other.ID,
+				Channel: models.NotificationChannelFCM,
+				Token:   "fcm-token-1",
+			},
+		},
+		{
+			name: "Non-existent User",
+			device: &models.DeviceToken{
+				UserID:  uuid.New(),
+				Channel: models.NotificationChannelFCM,
+				Token:   "fcm-token-2",
+			},
+			wantErr: true,
+			errType: repository.ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := repo.Upsert(context.Background(), tt.device)
+			if tt.wantErr {
+				require.Error(t, err)
+				if tt.errType != nil {
+					require.ErrorIs(t, err, tt.errType)
+				}
+				return
+			}
+			require.NoError(t, err)
+			require.NotEqual(t, uuid.Nil, tt.device.ID)
+
+			devices, err := repo.ListByUserID(context.Background(), tt.device.UserID)
+			require.NoError(t, err)
+			require.Len(t, devices, 1)
+			require.Equal(t, tt.device.Token, devices[0].Token)
+		})
+	}
+
+	// The token was reassigned, so the first user no longer has it
+	devices, err := repo.ListByUserID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Empty(t, devices)
+}
+
+func TestDeviceTokenRepository_DeleteAndMarkUsed(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewDeviceTokenRepository(tc.DB)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+
+	p256dh, auth := "p256dh-key", "auth-secret"
+	device := &models.DeviceToken{
+		UserID:  user.ID,
+		Channel: models.NotificationChannelWebPush,
+		Token:   "https://push.example.com/abc",
+		P256dh:  &p256dh,
+		Auth:    &auth,
+	}
+	require.NoError(t, repo.Upsert(context.Background(), device))
+
+	usedAt := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repo.MarkUsed(context.Background(), device.ID, usedAt))
+
+	got, err := repo.GetByID(context.Background(), device.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.LastUsedAt)
+	require.True(t, usedAt.Equal(got.LastUsedAt.UTC()))
+	require.Equal(t, p256dh, *got.P256dh)
+
+	require.NoError(t, repo.Delete(context.Background(), device.ID))
+	require.ErrorIs(t, repo.Delete(context.Background(), device.ID), repository.ErrNotFound)
+
+	_, err = repo.GetByID(context.Background(), device.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
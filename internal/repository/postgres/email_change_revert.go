@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type emailChangeRevertRepository struct {
+	repository.BaseRepository
+}
+
+// NewEmailChangeRevertRepository creates a new PostgreSQL email change revert repository
+func NewEmailChangeRevertRepository(db *sql.DB) repository.EmailChangeRevertRepository {
+	return &emailChangeRevertRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *emailChangeRevertRepository) Create(ctx context.Context, userID uuid.UUID, oldEmail string, newEmail *string, ttl time.Duration) (*repository.EmailChangeRevert, error) {
+	if ttl <= 0 {
+		ttl = repository.EmailChangeRevertExpiration
+	}
+
+	// First verify the user exists
+	var exists bool
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, repository.ErrNotFound
+	}
+
+	token, err := generateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	revert := &repository.EmailChangeRevert{
+		ID:        uuid.New(),
+		UserID:    userID,
+		OldEmail:  oldEmail,
+		NewEmail:  newEmail,
+		Token:     token,
+		ExpiresAt: time.Now().Add(ttl),
+	}
+
+	query := `
+		INSERT INTO email_change_reverts (id, user_id, old_email, new_email, token, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING created_at`
+
+	err = r.Conn(ctx).QueryRowContext(ctx, query,
+		revert.ID,
+		revert.UserID,
+		revert.OldEmail,
+		revert.NewEmail,
+		revert.Token,
+		revert.ExpiresAt,
+	).Scan(&revert.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return revert, nil
+}
+
+func (r *emailChangeRevertRepository) GetByToken(ctx context.Context, token string) (*repository.EmailChangeRevert, error) {
+	query := `
+		SELECT id, user_id, old_email, new_email, token, expires_at, used_at, created_at
+		FROM email_change_reverts
+		WHERE token = $1 AND used_at IS NULL`
+
+	revert := &repository.EmailChangeRevert{}
+	err := r.Conn(ctx).QueryRowContext(ctx, query, token).Scan(
+		&revert.ID,
+		&revert.UserID,
+		&revert.OldEmail,
+		&revert.NewEmail,
+		&revert.Token,
+		&revert.ExpiresAt,
+		&revert.UsedAt,
+		&revert.CreatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if time.Now().After(revert.ExpiresAt) {
+		return nil, repository.ErrTokenExpired
+	}
+
+	return revert, nil
+}
+
+func (r *emailChangeRevertRepository) MarkAsUsed(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx,
+		`UPDATE email_change_reverts SET used_at = CURRENT_TIMESTAMP WHERE id = $1 AND used_at IS NULL`,
+		id,
+	)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrTokenInvalid
+	}
+
+	return nil
+}
+
+func (r *emailChangeRevertRepository) InvalidateForUser(ctx context.Context, userID uuid.UUID) error {
+	_, err := r.Conn(ctx).ExecContext(ctx,
+		`UPDATE email_change_reverts SET used_at = CURRENT_TIMESTAMP WHERE user_id = $1 AND used_at IS NULL`,
+		userID,
+	)
+	return err
+}
+
+func (r *emailChangeRevertRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM email_change_reverts WHERE expires_at < $1`, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -0,0 +1,50 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailChangeRevertRepository(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewEmailChangeRevertRepository(tc.DB)
+	user := tc.CreateTestUser("test-user", "new@example.com", "password123", false)
+	ctx := context.Background()
+
+	_, err := repo.Create(ctx, uuid.New(), "old@example.com", nil, 0)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	newEmail := "new@example.com"
+	revert, err := repo.Create(ctx, user.ID, "old@example.com", &newEmail, time.Hour)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(time.Hour), revert.ExpiresAt, 2*time.Second)
+
+	got, err := repo.GetByToken(ctx, revert.Token)
+	require.NoError(t, err)
+	require.Equal(t, "old@example.com", got.OldEmail)
+	require.Equal(t, newEmail, *got.NewEmail)
+
+	require.NoError(t, repo.MarkAsUsed(ctx, revert.ID))
+	require.ErrorIs(t, repo.MarkAsUsed(ctx, revert.ID), repository.ErrTokenInvalid)
+	_, err = repo.GetByToken(ctx, revert.Token)
+	require.ErrorIs(t, err, repository.ErrTokenInvalid)
+
+	expired, err := repo.Create(ctx, user.ID, "old@example.com", &newEmail, time.Nanosecond)
+	require.NoError(t, err)
+	time.Sleep(time.Millisecond)
+	_, err = repo.GetByToken(ctx, expired.Token)
+	require.ErrorIs(t, err, repository.ErrTokenExpired)
+
+	pending, err := repo.Create(ctx, user.ID, "old@example.com", &newEmail, 0)
+	require.NoError(t, err)
+	require.NoError(t, repo.InvalidateForUser(ctx, user.ID))
+	_, err = repo.GetByToken(ctx, pending.Token)
+	require.ErrorIs(t, err, repository.ErrTokenInvalid)
+}
@@ -0,0 +1,190 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type emailDeadLetterRepository struct {
+	repository.BaseRepository
+}
+
+// NewEmailDeadLetterRepository creates a new PostgreSQL email dead letter repository
+func NewEmailDeadLetterRepository(db *sql.DB) repository.EmailDeadLetterRepository {
+	return &emailDeadLetterRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const emailDeadLetterColumns = `id, kind, recipient, subject, content_type, body, text_body, provider, error, attempts, created_at, updated_at, resent_at`
+
+// scanEmailDeadLetter scans a row selected with emailDeadLetterColumns
+func scanEmailDeadLetter(row interface{ Scan(...interface{}) error }) (*models.EmailDeadLetter, error) {
+	letter := &models.EmailDeadLetter{}
+	err := row.Scan(
+		&letter.ID,
+		&letter.Kind,
+		&letter.Recipient,
+		&letter.Subject,
+		&letter.ContentType,
+		&letter.Body,
+		&letter.TextBody,
+		&letter.Provider,
+		&letter.Error,
+		&letter.Attempts,
+		&letter.CreatedAt,
+		&letter.UpdatedAt,
+		&letter.ResentAt,
+	)
+	return letter, err
+}
+
+func (r *emailDeadLetterRepository) Create(ctx context.Context, letter *models.EmailDeadLetter) error {
+	query := `
+		INSERT INTO email_dead_letters (kind, recipient, subject, content_type, body, text_body, provider, error, attempts)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id, created_at, updated_at`
+
+	return r.Conn(ctx).QueryRowContext(ctx, query,
+		letter.Kind,
+		letter.Recipient,
+		letter.Subject,
+		letter.ContentType,
+		letter.Body,
+		letter.TextBody,
+		letter.Provider,
+		letter.Error,
+		letter.Attempts,
+	).Scan(&letter.ID, &letter.CreatedAt, &letter.UpdatedAt)
+}
+
+func (r *emailDeadLetterRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.EmailDeadLetter, error) {
+	query := `SELECT ` + emailDeadLetterColumns + ` FROM email_dead_letters WHERE id = $1`
+
+	letter, err := scanEmailDeadLetter(r.Conn(ctx).QueryRowContext(ctx, query, id))
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return letter, nil
+}
+
+func (r *emailDeadLetterRepository) List(ctx context.Context, filter repository.EmailDeadLetterFilter) ([]models.EmailDeadLetter, error) {
+	query, args := emailDeadLetterListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	letters := []models.EmailDeadLetter{}
+	for rows.Next() {
+		letter, err := scanEmailDeadLetter(rows)
+		if err != nil {
+			return nil, err
+		}
+		letters = append(letters, *letter)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return letters, nil
+}
+
+func (r *emailDeadLetterRepository) Total(ctx context.Context, filter repository.EmailDeadLetterFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := emailDeadLetterListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+// emailDeadLetterListQuery builds the query selecting the dead letters matching the filter
+func emailDeadLetterListQuery(filter repository.EmailDeadLetterFilter) (string, []interface{}) {
+	query := `SELECT ` + emailDeadLetterColumns + ` FROM email_dead_letters`
+
+	var conditions []string
+	var args []interface{}
+	argCount := 1
+
+	if filter.Recipient != nil {
+		conditions = append(conditions, fmt.Sprintf("recipient ILIKE $%d", argCount))
+		args = append(args, "%"+*filter.Recipient+"%")
+		argCount++
+	}
+
+	if filter.Kind != nil {
+		conditions = append(conditions, fmt.Sprintf("kind = $%d", argCount))
+		args = append(args, *filter.Kind)
+		argCount++
+	}
+
+	if filter.Pending {
+		conditions = append(conditions, "resent_at IS NULL")
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	if filter.Limit != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, *filter.Limit)
+		argCount++
+	}
+
+	if filter.Offset != nil {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, *filter.Offset)
+	}
+
+	return query, args
+}
+
+func (r *emailDeadLetterRepository) MarkResent(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `UPDATE email_dead_letters SET resent_at = CURRENT_TIMESTAMP WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *emailDeadLetterRepository) RecordFailure(ctx context.Context, id uuid.UUID, attempts int, lastError string) error {
+	result, err := r.Conn(ctx).ExecContext(ctx,
+		`UPDATE email_dead_letters SET attempts = attempts + $2, error = $3 WHERE id = $1`,
+		id, attempts, lastError)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,83 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailDeadLetterRepository(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewEmailDeadLetterRepository(tc.DB)
+	ctx := context.Background()
+
+	welcome := &models.EmailDeadLetter{
+		Kind:        "welcome",
+		Recipient:   "dead-letter@example.com",
+		Subject:     "Welcome to WattWatch",
+		ContentType: "text/html",
+		Body:        "<h2>Welcome</h2>",
+		Provider:    "smtp",
+		Error:       "connection refused",
+		Attempts:    3,
+	}
+	require.NoError(t, repo.Create(ctx, welcome))
+	require.NotEqual(t, uuid.Nil, welcome.ID)
+	require.False(t, welcome.CreatedAt.IsZero())
+
+	reset := &models.EmailDeadLetter{
+		Kind:        "password_reset",
+		Recipient:   "dead-letter@example.com",
+		Subject:     "Reset Your Password",
+		ContentType: "text/html",
+		Body:        "<h2>Reset</h2>",
+		Provider:    "sendgrid",
+		Error:       "status 503",
+		Attempts:    1,
+	}
+	require.NoError(t, repo.Create(ctx, reset))
+
+	got, err := repo.GetByID(ctx, welcome.ID)
+	require.NoError(t, err)
+	require.Equal(t, "<h2>Welcome</h2>", got.Body)
+	require.Nil(t, got.ResentAt)
+	_, err = repo.GetByID(ctx, uuid.New())
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	recipient := "DEAD-LETTER@"
+	limit := 1
+	letters, err := repo.List(ctx, repository.EmailDeadLetterFilter{Recipient: &recipient, Limit: &limit})
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.Equal(t, reset.ID, letters[0].ID)
+	total, err := repo.Total(ctx, repository.EmailDeadLetterFilter{Recipient: &recipient, Limit: &limit})
+	require.NoError(t, err)
+	require.Equal(t, 2, total)
+
+	require.NoError(t, repo.RecordFailure(ctx, welcome.ID, 2, "authentication failed"))
+	got, err = repo.GetByID(ctx, welcome.ID)
+	require.NoError(t, err)
+	require.Equal(t, 5, got.Attempts)
+	require.Equal(t, "authentication failed", got.Error)
+
+	require.NoError(t, repo.MarkResent(ctx, welcome.ID))
+	letters, err = repo.List(ctx, repository.EmailDeadLetterFilter{Recipient: &recipient, Pending: true})
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.Equal(t, reset.ID, letters[0].ID)
+
+	kind := "welcome"
+	letters, err = repo.List(ctx, repository.EmailDeadLetterFilter{Recipient: &recipient, Kind: &kind})
+	require.NoError(t, err)
+	require.Len(t, letters, 1)
+	require.NotNil(t, letters[0].ResentAt)
+
+	require.ErrorIs(t, repo.MarkResent(ctx, uuid.New()), repository.ErrNotFound)
+	require.ErrorIs(t, repo.RecordFailure(ctx, uuid.New(), 1, "error"), repository.ErrNotFound)
+}
@@ -0,0 +1,172 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type emailSuppressionRepository struct {
+	repository.BaseRepository
+}
+
+// NewEmailSuppressionRepository creates a new PostgreSQL email suppression repository
+func NewEmailSuppressionRepository(db *sql.DB) repository.EmailSuppressionRepository {
+	return &emailSuppressionRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *emailSuppressionRepository) Upsert(ctx context.Context, suppression *models.EmailSuppression) error {
+	suppression.Email = strings.ToLower(strings.TrimSpace(suppression.Email))
+
+	// A complaint is never downgraded to a bounce
+	query := `
+		INSERT INTO email_suppressions (email, reason, provider, detail)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (email) DO UPDATE SET
+			reason = CASE WHEN email_suppressions.reason = 'complained' THEN email_suppressions.reason ELSE EXCLUDED.reason END,
+			provider = EXCLUDED.provider,
+			detail = EXCLUDED.detail
+		RETURNING reason, created_at, updated_at`
+
+	return r.Conn(ctx).QueryRowContext(ctx, query,
+		suppression.Email,
+		suppression.Reason,
+		suppression.Provider,
+		suppression.Detail,
+	).Scan(&suppression.Reason, &suppression.CreatedAt, &suppression.UpdatedAt)
+}
+
+func (r *emailSuppressionRepository) Get(ctx context.Context, email string) (*models.EmailSuppression, error) {
+	query := `
+		SELECT email, reason, provider, detail, created_at, updated_at
+		FROM email_suppressions
+		WHERE email = lower($1)`
+
+	s := &models.EmailSuppression{}
+	err := r.Conn(ctx).QueryRowContext(ctx, query, strings.TrimSpace(email)).Scan(
+		&s.Email,
+		&s.Reason,
+		&s.Provider,
+		&s.Detail,
+		&s.CreatedAt,
+		&s.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+func (r *emailSuppressionRepository) IsSuppressed(ctx context.Context, email string) (bool, error) {
+	var exists bool
+	err := r.Conn(ctx).QueryRowContext(ctx,
+		"SELECT EXISTS(SELECT 1 FROM email_suppressions WHERE email = lower($1))",
+		strings.TrimSpace(email),
+	).Scan(&exists)
+	return exists, err
+}
+
+func (r *emailSuppressionRepository) List(ctx context.Context, filter repository.EmailSuppressionFilter) ([]models.EmailSuppression, error) {
+	query, args := emailSuppressionListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	suppressions := []models.EmailSuppression{}
+	for rows.Next() {
+		var s models.EmailSuppression
+		if err := rows.Scan(
+			&s.Email,
+			&s.Reason,
+			&s.Provider,
+			&s.Detail,
+			&s.CreatedAt,
+			&s.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		suppressions = append(suppressions, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return suppressions, nil
+}
+
+func (r *emailSuppressionRepository) Total(ctx context.Context, filter repository.EmailSuppressionFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := emailSuppressionListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+// emailSuppressionListQuery builds the query selecting the suppressions matching the filter
+func emailSuppressionListQuery(filter repository.EmailSuppressionFilter) (string, []interface{}) {
+	query := `
+		SELECT email, reason, provider, detail, created_at, updated_at
+		FROM email_suppressions`
+
+	var conditions []string
+	var args []interface{}
+	argCount := 1
+
+	if filter.Search != nil {
+		conditions = append(conditions, fmt.Sprintf("email ILIKE $%d", argCount))
+		args = append(args, "%"+*filter.Search+"%")
+		argCount++
+	}
+
+	if filter.Reason != nil {
+		conditions = append(conditions, fmt.Sprintf("reason = $%d", argCount))
+		args = append(args, *filter.Reason)
+		argCount++
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY updated_at DESC"
+
+	if filter.Limit != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, *filter.Limit)
+		argCount++
+	}
+
+	if filter.Offset != nil {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, *filter.Offset)
+	}
+
+	return query, args
+}
+
+func (r *emailSuppressionRepository) Delete(ctx context.Context, email string) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM email_suppressions WHERE email = lower($1)`, strings.TrimSpace(email))
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmailSuppressionRepository(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewEmailSuppressionRepository(tc.DB)
+	ctx := context.Background()
+
+	suppressed, err := repo.IsSuppressed(ctx, "gone@example.com")
+	require.NoError(t, err)
+	require.False(t, suppressed)
+
+	require.NoError(t, repo.Upsert(ctx, &models.EmailSuppression{
+		Email:    "Gone@Example.com",
+		Reason:   models.EmailSuppressionComplained,
+		Provider: "ses",
+	}))
+
+	suppressed, err = repo.IsSuppressed(ctx, "GONE@example.com")
+	require.NoError(t, err)
+	require.True(t, suppressed)
+
+	// A later bounce must not downgrade the complaint
+	bounce := &models.EmailSuppression{
+		Email:    "gone@example.com",
+		Reason:   models.EmailSuppressionBounced,
+		Provider: "sendgrid",
+	}
+	require.NoError(t, repo.Upsert(ctx, bounce))
+	require.Equal(t, models.EmailSuppressionComplained, bounce.Reason)
+
+	got, err := repo.Get(ctx, "gone@example.com")
+	require.NoError(t, err)
+	require.Equal(t, "sendgrid", got.Provider)
+
+	require.NoError(t, repo.Upsert(ctx, &models.EmailSuppression{
+		Email:    "full@example.com",
+		Reason:   models.EmailSuppressionBounced,
+		Provider: "ses",
+	}))
+
+	reason := models.EmailSuppressionBounced
+	list, err := repo.List(ctx, repository.EmailSuppressionFilter{Reason: &reason})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+	require.Equal(t, "full@example.com", list[0].Email)
+
+	require.NoError(t, repo.Delete(ctx, "gone@example.com"))
+	require.ErrorIs(t, repo.Delete(ctx, "gone@example.com"), repository.ErrNotFound)
+	_, err = repo.Get(ctx, "gone@example.com")
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
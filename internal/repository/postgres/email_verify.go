@@ -12,11 +12,11 @@ import (
 )
 
 type emailVerificationRepository struct {
-	db *sql.DB
+	repository.BaseRepository
 }
 
 func NewEmailVerificationRepository(db *sql.DB) repository.EmailVerificationRepository {
-	return &emailVerificationRepository{db: db}
+	return &emailVerificationRepository{BaseRepository: repository.NewBaseRepository(db)}
 }
 
 func generateToken() (string, error) {
@@ -27,10 +27,14 @@ func generateToken() (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
-func (r *emailVerificationRepository) Create(ctx context.Context, userID uuid.UUID) (*repository.EmailVerification, error) {
+func (r *emailVerificationRepository) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*repository.EmailVerification, error) {
+	if ttl <= 0 {
+		ttl = repository.TokenExpirationHours * time.Hour
+	}
+
 	// First verify the user exists
 	var exists bool
-	err := r.db.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return nil, err
 	}
@@ -47,7 +51,7 @@ func (r *emailVerificationRepository) Create(ctx context.Context, userID uuid.UU
 		ID:        uuid.New(),
 		UserID:    userID,
 		Token:     token,
-		ExpiresAt: time.Now().Add(repository.TokenExpirationHours * time.Hour),
+		ExpiresAt: time.Now().Add(ttl),
 	}
 
 	query := `
@@ -55,7 +59,7 @@ func (r *emailVerificationRepository) Create(ctx context.Context, userID uuid.UU
 		VALUES ($1, $2, $3, $4)
 		RETURNING created_at`
 
-	err = r.db.QueryRowContext(
+	err = r.Conn(ctx).QueryRowContext(
 		ctx,
 		query,
 		verification.ID,
@@ -72,7 +76,7 @@ func (r *emailVerificationRepository) Create(ctx context.Context, userID uuid.UU
 }
 
 func (r *emailVerificationRepository) Verify(ctx context.Context, token string) error {
-	tx, err := r.db.BeginTx(ctx, nil)
+	tx, err := r.BeginTx(ctx)
 	if err != nil {
 		return err
 	}
@@ -106,3 +110,20 @@ func (r *emailVerificationRepository) Verify(ctx context.Context, token string)
 
 	return tx.Commit()
 }
+
+func (r *emailVerificationRepository) CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	err := r.Conn(ctx).QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM email_verifications WHERE user_id = $1 AND created_at >= $2",
+		userID, since,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *emailVerificationRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.Conn(ctx).ExecContext(ctx, "DELETE FROM email_verifications WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -36,7 +36,7 @@ func TestEmailVerificationRepository_Create(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			verify, err := tc.EmailVerifyRepo.Create(context.Background(), tt.userID)
+			verify, err := tc.EmailVerifyRepo.Create(context.Background(), tt.userID, repository.TokenExpirationHours*time.Hour)
 			if tt.wantErr {
 				require.Error(t, err)
 				if tt.errType != nil {
@@ -60,11 +60,11 @@ func TestEmailVerificationRepository_Verify(t *testing.T) {
 	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
 
 	// Create a valid verification token
-	verify, err := tc.EmailVerifyRepo.Create(context.Background(), user.ID)
+	verify, err := tc.EmailVerifyRepo.Create(context.Background(), user.ID, repository.TokenExpirationHours*time.Hour)
 	require.NoError(t, err)
 
 	// Create an expired token
-	expiredVerify, err := tc.EmailVerifyRepo.Create(context.Background(), user.ID)
+	expiredVerify, err := tc.EmailVerifyRepo.Create(context.Background(), user.ID, repository.TokenExpirationHours*time.Hour)
 	require.NoError(t, err)
 	_, err = tc.DB.ExecContext(context.Background(),
 		"UPDATE email_verifications SET expires_at = $1 WHERE id = $2",
@@ -72,7 +72,7 @@ func TestEmailVerificationRepository_Verify(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create a used token
-	usedVerify, err := tc.EmailVerifyRepo.Create(context.Background(), user.ID)
+	usedVerify, err := tc.EmailVerifyRepo.Create(context.Background(), user.ID, repository.TokenExpirationHours*time.Hour)
 	require.NoError(t, err)
 	err = tc.EmailVerifyRepo.Verify(context.Background(), usedVerify.Token)
 	require.NoError(t, err)
@@ -139,3 +139,24 @@ func TestEmailVerificationRepository_Verify(t *testing.T) {
 		})
 	}
 }
+
+func TestEmailVerificationRepository_TTLAndCountSince(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+
+	start := time.Now().Add(-time.Second)
+	verify, err := tc.EmailVerifyRepo.Create(context.Background(), user.ID, 30*time.Minute)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(30*time.Minute), verify.ExpiresAt, 2*time.Second)
+
+	_, err = tc.EmailVerifyRepo.Create(context.Background(), user.ID, 0)
+	require.NoError(t, err)
+
+	count, err := tc.EmailVerifyRepo.CountSince(context.Background(), user.ID, start)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	count, err = tc.EmailVerifyRepo.CountSince(context.Background(), user.ID, time.Now().Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+}
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type entsoeAreaRepository struct {
+	repository.BaseRepository
+}
+
+// NewEntsoeAreaRepository creates a new PostgreSQL ENTSO-E area repository
+func NewEntsoeAreaRepository(db *sql.DB) repository.EntsoeAreaRepository {
+	return &entsoeAreaRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *entsoeAreaRepository) List(ctx context.Context) ([]models.EntsoeArea, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx, `
+		SELECT a.zone_id, z.name, a.area_code, a.created_at, a.updated_at
+		FROM entsoe_areas a
+		JOIN zones z ON z.id = a.zone_id
+		ORDER BY z.name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	areas := make([]models.EntsoeArea, 0)
+	for rows.Next() {
+		var area models.EntsoeArea
+		if err := rows.Scan(&area.ZoneID, &area.ZoneName, &area.AreaCode, &area.CreatedAt, &area.UpdatedAt); err != nil {
+			return nil, err
+		}
+		areas = append(areas, area)
+	}
+	return areas, rows.Err()
+}
+
+func (r *entsoeAreaRepository) Upsert(ctx context.Context, area *models.EntsoeArea) error {
+	query := `
+		WITH upserted AS (
+			INSERT INTO entsoe_areas (zone_id, area_code)
+			VALUES ($1, $2)
+			ON CONFLICT (zone_id) DO UPDATE SET area_code = EXCLUDED.area_code
+			RETURNING zone_id, created_at, updated_at
+		)
+		SELECT z.name, u.created_at, u.updated_at
+		FROM upserted u
+		JOIN zones z ON z.id = u.zone_id`
+
+	err := r.Conn(ctx).QueryRowContext(ctx, query, area.ZoneID, area.AreaCode).
+		Scan(&area.ZoneName, &area.CreatedAt, &area.UpdatedAt)
+	if errorCode(err) == foreignKeyViolation {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *entsoeAreaRepository) Delete(ctx context.Context, zoneID uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM entsoe_areas WHERE zone_id = $1`, zoneID)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntsoeAreaRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewEntsoeAreaRepository(tc.DB)
+	zones := postgres.NewZoneRepository(tc.DB)
+
+	zone := tc.CreateTestZone("test-zone", "UTC")
+
+	area := &models.EntsoeArea{ZoneID: zone.ID, AreaCode: "10Y1001A1001A46L"}
+	require.NoError(t, repo.Upsert(ctx, area))
+	require.Equal(t, "test-zone", area.ZoneName)
+	require.False(t, area.CreatedAt.IsZero())
+
+	// Upsert replaces the area of the zone
+	require.NoError(t, repo.Upsert(ctx, &models.EntsoeArea{ZoneID: zone.ID, AreaCode: "10Y1001A1001A47J"}))
+	areas, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, areas, 1)
+	require.Equal(t, "10Y1001A1001A47J", areas[0].AreaCode)
+
+	require.ErrorIs(t, repo.Upsert(ctx, &models.EntsoeArea{ZoneID: uuid.New(), AreaCode: "10Y1001A1001A46L"}), repository.ErrNotFound)
+
+	require.NoError(t, repo.Delete(ctx, zone.ID))
+	require.ErrorIs(t, repo.Delete(ctx, zone.ID), repository.ErrNotFound)
+
+	// Mappings don't keep zones from being deleted
+	require.NoError(t, repo.Upsert(ctx, area))
+	require.NoError(t, zones.Delete(ctx, zone.ID))
+	areas, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, areas)
+}
@@ -0,0 +1,189 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type exchangeRateRepository struct {
+	repository.BaseRepository
+}
+
+// NewExchangeRateRepository creates a new PostgreSQL exchange rate repository
+func NewExchangeRateRepository(db *sql.DB) repository.ExchangeRateRepository {
+	return NewExchangeRateRepositoryWithReplicas(db, nil)
+}
+
+// NewExchangeRateRepositoryWithReplicas creates a new PostgreSQL exchange rate repository
+// reading listings from replicas, nil reads them from db
+func NewExchangeRateRepositoryWithReplicas(db *sql.DB, replicas *repository.ReplicaSet) repository.ExchangeRateRepository {
+	return &exchangeRateRepository{
+		BaseRepository: repository.NewBaseRepositoryWithReplicas(db, replicas),
+	}
+}
+
+func (r *exchangeRateRepository) CreateBatch(ctx context.Context, rates []models.ExchangeRate) error {
+	if len(rates) == 0 {
+		return nil
+	}
+
+	valueStrings := make([]string, 0, len(rates))
+	valueArgs := make([]interface{}, 0, len(rates)*7)
+	now := time.Now()
+
+	for i, rate := range rates {
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			i*7+1, i*7+2, i*7+3, i*7+4, i*7+5, i*7+6, i*7+7))
+		valueArgs = append(valueArgs,
+			rate.BaseCurrencyID,
+			rate.QuoteCurrencyID,
+			rate.Timestamp,
+			rate.Rate,
+			rate.Source,
+			now,
+			now,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO exchange_rates (base_currency_id, quote_currency_id, timestamp, rate, source, created_at, updated_at)
+		VALUES %s
+		ON CONFLICT (base_currency_id, quote_currency_id, timestamp) DO UPDATE
+		SET rate = EXCLUDED.rate,
+			source = EXCLUDED.source,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at`, strings.Join(valueStrings, ","))
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, valueArgs...)
+	if err != nil {
+		if errorCode(err) == foreignKeyViolation {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+	defer rows.Close()
+
+	// Rows are returned in the order of the values
+	i := 0
+	for rows.Next() {
+		if err := rows.Scan(&rates[i].CreatedAt, &rates[i].UpdatedAt); err != nil {
+			return err
+		}
+		i++
+	}
+
+	return rows.Err()
+}
+
+func (r *exchangeRateRepository) List(ctx context.Context, filter repository.ExchangeRateFilter) ([]models.ExchangeRate, error) {
+	query, args := exchangeRateListQuery(filter)
+	return r.query(ctx, query, args...)
+}
+
+func (r *exchangeRateRepository) Total(ctx context.Context, filter repository.ExchangeRateFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := exchangeRateListQuery(filter)
+	return countRows(ctx, r.Reader(ctx), query, args)
+}
+
+func (r *exchangeRateRepository) Effective(ctx context.Context, base, quote uuid.UUID, start, end time.Time) ([]models.ExchangeRate, error) {
+	query := `
+		SELECT base_currency_id, quote_currency_id, timestamp, rate, source, created_at, updated_at
+		FROM exchange_rates
+		WHERE base_currency_id = $1 AND quote_currency_id = $2
+		AND timestamp >= COALESCE((
+			SELECT MAX(timestamp) FROM exchange_rates
+			WHERE base_currency_id = $1 AND quote_currency_id = $2 AND timestamp <= $3
+		), $3)
+		AND timestamp <= $4
+		ORDER BY timestamp ASC`
+	return r.query(ctx, query, base, quote, start, end)
+}
+
+// query scans the exchange rates selected by query
+func (r *exchangeRateRepository) query(ctx context.Context, query string, args ...interface{}) ([]models.ExchangeRate, error) {
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rates := make([]models.ExchangeRate, 0)
+	for rows.Next() {
+		var rate models.ExchangeRate
+		if err := rows.Scan(
+			&rate.BaseCurrencyID,
+			&rate.QuoteCurrencyID,
+			&rate.Timestamp,
+			&rate.Rate,
+			&rate.Source,
+			&rate.CreatedAt,
+			&rate.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		rates = append(rates, rate)
+	}
+	return rates, rows.Err()
+}
+
+// exchangeRateListQuery builds the query selecting the exchange rates matching the filter
+func exchangeRateListQuery(filter repository.ExchangeRateFilter) (string, []interface{}) {
+	query := `
+		SELECT base_currency_id, quote_currency_id, timestamp, rate, source, created_at, updated_at
+		FROM exchange_rates`
+
+	var conditions []string
+	var args []interface{}
+	argCount := 1
+
+	if filter.BaseCurrencyID != nil {
+		conditions = append(conditions, fmt.Sprintf("base_currency_id = $%d", argCount))
+		args = append(args, *filter.BaseCurrencyID)
+		argCount++
+	}
+
+	if filter.QuoteCurrencyID != nil {
+		conditions = append(conditions, fmt.Sprintf("quote_currency_id = $%d", argCount))
+		args = append(args, *filter.QuoteCurrencyID)
+		argCount++
+	}
+
+	if filter.StartTime != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", argCount))
+		args = append(args, *filter.StartTime)
+		argCount++
+	}
+
+	if filter.EndTime != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp <= $%d", argCount))
+		args = append(args, *filter.EndTime)
+		argCount++
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY timestamp DESC, base_currency_id ASC, quote_currency_id ASC"
+
+	if filter.Limit != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, *filter.Limit)
+		argCount++
+	}
+
+	if filter.Offset != nil {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, *filter.Offset)
+	}
+
+	return query, args
+}
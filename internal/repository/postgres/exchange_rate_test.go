@@ -0,0 +1,75 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExchangeRateRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewExchangeRateRepository(tc.DB)
+
+	eur, err := tc.CurrencyRepo.GetByName(ctx, "EUR")
+	require.NoError(t, err)
+	sek, err := tc.CurrencyRepo.GetByName(ctx, "SEK")
+	require.NoError(t, err)
+
+	day := time.Date(2025, 3, 20, 0, 0, 0, 0, time.UTC)
+	rates := []models.ExchangeRate{
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day.AddDate(0, 0, -1), Rate: 10, Source: models.ExchangeRateSourceECB},
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day, Rate: 11, Source: models.ExchangeRateSourceECB},
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day.AddDate(0, 0, 1), Rate: 12, Source: models.ExchangeRateSourceECB},
+	}
+	require.NoError(t, repo.CreateBatch(ctx, rates))
+	for _, rate := range rates {
+		assert.False(t, rate.CreatedAt.IsZero())
+	}
+
+	// Rates of the same pair and time are replaced
+	require.NoError(t, repo.CreateBatch(ctx, []models.ExchangeRate{
+		{BaseCurrencyID: eur.ID, QuoteCurrencyID: sek.ID, Timestamp: day, Rate: 11.5, Source: models.ExchangeRateSourceManual},
+	}))
+
+	t.Run("List", func(t *testing.T) {
+		list, err := repo.List(ctx, repository.ExchangeRateFilter{BaseCurrencyID: &eur.ID})
+		require.NoError(t, err)
+		require.Len(t, list, 3)
+		assert.Equal(t, 12.0, list[0].Rate)
+		assert.Equal(t, 11.5, list[1].Rate)
+		assert.Equal(t, models.ExchangeRateSourceManual, list[1].Source)
+
+		limit := 1
+		total, err := repo.Total(ctx, repository.ExchangeRateFilter{QuoteCurrencyID: &sek.ID, Limit: &limit})
+		require.NoError(t, err)
+		assert.Equal(t, 3, total)
+	})
+
+	t.Run("Effective", func(t *testing.T) {
+		effective, err := repo.Effective(ctx, eur.ID, sek.ID, day.Add(6*time.Hour), day.Add(30*time.Hour))
+		require.NoError(t, err)
+		require.Len(t, effective, 2)
+		assert.True(t, day.Equal(effective[0].Timestamp))
+		assert.True(t, day.AddDate(0, 0, 1).Equal(effective[1].Timestamp))
+
+		effective, err = repo.Effective(ctx, sek.ID, eur.ID, day, day.Add(time.Hour))
+		require.NoError(t, err)
+		assert.Empty(t, effective)
+	})
+
+	t.Run("Unknown Currency", func(t *testing.T) {
+		err := repo.CreateBatch(ctx, []models.ExchangeRate{
+			{BaseCurrencyID: eur.ID, QuoteCurrencyID: uuid.New(), Timestamp: day, Rate: 1, Source: models.ExchangeRateSourceManual},
+		})
+		assert.ErrorIs(t, err, repository.ErrNotFound)
+	})
+}
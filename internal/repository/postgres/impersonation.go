@@ -0,0 +1,93 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type impersonationRepository struct {
+	repository.BaseRepository
+}
+
+// NewImpersonationRepository creates a new PostgreSQL impersonation repository
+func NewImpersonationRepository(db *sql.DB) repository.ImpersonationRepository {
+	return &impersonationRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const impersonationColumns = `id, admin_id, user_id, started_at, expires_at, ended_at`
+
+func (r *impersonationRepository) Create(ctx context.Context, adminID, userID uuid.UUID, expiresAt time.Time) (*models.Impersonation, error) {
+	query := `
+		INSERT INTO impersonations (id, admin_id, user_id, started_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+
+	impersonation := &models.Impersonation{
+		ID:        uuid.New(),
+		AdminID:   adminID,
+		UserID:    userID,
+		StartedAt: time.Now(),
+		ExpiresAt: expiresAt,
+	}
+	_, err := r.Conn(ctx).ExecContext(ctx, query,
+		impersonation.ID,
+		impersonation.AdminID,
+		impersonation.UserID,
+		impersonation.StartedAt,
+		impersonation.ExpiresAt,
+	)
+	if errorCode(err) == foreignKeyViolation {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return impersonation, nil
+}
+
+func (r *impersonationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Impersonation, error) {
+	query := `SELECT ` + impersonationColumns + ` FROM impersonations WHERE id = $1`
+
+	impersonation := &models.Impersonation{}
+	err := r.Conn(ctx).QueryRowContext(ctx, query, id).Scan(
+		&impersonation.ID,
+		&impersonation.AdminID,
+		&impersonation.UserID,
+		&impersonation.StartedAt,
+		&impersonation.ExpiresAt,
+		&impersonation.EndedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return impersonation, nil
+}
+
+func (r *impersonationRepository) End(ctx context.Context, id uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE impersonations SET ended_at = $2
+		WHERE id = $1 AND ended_at IS NULL AND expires_at > $2`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, id, at)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImpersonationRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewImpersonationRepository(tc.DB)
+	admin := tc.CreateTestUser("admin", "admin@example.com", "password123", true)
+	user := tc.CreateTestUser("user", "user@example.com", "password123", false)
+
+	expiresAt := time.Now().Add(time.Hour)
+	_, err := repo.Create(ctx, admin.ID, uuid.New(), expiresAt)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	_, err = repo.GetByID(ctx, uuid.New())
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	impersonation, err := repo.Create(ctx, admin.ID, user.ID, expiresAt)
+	require.NoError(t, err)
+	got, err := repo.GetByID(ctx, impersonation.ID)
+	require.NoError(t, err)
+	assert.Equal(t, admin.ID, got.AdminID)
+	assert.Equal(t, user.ID, got.UserID)
+	assert.True(t, got.Active(time.Now()))
+	assert.False(t, got.Active(expiresAt.Add(time.Second)))
+
+	// Ended impersonations can't be ended again
+	now := time.Now()
+	require.NoError(t, repo.End(ctx, impersonation.ID, now))
+	require.ErrorIs(t, repo.End(ctx, impersonation.ID, now), repository.ErrNotFound)
+	got, err = repo.GetByID(ctx, impersonation.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.EndedAt)
+	assert.False(t, got.Active(now))
+}
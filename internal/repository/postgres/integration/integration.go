@@ -69,7 +69,7 @@ func (tc *TestContext) CreateTestLoginAttempt(userID uuid.UUID, success bool, ip
 // CreateTestPasswordReset creates a test password reset token
 func (tc *TestContext) CreateTestPasswordReset(userID uuid.UUID) *repository.PasswordReset {
 	tc.T.Helper()
-	reset, err := tc.PasswordResetRepo.Create(context.Background(), userID)
+	reset, err := tc.PasswordResetRepo.Create(context.Background(), userID, repository.ResetTokenExpiration)
 	require.NoError(tc.T, err)
 	return reset
 }
@@ -77,7 +77,7 @@ func (tc *TestContext) CreateTestPasswordReset(userID uuid.UUID) *repository.Pas
 // CreateTestEmailVerification creates a test email verification token
 func (tc *TestContext) CreateTestEmailVerification(userID uuid.UUID) *repository.EmailVerification {
 	tc.T.Helper()
-	verify, err := tc.EmailVerifyRepo.Create(context.Background(), userID)
+	verify, err := tc.EmailVerifyRepo.Create(context.Background(), userID, repository.TokenExpirationHours*time.Hour)
 	require.NoError(tc.T, err)
 	return verify
 }
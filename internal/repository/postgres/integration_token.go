@@ -0,0 +1,134 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type integrationTokenRepository struct {
+	repository.BaseRepository
+}
+
+// NewIntegrationTokenRepository creates a new PostgreSQL integration token repository
+func NewIntegrationTokenRepository(db *sql.DB) repository.IntegrationTokenRepository {
+	return &integrationTokenRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const integrationTokenColumns = `id, user_id, name, token_hash, last_used_at, created_at`
+
+func (r *integrationTokenRepository) Create(ctx context.Context, token *models.IntegrationToken) error {
+	// First verify the user exists
+	var exists bool
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", token.UserID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrNotFound
+	}
+
+	query := `
+		INSERT INTO integration_tokens (id, user_id, name, token_hash)
+		VALUES ($1, $2, $3, $4)
+		RETURNING ` + integrationTokenColumns
+
+	err = r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		uuid.New(),
+		token.UserID,
+		token.Name,
+		token.TokenHash,
+	), token)
+	if err != nil && strings.Contains(err.Error(), "integration_tokens_token_hash_key") {
+		return repository.ErrConflict
+	}
+	return err
+}
+
+func (r *integrationTokenRepository) get(ctx context.Context, where string, arg interface{}) (*models.IntegrationToken, error) {
+	query := `SELECT ` + integrationTokenColumns + ` FROM integration_tokens WHERE ` + where
+
+	token := &models.IntegrationToken{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, arg), token)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return token, nil
+}
+
+func (r *integrationTokenRepository) GetByHash(ctx context.Context, hash string) (*models.IntegrationToken, error) {
+	return r.get(ctx, "token_hash = $1", hash)
+}
+
+func (r *integrationTokenRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.IntegrationToken, error) {
+	return r.get(ctx, "id = $1", id)
+}
+
+func (r *integrationTokenRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.IntegrationToken, error) {
+	query := `SELECT ` + integrationTokenColumns + ` FROM integration_tokens WHERE user_id = $1 ORDER BY created_at DESC`
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []models.IntegrationToken{}
+	for rows.Next() {
+		var token models.IntegrationToken
+		if err := r.scan(rows, &token); err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return tokens, nil
+}
+
+func (r *integrationTokenRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM integration_tokens WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *integrationTokenRepository) MarkUsed(ctx context.Context, id uuid.UUID, usedAt time.Time) error {
+	_, err := r.Conn(ctx).ExecContext(ctx, `UPDATE integration_tokens SET last_used_at = $2 WHERE id = $1`, id, usedAt)
+	return err
+}
+
+func (r *integrationTokenRepository) scan(row interface{ Scan(...interface{}) error }, token *models.IntegrationToken) error {
+	return row.Scan(
+		&token.ID,
+		&token.UserID,
+		&token.Name,
+		&token.TokenHash,
+		&token.LastUsedAt,
+		&token.CreatedAt,
+	)
+}
@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type jobRepository struct {
+	repository.BaseRepository
+}
+
+// NewJobRepository creates a new PostgreSQL job repository
+func NewJobRepository(db *sql.DB) repository.JobRepository {
+	return &jobRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *jobRepository) SaveJob(ctx context.Context, job *models.Job) error {
+	_, err := r.Conn(ctx).ExecContext(ctx, `
+		INSERT INTO jobs (name, schedule)
+		VALUES ($1, $2)
+		ON CONFLICT (name) DO UPDATE SET schedule = EXCLUDED.schedule
+		WHERE jobs.schedule <> EXCLUDED.schedule`,
+		job.Name, job.Schedule)
+	return err
+}
+
+func (r *jobRepository) CreateRun(ctx context.Context, run *models.JobRun) error {
+	_, err := r.Conn(ctx).ExecContext(ctx, `
+		INSERT INTO job_runs (id, job_name, trigger, triggered_by, status, error, started_at, finished_at)
+		VALUES ($1, $2, $3, $4, $5, NULLIF($6, ''), $7, $8)`,
+		run.ID, run.JobName, run.Trigger, run.TriggeredBy, run.Status, run.Error, run.StartedAt, run.FinishedAt)
+	return err
+}
+
+func (r *jobRepository) FinishRun(ctx context.Context, run *models.JobRun) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `
+		UPDATE job_runs
+		SET status = $2, error = NULLIF($3, ''), finished_at = $4
+		WHERE id = $1`,
+		run.ID, run.Status, run.Error, run.FinishedAt)
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *jobRepository) ListRuns(ctx context.Context, filter repository.JobRunFilter) ([]models.JobRun, error) {
+	query, args := jobRunListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	runs := make([]models.JobRun, 0)
+	for rows.Next() {
+		var run models.JobRun
+		if err := rows.Scan(&run.ID, &run.JobName, &run.Trigger, &run.TriggeredBy, &run.Status, &run.Error, &run.StartedAt, &run.FinishedAt); err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+	return runs, rows.Err()
+}
+
+func (r *jobRepository) TotalRuns(ctx context.Context, filter repository.JobRunFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := jobRunListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+// jobRunListQuery builds the query selecting the job runs matching the filter
+func jobRunListQuery(filter repository.JobRunFilter) (string, []interface{}) {
+	query := `
+		SELECT id, job_name, trigger, triggered_by, status, COALESCE(error, ''), started_at, finished_at
+		FROM job_runs`
+	var params []interface{}
+	if filter.JobName != "" {
+		params = append(params, filter.JobName)
+		query += fmt.Sprintf(" WHERE job_name = $%d", len(params))
+	}
+	query += " ORDER BY started_at DESC, id"
+	if filter.Limit != nil {
+		params = append(params, *filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(params))
+	}
+	if filter.Offset != nil {
+		params = append(params, *filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(params))
+	}
+
+	return query, params
+}
@@ -0,0 +1,62 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJobRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewJobRepository(tc.DB)
+
+	require.NoError(t, repo.SaveJob(ctx, &models.Job{Name: "cleanup", Schedule: "0 * * * *"}))
+	require.NoError(t, repo.SaveJob(ctx, &models.Job{Name: "cleanup", Schedule: "30 * * * *"}))
+	require.NoError(t, repo.SaveJob(ctx, &models.Job{Name: "retention", Schedule: "0 3 * * *"}))
+
+	start := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+	first := &models.JobRun{ID: uuid.New(), JobName: "cleanup", Trigger: models.JobTriggerSchedule, Status: models.JobStatusRunning, StartedAt: start}
+	second := &models.JobRun{ID: uuid.New(), JobName: "cleanup", Trigger: models.JobTriggerManual, Status: models.JobStatusRunning, StartedAt: start.Add(time.Second)}
+	other := &models.JobRun{ID: uuid.New(), JobName: "retention", Trigger: models.JobTriggerSchedule, Status: models.JobStatusRunning, StartedAt: start}
+	for _, run := range []*models.JobRun{first, second, other} {
+		require.NoError(t, repo.CreateRun(ctx, run))
+	}
+
+	// Runs need a saved job
+	require.Error(t, repo.CreateRun(ctx, &models.JobRun{ID: uuid.New(), JobName: "unknown", Trigger: models.JobTriggerManual, Status: models.JobStatusRunning, StartedAt: start}))
+
+	finished := time.Now().Truncate(time.Microsecond)
+	first.Status, first.Error, first.FinishedAt = models.JobStatusFailed, "boom", &finished
+	require.NoError(t, repo.FinishRun(ctx, first))
+	require.ErrorIs(t, repo.FinishRun(ctx, &models.JobRun{ID: uuid.New(), Status: models.JobStatusSucceeded, FinishedAt: &finished}), repository.ErrNotFound)
+
+	runs, err := repo.ListRuns(ctx, repository.JobRunFilter{JobName: "cleanup"})
+	require.NoError(t, err)
+	require.Len(t, runs, 2)
+	assert.Equal(t, second.ID, runs[0].ID)
+	assert.Equal(t, models.JobStatusRunning, runs[0].Status)
+	assert.Nil(t, runs[0].FinishedAt)
+	assert.Equal(t, first.ID, runs[1].ID)
+	assert.Equal(t, models.JobStatusFailed, runs[1].Status)
+	assert.Equal(t, "boom", runs[1].Error)
+	require.NotNil(t, runs[1].FinishedAt)
+	assert.True(t, finished.Equal(*runs[1].FinishedAt))
+
+	limit, offset := 1, 1
+	runs, err = repo.ListRuns(ctx, repository.JobRunFilter{Limit: &limit, Offset: &offset})
+	require.NoError(t, err)
+	require.Len(t, runs, 1)
+
+	runs, err = repo.ListRuns(ctx, repository.JobRunFilter{})
+	require.NoError(t, err)
+	assert.Len(t, runs, 3)
+}
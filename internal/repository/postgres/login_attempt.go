@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"time"
+	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
 
 	"github.com/google/uuid"
@@ -22,7 +23,7 @@ func NewLoginAttemptRepository(db *sql.DB) repository.LoginAttemptRepository {
 func (r *loginAttemptRepository) Create(ctx context.Context, userID uuid.UUID, successful bool, ipAddress string, createdAt time.Time) error {
 	// First verify the user exists
 	var exists bool
-	err := r.DB().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return err
 	}
@@ -31,17 +32,51 @@ func (r *loginAttemptRepository) Create(ctx context.Context, userID uuid.UUID, s
 	}
 
 	query := `
-		INSERT INTO login_attempts (id, user_id, success, ip, created_at)
-		VALUES ($1, $2, $3, $4, $5)`
+		INSERT INTO login_attempts (id, user_id, username, success, ip, created_at)
+		VALUES ($1, $2, (SELECT username FROM users WHERE id = $2), $3, $4, $5)`
 
-	_, err = r.DB().ExecContext(ctx, query, uuid.New(), userID, successful, ipAddress, createdAt)
+	_, err = r.Conn(ctx).ExecContext(ctx, query, uuid.New(), userID, successful, ipAddress, createdAt)
 	return err
 }
 
+func (r *loginAttemptRepository) CreateFailure(ctx context.Context, username, ipAddress string, createdAt time.Time) error {
+	query := `
+		INSERT INTO login_attempts (id, user_id, username, success, ip, created_at)
+		VALUES ($1, (SELECT id FROM users WHERE username = $2 AND deleted_at IS NULL), $2, false, $3, $4)`
+
+	_, err := r.Conn(ctx).ExecContext(ctx, query, uuid.New(), username, ipAddress, createdAt)
+	return err
+}
+
+func (r *loginAttemptRepository) CreateSuccess(ctx context.Context, userID uuid.UUID, client models.SessionClient, fingerprint string, createdAt time.Time) error {
+	query := `
+		INSERT INTO login_attempts (id, user_id, username, success, ip, user_agent, fingerprint, created_at)
+		VALUES ($1, $2, (SELECT username FROM users WHERE id = $2), true, $3, $4, $5, $6)`
+
+	_, err := r.Conn(ctx).ExecContext(ctx, query, uuid.New(), userID, client.IPAddress, client.UserAgent, fingerprint, createdAt)
+	if errorCode(err) == foreignKeyViolation {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *loginAttemptRepository) LoginHistory(ctx context.Context, userID uuid.UUID, fingerprint, ipAddress string) (models.LoginHistory, error) {
+	query := `
+		SELECT
+			NOT EXISTS(SELECT 1 FROM login_attempts WHERE user_id = $1 AND success),
+			EXISTS(SELECT 1 FROM login_attempts WHERE user_id = $1 AND success AND fingerprint = $2),
+			EXISTS(SELECT 1 FROM login_attempts WHERE user_id = $1 AND success AND ip = $3)`
+
+	var history models.LoginHistory
+	err := r.Conn(ctx).QueryRowContext(ctx, query, userID, fingerprint, ipAddress).Scan(
+		&history.FirstLogin, &history.KnownDevice, &history.KnownIP)
+	return history, err
+}
+
 func (r *loginAttemptRepository) GetRecentAttempts(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
 	// First verify the user exists
 	var exists bool
-	err := r.DB().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return 0, err
 	}
@@ -57,14 +92,63 @@ func (r *loginAttemptRepository) GetRecentAttempts(ctx context.Context, userID u
 		AND success = false
 		AND created_at >= $2`
 
-	err = r.DB().QueryRowContext(ctx, query, userID, since).Scan(&count)
+	err = r.Conn(ctx).QueryRowContext(ctx, query, userID, since).Scan(&count)
+	return count, err
+}
+
+func (r *loginAttemptRepository) GetRecentFailures(ctx context.Context, username string, since time.Time) (int, error) {
+	var count int
+	query := `
+		SELECT COUNT(*)
+		FROM login_attempts
+		WHERE username = $1
+		AND success = false
+		AND created_at >= $2`
+
+	err := r.Conn(ctx).QueryRowContext(ctx, query, username, since).Scan(&count)
 	return count, err
 }
 
+func (r *loginAttemptRepository) ListRecentAttempts(ctx context.Context, userID uuid.UUID, since time.Time) ([]models.LoginAttempt, error) {
+	// First verify the user exists
+	var exists bool
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, repository.ErrNotFound
+	}
+
+	query := `
+		SELECT id, user_id, success, ip, created_at
+		FROM login_attempts
+		WHERE user_id = $1
+		AND success = false
+		AND created_at >= $2
+		ORDER BY created_at DESC`
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID, since)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	attempts := []models.LoginAttempt{}
+	for rows.Next() {
+		var attempt models.LoginAttempt
+		if err := rows.Scan(&attempt.ID, &attempt.UserID, &attempt.Success, &attempt.IP, &attempt.CreatedAt); err != nil {
+			return nil, err
+		}
+		attempts = append(attempts, attempt)
+	}
+	return attempts, rows.Err()
+}
+
 func (r *loginAttemptRepository) ClearAttempts(ctx context.Context, userID uuid.UUID) error {
 	// First verify the user exists
 	var exists bool
-	err := r.DB().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return err
 	}
@@ -72,8 +156,11 @@ func (r *loginAttemptRepository) ClearAttempts(ctx context.Context, userID uuid.
 		return repository.ErrNotFound
 	}
 
-	query := `DELETE FROM login_attempts WHERE user_id = $1`
-	result, err := r.DB().ExecContext(ctx, query, userID)
+	query := `
+		DELETE FROM login_attempts
+		WHERE (user_id = $1 OR username = (SELECT username FROM users WHERE id = $1))
+		AND success = false`
+	result, err := r.Conn(ctx).ExecContext(ctx, query, userID)
 	if err != nil {
 		return err
 	}
@@ -88,3 +175,11 @@ func (r *loginAttemptRepository) ClearAttempts(ctx context.Context, userID uuid.
 
 	return nil
 }
+
+func (r *loginAttemptRepository) DeleteOlderThan(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.Conn(ctx).ExecContext(ctx, "DELETE FROM login_attempts WHERE created_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 	"time"
+	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
 	"wattwatch/internal/repository/postgres/integration"
 
@@ -126,6 +127,65 @@ func TestLoginAttemptRepository_GetRecentAttempts(t *testing.T) {
 	}
 }
 
+func TestLoginAttemptRepository_GetRecentFailures(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+	ctx := context.Background()
+	now := time.Now().UTC()
+
+	// Failures are counted by username, whether or not a user has it
+	require.NoError(t, tc.LoginAttemptRepo.CreateFailure(ctx, "test-user", "127.0.0.1", now))
+	require.NoError(t, tc.LoginAttemptRepo.Create(ctx, user.ID, false, "127.0.0.1", now))
+	require.NoError(t, tc.LoginAttemptRepo.CreateFailure(ctx, "unknown-user", "127.0.0.1", now))
+	require.NoError(t, tc.LoginAttemptRepo.CreateFailure(ctx, "unknown-user", "127.0.0.1", now.Add(-2*time.Hour)))
+
+	count, err := tc.LoginAttemptRepo.GetRecentFailures(ctx, "test-user", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	count, err = tc.LoginAttemptRepo.GetRecentAttempts(ctx, user.ID, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+	count, err = tc.LoginAttemptRepo.GetRecentFailures(ctx, "unknown-user", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+
+	// Clearing the attempts of the user leaves the other usernames locked
+	require.NoError(t, tc.LoginAttemptRepo.ClearAttempts(ctx, user.ID))
+	count, err = tc.LoginAttemptRepo.GetRecentFailures(ctx, "test-user", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 0, count)
+	count, err = tc.LoginAttemptRepo.GetRecentFailures(ctx, "unknown-user", now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Equal(t, 1, count)
+}
+
+func TestLoginAttemptRepository_ListRecentAttempts(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+
+	now := time.Now().UTC()
+	require.NoError(t, tc.LoginAttemptRepo.Create(context.Background(), user.ID, false, "10.0.0.1", now.Add(-2*time.Hour)))
+	require.NoError(t, tc.LoginAttemptRepo.Create(context.Background(), user.ID, false, "10.0.0.2", now.Add(-10*time.Minute)))
+	require.NoError(t, tc.LoginAttemptRepo.Create(context.Background(), user.ID, true, "10.0.0.3", now.Add(-5*time.Minute)))
+	require.NoError(t, tc.LoginAttemptRepo.Create(context.Background(), user.ID, false, "10.0.0.4", now))
+
+	// Successful attempts and those before since are left out, the newest comes first
+	attempts, err := tc.LoginAttemptRepo.ListRecentAttempts(context.Background(), user.ID, now.Add(-time.Hour))
+	require.NoError(t, err)
+	require.Len(t, attempts, 2)
+	require.Equal(t, "10.0.0.4", attempts[0].IP)
+	require.Equal(t, "10.0.0.2", attempts[1].IP)
+	require.Equal(t, user.ID, attempts[0].UserID)
+	require.False(t, attempts[0].Success)
+
+	attempts, err = tc.LoginAttemptRepo.ListRecentAttempts(context.Background(), user.ID, now.Add(time.Hour))
+	require.NoError(t, err)
+	require.Empty(t, attempts)
+
+	_, err = tc.LoginAttemptRepo.ListRecentAttempts(context.Background(), uuid.New(), now)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
+
 func TestLoginAttemptRepository_ClearAttempts(t *testing.T) {
 	tc := integration.NewTestContext(t)
 	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
@@ -169,3 +229,36 @@ func TestLoginAttemptRepository_ClearAttempts(t *testing.T) {
 		})
 	}
 }
+
+func TestLoginAttemptRepository_LoginHistory(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	ctx := context.Background()
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+	fingerprint := models.DeviceFingerprint("Firefox")
+
+	history, err := tc.LoginAttemptRepo.LoginHistory(ctx, user.ID, fingerprint, "10.0.0.1")
+	require.NoError(t, err)
+	require.True(t, history.FirstLogin)
+
+	// Failed attempts are no history
+	require.NoError(t, tc.LoginAttemptRepo.Create(ctx, user.ID, false, "10.0.0.1", time.Now()))
+	require.NoError(t, tc.LoginAttemptRepo.CreateSuccess(ctx, user.ID,
+		models.SessionClient{UserAgent: "Firefox", IPAddress: "10.0.0.2"}, fingerprint, time.Now()))
+
+	history, err = tc.LoginAttemptRepo.LoginHistory(ctx, user.ID, fingerprint, "10.0.0.1")
+	require.NoError(t, err)
+	require.Equal(t, models.LoginHistory{KnownDevice: true}, history)
+
+	history, err = tc.LoginAttemptRepo.LoginHistory(ctx, user.ID, models.DeviceFingerprint("Safari"), "10.0.0.2")
+	require.NoError(t, err)
+	require.Equal(t, models.LoginHistory{KnownIP: true}, history)
+
+	// Clearing the failed attempts keeps the history
+	require.NoError(t, tc.LoginAttemptRepo.ClearAttempts(ctx, user.ID))
+	history, err = tc.LoginAttemptRepo.LoginHistory(ctx, user.ID, fingerprint, "10.0.0.2")
+	require.NoError(t, err)
+	require.Equal(t, models.LoginHistory{KnownDevice: true, KnownIP: true}, history)
+
+	err = tc.LoginAttemptRepo.CreateSuccess(ctx, uuid.New(), models.SessionClient{}, fingerprint, time.Now())
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
@@ -0,0 +1,177 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type notificationDeliveryRepository struct {
+	repository.BaseRepository
+}
+
+// NewNotificationDeliveryRepository creates a new PostgreSQL notification delivery repository
+func NewNotificationDeliveryRepository(db *sql.DB) repository.NotificationDeliveryRepository {
+	return &notificationDeliveryRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *notificationDeliveryRepository) Create(ctx context.Context, delivery *models.NotificationDelivery) error {
+	query := `
+		INSERT INTO notification_deliveries (
+			id, user_id, device_token_id, target_id, channel, alert_type, title, body, status
+		) VALUES (
+			$1, $2, $3, $4, $5, $6, $7, $8, $9
+		)
+		RETURNING created_at`
+
+	delivery.ID = uuid.New()
+	if delivery.Status == "" {
+		delivery.Status = models.DeliveryStatusPending
+	}
+
+	return r.Conn(ctx).QueryRowContext(ctx, query,
+		delivery.ID,
+		delivery.UserID,
+		delivery.DeviceTokenID,
+		delivery.TargetID,
+		delivery.Channel,
+		delivery.AlertType,
+		delivery.Title,
+		delivery.Body,
+		delivery.Status,
+	).Scan(&delivery.CreatedAt)
+}
+
+func (r *notificationDeliveryRepository) UpdateStatus(ctx context.Context, id uuid.UUID, status models.DeliveryStatus, errMsg *string) error {
+	var deliveredAt *time.Time
+	if status == models.DeliveryStatusSent {
+		now := time.Now()
+		deliveredAt = &now
+	}
+
+	query := `
+		UPDATE notification_deliveries
+		SET status = $2, error = $3, delivered_at = $4
+		WHERE id = $1`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, id, status, errMsg, deliveredAt)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *notificationDeliveryRepository) List(ctx context.Context, filter repository.NotificationDeliveryFilter) ([]models.NotificationDelivery, error) {
+	query, args := notificationDeliveryListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []models.NotificationDelivery{}
+	for rows.Next() {
+		var d models.NotificationDelivery
+		if err := rows.Scan(
+			&d.ID,
+			&d.UserID,
+			&d.DeviceTokenID,
+			&d.TargetID,
+			&d.Channel,
+			&d.AlertType,
+			&d.Title,
+			&d.Body,
+			&d.Status,
+			&d.Error,
+			&d.CreatedAt,
+			&d.DeliveredAt,
+		); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return deliveries, nil
+}
+
+func (r *notificationDeliveryRepository) Total(ctx context.Context, filter repository.NotificationDeliveryFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := notificationDeliveryListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+// notificationDeliveryListQuery builds the query selecting the notification deliveries matching the filter
+func notificationDeliveryListQuery(filter repository.NotificationDeliveryFilter) (string, []interface{}) {
+	query := `
+		SELECT id, user_id, device_token_id, target_id, channel, alert_type, title, body, status, error, created_at, delivered_at
+		FROM notification_deliveries`
+
+	var conditions []string
+	var args []interface{}
+	argCount := 1
+
+	if filter.UserID != nil {
+		conditions = append(conditions, fmt.Sprintf("user_id = $%d", argCount))
+		args = append(args, *filter.UserID)
+		argCount++
+	}
+
+	if filter.Channel != nil {
+		conditions = append(conditions, fmt.Sprintf("channel = $%d", argCount))
+		args = append(args, *filter.Channel)
+		argCount++
+	}
+
+	if filter.AlertType != nil {
+		conditions = append(conditions, fmt.Sprintf("alert_type = $%d", argCount))
+		args = append(args, *filter.AlertType)
+		argCount++
+	}
+
+	if filter.Status != nil {
+		conditions = append(conditions, fmt.Sprintf("status = $%d", argCount))
+		args = append(args, *filter.Status)
+		argCount++
+	}
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += " ORDER BY created_at DESC"
+
+	if filter.Limit != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, *filter.Limit)
+		argCount++
+	}
+
+	if filter.Offset != nil {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, *filter.Offset)
+	}
+
+	return query, args
+}
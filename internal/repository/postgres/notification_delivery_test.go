@@ -0,0 +1,78 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationDeliveryRepository_CreateAndList(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewNotificationDeliveryRepository(tc.DB)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+
+	sent := &models.NotificationDelivery{
+		UserID:    user.ID,
+		Channel:   models.NotificationChannelFCM,
+		AlertType: models.NotificationAlertPrice,
+		Title:     "Price alert",
+		Body:      "Prices are low",
+	}
+	require.NoError(t, repo.Create(context.Background(), sent))
+	require.Equal(t, models.DeliveryStatusPending, sent.Status)
+	require.NoError(t, repo.UpdateStatus(context.Background(), sent.ID, models.DeliveryStatusSent, nil))
+
+	failed := &models.NotificationDelivery{
+		UserID:    user.ID,
+		Channel:   models.NotificationChannelWebPush,
+		AlertType: models.NotificationAlertPrice,
+		Title:     "Price alert",
+		Body:      "Prices are low",
+	}
+	require.NoError(t, repo.Create(context.Background(), failed))
+	errMsg := "push service returned status 500"
+	require.NoError(t, repo.UpdateStatus(context.Background(), failed.ID, models.DeliveryStatusFailed, &errMsg))
+
+	require.ErrorIs(t, repo.UpdateStatus(context.Background(), uuid.New(), models.DeliveryStatusSent, nil), repository.ErrNotFound)
+
+	tests := []struct {
+		name      string
+		filter    repository.NotificationDeliveryFilter
+		wantCount int
+	}{
+		{
+			name:      "All For User",
+			filter:    repository.NotificationDeliveryFilter{UserID: &user.ID},
+			wantCount: 2,
+		},
+		{
+			name: "Sent Only",
+			filter: repository.NotificationDeliveryFilter{
+				UserID: &user.ID,
+				Status: func() *models.DeliveryStatus { s := models.DeliveryStatusSent; return &s }(),
+			},
+			wantCount: 1,
+		},
+		{
+			name: "WebPush Only",
+			filter: repository.NotificationDeliveryFilter{
+				Channel: func() *models.NotificationChannel { c := models.NotificationChannelWebPush; return &c }(),
+			},
+			wantCount: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deliveries, err := repo.List(context.Background(), tt.filter)
+			require.NoError(t, err)
+			require.Len(t, deliveries, tt.wantCount)
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type notificationPreferenceRepository struct {
+	repository.BaseRepository
+}
+
+// NewNotificationPreferenceRepository creates a new PostgreSQL notification preference repository
+func NewNotificationPreferenceRepository(db *sql.DB) repository.NotificationPreferenceRepository {
+	return &notificationPreferenceRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *notificationPreferenceRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.NotificationPreference, error) {
+	query := `
+		SELECT user_id, channel, alert_type, enabled, updated_at
+		FROM notification_preferences
+		WHERE user_id = $1
+		ORDER BY channel, alert_type`
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prefs := []models.NotificationPreference{}
+	for rows.Next() {
+		var pref models.NotificationPreference
+		if err := rows.Scan(
+			&pref.UserID,
+			&pref.Channel,
+			&pref.AlertType,
+			&pref.Enabled,
+			&pref.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		prefs = append(prefs, pref)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return prefs, nil
+}
+
+func (r *notificationPreferenceRepository) Upsert(ctx context.Context, pref *models.NotificationPreference) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, channel, alert_type, enabled)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, channel, alert_type) DO UPDATE SET enabled = EXCLUDED.enabled
+		RETURNING updated_at`
+
+	return r.Conn(ctx).QueryRowContext(ctx, query,
+		pref.UserID,
+		pref.Channel,
+		pref.AlertType,
+		pref.Enabled,
+	).Scan(&pref.UpdatedAt)
+}
+
+func (r *notificationPreferenceRepository) IsEnabled(ctx context.Context, userID uuid.UUID, channel models.NotificationChannel, alertType models.NotificationAlertType) (bool, error) {
+	query := `
+		SELECT enabled
+		FROM notification_preferences
+		WHERE user_id = $1 AND channel = $2 AND alert_type = $3`
+
+	var enabled bool
+	err := r.Conn(ctx).QueryRowContext(ctx, query, userID, channel, alertType).Scan(&enabled)
+	if err == sql.ErrNoRows {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return enabled, nil
+}
@@ -0,0 +1,47 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationPreferenceRepository_IsEnabled(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewNotificationPreferenceRepository(tc.DB)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+
+	// Without a stored preference every channel is enabled
+	enabled, err := repo.IsEnabled(context.Background(), user.ID, models.NotificationChannelFCM, models.NotificationAlertPrice)
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	pref := &models.NotificationPreference{
+		UserID:    user.ID,
+		Channel:   models.NotificationChannelFCM,
+		AlertType: models.NotificationAlertPrice,
+		Enabled:   false,
+	}
+	require.NoError(t, repo.Upsert(context.Background(), pref))
+
+	enabled, err = repo.IsEnabled(context.Background(), user.ID, models.NotificationChannelFCM, models.NotificationAlertPrice)
+	require.NoError(t, err)
+	require.False(t, enabled)
+
+	// Other alert types are unaffected
+	enabled, err = repo.IsEnabled(context.Background(), user.ID, models.NotificationChannelFCM, models.NotificationAlertConsumption)
+	require.NoError(t, err)
+	require.True(t, enabled)
+
+	pref.Enabled = true
+	require.NoError(t, repo.Upsert(context.Background(), pref))
+
+	prefs, err := repo.ListByUserID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, prefs, 1)
+	require.True(t, prefs[0].Enabled)
+}
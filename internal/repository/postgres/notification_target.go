@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type notificationTargetRepository struct {
+	repository.BaseRepository
+}
+
+// NewNotificationTargetRepository creates a new PostgreSQL notification target repository
+func NewNotificationTargetRepository(db *sql.DB) repository.NotificationTargetRepository {
+	return &notificationTargetRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const notificationTargetColumns = `id, user_id, channel, name, webhook_url, bot_token, chat_id, created_at, updated_at`
+
+func (r *notificationTargetRepository) Create(ctx context.Context, target *models.NotificationTarget) error {
+	// First verify the user exists
+	var exists bool
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", target.UserID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrNotFound
+	}
+
+	query := `
+		INSERT INTO notification_targets (id, user_id, channel, name, webhook_url, bot_token, chat_id)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING ` + notificationTargetColumns
+
+	return r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		uuid.New(),
+		target.UserID,
+		target.Channel,
+		target.Name,
+		target.WebhookURL,
+		target.BotToken,
+		target.ChatID,
+	), target)
+}
+
+func (r *notificationTargetRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.NotificationTarget, error) {
+	query := `SELECT ` + notificationTargetColumns + ` FROM notification_targets WHERE id = $1`
+
+	target := &models.NotificationTarget{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), target)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return target, nil
+}
+
+func (r *notificationTargetRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.NotificationTarget, error) {
+	query := `SELECT ` + notificationTargetColumns + ` FROM notification_targets WHERE user_id = $1 ORDER BY name`
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	targets := []models.NotificationTarget{}
+	for rows.Next() {
+		var target models.NotificationTarget
+		if err := r.scan(rows, &target); err != nil {
+			return nil, err
+		}
+		targets = append(targets, target)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return targets, nil
+}
+
+func (r *notificationTargetRepository) Update(ctx context.Context, target *models.NotificationTarget) error {
+	query := `
+		UPDATE notification_targets
+		SET name = $2, webhook_url = $3, bot_token = $4, chat_id = $5
+		WHERE id = $1
+		RETURNING ` + notificationTargetColumns
+
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		target.ID,
+		target.Name,
+		target.WebhookURL,
+		target.BotToken,
+		target.ChatID,
+	), target)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *notificationTargetRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM notification_targets WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
+
+func (r *notificationTargetRepository) scan(row interface{ Scan(...interface{}) error }, target *models.NotificationTarget) error {
+	return row.Scan(
+		&target.ID,
+		&target.UserID,
+		&target.Channel,
+		&target.Name,
+		&target.WebhookURL,
+		&target.BotToken,
+		&target.ChatID,
+		&target.CreatedAt,
+		&target.UpdatedAt,
+	)
+}
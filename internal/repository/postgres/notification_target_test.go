@@ -0,0 +1,52 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotificationTargetRepository_CRUD(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewNotificationTargetRepository(tc.DB)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+
+	webhook := "https://hooks.slack.com/services/T/B/X"
+	target := &models.NotificationTarget{
+		UserID:     user.ID,
+		Channel:    models.NotificationChannelSlack,
+		Name:       "Ops",
+		WebhookURL: &webhook,
+	}
+	require.NoError(t, repo.Create(context.Background(), target))
+	require.NotEqual(t, uuid.Nil, target.ID)
+
+	err := repo.Create(context.Background(), &models.NotificationTarget{
+		UserID:  uuid.New(),
+		Channel: models.NotificationChannelSlack,
+		Name:    "Orphan",
+	})
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	got, err := repo.GetByID(context.Background(), target.ID)
+	require.NoError(t, err)
+	require.Equal(t, webhook, *got.WebhookURL)
+
+	got.Name = "Ops alerts"
+	require.NoError(t, repo.Update(context.Background(), got))
+
+	targets, err := repo.ListByUserID(context.Background(), user.ID)
+	require.NoError(t, err)
+	require.Len(t, targets, 1)
+	require.Equal(t, "Ops alerts", targets[0].Name)
+
+	require.NoError(t, repo.Delete(context.Background(), target.ID))
+	require.ErrorIs(t, repo.Delete(context.Background(), target.ID), repository.ErrNotFound)
+	require.ErrorIs(t, repo.Update(context.Background(), got), repository.ErrNotFound)
+}
@@ -0,0 +1,306 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type organizationRepository struct {
+	repository.BaseRepository
+}
+
+// NewOrganizationRepository creates a new PostgreSQL organization repository
+func NewOrganizationRepository(db *sql.DB) repository.OrganizationRepository {
+	return &organizationRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const organizationColumns = `id, name, created_at, updated_at`
+
+func (r *organizationRepository) Create(ctx context.Context, org *models.Organization, ownerID uuid.UUID) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var exists bool
+	err = tx.QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND deleted_at IS NULL)", ownerID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrNotFound
+	}
+
+	query := `INSERT INTO organizations (id, name) VALUES ($1, $2) RETURNING ` + organizationColumns
+	if err := r.scan(tx.QueryRowContext(ctx, query, uuid.New(), org.Name), org); err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx,
+		`INSERT INTO organization_members (organization_id, user_id, role) VALUES ($1, $2, $3)`,
+		org.ID, ownerID, models.OrganizationRoleOwner)
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *organizationRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Organization, error) {
+	query := `SELECT ` + organizationColumns + ` FROM organizations WHERE id = $1`
+
+	org := &models.Organization{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), org)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return org, nil
+}
+
+// organizationListQuery builds the query listing the organizations matching the filter
+func organizationListQuery(filter repository.OrganizationFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.UserID != nil {
+		args = append(args, *filter.UserID)
+		conditions = append(conditions, fmt.Sprintf("id IN (SELECT organization_id FROM organization_members WHERE user_id = $%d)", len(args)))
+	}
+	if filter.Search != nil {
+		args = append(args, "%"+*filter.Search+"%")
+		conditions = append(conditions, fmt.Sprintf("name ILIKE $%d", len(args)))
+	}
+
+	query := `SELECT ` + organizationColumns + ` FROM organizations`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY name, id"
+
+	if filter.Limit != nil {
+		args = append(args, *filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset != nil {
+		args = append(args, *filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return query, args
+}
+
+func (r *organizationRepository) List(ctx context.Context, filter repository.OrganizationFilter) ([]models.Organization, error) {
+	query, args := organizationListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	orgs := []models.Organization{}
+	for rows.Next() {
+		var org models.Organization
+		if err := r.scan(rows, &org); err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, rows.Err()
+}
+
+func (r *organizationRepository) Total(ctx context.Context, filter repository.OrganizationFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := organizationListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+func (r *organizationRepository) Update(ctx context.Context, org *models.Organization) error {
+	query := `UPDATE organizations SET name = $2 WHERE id = $1 RETURNING ` + organizationColumns
+
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, org.ID, org.Name), org)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *organizationRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM organizations WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+const organizationMemberQuery = `
+	SELECT m.organization_id, m.user_id, u.username, m.role, m.created_at
+	FROM organization_members m
+	JOIN users u ON u.id = m.user_id AND u.deleted_at IS NULL`
+
+func (r *organizationRepository) GetMember(ctx context.Context, orgID, userID uuid.UUID) (*models.OrganizationMember, error) {
+	member := &models.OrganizationMember{}
+	err := r.scanMember(r.Conn(ctx).QueryRowContext(ctx,
+		organizationMemberQuery+` WHERE m.organization_id = $1 AND m.user_id = $2`, orgID, userID), member)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return member, nil
+}
+
+func (r *organizationRepository) ListMembers(ctx context.Context, orgID uuid.UUID) ([]models.OrganizationMember, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx,
+		organizationMemberQuery+` WHERE m.organization_id = $1 ORDER BY u.username`, orgID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	members := []models.OrganizationMember{}
+	for rows.Next() {
+		var member models.OrganizationMember
+		if err := r.scanMember(rows, &member); err != nil {
+			return nil, err
+		}
+		members = append(members, member)
+	}
+	return members, rows.Err()
+}
+
+func (r *organizationRepository) AddMember(ctx context.Context, member *models.OrganizationMember) error {
+	query := `
+		WITH added AS (
+			INSERT INTO organization_members (organization_id, user_id, role)
+			SELECT $1, id, $3 FROM users WHERE id = $2 AND deleted_at IS NULL
+			RETURNING organization_id, user_id, role, created_at
+		)
+		SELECT a.organization_id, a.user_id, u.username, a.role, a.created_at
+		FROM added a JOIN users u ON u.id = a.user_id`
+
+	err := r.scanMember(r.Conn(ctx).QueryRowContext(ctx, query, member.OrganizationID, member.UserID, member.Role), member)
+	switch errorCode(err) {
+	case uniqueViolation:
+		return repository.ErrDuplicateEntry
+	case foreignKeyViolation:
+		return repository.ErrNotFound
+	}
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+// changeMember runs change on a member while the organization is locked, after checking
+// that the organization keeps an owner when the member stops being one
+func (r *organizationRepository) changeMember(ctx context.Context, orgID, userID uuid.UUID, newRole *models.OrganizationRole, change func(tx repository.Executor) error) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var locked uuid.UUID
+	err = tx.QueryRowContext(ctx, `SELECT id FROM organizations WHERE id = $1 FOR UPDATE`, orgID).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	var role models.OrganizationRole
+	err = tx.QueryRowContext(ctx,
+		`SELECT role FROM organization_members WHERE organization_id = $1 AND user_id = $2`, orgID, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	if role == models.OrganizationRoleOwner && (newRole == nil || *newRole != models.OrganizationRoleOwner) {
+		var owners int
+		err = tx.QueryRowContext(ctx,
+			`SELECT COUNT(*) FROM organization_members WHERE organization_id = $1 AND role = $2`,
+			orgID, models.OrganizationRoleOwner).Scan(&owners)
+		if err != nil {
+			return err
+		}
+		if owners <= 1 {
+			return repository.ErrLastOwner
+		}
+	}
+
+	if err := change(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *organizationRepository) UpdateMemberRole(ctx context.Context, orgID, userID uuid.UUID, role models.OrganizationRole) error {
+	return r.changeMember(ctx, orgID, userID, &role, func(tx repository.Executor) error {
+		_, err := tx.ExecContext(ctx,
+			`UPDATE organization_members SET role = $3 WHERE organization_id = $1 AND user_id = $2`, orgID, userID, role)
+		return err
+	})
+}
+
+func (r *organizationRepository) RemoveMember(ctx context.Context, orgID, userID uuid.UUID) error {
+	return r.changeMember(ctx, orgID, userID, nil, func(tx repository.Executor) error {
+		_, err := tx.ExecContext(ctx,
+			`DELETE FROM organization_members WHERE organization_id = $1 AND user_id = $2`, orgID, userID)
+		return err
+	})
+}
+
+func (r *organizationRepository) SharesOrganization(ctx context.Context, userID, memberID uuid.UUID, roles ...models.OrganizationRole) (bool, error) {
+	query := `
+		SELECT EXISTS(
+			SELECT 1
+			FROM organization_members mine
+			JOIN organization_members theirs ON theirs.organization_id = mine.organization_id
+			JOIN users u ON u.id = theirs.user_id AND u.deleted_at IS NULL
+			WHERE mine.user_id = $1 AND theirs.user_id = $2`
+	args := []interface{}{userID, memberID}
+	if len(roles) > 0 {
+		names := make([]string, len(roles))
+		for i, role := range roles {
+			names[i] = string(role)
+		}
+		query += ` AND mine.role = ANY($3)`
+		args = append(args, names)
+	}
+	query += `)`
+
+	var shares bool
+	err := r.Conn(ctx).QueryRowContext(ctx, query, args...).Scan(&shares)
+	return shares, err
+}
+
+func (r *organizationRepository) scan(row interface{ Scan(...interface{}) error }, org *models.Organization) error {
+	return row.Scan(&org.ID, &org.Name, &org.CreatedAt, &org.UpdatedAt)
+}
+
+func (r *organizationRepository) scanMember(row interface{ Scan(...interface{}) error }, member *models.OrganizationMember) error {
+	return row.Scan(&member.OrganizationID, &member.UserID, &member.Username, &member.Role, &member.CreatedAt)
+}
@@ -0,0 +1,77 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOrganizationRepository(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewOrganizationRepository(tc.DB)
+	ctx := context.Background()
+	alice := tc.CreateTestUser("alice", "alice@example.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@example.com", "password123", false)
+	carol := tc.CreateTestUser("carol", "carol@example.com", "password123", false)
+
+	org := &models.Organization{Name: "Andersson household"}
+	require.NoError(t, repo.Create(ctx, org, alice.ID))
+	require.NotEqual(t, uuid.Nil, org.ID)
+	require.ErrorIs(t, repo.Create(ctx, &models.Organization{Name: "Orphan"}, uuid.New()), repository.ErrNotFound)
+
+	member := &models.OrganizationMember{OrganizationID: org.ID, UserID: bob.ID, Role: models.OrganizationRoleMember}
+	require.NoError(t, repo.AddMember(ctx, member))
+	require.Equal(t, "bob", member.Username)
+	require.ErrorIs(t, repo.AddMember(ctx, member), repository.ErrDuplicateEntry)
+	require.ErrorIs(t, repo.AddMember(ctx, &models.OrganizationMember{OrganizationID: uuid.New(), UserID: bob.ID, Role: models.OrganizationRoleMember}), repository.ErrNotFound)
+
+	members, err := repo.ListMembers(ctx, org.ID)
+	require.NoError(t, err)
+	require.Len(t, members, 2)
+	require.Equal(t, models.OrganizationRoleOwner, members[0].Role)
+
+	orgs, err := repo.List(ctx, repository.OrganizationFilter{UserID: &bob.ID})
+	require.NoError(t, err)
+	require.Len(t, orgs, 1)
+	total, err := repo.Total(ctx, repository.OrganizationFilter{UserID: &carol.ID})
+	require.NoError(t, err)
+	require.Zero(t, total)
+
+	shares, err := repo.SharesOrganization(ctx, bob.ID, alice.ID)
+	require.NoError(t, err)
+	require.True(t, shares)
+	shares, err = repo.SharesOrganization(ctx, bob.ID, alice.ID, models.OrganizationRoleOwner, models.OrganizationRoleAdmin)
+	require.NoError(t, err)
+	require.False(t, shares)
+	shares, err = repo.SharesOrganization(ctx, carol.ID, alice.ID)
+	require.NoError(t, err)
+	require.False(t, shares)
+
+	users, err := postgres.NewUserRepository(tc.DB).List(ctx, repository.UserFilter{OrganizationID: &org.ID})
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+
+	require.ErrorIs(t, repo.RemoveMember(ctx, org.ID, alice.ID), repository.ErrLastOwner)
+	require.ErrorIs(t, repo.UpdateMemberRole(ctx, org.ID, alice.ID, models.OrganizationRoleAdmin), repository.ErrLastOwner)
+	require.NoError(t, repo.UpdateMemberRole(ctx, org.ID, bob.ID, models.OrganizationRoleOwner))
+	require.NoError(t, repo.RemoveMember(ctx, org.ID, alice.ID))
+	_, err = repo.GetMember(ctx, org.ID, alice.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	org.Name = "Bob's household"
+	require.NoError(t, repo.Update(ctx, org))
+	got, err := repo.GetByID(ctx, org.ID)
+	require.NoError(t, err)
+	require.Equal(t, "Bob's household", got.Name)
+
+	require.NoError(t, repo.Delete(ctx, org.ID))
+	require.ErrorIs(t, repo.Delete(ctx, org.ID), repository.ErrNotFound)
+	_, err = repo.GetMember(ctx, org.ID, bob.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
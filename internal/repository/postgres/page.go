@@ -0,0 +1,14 @@
+package postgres
+
+import (
+	"context"
+	"wattwatch/internal/repository"
+)
+
+// countRows counts the rows the query returns, such as a list query without its limit
+// and offset to give the total number of items across all pages
+func countRows(ctx context.Context, db repository.Executor, query string, args []interface{}) (int, error) {
+	var count int
+	err := db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+query+") matching", args...).Scan(&count)
+	return count, err
+}
@@ -33,7 +33,7 @@ func (r *passwordHistoryRepository) Add(ctx context.Context, userID uuid.UUID, p
 	id := uuid.New()
 	now := time.Now()
 
-	_, err := r.DB().ExecContext(ctx, query,
+	_, err := r.Conn(ctx).ExecContext(ctx, query,
 		id,
 		userID,
 		passwordHash,
@@ -43,16 +43,20 @@ func (r *passwordHistoryRepository) Add(ctx context.Context, userID uuid.UUID, p
 	return err
 }
 
-func (r *passwordHistoryRepository) CheckReuse(ctx context.Context, userID uuid.UUID, newPasswordHash string) error {
+func (r *passwordHistoryRepository) CheckReuse(ctx context.Context, userID uuid.UUID, newPasswordHash string, depth int) error {
+	if depth <= 0 {
+		return nil
+	}
+
 	query := `
 		SELECT password_hash 
 		FROM password_history
 		WHERE user_id = $1
 		AND created_at > NOW() - INTERVAL '90 days'
 		ORDER BY created_at DESC
-		LIMIT 5`
+		LIMIT $2`
 
-	rows, err := r.DB().QueryContext(ctx, query, userID)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID, depth)
 	if err != nil {
 		return err
 	}
@@ -80,7 +84,7 @@ func (r *passwordHistoryRepository) CleanupOld(ctx context.Context, olderThan ti
 		WHERE created_at < $1`
 
 	cutoff := time.Now().Add(-olderThan)
-	_, err := r.DB().ExecContext(ctx, query, cutoff)
+	_, err := r.Conn(ctx).ExecContext(ctx, query, cutoff)
 	return err
 }
 
@@ -91,7 +95,7 @@ func (r *passwordHistoryRepository) GetByUserID(ctx context.Context, userID uuid
 		WHERE user_id = $1
 		ORDER BY created_at DESC`
 
-	rows, err := r.DB().QueryContext(ctx, query, userID)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
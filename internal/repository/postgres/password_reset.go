@@ -19,10 +19,14 @@ func NewPasswordResetRepository(db *sql.DB) repository.PasswordResetRepository {
 	}
 }
 
-func (r *passwordResetRepository) Create(ctx context.Context, userID uuid.UUID) (*repository.PasswordReset, error) {
+func (r *passwordResetRepository) Create(ctx context.Context, userID uuid.UUID, ttl time.Duration) (*repository.PasswordReset, error) {
+	if ttl <= 0 {
+		ttl = repository.ResetTokenExpiration
+	}
+
 	// First verify the user exists
 	var exists bool
-	err := r.DB().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return nil, err
 	}
@@ -34,7 +38,7 @@ func (r *passwordResetRepository) Create(ctx context.Context, userID uuid.UUID)
 		ID:        uuid.New(),
 		UserID:    userID,
 		Token:     uuid.New().String(),
-		ExpiresAt: time.Now().Add(repository.ResetTokenExpiration),
+		ExpiresAt: time.Now().Add(ttl),
 	}
 
 	query := `
@@ -42,7 +46,7 @@ func (r *passwordResetRepository) Create(ctx context.Context, userID uuid.UUID)
 		VALUES ($1, $2, $3, $4)
 		RETURNING created_at`
 
-	err = r.DB().QueryRowContext(ctx, query, reset.ID, reset.UserID, reset.Token, reset.ExpiresAt).
+	err = r.Conn(ctx).QueryRowContext(ctx, query, reset.ID, reset.UserID, reset.Token, reset.ExpiresAt).
 		Scan(&reset.CreatedAt)
 	if err != nil {
 		return nil, err
@@ -58,7 +62,7 @@ func (r *passwordResetRepository) GetByToken(ctx context.Context, token string)
 		FROM password_resets
 		WHERE token = $1`
 
-	err := r.DB().QueryRowContext(ctx, query, token).Scan(
+	err := r.Conn(ctx).QueryRowContext(ctx, query, token).Scan(
 		&reset.ID,
 		&reset.UserID,
 		&reset.Token,
@@ -88,7 +92,7 @@ func (r *passwordResetRepository) GetByToken(ctx context.Context, token string)
 func (r *passwordResetRepository) MarkAsUsed(ctx context.Context, id uuid.UUID) error {
 	// First check if token exists and is not already used
 	var usedAt *time.Time
-	err := r.DB().QueryRowContext(ctx,
+	err := r.Conn(ctx).QueryRowContext(ctx,
 		"SELECT used_at FROM password_resets WHERE id = $1",
 		id).Scan(&usedAt)
 
@@ -107,7 +111,7 @@ func (r *passwordResetRepository) MarkAsUsed(ctx context.Context, id uuid.UUID)
 		SET used_at = CURRENT_TIMESTAMP
 		WHERE id = $1 AND used_at IS NULL`
 
-	result, err := r.DB().ExecContext(ctx, query, id)
+	result, err := r.Conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -122,3 +126,20 @@ func (r *passwordResetRepository) MarkAsUsed(ctx context.Context, id uuid.UUID)
 
 	return nil
 }
+
+func (r *passwordResetRepository) CountSince(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	var count int
+	err := r.Conn(ctx).QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM password_resets WHERE user_id = $1 AND created_at >= $2",
+		userID, since,
+	).Scan(&count)
+	return count, err
+}
+
+func (r *passwordResetRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
+	result, err := r.Conn(ctx).ExecContext(ctx, "DELETE FROM password_resets WHERE expires_at < $1", before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -34,7 +34,7 @@ func TestPasswordResetRepository_Create(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			reset, err := tc.PasswordResetRepo.Create(context.Background(), tt.userID)
+			reset, err := tc.PasswordResetRepo.Create(context.Background(), tt.userID, repository.ResetTokenExpiration)
 			if tt.wantErr != nil {
 				require.ErrorIs(t, err, tt.wantErr)
 				require.Nil(t, reset)
@@ -67,11 +67,11 @@ func TestPasswordResetRepository_GetByToken(t *testing.T) {
 	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
 
 	// Create a valid reset token
-	validReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID)
+	validReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID, repository.ResetTokenExpiration)
 	require.NoError(t, err)
 
 	// Create an expired token
-	expiredReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID)
+	expiredReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID, repository.ResetTokenExpiration)
 	require.NoError(t, err)
 	_, err = tc.DB.ExecContext(context.Background(),
 		"UPDATE password_resets SET expires_at = $1 WHERE id = $2",
@@ -79,7 +79,7 @@ func TestPasswordResetRepository_GetByToken(t *testing.T) {
 	require.NoError(t, err)
 
 	// Create a used token
-	usedReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID)
+	usedReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID, repository.ResetTokenExpiration)
 	require.NoError(t, err)
 	err = tc.PasswordResetRepo.MarkAsUsed(context.Background(), usedReset.ID)
 	require.NoError(t, err)
@@ -143,11 +143,11 @@ func TestPasswordResetRepository_MarkAsUsed(t *testing.T) {
 	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
 
 	// Create a valid reset token
-	validReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID)
+	validReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID, repository.ResetTokenExpiration)
 	require.NoError(t, err)
 
 	// Create and mark a token as used
-	usedReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID)
+	usedReset, err := tc.PasswordResetRepo.Create(context.Background(), user.ID, repository.ResetTokenExpiration)
 	require.NoError(t, err)
 	err = tc.PasswordResetRepo.MarkAsUsed(context.Background(), usedReset.ID)
 	require.NoError(t, err)
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type priceAlertRepository struct {
+	repository.BaseRepository
+}
+
+// NewPriceAlertRepository creates a new PostgreSQL price alert repository
+func NewPriceAlertRepository(db *sql.DB) repository.PriceAlertRepository {
+	return &priceAlertRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const priceAlertColumns = `id, user_id, zone_id, currency_id, direction, threshold,
+	to_char(window_start, 'HH24:MI'), to_char(window_end, 'HH24:MI'),
+	enabled, last_triggered_at, created_at, updated_at`
+
+func (r *priceAlertRepository) Create(ctx context.Context, alert *models.PriceAlert) error {
+	query := `
+		INSERT INTO price_alerts (id, user_id, zone_id, currency_id, direction, threshold, window_start, window_end, enabled)
+		SELECT $1, id, $3, $4, $5, $6, $7, $8, $9 FROM users WHERE id = $2 AND deleted_at IS NULL
+		RETURNING ` + priceAlertColumns
+
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		uuid.New(),
+		alert.UserID,
+		alert.ZoneID,
+		alert.CurrencyID,
+		alert.Direction,
+		alert.Threshold,
+		alert.WindowStart,
+		alert.WindowEnd,
+		alert.Enabled,
+	), alert)
+	if errorCode(err) == foreignKeyViolation {
+		return repository.ErrNotFound
+	}
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *priceAlertRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.PriceAlert, error) {
+	query := `SELECT ` + priceAlertColumns + ` FROM price_alerts WHERE id = $1`
+
+	alert := &models.PriceAlert{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), alert)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return alert, nil
+}
+
+func (r *priceAlertRepository) ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.PriceAlert, error) {
+	query := `SELECT ` + priceAlertColumns + ` FROM price_alerts WHERE user_id = $1 ORDER BY created_at, id`
+	return r.list(ctx, query, userID)
+}
+
+func (r *priceAlertRepository) ListEnabled(ctx context.Context, zoneID, currencyID uuid.UUID) ([]models.PriceAlert, error) {
+	query := `
+		SELECT ` + priceAlertColumns + ` FROM price_alerts
+		WHERE zone_id = $1 AND currency_id = $2 AND enabled
+			AND user_id IN (SELECT id FROM users WHERE deleted_at IS NULL)
+		ORDER BY created_at, id`
+	return r.list(ctx, query, zoneID, currencyID)
+}
+
+func (r *priceAlertRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.PriceAlert, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	alerts := []models.PriceAlert{}
+	for rows.Next() {
+		var alert models.PriceAlert
+		if err := r.scan(rows, &alert); err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, alert)
+	}
+	return alerts, rows.Err()
+}
+
+func (r *priceAlertRepository) Update(ctx context.Context, alert *models.PriceAlert) error {
+	query := `
+		UPDATE price_alerts
+		SET direction = $2, threshold = $3, window_start = $4, window_end = $5, enabled = $6
+		WHERE id = $1
+		RETURNING ` + priceAlertColumns
+
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		alert.ID,
+		alert.Direction,
+		alert.Threshold,
+		alert.WindowStart,
+		alert.WindowEnd,
+		alert.Enabled,
+	), alert)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *priceAlertRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM price_alerts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *priceAlertRepository) MarkTriggered(ctx context.Context, id uuid.UUID, at time.Time) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `UPDATE price_alerts SET last_triggered_at = $2 WHERE id = $1`, id, at)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *priceAlertRepository) scan(row interface{ Scan(...interface{}) error }, alert *models.PriceAlert) error {
+	return row.Scan(
+		&alert.ID,
+		&alert.UserID,
+		&alert.ZoneID,
+		&alert.CurrencyID,
+		&alert.Direction,
+		&alert.Threshold,
+		&alert.WindowStart,
+		&alert.WindowEnd,
+		&alert.Enabled,
+		&alert.LastTriggeredAt,
+		&alert.CreatedAt,
+		&alert.UpdatedAt,
+	)
+}
@@ -0,0 +1,62 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceAlertRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewPriceAlertRepository(tc.DB)
+
+	user := tc.CreateTestUser("alice", "alice@example.com", "password123", false)
+	zone := tc.CreateTestZone("test-zone", "Europe/Stockholm")
+	currency := tc.CreateTestCurrency("EUR")
+	start, end := "22:00", "06:00"
+
+	alert := &models.PriceAlert{UserID: user.ID, ZoneID: zone.ID, CurrencyID: currency.ID, Direction: models.PriceAlertAbove,
+		Threshold: 150.5, WindowStart: &start, WindowEnd: &end, Enabled: true}
+	require.NoError(t, repo.Create(ctx, alert))
+	require.NotEqual(t, uuid.Nil, alert.ID)
+	require.Equal(t, "22:00", *alert.WindowStart)
+	require.ErrorIs(t, repo.Create(ctx, &models.PriceAlert{UserID: user.ID, ZoneID: uuid.New(), CurrencyID: currency.ID,
+		Direction: models.PriceAlertBelow}), repository.ErrNotFound)
+
+	disabled := &models.PriceAlert{UserID: user.ID, ZoneID: zone.ID, CurrencyID: currency.ID, Direction: models.PriceAlertBelow}
+	require.NoError(t, repo.Create(ctx, disabled))
+
+	alerts, err := repo.ListByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, alerts, 2)
+	alerts, err = repo.ListEnabled(ctx, zone.ID, currency.ID)
+	require.NoError(t, err)
+	require.Len(t, alerts, 1)
+	require.Equal(t, alert.ID, alerts[0].ID)
+	require.Equal(t, 150.5, alerts[0].Threshold)
+
+	alert.WindowStart, alert.WindowEnd = nil, nil
+	alert.Threshold = 200
+	require.NoError(t, repo.Update(ctx, alert))
+	require.Nil(t, alert.WindowStart)
+
+	at := time.Now().UTC().Truncate(time.Second)
+	require.NoError(t, repo.MarkTriggered(ctx, alert.ID, at))
+	got, err := repo.GetByID(ctx, alert.ID)
+	require.NoError(t, err)
+	require.Equal(t, 200.0, got.Threshold)
+	require.True(t, got.LastTriggeredAt.Equal(at))
+
+	require.NoError(t, repo.Delete(ctx, alert.ID))
+	require.ErrorIs(t, repo.Delete(ctx, alert.ID), repository.ErrNotFound)
+	_, err = repo.GetByID(ctx, alert.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
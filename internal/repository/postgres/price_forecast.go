@@ -0,0 +1,128 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type priceForecastRepository struct {
+	repository.BaseRepository
+}
+
+// NewPriceForecastRepository creates a new PostgreSQL price forecast repository
+func NewPriceForecastRepository(db *sql.DB) repository.PriceForecastRepository {
+	return NewPriceForecastRepositoryWithReplicas(db, nil)
+}
+
+// NewPriceForecastRepositoryWithReplicas creates a new PostgreSQL price forecast repository
+// reading listings from replicas, nil reads them from db
+func NewPriceForecastRepositoryWithReplicas(db *sql.DB, replicas *repository.ReplicaSet) repository.PriceForecastRepository {
+	return &priceForecastRepository{
+		BaseRepository: repository.NewBaseRepositoryWithReplicas(db, replicas),
+	}
+}
+
+// priceForecastBatchRows is the number of forecasts upserted per statement, keeping the
+// parameters within PostgreSQL's limit of 65535
+const priceForecastBatchRows = 5000
+
+func (r *priceForecastRepository) Upsert(ctx context.Context, forecasts []models.PriceForecast) error {
+	if len(forecasts) == 0 {
+		return nil
+	}
+
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	now := time.Now()
+	for start := 0; start < len(forecasts); start += priceForecastBatchRows {
+		end := min(start+priceForecastBatchRows, len(forecasts))
+		if err := upsertPriceForecasts(ctx, tx, forecasts[start:end], now); err != nil {
+			if errorCode(err) == foreignKeyViolation {
+				return repository.ErrNotFound
+			}
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertPriceForecasts upserts the forecasts in one statement
+func upsertPriceForecasts(ctx context.Context, tx repository.Executor, forecasts []models.PriceForecast, now time.Time) error {
+	valueStrings := make([]string, 0, len(forecasts))
+	valueArgs := make([]interface{}, 0, len(forecasts)*7)
+
+	for i := range forecasts {
+		f := &forecasts[i]
+		f.CreatedAt = now
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			i*7+1, i*7+2, i*7+3, i*7+4, i*7+5, i*7+6, i*7+7))
+		valueArgs = append(valueArgs,
+			f.Timestamp,
+			f.ZoneID,
+			f.CurrencyID,
+			f.Source,
+			f.Price,
+			f.HorizonHours,
+			now,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO price_forecasts (timestamp, zone_id, currency_id, source, price, horizon_hours, created_at)
+		VALUES %s
+		ON CONFLICT (zone_id, currency_id, source, timestamp) DO UPDATE
+		SET price = EXCLUDED.price,
+			horizon_hours = EXCLUDED.horizon_hours,
+			created_at = EXCLUDED.created_at`, strings.Join(valueStrings, ","))
+
+	_, err := tx.ExecContext(ctx, query, valueArgs...)
+	return err
+}
+
+func (r *priceForecastRepository) List(ctx context.Context, filter repository.PriceForecastFilter) ([]models.PriceForecast, error) {
+	conditions := []string{"zone_id = $1", "currency_id = $2", "timestamp >= $3", "timestamp < $4"}
+	args := []interface{}{filter.ZoneID, filter.CurrencyID, filter.StartTime, filter.EndTime}
+	if filter.Source != "" {
+		args = append(args, filter.Source)
+		conditions = append(conditions, fmt.Sprintf("source = $%d", len(args)))
+	}
+
+	query := `
+		SELECT timestamp, zone_id, currency_id, source, price, horizon_hours, created_at
+		FROM price_forecasts
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY timestamp, source`
+	if filter.Latest {
+		query = `
+			SELECT DISTINCT ON (timestamp) timestamp, zone_id, currency_id, source, price, horizon_hours, created_at
+			FROM price_forecasts
+			WHERE ` + strings.Join(conditions, " AND ") + `
+			ORDER BY timestamp, created_at DESC, source`
+	}
+
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	forecasts := make([]models.PriceForecast, 0)
+	for rows.Next() {
+		var f models.PriceForecast
+		if err := rows.Scan(&f.Timestamp, &f.ZoneID, &f.CurrencyID, &f.Source, &f.Price, &f.HorizonHours, &f.CreatedAt); err != nil {
+			return nil, err
+		}
+		forecasts = append(forecasts, f)
+	}
+	return forecasts, rows.Err()
+}
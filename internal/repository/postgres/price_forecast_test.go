@@ -0,0 +1,65 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPriceForecastRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	repo := postgres.NewPriceForecastRepository(tc.DB)
+	ctx := context.Background()
+
+	zone := tc.CreateTestZone("test-zone-forecast", "Europe/Stockholm")
+	currency := tc.CreateTestCurrency("NZD")
+
+	start := time.Date(2025, 3, 21, 0, 0, 0, 0, time.UTC)
+	forecast := func(source string, hour int, price float64) models.PriceForecast {
+		return models.PriceForecast{
+			Timestamp: start.Add(time.Duration(hour) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID,
+			Source: source, Price: price, HorizonHours: 24 + hour,
+		}
+	}
+	require.NoError(t, repo.Upsert(ctx, []models.PriceForecast{forecast("model", 0, 10), forecast("model", 1, 20)}))
+	time.Sleep(10 * time.Millisecond)
+	require.NoError(t, repo.Upsert(ctx, []models.PriceForecast{forecast("other", 1, 21)}))
+
+	// A newer forecast of a source replaces its earlier one
+	replaced := forecast("model", 0, 12)
+	replaced.HorizonHours = 6
+	require.NoError(t, repo.Upsert(ctx, []models.PriceForecast{replaced}))
+	require.False(t, replaced.CreatedAt.IsZero())
+
+	filter := repository.PriceForecastFilter{ZoneID: zone.ID, CurrencyID: currency.ID, StartTime: start, EndTime: start.Add(24 * time.Hour)}
+	forecasts, err := repo.List(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, forecasts, 3)
+	require.Equal(t, 12.0, forecasts[0].Price)
+	require.Equal(t, 6, forecasts[0].HorizonHours)
+	require.Equal(t, "model", forecasts[1].Source)
+	require.Equal(t, "other", forecasts[2].Source)
+
+	filter.Latest = true
+	forecasts, err = repo.List(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, forecasts, 2)
+	require.Equal(t, 21.0, forecasts[1].Price)
+
+	filter.Source = "model"
+	forecasts, err = repo.List(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, forecasts, 2)
+	require.Equal(t, 20.0, forecasts[1].Price)
+
+	unknown := forecast("model", 2, 30)
+	unknown.ZoneID = uuid.New()
+	require.ErrorIs(t, repo.Upsert(ctx, []models.PriceForecast{unknown}), repository.ErrNotFound)
+}
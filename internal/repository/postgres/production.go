@@ -0,0 +1,155 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type productionRepository struct {
+	repository.BaseRepository
+}
+
+// NewProductionRepository creates a new PostgreSQL production repository
+func NewProductionRepository(db *sql.DB) repository.ProductionRepository {
+	return NewProductionRepositoryWithReplicas(db, nil)
+}
+
+// NewProductionRepositoryWithReplicas creates a new PostgreSQL production repository
+// reading listings from replicas, nil reads them from db
+func NewProductionRepositoryWithReplicas(db *sql.DB, replicas *repository.ReplicaSet) repository.ProductionRepository {
+	return &productionRepository{
+		BaseRepository: repository.NewBaseRepositoryWithReplicas(db, replicas),
+	}
+}
+
+func (r *productionRepository) CreateBatch(ctx context.Context, records []models.ProductionRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+
+	valueStrings := make([]string, 0, len(records))
+	valueArgs := make([]interface{}, 0, len(records)*7)
+	now := time.Now()
+
+	for i, record := range records {
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
+			i*7+1, i*7+2, i*7+3, i*7+4, i*7+5, i*7+6, i*7+7))
+		valueArgs = append(valueArgs,
+			record.UserID,
+			record.MeterID,
+			record.Timestamp,
+			record.ProducedKWh,
+			record.ExportedKWh,
+			now,
+			now,
+		)
+	}
+
+	query := fmt.Sprintf(`
+		INSERT INTO production_records (user_id, meter_id, timestamp, produced_kwh, exported_kwh, created_at, updated_at)
+		VALUES %s
+		ON CONFLICT (user_id, meter_id, timestamp) DO UPDATE
+		SET produced_kwh = EXCLUDED.produced_kwh,
+			exported_kwh = EXCLUDED.exported_kwh,
+			updated_at = EXCLUDED.updated_at
+		RETURNING created_at, updated_at`, strings.Join(valueStrings, ","))
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, valueArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	// Rows are returned in the order of the values
+	i := 0
+	for rows.Next() {
+		if err := rows.Scan(&records[i].CreatedAt, &records[i].UpdatedAt); err != nil {
+			return err
+		}
+		i++
+	}
+
+	return rows.Err()
+}
+
+func (r *productionRepository) List(ctx context.Context, filter repository.ProductionFilter) ([]models.ProductionRecord, error) {
+	query, args := productionListQuery(filter)
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	records := make([]models.ProductionRecord, 0)
+	for rows.Next() {
+		var record models.ProductionRecord
+		if err := rows.Scan(
+			&record.UserID,
+			&record.MeterID,
+			&record.Timestamp,
+			&record.ProducedKWh,
+			&record.ExportedKWh,
+			&record.CreatedAt,
+			&record.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
+
+func (r *productionRepository) Total(ctx context.Context, filter repository.ProductionFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := productionListQuery(filter)
+	return countRows(ctx, r.Reader(ctx), query, args)
+}
+
+// productionListQuery builds the query selecting the production records matching the filter
+func productionListQuery(filter repository.ProductionFilter) (string, []interface{}) {
+	conditions := []string{"user_id = $1"}
+	args := []interface{}{filter.UserID}
+	argCount := 2
+
+	if filter.MeterID != nil {
+		conditions = append(conditions, fmt.Sprintf("meter_id = $%d", argCount))
+		args = append(args, *filter.MeterID)
+		argCount++
+	}
+
+	if filter.StartTime != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp >= $%d", argCount))
+		args = append(args, *filter.StartTime)
+		argCount++
+	}
+
+	if filter.EndTime != nil {
+		conditions = append(conditions, fmt.Sprintf("timestamp <= $%d", argCount))
+		args = append(args, *filter.EndTime)
+		argCount++
+	}
+
+	query := `
+		SELECT user_id, meter_id, timestamp, produced_kwh, exported_kwh, created_at, updated_at
+		FROM production_records
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		ORDER BY timestamp ASC, meter_id ASC`
+
+	if filter.Limit != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, *filter.Limit)
+		argCount++
+	}
+
+	if filter.Offset != nil {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, *filter.Offset)
+	}
+
+	return query, args
+}
@@ -0,0 +1,56 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProductionRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewProductionRepository(tc.DB)
+
+	alice := tc.CreateTestUser("alice", "alice@test.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@test.com", "password123", false)
+
+	start := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	records := []models.ProductionRecord{
+		{UserID: alice.ID, MeterID: "roof", Timestamp: start, ProducedKWh: 3, ExportedKWh: 2},
+		{UserID: alice.ID, MeterID: "roof", Timestamp: start.Add(time.Hour), ProducedKWh: 2.5, ExportedKWh: 1.5},
+		{UserID: bob.ID, MeterID: "roof", Timestamp: start, ProducedKWh: 4, ExportedKWh: 4},
+	}
+	require.NoError(t, repo.CreateBatch(ctx, records))
+	for _, record := range records {
+		assert.False(t, record.CreatedAt.IsZero())
+	}
+
+	// Records of the same meter and time are replaced
+	require.NoError(t, repo.CreateBatch(ctx, []models.ProductionRecord{
+		{UserID: alice.ID, MeterID: "roof", Timestamp: start, ProducedKWh: 3.25, ExportedKWh: 0.5},
+	}))
+
+	list, err := repo.List(ctx, repository.ProductionFilter{UserID: alice.ID})
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	assert.Equal(t, 3.25, list[0].ProducedKWh)
+	assert.Equal(t, 0.5, list[0].ExportedKWh)
+	assert.True(t, start.Add(time.Hour).Equal(list[1].Timestamp))
+
+	end := start
+	list, err = repo.List(ctx, repository.ProductionFilter{UserID: alice.ID, StartTime: &start, EndTime: &end})
+	require.NoError(t, err)
+	require.Len(t, list, 1)
+
+	limit := 1
+	total, err := repo.Total(ctx, repository.ProductionFilter{UserID: alice.ID, Limit: &limit})
+	require.NoError(t, err)
+	assert.Equal(t, 2, total)
+}
@@ -21,10 +21,10 @@ func NewRefreshTokenRepository(db *sql.DB) repository.RefreshTokenRepository {
 	}
 }
 
-func (r *refreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) error {
+func (r *refreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, client models.SessionClient) error {
 	// First verify the user exists
 	var exists bool
-	err := r.DB().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return err
 	}
@@ -34,41 +34,37 @@ func (r *refreshTokenRepository) Create(ctx context.Context, userID uuid.UUID, t
 
 	query := `
 		INSERT INTO refresh_tokens (
-			id, user_id, token, expires_at, created_at
+			id, user_id, token, family_id, user_agent, ip_address,
+			session_created_at, last_used_at, expires_at, created_at
 		) VALUES (
-			$1, $2, $3, $4, $5
+			$1, $2, $3, $1, $4, $5, $6, $6, $7, $6
 		)`
 
 	id := uuid.New()
 	now := time.Now()
 
-	_, err = r.DB().ExecContext(ctx, query,
+	_, err = r.Conn(ctx).ExecContext(ctx, query,
 		id,
 		userID,
 		token,
-		expiresAt,
+		client.UserAgent,
+		client.IPAddress,
 		now,
+		expiresAt,
 	)
 
 	return err
 }
 
+const refreshTokenColumns = `id, user_id, token, family_id, revoked_at, replaced_by,
+	COALESCE(user_agent, ''), COALESCE(ip_address, ''), session_created_at, last_used_at, expires_at, created_at`
+
 func (r *refreshTokenRepository) GetByToken(ctx context.Context, token string) (*models.RefreshToken, error) {
 	refreshToken := &models.RefreshToken{}
-	query := `
-		SELECT id, user_id, token, expires_at, created_at
-		FROM refresh_tokens
-		WHERE token = $1`
-
-	err := r.DB().QueryRowContext(ctx, query, token).Scan(
-		&refreshToken.ID,
-		&refreshToken.UserID,
-		&refreshToken.Token,
-		&refreshToken.ExpiresAt,
-		&refreshToken.CreatedAt,
-	)
+	query := `SELECT ` + refreshTokenColumns + ` FROM refresh_tokens WHERE token = $1`
 
-	if err == sql.ErrNoRows {
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, token), refreshToken)
+	if err == sql.ErrNoRows || (err == nil && refreshToken.RevokedAt != nil) {
 		return nil, repository.ErrTokenInvalid
 	}
 	if err != nil {
@@ -85,7 +81,7 @@ func (r *refreshTokenRepository) GetByToken(ctx context.Context, token string) (
 func (r *refreshTokenRepository) GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.RefreshToken, error) {
 	// First verify the user exists
 	var exists bool
-	err := r.DB().QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
 	if err != nil {
 		return nil, err
 	}
@@ -94,12 +90,12 @@ func (r *refreshTokenRepository) GetByUserID(ctx context.Context, userID uuid.UU
 	}
 
 	query := `
-		SELECT id, user_id, token, expires_at, created_at
+		SELECT ` + refreshTokenColumns + `
 		FROM refresh_tokens
 		WHERE user_id = $1
 		ORDER BY created_at DESC`
 
-	rows, err := r.DB().QueryContext(ctx, query, userID)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID)
 	if err != nil {
 		return nil, err
 	}
@@ -108,14 +104,7 @@ func (r *refreshTokenRepository) GetByUserID(ctx context.Context, userID uuid.UU
 	var tokens []models.RefreshToken
 	for rows.Next() {
 		var rt models.RefreshToken
-		err := rows.Scan(
-			&rt.ID,
-			&rt.UserID,
-			&rt.Token,
-			&rt.ExpiresAt,
-			&rt.CreatedAt,
-		)
-		if err != nil {
+		if err := r.scan(rows, &rt); err != nil {
 			return nil, err
 		}
 		tokens = append(tokens, rt)
@@ -130,7 +119,7 @@ func (r *refreshTokenRepository) GetByUserID(ctx context.Context, userID uuid.UU
 
 func (r *refreshTokenRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	query := `DELETE FROM refresh_tokens WHERE id = $1`
-	result, err := r.DB().ExecContext(ctx, query, id)
+	result, err := r.Conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -149,7 +138,7 @@ func (r *refreshTokenRepository) Delete(ctx context.Context, id uuid.UUID) error
 
 func (r *refreshTokenRepository) DeleteByToken(ctx context.Context, token string) error {
 	query := `DELETE FROM refresh_tokens WHERE token = $1`
-	result, err := r.DB().ExecContext(ctx, query, token)
+	result, err := r.Conn(ctx).ExecContext(ctx, query, token)
 	if err != nil {
 		return err
 	}
@@ -168,24 +157,28 @@ func (r *refreshTokenRepository) DeleteByToken(ctx context.Context, token string
 
 func (r *refreshTokenRepository) DeleteByUserID(ctx context.Context, userID uuid.UUID) error {
 	query := `DELETE FROM refresh_tokens WHERE user_id = $1`
-	_, err := r.DB().ExecContext(ctx, query, userID)
+	_, err := r.Conn(ctx).ExecContext(ctx, query, userID)
 	return err
 }
 
-func (r *refreshTokenRepository) DeleteExpired(ctx context.Context) error {
+func (r *refreshTokenRepository) DeleteExpired(ctx context.Context, before time.Time) (int64, error) {
 	query := `DELETE FROM refresh_tokens WHERE expires_at < $1`
-	_, err := r.DB().ExecContext(ctx, query, time.Now())
-	return err
+	result, err := r.Conn(ctx).ExecContext(ctx, query, before)
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
 }
 
 func (r *refreshTokenRepository) IsValid(ctx context.Context, token string) (bool, error) {
 	query := `
-		SELECT expires_at
+		SELECT expires_at, revoked_at
 		FROM refresh_tokens
 		WHERE token = $1`
 
 	var expiresAt time.Time
-	err := r.DB().QueryRowContext(ctx, query, token).Scan(&expiresAt)
+	var revokedAt *time.Time
+	err := r.Conn(ctx).QueryRowContext(ctx, query, token).Scan(&expiresAt, &revokedAt)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return false, repository.ErrTokenInvalid
@@ -193,5 +186,176 @@ func (r *refreshTokenRepository) IsValid(ctx context.Context, token string) (boo
 		return false, err
 	}
 
-	return time.Now().Before(expiresAt), nil
+	return revokedAt == nil && time.Now().Before(expiresAt), nil
+}
+
+func (r *refreshTokenRepository) Rotate(ctx context.Context, token, newToken string, expiresAt, at time.Time, client models.SessionClient) (*models.RefreshToken, error) {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	// Locking the row makes a concurrent rotation of the same token see it revoked
+	current := &models.RefreshToken{}
+	err = r.scan(tx.QueryRowContext(ctx,
+		`SELECT `+refreshTokenColumns+` FROM refresh_tokens WHERE token = $1 FOR UPDATE`, token), current)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrTokenInvalid
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if current.ReplacedBy != nil {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE refresh_tokens SET revoked_at = $2 WHERE family_id = $1 AND revoked_at IS NULL`,
+			current.FamilyID, at); err != nil {
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		return current, repository.ErrTokenReused
+	}
+	if current.RevokedAt != nil {
+		return nil, repository.ErrTokenInvalid
+	}
+	if at.After(current.ExpiresAt) {
+		return nil, repository.ErrTokenExpired
+	}
+
+	rotated := &models.RefreshToken{
+		ID:               uuid.New(),
+		UserID:           current.UserID,
+		Token:            newToken,
+		FamilyID:         current.FamilyID,
+		UserAgent:        client.UserAgent,
+		IPAddress:        client.IPAddress,
+		SessionCreatedAt: current.SessionCreatedAt,
+		LastUsedAt:       at,
+		ExpiresAt:        expiresAt,
+		CreatedAt:        at,
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO refresh_tokens (
+			id, user_id, token, family_id, user_agent, ip_address,
+			session_created_at, last_used_at, expires_at, created_at
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $8)`,
+		rotated.ID, rotated.UserID, rotated.Token, rotated.FamilyID, rotated.UserAgent, rotated.IPAddress,
+		rotated.SessionCreatedAt, rotated.LastUsedAt, rotated.ExpiresAt); err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = $2, replaced_by = $3 WHERE id = $1`, current.ID, at, rotated.ID); err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	return rotated, nil
+}
+
+func (r *refreshTokenRepository) RevokeFamily(ctx context.Context, token string, at time.Time) error {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = $2
+		WHERE family_id = (SELECT family_id FROM refresh_tokens WHERE token = $1)
+		AND revoked_at IS NULL`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, token, at)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected > 0 {
+		return nil
+	}
+
+	// Nothing left to revoke is fine for a known token
+	var exists bool
+	if err := r.Conn(ctx).QueryRowContext(ctx, `SELECT EXISTS(SELECT 1 FROM refresh_tokens WHERE token = $1)`, token).Scan(&exists); err != nil {
+		return err
+	}
+	if !exists {
+		return repository.ErrTokenInvalid
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) RevokeByUserID(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	query := `UPDATE refresh_tokens SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`
+	_, err := r.Conn(ctx).ExecContext(ctx, query, userID, at)
+	return err
+}
+
+func (r *refreshTokenRepository) ListSessions(ctx context.Context, userID uuid.UUID, at time.Time) ([]models.Session, error) {
+	query := `
+		SELECT family_id, COALESCE(user_agent, ''), COALESCE(ip_address, ''), session_created_at, last_used_at, expires_at
+		FROM refresh_tokens
+		WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > $2
+		ORDER BY last_used_at DESC, family_id`
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID, at)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := make([]models.Session, 0)
+	for rows.Next() {
+		var session models.Session
+		if err := rows.Scan(
+			&session.ID,
+			&session.UserAgent,
+			&session.IPAddress,
+			&session.CreatedAt,
+			&session.LastUsedAt,
+			&session.ExpiresAt,
+		); err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+func (r *refreshTokenRepository) RevokeSession(ctx context.Context, userID, sessionID uuid.UUID, at time.Time) error {
+	query := `
+		UPDATE refresh_tokens SET revoked_at = $3
+		WHERE user_id = $1 AND family_id = $2 AND revoked_at IS NULL AND expires_at > $3`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, userID, sessionID, at)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *refreshTokenRepository) scan(row interface{ Scan(...interface{}) error }, refreshToken *models.RefreshToken) error {
+	return row.Scan(
+		&refreshToken.ID,
+		&refreshToken.UserID,
+		&refreshToken.Token,
+		&refreshToken.FamilyID,
+		&refreshToken.RevokedAt,
+		&refreshToken.ReplacedBy,
+		&refreshToken.UserAgent,
+		&refreshToken.IPAddress,
+		&refreshToken.SessionCreatedAt,
+		&refreshToken.LastUsedAt,
+		&refreshToken.ExpiresAt,
+		&refreshToken.CreatedAt,
+	)
 }
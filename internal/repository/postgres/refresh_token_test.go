@@ -4,6 +4,7 @@ import (
 	"context"
 	"testing"
 	"time"
+	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
 	"wattwatch/internal/repository/postgres/integration"
 
@@ -39,7 +40,7 @@ func TestRefreshTokenRepository_Create(t *testing.T) {
 			token := uuid.New().String()
 			expiresAt := time.Now().UTC().Add(24 * time.Hour)
 
-			err := tc.RefreshTokenRepo.Create(context.Background(), tt.userID, token, expiresAt)
+			err := tc.RefreshTokenRepo.Create(context.Background(), tt.userID, token, expiresAt, models.SessionClient{})
 			if tt.wantErr {
 				require.Error(t, err)
 				if tt.errType != nil {
@@ -67,13 +68,13 @@ func TestRefreshTokenRepository_GetByToken(t *testing.T) {
 	// Create a valid token
 	validToken := uuid.New().String()
 	validExpiresAt := time.Now().UTC().Add(24 * time.Hour)
-	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, validToken, validExpiresAt)
+	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, validToken, validExpiresAt, models.SessionClient{})
 	require.NoError(t, err)
 
 	// Create an expired token
 	expiredToken := uuid.New().String()
 	expiredExpiresAt := time.Now().UTC().Add(-24 * time.Hour)
-	err = tc.RefreshTokenRepo.Create(context.Background(), user.ID, expiredToken, expiredExpiresAt)
+	err = tc.RefreshTokenRepo.Create(context.Background(), user.ID, expiredToken, expiredExpiresAt, models.SessionClient{})
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -127,7 +128,7 @@ func TestRefreshTokenRepository_GetByUserID(t *testing.T) {
 	// Create a token for the user
 	token := uuid.New().String()
 	expiresAt := time.Now().UTC().Add(24 * time.Hour)
-	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt)
+	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt, models.SessionClient{})
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -175,7 +176,7 @@ func TestRefreshTokenRepository_Delete(t *testing.T) {
 	// Create a token to delete
 	token := uuid.New().String()
 	expiresAt := time.Now().UTC().Add(24 * time.Hour)
-	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt)
+	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt, models.SessionClient{})
 	require.NoError(t, err)
 
 	// Get the token ID
@@ -234,7 +235,7 @@ func TestRefreshTokenRepository_DeleteByToken(t *testing.T) {
 	// Create a token to delete
 	token := uuid.New().String()
 	expiresAt := time.Now().UTC().Add(24 * time.Hour)
-	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt)
+	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt, models.SessionClient{})
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -287,7 +288,7 @@ func TestRefreshTokenRepository_DeleteByUserID(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		token := uuid.New().String()
 		expiresAt := time.Now().UTC().Add(24 * time.Hour)
-		err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt)
+		err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt, models.SessionClient{})
 		require.NoError(t, err)
 	}
 
@@ -311,18 +312,19 @@ func TestRefreshTokenRepository_DeleteExpired(t *testing.T) {
 	for i := 0; i < 3; i++ {
 		token := uuid.New().String()
 		expiresAt := time.Now().UTC().Add(-24 * time.Hour)
-		err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt)
+		err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, token, expiresAt, models.SessionClient{})
 		require.NoError(t, err)
 	}
 
 	// Create valid token
 	validToken := uuid.New().String()
 	validExpiresAt := time.Now().UTC().Add(24 * time.Hour)
-	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, validToken, validExpiresAt)
+	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, validToken, validExpiresAt, models.SessionClient{})
 	require.NoError(t, err)
 
-	err = tc.RefreshTokenRepo.DeleteExpired(context.Background())
+	deleted, err := tc.RefreshTokenRepo.DeleteExpired(context.Background(), time.Now())
 	require.NoError(t, err)
+	require.Equal(t, int64(3), deleted)
 
 	// Verify only expired tokens were deleted
 	var count int
@@ -340,13 +342,13 @@ func TestRefreshTokenRepository_IsValid(t *testing.T) {
 	// Create a valid token
 	validToken := uuid.New().String()
 	validExpiresAt := time.Now().UTC().Add(24 * time.Hour)
-	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, validToken, validExpiresAt)
+	err := tc.RefreshTokenRepo.Create(context.Background(), user.ID, validToken, validExpiresAt, models.SessionClient{})
 	require.NoError(t, err)
 
 	// Create an expired token
 	expiredToken := uuid.New().String()
 	expiredExpiresAt := time.Now().UTC().Add(-24 * time.Hour)
-	err = tc.RefreshTokenRepo.Create(context.Background(), user.ID, expiredToken, expiredExpiresAt)
+	err = tc.RefreshTokenRepo.Create(context.Background(), user.ID, expiredToken, expiredExpiresAt, models.SessionClient{})
 	require.NoError(t, err)
 
 	tests := []struct {
@@ -392,3 +394,75 @@ func TestRefreshTokenRepository_IsValid(t *testing.T) {
 		})
 	}
 }
+
+func TestRefreshTokenRepository_Rotate(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	ctx := context.Background()
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+	now := time.Now()
+	expiresAt := now.Add(24 * time.Hour)
+
+	require.NoError(t, tc.RefreshTokenRepo.Create(ctx, user.ID, "first", expiresAt, models.SessionClient{}))
+	_, err := tc.RefreshTokenRepo.Rotate(ctx, "unknown", "other", expiresAt, now, models.SessionClient{})
+	require.ErrorIs(t, err, repository.ErrTokenInvalid)
+
+	second, err := tc.RefreshTokenRepo.Rotate(ctx, "first", "second", expiresAt, now, models.SessionClient{})
+	require.NoError(t, err)
+	require.Equal(t, user.ID, second.UserID)
+	_, err = tc.RefreshTokenRepo.GetByToken(ctx, "first")
+	require.ErrorIs(t, err, repository.ErrTokenInvalid)
+	got, err := tc.RefreshTokenRepo.GetByToken(ctx, "second")
+	require.NoError(t, err)
+	require.Equal(t, second.FamilyID, got.FamilyID)
+
+	// A second login has a family of its own
+	require.NoError(t, tc.RefreshTokenRepo.Create(ctx, user.ID, "other", expiresAt, models.SessionClient{}))
+
+	// Reusing a rotated token revokes its family only
+	reused, err := tc.RefreshTokenRepo.Rotate(ctx, "first", "third", expiresAt, now, models.SessionClient{})
+	require.ErrorIs(t, err, repository.ErrTokenReused)
+	require.Equal(t, user.ID, reused.UserID)
+	valid, err := tc.RefreshTokenRepo.IsValid(ctx, "second")
+	require.NoError(t, err)
+	require.False(t, valid)
+	valid, err = tc.RefreshTokenRepo.IsValid(ctx, "other")
+	require.NoError(t, err)
+	require.True(t, valid)
+
+	_, err = tc.RefreshTokenRepo.Rotate(ctx, "other", "later", expiresAt, expiresAt.Add(time.Second), models.SessionClient{})
+	require.ErrorIs(t, err, repository.ErrTokenExpired)
+
+	require.ErrorIs(t, tc.RefreshTokenRepo.RevokeFamily(ctx, "unknown", now), repository.ErrTokenInvalid)
+	require.NoError(t, tc.RefreshTokenRepo.RevokeFamily(ctx, "second", now))
+	require.NoError(t, tc.RefreshTokenRepo.RevokeByUserID(ctx, user.ID, now))
+	valid, err = tc.RefreshTokenRepo.IsValid(ctx, "other")
+	require.NoError(t, err)
+	require.False(t, valid)
+}
+
+func TestRefreshTokenRepository_Sessions(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	ctx := context.Background()
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+	now := time.Now()
+	expiresAt := now.Add(24 * time.Hour)
+
+	require.NoError(t, tc.RefreshTokenRepo.Create(ctx, user.ID, "laptop", expiresAt, models.SessionClient{UserAgent: "laptop", IPAddress: "192.0.2.1"}))
+	require.NoError(t, tc.RefreshTokenRepo.Create(ctx, user.ID, "expired", now.Add(-time.Hour), models.SessionClient{}))
+	rotated, err := tc.RefreshTokenRepo.Rotate(ctx, "laptop", "laptop2", expiresAt, now.Add(time.Minute), models.SessionClient{UserAgent: "laptop", IPAddress: "192.0.2.9"})
+	require.NoError(t, err)
+
+	sessions, err := tc.RefreshTokenRepo.ListSessions(ctx, user.ID, now)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	require.Equal(t, rotated.FamilyID, sessions[0].ID)
+	require.Equal(t, "192.0.2.9", sessions[0].IPAddress)
+	require.True(t, sessions[0].LastUsedAt.After(sessions[0].CreatedAt))
+
+	require.ErrorIs(t, tc.RefreshTokenRepo.RevokeSession(ctx, uuid.New(), sessions[0].ID, now), repository.ErrNotFound)
+	require.NoError(t, tc.RefreshTokenRepo.RevokeSession(ctx, user.ID, sessions[0].ID, now))
+	require.ErrorIs(t, tc.RefreshTokenRepo.RevokeSession(ctx, user.ID, sessions[0].ID, now), repository.ErrNotFound)
+	sessions, err = tc.RefreshTokenRepo.ListSessions(ctx, user.ID, now)
+	require.NoError(t, err)
+	require.Empty(t, sessions)
+}
@@ -12,6 +12,13 @@ import (
 	"github.com/google/uuid"
 )
 
+const (
+	// rolePermissions selects the permissions granted to the role of the row
+	rolePermissions = `ARRAY(SELECT permission FROM role_permissions rp WHERE rp.role_id = roles.id ORDER BY permission)`
+	// userRolePermissions selects the permissions granted to the role joined as r
+	userRolePermissions = `ARRAY(SELECT permission FROM role_permissions rp WHERE rp.role_id = r.id ORDER BY permission)`
+)
+
 type roleRepository struct {
 	repository.BaseRepository
 }
@@ -26,7 +33,7 @@ func NewRoleRepository(db *sql.DB) repository.RoleRepository {
 func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
 	// Check if role with same name exists
 	var count int
-	err := r.DB().QueryRowContext(ctx,
+	err := r.Conn(ctx).QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM roles WHERE name = $1 AND deleted_at IS NULL",
 		role.Name,
 	).Scan(&count)
@@ -44,20 +51,21 @@ func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
 		) VALUES (
 			$1, $2, $3, $4, $5, $5, NULL
 		)
-		RETURNING id, created_at, updated_at`
+		RETURNING id, token_version, created_at, updated_at`
 
 	now := time.Now()
 	role.ID = uuid.New()
+	role.Permissions = []string{}
 	role.CreatedAt = now
 	role.UpdatedAt = now
 
-	err = r.DB().QueryRowContext(ctx, query,
+	err = r.Conn(ctx).QueryRowContext(ctx, query,
 		role.ID,
 		role.Name,
 		role.IsProtected,
 		role.IsAdminGroup,
 		now,
-	).Scan(&role.ID, &role.CreatedAt, &role.UpdatedAt)
+	).Scan(&role.ID, &role.TokenVersion, &role.CreatedAt, &role.UpdatedAt)
 
 	if err != nil {
 		return err
@@ -68,7 +76,7 @@ func (r *roleRepository) Create(ctx context.Context, role *models.Role) error {
 func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
 	// Check if role exists and is not protected
 	var isProtected bool
-	err := r.DB().QueryRowContext(ctx,
+	err := r.Conn(ctx).QueryRowContext(ctx,
 		"SELECT is_protected FROM roles WHERE id = $1 AND deleted_at IS NULL",
 		role.ID,
 	).Scan(&isProtected)
@@ -84,7 +92,7 @@ func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
 
 	// Check if new name conflicts with existing role
 	var count int
-	err = r.DB().QueryRowContext(ctx,
+	err = r.Conn(ctx).QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM roles WHERE name = $1 AND id != $2 AND deleted_at IS NULL",
 		role.Name,
 		role.ID,
@@ -101,11 +109,12 @@ func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
 		SET name = $1,
 			is_protected = $2,
 			is_admin_group = $3,
+			token_version = token_version + 1,
 			updated_at = $4
 		WHERE id = $5 AND deleted_at IS NULL
-		RETURNING updated_at`
+		RETURNING token_version, updated_at`
 
-	result := r.DB().QueryRowContext(ctx, query,
+	result := r.Conn(ctx).QueryRowContext(ctx, query,
 		role.Name,
 		role.IsProtected,
 		role.IsAdminGroup,
@@ -113,7 +122,7 @@ func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
 		role.ID,
 	)
 
-	if err := result.Scan(&role.UpdatedAt); err != nil {
+	if err := result.Scan(&role.TokenVersion, &role.UpdatedAt); err != nil {
 		if err == sql.ErrNoRows {
 			return repository.ErrNotFound
 		}
@@ -125,7 +134,7 @@ func (r *roleRepository) Update(ctx context.Context, role *models.Role) error {
 func (r *roleRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	// Check if role is protected
 	var isProtected bool
-	err := r.DB().QueryRowContext(ctx,
+	err := r.Conn(ctx).QueryRowContext(ctx,
 		"SELECT is_protected FROM roles WHERE id = $1 AND deleted_at IS NULL",
 		id,
 	).Scan(&isProtected)
@@ -141,7 +150,7 @@ func (r *roleRepository) Delete(ctx context.Context, id uuid.UUID) error {
 
 	// Check if role is in use
 	var inUse bool
-	err = r.DB().QueryRowContext(ctx,
+	err = r.Conn(ctx).QueryRowContext(ctx,
 		"SELECT EXISTS(SELECT 1 FROM users WHERE role_id = $1 AND deleted_at IS NULL)",
 		id,
 	).Scan(&inUse)
@@ -159,7 +168,7 @@ func (r *roleRepository) Delete(ctx context.Context, id uuid.UUID) error {
 		RETURNING deleted_at`
 
 	now := time.Now()
-	result := r.DB().QueryRowContext(ctx, query, now, id)
+	result := r.Conn(ctx).QueryRowContext(ctx, query, now, id)
 
 	var deletedAt time.Time
 	if err := result.Scan(&deletedAt); err != nil {
@@ -174,19 +183,21 @@ func (r *roleRepository) Delete(ctx context.Context, id uuid.UUID) error {
 func (r *roleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error) {
 	role := &models.Role{}
 	query := `
-		SELECT id, name, is_protected, is_admin_group,
-			   created_at, updated_at, deleted_at
+		SELECT id, name, is_protected, is_admin_group, token_version,
+			   created_at, updated_at, deleted_at, ` + rolePermissions + `
 		FROM roles
 		WHERE id = $1 AND deleted_at IS NULL`
 
-	err := r.DB().QueryRowContext(ctx, query, id).Scan(
+	err := r.Conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&role.ID,
 		&role.Name,
 		&role.IsProtected,
 		&role.IsAdminGroup,
+		&role.TokenVersion,
 		&role.CreatedAt,
 		&role.UpdatedAt,
 		&role.DeletedAt,
+		stringArray(&role.Permissions),
 	)
 
 	if err == sql.ErrNoRows {
@@ -201,19 +212,21 @@ func (r *roleRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Rol
 func (r *roleRepository) GetByName(ctx context.Context, name string) (*models.Role, error) {
 	role := &models.Role{}
 	query := `
-		SELECT id, name, is_protected, is_admin_group,
-			   created_at, updated_at, deleted_at
+		SELECT id, name, is_protected, is_admin_group, token_version,
+			   created_at, updated_at, deleted_at, ` + rolePermissions + `
 		FROM roles
 		WHERE name = $1 AND deleted_at IS NULL`
 
-	err := r.DB().QueryRowContext(ctx, query, name).Scan(
+	err := r.Conn(ctx).QueryRowContext(ctx, query, name).Scan(
 		&role.ID,
 		&role.Name,
 		&role.IsProtected,
 		&role.IsAdminGroup,
+		&role.TokenVersion,
 		&role.CreatedAt,
 		&role.UpdatedAt,
 		&role.DeletedAt,
+		stringArray(&role.Permissions),
 	)
 
 	if err == sql.ErrNoRows {
@@ -226,6 +239,45 @@ func (r *roleRepository) GetByName(ctx context.Context, name string) (*models.Ro
 }
 
 func (r *roleRepository) List(ctx context.Context, filter repository.RoleFilter) ([]models.Role, error) {
+	query, args := roleListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var roles []models.Role
+	for rows.Next() {
+		var role models.Role
+		if err := rows.Scan(
+			&role.ID,
+			&role.Name,
+			&role.IsAdminGroup,
+			&role.IsProtected,
+			&role.TokenVersion,
+			&role.CreatedAt,
+			&role.UpdatedAt,
+			stringArray(&role.Permissions),
+		); err != nil {
+			return nil, err
+		}
+		roles = append(roles, role)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return roles, nil
+}
+
+func (r *roleRepository) Total(ctx context.Context, filter repository.RoleFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := roleListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+// roleListQuery builds the query selecting the roles matching the filter
+func roleListQuery(filter repository.RoleFilter) (string, []interface{}) {
 	conditions := make([]string, 0)
 	args := make([]interface{}, 0)
 	argCount := 1
@@ -249,7 +301,7 @@ func (r *roleRepository) List(ctx context.Context, filter repository.RoleFilter)
 	}
 
 	query := `
-		SELECT id, name, is_admin_group, is_protected, created_at, updated_at
+		SELECT id, name, is_admin_group, is_protected, token_version, created_at, updated_at, ` + rolePermissions + `
 		FROM roles
 		WHERE deleted_at IS NULL`
 
@@ -281,30 +333,85 @@ func (r *roleRepository) List(ctx context.Context, filter repository.RoleFilter)
 		args = append(args, *filter.Offset)
 	}
 
-	rows, err := r.DB().QueryContext(ctx, query, args...)
+	return query, args
+}
+
+// modifiable returns ErrNotFound when the role doesn't exist and ErrProtectedRole when it
+// is protected
+func (r *roleRepository) modifiable(ctx context.Context, id uuid.UUID) error {
+	var isProtected bool
+	err := r.Conn(ctx).QueryRowContext(ctx,
+		"SELECT is_protected FROM roles WHERE id = $1 AND deleted_at IS NULL",
+		id,
+	).Scan(&isProtected)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
 	if err != nil {
-		return nil, err
+		return err
 	}
-	defer rows.Close()
+	if isProtected {
+		return repository.ErrProtectedRole
+	}
+	return nil
+}
 
-	var roles []models.Role
-	for rows.Next() {
-		var role models.Role
-		if err := rows.Scan(
-			&role.ID,
-			&role.Name,
-			&role.IsAdminGroup,
-			&role.IsProtected,
-			&role.CreatedAt,
-			&role.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		roles = append(roles, role)
+func (r *roleRepository) GrantPermission(ctx context.Context, roleID uuid.UUID, permission string) error {
+	if err := r.modifiable(ctx, roleID); err != nil {
+		return err
 	}
 
-	if err = rows.Err(); err != nil {
-		return nil, err
+	query := `
+		WITH granted AS (
+			INSERT INTO role_permissions (role_id, permission)
+			VALUES ($1, $2)
+			ON CONFLICT DO NOTHING
+		)
+		UPDATE roles
+		SET token_version = token_version + 1,
+			updated_at = $3
+		WHERE id = $1 AND deleted_at IS NULL`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, roleID, permission, time.Now())
+	if err != nil {
+		return err
 	}
-	return roles, nil
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *roleRepository) RevokePermission(ctx context.Context, roleID uuid.UUID, permission string) error {
+	if err := r.modifiable(ctx, roleID); err != nil {
+		return err
+	}
+
+	query := `
+		WITH revoked AS (
+			DELETE FROM role_permissions
+			WHERE role_id = $1 AND permission = $2
+			RETURNING role_id
+		)
+		UPDATE roles
+		SET token_version = token_version + 1,
+			updated_at = $3
+		WHERE id IN (SELECT role_id FROM revoked)`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, roleID, permission, time.Now())
+	if err != nil {
+		return err
+	}
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
 }
@@ -430,3 +430,45 @@ func TestRoleRepository_List(t *testing.T) {
 		})
 	}
 }
+
+func TestRoleRepository_Permissions(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	ctx := context.Background()
+
+	role := &models.Role{Name: "price-uploader"}
+	require.NoError(t, tc.RoleRepo.Create(ctx, role))
+	require.Empty(t, role.Permissions)
+
+	// Granting twice keeps a single grant, and every change bumps the token version
+	require.NoError(t, tc.RoleRepo.GrantPermission(ctx, role.ID, models.PermissionSpotPricesWrite))
+	require.NoError(t, tc.RoleRepo.GrantPermission(ctx, role.ID, models.PermissionSpotPricesWrite))
+	require.NoError(t, tc.RoleRepo.GrantPermission(ctx, role.ID, models.PermissionZonesManage))
+
+	got, err := tc.RoleRepo.GetByID(ctx, role.ID)
+	require.NoError(t, err)
+	require.Equal(t, []string{models.PermissionSpotPricesWrite, models.PermissionZonesManage}, got.Permissions)
+	require.Equal(t, role.TokenVersion+3, got.TokenVersion)
+	require.True(t, got.Has(models.PermissionSpotPricesWrite))
+	require.False(t, got.Has(models.PermissionUsersManage))
+
+	// Users see the permissions of their role
+	user := tc.CreateTestUser("uploader", "uploader@example.com", "password123", false)
+	_, err = tc.DB.ExecContext(ctx, "UPDATE users SET role_id = $1 WHERE id = $2", role.ID, user.ID)
+	require.NoError(t, err)
+	loaded, err := tc.UserRepo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.True(t, loaded.Can(models.PermissionZonesManage))
+
+	require.NoError(t, tc.RoleRepo.RevokePermission(ctx, role.ID, models.PermissionZonesManage))
+	require.ErrorIs(t, tc.RoleRepo.RevokePermission(ctx, role.ID, models.PermissionZonesManage), repository.ErrNotFound)
+
+	got, err = tc.RoleRepo.GetByName(ctx, role.Name)
+	require.NoError(t, err)
+	require.Equal(t, []string{models.PermissionSpotPricesWrite}, got.Permissions)
+
+	// Protected and missing roles can't be changed
+	admin, err := tc.RoleRepo.GetByName(ctx, "admin")
+	require.NoError(t, err)
+	require.ErrorIs(t, tc.RoleRepo.GrantPermission(ctx, admin.ID, models.PermissionUsersManage), repository.ErrProtectedRole)
+	require.ErrorIs(t, tc.RoleRepo.GrantPermission(ctx, uuid.New(), models.PermissionUsersManage), repository.ErrNotFound)
+}
@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type securityEventRepository struct {
+	repository.BaseRepository
+}
+
+// NewSecurityEventRepository creates a new PostgreSQL security event repository
+func NewSecurityEventRepository(db *sql.DB) repository.SecurityEventRepository {
+	return &securityEventRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *securityEventRepository) Create(ctx context.Context, event *models.SecurityEvent) error {
+	event.ID = uuid.New()
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+
+	_, err := r.Conn(ctx).ExecContext(ctx, `
+		INSERT INTO security_events (id, user_id, type, ip, user_agent, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		event.ID, event.UserID, event.Type, event.IPAddress, event.UserAgent, event.CreatedAt)
+	if errorCode(err) == foreignKeyViolation {
+		return repository.ErrUserNotFound
+	}
+	return err
+}
+
+func (r *securityEventRepository) ListByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.SecurityEvent, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx, `
+		SELECT id, user_id, type, ip, user_agent, created_at
+		FROM security_events
+		WHERE user_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2 OFFSET $3`, userID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []models.SecurityEvent{}
+	for rows.Next() {
+		var event models.SecurityEvent
+		if err := rows.Scan(&event.ID, &event.UserID, &event.Type, &event.IPAddress, &event.UserAgent, &event.CreatedAt); err != nil {
+			return nil, err
+		}
+		events = append(events, event)
+	}
+	return events, rows.Err()
+}
+
+func (r *securityEventRepository) CountByUserID(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM security_events WHERE user_id = $1`, userID).Scan(&count)
+	return count, err
+}
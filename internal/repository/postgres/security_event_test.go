@@ -0,0 +1,51 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecurityEventRepository(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	ctx := context.Background()
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+	other := tc.CreateTestUser("other-user", "other@example.com", "password123", false)
+
+	now := time.Now().UTC().Truncate(time.Second)
+	for i, event := range []models.SecurityEvent{
+		{UserID: user.ID, Type: models.SecurityEventNewIP, IPAddress: "10.0.0.1", UserAgent: "Firefox"},
+		{UserID: user.ID, Type: models.SecurityEventNewDevice, IPAddress: "10.0.0.1", UserAgent: "Safari"},
+		{UserID: other.ID, Type: models.SecurityEventNewDevice, IPAddress: "10.0.0.2", UserAgent: "Chrome"},
+	} {
+		event.CreatedAt = now.Add(time.Duration(i) * time.Minute)
+		require.NoError(t, tc.SecurityEventRepo.Create(ctx, &event))
+		require.NotEqual(t, uuid.Nil, event.ID)
+	}
+
+	// The newest comes first
+	events, err := tc.SecurityEventRepo.ListByUserID(ctx, user.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, models.SecurityEventNewDevice, events[0].Type)
+	require.Equal(t, "Safari", events[0].UserAgent)
+	require.Equal(t, "10.0.0.1", events[1].IPAddress)
+
+	events, err = tc.SecurityEventRepo.ListByUserID(ctx, user.ID, 1, 1)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.Equal(t, models.SecurityEventNewIP, events[0].Type)
+
+	count, err := tc.SecurityEventRepo.CountByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, 2, count)
+
+	err = tc.SecurityEventRepo.Create(ctx, &models.SecurityEvent{UserID: uuid.New(), Type: models.SecurityEventNewIP})
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+}
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+)
+
+type settingRepository struct {
+	repository.BaseRepository
+}
+
+// NewSettingRepository creates a new PostgreSQL runtime setting repository
+func NewSettingRepository(db *sql.DB) repository.SettingRepository {
+	return &settingRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *settingRepository) Upsert(ctx context.Context, setting *models.Setting) error {
+	query := `
+		INSERT INTO settings (key, value, updated_by, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (key) DO UPDATE SET
+			value = EXCLUDED.value,
+			updated_by = EXCLUDED.updated_by,
+			updated_at = EXCLUDED.updated_at
+		RETURNING updated_at`
+
+	return r.Conn(ctx).QueryRowContext(ctx, query,
+		setting.Key,
+		setting.Value,
+		setting.UpdatedBy,
+	).Scan(&setting.UpdatedAt)
+}
+
+func (r *settingRepository) List(ctx context.Context) ([]models.Setting, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx, `
+		SELECT key, value, updated_by, updated_at
+		FROM settings
+		ORDER BY key`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := []models.Setting{}
+	for rows.Next() {
+		var s models.Setting
+		if err := rows.Scan(&s.Key, &s.Value, &s.UpdatedBy, &s.UpdatedAt); err != nil {
+			return nil, err
+		}
+		settings = append(settings, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+func (r *settingRepository) Delete(ctx context.Context, key string) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM settings WHERE key = $1`, key)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	return nil
+}
@@ -0,0 +1,37 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSettingRepository(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	repo := postgres.NewSettingRepository(tc.DB)
+	ctx := context.Background()
+
+	list, err := repo.List(ctx)
+	require.NoError(t, err)
+	require.Empty(t, list)
+
+	require.NoError(t, repo.Upsert(ctx, &models.Setting{Key: "auth.registration_open", Value: "false"}))
+	setting := &models.Setting{Key: "auth.registration_open", Value: "true"}
+	require.NoError(t, repo.Upsert(ctx, setting))
+	require.False(t, setting.UpdatedAt.IsZero())
+	require.NoError(t, repo.Upsert(ctx, &models.Setting{Key: "auth.default_role", Value: "viewer"}))
+
+	list, err = repo.List(ctx)
+	require.NoError(t, err)
+	require.Len(t, list, 2)
+	require.Equal(t, "auth.default_role", list[0].Key)
+	require.Equal(t, "true", list[1].Value, "upsert replaces the value")
+
+	require.NoError(t, repo.Delete(ctx, "auth.default_role"))
+	require.ErrorIs(t, repo.Delete(ctx, "auth.default_role"), repository.ErrNotFound)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+	"strconv"
 	"strings"
 	"time"
 	"wattwatch/internal/models"
@@ -18,8 +19,14 @@ type spotPriceRepository struct {
 
 // NewSpotPriceRepository creates a new PostgreSQL spot price repository
 func NewSpotPriceRepository(db *sql.DB) repository.SpotPriceRepository {
+	return NewSpotPriceRepositoryWithReplicas(db, nil)
+}
+
+// NewSpotPriceRepositoryWithReplicas creates a new PostgreSQL spot price repository
+// reading listings from replicas, nil reads them from db
+func NewSpotPriceRepositoryWithReplicas(db *sql.DB, replicas *repository.ReplicaSet) repository.SpotPriceRepository {
 	return &spotPriceRepository{
-		BaseRepository: repository.NewBaseRepository(db),
+		BaseRepository: repository.NewBaseRepositoryWithReplicas(db, replicas),
 	}
 }
 
@@ -29,19 +36,21 @@ func (r *spotPriceRepository) Create(ctx context.Context, spotPrice *models.Spot
 		VALUES ($1, $2, $3, $4, $5, $6, $6)
 		ON CONFLICT (timestamp, zone_id, currency_id) DO UPDATE
 		SET price = EXCLUDED.price,
-			updated_at = EXCLUDED.updated_at
+			updated_at = EXCLUDED.updated_at,
+			changed_by = $7
 		RETURNING id, created_at, updated_at`
 
 	now := time.Now()
 	spotPrice.ID = uuid.New()
 
-	err := r.DB().QueryRowContext(ctx, query,
+	err := r.Conn(ctx).QueryRowContext(ctx, query,
 		spotPrice.ID,
 		spotPrice.Timestamp,
 		spotPrice.ZoneID,
 		spotPrice.CurrencyID,
 		spotPrice.Price,
 		now,
+		repository.ChangedBy(ctx),
 	).Scan(&spotPrice.ID, &spotPrice.CreatedAt, &spotPrice.UpdatedAt)
 
 	if err != nil {
@@ -50,22 +59,49 @@ func (r *spotPriceRepository) Create(ctx context.Context, spotPrice *models.Spot
 	return nil
 }
 
+// spotPriceBatchRows is the number of spot prices upserted per statement. PostgreSQL
+// allows at most 65535 parameters in one, a little over a year of hourly prices.
+const spotPriceBatchRows = 5000
+
 func (r *spotPriceRepository) CreateBatch(ctx context.Context, spotPrices []models.SpotPrice) error {
 	if len(spotPrices) == 0 {
 		return nil
 	}
 
-	// Build the query for batch upsert
-	valueStrings := make([]string, 0, len(spotPrices))
-	valueArgs := make([]interface{}, 0, len(spotPrices)*7)
+	// Larger batches are split over several statements in one transaction, so the
+	// prices are stored all together or not at all
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
 	now := time.Now()
+	for start := 0; start < len(spotPrices); start += spotPriceBatchRows {
+		end := min(start+spotPriceBatchRows, len(spotPrices))
+		if err := upsertSpotPrices(ctx, tx, spotPrices[start:end], now); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// upsertSpotPrices upserts the spot prices in one statement and updates them to the
+// values stored
+func upsertSpotPrices(ctx context.Context, tx repository.Executor, spotPrices []models.SpotPrice, now time.Time) error {
+	// Build the query for batch upsert, the user the changes are attributed to is the
+	// first parameter
+	valueStrings := make([]string, 0, len(spotPrices))
+	valueArgs := make([]interface{}, 0, len(spotPrices)*7+1)
+	valueArgs = append(valueArgs, repository.ChangedBy(ctx))
 
 	for i, sp := range spotPrices {
 		if sp.ID == uuid.Nil {
 			sp.ID = uuid.New()
 		}
 		valueStrings = append(valueStrings, fmt.Sprintf("($%d, $%d, $%d, $%d, $%d, $%d, $%d)",
-			i*7+1, i*7+2, i*7+3, i*7+4, i*7+5, i*7+6, i*7+7))
+			i*7+2, i*7+3, i*7+4, i*7+5, i*7+6, i*7+7, i*7+8))
 		valueArgs = append(valueArgs,
 			sp.ID,
 			sp.Timestamp,
@@ -82,10 +118,11 @@ func (r *spotPriceRepository) CreateBatch(ctx context.Context, spotPrices []mode
 		VALUES %s
 		ON CONFLICT (timestamp, zone_id, currency_id) DO UPDATE
 		SET price = EXCLUDED.price,
-			updated_at = EXCLUDED.updated_at
+			updated_at = EXCLUDED.updated_at,
+			changed_by = $1
 		RETURNING id, created_at, updated_at`, strings.Join(valueStrings, ","))
 
-	rows, err := r.DB().QueryContext(ctx, query, valueArgs...)
+	rows, err := tx.QueryContext(ctx, query, valueArgs...)
 	if err != nil {
 		return err
 	}
@@ -106,17 +143,18 @@ func (r *spotPriceRepository) CreateBatch(ctx context.Context, spotPrices []mode
 func (r *spotPriceRepository) Update(ctx context.Context, spotPrice *models.SpotPrice) error {
 	query := `
 		UPDATE spot_prices
-		SET timestamp = $1, zone_id = $2, currency_id = $3, price = $4, updated_at = $5
+		SET timestamp = $1, zone_id = $2, currency_id = $3, price = $4, updated_at = $5, changed_by = $7
 		WHERE id = $6
 		RETURNING updated_at`
 
-	result := r.DB().QueryRowContext(ctx, query,
+	result := r.Conn(ctx).QueryRowContext(ctx, query,
 		spotPrice.Timestamp,
 		spotPrice.ZoneID,
 		spotPrice.CurrencyID,
 		spotPrice.Price,
 		time.Now(),
 		spotPrice.ID,
+		repository.ChangedBy(ctx),
 	)
 
 	if err := result.Scan(&spotPrice.UpdatedAt); err != nil {
@@ -129,18 +167,20 @@ func (r *spotPriceRepository) Update(ctx context.Context, spotPrice *models.Spot
 }
 
 func (r *spotPriceRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	query := `DELETE FROM spot_prices WHERE id = $1`
-	result, err := r.DB().ExecContext(ctx, query, id)
-	if err != nil {
-		return err
-	}
-
-	rowsAffected, err := result.RowsAffected()
-	if err != nil {
+	// The revisions of the spot price are deleted with it
+	query := `
+		WITH deleted AS (
+			DELETE FROM spot_prices WHERE id = $1 RETURNING id
+		), revisions AS (
+			DELETE FROM spot_price_revisions WHERE spot_price_id IN (SELECT id FROM deleted)
+		)
+		SELECT COUNT(*) FROM deleted`
+	var deleted int
+	if err := r.Conn(ctx).QueryRowContext(ctx, query, id).Scan(&deleted); err != nil {
 		return err
 	}
 
-	if rowsAffected == 0 {
+	if deleted == 0 {
 		return repository.ErrNotFound
 	}
 	return nil
@@ -153,7 +193,7 @@ func (r *spotPriceRepository) GetByID(ctx context.Context, id uuid.UUID) (*model
 		WHERE id = $1`
 
 	spotPrice := &models.SpotPrice{}
-	err := r.DB().QueryRowContext(ctx, query, id).Scan(
+	err := r.Conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&spotPrice.ID,
 		&spotPrice.Timestamp,
 		&spotPrice.ZoneID,
@@ -173,6 +213,66 @@ func (r *spotPriceRepository) GetByID(ctx context.Context, id uuid.UUID) (*model
 }
 
 func (r *spotPriceRepository) List(ctx context.Context, filter repository.SpotPriceFilter) ([]models.SpotPrice, error) {
+	var spotPrices []models.SpotPrice
+	err := r.Each(ctx, filter, func(sp *models.SpotPrice) error {
+		spotPrices = append(spotPrices, *sp)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return spotPrices, nil
+}
+
+func (r *spotPriceRepository) Total(ctx context.Context, filter repository.SpotPriceFilter) (int, error) {
+	filter.Limit, filter.Offset, filter.After = nil, nil, nil
+	query, args := spotPriceListQuery(filter)
+	return countRows(ctx, r.Reader(ctx), query, args)
+}
+
+func (r *spotPriceRepository) LastModified(ctx context.Context, filter repository.SpotPriceFilter) (time.Time, int, error) {
+	filter.Limit, filter.Offset, filter.After = nil, nil, nil
+	query, args := spotPriceListQuery(filter)
+	var lastModified sql.NullTime
+	var count int
+	err := r.Reader(ctx).QueryRowContext(ctx, "SELECT MAX(updated_at), COUNT(*) FROM ("+query+") matching", args...).
+		Scan(&lastModified, &count)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	return lastModified.Time, count, nil
+}
+
+func (r *spotPriceRepository) Each(ctx context.Context, filter repository.SpotPriceFilter, fn func(*models.SpotPrice) error) error {
+	query, args := spotPriceListQuery(filter)
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var sp models.SpotPrice
+	for rows.Next() {
+		if err := rows.Scan(
+			&sp.ID,
+			&sp.Timestamp,
+			&sp.ZoneID,
+			&sp.CurrencyID,
+			&sp.Price,
+			&sp.CreatedAt,
+			&sp.UpdatedAt,
+		); err != nil {
+			return err
+		}
+		if err := fn(&sp); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// spotPriceListQuery builds the query selecting the spot prices matching the filter
+func spotPriceListQuery(filter repository.SpotPriceFilter) (string, []interface{}) {
 	conditions := make([]string, 0)
 	args := make([]interface{}, 0)
 	argCount := 1
@@ -201,24 +301,50 @@ func (r *spotPriceRepository) List(ctx context.Context, filter repository.SpotPr
 		argCount++
 	}
 
+	if filter.After != nil {
+		op := ">"
+		if filter.OrderDesc {
+			op = "<"
+		}
+		conditions = append(conditions, fmt.Sprintf("(timestamp, zone_id) %s ($%d, $%d)", op, argCount, argCount+1))
+		args = append(args, filter.After.Timestamp, filter.After.ZoneID)
+		argCount += 2
+	}
+
+	price := "price"
+	if filter.AsOf != nil {
+		// A price changed since was the one its first later revision replaced
+		conditions = append(conditions, fmt.Sprintf("created_at <= $%d", argCount))
+		price = fmt.Sprintf(`COALESCE((
+			SELECT r.price FROM spot_price_revisions r
+			WHERE r.spot_price_id = spot_prices.id AND r.changed_at > $%d
+			ORDER BY r.changed_at
+			LIMIT 1
+		), spot_prices.price) AS price`, argCount)
+		args = append(args, *filter.AsOf)
+		argCount++
+	}
+
 	query := `
-		SELECT id, timestamp, zone_id, currency_id, price, created_at, updated_at
+		SELECT id, timestamp, zone_id, currency_id, ` + price + `, created_at, updated_at
 		FROM spot_prices`
 
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	// Add ORDER BY clause
+	// Add ORDER BY clause, prices sharing a timestamp are ordered by zone so pages are stable
 	if filter.OrderBy != "" {
-		query += fmt.Sprintf(" ORDER BY %s", filter.OrderBy)
+		direction := " ASC"
 		if filter.OrderDesc {
-			query += " DESC"
-		} else {
-			query += " ASC"
+			direction = " DESC"
+		}
+		query += fmt.Sprintf(" ORDER BY %s%s", filter.OrderBy, direction)
+		if filter.OrderBy == "timestamp" {
+			query += ", zone_id" + direction
 		}
 	} else {
-		query += " ORDER BY timestamp DESC"
+		query += " ORDER BY timestamp DESC, zone_id DESC"
 	}
 
 	// Add LIMIT and OFFSET
@@ -233,31 +359,195 @@ func (r *spotPriceRepository) List(ctx context.Context, filter repository.SpotPr
 		args = append(args, *filter.Offset)
 	}
 
-	rows, err := r.DB().QueryContext(ctx, query, args...)
+	return query, args
+}
+
+func (r *spotPriceRepository) Aggregate(ctx context.Context, filter repository.SpotPriceAggregateFilter) ([]models.SpotPriceAggregate, error) {
+	if !filter.GroupBy.Valid() {
+		return nil, fmt.Errorf("invalid aggregation period %q", filter.GroupBy)
+	}
+
+	// The period names are the date_trunc fields. Truncating the local time keeps buckets
+	// aligned with the zone's midnight across daylight saving changes.
+	conditions := []string{"sp.timestamp >= $2", "sp.timestamp < $3"}
+	args := []interface{}{string(filter.GroupBy), filter.StartTime, filter.EndTime}
+	if filter.ZoneID != nil {
+		args = append(args, *filter.ZoneID)
+		conditions = append(conditions, fmt.Sprintf("sp.zone_id = $%d", len(args)))
+	}
+	if filter.CurrencyID != nil {
+		args = append(args, *filter.CurrencyID)
+		conditions = append(conditions, fmt.Sprintf("sp.currency_id = $%d", len(args)))
+	}
+
+	query := `
+		SELECT
+			date_trunc($1, sp.timestamp AT TIME ZONE z.timezone) AT TIME ZONE z.timezone AS bucket,
+			z.id, z.name, c.id, c.name,
+			AVG(sp.price), MIN(sp.price), MAX(sp.price),
+			COALESCE(STDDEV_POP(sp.price), 0), COUNT(*)
+		FROM spot_prices sp
+		JOIN zones z ON z.id = sp.zone_id
+		JOIN currencies c ON c.id = sp.currency_id
+		WHERE ` + strings.Join(conditions, " AND ") + `
+		GROUP BY z.id, z.name, c.id, c.name, bucket
+		ORDER BY z.name, c.name, bucket`
+
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	var spotPrices []models.SpotPrice
+	aggregates := make([]models.SpotPriceAggregate, 0)
 	for rows.Next() {
-		var sp models.SpotPrice
-		if err := rows.Scan(
-			&sp.ID,
-			&sp.Timestamp,
-			&sp.ZoneID,
-			&sp.CurrencyID,
-			&sp.Price,
-			&sp.CreatedAt,
-			&sp.UpdatedAt,
-		); err != nil {
+		var a models.SpotPriceAggregate
+		if err := rows.Scan(&a.Start, &a.ZoneID, &a.Zone, &a.CurrencyID, &a.Currency,
+			&a.Avg, &a.Min, &a.Max, &a.StdDev, &a.Count); err != nil {
 			return nil, err
 		}
-		spotPrices = append(spotPrices, sp)
+		aggregates = append(aggregates, a)
+	}
+	return aggregates, rows.Err()
+}
+
+func (r *spotPriceRepository) CheapestPrices(ctx context.Context, filter repository.SpotPriceWindowFilter) ([]models.SpotPrice, time.Duration, error) {
+	// The resolution is the shortest gap between consecutive prices
+	var gap sql.NullFloat64
+	err := r.Reader(ctx).QueryRowContext(ctx, `
+		SELECT EXTRACT(EPOCH FROM MIN(gap))
+		FROM (
+			SELECT timestamp - LAG(timestamp) OVER (ORDER BY timestamp) AS gap
+			FROM spot_prices
+			WHERE zone_id = $1 AND currency_id = $2 AND timestamp >= $3 AND timestamp < $4
+		) gaps`,
+		filter.ZoneID, filter.CurrencyID, filter.StartTime, filter.EndTime,
+	).Scan(&gap)
+	if err != nil {
+		return nil, 0, err
+	}
+	resolution := time.Hour
+	if gap.Valid {
+		resolution = time.Duration(gap.Float64 * float64(time.Second))
+	}
+	n := int((filter.Duration + resolution - 1) / resolution)
+	if n < 1 {
+		return nil, resolution, nil
+	}
+
+	// Prices must stop applying by the end of the range
+	args := []interface{}{filter.ZoneID, filter.CurrencyID, filter.StartTime, filter.EndTime.Add(-resolution), n}
+	var query string
+	if filter.Contiguous {
+		// A run of n prices whose last is n-1 resolutions after its first has no gaps, as
+		// no gap is shorter than the resolution
+		args = append(args, resolution.Seconds())
+		query = `
+			WITH candidates AS (
+				SELECT timestamp,
+					LEAD(timestamp, $5 - 1) OVER w AS last,
+					SUM(price) OVER (w ROWS BETWEEN CURRENT ROW AND ` + strconv.Itoa(n-1) + ` FOLLOWING) AS total
+				FROM spot_prices
+				WHERE zone_id = $1 AND currency_id = $2 AND timestamp >= $3 AND timestamp <= $4
+				WINDOW w AS (ORDER BY timestamp)
+			), cheapest AS (
+				SELECT timestamp AS first, last
+				FROM candidates
+				WHERE last = timestamp + ($5 - 1) * make_interval(secs => $6)
+				ORDER BY total, timestamp
+				LIMIT 1
+			)
+			SELECT sp.id, sp.timestamp, sp.zone_id, sp.currency_id, sp.price, sp.created_at, sp.updated_at
+			FROM spot_prices sp, cheapest
+			WHERE sp.zone_id = $1 AND sp.currency_id = $2
+				AND sp.timestamp >= cheapest.first AND sp.timestamp <= cheapest.last
+			ORDER BY sp.timestamp`
+	} else {
+		query = `
+			SELECT id, timestamp, zone_id, currency_id, price, created_at, updated_at
+			FROM (
+				SELECT *, COUNT(*) OVER () AS available
+				FROM spot_prices
+				WHERE zone_id = $1 AND currency_id = $2 AND timestamp >= $3 AND timestamp <= $4
+				ORDER BY price, timestamp
+				LIMIT $5
+			) cheapest
+			WHERE available >= $5
+			ORDER BY timestamp`
+	}
+
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+
+	prices := make([]models.SpotPrice, 0, n)
+	for rows.Next() {
+		var sp models.SpotPrice
+		if err := rows.Scan(&sp.ID, &sp.Timestamp, &sp.ZoneID, &sp.CurrencyID, &sp.Price, &sp.CreatedAt, &sp.UpdatedAt); err != nil {
+			return nil, 0, err
+		}
+		prices = append(prices, sp)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+	if len(prices) < n {
+		return nil, resolution, nil
+	}
+	return prices, resolution, nil
+}
+
+func (r *spotPriceRepository) Compare(ctx context.Context, filter repository.SpotPriceCompareFilter) ([]models.SpotPricePeriodComparison, error) {
+	starts := make([]time.Time, len(filter.Periods))
+	ends := make([]time.Time, len(filter.Periods))
+	for i, p := range filter.Periods {
+		starts[i], ends[i] = p.StartTime, p.EndTime
 	}
 
-	if err = rows.Err(); err != nil {
+	// The lateral subquery reads each period on its own so only its chunks are scanned,
+	// the window compares the first period with all of them in the same round trip
+	query := `
+		WITH stats AS (
+			SELECT p.position, p.start_time, p.end_time, s.avg, s.min, s.max, s.count
+			FROM unnest($3::timestamptz[], $4::timestamptz[]) WITH ORDINALITY AS p(start_time, end_time, position)
+			CROSS JOIN LATERAL (
+				SELECT AVG(price) AS avg, MIN(price) AS min, MAX(price) AS max, COUNT(*) AS count
+				FROM spot_prices
+				WHERE zone_id = $1 AND currency_id = $2 AND timestamp >= p.start_time AND timestamp < p.end_time
+			) s
+		), changes AS (
+			SELECT *,
+				FIRST_VALUE(avg) OVER w - avg AS avg_change,
+				FIRST_VALUE(min) OVER w - min AS min_change,
+				FIRST_VALUE(max) OVER w - max AS max_change
+			FROM stats
+			WINDOW w AS (ORDER BY position)
+		)
+		SELECT start_time, end_time, avg, min, max, count,
+			avg_change, ROUND(avg_change / NULLIF(ABS(avg), 0) * 100, 2),
+			min_change, ROUND(min_change / NULLIF(ABS(min), 0) * 100, 2),
+			max_change, ROUND(max_change / NULLIF(ABS(max), 0) * 100, 2)
+		FROM changes
+		ORDER BY position`
+
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, filter.ZoneID, filter.CurrencyID, starts, ends)
+	if err != nil {
 		return nil, err
 	}
-	return spotPrices, nil
+	defer rows.Close()
+
+	comparisons := make([]models.SpotPricePeriodComparison, 0, len(filter.Periods))
+	for rows.Next() {
+		var c models.SpotPricePeriodComparison
+		if err := rows.Scan(&c.Start, &c.End, &c.Avg, &c.Min, &c.Max, &c.Count,
+			&c.AvgChange, &c.AvgChangePercent,
+			&c.MinChange, &c.MinChangePercent,
+			&c.MaxChange, &c.MaxChangePercent); err != nil {
+			return nil, err
+		}
+		comparisons = append(comparisons, c)
+	}
+	return comparisons, rows.Err()
 }
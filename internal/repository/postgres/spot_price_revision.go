@@ -0,0 +1,62 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type spotPriceRevisionRepository struct {
+	repository.BaseRepository
+}
+
+// NewSpotPriceRevisionRepository creates a new PostgreSQL spot price revision repository
+func NewSpotPriceRevisionRepository(db *sql.DB) repository.SpotPriceRevisionRepository {
+	return &spotPriceRevisionRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *spotPriceRevisionRepository) ListBySpotPriceID(ctx context.Context, spotPriceID uuid.UUID, limit, offset int) ([]models.SpotPriceRevision, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx, `
+		SELECT id, spot_price_id, timestamp, zone_id, currency_id, price, new_price,
+			changed_by, COALESCE(source, ''), changed_at
+		FROM spot_price_revisions
+		WHERE spot_price_id = $1
+		ORDER BY changed_at DESC
+		LIMIT $2 OFFSET $3`, spotPriceID, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	revisions := []models.SpotPriceRevision{}
+	for rows.Next() {
+		var revision models.SpotPriceRevision
+		if err := rows.Scan(
+			&revision.ID,
+			&revision.SpotPriceID,
+			&revision.Timestamp,
+			&revision.ZoneID,
+			&revision.CurrencyID,
+			&revision.Price,
+			&revision.NewPrice,
+			&revision.ChangedBy,
+			&revision.Source,
+			&revision.ChangedAt,
+		); err != nil {
+			return nil, err
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, rows.Err()
+}
+
+func (r *spotPriceRevisionRepository) CountBySpotPriceID(ctx context.Context, spotPriceID uuid.UUID) (int, error) {
+	var count int
+	err := r.Conn(ctx).QueryRowContext(ctx, `SELECT COUNT(*) FROM spot_price_revisions WHERE spot_price_id = $1`, spotPriceID).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,75 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpotPriceRevisionRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewSpotPriceRevisionRepository(tc.DB)
+	spotPrices := postgres.NewSpotPriceRepository(tc.DB)
+	sources := postgres.NewSpotPriceSourceRepository(tc.DB)
+
+	user := tc.CreateTestUser("reviser", "reviser@test.com", "password123", false)
+	zone := tc.CreateTestZone("test-zone", "UTC")
+	currency := tc.CreateTestCurrency("USD")
+	timestamp := time.Now().UTC().Truncate(time.Hour)
+	price := func(value float64) []models.SpotPrice {
+		return []models.SpotPrice{{Timestamp: timestamp, ZoneID: zone.ID, CurrencyID: currency.ID, Price: value}}
+	}
+
+	recorded := price(10)
+	require.NoError(t, sources.Record(ctx, "nordpool", recorded))
+	spotPrice := recorded[0]
+
+	// Storing the same price again records nothing
+	require.NoError(t, spotPrices.CreateBatch(ctx, price(10)))
+	count, err := repo.CountBySpotPriceID(ctx, spotPrice.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+
+	require.NoError(t, spotPrices.CreateBatch(repository.WithChangedBy(ctx, user.ID), price(12)))
+	require.NoError(t, sources.Record(ctx, "entsoe", price(11)))
+
+	revisions, err := repo.ListBySpotPriceID(ctx, spotPrice.ID, 10, 0)
+	require.NoError(t, err)
+	require.Len(t, revisions, 2)
+	require.Equal(t, 12.0, revisions[0].Price, "newest first")
+	require.Equal(t, 11.0, revisions[0].NewPrice)
+	require.Equal(t, "entsoe", revisions[0].Source)
+	require.Nil(t, revisions[0].ChangedBy)
+	require.Equal(t, 10.0, revisions[1].Price)
+	require.Equal(t, 12.0, revisions[1].NewPrice)
+	require.Equal(t, &user.ID, revisions[1].ChangedBy)
+	require.Empty(t, revisions[1].Source)
+
+	// Prices as of a time have the value they had then, and leave out later ones
+	asOf := func(at time.Time) []models.SpotPrice {
+		t.Helper()
+		list, err := spotPrices.List(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID, AsOf: &at})
+		require.NoError(t, err)
+		return list
+	}
+	require.Empty(t, asOf(spotPrice.CreatedAt.Add(-time.Second)))
+	require.Equal(t, 10.0, asOf(revisions[1].ChangedAt.Add(-time.Microsecond))[0].Price)
+	require.Equal(t, 12.0, asOf(revisions[1].ChangedAt)[0].Price)
+	require.Equal(t, 11.0, asOf(revisions[0].ChangedAt)[0].Price)
+	total, err := spotPrices.Total(ctx, repository.SpotPriceFilter{ZoneID: &zone.ID, AsOf: &revisions[0].ChangedAt})
+	require.NoError(t, err)
+	require.Equal(t, 1, total)
+
+	// Revisions go with their spot price
+	require.NoError(t, spotPrices.Delete(ctx, spotPrice.ID))
+	count, err = repo.CountBySpotPriceID(ctx, spotPrice.ID)
+	require.NoError(t, err)
+	require.Zero(t, count)
+}
@@ -0,0 +1,227 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type spotPriceSourceRepository struct {
+	repository.BaseRepository
+}
+
+// NewSpotPriceSourceRepository creates a new PostgreSQL spot price source repository
+func NewSpotPriceSourceRepository(db *sql.DB) repository.SpotPriceSourceRepository {
+	return &spotPriceSourceRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *spotPriceSourceRepository) Record(ctx context.Context, source string, spotPrices []models.SpotPrice) error {
+	if len(spotPrices) == 0 {
+		return nil
+	}
+
+	valueStrings := make([]string, 0, len(spotPrices))
+	valueArgs := make([]interface{}, 0, len(spotPrices)*4+2)
+	valueArgs = append(valueArgs, source, repository.ChangedBy(ctx))
+	for i, sp := range spotPrices {
+		valueStrings = append(valueStrings, fmt.Sprintf("($%d::timestamptz, $%d::uuid, $%d::uuid, $1, $%d::decimal)",
+			i*4+3, i*4+4, i*4+5, i*4+6))
+		valueArgs = append(valueArgs, sp.Timestamp, sp.ZoneID, sp.CurrencyID, sp.Price)
+	}
+
+	// Both tables are written in one statement so the spot price always matches a source.
+	// The resolved spot prices the upsert skips are read from the statement's snapshot,
+	// which they are unchanged in.
+	query := fmt.Sprintf(`
+		WITH reported AS (
+			INSERT INTO spot_price_sources (timestamp, zone_id, currency_id, source, price)
+			VALUES %s
+			ON CONFLICT (timestamp, zone_id, currency_id, source) DO UPDATE
+			SET price = EXCLUDED.price
+			RETURNING timestamp, zone_id, currency_id, price
+		), upserted AS (
+			INSERT INTO spot_prices (timestamp, zone_id, currency_id, price)
+			SELECT timestamp, zone_id, currency_id, price FROM reported
+			ON CONFLICT (timestamp, zone_id, currency_id) DO UPDATE
+			SET price = EXCLUDED.price,
+				updated_at = CURRENT_TIMESTAMP,
+				changed_by = $2,
+				change_source = $1
+			WHERE spot_prices.resolved_source IS NULL
+			RETURNING id, timestamp, zone_id, currency_id, price, created_at, updated_at
+		)
+		SELECT id, timestamp, zone_id, currency_id, price, created_at, updated_at FROM upserted
+		UNION ALL
+		SELECT p.id, p.timestamp, p.zone_id, p.currency_id, p.price, p.created_at, p.updated_at
+		FROM spot_prices p
+		JOIN reported r ON r.timestamp = p.timestamp AND r.zone_id = p.zone_id AND r.currency_id = p.currency_id
+		WHERE p.resolved_source IS NOT NULL`, strings.Join(valueStrings, ","))
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, valueArgs...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	type key struct {
+		timestamp          int64
+		zoneID, currencyID uuid.UUID
+	}
+	index := make(map[key]int, len(spotPrices))
+	for i, sp := range spotPrices {
+		index[key{sp.Timestamp.UnixNano(), sp.ZoneID, sp.CurrencyID}] = i
+	}
+	for rows.Next() {
+		var stored models.SpotPrice
+		if err := rows.Scan(&stored.ID, &stored.Timestamp, &stored.ZoneID, &stored.CurrencyID, &stored.Price, &stored.CreatedAt, &stored.UpdatedAt); err != nil {
+			return err
+		}
+		if i, ok := index[key{stored.Timestamp.UnixNano(), stored.ZoneID, stored.CurrencyID}]; ok {
+			spotPrices[i] = stored
+		}
+	}
+	return rows.Err()
+}
+
+func (r *spotPriceSourceRepository) ListConflicts(ctx context.Context, filter repository.SpotPriceConflictFilter) ([]models.SpotPriceConflict, error) {
+	query, args := spotPriceConflictListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	conflicts := make([]models.SpotPriceConflict, 0)
+	for rows.Next() {
+		var conflict models.SpotPriceConflict
+		var values []byte
+		if err := rows.Scan(
+			&conflict.Timestamp,
+			&conflict.ZoneID,
+			&conflict.CurrencyID,
+			&conflict.Price,
+			&conflict.ResolvedSource,
+			&values,
+		); err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(values, &conflict.Values); err != nil {
+			return nil, fmt.Errorf("failed to decode source values: %w", err)
+		}
+		conflicts = append(conflicts, conflict)
+	}
+	return conflicts, rows.Err()
+}
+
+func (r *spotPriceSourceRepository) TotalConflicts(ctx context.Context, filter repository.SpotPriceConflictFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := spotPriceConflictListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+// spotPriceConflictListQuery builds the query selecting the spot price conflicts matching the filter
+func spotPriceConflictListQuery(filter repository.SpotPriceConflictFilter) (string, []interface{}) {
+	conditions := make([]string, 0)
+	args := make([]interface{}, 0)
+	argCount := 1
+
+	if filter.ZoneID != nil {
+		conditions = append(conditions, fmt.Sprintf("s.zone_id = $%d", argCount))
+		args = append(args, *filter.ZoneID)
+		argCount++
+	}
+
+	if filter.CurrencyID != nil {
+		conditions = append(conditions, fmt.Sprintf("s.currency_id = $%d", argCount))
+		args = append(args, *filter.CurrencyID)
+		argCount++
+	}
+
+	// The range is repeated for spot_prices, the join alone doesn't exclude its chunks
+	if filter.StartTime != nil {
+		conditions = append(conditions, fmt.Sprintf("s.timestamp >= $%d AND p.timestamp >= $%d", argCount, argCount))
+		args = append(args, *filter.StartTime)
+		argCount++
+	}
+
+	if filter.EndTime != nil {
+		conditions = append(conditions, fmt.Sprintf("s.timestamp <= $%d AND p.timestamp <= $%d", argCount, argCount))
+		args = append(args, *filter.EndTime)
+		argCount++
+	}
+
+	if !filter.IncludeResolved {
+		conditions = append(conditions, "p.resolved_source IS NULL")
+	}
+
+	query := `
+		SELECT s.timestamp, s.zone_id, s.currency_id, p.price, COALESCE(p.resolved_source, ''),
+			json_agg(json_build_object('source', s.source, 'price', s.price, 'updated_at', s.updated_at) ORDER BY s.source)
+		FROM spot_price_sources s
+		JOIN spot_prices p ON p.timestamp = s.timestamp AND p.zone_id = s.zone_id AND p.currency_id = s.currency_id`
+
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	query += `
+		GROUP BY s.timestamp, s.zone_id, s.currency_id, p.price, p.resolved_source
+		HAVING COUNT(DISTINCT s.price) > 1
+		ORDER BY s.timestamp DESC, s.zone_id, s.currency_id`
+
+	// Add LIMIT and OFFSET
+	if filter.Limit != nil {
+		query += fmt.Sprintf(" LIMIT $%d", argCount)
+		args = append(args, *filter.Limit)
+		argCount++
+	}
+
+	if filter.Offset != nil {
+		query += fmt.Sprintf(" OFFSET $%d", argCount)
+		args = append(args, *filter.Offset)
+	}
+
+	return query, args
+}
+
+func (r *spotPriceSourceRepository) Resolve(ctx context.Context, timestamp time.Time, zoneID, currencyID uuid.UUID, source string) (*models.SpotPrice, error) {
+	query := `
+		UPDATE spot_prices p
+		SET price = s.price,
+			resolved_source = s.source,
+			updated_at = CURRENT_TIMESTAMP,
+			changed_by = $5,
+			change_source = s.source
+		FROM spot_price_sources s
+		WHERE s.timestamp = $1 AND s.zone_id = $2 AND s.currency_id = $3 AND s.source = $4
+			AND p.timestamp = s.timestamp AND p.zone_id = s.zone_id AND p.currency_id = s.currency_id
+		RETURNING p.id, p.timestamp, p.zone_id, p.currency_id, p.price, p.created_at, p.updated_at`
+
+	spotPrice := &models.SpotPrice{}
+	err := r.Conn(ctx).QueryRowContext(ctx, query, timestamp, zoneID, currencyID, source, repository.ChangedBy(ctx)).Scan(
+		&spotPrice.ID,
+		&spotPrice.Timestamp,
+		&spotPrice.ZoneID,
+		&spotPrice.CurrencyID,
+		&spotPrice.Price,
+		&spotPrice.CreatedAt,
+		&spotPrice.UpdatedAt,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return spotPrice, nil
+}
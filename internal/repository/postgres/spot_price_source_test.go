@@ -0,0 +1,71 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpotPriceSourceRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewSpotPriceSourceRepository(tc.DB)
+	spotPrices := postgres.NewSpotPriceRepository(tc.DB)
+
+	zone := tc.CreateTestZone("test-zone", "UTC")
+	currency := tc.CreateTestCurrency("USD")
+	timestamp := time.Now().UTC().Truncate(time.Hour)
+	report := func(source string, price float64) {
+		t.Helper()
+		require.NoError(t, repo.Record(ctx, source, []models.SpotPrice{
+			{Timestamp: timestamp, ZoneID: zone.ID, CurrencyID: currency.ID, Price: price},
+			{Timestamp: timestamp.Add(time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: 20},
+		}))
+	}
+
+	report("first", 10)
+	report("second", 12)
+
+	// Only the hour the sources disagree on is a conflict
+	conflicts, err := repo.ListConflicts(ctx, repository.SpotPriceConflictFilter{ZoneID: &zone.ID})
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	require.True(t, conflicts[0].Timestamp.Equal(timestamp))
+	require.Equal(t, 12.0, conflicts[0].Price)
+	require.Len(t, conflicts[0].Values, 2)
+	require.Equal(t, "first", conflicts[0].Values[0].Source)
+	require.Equal(t, 10.0, conflicts[0].Values[0].Price)
+
+	resolved, err := repo.Resolve(ctx, timestamp, zone.ID, currency.ID, "first")
+	require.NoError(t, err)
+	require.Equal(t, 10.0, resolved.Price)
+
+	// Later reports are recorded without replacing the chosen value
+	report("second", 14)
+	recorded := []models.SpotPrice{{Timestamp: timestamp, ZoneID: zone.ID, CurrencyID: currency.ID, Price: 16}}
+	require.NoError(t, repo.Record(ctx, "second", recorded))
+	require.Equal(t, resolved.ID, recorded[0].ID)
+	require.Equal(t, 10.0, recorded[0].Price)
+	stored, err := spotPrices.GetByID(ctx, resolved.ID)
+	require.NoError(t, err)
+	require.Equal(t, 10.0, stored.Price)
+
+	conflicts, err = repo.ListConflicts(ctx, repository.SpotPriceConflictFilter{ZoneID: &zone.ID})
+	require.NoError(t, err)
+	require.Empty(t, conflicts)
+
+	conflicts, err = repo.ListConflicts(ctx, repository.SpotPriceConflictFilter{ZoneID: &zone.ID, IncludeResolved: true})
+	require.NoError(t, err)
+	require.Len(t, conflicts, 1)
+	require.Equal(t, "first", conflicts[0].ResolvedSource)
+	require.Equal(t, 14.0, conflicts[0].Values[1].Price)
+
+	_, err = repo.Resolve(ctx, timestamp, zone.ID, currency.ID, "unknown")
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
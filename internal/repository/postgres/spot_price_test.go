@@ -8,6 +8,7 @@ import (
 	"wattwatch/internal/repository"
 	"wattwatch/internal/repository/postgres"
 	"wattwatch/internal/testutil"
+	"wattwatch/internal/testutil/db"
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
@@ -99,6 +100,7 @@ func TestSpotPriceRepository_CreateBatch(t *testing.T) {
 	currency := tc.CreateTestCurrency("USD")
 
 	baseTime := time.Now().UTC()
+	yearStart := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
 
 	tests := []struct {
 		name      string
@@ -170,6 +172,34 @@ func TestSpotPriceRepository_CreateBatch(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			// More rows than fit in one statement
+			name:  "Success - Leap Year Of Hourly Prices",
+			input: hourlyPrices(yearStart, 366*24, zone.ID, currency.ID),
+			checkFunc: func(t *testing.T, input []models.SpotPrice) {
+				for _, sp := range input {
+					require.NotEqual(t, uuid.Nil, sp.ID)
+					require.False(t, sp.CreatedAt.IsZero())
+				}
+				start, end := yearStart, yearStart.AddDate(1, 0, 0)
+				total, err := repo.Total(context.Background(), repository.SpotPriceFilter{ZoneID: &zone.ID, StartTime: &start, EndTime: &end})
+				require.NoError(t, err)
+				require.Equal(t, 366*24, total)
+			},
+		},
+		{
+			// The statements before the failing one are rolled back
+			name:    "Error - Invalid Zone ID In Later Statement",
+			input:   append(hourlyPrices(yearStart.AddDate(2, 0, 0), 6000, zone.ID, currency.ID), models.SpotPrice{Timestamp: yearStart, ZoneID: uuid.New(), CurrencyID: currency.ID}),
+			wantErr: true,
+			checkFunc: func(t *testing.T, input []models.SpotPrice) {
+				start := yearStart.AddDate(2, 0, 0)
+				end := start.Add(6000 * time.Hour)
+				total, err := repo.Total(context.Background(), repository.SpotPriceFilter{ZoneID: &zone.ID, StartTime: &start, EndTime: &end})
+				require.NoError(t, err)
+				require.Zero(t, total)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -179,14 +209,28 @@ func TestSpotPriceRepository_CreateBatch(t *testing.T) {
 				require.Error(t, err)
 			} else {
 				require.NoError(t, err)
-				if tt.checkFunc != nil {
-					tt.checkFunc(t, tt.input)
-				}
+			}
+			if tt.checkFunc != nil {
+				tt.checkFunc(t, tt.input)
 			}
 		})
 	}
 }
 
+// hourlyPrices returns count hourly spot prices from start
+func hourlyPrices(start time.Time, count int, zoneID, currencyID uuid.UUID) []models.SpotPrice {
+	prices := make([]models.SpotPrice, count)
+	for i := range prices {
+		prices[i] = models.SpotPrice{
+			Timestamp:  start.Add(time.Duration(i) * time.Hour),
+			ZoneID:     zoneID,
+			CurrencyID: currencyID,
+			Price:      float64(i % 100),
+		}
+	}
+	return prices
+}
+
 func TestSpotPriceRepository_Update(t *testing.T) {
 	tc := testutil.NewTestContext(t)
 	repo := postgres.NewSpotPriceRepository(tc.DB)
@@ -319,7 +363,7 @@ func TestSpotPriceRepository_List(t *testing.T) {
 	// Create test zones and currencies
 	zone1 := tc.CreateTestZone("test-zone-1", "UTC")
 	zone2 := tc.CreateTestZone("test-zone-2", "UTC")
-	currency1 := tc.CreateTestCurrency("DKK")
+	currency1 := tc.CreateTestCurrency("CAD")
 	currency2 := tc.CreateTestCurrency("AUD")
 
 	// Create test data
@@ -419,6 +463,30 @@ func TestSpotPriceRepository_List(t *testing.T) {
 			},
 			wantCount: 2,
 		},
+		{
+			name: "After Cursor",
+			filter: repository.SpotPriceFilter{
+				OrderBy: "timestamp",
+				After:   &repository.SpotPriceCursor{Timestamp: baseTime.Add(time.Hour).Truncate(time.Microsecond), ZoneID: zone1.ID},
+			},
+			wantCount: 2,
+			checkFunc: func(t *testing.T, results []models.SpotPrice) {
+				require.Equal(t, spotPrices[2].ID, results[0].ID)
+				require.Equal(t, spotPrices[3].ID, results[1].ID)
+			},
+		},
+		{
+			name: "Before Cursor Descending",
+			filter: repository.SpotPriceFilter{
+				OrderBy:   "timestamp",
+				OrderDesc: true,
+				After:     &repository.SpotPriceCursor{Timestamp: baseTime.Add(time.Hour).Truncate(time.Microsecond), ZoneID: zone1.ID},
+			},
+			wantCount: 1,
+			checkFunc: func(t *testing.T, results []models.SpotPrice) {
+				require.Equal(t, spotPrices[0].ID, results[0].ID)
+			},
+		},
 	}
 
 	for _, tt := range tests {
@@ -433,3 +501,195 @@ func TestSpotPriceRepository_List(t *testing.T) {
 		})
 	}
 }
+
+func TestSpotPriceRepository_Aggregate(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	repo := postgres.NewSpotPriceRepository(tc.DB)
+
+	zone := tc.CreateTestZone("test-zone-aggregate", "Europe/Stockholm")
+	currency := tc.CreateTestCurrency("NZD")
+
+	// Midnight in Stockholm is 23:00 UTC in winter
+	start := time.Date(2025, 1, 1, 23, 0, 0, 0, time.UTC)
+	for i, price := range []float64{10, 20, 30, 40} {
+		require.NoError(t, repo.Create(context.Background(), &models.SpotPrice{
+			Timestamp:  start.Add(time.Duration(i*12) * time.Hour),
+			ZoneID:     zone.ID,
+			CurrencyID: currency.ID,
+			Price:      price,
+		}))
+	}
+
+	aggregates, err := repo.Aggregate(context.Background(), repository.SpotPriceAggregateFilter{
+		ZoneID:    &zone.ID,
+		StartTime: start,
+		EndTime:   start.Add(48 * time.Hour),
+		GroupBy:   models.AggregateDay,
+	})
+	require.NoError(t, err)
+	require.Len(t, aggregates, 2)
+
+	require.True(t, start.Equal(aggregates[0].Start))
+	require.Equal(t, zone.Name, aggregates[0].Zone)
+	require.Equal(t, currency.Name, aggregates[0].Currency)
+	require.Equal(t, 15.0, aggregates[0].Avg)
+	require.Equal(t, 10.0, aggregates[0].Min)
+	require.Equal(t, 20.0, aggregates[0].Max)
+	require.InDelta(t, 5.0, aggregates[0].StdDev, 1e-9)
+	require.Equal(t, 2, aggregates[0].Count)
+	require.True(t, start.Add(24*time.Hour).Equal(aggregates[1].Start))
+	require.Equal(t, 35.0, aggregates[1].Avg)
+}
+
+func TestSpotPriceRepository_Compare(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	repo := postgres.NewSpotPriceRepository(tc.DB)
+
+	zone := tc.CreateTestZone("test-zone-compare", "Europe/Stockholm")
+	currency := tc.CreateTestCurrency("NZD")
+
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	weekEarlier := day.AddDate(0, 0, -7)
+	var batch []models.SpotPrice
+	for i, price := range []float64{10, 30} {
+		batch = append(batch, models.SpotPrice{Timestamp: day.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: price})
+	}
+	for i, price := range []float64{20, 40} {
+		batch = append(batch, models.SpotPrice{Timestamp: weekEarlier.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: price})
+	}
+	require.NoError(t, repo.CreateBatch(context.Background(), batch))
+
+	comparisons, err := repo.Compare(context.Background(), repository.SpotPriceCompareFilter{
+		ZoneID:     zone.ID,
+		CurrencyID: currency.ID,
+		Periods: []repository.SpotPriceRange{
+			{StartTime: day, EndTime: day.AddDate(0, 0, 1)},
+			{StartTime: weekEarlier, EndTime: weekEarlier.AddDate(0, 0, 1)},
+			{StartTime: day.AddDate(-1, 0, 0), EndTime: day.AddDate(-1, 0, 1)},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, comparisons, 3)
+
+	require.True(t, day.Equal(comparisons[0].Start))
+	require.Equal(t, 2, comparisons[0].Count)
+	require.Equal(t, 20.0, *comparisons[0].Avg)
+	require.Equal(t, 0.0, *comparisons[0].AvgChange)
+
+	week := comparisons[1]
+	require.True(t, weekEarlier.Equal(week.Start))
+	require.Equal(t, 30.0, *week.Avg)
+	require.Equal(t, -10.0, *week.AvgChange)
+	require.Equal(t, -33.33, *week.AvgChangePercent)
+	require.Equal(t, -10.0, *week.MinChange)
+	require.Equal(t, -50.0, *week.MinChangePercent)
+	require.Equal(t, -25.0, *week.MaxChangePercent)
+
+	year := comparisons[2]
+	require.Zero(t, year.Count)
+	require.Nil(t, year.Avg)
+	require.Nil(t, year.AvgChange)
+	require.Nil(t, year.AvgChangePercent)
+}
+
+func TestSpotPriceRepository_CheapestPrices(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	repo := postgres.NewSpotPriceRepository(tc.DB)
+
+	zone := tc.CreateTestZone("test-zone-cheapest", "Europe/Stockholm")
+	currency := tc.CreateTestCurrency("NZD")
+
+	// Hourly prices across the start of daylight saving time, with 05:00 UTC missing
+	start := time.Date(2025, 3, 29, 23, 0, 0, 0, time.UTC)
+	var batch []models.SpotPrice
+	for i, price := range []float64{90, 20, 10, 30, 80, 0, 5, 4} {
+		if i == 5 {
+			continue
+		}
+		batch = append(batch, models.SpotPrice{Timestamp: start.Add(time.Duration(i) * time.Hour), ZoneID: zone.ID, CurrencyID: currency.ID, Price: price})
+	}
+	require.NoError(t, repo.CreateBatch(context.Background(), batch))
+
+	filter := repository.SpotPriceWindowFilter{
+		ZoneID:     zone.ID,
+		CurrencyID: currency.ID,
+		StartTime:  start,
+		EndTime:    start.Add(8 * time.Hour),
+		Duration:   3 * time.Hour,
+		Contiguous: true,
+	}
+	prices, resolution, err := repo.CheapestPrices(context.Background(), filter)
+	require.NoError(t, err)
+	require.Equal(t, time.Hour, resolution)
+	require.Len(t, prices, 3)
+	require.True(t, start.Add(time.Hour).Equal(prices[0].Timestamp))
+	require.Equal(t, []float64{20, 10, 30}, []float64{prices[0].Price, prices[1].Price, prices[2].Price})
+
+	// The cheapest prices on either side of the gap
+	filter.Contiguous = false
+	prices, _, err = repo.CheapestPrices(context.Background(), filter)
+	require.NoError(t, err)
+	require.Equal(t, []float64{10, 5, 4}, []float64{prices[0].Price, prices[1].Price, prices[2].Price})
+
+	// The last price ends at the end of the range, there aren't enough before it
+	filter.EndTime = start.Add(3 * time.Hour)
+	filter.Duration = 4 * time.Hour
+	prices, _, err = repo.CheapestPrices(context.Background(), filter)
+	require.NoError(t, err)
+	require.Empty(t, prices)
+}
+
+// BenchmarkSpotPriceRepository_List measures the query behind the spot price list endpoint,
+// the heaviest the API runs, over a year of quarter-hourly prices
+func BenchmarkSpotPriceRepository_List(b *testing.B) {
+	cfg := testutil.LoadTestConfig(b)
+	testDB := db.SetupTestDB(b, &cfg.Database)
+	defer testDB.Close()
+
+	ctx := context.Background()
+	repo := postgres.NewSpotPriceRepository(testDB)
+	zone, err := postgres.NewZoneRepository(testDB).GetByName(ctx, "SE3")
+	require.NoError(b, err)
+	currency, err := postgres.NewCurrencyRepository(testDB).GetByName(ctx, "EUR")
+	require.NoError(b, err)
+
+	yearStart := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var prices []models.SpotPrice
+	for ts := yearStart; ts.Before(yearStart.AddDate(1, 0, 0)); ts = ts.Add(15 * time.Minute) {
+		prices = append(prices, models.SpotPrice{Timestamp: ts, ZoneID: zone.ID, CurrencyID: currency.ID, Price: float64(ts.Hour())})
+	}
+	require.NoError(b, repo.CreateBatch(ctx, prices))
+
+	limit := 100
+	offset := 5000
+	dayStart := yearStart.AddDate(0, 6, 0)
+	dayEnd := dayStart.AddDate(0, 0, 1)
+	monthEnd := dayStart.AddDate(0, 1, 0)
+	benchmarks := []struct {
+		name   string
+		filter repository.SpotPriceFilter
+	}{
+		{
+			name:   "Day",
+			filter: repository.SpotPriceFilter{ZoneID: &zone.ID, CurrencyID: &currency.ID, StartTime: &dayStart, EndTime: &dayEnd},
+		},
+		{
+			name:   "Month",
+			filter: repository.SpotPriceFilter{ZoneID: &zone.ID, CurrencyID: &currency.ID, StartTime: &dayStart, EndTime: &monthEnd},
+		},
+		{
+			name:   "Page",
+			filter: repository.SpotPriceFilter{ZoneID: &zone.ID, OrderDesc: true, Limit: &limit, Offset: &offset},
+		},
+	}
+
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := repo.List(ctx, bm.filter); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
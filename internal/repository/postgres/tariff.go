@@ -0,0 +1,195 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type tariffRepository struct {
+	repository.BaseRepository
+}
+
+// NewTariffRepository creates a new PostgreSQL tariff repository
+func NewTariffRepository(db *sql.DB) repository.TariffRepository {
+	return &tariffRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const tariffColumns = `id, user_id, organization_id, name, zone_id, currency_id, transfer_fee, energy_tax, monthly_fee, vat, export_fee, rates, created_at, updated_at`
+
+func (r *tariffRepository) Create(ctx context.Context, tariff *models.Tariff) error {
+	rates, err := marshalRates(tariff.Rates)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO tariffs (id, user_id, organization_id, name, zone_id, currency_id, transfer_fee, energy_tax, monthly_fee, vat, export_fee, rates)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12
+		WHERE $2::uuid IS NULL OR EXISTS (SELECT 1 FROM users WHERE id = $2 AND deleted_at IS NULL)
+		RETURNING ` + tariffColumns
+
+	err = r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		uuid.New(),
+		tariff.UserID,
+		tariff.OrganizationID,
+		tariff.Name,
+		tariff.ZoneID,
+		tariff.CurrencyID,
+		tariff.TransferFee,
+		tariff.EnergyTax,
+		tariff.MonthlyFee,
+		tariff.VAT,
+		tariff.ExportFee,
+		rates,
+	), tariff)
+	switch {
+	case errorCode(err) == foreignKeyViolation, err == sql.ErrNoRows:
+		return repository.ErrNotFound
+	case errorCode(err) == uniqueViolation:
+		return repository.ErrDuplicateEntry
+	}
+	return err
+}
+
+func (r *tariffRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Tariff, error) {
+	query := `SELECT ` + tariffColumns + ` FROM tariffs WHERE id = $1`
+
+	tariff := &models.Tariff{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), tariff)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tariff, nil
+}
+
+func (r *tariffRepository) ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Tariff, error) {
+	query := `
+		SELECT ` + tariffColumns + ` FROM tariffs
+		WHERE user_id = $1
+			OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = $1)
+		ORDER BY created_at, id`
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tariffs := []models.Tariff{}
+	for rows.Next() {
+		var tariff models.Tariff
+		if err := r.scan(rows, &tariff); err != nil {
+			return nil, err
+		}
+		tariffs = append(tariffs, tariff)
+	}
+	return tariffs, rows.Err()
+}
+
+func (r *tariffRepository) FindForUser(ctx context.Context, userID, zoneID, currencyID uuid.UUID) (*models.Tariff, error) {
+	query := `
+		SELECT ` + tariffColumns + ` FROM tariffs
+		WHERE zone_id = $2 AND currency_id = $3
+			AND (user_id = $1 OR organization_id IN (SELECT organization_id FROM organization_members WHERE user_id = $1))
+		ORDER BY user_id IS NULL,
+			(SELECT created_at FROM organization_members m WHERE m.organization_id = tariffs.organization_id AND m.user_id = $1),
+			id
+		LIMIT 1`
+
+	tariff := &models.Tariff{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, userID, zoneID, currencyID), tariff)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return tariff, nil
+}
+
+func (r *tariffRepository) Update(ctx context.Context, tariff *models.Tariff) error {
+	rates, err := marshalRates(tariff.Rates)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		UPDATE tariffs
+		SET name = $2, transfer_fee = $3, energy_tax = $4, monthly_fee = $5, vat = $6, export_fee = $7, rates = $8
+		WHERE id = $1
+		RETURNING ` + tariffColumns
+
+	err = r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		tariff.ID,
+		tariff.Name,
+		tariff.TransferFee,
+		tariff.EnergyTax,
+		tariff.MonthlyFee,
+		tariff.VAT,
+		tariff.ExportFee,
+		rates,
+	), tariff)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *tariffRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM tariffs WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *tariffRepository) scan(row interface{ Scan(...interface{}) error }, tariff *models.Tariff) error {
+	var rates []byte
+	if err := row.Scan(
+		&tariff.ID,
+		&tariff.UserID,
+		&tariff.OrganizationID,
+		&tariff.Name,
+		&tariff.ZoneID,
+		&tariff.CurrencyID,
+		&tariff.TransferFee,
+		&tariff.EnergyTax,
+		&tariff.MonthlyFee,
+		&tariff.VAT,
+		&tariff.ExportFee,
+		&rates,
+		&tariff.CreatedAt,
+		&tariff.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	tariff.Rates = []models.TariffRate{}
+	return json.Unmarshal(rates, &tariff.Rates)
+}
+
+// marshalRates encodes the rates of a tariff for the JSONB column
+func marshalRates(rates []models.TariffRate) (string, error) {
+	if rates == nil {
+		rates = []models.TariffRate{}
+	}
+	data, err := json.Marshal(rates)
+	return string(data), err
+}
@@ -0,0 +1,74 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTariffRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewTariffRepository(tc.DB)
+	orgs := postgres.NewOrganizationRepository(tc.DB)
+
+	alice := tc.CreateTestUser("alice", "alice@example.com", "password123", false)
+	bob := tc.CreateTestUser("bob", "bob@example.com", "password123", false)
+	zone := tc.CreateTestZone("test-zone", "Europe/Stockholm")
+	eur := tc.CreateTestCurrency("EUR")
+	sek := tc.CreateTestCurrency("SEK")
+	org := &models.Organization{Name: "Household"}
+	require.NoError(t, orgs.Create(ctx, org, alice.ID))
+	require.NoError(t, orgs.AddMember(ctx, &models.OrganizationMember{OrganizationID: org.ID, UserID: bob.ID, Role: models.OrganizationRoleMember}))
+
+	own := &models.Tariff{UserID: &alice.ID, Name: "mine", ZoneID: zone.ID, CurrencyID: eur.ID, TransferFee: 0.25, VAT: 25,
+		Rates: []models.TariffRate{{Start: "06:00", End: "22:00", Weekdays: true, Months: []int{1, 2}, TransferFee: 0.75}}}
+	require.NoError(t, repo.Create(ctx, own))
+	require.NotEqual(t, uuid.Nil, own.ID)
+	require.Len(t, own.Rates, 1)
+	require.Equal(t, []int{1, 2}, own.Rates[0].Months)
+	require.ErrorIs(t, repo.Create(ctx, &models.Tariff{UserID: &alice.ID, Name: "again", ZoneID: zone.ID, CurrencyID: eur.ID}),
+		repository.ErrDuplicateEntry)
+	require.ErrorIs(t, repo.Create(ctx, &models.Tariff{UserID: &alice.ID, Name: "unknown", ZoneID: uuid.New(), CurrencyID: eur.ID}),
+		repository.ErrNotFound)
+
+	shared := &models.Tariff{OrganizationID: &org.ID, Name: "household", ZoneID: zone.ID, CurrencyID: eur.ID, MonthlyFee: 300}
+	require.NoError(t, repo.Create(ctx, shared))
+	require.Empty(t, shared.Rates)
+
+	tariffs, err := repo.ListForUser(ctx, alice.ID)
+	require.NoError(t, err)
+	require.Len(t, tariffs, 2)
+	tariffs, err = repo.ListForUser(ctx, bob.ID)
+	require.NoError(t, err)
+	require.Len(t, tariffs, 1)
+
+	// The user's own tariff comes before the organization's
+	found, err := repo.FindForUser(ctx, alice.ID, zone.ID, eur.ID)
+	require.NoError(t, err)
+	require.Equal(t, own.ID, found.ID)
+	found, err = repo.FindForUser(ctx, bob.ID, zone.ID, eur.ID)
+	require.NoError(t, err)
+	require.Equal(t, shared.ID, found.ID)
+	_, err = repo.FindForUser(ctx, bob.ID, zone.ID, sek.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+
+	own.Name, own.EnergyTax, own.Rates = "renamed", 0.439, nil
+	require.NoError(t, repo.Update(ctx, own))
+	got, err := repo.GetByID(ctx, own.ID)
+	require.NoError(t, err)
+	require.Equal(t, "renamed", got.Name)
+	require.Equal(t, 0.439, got.EnergyTax)
+	require.Empty(t, got.Rates)
+
+	require.NoError(t, repo.Delete(ctx, own.ID))
+	require.ErrorIs(t, repo.Delete(ctx, own.ID), repository.ErrNotFound)
+	_, err = repo.GetByID(ctx, own.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+}
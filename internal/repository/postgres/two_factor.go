@@ -0,0 +1,216 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type twoFactorRepository struct {
+	repository.BaseRepository
+}
+
+// NewTwoFactorRepository creates a new PostgreSQL two-factor repository
+func NewTwoFactorRepository(db *sql.DB) repository.TwoFactorRepository {
+	return &twoFactorRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const twoFactorColumns = `user_id, secret, enabled_at, last_used_step, created_at, updated_at`
+
+func (r *twoFactorRepository) GetByUserID(ctx context.Context, userID uuid.UUID) (*models.TwoFactor, error) {
+	query := `SELECT ` + twoFactorColumns + ` FROM user_totp WHERE user_id = $1`
+
+	twoFactor := &models.TwoFactor{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, userID), twoFactor)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return twoFactor, nil
+}
+
+func (r *twoFactorRepository) Enroll(ctx context.Context, userID uuid.UUID, secret string) error {
+	// Only an unconfirmed enrollment is replaced, the update matches no row when enabled
+	query := `
+		INSERT INTO user_totp (user_id, secret) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, last_used_step = NULL
+		WHERE user_totp.enabled_at IS NULL`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, userID, secret)
+	if errorCode(err) == foreignKeyViolation {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) Enable(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string, at time.Time) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var enabledAt *time.Time
+	err = tx.QueryRowContext(ctx, `SELECT enabled_at FROM user_totp WHERE user_id = $1 FOR UPDATE`, userID).Scan(&enabledAt)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	if err != nil {
+		return err
+	}
+	if enabledAt != nil {
+		return repository.ErrConflict
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE user_totp SET enabled_at = $2, last_used_step = $3 WHERE user_id = $1`, userID, at, step); err != nil {
+		return err
+	}
+	if err := replaceBackupCodes(ctx, tx, userID, codeHashes); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// replaceBackupCodes swaps the user's backup codes for the codes hashed to codeHashes
+func replaceBackupCodes(ctx context.Context, tx repository.Executor, userID uuid.UUID, codeHashes []string) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM totp_backup_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if len(codeHashes) == 0 {
+		return nil
+	}
+	_, err := tx.ExecContext(ctx, `
+		INSERT INTO totp_backup_codes (user_id, code_hash)
+		SELECT $1, code_hash FROM unnest($2::text[]) AS code_hash`,
+		userID, codeHashes)
+	return err
+}
+
+func (r *twoFactorRepository) UseStep(ctx context.Context, userID uuid.UUID, step int64) error {
+	query := `
+		UPDATE user_totp SET last_used_step = $2
+		WHERE user_id = $1 AND (last_used_step IS NULL OR last_used_step < $2)`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, userID, step)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrConflict
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) UsePreAuthToken(ctx context.Context, userID, tokenID uuid.UUID, expiresAt time.Time) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		`DELETE FROM used_pre_auth_tokens WHERE user_id = $1 AND expires_at <= $2`, userID, time.Now()); err != nil {
+		return err
+	}
+	result, err := tx.ExecContext(ctx, `
+		INSERT INTO used_pre_auth_tokens (id, user_id, expires_at) VALUES ($1, $2, $3)
+		ON CONFLICT (id) DO NOTHING`, tokenID, userID, expiresAt)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrConflict
+	}
+	return tx.Commit()
+}
+
+func (r *twoFactorRepository) UseBackupCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) error {
+	query := `
+		UPDATE totp_backup_codes SET used_at = $3
+		WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query, userID, codeHash, at)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *twoFactorRepository) CountBackupCodes(ctx context.Context, userID uuid.UUID) (int, error) {
+	var count int
+	err := r.Conn(ctx).QueryRowContext(ctx,
+		`SELECT COUNT(*) FROM totp_backup_codes WHERE user_id = $1 AND used_at IS NULL`, userID).Scan(&count)
+	return count, err
+}
+
+func (r *twoFactorRepository) Delete(ctx context.Context, userID uuid.UUID) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM user_totp WHERE user_id = $1`, userID)
+	if err != nil {
+		return err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+
+	if err := replaceBackupCodes(ctx, tx, userID, nil); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func (r *twoFactorRepository) scan(row interface{ Scan(...interface{}) error }, twoFactor *models.TwoFactor) error {
+	return row.Scan(
+		&twoFactor.UserID,
+		&twoFactor.Secret,
+		&twoFactor.EnabledAt,
+		&twoFactor.LastUsedStep,
+		&twoFactor.CreatedAt,
+		&twoFactor.UpdatedAt,
+	)
+}
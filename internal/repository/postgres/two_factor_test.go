@@ -0,0 +1,69 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFactorRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewTwoFactorRepository(tc.DB)
+	user := tc.CreateTestUser("user", "user@example.com", "password123", false)
+
+	_, err := repo.GetByUserID(ctx, user.ID)
+	require.ErrorIs(t, err, repository.ErrNotFound)
+	require.ErrorIs(t, repo.Enroll(ctx, uuid.New(), "SECRET"), repository.ErrNotFound)
+
+	// Unconfirmed enrollments are replaced
+	require.NoError(t, repo.Enroll(ctx, user.ID, "FIRST"))
+	require.NoError(t, repo.Enroll(ctx, user.ID, "SECOND"))
+	twoFactor, err := repo.GetByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, "SECOND", twoFactor.Secret)
+	assert.False(t, twoFactor.Enabled())
+
+	now := time.Now()
+	require.NoError(t, repo.Enable(ctx, user.ID, 100, []string{"hash1", "hash2"}, now))
+	require.ErrorIs(t, repo.Enable(ctx, user.ID, 101, nil, now), repository.ErrConflict)
+	require.ErrorIs(t, repo.Enroll(ctx, user.ID, "THIRD"), repository.ErrConflict)
+	twoFactor, err = repo.GetByUserID(ctx, user.ID)
+	require.NoError(t, err)
+	assert.True(t, twoFactor.Enabled())
+	require.NotNil(t, twoFactor.LastUsedStep)
+	assert.Equal(t, int64(100), *twoFactor.LastUsedStep)
+
+	// Time steps are used once, in order
+	require.ErrorIs(t, repo.UseStep(ctx, user.ID, 100), repository.ErrConflict)
+	require.ErrorIs(t, repo.UseStep(ctx, user.ID, 99), repository.ErrConflict)
+	require.NoError(t, repo.UseStep(ctx, user.ID, 101))
+
+	// Pre-auth tokens are exchanged once, expired ones are forgotten
+	tokenID := uuid.New()
+	require.NoError(t, repo.UsePreAuthToken(ctx, user.ID, tokenID, now.Add(time.Minute)))
+	require.ErrorIs(t, repo.UsePreAuthToken(ctx, user.ID, tokenID, now.Add(time.Minute)), repository.ErrConflict)
+	expired := uuid.New()
+	require.NoError(t, repo.UsePreAuthToken(ctx, user.ID, expired, now.Add(-time.Minute)))
+	require.NoError(t, repo.UsePreAuthToken(ctx, user.ID, expired, now.Add(-time.Minute)))
+
+	require.NoError(t, repo.UseBackupCode(ctx, user.ID, "hash1", now))
+	require.ErrorIs(t, repo.UseBackupCode(ctx, user.ID, "hash1", now), repository.ErrNotFound)
+	require.ErrorIs(t, repo.UseBackupCode(ctx, user.ID, "other", now), repository.ErrNotFound)
+	count, err := repo.CountBackupCodes(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Equal(t, 1, count)
+
+	require.NoError(t, repo.Delete(ctx, user.ID))
+	require.ErrorIs(t, repo.Delete(ctx, user.ID), repository.ErrNotFound)
+	count, err = repo.CountBackupCodes(ctx, user.ID)
+	require.NoError(t, err)
+	assert.Zero(t, count)
+}
@@ -0,0 +1,86 @@
+package postgres_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTxManager(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	ctx := context.Background()
+
+	role, err := tc.RoleRepo.GetByName(ctx, "user")
+	require.NoError(t, err)
+
+	register := func(ctx context.Context, username string) (*models.User, error) {
+		email := username + "@example.com"
+		user := &models.User{Username: username, Password: "hash", Email: &email, RoleID: role.ID}
+		if err := tc.UserRepo.Create(ctx, user); err != nil {
+			return nil, err
+		}
+		if _, err := tc.EmailVerifyRepo.Create(ctx, user.ID, 0); err != nil {
+			return nil, err
+		}
+		return user, tc.AuditRepo.Create(ctx, &models.CreateAuditLogRequest{
+			UserID:     &user.ID,
+			Action:     "user_registered",
+			EntityType: "user",
+			EntityID:   user.ID.String(),
+		})
+	}
+
+	t.Run("Commit", func(t *testing.T) {
+		var user *models.User
+		err := tc.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			user, err = register(ctx, "tx-commit")
+			return err
+		})
+		require.NoError(t, err)
+
+		got, err := tc.UserRepo.GetByID(ctx, user.ID)
+		require.NoError(t, err)
+		require.Equal(t, "tx-commit", got.Username)
+	})
+
+	t.Run("Rollback", func(t *testing.T) {
+		failed := errors.New("failed")
+		var user *models.User
+		err := tc.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			if user, err = register(ctx, "tx-rollback"); err != nil {
+				return err
+			}
+			return failed
+		})
+		require.ErrorIs(t, err, failed)
+
+		_, err = tc.UserRepo.GetByID(ctx, user.ID)
+		require.ErrorIs(t, err, repository.ErrUserNotFound)
+		var verifications int
+		require.NoError(t, tc.DB.QueryRow(`SELECT COUNT(*) FROM email_verifications WHERE user_id = $1`, user.ID).Scan(&verifications))
+		require.Zero(t, verifications)
+	})
+
+	t.Run("Savepoint", func(t *testing.T) {
+		// Deleting a zone opens a transaction of its own, which becomes a savepoint. Its
+		// failure leaves the outer transaction usable.
+		err := tc.TxManager.WithinTx(ctx, func(ctx context.Context) error {
+			_, err := tc.ZoneRepo.DeleteCascade(ctx, uuid.New(), nil)
+			require.ErrorIs(t, err, repository.ErrNotFound)
+			_, err = register(ctx, "tx-savepoint")
+			return err
+		})
+		require.NoError(t, err)
+
+		_, err = tc.UserRepo.GetByUsername(ctx, "tx-savepoint")
+		require.NoError(t, err)
+	})
+}
@@ -28,18 +28,22 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 		INSERT INTO users (
 			id, username, password, email, email_verified, role_id,
 			last_login_at, last_failed_login, password_changed_at,
-			failed_login_attempts, deleted_at, created_at, updated_at
+			failed_login_attempts, deleted_at, language, created_at, updated_at
 		) VALUES (
-			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $12
+			$1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $13
 		)
-		RETURNING id, created_at, updated_at`
+		RETURNING id, created_at, updated_at,
+			COALESCE((SELECT s.reason FROM email_suppressions s WHERE s.email = lower($4)), 'deliverable')`
 
 	now := time.Now()
 	user.ID = uuid.New()
 	user.CreatedAt = now
 	user.UpdatedAt = now
+	if user.Language == "" {
+		user.Language = models.DefaultLanguage
+	}
 
-	err := r.DB().QueryRowContext(ctx, query,
+	err := r.Conn(ctx).QueryRowContext(ctx, query,
 		user.ID,
 		user.Username,
 		user.Password,
@@ -51,8 +55,9 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 		user.PasswordChangedAt,
 		user.FailedLoginAttempts,
 		user.DeletedAt,
+		user.Language,
 		now,
-	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt)
+	).Scan(&user.ID, &user.CreatedAt, &user.UpdatedAt, &user.EmailStatus)
 
 	if err != nil {
 		return err
@@ -63,7 +68,7 @@ func (r *userRepository) Create(ctx context.Context, user *models.User) error {
 func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 	// Check if new email conflicts with existing user
 	var count int
-	err := r.DB().QueryRowContext(ctx,
+	err := r.Conn(ctx).QueryRowContext(ctx,
 		"SELECT COUNT(*) FROM users WHERE email = $1 AND id != $2 AND deleted_at IS NULL",
 		user.Email,
 		user.ID,
@@ -81,20 +86,23 @@ func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 			email = $2,
 			email_verified = $3,
 			role_id = $4,
-			updated_at = $5
-		WHERE id = $6 AND deleted_at IS NULL
-		RETURNING updated_at`
+			language = COALESCE(NULLIF($5, ''), language),
+			updated_at = $6
+		WHERE id = $7 AND deleted_at IS NULL
+		RETURNING updated_at, language,
+			COALESCE((SELECT s.reason FROM email_suppressions s WHERE s.email = lower(users.email)), 'deliverable')`
 
-	result := r.DB().QueryRowContext(ctx, query,
+	result := r.Conn(ctx).QueryRowContext(ctx, query,
 		user.Username,
 		user.Email,
 		user.EmailVerified,
 		user.RoleID,
+		user.Language,
 		time.Now(),
 		user.ID,
 	)
 
-	if err := result.Scan(&user.UpdatedAt); err != nil {
+	if err := result.Scan(&user.UpdatedAt, &user.Language, &user.EmailStatus); err != nil {
 		if err == sql.ErrNoRows {
 			return repository.ErrNotFound
 		}
@@ -106,7 +114,7 @@ func (r *userRepository) Update(ctx context.Context, user *models.User) error {
 func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	// First check if user is an admin
 	var isAdmin bool
-	err := r.DB().QueryRowContext(ctx, `
+	err := r.Conn(ctx).QueryRowContext(ctx, `
 		SELECT r.is_admin_group 
 		FROM users u 
 		JOIN roles r ON u.role_id = r.id 
@@ -130,7 +138,7 @@ func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
 		RETURNING deleted_at`
 
 	now := time.Now()
-	result := r.DB().QueryRowContext(ctx, query, now, id)
+	result := r.Conn(ctx).QueryRowContext(ctx, query, now, id)
 
 	var deletedAt time.Time
 	if err := result.Scan(&deletedAt); err != nil {
@@ -142,40 +150,153 @@ func (r *userRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (r *userRepository) Restore(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `
+		UPDATE users
+		SET deleted_at = NULL, updated_at = $1
+		WHERE id = $2 AND deleted_at IS NOT NULL`, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrUserNotFound
+	}
+	return nil
+}
+
+// userPurgeStatements remove the rows referencing a user that the foreign keys don't
+// cascade to, and then the user. Audit logs are kept as a record of what the user did.
+var userPurgeStatements = []string{
+	`UPDATE audit_logs SET user_id = NULL WHERE user_id = $1`,
+	`DELETE FROM login_attempts WHERE user_id = $1`,
+	`DELETE FROM password_history WHERE user_id = $1`,
+	`DELETE FROM email_verifications WHERE user_id = $1`,
+	`DELETE FROM password_resets WHERE user_id = $1`,
+	`DELETE FROM refresh_tokens WHERE user_id = $1`,
+	`DELETE FROM users WHERE id = $1`,
+}
+
+func (r *userRepository) HardDelete(ctx context.Context, id uuid.UUID) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Lock the user so it isn't restored while its rows are being removed
+	var locked uuid.UUID
+	err = tx.QueryRowContext(ctx, `SELECT id FROM users WHERE id = $1 AND deleted_at IS NOT NULL FOR UPDATE`, id).Scan(&locked)
+	if err == sql.ErrNoRows {
+		return repository.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, query := range userPurgeStatements {
+		if _, err := tx.ExecContext(ctx, query, id); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func (r *userRepository) ScheduleDeletion(ctx context.Context, id uuid.UUID, at time.Time) error {
+	return r.setDeletionScheduledAt(ctx, id, &at)
+}
+
+func (r *userRepository) CancelDeletion(ctx context.Context, id uuid.UUID) error {
+	return r.setDeletionScheduledAt(ctx, id, nil)
+}
+
+func (r *userRepository) setDeletionScheduledAt(ctx context.Context, id uuid.UUID, at *time.Time) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `
+		UPDATE users
+		SET deletion_scheduled_at = $1, updated_at = $2
+		WHERE id = $3 AND deleted_at IS NULL`, at, time.Now(), id)
+	if err != nil {
+		return err
+	}
+
+	rows, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rows == 0 {
+		return repository.ErrUserNotFound
+	}
+	return nil
+}
+
+func (r *userRepository) DeleteScheduled(ctx context.Context, now time.Time) ([]uuid.UUID, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx, `
+		UPDATE users u
+		SET deleted_at = $1, deletion_scheduled_at = NULL, updated_at = $1
+		FROM roles r
+		WHERE r.id = u.role_id AND NOT r.is_admin_group
+			AND u.deleted_at IS NULL AND u.deletion_scheduled_at <= $1
+		RETURNING u.id`, now)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, rows.Err()
+}
+
 func (r *userRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.User, error) {
 	query := `
 		SELECT 
 			u.id, u.username, u.password, u.email, u.email_verified,
+			COALESCE((SELECT s.reason FROM email_suppressions s WHERE s.email = lower(u.email)), 'deliverable'),
 			u.role_id, u.last_login_at, u.last_failed_login,
-			u.password_changed_at, u.failed_login_attempts,
-			u.deleted_at, u.created_at, u.updated_at,
-			r.id, r.name, r.is_admin_group, r.is_protected,
-			r.created_at, r.updated_at
+			u.password_changed_at, u.failed_login_attempts, u.language,
+			u.deleted_at, u.deletion_scheduled_at, u.created_at, u.updated_at,
+			r.id, r.name, r.is_admin_group, r.is_protected, r.token_version,
+			r.created_at, r.updated_at, ` + userRolePermissions + `
 		FROM users u
 		LEFT JOIN roles r ON u.role_id = r.id
 		WHERE u.id = $1 AND u.deleted_at IS NULL`
 
 	user := &models.User{Role: &models.Role{}}
-	err := r.DB().QueryRowContext(ctx, query, id).Scan(
+	err := r.Conn(ctx).QueryRowContext(ctx, query, id).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Password,
 		&user.Email,
 		&user.EmailVerified,
+		&user.EmailStatus,
 		&user.RoleID,
 		&user.LastLoginAt,
 		&user.LastFailedLogin,
 		&user.PasswordChangedAt,
 		&user.FailedLoginAttempts,
+		&user.Language,
 		&user.DeletedAt,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.IsAdminGroup,
 		&user.Role.IsProtected,
+		&user.Role.TokenVersion,
 		&user.Role.CreatedAt,
 		&user.Role.UpdatedAt,
+		stringArray(&user.Role.Permissions),
 	)
 
 	if err == sql.ErrNoRows {
@@ -191,36 +312,42 @@ func (r *userRepository) GetByUsername(ctx context.Context, username string) (*m
 	query := `
 		SELECT 
 			u.id, u.username, u.password, u.email, u.email_verified,
+			COALESCE((SELECT s.reason FROM email_suppressions s WHERE s.email = lower(u.email)), 'deliverable'),
 			u.role_id, u.last_login_at, u.last_failed_login,
-			u.password_changed_at, u.failed_login_attempts,
-			u.deleted_at, u.created_at, u.updated_at,
-			r.id, r.name, r.is_admin_group, r.is_protected,
-			r.created_at, r.updated_at
+			u.password_changed_at, u.failed_login_attempts, u.language,
+			u.deleted_at, u.deletion_scheduled_at, u.created_at, u.updated_at,
+			r.id, r.name, r.is_admin_group, r.is_protected, r.token_version,
+			r.created_at, r.updated_at, ` + userRolePermissions + `
 		FROM users u
 		LEFT JOIN roles r ON u.role_id = r.id
 		WHERE u.username = $1 AND u.deleted_at IS NULL`
 
 	user := &models.User{Role: &models.Role{}}
-	err := r.DB().QueryRowContext(ctx, query, username).Scan(
+	err := r.Conn(ctx).QueryRowContext(ctx, query, username).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Password,
 		&user.Email,
 		&user.EmailVerified,
+		&user.EmailStatus,
 		&user.RoleID,
 		&user.LastLoginAt,
 		&user.LastFailedLogin,
 		&user.PasswordChangedAt,
 		&user.FailedLoginAttempts,
+		&user.Language,
 		&user.DeletedAt,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.IsAdminGroup,
 		&user.Role.IsProtected,
+		&user.Role.TokenVersion,
 		&user.Role.CreatedAt,
 		&user.Role.UpdatedAt,
+		stringArray(&user.Role.Permissions),
 	)
 
 	if err == sql.ErrNoRows {
@@ -236,36 +363,42 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 	query := `
 		SELECT 
 			u.id, u.username, u.password, u.email, u.email_verified,
+			COALESCE((SELECT s.reason FROM email_suppressions s WHERE s.email = lower(u.email)), 'deliverable'),
 			u.role_id, u.last_login_at, u.last_failed_login,
-			u.password_changed_at, u.failed_login_attempts,
-			u.deleted_at, u.created_at, u.updated_at,
-			r.id, r.name, r.is_admin_group, r.is_protected,
-			r.created_at, r.updated_at
+			u.password_changed_at, u.failed_login_attempts, u.language,
+			u.deleted_at, u.deletion_scheduled_at, u.created_at, u.updated_at,
+			r.id, r.name, r.is_admin_group, r.is_protected, r.token_version,
+			r.created_at, r.updated_at, ` + userRolePermissions + `
 		FROM users u
 		LEFT JOIN roles r ON u.role_id = r.id
 		WHERE u.email = $1 AND u.deleted_at IS NULL`
 
 	user := &models.User{Role: &models.Role{}}
-	err := r.DB().QueryRowContext(ctx, query, email).Scan(
+	err := r.Conn(ctx).QueryRowContext(ctx, query, email).Scan(
 		&user.ID,
 		&user.Username,
 		&user.Password,
 		&user.Email,
 		&user.EmailVerified,
+		&user.EmailStatus,
 		&user.RoleID,
 		&user.LastLoginAt,
 		&user.LastFailedLogin,
 		&user.PasswordChangedAt,
 		&user.FailedLoginAttempts,
+		&user.Language,
 		&user.DeletedAt,
+		&user.DeletionScheduledAt,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.Role.ID,
 		&user.Role.Name,
 		&user.Role.IsAdminGroup,
 		&user.Role.IsProtected,
+		&user.Role.TokenVersion,
 		&user.Role.CreatedAt,
 		&user.Role.UpdatedAt,
+		stringArray(&user.Role.Permissions),
 	)
 
 	if err == sql.ErrNoRows {
@@ -278,10 +411,68 @@ func (r *userRepository) GetByEmail(ctx context.Context, email string) (*models.
 }
 
 func (r *userRepository) List(ctx context.Context, filter repository.UserFilter) ([]models.User, error) {
+	query, args := userListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []models.User
+	for rows.Next() {
+		var user models.User
+		user.Role = &models.Role{}
+
+		err := rows.Scan(
+			&user.ID,
+			&user.Username,
+			&user.Email,
+			&user.RoleID,
+			&user.EmailVerified,
+			&user.EmailStatus,
+			&user.CreatedAt,
+			&user.UpdatedAt,
+			&user.LastLoginAt,
+			&user.FailedLoginAttempts,
+			&user.LastFailedLogin,
+			&user.PasswordChangedAt,
+			&user.Language,
+			&user.DeletedAt,
+			&user.DeletionScheduledAt,
+			&user.Role.Name,
+			&user.Role.IsAdminGroup,
+			&user.Role.IsProtected,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		users = append(users, user)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (r *userRepository) Total(ctx context.Context, filter repository.UserFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := userListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+// userListQuery builds the query selecting the users matching the filter
+func userListQuery(filter repository.UserFilter) (string, []interface{}) {
 	conditions := make([]string, 0)
 	args := make([]interface{}, 0)
 	argCount := 1
 
+	if !filter.IncludeDeleted {
+		conditions = append(conditions, "u.deleted_at IS NULL")
+	}
+
 	if filter.Search != nil {
 		conditions = append(conditions, fmt.Sprintf("(username ILIKE $%d OR email ILIKE $%d)", argCount, argCount))
 		args = append(args, "%"+*filter.Search+"%")
@@ -294,17 +485,23 @@ func (r *userRepository) List(ctx context.Context, filter repository.UserFilter)
 		argCount++
 	}
 
+	if filter.OrganizationID != nil {
+		conditions = append(conditions, fmt.Sprintf("u.id IN (SELECT user_id FROM organization_members WHERE organization_id = $%d)", argCount))
+		args = append(args, *filter.OrganizationID)
+		argCount++
+	}
+
 	query := `
 		SELECT u.id, u.username, u.email, u.role_id, u.email_verified,
+		       COALESCE((SELECT s.reason FROM email_suppressions s WHERE s.email = lower(u.email)), 'deliverable'),
 		       u.created_at, u.updated_at, u.last_login_at, u.failed_login_attempts,
-		       u.last_failed_login, u.password_changed_at,
-		       r.name as role_name, r.is_admin_group, r.is_protected
+		       u.last_failed_login, u.password_changed_at, u.language, u.deleted_at,
+		       u.deletion_scheduled_at, r.name as role_name, r.is_admin_group, r.is_protected
 		FROM users u
-		JOIN roles r ON u.role_id = r.id
-		WHERE u.deleted_at IS NULL`
+		JOIN roles r ON u.role_id = r.id`
 
 	if len(conditions) > 0 {
-		query += " AND " + strings.Join(conditions, " AND ")
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
 	// Add ORDER BY clause
@@ -331,45 +528,13 @@ func (r *userRepository) List(ctx context.Context, filter repository.UserFilter)
 		args = append(args, *filter.Offset)
 	}
 
-	rows, err := r.DB().QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var users []models.User
-	for rows.Next() {
-		var user models.User
-		user.Role = &models.Role{}
-
-		err := rows.Scan(
-			&user.ID,
-			&user.Username,
-			&user.Email,
-			&user.RoleID,
-			&user.EmailVerified,
-			&user.CreatedAt,
-			&user.UpdatedAt,
-			&user.LastLoginAt,
-			&user.FailedLoginAttempts,
-			&user.LastFailedLogin,
-			&user.PasswordChangedAt,
-			&user.Role.Name,
-			&user.Role.IsAdminGroup,
-			&user.Role.IsProtected,
-		)
-		if err != nil {
-			return nil, err
-		}
-
-		users = append(users, user)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
+	return query, args
+}
 
-	return users, nil
+func (r *userRepository) Count(ctx context.Context) (int, error) {
+	var count int
+	err := r.Conn(ctx).QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE deleted_at IS NULL").Scan(&count)
+	return count, err
 }
 
 func (r *userRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID, lastLogin time.Time) error {
@@ -380,7 +545,7 @@ func (r *userRepository) UpdateLastLogin(ctx context.Context, id uuid.UUID, last
 		RETURNING last_login_at`
 
 	now := time.Now()
-	result := r.DB().QueryRowContext(ctx, query, lastLogin, now, id)
+	result := r.Conn(ctx).QueryRowContext(ctx, query, lastLogin, now, id)
 
 	var updatedLastLogin time.Time
 	if err := result.Scan(&updatedLastLogin); err != nil {
@@ -400,7 +565,7 @@ func (r *userRepository) UpdateFailedLogin(ctx context.Context, id uuid.UUID, la
 		RETURNING last_failed_login, failed_login_attempts`
 
 	now := time.Now()
-	result := r.DB().QueryRowContext(ctx, query, lastFailedLogin, failedAttempts, now, id)
+	result := r.Conn(ctx).QueryRowContext(ctx, query, lastFailedLogin, failedAttempts, now, id)
 
 	var updatedLastFailedLogin time.Time
 	var updatedFailedAttempts int
@@ -421,7 +586,7 @@ func (r *userRepository) UpdatePassword(ctx context.Context, id uuid.UUID, hashe
 		RETURNING password_changed_at`
 
 	now := time.Now()
-	result := r.DB().QueryRowContext(ctx, query, hashedPassword, now, id)
+	result := r.Conn(ctx).QueryRowContext(ctx, query, hashedPassword, now, id)
 
 	var passwordChangedAt time.Time
 	if err := result.Scan(&passwordChangedAt); err != nil {
@@ -441,7 +606,7 @@ func (r *userRepository) VerifyEmail(ctx context.Context, id uuid.UUID) error {
 		RETURNING email_verified, password_changed_at`
 
 	now := time.Now()
-	result := r.DB().QueryRowContext(ctx, query, now, id)
+	result := r.Conn(ctx).QueryRowContext(ctx, query, now, id)
 
 	var emailVerified bool
 	var passwordChangedAt time.Time
@@ -461,7 +626,7 @@ func (r *userRepository) IncrementFailedAttempts(ctx context.Context, username s
 		    last_failed_login = CURRENT_TIMESTAMP
 		WHERE username = $1 AND deleted_at IS NULL`
 
-	result, err := r.DB().ExecContext(ctx, query, username)
+	result, err := r.Conn(ctx).ExecContext(ctx, query, username)
 	if err != nil {
 		return err
 	}
@@ -484,7 +649,7 @@ func (r *userRepository) ResetFailedAttempts(ctx context.Context, username strin
 		    last_failed_login = NULL
 		WHERE username = $1 AND deleted_at IS NULL`
 
-	result, err := r.DB().ExecContext(ctx, query, username)
+	result, err := r.Conn(ctx).ExecContext(ctx, query, username)
 	if err != nil {
 		return err
 	}
@@ -508,7 +673,7 @@ func (r *userRepository) UpdateFailedAttempts(ctx context.Context, id uuid.UUID,
 		    updated_at = CURRENT_TIMESTAMP
 		WHERE id = $2 AND deleted_at IS NULL`
 
-	result, err := r.DB().ExecContext(ctx, query, attempts, id)
+	result, err := r.Conn(ctx).ExecContext(ctx, query, attempts, id)
 	if err != nil {
 		return err
 	}
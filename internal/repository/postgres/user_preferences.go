@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type userPreferenceRepository struct {
+	repository.BaseRepository
+}
+
+// NewUserPreferenceRepository creates a new PostgreSQL user preference repository
+func NewUserPreferenceRepository(db *sql.DB) repository.UserPreferenceRepository {
+	return &userPreferenceRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+func (r *userPreferenceRepository) Get(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error) {
+	query := `
+		SELECT u.language, p.zone_id, z.name, p.currency_id, c.name, p.timezone,
+			COALESCE(p.weekly_report, false), p.updated_at
+		FROM users u
+		LEFT JOIN user_preferences p ON p.user_id = u.id
+		LEFT JOIN zones z ON z.id = p.zone_id
+		LEFT JOIN currencies c ON c.id = p.currency_id
+		WHERE u.id = $1 AND u.deleted_at IS NULL`
+
+	prefs := &models.UserPreferences{UserID: userID}
+	err := r.Conn(ctx).QueryRowContext(ctx, query, userID).Scan(
+		&prefs.Locale,
+		&prefs.ZoneID,
+		&prefs.Zone,
+		&prefs.CurrencyID,
+		&prefs.Currency,
+		&prefs.Timezone,
+		&prefs.WeeklyReport,
+		&prefs.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrUserNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return prefs, nil
+}
+
+func (r *userPreferenceRepository) Upsert(ctx context.Context, prefs *models.UserPreferences) error {
+	tx, err := r.BeginTx(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// The user row is locked so it isn't deleted before the preferences are stored
+	query := `
+		UPDATE users
+		SET language = COALESCE(NULLIF($2, ''), language)
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING language`
+
+	err = tx.QueryRowContext(ctx, query, prefs.UserID, prefs.Locale).Scan(&prefs.Locale)
+	if err == sql.ErrNoRows {
+		return repository.ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+
+	query = `
+		INSERT INTO user_preferences (user_id, zone_id, currency_id, timezone, weekly_report)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE SET
+			zone_id = EXCLUDED.zone_id,
+			currency_id = EXCLUDED.currency_id,
+			timezone = EXCLUDED.timezone,
+			weekly_report = EXCLUDED.weekly_report,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING updated_at`
+
+	var updatedAt time.Time
+	if err := tx.QueryRowContext(ctx, query,
+		prefs.UserID,
+		prefs.ZoneID,
+		prefs.CurrencyID,
+		prefs.Timezone,
+		prefs.WeeklyReport,
+	).Scan(&updatedAt); err != nil {
+		if errorCode(err) == foreignKeyViolation {
+			return repository.ErrNotFound
+		}
+		return err
+	}
+	prefs.UpdatedAt = &updatedAt
+
+	return tx.Commit()
+}
+
+func (r *userPreferenceRepository) ListWeeklyReports(ctx context.Context) ([]models.UserPreferences, error) {
+	query := `
+		SELECT p.user_id, u.language, p.zone_id, z.name, p.currency_id, c.name, p.timezone,
+			p.weekly_report, p.updated_at
+		FROM user_preferences p
+		JOIN users u ON u.id = p.user_id
+		LEFT JOIN zones z ON z.id = p.zone_id
+		LEFT JOIN currencies c ON c.id = p.currency_id
+		WHERE p.weekly_report AND u.deleted_at IS NULL
+		ORDER BY p.user_id`
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	list := []models.UserPreferences{}
+	for rows.Next() {
+		var prefs models.UserPreferences
+		if err := rows.Scan(
+			&prefs.UserID,
+			&prefs.Locale,
+			&prefs.ZoneID,
+			&prefs.Zone,
+			&prefs.CurrencyID,
+			&prefs.Currency,
+			&prefs.Timezone,
+			&prefs.WeeklyReport,
+			&prefs.UpdatedAt,
+		); err != nil {
+			return nil, err
+		}
+		list = append(list, prefs)
+	}
+	return list, rows.Err()
+}
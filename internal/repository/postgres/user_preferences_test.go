@@ -0,0 +1,69 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/repository/postgres/integration"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUserPreferenceRepository(t *testing.T) {
+	tc := integration.NewTestContext(t)
+	ctx := context.Background()
+	repo := postgres.NewUserPreferenceRepository(tc.DB)
+	user := tc.CreateTestUser("test-user", "test@example.com", "password123", false)
+
+	// Without stored preferences there are no defaults
+	prefs, err := repo.Get(ctx, user.ID)
+	require.NoError(t, err)
+	require.Nil(t, prefs.Zone)
+	require.Nil(t, prefs.UpdatedAt)
+	require.Equal(t, models.DefaultLanguage, prefs.Locale)
+
+	zone, err := tc.ZoneRepo.GetByName(ctx, "SE3")
+	require.NoError(t, err)
+	timezone := "Europe/Stockholm"
+	require.NoError(t, repo.Upsert(ctx, &models.UserPreferences{UserID: user.ID, ZoneID: &zone.ID, Timezone: &timezone, Locale: models.LanguageSwedish}))
+
+	prefs, err = repo.Get(ctx, user.ID)
+	require.NoError(t, err)
+	require.Equal(t, "SE3", *prefs.Zone)
+	require.Nil(t, prefs.Currency)
+	require.Equal(t, timezone, *prefs.Timezone)
+	require.Equal(t, models.LanguageSwedish, prefs.Locale)
+	require.NotNil(t, prefs.UpdatedAt)
+
+	// An empty locale keeps the language, cleared defaults are removed
+	require.NoError(t, repo.Upsert(ctx, &models.UserPreferences{UserID: user.ID}))
+	prefs, err = repo.Get(ctx, user.ID)
+	require.NoError(t, err)
+	require.Nil(t, prefs.Zone)
+	require.Nil(t, prefs.Timezone)
+	require.Equal(t, models.LanguageSwedish, prefs.Locale)
+
+	// Users opting in to the weekly report are listed until they are deleted
+	other := tc.CreateTestUser("other-user", "other@example.com", "password123", false)
+	require.NoError(t, repo.Upsert(ctx, &models.UserPreferences{UserID: user.ID, ZoneID: &zone.ID, WeeklyReport: true}))
+	require.NoError(t, repo.Upsert(ctx, &models.UserPreferences{UserID: other.ID}))
+	reports, err := repo.ListWeeklyReports(ctx)
+	require.NoError(t, err)
+	require.Len(t, reports, 1)
+	require.Equal(t, user.ID, reports[0].UserID)
+	require.Equal(t, "SE3", *reports[0].Zone)
+	require.True(t, reports[0].WeeklyReport)
+	require.NoError(t, tc.UserRepo.Delete(ctx, user.ID))
+	reports, err = repo.ListWeeklyReports(ctx)
+	require.NoError(t, err)
+	require.Empty(t, reports)
+
+	missing := uuid.New()
+	require.ErrorIs(t, repo.Upsert(ctx, &models.UserPreferences{UserID: other.ID, ZoneID: &missing}), repository.ErrNotFound)
+	_, err = repo.Get(ctx, uuid.New())
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+	require.ErrorIs(t, repo.Upsert(ctx, &models.UserPreferences{UserID: uuid.New()}), repository.ErrUserNotFound)
+}
@@ -258,6 +258,95 @@ func TestUserRepository_Delete(t *testing.T) {
 	}
 }
 
+func TestUserRepository_RestoreAndHardDelete(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	repo := postgres.NewUserRepository(tc.DB)
+	ctx := context.Background()
+
+	_, err := tc.DB.ExecContext(ctx, "DELETE FROM users WHERE username != 'admin'")
+	require.NoError(t, err)
+
+	user := tc.CreateTestUser("testuser", "test@example.com", "password123", false)
+	_, err = tc.DB.ExecContext(ctx,
+		"INSERT INTO audit_logs (user_id, action, entity_type, entity_id, description) VALUES ($1, 'update', 'user', $2, 'test')",
+		user.ID, user.ID.String())
+	require.NoError(t, err)
+
+	// Only deleted users are restored or purged
+	require.ErrorIs(t, repo.Restore(ctx, user.ID), repository.ErrUserNotFound)
+	require.ErrorIs(t, repo.HardDelete(ctx, user.ID), repository.ErrUserNotFound)
+	require.ErrorIs(t, repo.Restore(ctx, uuid.New()), repository.ErrUserNotFound)
+
+	require.NoError(t, repo.Delete(ctx, user.ID))
+	users, err := repo.List(ctx, repository.UserFilter{IncludeDeleted: true})
+	require.NoError(t, err)
+	var listed bool
+	for _, u := range users {
+		if u.ID == user.ID {
+			listed = true
+			require.NotNil(t, u.DeletedAt)
+		}
+	}
+	require.True(t, listed)
+
+	require.NoError(t, repo.Restore(ctx, user.ID))
+	_, err = repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+
+	require.NoError(t, repo.Delete(ctx, user.ID))
+	require.NoError(t, repo.HardDelete(ctx, user.ID))
+
+	var count int
+	require.NoError(t, tc.DB.QueryRowContext(ctx, "SELECT COUNT(*) FROM users WHERE id = $1", user.ID).Scan(&count))
+	require.Zero(t, count)
+
+	// Audit logs of the user are kept without it
+	require.NoError(t, tc.DB.QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM audit_logs WHERE entity_id = $1 AND user_id IS NULL", user.ID.String()).Scan(&count))
+	require.Equal(t, 1, count)
+}
+
+func TestUserRepository_ScheduledDeletion(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	repo := postgres.NewUserRepository(tc.DB)
+	ctx := context.Background()
+
+	_, err := tc.DB.ExecContext(ctx, "DELETE FROM users WHERE username != 'admin'")
+	require.NoError(t, err)
+
+	user := tc.CreateTestUser("testuser", "test@example.com", "password123", false)
+	admin := tc.CreateTestUser("otheradmin", "admin2@example.com", "password123", true)
+	require.ErrorIs(t, repo.ScheduleDeletion(ctx, uuid.New(), time.Now()), repository.ErrUserNotFound)
+
+	deleteAt := time.Now().Add(time.Hour).Truncate(time.Microsecond)
+	require.NoError(t, repo.ScheduleDeletion(ctx, user.ID, deleteAt))
+	require.NoError(t, repo.ScheduleDeletion(ctx, admin.ID, deleteAt))
+	got, err := repo.GetByID(ctx, user.ID)
+	require.NoError(t, err)
+	require.NotNil(t, got.DeletionScheduledAt)
+	require.True(t, deleteAt.Equal(*got.DeletionScheduledAt))
+
+	// Nothing is due yet
+	ids, err := repo.DeleteScheduled(ctx, time.Now())
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	// Cancelled deletions are left alone, admins are never deleted
+	require.NoError(t, repo.CancelDeletion(ctx, user.ID))
+	ids, err = repo.DeleteScheduled(ctx, deleteAt.Add(time.Minute))
+	require.NoError(t, err)
+	require.Empty(t, ids)
+
+	require.NoError(t, repo.ScheduleDeletion(ctx, user.ID, deleteAt))
+	ids, err = repo.DeleteScheduled(ctx, deleteAt.Add(time.Minute))
+	require.NoError(t, err)
+	require.Equal(t, []uuid.UUID{user.ID}, ids)
+	_, err = repo.GetByID(ctx, user.ID)
+	require.ErrorIs(t, err, repository.ErrUserNotFound)
+	_, err = repo.GetByID(ctx, admin.ID)
+	require.NoError(t, err)
+}
+
 func TestUserRepository_GetByID(t *testing.T) {
 	tc := testutil.NewTestContext(t)
 	repo := postgres.NewUserRepository(tc.DB)
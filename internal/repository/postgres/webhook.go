@@ -0,0 +1,316 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+type webhookRepository struct {
+	repository.BaseRepository
+}
+
+// NewWebhookRepository creates a new PostgreSQL webhook repository
+func NewWebhookRepository(db *sql.DB) repository.WebhookRepository {
+	return &webhookRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const webhookColumns = `id, url, secret, events, enabled, created_by, created_at, updated_at`
+
+// webhookEvents converts events to the text array they are stored as
+func webhookEvents(events []models.WebhookEvent) []string {
+	names := make([]string, len(events))
+	for i, event := range events {
+		names[i] = string(event)
+	}
+	return names
+}
+
+func (r *webhookRepository) Create(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		INSERT INTO webhooks (id, url, secret, events, enabled, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING ` + webhookColumns
+
+	return r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		uuid.New(),
+		webhook.URL,
+		webhook.Secret,
+		webhookEvents(webhook.Events),
+		webhook.Enabled,
+		webhook.CreatedBy,
+	), webhook)
+}
+
+func (r *webhookRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error) {
+	query := `SELECT ` + webhookColumns + ` FROM webhooks WHERE id = $1`
+
+	webhook := &models.Webhook{}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), webhook)
+	if err == sql.ErrNoRows {
+		return nil, repository.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return webhook, nil
+}
+
+func (r *webhookRepository) List(ctx context.Context) ([]models.Webhook, error) {
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhooks ORDER BY created_at, id`)
+}
+
+func (r *webhookRepository) ListByEvent(ctx context.Context, event models.WebhookEvent) ([]models.Webhook, error) {
+	return r.list(ctx, `SELECT `+webhookColumns+` FROM webhooks WHERE enabled AND $1 = ANY(events) ORDER BY created_at, id`, event)
+}
+
+func (r *webhookRepository) list(ctx context.Context, query string, args ...interface{}) ([]models.Webhook, error) {
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	webhooks := []models.Webhook{}
+	for rows.Next() {
+		var webhook models.Webhook
+		if err := r.scan(rows, &webhook); err != nil {
+			return nil, err
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	return webhooks, rows.Err()
+}
+
+func (r *webhookRepository) Update(ctx context.Context, webhook *models.Webhook) error {
+	query := `
+		UPDATE webhooks SET url = $2, events = $3, enabled = $4
+		WHERE id = $1
+		RETURNING ` + webhookColumns
+
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		webhook.ID,
+		webhook.URL,
+		webhookEvents(webhook.Events),
+		webhook.Enabled,
+	), webhook)
+	if err == sql.ErrNoRows {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *webhookRepository) Delete(ctx context.Context, id uuid.UUID) error {
+	result, err := r.Conn(ctx).ExecContext(ctx, `DELETE FROM webhooks WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *webhookRepository) scan(row interface{ Scan(...interface{}) error }, webhook *models.Webhook) error {
+	var events []string
+	if err := row.Scan(
+		&webhook.ID,
+		&webhook.URL,
+		&webhook.Secret,
+		stringArray(&events),
+		&webhook.Enabled,
+		&webhook.CreatedBy,
+		&webhook.CreatedAt,
+		&webhook.UpdatedAt,
+	); err != nil {
+		return err
+	}
+	webhook.Events = make([]models.WebhookEvent, len(events))
+	for i, event := range events {
+		webhook.Events[i] = models.WebhookEvent(event)
+	}
+	return nil
+}
+
+type webhookDeliveryRepository struct {
+	repository.BaseRepository
+}
+
+// NewWebhookDeliveryRepository creates a new PostgreSQL webhook delivery repository
+func NewWebhookDeliveryRepository(db *sql.DB) repository.WebhookDeliveryRepository {
+	return &webhookDeliveryRepository{
+		BaseRepository: repository.NewBaseRepository(db),
+	}
+}
+
+const webhookDeliveryColumns = `id, webhook_id, event, payload, status, attempts, response_status, error, next_attempt_at, created_at, delivered_at`
+
+func (r *webhookDeliveryRepository) Create(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		INSERT INTO webhook_deliveries (id, webhook_id, event, payload, status, next_attempt_at)
+		VALUES ($1, $2, $3, $4, $5, COALESCE($6, CURRENT_TIMESTAMP))
+		RETURNING ` + webhookDeliveryColumns
+
+	if delivery.Status == "" {
+		delivery.Status = models.DeliveryStatusPending
+	}
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query,
+		uuid.New(),
+		delivery.WebhookID,
+		delivery.Event,
+		string(delivery.Payload),
+		delivery.Status,
+		delivery.NextAttemptAt,
+	), delivery)
+	if errorCode(err) == foreignKeyViolation {
+		return repository.ErrNotFound
+	}
+	return err
+}
+
+func (r *webhookDeliveryRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error) {
+	query := `
+		UPDATE webhook_deliveries SET next_attempt_at = $2
+		WHERE id IN (
+			SELECT id FROM webhook_deliveries
+			WHERE status = $3 AND next_attempt_at <= $1
+			ORDER BY next_attempt_at, id
+			LIMIT $4
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING ` + webhookDeliveryColumns
+
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, now, now.Add(lease), models.DeliveryStatusPending, limit)
+	if err != nil {
+		return nil, err
+	}
+	return r.collect(rows)
+}
+
+func (r *webhookDeliveryRepository) Record(ctx context.Context, delivery *models.WebhookDelivery) error {
+	query := `
+		UPDATE webhook_deliveries
+		SET status = $2, attempts = $3, response_status = $4, error = $5, next_attempt_at = $6, delivered_at = $7
+		WHERE id = $1`
+
+	result, err := r.Conn(ctx).ExecContext(ctx, query,
+		delivery.ID,
+		delivery.Status,
+		delivery.Attempts,
+		delivery.ResponseStatus,
+		delivery.Error,
+		delivery.NextAttemptAt,
+		delivery.DeliveredAt,
+	)
+	if err != nil {
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if rowsAffected == 0 {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+// webhookDeliveryListQuery builds the query selecting the webhook deliveries matching the filter
+func webhookDeliveryListQuery(filter repository.WebhookDeliveryFilter) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+
+	if filter.WebhookID != nil {
+		args = append(args, *filter.WebhookID)
+		conditions = append(conditions, fmt.Sprintf("webhook_id = $%d", len(args)))
+	}
+	if filter.Event != nil {
+		args = append(args, *filter.Event)
+		conditions = append(conditions, fmt.Sprintf("event = $%d", len(args)))
+	}
+	if filter.Status != nil {
+		args = append(args, *filter.Status)
+		conditions = append(conditions, fmt.Sprintf("status = $%d", len(args)))
+	}
+
+	query := `SELECT ` + webhookDeliveryColumns + ` FROM webhook_deliveries`
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id"
+
+	if filter.Limit != nil {
+		args = append(args, *filter.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+	if filter.Offset != nil {
+		args = append(args, *filter.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return query, args
+}
+
+func (r *webhookDeliveryRepository) List(ctx context.Context, filter repository.WebhookDeliveryFilter) ([]models.WebhookDelivery, error) {
+	query, args := webhookDeliveryListQuery(filter)
+	rows, err := r.Conn(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	return r.collect(rows)
+}
+
+func (r *webhookDeliveryRepository) Total(ctx context.Context, filter repository.WebhookDeliveryFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := webhookDeliveryListQuery(filter)
+	return countRows(ctx, r.Conn(ctx), query, args)
+}
+
+// collect scans and closes rows of deliveries
+func (r *webhookDeliveryRepository) collect(rows *sql.Rows) ([]models.WebhookDelivery, error) {
+	defer rows.Close()
+
+	deliveries := []models.WebhookDelivery{}
+	for rows.Next() {
+		var delivery models.WebhookDelivery
+		if err := r.scan(rows, &delivery); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, delivery)
+	}
+	return deliveries, rows.Err()
+}
+
+func (r *webhookDeliveryRepository) scan(row interface{ Scan(...interface{}) error }, delivery *models.WebhookDelivery) error {
+	var payload []byte
+	if err := row.Scan(
+		&delivery.ID,
+		&delivery.WebhookID,
+		&delivery.Event,
+		&payload,
+		&delivery.Status,
+		&delivery.Attempts,
+		&delivery.ResponseStatus,
+		&delivery.Error,
+		&delivery.NextAttemptAt,
+		&delivery.CreatedAt,
+		&delivery.DeliveredAt,
+	); err != nil {
+		return err
+	}
+	delivery.Payload = payload
+	return nil
+}
@@ -0,0 +1,87 @@
+package postgres_test
+
+import (
+	"context"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/testutil"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhookRepository(t *testing.T) {
+	tc := testutil.NewTestContext(t)
+	ctx := context.Background()
+	webhooks := postgres.NewWebhookRepository(tc.DB)
+	deliveries := postgres.NewWebhookDeliveryRepository(tc.DB)
+
+	admin := tc.CreateTestUser("admin", "admin@example.com", "password123", true)
+	hook := &models.Webhook{URL: "https://example.com/hook", Secret: "secret", CreatedBy: &admin.ID, Enabled: true,
+		Events: []models.WebhookEvent{models.WebhookEventSpotPricesIngested, models.WebhookEventAlertTriggered}}
+	require.NoError(t, webhooks.Create(ctx, hook))
+	require.NotEqual(t, uuid.Nil, hook.ID)
+	disabled := &models.Webhook{URL: "https://example.com/off", Secret: "secret",
+		Events: []models.WebhookEvent{models.WebhookEventSpotPricesIngested}}
+	require.NoError(t, webhooks.Create(ctx, disabled))
+
+	subscribed, err := webhooks.ListByEvent(ctx, models.WebhookEventSpotPricesIngested)
+	require.NoError(t, err)
+	require.Len(t, subscribed, 1)
+	assert.Equal(t, hook.ID, subscribed[0].ID)
+	assert.Equal(t, "secret", subscribed[0].Secret)
+	subscribed, err = webhooks.ListByEvent(ctx, models.WebhookEventUserRegistered)
+	require.NoError(t, err)
+	assert.Empty(t, subscribed)
+
+	hook.Events = []models.WebhookEvent{models.WebhookEventUserRegistered}
+	require.NoError(t, webhooks.Update(ctx, hook))
+	got, err := webhooks.GetByID(ctx, hook.ID)
+	require.NoError(t, err)
+	assert.Equal(t, []models.WebhookEvent{models.WebhookEventUserRegistered}, got.Events)
+
+	// Deliveries are claimed once until their lease ends
+	now := time.Now()
+	delivery := &models.WebhookDelivery{WebhookID: hook.ID, Event: models.WebhookEventUserRegistered, Payload: []byte(`{"event":"user.registered"}`)}
+	require.NoError(t, deliveries.Create(ctx, delivery))
+	assert.Equal(t, models.DeliveryStatusPending, delivery.Status)
+	require.ErrorIs(t, deliveries.Create(ctx, &models.WebhookDelivery{WebhookID: uuid.New(), Event: models.WebhookEventUserRegistered,
+		Payload: []byte(`{}`)}), repository.ErrNotFound)
+
+	claimed, err := deliveries.ClaimDue(ctx, now.Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.JSONEq(t, `{"event":"user.registered"}`, string(claimed[0].Payload))
+	claimed, err = deliveries.ClaimDue(ctx, now.Add(time.Second), time.Minute, 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	status := 200
+	delivery.Status = models.DeliveryStatusSent
+	delivery.Attempts = 1
+	delivery.ResponseStatus = &status
+	delivery.NextAttemptAt = nil
+	delivery.DeliveredAt = &now
+	require.NoError(t, deliveries.Record(ctx, delivery))
+
+	sent := models.DeliveryStatusSent
+	filter := repository.WebhookDeliveryFilter{WebhookID: &hook.ID, Status: &sent}
+	listed, err := deliveries.List(ctx, filter)
+	require.NoError(t, err)
+	require.Len(t, listed, 1)
+	assert.Equal(t, 200, *listed[0].ResponseStatus)
+	total, err := deliveries.Total(ctx, filter)
+	require.NoError(t, err)
+	assert.Equal(t, 1, total)
+
+	// Deleting a webhook removes its deliveries
+	require.NoError(t, webhooks.Delete(ctx, hook.ID))
+	require.ErrorIs(t, webhooks.Delete(ctx, hook.ID), repository.ErrNotFound)
+	total, err = deliveries.Total(ctx, repository.WebhookDeliveryFilter{WebhookID: &hook.ID})
+	require.NoError(t, err)
+	assert.Zero(t, total)
+}
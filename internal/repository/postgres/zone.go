@@ -18,22 +18,31 @@ type zoneRepository struct {
 
 // NewZoneRepository creates a new PostgreSQL zone repository
 func NewZoneRepository(db *sql.DB) repository.ZoneRepository {
+	return NewZoneRepositoryWithReplicas(db, nil)
+}
+
+// NewZoneRepositoryWithReplicas creates a new PostgreSQL zone repository
+// reading listings from replicas, nil reads them from db
+func NewZoneRepositoryWithReplicas(db *sql.DB, replicas *repository.ReplicaSet) repository.ZoneRepository {
 	return &zoneRepository{
-		BaseRepository: repository.NewBaseRepository(db),
+		BaseRepository: repository.NewBaseRepositoryWithReplicas(db, replicas),
 	}
 }
 
+const zoneColumns = `id, name, timezone, country_code, eic_code, grid_operator, display_name, created_at, updated_at`
+
 func (r *zoneRepository) Create(ctx context.Context, zone *models.Zone) error {
 	// Validate timezone
 	if _, err := time.LoadLocation(zone.Timezone); err != nil {
 		return repository.ErrInvalidTimezone
 	}
 
-	// Check if zone with same name exists
+	// Check if zone with same name or EIC code exists
 	var count int
-	err := r.DB().QueryRowContext(ctx,
-		"SELECT COUNT(*) FROM zones WHERE name = $1",
+	err := r.Conn(ctx).QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM zones WHERE name = $1 OR eic_code = $2",
 		zone.Name,
+		zone.EICCode,
 	).Scan(&count)
 	if err != nil {
 		return err
@@ -43,22 +52,26 @@ func (r *zoneRepository) Create(ctx context.Context, zone *models.Zone) error {
 	}
 
 	query := `
-		INSERT INTO zones (id, name, timezone, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $4)
+		INSERT INTO zones (id, name, timezone, country_code, eic_code, grid_operator, display_name, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $8)
 		RETURNING id, created_at, updated_at`
 
 	now := time.Now()
 	zone.ID = uuid.New()
 
-	err = r.DB().QueryRowContext(ctx, query,
+	err = r.Conn(ctx).QueryRowContext(ctx, query,
 		zone.ID,
 		zone.Name,
 		zone.Timezone,
+		zone.CountryCode,
+		zone.EICCode,
+		zone.GridOperator,
+		zone.DisplayName,
 		now,
 	).Scan(&zone.ID, &zone.CreatedAt, &zone.UpdatedAt)
 
 	if err != nil {
-		if strings.Contains(err.Error(), "zones_name_key") {
+		if strings.Contains(err.Error(), "zones_name_key") || strings.Contains(err.Error(), "idx_zones_eic_code") {
 			return repository.ErrConflict
 		}
 		return err
@@ -74,7 +87,7 @@ func (r *zoneRepository) Update(ctx context.Context, zone *models.Zone) error {
 
 	// Check if zone exists
 	var exists bool
-	err := r.DB().QueryRowContext(ctx,
+	err := r.Conn(ctx).QueryRowContext(ctx,
 		"SELECT EXISTS(SELECT 1 FROM zones WHERE id = $1)",
 		zone.ID,
 	).Scan(&exists)
@@ -85,11 +98,12 @@ func (r *zoneRepository) Update(ctx context.Context, zone *models.Zone) error {
 		return repository.ErrNotFound
 	}
 
-	// Check if new name conflicts with existing zone
+	// Check if new name or EIC code conflicts with existing zone
 	var count int
-	err = r.DB().QueryRowContext(ctx,
-		"SELECT COUNT(*) FROM zones WHERE name = $1 AND id != $2",
+	err = r.Conn(ctx).QueryRowContext(ctx,
+		"SELECT COUNT(*) FROM zones WHERE (name = $1 OR eic_code = $2) AND id != $3",
 		zone.Name,
+		zone.EICCode,
 		zone.ID,
 	).Scan(&count)
 	if err != nil {
@@ -101,13 +115,18 @@ func (r *zoneRepository) Update(ctx context.Context, zone *models.Zone) error {
 
 	query := `
 		UPDATE zones
-		SET name = $1, timezone = $2, updated_at = $3
-		WHERE id = $4
+		SET name = $1, timezone = $2, country_code = $3, eic_code = $4, grid_operator = $5,
+			display_name = $6, updated_at = $7
+		WHERE id = $8
 		RETURNING updated_at`
 
-	result := r.DB().QueryRowContext(ctx, query,
+	result := r.Conn(ctx).QueryRowContext(ctx, query,
 		zone.Name,
 		zone.Timezone,
+		zone.CountryCode,
+		zone.EICCode,
+		zone.GridOperator,
+		zone.DisplayName,
 		time.Now(),
 		zone.ID,
 	)
@@ -116,7 +135,7 @@ func (r *zoneRepository) Update(ctx context.Context, zone *models.Zone) error {
 		if err == sql.ErrNoRows {
 			return repository.ErrNotFound
 		}
-		if strings.Contains(err.Error(), "zones_name_key") {
+		if strings.Contains(err.Error(), "zones_name_key") || strings.Contains(err.Error(), "idx_zones_eic_code") {
 			return repository.ErrConflict
 		}
 		return err
@@ -125,20 +144,21 @@ func (r *zoneRepository) Update(ctx context.Context, zone *models.Zone) error {
 }
 
 func (r *zoneRepository) Delete(ctx context.Context, id uuid.UUID) error {
-	// First check if there are any spot prices using this zone
-	var count int
-	err := r.DB().QueryRowContext(ctx, `
-		SELECT COUNT(*) FROM spot_prices WHERE zone_id = $1
-	`, id).Scan(&count)
+	// First check if there are any spot prices using this zone. EXISTS stops at the
+	// first one instead of counting the prices of every chunk.
+	var exists bool
+	err := r.Conn(ctx).QueryRowContext(ctx, `
+		SELECT EXISTS(SELECT 1 FROM spot_prices WHERE zone_id = $1)
+	`, id).Scan(&exists)
 	if err != nil {
 		return err
 	}
-	if count > 0 {
+	if exists {
 		return repository.ErrHasAssociatedRecords
 	}
 
 	query := `DELETE FROM zones WHERE id = $1`
-	result, err := r.DB().ExecContext(ctx, query, id)
+	result, err := r.Conn(ctx).ExecContext(ctx, query, id)
 	if err != nil {
 		return err
 	}
@@ -154,20 +174,15 @@ func (r *zoneRepository) Delete(ctx context.Context, id uuid.UUID) error {
 	return nil
 }
 
+func (r *zoneRepository) DeleteCascade(ctx context.Context, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error) {
+	return deleteCascade(ctx, &r.BaseRepository, "zones", "zone_id", id, reassignTo)
+}
+
 func (r *zoneRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Zone, error) {
-	query := `
-		SELECT id, name, timezone, created_at, updated_at
-		FROM zones
-		WHERE id = $1`
+	query := `SELECT ` + zoneColumns + ` FROM zones WHERE id = $1`
 
 	zone := &models.Zone{}
-	err := r.DB().QueryRowContext(ctx, query, id).Scan(
-		&zone.ID,
-		&zone.Name,
-		&zone.Timezone,
-		&zone.CreatedAt,
-		&zone.UpdatedAt,
-	)
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, id), zone)
 
 	if err == sql.ErrNoRows {
 		return nil, repository.ErrNotFound
@@ -179,19 +194,10 @@ func (r *zoneRepository) GetByID(ctx context.Context, id uuid.UUID) (*models.Zon
 }
 
 func (r *zoneRepository) GetByName(ctx context.Context, name string) (*models.Zone, error) {
-	query := `
-		SELECT id, name, timezone, created_at, updated_at
-		FROM zones
-		WHERE name = $1`
+	query := `SELECT ` + zoneColumns + ` FROM zones WHERE name = $1`
 
 	zone := &models.Zone{}
-	err := r.DB().QueryRowContext(ctx, query, name).Scan(
-		&zone.ID,
-		&zone.Name,
-		&zone.Timezone,
-		&zone.CreatedAt,
-		&zone.UpdatedAt,
-	)
+	err := r.scan(r.Conn(ctx).QueryRowContext(ctx, query, name), zone)
 
 	if err == sql.ErrNoRows {
 		return nil, repository.ErrNotFound
@@ -203,6 +209,50 @@ func (r *zoneRepository) GetByName(ctx context.Context, name string) (*models.Zo
 }
 
 func (r *zoneRepository) List(ctx context.Context, filter repository.ZoneFilter) ([]models.Zone, error) {
+	query, args := zoneListQuery(filter)
+	rows, err := r.Reader(ctx).QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var zones []models.Zone
+	for rows.Next() {
+		var zone models.Zone
+		if err := r.scan(rows, &zone); err != nil {
+			return nil, err
+		}
+		zones = append(zones, zone)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, err
+	}
+	return zones, nil
+}
+
+func (r *zoneRepository) Total(ctx context.Context, filter repository.ZoneFilter) (int, error) {
+	filter.Limit, filter.Offset = nil, nil
+	query, args := zoneListQuery(filter)
+	return countRows(ctx, r.Reader(ctx), query, args)
+}
+
+func (r *zoneRepository) scan(row interface{ Scan(...interface{}) error }, zone *models.Zone) error {
+	return row.Scan(
+		&zone.ID,
+		&zone.Name,
+		&zone.Timezone,
+		&zone.CountryCode,
+		&zone.EICCode,
+		&zone.GridOperator,
+		&zone.DisplayName,
+		&zone.CreatedAt,
+		&zone.UpdatedAt,
+	)
+}
+
+// zoneListQuery builds the query selecting the zones matching the filter
+func zoneListQuery(filter repository.ZoneFilter) (string, []interface{}) {
 	conditions := make([]string, 0)
 	args := make([]interface{}, 0)
 	argCount := 1
@@ -213,9 +263,13 @@ func (r *zoneRepository) List(ctx context.Context, filter repository.ZoneFilter)
 		argCount++
 	}
 
-	query := `
-		SELECT id, name, timezone, created_at, updated_at
-		FROM zones`
+	if filter.CountryCode != nil {
+		conditions = append(conditions, fmt.Sprintf("country_code = $%d", argCount))
+		args = append(args, *filter.CountryCode)
+		argCount++
+	}
+
+	query := `SELECT ` + zoneColumns + ` FROM zones`
 
 	if len(conditions) > 0 {
 		query += " WHERE " + strings.Join(conditions, " AND ")
@@ -245,29 +299,5 @@ func (r *zoneRepository) List(ctx context.Context, filter repository.ZoneFilter)
 		args = append(args, *filter.Offset)
 	}
 
-	rows, err := r.DB().QueryContext(ctx, query, args...)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var zones []models.Zone
-	for rows.Next() {
-		var zone models.Zone
-		if err := rows.Scan(
-			&zone.ID,
-			&zone.Name,
-			&zone.Timezone,
-			&zone.CreatedAt,
-			&zone.UpdatedAt,
-		); err != nil {
-			return nil, err
-		}
-		zones = append(zones, zone)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, err
-	}
-	return zones, nil
+	return query, args
 }
@@ -461,4 +461,16 @@ func TestZoneRepository_List(t *testing.T) {
 			}
 		})
 	}
+
+	t.Run("Success - Total Ignores Limit And Offset", func(t *testing.T) {
+		limit, offset := 1, 1
+		total, err := tc.ZoneRepo.Total(context.Background(), repository.ZoneFilter{Limit: &limit, Offset: &offset})
+		require.NoError(t, err)
+		require.Equal(t, len(zones), total)
+
+		search := "test-zone-1"
+		total, err = tc.ZoneRepo.Total(context.Background(), repository.ZoneFilter{Search: &search})
+		require.NoError(t, err)
+		require.Equal(t, 1, total)
+	})
 }
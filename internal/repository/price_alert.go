@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PriceAlertRepository defines the interface for price alert operations
+type PriceAlertRepository interface {
+	Repository
+	Create(ctx context.Context, alert *models.PriceAlert) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.PriceAlert, error)
+	ListByUserID(ctx context.Context, userID uuid.UUID) ([]models.PriceAlert, error)
+	// ListEnabled returns the enabled alerts on a zone and currency, whose owners aren't deleted
+	ListEnabled(ctx context.Context, zoneID, currencyID uuid.UUID) ([]models.PriceAlert, error)
+	Update(ctx context.Context, alert *models.PriceAlert) error
+	Delete(ctx context.Context, id uuid.UUID) error
+	// MarkTriggered records when the alert last sent a notification
+	MarkTriggered(ctx context.Context, id uuid.UUID, at time.Time) error
+}
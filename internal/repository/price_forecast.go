@@ -0,0 +1,33 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// PriceForecastRepository stores forecasts of spot prices, apart from the published ones
+type PriceForecastRepository interface {
+	Repository
+	// Upsert stores the forecasts, replacing those the same source made for the same
+	// time, zone and currency. The forecasts are updated to the values stored.
+	Upsert(ctx context.Context, forecasts []models.PriceForecast) error
+	// List returns the forecasts matching the filter, ordered by timestamp and source
+	List(ctx context.Context, filter PriceForecastFilter) ([]models.PriceForecast, error)
+}
+
+// PriceForecastFilter defines the forecasts listed. StartTime is inclusive and EndTime
+// exclusive.
+type PriceForecastFilter struct {
+	ZoneID     uuid.UUID
+	CurrencyID uuid.UUID
+	StartTime  time.Time
+	EndTime    time.Time
+	// Source limits the forecasts to those of one source, empty lists all sources
+	Source string
+	// Latest keeps only the most recently stored forecast of each time, the source named
+	// first winning a tie
+	Latest bool
+}
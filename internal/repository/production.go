@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// ProductionRepository stores the energy produced by users' solar panels and other
+// generation, and the part exported to the grid
+type ProductionRepository interface {
+	Repository
+	// CreateBatch stores the records, replacing the kWh of records the meter already has at
+	// the same timestamp. The records are updated to the values stored.
+	CreateBatch(ctx context.Context, records []models.ProductionRecord) error
+	// List returns the records of a user, oldest first
+	List(ctx context.Context, filter ProductionFilter) ([]models.ProductionRecord, error)
+	// Total counts the records matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter ProductionFilter) (int, error)
+}
+
+// ProductionFilter defines the filter options for listing production records
+type ProductionFilter struct {
+	UserID    uuid.UUID
+	MeterID   *string    // Filter by meter
+	StartTime *time.Time // Records at or after
+	EndTime   *time.Time // Records at or before
+	Limit     *int       // Limit results
+	Offset    *int       // Offset results
+}
@@ -11,14 +11,35 @@ import (
 // RefreshTokenRepository defines the interface for refresh token operations
 type RefreshTokenRepository interface {
 	Repository
-	Create(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time) error
+	// Create stores the token of a new login by client, starting a token family
+	Create(ctx context.Context, userID uuid.UUID, token string, expiresAt time.Time, client models.SessionClient) error
+	// GetByToken returns a token that is neither revoked nor expired, ErrTokenInvalid if it
+	// doesn't exist or was revoked and ErrTokenExpired if it has expired
 	GetByToken(ctx context.Context, token string) (*models.RefreshToken, error)
 	GetByUserID(ctx context.Context, userID uuid.UUID) ([]models.RefreshToken, error)
 	Delete(ctx context.Context, id uuid.UUID) error
 	DeleteByToken(ctx context.Context, token string) error
 	DeleteByUserID(ctx context.Context, userID uuid.UUID) error
-	DeleteExpired(ctx context.Context) error
+	// DeleteExpired removes the tokens that expired before the given time and returns how many
+	DeleteExpired(ctx context.Context, before time.Time) (int64, error)
 	IsValid(ctx context.Context, token string) (bool, error)
+	// Rotate revokes token and stores newToken in its family, returning the new token. A
+	// token that was rotated before is a reuse, possibly of a stolen token, so the whole
+	// family is revoked and ErrTokenReused returned with the reused token. Unknown and
+	// otherwise revoked tokens give ErrTokenInvalid, expired ones ErrTokenExpired.
+	// The new token records client as the last to use the session.
+	Rotate(ctx context.Context, token, newToken string, expiresAt, at time.Time, client models.SessionClient) (*models.RefreshToken, error)
+	// RevokeFamily revokes the token and the others of its family, or returns ErrTokenInvalid
+	// if the token doesn't exist
+	RevokeFamily(ctx context.Context, token string, at time.Time) error
+	// RevokeByUserID revokes all tokens of the user
+	RevokeByUserID(ctx context.Context, userID uuid.UUID, at time.Time) error
+	// ListSessions returns the sessions of the user that haven't ended at the given time,
+	// most recently used first. A session is a token family with a live token.
+	ListSessions(ctx context.Context, userID uuid.UUID, at time.Time) ([]models.Session, error)
+	// RevokeSession ends a session of the user, or returns ErrNotFound if the user has no
+	// such session that hasn't ended at the given time
+	RevokeSession(ctx context.Context, userID, sessionID uuid.UUID, at time.Time) error
 }
 
 // RefreshTokenFilter defines the filter options for listing refresh tokens
@@ -0,0 +1,173 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"sync/atomic"
+	"time"
+)
+
+// ReplicaSet spreads reads over the read-only replicas of a primary database that passed
+// their last check, and falls back to the primary while none did. A replica that can't be
+// reached between checks is read from no more, and the query runs on the primary instead.
+type ReplicaSet struct {
+	primary  *sql.DB
+	replicas []*replica
+	next     atomic.Uint64
+}
+
+type replica struct {
+	db *sql.DB
+	// number counts the replicas from 1 in the logs
+	number  int
+	healthy atomic.Bool
+}
+
+// NewReplicaSet creates a set of the replicas of primary. Replicas are read from until a
+// check or a connection to them fails.
+func NewReplicaSet(primary *sql.DB, replicas ...*sql.DB) *ReplicaSet {
+	set := &ReplicaSet{primary: primary}
+	for i, db := range replicas {
+		r := &replica{db: db, number: i + 1}
+		r.healthy.Store(true)
+		set.replicas = append(set.replicas, r)
+	}
+	return set
+}
+
+// Reader returns the next healthy replica, or the primary when none is healthy
+func (s *ReplicaSet) Reader() *sql.DB {
+	if r := s.pick(); r != nil {
+		return r.db
+	}
+	return s.primary
+}
+
+// Executor returns what to read from like Reader, except that a query failing to reach
+// the replica marks it unhealthy until its next check and runs on the primary instead
+func (s *ReplicaSet) Executor() Executor {
+	if r := s.pick(); r != nil {
+		return &failover{set: s, replica: r}
+	}
+	return s.primary
+}
+
+// pick returns the next healthy replica, or nil when none is healthy
+func (s *ReplicaSet) pick() *replica {
+	n := uint64(len(s.replicas))
+	start := s.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		if r := s.replicas[(start+i)%n]; r.healthy.Load() {
+			return r
+		}
+	}
+	return nil
+}
+
+// Len returns the number of replicas
+func (s *ReplicaSet) Len() int {
+	return len(s.replicas)
+}
+
+// Healthy returns the number of replicas that passed their last check
+func (s *ReplicaSet) Healthy() int {
+	healthy := 0
+	for _, r := range s.replicas {
+		if r.healthy.Load() {
+			healthy++
+		}
+	}
+	return healthy
+}
+
+// Check pings every replica and reads only from those answering before ctx is done
+func (s *ReplicaSet) Check(ctx context.Context) {
+	for _, r := range s.replicas {
+		err := r.db.PingContext(ctx)
+		if was := r.healthy.Swap(err == nil); was && err != nil {
+			log.Printf("Replica %d failed its check, reading elsewhere: %v", r.number, err)
+		} else if !was && err == nil {
+			log.Printf("Replica %d is back, reading from it again", r.number)
+		}
+	}
+}
+
+// Run checks the replicas every interval until ctx is done
+func (s *ReplicaSet) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		checkCtx, cancel := context.WithTimeout(ctx, interval)
+		s.Check(checkCtx)
+		cancel()
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Close closes the replicas, the primary is left open
+func (s *ReplicaSet) Close() error {
+	var errs []error
+	for _, r := range s.replicas {
+		errs = append(errs, r.db.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// failover reads from a replica, and from the primary when the replica can't be reached
+type failover struct {
+	set     *ReplicaSet
+	replica *replica
+}
+
+func (f *failover) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := f.replica.db.ExecContext(ctx, query, args...)
+	if f.failed(ctx, err) {
+		return f.set.primary.ExecContext(ctx, query, args...)
+	}
+	return result, err
+}
+
+func (f *failover) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := f.replica.db.QueryContext(ctx, query, args...)
+	if f.failed(ctx, err) {
+		return f.set.primary.QueryContext(ctx, query, args...)
+	}
+	return rows, err
+}
+
+func (f *failover) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := f.replica.db.QueryRowContext(ctx, query, args...)
+	if f.failed(ctx, row.Err()) {
+		return f.set.primary.QueryRowContext(ctx, query, args...)
+	}
+	return row
+}
+
+// failed reports whether err means the replica couldn't be reached, rather than the query
+// failing or ctx ending, and marks the replica unhealthy if so
+func (f *failover) failed(ctx context.Context, err error) bool {
+	if err == nil || ctx.Err() != nil || !isConnectionError(err) {
+		return false
+	}
+	if f.replica.healthy.Swap(false) {
+		log.Printf("Replica %d failed a query, reading elsewhere: %v", f.replica.number, err)
+	}
+	return true
+}
+
+// isConnectionError reports whether err is a failure to connect to or talk to the database
+func isConnectionError(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
@@ -0,0 +1,152 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+	"time"
+	"wattwatch/internal/repository"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openUnreachable opens a database nothing listens for, connections are only attempted
+// when it is used
+func openUnreachable(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("pgx", "postgres://postgres@127.0.0.1:1/wattwatch?connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+// answeringConn is a driver connection answering every query with the single value 1 and
+// recording the queries it ran
+type answeringConn struct {
+	queries []string
+}
+
+func (c *answeringConn) Prepare(query string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+func (c *answeringConn) Close() error              { return nil }
+func (c *answeringConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+func (c *answeringConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.queries = append(c.queries, query)
+	return driver.RowsAffected(1), nil
+}
+
+func (c *answeringConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.queries = append(c.queries, query)
+	return &oneRow{}, nil
+}
+
+// oneRow is a result of a single row with the value 1
+type oneRow struct {
+	done bool
+}
+
+func (r *oneRow) Columns() []string { return []string{"value"} }
+func (r *oneRow) Close() error      { return nil }
+
+func (r *oneRow) Next(dest []driver.Value) error {
+	if r.done {
+		return io.EOF
+	}
+	r.done = true
+	dest[0] = int64(1)
+	return nil
+}
+
+type answeringConnector struct {
+	conn *answeringConn
+}
+
+func (c *answeringConnector) Connect(ctx context.Context) (driver.Conn, error) { return c.conn, nil }
+func (c *answeringConnector) Driver() driver.Driver                            { return nil }
+
+// openAnswering opens a database answering every query, returning the connection
+// recording them
+func openAnswering(t *testing.T) (*sql.DB, *answeringConn) {
+	t.Helper()
+	conn := &answeringConn{}
+	db := sql.OpenDB(&answeringConnector{conn: conn})
+	t.Cleanup(func() { db.Close() })
+	return db, conn
+}
+
+func TestReplicaSet(t *testing.T) {
+	primary, first, second := openUnreachable(t), openUnreachable(t), openUnreachable(t)
+	set := repository.NewReplicaSet(primary, first, second)
+	require.Equal(t, 2, set.Len())
+	require.Equal(t, 2, set.Healthy())
+
+	// Reads alternate between the replicas
+	seen := map[*sql.DB]int{}
+	for i := 0; i < 4; i++ {
+		seen[set.Reader()]++
+	}
+	assert.Equal(t, map[*sql.DB]int{first: 2, second: 2}, seen)
+
+	// Reads go to the primary while no replica answers
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	set.Check(ctx)
+	assert.Equal(t, 0, set.Healthy())
+	assert.Same(t, primary, set.Reader())
+}
+
+func TestBaseRepository_Reader(t *testing.T) {
+	primary, replica := openUnreachable(t), openUnreachable(t)
+	ctx := context.Background()
+
+	base := repository.NewBaseRepository(primary)
+	assert.Same(t, primary, base.Reader(ctx), "reads go to the primary without replicas")
+
+	set := repository.NewReplicaSet(primary, replica)
+	base = repository.NewBaseRepositoryWithReplicas(primary, set)
+	assert.NotSame(t, primary, base.Reader(ctx), "listings read from the replica")
+	assert.Same(t, primary, base.Conn(ctx), "writes stay on the primary")
+
+	// Other repositories of the same database aren't affected
+	other := repository.NewBaseRepository(primary)
+	assert.Same(t, primary, other.Reader(ctx))
+}
+
+func TestReplicaSet_Failover(t *testing.T) {
+	ctx := context.Background()
+	primary, conn := openAnswering(t)
+
+	// A replica failing between checks is read from no more, the query runs on the primary
+	set := repository.NewReplicaSet(primary, openUnreachable(t))
+	var value int
+	require.NoError(t, set.Executor().QueryRowContext(ctx, "SELECT 1").Scan(&value))
+	assert.Equal(t, 1, value)
+	assert.Equal(t, []string{"SELECT 1"}, conn.queries)
+	assert.Equal(t, 0, set.Healthy())
+	assert.Same(t, primary, set.Executor())
+
+	set = repository.NewReplicaSet(primary, openUnreachable(t))
+	rows, err := set.Executor().QueryContext(ctx, "SELECT 2")
+	require.NoError(t, err)
+	require.NoError(t, rows.Close())
+	_, err = repository.NewReplicaSet(primary, openUnreachable(t)).Executor().ExecContext(ctx, "SELECT 3")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"SELECT 1", "SELECT 2", "SELECT 3"}, conn.queries)
+	assert.Equal(t, 0, set.Healthy())
+
+	// Reads whose context ended don't fail over
+	set = repository.NewReplicaSet(primary, openUnreachable(t))
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	_, err = set.Executor().QueryContext(cancelled, "SELECT 4")
+	require.Error(t, err)
+	assert.Equal(t, 1, set.Healthy())
+	assert.Len(t, conn.queries, 3)
+}
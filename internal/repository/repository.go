@@ -14,7 +14,8 @@ type Repository interface {
 
 // BaseRepository provides common functionality for all repositories
 type BaseRepository struct {
-	db *sql.DB
+	db       *sql.DB
+	replicas *ReplicaSet
 }
 
 // NewBaseRepository creates a new base repository
@@ -22,25 +23,54 @@ func NewBaseRepository(db *sql.DB) BaseRepository {
 	return BaseRepository{db: db}
 }
 
+// NewBaseRepositoryWithReplicas creates a base repository whose Reader reads from the
+// replicas of db, nil reads from db
+func NewBaseRepositoryWithReplicas(db *sql.DB, replicas *ReplicaSet) BaseRepository {
+	return BaseRepository{db: db, replicas: replicas}
+}
+
 // DB returns the database connection
 func (r *BaseRepository) DB() *sql.DB {
 	return r.db
 }
 
-// Transaction implements the Repository interface
-func (r *BaseRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return err
+// Conn returns the transaction ctx carries from TxManager.WithinTx, or the database
+// outside of one. Repositories run their queries on it so they take part in transactions.
+func (r *BaseRepository) Conn(ctx context.Context) Executor {
+	if tx := txFrom(ctx); tx != nil {
+		return tx
 	}
+	return r.db
+}
 
-	err = fn(ctx)
-	if err != nil {
-		if rbErr := tx.Rollback(); rbErr != nil {
-			return err
-		}
-		return err
+// Reader returns what the repository reads listings on, which may lag slightly behind
+// writes: a replica of the repository's ReplicaSet, failing over to the database when the
+// replica can't be reached, or the database without one. Reads feeding an update stay on
+// Conn. Within a transaction of ctx it returns the transaction, so reads see its writes.
+func (r *BaseRepository) Reader(ctx context.Context) Executor {
+	if tx := txFrom(ctx); tx != nil {
+		return tx
 	}
+	if r.replicas != nil {
+		return r.replicas.Executor()
+	}
+	return r.db
+}
 
-	return tx.Commit()
+// BeginTx begins a transaction for an operation of the repository that must be atomic.
+// Within a transaction of ctx it sets a savepoint instead, committed with the transaction.
+func (r *BaseRepository) BeginTx(ctx context.Context) (Tx, error) {
+	tx := txFrom(ctx)
+	if tx == nil {
+		return r.db.BeginTx(ctx, nil)
+	}
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+savepointName); err != nil {
+		return nil, err
+	}
+	return &savepoint{ctx: ctx, tx: tx}, nil
+}
+
+// Transaction implements the Repository interface
+func (r *BaseRepository) Transaction(ctx context.Context, fn func(ctx context.Context) error) error {
+	return NewTxManager(r.db).WithinTx(ctx, fn)
 }
@@ -17,6 +17,13 @@ type RoleRepository interface {
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Role, error)
 	GetByName(ctx context.Context, name string) (*models.Role, error)
 	List(ctx context.Context, filter RoleFilter) ([]models.Role, error)
+	// Total counts the roles matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter RoleFilter) (int, error)
+	// GrantPermission adds the permission to the role, RevokePermission removes it. Both
+	// change the token version of the role and return ErrProtectedRole for protected
+	// roles. RevokePermission returns ErrNotFound when the role doesn't have it.
+	GrantPermission(ctx context.Context, roleID uuid.UUID, permission string) error
+	RevokePermission(ctx context.Context, roleID uuid.UUID, permission string) error
 }
 
 // RoleFilter defines the filter options for listing roles
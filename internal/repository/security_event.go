@@ -0,0 +1,20 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SecurityEventRepository defines the interface for security event operations
+type SecurityEventRepository interface {
+	Repository
+	// Create stores the event, setting its ID and creation time. It returns ErrUserNotFound
+	// when the user doesn't exist.
+	Create(ctx context.Context, event *models.SecurityEvent) error
+	// ListByUserID returns the events of a user, newest first
+	ListByUserID(ctx context.Context, userID uuid.UUID, limit, offset int) ([]models.SecurityEvent, error)
+	// CountByUserID counts the events of a user
+	CountByUserID(ctx context.Context, userID uuid.UUID) (int, error)
+}
@@ -0,0 +1,15 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+)
+
+// SettingRepository defines the interface for runtime setting overrides
+type SettingRepository interface {
+	Repository
+	// Upsert stores a value, replacing the current one for the key
+	Upsert(ctx context.Context, setting *models.Setting) error
+	List(ctx context.Context) ([]models.Setting, error)
+	Delete(ctx context.Context, key string) error
+}
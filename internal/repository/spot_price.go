@@ -3,6 +3,9 @@ package repository
 import (
 	"context"
 	"database/sql"
+	"encoding/base64"
+	"errors"
+	"strings"
 	"time"
 	"wattwatch/internal/models"
 
@@ -13,14 +16,77 @@ import (
 type SpotPriceRepository interface {
 	Repository
 	Create(ctx context.Context, spotPrice *models.SpotPrice) error
+	// CreateBatch upserts the spot prices in one transaction, in statements of many rows
+	// each. A batch must hold each timestamp, zone and currency once.
 	CreateBatch(ctx context.Context, spotPrices []models.SpotPrice) error
 	Update(ctx context.Context, spotPrice *models.SpotPrice) error
 	Delete(ctx context.Context, id uuid.UUID) error
 	GetByID(ctx context.Context, id uuid.UUID) (*models.SpotPrice, error)
 	List(ctx context.Context, filter SpotPriceFilter) ([]models.SpotPrice, error)
+	// Total counts the spot prices matching the filter, ignoring its limit, offset and cursor
+	Total(ctx context.Context, filter SpotPriceFilter) (int, error)
+	// LastModified returns when the spot prices matching the filter last changed and how
+	// many there are, ignoring its limit, offset and cursor. Removing a spot price lowers the
+	// count, so together they change whenever the matching spot prices do. The time is
+	// zero when none match.
+	LastModified(ctx context.Context, filter SpotPriceFilter) (time.Time, int, error)
+	// Each calls fn for the spot prices List would return as they are read, without
+	// holding them all in memory. fn must not keep the spot price, it is reused.
+	Each(ctx context.Context, filter SpotPriceFilter, fn func(*models.SpotPrice) error) error
+	// Aggregate returns statistics of the spot prices per zone, currency and bucket, ordered
+	// by zone name, currency name and bucket start
+	Aggregate(ctx context.Context, filter SpotPriceAggregateFilter) ([]models.SpotPriceAggregate, error)
+	// CheapestPrices returns the spot prices with the lowest average that together last
+	// filter.Duration, ordered by timestamp, and how long each price applies. The
+	// resolution is the shortest gap between the prices in the range, an hour when there
+	// are fewer than two. No prices are returned when the range doesn't hold enough.
+	CheapestPrices(ctx context.Context, filter SpotPriceWindowFilter) ([]models.SpotPrice, time.Duration, error)
+	// Compare returns statistics of the spot prices in each of the filter's periods, in
+	// order, with how those of the first period differ from them. The first period's
+	// changes are compared with itself.
+	Compare(ctx context.Context, filter SpotPriceCompareFilter) ([]models.SpotPricePeriodComparison, error)
 }
 
-// SpotPriceFilter defines the filter options for listing spot prices
+// SpotPriceCompareFilter defines the periods Compare reads the spot prices of a zone and
+// currency in. Each period's StartTime is inclusive and EndTime exclusive.
+type SpotPriceCompareFilter struct {
+	ZoneID     uuid.UUID
+	CurrencyID uuid.UUID
+	Periods    []SpotPriceRange
+}
+
+// SpotPriceRange is a period of time spot prices are read in
+type SpotPriceRange struct {
+	StartTime time.Time
+	EndTime   time.Time
+}
+
+// SpotPriceWindowFilter defines the spot prices CheapestPrices chooses from. StartTime is
+// inclusive and the chosen prices stop applying by EndTime. Contiguous prices follow
+// each other without gaps, otherwise the cheapest prices in the range are chosen
+// wherever they are. Ties go to the earliest prices.
+type SpotPriceWindowFilter struct {
+	ZoneID     uuid.UUID
+	CurrencyID uuid.UUID
+	StartTime  time.Time
+	EndTime    time.Time
+	Duration   time.Duration
+	Contiguous bool
+}
+
+// SpotPriceAggregateFilter defines the spot prices aggregated and the bucket length.
+// StartTime is inclusive and EndTime exclusive, so a range covering whole buckets doesn't
+// add a bucket for the price at EndTime.
+type SpotPriceAggregateFilter struct {
+	ZoneID     *uuid.UUID
+	CurrencyID *uuid.UUID
+	StartTime  time.Time
+	EndTime    time.Time
+	GroupBy    models.AggregatePeriod
+}
+
+// SpotPriceFilter defines the filter options for listing spot prices. StartTime and EndTime
+// limit the chunks of the spot_prices hypertable read, the other fields don't.
 type SpotPriceFilter struct {
 	ZoneID     *uuid.UUID
 	CurrencyID *uuid.UUID
@@ -30,6 +96,47 @@ type SpotPriceFilter struct {
 	OrderDesc  bool
 	Limit      *int
 	Offset     *int
+	// AsOf returns the spot prices as they were at that time, leaving out those created
+	// later and giving the others the price they had then
+	AsOf *time.Time
+	// After returns only the spot prices following the cursor in (timestamp, zone_id)
+	// order, or preceding it when ordered descending
+	After *SpotPriceCursor
+}
+
+// SpotPriceCursor is the position of a spot price in a listing ordered by timestamp and
+// zone, which stays stable as prices are added to other parts of the range
+type SpotPriceCursor struct {
+	Timestamp time.Time
+	ZoneID    uuid.UUID
+}
+
+// ErrInvalidCursor is returned when decoding a cursor that wasn't encoded by Encode
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Encode returns the cursor as an opaque URL safe string
+func (c SpotPriceCursor) Encode() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.Timestamp.UTC().Format(time.RFC3339Nano) + "|" + c.ZoneID.String()))
+}
+
+// DecodeSpotPriceCursor parses a cursor returned by Encode
+func DecodeSpotPriceCursor(s string) (SpotPriceCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return SpotPriceCursor{}, ErrInvalidCursor
+	}
+	timestamp, zone, ok := strings.Cut(string(data), "|")
+	if !ok {
+		return SpotPriceCursor{}, ErrInvalidCursor
+	}
+	var c SpotPriceCursor
+	if c.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp); err != nil {
+		return SpotPriceCursor{}, ErrInvalidCursor
+	}
+	if c.ZoneID, err = uuid.Parse(zone); err != nil {
+		return SpotPriceCursor{}, ErrInvalidCursor
+	}
+	return c, nil
 }
 
 type SpotPriceRepositoryImpl struct {
@@ -0,0 +1,35 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SpotPriceRevisionRepository reads the earlier prices of spot prices. The spot price
+// repositories record a revision whenever they change a price.
+type SpotPriceRevisionRepository interface {
+	Repository
+	// ListBySpotPriceID returns the revisions of a spot price, newest first
+	ListBySpotPriceID(ctx context.Context, spotPriceID uuid.UUID, limit, offset int) ([]models.SpotPriceRevision, error)
+	// CountBySpotPriceID counts the revisions of a spot price
+	CountBySpotPriceID(ctx context.Context, spotPriceID uuid.UUID) (int, error)
+}
+
+type changedByKey struct{}
+
+// WithChangedBy attributes the spot price changes made with the returned context to the
+// user, in the revisions recorded for them
+func WithChangedBy(ctx context.Context, userID uuid.UUID) context.Context {
+	return context.WithValue(ctx, changedByKey{}, userID)
+}
+
+// ChangedBy returns the user WithChangedBy attributed the changes made with ctx to, nil
+// when there is none
+func ChangedBy(ctx context.Context) *uuid.UUID {
+	if userID, ok := ctx.Value(changedByKey{}).(uuid.UUID); ok {
+		return &userID
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SpotPriceSourceRepository keeps the price every source reported for a spot price, so
+// differing values from providers feeding the same zone can be reviewed and resolved
+type SpotPriceSourceRepository interface {
+	Repository
+	// Record stores the prices as reported by source and upserts them as spot prices,
+	// except for spot prices already resolved to a source, which keep the chosen value.
+	// The spot prices are updated to the values stored.
+	Record(ctx context.Context, source string, spotPrices []models.SpotPrice) error
+	// ListConflicts returns the spot prices that sources reported differing values for,
+	// newest first
+	ListConflicts(ctx context.Context, filter SpotPriceConflictFilter) ([]models.SpotPriceConflict, error)
+	// TotalConflicts counts the conflicts matching the filter, ignoring its limit and offset
+	TotalConflicts(ctx context.Context, filter SpotPriceConflictFilter) (int, error)
+	// Resolve sets the spot price to the value source reported and keeps it from then on.
+	// It returns ErrNotFound when the source reported no value for the spot price.
+	Resolve(ctx context.Context, timestamp time.Time, zoneID, currencyID uuid.UUID, source string) (*models.SpotPrice, error)
+}
+
+// SpotPriceConflictFilter defines the filter options for listing spot price conflicts
+type SpotPriceConflictFilter struct {
+	ZoneID     *uuid.UUID
+	CurrencyID *uuid.UUID
+	StartTime  *time.Time
+	EndTime    *time.Time
+	// IncludeResolved also returns conflicts an administrator chose a source for
+	IncludeResolved bool
+	Limit           *int
+	Offset          *int
+}
@@ -0,0 +1,27 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// TariffRepository defines the interface for tariff operations
+type TariffRepository interface {
+	Repository
+	// Create stores a new tariff of a user or organization. It returns ErrNotFound when the
+	// owner, zone or currency doesn't exist and ErrDuplicateEntry when the owner already has
+	// a tariff for the zone and currency.
+	Create(ctx context.Context, tariff *models.Tariff) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Tariff, error)
+	// ListForUser returns the tariffs of the user and of the organizations the user is a
+	// member of
+	ListForUser(ctx context.Context, userID uuid.UUID) ([]models.Tariff, error)
+	// FindForUser returns the tariff the user pays in the zone and currency: the user's own,
+	// or else that of the organization the user joined first. It returns ErrNotFound when
+	// there is none.
+	FindForUser(ctx context.Context, userID, zoneID, currencyID uuid.UUID) (*models.Tariff, error)
+	Update(ctx context.Context, tariff *models.Tariff) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
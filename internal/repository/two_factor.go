@@ -0,0 +1,38 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// TwoFactorRepository defines the interface for TOTP secrets and backup codes
+type TwoFactorRepository interface {
+	Repository
+	// GetByUserID returns the user's secret, enabled or not, or ErrNotFound
+	GetByUserID(ctx context.Context, userID uuid.UUID) (*models.TwoFactor, error)
+	// Enroll stores a secret waiting to be confirmed, replacing an unconfirmed one. It
+	// returns ErrConflict when two-factor authentication is already enabled and ErrNotFound
+	// for unknown users.
+	Enroll(ctx context.Context, userID uuid.UUID, secret string) error
+	// Enable confirms the enrollment with the time step of the code that confirmed it, and
+	// replaces the backup codes with the codes hashed to codeHashes. It returns ErrNotFound
+	// without an enrollment and ErrConflict when it is already enabled.
+	Enable(ctx context.Context, userID uuid.UUID, step int64, codeHashes []string, at time.Time) error
+	// UseStep records the time step of an accepted code. It returns ErrConflict when a code
+	// of the step or a later one was used already, so codes can't be replayed.
+	UseStep(ctx context.Context, userID uuid.UUID, step int64) error
+	// UsePreAuthToken records that the user exchanged the pre-auth token with the ID for a
+	// session, forgetting the user's expired ones. It returns ErrConflict when the token was
+	// exchanged already, so it can't be replayed.
+	UsePreAuthToken(ctx context.Context, userID, tokenID uuid.UUID, expiresAt time.Time) error
+	// UseBackupCode marks the unused backup code hashed to codeHash as used, or returns
+	// ErrNotFound
+	UseBackupCode(ctx context.Context, userID uuid.UUID, codeHash string, at time.Time) error
+	// CountBackupCodes counts the user's unused backup codes
+	CountBackupCodes(ctx context.Context, userID uuid.UUID) (int, error)
+	// Delete removes the user's secret and backup codes, or returns ErrNotFound
+	Delete(ctx context.Context, userID uuid.UUID) error
+}
@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+)
+
+// Executor runs queries, on the database or within a transaction
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Tx is a transaction a repository opens for an operation that must be atomic
+type Tx interface {
+	Executor
+	Commit() error
+	Rollback() error
+}
+
+// TxManager runs several repository operations atomically, such as creating a user
+// together with its email verification and audit log
+type TxManager interface {
+	// WithinTx runs fn in a transaction, committed when fn returns nil and rolled back
+	// otherwise. Repositories called with the context passed to fn take part in the
+	// transaction, and WithinTx called with it again joins the transaction. The context
+	// must not be used by several goroutines at once.
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type txKey struct{}
+
+// txFrom returns the transaction ctx carries, nil outside of one
+func txFrom(ctx context.Context) *sql.Tx {
+	tx, _ := ctx.Value(txKey{}).(*sql.Tx)
+	return tx
+}
+
+type txManager struct {
+	db *sql.DB
+}
+
+// NewTxManager creates a transaction manager for the repositories of db
+func NewTxManager(db *sql.DB) TxManager {
+	return &txManager{db: db}
+}
+
+func (m *txManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if txFrom(ctx) != nil {
+		return fn(ctx)
+	}
+
+	tx, err := m.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(context.WithValue(ctx, txKey{}, tx)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// savepointName is the same for every savepoint, PostgreSQL releases and rolls back to
+// the most recent savepoint of a name so nested savepoints still pair up
+const savepointName = "repository_tx"
+
+// savepoint is a Tx within the transaction of a TxManager, so rolling it back undoes its
+// own changes and leaves the transaction usable
+type savepoint struct {
+	ctx  context.Context
+	tx   *sql.Tx
+	done bool
+}
+
+func (s *savepoint) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return s.tx.ExecContext(ctx, query, args...)
+}
+
+func (s *savepoint) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return s.tx.QueryContext(ctx, query, args...)
+}
+
+func (s *savepoint) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return s.tx.QueryRowContext(ctx, query, args...)
+}
+
+func (s *savepoint) Commit() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.tx.ExecContext(s.ctx, "RELEASE SAVEPOINT "+savepointName)
+	return err
+}
+
+func (s *savepoint) Rollback() error {
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	if _, err := s.tx.ExecContext(s.ctx, "ROLLBACK TO SAVEPOINT "+savepointName); err != nil {
+		return err
+	}
+	_, err := s.tx.ExecContext(s.ctx, "RELEASE SAVEPOINT "+savepointName)
+	return err
+}
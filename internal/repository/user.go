@@ -15,10 +15,29 @@ type UserRepository interface {
 	Create(ctx context.Context, user *models.User) error
 	Update(ctx context.Context, user *models.User) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	// Restore undoes the soft delete of a user. It returns ErrUserNotFound when no deleted
+	// user has the ID.
+	Restore(ctx context.Context, id uuid.UUID) error
+	// HardDelete permanently removes a soft-deleted user with its tokens, login attempts and
+	// other rows of its own. Audit logs are kept without the user. It returns
+	// ErrUserNotFound when no deleted user has the ID.
+	HardDelete(ctx context.Context, id uuid.UUID) error
+	// ScheduleDeletion marks the user to be deleted at the time. It returns ErrUserNotFound
+	// when no user has the ID.
+	ScheduleDeletion(ctx context.Context, id uuid.UUID, at time.Time) error
+	// CancelDeletion clears the scheduled deletion of the user. It returns ErrUserNotFound
+	// when no user has the ID.
+	CancelDeletion(ctx context.Context, id uuid.UUID) error
+	// DeleteScheduled soft deletes the users whose deletion is scheduled at or before now,
+	// returning their IDs. Admins are skipped, like Delete refuses them.
+	DeleteScheduled(ctx context.Context, now time.Time) ([]uuid.UUID, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.User, error)
 	GetByUsername(ctx context.Context, username string) (*models.User, error)
 	GetByEmail(ctx context.Context, email string) (*models.User, error)
 	List(ctx context.Context, filter UserFilter) ([]models.User, error)
+	// Total counts the users matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter UserFilter) (int, error)
+	Count(ctx context.Context) (int, error)
 	UpdatePassword(ctx context.Context, id uuid.UUID, hashedPassword string) error
 	UpdateLastLogin(ctx context.Context, id uuid.UUID, lastLoginAt time.Time) error
 	UpdateFailedAttempts(ctx context.Context, id uuid.UUID, attempts int) error
@@ -29,12 +48,14 @@ type UserRepository interface {
 
 // UserFilter defines the filter options for listing users
 type UserFilter struct {
-	Search    *string // Search by username or email
-	RoleID    *uuid.UUID
-	OrderBy   string // Field to order by
-	OrderDesc bool   // Order descending
-	Limit     *int   // Limit results
-	Offset    *int   // Offset results
+	Search         *string // Search by username or email
+	RoleID         *uuid.UUID
+	OrganizationID *uuid.UUID // Only members of the organization
+	IncludeDeleted bool       // Include soft-deleted users
+	OrderBy        string     // Field to order by
+	OrderDesc      bool       // Order descending
+	Limit          *int       // Limit results
+	Offset         *int       // Offset results
 }
 
 type userRepositoryImpl struct {
@@ -206,7 +227,7 @@ func (r *userRepositoryImpl) Delete(id uuid.UUID) error {
 
 func (r *userRepositoryImpl) UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
 	// Check if password was recently used
-	if err := r.passwordHistory.CheckReuse(ctx, userID, hashedPassword); err != nil {
+	if err := r.passwordHistory.CheckReuse(ctx, userID, hashedPassword, DefaultPasswordHistoryDepth); err != nil {
 		return err
 	}
 
@@ -0,0 +1,23 @@
+package repository
+
+import (
+	"context"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// UserPreferenceRepository defines the interface for user preference operations
+type UserPreferenceRepository interface {
+	Repository
+	// Get returns the preferences of a user, without defaults when none were stored. It
+	// returns ErrUserNotFound for unknown and deleted users.
+	Get(ctx context.Context, userID uuid.UUID) (*models.UserPreferences, error)
+	// Upsert stores the defaults, timezone and weekly report opt-in of a user, and the locale
+	// as the language of the user unless it is empty. The zone and currency names are left
+	// as they are.
+	Upsert(ctx context.Context, prefs *models.UserPreferences) error
+	// ListWeeklyReports returns the preferences of the users who opted in to the weekly
+	// report, except deleted users
+	ListWeeklyReports(ctx context.Context) ([]models.UserPreferences, error)
+}
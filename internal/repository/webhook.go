@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"time"
+	"wattwatch/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// WebhookRepository defines the interface for webhook operations
+type WebhookRepository interface {
+	Repository
+	Create(ctx context.Context, webhook *models.Webhook) error
+	GetByID(ctx context.Context, id uuid.UUID) (*models.Webhook, error)
+	List(ctx context.Context) ([]models.Webhook, error)
+	// ListByEvent returns the enabled webhooks subscribed to the event
+	ListByEvent(ctx context.Context, event models.WebhookEvent) ([]models.Webhook, error)
+	Update(ctx context.Context, webhook *models.Webhook) error
+	Delete(ctx context.Context, id uuid.UUID) error
+}
+
+// WebhookDeliveryRepository defines the interface for the queue and log of webhook deliveries
+type WebhookDeliveryRepository interface {
+	Repository
+	// Create queues a delivery, due right away unless it has a next attempt
+	Create(ctx context.Context, delivery *models.WebhookDelivery) error
+	// ClaimDue returns up to limit pending deliveries due at now, oldest first, and moves
+	// their next attempt to now plus lease so other instances skip them while they are sent
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]models.WebhookDelivery, error)
+	// Record stores the outcome of an attempt: the status, attempts, response status,
+	// error, next attempt and delivery time of the delivery
+	Record(ctx context.Context, delivery *models.WebhookDelivery) error
+	List(ctx context.Context, filter WebhookDeliveryFilter) ([]models.WebhookDelivery, error)
+	// Total counts the deliveries matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter WebhookDeliveryFilter) (int, error)
+}
+
+// WebhookDeliveryFilter defines the filter options for listing webhook deliveries
+type WebhookDeliveryFilter struct {
+	WebhookID *uuid.UUID             // Filter by webhook ID
+	Event     *models.WebhookEvent   // Filter by event
+	Status    *models.DeliveryStatus // Filter by status
+	Limit     *int                   // Limit results
+	Offset    *int                   // Offset results
+}
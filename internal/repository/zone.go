@@ -14,18 +14,25 @@ type ZoneRepository interface {
 	Create(ctx context.Context, zone *models.Zone) error
 	Update(ctx context.Context, zone *models.Zone) error
 	Delete(ctx context.Context, id uuid.UUID) error
+	// DeleteCascade deletes the zone with its spot prices in one transaction. With reassignTo
+	// set the spot prices are moved to that zone instead, except those it already has a
+	// price for, which are deleted.
+	DeleteCascade(ctx context.Context, id uuid.UUID, reassignTo *uuid.UUID) (*models.DeletionSummary, error)
 	GetByID(ctx context.Context, id uuid.UUID) (*models.Zone, error)
 	GetByName(ctx context.Context, name string) (*models.Zone, error)
 	List(ctx context.Context, filter ZoneFilter) ([]models.Zone, error)
+	// Total counts the zones matching the filter, ignoring its limit and offset
+	Total(ctx context.Context, filter ZoneFilter) (int, error)
 }
 
 // ZoneFilter defines the filter options for listing zones
 type ZoneFilter struct {
-	Search    *string // Search by name
-	OrderBy   string  // Field to order by
-	OrderDesc bool    // Order descending
-	Limit     *int    // Limit results
-	Offset    *int    // Offset results
+	Search      *string // Search by name
+	CountryCode *string // Filter by ISO 3166-1 alpha-2 country code
+	OrderBy     string  // Field to order by
+	OrderDesc   bool    // Order descending
+	Limit       *int    // Limit results
+	Offset      *int    // Offset results
 }
 
 type ZoneRepositoryImpl struct {
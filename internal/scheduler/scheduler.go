@@ -0,0 +1,304 @@
+// Package scheduler runs recurring background jobs on cron schedules and records the
+// history of their runs, so failures show up without reading the logs
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+	"wattwatch/internal/metrics"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/worker"
+
+	"github.com/google/uuid"
+	"github.com/robfig/cron/v3"
+)
+
+var (
+	// ErrJobNotFound is returned when triggering a job that was never added
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning is returned when triggering a job with a run in progress
+	ErrJobRunning = errors.New("job is already running")
+)
+
+// recordTimeout bounds the time spent storing run history, which must not hold up jobs
+const recordTimeout = 5 * time.Second
+
+// Leader reports whether this instance should run scheduled jobs
+type Leader interface {
+	IsLeader() bool
+}
+
+type job struct {
+	name     string
+	schedule string
+	fn       func(ctx context.Context) error
+	// entry is the cron entry of a job with a schedule
+	entry cron.EntryID
+	// saved is set once the job is stored, runs reference it
+	saved bool
+}
+
+// Scheduler runs jobs on their cron schedules or when triggered. A job never runs twice at
+// the same time on one instance, a scheduled run is skipped while the previous one is in
+// progress.
+type Scheduler struct {
+	repo repository.JobRepository
+	cron *cron.Cron
+	runs *worker.Group
+	// leader is nil when scheduled runs execute on every instance
+	leader Leader
+	now    func() time.Time
+
+	mu      sync.Mutex
+	jobs    map[string]*job
+	running map[string]bool
+	started bool
+}
+
+// New creates a scheduler recording runs in repo
+func New(repo repository.JobRepository) *Scheduler {
+	return &Scheduler{
+		repo: repo,
+		// Standard five field expressions, as in the configuration
+		cron: cron.New(cron.WithParser(cron.NewParser(
+			cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow,
+		))),
+		runs:    worker.NewGroup(),
+		now:     time.Now,
+		jobs:    make(map[string]*job),
+		running: make(map[string]bool),
+	}
+}
+
+// SetLeader makes scheduled runs execute only while l reports this instance as leader.
+// Triggered runs are not affected.
+func (s *Scheduler) SetLeader(l Leader) {
+	s.leader = l
+}
+
+// Add registers a job running fn, replacing any job with the same name. fn returning an
+// error marks the run as failed. A job with an empty schedule only runs when triggered.
+func (s *Scheduler) Add(name, schedule string, fn func(ctx context.Context) error) error {
+	if schedule != "" {
+		if _, err := cron.ParseStandard(schedule); err != nil {
+			return fmt.Errorf("invalid schedule for job %s: %w", name, err)
+		}
+	}
+
+	s.mu.Lock()
+	if current, ok := s.jobs[name]; ok && current.entry != 0 {
+		s.cron.Remove(current.entry)
+	}
+	j := &job{name: name, schedule: schedule, fn: fn}
+	if schedule != "" {
+		id, err := s.cron.AddFunc(schedule, func() { s.scheduled(name) })
+		if err != nil {
+			delete(s.jobs, name)
+			s.mu.Unlock()
+			return fmt.Errorf("failed to schedule job %s: %w", name, err)
+		}
+		j.entry = id
+	}
+	s.jobs[name] = j
+	started := s.started
+	s.mu.Unlock()
+
+	// Jobs added before Run are stored when it starts
+	if started {
+		s.save(j)
+	}
+	return nil
+}
+
+// Remove unregisters a job. A run in progress is not interrupted.
+func (s *Scheduler) Remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if j, ok := s.jobs[name]; ok {
+		if j.entry != 0 {
+			s.cron.Remove(j.entry)
+		}
+		delete(s.jobs, name)
+	}
+}
+
+// Run stores the jobs and runs them on their schedules until ctx is cancelled
+func (s *Scheduler) Run(ctx context.Context) {
+	s.mu.Lock()
+	jobs := make([]*job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		jobs = append(jobs, j)
+	}
+	s.started = true
+	s.mu.Unlock()
+
+	for _, j := range jobs {
+		s.save(j)
+	}
+
+	s.cron.Start()
+	log.Printf("Job scheduler started with %d jobs", len(jobs))
+	<-ctx.Done()
+	s.cron.Stop()
+}
+
+// Shutdown stops scheduling runs and waits for running jobs to finish. When ctx expires
+// first the jobs are cancelled and an error names the ones still running.
+func (s *Scheduler) Shutdown(ctx context.Context) error {
+	s.cron.Stop()
+	return s.runs.Drain(ctx)
+}
+
+// Jobs returns the registered jobs ordered by name, with their latest run from repo
+func (s *Scheduler) Jobs(ctx context.Context) ([]models.Job, error) {
+	s.mu.Lock()
+	jobs := make([]models.Job, 0, len(s.jobs))
+	for _, j := range s.jobs {
+		job := models.Job{Name: j.name, Schedule: j.schedule, Running: s.running[j.name]}
+		if j.entry != 0 && s.started {
+			if next := s.cron.Entry(j.entry).Next; !next.IsZero() {
+				job.NextRun = &next
+			}
+		}
+		jobs = append(jobs, job)
+	}
+	s.mu.Unlock()
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Name < jobs[j].Name })
+
+	limit := 1
+	for i := range jobs {
+		runs, err := s.repo.ListRuns(ctx, repository.JobRunFilter{JobName: jobs[i].Name, Limit: &limit})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get last run of job %s: %w", jobs[i].Name, err)
+		}
+		if len(runs) > 0 {
+			jobs[i].LastRun = &runs[0]
+		}
+	}
+	return jobs, nil
+}
+
+// Trigger starts a run of a job in the background and returns it as recorded at the start
+func (s *Scheduler) Trigger(name string, triggeredBy *uuid.UUID) (*models.JobRun, error) {
+	return s.start(name, models.JobTriggerManual, triggeredBy)
+}
+
+// scheduled is called by cron when a job is due
+func (s *Scheduler) scheduled(name string) {
+	if s.leader != nil && !s.leader.IsLeader() {
+		log.Printf("Skipping scheduled run of job %s, another instance is leader", name)
+		return
+	}
+	if _, err := s.start(name, models.JobTriggerSchedule, nil); err != nil {
+		log.Printf("Skipping scheduled run of job %s: %v", name, err)
+	}
+}
+
+func (s *Scheduler) start(name string, trigger models.JobTrigger, triggeredBy *uuid.UUID) (*models.JobRun, error) {
+	s.mu.Lock()
+	j, ok := s.jobs[name]
+	if !ok {
+		s.mu.Unlock()
+		return nil, ErrJobNotFound
+	}
+	if s.running[name] {
+		s.mu.Unlock()
+		return nil, ErrJobRunning
+	}
+	s.running[name] = true
+	s.mu.Unlock()
+
+	run := &models.JobRun{
+		ID:          uuid.New(),
+		JobName:     name,
+		Trigger:     trigger,
+		TriggeredBy: triggeredBy,
+		Status:      models.JobStatusRunning,
+		StartedAt:   s.now(),
+	}
+	// A job runs even when its history can't be stored
+	recorded := s.save(j) && s.record(run, s.repo.CreateRun)
+	started := *run
+
+	// Runs are tracked so shutdown waits for them instead of cutting them off
+	err := s.runs.Go("job "+name, func(ctx context.Context) {
+		s.execute(ctx, j, run, recorded)
+	})
+	if err != nil {
+		s.finish(j, run, recorded, err)
+		return nil, err
+	}
+	return &started, nil
+}
+
+func (s *Scheduler) execute(ctx context.Context, j *job, run *models.JobRun, recorded bool) {
+	var err error
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+		s.finish(j, run, recorded, err)
+	}()
+
+	log.Printf("Running job %s (%s)", j.name, run.Trigger)
+	err = j.fn(ctx)
+}
+
+// finish records the outcome of a run and allows the job to run again
+func (s *Scheduler) finish(j *job, run *models.JobRun, recorded bool, err error) {
+	finished := s.now()
+	run.FinishedAt = &finished
+	run.Status = models.JobStatusSucceeded
+	if err != nil {
+		run.Status = models.JobStatusFailed
+		run.Error = err.Error()
+		log.Printf("Job %s failed: %v", j.name, err)
+	}
+	metrics.JobRunFinished(j.name, string(run.Status), finished.Sub(run.StartedAt))
+	if recorded {
+		s.record(run, s.repo.FinishRun)
+	}
+
+	s.mu.Lock()
+	delete(s.running, j.name)
+	s.mu.Unlock()
+}
+
+// save stores the job once, reporting whether it is stored
+func (s *Scheduler) save(j *job) bool {
+	s.mu.Lock()
+	saved := j.saved
+	s.mu.Unlock()
+	if saved {
+		return true
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	if err := s.repo.SaveJob(ctx, &models.Job{Name: j.name, Schedule: j.schedule}); err != nil {
+		log.Printf("Failed to store job %s: %v", j.name, err)
+		return false
+	}
+
+	s.mu.Lock()
+	j.saved = true
+	s.mu.Unlock()
+	return true
+}
+
+func (s *Scheduler) record(run *models.JobRun, store func(context.Context, *models.JobRun) error) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), recordTimeout)
+	defer cancel()
+	if err := store(ctx, run); err != nil {
+		log.Printf("Failed to record run of job %s: %v", run.JobName, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,167 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type follower struct{}
+
+func (follower) IsLeader() bool { return false }
+
+// runs waits for the runs of a job to finish and returns them, most recent first
+func runs(t *testing.T, s *Scheduler, name string) []models.JobRun {
+	t.Helper()
+	require.Eventually(t, func() bool {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return len(s.running) == 0
+	}, time.Second, time.Millisecond)
+	runs, err := s.repo.ListRuns(context.Background(), repository.JobRunFilter{JobName: name})
+	require.NoError(t, err)
+	return runs
+}
+
+func TestScheduler(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Records Runs", func(t *testing.T) {
+		s := New(memory.NewJobRepository(memory.NewStore()))
+		fail := false
+		require.NoError(t, s.Add("cleanup", "0 * * * *", func(ctx context.Context) error {
+			if fail {
+				return errors.New("database unavailable")
+			}
+			return nil
+		}))
+
+		userID := uuid.New()
+		run, err := s.Trigger("cleanup", &userID)
+		require.NoError(t, err)
+		assert.Equal(t, models.JobStatusRunning, run.Status)
+		assert.Equal(t, models.JobTriggerManual, run.Trigger)
+
+		recorded := runs(t, s, "cleanup")
+		require.Len(t, recorded, 1)
+		assert.Equal(t, run.ID, recorded[0].ID)
+		assert.Equal(t, models.JobStatusSucceeded, recorded[0].Status)
+		assert.Equal(t, &userID, recorded[0].TriggeredBy)
+		assert.NotNil(t, recorded[0].FinishedAt)
+
+		fail = true
+		s.scheduled("cleanup")
+		recorded = runs(t, s, "cleanup")
+		require.Len(t, recorded, 2)
+		assert.Equal(t, models.JobStatusFailed, recorded[0].Status)
+		assert.Equal(t, models.JobTriggerSchedule, recorded[0].Trigger)
+		assert.Equal(t, "database unavailable", recorded[0].Error)
+	})
+
+	t.Run("Records Panics As Failures", func(t *testing.T) {
+		s := New(memory.NewJobRepository(memory.NewStore()))
+		require.NoError(t, s.Add("broken", "", func(ctx context.Context) error { panic("nil map") }))
+
+		_, err := s.Trigger("broken", nil)
+		require.NoError(t, err)
+		recorded := runs(t, s, "broken")
+		require.Len(t, recorded, 1)
+		assert.Equal(t, models.JobStatusFailed, recorded[0].Status)
+		assert.Contains(t, recorded[0].Error, "nil map")
+	})
+
+	t.Run("Doesn't Run A Job Twice At Once", func(t *testing.T) {
+		s := New(memory.NewJobRepository(memory.NewStore()))
+		release := make(chan struct{})
+		require.NoError(t, s.Add("slow", "", func(ctx context.Context) error {
+			<-release
+			return nil
+		}))
+
+		_, err := s.Trigger("slow", nil)
+		require.NoError(t, err)
+		_, err = s.Trigger("slow", nil)
+		assert.ErrorIs(t, err, ErrJobRunning)
+
+		jobs, err := s.Jobs(ctx)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.True(t, jobs[0].Running)
+
+		close(release)
+		assert.Len(t, runs(t, s, "slow"), 1)
+	})
+
+	t.Run("Skips Scheduled Runs On Followers", func(t *testing.T) {
+		s := New(memory.NewJobRepository(memory.NewStore()))
+		ran := false
+		require.NoError(t, s.Add("cleanup", "0 * * * *", func(ctx context.Context) error {
+			ran = true
+			return nil
+		}))
+		s.SetLeader(follower{})
+
+		s.scheduled("cleanup")
+		assert.Empty(t, runs(t, s, "cleanup"))
+		assert.False(t, ran)
+
+		// Triggered runs are not limited to the leader
+		_, err := s.Trigger("cleanup", nil)
+		require.NoError(t, err)
+		assert.Len(t, runs(t, s, "cleanup"), 1)
+		assert.True(t, ran)
+	})
+
+	t.Run("Add Replaces And Remove Unregisters", func(t *testing.T) {
+		s := New(memory.NewJobRepository(memory.NewStore()))
+		noop := func(ctx context.Context) error { return nil }
+
+		assert.Error(t, s.Add("cleanup", "not a schedule", noop))
+		require.NoError(t, s.Add("cleanup", "0 * * * *", noop))
+		require.NoError(t, s.Add("cleanup", "30 * * * *", noop))
+		require.NoError(t, s.Add("retention", "", noop))
+		assert.Len(t, s.cron.Entries(), 1)
+
+		jobs, err := s.Jobs(ctx)
+		require.NoError(t, err)
+		require.Len(t, jobs, 2)
+		assert.Equal(t, "cleanup", jobs[0].Name)
+		assert.Equal(t, "30 * * * *", jobs[0].Schedule)
+		assert.Equal(t, "retention", jobs[1].Name)
+
+		s.Remove("cleanup")
+		assert.Empty(t, s.cron.Entries())
+		_, err = s.Trigger("cleanup", nil)
+		assert.ErrorIs(t, err, ErrJobNotFound)
+	})
+
+	t.Run("Lists Last Run", func(t *testing.T) {
+		s := New(memory.NewJobRepository(memory.NewStore()))
+		now := time.Date(2025, 3, 21, 12, 0, 0, 0, time.UTC)
+		s.now = func() time.Time { return now }
+		require.NoError(t, s.Add("cleanup", "0 * * * *", func(ctx context.Context) error { return nil }))
+
+		for range 2 {
+			now = now.Add(time.Hour)
+			_, err := s.Trigger("cleanup", nil)
+			require.NoError(t, err)
+			runs(t, s, "cleanup")
+		}
+
+		jobs, err := s.Jobs(ctx)
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		require.NotNil(t, jobs[0].LastRun)
+		assert.Equal(t, now, jobs[0].LastRun.StartedAt)
+		// Next runs are only known once the scheduler runs
+		assert.Nil(t, jobs[0].NextRun)
+	})
+}
@@ -0,0 +1,285 @@
+// Package selfcheck verifies at startup that the server's dependencies are usable
+package selfcheck
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/database"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+)
+
+// ErrSkipped is returned by a check that does not apply to the current configuration
+var ErrSkipped = errors.New("skipped")
+
+// Status is the outcome of a check
+type Status string
+
+const (
+	StatusOK      Status = "ok"
+	StatusSkipped Status = "skip"
+	// StatusWarn is a failed check that is not critical
+	StatusWarn Status = "warn"
+	StatusFail Status = "fail"
+)
+
+// Check is a single startup check. Run returns a short description of what was found.
+type Check struct {
+	Name string
+	// Critical checks make the report fail, others only warn
+	Critical bool
+	Run      func(ctx context.Context) (string, error)
+}
+
+// Result is the outcome of a check
+type Result struct {
+	Name   string
+	Status Status
+	Detail string
+}
+
+// Report holds the results of all checks in the order they ran
+type Report struct {
+	Results []Result
+}
+
+// Run runs the checks one after another, each bounded by timeout
+func Run(ctx context.Context, checks []Check, timeout time.Duration) *Report {
+	report := &Report{}
+	for _, check := range checks {
+		checkCtx, cancel := context.WithTimeout(ctx, timeout)
+		detail, err := check.Run(checkCtx)
+		cancel()
+
+		result := Result{Name: check.Name, Status: StatusOK, Detail: detail}
+		switch {
+		case errors.Is(err, ErrSkipped):
+			result.Status = StatusSkipped
+		case err != nil && check.Critical:
+			result.Status = StatusFail
+			result.Detail = err.Error()
+		case err != nil:
+			result.Status = StatusWarn
+			result.Detail = err.Error()
+		}
+		report.Results = append(report.Results, result)
+	}
+	return report
+}
+
+// Failed returns the critical checks that failed
+func (r *Report) Failed() []Result {
+	var failed []Result
+	for _, result := range r.Results {
+		if result.Status == StatusFail {
+			failed = append(failed, result)
+		}
+	}
+	return failed
+}
+
+// Log writes a summary with one line per check
+func (r *Report) Log() {
+	var summary strings.Builder
+	summary.WriteString("Startup checks:")
+	for _, result := range r.Results {
+		fmt.Fprintf(&summary, "\n  %-4s  %s", result.Status, result.Name)
+		if result.Detail != "" {
+			fmt.Fprintf(&summary, ": %s", result.Detail)
+		}
+	}
+	log.Println(summary.String())
+}
+
+// Default returns the checks run when the server starts. The database, its schema and the
+// JWT secret are critical, mail and provider endpoints only warn since the API works without them.
+func Default(cfg *config.Config, db *sql.DB, replicas *repository.ReplicaSet, providers []provider.Provider) []Check {
+	return []Check{
+		Database(db),
+		Replicas(replicas),
+		Migrations(cfg.Database),
+		JWTSecret(cfg.Auth.JWTSecret),
+		SMTP(cfg.EmailSettings()),
+		Providers(providers),
+	}
+}
+
+// Readiness returns the checks deciding whether the server can take traffic. They are built
+// from the current configuration, so email settings reloaded since startup are used.
+func Readiness(cfg *config.Config, db *sql.DB, replicas *repository.ReplicaSet, providers []provider.Provider) []Check {
+	return []Check{
+		Database(db),
+		Replicas(replicas),
+		Migrations(cfg.Database),
+		Email(cfg.EmailSettings()),
+		Providers(providers),
+	}
+}
+
+// Database checks that the database accepts connections
+func Database(db *sql.DB) Check {
+	return Check{
+		Name:     "database",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			var version string
+			if err := db.QueryRowContext(ctx, "SHOW server_version").Scan(&version); err != nil {
+				return "", fmt.Errorf("not reachable: %w", err)
+			}
+			return "PostgreSQL " + version, nil
+		},
+	}
+}
+
+// Replicas checks the read-only replicas of set, nil when none are configured. Reads go to
+// the primary while none answers, so it only warns.
+func Replicas(set *repository.ReplicaSet) Check {
+	return Check{
+		Name: "replicas",
+		Run: func(ctx context.Context) (string, error) {
+			if set == nil {
+				return "not configured", ErrSkipped
+			}
+			set.Check(ctx)
+			healthy := set.Healthy()
+			if healthy == 0 {
+				return "", fmt.Errorf("none of %d reachable, reading from the primary", set.Len())
+			}
+			return fmt.Sprintf("%d of %d reachable", healthy, set.Len()), nil
+		},
+	}
+}
+
+// Migrations checks that the schema is at the latest version and not left dirty by a failed migration
+func Migrations(cfg config.DatabaseConfig) Check {
+	return Check{
+		Name:     "migrations",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			state, err := database.MigrationStatus(cfg)
+			if err != nil {
+				return "", err
+			}
+			if state.Dirty {
+				return "", fmt.Errorf("version %d is dirty, a migration failed and must be repaired by hand", state.Current)
+			}
+			if state.Pending() {
+				return "", fmt.Errorf("at version %d, %d is available", state.Current, state.Latest)
+			}
+			return fmt.Sprintf("at version %d", state.Current), nil
+		},
+	}
+}
+
+// minJWTSecretLength is the length of a 256 bit secret, as recommended for HS256
+const minJWTSecretLength = 32
+
+// placeholderSecrets are values from examples and tutorials that must not sign real tokens
+var placeholderSecrets = []string{"your-secret-key-here", "secret", "changeme", "change-me", "jwt-secret"}
+
+// JWTSecret checks that tokens are signed with a secret that is hard to guess
+func JWTSecret(secret string) Check {
+	return Check{
+		Name:     "jwt_secret",
+		Critical: true,
+		Run: func(ctx context.Context) (string, error) {
+			for _, placeholder := range placeholderSecrets {
+				if strings.EqualFold(secret, placeholder) {
+					return "", fmt.Errorf("is the placeholder %q, generate a random secret", secret)
+				}
+			}
+			if len(secret) < minJWTSecretLength {
+				return "", fmt.Errorf("is %d bytes, use at least %d", len(secret), minJWTSecretLength)
+			}
+			return fmt.Sprintf("%d bytes", len(secret)), nil
+		},
+	}
+}
+
+// SMTP checks that the mail server accepts connections
+func SMTP(cfg config.EmailConfig) Check {
+	return Check{
+		Name: "smtp",
+		Run: func(ctx context.Context) (string, error) {
+			if cfg.Provider != "" && cfg.Provider != config.EmailProviderSMTP {
+				return "email is sent with " + cfg.Provider, ErrSkipped
+			}
+			if cfg.SMTPHost == "" {
+				return "not configured", ErrSkipped
+			}
+			addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort))
+			conn, err := (&net.Dialer{}).DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return "", err
+			}
+			conn.Close()
+			return addr, nil
+		},
+	}
+}
+
+// Email checks that the configured email backend can be used. The SMTP server is dialed,
+// delivery APIs only need their credentials since every request is billed or rate limited.
+func Email(cfg config.EmailConfig) Check {
+	return Check{
+		Name: "email",
+		Run: func(ctx context.Context) (string, error) {
+			switch cfg.Provider {
+			case config.EmailProviderConsole:
+				return "logged to the console", nil
+			case config.EmailProviderSendGrid:
+				if cfg.SendGridAPIKey == "" {
+					return "", errors.New("sendgrid has no API key")
+				}
+				return "sent with sendgrid", nil
+			case config.EmailProviderMailgun:
+				if cfg.MailgunDomain == "" || cfg.MailgunAPIKey == "" {
+					return "", errors.New("mailgun has no domain or API key")
+				}
+				return "sent with mailgun", nil
+			}
+			detail, err := SMTP(cfg).Run(ctx)
+			if err != nil {
+				return detail, err
+			}
+			return "sent with smtp at " + detail, nil
+		},
+	}
+}
+
+// Providers checks that the services behind enabled providers can be reached
+func Providers(providers []provider.Provider) Check {
+	return Check{
+		Name: "providers",
+		Run: func(ctx context.Context) (string, error) {
+			var checked []string
+			var errs []error
+			for _, p := range providers {
+				checker, ok := p.(provider.Checker)
+				if !ok || !p.GetConfig().Enabled {
+					continue
+				}
+				if err := checker.Check(ctx); err != nil {
+					errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+					continue
+				}
+				checked = append(checked, p.Name())
+			}
+			if err := errors.Join(errs...); err != nil {
+				return "", err
+			}
+			if len(checked) == 0 {
+				return "none enabled", ErrSkipped
+			}
+			return strings.Join(checked, ", ") + " reachable", nil
+		},
+	}
+}
@@ -0,0 +1,142 @@
+package selfcheck
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/provider"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	checks := []Check{
+		{Name: "ok", Critical: true, Run: func(ctx context.Context) (string, error) { return "fine", nil }},
+		{Name: "skipped", Run: func(ctx context.Context) (string, error) { return "not configured", ErrSkipped }},
+		{Name: "warning", Run: func(ctx context.Context) (string, error) { return "", errors.New("unreachable") }},
+		{Name: "failure", Critical: true, Run: func(ctx context.Context) (string, error) { return "", errors.New("broken") }},
+		{Name: "timeout", Critical: true, Run: func(ctx context.Context) (string, error) {
+			<-ctx.Done()
+			return "", ctx.Err()
+		}},
+	}
+
+	report := Run(context.Background(), checks, 10*time.Millisecond)
+	assert.Equal(t, []Result{
+		{Name: "ok", Status: StatusOK, Detail: "fine"},
+		{Name: "skipped", Status: StatusSkipped, Detail: "not configured"},
+		{Name: "warning", Status: StatusWarn, Detail: "unreachable"},
+		{Name: "failure", Status: StatusFail, Detail: "broken"},
+		{Name: "timeout", Status: StatusFail, Detail: context.DeadlineExceeded.Error()},
+	}, report.Results)
+
+	failed := report.Failed()
+	require.Len(t, failed, 2)
+	assert.Equal(t, "failure", failed[0].Name)
+	assert.Equal(t, "timeout", failed[1].Name)
+}
+
+func TestJWTSecret(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		wantErr string
+	}{
+		{name: "Random Secret", secret: "kq3VZp8mW2xY7tN4rB6cD1fG5hJ9lS0a"},
+		{name: "Placeholder", secret: "your-secret-key-here", wantErr: "placeholder"},
+		{name: "Too Short", secret: "s3cr3t-but-short", wantErr: "use at least 32"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := JWTSecret(tt.secret).Run(context.Background())
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSMTP(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	port := ln.Addr().(*net.TCPAddr).Port
+
+	_, err = SMTP(config.EmailConfig{SMTPHost: "127.0.0.1", SMTPPort: port}).Run(context.Background())
+	assert.NoError(t, err)
+
+	ln.Close()
+	_, err = SMTP(config.EmailConfig{SMTPHost: "127.0.0.1", SMTPPort: port}).Run(context.Background())
+	assert.Error(t, err, "nothing listens on the port anymore")
+
+	_, err = SMTP(config.EmailConfig{}).Run(context.Background())
+	assert.ErrorIs(t, err, ErrSkipped)
+}
+
+func TestEmail(t *testing.T) {
+	detail, err := Email(config.EmailConfig{Provider: config.EmailProviderConsole}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "logged to the console", detail)
+
+	_, err = Email(config.EmailConfig{Provider: config.EmailProviderSendGrid, SendGridAPIKey: "key"}).Run(context.Background())
+	assert.NoError(t, err)
+	_, err = Email(config.EmailConfig{Provider: config.EmailProviderMailgun, MailgunDomain: "mg.example.com"}).Run(context.Background())
+	assert.ErrorContains(t, err, "mailgun")
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+	detail, err = Email(config.EmailConfig{SMTPHost: "127.0.0.1", SMTPPort: port}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Contains(t, detail, "sent with smtp at 127.0.0.1")
+
+	_, err = Email(config.EmailConfig{}).Run(context.Background())
+	assert.ErrorIs(t, err, ErrSkipped)
+}
+
+// checkedProvider is a provider whose remote service is reachable unless err is set
+type checkedProvider struct {
+	provider.BaseProvider
+	name string
+	err  error
+}
+
+func (p *checkedProvider) Name() string                  { return p.name }
+func (p *checkedProvider) Run(ctx context.Context) error { return nil }
+func (p *checkedProvider) RunWithOptions(ctx context.Context, _ provider.RunOptions) error {
+	return nil
+}
+func (p *checkedProvider) Fetch(ctx context.Context, _ string, _ provider.DateRange) error {
+	return nil
+}
+func (p *checkedProvider) Check(ctx context.Context) error { return p.err }
+
+func TestProviders(t *testing.T) {
+	newProvider := func(name string, enabled bool, err error) provider.Provider {
+		return &checkedProvider{
+			BaseProvider: provider.NewBaseProvider(nil, provider.Config{Enabled: enabled}),
+			name:         name,
+			err:          err,
+		}
+	}
+
+	detail, err := Providers([]provider.Provider{
+		newProvider("nordpool", true, nil),
+		newProvider("entsoe", false, errors.New("unreachable")),
+	}).Run(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "nordpool reachable", detail)
+
+	_, err = Providers([]provider.Provider{newProvider("nordpool", true, errors.New("connection refused"))}).Run(context.Background())
+	assert.ErrorContains(t, err, "nordpool: connection refused")
+
+	_, err = Providers(nil).Run(context.Background())
+	assert.ErrorIs(t, err, ErrSkipped)
+}
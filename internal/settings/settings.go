@@ -0,0 +1,517 @@
+// Package settings layers runtime overrides stored in the database over the env and file
+// configuration, for operational values that shouldn't need a redeploy
+package settings
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrUnknownSetting is returned for keys that can't be changed at runtime
+	ErrUnknownSetting = errors.New("unknown runtime setting")
+	// ErrInvalidValue is returned when a value doesn't parse or is rejected by a validator
+	ErrInvalidValue = errors.New("invalid setting value")
+)
+
+// Runtime setting keys, named like the config settings they override
+const (
+	KeyRegistrationOpen = "auth.registration_open"
+	KeyDefaultRole      = "auth.default_role"
+	KeyThrottleInterval = "push.throttle_interval"
+	// Secret rotations end at the same moment on every instance
+	KeyJWTPreviousSecretUntil     = "auth.jwt_previous_secret_until"
+	KeyWebhookPreviousSecretUntil = "email.webhook_previous_secret_until"
+	KeyEntsoeToken                = "providers.entsoe.token"
+	KeyLockoutThreshold           = "auth.lockout_threshold"
+	KeyLockoutWindow              = "auth.lockout_window"
+	KeyPasswordHistoryDepth       = "password.history_depth"
+)
+
+// ProviderEnabledKey returns the key of the runtime setting that enables the named provider,
+// one exists for every provider in the configuration
+func ProviderEnabledKey(name string) string {
+	return "providers." + name + ".enabled"
+}
+
+// DefaultRefreshInterval is how often overrides are reloaded, so changes made through
+// another instance are picked up
+const DefaultRefreshInterval = 30 * time.Second
+
+// Value types
+const (
+	TypeBool     = "bool"
+	TypeInt      = "int"
+	TypeString   = "string"
+	TypeDuration = "duration"
+	// TypeTime values are RFC 3339 timestamps, settings without a configured value are empty
+	TypeTime = "time"
+	// TypeSecret values are strings that are never shown, only whether one is set
+	TypeSecret = "secret"
+)
+
+// maskedValue replaces secret values in the API representation
+const maskedValue = "********"
+
+// definition describes a runtime setting and where its configured value comes from
+type definition struct {
+	key         string
+	typ         string
+	description string
+	config      func(c *config.Config) string
+}
+
+var definitions = []definition{
+	{
+		key:         KeyRegistrationOpen,
+		typ:         TypeBool,
+		description: "Whether anyone can register an account, the first user and admins always can",
+		config:      func(c *config.Config) string { return strconv.FormatBool(c.Auth.RegistrationOpen) },
+	},
+	{
+		key:         KeyDefaultRole,
+		typ:         TypeString,
+		description: "Role given to users who register themselves",
+		config:      func(c *config.Config) string { return c.Auth.DefaultRole },
+	},
+	{
+		key:         KeyThrottleInterval,
+		typ:         TypeDuration,
+		description: "How often the same price or consumption alert is sent before further alerts are batched into a digest, 0s disables throttling",
+		config:      func(c *config.Config) string { return c.ThrottleInterval().String() },
+	},
+	{
+		key:         KeyJWTPreviousSecretUntil,
+		typ:         TypeTime,
+		description: "When tokens signed with auth.jwt_previous_secret stop being accepted, empty accepts them while the secret is configured",
+		config:      func(c *config.Config) string { return "" },
+	},
+	{
+		key:         KeyWebhookPreviousSecretUntil,
+		typ:         TypeTime,
+		description: "When email webhooks sending email.webhook_previous_secret stop being accepted, empty accepts them while the secret is configured",
+		config:      func(c *config.Config) string { return "" },
+	},
+	{
+		key:         KeyEntsoeToken,
+		typ:         TypeSecret,
+		description: "Security token for the ENTSO-E Transparency Platform API",
+		config:      func(c *config.Config) string { return c.EntsoeToken() },
+	},
+	{
+		key:         KeyLockoutThreshold,
+		typ:         TypeInt,
+		description: "Failed logins within auth.lockout_window that lock an account, 0 disables lockouts",
+		config:      func(c *config.Config) string { return strconv.Itoa(c.Auth.LockoutThreshold) },
+	},
+	{
+		key:         KeyLockoutWindow,
+		typ:         TypeDuration,
+		description: "How far back failed logins count towards a lockout, which also lasts as long",
+		config:      func(c *config.Config) string { return c.Auth.LockoutWindow.String() },
+	},
+	{
+		key:         KeyPasswordHistoryDepth,
+		typ:         TypeInt,
+		description: "How many previous passwords from the past 90 days a user can't reuse, 0 allows reuse",
+		config:      func(c *config.Config) string { return strconv.Itoa(c.Password.HistoryDepth) },
+	},
+}
+
+// Store caches the overrides in memory. Changes made through it apply right away, changes
+// made by other instances once the cache is refreshed.
+type Store struct {
+	repo repository.SettingRepository
+	cfg  *config.Config
+
+	mu         sync.RWMutex
+	overrides  map[string]models.Setting
+	validators map[string]func(ctx context.Context, value string) error
+	onChange   []func()
+}
+
+// NewStore creates a store that falls back to cfg for settings without an override.
+// Call Refresh to load the overrides.
+func NewStore(repo repository.SettingRepository, cfg *config.Config) *Store {
+	return &Store{
+		repo:       repo,
+		cfg:        cfg,
+		overrides:  make(map[string]models.Setting),
+		validators: make(map[string]func(ctx context.Context, value string) error),
+	}
+}
+
+// Validator adds a check for values of key beyond parsing, such as a role existing
+func (s *Store) Validator(key string, fn func(ctx context.Context, value string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.validators[key] = fn
+}
+
+// OnChange registers fn to run after overrides change, for values services cache
+func (s *Store) OnChange(fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onChange = append(s.onChange, fn)
+}
+
+// Refresh reloads the overrides from the database
+func (s *Store) Refresh(ctx context.Context) error {
+	list, err := s.repo.List(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load runtime settings: %w", err)
+	}
+
+	overrides := make(map[string]models.Setting, len(list))
+	for _, setting := range list {
+		if _, ok := s.lookup(setting.Key); !ok {
+			// Left behind by a newer or older release
+			continue
+		}
+		overrides[setting.Key] = setting
+	}
+
+	s.mu.Lock()
+	changed := len(overrides) != len(s.overrides)
+	for key, setting := range overrides {
+		if current, ok := s.overrides[key]; !ok || current.Value != setting.Value {
+			changed = true
+		}
+	}
+	s.overrides = overrides
+	s.mu.Unlock()
+
+	if changed {
+		s.notify()
+	}
+	return nil
+}
+
+// Run refreshes the overrides every interval until ctx is cancelled
+func (s *Store) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+				log.Printf("Failed to refresh runtime settings: %v", err)
+			}
+		}
+	}
+}
+
+// List returns every runtime setting with its effective value
+func (s *Store) List() []models.RuntimeSetting {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	defs := s.definitions()
+	list := make([]models.RuntimeSetting, 0, len(defs))
+	for _, def := range defs {
+		list = append(list, s.describe(def))
+	}
+	return list
+}
+
+// Get returns a runtime setting with its effective value
+func (s *Store) Get(key string) (*models.RuntimeSetting, error) {
+	def, ok := s.lookup(key)
+	if !ok {
+		return nil, ErrUnknownSetting
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	setting := s.describe(def)
+	return &setting, nil
+}
+
+// Set stores an override for key after validating it
+func (s *Store) Set(ctx context.Context, key, value string, updatedBy *uuid.UUID) (*models.RuntimeSetting, error) {
+	def, err := s.check(ctx, key, value)
+	if err != nil {
+		return nil, err
+	}
+
+	setting := models.Setting{Key: key, Value: value, UpdatedBy: updatedBy}
+	if err := s.repo.Upsert(ctx, &setting); err != nil {
+		return nil, fmt.Errorf("failed to store setting: %w", err)
+	}
+
+	s.mu.Lock()
+	s.overrides[key] = setting
+	described := s.describe(def)
+	s.mu.Unlock()
+
+	s.notify()
+	return &described, nil
+}
+
+// SetMany stores overrides for several keys. Every value is validated before any is
+// stored, so an invalid one leaves all settings as they were.
+func (s *Store) SetMany(ctx context.Context, values map[string]string, updatedBy *uuid.UUID) ([]models.RuntimeSetting, error) {
+	keys := slices.Sorted(maps.Keys(values))
+	defs := make([]definition, 0, len(keys))
+	for _, key := range keys {
+		def, err := s.check(ctx, key, values[key])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", key, err)
+		}
+		defs = append(defs, def)
+	}
+
+	stored := make([]models.Setting, 0, len(defs))
+	for _, def := range defs {
+		setting := models.Setting{Key: def.key, Value: values[def.key], UpdatedBy: updatedBy}
+		if err := s.repo.Upsert(ctx, &setting); err != nil {
+			return nil, fmt.Errorf("failed to store setting %s: %w", def.key, err)
+		}
+		stored = append(stored, setting)
+	}
+
+	s.mu.Lock()
+	described := make([]models.RuntimeSetting, 0, len(defs))
+	for i, def := range defs {
+		s.overrides[def.key] = stored[i]
+		described = append(described, s.describe(def))
+	}
+	s.mu.Unlock()
+
+	s.notify()
+	return described, nil
+}
+
+// check validates value for key and returns the definition of the key
+func (s *Store) check(ctx context.Context, key, value string) (definition, error) {
+	def, ok := s.lookup(key)
+	if !ok {
+		return definition{}, ErrUnknownSetting
+	}
+	if err := parse(def.typ, value); err != nil {
+		return definition{}, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+	}
+	s.mu.RLock()
+	validate := s.validators[key]
+	s.mu.RUnlock()
+	if validate != nil {
+		if err := validate(ctx, value); err != nil {
+			return definition{}, fmt.Errorf("%w: %v", ErrInvalidValue, err)
+		}
+	}
+	return def, nil
+}
+
+// Reset removes the override for key so the configured value applies again
+func (s *Store) Reset(ctx context.Context, key string) (*models.RuntimeSetting, error) {
+	def, ok := s.lookup(key)
+	if !ok {
+		return nil, ErrUnknownSetting
+	}
+	if err := s.repo.Delete(ctx, key); err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, fmt.Errorf("failed to remove setting: %w", err)
+	}
+
+	s.mu.Lock()
+	delete(s.overrides, key)
+	described := s.describe(def)
+	s.mu.Unlock()
+
+	s.notify()
+	return &described, nil
+}
+
+// RegistrationOpen reports whether anyone can register an account
+func (s *Store) RegistrationOpen() bool {
+	open, _ := strconv.ParseBool(s.value(KeyRegistrationOpen))
+	return open
+}
+
+// DefaultRole returns the name of the role given to users who register themselves
+func (s *Store) DefaultRole() string {
+	return s.value(KeyDefaultRole)
+}
+
+// ThrottleInterval returns how often the same alert is sent before further ones are batched
+func (s *Store) ThrottleInterval() time.Duration {
+	d, _ := time.ParseDuration(s.value(KeyThrottleInterval))
+	return d
+}
+
+// JWTPreviousSecretUntil returns when the previous JWT secret stops being accepted, the zero
+// time when no end is set
+func (s *Store) JWTPreviousSecretUntil() time.Time {
+	return s.timeValue(KeyJWTPreviousSecretUntil)
+}
+
+// WebhookPreviousSecretUntil returns when the previous email webhook secret stops being
+// accepted, the zero time when no end is set
+func (s *Store) WebhookPreviousSecretUntil() time.Time {
+	return s.timeValue(KeyWebhookPreviousSecretUntil)
+}
+
+// EntsoeToken returns the ENTSO-E API token, empty when none is set
+func (s *Store) EntsoeToken() string {
+	return s.value(KeyEntsoeToken)
+}
+
+// LockoutThreshold returns how many failed logins within the lockout window lock an
+// account, 0 when lockouts are disabled
+func (s *Store) LockoutThreshold() int {
+	return s.intValue(KeyLockoutThreshold)
+}
+
+// LockoutWindow returns how far back failed logins count towards a lockout
+func (s *Store) LockoutWindow() time.Duration {
+	d, _ := time.ParseDuration(s.value(KeyLockoutWindow))
+	return d
+}
+
+// ProviderEnabled reports whether the named provider runs on schedule
+func (s *Store) ProviderEnabled(name string) bool {
+	enabled, _ := strconv.ParseBool(s.value(ProviderEnabledKey(name)))
+	return enabled
+}
+
+// PasswordHistoryDepth returns how many previous passwords a user can't reuse
+func (s *Store) PasswordHistoryDepth() int {
+	return s.intValue(KeyPasswordHistoryDepth)
+}
+
+func (s *Store) intValue(key string) int {
+	n, _ := strconv.Atoi(s.value(key))
+	return n
+}
+
+func (s *Store) timeValue(key string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s.value(key))
+	return t
+}
+
+// value returns the effective value of a known key
+func (s *Store) value(key string) string {
+	def, _ := s.lookup(key)
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if setting, ok := s.overrides[key]; ok {
+		return setting.Value
+	}
+	return def.config(s.cfg)
+}
+
+// describe builds the API representation of def, s.mu must be held
+func (s *Store) describe(def definition) models.RuntimeSetting {
+	setting := models.RuntimeSetting{
+		Key:         def.key,
+		Type:        def.typ,
+		Description: def.description,
+		ConfigValue: def.config(s.cfg),
+	}
+	setting.Value = setting.ConfigValue
+	if override, ok := s.overrides[def.key]; ok {
+		updatedAt := override.UpdatedAt
+		setting.Value = override.Value
+		setting.Overridden = true
+		setting.UpdatedBy = override.UpdatedBy
+		setting.UpdatedAt = &updatedAt
+	}
+	if def.typ == TypeSecret {
+		setting.Value = mask(setting.Value)
+		setting.ConfigValue = mask(setting.ConfigValue)
+	}
+	return setting
+}
+
+// mask hides a secret value, keeping whether it is set
+func mask(value string) string {
+	if value == "" {
+		return ""
+	}
+	return maskedValue
+}
+
+func (s *Store) notify() {
+	s.mu.RLock()
+	callbacks := append([]func(){}, s.onChange...)
+	s.mu.RUnlock()
+	for _, fn := range callbacks {
+		fn()
+	}
+}
+
+// definitions returns the fixed settings followed by the enabled setting of each
+// configured provider
+func (s *Store) definitions() []definition {
+	defs := slices.Clone(definitions)
+	for _, name := range s.cfg.ProviderNames() {
+		defs = append(defs, definition{
+			key:         ProviderEnabledKey(name),
+			typ:         TypeBool,
+			description: "Whether the " + name + " provider fetches prices on its schedule",
+			config: func(c *config.Config) string {
+				p, _ := c.ProviderSettings(name)
+				return strconv.FormatBool(p.Enabled)
+			},
+		})
+	}
+	return defs
+}
+
+func (s *Store) lookup(key string) (definition, bool) {
+	for _, def := range s.definitions() {
+		if def.key == key {
+			return def, true
+		}
+	}
+	return definition{}, false
+}
+
+// parse checks that value is valid for the type
+func parse(typ, value string) error {
+	switch typ {
+	case TypeBool:
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("expected true or false, got %q", value)
+		}
+	case TypeInt:
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("expected a whole number, got %q", value)
+		}
+		if n < 0 {
+			return fmt.Errorf("must not be negative, got %d", n)
+		}
+	case TypeDuration:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("expected a duration such as \"30m\" or \"6h\", got %q", value)
+		}
+		if d < 0 {
+			return fmt.Errorf("must not be negative, got %s", d)
+		}
+	case TypeTime:
+		if _, err := time.Parse(time.RFC3339, value); err != nil {
+			return fmt.Errorf("expected a time such as \"2025-01-31T12:00:00Z\", got %q", value)
+		}
+	case TypeString, TypeSecret:
+		if value == "" {
+			return errors.New("must not be empty")
+		}
+	}
+	return nil
+}
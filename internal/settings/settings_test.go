@@ -0,0 +1,240 @@
+package settings
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/provider"
+	"wattwatch/internal/repository"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepo keeps overrides in a map, shared between stores to act like one database
+type memoryRepo struct {
+	repository.BaseRepository
+	settings map[string]models.Setting
+}
+
+func (r *memoryRepo) Upsert(ctx context.Context, setting *models.Setting) error {
+	setting.UpdatedAt = time.Now()
+	r.settings[setting.Key] = *setting
+	return nil
+}
+
+func (r *memoryRepo) List(ctx context.Context) ([]models.Setting, error) {
+	list := []models.Setting{}
+	for _, s := range r.settings {
+		list = append(list, s)
+	}
+	return list, nil
+}
+
+func (r *memoryRepo) Delete(ctx context.Context, key string) error {
+	if _, ok := r.settings[key]; !ok {
+		return repository.ErrNotFound
+	}
+	delete(r.settings, key)
+	return nil
+}
+
+func newTestConfig() *config.Config {
+	return &config.Config{
+		Auth: config.AuthConfig{RegistrationOpen: true, DefaultRole: "user"},
+		Push: config.PushConfig{ThrottleInterval: 6 * time.Hour},
+	}
+}
+
+func TestStore(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryRepo{settings: map[string]models.Setting{}}
+	store := NewStore(repo, newTestConfig())
+	require.NoError(t, store.Refresh(ctx))
+
+	// Without overrides the configured values apply
+	assert.True(t, store.RegistrationOpen())
+	assert.Equal(t, "user", store.DefaultRole())
+	assert.Equal(t, 6*time.Hour, store.ThrottleInterval())
+
+	var changes int
+	store.OnChange(func() { changes++ })
+
+	admin := uuid.New()
+	setting, err := store.Set(ctx, KeyRegistrationOpen, "false", &admin)
+	require.NoError(t, err)
+	assert.False(t, store.RegistrationOpen())
+	assert.Equal(t, "false", setting.Value)
+	assert.Equal(t, "true", setting.ConfigValue)
+	assert.True(t, setting.Overridden)
+	assert.Equal(t, &admin, setting.UpdatedBy)
+	assert.Equal(t, 1, changes)
+
+	_, err = store.Set(ctx, KeyThrottleInterval, "soon", nil)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = store.Set(ctx, KeyThrottleInterval, "-1h", nil)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = store.Set(ctx, "database.host", "elsewhere", nil)
+	assert.ErrorIs(t, err, ErrUnknownSetting)
+
+	store.Validator(KeyDefaultRole, func(ctx context.Context, value string) error {
+		if value != "viewer" {
+			return errors.New("no such role")
+		}
+		return nil
+	})
+	_, err = store.Set(ctx, KeyDefaultRole, "superuser", nil)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	_, err = store.Set(ctx, KeyDefaultRole, "viewer", nil)
+	require.NoError(t, err)
+
+	// Another instance sharing the database picks the overrides up on refresh
+	other := NewStore(repo, newTestConfig())
+	require.NoError(t, other.Refresh(ctx))
+	assert.False(t, other.RegistrationOpen())
+	assert.Equal(t, "viewer", other.DefaultRole())
+
+	setting, err = store.Reset(ctx, KeyRegistrationOpen)
+	require.NoError(t, err)
+	assert.False(t, setting.Overridden)
+	assert.True(t, store.RegistrationOpen())
+
+	var otherChanges int
+	other.OnChange(func() { otherChanges++ })
+	require.NoError(t, other.Refresh(ctx))
+	assert.True(t, other.RegistrationOpen())
+	assert.Equal(t, 1, otherChanges)
+	require.NoError(t, other.Refresh(ctx))
+	assert.Equal(t, 1, otherChanges, "unchanged overrides don't notify")
+
+	// Resetting a setting without an override is not an error
+	_, err = store.Reset(ctx, KeyRegistrationOpen)
+	assert.NoError(t, err)
+	assert.Len(t, store.List(), len(definitions))
+}
+
+func TestStoreSecretRotation(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryRepo{settings: map[string]models.Setting{}}
+	store := NewStore(repo, newTestConfig())
+	require.NoError(t, store.Refresh(ctx))
+
+	// Without an end the previous secrets are accepted while they are configured
+	assert.True(t, store.JWTPreviousSecretUntil().IsZero())
+	assert.True(t, store.WebhookPreviousSecretUntil().IsZero())
+
+	_, err := store.Set(ctx, KeyJWTPreviousSecretUntil, "tomorrow", nil)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+
+	_, err = store.Set(ctx, KeyJWTPreviousSecretUntil, "2025-01-31T12:00:00Z", nil)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 31, 12, 0, 0, 0, time.UTC), store.JWTPreviousSecretUntil().UTC())
+
+	// Every instance ends the rotation at the same time once it has refreshed
+	other := NewStore(repo, newTestConfig())
+	require.NoError(t, other.Refresh(ctx))
+	assert.Equal(t, store.JWTPreviousSecretUntil(), other.JWTPreviousSecretUntil())
+	assert.True(t, other.WebhookPreviousSecretUntil().IsZero())
+}
+
+func TestStoreSecret(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryRepo{settings: map[string]models.Setting{}}
+	cfg := newTestConfig()
+	cfg.Entsoe.Token = "configured"
+	store := NewStore(repo, cfg)
+	require.NoError(t, store.Refresh(ctx))
+	assert.Equal(t, "configured", store.EntsoeToken())
+
+	_, err := store.Set(ctx, KeyEntsoeToken, "", nil)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+
+	// Secrets apply but are never shown, only whether one is set
+	setting, err := store.Set(ctx, KeyEntsoeToken, "overridden", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "overridden", store.EntsoeToken())
+	assert.Equal(t, maskedValue, setting.Value)
+	assert.Equal(t, maskedValue, setting.ConfigValue)
+
+	cfg.Entsoe.Token = ""
+	setting, err = store.Reset(ctx, KeyEntsoeToken)
+	require.NoError(t, err)
+	assert.Empty(t, setting.Value)
+	assert.Empty(t, store.EntsoeToken())
+}
+
+func TestStoreSetMany(t *testing.T) {
+	ctx := context.Background()
+	repo := &memoryRepo{settings: map[string]models.Setting{}}
+	cfg := newTestConfig()
+	cfg.Auth.LockoutThreshold = 5
+	cfg.Auth.LockoutWindow = 15 * time.Minute
+	cfg.Password.HistoryDepth = 5
+	store := NewStore(repo, cfg)
+	require.NoError(t, store.Refresh(ctx))
+	assert.Equal(t, 5, store.LockoutThreshold())
+	assert.Equal(t, 15*time.Minute, store.LockoutWindow())
+	assert.Equal(t, 5, store.PasswordHistoryDepth())
+
+	// One invalid value leaves every setting as it was
+	_, err := store.SetMany(ctx, map[string]string{
+		KeyLockoutThreshold:     "10",
+		KeyPasswordHistoryDepth: "-1",
+	}, nil)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+	assert.ErrorContains(t, err, KeyPasswordHistoryDepth)
+	_, err = store.SetMany(ctx, map[string]string{
+		KeyLockoutThreshold: "10",
+		"database.host":     "elsewhere",
+	}, nil)
+	assert.ErrorIs(t, err, ErrUnknownSetting)
+	assert.Empty(t, repo.settings)
+	assert.Equal(t, 5, store.LockoutThreshold())
+
+	var changes int
+	store.OnChange(func() { changes++ })
+	updated, err := store.SetMany(ctx, map[string]string{
+		KeyLockoutThreshold:     "10",
+		KeyLockoutWindow:        "1h",
+		KeyPasswordHistoryDepth: "0",
+	}, nil)
+	require.NoError(t, err)
+	assert.Len(t, updated, 3)
+	assert.Equal(t, 1, changes)
+	assert.Equal(t, 10, store.LockoutThreshold())
+	assert.Equal(t, time.Hour, store.LockoutWindow())
+	assert.Equal(t, 0, store.PasswordHistoryDepth())
+
+	other := NewStore(repo, cfg)
+	require.NoError(t, other.Refresh(ctx))
+	assert.Equal(t, 10, other.LockoutThreshold())
+}
+
+func TestStoreProviderEnabled(t *testing.T) {
+	ctx := context.Background()
+	cfg := newTestConfig()
+	cfg.Provider = map[string]provider.Config{
+		"nordpool": {Enabled: true},
+		"entsoe":   {Enabled: false},
+	}
+	store := NewStore(&memoryRepo{settings: map[string]models.Setting{}}, cfg)
+	require.NoError(t, store.Refresh(ctx))
+
+	// Every configured provider gets a setting, unconfigured ones don't
+	assert.Len(t, store.List(), len(definitions)+2)
+	assert.True(t, store.ProviderEnabled("nordpool"))
+	assert.False(t, store.ProviderEnabled("entsoe"))
+	_, err := store.Get(ProviderEnabledKey("awattar"))
+	assert.ErrorIs(t, err, ErrUnknownSetting)
+
+	setting, err := store.Set(ctx, ProviderEnabledKey("nordpool"), "false", nil)
+	require.NoError(t, err)
+	assert.Equal(t, "true", setting.ConfigValue)
+	assert.False(t, store.ProviderEnabled("nordpool"))
+	_, err = store.Set(ctx, ProviderEnabledKey("entsoe"), "maybe", nil)
+	assert.ErrorIs(t, err, ErrInvalidValue)
+}
@@ -10,7 +10,7 @@ import (
 	"github.com/stretchr/testify/require"
 )
 
-func LoadTestConfig(t *testing.T) *config.Config {
+func LoadTestConfig(t testing.TB) *config.Config {
 	t.Helper()
 
 	// Get the absolute path to this file
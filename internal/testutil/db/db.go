@@ -58,7 +58,7 @@ func CleanupTestDB(db *sql.DB) error {
 	return nil
 }
 
-func SetupTestDB(t *testing.T, cfg *config.DatabaseConfig) *sql.DB {
+func SetupTestDB(t testing.TB, cfg *config.DatabaseConfig) *sql.DB {
 	t.Helper()
 
 	db, err := database.Connect(*cfg)
@@ -4,27 +4,28 @@ package testutil
 import (
 	"context"
 	"database/sql"
-	"strings"
 	"testing"
+	"time"
 	"wattwatch/internal/api/handlers"
 	"wattwatch/internal/auth"
 	"wattwatch/internal/config"
 	"wattwatch/internal/email"
 	"wattwatch/internal/models"
 	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
 	"wattwatch/internal/repository/postgres"
+	"wattwatch/internal/settings"
 	"wattwatch/internal/testutil/db"
+	"wattwatch/internal/validation"
 
 	"github.com/gin-gonic/gin"
-	"github.com/gin-gonic/gin/binding"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/crypto/bcrypt"
 )
 
 // LoadTestConfig loads the test configuration
-func LoadTestConfig(t *testing.T) *config.Config {
+func LoadTestConfig(t testing.TB) *config.Config {
 	t.Helper()
 	return db.LoadTestConfig(t)
 }
@@ -39,6 +40,7 @@ type TestContext struct {
 	PasswordHistoryRepo repository.PasswordHistoryRepository
 	EmailVerifyRepo     repository.EmailVerificationRepository
 	PasswordResetRepo   repository.PasswordResetRepository
+	EmailChangeRepo     repository.EmailChangeRevertRepository
 	LoginAttemptRepo    repository.LoginAttemptRepository
 	AuditRepo           repository.AuditLogRepository
 	AuthService         *auth.Service
@@ -47,6 +49,26 @@ type TestContext struct {
 	RefreshTokenRepo    repository.RefreshTokenRepository
 	ZoneRepo            repository.ZoneRepository
 	CurrencyRepo        repository.CurrencyRepository
+	SettingRepo         repository.SettingRepository
+	Settings            *settings.Store
+	EntsoeAreaRepo      repository.EntsoeAreaRepository
+	OrganizationRepo    repository.OrganizationRepository
+	PriceAlertRepo      repository.PriceAlertRepository
+	BudgetRepo          repository.BudgetRepository
+	TariffRepo          repository.TariffRepository
+	WebhookRepo         repository.WebhookRepository
+	WebhookDeliveryRepo repository.WebhookDeliveryRepository
+	TwoFactorRepo       repository.TwoFactorRepository
+	ImpersonationRepo   repository.ImpersonationRepository
+	IntegrationRepo     repository.IntegrationTokenRepository
+	SpotPriceRepo       repository.SpotPriceRepository
+	SpotPriceRevisions  repository.SpotPriceRevisionRepository
+	UserPreferenceRepo  repository.UserPreferenceRepository
+	ConsumptionRepo     repository.ConsumptionRepository
+	ProductionRepo      repository.ProductionRepository
+	PriceForecastRepo   repository.PriceForecastRepository
+	SecurityEventRepo   repository.SecurityEventRepository
+	TxManager           repository.TxManager
 }
 
 // MockEmailService is a mock implementation of the email service for testing
@@ -56,84 +78,221 @@ func NewMockEmailService() *MockEmailService {
 	return &MockEmailService{}
 }
 
-func (s *MockEmailService) SendVerificationEmail(to, username, token string) error {
+func (s *MockEmailService) SendVerificationEmail(to, username, language, token string, expiresAt time.Time) error {
 	return nil
 }
 
-func (s *MockEmailService) SendPasswordResetEmail(to, username, token string) error {
+func (s *MockEmailService) SendPasswordResetEmail(to, username, language, token string, expiresAt time.Time) error {
 	return nil
 }
 
+func (s *MockEmailService) SendEmailChangedNotification(to, username, language, newEmail, revertToken string, expiresAt time.Time) error {
+	return nil
+}
+
+func (s *MockEmailService) SendWelcomeEmail(to, username, language string) error {
+	return nil
+}
+
+func (s *MockEmailService) SendInviteEmail(to, username, language, token string, expiresAt time.Time) error {
+	return nil
+}
+
+func (s *MockEmailService) SendAccountDeletionEmail(to, username, language string, deleteAt time.Time) error {
+	return nil
+}
+
+func (s *MockEmailService) SendNewDeviceLoginEmail(to, username, language, ipAddress, userAgent string, at time.Time) error {
+	return nil
+}
+
+// repositories are the repositories a TestContext is built from
+type repositories struct {
+	user            repository.UserRepository
+	role            repository.RoleRepository
+	passwordHistory repository.PasswordHistoryRepository
+	emailVerify     repository.EmailVerificationRepository
+	passwordReset   repository.PasswordResetRepository
+	emailChange     repository.EmailChangeRevertRepository
+	loginAttempt    repository.LoginAttemptRepository
+	audit           repository.AuditLogRepository
+	refreshToken    repository.RefreshTokenRepository
+	zone            repository.ZoneRepository
+	currency        repository.CurrencyRepository
+	setting         repository.SettingRepository
+	entsoeArea      repository.EntsoeAreaRepository
+	organization    repository.OrganizationRepository
+	priceAlert      repository.PriceAlertRepository
+	budget          repository.BudgetRepository
+	tariff          repository.TariffRepository
+	webhook         repository.WebhookRepository
+	webhookDelivery repository.WebhookDeliveryRepository
+	twoFactor       repository.TwoFactorRepository
+	impersonation   repository.ImpersonationRepository
+	integration     repository.IntegrationTokenRepository
+	spotPrice       repository.SpotPriceRepository
+	priceRevision   repository.SpotPriceRevisionRepository
+	userPreference  repository.UserPreferenceRepository
+	consumption     repository.ConsumptionRepository
+	production      repository.ProductionRepository
+	priceForecasts  repository.PriceForecastRepository
+	securityEvent   repository.SecurityEventRepository
+	tx              repository.TxManager
+}
+
 // NewTestContext creates a new test context with all dependencies
 func NewTestContext(t *testing.T) *TestContext {
 	t.Helper()
 
-	// Set Gin to test mode
-	gin.SetMode(gin.TestMode)
-
-	// Initialize validators
-	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
-		err := v.RegisterValidation("nospaces", func(fl validator.FieldLevel) bool {
-			value := fl.Field().String()
-			return strings.TrimSpace(value) != ""
-		})
-		if err != nil {
-			t.Fatal("Failed to register validator:", err)
-		}
-	}
-
 	// Load test config
 	cfg := LoadTestConfig(t)
 
 	// Setup test database
 	testDB := db.SetupTestDB(t, &cfg.Database)
 
-	// Initialize repositories
-	userRepo := postgres.NewUserRepository(testDB)
-	roleRepo := postgres.NewRoleRepository(testDB)
-	passwordHistoryRepo := postgres.NewPasswordHistoryRepository(testDB)
-	emailVerifyRepo := postgres.NewEmailVerificationRepository(testDB)
-	passwordResetRepo := postgres.NewPasswordResetRepository(testDB)
-	loginAttemptRepo := postgres.NewLoginAttemptRepository(testDB)
-	auditRepo := postgres.NewAuditLogRepository(testDB)
-	refreshTokenRepo := postgres.NewRefreshTokenRepository(testDB)
-	zoneRepo := postgres.NewZoneRepository(testDB)
-	currencyRepo := postgres.NewCurrencyRepository(testDB)
+	return newTestContext(t, cfg, testDB, repositories{
+		user:            postgres.NewUserRepository(testDB),
+		role:            postgres.NewRoleRepository(testDB),
+		passwordHistory: postgres.NewPasswordHistoryRepository(testDB),
+		emailVerify:     postgres.NewEmailVerificationRepository(testDB),
+		passwordReset:   postgres.NewPasswordResetRepository(testDB),
+		emailChange:     postgres.NewEmailChangeRevertRepository(testDB),
+		loginAttempt:    postgres.NewLoginAttemptRepository(testDB),
+		audit:           postgres.NewAuditLogRepository(testDB),
+		refreshToken:    postgres.NewRefreshTokenRepository(testDB),
+		zone:            postgres.NewZoneRepository(testDB),
+		currency:        postgres.NewCurrencyRepository(testDB),
+		setting:         postgres.NewSettingRepository(testDB),
+		entsoeArea:      postgres.NewEntsoeAreaRepository(testDB),
+		organization:    postgres.NewOrganizationRepository(testDB),
+		priceAlert:      postgres.NewPriceAlertRepository(testDB),
+		budget:          postgres.NewBudgetRepository(testDB),
+		tariff:          postgres.NewTariffRepository(testDB),
+		webhook:         postgres.NewWebhookRepository(testDB),
+		webhookDelivery: postgres.NewWebhookDeliveryRepository(testDB),
+		twoFactor:       postgres.NewTwoFactorRepository(testDB),
+		impersonation:   postgres.NewImpersonationRepository(testDB),
+		integration:     postgres.NewIntegrationTokenRepository(testDB),
+		spotPrice:       postgres.NewSpotPriceRepository(testDB),
+		priceRevision:   postgres.NewSpotPriceRevisionRepository(testDB),
+		userPreference:  postgres.NewUserPreferenceRepository(testDB),
+		consumption:     postgres.NewConsumptionRepository(testDB),
+		production:      postgres.NewProductionRepository(testDB),
+		priceForecasts:  postgres.NewPriceForecastRepository(testDB),
+		securityEvent:   postgres.NewSecurityEventRepository(testDB),
+		tx:              repository.NewTxManager(testDB),
+	})
+}
+
+// NewMemoryTestContext creates a test context backed by in-memory repositories, for tests
+// that don't need PostgreSQL. DB is nil, so tests using it need NewTestContext.
+func NewMemoryTestContext(t *testing.T) *TestContext {
+	t.Helper()
+
+	store := memory.NewStore()
+	return newTestContext(t, LoadTestConfig(t), nil, repositories{
+		user:            memory.NewUserRepository(store),
+		role:            memory.NewRoleRepository(store),
+		passwordHistory: memory.NewPasswordHistoryRepository(store),
+		emailVerify:     memory.NewEmailVerificationRepository(store),
+		passwordReset:   memory.NewPasswordResetRepository(store),
+		emailChange:     memory.NewEmailChangeRevertRepository(store),
+		loginAttempt:    memory.NewLoginAttemptRepository(store),
+		audit:           memory.NewAuditLogRepository(store),
+		refreshToken:    memory.NewRefreshTokenRepository(store),
+		zone:            memory.NewZoneRepository(store),
+		currency:        memory.NewCurrencyRepository(store),
+		setting:         memory.NewSettingRepository(store),
+		entsoeArea:      memory.NewEntsoeAreaRepository(store),
+		organization:    memory.NewOrganizationRepository(store),
+		priceAlert:      memory.NewPriceAlertRepository(store),
+		budget:          memory.NewBudgetRepository(store),
+		tariff:          memory.NewTariffRepository(store),
+		webhook:         memory.NewWebhookRepository(store),
+		webhookDelivery: memory.NewWebhookDeliveryRepository(store),
+		twoFactor:       memory.NewTwoFactorRepository(store),
+		impersonation:   memory.NewImpersonationRepository(store),
+		integration:     memory.NewIntegrationTokenRepository(store),
+		spotPrice:       memory.NewSpotPriceRepository(store),
+		priceRevision:   memory.NewSpotPriceRevisionRepository(store),
+		userPreference:  memory.NewUserPreferenceRepository(store),
+		consumption:     memory.NewConsumptionRepository(store),
+		production:      memory.NewProductionRepository(store),
+		priceForecasts:  memory.NewPriceForecastRepository(store),
+		securityEvent:   memory.NewSecurityEventRepository(store),
+		tx:              memory.NewTxManager(store),
+	})
+}
+
+func newTestContext(t *testing.T, cfg *config.Config, testDB *sql.DB, repos repositories) *TestContext {
+	t.Helper()
+
+	// Set Gin to test mode
+	gin.SetMode(gin.TestMode)
+
+	// Initialize validators
+	validation.Initialize()
 
 	// Initialize services
-	authService := auth.NewService(cfg, refreshTokenRepo)
+	authService := auth.NewService(cfg, repos.refreshToken)
 	emailService := &MockEmailService{} // Use mock email service for testing
+	settingsStore := settings.NewStore(repos.setting, cfg)
 
 	// Initialize handlers
 	authHandler := handlers.NewAuthHandler(
-		userRepo,
-		roleRepo,
+		repos.user,
+		repos.role,
 		authService,
-		auditRepo,
+		repos.audit,
 		emailService,
 		cfg,
-		loginAttemptRepo,
-		emailVerifyRepo,
-		passwordResetRepo,
+		repos.loginAttempt,
+		repos.emailVerify,
+		repos.passwordReset,
+		settingsStore,
 	)
+	authHandler.SetTwoFactor(repos.twoFactor)
+	authHandler.SetTxManager(repos.tx)
+	authHandler.SetSecurityEvents(repos.securityEvent)
 
 	tc := &TestContext{
 		T:                   t,
 		DB:                  testDB,
 		Config:              cfg,
-		UserRepo:            userRepo,
-		RoleRepo:            roleRepo,
-		PasswordHistoryRepo: passwordHistoryRepo,
-		EmailVerifyRepo:     emailVerifyRepo,
-		PasswordResetRepo:   passwordResetRepo,
-		LoginAttemptRepo:    loginAttemptRepo,
-		AuditRepo:           auditRepo,
-		RefreshTokenRepo:    refreshTokenRepo,
+		UserRepo:            repos.user,
+		RoleRepo:            repos.role,
+		PasswordHistoryRepo: repos.passwordHistory,
+		EmailVerifyRepo:     repos.emailVerify,
+		PasswordResetRepo:   repos.passwordReset,
+		EmailChangeRepo:     repos.emailChange,
+		LoginAttemptRepo:    repos.loginAttempt,
+		AuditRepo:           repos.audit,
+		RefreshTokenRepo:    repos.refreshToken,
 		AuthService:         authService,
 		EmailService:        emailService,
 		AuthHandler:         authHandler,
-		ZoneRepo:            zoneRepo,
-		CurrencyRepo:        currencyRepo,
+		ZoneRepo:            repos.zone,
+		CurrencyRepo:        repos.currency,
+		SettingRepo:         repos.setting,
+		Settings:            settingsStore,
+		EntsoeAreaRepo:      repos.entsoeArea,
+		OrganizationRepo:    repos.organization,
+		PriceAlertRepo:      repos.priceAlert,
+		BudgetRepo:          repos.budget,
+		TariffRepo:          repos.tariff,
+		WebhookRepo:         repos.webhook,
+		WebhookDeliveryRepo: repos.webhookDelivery,
+		TwoFactorRepo:       repos.twoFactor,
+		ImpersonationRepo:   repos.impersonation,
+		IntegrationRepo:     repos.integration,
+		SpotPriceRepo:       repos.spotPrice,
+		SpotPriceRevisions:  repos.priceRevision,
+		UserPreferenceRepo:  repos.userPreference,
+		ConsumptionRepo:     repos.consumption,
+		ProductionRepo:      repos.production,
+		PriceForecastRepo:   repos.priceForecasts,
+		SecurityEventRepo:   repos.securityEvent,
+		TxManager:           repos.tx,
 	}
 
 	// Register cleanup function
@@ -276,3 +435,22 @@ func (tc *TestContext) CreateTestCurrency(name string) *models.Currency {
 
 	return currency
 }
+
+// NewUserHandler creates a UserHandler wired to the test context's dependencies
+func (tc *TestContext) NewUserHandler() *handlers.UserHandler {
+	handler := handlers.NewUserHandler(
+		tc.UserRepo,
+		tc.AuthService,
+		tc.PasswordHistoryRepo,
+		tc.AuditRepo,
+		tc.EmailService,
+		tc.EmailVerifyRepo,
+		tc.EmailChangeRepo,
+		tc.RefreshTokenRepo,
+		tc.LoginAttemptRepo,
+		tc.Config,
+	)
+	handler.SetOrganizationRepository(tc.OrganizationRepo)
+	handler.SetSettings(tc.Settings)
+	return handler
+}
@@ -0,0 +1,152 @@
+package validation
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// RuleType is the rule of a field whose JSON value has the wrong type, such as a string
+// where a number is expected. The other rules are the validator tags of the field, such
+// as required, max or email.
+const RuleType = "type"
+
+// FieldError is a field of a request that failed a validation rule
+type FieldError struct {
+	// Field is the path of the field by its JSON names, such as items[0].name
+	Field   string `json:"field" example:"username"`
+	Rule    string `json:"rule" example:"required"`
+	Message string `json:"message" example:"is required"`
+}
+
+// FieldErrors returns the fields of a request that err, returned when binding it, reports
+// as invalid. It returns nil when err isn't about specific fields, such as malformed JSON.
+func FieldErrors(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, 0, len(validationErrors))
+		for _, fe := range validationErrors {
+			fields = append(fields, FieldError{
+				Field:   fieldPath(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Message: message(fe),
+			})
+		}
+		return fields
+	}
+
+	var typeError *json.UnmarshalTypeError
+	if errors.As(err, &typeError) && typeError.Field != "" {
+		return []FieldError{{
+			Field:   typeError.Field,
+			Rule:    RuleType,
+			Message: "must be " + jsonType(typeError.Type),
+		}}
+	}
+	return nil
+}
+
+// fieldPath strips the name of the request struct from the namespace of a field
+func fieldPath(namespace string) string {
+	if _, path, ok := strings.Cut(namespace, "."); ok {
+		return path
+	}
+	return namespace
+}
+
+// jsonName names struct fields by their JSON key in validation errors, registered by
+// Initialize. Fields left out of JSON keep their Go name.
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" || name == "-" {
+		return field.Name
+	}
+	return name
+}
+
+// message describes the rule a field failed, without naming the field
+func message(fe validator.FieldError) string {
+	param := fe.Param()
+	counted := fe.Kind() == reflect.String || fe.Kind() == reflect.Slice || fe.Kind() == reflect.Map
+	unit := "items"
+	if fe.Kind() == reflect.String {
+		unit = "characters"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_with", "required_without":
+		return "is required"
+	case "min", "gte":
+		if counted {
+			return fmt.Sprintf("must have at least %s %s", param, unit)
+		}
+		return "must be at least " + param
+	case "max", "lte":
+		if counted {
+			return fmt.Sprintf("must have at most %s %s", param, unit)
+		}
+		return "must be at most " + param
+	case "len":
+		if counted {
+			return fmt.Sprintf("must have exactly %s %s", param, unit)
+		}
+		return "must be " + param
+	case "gt":
+		if counted {
+			return fmt.Sprintf("must have more than %s %s", param, unit)
+		}
+		return "must be greater than " + param
+	case "lt":
+		if counted {
+			return fmt.Sprintf("must have fewer than %s %s", param, unit)
+		}
+		return "must be less than " + param
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(param), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "url":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a UUID"
+	case "iso3166_1_alpha2":
+		return "must be an ISO 3166-1 alpha-2 country code"
+	case "nospaces":
+		return "must not be blank"
+	}
+	return fmt.Sprintf("failed the %s rule", fe.Tag())
+}
+
+var textUnmarshaler = reflect.TypeFor[encoding.TextUnmarshaler]()
+
+// jsonType names the JSON type a value of t is decoded from
+func jsonType(t reflect.Type) string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Times and UUIDs are decoded from strings
+	if reflect.PointerTo(t).Implements(textUnmarshaler) {
+		return "a string"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "a boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return "an integer"
+	case reflect.Float32, reflect.Float64:
+		return "a number"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice, reflect.Array:
+		return "an array"
+	case reflect.Map, reflect.Struct:
+		return "an object"
+	}
+	return "a " + t.String()
+}
@@ -0,0 +1,126 @@
+package validation
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ccojocar/zxcvbn-go"
+)
+
+// MaxPasswordLength is the number of bytes bcrypt hashes, longer passwords can't be stored
+const MaxPasswordLength = 72
+
+// minUserInfoLength keeps short usernames like "al" from ruling out common words
+const minUserInfoLength = 3
+
+// PasswordRule names a requirement of a PasswordPolicy
+type PasswordRule string
+
+const (
+	PasswordRuleMinLength PasswordRule = "min_length"
+	PasswordRuleMaxLength PasswordRule = "max_length"
+	PasswordRuleUppercase PasswordRule = "uppercase"
+	PasswordRuleLowercase PasswordRule = "lowercase"
+	PasswordRuleDigit     PasswordRule = "digit"
+	PasswordRuleSymbol    PasswordRule = "symbol"
+	PasswordRuleStrength  PasswordRule = "strength"
+	PasswordRuleUserInfo  PasswordRule = "user_info"
+)
+
+// PasswordViolation is a rule a password breaks
+type PasswordViolation struct {
+	Rule    PasswordRule `json:"rule" example:"min_length"`
+	Message string       `json:"message" example:"must be at least 8 characters"`
+}
+
+// PasswordPolicy holds the requirements new passwords must meet
+type PasswordPolicy struct {
+	// MinLength is the minimum number of characters
+	MinLength int
+	// RequireUppercase, RequireLowercase, RequireDigit and RequireSymbol require at least
+	// one character of the class
+	RequireUppercase bool
+	RequireLowercase bool
+	RequireDigit     bool
+	RequireSymbol    bool
+	// MinStrength is the minimum zxcvbn score from 0 to 4, 0 accepts any password
+	MinStrength int
+	// DisallowUserInfo rejects passwords containing the username or email address
+	DisallowUserInfo bool
+}
+
+// Check returns the rules password breaks, none when it meets the policy. userInfo holds
+// the username and email address of the account, which are matched ignoring case.
+func (p PasswordPolicy) Check(password string, userInfo ...string) []PasswordViolation {
+	var violations []PasswordViolation
+	fail := func(rule PasswordRule, format string, args ...interface{}) {
+		violations = append(violations, PasswordViolation{Rule: rule, Message: fmt.Sprintf(format, args...)})
+	}
+
+	if utf8.RuneCountInString(password) < p.MinLength {
+		fail(PasswordRuleMinLength, "must be at least %d characters", p.MinLength)
+	}
+	if len(password) > MaxPasswordLength {
+		fail(PasswordRuleMaxLength, "must be at most %d bytes", MaxPasswordLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	if p.RequireUppercase && !upper {
+		fail(PasswordRuleUppercase, "must contain an uppercase letter")
+	}
+	if p.RequireLowercase && !lower {
+		fail(PasswordRuleLowercase, "must contain a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		fail(PasswordRuleDigit, "must contain a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		fail(PasswordRuleSymbol, "must contain a symbol")
+	}
+
+	inputs := userInputs(userInfo)
+	if p.DisallowUserInfo {
+		lowered := strings.ToLower(password)
+		for _, input := range inputs {
+			if strings.Contains(lowered, input) {
+				fail(PasswordRuleUserInfo, "must not contain the username or email address")
+				break
+			}
+		}
+	}
+
+	// zxcvbn slows down quickly on long input, passwords too long to store aren't scored
+	if p.MinStrength > 0 && len(password) <= MaxPasswordLength {
+		if score := zxcvbn.PasswordStrength(password, inputs).Score; score < p.MinStrength {
+			fail(PasswordRuleStrength, "is too easy to guess, scored %d of the required %d out of 4", score, p.MinStrength)
+		}
+	}
+
+	return violations
+}
+
+// userInputs lowercases userInfo, leaving out values too short to match on
+func userInputs(userInfo []string) []string {
+	var inputs []string
+	for _, info := range userInfo {
+		info = strings.ToLower(strings.TrimSpace(info))
+		if utf8.RuneCountInString(info) >= minUserInfoLength {
+			inputs = append(inputs, info)
+		}
+	}
+	return inputs
+}
@@ -0,0 +1,58 @@
+package validation
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy_Check(t *testing.T) {
+	strict := PasswordPolicy{
+		MinLength:        10,
+		RequireUppercase: true,
+		RequireLowercase: true,
+		RequireDigit:     true,
+		RequireSymbol:    true,
+		DisallowUserInfo: true,
+	}
+
+	tests := []struct {
+		name      string
+		policy    PasswordPolicy
+		password  string
+		wantRules []PasswordRule
+	}{
+		{"meets defaults", PasswordPolicy{MinLength: 8}, "password123", nil},
+		{"too short", PasswordPolicy{MinLength: 8}, "short", []PasswordRule{PasswordRuleMinLength}},
+		{"length counts characters", PasswordPolicy{MinLength: 4}, "åäö!", nil},
+		{"too long for bcrypt", PasswordPolicy{MinLength: 8}, strings.Repeat("a", 73), []PasswordRule{PasswordRuleMaxLength}},
+		{"meets strict", strict, "Correct-Horse-9", nil},
+		{"every class missing", strict, "          ", []PasswordRule{
+			PasswordRuleUppercase, PasswordRuleLowercase, PasswordRuleDigit,
+		}},
+		{"symbol missing", strict, "CorrectHorse9", []PasswordRule{PasswordRuleSymbol}},
+		{"contains username", strict, "My-Alice-2024", []PasswordRule{PasswordRuleUserInfo}},
+		{"contains email", strict, "X1-alice@example.com", []PasswordRule{PasswordRuleUserInfo}},
+		{"user info allowed", PasswordPolicy{MinLength: 8}, "alice2024", nil},
+		{"too easy to guess", PasswordPolicy{MinLength: 8, MinStrength: 3}, "password1", []PasswordRule{PasswordRuleStrength}},
+		{"strong enough", PasswordPolicy{MinLength: 8, MinStrength: 3}, "tr0mbone-galaxy-quilt", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rules []PasswordRule
+			for _, v := range tt.policy.Check(tt.password, "alice", "alice@example.com") {
+				assert.NotEmpty(t, v.Message)
+				rules = append(rules, v.Rule)
+			}
+			assert.Equal(t, tt.wantRules, rules)
+		})
+	}
+}
+
+func TestPasswordPolicy_CheckShortUserInfo(t *testing.T) {
+	// Two letter usernames would rule out too many passwords
+	policy := PasswordPolicy{MinLength: 8, DisallowUserInfo: true}
+	assert.Empty(t, policy.Check("alphabet99", "al", ""))
+}
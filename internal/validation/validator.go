@@ -8,9 +8,11 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
-// Initialize registers all custom validators
+// Initialize registers all custom validators and names fields by their JSON key in
+// validation errors
 func Initialize() {
 	if v, ok := binding.Validator.Engine().(*validator.Validate); ok {
+		v.RegisterTagNameFunc(jsonName)
 		err := v.RegisterValidation("nospaces", validateNoSpaces)
 		if err != nil {
 			panic(err)
@@ -0,0 +1,260 @@
+// Package webhook calls the URLs administrators register when events occur. Deliveries are
+// queued in the database, signed with the webhook's secret and retried with exponential
+// backoff, so they survive restarts and are sent by whichever instance claims them first.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/pubsub"
+	"wattwatch/internal/repository"
+)
+
+// Headers sent with every webhook request
+const (
+	EventHeader     = "X-WattWatch-Event"
+	DeliveryHeader  = "X-WattWatch-Delivery"
+	SignatureHeader = "X-WattWatch-Signature"
+)
+
+const (
+	// DefaultSendInterval is how often due deliveries are looked for
+	DefaultSendInterval = 10 * time.Second
+	// DefaultMaxAttempts is how often a delivery is attempted before it is marked as failed
+	DefaultMaxAttempts = 8
+	// DefaultRetryBackoff is the wait before the first retry, it doubles with every attempt
+	DefaultRetryBackoff = 30 * time.Second
+
+	// requestTimeout bounds a single attempt
+	requestTimeout = 10 * time.Second
+	// claimLease keeps a claimed delivery from other instances while it is sent, it must be
+	// longer than requestTimeout
+	claimLease = time.Minute
+	// claimBatch is the number of due deliveries claimed at once
+	claimBatch = 50
+	// maxResponseExcerpt is how much of a failed response's body is kept with the error
+	maxResponseExcerpt = 512
+)
+
+// Payload is the body of every webhook request
+type Payload struct {
+	Event     models.WebhookEvent `json:"event" example:"user.registered"`
+	CreatedAt time.Time           `json:"created_at"`
+	Data      interface{}         `json:"data"`
+}
+
+// Dispatcher queues events for the webhooks subscribed to them and sends the queued deliveries
+type Dispatcher struct {
+	webhooks    repository.WebhookRepository
+	deliveries  repository.WebhookDeliveryRepository
+	client      *http.Client
+	maxAttempts int
+	backoff     time.Duration
+	now         func() time.Time
+}
+
+// NewDispatcher creates a dispatcher sending with client, or a client with a 10 second
+// timeout when client is nil
+func NewDispatcher(webhooks repository.WebhookRepository, deliveries repository.WebhookDeliveryRepository, client *http.Client) *Dispatcher {
+	if client == nil {
+		client = &http.Client{Timeout: requestTimeout}
+	}
+	return &Dispatcher{
+		webhooks:    webhooks,
+		deliveries:  deliveries,
+		client:      client,
+		maxAttempts: DefaultMaxAttempts,
+		backoff:     DefaultRetryBackoff,
+		now:         time.Now,
+	}
+}
+
+// SetRetry sets how often a delivery is attempted and the wait before the first retry
+func (d *Dispatcher) SetRetry(maxAttempts int, backoff time.Duration) {
+	d.maxAttempts = max(maxAttempts, 1)
+	d.backoff = backoff
+}
+
+// NewSecret generates a secret to sign a webhook's requests with
+func NewSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Sign returns the signature header of a body sent at timestamp, as "t=<unix time>,v1=<hex>".
+// v1 is the HMAC-SHA256 of "<unix time>.<body>" keyed with the secret, receivers compute it
+// the same way and should reject old timestamps to prevent replays.
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix + "."))
+	mac.Write(body)
+	return "t=" + unix + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Publish queues the event for every enabled webhook subscribed to it. data is sent as the
+// data field of the payload.
+func (d *Dispatcher) Publish(ctx context.Context, event models.WebhookEvent, data interface{}) error {
+	webhooks, err := d.webhooks.ListByEvent(ctx, event)
+	if err != nil {
+		return fmt.Errorf("failed to list webhooks: %w", err)
+	}
+	if len(webhooks) == 0 {
+		return nil
+	}
+
+	now := d.now()
+	body, err := json.Marshal(Payload{Event: event, CreatedAt: now.UTC(), Data: data})
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", event, err)
+	}
+
+	var errs []error
+	for _, webhook := range webhooks {
+		delivery := &models.WebhookDelivery{WebhookID: webhook.ID, Event: event, Payload: body, NextAttemptAt: &now}
+		if err := d.deliveries.Create(ctx, delivery); err != nil && !errors.Is(err, repository.ErrNotFound) {
+			errs = append(errs, fmt.Errorf("failed to queue %s for webhook %s: %w", event, webhook.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FollowSpotPrices publishes a spot_prices.ingested event for every write of spot prices to
+// hub until ctx is cancelled. It takes one of the hub's subscriber slots.
+func (d *Dispatcher) FollowSpotPrices(ctx context.Context, hub *pubsub.Hub) {
+	hub.Follow(ctx, "Spot price webhooks", pubsub.Filter{}, func(ctx context.Context, spotPrices []models.SpotPrice) {
+		data := map[string]interface{}{"spot_prices": spotPrices}
+		if err := d.Publish(ctx, models.WebhookEventSpotPricesIngested, data); err != nil {
+			log.Printf("Failed to publish spot prices to webhooks: %v", err)
+		}
+	})
+}
+
+// Run sends the due deliveries every interval until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := d.SendDue(ctx); err != nil {
+				log.Printf("Failed to send webhook deliveries: %v", err)
+			}
+		}
+	}
+}
+
+// SendDue attempts every delivery that is due. Failed attempts are recorded with the
+// delivery and scheduled for a retry, they are not returned as errors.
+func (d *Dispatcher) SendDue(ctx context.Context) error {
+	for {
+		deliveries, err := d.deliveries.ClaimDue(ctx, d.now(), claimLease, claimBatch)
+		if err != nil {
+			return fmt.Errorf("failed to claim deliveries: %w", err)
+		}
+
+		for i := range deliveries {
+			if err := d.attempt(ctx, &deliveries[i]); err != nil {
+				return err
+			}
+		}
+		if len(deliveries) < claimBatch {
+			return nil
+		}
+	}
+}
+
+// attempt sends a delivery once and records the outcome
+func (d *Dispatcher) attempt(ctx context.Context, delivery *models.WebhookDelivery) error {
+	webhook, err := d.webhooks.GetByID(ctx, delivery.WebhookID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get webhook %s: %w", delivery.WebhookID, err)
+	}
+
+	var status int
+	sendErr := errors.New("webhook is disabled")
+	if webhook.Enabled {
+		status, sendErr = d.send(ctx, webhook, delivery)
+	}
+
+	now := d.now()
+	delivery.Attempts++
+	delivery.ResponseStatus = nil
+	if status != 0 {
+		delivery.ResponseStatus = &status
+	}
+	switch {
+	case sendErr == nil:
+		delivery.Status = models.DeliveryStatusSent
+		delivery.Error = nil
+		delivery.NextAttemptAt = nil
+		delivery.DeliveredAt = &now
+	case !webhook.Enabled || delivery.Attempts >= d.maxAttempts:
+		msg := sendErr.Error()
+		delivery.Status = models.DeliveryStatusFailed
+		delivery.Error = &msg
+		delivery.NextAttemptAt = nil
+	default:
+		msg := sendErr.Error()
+		next := now.Add(d.backoff << (delivery.Attempts - 1))
+		delivery.Error = &msg
+		delivery.NextAttemptAt = &next
+	}
+
+	if err := d.deliveries.Record(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to record delivery %s: %w", delivery.ID, err)
+	}
+	return nil
+}
+
+// send posts the delivery's payload to the webhook and returns the response status, zero
+// when no response was received
+func (d *Dispatcher) send(ctx context.Context, webhook *models.Webhook, delivery *models.WebhookDelivery) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, requestTimeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "WattWatch-Webhook")
+	req.Header.Set(EventHeader, string(delivery.Event))
+	req.Header.Set(DeliveryHeader, delivery.ID.String())
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, d.now(), delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		excerpt, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseExcerpt))
+		return resp.StatusCode, fmt.Errorf("unexpected status %d: %s", resp.StatusCode, bytes.TrimSpace(excerpt))
+	}
+	io.Copy(io.Discard, resp.Body)
+	return resp.StatusCode, nil
+}
@@ -0,0 +1,126 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSign(t *testing.T) {
+	at := time.Unix(1700000000, 0)
+	assert.Equal(t, "t=1700000000,v1=8a347a5c309c16f1318e9216a539679c412e76b8bbad2fec46a9605fc135e82f", Sign("secret", at, []byte(`{"event":"user.registered"}`)))
+}
+
+func TestDispatcher(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	webhooks := memory.NewWebhookRepository(store)
+	deliveries := memory.NewWebhookDeliveryRepository(store)
+
+	var calls atomic.Int32
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// The first attempt fails, the retry succeeds
+		if calls.Add(1) == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		received = r
+		body, _ = io.ReadAll(r.Body)
+	}))
+	defer server.Close()
+
+	hook := &models.Webhook{URL: server.URL, Secret: "secret", Enabled: true, Events: []models.WebhookEvent{models.WebhookEventUserRegistered}}
+	require.NoError(t, webhooks.Create(ctx, hook))
+	other := &models.Webhook{URL: server.URL, Secret: "secret", Enabled: true, Events: []models.WebhookEvent{models.WebhookEventAlertTriggered}}
+	require.NoError(t, webhooks.Create(ctx, other))
+
+	dispatcher := NewDispatcher(webhooks, deliveries, server.Client())
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+
+	require.NoError(t, dispatcher.Publish(ctx, models.WebhookEventUserRegistered, map[string]string{"username": "alice"}))
+	queued, err := deliveries.List(ctx, repository.WebhookDeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, queued, 1)
+	assert.Equal(t, hook.ID, queued[0].WebhookID)
+
+	require.NoError(t, dispatcher.SendDue(ctx))
+	got, err := deliveries.List(ctx, repository.WebhookDeliveryFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryStatusPending, got[0].Status)
+	assert.Equal(t, 1, got[0].Attempts)
+	assert.Equal(t, http.StatusServiceUnavailable, *got[0].ResponseStatus)
+	assert.True(t, strings.HasPrefix(*got[0].Error, "unexpected status 503"))
+	assert.True(t, got[0].NextAttemptAt.Equal(now.Add(DefaultRetryBackoff)))
+
+	// Nothing is sent before the retry is due
+	require.NoError(t, dispatcher.SendDue(ctx))
+	assert.EqualValues(t, 1, calls.Load())
+
+	now = now.Add(DefaultRetryBackoff)
+	require.NoError(t, dispatcher.SendDue(ctx))
+	got, err = deliveries.List(ctx, repository.WebhookDeliveryFilter{})
+	require.NoError(t, err)
+	assert.Equal(t, models.DeliveryStatusSent, got[0].Status)
+	assert.Equal(t, 2, got[0].Attempts)
+	assert.Nil(t, got[0].NextAttemptAt)
+
+	require.NotNil(t, received)
+	assert.Equal(t, string(models.WebhookEventUserRegistered), received.Header.Get(EventHeader))
+	assert.Equal(t, got[0].ID.String(), received.Header.Get(DeliveryHeader))
+	assert.Equal(t, Sign("secret", now, body), received.Header.Get(SignatureHeader))
+	var payload struct {
+		Event models.WebhookEvent `json:"event"`
+		Data  map[string]string   `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(body, &payload))
+	assert.Equal(t, models.WebhookEventUserRegistered, payload.Event)
+	assert.Equal(t, "alice", payload.Data["username"])
+}
+
+func TestDispatcher_GivesUp(t *testing.T) {
+	ctx := context.Background()
+	store := memory.NewStore()
+	webhooks := memory.NewWebhookRepository(store)
+	deliveries := memory.NewWebhookDeliveryRepository(store)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	hook := &models.Webhook{URL: server.URL, Secret: "secret", Enabled: true, Events: []models.WebhookEvent{models.WebhookEventAlertTriggered}}
+	require.NoError(t, webhooks.Create(ctx, hook))
+
+	dispatcher := NewDispatcher(webhooks, deliveries, server.Client())
+	dispatcher.SetRetry(3, time.Minute)
+	now := time.Now()
+	dispatcher.now = func() time.Time { return now }
+	require.NoError(t, dispatcher.Publish(ctx, models.WebhookEventAlertTriggered, nil))
+
+	// Retries wait one, then two minutes
+	for _, wait := range []time.Duration{0, time.Minute, 2 * time.Minute} {
+		now = now.Add(wait)
+		require.NoError(t, dispatcher.SendDue(ctx))
+	}
+	got, err := deliveries.List(ctx, repository.WebhookDeliveryFilter{})
+	require.NoError(t, err)
+	require.Len(t, got, 1)
+	assert.Equal(t, models.DeliveryStatusFailed, got[0].Status)
+	assert.Equal(t, 3, got[0].Attempts)
+	assert.Nil(t, got[0].NextAttemptAt)
+}
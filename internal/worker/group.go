@@ -0,0 +1,121 @@
+// Package worker tracks background goroutines so they can be stopped or drained on shutdown
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrStopped is returned when work is started after shutdown began
+var ErrStopped = errors.New("shutting down, not accepting new work")
+
+// Group runs named background functions that share a context cancelled on shutdown
+type Group struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	mu       sync.Mutex
+	wg       sync.WaitGroup
+	stopping bool
+	stopped  chan struct{}
+	running  map[string]int
+}
+
+// NewGroup creates an empty group
+func NewGroup() *Group {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Group{
+		ctx:     ctx,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+		running: make(map[string]int),
+	}
+}
+
+// Go runs fn in a new goroutine. The context passed to fn is cancelled by Stop, or by
+// Drain when the shutdown deadline passes.
+func (g *Group) Go(name string, fn func(ctx context.Context)) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.stopping {
+		return ErrStopped
+	}
+
+	g.wg.Add(1)
+	g.running[name]++
+	go func() {
+		defer func() {
+			g.mu.Lock()
+			g.running[name]--
+			if g.running[name] == 0 {
+				delete(g.running, name)
+			}
+			g.mu.Unlock()
+			g.wg.Done()
+		}()
+		fn(g.ctx)
+	}()
+	return nil
+}
+
+// Drain rejects new work and waits for running functions to return on their own.
+// If ctx expires first their context is cancelled and an error names what was still running.
+func (g *Group) Drain(ctx context.Context) error {
+	g.beginShutdown()
+	return g.wait(ctx)
+}
+
+// Stop rejects new work, cancels running functions and waits for them to return until ctx expires
+func (g *Group) Stop(ctx context.Context) error {
+	g.beginShutdown()
+	g.cancel()
+	return g.wait(ctx)
+}
+
+// Stopping is closed when shutdown begins, so long running work can stop at a safe point
+func (g *Group) Stopping() <-chan struct{} {
+	return g.stopped
+}
+
+func (g *Group) beginShutdown() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if !g.stopping {
+		g.stopping = true
+		close(g.stopped)
+	}
+}
+
+func (g *Group) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		g.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		g.cancel()
+		return fmt.Errorf("still running after shutdown deadline: %s", strings.Join(g.runningNames(), ", "))
+	}
+}
+
+func (g *Group) runningNames() []string {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	names := make([]string, 0, len(g.running))
+	for name, count := range g.running {
+		if count > 1 {
+			name = fmt.Sprintf("%s (%d)", name, count)
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
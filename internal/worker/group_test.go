@@ -0,0 +1,63 @@
+package worker
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestGroup_Drain(t *testing.T) {
+	g := NewGroup()
+	var finished atomic.Bool
+	require.NoError(t, g.Go("job", func(ctx context.Context) {
+		time.Sleep(20 * time.Millisecond)
+		finished.Store(true)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, g.Drain(ctx))
+	require.True(t, finished.Load(), "drain waits for running work")
+
+	require.ErrorIs(t, g.Go("late", func(ctx context.Context) {}), ErrStopped)
+
+	select {
+	case <-g.Stopping():
+	default:
+		t.Fatal("Stopping is closed once shutdown begins")
+	}
+}
+
+func TestGroup_DrainTimeout(t *testing.T) {
+	g := NewGroup()
+	cancelled := make(chan struct{})
+	require.NoError(t, g.Go("ingest", func(ctx context.Context) {
+		<-ctx.Done()
+		close(cancelled)
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := g.Drain(ctx)
+	require.ErrorContains(t, err, "ingest")
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Fatal("work was not cancelled after the deadline")
+	}
+}
+
+func TestGroup_Stop(t *testing.T) {
+	g := NewGroup()
+	require.NoError(t, g.Go("loop", func(ctx context.Context) {
+		<-ctx.Done()
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, g.Stop(ctx))
+}
@@ -0,0 +1,59 @@
+-- Create device_tokens table for push notification targets
+CREATE TABLE device_tokens (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL CHECK (channel IN ('fcm', 'webpush')),
+    token TEXT NOT NULL,
+    p256dh VARCHAR(255),
+    auth VARCHAR(255),
+    user_agent VARCHAR(255),
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (channel, token)
+);
+
+-- Create updated_at trigger for device_tokens
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON device_tokens
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Create indexes for device_tokens
+CREATE INDEX idx_device_tokens_user_id ON device_tokens(user_id);
+
+-- Create notification_preferences table, a missing row means the channel is enabled
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL,
+    alert_type VARCHAR(20) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, channel, alert_type)
+);
+
+-- Create updated_at trigger for notification_preferences
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON notification_preferences
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Create notification_deliveries table to track the outcome of each send
+CREATE TABLE notification_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    device_token_id UUID REFERENCES device_tokens(id) ON DELETE SET NULL,
+    channel VARCHAR(20) NOT NULL,
+    alert_type VARCHAR(20) NOT NULL,
+    title VARCHAR(255) NOT NULL,
+    body TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'sent', 'failed')),
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    delivered_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create indexes for notification_deliveries
+CREATE INDEX idx_notification_deliveries_user_id ON notification_deliveries(user_id, created_at DESC);
+CREATE INDEX idx_notification_deliveries_status ON notification_deliveries(status);
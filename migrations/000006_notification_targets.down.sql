@@ -0,0 +1,2 @@
+ALTER TABLE notification_deliveries DROP COLUMN IF EXISTS target_id;
+DROP TABLE IF EXISTS notification_targets;
@@ -0,0 +1,25 @@
+-- Create notification_targets table for chat destinations such as Slack, Discord and Telegram
+CREATE TABLE notification_targets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    channel VARCHAR(20) NOT NULL CHECK (channel IN ('slack', 'discord', 'telegram')),
+    name VARCHAR(100) NOT NULL,
+    webhook_url TEXT,
+    bot_token VARCHAR(255),
+    chat_id VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create updated_at trigger for notification_targets
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON notification_targets
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Create indexes for notification_targets
+CREATE INDEX idx_notification_targets_user_id ON notification_targets(user_id);
+
+-- Track deliveries to targets alongside device deliveries
+ALTER TABLE notification_deliveries
+    ADD COLUMN target_id UUID REFERENCES notification_targets(id) ON DELETE SET NULL;
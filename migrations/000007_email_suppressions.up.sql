@@ -0,0 +1,18 @@
+-- Create email_suppressions table for addresses that bounced or complained
+CREATE TABLE email_suppressions (
+    email VARCHAR(255) PRIMARY KEY,
+    reason VARCHAR(20) NOT NULL CHECK (reason IN ('bounced', 'complained')),
+    provider VARCHAR(50) NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create updated_at trigger for email_suppressions
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON email_suppressions
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Users are matched against suppressions case-insensitively
+CREATE INDEX idx_users_email_lower ON users(lower(email)) WHERE deleted_at IS NULL;
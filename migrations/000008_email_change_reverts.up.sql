@@ -0,0 +1,15 @@
+-- Create email_change_reverts table for "this wasn't me" links sent to the previous address
+CREATE TABLE email_change_reverts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    old_email VARCHAR(255) NOT NULL,
+    new_email VARCHAR(255),
+    token VARCHAR(255) NOT NULL UNIQUE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create indexes for email_change_reverts
+CREATE INDEX idx_email_change_reverts_user_id ON email_change_reverts(user_id);
+CREATE INDEX idx_email_change_reverts_expires_at ON email_change_reverts(expires_at);
@@ -0,0 +1,2 @@
+-- Bumped whenever a role changes, so access tokens carrying the previous role claims are rejected
+ALTER TABLE roles ADD COLUMN token_version INTEGER NOT NULL DEFAULT 1;
@@ -0,0 +1,4 @@
+-- Entity IDs that aren't UUIDs can't be kept
+ALTER TABLE audit_logs ALTER COLUMN entity_id TYPE UUID USING (
+    CASE WHEN entity_id ~* '^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$' THEN entity_id::uuid END
+);
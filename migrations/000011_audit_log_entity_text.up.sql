@@ -0,0 +1,2 @@
+-- Entities such as rate limit policies and data quality reports aren't identified by a UUID
+ALTER TABLE audit_logs ALTER COLUMN entity_id TYPE VARCHAR(255) USING entity_id::text;
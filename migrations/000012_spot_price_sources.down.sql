@@ -0,0 +1,2 @@
+ALTER TABLE spot_prices DROP COLUMN IF EXISTS resolved_source;
+DROP TABLE IF EXISTS spot_price_sources;
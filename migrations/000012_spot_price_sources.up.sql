@@ -0,0 +1,24 @@
+-- Create spot_price_sources table holding the price each provider reported, so hours on
+-- which providers disagree can be reviewed
+CREATE TABLE spot_price_sources (
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    zone_id UUID NOT NULL REFERENCES zones(id),
+    currency_id UUID NOT NULL REFERENCES currencies(id),
+    source VARCHAR(50) NOT NULL,
+    price DECIMAL(10,4) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (timestamp, zone_id, currency_id, source)
+);
+
+CREATE INDEX idx_spot_price_sources_zone_currency_time
+    ON spot_price_sources (zone_id, currency_id, timestamp DESC);
+
+-- Create updated_at trigger for spot_price_sources
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON spot_price_sources
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Source an administrator chose the price of, later values from providers don't replace it
+ALTER TABLE spot_prices ADD COLUMN resolved_source VARCHAR(50);
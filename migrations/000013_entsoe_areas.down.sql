@@ -0,0 +1 @@
+DROP TABLE IF EXISTS entsoe_areas;
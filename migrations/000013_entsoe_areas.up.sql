@@ -0,0 +1,13 @@
+-- Create entsoe_areas table mapping zones to the EIC codes of ENTSO-E bidding zones
+CREATE TABLE entsoe_areas (
+    zone_id UUID PRIMARY KEY REFERENCES zones(id) ON DELETE CASCADE,
+    area_code VARCHAR(16) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create updated_at trigger for entsoe_areas
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON entsoe_areas
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
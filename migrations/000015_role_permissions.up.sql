@@ -0,0 +1,8 @@
+-- Create role_permissions table granting roles individual permissions, such as
+-- spot_prices:write, on top of what every role may do. Admin groups have them all.
+CREATE TABLE role_permissions (
+    role_id UUID NOT NULL REFERENCES roles(id) ON DELETE CASCADE,
+    permission VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (role_id, permission)
+);
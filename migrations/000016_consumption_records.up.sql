@@ -0,0 +1,26 @@
+-- Create consumption_records table holding the energy users' meters measured per interval,
+-- the counterpart of spot prices for calculating what the energy cost
+CREATE TABLE consumption_records (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    meter_id VARCHAR(100) NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    kwh DECIMAL(12,4) NOT NULL CHECK (kwh >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, meter_id, timestamp)
+);
+
+CREATE INDEX idx_consumption_records_user_time
+    ON consumption_records (user_id, timestamp DESC);
+
+-- Convert consumption_records to hypertable
+SELECT create_hypertable('consumption_records', 'timestamp',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE
+);
+
+-- Create updated_at trigger for consumption_records
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON consumption_records
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
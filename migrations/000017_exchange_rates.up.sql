@@ -0,0 +1,19 @@
+-- Create exchange_rates table holding how many units of the quote currency one unit of the
+-- base currency buys, from timestamp until the next rate of the pair
+CREATE TABLE exchange_rates (
+    base_currency_id UUID NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
+    quote_currency_id UUID NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    rate DECIMAL(18,8) NOT NULL CHECK (rate > 0),
+    source VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (base_currency_id, quote_currency_id, timestamp),
+    CHECK (base_currency_id <> quote_currency_id)
+);
+
+-- Create updated_at trigger for exchange_rates
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON exchange_rates
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
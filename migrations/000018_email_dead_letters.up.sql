@@ -0,0 +1,25 @@
+-- Create email_dead_letters table keeping emails that failed every delivery attempt so
+-- administrators can resend them
+CREATE TABLE email_dead_letters (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    kind VARCHAR(50) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    content_type VARCHAR(50) NOT NULL,
+    body TEXT NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    error TEXT NOT NULL,
+    attempts INTEGER NOT NULL CHECK (attempts > 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    resent_at TIMESTAMP WITH TIME ZONE
+);
+
+-- Create updated_at trigger for email_dead_letters
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON email_dead_letters
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Pending dead letters are listed newest first
+CREATE INDEX idx_email_dead_letters_pending ON email_dead_letters(created_at DESC) WHERE resent_at IS NULL;
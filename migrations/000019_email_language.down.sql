@@ -0,0 +1,2 @@
+ALTER TABLE email_dead_letters DROP COLUMN IF EXISTS text_body;
+ALTER TABLE users DROP COLUMN IF EXISTS language;
@@ -0,0 +1,5 @@
+-- Users receive emails in the language they picked
+ALTER TABLE users ADD COLUMN language VARCHAR(10) NOT NULL DEFAULT 'en';
+
+-- Emails are sent with a plain text alternative to the HTML body
+ALTER TABLE email_dead_letters ADD COLUMN text_body TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,28 @@
+-- Create price_alerts table with the thresholds users are notified about. The optional
+-- window limits the alert to hours of the day in the zone's timezone, and wraps past
+-- midnight when it ends before it starts.
+CREATE TABLE price_alerts (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    zone_id UUID NOT NULL REFERENCES zones(id) ON DELETE CASCADE,
+    currency_id UUID NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
+    direction VARCHAR(10) NOT NULL CHECK (direction IN ('above', 'below')),
+    threshold DECIMAL(10,4) NOT NULL,
+    window_start TIME,
+    window_end TIME,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    last_triggered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((window_start IS NULL) = (window_end IS NULL))
+);
+
+-- Create updated_at trigger for price_alerts
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON price_alerts
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Alerts are listed by user and evaluated by zone and currency as prices arrive
+CREATE INDEX idx_price_alerts_user ON price_alerts(user_id);
+CREATE INDEX idx_price_alerts_zone_currency ON price_alerts(zone_id, currency_id) WHERE enabled;
@@ -0,0 +1,29 @@
+-- Create user_totp table with the TOTP secret of users enrolled in two-factor
+-- authentication. The secret only protects logins once enabled_at is set by confirming a
+-- code. last_used_step is the time step of the last accepted code, so codes can't be
+-- replayed within their window.
+CREATE TABLE user_totp (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled_at TIMESTAMP WITH TIME ZONE,
+    last_used_step BIGINT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create updated_at trigger for user_totp
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON user_totp
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Create totp_backup_codes table with the SHA-256 hashes of the single-use codes that
+-- replace a TOTP code when the authenticator is lost
+CREATE TABLE totp_backup_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, code_hash)
+);
@@ -0,0 +1,8 @@
+-- A token family is a session, the device it was used from is shown to the user
+ALTER TABLE refresh_tokens ADD COLUMN user_agent TEXT;
+ALTER TABLE refresh_tokens ADD COLUMN ip_address VARCHAR(45);
+ALTER TABLE refresh_tokens ADD COLUMN session_created_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE refresh_tokens ADD COLUMN last_used_at TIMESTAMP WITH TIME ZONE;
+UPDATE refresh_tokens SET session_created_at = created_at, last_used_at = created_at;
+ALTER TABLE refresh_tokens ALTER COLUMN session_created_at SET NOT NULL;
+ALTER TABLE refresh_tokens ALTER COLUMN last_used_at SET NOT NULL;
@@ -0,0 +1,17 @@
+-- Create impersonations table with the sessions in which an admin acts as another user.
+-- Tokens issued for a session stop working when ended_at is set or expires_at passes.
+CREATE TABLE impersonations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    admin_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_impersonations_admin_id ON impersonations(admin_id);
+CREATE INDEX idx_impersonations_user_id ON impersonations(user_id);
+
+-- Actions taken while impersonating record the admin next to the impersonated user
+ALTER TABLE audit_logs ADD COLUMN impersonator_id UUID REFERENCES users(id) ON DELETE SET NULL;
+CREATE INDEX idx_audit_logs_impersonator_id ON audit_logs(impersonator_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_spot_prices_id;
+
+SELECT set_chunk_time_interval('spot_prices', INTERVAL '1 day');
@@ -0,0 +1,7 @@
+-- Daily chunks hold a few hundred hourly prices each, so years of prices for many zones
+-- leave queries planning over thousands of chunks. New chunks cover a month, existing ones
+-- keep their day. TimescaleDB creates each chunk as the first price in its range is stored.
+SELECT set_chunk_time_interval('spot_prices', INTERVAL '1 month');
+
+-- Lookups by ID can't exclude chunks, the index keeps them to an index scan per chunk
+CREATE INDEX idx_spot_prices_id ON spot_prices (id);
@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_zones_country_code;
+DROP INDEX IF EXISTS idx_zones_eic_code;
+
+ALTER TABLE zones
+    DROP COLUMN IF EXISTS display_name,
+    DROP COLUMN IF EXISTS grid_operator,
+    DROP COLUMN IF EXISTS eic_code,
+    DROP COLUMN IF EXISTS country_code;
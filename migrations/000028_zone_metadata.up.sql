@@ -0,0 +1,20 @@
+-- Describe zones so providers can match them to the areas of external data sources
+ALTER TABLE zones
+    ADD COLUMN country_code CHAR(2),
+    ADD COLUMN eic_code VARCHAR(16),
+    ADD COLUMN grid_operator VARCHAR(100),
+    ADD COLUMN display_name VARCHAR(100);
+
+-- An ENTSO-E bidding zone is a single zone
+CREATE UNIQUE INDEX idx_zones_eic_code ON zones(eic_code) WHERE eic_code IS NOT NULL;
+CREATE INDEX idx_zones_country_code ON zones(country_code);
+
+-- Fill in the default zones
+UPDATE zones SET country_code = 'SE', grid_operator = 'Svenska kraftnät', eic_code = v.eic_code, display_name = v.display_name
+FROM (VALUES
+    ('SE1', '10Y1001A1001A44P', 'Luleå'),
+    ('SE2', '10Y1001A1001A45N', 'Sundsvall'),
+    ('SE3', '10Y1001A1001A46L', 'Stockholm'),
+    ('SE4', '10Y1001A1001A47J', 'Malmö')
+) AS v(name, eic_code, display_name)
+WHERE zones.name = v.name;
@@ -0,0 +1,5 @@
+ALTER TABLE currencies
+    DROP COLUMN IF EXISTS minor_units,
+    DROP COLUMN IF EXISTS symbol_first,
+    DROP COLUMN IF EXISTS symbol,
+    DROP COLUMN IF EXISTS numeric_code;
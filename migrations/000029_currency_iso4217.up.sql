@@ -0,0 +1,189 @@
+-- Describe currencies by ISO 4217, with hints for formatting amounts
+ALTER TABLE currencies
+    ADD COLUMN numeric_code VARCHAR(3) NOT NULL DEFAULT '',
+    ADD COLUMN symbol VARCHAR(8) NOT NULL DEFAULT '',
+    ADD COLUMN symbol_first BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN minor_units SMALLINT NOT NULL DEFAULT 2;
+
+-- Seed the currencies Nord Pool publishes prices in
+INSERT INTO currencies (name) VALUES
+    ('EUR'),
+    ('SEK'),
+    ('NOK'),
+    ('DKK'),
+    ('PLN')
+ON CONFLICT (name) DO NOTHING;
+
+-- Fill in the currencies of the standard
+UPDATE currencies SET numeric_code = v.numeric_code, minor_units = v.minor_units, symbol = v.symbol, symbol_first = v.symbol_first
+FROM (VALUES
+    ('AED', '784', 2, 'AED', TRUE),
+    ('AFN', '971', 2, 'AFN', TRUE),
+    ('ALL', '008', 2, 'ALL', TRUE),
+    ('AMD', '051', 2, 'AMD', TRUE),
+    ('ANG', '532', 2, 'ANG', TRUE),
+    ('AOA', '973', 2, 'AOA', TRUE),
+    ('ARS', '032', 2, 'ARS', TRUE),
+    ('AUD', '036', 2, 'A$', TRUE),
+    ('AWG', '533', 2, 'AWG', TRUE),
+    ('AZN', '944', 2, 'AZN', TRUE),
+    ('BAM', '977', 2, 'BAM', TRUE),
+    ('BBD', '052', 2, 'BBD', TRUE),
+    ('BDT', '050', 2, 'BDT', TRUE),
+    ('BGN', '975', 2, 'лв.', FALSE),
+    ('BHD', '048', 3, 'BHD', TRUE),
+    ('BIF', '108', 0, 'BIF', TRUE),
+    ('BMD', '060', 2, 'BMD', TRUE),
+    ('BND', '096', 2, 'BND', TRUE),
+    ('BOB', '068', 2, 'BOB', TRUE),
+    ('BOV', '984', 2, 'BOV', TRUE),
+    ('BRL', '986', 2, 'R$', TRUE),
+    ('BSD', '044', 2, 'BSD', TRUE),
+    ('BTN', '064', 2, 'BTN', TRUE),
+    ('BWP', '072', 2, 'BWP', TRUE),
+    ('BYN', '933', 2, 'BYN', TRUE),
+    ('BZD', '084', 2, 'BZD', TRUE),
+    ('CAD', '124', 2, 'CA$', TRUE),
+    ('CDF', '976', 2, 'CDF', TRUE),
+    ('CHE', '947', 2, 'CHE', TRUE),
+    ('CHF', '756', 2, 'CHF', TRUE),
+    ('CHW', '948', 2, 'CHW', TRUE),
+    ('CLF', '990', 4, 'CLF', TRUE),
+    ('CLP', '152', 0, 'CLP', TRUE),
+    ('CNY', '156', 2, '¥', TRUE),
+    ('COP', '170', 2, 'COP', TRUE),
+    ('COU', '970', 2, 'COU', TRUE),
+    ('CRC', '188', 2, 'CRC', TRUE),
+    ('CUP', '192', 2, 'CUP', TRUE),
+    ('CVE', '132', 2, 'CVE', TRUE),
+    ('CZK', '203', 2, 'Kč', FALSE),
+    ('DJF', '262', 0, 'DJF', TRUE),
+    ('DKK', '208', 2, 'kr.', FALSE),
+    ('DOP', '214', 2, 'DOP', TRUE),
+    ('DZD', '012', 2, 'DZD', TRUE),
+    ('EGP', '818', 2, 'EGP', TRUE),
+    ('ERN', '232', 2, 'ERN', TRUE),
+    ('ETB', '230', 2, 'ETB', TRUE),
+    ('EUR', '978', 2, '€', TRUE),
+    ('FJD', '242', 2, 'FJD', TRUE),
+    ('FKP', '238', 2, 'FKP', TRUE),
+    ('GBP', '826', 2, '£', TRUE),
+    ('GEL', '981', 2, 'GEL', TRUE),
+    ('GHS', '936', 2, 'GHS', TRUE),
+    ('GIP', '292', 2, 'GIP', TRUE),
+    ('GMD', '270', 2, 'GMD', TRUE),
+    ('GNF', '324', 0, 'GNF', TRUE),
+    ('GTQ', '320', 2, 'GTQ', TRUE),
+    ('GYD', '328', 2, 'GYD', TRUE),
+    ('HKD', '344', 2, 'HK$', TRUE),
+    ('HNL', '340', 2, 'HNL', TRUE),
+    ('HTG', '332', 2, 'HTG', TRUE),
+    ('HUF', '348', 2, 'Ft', FALSE),
+    ('IDR', '360', 2, 'IDR', TRUE),
+    ('ILS', '376', 2, '₪', TRUE),
+    ('INR', '356', 2, '₹', TRUE),
+    ('IQD', '368', 3, 'IQD', TRUE),
+    ('IRR', '364', 2, 'IRR', TRUE),
+    ('ISK', '352', 0, 'kr', FALSE),
+    ('JMD', '388', 2, 'JMD', TRUE),
+    ('JOD', '400', 3, 'JOD', TRUE),
+    ('JPY', '392', 0, '¥', TRUE),
+    ('KES', '404', 2, 'KES', TRUE),
+    ('KGS', '417', 2, 'KGS', TRUE),
+    ('KHR', '116', 2, 'KHR', TRUE),
+    ('KMF', '174', 0, 'KMF', TRUE),
+    ('KPW', '408', 2, 'KPW', TRUE),
+    ('KRW', '410', 0, '₩', TRUE),
+    ('KWD', '414', 3, 'KWD', TRUE),
+    ('KYD', '136', 2, 'KYD', TRUE),
+    ('KZT', '398', 2, 'KZT', TRUE),
+    ('LAK', '418', 2, 'LAK', TRUE),
+    ('LBP', '422', 2, 'LBP', TRUE),
+    ('LKR', '144', 2, 'LKR', TRUE),
+    ('LRD', '430', 2, 'LRD', TRUE),
+    ('LSL', '426', 2, 'LSL', TRUE),
+    ('LYD', '434', 3, 'LYD', TRUE),
+    ('MAD', '504', 2, 'MAD', TRUE),
+    ('MDL', '498', 2, 'MDL', TRUE),
+    ('MGA', '969', 2, 'MGA', TRUE),
+    ('MKD', '807', 2, 'MKD', TRUE),
+    ('MMK', '104', 2, 'MMK', TRUE),
+    ('MNT', '496', 2, 'MNT', TRUE),
+    ('MOP', '446', 2, 'MOP', TRUE),
+    ('MRU', '929', 2, 'MRU', TRUE),
+    ('MUR', '480', 2, 'MUR', TRUE),
+    ('MVR', '462', 2, 'MVR', TRUE),
+    ('MWK', '454', 2, 'MWK', TRUE),
+    ('MXN', '484', 2, 'MX$', TRUE),
+    ('MXV', '979', 2, 'MXV', TRUE),
+    ('MYR', '458', 2, 'MYR', TRUE),
+    ('MZN', '943', 2, 'MZN', TRUE),
+    ('NAD', '516', 2, 'NAD', TRUE),
+    ('NGN', '566', 2, '₦', TRUE),
+    ('NIO', '558', 2, 'NIO', TRUE),
+    ('NOK', '578', 2, 'kr', FALSE),
+    ('NPR', '524', 2, 'NPR', TRUE),
+    ('NZD', '554', 2, 'NZ$', TRUE),
+    ('OMR', '512', 3, 'OMR', TRUE),
+    ('PAB', '590', 2, 'PAB', TRUE),
+    ('PEN', '604', 2, 'PEN', TRUE),
+    ('PGK', '598', 2, 'PGK', TRUE),
+    ('PHP', '608', 2, '₱', TRUE),
+    ('PKR', '586', 2, 'PKR', TRUE),
+    ('PLN', '985', 2, 'zł', FALSE),
+    ('PYG', '600', 0, 'PYG', TRUE),
+    ('QAR', '634', 2, 'QAR', TRUE),
+    ('RON', '946', 2, 'lei', FALSE),
+    ('RSD', '941', 2, 'RSD', TRUE),
+    ('RUB', '643', 2, '₽', FALSE),
+    ('RWF', '646', 0, 'RWF', TRUE),
+    ('SAR', '682', 2, 'SAR', TRUE),
+    ('SBD', '090', 2, 'SBD', TRUE),
+    ('SCR', '690', 2, 'SCR', TRUE),
+    ('SDG', '938', 2, 'SDG', TRUE),
+    ('SEK', '752', 2, 'kr', FALSE),
+    ('SGD', '702', 2, 'S$', TRUE),
+    ('SHP', '654', 2, 'SHP', TRUE),
+    ('SLE', '925', 2, 'SLE', TRUE),
+    ('SOS', '706', 2, 'SOS', TRUE),
+    ('SRD', '968', 2, 'SRD', TRUE),
+    ('SSP', '728', 2, 'SSP', TRUE),
+    ('STN', '930', 2, 'STN', TRUE),
+    ('SVC', '222', 2, 'SVC', TRUE),
+    ('SYP', '760', 2, 'SYP', TRUE),
+    ('SZL', '748', 2, 'SZL', TRUE),
+    ('THB', '764', 2, '฿', TRUE),
+    ('TJS', '972', 2, 'TJS', TRUE),
+    ('TMT', '934', 2, 'TMT', TRUE),
+    ('TND', '788', 3, 'TND', TRUE),
+    ('TOP', '776', 2, 'TOP', TRUE),
+    ('TRY', '949', 2, '₺', TRUE),
+    ('TTD', '780', 2, 'TTD', TRUE),
+    ('TWD', '901', 2, 'TWD', TRUE),
+    ('TZS', '834', 2, 'TZS', TRUE),
+    ('UAH', '980', 2, '₴', FALSE),
+    ('UGX', '800', 0, 'UGX', TRUE),
+    ('USD', '840', 2, '$', TRUE),
+    ('USN', '997', 2, 'USN', TRUE),
+    ('UYI', '940', 0, 'UYI', TRUE),
+    ('UYU', '858', 2, 'UYU', TRUE),
+    ('UYW', '927', 4, 'UYW', TRUE),
+    ('UZS', '860', 2, 'UZS', TRUE),
+    ('VED', '926', 2, 'VED', TRUE),
+    ('VES', '928', 2, 'VES', TRUE),
+    ('VND', '704', 0, '₫', FALSE),
+    ('VUV', '548', 0, 'VUV', TRUE),
+    ('WST', '882', 2, 'WST', TRUE),
+    ('XAF', '950', 0, 'XAF', TRUE),
+    ('XCD', '951', 2, 'XCD', TRUE),
+    ('XOF', '952', 0, 'XOF', TRUE),
+    ('XPF', '953', 0, 'XPF', TRUE),
+    ('YER', '886', 2, 'YER', TRUE),
+    ('ZAR', '710', 2, 'R', TRUE),
+    ('ZMW', '967', 2, 'ZMW', TRUE),
+    ('ZWG', '924', 2, 'ZWG', TRUE)
+) AS v(code, numeric_code, minor_units, symbol, symbol_first)
+WHERE currencies.name = v.code;
+
+-- Other currencies are written with their code
+UPDATE currencies SET symbol = name WHERE symbol = '';
@@ -0,0 +1,9 @@
+-- Defaults users pick for endpoints called without a zone or currency, and the timezone
+-- timestamps are shown in. The locale is the language column of users.
+CREATE TABLE user_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    zone_id UUID REFERENCES zones(id) ON DELETE SET NULL,
+    currency_id UUID REFERENCES currencies(id) ON DELETE SET NULL,
+    timezone VARCHAR(64),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
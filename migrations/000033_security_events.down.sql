@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS security_events;
+DROP INDEX IF EXISTS idx_login_attempts_user_fingerprint;
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS fingerprint;
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS user_agent;
@@ -0,0 +1,19 @@
+-- Logins are compared with the earlier successful logins of the user to spot new devices,
+-- identified by a hash of their user agent
+ALTER TABLE login_attempts ADD COLUMN user_agent TEXT NOT NULL DEFAULT '';
+ALTER TABLE login_attempts ADD COLUMN fingerprint VARCHAR(64);
+
+CREATE INDEX idx_login_attempts_user_fingerprint ON login_attempts(user_id, fingerprint)
+    WHERE success;
+
+-- Logins from devices or IP addresses the user hadn't logged in from before
+CREATE TABLE security_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    ip VARCHAR(45) NOT NULL,
+    user_agent TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX idx_security_events_user_created_at ON security_events(user_id, created_at DESC);
@@ -0,0 +1,5 @@
+DROP TRIGGER IF EXISTS record_revision ON spot_prices;
+DROP FUNCTION IF EXISTS record_spot_price_revision();
+ALTER TABLE spot_prices DROP COLUMN IF EXISTS change_source;
+ALTER TABLE spot_prices DROP COLUMN IF EXISTS changed_by;
+DROP TABLE IF EXISTS spot_price_revisions;
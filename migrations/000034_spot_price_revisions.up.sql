@@ -0,0 +1,41 @@
+-- Earlier prices of spot prices, recorded whenever an update changes the price so prices
+-- can be traced and read as they were at a point in time
+CREATE TABLE spot_price_revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    spot_price_id UUID NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    zone_id UUID NOT NULL REFERENCES zones(id),
+    currency_id UUID NOT NULL REFERENCES currencies(id),
+    price DECIMAL(10,4) NOT NULL,
+    new_price DECIMAL(10,4) NOT NULL,
+    changed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    source VARCHAR(50),
+    changed_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT clock_timestamp()
+);
+
+CREATE INDEX idx_spot_price_revisions_spot_price ON spot_price_revisions(spot_price_id, changed_at);
+CREATE INDEX idx_spot_price_revisions_zone_currency_time
+    ON spot_price_revisions(zone_id, currency_id, timestamp);
+
+-- Updates set who or which source changed the price, the trigger moves them to the
+-- revision so they are never kept on the spot price itself
+ALTER TABLE spot_prices ADD COLUMN changed_by UUID;
+ALTER TABLE spot_prices ADD COLUMN change_source VARCHAR(50);
+
+CREATE OR REPLACE FUNCTION record_spot_price_revision()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF NEW.price IS DISTINCT FROM OLD.price THEN
+        INSERT INTO spot_price_revisions (spot_price_id, timestamp, zone_id, currency_id, price, new_price, changed_by, source)
+        VALUES (OLD.id, NEW.timestamp, NEW.zone_id, NEW.currency_id, OLD.price, NEW.price, NEW.changed_by, NEW.change_source);
+    END IF;
+    NEW.changed_by := NULL;
+    NEW.change_source := NULL;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER record_revision
+    BEFORE UPDATE ON spot_prices
+    FOR EACH ROW
+    EXECUTE FUNCTION record_spot_price_revision();
@@ -0,0 +1,21 @@
+-- Create budgets table with the monthly amount users plan to spend on electricity in a
+-- zone and currency. Owners are notified once a month when the projected cost of the
+-- month exceeds the amount.
+CREATE TABLE budgets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    zone_id UUID NOT NULL REFERENCES zones(id) ON DELETE CASCADE,
+    currency_id UUID NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    notify BOOLEAN NOT NULL DEFAULT TRUE,
+    last_notified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_id, zone_id, currency_id)
+);
+
+-- Create updated_at trigger for budgets
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON budgets
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
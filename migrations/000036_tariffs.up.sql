@@ -0,0 +1,29 @@
+-- Create tariffs table with the fees and taxes added to the spot price, which belong to a
+-- user or to an organization whose members share them. Rates replace the transfer fee at
+-- times of day in the zone's timezone.
+CREATE TABLE tariffs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    organization_id UUID REFERENCES organizations(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    zone_id UUID NOT NULL REFERENCES zones(id) ON DELETE CASCADE,
+    currency_id UUID NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
+    transfer_fee DECIMAL(10,4) NOT NULL DEFAULT 0 CHECK (transfer_fee >= 0),
+    energy_tax DECIMAL(10,4) NOT NULL DEFAULT 0 CHECK (energy_tax >= 0),
+    monthly_fee DECIMAL(12,2) NOT NULL DEFAULT 0 CHECK (monthly_fee >= 0),
+    vat DECIMAL(5,2) NOT NULL DEFAULT 0 CHECK (vat >= 0 AND vat <= 100),
+    rates JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CHECK ((user_id IS NULL) <> (organization_id IS NULL))
+);
+
+-- Create updated_at trigger for tariffs
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON tariffs
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- An owner has one tariff per zone and currency
+CREATE UNIQUE INDEX idx_tariffs_user ON tariffs(user_id, zone_id, currency_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_tariffs_organization ON tariffs(organization_id, zone_id, currency_id) WHERE organization_id IS NOT NULL;
@@ -0,0 +1,2 @@
+ALTER TABLE tariffs DROP COLUMN IF EXISTS export_fee;
+DROP TABLE IF EXISTS production_records;
@@ -0,0 +1,30 @@
+-- Create production_records table holding the energy users' solar panels and other
+-- generation produced per interval, and how much of it was exported to the grid
+CREATE TABLE production_records (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    meter_id VARCHAR(100) NOT NULL,
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    produced_kwh DECIMAL(12,4) NOT NULL CHECK (produced_kwh >= 0),
+    exported_kwh DECIMAL(12,4) NOT NULL CHECK (exported_kwh >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, meter_id, timestamp)
+);
+
+CREATE INDEX idx_production_records_user_time
+    ON production_records (user_id, timestamp DESC);
+
+-- Convert production_records to hypertable
+SELECT create_hypertable('production_records', 'timestamp',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE
+);
+
+-- Create updated_at trigger for production_records
+CREATE TRIGGER set_timestamp
+    BEFORE UPDATE ON production_records
+    FOR EACH ROW
+    EXECUTE FUNCTION trigger_set_timestamp();
+
+-- Exported energy is sold at the spot price less the fee the buyer deducts per kWh
+ALTER TABLE tariffs ADD COLUMN export_fee DECIMAL(10,4) NOT NULL DEFAULT 0 CHECK (export_fee >= 0);
@@ -0,0 +1,21 @@
+-- Create price_forecasts table holding forecasts of spot prices, kept apart from the
+-- published prices. Each source keeps its latest forecast of a time.
+CREATE TABLE price_forecasts (
+    timestamp TIMESTAMP WITH TIME ZONE NOT NULL,
+    zone_id UUID NOT NULL REFERENCES zones(id) ON DELETE CASCADE,
+    currency_id UUID NOT NULL REFERENCES currencies(id) ON DELETE CASCADE,
+    source VARCHAR(100) NOT NULL,
+    price DECIMAL(10,4) NOT NULL,
+    horizon_hours INTEGER NOT NULL CHECK (horizon_hours >= 0),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (zone_id, currency_id, source, timestamp)
+);
+
+CREATE INDEX idx_price_forecasts_zone_currency_time
+    ON price_forecasts (zone_id, currency_id, timestamp DESC);
+
+-- Convert price_forecasts to hypertable
+SELECT create_hypertable('price_forecasts', 'timestamp',
+    chunk_time_interval => INTERVAL '7 days',
+    if_not_exists => TRUE
+);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_login_attempts_username;
+ALTER TABLE login_attempts DROP COLUMN IF EXISTS username;
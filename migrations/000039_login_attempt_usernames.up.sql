@@ -0,0 +1,8 @@
+-- Failed logins are counted by the submitted username whether or not a user has it, so
+-- lockouts don't reveal which usernames exist
+ALTER TABLE login_attempts ADD COLUMN username VARCHAR(50);
+
+UPDATE login_attempts a SET username = u.username FROM users u WHERE a.user_id = u.id;
+
+CREATE INDEX idx_login_attempts_username ON login_attempts(username, created_at)
+    WHERE NOT success;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_user_preferences_weekly_report;
+ALTER TABLE user_preferences DROP COLUMN IF EXISTS weekly_report;
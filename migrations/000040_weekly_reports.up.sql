@@ -0,0 +1,4 @@
+-- Users opting in get a weekly email with the consumption and cost of each of their meters
+ALTER TABLE user_preferences ADD COLUMN weekly_report BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX idx_user_preferences_weekly_report ON user_preferences(user_id) WHERE weekly_report;
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS used_pre_auth_tokens;
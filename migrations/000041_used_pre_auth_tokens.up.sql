@@ -0,0 +1,10 @@
+-- Create used_pre_auth_tokens table with the IDs of the two-factor login tokens already
+-- exchanged for a session, so a captured token can't be exchanged again before it expires.
+-- Rows of expired tokens are removed when the user exchanges the next one.
+CREATE TABLE used_pre_auth_tokens (
+    id UUID PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX idx_used_pre_auth_tokens_user_id ON used_pre_auth_tokens(user_id);
@@ -0,0 +1,243 @@
+// Package wattwatchtest provides a fake WattWatch API for testing integrations without
+// network access or a database. The fake runs the real authentication, zone, currency
+// and spot price handlers on an httptest.Server, backed by in-memory repositories.
+//
+//	srv := wattwatchtest.NewServer(t)
+//	srv.AddUser("bridge", "password123", false)
+//	srv.AddSpotPrices("SE3", "EUR", wattwatchtest.Price{Time: hour, Value: 42.5})
+//	client := mybridge.New(srv.URL, ...)
+package wattwatchtest
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http/httptest"
+	"testing"
+	"time"
+	"wattwatch/internal/api/handlers"
+	"wattwatch/internal/api/middleware"
+	"wattwatch/internal/auth"
+	"wattwatch/internal/config"
+	"wattwatch/internal/models"
+	"wattwatch/internal/repository"
+	"wattwatch/internal/repository/memory"
+	"wattwatch/internal/settings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Server is a fake WattWatch API listening on a local address. Zones SE1 to SE4 and the
+// currencies EUR and SEK exist from the start, like in a freshly migrated database.
+type Server struct {
+	*httptest.Server
+
+	tb          testing.TB
+	users       repository.UserRepository
+	roles       repository.RoleRepository
+	zones       repository.ZoneRepository
+	currencies  repository.CurrencyRepository
+	spotPrices  repository.SpotPriceRepository
+	authService *auth.Service
+}
+
+// Price is the spot price of the hour starting at Time
+type Price struct {
+	Time  time.Time
+	Value float64
+}
+
+// NewServer starts a fake server that is closed when the test finishes. The API is served
+// under /api/v1 like the real server:
+//
+//	POST /api/v1/auth/login, /api/v1/auth/register and /api/v1/auth/refresh
+//	GET  /api/v1/zones, /api/v1/zones/:id, /api/v1/currencies and /api/v1/currencies/:id
+//	GET  /api/v1/spot-prices and /api/v1/spot-prices/:id, POST /api/v1/spot-prices for admins
+//
+// Registration is open and sends no email.
+func NewServer(tb testing.TB) *Server {
+	tb.Helper()
+	gin.SetMode(gin.TestMode)
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		tb.Fatalf("wattwatchtest: failed to generate JWT secret: %v", err)
+	}
+	cfg := config.Default()
+	cfg.JWTSecret = hex.EncodeToString(secret)
+	cfg.Auth.JWTSecret = cfg.JWTSecret
+	cfg.Email.WelcomeEmail = false
+
+	store := memory.NewStore()
+	s := &Server{
+		tb:         tb,
+		users:      memory.NewUserRepository(store),
+		roles:      memory.NewRoleRepository(store),
+		zones:      memory.NewZoneRepository(store),
+		currencies: memory.NewCurrencyRepository(store),
+		spotPrices: memory.NewSpotPriceRepository(store),
+	}
+	auditRepo := memory.NewAuditLogRepository(store)
+	s.authService = auth.NewService(cfg, memory.NewRefreshTokenRepository(store))
+
+	authHandler := handlers.NewAuthHandler(
+		s.users,
+		s.roles,
+		s.authService,
+		auditRepo,
+		discardEmail{},
+		cfg,
+		memory.NewLoginAttemptRepository(store),
+		memory.NewEmailVerificationRepository(store),
+		memory.NewPasswordResetRepository(store),
+		settings.NewStore(memory.NewSettingRepository(store), cfg),
+	)
+	authHandler.SetTxManager(memory.NewTxManager(store))
+	zoneHandler := handlers.NewZoneHandler(s.zones, auditRepo)
+	currencyHandler := handlers.NewCurrencyHandler(s.currencies, auditRepo)
+	spotPriceHandler := handlers.NewSpotPriceHandler(s.spotPrices, s.zones, s.currencies)
+	spotPriceHandler.SetRevisions(memory.NewSpotPriceRevisionRepository(store))
+	authMiddleware := middleware.NewAuthMiddleware(s.authService, s.users, s.roles)
+
+	r := gin.New()
+	r.GET("/ping", handlers.NewHealthHandler(nil).Ping)
+	v1 := r.Group("/api/v1")
+	{
+		authRoutes := v1.Group("/auth")
+		authRoutes.POST("/login", authHandler.Login)
+		authRoutes.POST("/register", authHandler.Register)
+		authRoutes.POST("/refresh", authHandler.Refresh)
+
+		zones := v1.Group("/zones", authMiddleware.ClaimsRequired())
+		zones.GET("", zoneHandler.ListZones)
+		zones.GET("/:id", zoneHandler.GetZone)
+
+		currencies := v1.Group("/currencies", authMiddleware.ClaimsRequired())
+		currencies.GET("", currencyHandler.ListCurrencies)
+		currencies.GET("/:id", currencyHandler.GetCurrency)
+
+		spotPrices := v1.Group("/spot-prices")
+		spotPrices.GET("", spotPriceHandler.ListSpotPrices)
+		spotPrices.GET("/:id", spotPriceHandler.GetSpotPrice)
+		spotPrices.GET("/:id/history", spotPriceHandler.GetSpotPriceHistory)
+		spotPrices.POST("", authMiddleware.AdminRequired(), spotPriceHandler.CreateSpotPrices)
+	}
+
+	s.Server = httptest.NewServer(r)
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// AddUser creates a user that can log in with the password, admins may also create spot
+// prices. It returns the user's ID.
+func (s *Server) AddUser(username, password string, admin bool) string {
+	s.tb.Helper()
+	ctx := context.Background()
+
+	roleName := "user"
+	if admin {
+		roleName = "admin"
+	}
+	role, err := s.roles.GetByName(ctx, roleName)
+	if err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to get role %s: %v", roleName, err)
+	}
+	hashed, err := s.authService.HashPassword(password)
+	if err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to hash password: %v", err)
+	}
+
+	user := &models.User{Username: username, Password: hashed, RoleID: role.ID}
+	if err := s.users.Create(ctx, user); err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to create user %s: %v", username, err)
+	}
+	return user.ID.String()
+}
+
+// Token returns an access token for the user, for tests that skip the login request
+func (s *Server) Token(username string) string {
+	s.tb.Helper()
+
+	user, err := s.users.GetByUsername(context.Background(), username)
+	if err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to get user %s: %v", username, err)
+	}
+	token, err := s.authService.GenerateToken(user, false)
+	if err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to generate token: %v", err)
+	}
+	return token
+}
+
+// AddZone creates a bidding zone in addition to SE1 to SE4
+func (s *Server) AddZone(name, timezone string) {
+	s.tb.Helper()
+
+	if err := s.zones.Create(context.Background(), &models.Zone{Name: name, Timezone: timezone}); err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to create zone %s: %v", name, err)
+	}
+}
+
+// AddCurrency creates a currency in addition to EUR and SEK
+func (s *Server) AddCurrency(name string) {
+	s.tb.Helper()
+
+	if err := s.currencies.Create(context.Background(), &models.Currency{Name: name}); err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to create currency %s: %v", name, err)
+	}
+}
+
+// AddSpotPrices stores prices for the zone and currency, replacing prices already stored
+// for the same hours
+func (s *Server) AddSpotPrices(zone, currency string, prices ...Price) {
+	s.tb.Helper()
+	ctx := context.Background()
+
+	z, err := s.zones.GetByName(ctx, zone)
+	if err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to get zone %s: %v", zone, err)
+	}
+	c, err := s.currencies.GetByName(ctx, currency)
+	if err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to get currency %s: %v", currency, err)
+	}
+
+	spotPrices := make([]models.SpotPrice, len(prices))
+	for i, p := range prices {
+		spotPrices[i] = models.SpotPrice{Timestamp: p.Time, ZoneID: z.ID, CurrencyID: c.ID, Price: p.Value}
+	}
+	if err := s.spotPrices.CreateBatch(ctx, spotPrices); err != nil {
+		s.tb.Fatalf("wattwatchtest: failed to store spot prices: %v", err)
+	}
+}
+
+// discardEmail accepts every email without sending it
+type discardEmail struct{}
+
+func (discardEmail) SendVerificationEmail(to, username, language, token string, expiresAt time.Time) error {
+	return nil
+}
+
+func (discardEmail) SendPasswordResetEmail(to, username, language, token string, expiresAt time.Time) error {
+	return nil
+}
+
+func (discardEmail) SendEmailChangedNotification(to, username, language, newEmail, revertToken string, expiresAt time.Time) error {
+	return nil
+}
+
+func (discardEmail) SendWelcomeEmail(to, username, language string) error {
+	return nil
+}
+
+func (discardEmail) SendInviteEmail(to, username, language, token string, expiresAt time.Time) error {
+	return nil
+}
+
+func (discardEmail) SendAccountDeletionEmail(to, username, language string, deleteAt time.Time) error {
+	return nil
+}
+
+func (discardEmail) SendNewDeviceLoginEmail(to, username, language, ipAddress, userAgent string, at time.Time) error {
+	return nil
+}
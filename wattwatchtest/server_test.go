@@ -0,0 +1,71 @@
+package wattwatchtest_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+	"wattwatch/internal/models"
+	"wattwatch/wattwatchtest"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestServer(t *testing.T) {
+	srv := wattwatchtest.NewServer(t)
+	srv.AddUser("bridge", "password123", false)
+
+	start := time.Date(2024, 3, 20, 0, 0, 0, 0, time.UTC)
+	srv.AddSpotPrices("SE3", "EUR",
+		wattwatchtest.Price{Time: start, Value: 41.5},
+		wattwatchtest.Price{Time: start.Add(time.Hour), Value: 38.25},
+	)
+
+	// Log in like a client would
+	body, _ := json.Marshal(models.LoginRequest{Username: "bridge", Password: "password123"})
+	resp, err := http.Post(srv.URL+"/api/v1/auth/login", "application/json", bytes.NewReader(body))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var login struct {
+		AccessToken string `json:"access_token"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&login))
+	require.NotEmpty(t, login.AccessToken)
+
+	// Zones require authentication
+	resp, err = http.Get(srv.URL + "/api/v1/zones")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/api/v1/zones", nil)
+	req.Header.Set("Authorization", "Bearer "+srv.Token("bridge"))
+	resp, err = http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var zones models.Page[models.Zone]
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&zones))
+	require.Len(t, zones.Items, 4)
+	require.Equal(t, 4, zones.Total)
+
+	query := url.Values{
+		"zone":       {"SE3"},
+		"currency":   {"EUR"},
+		"start_time": {start.Format(time.RFC3339)},
+		"end_time":   {start.Add(24 * time.Hour).Format(time.RFC3339)},
+	}
+	resp, err = http.Get(srv.URL + "/api/v1/spot-prices?" + query.Encode())
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var prices models.Page[models.SpotPrice]
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&prices))
+	require.Len(t, prices.Items, 2)
+	require.Equal(t, 2, prices.Total)
+	require.Nil(t, prices.NextOffset)
+	require.Equal(t, 41.5, prices.Items[0].Price)
+}
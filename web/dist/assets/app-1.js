@@ -0,0 +1,130 @@
+// Minimal dashboard for the WattWatch API: log in and browse spot prices per zone and day.
+(function () {
+  'use strict';
+
+  const api = '/api/v1';
+  const $ = (selector) => document.querySelector(selector);
+  let accessToken = sessionStorage.getItem('access_token');
+
+  async function request(path, options = {}) {
+    const headers = { 'Content-Type': 'application/json' };
+    if (accessToken) headers.Authorization = 'Bearer ' + accessToken;
+    const res = await fetch(api + path, { ...options, headers });
+    if (res.status === 401 && accessToken) {
+      logout();
+      throw new Error('Your session has expired, please log in again');
+    }
+    const body = res.status === 204 ? null : await res.json().catch(() => null);
+    if (!res.ok) throw new Error((body && body.error) || res.statusText);
+    return body;
+  }
+
+  function show(loggedIn) {
+    $('#login').hidden = loggedIn;
+    $('#dashboard').hidden = !loggedIn;
+    $('#logout').hidden = !loggedIn;
+  }
+
+  function logout() {
+    accessToken = null;
+    sessionStorage.removeItem('access_token');
+    show(false);
+  }
+
+  function fillSelect(select, items) {
+    select.replaceChildren(...items.map((item) => new Option(item.name, item.name)));
+  }
+
+  async function loadPrices() {
+    const error = $('#dashboard .error');
+    error.textContent = '';
+    const start = new Date($('#day').value + 'T00:00:00');
+    const end = new Date(start.getTime() + 24 * 60 * 60 * 1000);
+    const query = new URLSearchParams({
+      zone: $('#zone').value,
+      currency: $('#currency').value,
+      start_time: start.toISOString(),
+      end_time: end.toISOString(),
+    });
+
+    try {
+      const page = await request('/spot-prices?' + query);
+      const prices = (page && page.items) || [];
+      render(prices);
+    } catch (err) {
+      render([]);
+      error.textContent = err.message;
+    }
+  }
+
+  function render(prices) {
+    const currency = $('#currency').value;
+    const max = Math.max(...prices.map((p) => Math.abs(p.price)), 0);
+    const rows = prices.map((p) => {
+      const row = document.createElement('tr');
+      const time = new Date(p.timestamp).toLocaleTimeString([], { hour: '2-digit', minute: '2-digit' });
+      row.append(cell(time), cell(p.price.toFixed(2) + ' ' + currency));
+      return row;
+    });
+    $('#prices').replaceChildren(...rows);
+
+    $('#chart').replaceChildren(...prices.map((p) => {
+      const bar = document.createElement('div');
+      bar.style.height = max ? (Math.abs(p.price) / max) * 100 + '%' : '0';
+      bar.title = p.price.toFixed(2) + ' ' + currency;
+      if (p.price < 0) bar.className = 'negative';
+      return bar;
+    }));
+
+    if (prices.length === 0) {
+      $('.summary').textContent = 'No prices for this day yet.';
+      return;
+    }
+    const values = prices.map((p) => p.price);
+    const avg = values.reduce((a, b) => a + b, 0) / values.length;
+    $('.summary').textContent = 'Min ' + Math.min(...values).toFixed(2) + ', average ' + avg.toFixed(2) +
+      ', max ' + Math.max(...values).toFixed(2) + ' ' + currency;
+  }
+
+  function cell(text) {
+    const td = document.createElement('td');
+    td.textContent = text;
+    return td;
+  }
+
+  async function start() {
+    show(true);
+    try {
+      const [zones, currencies] = await Promise.all([request('/zones'), request('/currencies')]);
+      fillSelect($('#zone'), (zones && zones.items) || []);
+      fillSelect($('#currency'), currencies || []);
+      await loadPrices();
+    } catch (err) {
+      $('#dashboard .error').textContent = err.message;
+    }
+  }
+
+  $('#login').addEventListener('submit', async (event) => {
+    event.preventDefault();
+    const form = new FormData(event.target);
+    const error = $('#login .error');
+    error.textContent = '';
+    try {
+      const tokens = await request('/auth/login', {
+        method: 'POST',
+        body: JSON.stringify({ username: form.get('username'), password: form.get('password') }),
+      });
+      accessToken = tokens.access_token;
+      sessionStorage.setItem('access_token', accessToken);
+      await start();
+    } catch (err) {
+      error.textContent = err.message;
+    }
+  });
+
+  $('#logout').addEventListener('click', logout);
+  for (const id of ['#zone', '#currency', '#day']) $(id).addEventListener('change', loadPrices);
+  $('#day').value = new Date().toLocaleDateString('sv-SE'); // YYYY-MM-DD in local time
+
+  if (accessToken) start(); else show(false);
+})();
@@ -0,0 +1,23 @@
+// Package web embeds the built dashboard served at the root of the API server.
+//
+// dist holds the build output. Replace its contents with the output of a frontend build to
+// ship a different UI, files under dist/assets are expected to have content hashed names.
+package web
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed all:dist
+var dist embed.FS
+
+// Files returns the dashboard files, rooted at the directory containing index.html
+func Files() fs.FS {
+	files, err := fs.Sub(dist, "dist")
+	if err != nil {
+		// fs.Sub only fails for invalid paths, "dist" is always valid
+		panic(err)
+	}
+	return files
+}